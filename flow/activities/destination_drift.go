@@ -0,0 +1,177 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+	"golang.org/x/exp/maps"
+
+	"github.com/PeerDB-io/peer-flow/connectors"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// CheckDestinationDrift compares the destination tables of a CDC mirror, and those of its fan-out destinations,
+// with the schemas the mirror replicates, so that tables changed by users are caught before normalize fails on them.
+// Under DESTINATION_DRIFT_REPAIR the tables are changed back to their schemas, what can't be repaired is alerted on
+// and returned as drifted for the workflow to pause on under DESTINATION_DRIFT_PAUSE.
+func (a *FlowableActivity) CheckDestinationDrift(
+	ctx context.Context,
+	input *protos.CheckDestinationDriftInput,
+) (*protos.CheckDestinationDriftOutput, error) {
+	config := input.FlowConnectionConfigs
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	logger := activity.GetLogger(ctx)
+
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return "checking destination tables for drift"
+	})
+	defer shutdown()
+
+	var drifted, problems []string
+	for _, destinationConfig := range append([]*protos.FlowConnectionConfigs{config}, model.FanoutConfigs(config)...) {
+		prefix := ""
+		if destinationConfig != config {
+			prefix = destinationConfig.Destination.Name + "."
+		}
+		tableProblems, err := a.checkDestinationDrift(ctx, destinationConfig, input.TableNameSchemaMapping)
+		if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			logger.Info("destination doesn't support checking for drift",
+				slog.String("peer", destinationConfig.Destination.Name))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		tables := maps.Keys(tableProblems)
+		slices.Sort(tables)
+		for _, table := range tables {
+			drifted = append(drifted, prefix+table)
+			problems = append(problems, prefix+tableProblems[table])
+		}
+	}
+
+	if len(problems) != 0 {
+		logger.Warn("destination tables drifted from their schemas", slog.Any("tables", drifted))
+		a.Alerter.AlertDestinationDrift(ctx, config.FlowJobName, strings.Join(problems, "; "),
+			config.DestinationDriftPolicy == protos.DestinationDriftPolicy_DESTINATION_DRIFT_PAUSE)
+	}
+	return &protos.CheckDestinationDriftOutput{DriftedTables: drifted}, nil
+}
+
+// checkDestinationDrift diffs the tables of one destination, repairing them under DESTINATION_DRIFT_REPAIR,
+// and returns a description of the drift left on each table
+func (a *FlowableActivity) checkDestinationDrift(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	tableNameSchemaMapping map[string]*protos.TableSchema,
+) (map[string]string, error) {
+	conn, err := connectors.GetCDCSyncConnector(ctx, config.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connector of destination %s: %w", config.Destination.Name, err)
+	}
+	defer connectors.CloseConnector(ctx, conn)
+	driftConn, ok := conn.(connectors.TableAdoptionConnector)
+	if !ok {
+		return nil, connectors.ErrUnsupportedFunctionality
+	}
+
+	setupConfig := &protos.SetupNormalizedTableBatchInput{
+		PeerConnectionConfig: config.Destination,
+		SoftDeleteColName:    config.SoftDeleteColName,
+		SyncedAtColName:      config.SyncedAtColName,
+		FlowName:             config.FlowJobName,
+	}
+	srcTableNames := make(map[string]string, len(config.TableMappings))
+	for _, mapping := range config.TableMappings {
+		srcTableNames[mapping.DestinationTableIdentifier] = mapping.SourceTableIdentifier
+	}
+
+	drift := make(map[string]string)
+	for tableIdentifier, tableSchema := range tableNameSchemaMapping {
+		tableDrift, err := driftConn.DiffTable(ctx, setupConfig, tableIdentifier, tableSchema)
+		if errors.Is(err, utils.ErrNotAdoptable) {
+			drift[tableIdentifier] = tableIdentifier + ": table doesn't exist"
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to check table %s for drift: %w", tableIdentifier, err)
+		}
+
+		// extra columns of tables the mirror adopted may have been there before it
+		if config.AdoptExistingTables {
+			tableDrift.Extra = nil
+		}
+		repair, unrepaired := reconcileTableDrift(
+			config.DestinationDriftPolicy, srcTableNames[tableIdentifier], tableIdentifier, tableSchema, tableDrift)
+		if repair != nil {
+			if err := conn.ReplayTableSchemaDeltas(ctx, config.FlowJobName, []*protos.TableSchemaDelta{repair}); err != nil {
+				activity.GetLogger(ctx).Warn("failed to repair destination table",
+					slog.String("table", tableIdentifier), slog.Any("error", err))
+				drift[tableIdentifier] = fmt.Sprintf("%s: %s, failed to repair it: %v", tableIdentifier, tableDrift, err)
+				continue
+			}
+			activity.GetLogger(ctx).Info("repaired destination table",
+				slog.String("table", tableIdentifier),
+				slog.Int("addedColumns", len(repair.AddedColumns)),
+				slog.Int("droppedColumns", len(repair.DroppedColumns)))
+		}
+		if !unrepaired.Empty() {
+			drift[tableIdentifier] = fmt.Sprintf("%s: %s", tableIdentifier, unrepaired)
+		}
+	}
+	return drift, nil
+}
+
+// reconcileTableDrift returns the schema delta repairing the drift of a destination table under
+// DESTINATION_DRIFT_REPAIR, nil if there's nothing to repair, and the drift left to alert on.
+// Dropped columns are added back and retyped ones are dropped and added back with their type, either way they're
+// filled again by new changes of their rows. Columns users added are dropped.
+// Primary key columns and the soft delete and synced at columns are left to be alerted on.
+func reconcileTableDrift(
+	policy protos.DestinationDriftPolicy,
+	srcTableName string,
+	dstTableName string,
+	tableSchema *protos.TableSchema,
+	drift *utils.TableDrift,
+) (*protos.TableSchemaDelta, *utils.TableDrift) {
+	if policy != protos.DestinationDriftPolicy_DESTINATION_DRIFT_REPAIR || drift.Empty() {
+		return nil, drift
+	}
+
+	repair := &protos.TableSchemaDelta{SrcTableName: srcTableName, DstTableName: dstTableName}
+	left := &utils.TableDrift{}
+	for _, column := range drift.Missing {
+		if column.Source == nil {
+			left.Missing = append(left.Missing, column)
+			continue
+		}
+		repair.AddedColumns = append(repair.AddedColumns, &protos.DeltaAddedColumn{
+			ColumnName: column.Source.Name,
+			ColumnType: column.Source.Type,
+		})
+	}
+	for _, column := range drift.Mismatched {
+		if column.Column.Source == nil || slices.Contains(tableSchema.PrimaryKeyColumns, column.Column.Source.Name) {
+			left.Mismatched = append(left.Mismatched, column)
+			continue
+		}
+		repair.DroppedColumns = append(repair.DroppedColumns, column.Column.Source.Name)
+		repair.AddedColumns = append(repair.AddedColumns, &protos.DeltaAddedColumn{
+			ColumnName: column.Column.Source.Name,
+			ColumnType: column.Column.Source.Type,
+		})
+	}
+	repair.DroppedColumns = append(repair.DroppedColumns, drift.Extra...)
+
+	if len(repair.AddedColumns) == 0 && len(repair.DroppedColumns) == 0 {
+		return nil, left
+	}
+	return repair, left
+}
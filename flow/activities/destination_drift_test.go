@@ -0,0 +1,85 @@
+package activities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func testTableDrift() (*protos.TableSchema, *utils.TableDrift) {
+	id := &protos.FieldDescription{Name: "id", Type: "int64"}
+	name := &protos.FieldDescription{Name: "name", Type: "string"}
+	age := &protos.FieldDescription{Name: "age", Type: "int32"}
+	schema := &protos.TableSchema{
+		TableIdentifier:   "public.users",
+		PrimaryKeyColumns: []string{"id"},
+		Columns:           []*protos.FieldDescription{id, name, age},
+	}
+	drift := &utils.TableDrift{
+		Missing: []utils.AdoptedColumn{
+			{Name: "name", Type: "text", Source: name},
+			{Name: "_peerdb_synced_at", Type: "timestamp"},
+		},
+		Mismatched: []utils.MismatchedColumn{
+			{Column: utils.AdoptedColumn{Name: "id", Type: "bigint", Source: id}, ExistingType: "text"},
+			{Column: utils.AdoptedColumn{Name: "age", Type: "integer", Source: age}, ExistingType: "text"},
+		},
+		Extra: []string{"notes"},
+	}
+	return schema, drift
+}
+
+func TestReconcileTableDriftRepair(t *testing.T) {
+	schema, drift := testTableDrift()
+	repair, unrepaired := reconcileTableDrift(
+		protos.DestinationDriftPolicy_DESTINATION_DRIFT_REPAIR, "public.users", "public.users_dst", schema, drift)
+
+	require.Equal(t, "public.users", repair.SrcTableName)
+	require.Equal(t, "public.users_dst", repair.DstTableName)
+	require.Equal(t, []*protos.DeltaAddedColumn{
+		{ColumnName: "name", ColumnType: "string"},
+		{ColumnName: "age", ColumnType: "int32"},
+	}, repair.AddedColumns, "dropped columns are added back, retyped ones are added back with their type")
+	require.Equal(t, []string{"age", "notes"}, repair.DroppedColumns, "retyped columns and columns users added are dropped")
+
+	// the synced at column and primary key columns aren't repaired
+	require.Equal(t, []utils.AdoptedColumn{drift.Missing[1]}, unrepaired.Missing)
+	require.Equal(t, []utils.MismatchedColumn{drift.Mismatched[0]}, unrepaired.Mismatched)
+	require.Empty(t, unrepaired.Extra)
+	require.Equal(t, "missing columns _peerdb_synced_at; column id is text instead of bigint", unrepaired.String())
+}
+
+func TestReconcileTableDriftRepairAll(t *testing.T) {
+	schema, drift := testTableDrift()
+	drift.Missing = drift.Missing[:1]
+	drift.Mismatched = drift.Mismatched[1:]
+	repair, unrepaired := reconcileTableDrift(
+		protos.DestinationDriftPolicy_DESTINATION_DRIFT_REPAIR, "public.users", "public.users", schema, drift)
+	require.NotNil(t, repair)
+	require.True(t, unrepaired.Empty(), "nothing is left to alert on")
+
+	repair, unrepaired = reconcileTableDrift(
+		protos.DestinationDriftPolicy_DESTINATION_DRIFT_REPAIR, "public.users", "public.users", schema, &utils.TableDrift{})
+	require.Nil(t, repair)
+	require.True(t, unrepaired.Empty())
+}
+
+func TestReconcileTableDriftAlertAndPause(t *testing.T) {
+	for _, policy := range []protos.DestinationDriftPolicy{
+		protos.DestinationDriftPolicy_DESTINATION_DRIFT_ALERT,
+		protos.DestinationDriftPolicy_DESTINATION_DRIFT_PAUSE,
+	} {
+		t.Run(policy.String(), func(t *testing.T) {
+			schema, drift := testTableDrift()
+			repair, unrepaired := reconcileTableDrift(policy, "public.users", "public.users", schema, drift)
+			require.Nil(t, repair, "tables are only changed under DESTINATION_DRIFT_REPAIR")
+			require.Equal(t, drift, unrepaired, "all of the drift is alerted on")
+			require.Equal(t,
+				"missing columns name, _peerdb_synced_at; column id is text instead of bigint, age is text instead of integer; "+
+					"extra columns notes", unrepaired.String())
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.temporal.io/sdk/activity"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PeerDB-io/peer-flow/connectors"
+	connmetadata "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// fanoutDestination is a fan-out destination of a CDC mirror, synced alongside the mirror's own destination
+type fanoutDestination struct {
+	config *protos.FlowConnectionConfigs
+	conn   connectors.CDCSyncConnector
+	// the last offset the destination synced, records up to it are left out of its stream
+	offset int64
+	// why the destination failed to sync this batch, failures of fan-out destinations don't fail the mirror's sync
+	err error
+	// the batch the destination synced
+	syncBatchID int64
+}
+
+// connectFanoutDestinations connects to the fan-out destinations of a CDC mirror, reads how far each has synced
+// and replays the schema changes each missed while failing. Destinations which fail to do so sit out the batch,
+// the slot is then read from its start as their offset is unknown.
+func (a *FlowableActivity) connectFanoutDestinations(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+) []*fanoutDestination {
+	var destinations []*fanoutDestination
+	for _, fanoutConfig := range model.FanoutConfigs(config) {
+		destination := &fanoutDestination{config: fanoutConfig}
+		destinations = append(destinations, destination)
+
+		conn, err := connectors.GetCDCSyncConnector(ctx, fanoutConfig.Destination)
+		if err != nil {
+			destination.err = fmt.Errorf("failed to get connector of fan-out destination %s: %w", fanoutConfig.Destination.Name, err)
+			continue
+		}
+		destination.conn = conn
+
+		offset, err := conn.GetLastOffset(ctx, fanoutConfig.FlowJobName)
+		if err != nil {
+			destination.err = fmt.Errorf("failed to get last offset of fan-out destination %s: %w", fanoutConfig.Destination.Name, err)
+			continue
+		}
+		destination.offset = offset
+
+		if err := a.replayMissedFanoutSchemaDeltas(ctx, destination); err != nil {
+			destination.err = err
+		}
+	}
+	return destinations
+}
+
+func closeFanoutDestinations(ctx context.Context, destinations []*fanoutDestination) {
+	for _, destination := range destinations {
+		if destination.conn != nil {
+			connectors.CloseConnector(ctx, destination.conn)
+		}
+	}
+}
+
+// fanoutPullOffset returns the offset to read the slot from so every destination gets the records it hasn't synced
+func fanoutPullOffset(lastOffset int64, destinations []*fanoutDestination) int64 {
+	pullOffset := lastOffset
+	for _, destination := range destinations {
+		if destination.err != nil {
+			return 0
+		}
+		pullOffset = min(pullOffset, destination.offset)
+	}
+	return pullOffset
+}
+
+// replayMissedFanoutSchemaDeltas replays the schema changes queued for a fan-out destination by batches it failed
+func (a *FlowableActivity) replayMissedFanoutSchemaDeltas(ctx context.Context, destination *fanoutDestination) error {
+	missed, err := catalog.ApprovedSchemaDeltas(ctx, a.CatalogPool, destination.config.FlowJobName)
+	if err != nil || len(missed) == 0 {
+		return err
+	}
+	ids := make([]int64, 0, len(missed))
+	deltas := make([]*protos.TableSchemaDelta, 0, len(missed))
+	for _, queued := range missed {
+		ids = append(ids, queued.ID)
+		deltas = append(deltas, queued.Delta)
+	}
+	if err := destination.conn.ReplayTableSchemaDeltas(ctx, destination.config.FlowJobName, deltas); err != nil {
+		return fmt.Errorf("failed to sync schema of fan-out destination %s: %w", destination.config.Destination.Name, err)
+	}
+	return catalog.MarkSchemaDeltasApplied(ctx, a.CatalogPool, ids)
+}
+
+// replayFanoutSchemaDeltas replays schema changes the sync doesn't replay itself on the fan-out destinations,
+// destinations failing to do so sit out the rest of the batch
+func replayFanoutSchemaDeltas(
+	ctx context.Context,
+	destinations []*fanoutDestination,
+	deltas []*protos.TableSchemaDelta,
+) {
+	if len(deltas) == 0 {
+		return
+	}
+	for _, destination := range destinations {
+		if destination.err != nil {
+			continue
+		}
+		if err := destination.conn.ReplayTableSchemaDeltas(ctx, destination.config.FlowJobName, deltas); err != nil {
+			destination.err = fmt.Errorf("failed to sync schema of fan-out destination %s: %w", destination.config.Destination.Name, err)
+		}
+	}
+}
+
+// finishFanoutDestinations alerts on the fan-out destinations which failed the batch and queues the batch's schema
+// changes for them to replay once they sync again, as the source doesn't send them again.
+// Returns the names of the destinations which failed.
+func (a *FlowableActivity) finishFanoutDestinations(
+	ctx context.Context,
+	flowName string,
+	destinations []*fanoutDestination,
+	deltas []*protos.TableSchemaDelta,
+) ([]string, error) {
+	var failed []string
+	for _, destination := range destinations {
+		if destination.err == nil {
+			continue
+		}
+		failed = append(failed, destination.config.Destination.Name)
+		activity.GetLogger(ctx).Warn("fan-out destination failed to sync, it catches up on later batches",
+			slog.String("destination", destination.config.Destination.Name), slog.Any("error", destination.err))
+		a.Alerter.LogFlowError(ctx, flowName, destination.err)
+		if err := catalog.QueueApprovedSchemaDeltas(ctx, a.CatalogPool, destination.config.FlowJobName, deltas); err != nil {
+			return nil, err
+		}
+	}
+	return failed, nil
+}
+
+// syncFanoutDestination syncs a fan-out destination's stream of a batch into its raw table, returning the batch id
+func (a *FlowableActivity) syncFanoutDestination(
+	ctx context.Context,
+	destination *fanoutDestination,
+	options *protos.SyncFlowOptions,
+	records *model.CDCRecordStream,
+	startTime time.Time,
+) (int64, error) {
+	flowName := destination.config.FlowJobName
+	syncBatchID, err := destination.conn.GetLastSyncBatchID(ctx, flowName)
+	if err != nil && destination.config.Destination.Type != protos.DBType_EVENTHUB {
+		return 0, err
+	}
+	syncBatchID += 1
+
+	if err := monitoring.AddCDCBatchForFlow(ctx, a.CatalogPool, flowName, monitoring.CDCBatchInfo{
+		BatchID:   syncBatchID,
+		StartTime: startTime,
+	}); err != nil {
+		return 0, err
+	}
+
+	res, err := destination.conn.SyncRecords(ctx, &model.SyncRecordsRequest{
+		SyncBatchID:      syncBatchID,
+		Records:          records,
+		FlowJobName:      flowName,
+		TableMappings:    options.TableMappings,
+		StagingPath:      destination.config.CdcStagingPath,
+		SnowflakeSession: destination.config.Snowflake.GetSync(),
+	})
+	if utils.IsDestinationMaintenanceError(err) {
+		return 0, utils.DestinationMaintenanceError(err)
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to push records to fan-out destination %s: %w", destination.config.Destination.Name, err)
+	}
+
+	lastCheckpoint, err := records.GetLastCheckpoint()
+	if err != nil {
+		return 0, err
+	}
+	if err := monitoring.UpdateNumRowsAndEndLSNForCDCBatch(
+		ctx, a.CatalogPool, flowName, res.CurrentSyncBatchID, uint32(res.NumRecordsSynced), lastCheckpoint,
+	); err != nil {
+		return 0, err
+	}
+	if err := monitoring.UpdateLatestLSNAtTargetForCDCFlow(ctx, a.CatalogPool, flowName, lastCheckpoint); err != nil {
+		return 0, err
+	}
+	return res.CurrentSyncBatchID, nil
+}
+
+// dropFanoutDestinations drops the raw tables and metadata a CDC mirror keeps on its fan-out destinations
+func (a *FlowableActivity) dropFanoutDestinations(ctx context.Context, flowJobName string) error {
+	var configBytes []byte
+	err := a.CatalogPool.QueryRow(ctx,
+		"SELECT config_proto FROM flows WHERE name = $1 AND query_string IS NULL", flowJobName).Scan(&configBytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get mirror config: %w", err)
+	}
+	var config protos.FlowConnectionConfigs
+	if err := proto.Unmarshal(configBytes, &config); err != nil {
+		return fmt.Errorf("failed to unmarshal mirror config: %w", err)
+	}
+
+	metadataStore := connmetadata.NewPostgresMetadataStoreFromCatalog(activity.GetLogger(ctx), a.CatalogPool)
+	for _, fanoutConfig := range model.FanoutConfigs(&config) {
+		conn, err := connectors.GetCDCSyncConnector(ctx, fanoutConfig.Destination)
+		if err != nil {
+			return fmt.Errorf("failed to get connector of fan-out destination %s: %w", fanoutConfig.Destination.Name, err)
+		}
+		err = conn.SyncFlowCleanup(ctx, fanoutConfig.FlowJobName)
+		connectors.CloseConnector(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := metadataStore.DropMetadata(ctx, fanoutConfig.FlowJobName); err != nil {
+			return err
+		}
+		if err := catalog.DeleteQueuedSchemaDeltas(ctx, a.CatalogPool, fanoutConfig.FlowJobName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
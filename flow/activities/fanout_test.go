@@ -0,0 +1,19 @@
+package activities
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanoutPullOffset(t *testing.T) {
+	require.Equal(t, int64(100), fanoutPullOffset(100, nil))
+
+	destinations := []*fanoutDestination{{offset: 120}, {offset: 80}}
+	require.Equal(t, int64(80), fanoutPullOffset(100, destinations), "the slot is read from the destination furthest behind")
+
+	// a destination which couldn't say how far it synced has the slot read from its start
+	destinations = append(destinations, &fanoutDestination{err: errors.New("destination unavailable")})
+	require.Equal(t, int64(0), fanoutPullOffset(100, destinations))
+}
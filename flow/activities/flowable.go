@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,22 +14,38 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"golang.org/x/exp/maps"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/PeerDB-io/peer-flow/connectors"
 	connbigquery "github.com/PeerDB-io/peer-flow/connectors/bigquery"
+	connmetadata "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
 	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
 	connsnowflake "github.com/PeerDB-io/peer-flow/connectors/snowflake"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/cdc_records"
 	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/model/rowfilter"
 	"github.com/PeerDB-io/peer-flow/peerdbenv"
 	"github.com/PeerDB-io/peer-flow/shared"
 	"github.com/PeerDB-io/peer-flow/shared/alerting"
+	"github.com/PeerDB-io/peer-flow/shared/datacatalog"
+	"github.com/PeerDB-io/peer-flow/shared/lineage"
+	"github.com/PeerDB-io/peer-flow/transform"
 )
 
+// DefaultSyncBatchSize is the number of records a sync flow pulls at most when its options don't set a batch size.
+const DefaultSyncBatchSize = 1_000_000
+
 // CheckConnectionResult is the result of a CheckConnection call.
 type CheckConnectionResult struct {
 	NeedsSetupMetadataTables bool
@@ -42,8 +60,14 @@ type SlotSnapshotSignal struct {
 type FlowableActivity struct {
 	CatalogPool *pgxpool.Pool
 	Alerter     *alerting.Alerter
+	Lineage     *lineage.Emitter
+	DataCatalog datacatalog.Publisher
 	CdcCacheRw  sync.RWMutex
 	CdcCache    map[string]connectors.CDCPullConnector
+	// restarts failed mirrors
+	TemporalClient client.Client
+	// data converter of TemporalClient, for reading workflow inputs from history, the default one if nil
+	DataConverter converter.DataConverter
 }
 
 func (a *FlowableActivity) CheckConnection(
@@ -122,10 +146,62 @@ func (a *FlowableActivity) CreateRawTable(
 	if err != nil {
 		return nil, err
 	}
+	if err := catalog.InitRawTableVersion(ctx, a.CatalogPool, config.FlowJobName, shared.RawTableVersion); err != nil {
+		return nil, err
+	}
 
 	return res, nil
 }
 
+// UpgradeRawTable brings the layout of a mirror's raw table up to the one this worker syncs and normalizes with.
+// Raw tables of mirrors created before versions were recorded are taken to be at the first version.
+func (a *FlowableActivity) UpgradeRawTable(ctx context.Context, config *protos.FlowConnectionConfigs) error {
+	logger := activity.GetLogger(ctx)
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	version, err := catalog.GetRawTableVersion(ctx, a.CatalogPool, config.FlowJobName)
+	if err != nil {
+		return err
+	}
+	version = max(version, 1)
+	if version >= shared.RawTableVersion {
+		return nil
+	}
+
+	dstConn, err := connectors.GetConnectorAs[connectors.RawTableUpgradeConnector](ctx, config.Destination)
+	if err != nil {
+		if !errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			return fmt.Errorf("failed to get connector: %w", err)
+		}
+		// only destinations which normalize have a raw table, there's nothing to upgrade for the others
+		normConn, normErr := connectors.GetConnectorAs[connectors.CDCNormalizeConnector](ctx, config.Destination)
+		if errors.Is(normErr, connectors.ErrUnsupportedFunctionality) {
+			return catalog.SetRawTableVersion(ctx, a.CatalogPool, config.FlowJobName, shared.RawTableVersion)
+		} else if normErr != nil {
+			return fmt.Errorf("failed to get connector: %w", normErr)
+		}
+		connectors.CloseConnector(ctx, normConn)
+
+		// the raw table keeps its version, syncing into it with the new layout would corrupt it
+		err := fmt.Errorf("destination can't upgrade raw table from version %d to %d", version, shared.RawTableVersion)
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return temporal.NewNonRetryableApplicationError(err.Error(), "rawTableUpgrade", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	logger.Info("upgrading raw table",
+		slog.Int("fromVersion", int(version)), slog.Int("toVersion", int(shared.RawTableVersion)))
+	if err := dstConn.UpgradeRawTable(ctx, config.FlowJobName, version); err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to upgrade raw table: %w", err)
+	}
+	if err := catalog.SetRawTableVersion(ctx, a.CatalogPool, config.FlowJobName, shared.RawTableVersion); err != nil {
+		return err
+	}
+	a.Alerter.LogFlowInfo(ctx, config.FlowJobName,
+		fmt.Sprintf("upgraded raw table from version %d to %d", version, shared.RawTableVersion))
+	return nil
+}
+
 // GetTableSchema returns the schema of a table.
 func (a *FlowableActivity) GetTableSchema(
 	ctx context.Context,
@@ -141,6 +217,42 @@ func (a *FlowableActivity) GetTableSchema(
 	return srcConn.GetTableSchema(ctx, config)
 }
 
+// TransformTableSchemas returns the schemas destination tables are created with, which are the columns the mirror's
+// transformation script declares for the tables it changes, and the schemas of tableNameSchemaMapping otherwise.
+func (a *FlowableActivity) TransformTableSchemas(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	tableNameSchemaMapping map[string]*protos.TableSchema,
+) (map[string]*protos.TableSchema, error) {
+	script, err := loadTransformScript(ctx, config.TransformScript, config.TableMappings)
+	if err != nil {
+		return nil, err
+	}
+	defer script.Close()
+
+	transformed := make(map[string]*protos.TableSchema, len(tableNameSchemaMapping))
+	for destination, tableSchema := range tableNameSchemaMapping {
+		transformed[destination], err = script.DestinationSchema(destination, tableSchema)
+		if err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "transformScript", err)
+		}
+	}
+	return transformed, nil
+}
+
+// loadTransformScript loads a mirror's transformation script, which can route records to any of its destination tables.
+func loadTransformScript(ctx context.Context, source string, tableMappings []*protos.TableMapping) (*transform.Script, error) {
+	destinations := make([]string, 0, len(tableMappings))
+	for _, v := range tableMappings {
+		destinations = append(destinations, v.DestinationTableIdentifier)
+	}
+	script, err := transform.Load(ctx, source, destinations)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "transformScript", err)
+	}
+	return script, nil
+}
+
 // CreateNormalizedTable creates normalized tables in destination.
 func (a *FlowableActivity) CreateNormalizedTable(
 	ctx context.Context,
@@ -177,10 +289,9 @@ func (a *FlowableActivity) CreateNormalizedTable(
 		existing, err := conn.SetupNormalizedTable(
 			ctx,
 			tx,
+			config,
 			tableIdentifier,
 			tableSchema,
-			config.SoftDeleteColName,
-			config.SyncedAtColName,
 		)
 		if err != nil {
 			a.Alerter.LogFlowError(ctx, config.FlowName, err)
@@ -200,6 +311,54 @@ func (a *FlowableActivity) CreateNormalizedTable(
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit normalized tables tx: %w", err)
 	}
+	a.publishNormalizedTables(ctx, config)
+
+	return &protos.SetupNormalizedTableBatchOutput{
+		TableExistsMapping: tableExistsMapping,
+	}, nil
+}
+
+// AdoptNormalizedTables checks that normalized tables which already exist in destination
+// have the columns the mirror writes to, instead of creating them.
+func (a *FlowableActivity) AdoptNormalizedTables(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+) (*protos.SetupNormalizedTableBatchOutput, error) {
+	logger := activity.GetLogger(ctx)
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowName)
+	conn, err := connectors.GetConnectorAs[connectors.TableAdoptionConnector](ctx, config.PeerConnectionConfig)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		return nil, temporal.NewNonRetryableApplicationError("destination doesn't support adopting existing tables",
+			"notAdoptable", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, conn)
+
+	tableIdentifiers := maps.Keys(config.TableNameSchemaMapping)
+	slices.Sort(tableIdentifiers)
+	numTablesChecked := atomic.Uint32{}
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return fmt.Sprintf("checking existing normalized tables - %d of %d done",
+			numTablesChecked.Load(), len(tableIdentifiers))
+	})
+	defer shutdown()
+
+	tableExistsMapping := make(map[string]bool, len(tableIdentifiers))
+	for _, tableIdentifier := range tableIdentifiers {
+		if err := conn.CheckAdoptedTable(ctx, config, tableIdentifier,
+			config.TableNameSchemaMapping[tableIdentifier]); err != nil {
+			a.Alerter.LogFlowError(ctx, config.FlowName, err)
+			if errors.Is(err, utils.ErrNotAdoptable) {
+				return nil, temporal.NewNonRetryableApplicationError(err.Error(), "notAdoptable", err)
+			}
+			return nil, fmt.Errorf("failed to check normalized table %s: %w", tableIdentifier, err)
+		}
+		tableExistsMapping[tableIdentifier] = true
+		numTablesChecked.Add(1)
+		logger.Info("adopting existing table " + tableIdentifier)
+	}
+	a.publishNormalizedTables(ctx, config)
 
 	return &protos.SetupNormalizedTableBatchOutput{
 		TableExistsMapping: tableExistsMapping,
@@ -277,16 +436,48 @@ func (a *FlowableActivity) SyncFlow(
 	logger := activity.GetLogger(ctx)
 	activity.RecordHeartbeat(ctx, "starting flow...")
 	dstConn, err := connectors.GetCDCSyncConnector(ctx, config.Destination)
-	if err != nil {
+	if utils.IsDestinationMaintenanceError(err) {
+		return nil, utils.DestinationMaintenanceError(err)
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to get destination connector: %w", err)
 	}
 	defer connectors.CloseConnector(ctx, dstConn)
 
+	var script *transform.Script
+	var recordTransform model.RecordTransform
+	if config.TransformScript != "" {
+		script, err = loadTransformScript(ctx, config.TransformScript, options.TableMappings)
+		if err != nil {
+			return nil, err
+		}
+		defer script.Close()
+		recordTransform = script.Transform
+	}
+
 	logger.Info("pulling records...")
 	tblNameMapping := make(map[string]model.NameAndExclude, len(options.TableMappings))
 	for _, v := range options.TableMappings {
-		tblNameMapping[v.SourceTableIdentifier] = model.NewNameAndExclude(v.DestinationTableIdentifier, v.Exclude)
+		nameAndExclude := model.NewNameAndExclude(v.DestinationTableIdentifier, v.Exclude, v.Include)
+		nameAndExclude.Masks = model.NewColumnMasks(v.Masks)
+		nameAndExclude.DeclaredColumns = script != nil && script.DeclaresOutput(v.DestinationTableIdentifier)
+		if v.RowFilter != "" {
+			nameAndExclude.RowFilter, err = rowfilter.Parse(v.RowFilter)
+			if err != nil {
+				return nil, temporal.NewNonRetryableApplicationError(
+					fmt.Sprintf("invalid row filter of table %s: %v", v.SourceTableIdentifier, err), "rowFilter", err)
+			}
+		}
+		tblNameMapping[v.SourceTableIdentifier] = nameAndExclude
+	}
+
+	// fan-out destinations sync the same records, so geometries are only converted when none has geospatial types
+	geoFormat := config.GeoFormat
+	for _, destination := range config.FanoutDestinations {
+		if !model.GeoFormatApplies(destination.Type) {
+			geoFormat = protos.GeoFormat_GEO_FORMAT_WKT
+		}
 	}
+	recordTransform = model.GeoFormatTransform(config.Destination.Type, geoFormat, recordTransform)
 
 	srcConn, err := a.waitForCdcCache(ctx, sessionID)
 	if err != nil {
@@ -303,19 +494,57 @@ func (a *FlowableActivity) SyncFlow(
 
 	batchSize := options.BatchSize
 	if batchSize <= 0 {
-		batchSize = 1_000_000
+		batchSize = DefaultSyncBatchSize
+	}
+	batchBytes := options.BatchBytes
+	if batchBytes == 0 {
+		batchBytes = peerdbenv.PeerDBCDCMaxBatchBytes()
 	}
 
 	lastOffset, err := dstConn.GetLastOffset(ctx, config.FlowJobName)
-	if err != nil {
+	if utils.IsDestinationMaintenanceError(err) {
+		return nil, utils.DestinationMaintenanceError(err)
+	} else if err != nil {
 		return nil, err
 	}
 
+	// the slot is read from the destination furthest behind, the others skip the records they already synced
+	fanout := a.connectFanoutDestinations(ctx, config)
+	defer closeFanoutDestinations(ctx, fanout)
+	pullOffset := fanoutPullOffset(lastOffset, fanout)
+
 	// start a goroutine to pull records from the source
+	errGroup, errCtx := errgroup.WithContext(ctx)
 	recordBatch := model.NewCDCRecordStream()
+	if peerdbenv.PeerDBCDCStreamSpillToDisk() {
+		spillQueue, err := cdc_records.NewSpillQueue()
+		if err != nil {
+			return nil, err
+		}
+		// the stream closes the queue once drained, this covers pulls failing before that
+		defer spillQueue.Close()
+		// spilled records are dropped once pulling or syncing fails, leaving the batch to be pulled again
+		recordBatch = model.NewSpillingCDCRecordStream(errCtx, spillQueue)
+	}
+	recordBatch.SetSchemaChangePolicy(config.SchemaChangePolicy)
+	if config.PauseOnIncompatibleSchemaChanges {
+		recordBatch.PauseOnIncompatibleSchemaChanges(options.TableNameSchemaMapping)
+	}
 	startTime := time.Now()
 
-	errGroup, errCtx := errgroup.WithContext(ctx)
+	var approvedSchemaDeltas []catalog.QueuedSchemaDelta
+	// incompatible schema deltas approved with SCHEMA_CHANGE_RESOLUTION_APPLY are replayed with the approved ones
+	if config.SchemaChangesRequireApproval || config.PauseOnIncompatibleSchemaChanges {
+		approvedSchemaDeltas, err = a.replayApprovedSchemaDeltas(ctx, dstConn, fanout, flowName)
+		if err != nil {
+			a.Alerter.LogFlowError(ctx, flowName, err)
+			return nil, err
+		}
+	}
+	if config.SchemaChangesRequireApproval {
+		recordBatch.HoldSchemaDeltas()
+	}
+
 	errGroup.Go(func() error {
 		if options.RelationMessageMapping == nil {
 			options.RelationMessageMapping = make(map[uint32]*protos.RelationMessage)
@@ -325,16 +554,19 @@ func (a *FlowableActivity) SyncFlow(
 			FlowJobName:           flowName,
 			SrcTableIDNameMapping: options.SrcTableIdNameMapping,
 			TableNameMapping:      tblNameMapping,
-			LastOffset:            lastOffset,
+			LastOffset:            pullOffset,
 			MaxBatchSize:          batchSize,
+			MaxBatchBytes:         batchBytes,
 			IdleTimeout: peerdbenv.PeerDBCDCIdleTimeoutSeconds(
 				int(options.IdleTimeoutSeconds),
 			),
 			TableNameSchemaMapping:      options.TableNameSchemaMapping,
 			OverridePublicationName:     config.PublicationName,
 			OverrideReplicationSlotName: config.ReplicationSlotName,
+			PublicationColumnLists:      config.PublicationColumnLists,
 			RelationMessageMapping:      options.RelationMessageMapping,
 			RecordStream:                recordBatch,
+			Transform:                   recordTransform,
 		})
 	})
 
@@ -354,14 +586,71 @@ func (a *FlowableActivity) SyncFlow(
 		if err != nil {
 			return nil, fmt.Errorf("failed to sync schema: %w", err)
 		}
+		replayFanoutSchemaDeltas(ctx, fanout, recordBatch.SchemaDeltas)
+		appliedSchemaDeltas, err := a.finishSchemaDeltaApprovals(ctx, flowName, recordBatch, approvedSchemaDeltas)
+		if err != nil {
+			return nil, err
+		}
+		failedFanout, err := a.finishFanoutDestinations(ctx, flowName, fanout,
+			slices.Concat(queuedSchemaDeltas(approvedSchemaDeltas), recordBatch.SchemaDeltas))
+		if err != nil {
+			return nil, err
+		}
+
+		a.alertPausingSchemaDeltas(ctx, flowName, recordBatch.PausingSchemaDeltas)
+		if err := a.recordIncompatibleSchemaDeltas(ctx, flowName, recordBatch.IncompatibleSchemaDeltas); err != nil {
+			return nil, err
+		}
 
 		return &model.SyncResponse{
-			CurrentSyncBatchID:     -1,
-			TableSchemaDeltas:      recordBatch.SchemaDeltas,
-			RelationMessageMapping: options.RelationMessageMapping,
+			CurrentSyncBatchID:       -1,
+			TableSchemaDeltas:        append(recordBatch.SchemaDeltas, appliedSchemaDeltas...),
+			PausingSchemaDeltas:      recordBatch.PausingSchemaDeltas,
+			IncompatibleSchemaDeltas: recordBatch.IncompatibleSchemaDeltas,
+			RelationMessageMapping:   options.RelationMessageMapping,
+			SourceLagMB:              a.sourceLagMB(ctx, srcConn, config),
+			FailedFanoutDestinations: failedFanout,
 		}, nil
 	}
 
+	// with fan-out destinations, each destination syncs its own copy of the stream. They sync outside of errGroup,
+	// a fan-out destination failing stops its own copy and sits out the batch, the others carry on.
+	syncRecords := recordBatch
+	var fanoutGroup sync.WaitGroup
+	if len(fanout) != 0 {
+		offsets := make([]int64, 0, len(fanout)+1)
+		offsets = append(offsets, lastOffset)
+		streamCtxs := make([]context.Context, 0, len(fanout)+1)
+		streamCtxs = append(streamCtxs, errCtx)
+		cancelStreams := make([]context.CancelFunc, 0, len(fanout))
+		for _, destination := range fanout {
+			offsets = append(offsets, destination.offset)
+			fanoutCtx, cancelFanout := context.WithCancel(errCtx)
+			defer cancelFanout()
+			if destination.err != nil {
+				cancelFanout()
+			}
+			streamCtxs = append(streamCtxs, fanoutCtx)
+			cancelStreams = append(cancelStreams, cancelFanout)
+		}
+		streams := recordBatch.Fanout(streamCtxs, offsets)
+		syncRecords = streams[0]
+		for i, destination := range fanout {
+			if destination.err != nil {
+				continue
+			}
+			fanoutGroup.Add(1)
+			go func() {
+				defer fanoutGroup.Done()
+				destination.syncBatchID, destination.err = a.syncFanoutDestination(
+					streamCtxs[i+1], destination, options, streams[i+1], startTime)
+				if destination.err != nil {
+					cancelStreams[i]()
+				}
+			}()
+		}
+	}
+
 	var syncStartTime time.Time
 	var res *model.SyncResponse
 	errGroup.Go(func() error {
@@ -385,13 +674,18 @@ func (a *FlowableActivity) SyncFlow(
 
 		syncStartTime = time.Now()
 		res, err = dstConn.SyncRecords(errCtx, &model.SyncRecordsRequest{
-			SyncBatchID:   syncBatchID,
-			Records:       recordBatch,
-			FlowJobName:   flowName,
-			TableMappings: options.TableMappings,
-			StagingPath:   config.CdcStagingPath,
+			SyncBatchID:      syncBatchID,
+			Records:          syncRecords,
+			FlowJobName:      flowName,
+			TableMappings:    options.TableMappings,
+			StagingPath:      config.CdcStagingPath,
+			SnowflakeSession: config.Snowflake.GetSync(),
 		})
-		if err != nil {
+		if utils.IsDestinationMaintenanceError(err) {
+			// not alerted on, the workflow backs off until the destination is writable again
+			logger.Warn("destination is read-only or in maintenance", slog.Any("error", err))
+			return utils.DestinationMaintenanceError(err)
+		} else if err != nil {
 			logger.Warn("failed to push records", slog.Any("error", err))
 			a.Alerter.LogFlowError(ctx, flowName, err)
 			return fmt.Errorf("failed to push records: %w", err)
@@ -402,17 +696,49 @@ func (a *FlowableActivity) SyncFlow(
 	})
 
 	err = errGroup.Wait()
-	if err != nil {
+	fanoutGroup.Wait()
+	if utils.IsDestinationMaintenanceFailure(err) {
+		return nil, err
+	} else if err != nil {
 		a.Alerter.LogFlowError(ctx, flowName, err)
 		return nil, fmt.Errorf("failed to pull records: %w", err)
 	}
+	appliedSchemaDeltas, err := a.finishSchemaDeltaApprovals(ctx, flowName, recordBatch, approvedSchemaDeltas)
+	if err != nil {
+		return nil, err
+	}
+	res.FailedFanoutDestinations, err = a.finishFanoutDestinations(ctx, flowName, fanout,
+		slices.Concat(queuedSchemaDeltas(approvedSchemaDeltas), recordBatch.SchemaDeltas))
+	if err != nil {
+		return nil, err
+	}
+	res.TableSchemaDeltas = append(res.TableSchemaDeltas, appliedSchemaDeltas...)
+	res.PausingSchemaDeltas = recordBatch.PausingSchemaDeltas
+	a.alertPausingSchemaDeltas(ctx, flowName, recordBatch.PausingSchemaDeltas)
+	if err := a.recordIncompatibleSchemaDeltas(ctx, flowName, recordBatch.IncompatibleSchemaDeltas); err != nil {
+		return nil, err
+	}
+	res.IncompatibleSchemaDeltas = recordBatch.IncompatibleSchemaDeltas
+	res.SourceLagMB = a.sourceLagMB(ctx, srcConn, config)
+	if len(fanout) != 0 {
+		res.FanoutSyncBatchIDs = make(map[string]int64, len(fanout))
+		for _, destination := range fanout {
+			if destination.err == nil {
+				res.FanoutSyncBatchIDs[destination.config.FlowJobName] = destination.syncBatchID
+			}
+		}
+	}
 
 	numRecords := res.NumRecordsSynced
 	syncDuration := time.Since(syncStartTime)
 
 	logger.Info(fmt.Sprintf("pushed %d records in %d seconds", numRecords, int(syncDuration.Seconds())))
 
-	lastCheckpoint := recordBatch.GetLastCheckpoint()
+	lastCheckpoint, err := recordBatch.GetLastCheckpoint()
+	if err != nil {
+		a.Alerter.LogFlowError(ctx, flowName, err)
+		return nil, err
+	}
 
 	err = monitoring.UpdateNumRowsAndEndLSNForCDCBatch(
 		ctx,
@@ -451,6 +777,134 @@ func (a *FlowableActivity) SyncFlow(
 	return res, nil
 }
 
+// sourceLagMB measures how far the replication slot of the mirror trails the source, for catch-up mode.
+// Returns -1 when catch-up mode is disabled or the lag could not be measured.
+func (a *FlowableActivity) sourceLagMB(
+	ctx context.Context,
+	srcConn connectors.CDCPullConnector,
+	config *protos.FlowConnectionConfigs,
+) float32 {
+	if dynamicconf.PeerDBCDCCatchUpLagThresholdMB(ctx) == 0 {
+		return -1
+	}
+
+	slotName := "peerflow_slot_" + config.FlowJobName
+	if config.ReplicationSlotName != "" {
+		slotName = config.ReplicationSlotName
+	}
+	slotInfo, err := srcConn.GetSlotInfo(ctx, slotName)
+	if err != nil || len(slotInfo) == 0 {
+		activity.GetLogger(ctx).Warn("failed to measure replication lag", slog.String("slot", slotName), slog.Any("error", err))
+		return -1
+	}
+	return slotInfo[0].LagInMb
+}
+
+// replayApprovedSchemaDeltas replays the schema deltas an operator approved since the last sync.
+// They are only marked as applied once the sync succeeds, replaying them again on retry is harmless.
+func (a *FlowableActivity) replayApprovedSchemaDeltas(
+	ctx context.Context,
+	dstConn connectors.CDCSyncConnector,
+	fanout []*fanoutDestination,
+	flowName string,
+) ([]catalog.QueuedSchemaDelta, error) {
+	approved, err := catalog.ApprovedSchemaDeltas(ctx, a.CatalogPool, flowName)
+	if err != nil || len(approved) == 0 {
+		return nil, err
+	}
+
+	deltas := queuedSchemaDeltas(approved)
+	if err := dstConn.ReplayTableSchemaDeltas(ctx, flowName, deltas); err != nil {
+		return nil, fmt.Errorf("failed to replay approved schema deltas: %w", err)
+	}
+	replayFanoutSchemaDeltas(ctx, fanout, deltas)
+	return approved, nil
+}
+
+func queuedSchemaDeltas(queued []catalog.QueuedSchemaDelta) []*protos.TableSchemaDelta {
+	deltas := make([]*protos.TableSchemaDelta, 0, len(queued))
+	for _, delta := range queued {
+		deltas = append(deltas, delta.Delta)
+	}
+	return deltas
+}
+
+// finishSchemaDeltaApprovals queues the deltas held back during this sync and marks the approved ones as applied,
+// returning the latter so the workflow picks up their columns.
+func (a *FlowableActivity) finishSchemaDeltaApprovals(
+	ctx context.Context,
+	flowName string,
+	recordBatch *model.CDCRecordStream,
+	approved []catalog.QueuedSchemaDelta,
+) ([]*protos.TableSchemaDelta, error) {
+	if len(recordBatch.HeldSchemaDeltas) != 0 {
+		if err := catalog.QueueSchemaDeltas(ctx, a.CatalogPool, flowName, recordBatch.HeldSchemaDeltas); err != nil {
+			a.Alerter.LogFlowError(ctx, flowName, err)
+			return nil, err
+		}
+		a.Alerter.LogFlowInfo(ctx, flowName,
+			fmt.Sprintf("%d schema changes are waiting for approval", len(recordBatch.HeldSchemaDeltas)))
+	}
+
+	if len(approved) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, 0, len(approved))
+	deltas := make([]*protos.TableSchemaDelta, 0, len(approved))
+	for _, queued := range approved {
+		ids = append(ids, queued.ID)
+		deltas = append(deltas, queued.Delta)
+	}
+	if err := catalog.MarkSchemaDeltasApplied(ctx, a.CatalogPool, ids); err != nil {
+		return nil, err
+	}
+	activity.GetLogger(ctx).Info("applied approved schema deltas", slog.Int("count", len(deltas)))
+	return deltas, nil
+}
+
+// alertPausingSchemaDeltas alerts on schema changes the mirror pauses on, which are left to be made on the destination.
+func (a *FlowableActivity) alertPausingSchemaDeltas(ctx context.Context, flowName string, deltas []*protos.TableSchemaDelta) {
+	if len(deltas) == 0 {
+		return
+	}
+	changes := make([]string, 0, len(deltas))
+	for _, delta := range deltas {
+		changes = append(changes, fmt.Sprintf("%s (dropped %v, widened %v, renamed %v)",
+			delta.SrcTableName, delta.DroppedColumns, delta.AlteredColumns, delta.RenamedColumns))
+	}
+	a.Alerter.LogFlowError(ctx, flowName, fmt.Errorf("mirror paused on schema changes: %s", strings.Join(changes, ", ")))
+	a.Alerter.AlertSchemaChangePaused(ctx, flowName, strings.Join(changes, ", "))
+}
+
+// recordIncompatibleSchemaDeltas records schema changes destinations can't follow for approval and alerts on them,
+// the mirror pauses until ApproveSchemaChange approves them.
+func (a *FlowableActivity) recordIncompatibleSchemaDeltas(
+	ctx context.Context,
+	flowName string,
+	deltas []*protos.TableSchemaDelta,
+) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	if err := catalog.QueueIncompatibleSchemaDeltas(ctx, a.CatalogPool, flowName, deltas); err != nil {
+		a.Alerter.LogFlowError(ctx, flowName, err)
+		return err
+	}
+	changes := make([]string, 0, len(deltas))
+	for _, delta := range deltas {
+		narrowed := make([]string, 0, len(delta.NarrowedColumns))
+		for _, column := range delta.NarrowedColumns {
+			narrowed = append(narrowed, fmt.Sprintf("%s %s to %s", column.ColumnName, column.OldType, column.NewType))
+		}
+		changes = append(changes, fmt.Sprintf("%s (dropped primary key columns %v, narrowed %v)",
+			delta.SrcTableName, delta.DroppedColumns, narrowed))
+	}
+	a.Alerter.LogFlowError(ctx, flowName,
+		fmt.Errorf("mirror paused on incompatible schema changes: %s", strings.Join(changes, ", ")))
+	a.Alerter.AlertSchemaChangeApprovalPending(ctx, flowName, strings.Join(changes, ", "))
+	return nil
+}
+
 func (a *FlowableActivity) StartNormalize(
 	ctx context.Context,
 	input *protos.StartNormalizeInput,
@@ -474,18 +928,33 @@ func (a *FlowableActivity) StartNormalize(
 	})
 	defer shutdown()
 
-	res, err := dstConn.NormalizeRecords(ctx, &model.NormalizeRecordsRequest{
-		FlowJobName:            input.FlowConnectionConfigs.FlowJobName,
-		SyncBatchID:            input.SyncBatchID,
-		SoftDelete:             input.FlowConnectionConfigs.SoftDelete,
-		SoftDeleteColName:      input.FlowConnectionConfigs.SoftDeleteColName,
-		SyncedAtColName:        input.FlowConnectionConfigs.SyncedAtColName,
-		TableNameSchemaMapping: input.TableNameSchemaMapping,
+	a.emitNormalizeLineage(ctx, lineage.EventTypeStart, input)
+	var res *model.NormalizeResponse
+	// normalize waits out maintenance windows itself, failing would only have the workflow retry it right away
+	err = utils.RetryDuringMaintenance(ctx, func(attempt int, backoff time.Duration, err error) {
+		logger.Warn("destination is read-only or in maintenance, retrying normalize",
+			slog.Int("attempt", attempt), slog.Duration("backoff", backoff), slog.Any("error", err))
+		activity.RecordHeartbeat(ctx, fmt.Sprintf("destination in maintenance, retrying normalize in %s", backoff))
+	}, func() error {
+		var err error
+		res, err = dstConn.NormalizeRecords(ctx, &model.NormalizeRecordsRequest{
+			FlowJobName:            input.FlowConnectionConfigs.FlowJobName,
+			SyncBatchID:            input.SyncBatchID,
+			SoftDelete:             input.FlowConnectionConfigs.SoftDelete,
+			SoftDeleteColName:      input.FlowConnectionConfigs.SoftDeleteColName,
+			SyncedAtColName:        input.FlowConnectionConfigs.SyncedAtColName,
+			TableNameSchemaMapping: input.TableNameSchemaMapping,
+			SnowflakeSession:       input.FlowConnectionConfigs.Snowflake.GetNormalize(),
+			NumericOverflowPolicy:  input.FlowConnectionConfigs.NumericOverflowPolicy,
+		})
+		return err
 	})
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, input.FlowConnectionConfigs.FlowJobName, err)
+		a.emitNormalizeLineage(ctx, lineage.EventTypeFail, input)
 		return nil, fmt.Errorf("failed to normalized records: %w", err)
 	}
+	a.emitNormalizeLineage(ctx, lineage.EventTypeComplete, input)
 
 	// normalize flow did not run due to no records, no need to update end time.
 	if res.Done {
@@ -498,6 +967,11 @@ func (a *FlowableActivity) StartNormalize(
 		if err != nil {
 			return nil, err
 		}
+		a.publishTableFreshness(ctx, input.FlowConnectionConfigs, time.Now())
+		if err := catalog.RecordNormalizedBatch(ctx, a.CatalogPool, input.FlowConnectionConfigs.FlowJobName,
+			res.EndBatchID); err != nil {
+			return nil, err
+		}
 	}
 
 	// log the number of batches normalized
@@ -507,6 +981,89 @@ func (a *FlowableActivity) StartNormalize(
 	return res, nil
 }
 
+// emitNormalizeLineage reports a normalize batch as a run reading the source tables of the mirror
+// and writing their destination tables, with column lineage from the tables' schemas.
+func (a *FlowableActivity) emitNormalizeLineage(ctx context.Context, eventType lineage.EventType,
+	input *protos.StartNormalizeInput,
+) {
+	if !a.Lineage.Enabled() {
+		return
+	}
+
+	cfg := input.FlowConnectionConfigs
+	event := a.Lineage.NewRunEvent(eventType, cfg.FlowJobName, lineage.BatchRunID(cfg.FlowJobName, input.SyncBatchID))
+	for _, tm := range cfg.TableMappings {
+		src, dst := lineage.TableDatasets(cfg.Source, cfg.Destination, tm.SourceTableIdentifier,
+			tm.DestinationTableIdentifier, input.TableNameSchemaMapping[tm.DestinationTableIdentifier])
+		event.Inputs = append(event.Inputs, src)
+		event.Outputs = append(event.Outputs, dst)
+	}
+	a.Lineage.Emit(ctx, event)
+}
+
+// publishNormalizedTables registers freshly set up tables in the data catalog,
+// failures are only logged as the catalog is not worth failing a mirror over.
+func (a *FlowableActivity) publishNormalizedTables(ctx context.Context, config *protos.SetupNormalizedTableBatchInput) {
+	if a.DataCatalog == nil {
+		return
+	}
+
+	sourceTables := make(map[string]string, len(config.TableMappings))
+	for _, tm := range config.TableMappings {
+		sourceTables[tm.DestinationTableIdentifier] = tm.SourceTableIdentifier
+	}
+	for tableIdentifier, tableSchema := range config.TableNameSchemaMapping {
+		sourceTable, ok := sourceTables[tableIdentifier]
+		if !ok {
+			sourceTable = tableSchema.TableIdentifier
+		}
+		if err := a.DataCatalog.PublishTable(ctx, &datacatalog.Table{
+			FlowJobName:      config.FlowName,
+			Source:           config.SourcePeer,
+			SourceTable:      sourceTable,
+			Destination:      config.PeerConnectionConfig,
+			DestinationTable: tableIdentifier,
+			Schema:           tableSchema,
+		}); err != nil {
+			activity.GetLogger(ctx).Warn("failed to publish table to data catalog",
+				slog.String("table", tableIdentifier), slog.Any("error", err))
+		}
+	}
+}
+
+func (a *FlowableActivity) publishTableFreshness(ctx context.Context, cfg *protos.FlowConnectionConfigs, updatedAt time.Time) {
+	if a.DataCatalog == nil {
+		return
+	}
+
+	for _, tm := range cfg.TableMappings {
+		if err := a.DataCatalog.PublishFreshness(ctx, &datacatalog.Table{
+			FlowJobName:      cfg.FlowJobName,
+			Source:           cfg.Source,
+			SourceTable:      tm.SourceTableIdentifier,
+			Destination:      cfg.Destination,
+			DestinationTable: tm.DestinationTableIdentifier,
+		}, updatedAt); err != nil {
+			activity.GetLogger(ctx).Warn("failed to publish table freshness to data catalog",
+				slog.String("table", tm.DestinationTableIdentifier), slog.Any("error", err))
+		}
+	}
+}
+
+// emitQRepLineage reports a QRep run, columns are left out as they are only known once a query's results come in.
+func (a *FlowableActivity) emitQRepLineage(ctx context.Context, eventType lineage.EventType,
+	config *protos.QRepConfig, runUUID string,
+) {
+	if !a.Lineage.Enabled() {
+		return
+	}
+
+	event := a.Lineage.NewRunEvent(eventType, config.FlowJobName, runUUID)
+	event.Inputs = []lineage.Dataset{lineage.NewDataset(config.SourcePeer, config.WatermarkTable)}
+	event.Outputs = []lineage.Dataset{lineage.NewDataset(config.DestinationPeer, config.DestinationTableIdentifier)}
+	a.Lineage.Emit(ctx, event)
+}
+
 // SetupQRepMetadataTables sets up the metadata tables for QReplication.
 func (a *FlowableActivity) SetupQRepMetadataTables(ctx context.Context, config *protos.QRepConfig) error {
 	conn, err := connectors.GetQRepSyncConnector(ctx, config.DestinationPeer)
@@ -547,6 +1104,7 @@ func (a *FlowableActivity) GetQRepPartitions(ctx context.Context,
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
 		return nil, fmt.Errorf("failed to get partitions from source: %w", err)
 	}
+	var estimatedRows int64
 	if len(partitions) > 0 {
 		err = monitoring.InitializeQRepRun(
 			ctx,
@@ -558,25 +1116,58 @@ func (a *FlowableActivity) GetQRepPartitions(ctx context.Context,
 		if err != nil {
 			return nil, err
 		}
+		// runs after the first only copy the rows added since, whose count isn't known upfront
+		if last == nil || last.Range == nil {
+			estimatedRows = a.estimateQRepRows(ctx, config)
+			if err := monitoring.UpdateEstimatedRowsForQRepRun(
+				ctx, a.CatalogPool, config.FlowJobName, runUUID, estimatedRows,
+			); err != nil {
+				return nil, err
+			}
+		}
+		a.emitQRepLineage(ctx, lineage.EventTypeStart, config, runUUID)
 	}
 
 	return &protos.QRepParitionResult{
-		Partitions: partitions,
+		Partitions:    partitions,
+		EstimatedRows: estimatedRows,
 	}, nil
 }
 
-// ReplicateQRepPartitions spawns multiple ReplicateQRepPartition
+// estimateQRepRows estimates the rows an initial copy copies for its progress, 0 if the source can't estimate them
+func (a *FlowableActivity) estimateQRepRows(ctx context.Context, config *protos.QRepConfig) int64 {
+	logger := activity.GetLogger(ctx)
+	conn, err := connectors.GetConnectorAs[connectors.QRepRowEstimateConnector](ctx, config.SourcePeer)
+	if err != nil {
+		if !errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			logger.Warn("failed to get connector to estimate rows", slog.Any("error", err))
+		}
+		return 0
+	}
+	defer connectors.CloseConnector(ctx, conn)
+
+	rows, err := conn.EstimateQRepRows(ctx, config)
+	if err != nil {
+		// progress is still reported by partitions
+		logger.Warn("failed to estimate rows of initial copy", slog.Any("error", err))
+		return 0
+	}
+	return rows
+}
+
+// ReplicateQRepPartitions spawns multiple ReplicateQRepPartition, returning the rows synced by the partitions
+// it replicated, partitions replicated before a retry aren't counted again
 func (a *FlowableActivity) ReplicateQRepPartitions(ctx context.Context,
 	config *protos.QRepConfig,
 	partitions *protos.QRepPartitionBatch,
 	runUUID string,
-) error {
+) (int64, error) {
 	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
 	logger := activity.GetLogger(ctx)
 
 	err := monitoring.UpdateStartTimeForQRepRun(ctx, a.CatalogPool, runUUID)
 	if err != nil {
-		return fmt.Errorf("failed to update start time for qrep run: %w", err)
+		return 0, fmt.Errorf("failed to update start time for qrep run: %w", err)
 	}
 
 	numPartitions := len(partitions.Partitions)
@@ -584,33 +1175,96 @@ func (a *FlowableActivity) ReplicateQRepPartitions(ctx context.Context,
 	logger.Info(fmt.Sprintf("replicating partitions for batch %d - size: %d",
 		partitions.BatchId, numPartitions),
 	)
-	for i, p := range partitions.Partitions {
-		logger.Info(fmt.Sprintf("batch-%d - replicating partition - %s", partitions.BatchId, p.PartitionId))
-		err := a.replicateQRepPartition(ctx, config, i+1, numPartitions, p, runUUID)
-		if err != nil {
-			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-			return err
+
+	throttleConn, err := connectors.GetConnectorAs[connectors.QRepThrottleConnector](ctx, config.SourcePeer)
+	if err != nil {
+		if !errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			return 0, fmt.Errorf("failed to get qrep source connector: %w", err)
 		}
+	} else {
+		defer connectors.CloseConnector(ctx, throttleConn)
 	}
 
-	return nil
+	maxParallelism := int(config.MaxParallelWorkers)
+	if maxParallelism <= 0 {
+		maxParallelism = shared.DefaultQRepMaxParallelWorkers
+	}
+	// shared by the partitions of the batch, which are replicated one after another
+	throttle := utils.NewQRepThrottle(config, maxParallelism)
+	var checkpoints qrepCheckpoints = connmetadata.NewPostgresMetadataStoreFromCatalog(logger, a.CatalogPool)
+	dstConn, err := connectors.GetConnectorAs[connectors.QRepCheckpointConnector](ctx, config.DestinationPeer)
+	if err != nil {
+		if !errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			return 0, fmt.Errorf("failed to get qrep destination connector: %w", err)
+		}
+	} else {
+		defer connectors.CloseConnector(ctx, dstConn)
+		checkpoints = &destinationCheckpoints{qrepCheckpoints: checkpoints, destination: dstConn, config: config}
+	}
+
+	return replicateUncheckpointedPartitions(ctx, logger, checkpoints, config.FlowJobName, partitions,
+		func(i int, p *protos.QRepPartition) (int, error) {
+			if throttleConn != nil {
+				if err := waitForSourceLoad(ctx, throttleConn, config, partitions.BatchId); err != nil {
+					return 0, err
+				}
+			}
+			logger.Info(fmt.Sprintf("batch-%d - replicating partition - %s", partitions.BatchId, p.PartitionId))
+			partitionRows, err := a.replicateQRepPartition(ctx, config, i+1, numPartitions, p, runUUID, throttle)
+			if err != nil {
+				a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+				a.emitQRepLineage(ctx, lineage.EventTypeFail, config, runUUID)
+				return 0, err
+			}
+			return partitionRows, nil
+		})
+}
+
+// waitForSourceLoad holds a partition batch back while the source's load allows fewer batches in parallel
+// than its id. The first batch always proceeds, so partitions keep being replicated under any load.
+func waitForSourceLoad(ctx context.Context, conn connectors.QRepThrottleConnector,
+	config *protos.QRepConfig, batchID int32,
+) error {
+	maxParallelism := int(config.MaxParallelWorkers)
+	if maxParallelism <= 0 {
+		maxParallelism = shared.DefaultQRepMaxParallelWorkers
+	}
+	for {
+		parallelism, err := conn.PartitionParallelism(ctx, maxParallelism)
+		if err != nil {
+			return fmt.Errorf("failed to check source load: %w", err)
+		}
+		if int(batchID) <= parallelism {
+			return nil
+		}
+		msg := fmt.Sprintf("batch-%d waiting for source load, which allows %d partitions in parallel",
+			batchID, parallelism)
+		activity.GetLogger(ctx).Info(msg)
+		activity.RecordHeartbeat(ctx, msg)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(30 * time.Second):
+		}
+	}
 }
 
-// ReplicateQRepPartition replicates a QRepPartition from the source to the destination.
+// ReplicateQRepPartition replicates a QRepPartition from the source to the destination, returning the rows synced.
 func (a *FlowableActivity) replicateQRepPartition(ctx context.Context,
 	config *protos.QRepConfig,
 	idx int,
 	total int,
 	partition *protos.QRepPartition,
 	runUUID string,
-) error {
+	throttle *utils.QRepThrottle,
+) (int, error) {
 	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
 	logger := activity.GetLogger(ctx)
 
 	err := monitoring.UpdateStartTimeForPartition(ctx, a.CatalogPool, runUUID, partition, time.Now())
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-		return fmt.Errorf("failed to update start time for partition: %w", err)
+		return 0, fmt.Errorf("failed to update start time for partition: %w", err)
 	}
 
 	pullCtx, pullCancel := context.WithCancel(ctx)
@@ -618,14 +1272,14 @@ func (a *FlowableActivity) replicateQRepPartition(ctx context.Context,
 	srcConn, err := connectors.GetQRepPullConnector(pullCtx, config.SourcePeer)
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-		return fmt.Errorf("failed to get qrep source connector: %w", err)
+		return 0, fmt.Errorf("failed to get qrep source connector: %w", err)
 	}
 	defer connectors.CloseConnector(ctx, srcConn)
 
 	dstConn, err := connectors.GetQRepSyncConnector(ctx, config.DestinationPeer)
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-		return fmt.Errorf("failed to get qrep destination connector: %w", err)
+		return 0, fmt.Errorf("failed to get qrep destination connector: %w", err)
 	}
 	defer connectors.CloseConnector(ctx, dstConn)
 
@@ -640,13 +1294,12 @@ func (a *FlowableActivity) replicateQRepPartition(ctx context.Context,
 	var wg sync.WaitGroup
 
 	var goroutineErr error = nil
-	if config.SourcePeer.Type == protos.DBType_POSTGRES {
+	if streamConn, ok := srcConn.(connectors.QRepPullStreamConnector); ok {
 		stream = model.NewQRecordStream(bufferSize)
 		wg.Add(1)
 
 		go func() {
-			pgConn := srcConn.(*connpostgres.PostgresConnector)
-			tmp, err := pgConn.PullQRepRecordStream(ctx, config, partition, stream)
+			tmp, err := streamConn.PullQRepRecordStream(ctx, config, partition, stream)
 			numRecords := int64(tmp)
 			if err != nil {
 				a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
@@ -666,26 +1319,40 @@ func (a *FlowableActivity) replicateQRepPartition(ctx context.Context,
 		recordBatch, err := srcConn.PullQRepRecords(ctx, config, partition)
 		if err != nil {
 			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-			return fmt.Errorf("failed to pull qrep records: %w", err)
+			return 0, fmt.Errorf("failed to pull qrep records: %w", err)
 		}
 		logger.Info(fmt.Sprintf("pulled %d records", len(recordBatch.Records)))
 
 		err = monitoring.UpdatePullEndTimeAndRowsForPartition(ctx, a.CatalogPool, runUUID, partition, int64(len(recordBatch.Records)))
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		stream, err = recordBatch.ToQRecordStream(bufferSize)
 		if err != nil {
 			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-			return fmt.Errorf("failed to convert to qrecord stream: %w", err)
+			return 0, fmt.Errorf("failed to convert to qrecord stream: %w", err)
 		}
 	}
 
-	rowsSynced, err := dstConn.SyncQRepRecords(ctx, config, partition, stream)
+	if throttle != nil {
+		shutdownThrottled := a.recordThrottledTime(ctx, throttle, runUUID, partition)
+		defer shutdownThrottled()
+	}
+	maskedStream := model.NewColumnMasks(config.Masks).MaskStream(pullCtx, stream, bufferSize)
+	transformedStream, closeScript, err := transformQRepStream(pullCtx, config, maskedStream, bufferSize)
+	if err != nil {
+		return 0, err
+	}
+	defer closeScript()
+	numericStream := model.NumericOverflowStream(pullCtx, transformedStream, bufferSize,
+		config.DestinationPeer.Type, config.NumericOverflowPolicy)
+	geoStream := model.GeoFormatStream(pullCtx, numericStream, bufferSize, config.DestinationPeer.Type, config.GeoFormat)
+	measuredStream, bytesSynced := measureRecordBytes(pullCtx, geoStream, bufferSize, throttle)
+	rowsSynced, err := dstConn.SyncQRepRecords(ctx, config, partition, measuredStream)
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-		return fmt.Errorf("failed to sync records: %w", err)
+		return 0, fmt.Errorf("failed to sync records: %w", err)
 	}
 
 	if rowsSynced == 0 {
@@ -695,19 +1362,92 @@ func (a *FlowableActivity) replicateQRepPartition(ctx context.Context,
 		wg.Wait()
 		if goroutineErr != nil {
 			a.Alerter.LogFlowError(ctx, config.FlowJobName, goroutineErr)
-			return goroutineErr
+			return 0, goroutineErr
 		}
 
-		err := monitoring.UpdateRowsSyncedForPartition(ctx, a.CatalogPool, rowsSynced, runUUID, partition)
+		err := monitoring.UpdateRowsSyncedForPartition(ctx, a.CatalogPool, rowsSynced, bytesSynced.Load(),
+			runUUID, partition)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		logger.Info(fmt.Sprintf("pushed %d records", rowsSynced))
 	}
 
-	err = monitoring.UpdateEndTimeForPartition(ctx, a.CatalogPool, runUUID, partition)
-	return err
+	if err := monitoring.UpdateEndTimeForPartition(ctx, a.CatalogPool, runUUID, partition); err != nil {
+		return 0, err
+	}
+	return rowsSynced, nil
+}
+
+// transformQRepStream passes the rows of a partition through the mirror's transformation script, if it has one.
+// The returned function closes the script once the partition is synced.
+func transformQRepStream(ctx context.Context, config *protos.QRepConfig, stream *model.QRecordStream, bufferSize int,
+) (*model.QRecordStream, func(), error) {
+	if config.TransformScript == "" {
+		return stream, func() {}, nil
+	}
+	script, err := transform.LoadTable(ctx, config.TransformScript, config.DestinationTableIdentifier)
+	if err != nil {
+		return nil, nil, temporal.NewNonRetryableApplicationError(err.Error(), "transformScript", err)
+	}
+	return script.TransformStream(ctx, stream, bufferSize, config.WatermarkTable, config.DestinationTableIdentifier),
+		script.Close, nil
+}
+
+// measureRecordBytes hands on the records of a partition while estimating how many bytes they take up,
+// holding them back to the rate limits of throttle unless it's nil.
+func measureRecordBytes(ctx context.Context, stream *model.QRecordStream, bufferSize int, throttle *utils.QRepThrottle,
+) (*model.QRecordStream, *atomic.Int64) {
+	var bytes atomic.Int64
+	measured := stream.Measured(ctx, bufferSize, func(record []qvalue.QValue) {
+		var size int64
+		for _, qv := range record {
+			size += int64(qv.EstimatedSize())
+		}
+		bytes.Add(size)
+		if throttle != nil {
+			// only fails once ctx is done, which stops the stream too
+			_ = throttle.Wait(ctx, size)
+		}
+	})
+	return measured, &bytes
+}
+
+// recordThrottledTime keeps the time rate limits held back a partition up to date in the catalog while it's
+// replicated, the returned function records the final time and stops.
+func (a *FlowableActivity) recordThrottledTime(ctx context.Context, throttle *utils.QRepThrottle,
+	runUUID string, partition *protos.QRepPartition,
+) func() {
+	logger := activity.GetLogger(ctx)
+	start := throttle.Throttled()
+	update := func() {
+		err := monitoring.UpdateThrottledTimeForPartition(ctx, a.CatalogPool, runUUID, partition, throttle.Throttled()-start)
+		if err != nil {
+			logger.Warn("failed to record throttled time", slog.Any("error", err))
+		}
+	}
+	update()
+
+	ticker := time.NewTicker(15 * time.Second)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		update()
+	}
 }
 
 func (a *FlowableActivity) ConsolidateQRepPartitions(ctx context.Context, config *protos.QRepConfig,
@@ -715,6 +1455,7 @@ func (a *FlowableActivity) ConsolidateQRepPartitions(ctx context.Context, config
 ) error {
 	dstConn, err := connectors.GetQRepConsolidateConnector(ctx, config.DestinationPeer)
 	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		a.emitQRepLineage(ctx, lineage.EventTypeComplete, config, runUUID)
 		return monitoring.UpdateEndTimeForQRepRun(ctx, a.CatalogPool, runUUID)
 	} else if err != nil {
 		return err
@@ -729,12 +1470,22 @@ func (a *FlowableActivity) ConsolidateQRepPartitions(ctx context.Context, config
 	err = dstConn.ConsolidateQRepPartitions(ctx, config)
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		a.emitQRepLineage(ctx, lineage.EventTypeFail, config, runUUID)
 		return err
 	}
 
+	a.emitQRepLineage(ctx, lineage.EventTypeComplete, config, runUUID)
 	return monitoring.UpdateEndTimeForQRepRun(ctx, a.CatalogPool, runUUID)
 }
 
+// RecordQRepRunSummary persists the summary of a run once its partitions are replicated and consolidated.
+func (a *FlowableActivity) RecordQRepRunSummary(ctx context.Context, config *protos.QRepConfig,
+	runUUID string, startTime time.Time,
+) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	return monitoring.RecordQRepRunSummary(ctx, a.CatalogPool, config.FlowJobName, runUUID, startTime)
+}
+
 func (a *FlowableActivity) CleanupQRepFlow(ctx context.Context, config *protos.QRepConfig) error {
 	dst, err := connectors.GetQRepConsolidateConnector(ctx, config.DestinationPeer)
 	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
@@ -765,45 +1516,48 @@ func (a *FlowableActivity) DropFlowDestination(ctx context.Context, config *prot
 	}
 	defer connectors.CloseConnector(ctx, dstConn)
 
-	return dstConn.SyncFlowCleanup(ctx, config.FlowJobName)
+	if err := dstConn.SyncFlowCleanup(ctx, config.FlowJobName); err != nil {
+		return err
+	}
+	// destinations without catalog metadata of their own still have the partitions checkpointed in it
+	if err := connmetadata.NewPostgresMetadataStoreFromCatalog(activity.GetLogger(ctx), a.CatalogPool).
+		DropMetadata(ctx, config.FlowJobName); err != nil {
+		return err
+	}
+	return a.dropFanoutDestinations(ctx, config.FlowJobName)
 }
 
 func (a *FlowableActivity) getPostgresPeerConfigs(ctx context.Context) ([]*protos.Peer, error) {
-	optionRows, err := a.CatalogPool.Query(ctx, `
-			SELECT DISTINCT p.name, p.options
+	nameRows, err := a.CatalogPool.Query(ctx, `
+			SELECT DISTINCT p.name
 			FROM peers p
 			JOIN flows f ON p.id = f.source_peer
 			WHERE p.type = $1`, protos.DBType_POSTGRES)
 	if err != nil {
 		return nil, err
 	}
+	peerNames, err := pgx.CollectRows(nameRows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
 
-	return pgx.CollectRows(optionRows, func(row pgx.CollectableRow) (*protos.Peer, error) {
-		var peerName string
-		var peerOptions []byte
-		err := optionRows.Scan(&peerName, &peerOptions)
+	// peers are loaded through the catalog, which decrypts their configs
+	peers := make([]*protos.Peer, 0, len(peerNames))
+	for _, peerName := range peerNames {
+		peer, err := catalog.LoadPeer(ctx, a.CatalogPool, peerName)
 		if err != nil {
 			return nil, err
 		}
-		var pgPeerConfig protos.PostgresConfig
-		unmarshalErr := proto.Unmarshal(peerOptions, &pgPeerConfig)
-		if unmarshalErr != nil {
-			return nil, unmarshalErr
-		}
-		return &protos.Peer{
-			Name:   peerName,
-			Type:   protos.DBType_POSTGRES,
-			Config: &protos.Peer_PostgresConfig{PostgresConfig: &pgPeerConfig},
-		}, nil
-	})
+		peers = append(peers, peer)
+	}
+	return peers, nil
 }
 
-func (a *FlowableActivity) SendWALHeartbeat(ctx context.Context) error {
+// SendWALHeartbeat runs every minute, writing the WAL heartbeat to the sources of mirrors when walHeartbeat is set,
+// and source heartbeats to those with a mirror due for one, each over a single connection to the source.
+func (a *FlowableActivity) SendWALHeartbeat(ctx context.Context, walHeartbeat bool) error {
 	logger := activity.GetLogger(ctx)
-	if !peerdbenv.PeerDBEnableWALHeartbeat() {
-		logger.Info("wal heartbeat is disabled")
-		return nil
-	}
+	walHeartbeat = walHeartbeat && peerdbenv.PeerDBEnableWALHeartbeat()
 
 	pgPeers, err := a.getPostgresPeerConfigs(ctx)
 	if err != nil {
@@ -811,6 +1565,10 @@ func (a *FlowableActivity) SendWALHeartbeat(ctx context.Context) error {
 			"Skipping walheartbeat send. Error: " + err.Error())
 		return err
 	}
+	sourceHeartbeats, err := a.dueSourceHeartbeats(ctx, time.Now())
+	if err != nil {
+		logger.Warn("[sendwalheartbeat] unable to fetch mirrors, skipping source heartbeats", slog.Any("error", err))
+	}
 
 	command := `
 		BEGIN;
@@ -821,40 +1579,82 @@ func (a *FlowableActivity) SendWALHeartbeat(ctx context.Context) error {
 		`
 	// run above command for each Postgres peer
 	for _, pgPeer := range pgPeers {
+		flowNames := sourceHeartbeats[pgPeer.Name]
+		if !walHeartbeat && len(flowNames) == 0 {
+			continue
+		}
 		activity.RecordHeartbeat(ctx, pgPeer.Name)
 		if ctx.Err() != nil {
 			return nil
 		}
 
 		func() {
-			pgConfig := pgPeer.GetPostgresConfig()
-			peerConn, peerErr := pgx.Connect(ctx, utils.GetPGConnectionString(pgConfig))
+			peerConn, peerErr := connpostgres.NewPostgresConnector(ctx, pgPeer.GetPostgresConfig())
 			if peerErr != nil {
-				logger.Error(fmt.Sprintf("error creating pool for postgres peer %v with host %v: %v",
-					pgPeer.Name, pgConfig.Host, peerErr))
+				logger.Error(fmt.Sprintf("error connecting to postgres peer %v: %v", pgPeer.Name, peerErr))
 				return
 			}
-			defer peerConn.Close(ctx)
+			defer connectors.CloseConnector(ctx, peerConn)
 
-			_, err := peerConn.Exec(ctx, command)
-			if err != nil {
-				logger.Warn(fmt.Sprintf("could not send walheartbeat to peer %v: %v", pgPeer.Name, err))
+			if walHeartbeat {
+				if _, err := peerConn.Conn().Exec(ctx, command); err != nil {
+					logger.Warn(fmt.Sprintf("could not send walheartbeat to peer %v: %v", pgPeer.Name, err))
+				} else {
+					logger.Info(fmt.Sprintf("sent walheartbeat to peer %v", pgPeer.Name))
+				}
+			}
+			if len(flowNames) != 0 {
+				if err := peerConn.SendSourceHeartbeat(ctx, flowNames); err != nil {
+					logger.Warn("failed to send source heartbeat",
+						slog.String("peer", pgPeer.Name), slog.Any("error", err))
+				}
 			}
-
-			logger.Info(fmt.Sprintf("sent walheartbeat to peer %v", pgPeer.Name))
 		}()
 	}
 
 	return nil
 }
 
-func (a *FlowableActivity) RecordSlotSizes(ctx context.Context) error {
+// dueSourceHeartbeats returns the mirrors due for a source heartbeat at now, by source peer
+func (a *FlowableActivity) dueSourceHeartbeats(ctx context.Context, now time.Time) (map[string][]string, error) {
+	configs, err := a.loadCDCFlowConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defaultInterval := dynamicconf.PeerDBSourceHeartbeatInterval(ctx)
+
+	due := make(map[string][]string)
+	for _, config := range configs {
+		interval := time.Duration(config.SourceHeartbeatIntervalSeconds) * time.Second
+		if interval == 0 {
+			interval = defaultInterval
+		}
+		if config.Source != nil && sourceHeartbeatDue(now, interval) {
+			due[config.Source.Name] = append(due[config.Source.Name], config.FlowJobName)
+		}
+	}
+	return due, nil
+}
+
+// sourceHeartbeatDue returns whether a heartbeat is due at now for a mirror with the interval, 0 disabling them.
+// Heartbeats are sent every whole number of minutes the interval rounds up to, counted from the Unix epoch,
+// so that it doesn't matter which worker sends them.
+func sourceHeartbeatDue(now time.Time, interval time.Duration) bool {
+	if interval <= 0 {
+		return false
+	}
+	minutes := int64((interval + time.Minute - 1) / time.Minute)
+	return now.Unix()/60%minutes == 0
+}
+
+// loadCDCFlowConfigs returns the configs of every CDC mirror in the catalog
+func (a *FlowableActivity) loadCDCFlowConfigs(ctx context.Context) ([]*protos.FlowConnectionConfigs, error) {
 	rows, err := a.CatalogPool.Query(ctx, "SELECT flows.name, flows.config_proto FROM flows WHERE query_string IS NULL")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	configs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.FlowConnectionConfigs, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.FlowConnectionConfigs, error) {
 		var flowName string
 		var configProto []byte
 		err := rows.Scan(&flowName, &configProto)
@@ -870,6 +1670,10 @@ func (a *FlowableActivity) RecordSlotSizes(ctx context.Context) error {
 
 		return &config, nil
 	})
+}
+
+func (a *FlowableActivity) RecordSlotSizes(ctx context.Context) error {
+	configs, err := a.loadCDCFlowConfigs(ctx)
 	if err != nil {
 		return err
 	}
@@ -909,6 +1713,149 @@ func (a *FlowableActivity) RecordSlotSizes(ctx context.Context) error {
 	return nil
 }
 
+// CheckPeerCredentialExpiry records when credentials attached to peers expire and alerts on those expiring soon.
+// The expiries recorded for a peer are replaced with those found on each check, so credentials removed or rotated
+// since, and peers dropped since, don't keep reporting stale expiries.
+func (a *FlowableActivity) CheckPeerCredentialExpiry(ctx context.Context) error {
+	logger := activity.GetLogger(ctx)
+	peers, err := catalog.LoadPeers(ctx, a.CatalogPool)
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.CatalogPool.Exec(ctx, `DELETE FROM peerdb_stats.peer_credential_expiry e
+		WHERE NOT EXISTS (SELECT 1 FROM peers p WHERE p.name = e.peer_name)`); err != nil {
+		return fmt.Errorf("failed to delete credential expiry of dropped peers: %w", err)
+	}
+
+	for _, peer := range peers {
+		activity.RecordHeartbeat(ctx, "checking credential expiry of peer "+peer.Name)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		expiries, err := connectors.PeerCredentialExpiries(ctx, peer)
+		if err != nil {
+			logger.Warn("failed to check credential expiry", slog.String("peer", peer.Name), slog.Any("error", err))
+			continue
+		}
+		if err := a.replaceCredentialExpiries(ctx, peer.Name, expiries); err != nil {
+			return err
+		}
+		for _, expiry := range expiries {
+			a.Alerter.AlertIfCredentialExpiring(ctx, peer.Name, expiry)
+		}
+	}
+
+	return nil
+}
+
+func (a *FlowableActivity) replaceCredentialExpiries(
+	ctx context.Context,
+	peerName string,
+	expiries []*protos.PeerCredentialExpiry,
+) error {
+	tx, err := a.CatalogPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record credential expiry of peer %s: %w", peerName, err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			activity.GetLogger(ctx).Error("failed to rollback recording credential expiry", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, "DELETE FROM peerdb_stats.peer_credential_expiry WHERE peer_name = $1", peerName); err != nil {
+		return fmt.Errorf("failed to record credential expiry of peer %s: %w", peerName, err)
+	}
+	for _, expiry := range expiries {
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO peerdb_stats.peer_credential_expiry(peer_name,credential,expires_at) VALUES($1,$2,$3)",
+			peerName, expiry.Credential, expiry.ExpiresAt.AsTime(),
+		); err != nil {
+			return fmt.Errorf("failed to record credential expiry of peer %s: %w", peerName, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to record credential expiry of peer %s: %w", peerName, err)
+	}
+	return nil
+}
+
+// RollupStats aggregates mirror stats into hourly and daily rollups and enforces their retention
+func (a *FlowableActivity) RollupStats(ctx context.Context) error {
+	return monitoring.RollupCDCStats(ctx, a.CatalogPool, time.Now(), monitoring.StatsRetentionFromEnv())
+}
+
+// PruneRawTables deletes the raw table rows of batches CDC mirrors normalized longer ago than their raw table retention
+func (a *FlowableActivity) PruneRawTables(ctx context.Context) error {
+	configs, err := a.loadCDCFlowConfigs(ctx)
+	if err != nil {
+		return err
+	}
+
+	logger := activity.GetLogger(ctx)
+	defaultRetention := peerdbenv.PeerDBRawTableRetention()
+	for _, config := range configs {
+		retention := defaultRetention
+		if config.RawTableRetentionHours > 0 {
+			retention = time.Duration(config.RawTableRetentionHours) * time.Hour
+		}
+		if retention <= 0 {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		// fan-out destinations keep raw tables of their own
+		for _, rawTableConfig := range append([]*protos.FlowConnectionConfigs{config}, model.FanoutConfigs(config)...) {
+			if err := a.pruneRawTable(ctx, rawTableConfig, time.Now().Add(-retention)); err != nil {
+				logger.Error("failed to prune raw table",
+					slog.String(string(shared.FlowNameKey), rawTableConfig.FlowJobName), slog.Any("error", err))
+			}
+		}
+	}
+	return nil
+}
+
+func (a *FlowableActivity) pruneRawTable(ctx context.Context, config *protos.FlowConnectionConfigs, cutoff time.Time) error {
+	batchID, err := catalog.LastBatchNormalizedBefore(ctx, a.CatalogPool, config.FlowJobName, cutoff)
+	if err != nil || batchID <= 0 {
+		return err
+	}
+
+	dstConn, err := connectors.GetConnectorAs[connectors.RawTablePruneConnector](ctx, config.Destination)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return "pruning raw table of " + config.FlowJobName
+	})
+	defer shutdown()
+
+	// the catalog outlives raw tables recreated under the same mirror name, so never go past the destination
+	lastNormalizedBatchID, err := dstConn.GetLastNormalizeBatchID(ctx, config.FlowJobName)
+	if err != nil {
+		return err
+	}
+	batchID = min(batchID, lastNormalizedBatchID)
+	if batchID <= 0 {
+		return nil
+	}
+
+	if err := dstConn.PruneRawTable(ctx, config.FlowJobName, batchID); err != nil {
+		return err
+	}
+	activity.GetLogger(ctx).Info("pruned raw table",
+		slog.String(string(shared.FlowNameKey), config.FlowJobName), slog.Int64("batchID", batchID))
+	return catalog.PruneNormalizedBatches(ctx, a.CatalogPool, config.FlowJobName, batchID)
+}
+
 func (a *FlowableActivity) QRepWaitUntilNewRows(ctx context.Context,
 	config *protos.QRepConfig, last *protos.QRepPartition,
 ) error {
@@ -932,6 +1879,7 @@ func (a *FlowableActivity) QRepWaitUntilNewRows(ctx context.Context,
 	pgSrcConn := srcConn.(*connpostgres.PostgresConnector)
 	logger.Info(fmt.Sprintf("current last partition value is %v", last))
 	attemptCount := 1
+	waitStart := time.Now()
 	for {
 		activity.RecordHeartbeat(ctx, fmt.Sprintf("no new rows yet, attempt #%d", attemptCount))
 		waitUntil := time.Now().Add(waitBetweenBatches)
@@ -958,6 +1906,12 @@ func (a *FlowableActivity) QRepWaitUntilNewRows(ctx context.Context,
 		if result {
 			break
 		}
+		if config.DeleteReconciliationIntervalSeconds > 0 &&
+			time.Since(waitStart) >= time.Duration(config.DeleteReconciliationIntervalSeconds)*time.Second {
+			// deletes don't show up as new rows, return so the workflow can reconcile them
+			logger.Info("no new rows yet, returning to reconcile deletes")
+			break
+		}
 
 		attemptCount += 1
 	}
@@ -965,6 +1919,231 @@ func (a *FlowableActivity) QRepWaitUntilNewRows(ctx context.Context,
 	return nil
 }
 
+const (
+	deleteReconcileBuckets   = 1 << 12
+	deleteReconcileBatchSize = 500
+	adoptVerifyBuckets       = 1 << 12
+)
+
+// ReconcileQRepDeletes deletes rows from the destination table whose primary key no longer exists in the
+// watermark table. Both tables' keys are first summarized into hash buckets, and only keys in buckets which
+// differ are held in memory and compared.
+func (a *FlowableActivity) ReconcileQRepDeletes(ctx context.Context, config *protos.QRepConfig) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	logger := activity.GetLogger(ctx)
+
+	pkeyColumns := config.WriteMode.GetUpsertKeyColumns()
+	if len(pkeyColumns) == 0 {
+		return errors.New("delete reconciliation requires upsert key columns")
+	}
+
+	srcConn, err := connectors.GetConnectorAs[connectors.PrimaryKeyScanConnector](ctx, config.SourcePeer)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		logger.Warn("source doesn't support delete reconciliation, skipping")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcConn)
+
+	dstConn, err := connectors.GetConnectorAs[connectors.DeleteReconcileConnector](ctx, config.DestinationPeer)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		logger.Warn("destination doesn't support delete reconciliation, skipping")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return "reconciling deletes for job - " + config.FlowJobName
+	})
+	defer shutdown()
+
+	// destination is scanned after the source, so rows it holds which the source scan misses have been deleted
+	srcBuckets := utils.NewKeyBuckets(deleteReconcileBuckets)
+	if err := srcConn.ScanPrimaryKeys(ctx, config.WatermarkTable, pkeyColumns, "", func(key []qvalue.QValue) error {
+		srcBuckets.Add(key)
+		return nil
+	}); err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to scan source keys: %w", err)
+	}
+	dstBuckets := utils.NewKeyBuckets(deleteReconcileBuckets)
+	if err := dstConn.ScanPrimaryKeys(ctx, config.DestinationTableIdentifier, pkeyColumns, config.SoftDeleteColName,
+		func(key []qvalue.QValue) error {
+			dstBuckets.Add(key)
+			return nil
+		},
+	); err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to scan destination keys: %w", err)
+	}
+
+	mismatched := srcBuckets.Mismatched(dstBuckets)
+	logger.Info("compared primary keys", slog.Int("mismatchedBuckets", len(mismatched)))
+	if len(mismatched) == 0 {
+		return nil
+	}
+
+	srcKeys := make(map[string]struct{})
+	if err := srcConn.ScanPrimaryKeys(ctx, config.WatermarkTable, pkeyColumns, "", func(key []qvalue.QValue) error {
+		if _, ok := mismatched[srcBuckets.Bucket(key)]; ok {
+			srcKeys[utils.PrimaryKeyString(key)] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to scan source keys: %w", err)
+	}
+	// deleted afterwards, connections can't run statements while scanning
+	var deletedKeys [][]qvalue.QValue
+	if err := dstConn.ScanPrimaryKeys(ctx, config.DestinationTableIdentifier, pkeyColumns, config.SoftDeleteColName,
+		func(key []qvalue.QValue) error {
+			if _, ok := mismatched[dstBuckets.Bucket(key)]; ok {
+				keyString := utils.PrimaryKeyString(key)
+				if _, ok := srcKeys[keyString]; !ok {
+					deletedKeys = append(deletedKeys, key)
+					// duplicates at the destination are deleted together
+					srcKeys[keyString] = struct{}{}
+				}
+			}
+			return nil
+		},
+	); err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to scan destination keys: %w", err)
+	}
+
+	var numRowsDeleted int64
+	for start := 0; start < len(deletedKeys); start += deleteReconcileBatchSize {
+		batch := deletedKeys[start:min(start+deleteReconcileBatchSize, len(deletedKeys))]
+		numRows, err := dstConn.DeleteRowsByPrimaryKey(ctx, config.DestinationTableIdentifier, pkeyColumns,
+			batch, config.SoftDeleteColName)
+		if err != nil {
+			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+			return fmt.Errorf("failed to delete rows: %w", err)
+		}
+		numRowsDeleted += numRows
+	}
+	logger.Info("reconciled deletes", slog.Int("keys", len(deletedKeys)), slog.Int64("rowsDeleted", numRowsDeleted))
+	return nil
+}
+
+// AdoptTables returns the LSN replication of a mirror over adopted tables starts from, which is
+// where its replication slot was created. If verification is opted for, the primary keys of every source
+// table are compared with its adopted table in hash buckets, failing if any bucket differs.
+func (a *FlowableActivity) AdoptTables(ctx context.Context, cfg *protos.FlowConnectionConfigs,
+	tableNameSchemaMapping map[string]*protos.TableSchema,
+) (*protos.AdoptTablesOutput, error) {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, cfg.FlowJobName)
+	logger := activity.GetLogger(ctx)
+
+	srcConn, err := connectors.GetCDCPullConnector(ctx, cfg.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcConn)
+
+	slotName := "peerflow_slot_" + cfg.FlowJobName
+	if cfg.ReplicationSlotName != "" {
+		slotName = cfg.ReplicationSlotName
+	}
+	slotInfo, err := srcConn.GetSlotInfo(ctx, slotName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replication slot %s: %w", slotName, err)
+	} else if len(slotInfo) == 0 {
+		return nil, fmt.Errorf("replication slot %s not found", slotName)
+	}
+	output := &protos.AdoptTablesOutput{StartLsn: slotInfo[0].ConfirmedFlushLSN}
+	if !cfg.VerifyAdoptedTables {
+		return output, nil
+	}
+
+	srcScanConn, err := connectors.GetConnectorAs[connectors.PrimaryKeyScanConnector](ctx, cfg.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcScanConn)
+
+	dstConn, err := connectors.GetConnectorAs[connectors.PrimaryKeyScanConnector](ctx, cfg.Destination)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		return nil, temporal.NewNonRetryableApplicationError("destination doesn't support verifying adopted tables",
+			"notAdoptable", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	softDeleteColName := ""
+	if cfg.SoftDelete {
+		softDeleteColName = cfg.SoftDeleteColName
+	}
+
+	var currentTable atomic.Pointer[string]
+	currentTable.Store(new(string))
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return "verifying adopted table " + *currentTable.Load()
+	})
+	defer shutdown()
+
+	var mismatchedTables []string
+	for _, mapping := range cfg.TableMappings {
+		currentTable.Store(&mapping.DestinationTableIdentifier)
+		tableSchema, ok := tableNameSchemaMapping[mapping.DestinationTableIdentifier]
+		if !ok {
+			return nil, fmt.Errorf("schema of table %s not found", mapping.DestinationTableIdentifier)
+		}
+
+		checksum := &protos.AdoptedTableChecksum{
+			SourceTableIdentifier:      mapping.SourceTableIdentifier,
+			DestinationTableIdentifier: mapping.DestinationTableIdentifier,
+		}
+		srcBuckets := utils.NewKeyBuckets(adoptVerifyBuckets)
+		if err := srcScanConn.ScanPrimaryKeys(ctx, mapping.SourceTableIdentifier, tableSchema.PrimaryKeyColumns, "",
+			func(key []qvalue.QValue) error {
+				srcBuckets.Add(key)
+				checksum.SourceRows += 1
+				return nil
+			},
+		); err != nil {
+			a.Alerter.LogFlowError(ctx, cfg.FlowJobName, err)
+			return nil, fmt.Errorf("failed to scan source keys of %s: %w", mapping.SourceTableIdentifier, err)
+		}
+		dstBuckets := utils.NewKeyBuckets(adoptVerifyBuckets)
+		if err := dstConn.ScanPrimaryKeys(ctx, mapping.DestinationTableIdentifier, tableSchema.PrimaryKeyColumns,
+			softDeleteColName, func(key []qvalue.QValue) error {
+				dstBuckets.Add(key)
+				checksum.DestinationRows += 1
+				return nil
+			},
+		); err != nil {
+			a.Alerter.LogFlowError(ctx, cfg.FlowJobName, err)
+			return nil, fmt.Errorf("failed to scan destination keys of %s: %w", mapping.DestinationTableIdentifier, err)
+		}
+
+		checksum.MismatchedBuckets = uint32(len(srcBuckets.Mismatched(dstBuckets)))
+		logger.Info("verified adopted table",
+			slog.String("table", mapping.DestinationTableIdentifier),
+			slog.Int64("sourceRows", checksum.SourceRows),
+			slog.Int64("destinationRows", checksum.DestinationRows),
+			slog.Uint64("mismatchedBuckets", uint64(checksum.MismatchedBuckets)))
+		if checksum.MismatchedBuckets != 0 {
+			mismatchedTables = append(mismatchedTables, fmt.Sprintf("%s (%d of %d buckets)",
+				mapping.DestinationTableIdentifier, checksum.MismatchedBuckets, adoptVerifyBuckets))
+		}
+		output.Checksums = append(output.Checksums, checksum)
+	}
+
+	if len(mismatchedTables) != 0 {
+		err := fmt.Errorf("adopted tables %w: they differ from source in %s",
+			utils.ErrNotAdoptable, strings.Join(mismatchedTables, ", "))
+		a.Alerter.LogFlowError(ctx, cfg.FlowJobName, err)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "notAdoptable", err)
+	}
+	return output, nil
+}
+
 func (a *FlowableActivity) RenameTables(ctx context.Context, config *protos.RenameTablesInput) (
 	*protos.RenameTablesOutput, error,
 ) {
@@ -1026,6 +2205,22 @@ func (a *FlowableActivity) CreateTablesFromExisting(ctx context.Context, req *pr
 	return nil, errors.New("create tables from existing is only supported on snowflake and bigquery")
 }
 
+// XminLag returns how many xids the source is ahead of the xmin an xmin mirror last synced up to.
+func (a *FlowableActivity) XminLag(ctx context.Context, config *protos.QRepConfig, lastXmin int64) (int64, error) {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	srcConn, err := connectors.GetQRepPullConnector(ctx, config.SourcePeer)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get qrep source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcConn)
+
+	pgConn, ok := srcConn.(*connpostgres.PostgresConnector)
+	if !ok {
+		return 0, errors.New("xmin mirrors require a postgres source")
+	}
+	return pgConn.XminLag(ctx, lastXmin)
+}
+
 // ReplicateXminPartition replicates a XminPartition from the source to the destination.
 func (a *FlowableActivity) ReplicateXminPartition(ctx context.Context,
 	config *protos.QRepConfig,
@@ -1035,6 +2230,14 @@ func (a *FlowableActivity) ReplicateXminPartition(ctx context.Context,
 	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
 	logger := activity.GetLogger(ctx)
 
+	checkpoints := connmetadata.NewPostgresMetadataStoreFromCatalog(logger, a.CatalogPool)
+	if snapshotXmin, synced, err := checkpointedXmin(ctx, checkpoints, config.FlowJobName, runUUID); err != nil {
+		return 0, err
+	} else if synced {
+		logger.Info("xmin run already replicated, skipping")
+		return snapshotXmin, nil
+	}
+
 	startTime := time.Now()
 	srcConn, err := connectors.GetQRepPullConnector(ctx, config.SourcePeer)
 	if err != nil {
@@ -1062,9 +2265,9 @@ func (a *FlowableActivity) ReplicateXminPartition(ctx context.Context,
 		var numRecords int
 		numRecords, currentSnapshotXmin, pullErr = pgConn.PullXminRecordStream(ctx, config, partition, stream)
 		if pullErr != nil {
-			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-			logger.Warn(fmt.Sprintf("[xmin] failed to pull records: %v", err))
-			return err
+			a.Alerter.LogFlowError(ctx, config.FlowJobName, pullErr)
+			logger.Warn(fmt.Sprintf("[xmin] failed to pull records: %v", pullErr))
+			return pullErr
 		}
 
 		// The first sync of an XMIN mirror will have a partition without a range
@@ -1107,22 +2310,41 @@ func (a *FlowableActivity) ReplicateXminPartition(ctx context.Context,
 	})
 	defer shutdown()
 
-	rowsSynced, err := dstConn.SyncQRepRecords(ctx, config, partition, stream)
+	measureCtx, measureCancel := context.WithCancel(ctx)
+	defer measureCancel()
+	// a single activity replicates the whole table
+	throttle := utils.NewQRepThrottle(config, 1)
+	if throttle != nil {
+		shutdownThrottled := a.recordThrottledTime(ctx, throttle, runUUID, partition)
+		defer shutdownThrottled()
+	}
+	maskedStream := model.NewColumnMasks(config.Masks).MaskStream(measureCtx, stream, bufferSize)
+	transformedStream, closeScript, err := transformQRepStream(measureCtx, config, maskedStream, bufferSize)
+	if err != nil {
+		return 0, err
+	}
+	defer closeScript()
+	numericStream := model.NumericOverflowStream(measureCtx, transformedStream, bufferSize,
+		config.DestinationPeer.Type, config.NumericOverflowPolicy)
+	geoStream := model.GeoFormatStream(measureCtx, numericStream, bufferSize, config.DestinationPeer.Type, config.GeoFormat)
+	measuredStream, bytesSynced := measureRecordBytes(measureCtx, geoStream, bufferSize, throttle)
+	rowsSynced, err := dstConn.SyncQRepRecords(ctx, config, partition, measuredStream)
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
 		return 0, fmt.Errorf("failed to sync records: %w", err)
 	}
 
+	// the snapshot xmin checkpointed below is only known once the pull is done
+	if err := errGroup.Wait(); err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return 0, err
+	}
+
 	if rowsSynced == 0 {
 		logger.Info("no records to push for xmin")
 	} else {
-		err := errGroup.Wait()
-		if err != nil {
-			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
-			return 0, err
-		}
-
-		err = monitoring.UpdateRowsSyncedForPartition(ctx, a.CatalogPool, rowsSynced, runUUID, partition)
+		err = monitoring.UpdateRowsSyncedForPartition(ctx, a.CatalogPool, rowsSynced, bytesSynced.Load(),
+			runUUID, partition)
 		if err != nil {
 			return 0, err
 		}
@@ -1130,6 +2352,12 @@ func (a *FlowableActivity) ReplicateXminPartition(ctx context.Context,
 		logger.Info(fmt.Sprintf("pushed %d records", rowsSynced))
 	}
 
+	err = checkpoints.FinishQrepPartition(ctx, xminCheckpoint(runUUID, partition, currentSnapshotXmin),
+		config.FlowJobName, startTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to checkpoint xmin run: %w", err)
+	}
+
 	err = monitoring.UpdateEndTimeForPartition(ctx, a.CatalogPool, runUUID, partition)
 	if err != nil {
 		return 0, err
@@ -1149,9 +2377,10 @@ func (a *FlowableActivity) AddTablesToPublication(ctx context.Context, cfg *prot
 	defer connectors.CloseConnector(ctx, srcConn)
 
 	err = srcConn.AddTablesToPublication(ctx, &protos.AddTablesToPublicationInput{
-		FlowJobName:      cfg.FlowJobName,
-		PublicationName:  cfg.PublicationName,
-		AdditionalTables: additionalTableMappings,
+		FlowJobName:            cfg.FlowJobName,
+		PublicationName:        cfg.PublicationName,
+		AdditionalTables:       additionalTableMappings,
+		PublicationColumnLists: cfg.PublicationColumnLists,
 	})
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, cfg.FlowJobName, err)
@@ -0,0 +1,248 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+
+	"github.com/PeerDB-io/peer-flow/connectors"
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// a mirror which ran this long since it was last restarted has its restarts counted from 0 again
+const restartCountResetAfter = 24 * time.Hour
+
+type restartPolicy struct {
+	maxRestarts int32
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// delay returns how long after failing a mirror restarted restarts times before is restarted again
+func (p restartPolicy) delay(restarts int32) time.Duration {
+	delay := p.backoff
+	for range restarts {
+		if delay >= p.maxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	return min(delay, p.maxBackoff)
+}
+
+// RestartFailedMirrors restarts mirrors whose workflow failed, backing off between restarts of a mirror,
+// and alerts when a mirror keeps failing after the most restarts allowed.
+func (a *FlowableActivity) RestartFailedMirrors(ctx context.Context) error {
+	policy := restartPolicy{
+		maxRestarts: int32(dynamicconf.PeerDBMirrorRestartMaxAttempts(ctx)),
+		backoff:     dynamicconf.PeerDBMirrorRestartBackoff(ctx),
+		maxBackoff:  dynamicconf.PeerDBMirrorRestartMaxBackoff(ctx),
+	}
+	if policy.maxRestarts <= 0 {
+		return nil
+	}
+
+	rows, err := a.CatalogPool.Query(ctx, "SELECT name, workflow_id FROM flows WHERE workflow_id IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	type mirror struct {
+		name       string
+		workflowID string
+	}
+	var mirrors []mirror
+	for rows.Next() {
+		var m mirror
+		if err := rows.Scan(&m.name, &m.workflowID); err != nil {
+			rows.Close()
+			return err
+		}
+		mirrors = append(mirrors, m)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	logger := activity.GetLogger(ctx)
+	for _, m := range mirrors {
+		activity.RecordHeartbeat(ctx, "checking mirror "+m.name)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := a.superviseMirror(ctx, m.name, m.workflowID, policy); err != nil {
+			logger.Error("failed to supervise mirror", slog.String(string(shared.FlowNameKey), m.name), slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+func (a *FlowableActivity) superviseMirror(ctx context.Context, flowName string, workflowID string, policy restartPolicy) error {
+	desc, err := a.TemporalClient.DescribeWorkflowExecution(ctx, workflowID, "")
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to describe workflow: %w", err)
+	}
+	info := desc.GetWorkflowExecutionInfo()
+	if status := info.GetStatus(); status != enums.WORKFLOW_EXECUTION_STATUS_FAILED &&
+		status != enums.WORKFLOW_EXECUTION_STATUS_TIMED_OUT {
+		return nil
+	}
+	runID := info.GetExecution().GetRunId()
+	closedAt := time.Now()
+	if info.CloseTime != nil {
+		closedAt = *info.CloseTime
+	}
+
+	restart, err := catalog.GetMirrorRestart(ctx, a.CatalogPool, flowName)
+	if err != nil {
+		return err
+	}
+	if restart == nil || restart.FailedRunID != runID {
+		// a failure not seen before, schedule its restart
+		var restarts int32
+		var restartedAt *time.Time
+		if restart != nil {
+			restartedAt = restart.RestartedAt
+			if restartedAt == nil || closedAt.Sub(*restartedAt) < restartCountResetAfter {
+				restarts = restart.Restarts
+			}
+		}
+		restart = &catalog.MirrorRestart{
+			FailedRunID:   runID,
+			Restarts:      restarts,
+			NextRestartAt: closedAt.Add(policy.delay(restarts)),
+			RestartedAt:   restartedAt,
+			GaveUp:        restarts >= policy.maxRestarts,
+		}
+		if err := catalog.SaveMirrorRestart(ctx, a.CatalogPool, flowName, restart); err != nil {
+			return err
+		}
+		if restart.GaveUp {
+			failure := a.workflowFailure(ctx, workflowID, runID)
+			a.Alerter.LogFlowError(ctx, flowName,
+				fmt.Errorf("mirror failed after %d restarts, giving up: %s", restarts, failure))
+			a.Alerter.AlertMirrorRestartsExhausted(ctx, flowName, restarts, failure)
+			return nil
+		}
+	}
+	if restart.GaveUp || time.Now().Before(restart.NextRestartAt) {
+		return nil
+	}
+
+	if err := a.restartWorkflow(ctx, flowName, workflowID, runID); err != nil {
+		return err
+	}
+	now := time.Now()
+	restart.Restarts += 1
+	restart.RestartedAt = &now
+	activity.GetLogger(ctx).Info("restarted failed mirror",
+		slog.String(string(shared.FlowNameKey), flowName), slog.Int("restarts", int(restart.Restarts)))
+	return catalog.SaveMirrorRestart(ctx, a.CatalogPool, flowName, restart)
+}
+
+// restartWorkflow starts a failed workflow again with the arguments of its failed run,
+// mirrors pick up from the progress recorded on their destination
+func (a *FlowableActivity) restartWorkflow(ctx context.Context, flowName string, workflowID string, runID string) error {
+	iter := a.TemporalClient.GetWorkflowHistory(ctx, workflowID, runID, false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	if !iter.HasNext() {
+		return errors.New("workflow has no history")
+	}
+	event, err := iter.Next()
+	if err != nil {
+		return fmt.Errorf("failed to get workflow history: %w", err)
+	}
+	attrs := event.GetWorkflowExecutionStartedEventAttributes()
+	if attrs == nil {
+		return errors.New("workflow history doesn't start with the workflow starting")
+	}
+
+	dataConverter := a.DataConverter
+	if dataConverter == nil {
+		dataConverter = converter.GetDefaultDataConverter()
+	}
+	workflowType := attrs.GetWorkflowType().GetName()
+	var args []interface{}
+	switch workflowType {
+	case "CDCFlowWorkflow":
+		var cfg *protos.FlowConnectionConfigs
+		// the state is passed on as is, the workflow decodes it
+		var state json.RawMessage
+		if err := dataConverter.FromPayloads(attrs.GetInput(), &cfg, &state); err != nil {
+			return fmt.Errorf("failed to decode workflow input: %w", err)
+		}
+		if err := a.releaseReplicationSlot(ctx, cfg); err != nil {
+			return err
+		}
+		args = []interface{}{cfg, state}
+	case "QRepFlowWorkflow", "XminFlowWorkflow":
+		var cfg *protos.QRepConfig
+		var state *protos.QRepFlowState
+		if err := dataConverter.FromPayloads(attrs.GetInput(), &cfg, &state); err != nil {
+			return fmt.Errorf("failed to decode workflow input: %w", err)
+		}
+		args = []interface{}{cfg, state}
+	default:
+		return fmt.Errorf("workflows of type %s can't be restarted", workflowType)
+	}
+
+	_, err = a.TemporalClient.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: attrs.GetTaskQueue().GetName(),
+		SearchAttributes: map[string]interface{}{
+			shared.MirrorNameSearchAttribute: flowName,
+		},
+	}, workflowType, args...)
+	if err != nil {
+		return fmt.Errorf("failed to restart workflow: %w", err)
+	}
+	return nil
+}
+
+// releaseReplicationSlot frees the slot of a CDC mirror from connections of its failed run
+func (a *FlowableActivity) releaseReplicationSlot(ctx context.Context, cfg *protos.FlowConnectionConfigs) error {
+	srcConn, err := connectors.GetConnectorAs[connectors.SlotReleaseConnector](ctx, cfg.Source)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcConn)
+
+	slotName := "peerflow_slot_" + cfg.FlowJobName
+	if cfg.ReplicationSlotName != "" {
+		slotName = cfg.ReplicationSlotName
+	}
+	return srcConn.ReleaseReplicationSlot(ctx, slotName)
+}
+
+// workflowFailure returns the message a failed workflow run failed with, for alerts
+func (a *FlowableActivity) workflowFailure(ctx context.Context, workflowID string, runID string) string {
+	iter := a.TemporalClient.GetWorkflowHistory(ctx, workflowID, runID, false, enums.HISTORY_EVENT_FILTER_TYPE_CLOSE_EVENT)
+	if !iter.HasNext() {
+		return "unknown failure"
+	}
+	event, err := iter.Next()
+	if err != nil {
+		return "unknown failure"
+	}
+	if attrs := event.GetWorkflowExecutionFailedEventAttributes(); attrs != nil {
+		return attrs.GetFailure().GetMessage()
+	} else if event.GetWorkflowExecutionTimedOutEventAttributes() != nil {
+		return "workflow timed out"
+	}
+	return "unknown failure"
+}
@@ -0,0 +1,104 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/log"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// qrepCheckpoints records which partitions of a mirror have been synced, so a retried batch or xmin run
+// resumes after what it synced before failing. The catalog records them for every destination.
+type qrepCheckpoints interface {
+	GetQrepPartition(ctx context.Context, jobName string, partitionID string) (*protos.QRepPartition, error)
+	FinishQrepPartition(ctx context.Context, partition *protos.QRepPartition, jobName string, startTime time.Time) error
+}
+
+// destinationCheckpoints are the catalog's checkpoints along with the partitions a destination marked synced itself.
+// A partition is synced if either has it: the catalog is only written once the destination committed the partition,
+// a crash in between leaves the destination's mark as the one record of it.
+type destinationCheckpoints struct {
+	qrepCheckpoints
+	destination destinationPartitionMarks
+	config      *protos.QRepConfig
+}
+
+type destinationPartitionMarks interface {
+	IsQRepPartitionSynced(ctx context.Context, config *protos.QRepConfig, partitionID string) (bool, error)
+}
+
+func (c *destinationCheckpoints) GetQrepPartition(ctx context.Context, jobName string, partitionID string) (*protos.QRepPartition, error) {
+	synced, err := c.qrepCheckpoints.GetQrepPartition(ctx, jobName, partitionID)
+	if err != nil || synced != nil {
+		return synced, err
+	}
+	marked, err := c.destination.IsQRepPartitionSynced(ctx, c.config, partitionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if partition %s is marked synced on the destination: %w", partitionID, err)
+	}
+	if !marked {
+		return nil, nil
+	}
+	return &protos.QRepPartition{PartitionId: partitionID}, nil
+}
+
+// replicateUncheckpointedPartitions replicates the partitions of a batch one after another, skipping those
+// already checkpointed and checkpointing each one once replicated. Returns the number of rows replicated.
+func replicateUncheckpointedPartitions(
+	ctx context.Context,
+	logger log.Logger,
+	checkpoints qrepCheckpoints,
+	flowJobName string,
+	partitions *protos.QRepPartitionBatch,
+	replicate func(i int, partition *protos.QRepPartition) (int, error),
+) (int64, error) {
+	var rowsSynced int64
+	for i, p := range partitions.Partitions {
+		synced, err := checkpoints.GetQrepPartition(ctx, flowJobName, p.PartitionId)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check if partition %s is synced: %w", p.PartitionId, err)
+		}
+		if synced != nil {
+			logger.Info(fmt.Sprintf("batch-%d - partition %s already replicated, skipping", partitions.BatchId, p.PartitionId))
+			continue
+		}
+
+		startTime := time.Now()
+		partitionRows, err := replicate(i, p)
+		if err != nil {
+			return 0, err
+		}
+		if err := checkpoints.FinishQrepPartition(ctx, p, flowJobName, startTime); err != nil {
+			return 0, fmt.Errorf("failed to checkpoint partition %s: %w", p.PartitionId, err)
+		}
+		rowsSynced += int64(partitionRows)
+	}
+	return rowsSynced, nil
+}
+
+// xminCheckpoint is what an xmin run checkpoints once synced, keyed by the run since every run
+// replicates a single partition, with the snapshot xmin the run synced up to as the end of its range.
+func xminCheckpoint(runUUID string, partition *protos.QRepPartition, snapshotXmin int64) *protos.QRepPartition {
+	return &protos.QRepPartition{
+		PartitionId: runUUID,
+		Range: &protos.PartitionRange{Range: &protos.PartitionRange_IntRange{IntRange: &protos.IntPartitionRange{
+			Start: partition.GetRange().GetIntRange().GetStart(),
+			End:   snapshotXmin,
+		}}},
+	}
+}
+
+// checkpointedXmin returns the snapshot xmin an xmin run synced up to, if the run was checkpointed.
+func checkpointedXmin(ctx context.Context, checkpoints qrepCheckpoints, flowJobName string, runUUID string) (int64, bool, error) {
+	synced, err := checkpoints.GetQrepPartition(ctx, flowJobName, runUUID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to check if xmin run %s is synced: %w", runUUID, err)
+	}
+	if synced == nil {
+		return 0, false, nil
+	}
+	return synced.GetRange().GetIntRange().GetEnd(), true, nil
+}
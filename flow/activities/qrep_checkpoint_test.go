@@ -0,0 +1,134 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/log"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type testCheckpoints struct {
+	synced    map[string]*protos.QRepPartition
+	finishErr error
+}
+
+func (c *testCheckpoints) GetQrepPartition(_ context.Context, _ string, partitionID string) (*protos.QRepPartition, error) {
+	return c.synced[partitionID], nil
+}
+
+func (c *testCheckpoints) FinishQrepPartition(_ context.Context, partition *protos.QRepPartition, _ string, _ time.Time) error {
+	if c.finishErr != nil {
+		return c.finishErr
+	}
+	c.synced[partition.PartitionId] = partition
+	return nil
+}
+
+func TestReplicateUncheckpointedPartitions(t *testing.T) {
+	logger := log.NewStructuredLogger(slog.Default())
+	batch := &protos.QRepPartitionBatch{BatchId: 1, Partitions: []*protos.QRepPartition{
+		{PartitionId: "p1"}, {PartitionId: "p2"}, {PartitionId: "p3"},
+	}}
+	checkpoints := &testCheckpoints{synced: map[string]*protos.QRepPartition{}}
+
+	// the batch fails on its second partition, only the first one is checkpointed
+	var replicated []string
+	_, err := replicateUncheckpointedPartitions(context.Background(), logger, checkpoints, "mirror", batch,
+		func(_ int, p *protos.QRepPartition) (int, error) {
+			if p.PartitionId == "p2" {
+				return 0, errors.New("destination unavailable")
+			}
+			replicated = append(replicated, p.PartitionId)
+			return 10, nil
+		})
+	require.Error(t, err)
+	require.Equal(t, []string{"p1"}, replicated)
+	require.Contains(t, checkpoints.synced, "p1")
+	require.NotContains(t, checkpoints.synced, "p2")
+
+	// the retried batch resumes at the partition which failed, only counting the rows it replicated
+	replicated = nil
+	var indexes []int
+	rows, err := replicateUncheckpointedPartitions(context.Background(), logger, checkpoints, "mirror", batch,
+		func(i int, p *protos.QRepPartition) (int, error) {
+			replicated = append(replicated, p.PartitionId)
+			indexes = append(indexes, i)
+			return 10, nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), rows)
+	require.Equal(t, []string{"p2", "p3"}, replicated)
+	require.Equal(t, []int{1, 2}, indexes)
+	require.Len(t, checkpoints.synced, 3)
+
+	// a partition which can't be checkpointed fails the batch, to be replicated again on retry
+	checkpoints = &testCheckpoints{synced: map[string]*protos.QRepPartition{}, finishErr: errors.New("catalog down")}
+	_, err = replicateUncheckpointedPartitions(context.Background(), logger, checkpoints, "mirror", batch,
+		func(int, *protos.QRepPartition) (int, error) { return 10, nil })
+	require.ErrorContains(t, err, "p1")
+	require.Empty(t, checkpoints.synced)
+}
+
+type testPartitionMarks map[string]bool
+
+func (m testPartitionMarks) IsQRepPartitionSynced(_ context.Context, _ *protos.QRepConfig, partitionID string) (bool, error) {
+	return m[partitionID], nil
+}
+
+func TestReplicateDestinationCheckpointedPartitions(t *testing.T) {
+	logger := log.NewStructuredLogger(slog.Default())
+	batch := &protos.QRepPartitionBatch{BatchId: 1, Partitions: []*protos.QRepPartition{
+		{PartitionId: "p1"}, {PartitionId: "p2"}, {PartitionId: "p3"},
+	}}
+	// p1 was committed on the destination but the activity failed before the catalog recorded it,
+	// p2 is only in the catalog
+	catalog := &testCheckpoints{synced: map[string]*protos.QRepPartition{"p2": {PartitionId: "p2"}}}
+	checkpoints := &destinationCheckpoints{
+		qrepCheckpoints: catalog,
+		destination:     testPartitionMarks{"p1": true},
+		config:          &protos.QRepConfig{FlowJobName: "mirror"},
+	}
+
+	var replicated []string
+	rows, err := replicateUncheckpointedPartitions(context.Background(), logger, checkpoints, "mirror", batch,
+		func(_ int, p *protos.QRepPartition) (int, error) {
+			replicated = append(replicated, p.PartitionId)
+			return 10, nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), rows)
+	require.Equal(t, []string{"p3"}, replicated, "partitions marked in either place are skipped")
+	require.Contains(t, catalog.synced, "p3")
+}
+
+func TestXminCheckpoint(t *testing.T) {
+	checkpoints := &testCheckpoints{synced: map[string]*protos.QRepPartition{}}
+
+	_, synced, err := checkpointedXmin(context.Background(), checkpoints, "mirror", "run-2")
+	require.NoError(t, err)
+	require.False(t, synced)
+
+	lastPartition := &protos.QRepPartition{
+		PartitionId: "run-1",
+		Range:       &protos.PartitionRange{Range: &protos.PartitionRange_IntRange{IntRange: &protos.IntPartitionRange{Start: 700}}},
+	}
+	require.NoError(t, checkpoints.FinishQrepPartition(context.Background(),
+		xminCheckpoint("run-2", lastPartition, 900), "mirror", time.Now()))
+
+	snapshotXmin, synced, err := checkpointedXmin(context.Background(), checkpoints, "mirror", "run-2")
+	require.NoError(t, err)
+	require.True(t, synced)
+	require.Equal(t, int64(900), snapshotXmin)
+	require.Equal(t, int64(700), checkpoints.synced["run-2"].GetRange().GetIntRange().GetStart())
+
+	// the first run of a mirror starts from a partition without a range
+	checkpoint := xminCheckpoint("run-1", &protos.QRepPartition{PartitionId: "not-applicable-partition"}, 500)
+	require.Equal(t, int64(0), checkpoint.GetRange().GetIntRange().GetStart())
+	require.Equal(t, int64(500), checkpoint.GetRange().GetIntRange().GetEnd())
+}
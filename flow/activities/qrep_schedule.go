@@ -0,0 +1,23 @@
+package activities
+
+import (
+	"context"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// GetQRepScheduleState returns the state a scheduled QRep mirror's last run left for the next run to resume from,
+// nil before the first run completes.
+func (a *FlowableActivity) GetQRepScheduleState(ctx context.Context, flowJobName string) (*protos.QRepFlowState, error) {
+	return catalog.GetQRepScheduleState(ctx, a.CatalogPool, flowJobName)
+}
+
+// SaveQRepScheduleState stores the state a run of a scheduled QRep mirror completed with.
+func (a *FlowableActivity) SaveQRepScheduleState(
+	ctx context.Context,
+	flowJobName string,
+	state *protos.QRepFlowState,
+) error {
+	return catalog.SaveQRepScheduleState(ctx, a.CatalogPool, flowJobName, state)
+}
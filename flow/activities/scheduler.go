@@ -0,0 +1,34 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/activity"
+
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// StaleLeaseRequests returns the ids of the lease requests whose workflow run is no longer running,
+// so the global scheduler hands on leases of mirrors which failed or were terminated while holding them.
+func (a *FlowableActivity) StaleLeaseRequests(ctx context.Context, requests []*model.LeaseRequest) ([]string, error) {
+	var stale []string
+	for _, request := range requests {
+		activity.RecordHeartbeat(ctx, "checking lease of "+request.WorkflowID)
+		desc, err := a.TemporalClient.DescribeWorkflowExecution(ctx, request.WorkflowID, request.RunID)
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) {
+			stale = append(stale, request.ID)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to describe workflow %s: %w", request.WorkflowID, err)
+		}
+		if desc.GetWorkflowExecutionInfo().GetStatus() != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			stale = append(stale, request.ID)
+		}
+	}
+	return stale, nil
+}
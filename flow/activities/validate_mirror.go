@@ -0,0 +1,202 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+
+	"go.temporal.io/sdk/activity"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/connectors"
+	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/model/rowfilter"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// ValidateMirrorTable compares a source table of a CDC mirror with its destination table. Rows of both are hashed
+// into chunks by primary key, and the chunks are compared by row count and sum of row hashes.
+// Only the columns the mirror replicates are compared, which leaves out the destination's soft delete and synced at
+// columns, and rows marked deleted at the destination aren't counted. Masked columns aren't compared, and neither
+// are columns of mirrors transforming their records. Rows changed while the tables are scanned may show as drift.
+// Tables which can't be validated are reported in the result's error, failing to scan a table fails the activity.
+func (a *FlowableActivity) ValidateMirrorTable(
+	ctx context.Context,
+	input *protos.ValidateMirrorInput,
+	tableMapping *protos.TableMapping,
+) (*protos.TableValidationResult, error) {
+	cfg := input.Config
+	ctx = context.WithValue(ctx, shared.FlowNameKey, cfg.FlowJobName)
+	logger := activity.GetLogger(ctx)
+	numChunks := max(int(input.NumChunks), 1)
+	result := &protos.TableValidationResult{
+		SourceTableIdentifier:      tableMapping.SourceTableIdentifier,
+		DestinationTableIdentifier: tableMapping.DestinationTableIdentifier,
+		NumChunks:                  uint32(numChunks),
+	}
+
+	srcConn, err := connectors.GetConnectorAs[*connpostgres.PostgresConnector](ctx, cfg.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcConn)
+
+	dstConn, err := connectors.GetConnectorAs[connectors.RowScanConnector](ctx, cfg.Destination)
+	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+		result.Error = fmt.Sprintf("validation is not supported for %s peers", cfg.Destination.Type)
+		result.ValidatedAt = timestamppb.Now()
+		return result, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	schemaOutput, err := srcConn.GetTableSchema(ctx, &protos.GetTableSchemaBatchInput{
+		PeerConnectionConfig: cfg.Source,
+		TableIdentifiers:     []string{tableMapping.SourceTableIdentifier},
+		FlowName:             cfg.FlowJobName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema of source table: %w", err)
+	}
+	tableSchema := schemaOutput.TableNameSchemaMapping[tableMapping.SourceTableIdentifier]
+	columns, pkeyIdx, err := validationColumns(cfg, tableMapping, tableSchema)
+	if err != nil {
+		result.Error = err.Error()
+		result.ValidatedAt = timestamppb.Now()
+		return result, nil
+	}
+
+	// the source is scanned with the columns its row filter depends on, for rows it leaves out
+	srcColumns := columns
+	var filter *rowfilter.Filter
+	if tableMapping.RowFilter != "" {
+		filter, err = rowfilter.Parse(tableMapping.RowFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse row filter of table %s: %w", tableMapping.SourceTableIdentifier, err)
+		}
+		for _, column := range filter.Columns() {
+			if !slices.Contains(srcColumns, column) {
+				srcColumns = append(slices.Clip(srcColumns), column)
+			}
+		}
+	}
+
+	var scanned atomic.Int64
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return fmt.Sprintf("validating table %s, %d rows scanned", tableMapping.SourceTableIdentifier, scanned.Load())
+	})
+	defer shutdown()
+
+	keyOf := func(row []qvalue.QValue) []qvalue.QValue {
+		key := make([]qvalue.QValue, 0, len(pkeyIdx))
+		for _, idx := range pkeyIdx {
+			key = append(key, row[idx])
+		}
+		return key
+	}
+
+	srcChunks := utils.NewKeyBuckets(numChunks)
+	if err := srcConn.ScanRows(ctx, tableMapping.SourceTableIdentifier, srcColumns, "", func(row []qvalue.QValue) error {
+		scanned.Add(1)
+		if filter != nil {
+			match, err := filter.Match(func(column string) (any, bool) {
+				idx := slices.Index(srcColumns, column)
+				if idx == -1 {
+					return nil, false
+				}
+				return row[idx].Value(), true
+			})
+			if err != nil {
+				return fmt.Errorf("error evaluating row filter %s: %w", filter, err)
+			} else if !match {
+				return nil
+			}
+		}
+		srcChunks.AddRow(keyOf(row), row[:len(columns)])
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to scan source table %s: %w", tableMapping.SourceTableIdentifier, err)
+	}
+
+	softDeleteColName := ""
+	if cfg.SoftDelete {
+		softDeleteColName = cfg.SoftDeleteColName
+	}
+	dstChunks := utils.NewKeyBuckets(numChunks)
+	if err := dstConn.ScanRows(ctx, tableMapping.DestinationTableIdentifier, columns, softDeleteColName,
+		func(row []qvalue.QValue) error {
+			scanned.Add(1)
+			dstChunks.AddRow(keyOf(row), row)
+			return nil
+		},
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan destination table %s: %w", tableMapping.DestinationTableIdentifier, err)
+	}
+
+	result.SourceRows = srcChunks.Count()
+	result.DestinationRows = dstChunks.Count()
+	result.MismatchedChunks = uint32(len(srcChunks.Mismatched(dstChunks)))
+	result.ValidatedAt = timestamppb.Now()
+	logger.Info("validated table", slog.String("table", tableMapping.SourceTableIdentifier),
+		slog.Int64("sourceRows", result.SourceRows), slog.Int64("destinationRows", result.DestinationRows),
+		slog.Int("mismatchedChunks", int(result.MismatchedChunks)))
+	return result, nil
+}
+
+// validationColumns returns the columns of a table a validation compares, and the positions of its primary key
+// among them. Errors when the table can't be validated.
+func validationColumns(
+	cfg *protos.FlowConnectionConfigs,
+	tableMapping *protos.TableMapping,
+	tableSchema *protos.TableSchema,
+) ([]string, []int, error) {
+	if tableSchema == nil || len(tableSchema.PrimaryKeyColumns) == 0 {
+		return nil, nil, fmt.Errorf("table %s has no primary key to chunk rows by", tableMapping.SourceTableIdentifier)
+	}
+
+	var columns []string
+	if cfg.TransformScript != "" {
+		// transformed values can't be compared with the source's, only which rows are there
+		columns = tableSchema.PrimaryKeyColumns
+	} else {
+		columns = make([]string, 0, len(tableSchema.Columns))
+		for _, column := range tableSchema.Columns {
+			masked := slices.ContainsFunc(tableMapping.Masks, func(mask *protos.ColumnMask) bool {
+				return mask.Column == column.Name
+			})
+			if !masked && !model.ColumnExcluded(tableMapping, column.Name) {
+				columns = append(columns, column.Name)
+			}
+		}
+	}
+
+	pkeyIdx := make([]int, 0, len(tableSchema.PrimaryKeyColumns))
+	for _, pkeyCol := range tableSchema.PrimaryKeyColumns {
+		idx := slices.Index(columns, pkeyCol)
+		if idx == -1 {
+			return nil, nil, fmt.Errorf("primary key column %s of table %s isn't replicated as is",
+				pkeyCol, tableMapping.SourceTableIdentifier)
+		}
+		pkeyIdx = append(pkeyIdx, idx)
+	}
+	return columns, pkeyIdx, nil
+}
+
+// RecordTableValidation stores the drift a mirror validation found for a table in the catalog.
+func (a *FlowableActivity) RecordTableValidation(
+	ctx context.Context,
+	validationID string,
+	flowJobName string,
+	result *protos.TableValidationResult,
+) error {
+	return catalog.SaveTableValidation(ctx, a.CatalogPool, validationID, flowJobName, result)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared/alerting"
+)
+
+func (h *FlowRequestHandler) ListAlertConfigs(
+	ctx context.Context,
+	req *protos.ListAlertConfigsRequest,
+) (*protos.ListAlertConfigsResponse, error) {
+	rows, err := h.pool.Query(ctx,
+		"SELECT id,service_type,service_config::text FROM peerdb_stats.alerting_config ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert configs: %w", err)
+	}
+	configs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.AlertConfig, error) {
+		var config protos.AlertConfig
+		if err := row.Scan(&config.Id, &config.ServiceType, &config.ServiceConfig); err != nil {
+			return nil, err
+		}
+		// secrets are left blank, PostAlertConfig keeps them when an update leaves them so
+		serviceConfig, err := alerting.RedactServiceConfig(config.ServiceType, []byte(config.ServiceConfig))
+		if err != nil {
+			return nil, err
+		}
+		config.ServiceConfig = string(serviceConfig)
+		return &config, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert configs: %w", err)
+	}
+	return &protos.ListAlertConfigsResponse{Configs: configs}, nil
+}
+
+// serviceConfigWithStoredSecrets returns the service config of an alert config with the secrets it leaves blank,
+// as ListAlertConfigs does, taken from the stored config it updates.
+func (h *FlowRequestHandler) serviceConfigWithStoredSecrets(ctx context.Context, config *protos.AlertConfig) ([]byte, error) {
+	if config.Id == 0 {
+		return []byte(config.ServiceConfig), nil
+	}
+
+	var storedServiceType, storedServiceConfig string
+	if err := h.pool.QueryRow(ctx,
+		"SELECT service_type,service_config::text FROM peerdb_stats.alerting_config WHERE id=$1", config.Id,
+	).Scan(&storedServiceType, &storedServiceConfig); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("alert config %d does not exist", config.Id)
+		}
+		return nil, fmt.Errorf("failed to read alert config: %w", err)
+	}
+	if storedServiceType != config.ServiceType {
+		return []byte(config.ServiceConfig), nil
+	}
+	storedConfig, err := alerting.DecryptServiceConfig(ctx, storedServiceType, []byte(storedServiceConfig))
+	if err != nil {
+		return nil, err
+	}
+	return alerting.KeepServiceConfigSecrets(config.ServiceType, []byte(config.ServiceConfig), storedConfig)
+}
+
+// PostAlertConfig creates or updates an alert config, once it's been checked to make a valid sender.
+// Secrets are stored encrypted, and those an update leaves blank keep their stored value.
+func (h *FlowRequestHandler) PostAlertConfig(
+	ctx context.Context,
+	req *protos.PostAlertConfigRequest,
+) (*protos.PostAlertConfigResponse, error) {
+	config := req.Config
+	if config == nil {
+		return nil, errors.New("config is required")
+	}
+	serviceConfig, err := h.serviceConfigWithStoredSecrets(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := alerting.NewAlertSender(config.ServiceType, serviceConfig); err != nil {
+		return nil, fmt.Errorf("invalid alert config: %w", err)
+	}
+	encryptedConfig, err := alerting.EncryptServiceConfig(ctx, config.ServiceType, serviceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt alert config: %w", err)
+	}
+
+	if config.Id == 0 {
+		var id int64
+		if err := h.pool.QueryRow(ctx,
+			"INSERT INTO peerdb_stats.alerting_config(service_type,service_config) VALUES($1,$2) RETURNING id",
+			config.ServiceType, string(encryptedConfig),
+		).Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to create alert config: %w", err)
+		}
+		return &protos.PostAlertConfigResponse{Id: id}, nil
+	}
+
+	tag, err := h.pool.Exec(ctx,
+		"UPDATE peerdb_stats.alerting_config SET service_type=$2,service_config=$3 WHERE id=$1",
+		config.Id, config.ServiceType, string(encryptedConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update alert config: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("alert config %d does not exist", config.Id)
+	}
+	return &protos.PostAlertConfigResponse{Id: config.Id}, nil
+}
+
+func (h *FlowRequestHandler) DeleteAlertConfig(
+	ctx context.Context,
+	req *protos.DeleteAlertConfigRequest,
+) (*protos.DeleteAlertConfigResponse, error) {
+	tag, err := h.pool.Exec(ctx, "DELETE FROM peerdb_stats.alerting_config WHERE id=$1", req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete alert config: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, fmt.Errorf("alert config %d does not exist", req.Id)
+	}
+	return &protos.DeleteAlertConfigResponse{}, nil
+}
+
+// TestAlertConfig sends a test alert with a config, which doesn't have to be saved, to check it reaches its service.
+// Secrets a saved config leaves blank are taken from the stored config, as in PostAlertConfig.
+func (h *FlowRequestHandler) TestAlertConfig(
+	ctx context.Context,
+	req *protos.TestAlertConfigRequest,
+) (*protos.TestAlertConfigResponse, error) {
+	config := req.Config
+	if config == nil {
+		return nil, errors.New("config is required")
+	}
+	serviceConfig, err := h.serviceConfigWithStoredSecrets(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := alerting.NewAlertSender(config.ServiceType, serviceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert config: %w", err)
+	}
+	if err := sender.SendAlert(ctx, "test-alert", "This is a test alert from PeerDB, alerts will be sent here."); err != nil {
+		return nil, err
+	}
+	return &protos.TestAlertConfigResponse{}, nil
+}
@@ -9,22 +9,30 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"runtime/debug"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 
 	utils "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/federation"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
 	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/PeerDB-io/peer-flow/shared/alerting"
+	"github.com/PeerDB-io/peer-flow/shared/payloadcodec"
 	peerflow "github.com/PeerDB-io/peer-flow/workflows"
 )
 
@@ -37,6 +45,24 @@ type APIServerParams struct {
 	TemporalKey       string
 }
 
+// recoveryInterceptor turns a panic in a call into an Internal error of that call,
+// so that one bad request doesn't bring down the server.
+func recoveryInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("panic in grpc call", slog.String("method", info.FullMethod),
+				slog.Any("panic", r), slog.String("stack", string(debug.Stack())))
+			res, err = nil, status.Errorf(codes.Internal, "panic in %s: %v", info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
 // setupGRPCGatewayServer sets up the grpc-gateway mux
 func setupGRPCGatewayServer(args *APIServerParams) (*http.Server, error) {
 	conn, err := grpc.DialContext(
@@ -49,7 +75,16 @@ func setupGRPCGatewayServer(args *APIServerParams) (*http.Server, error) {
 		return nil, fmt.Errorf("unable to dial grpc server: %w", err)
 	}
 
-	gwmux := runtime.NewServeMux()
+	// let HTTP clients of the federation API pick a region, and name themselves for the audit log, with plain headers
+	gwmux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+		if strings.EqualFold(key, federation.RegionMetadataKey) {
+			return federation.RegionMetadataKey, true
+		}
+		if strings.EqualFold(key, auditUserMetadataKey) {
+			return auditUserMetadataKey, true
+		}
+		return runtime.DefaultHeaderMatcher(key)
+	}))
 	err = protos.RegisterFlowServiceHandler(context.Background(), gwmux, conn)
 	if err != nil {
 		return nil, fmt.Errorf("unable to register gateway: %w", err)
@@ -63,30 +98,52 @@ func setupGRPCGatewayServer(args *APIServerParams) (*http.Server, error) {
 	return server, nil
 }
 
+// killExistingScheduleFlows cancels the scheduler flows, returning the run id of the running global scheduler if any
 func killExistingScheduleFlows(
 	ctx context.Context,
 	tc client.Client,
 	namespace string,
 	taskQueue string,
-) error {
+) (string, error) {
 	listRes, err := tc.ListWorkflow(ctx,
 		&workflowservice.ListWorkflowExecutionsRequest{
 			Namespace: namespace,
 			Query:     "WorkflowType = 'GlobalScheduleManagerWorkflow' AND TaskQueue = '" + taskQueue + "'",
 		})
 	if err != nil {
-		return fmt.Errorf("unable to list workflows: %w", err)
+		return "", fmt.Errorf("unable to list workflows: %w", err)
 	}
 	slog.Info("Requesting cancellation of pre-existing scheduler flows")
+	var schedulerRunID string
 	for _, workflow := range listRes.Executions {
 		slog.Info("Cancelling workflow", slog.String("workflowId", workflow.Execution.WorkflowId))
 		err := tc.CancelWorkflow(ctx,
 			workflow.Execution.WorkflowId, workflow.Execution.RunId)
 		if err != nil && err.Error() != "workflow execution already completed" {
-			return fmt.Errorf("unable to cancel workflow: %w", err)
+			return "", fmt.Errorf("unable to cancel workflow: %w", err)
+		}
+		if workflow.Execution.WorkflowId == shared.SchedulerWorkflowID &&
+			workflow.Status == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+			schedulerRunID = workflow.Execution.RunId
 		}
 	}
-	return nil
+	return schedulerRunID, nil
+}
+
+// awaitSchedulerState waits for the canceled global scheduler to return the leases it granted and the requests
+// waiting for one, so the scheduler replacing it doesn't grant leases again while their holders still run.
+func awaitSchedulerState(ctx context.Context, tc client.Client, runID string) *peerflow.SchedulerState {
+	if runID == "" {
+		return nil
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var state *peerflow.SchedulerState
+	if err := tc.GetWorkflow(waitCtx, shared.SchedulerWorkflowID, runID).Get(waitCtx, &state); err != nil {
+		slog.Warn("unable to get leases of the previous scheduler, starting without them", slog.Any("error", err))
+		return nil
+	}
+	return state
 }
 
 func APIMain(ctx context.Context, args *APIServerParams) error {
@@ -95,6 +152,11 @@ func APIMain(ctx context.Context, args *APIServerParams) error {
 		Namespace: args.TemporalNamespace,
 		Logger:    slog.New(logger.NewHandler(slog.NewJSONHandler(os.Stdout, nil))),
 	}
+	dataConverter, err := payloadcodec.NewDataConverterFromEnv(ctx)
+	if err != nil {
+		return err
+	}
+	clientOptions.DataConverter = dataConverter
 	if args.TemporalCert != "" && args.TemporalKey != "" {
 		slog.Info("Using temporal certificate/key for authentication")
 
@@ -117,40 +179,61 @@ func APIMain(ctx context.Context, args *APIServerParams) error {
 		return fmt.Errorf("unable to create Temporal client: %w", err)
 	}
 
-	grpcServer := grpc.NewServer()
-
 	catalogConn, err := utils.GetCatalogConnectionPoolFromEnv(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to get catalog connection pool: %w", err)
 	}
 
+	// peers are brought under the current encryption key on startup, so older keys can be retired after a restart
+	rotatedPeers, err := utils.RotatePeerEncryption(ctx, catalogConn)
+	if err != nil {
+		return fmt.Errorf("unable to rotate peer encryption: %w", err)
+	}
+	if rotatedPeers > 0 {
+		slog.Info("encrypted peers with the current catalog encryption key", slog.Int("peers", rotatedPeers))
+	}
+	rotatedAlertConfigs, err := alerting.RotateAlertConfigEncryption(ctx, catalogConn)
+	if err != nil {
+		return fmt.Errorf("unable to rotate alert config encryption: %w", err)
+	}
+	if rotatedAlertConfigs > 0 {
+		slog.Info("encrypted alert configs with the current catalog encryption key",
+			slog.Int("alertConfigs", rotatedAlertConfigs))
+	}
+
 	taskQueue, err := shared.GetPeerFlowTaskQueueName(shared.PeerFlowTaskQueueID)
 	if err != nil {
 		return err
 	}
 
 	flowHandler := NewFlowRequestHandler(tc, catalogConn, taskQueue)
+	go flowHandler.revalidatePeers(ctx)
 
-	err = killExistingScheduleFlows(ctx, tc, args.TemporalNamespace, taskQueue)
+	schedulerRunID, err := killExistingScheduleFlows(ctx, tc, args.TemporalNamespace, taskQueue)
 	if err != nil {
 		return fmt.Errorf("unable to kill existing scheduler flows: %w", err)
 	}
+	schedulerState := awaitSchedulerState(ctx, tc, schedulerRunID)
 
-	workflowID := fmt.Sprintf("scheduler-%s", uuid.New())
+	// mirrors find the scheduler by its workflow id to ask it for leases,
+	// a scheduler which didn't wind down in time after being canceled is terminated to start the new one
 	workflowOptions := client.StartWorkflowOptions{
-		ID:        workflowID,
-		TaskQueue: taskQueue,
+		ID:                    shared.SchedulerWorkflowID,
+		TaskQueue:             taskQueue,
+		WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_TERMINATE_IF_RUNNING,
 	}
 
 	_, err = flowHandler.temporalClient.ExecuteWorkflow(
 		ctx,
 		workflowOptions,
 		peerflow.GlobalScheduleManagerWorkflow,
+		schedulerState,
 	)
 	if err != nil {
 		return fmt.Errorf("unable to start scheduler workflow: %w", err)
 	}
 
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(recoveryInterceptor, flowHandler.auditInterceptor))
 	protos.RegisterFlowServiceServer(grpcServer, flowHandler)
 	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
 	reflection.Register(grpcServer)
@@ -179,6 +262,22 @@ func APIMain(ctx context.Context, args *APIServerParams) error {
 		}
 	}()
 
+	// the codec server decrypts payloads, so it's kept off the public gateway on a listener of its own
+	codecServer, err := payloadcodec.NewCodecServerFromEnv(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to set up codec server: %w", err)
+	}
+	if codecServer != nil {
+		codecAddr := peerdbenv.PeerDBTemporalCodecServerAddress()
+		slog.Info("Starting Temporal codec server on " + codecAddr)
+		go func() {
+			server := &http.Server{Addr: codecAddr, Handler: codecServer, ReadHeaderTimeout: 5 * time.Minute}
+			if err := server.ListenAndServe(); err != nil {
+				log.Fatalf("failed to serve codec server: %v", err)
+			}
+		}()
+	}
+
 	<-ctx.Done()
 
 	grpcServer.GracefulStop()
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/peerdb_route.FlowService/CompareSample"}
+	_, err := recoveryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		panic("interface conversion: interface {} is nil, not string")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	res, err := recoveryInterceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ok", res)
+}
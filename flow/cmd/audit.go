@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// auditUserMetadataKey is the gRPC metadata key, or HTTP header through the gateway, naming the caller of a call.
+const auditUserMetadataKey = "x-peerdb-user"
+
+const (
+	defaultAuditEventsLimit = 100
+	redactedValue           = "REDACTED"
+)
+
+// auditedMethods are the FlowService methods changing peers, mirrors, alerting or settings, which are recorded in the audit log.
+var auditedMethods = map[string]struct{}{
+	"CreatePeer":             {},
+	"DropPeer":               {},
+	"CreateCDCFlow":          {},
+	"CreateQRepFlow":         {},
+	"ShutdownFlow":           {},
+	"FlowStateChange":        {},
+	"TriggerNormalize":       {},
+	"ApproveSchemaDeltas":    {},
+	"ApproveSchemaChange":    {},
+	"CreateMirrorGroup":      {},
+	"MirrorGroupStateChange": {},
+	"PostAlertConfig":        {},
+	"DeleteAlertConfig":      {},
+	"PostSetting":            {},
+}
+
+// auditInterceptor records audited calls, with their caller, redacted request and result, once they're done.
+// Failing to record a call is logged, the call's own result is returned regardless.
+func (h *FlowRequestHandler) auditInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	method, isFlowService := strings.CutPrefix(info.FullMethod, "/"+protos.FlowService_ServiceDesc.ServiceName+"/")
+	if _, audited := auditedMethods[method]; !isFlowService || !audited {
+		return handler(ctx, req)
+	}
+
+	res, err := handler(ctx, req)
+	// the call may have been canceled, its audit event is still recorded
+	if auditErr := h.recordAuditEvent(context.WithoutCancel(ctx), method, req, err); auditErr != nil {
+		slog.Error("failed to record audit event", slog.String("method", method), slog.Any("error", auditErr))
+	}
+	return res, err
+}
+
+func (h *FlowRequestHandler) recordAuditEvent(ctx context.Context, method string, req any, callErr error) error {
+	caller, callerAddress := auditCaller(ctx)
+
+	var request *string
+	if msg, ok := req.(proto.Message); ok {
+		redacted := proto.Clone(msg)
+		redactSecrets(redacted.ProtoReflect())
+		requestJSON, err := protojson.Marshal(redacted)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		requestStr := string(requestJSON)
+		request = &requestStr
+	}
+
+	var errMsg *string
+	if callErr != nil {
+		errStr := callErr.Error()
+		errMsg = &errStr
+	}
+
+	if _, err := h.pool.Exec(ctx,
+		`INSERT INTO peerdb_stats.audit_events(method,caller,caller_address,request,success,error)
+		 VALUES($1,$2,$3,$4,$5,$6)`,
+		method, caller, callerAddress, request, callErr == nil, errMsg,
+	); err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return nil
+}
+
+// auditCaller returns who made a call, as named by its metadata, and where from.
+// Calls through the gateway are from the address the gateway forwarded.
+func auditCaller(ctx context.Context) (string, string) {
+	var caller, address string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if users := md.Get(auditUserMetadataKey); len(users) > 0 {
+			caller = users[0]
+		}
+		if forwardedFor := md.Get("x-forwarded-for"); len(forwardedFor) > 0 {
+			address = forwardedFor[0]
+		}
+	}
+	if address == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			address = p.Addr.String()
+		}
+	}
+	return caller, address
+}
+
+// isSecretField returns whether a field holds credentials, going by its name.
+// Alert service configs are redacted as a whole, they hold webhook URLs and API keys.
+func isSecretField(name protoreflect.Name) bool {
+	lower := strings.ToLower(string(name))
+	return lower == "service_config" ||
+		strings.Contains(lower, "password") ||
+		strings.Contains(lower, "secret") ||
+		strings.Contains(lower, "private_key") ||
+		strings.Contains(lower, "client_key") ||
+		strings.Contains(lower, "token")
+}
+
+// redactSecrets replaces the values of secret fields of msg, and of the messages it holds, in place.
+func redactSecrets(msg protoreflect.Message) {
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					redactSecrets(mv.Message())
+					return true
+				})
+			}
+		case fd.IsList():
+			if fd.Kind() == protoreflect.MessageKind {
+				list := v.List()
+				for i := range list.Len() {
+					redactSecrets(list.Get(i).Message())
+				}
+			}
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			redactSecrets(v.Message())
+		case isSecretField(fd.Name()) && fd.Kind() == protoreflect.StringKind:
+			msg.Set(fd, protoreflect.ValueOfString(redactedValue))
+		case isSecretField(fd.Name()) && fd.Kind() == protoreflect.BytesKind:
+			msg.Set(fd, protoreflect.ValueOfBytes([]byte(redactedValue)))
+		}
+		return true
+	})
+}
+
+// ListAuditEvents returns the audit events of mutating API calls in a time range, newest first.
+func (h *FlowRequestHandler) ListAuditEvents(
+	ctx context.Context,
+	req *protos.ListAuditEventsRequest,
+) (*protos.ListAuditEventsResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAuditEventsLimit
+	}
+	endTime := time.Now()
+	if req.EndTime != nil {
+		endTime = req.EndTime.AsTime()
+	}
+
+	rows, err := h.pool.Query(ctx, `SELECT id,created_at,method,caller,coalesce(caller_address,''),
+	 coalesce(request::text,''),success,coalesce(error,'') FROM peerdb_stats.audit_events
+	 WHERE ($1='' OR method=$1) AND ($2='' OR caller=$2) AND created_at>=$3 AND created_at<=$4
+	 ORDER BY created_at DESC LIMIT $5`,
+		req.Method, req.Caller, req.StartTime.AsTime(), endTime, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit events: %w", err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.AuditEvent, error) {
+		var event protos.AuditEvent
+		var createdAt time.Time
+		if err := row.Scan(&event.Id, &createdAt, &event.Method, &event.Caller, &event.CallerAddress,
+			&event.Request, &event.Success, &event.Error); err != nil {
+			return nil, err
+		}
+		event.CreatedAt = timestamppb.New(createdAt)
+		return &event, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit events: %w", err)
+	}
+	return &protos.ListAuditEventsResponse{Events: events}, nil
+}
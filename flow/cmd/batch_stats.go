@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// GetCDCBatchStats returns sync stats of a CDC mirror over a time range, read per batch or from rollups
+// depending on how long the range is and which stats are still kept for it.
+func (h *FlowRequestHandler) GetCDCBatchStats(
+	ctx context.Context,
+	req *protos.CDCBatchStatsRequest,
+) (*protos.CDCBatchStatsResponse, error) {
+	if req.StartTime == nil {
+		return nil, errors.New("start_time is required")
+	}
+	now := time.Now()
+	start := req.StartTime.AsTime()
+	end := now
+	if req.EndTime != nil {
+		end = req.EndTime.AsTime()
+	}
+	if !start.Before(end) {
+		return nil, errors.New("start_time must be before end_time")
+	}
+
+	granularity := req.Granularity
+	if granularity == protos.StatsGranularity_STATS_GRANULARITY_AUTO {
+		granularity = monitoring.ChooseStatsGranularity(start, end, now, monitoring.StatsRetentionFromEnv())
+	}
+	buckets, tables, err := monitoring.GetCDCBatchStats(ctx, h.pool, req.FlowJobName, start, end, granularity)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.CDCBatchStatsResponse{
+		Granularity: granularity,
+		Buckets:     buckets,
+		Tables:      tables,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/PeerDB-io/peer-flow/connectors"
+	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+const (
+	defaultCompareSampleSize = 10
+	maxCompareSampleSize     = 1000
+)
+
+// CompareSample picks random rows of a mirrored table at the source, fetches the rows with the same primary keys
+// from the destination and reports the fields that differ. It is a quick spot check, not a full validation.
+func (h *FlowRequestHandler) CompareSample(
+	ctx context.Context,
+	req *protos.CompareSampleRequest,
+) (*protos.CompareSampleResponse, error) {
+	slog.Info("Compare sample endpoint called",
+		slog.String(string(shared.FlowNameKey), req.FlowJobName), slog.String("table", req.SourceTableIdentifier))
+
+	config, err := h.getFlowConfigFromCatalog(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+	var tableMapping *protos.TableMapping
+	for _, mapping := range config.TableMappings {
+		if mapping.SourceTableIdentifier == req.SourceTableIdentifier {
+			tableMapping = mapping
+			break
+		}
+	}
+	if tableMapping == nil {
+		return nil, fmt.Errorf("table %s is not part of mirror %s", req.SourceTableIdentifier, req.FlowJobName)
+	}
+
+	sampleSize := int(req.SampleSize)
+	if sampleSize == 0 {
+		sampleSize = defaultCompareSampleSize
+	} else if sampleSize > maxCompareSampleSize {
+		return nil, fmt.Errorf("sample size must be at most %d", maxCompareSampleSize)
+	}
+
+	srcConn, err := connectors.GetConnectorAs[*connpostgres.PostgresConnector](ctx, config.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, srcConn)
+
+	dstConn, err := connectors.GetConnectorAs[connectors.RowSampleConnector](ctx, config.Destination)
+	if err != nil {
+		if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			return nil, fmt.Errorf("sample comparison is not supported for %s peers", config.Destination.Type)
+		}
+		return nil, fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	schemaOutput, err := srcConn.GetTableSchema(ctx, &protos.GetTableSchemaBatchInput{
+		PeerConnectionConfig: config.Source,
+		TableIdentifiers:     []string{req.SourceTableIdentifier},
+		FlowName:             req.FlowJobName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema of source table: %w", err)
+	}
+	tableSchema := schemaOutput.TableNameSchemaMapping[req.SourceTableIdentifier]
+	if tableSchema == nil || len(tableSchema.PrimaryKeyColumns) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key to match rows on", req.SourceTableIdentifier)
+	}
+
+	columns := make([]string, 0, len(tableSchema.Columns))
+	for _, column := range tableSchema.Columns {
+		if !model.ColumnExcluded(tableMapping, column.Name) {
+			columns = append(columns, column.Name)
+		}
+	}
+	pkeyIdx := make([]int, 0, len(tableSchema.PrimaryKeyColumns))
+	for _, pkeyCol := range tableSchema.PrimaryKeyColumns {
+		idx := slices.Index(columns, pkeyCol)
+		if idx == -1 {
+			return nil, fmt.Errorf("primary key column %s of table %s is excluded from the mirror", pkeyCol, req.SourceTableIdentifier)
+		}
+		pkeyIdx = append(pkeyIdx, idx)
+	}
+
+	srcRows, err := srcConn.SampleRows(ctx, req.SourceTableIdentifier, columns, sampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample source table: %w", err)
+	}
+	if len(srcRows.Records) == 0 {
+		return &protos.CompareSampleResponse{}, nil
+	}
+
+	keys := make([][]qvalue.QValue, 0, len(srcRows.Records))
+	for _, row := range srcRows.Records {
+		key := make([]qvalue.QValue, 0, len(pkeyIdx))
+		for _, idx := range pkeyIdx {
+			key = append(key, row[idx])
+		}
+		keys = append(keys, key)
+	}
+	dstRows, err := dstConn.GetRowsByPrimaryKey(ctx, tableMapping.DestinationTableIdentifier,
+		columns, tableSchema.PrimaryKeyColumns, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sampled rows from destination: %w", err)
+	}
+
+	var caseFoldColumns []string
+	if req.Canonicalization.GetCaseFoldCitext() {
+		caseFoldColumns, err = srcConn.CitextColumns(ctx, req.SourceTableIdentifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	canonicalizer := utils.NewSampleCanonicalizer(req.Canonicalization, caseFoldColumns)
+
+	diffs := utils.DiffSampleRows(columns, pkeyIdx, srcRows.Records, dstRows.Records, canonicalizer)
+	return &protos.CompareSampleResponse{
+		RowsSampled: uint32(len(srcRows.Records)),
+		RowsMatched: uint32(len(srcRows.Records) - len(diffs)),
+		Diffs:       diffs,
+	}, nil
+}
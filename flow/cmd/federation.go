@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/PeerDB-io/peer-flow/federation"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+type FederationServerParams struct {
+	Port        uint16
+	GatewayPort uint16
+}
+
+// FederationMain serves the FlowService API for several regional PeerDB deployments,
+// listing mirrors and peers across all of them and routing other calls to one region.
+func FederationMain(ctx context.Context, args *FederationServerParams) error {
+	addrs, err := federation.ParseRegions(peerdbenv.PeerDBFederationRegions())
+	if err != nil {
+		return fmt.Errorf("invalid PEERDB_FEDERATION_REGIONS: %w", err)
+	}
+	fed, err := federation.New(addrs, peerdbenv.PeerDBFederationDefaultRegion(), peerdbenv.PeerDBFederationTLS())
+	if err != nil {
+		return fmt.Errorf("unable to set up federation: %w", err)
+	}
+	defer fed.Close()
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(fed.UnaryInterceptor))
+	protos.RegisterFlowServiceServer(grpcServer, fed)
+	grpc_health_v1.RegisterHealthServer(grpcServer, health.NewServer())
+	reflection.Register(grpcServer)
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", args.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Starting federation API server on port %d for %d regions", args.Port, len(addrs)))
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("failed to serve: %v", err)
+		}
+	}()
+
+	gateway, err := setupGRPCGatewayServer(&APIServerParams{Port: args.Port, GatewayPort: args.GatewayPort})
+	if err != nil {
+		return fmt.Errorf("unable to setup gateway server: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Starting federation API gateway on port %d", args.GatewayPort))
+	go func() {
+		if err := gateway.ListenAndServe(); err != nil {
+			log.Fatalf("failed to serve http: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	grpcServer.GracefulStop()
+	slog.Info("Federation server has been shut down gracefully. Exiting...")
+
+	return nil
+}
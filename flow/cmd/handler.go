@@ -14,8 +14,12 @@ import (
 	"go.temporal.io/sdk/client"
 	"google.golang.org/protobuf/proto"
 
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+	"github.com/PeerDB-io/peer-flow/sdk"
 	"github.com/PeerDB-io/peer-flow/shared"
 	peerflow "github.com/PeerDB-io/peer-flow/workflows"
 )
@@ -25,6 +29,7 @@ type FlowRequestHandler struct {
 	temporalClient      client.Client
 	pool                *pgxpool.Pool
 	peerflowTaskQueueID string
+	peerValidations     *peerValidationCache
 	protos.UnimplementedFlowServiceServer
 }
 
@@ -33,6 +38,7 @@ func NewFlowRequestHandler(temporalClient client.Client, pool *pgxpool.Pool, tas
 		temporalClient:      temporalClient,
 		pool:                pool,
 		peerflowTaskQueueID: taskQueue,
+		peerValidations:     newPeerValidationCache(peerdbenv.PeerDBPeerValidationCacheTTL()),
 	}
 }
 
@@ -69,12 +75,16 @@ func (h *FlowRequestHandler) createCdcJobEntry(ctx context.Context,
 			req.ConnectionConfigs.Destination.Name, srcErr)
 	}
 
+	description := "Mirror created via GRPC"
+	if req.Metadata.GetDescription() != "" {
+		description = req.Metadata.Description
+	}
 	for _, v := range req.ConnectionConfigs.TableMappings {
 		_, err := h.pool.Exec(ctx, `
 		INSERT INTO flows (workflow_id, name, source_peer, destination_peer, description,
 		source_table_identifier, destination_table_identifier) VALUES ($1, $2, $3, $4, $5, $6, $7)
 		`, workflowID, req.ConnectionConfigs.FlowJobName, sourcePeerID, destinationPeerID,
-			"Mirror created via GRPC",
+			description,
 			schemaForTableIdentifier(v.SourceTableIdentifier, sourePeerType),
 			schemaForTableIdentifier(v.DestinationTableIdentifier, destinationPeerType))
 		if err != nil {
@@ -122,34 +132,36 @@ func (h *FlowRequestHandler) CreateCDCFlow(
 	ctx context.Context, req *protos.CreateCDCFlowRequest,
 ) (*protos.CreateCDCFlowResponse, error) {
 	cfg := req.ConnectionConfigs
-	_, validateErr := h.ValidateCDCMirror(ctx, req)
+	validateRes, validateErr := h.ValidateCDCMirror(ctx, req)
 	if validateErr != nil {
 		slog.Error("validate mirror error", slog.Any("error", validateErr))
 		return nil, fmt.Errorf("invalid mirror: %w", validateErr)
 	}
+	for _, warning := range validateRes.Warnings {
+		slog.Warn("validate mirror warning", slog.String("flowName", cfg.FlowJobName), slog.String("warning", warning))
+	}
+
+	taskQueue, err := h.mirrorTaskQueue(ctx, cfg.TaskQueue)
+	if err != nil {
+		slog.Error("unable to use task queue of mirror", slog.Any("error", err))
+		return nil, err
+	}
 
 	workflowID := fmt.Sprintf("%s-peerflow-%s", cfg.FlowJobName, uuid.New())
 	workflowOptions := client.StartWorkflowOptions{
 		ID:        workflowID,
-		TaskQueue: h.peerflowTaskQueueID,
+		TaskQueue: taskQueue,
 		SearchAttributes: map[string]interface{}{
 			shared.MirrorNameSearchAttribute: cfg.FlowJobName,
 		},
 	}
-
-	if req.ConnectionConfigs.SoftDeleteColName == "" {
-		req.ConnectionConfigs.SoftDeleteColName = "_PEERDB_IS_DELETED"
-	} else {
-		// make them all uppercase
-		req.ConnectionConfigs.SoftDeleteColName = strings.ToUpper(req.ConnectionConfigs.SoftDeleteColName)
+	if req.Metadata != nil {
+		workflowOptions.Memo = map[string]interface{}{
+			shared.MirrorMetadataMemoKey: req.Metadata,
+		}
 	}
 
-	if req.ConnectionConfigs.SyncedAtColName == "" {
-		req.ConnectionConfigs.SyncedAtColName = "_PEERDB_SYNCED_AT"
-	} else {
-		// make them all uppercase
-		req.ConnectionConfigs.SyncedAtColName = strings.ToUpper(req.ConnectionConfigs.SyncedAtColName)
-	}
+	sdk.ApplyCDCDefaults(cfg)
 
 	if req.CreateCatalogEntry {
 		err := h.createCdcJobEntry(ctx, req, workflowID)
@@ -159,12 +171,19 @@ func (h *FlowRequestHandler) CreateCDCFlow(
 		}
 	}
 
-	err := h.updateFlowConfigInCatalog(ctx, cfg)
+	err = h.updateFlowConfigInCatalog(ctx, cfg)
 	if err != nil {
 		slog.Error("unable to update flow config in catalog", slog.Any("error", err))
 		return nil, fmt.Errorf("unable to update flow config in catalog: %w", err)
 	}
 
+	if req.Metadata != nil {
+		if err := h.updateMirrorMetadataInCatalog(ctx, cfg.FlowJobName, req.Metadata); err != nil {
+			slog.Error("unable to update mirror metadata in catalog", slog.Any("error", err))
+			return nil, err
+		}
+	}
+
 	_, err = h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, peerflow.CDCFlowWorkflow, cfg, nil)
 	if err != nil {
 		slog.Error("unable to start PeerFlow workflow", slog.Any("error", err))
@@ -200,10 +219,46 @@ func (h *FlowRequestHandler) removeFlowEntryInCatalog(
 	ctx context.Context,
 	flowName string,
 ) error {
+	// fan-out destinations keep their raw table versions and normalized batches under names of their own
+	rawTableNames := []string{flowName}
+	if isCDC, err := h.isCDCFlow(ctx, flowName); err == nil && isCDC {
+		if config, err := h.getFlowConfigFromCatalog(ctx, flowName); err == nil {
+			for _, fanoutConfig := range model.FanoutConfigs(config) {
+				rawTableNames = append(rawTableNames, fanoutConfig.FlowJobName)
+			}
+		}
+	}
+
 	_, err := h.pool.Exec(ctx, "DELETE FROM flows WHERE name = $1", flowName)
 	if err != nil {
 		return fmt.Errorf("unable to remove flow entry in catalog: %w", err)
 	}
+	if err := catalog.DeleteQueuedSchemaDeltas(ctx, h.pool, flowName); err != nil {
+		return err
+	}
+	if err := catalog.DeletePreparedTransactions(ctx, h.pool, flowName); err != nil {
+		return err
+	}
+	for _, rawTableName := range rawTableNames {
+		if err := catalog.DeleteRawTableVersion(ctx, h.pool, rawTableName); err != nil {
+			return err
+		}
+		if err := catalog.DeleteNormalizedBatches(ctx, h.pool, rawTableName); err != nil {
+			return err
+		}
+	}
+	if err := catalog.DeleteMirrorRestart(ctx, h.pool, flowName); err != nil {
+		return err
+	}
+	if err := catalog.DeleteMirrorValidations(ctx, h.pool, flowName); err != nil {
+		return err
+	}
+	if err := catalog.DeleteQRepScheduleState(ctx, h.pool, flowName); err != nil {
+		return err
+	}
+	if err := catalog.DeleteMirrorSettings(ctx, h.pool, flowName); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -212,10 +267,24 @@ func (h *FlowRequestHandler) CreateQRepFlow(
 	ctx context.Context, req *protos.CreateQRepFlowRequest,
 ) (*protos.CreateQRepFlowResponse, error) {
 	cfg := req.QrepConfig
+	if err := sdk.ValidateQRepConfig(cfg); err != nil {
+		return nil, err
+	}
+	taskQueue, err := h.mirrorTaskQueue(ctx, cfg.TaskQueue)
+	if err != nil {
+		slog.Error("unable to use task queue of mirror",
+			slog.Any("error", err), slog.String("flowName", cfg.FlowJobName))
+		return nil, err
+	}
+
 	workflowID := fmt.Sprintf("%s-qrepflow-%s", cfg.FlowJobName, uuid.New())
+	if cfg.Schedule != nil {
+		// the schedule takes the place of the mirror's workflow, each run gets an id of its own
+		workflowID = fmt.Sprintf("%s-qrepschedule-%s", cfg.FlowJobName, uuid.New())
+	}
 	workflowOptions := client.StartWorkflowOptions{
 		ID:        workflowID,
-		TaskQueue: h.peerflowTaskQueueID,
+		TaskQueue: taskQueue,
 		SearchAttributes: map[string]interface{}{
 			shared.MirrorNameSearchAttribute: cfg.FlowJobName,
 		},
@@ -253,21 +322,20 @@ func (h *FlowRequestHandler) CreateQRepFlow(
 		workflowFn = peerflow.QRepFlowWorkflow
 	}
 
-	if req.QrepConfig.SyncedAtColName == "" {
-		cfg.SyncedAtColName = "_PEERDB_SYNCED_AT"
-	} else {
-		// make them all uppercase
-		cfg.SyncedAtColName = strings.ToUpper(req.QrepConfig.SyncedAtColName)
-	}
-	_, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, workflowFn, cfg, state)
-	if err != nil {
+	sdk.ApplyQRepDefaults(cfg)
+	if cfg.Schedule != nil {
+		if err := h.createQRepSchedule(ctx, workflowID, taskQueue, cfg, state); err != nil {
+			slog.Error("unable to create QRepFlow schedule",
+				slog.Any("error", err), slog.String("flowName", cfg.FlowJobName))
+			return nil, fmt.Errorf("unable to create QRepFlow schedule: %w", err)
+		}
+	} else if _, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, workflowFn, cfg, state); err != nil {
 		slog.Error("unable to start QRepFlow workflow",
 			slog.Any("error", err), slog.String("flowName", cfg.FlowJobName))
 		return nil, fmt.Errorf("unable to start QRepFlow workflow: %w", err)
 	}
 
-	err = h.updateQRepConfigInCatalog(ctx, cfg)
-	if err != nil {
+	if err := h.updateQRepConfigInCatalog(ctx, cfg); err != nil {
 		slog.Error("unable to update qrep config in catalog",
 			slog.Any("error", err), slog.String("flowName", cfg.FlowJobName))
 		return nil, fmt.Errorf("unable to update qrep config in catalog: %w", err)
@@ -310,7 +378,15 @@ func (h *FlowRequestHandler) ShutdownFlow(
 		slog.String("workflowId", req.WorkflowId),
 	)
 
-	err := h.handleCancelWorkflow(ctx, req.WorkflowId, "")
+	scheduled, err := h.isQRepScheduled(ctx, req.FlowJobName)
+	if err != nil {
+		slog.Warn("unable to check if mirror is scheduled", logs, slog.Any("error", err))
+	}
+	if scheduled {
+		err = h.deleteQRepSchedule(ctx, req.WorkflowId)
+	} else {
+		err = h.handleCancelWorkflow(ctx, req.WorkflowId, "")
+	}
 	if err != nil {
 		slog.Error("unable to cancel workflow", logs, slog.Any("error", err))
 		return &protos.ShutdownResponse{
@@ -319,10 +395,28 @@ func (h *FlowRequestHandler) ShutdownFlow(
 		}, fmt.Errorf("unable to wait for PeerFlow workflow to close: %w", err)
 	}
 
+	pinnedTaskQueue, err := h.pinnedTaskQueue(ctx, req.FlowJobName)
+	if err != nil {
+		slog.Error("unable to get task queue of mirror", logs, slog.Any("error", err))
+		return &protos.ShutdownResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to get task queue of mirror: %v", err),
+		}, fmt.Errorf("unable to get task queue of mirror: %w", err)
+	}
+	// dropping the mirror's slot and destination tables needs the workers which can reach its peers
+	taskQueue, err := h.mirrorTaskQueue(ctx, pinnedTaskQueue)
+	if err != nil {
+		slog.Error("unable to start DropFlow workflow", logs, slog.Any("error", err))
+		return &protos.ShutdownResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to start DropFlow workflow: %v", err),
+		}, fmt.Errorf("unable to start DropFlow workflow: %w", err)
+	}
+
 	workflowID := fmt.Sprintf("%s-dropflow-%s", req.FlowJobName, uuid.New())
 	workflowOptions := client.StartWorkflowOptions{
 		ID:        workflowID,
-		TaskQueue: h.peerflowTaskQueueID,
+		TaskQueue: taskQueue,
 		SearchAttributes: map[string]interface{}{
 			shared.MirrorNameSearchAttribute: req.FlowJobName,
 		},
@@ -372,6 +466,14 @@ func (h *FlowRequestHandler) ShutdownFlow(
 		}
 	}
 
+	if err := h.removeMirrorGroupMember(ctx, req.FlowJobName); err != nil {
+		slog.Error("unable to remove mirror from its group", logs, slog.Any("error", err))
+		return &protos.ShutdownResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, err
+	}
+
 	if req.RemoveFlowEntry {
 		delErr := h.removeFlowEntryInCatalog(ctx, req.FlowJobName)
 		if delErr != nil {
@@ -399,6 +501,16 @@ func (h *FlowRequestHandler) FlowStateChange(
 	if err != nil {
 		return nil, err
 	}
+	if scheduled, err := h.isQRepScheduled(ctx, req.FlowJobName); err != nil {
+		return nil, err
+	} else if scheduled {
+		if err := h.qrepScheduleStateChange(ctx, workflowID, req); err != nil {
+			return nil, err
+		}
+		return &protos.FlowStateChangeResponse{
+			Ok: true,
+		}, nil
+	}
 	currState, err := h.getWorkflowStatus(ctx, workflowID)
 	if err != nil {
 		return nil, err
@@ -416,6 +528,18 @@ func (h *FlowRequestHandler) FlowStateChange(
 			return nil, fmt.Errorf("unable to signal workflow: %w", err)
 		}
 	}
+	if req.FlowConfigUpdate != nil && req.FlowConfigUpdate.GetQrepFlowConfigUpdate() != nil {
+		err = model.QRepDynamicPropertiesSignal.SignalClientWorkflow(
+			ctx,
+			h.temporalClient,
+			workflowID,
+			"",
+			req.FlowConfigUpdate.GetQrepFlowConfigUpdate(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to signal workflow: %w", err)
+		}
+	}
 
 	// in case we only want to update properties without changing status
 	if req.RequestedFlowState != protos.FlowStatus_STATUS_UNKNOWN {
@@ -434,6 +558,12 @@ func (h *FlowRequestHandler) FlowStateChange(
 			)
 		} else if req.RequestedFlowState == protos.FlowStatus_STATUS_RUNNING &&
 			currState == protos.FlowStatus_STATUS_PAUSED {
+			if pending, err := catalog.HasPendingIncompatibleSchemaDeltas(ctx, h.pool, req.FlowJobName); err != nil {
+				return nil, err
+			} else if pending {
+				return nil, fmt.Errorf("mirror %s is paused on schema changes, approve them with ApproveSchemaChange to resume it",
+					req.FlowJobName)
+			}
 			err = model.FlowSignal.SignalClientWorkflow(
 				ctx,
 				h.temporalClient,
@@ -582,8 +712,15 @@ func (h *FlowRequestHandler) CreatePeer(
 		return nil, encodingErr
 	}
 
-	_, err := h.pool.Exec(ctx, "INSERT INTO peers (name, type, options) VALUES ($1, $2, $3)",
-		req.Peer.Name, peerType, encodedConfig,
+	encodedConfig, encKeyID, encDataKey, encryptErr := catalog.EncryptPeerOptions(ctx, encodedConfig)
+	if encryptErr != nil {
+		slog.Error(fmt.Sprintf("failed to encrypt peer configuration for %s peer %s : %v",
+			req.Peer.Type, req.Peer.Name, encryptErr))
+		return nil, encryptErr
+	}
+
+	_, err := h.pool.Exec(ctx, "INSERT INTO peers (name, type, options, enc_key_id, enc_data_key) VALUES ($1, $2, $3, $4, $5)",
+		req.Peer.Name, peerType, encodedConfig, encKeyID, encDataKey,
 	)
 	if err != nil {
 		return &protos.CreatePeerResponse{
@@ -645,6 +782,15 @@ func (h *FlowRequestHandler) DropPeer(
 		}, fmt.Errorf("failed to delete peer %s from metadata table: %v", req.PeerName, delErr)
 	}
 
+	_, delErr = h.pool.Exec(ctx, "DELETE FROM peerdb_stats.peer_credential_expiry WHERE peer_name = $1", req.PeerName)
+	if delErr != nil {
+		slog.Warn("failed to delete credential expiry of peer "+req.PeerName, slog.Any("error", delErr))
+	}
+
+	if delErr := monitoring.UpdateOpenTransaction(ctx, h.pool, req.PeerName, nil); delErr != nil {
+		slog.Warn("failed to delete open transaction of peer "+req.PeerName, slog.Any("error", delErr))
+	}
+
 	return &protos.DropPeerResponse{
 		Ok: true,
 	}, nil
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -11,9 +13,30 @@ import (
 	"github.com/urfave/cli/v3"
 	_ "go.uber.org/automaxprocs"
 
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
 )
 
+// validateEnv reports malformed, unknown and missing PEERDB_* variables,
+// failing startup with them when PEERDB_STRICT_CONFIG is set.
+func validateEnv(required []string) error {
+	err := peerdbenv.ValidateEnv(dynamicconf.EnvVars(), required)
+	if err == nil {
+		return nil
+	}
+	if peerdbenv.PeerDBStrictConfig() {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		for _, envErr := range joined.Unwrap() {
+			slog.Warn("invalid configuration", slog.Any("error", envErr))
+		}
+	}
+	return nil
+}
+
 func main() {
 	appCtx, appClose := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer appClose()
@@ -65,6 +88,9 @@ func main() {
 			{
 				Name: "worker",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := validateEnv(peerdbenv.CatalogEnvVars); err != nil {
+						return err
+					}
 					temporalHostPort := cmd.String("temporal-host-port")
 					return WorkerMain(&WorkerOptions{
 						TemporalHostPort:  temporalHostPort,
@@ -73,6 +99,7 @@ func main() {
 						TemporalNamespace: cmd.String("temporal-namespace"),
 						TemporalCert:      cmd.String("temporal-cert"),
 						TemporalKey:       cmd.String("temporal-key"),
+						TaskQueue:         cmd.String("task-queue"),
 					})
 				},
 				Flags: []cli.Flag{
@@ -82,11 +109,19 @@ func main() {
 					temporalNamespaceFlag,
 					&temporalCertFlag,
 					&temporalKeyFlag,
+					&cli.StringFlag{
+						Name:    "task-queue",
+						Usage:   "Dedicated task queue to poll instead of the default one, for mirrors pinned to it",
+						Sources: cli.EnvVars("PEERDB_WORKER_TASK_QUEUE"),
+					},
 				},
 			},
 			{
 				Name: "snapshot-worker",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := validateEnv(peerdbenv.CatalogEnvVars); err != nil {
+						return err
+					}
 					temporalHostPort := cmd.String("temporal-host-port")
 					return SnapshotWorkerMain(&SnapshotWorkerOptions{
 						TemporalHostPort:  temporalHostPort,
@@ -121,6 +156,9 @@ func main() {
 					&temporalKeyFlag,
 				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := validateEnv(peerdbenv.CatalogEnvVars); err != nil {
+						return err
+					}
 					temporalHostPort := cmd.String("temporal-host-port")
 
 					return APIMain(ctx, &APIServerParams{
@@ -133,6 +171,29 @@ func main() {
 					})
 				},
 			},
+			{
+				Name: "federation",
+				Flags: []cli.Flag{
+					&cli.UintFlag{
+						Name:    "port",
+						Aliases: []string{"p"},
+						Value:   8110,
+					},
+					&cli.UintFlag{
+						Name:  "gateway-port",
+						Value: 8111,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := validateEnv(nil); err != nil {
+						return err
+					}
+					return FederationMain(ctx, &FederationServerParams{
+						Port:        uint16(cmd.Uint("port")),
+						GatewayPort: uint16(cmd.Uint("gateway-port")),
+					})
+				},
+			},
 		},
 	}
 
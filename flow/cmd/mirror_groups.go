@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/sync/errgroup"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared/alerting"
+)
+
+// groupStatusOrder ranks states from least to most settled, a group whose mirrors disagree reports the first
+// state any of them is in, so a group being paused reads as pausing and a partly paused group as running.
+var groupStatusOrder = []protos.FlowStatus{
+	protos.FlowStatus_STATUS_UNKNOWN,
+	protos.FlowStatus_STATUS_TERMINATING,
+	protos.FlowStatus_STATUS_PAUSING,
+	protos.FlowStatus_STATUS_SETUP,
+	protos.FlowStatus_STATUS_SNAPSHOT,
+	protos.FlowStatus_STATUS_RUNNING,
+	protos.FlowStatus_STATUS_PAUSED,
+	protos.FlowStatus_STATUS_TERMINATED,
+}
+
+type mirrorGroupMember struct {
+	flowJobName     string
+	workflowID      string
+	sourceName      string
+	destinationName string
+	cdc             bool
+}
+
+// groupMemberChange is a change of state of a mirror of a group, along with the state it's changed from
+type groupMemberChange struct {
+	member    mirrorGroupMember
+	prevState protos.FlowStatus
+}
+
+func (h *FlowRequestHandler) CreateMirrorGroup(
+	ctx context.Context,
+	req *protos.CreateMirrorGroupRequest,
+) (*protos.CreateMirrorGroupResponse, error) {
+	group := req.Group
+	if group == nil || group.Name == "" {
+		return nil, errors.New("mirror group name is required")
+	}
+
+	tx, err := h.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Error("error rolling back transaction for mirror group", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, "INSERT INTO mirror_groups(name,description) VALUES($1,$2)",
+		group.Name, group.Description); err != nil {
+		return nil, fmt.Errorf("unable to create mirror group %s: %w", group.Name, err)
+	}
+	for _, mirror := range group.Mirrors {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM flows WHERE name = $1)", mirror).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("unable to look up mirror %s: %w", mirror, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("mirror %s does not exist", mirror)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO mirror_group_members(group_name,flow_job_name) VALUES($1,$2)",
+			group.Name, mirror); err != nil {
+			return nil, fmt.Errorf("unable to add mirror %s to group %s, it may already be in a group: %w",
+				mirror, group.Name, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to commit mirror group %s: %w", group.Name, err)
+	}
+
+	return &protos.CreateMirrorGroupResponse{}, nil
+}
+
+func (h *FlowRequestHandler) ListMirrorGroups(
+	ctx context.Context,
+	req *protos.ListMirrorGroupsRequest,
+) (*protos.ListMirrorGroupsResponse, error) {
+	rows, err := h.pool.Query(ctx, `SELECT g.name, g.description, m.flow_job_name
+		FROM mirror_groups g LEFT JOIN mirror_group_members m ON m.group_name = g.name
+		ORDER BY g.name, m.flow_job_name`)
+	if err != nil {
+		slog.Error("Failed to list mirror groups", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list mirror groups: %w", err)
+	}
+
+	var groups []*protos.MirrorGroup
+	var name, description string
+	var mirror *string
+	_, err = pgx.ForEachRow(rows, []any{&name, &description, &mirror}, func() error {
+		if len(groups) == 0 || groups[len(groups)-1].Name != name {
+			groups = append(groups, &protos.MirrorGroup{Name: name, Description: description})
+		}
+		if mirror != nil {
+			group := groups[len(groups)-1]
+			group.Mirrors = append(group.Mirrors, *mirror)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to list mirror groups", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list mirror groups: %w", err)
+	}
+
+	return &protos.ListMirrorGroupsResponse{Groups: groups}, nil
+}
+
+func (h *FlowRequestHandler) getMirrorGroupMembers(ctx context.Context, groupName string) ([]mirrorGroupMember, error) {
+	var exists bool
+	if err := h.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM mirror_groups WHERE name = $1)",
+		groupName).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("unable to look up mirror group %s: %w", groupName, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("mirror group %s does not exist", groupName)
+	}
+
+	// flows has a row per table for mirrors created over GRPC
+	rows, err := h.pool.Query(ctx, `SELECT DISTINCT ON (f.name) f.name, f.workflow_id, src.name, dst.name,
+		f.query_string IS NULL FROM mirror_group_members m JOIN flows f ON f.name = m.flow_job_name
+		JOIN peers src ON src.id = f.source_peer JOIN peers dst ON dst.id = f.destination_peer
+		WHERE m.group_name = $1 ORDER BY f.name, f.id`, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query members of mirror group %s: %w", groupName, err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (mirrorGroupMember, error) {
+		var member mirrorGroupMember
+		err := row.Scan(&member.flowJobName, &member.workflowID, &member.sourceName, &member.destinationName, &member.cdc)
+		return member, err
+	})
+}
+
+func (h *FlowRequestHandler) MirrorGroupStatus(
+	ctx context.Context,
+	req *protos.MirrorGroupStatusRequest,
+) (*protos.MirrorGroupStatusResponse, error) {
+	members, err := h.getMirrorGroupMembers(ctx, req.GroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*protos.MirrorGroupMemberStatus, 0, len(members))
+	for _, member := range members {
+		status := &protos.MirrorGroupMemberStatus{FlowJobName: member.flowJobName}
+		state, err := h.getWorkflowStatus(ctx, member.workflowID)
+		if err != nil {
+			status.ErrorMessage = err.Error()
+		}
+		status.CurrentFlowState = state
+		statuses = append(statuses, status)
+	}
+
+	return &protos.MirrorGroupStatusResponse{
+		GroupName:        req.GroupName,
+		CurrentFlowState: aggregateGroupStatus(statuses),
+		Mirrors:          statuses,
+	}, nil
+}
+
+func aggregateGroupStatus(statuses []*protos.MirrorGroupMemberStatus) protos.FlowStatus {
+	if len(statuses) == 0 {
+		return protos.FlowStatus_STATUS_UNKNOWN
+	}
+	groupStatusIdx := len(groupStatusOrder) - 1
+	for _, status := range statuses {
+		if idx := slices.Index(groupStatusOrder, status.CurrentFlowState); idx != -1 && idx < groupStatusIdx {
+			groupStatusIdx = idx
+		}
+	}
+	return groupStatusOrder[groupStatusIdx]
+}
+
+// MirrorGroupStateChange moves every mirror of a group to the requested state, or resyncs them.
+// All mirrors are checked before any is touched, and when changing the state of some of them fails,
+// those already paused or resumed are moved back, so a group is changed as a whole or not at all.
+// Drops and resyncs can't be undone, the mirrors they failed on are reported.
+// The group's state changes and failures are logged under the group name.
+func (h *FlowRequestHandler) MirrorGroupStateChange(
+	ctx context.Context,
+	req *protos.MirrorGroupStateChangeRequest,
+) (*protos.MirrorGroupStateChangeResponse, error) {
+	members, err := h.getMirrorGroupMembers(ctx, req.GroupName)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]protos.FlowStatus, 0, len(members))
+	for _, member := range members {
+		currState, err := h.getWorkflowStatus(ctx, member.workflowID)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, currState)
+	}
+	pending, err := planGroupStateChange(req.GroupName, members, states, req.RequestedFlowState, req.Resync)
+	if err != nil {
+		return nil, err
+	}
+
+	alerter, err := alerting.NewAlerter(h.pool)
+	if err != nil {
+		return nil, err
+	}
+	if req.Resync {
+		alerter.LogFlowInfo(ctx, req.GroupName, fmt.Sprintf("resyncing %d mirrors of group", len(pending)))
+	} else {
+		alerter.LogFlowInfo(ctx, req.GroupName, fmt.Sprintf("changing state of %d mirrors of group to %v",
+			len(pending), req.RequestedFlowState))
+	}
+
+	apply := func(ctx context.Context, change groupMemberChange) error {
+		var err error
+		if req.Resync {
+			err = h.resyncMirror(ctx, req.GroupName, change.member)
+		} else {
+			err = h.changeMirrorState(ctx, change.member, req.RequestedFlowState)
+		}
+		if err != nil {
+			alerter.LogFlowError(ctx, req.GroupName, fmt.Errorf("mirror %s: %w", change.member.flowJobName, err))
+		}
+		return err
+	}
+	var revert func(context.Context, groupMemberChange) error
+	if !req.Resync && req.RequestedFlowState != protos.FlowStatus_STATUS_TERMINATED {
+		revert = h.revertMirrorState
+	}
+	reverted, err := applyGroupChanges(ctx, pending, apply, revert)
+	if len(reverted) != 0 {
+		alerter.LogFlowInfo(ctx, req.GroupName, fmt.Sprintf("moved %d mirrors of group back to their previous state",
+			len(reverted)))
+	}
+	if err != nil {
+		slog.Error("unable to change state of mirror group",
+			slog.String("groupName", req.GroupName), slog.Any("error", err))
+		return &protos.MirrorGroupStateChangeResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, err
+	}
+
+	return &protos.MirrorGroupStateChangeResponse{
+		Ok: true,
+	}, nil
+}
+
+// planGroupStateChange returns the changes the mirrors of a group, in the given states, need for the group
+// to reach the requested state or be resynced, erroring when any of them can't make the transition.
+func planGroupStateChange(
+	groupName string,
+	members []mirrorGroupMember,
+	states []protos.FlowStatus,
+	requestedState protos.FlowStatus,
+	resync bool,
+) ([]groupMemberChange, error) {
+	pending := make([]groupMemberChange, 0, len(members))
+	for i, member := range members {
+		currState := states[i]
+		if resync {
+			if !member.cdc {
+				return nil, fmt.Errorf("mirror %s of group %s can't be resynced, only CDC mirrors can", member.flowJobName, groupName)
+			}
+			if currState == protos.FlowStatus_STATUS_TERMINATING || currState == protos.FlowStatus_STATUS_TERMINATED {
+				return nil, fmt.Errorf("mirror %s of group %s can't be resynced, it's %v", member.flowJobName, groupName, currState)
+			}
+		} else if currState == requestedState {
+			continue
+		} else if !isLegalStateChange(currState, requestedState) {
+			return nil, fmt.Errorf("illegal state change requested for mirror %s of group %s: %v, current state is: %v",
+				member.flowJobName, groupName, requestedState, currState)
+		}
+		pending = append(pending, groupMemberChange{member: member, prevState: currState})
+	}
+	return pending, nil
+}
+
+// applyGroupChanges applies the changes concurrently. When any of them fails, those which were applied are reverted,
+// unless revert is nil, returning the mirrors which were reverted along with the errors.
+func applyGroupChanges(
+	ctx context.Context,
+	changes []groupMemberChange,
+	apply func(context.Context, groupMemberChange) error,
+	revert func(context.Context, groupMemberChange) error,
+) ([]string, error) {
+	// changes aren't canceled once one fails, so each mirror is known to be changed or not
+	var g errgroup.Group
+	errs := make([]error, len(changes))
+	for i, change := range changes {
+		g.Go(func() error {
+			if err := apply(ctx, change); err != nil {
+				errs[i] = fmt.Errorf("unable to change state of mirror %s: %w", change.member.flowJobName, err)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	err := errors.Join(errs...)
+	if err == nil || revert == nil {
+		return nil, err
+	}
+
+	var reverted []string
+	for i, change := range changes {
+		if errs[i] != nil {
+			continue
+		}
+		if revertErr := revert(ctx, change); revertErr != nil {
+			err = errors.Join(err, fmt.Errorf("unable to move mirror %s back to %v: %w",
+				change.member.flowJobName, change.prevState, revertErr))
+		} else {
+			reverted = append(reverted, change.member.flowJobName)
+		}
+	}
+	return reverted, err
+}
+
+func (h *FlowRequestHandler) changeMirrorState(
+	ctx context.Context,
+	member mirrorGroupMember,
+	requestedState protos.FlowStatus,
+) error {
+	stateChangeReq := &protos.FlowStateChangeRequest{
+		FlowJobName:        member.flowJobName,
+		RequestedFlowState: requestedState,
+	}
+	if requestedState == protos.FlowStatus_STATUS_TERMINATED {
+		// dropping a mirror needs its peers to clean up after it
+		sourcePeer, err := catalog.LoadPeer(ctx, h.pool, member.sourceName)
+		if err != nil {
+			return err
+		}
+		destinationPeer, err := catalog.LoadPeer(ctx, h.pool, member.destinationName)
+		if err != nil {
+			return err
+		}
+		stateChangeReq.SourcePeer = sourcePeer
+		stateChangeReq.DestinationPeer = destinationPeer
+	}
+	_, err := h.FlowStateChange(ctx, stateChangeReq)
+	return err
+}
+
+// revertMirrorState moves a mirror which was paused or resumed back to the state it was in.
+// Signals are handled in order, so a mirror still pausing or resuming is moved back once it's done.
+func (h *FlowRequestHandler) revertMirrorState(ctx context.Context, change groupMemberChange) error {
+	if scheduled, err := h.isQRepScheduled(ctx, change.member.flowJobName); err != nil {
+		return err
+	} else if scheduled {
+		return h.qrepScheduleStateChange(ctx, change.member.workflowID, &protos.FlowStateChangeRequest{
+			FlowJobName:        change.member.flowJobName,
+			RequestedFlowState: change.prevState,
+		})
+	}
+
+	signal := model.NoopSignal
+	if change.prevState == protos.FlowStatus_STATUS_PAUSED {
+		if err := h.updateWorkflowStatus(ctx, change.member.workflowID, protos.FlowStatus_STATUS_PAUSING); err != nil {
+			return err
+		}
+		signal = model.PauseSignal
+	}
+	if err := model.FlowSignal.SignalClientWorkflow(ctx, h.temporalClient, change.member.workflowID, "", signal); err != nil {
+		return fmt.Errorf("unable to signal workflow: %w", err)
+	}
+	return nil
+}
+
+// resyncMirror drops a CDC mirror and creates it again, snapshotting its tables into new ones which then replace them.
+// The mirror keeps its metadata and stays in its group.
+func (h *FlowRequestHandler) resyncMirror(ctx context.Context, groupName string, member mirrorGroupMember) error {
+	cfg, err := h.getFlowConfigFromCatalog(ctx, member.flowJobName)
+	if err != nil {
+		return err
+	}
+	metadata, err := h.getMirrorMetadata(ctx, member.flowJobName)
+	if err != nil {
+		return err
+	}
+	sourcePeer, err := catalog.LoadPeer(ctx, h.pool, member.sourceName)
+	if err != nil {
+		return err
+	}
+	destinationPeer, err := catalog.LoadPeer(ctx, h.pool, member.destinationName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.ShutdownFlow(ctx, &protos.ShutdownRequest{
+		WorkflowId:      member.workflowID,
+		FlowJobName:     member.flowJobName,
+		SourcePeer:      sourcePeer,
+		DestinationPeer: destinationPeer,
+		RemoveFlowEntry: true,
+	}); err != nil {
+		return err
+	}
+
+	cfg.Resync = true
+	cfg.DoInitialSnapshot = true
+	if _, err := h.CreateCDCFlow(ctx, &protos.CreateCDCFlowRequest{
+		ConnectionConfigs:  cfg,
+		CreateCatalogEntry: true,
+		Metadata:           metadata,
+	}); err != nil {
+		return fmt.Errorf("mirror was dropped but couldn't be created again: %w", err)
+	}
+	if _, err := h.pool.Exec(ctx, "INSERT INTO mirror_group_members(group_name,flow_job_name) VALUES($1,$2)",
+		groupName, member.flowJobName); err != nil {
+		return fmt.Errorf("unable to add resynced mirror back to group %s: %w", groupName, err)
+	}
+	return nil
+}
+
+// removeMirrorGroupMember takes a dropped mirror out of its group
+func (h *FlowRequestHandler) removeMirrorGroupMember(ctx context.Context, flowJobName string) error {
+	if _, err := h.pool.Exec(ctx, "DELETE FROM mirror_group_members WHERE flow_job_name = $1", flowJobName); err != nil {
+		return fmt.Errorf("unable to remove mirror %s from its group: %w", flowJobName, err)
+	}
+	return nil
+}
+
+func isLegalStateChange(currState protos.FlowStatus, requestedState protos.FlowStatus) bool {
+	switch requestedState {
+	case protos.FlowStatus_STATUS_PAUSED:
+		return currState == protos.FlowStatus_STATUS_RUNNING
+	case protos.FlowStatus_STATUS_RUNNING:
+		return currState == protos.FlowStatus_STATUS_PAUSED
+	case protos.FlowStatus_STATUS_TERMINATED:
+		return currState != protos.FlowStatus_STATUS_TERMINATED
+	default:
+		return false
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestPlanGroupStateChange(t *testing.T) {
+	members := []mirrorGroupMember{
+		{flowJobName: "orders", cdc: true},
+		{flowJobName: "users", cdc: true},
+		{flowJobName: "events"},
+	}
+	running := protos.FlowStatus_STATUS_RUNNING
+	paused := protos.FlowStatus_STATUS_PAUSED
+
+	// mirrors already in the requested state are left alone
+	pending, err := planGroupStateChange("group", members, []protos.FlowStatus{running, paused, running},
+		paused, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, "orders", pending[0].member.flowJobName)
+	require.Equal(t, running, pending[0].prevState)
+	require.Equal(t, "events", pending[1].member.flowJobName)
+
+	// one mirror which can't make the transition fails the whole group
+	_, err = planGroupStateChange("group", members,
+		[]protos.FlowStatus{running, protos.FlowStatus_STATUS_SNAPSHOT, running}, paused, false)
+	require.ErrorContains(t, err, "users")
+
+	// only CDC mirrors are resynced
+	_, err = planGroupStateChange("group", members, []protos.FlowStatus{running, paused, running},
+		protos.FlowStatus_STATUS_UNKNOWN, true)
+	require.ErrorContains(t, err, "events")
+	pending, err = planGroupStateChange("group", members[:2], []protos.FlowStatus{running, paused},
+		protos.FlowStatus_STATUS_UNKNOWN, true)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	_, err = planGroupStateChange("group", members[:2], []protos.FlowStatus{running, protos.FlowStatus_STATUS_TERMINATED},
+		protos.FlowStatus_STATUS_UNKNOWN, true)
+	require.ErrorContains(t, err, "users")
+}
+
+func TestApplyGroupChanges(t *testing.T) {
+	changes := []groupMemberChange{
+		{member: mirrorGroupMember{flowJobName: "orders"}, prevState: protos.FlowStatus_STATUS_RUNNING},
+		{member: mirrorGroupMember{flowJobName: "users"}, prevState: protos.FlowStatus_STATUS_RUNNING},
+		{member: mirrorGroupMember{flowJobName: "events"}, prevState: protos.FlowStatus_STATUS_RUNNING},
+	}
+	var mu sync.Mutex
+	var revertedChanges []string
+	revert := func(_ context.Context, change groupMemberChange) error {
+		mu.Lock()
+		defer mu.Unlock()
+		revertedChanges = append(revertedChanges, change.member.flowJobName)
+		return nil
+	}
+
+	reverted, err := applyGroupChanges(context.Background(), changes,
+		func(context.Context, groupMemberChange) error { return nil }, revert)
+	require.NoError(t, err)
+	require.Empty(t, reverted)
+	require.Empty(t, revertedChanges)
+
+	// the mirrors which were changed are moved back when one fails
+	failing := func(_ context.Context, change groupMemberChange) error {
+		if change.member.flowJobName == "users" {
+			return errors.New("workflow not found")
+		}
+		return nil
+	}
+	reverted, err = applyGroupChanges(context.Background(), changes, failing, revert)
+	require.ErrorContains(t, err, "users")
+	slices.Sort(reverted)
+	require.Equal(t, []string{"events", "orders"}, reverted)
+	slices.Sort(revertedChanges)
+	require.Equal(t, []string{"events", "orders"}, revertedChanges)
+
+	// failing to move a mirror back is reported along with the original failure
+	revertedChanges = nil
+	reverted, err = applyGroupChanges(context.Background(), changes, failing,
+		func(_ context.Context, change groupMemberChange) error {
+			if change.member.flowJobName == "orders" {
+				return errors.New("signal failed")
+			}
+			return revert(context.Background(), change)
+		})
+	require.ErrorContains(t, err, "users")
+	require.ErrorContains(t, err, "orders back")
+	require.Equal(t, []string{"events"}, reverted)
+
+	// changes without a revert, like drops, stay made
+	reverted, err = applyGroupChanges(context.Background(), changes, failing, nil)
+	require.Error(t, err)
+	require.Empty(t, reverted)
+}
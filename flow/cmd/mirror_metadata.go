@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func (h *FlowRequestHandler) updateMirrorMetadataInCatalog(
+	ctx context.Context,
+	flowJobName string,
+	metadata *protos.MirrorMetadata,
+) error {
+	metadataJSON, err := protojson.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("unable to marshal mirror metadata: %w", err)
+	}
+
+	_, err = h.pool.Exec(ctx, "UPDATE flows SET mirror_metadata = $1 WHERE name = $2", metadataJSON, flowJobName)
+	if err != nil {
+		return fmt.Errorf("unable to update mirror metadata in catalog: %w", err)
+	}
+	return nil
+}
+
+func (h *FlowRequestHandler) getMirrorMetadata(ctx context.Context, flowJobName string) (*protos.MirrorMetadata, error) {
+	var metadataJSON []byte
+	err := h.pool.QueryRow(ctx, "SELECT mirror_metadata FROM flows WHERE name = $1 AND mirror_metadata IS NOT NULL LIMIT 1",
+		flowJobName).Scan(&metadataJSON)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to query mirror metadata: %w", err)
+	}
+	return unmarshalMirrorMetadata(metadataJSON)
+}
+
+func unmarshalMirrorMetadata(metadataJSON []byte) (*protos.MirrorMetadata, error) {
+	if metadataJSON == nil {
+		return nil, nil
+	}
+	var metadata protos.MirrorMetadata
+	if err := protojson.Unmarshal(metadataJSON, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal mirror metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+func (h *FlowRequestHandler) ListMirrors(
+	ctx context.Context,
+	req *protos.ListMirrorsRequest,
+) (*protos.ListMirrorsResponse, error) {
+	// flows has a row per table for mirrors created over GRPC
+	rows, err := h.pool.Query(ctx, `SELECT DISTINCT ON (f.name) f.name, f.workflow_id, src.name, dst.name,
+		f.query_string, f.created_at, f.mirror_metadata
+		FROM flows f JOIN peers src ON src.id = f.source_peer JOIN peers dst ON dst.id = f.destination_peer
+		ORDER BY f.name, f.id`)
+	if err != nil {
+		slog.Error("Failed to list mirrors", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list mirrors: %w", err)
+	}
+
+	var items []*protos.MirrorListItem
+	var name, sourceName, destinationName string
+	var workflowID, queryString pgtype.Text
+	var createdAt time.Time
+	var metadataJSON []byte
+	_, err = pgx.ForEachRow(rows, []any{
+		&name, &workflowID, &sourceName, &destinationName, &queryString, &createdAt, &metadataJSON,
+	}, func() error {
+		metadata, err := unmarshalMirrorMetadata(metadataJSON)
+		if err != nil {
+			return err
+		}
+		items = append(items, &protos.MirrorListItem{
+			Name:            name,
+			WorkflowId:      workflowID.String,
+			SourceName:      sourceName,
+			DestinationName: destinationName,
+			IsCdc:           queryString.String == "",
+			CreatedAt:       timestamppb.New(createdAt),
+			Metadata:        metadata,
+		})
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to list mirrors", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list mirrors: %w", err)
+	}
+
+	return &protos.ListMirrorsResponse{Items: items}, nil
+}
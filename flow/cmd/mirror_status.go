@@ -3,15 +3,22 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"go.temporal.io/api/serviceerror"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/PeerDB-io/peer-flow/shared/alerting"
 	peerflow "github.com/PeerDB-io/peer-flow/workflows"
 )
 
@@ -40,6 +47,16 @@ func (h *FlowRequestHandler) MirrorStatus(
 		}, nil
 	}
 
+	// metadata only helps whoever is looking at the status, don't fail the request over it
+	metadata, err := h.getMirrorMetadata(ctx, req.FlowJobName)
+	if err != nil {
+		slog.Warn("unable to get mirror metadata", slog.Any("error", err))
+	}
+	recentErrors, err := h.getRecentMirrorErrors(ctx, req.FlowJobName)
+	if err != nil {
+		slog.Warn("unable to get recent mirror errors", slog.Any("error", err))
+	}
+
 	if cdcFlow {
 		cdcStatus, err := h.CDCFlowStatus(ctx, req)
 		if err != nil {
@@ -54,6 +71,8 @@ func (h *FlowRequestHandler) MirrorStatus(
 				CdcStatus: cdcStatus,
 			},
 			CurrentFlowState: currState,
+			Metadata:         metadata,
+			RecentErrors:     recentErrors,
 		}, nil
 	} else {
 		qrepStatus, err := h.QRepFlowStatus(ctx, req)
@@ -69,10 +88,37 @@ func (h *FlowRequestHandler) MirrorStatus(
 				QrepStatus: qrepStatus,
 			},
 			CurrentFlowState: currState,
+			Metadata:         metadata,
+			RecentErrors:     recentErrors,
 		}, nil
 	}
 }
 
+// recentMirrorErrorsLimit is how many of a mirror's latest errors are returned with its status.
+const recentMirrorErrorsLimit = 10
+
+// getRecentMirrorErrors returns the latest errors of a mirror with their class, newest first.
+// Errors logged before errors were classified are returned as internal.
+func (h *FlowRequestHandler) getRecentMirrorErrors(ctx context.Context, flowJobName string) ([]*protos.MirrorError, error) {
+	rows, err := h.pool.Query(ctx, `SELECT error_timestamp,coalesce(error_class,$3),coalesce(error_code,''),error_message
+	 FROM peerdb_stats.flow_errors WHERE flow_name=$1 AND error_type='error'
+	 ORDER BY error_timestamp DESC LIMIT $2`, flowJobName, recentMirrorErrorsLimit, string(alerting.ErrorClassInternal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flow errors: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.MirrorError, error) {
+		var mirrorError protos.MirrorError
+		var errorTimestamp time.Time
+		if err := row.Scan(&errorTimestamp, &mirrorError.ErrorClass, &mirrorError.ErrorCode,
+			&mirrorError.ErrorMessage); err != nil {
+			return nil, err
+		}
+		mirrorError.ErrorTimestamp = timestamppb.New(errorTimestamp)
+		mirrorError.Action = alerting.ErrorClass(mirrorError.ErrorClass).Action()
+		return &mirrorError, nil
+	})
+}
+
 func (h *FlowRequestHandler) CDCFlowStatus(
 	ctx context.Context,
 	req *protos.MirrorStatusRequest,
@@ -95,8 +141,15 @@ func (h *FlowRequestHandler) CDCFlowStatus(
 	if state.SyncFlowOptions != nil {
 		config.IdleTimeoutSeconds = state.SyncFlowOptions.IdleTimeoutSeconds
 		config.MaxBatchSize = state.SyncFlowOptions.BatchSize
+		config.MaxBatchBytes = state.SyncFlowOptions.BatchBytes
 		config.TableMappings = state.SyncFlowOptions.TableMappings
 	}
+	if state.SnapshotMaxParallelWorkers > 0 {
+		config.SnapshotMaxParallelWorkers = state.SnapshotMaxParallelWorkers
+	}
+	if state.SnapshotNumRowsPerPartition > 0 {
+		config.SnapshotNumRowsPerPartition = state.SnapshotNumRowsPerPartition
+	}
 
 	var initialCopyStatus *protos.SnapshotStatus
 
@@ -109,9 +162,30 @@ func (h *FlowRequestHandler) CDCFlowStatus(
 		Clones: cloneStatuses,
 	}
 
+	openTx, err := monitoring.GetOpenTransaction(ctx, h.pool, config.Source.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var maintenance *protos.DestinationMaintenanceStatus
+	if state.DestinationMaintenance != nil {
+		maintenance = &protos.DestinationMaintenanceStatus{
+			Since:     timestamppb.New(state.DestinationMaintenance.Since),
+			Retries:   uint32(state.DestinationMaintenance.Retries),
+			LastError: state.DestinationMaintenance.LastError,
+			NextRetry: timestamppb.New(state.DestinationMaintenance.NextRetry),
+		}
+	}
+
 	return &protos.CDCMirrorStatus{
 		Config:         config,
 		SnapshotStatus: initialCopyStatus,
+		CatchUpStatus: &protos.CDCCatchUpStatus{
+			CatchingUp: state.CatchingUp,
+			LagInMb:    state.SourceLagMB,
+		},
+		OldestOpenTransaction:  openTx,
+		DestinationMaintenance: maintenance,
 	}, nil
 }
 
@@ -127,7 +201,8 @@ func (h *FlowRequestHandler) cloneTableSummary(
 		COUNT(*) AS NumPartitionsTotal,
 		COUNT(CASE WHEN qp.end_time IS NOT NULL THEN 1 END) AS NumPartitionsCompleted,
 		SUM(qp.rows_in_partition) FILTER (WHERE qp.end_time IS NOT NULL) AS NumRowsSynced,
-		AVG(EXTRACT(EPOCH FROM (qp.end_time - qp.start_time)) * 1000) FILTER (WHERE qp.end_time IS NOT NULL) AS AvgTimePerPartitionMs
+		AVG(EXTRACT(EPOCH FROM (qp.end_time - qp.start_time)) * 1000) FILTER (WHERE qp.end_time IS NOT NULL) AS AvgTimePerPartitionMs,
+		MAX(qr.estimated_rows) AS EstimatedRows
 	FROM peerdb_stats.qrep_partitions qp
 	JOIN peerdb_stats.qrep_runs qr ON qp.flow_name = qr.flow_name
 	WHERE qp.flow_name ILIKE $1
@@ -141,6 +216,7 @@ func (h *FlowRequestHandler) cloneTableSummary(
 	var numPartitionsCompleted pgtype.Int8
 	var numRowsSynced pgtype.Int8
 	var avgTimePerPartitionMs pgtype.Float8
+	var estimatedRows pgtype.Int8
 
 	rows, err := h.pool.Query(ctx, q, "clone_"+flowJobName+"_%")
 	if err != nil {
@@ -160,6 +236,7 @@ func (h *FlowRequestHandler) cloneTableSummary(
 			&numPartitionsCompleted,
 			&numRowsSynced,
 			&avgTimePerPartitionMs,
+			&estimatedRows,
 		); err != nil {
 			return nil, fmt.Errorf("unable to scan initial load partition - %s: %w", flowJobName, err)
 		}
@@ -189,6 +266,17 @@ func (h *FlowRequestHandler) cloneTableSummary(
 			res.AvgTimePerPartitionMs = int64(avgTimePerPartitionMs.Float64)
 		}
 
+		if estimatedRows.Valid {
+			res.EstimatedRows = estimatedRows.Int64
+		}
+		res.Eta = model.InitialLoadETA(&protos.InitialLoadProgress{
+			RowsCopied:          res.NumRowsSynced,
+			EstimatedRows:       res.EstimatedRows,
+			PartitionsCompleted: uint32(res.NumPartitionsCompleted),
+			PartitionsTotal:     uint32(res.NumPartitionsTotal),
+			StartTime:           res.StartTime,
+		}, time.Now())
+
 		if configBytes != nil {
 			var config protos.QRepConfig
 			if err := proto.Unmarshal(configBytes, &config); err != nil {
@@ -214,11 +302,55 @@ func (h *FlowRequestHandler) QRepFlowStatus(
 		return nil, err
 	}
 
+	// The clone table jobs that are children of the CDC snapshot flow
+	// do not have a config entry, so allow this to be nil.
+	config := h.getQRepConfigFromCatalog(ctx, req.FlowJobName)
+	var throttle *protos.QRepThrottleStatus
+	if config != nil && (config.MaxRowsPerSecond != 0 || config.MaxBytesPerSecond != 0) {
+		running, throttled, err := monitoring.GetQRepThrottledPartitions(ctx, h.pool, req.FlowJobName)
+		if err != nil {
+			slog.Error("unable to query throttled partitions",
+				slog.String(string(shared.FlowNameKey), req.FlowJobName), slog.Any("error", err))
+			return nil, err
+		}
+		throttle = &protos.QRepThrottleStatus{
+			MaxRowsPerSecond:  config.MaxRowsPerSecond,
+			MaxBytesPerSecond: config.MaxBytesPerSecond,
+			BurstSeconds:      max(config.ThrottleBurstSeconds, 1),
+			RunningPartitions: uint32(running),
+			ThrottledSeconds:  throttled.Seconds(),
+		}
+	}
+
+	var xminLag int64
+	if config != nil && config.WatermarkColumn == "xmin" {
+		// lag only helps whoever is looking at the status, don't fail the request over it
+		state, err := h.getQRepWorkflowState(ctx, req.FlowJobName)
+		if err != nil {
+			slog.Warn("unable to get xmin lag", slog.String(string(shared.FlowNameKey), req.FlowJobName), slog.Any("error", err))
+		} else {
+			xminLag = state.XminLag
+		}
+	}
+
+	// progress only helps whoever is looking at the status, don't fail the request over it
+	initialLoad, err := monitoring.GetQRepInitialLoadProgress(ctx, h.pool, req.FlowJobName)
+	if err != nil {
+		slog.Warn("unable to get initial load progress",
+			slog.String(string(shared.FlowNameKey), req.FlowJobName), slog.Any("error", err))
+	} else if initialLoad != nil {
+		if config != nil {
+			initialLoad.TableName = config.WatermarkTable
+		}
+		initialLoad.Eta = model.InitialLoadETA(initialLoad, time.Now())
+	}
+
 	return &protos.QRepMirrorStatus{
-		// The clone table jobs that are children of the CDC snapshot flow
-		// do not have a config entry, so allow this to be nil.
-		Config:     h.getQRepConfigFromCatalog(ctx, req.FlowJobName),
-		Partitions: partitionStatuses,
+		Config:      config,
+		Partitions:  partitionStatuses,
+		Throttle:    throttle,
+		XminLag:     xminLag,
+		InitialLoad: initialLoad,
 	}, nil
 }
 
@@ -348,6 +480,13 @@ func (h *FlowRequestHandler) isCDCFlow(ctx context.Context, flowJobName string)
 
 func (h *FlowRequestHandler) getWorkflowStatus(ctx context.Context, workflowID string) (protos.FlowStatus, error) {
 	res, err := h.temporalClient.QueryWorkflow(ctx, workflowID, "", shared.FlowStatusQuery)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		// scheduled QRep mirrors have a schedule in place of a workflow
+		if status, scheduleErr := h.getQRepScheduleStatus(ctx, workflowID); scheduleErr == nil {
+			return status, nil
+		}
+	}
 	if err != nil {
 		slog.Error(fmt.Sprintf("failed to get status in workflow with ID %s: %s", workflowID, err.Error()))
 		return protos.FlowStatus_STATUS_UNKNOWN,
@@ -376,6 +515,22 @@ func (h *FlowRequestHandler) updateWorkflowStatus(
 	return nil
 }
 
+func (h *FlowRequestHandler) getQRepWorkflowState(ctx context.Context, flowJobName string) (*protos.QRepFlowState, error) {
+	workflowID, err := h.getWorkflowID(ctx, flowJobName)
+	if err != nil {
+		return nil, err
+	}
+	res, err := h.temporalClient.QueryWorkflow(ctx, workflowID, "", shared.QRepFlowStateQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state in workflow with ID %s: %w", workflowID, err)
+	}
+	var state protos.QRepFlowState
+	if err := res.Get(&state); err != nil {
+		return nil, fmt.Errorf("failed to get state in workflow with ID %s: %w", workflowID, err)
+	}
+	return &state, nil
+}
+
 func (h *FlowRequestHandler) getCDCWorkflowState(ctx context.Context,
 	workflowID string,
 ) (*peerflow.CDCFlowWorkflowState, error) {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/connectors"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// SimulateNormalize renders the statements the next normalize would run for one table of a CDC mirror,
+// using the table schema the mirror's workflow currently holds, and optionally explains them on the destination.
+func (h *FlowRequestHandler) SimulateNormalize(
+	ctx context.Context,
+	req *protos.NormalizeSimulationRequest,
+) (*protos.NormalizeSimulationResponse, error) {
+	slog.Info("Normalize simulation endpoint called",
+		slog.String(string(shared.FlowNameKey), req.FlowJobName), slog.String("table", req.TableName))
+
+	config, err := h.getFlowConfigFromCatalog(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+	workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+	state, err := h.getCDCWorkflowState(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if state.SyncFlowOptions == nil {
+		return nil, fmt.Errorf("mirror %s has not finished setup", req.FlowJobName)
+	}
+	if _, ok := state.SyncFlowOptions.TableNameSchemaMapping[req.TableName]; !ok {
+		return nil, fmt.Errorf("table %s is not a destination table of mirror %s", req.TableName, req.FlowJobName)
+	}
+
+	dstConn, err := connectors.GetConnectorAs[connectors.NormalizeSimulationConnector](ctx, config.Destination)
+	if err != nil {
+		if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
+			return nil, fmt.Errorf("normalize simulation is not supported for %s peers", config.Destination.Type)
+		}
+		return nil, fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(ctx, dstConn)
+
+	syncBatchID, err := dstConn.GetLastSyncBatchID(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last sync batch ID: %w", err)
+	}
+	simulation, err := dstConn.SimulateNormalize(ctx, &model.NormalizeRecordsRequest{
+		FlowJobName:            req.FlowJobName,
+		SyncBatchID:            syncBatchID,
+		SoftDelete:             config.SoftDelete,
+		SoftDeleteColName:      config.SoftDeleteColName,
+		SyncedAtColName:        config.SyncedAtColName,
+		TableNameSchemaMapping: state.SyncFlowOptions.TableNameSchemaMapping,
+		NumericOverflowPolicy:  config.NumericOverflowPolicy,
+	}, req.TableName, req.Explain)
+	if err != nil {
+		slog.Error("failed to simulate normalize", slog.Any("error", err),
+			slog.String(string(shared.FlowNameKey), req.FlowJobName))
+		return nil, err
+	}
+
+	statements := make([]*protos.SimulatedNormalizeStatement, 0, len(simulation.Statements))
+	for _, stmt := range simulation.Statements {
+		args := make([]string, 0, len(stmt.Args))
+		for _, arg := range stmt.Args {
+			args = append(args, fmt.Sprint(arg))
+		}
+		statements = append(statements, &protos.SimulatedNormalizeStatement{
+			Sql:  stmt.SQL,
+			Args: args,
+			Plan: stmt.Plan,
+		})
+	}
+	return &protos.NormalizeSimulationResponse{
+		StartBatchId: simulation.StartBatchID,
+		EndBatchId:   simulation.EndBatchID,
+		Statements:   statements,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// TriggerNormalize normalizes the batches a CDC mirror synced but left pending under its normalize schedule,
+// which is the only way batches of mirrors with a manual schedule are normalized.
+func (h *FlowRequestHandler) TriggerNormalize(
+	ctx context.Context,
+	req *protos.TriggerNormalizeRequest,
+) (*protos.TriggerNormalizeResponse, error) {
+	isCDC, err := h.isCDCFlow(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+	if !isCDC {
+		return nil, fmt.Errorf("mirror %s is not a CDC mirror", req.FlowJobName)
+	}
+	workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := model.NormalizeTriggerSignal.SignalClientWorkflow(ctx, h.temporalClient, workflowID, "", struct{}{}); err != nil {
+		return nil, fmt.Errorf("unable to signal workflow: %w", err)
+	}
+	slog.Info("triggered normalize", slog.String(string(shared.FlowNameKey), req.FlowJobName))
+	return &protos.TriggerNormalizeResponse{}, nil
+}
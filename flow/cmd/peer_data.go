@@ -3,32 +3,33 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/PeerDB-io/peer-flow/connectors"
 	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 )
 
 func (h *FlowRequestHandler) getPGPeerConfig(ctx context.Context, peerName string) (*protos.PostgresConfig, error) {
-	var pgPeerOptions sql.RawBytes
-	var pgPeerConfig protos.PostgresConfig
-	err := h.pool.QueryRow(ctx,
-		"SELECT options FROM peers WHERE name = $1 AND type=3", peerName).Scan(&pgPeerOptions)
+	peer, err := catalog.LoadPeer(ctx, h.pool, peerName)
 	if err != nil {
 		return nil, err
 	}
-
-	unmarshalErr := proto.Unmarshal(pgPeerOptions, &pgPeerConfig)
-	if err != nil {
-		return nil, unmarshalErr
+	pgPeerConfig := peer.GetPostgresConfig()
+	if pgPeerConfig == nil {
+		return nil, fmt.Errorf("peer %s is not a Postgres peer", peerName)
 	}
-
-	return &pgPeerConfig, nil
+	return pgPeerConfig, nil
 }
 
 func (h *FlowRequestHandler) getConnForPGPeer(ctx context.Context, peerName string) (*connpostgres.SSHTunnel, *pgx.Conn, error) {
@@ -37,7 +38,7 @@ func (h *FlowRequestHandler) getConnForPGPeer(ctx context.Context, peerName stri
 		return nil, nil, err
 	}
 
-	tunnel, err := connpostgres.NewSSHTunnel(ctx, pgPeerConfig.SshConfig)
+	tunnel, err := connpostgres.NewSSHTunnel(ctx, pgPeerConfig.SshConfig, pgPeerConfig.ProxyConfig)
 	if err != nil {
 		slog.Error("Failed to create postgres pool", slog.Any("error", err))
 		return nil, nil, err
@@ -102,7 +103,7 @@ func (h *FlowRequestHandler) GetTablesInSchema(
 	WHERE
 		n.nspname = $1
 	AND
-		t.relkind = 'r'
+		t.relkind IN ('r', 'p') AND NOT t.relispartition
 	ORDER BY
     t.relname,
     can_mirror DESC;
@@ -148,7 +149,7 @@ func (h *FlowRequestHandler) GetAllTables(
 	rows, err := peerConn.Query(ctx, "SELECT n.nspname || '.' || c.relname AS schema_table "+
 		"FROM pg_class c "+
 		"JOIN pg_namespace n ON c.relnamespace = n.oid "+
-		"WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema' AND c.relkind = 'r';")
+		"WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema' AND c.relkind IN ('r', 'p') AND NOT c.relispartition;")
 	if err != nil {
 		return &protos.AllTablesResponse{Tables: nil}, err
 	}
@@ -249,6 +250,31 @@ func (h *FlowRequestHandler) GetSlotInfo(
 	}, nil
 }
 
+// GetSlotLagHistory returns the lag of a slot recorded by the slot size workflow over a time range.
+func (h *FlowRequestHandler) GetSlotLagHistory(
+	ctx context.Context,
+	req *protos.SlotLagHistoryRequest,
+) (*protos.SlotLagHistoryResponse, error) {
+	if req.StartTime == nil {
+		return nil, errors.New("start_time is required")
+	}
+	start := req.StartTime.AsTime()
+	end := time.Now()
+	if req.EndTime != nil {
+		end = req.EndTime.AsTime()
+	}
+	if !start.Before(end) {
+		return nil, errors.New("start_time must be before end_time")
+	}
+
+	points, err := monitoring.GetSlotLagHistory(ctx, h.pool, req.PeerName, req.SlotName, start, end)
+	if err != nil {
+		slog.Error("Failed to get slot lag history", slog.Any("error", err))
+		return nil, err
+	}
+	return &protos.SlotLagHistoryResponse{Points: points}, nil
+}
+
 func (h *FlowRequestHandler) GetStatInfo(
 	ctx context.Context,
 	req *protos.PostgresPeerActivityInfoRequest,
@@ -325,3 +351,80 @@ func (h *FlowRequestHandler) GetStatInfo(
 		StatData: statInfoRows,
 	}, nil
 }
+
+func credentialExpiringSoon(ctx context.Context, expiresAt time.Time) bool {
+	alertDays := dynamicconf.PeerDBCredentialExpiryAlertDays(ctx)
+	return alertDays > 0 && time.Until(expiresAt) <= time.Duration(alertDays)*24*time.Hour
+}
+
+func (h *FlowRequestHandler) ListPeers(
+	ctx context.Context,
+	req *protos.ListPeersRequest,
+) (*protos.ListPeersResponse, error) {
+	rows, err := h.pool.Query(ctx, `SELECT p.name, p.type, e.credential, e.expires_at
+		FROM peers p LEFT JOIN peerdb_stats.peer_credential_expiry e ON e.peer_name = p.name
+		ORDER BY p.name, e.credential`)
+	if err != nil {
+		slog.Error("Failed to list peers", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+
+	var items []*protos.PeerListItem
+	var name string
+	var peerType int32
+	var credential pgtype.Text
+	var expiresAt pgtype.Timestamptz
+	_, err = pgx.ForEachRow(rows, []any{&name, &peerType, &credential, &expiresAt}, func() error {
+		if len(items) == 0 || items[len(items)-1].Name != name {
+			items = append(items, &protos.PeerListItem{
+				Name: name,
+				Type: protos.DBType(peerType),
+			})
+		}
+		if credential.Valid {
+			item := items[len(items)-1]
+			item.CredentialExpiries = append(item.CredentialExpiries, &protos.PeerCredentialExpiry{
+				Credential:   credential.String,
+				ExpiresAt:    timestamppb.New(expiresAt.Time),
+				ExpiringSoon: credentialExpiringSoon(ctx, expiresAt.Time),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to list peers", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+
+	return &protos.ListPeersResponse{Items: items}, nil
+}
+
+func (h *FlowRequestHandler) GetPeerHealth(
+	ctx context.Context,
+	req *protos.PeerHealthRequest,
+) (*protos.PeerHealthResponse, error) {
+	peer, err := catalog.LoadPeer(ctx, h.pool, req.PeerName)
+	if err != nil {
+		return nil, err
+	}
+
+	validateResp, err := h.ValidatePeer(ctx, &protos.ValidatePeerRequest{Peer: peer, ForceRefresh: req.ForceRefresh})
+	if err != nil {
+		return nil, err
+	}
+
+	expiries, err := connectors.PeerCredentialExpiries(ctx, peer)
+	if err != nil {
+		slog.Warn("Failed to check credential expiry", slog.String("peer", req.PeerName), slog.Any("error", err))
+	}
+	for _, expiry := range expiries {
+		expiry.ExpiringSoon = credentialExpiringSoon(ctx, expiry.ExpiresAt.AsTime())
+	}
+
+	return &protos.PeerHealthResponse{
+		Status:             validateResp.Status,
+		Message:            validateResp.Message,
+		CredentialExpiries: expiries,
+		ValidatedAt:        validateResp.ValidatedAt,
+	}, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/proto"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type peerValidation struct {
+	res       *protos.ValidatePeerResponse
+	expiresAt time.Time
+}
+
+// peerValidationCache keeps ValidatePeer results keyed by a hash of the peer's config,
+// so a peer is only dialed again once its config changes or the result expires.
+type peerValidationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[[sha256.Size]byte]peerValidation
+}
+
+func newPeerValidationCache(ttl time.Duration) *peerValidationCache {
+	return &peerValidationCache{
+		ttl:     ttl,
+		entries: make(map[[sha256.Size]byte]peerValidation),
+	}
+}
+
+func peerConfigKey(peer *protos.Peer) ([sha256.Size]byte, error) {
+	peerBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(peer)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("unable to marshal peer: %w", err)
+	}
+	return sha256.Sum256(peerBytes), nil
+}
+
+func (c *peerValidationCache) get(key [sha256.Size]byte) *protos.ValidatePeerResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	res := proto.Clone(entry.res).(*protos.ValidatePeerResponse)
+	res.Cached = true
+	return res
+}
+
+func (c *peerValidationCache) put(key [sha256.Size]byte, res *protos.ValidatePeerResponse) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = peerValidation{
+		res:       proto.Clone(res).(*protos.ValidatePeerResponse),
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+func (c *peerValidationCache) evictExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// revalidatePeers re-checks every peer in the catalog once per cache TTL, so GetPeerHealth
+// and ValidatePeer calls for existing peers are answered from fresh results.
+func (h *FlowRequestHandler) revalidatePeers(ctx context.Context) {
+	ttl := h.peerValidations.ttl
+	if ttl <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		h.peerValidations.evictExpired()
+
+		rows, err := h.pool.Query(ctx, "SELECT name FROM peers")
+		if err != nil {
+			slog.Warn("failed to list peers for revalidation", slog.Any("error", err))
+			continue
+		}
+		peerNames, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			slog.Warn("failed to list peers for revalidation", slog.Any("error", err))
+			continue
+		}
+
+		for _, peerName := range peerNames {
+			peer, err := catalog.LoadPeer(ctx, h.pool, peerName)
+			if err != nil {
+				slog.Warn("failed to load peer for revalidation", slog.String("peer", peerName), slog.Any("error", err))
+				continue
+			}
+			res, err := h.ValidatePeer(ctx, &protos.ValidatePeerRequest{Peer: peer, ForceRefresh: true})
+			if err != nil {
+				slog.Warn("failed to revalidate peer", slog.String("peer", peerName), slog.Any("error", err))
+			} else if res.Status != protos.ValidatePeerStatus_VALID {
+				slog.Warn("peer failed revalidation", slog.String("peer", peerName), slog.String("message", res.Message))
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+const defaultQRepRunsLimit = 100
+
+// ListQRepRuns returns a summary per run of a query replication mirror, newest first.
+func (h *FlowRequestHandler) ListQRepRuns(
+	ctx context.Context,
+	req *protos.ListQRepRunsRequest,
+) (*protos.ListQRepRunsResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultQRepRunsLimit
+	}
+	runs, err := monitoring.ListQRepRunSummaries(ctx, h.pool, req.FlowJobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.ListQRepRunsResponse{Runs: runs}, nil
+}
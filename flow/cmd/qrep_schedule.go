@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+	peerflow "github.com/PeerDB-io/peer-flow/workflows"
+)
+
+var qrepScheduleOverlapPolicies = map[protos.QRepScheduleOverlapPolicy]enums.ScheduleOverlapPolicy{
+	protos.QRepScheduleOverlapPolicy_QREP_SCHEDULE_OVERLAP_POLICY_SKIP:         enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+	protos.QRepScheduleOverlapPolicy_QREP_SCHEDULE_OVERLAP_POLICY_BUFFER_ONE:   enums.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE,
+	protos.QRepScheduleOverlapPolicy_QREP_SCHEDULE_OVERLAP_POLICY_BUFFER_ALL:   enums.SCHEDULE_OVERLAP_POLICY_BUFFER_ALL,
+	protos.QRepScheduleOverlapPolicy_QREP_SCHEDULE_OVERLAP_POLICY_CANCEL_OTHER: enums.SCHEDULE_OVERLAP_POLICY_CANCEL_OTHER,
+}
+
+// createQRepSchedule creates the Temporal Schedule starting the runs of a scheduled QRep mirror.
+// Runs are started with the initial state, later runs resume from the state the previous run saved in the catalog.
+func (h *FlowRequestHandler) createQRepSchedule(
+	ctx context.Context,
+	scheduleID string,
+	taskQueue string,
+	cfg *protos.QRepConfig,
+	state *protos.QRepFlowState,
+) error {
+	overlap, ok := qrepScheduleOverlapPolicies[cfg.Schedule.OverlapPolicy]
+	if !ok {
+		return fmt.Errorf("unsupported schedule overlap policy %s", cfg.Schedule.OverlapPolicy)
+	}
+	catchupWindow := time.Minute
+	if cfg.Schedule.CatchupWindowSeconds > 0 {
+		catchupWindow = time.Duration(cfg.Schedule.CatchupWindowSeconds) * time.Second
+	}
+
+	_, err := h.temporalClient.ScheduleClient().Create(ctx, client.ScheduleOptions{
+		ID: scheduleID,
+		Spec: client.ScheduleSpec{
+			CronExpressions: []string{cfg.Schedule.Cron},
+		},
+		Action: &client.ScheduleWorkflowAction{
+			// runs are started with the time they're due appended to the id
+			ID:        scheduleID,
+			Workflow:  peerflow.QRepFlowWorkflow,
+			Args:      []interface{}{cfg, state},
+			TaskQueue: taskQueue,
+			SearchAttributes: map[string]interface{}{
+				shared.MirrorNameSearchAttribute: cfg.FlowJobName,
+			},
+		},
+		Overlap:            overlap,
+		CatchupWindow:      catchupWindow,
+		TriggerImmediately: true,
+		SearchAttributes: map[string]interface{}{
+			shared.MirrorNameSearchAttribute: cfg.FlowJobName,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create schedule: %w", err)
+	}
+	return nil
+}
+
+// isQRepScheduled returns whether the runs of a mirror are started by a schedule, whose id is the mirror's workflow id.
+func (h *FlowRequestHandler) isQRepScheduled(ctx context.Context, flowJobName string) (bool, error) {
+	cdcFlow, err := h.isCDCFlow(ctx, flowJobName)
+	if err != nil || cdcFlow {
+		return false, err
+	}
+	return h.getQRepConfigFromCatalog(ctx, flowJobName).GetSchedule() != nil, nil
+}
+
+// getQRepScheduleStatus returns the status of a scheduled QRep mirror, running unless its schedule is paused.
+func (h *FlowRequestHandler) getQRepScheduleStatus(ctx context.Context, scheduleID string) (protos.FlowStatus, error) {
+	desc, err := h.temporalClient.ScheduleClient().GetHandle(ctx, scheduleID).Describe(ctx)
+	if err != nil {
+		return protos.FlowStatus_STATUS_UNKNOWN, fmt.Errorf("failed to describe schedule %s: %w", scheduleID, err)
+	}
+	if desc.Schedule.State != nil && desc.Schedule.State.Paused {
+		return protos.FlowStatus_STATUS_PAUSED, nil
+	}
+	return protos.FlowStatus_STATUS_RUNNING, nil
+}
+
+// qrepScheduleStateChange pauses, resumes or updates a scheduled QRep mirror. Pausing the schedule lets a running
+// run complete, no runs start until it's resumed. Config updates are signaled to the running runs.
+func (h *FlowRequestHandler) qrepScheduleStateChange(
+	ctx context.Context,
+	scheduleID string,
+	req *protos.FlowStateChangeRequest,
+) error {
+	handle := h.temporalClient.ScheduleClient().GetHandle(ctx, scheduleID)
+	desc, err := handle.Describe(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe schedule %s: %w", scheduleID, err)
+	}
+	currState := protos.FlowStatus_STATUS_RUNNING
+	if desc.Schedule.State != nil && desc.Schedule.State.Paused {
+		currState = protos.FlowStatus_STATUS_PAUSED
+	}
+
+	if update := req.FlowConfigUpdate.GetQrepFlowConfigUpdate(); update != nil {
+		if len(desc.Info.RunningWorkflows) == 0 {
+			return errors.New("config of a scheduled mirror can only be updated while a run is running")
+		}
+		for _, run := range desc.Info.RunningWorkflows {
+			if err := model.QRepDynamicPropertiesSignal.SignalClientWorkflow(
+				ctx, h.temporalClient, run.WorkflowID, "", update,
+			); err != nil {
+				return fmt.Errorf("unable to signal workflow: %w", err)
+			}
+		}
+	}
+
+	switch {
+	case req.RequestedFlowState == protos.FlowStatus_STATUS_UNKNOWN || req.RequestedFlowState == currState:
+		return nil
+	case req.RequestedFlowState == protos.FlowStatus_STATUS_PAUSED:
+		return handle.Pause(ctx, client.SchedulePauseOptions{Note: "mirror paused"})
+	case req.RequestedFlowState == protos.FlowStatus_STATUS_RUNNING:
+		return handle.Unpause(ctx, client.ScheduleUnpauseOptions{Note: "mirror resumed"})
+	case req.RequestedFlowState == protos.FlowStatus_STATUS_TERMINATED:
+		_, err := h.ShutdownFlow(ctx, &protos.ShutdownRequest{
+			WorkflowId:      scheduleID,
+			FlowJobName:     req.FlowJobName,
+			SourcePeer:      req.SourcePeer,
+			DestinationPeer: req.DestinationPeer,
+			RemoveFlowEntry: false,
+		})
+		return err
+	default:
+		return fmt.Errorf("illegal state change requested: %v, current state is: %v", req.RequestedFlowState, currState)
+	}
+}
+
+// deleteQRepSchedule deletes the schedule of a scheduled QRep mirror and cancels its running runs.
+func (h *FlowRequestHandler) deleteQRepSchedule(ctx context.Context, scheduleID string) error {
+	handle := h.temporalClient.ScheduleClient().GetHandle(ctx, scheduleID)
+	desc, err := handle.Describe(ctx)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to describe schedule %s: %w", scheduleID, err)
+	}
+	if err := handle.Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete schedule %s: %w", scheduleID, err)
+	}
+	for _, run := range desc.Info.RunningWorkflows {
+		slog.Info("canceling run of deleted schedule", slog.String("scheduleId", scheduleID),
+			slog.String("workflowId", run.WorkflowID))
+		if err := h.handleCancelWorkflow(ctx, run.WorkflowID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// ListSchemaDeltas returns the schema changes of a mirror which are queued for approval or approved
+// but not yet applied to the destination.
+func (h *FlowRequestHandler) ListSchemaDeltas(
+	ctx context.Context,
+	req *protos.ListSchemaDeltasRequest,
+) (*protos.ListSchemaDeltasResponse, error) {
+	queued, err := catalog.ListQueuedSchemaDeltas(ctx, h.pool, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make([]*protos.QueuedSchemaDelta, 0, len(queued))
+	for _, q := range queued {
+		delta := &protos.QueuedSchemaDelta{
+			Id:           q.ID,
+			Delta:        q.Delta,
+			QueuedAt:     timestamppb.New(q.QueuedAt),
+			Incompatible: q.Incompatible,
+		}
+		if q.ApprovedAt != nil {
+			delta.ApprovedAt = timestamppb.New(*q.ApprovedAt)
+		}
+		deltas = append(deltas, delta)
+	}
+	return &protos.ListSchemaDeltasResponse{Deltas: deltas}, nil
+}
+
+// ApproveSchemaDeltas approves queued schema changes, the next sync of the mirror applies them to the destination.
+// Approving the last incompatible changes a mirror paused on resumes it.
+func (h *FlowRequestHandler) ApproveSchemaDeltas(
+	ctx context.Context,
+	req *protos.ApproveSchemaDeltasRequest,
+) (*protos.ApproveSchemaDeltasResponse, error) {
+	approved, resumed, err := h.approveSchemaDeltas(ctx, req.FlowJobName, req.Ids, false, req.Resolution)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.ApproveSchemaDeltasResponse{Approved: approved, Resumed: resumed}, nil
+}
+
+// ApproveSchemaChange approves the incompatible schema changes a mirror paused on, deciding how to proceed with them,
+// and resumes it once none are left.
+func (h *FlowRequestHandler) ApproveSchemaChange(
+	ctx context.Context,
+	req *protos.ApproveSchemaChangeRequest,
+) (*protos.ApproveSchemaChangeResponse, error) {
+	approved, resumed, err := h.approveSchemaDeltas(ctx, req.FlowJobName, req.Ids, true, req.Resolution)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.ApproveSchemaChangeResponse{Approved: approved, Resumed: resumed}, nil
+}
+
+// approveSchemaDeltas approves queued schema changes of a mirror and returns how many were approved,
+// and whether the mirror was resumed.
+func (h *FlowRequestHandler) approveSchemaDeltas(
+	ctx context.Context,
+	flowJobName string,
+	ids []int64,
+	incompatibleOnly bool,
+	resolution protos.SchemaChangeResolution,
+) (uint32, bool, error) {
+	config, err := h.getFlowConfigFromCatalog(ctx, flowJobName)
+	if err != nil {
+		return 0, false, err
+	}
+	if !config.SchemaChangesRequireApproval && !config.PauseOnIncompatibleSchemaChanges {
+		return 0, false, fmt.Errorf("mirror %s does not require approval for schema changes", flowJobName)
+	}
+
+	approvals, err := catalog.ApproveSchemaDeltas(ctx, h.pool, flowJobName, ids, incompatibleOnly, resolution)
+	if err != nil {
+		return 0, false, err
+	}
+	slog.Info("approved schema deltas",
+		slog.String(string(shared.FlowNameKey), flowJobName),
+		slog.Int64("count", approvals.Approved),
+		slog.Int64("incompatible", approvals.Incompatible),
+		slog.String("resolution", resolution.String()))
+	approved := uint32(approvals.Approved)
+	if approvals.Incompatible == 0 || approvals.PendingIncompatible != 0 {
+		return approved, false, nil
+	}
+
+	workflowID, err := h.getWorkflowID(ctx, flowJobName)
+	if err != nil {
+		return 0, false, err
+	}
+	currState, err := h.getWorkflowStatus(ctx, workflowID)
+	if err != nil {
+		return 0, false, err
+	}
+	if currState != protos.FlowStatus_STATUS_PAUSED && currState != protos.FlowStatus_STATUS_PAUSING {
+		return approved, false, nil
+	}
+	if err := model.FlowSignal.SignalClientWorkflow(ctx, h.temporalClient, workflowID, "", model.NoopSignal); err != nil {
+		return 0, false, fmt.Errorf("unable to signal workflow: %w", err)
+	}
+	return approved, true, nil
+}
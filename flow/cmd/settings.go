@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// ListSettings returns the values of all dynamic settings, for a mirror when one is named, and where they come from.
+func (h *FlowRequestHandler) ListSettings(
+	ctx context.Context,
+	req *protos.ListSettingsRequest,
+) (*protos.ListSettingsResponse, error) {
+	resolved, err := dynamicconf.ResolveSettings(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve settings: %w", err)
+	}
+	settings := make([]*protos.Setting, 0, len(resolved))
+	for _, setting := range resolved {
+		settings = append(settings, &protos.Setting{
+			Name:         setting.Name,
+			Value:        setting.Value,
+			DefaultValue: setting.DefaultValue,
+			ValueType:    string(setting.Type),
+			Source:       string(setting.Source),
+			Description:  setting.Description,
+		})
+	}
+	return &protos.ListSettingsResponse{Settings: settings}, nil
+}
+
+// PostSetting sets or unsets a dynamic setting catalog-wide, or for a single mirror.
+// Workers pick up the change the next time they reload settings.
+func (h *FlowRequestHandler) PostSetting(
+	ctx context.Context,
+	req *protos.PostSettingRequest,
+) (*protos.PostSettingResponse, error) {
+	setting := dynamicconf.FindSetting(req.Name)
+	if setting == nil {
+		return nil, fmt.Errorf("unknown setting %s", req.Name)
+	}
+	if !req.Unset {
+		if err := setting.Validate(req.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.FlowJobName == "" {
+		var err error
+		if req.Unset {
+			_, err = h.pool.Exec(ctx, "DELETE FROM alerting_settings WHERE config_name=$1", req.Name)
+		} else {
+			_, err = h.pool.Exec(ctx, `INSERT INTO alerting_settings(config_name,config_value) VALUES($1,$2)
+			 ON CONFLICT(config_name) DO UPDATE SET config_value=$2`, req.Name, req.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update setting %s: %w", req.Name, err)
+		}
+		return &protos.PostSettingResponse{}, nil
+	}
+
+	var exists bool
+	if err := h.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM flows WHERE name=$1)", req.FlowJobName).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up mirror %s: %w", req.FlowJobName, err)
+	}
+	if !exists {
+		return nil, errors.New("mirror " + req.FlowJobName + " does not exist")
+	}
+	var err error
+	if req.Unset {
+		_, err = h.pool.Exec(ctx, "DELETE FROM mirror_settings WHERE flow_name=$1 AND config_name=$2",
+			req.FlowJobName, req.Name)
+	} else {
+		_, err = h.pool.Exec(ctx, `INSERT INTO mirror_settings(flow_name,config_name,config_value) VALUES($1,$2,$3)
+		 ON CONFLICT(flow_name,config_name) DO UPDATE SET config_value=$3`, req.FlowJobName, req.Name, req.Value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update setting %s of mirror %s: %w", req.Name, req.FlowJobName, err)
+	}
+	return &protos.PostSettingResponse{}, nil
+}
+
+// GetSettingsSchema documents every setting, those only read from the environment and dynamic ones,
+// for the UI to build configuration forms from.
+func (h *FlowRequestHandler) GetSettingsSchema(
+	ctx context.Context,
+	req *protos.GetSettingsSchemaRequest,
+) (*protos.GetSettingsSchemaResponse, error) {
+	envVars := peerdbenv.Registry()
+	dynamicEnvVars := dynamicconf.EnvVars()
+	settings := make([]*protos.SettingSchema, 0, len(envVars)+len(dynamicEnvVars))
+	for _, envVar := range envVars {
+		settings = append(settings, settingSchema(envVar, false))
+	}
+	for _, envVar := range dynamicEnvVars {
+		settings = append(settings, settingSchema(envVar, true))
+	}
+	return &protos.GetSettingsSchemaResponse{Settings: settings}, nil
+}
+
+func settingSchema(envVar peerdbenv.EnvVar, dynamic bool) *protos.SettingSchema {
+	return &protos.SettingSchema{
+		Name:         envVar.Name,
+		ValueType:    string(envVar.Type),
+		DefaultValue: envVar.Default,
+		Description:  envVar.Description,
+		Dynamic:      dynamic,
+	}
+}
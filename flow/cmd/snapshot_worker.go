@@ -8,13 +8,16 @@ import (
 	"os"
 
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
 
 	"github.com/PeerDB-io/peer-flow/activities"
 	utils "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
 	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
 	"github.com/PeerDB-io/peer-flow/shared"
 	"github.com/PeerDB-io/peer-flow/shared/alerting"
+	"github.com/PeerDB-io/peer-flow/shared/payloadcodec"
 	peerflow "github.com/PeerDB-io/peer-flow/workflows"
 )
 
@@ -31,6 +34,11 @@ func SnapshotWorkerMain(opts *SnapshotWorkerOptions) error {
 		Namespace: opts.TemporalNamespace,
 		Logger:    slog.New(logger.NewHandler(slog.NewJSONHandler(os.Stdout, nil))),
 	}
+	dataConverter, err := payloadcodec.NewDataConverterFromEnv(context.Background())
+	if err != nil {
+		return err
+	}
+	clientOptions.DataConverter = dataConverter
 
 	if opts.TemporalCert != "" && opts.TemporalKey != "" {
 		certs, err := Base64DecodeCertAndKey(opts.TemporalCert, opts.TemporalKey)
@@ -58,15 +66,23 @@ func SnapshotWorkerMain(opts *SnapshotWorkerOptions) error {
 		return queueErr
 	}
 
-	w := worker.New(c, taskQueue, worker.Options{
-		EnableSessionWorker: true,
-	})
-
 	conn, err := utils.GetCatalogConnectionPoolFromEnv(context.Background())
 	if err != nil {
 		return fmt.Errorf("unable to create catalog connection pool: %w", err)
 	}
 
+	maxConcurrentActivities := peerdbenv.PeerDBWorkerMaxConcurrentActivities()
+	registry := newWorkerRegistry(conn, []string{taskQueue}, maxConcurrentActivities)
+	workerOptions, err := versionedWorkerOptions(context.Background(), c, taskQueue, worker.Options{
+		EnableSessionWorker:                true,
+		MaxConcurrentActivityExecutionSize: maxConcurrentActivities,
+		Interceptors:                       []interceptor.WorkerInterceptor{registry},
+	})
+	if err != nil {
+		return err
+	}
+	w := worker.New(c, taskQueue, workerOptions)
+
 	alerter, err := alerting.NewAlerter(conn)
 	if err != nil {
 		return fmt.Errorf("unable to create alerter: %w", err)
@@ -78,6 +94,9 @@ func SnapshotWorkerMain(opts *SnapshotWorkerOptions) error {
 		Alerter:             alerter,
 	})
 
+	stopRegistry := registry.start()
+	defer stopRegistry()
+
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
 		return fmt.Errorf("worker run error: %w", err)
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.temporal.io/api/enums/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// mirrorTaskQueue returns the task queue to start a mirror's workflows on. Mirrors pinned to a dedicated task queue
+// are only started once a worker polls it, as they'd wait for one indefinitely otherwise.
+func (h *FlowRequestHandler) mirrorTaskQueue(ctx context.Context, pinnedTaskQueue string) (string, error) {
+	if pinnedTaskQueue == "" {
+		return h.peerflowTaskQueueID, nil
+	}
+	taskQueue, err := shared.GetMirrorTaskQueueName(pinnedTaskQueue)
+	if err != nil {
+		return "", err
+	}
+	for _, queueType := range []enums.TaskQueueType{enums.TASK_QUEUE_TYPE_WORKFLOW, enums.TASK_QUEUE_TYPE_ACTIVITY} {
+		res, err := h.temporalClient.DescribeTaskQueue(ctx, taskQueue, queueType)
+		if err != nil {
+			return "", fmt.Errorf("unable to describe task queue %s: %w", taskQueue, err)
+		}
+		if len(res.GetPollers()) == 0 {
+			return "", fmt.Errorf("no worker is polling task queue %s, start one with --task-queue %s",
+				taskQueue, pinnedTaskQueue)
+		}
+	}
+	return taskQueue, nil
+}
+
+// pinnedTaskQueue returns the dedicated task queue a mirror is pinned to, or "" when it isn't pinned
+// or is no longer in the catalog.
+func (h *FlowRequestHandler) pinnedTaskQueue(ctx context.Context, flowJobName string) (string, error) {
+	cdcFlow, err := h.isCDCFlow(ctx, flowJobName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if cdcFlow {
+		config, err := h.getFlowConfigFromCatalog(ctx, flowJobName)
+		if err != nil {
+			return "", err
+		}
+		return config.TaskQueue, nil
+	}
+	return h.getQRepConfigFromCatalog(ctx, flowJobName).GetTaskQueue(), nil
+}
+
+// dedicatedTaskQueues returns the dedicated task queues mirrors in the catalog are pinned to.
+func (h *FlowRequestHandler) dedicatedTaskQueues(ctx context.Context) ([]string, error) {
+	rows, err := h.pool.Query(ctx, "SELECT config_proto, query_string FROM flows WHERE config_proto IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("unable to query flow configs from catalog: %w", err)
+	}
+	var taskQueues []string
+	var configBytes []byte
+	var query pgtype.Text
+	if _, err := pgx.ForEachRow(rows, []any{&configBytes, &query}, func() error {
+		var pinned string
+		if !query.Valid || query.String == "" {
+			var config protos.FlowConnectionConfigs
+			if err := proto.Unmarshal(configBytes, &config); err != nil {
+				return fmt.Errorf("unable to unmarshal flow config: %w", err)
+			}
+			pinned = config.TaskQueue
+		} else {
+			var config protos.QRepConfig
+			if err := proto.Unmarshal(configBytes, &config); err != nil {
+				return fmt.Errorf("unable to unmarshal qrep config: %w", err)
+			}
+			pinned = config.TaskQueue
+		}
+		if pinned == "" {
+			return nil
+		}
+		taskQueue, err := shared.GetMirrorTaskQueueName(pinned)
+		if err != nil {
+			return err
+		}
+		taskQueues = append(taskQueues, taskQueue)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	slices.Sort(taskQueues)
+	return slices.Compact(taskQueues), nil
+}
@@ -5,75 +5,102 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 
-	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
-	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/client"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+	peerflow "github.com/PeerDB-io/peer-flow/workflows"
 )
 
-func (h *FlowRequestHandler) ValidateCDCMirror(
-	ctx context.Context, req *protos.CreateCDCFlowRequest,
-) (*protos.ValidateCDCMirrorResponse, error) {
-	if req.ConnectionConfigs == nil {
-		slog.Error("/validatecdc connection configs is nil")
-		return &protos.ValidateCDCMirrorResponse{
-			Ok: false,
-		}, errors.New("connection configs is nil")
-	}
-	sourcePeerConfig := req.ConnectionConfigs.Source.GetPostgresConfig()
-	if sourcePeerConfig == nil {
-		slog.Error("/validatecdc source peer config is nil", slog.Any("peer", req.ConnectionConfigs.Source))
-		return nil, errors.New("source peer config is nil")
-	}
+const (
+	defaultValidationChunks = 1 << 12
+	maxValidationChunks     = 1 << 20
+)
+
+// ValidateMirror starts validating the tables of a CDC mirror against its source, comparing their row counts
+// and hashes of their rows in chunks. Drift is recorded per table as the validation goes, see GetMirrorValidation.
+func (h *FlowRequestHandler) ValidateMirror(
+	ctx context.Context,
+	req *protos.ValidateMirrorRequest,
+) (*protos.ValidateMirrorResponse, error) {
+	slog.Info("Validate mirror endpoint called", slog.String(string(shared.FlowNameKey), req.FlowJobName))
 
-	pgPeer, err := connpostgres.NewPostgresConnector(ctx, sourcePeerConfig)
+	cdcFlow, err := h.isCDCFlow(ctx, req.FlowJobName)
 	if err != nil {
-		return &protos.ValidateCDCMirrorResponse{
-			Ok: false,
-		}, fmt.Errorf("failed to create postgres connector: %v", err)
+		return nil, err
+	} else if !cdcFlow {
+		return nil, errors.New("only CDC mirrors can be validated")
 	}
-	defer pgPeer.Close()
-
-	// Check replication connectivity
-	err = pgPeer.CheckReplicationConnectivity(ctx)
+	config, err := h.getFlowConfigFromCatalog(ctx, req.FlowJobName)
 	if err != nil {
-		return &protos.ValidateCDCMirrorResponse{
-			Ok: false,
-		}, fmt.Errorf("unable to establish replication connectivity: %v", err)
+		return nil, err
 	}
-
-	// Check permissions of postgres peer
-	err = pgPeer.CheckReplicationPermissions(ctx, sourcePeerConfig.User)
+	// tables added while the mirror ran are only in its state
+	workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
 	if err != nil {
-		return &protos.ValidateCDCMirrorResponse{
-			Ok: false,
-		}, fmt.Errorf("failed to check replication permissions: %v", err)
+		return nil, err
 	}
-
-	// Check source tables
-	sourceTables := make([]*utils.SchemaTable, 0, len(req.ConnectionConfigs.TableMappings))
-	for _, tableMapping := range req.ConnectionConfigs.TableMappings {
-		parsedTable, parseErr := utils.ParseSchemaTable(tableMapping.SourceTableIdentifier)
-		if parseErr != nil {
-			return &protos.ValidateCDCMirrorResponse{
-				Ok: false,
-			}, fmt.Errorf("invalid source table identifier: %s", tableMapping.SourceTableIdentifier)
+	if state, err := h.getCDCWorkflowState(ctx, workflowID); err == nil && state.SyncFlowOptions != nil {
+		config.TableMappings = state.SyncFlowOptions.TableMappings
+	}
+	for _, table := range req.SourceTableIdentifiers {
+		if !slices.ContainsFunc(config.TableMappings, func(mapping *protos.TableMapping) bool {
+			return mapping.SourceTableIdentifier == table
+		}) {
+			return nil, fmt.Errorf("table %s is not part of mirror %s", table, req.FlowJobName)
 		}
+	}
 
-		sourceTables = append(sourceTables, parsedTable)
+	numChunks := req.NumChunks
+	if numChunks == 0 {
+		numChunks = defaultValidationChunks
+	} else if numChunks > maxValidationChunks {
+		return nil, fmt.Errorf("number of chunks must be at most %d", maxValidationChunks)
 	}
 
-	pubName := req.ConnectionConfigs.PublicationName
-	if pubName != "" {
-		err = pgPeer.CheckSourceTables(ctx, sourceTables, pubName)
-		if err != nil {
-			return &protos.ValidateCDCMirrorResponse{
-				Ok: false,
-			}, fmt.Errorf("provided source tables invalidated: %v", err)
-		}
+	taskQueue, err := h.mirrorTaskQueue(ctx, config.TaskQueue)
+	if err != nil {
+		return nil, err
 	}
 
-	return &protos.ValidateCDCMirrorResponse{
-		Ok: true,
+	validationID := uuid.New().String()
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("%s-validate-%s", req.FlowJobName, validationID),
+		TaskQueue: taskQueue,
+		SearchAttributes: map[string]interface{}{
+			shared.MirrorNameSearchAttribute: req.FlowJobName,
+		},
+	}
+	if _, err := h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, peerflow.ValidateMirrorWorkflow,
+		&protos.ValidateMirrorInput{
+			ValidationId:           validationID,
+			Config:                 config,
+			SourceTableIdentifiers: req.SourceTableIdentifiers,
+			NumChunks:              numChunks,
+		},
+	); err != nil {
+		slog.Error("unable to start validation workflow",
+			slog.String(string(shared.FlowNameKey), req.FlowJobName), slog.Any("error", err))
+		return nil, fmt.Errorf("unable to start validation workflow: %w", err)
+	}
+	return &protos.ValidateMirrorResponse{ValidationId: validationID}, nil
+}
+
+// GetMirrorValidation returns the drift a validation of a mirror found in the tables it validated so far.
+func (h *FlowRequestHandler) GetMirrorValidation(
+	ctx context.Context,
+	req *protos.GetMirrorValidationRequest,
+) (*protos.GetMirrorValidationResponse, error) {
+	validationID, tables, err := catalog.GetMirrorValidation(ctx, h.pool, req.FlowJobName, req.ValidationId)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.GetMirrorValidationResponse{
+		ValidationId: validationID,
+		Tables:       tables,
 	}, nil
 }
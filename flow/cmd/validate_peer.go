@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
+
 	"github.com/PeerDB-io/peer-flow/connectors"
 	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
@@ -28,6 +30,29 @@ func (h *FlowRequestHandler) ValidatePeer(
 		}, nil
 	}
 
+	key, err := peerConfigKey(req.Peer)
+	if err != nil {
+		return nil, err
+	}
+	if !req.ForceRefresh {
+		if cached := h.peerValidations.get(key); cached != nil {
+			return cached, nil
+		}
+	}
+
+	res, err := h.validatePeer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res.ValidatedAt = timestamppb.Now()
+	h.peerValidations.put(key, res)
+	return res, nil
+}
+
+func (h *FlowRequestHandler) validatePeer(
+	ctx context.Context,
+	req *protos.ValidatePeerRequest,
+) (*protos.ValidatePeerResponse, error) {
 	conn, err := connectors.GetConnector(ctx, req.Peer)
 	if err != nil {
 		return &protos.ValidatePeerResponse{
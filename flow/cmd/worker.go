@@ -13,14 +13,19 @@ import (
 
 	"github.com/grafana/pyroscope-go"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
 
 	"github.com/PeerDB-io/peer-flow/activities"
 	"github.com/PeerDB-io/peer-flow/connectors"
 	utils "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
 	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
 	"github.com/PeerDB-io/peer-flow/shared"
 	"github.com/PeerDB-io/peer-flow/shared/alerting"
+	"github.com/PeerDB-io/peer-flow/shared/datacatalog"
+	"github.com/PeerDB-io/peer-flow/shared/lineage"
+	"github.com/PeerDB-io/peer-flow/shared/payloadcodec"
 	peerflow "github.com/PeerDB-io/peer-flow/workflows"
 )
 
@@ -31,6 +36,8 @@ type WorkerOptions struct {
 	TemporalNamespace string
 	TemporalCert      string
 	TemporalKey       string
+	// dedicated task queue polled instead of the default one, for mirrors pinned to it
+	TaskQueue string
 }
 
 func setupPyroscope(opts *WorkerOptions) {
@@ -95,6 +102,11 @@ func WorkerMain(opts *WorkerOptions) error {
 		Namespace: opts.TemporalNamespace,
 		Logger:    slog.New(logger.NewHandler(slog.NewJSONHandler(os.Stdout, nil))),
 	}
+	dataConverter, err := payloadcodec.NewDataConverterFromEnv(context.Background())
+	if err != nil {
+		return err
+	}
+	clientOptions.DataConverter = dataConverter
 
 	if opts.TemporalCert != "" && opts.TemporalKey != "" {
 		slog.Info("Using temporal certificate/key for authentication")
@@ -123,14 +135,23 @@ func WorkerMain(opts *WorkerOptions) error {
 	slog.Info("Created temporal client")
 	defer c.Close()
 
-	taskQueue, queueErr := shared.GetPeerFlowTaskQueueName(shared.PeerFlowTaskQueueID)
+	taskQueue, queueErr := shared.GetMirrorTaskQueueName(opts.TaskQueue)
 	if queueErr != nil {
 		return queueErr
 	}
-
-	w := worker.New(c, taskQueue, worker.Options{
-		EnableSessionWorker: true,
+	slog.Info("Polling task queue", slog.String("taskQueue", taskQueue))
+
+	maxConcurrentActivities := peerdbenv.PeerDBWorkerMaxConcurrentActivities()
+	registry := newWorkerRegistry(conn, []string{taskQueue}, maxConcurrentActivities)
+	workerOptions, err := versionedWorkerOptions(context.Background(), c, taskQueue, worker.Options{
+		EnableSessionWorker:                true,
+		MaxConcurrentActivityExecutionSize: maxConcurrentActivities,
+		Interceptors:                       []interceptor.WorkerInterceptor{registry},
 	})
+	if err != nil {
+		return err
+	}
+	w := worker.New(c, taskQueue, workerOptions)
 	peerflow.RegisterFlowWorkerWorkflows(w)
 
 	alerter, err := alerting.NewAlerter(conn)
@@ -141,9 +162,17 @@ func WorkerMain(opts *WorkerOptions) error {
 	w.RegisterActivity(&activities.FlowableActivity{
 		CatalogPool: conn,
 		Alerter:     alerter,
+		Lineage:     lineage.NewEmitterFromEnv(),
+		DataCatalog: datacatalog.NewPublisherFromEnv(),
 		CdcCache:    make(map[string]connectors.CDCPullConnector),
+
+		TemporalClient: c,
+		DataConverter:  dataConverter,
 	})
 
+	stopRegistry := registry.start()
+	defer stopRegistry()
+
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
 		return fmt.Errorf("worker run error: %w", err)
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+const (
+	workerHeartbeatInterval = 30 * time.Second
+	// workers missing this many heartbeats in a row are no longer alive
+	workerMissedHeartbeats = 3
+	// workers not heard from for this long are removed from the registry
+	workerRegistryRetention = 24 * time.Hour
+)
+
+type runningActivity struct {
+	ActivityType string    `json:"activity_type"`
+	WorkflowID   string    `json:"workflow_id"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// workerRegistry registers a worker in the catalog with periodic heartbeats, along with the activities it's running.
+// It's the worker's interceptor to keep track of those.
+type workerRegistry struct {
+	interceptor.WorkerInterceptorBase
+	pool                    *pgxpool.Pool
+	workerID                string
+	hostname                string
+	taskQueues              []string
+	buildID                 string
+	maxConcurrentActivities int
+	startedAt               time.Time
+
+	runningLock sync.Mutex
+	running     map[uint64]runningActivity
+	nextID      uint64
+}
+
+func newWorkerRegistry(pool *pgxpool.Pool, taskQueues []string, maxConcurrentActivities int) *workerRegistry {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = os.Getenv("HOSTNAME")
+	}
+	return &workerRegistry{
+		pool:                    pool,
+		workerID:                uuid.NewString(),
+		hostname:                hostname,
+		taskQueues:              taskQueues,
+		buildID:                 peerdbenv.PeerDBWorkerBuildID(),
+		maxConcurrentActivities: maxConcurrentActivities,
+		startedAt:               time.Now(),
+		running:                 make(map[uint64]runningActivity),
+	}
+}
+
+func (r *workerRegistry) InterceptActivity(
+	ctx context.Context,
+	next interceptor.ActivityInboundInterceptor,
+) interceptor.ActivityInboundInterceptor {
+	return &registryActivityInterceptor{
+		ActivityInboundInterceptorBase: interceptor.ActivityInboundInterceptorBase{Next: next},
+		registry:                       r,
+	}
+}
+
+type registryActivityInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+	registry *workerRegistry
+}
+
+func (i *registryActivityInterceptor) ExecuteActivity(
+	ctx context.Context,
+	in *interceptor.ExecuteActivityInput,
+) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	r := i.registry
+	r.runningLock.Lock()
+	id := r.nextID
+	r.nextID++
+	r.running[id] = runningActivity{
+		ActivityType: info.ActivityType.Name,
+		WorkflowID:   info.WorkflowExecution.ID,
+		StartedAt:    time.Now(),
+	}
+	r.runningLock.Unlock()
+	defer func() {
+		r.runningLock.Lock()
+		delete(r.running, id)
+		r.runningLock.Unlock()
+	}()
+
+	return i.Next.ExecuteActivity(ctx, in)
+}
+
+func (r *workerRegistry) runningActivities() []runningActivity {
+	r.runningLock.Lock()
+	defer r.runningLock.Unlock()
+	running := make([]runningActivity, 0, len(r.running))
+	for _, ra := range r.running {
+		running = append(running, ra)
+	}
+	return running
+}
+
+// heartbeat registers the worker, or refreshes its registration, and removes workers gone for long.
+func (r *workerRegistry) heartbeat(ctx context.Context) error {
+	running, err := json.Marshal(r.runningActivities())
+	if err != nil {
+		return fmt.Errorf("failed to marshal running activities: %w", err)
+	}
+	if _, err := r.pool.Exec(ctx, `INSERT INTO flow_workers(worker_id,hostname,version,build_id,task_queues,
+	 max_concurrent_activities,running_activities,started_at,last_heartbeat_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,now())
+	 ON CONFLICT(worker_id) DO UPDATE SET running_activities=$7,last_heartbeat_at=now()`,
+		r.workerID, r.hostname, peerdbenv.PeerDBVersionShaShort(), r.buildID, r.taskQueues,
+		r.maxConcurrentActivities, string(running), r.startedAt,
+	); err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+	if _, err := r.pool.Exec(ctx, "DELETE FROM flow_workers WHERE last_heartbeat_at<$1",
+		time.Now().Add(-workerRegistryRetention)); err != nil {
+		return fmt.Errorf("failed to remove stale workers: %w", err)
+	}
+	return nil
+}
+
+// run heartbeats until ctx is done, then removes the worker from the registry.
+func (r *workerRegistry) run(ctx context.Context) {
+	logger := slog.With(slog.String("workerID", r.workerID))
+	ticker := time.NewTicker(workerHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.heartbeat(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("failed to heartbeat worker", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			if _, err := r.pool.Exec(context.Background(),
+				"DELETE FROM flow_workers WHERE worker_id=$1", r.workerID); err != nil {
+				logger.Warn("failed to deregister worker", slog.Any("error", err))
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// start runs the registry in the background, the returned function stops it once the worker has.
+func (r *workerRegistry) start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// ListWorkers returns the workers registered in the catalog, with whether they're still heartbeating.
+func (h *FlowRequestHandler) ListWorkers(
+	ctx context.Context,
+	req *protos.ListWorkersRequest,
+) (*protos.ListWorkersResponse, error) {
+	rows, err := h.pool.Query(ctx, `SELECT worker_id,hostname,version,coalesce(build_id,''),task_queues,
+	 max_concurrent_activities,running_activities::text,started_at,last_heartbeat_at
+	 FROM flow_workers ORDER BY hostname,started_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workers: %w", err)
+	}
+	aliveSince := time.Now().Add(-workerMissedHeartbeats * workerHeartbeatInterval)
+	workers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.FlowWorker, error) {
+		var worker protos.FlowWorker
+		var runningJSON string
+		var startedAt, lastHeartbeatAt time.Time
+		if err := row.Scan(&worker.WorkerId, &worker.Hostname, &worker.Version, &worker.BuildId, &worker.TaskQueues,
+			&worker.MaxConcurrentActivities, &runningJSON, &startedAt, &lastHeartbeatAt); err != nil {
+			return nil, err
+		}
+		var running []runningActivity
+		if err := json.Unmarshal([]byte(runningJSON), &running); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal running activities of worker %s: %w", worker.WorkerId, err)
+		}
+		for _, ra := range running {
+			worker.RunningActivities = append(worker.RunningActivities, &protos.RunningActivity{
+				ActivityType: ra.ActivityType,
+				WorkflowId:   ra.WorkflowID,
+				StartedAt:    timestamppb.New(ra.StartedAt),
+			})
+		}
+		worker.StartedAt = timestamppb.New(startedAt)
+		worker.LastHeartbeatAt = timestamppb.New(lastHeartbeatAt)
+		worker.Alive = lastHeartbeatAt.After(aliveSince)
+		return &worker, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workers: %w", err)
+	}
+	return &protos.ListWorkersResponse{Workers: workers}, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// versionedWorkerOptions opts the worker into build id based versioning when PEERDB_WORKER_BUILD_ID is set,
+// making its build the default of the task queue. Workflows started on a previous build keep running
+// there, along with their activities, until they complete or continue as new.
+func versionedWorkerOptions(
+	ctx context.Context,
+	c client.Client,
+	taskQueue string,
+	options worker.Options,
+) (worker.Options, error) {
+	buildID := peerdbenv.PeerDBWorkerBuildID()
+	if buildID == "" {
+		return options, nil
+	}
+	if err := setDefaultBuildID(ctx, c, taskQueue, buildID); err != nil {
+		return options, err
+	}
+	options.BuildID = buildID
+	options.UseBuildIDForVersioning = true
+	return options, nil
+}
+
+func setDefaultBuildID(ctx context.Context, c client.Client, taskQueue string, buildID string) error {
+	sets, err := c.GetWorkerBuildIdCompatibility(ctx, &client.GetWorkerBuildIdCompatibilityOptions{
+		TaskQueue: taskQueue,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get build ids of task queue %s: %w", taskQueue, err)
+	}
+	if sets.Default() == buildID {
+		return nil
+	}
+
+	options := &client.UpdateWorkerBuildIdCompatibilityOptions{
+		TaskQueue: taskQueue,
+		Operation: &client.BuildIDOpAddNewIDInNewDefaultSet{BuildID: buildID},
+	}
+	for _, set := range sets.Sets {
+		if slices.Contains(set.BuildIDs, buildID) {
+			// rolling back to a previous build
+			options.Operation = &client.BuildIDOpPromoteSet{BuildID: buildID}
+		}
+	}
+	slog.Info("Setting default build id of task queue",
+		slog.String("taskQueue", taskQueue), slog.String("buildID", buildID))
+	updateErr := c.UpdateWorkerBuildIdCompatibility(ctx, options)
+	if updateErr != nil {
+		// other replicas of the same release race to do the same update
+		sets, err := c.GetWorkerBuildIdCompatibility(ctx, &client.GetWorkerBuildIdCompatibilityOptions{
+			TaskQueue: taskQueue,
+		})
+		if err == nil && sets.Default() == buildID {
+			return nil
+		}
+		return fmt.Errorf("unable to set default build id of task queue %s to %s: %w", taskQueue, buildID, updateErr)
+	}
+	return nil
+}
+
+// buildDrained is true when no new or open workflow can be dispatched to a build anymore.
+func buildDrained(reachability []client.TaskReachability) bool {
+	for _, r := range reachability {
+		if r != client.TaskReachabilityClosedWorkflows {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *FlowRequestHandler) GetWorkerBuilds(
+	ctx context.Context,
+	req *protos.WorkerBuildsRequest,
+) (*protos.WorkerBuildsResponse, error) {
+	for _, buildID := range req.BuildIds {
+		if strings.ContainsAny(buildID, `'"\`) {
+			return nil, fmt.Errorf("invalid build id %q", buildID)
+		}
+	}
+	snapshotTaskQueue, err := shared.GetPeerFlowTaskQueueName(shared.SnapshotFlowTaskQueueID)
+	if err != nil {
+		return nil, err
+	}
+
+	dedicatedTaskQueues, err := h.dedicatedTaskQueues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &protos.WorkerBuildsResponse{}
+	for _, taskQueue := range append([]string{h.peerflowTaskQueueID, snapshotTaskQueue}, dedicatedTaskQueues...) {
+		taskQueueBuilds, err := h.getTaskQueueBuilds(ctx, taskQueue, req.BuildIds)
+		if err != nil {
+			return nil, err
+		}
+		res.TaskQueues = append(res.TaskQueues, taskQueueBuilds)
+	}
+	return res, nil
+}
+
+func (h *FlowRequestHandler) getTaskQueueBuilds(
+	ctx context.Context,
+	taskQueue string,
+	requestedBuildIDs []string,
+) (*protos.TaskQueueBuilds, error) {
+	sets, err := h.temporalClient.GetWorkerBuildIdCompatibility(ctx, &client.GetWorkerBuildIdCompatibilityOptions{
+		TaskQueue: taskQueue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get build ids of task queue %s: %w", taskQueue, err)
+	}
+	buildIDs := slices.Clone(requestedBuildIDs)
+	for _, set := range sets.Sets {
+		buildIDs = append(buildIDs, set.BuildIDs...)
+	}
+	slices.Sort(buildIDs)
+	buildIDs = slices.Compact(buildIDs)
+
+	res := &protos.TaskQueueBuilds{TaskQueue: taskQueue}
+	if len(buildIDs) == 0 {
+		return res, nil
+	}
+	reachability, err := h.temporalClient.GetWorkerTaskReachability(ctx, &client.GetWorkerTaskReachabilityOptions{
+		BuildIDs:   buildIDs,
+		TaskQueues: []string{taskQueue},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get reachability of builds on task queue %s: %w", taskQueue, err)
+	}
+
+	defaultBuildID := sets.Default()
+	for _, buildID := range buildIDs {
+		var taskQueueReachability []client.TaskReachability
+		if buildReachability, ok := reachability.BuildIDReachability[buildID]; ok {
+			if reachable, ok := buildReachability.TaskQueueReachable[taskQueue]; ok {
+				taskQueueReachability = reachable.TaskQueueReachability
+			}
+		}
+		count, err := h.temporalClient.CountWorkflow(ctx, &workflowservice.CountWorkflowExecutionsRequest{
+			Query: fmt.Sprintf("TaskQueue = '%s' AND BuildIds = 'versioned:%s' AND ExecutionStatus = 'Running'",
+				taskQueue, buildID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to count running workflows of build %s: %w", buildID, err)
+		}
+		res.Builds = append(res.Builds, &protos.WorkerBuild{
+			BuildId:          buildID,
+			IsDefault:        buildID == defaultBuildID,
+			RunningWorkflows: count.Count,
+			Drained:          buildDrained(taskQueueReachability),
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,86 @@
+package connbigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// adoptedFieldType describes a field for comparing it with the columns of an adopted table
+func adoptedFieldType(fieldType bigquery.FieldType, repeated bool) string {
+	// NUMERIC columns are accepted for BIGNUMERIC, values merely need to fit
+	if fieldType == bigquery.NumericFieldType {
+		fieldType = bigquery.BigNumericFieldType
+	}
+	if repeated {
+		return fmt.Sprintf("ARRAY<%s>", fieldType)
+	}
+	return string(fieldType)
+}
+
+func (c *BigQueryConnector) CheckAdoptedTable(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+	tableIdentifier string,
+	tableSchema *protos.TableSchema,
+) error {
+	drift, err := c.DiffTable(ctx, config, tableIdentifier, tableSchema)
+	if err != nil {
+		return err
+	}
+	return drift.Err(tableIdentifier)
+}
+
+func (c *BigQueryConnector) DiffTable(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+	tableIdentifier string,
+	tableSchema *protos.TableSchema,
+) (*utils.TableDrift, error) {
+	datasetTable, err := c.convertToDatasetTable(tableIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := c.client.DatasetInProject(c.projectID, datasetTable.dataset).Table(datasetTable.table).Metadata(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), "notFound") {
+			return nil, fmt.Errorf("table %s %w: it doesn't exist", tableIdentifier, utils.ErrNotAdoptable)
+		}
+		return nil, fmt.Errorf("failed to get metadata of table %s: %w", tableIdentifier, err)
+	}
+
+	// column names are case insensitive
+	existing := make(map[string]string, len(metadata.Schema))
+	for _, field := range metadata.Schema {
+		existing[strings.ToLower(field.Name)] = adoptedFieldType(field.Type, field.Repeated)
+	}
+
+	expected := make([]utils.AdoptedColumn, 0, len(tableSchema.Columns)+2)
+	for _, column := range tableSchema.Columns {
+		expected = append(expected, utils.AdoptedColumn{
+			Name:   strings.ToLower(column.Name),
+			Type:   adoptedFieldType(qValueKindToBigQueryType(column.Type), qvalue.QValueKind(column.Type).IsArray()),
+			Source: column,
+		})
+	}
+	if config.SoftDeleteColName != "" {
+		expected = append(expected, utils.AdoptedColumn{
+			Name: strings.ToLower(config.SoftDeleteColName),
+			Type: adoptedFieldType(bigquery.BooleanFieldType, false),
+		})
+	}
+	if config.SyncedAtColName != "" {
+		expected = append(expected, utils.AdoptedColumn{
+			Name: strings.ToLower(config.SyncedAtColName),
+			Type: adoptedFieldType(bigquery.TimestampFieldType, false),
+		})
+	}
+
+	return utils.DiffAdoptedColumns(expected, existing), nil
+}
@@ -28,7 +28,9 @@ func TestAvroTransform(t *testing.T) {
 	}
 
 	expectedTransformCols := []string{
-		"ST_GEOGFROMTEXT(`col1`) AS `col1`",
+		"IF(`col1` IS NULL OR NOT STARTS_WITH(`col1`, 'SRID=') OR REGEXP_CONTAINS(`col1`, r'^SRID=(0|4326);'), " +
+			"ST_GEOGFROMTEXT(REGEXP_REPLACE(`col1`, r'^SRID=[0-9]+;', '')), " +
+			"ERROR(CONCAT('geography with an SRID other than 4326 is not supported: ', SUBSTR(`col1`, 1, 64)))) AS `col1`",
 		"PARSE_JSON(`col2`,wide_number_mode=>'round') AS `col2`",
 		"`camelCol4`",
 	}
@@ -22,10 +22,10 @@ import (
 	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	cc "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/model"
-	"github.com/PeerDB-io/peer-flow/model/numeric"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 	"github.com/PeerDB-io/peer-flow/shared"
 )
@@ -58,10 +58,20 @@ type BigQueryConnector struct {
 	logger        log.Logger
 }
 
+// ApplicationDefaultAuthType authenticates with the application default credentials of PeerDB's environment,
+// like GKE workload identity or a VM's service account, instead of a service account key.
+const ApplicationDefaultAuthType = "application_default"
+
 func NewBigQueryServiceAccount(bqConfig *protos.BigqueryConfig) (*BigQueryServiceAccount, error) {
 	var serviceAccount BigQueryServiceAccount
 	serviceAccount.Type = bqConfig.AuthType
 	serviceAccount.ProjectID = bqConfig.ProjectId
+	if serviceAccount.Type == ApplicationDefaultAuthType {
+		if serviceAccount.ProjectID == "" {
+			return nil, errors.New("project id is required with application default credentials")
+		}
+		return &serviceAccount, nil
+	}
 	serviceAccount.PrivateKeyID = bqConfig.PrivateKeyId
 	serviceAccount.PrivateKey = bqConfig.PrivateKey
 	serviceAccount.ClientEmail = bqConfig.ClientEmail
@@ -94,18 +104,26 @@ func (bqsa *BigQueryServiceAccount) ToJSON() ([]byte, error) {
 	return json.Marshal(bqsa)
 }
 
-// CreateBigQueryClient creates a new BigQuery client from a BigQueryServiceAccount.
-func (bqsa *BigQueryServiceAccount) CreateBigQueryClient(ctx context.Context) (*bigquery.Client, error) {
+// clientOptions returns the credentials clients authenticate with, none leaves them to find the default credentials.
+func (bqsa *BigQueryServiceAccount) clientOptions() ([]option.ClientOption, error) {
+	if bqsa.Type == ApplicationDefaultAuthType {
+		return nil, nil
+	}
 	bqsaJSON, err := bqsa.ToJSON()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get json: %v", err)
 	}
+	return []option.ClientOption{option.WithCredentialsJSON(bqsaJSON)}, nil
+}
 
-	client, err := bigquery.NewClient(
-		ctx,
-		bqsa.ProjectID,
-		option.WithCredentialsJSON(bqsaJSON),
-	)
+// CreateBigQueryClient creates a new BigQuery client from a BigQueryServiceAccount.
+func (bqsa *BigQueryServiceAccount) CreateBigQueryClient(ctx context.Context) (*bigquery.Client, error) {
+	opts, err := bqsa.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := bigquery.NewClient(ctx, bqsa.ProjectID, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create BigQuery client: %v", err)
 	}
@@ -115,15 +133,12 @@ func (bqsa *BigQueryServiceAccount) CreateBigQueryClient(ctx context.Context) (*
 
 // CreateStorageClient creates a new Storage client from a BigQueryServiceAccount.
 func (bqsa *BigQueryServiceAccount) CreateStorageClient(ctx context.Context) (*storage.Client, error) {
-	bqsaJSON, err := bqsa.ToJSON()
+	opts, err := bqsa.clientOptions()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get json: %v", err)
+		return nil, err
 	}
 
-	client, err := storage.NewClient(
-		ctx,
-		option.WithCredentialsJSON(bqsaJSON),
-	)
+	client, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Storage client: %v", err)
 	}
@@ -135,7 +150,9 @@ func (bqsa *BigQueryServiceAccount) CreateStorageClient(ctx context.Context) (*s
 // 1. Creates a table
 // 2. Inserts one row into the table
 // 3. Deletes the table
-func TableCheck(ctx context.Context, client *bigquery.Client, dataset string, project string) error {
+func TableCheck(ctx context.Context, client *bigquery.Client, dataset string, project string,
+	encryption *bigquery.EncryptionConfig,
+) error {
 	dummyTable := "peerdb_validate_dummy_" + shared.RandomString(4)
 
 	newTable := client.DatasetInProject(project, dataset).Table(dummyTable)
@@ -148,6 +165,7 @@ func TableCheck(ctx context.Context, client *bigquery.Client, dataset string, pr
 				Repeated: false,
 			},
 		},
+		EncryptionConfig: encryption,
 	})
 	if createErr != nil {
 		return fmt.Errorf("unable to validate table creation within dataset: %w. "+
@@ -201,13 +219,19 @@ func NewBigQueryConnector(ctx context.Context, config *protos.BigqueryConfig) (*
 		return nil, fmt.Errorf("failed to create BigQuery client: %v", err)
 	}
 
-	_, datasetErr := client.DatasetInProject(projectID, datasetID).Metadata(ctx)
+	client.Location = config.Location
+
+	datasetMetadata, datasetErr := client.DatasetInProject(projectID, datasetID).Metadata(ctx)
 	if datasetErr != nil {
 		logger.Error("failed to get dataset metadata", "error", datasetErr)
 		return nil, fmt.Errorf("failed to get dataset metadata: %v", datasetErr)
 	}
+	if config.Location != "" && !strings.EqualFold(datasetMetadata.Location, config.Location) {
+		return nil, fmt.Errorf("dataset %s is in location %s, but the peer is configured for %s",
+			datasetID, datasetMetadata.Location, config.Location)
+	}
 
-	permissionErr := TableCheck(ctx, client, datasetID, projectID)
+	permissionErr := TableCheck(ctx, client, datasetID, projectID, encryptionConfig(config))
 	if permissionErr != nil {
 		logger.Error("failed to get run mock table check", "error", permissionErr)
 		return nil, permissionErr
@@ -235,6 +259,20 @@ func NewBigQueryConnector(ctx context.Context, config *protos.BigqueryConfig) (*
 	}, nil
 }
 
+// encryptionConfig returns the customer-managed key configured for the peer, nil when there is none.
+// Tables are created with it and datasets get it as default, so DDL and DML keep their data under the key;
+// it is additionally set on jobs whose output is a new table, namely loads and SELECT results.
+func encryptionConfig(config *protos.BigqueryConfig) *bigquery.EncryptionConfig {
+	if config.KmsKeyName == "" {
+		return nil
+	}
+	return &bigquery.EncryptionConfig{KMSKeyName: config.KmsKeyName}
+}
+
+func (c *BigQueryConnector) encryptionConfig() *bigquery.EncryptionConfig {
+	return encryptionConfig(c.bqConfig)
+}
+
 // Close closes the BigQuery driver.
 func (c *BigQueryConnector) Close() error {
 	if c != nil {
@@ -281,27 +319,82 @@ func (c *BigQueryConnector) waitForTableReady(ctx context.Context, datasetTable
 	}
 }
 
+// bigQueryIntegerWidenings holds the types integer columns can be changed to in place, by their DDL names
+var bigQueryIntegerWidenings = map[bigquery.FieldType]string{
+	bigquery.FloatFieldType:      "FLOAT64",
+	bigquery.NumericFieldType:    "NUMERIC",
+	bigquery.BigNumericFieldType: "BIGNUMERIC",
+}
+
 // ReplayTableSchemaDeltas changes a destination table to match the schema at source
-// This could involve adding or dropping multiple columns.
+// This could involve adding, dropping, renaming or widening multiple columns.
 func (c *BigQueryConnector) ReplayTableSchemaDeltas(
 	ctx context.Context,
 	flowJobName string,
 	schemaDeltas []*protos.TableSchemaDelta,
 ) error {
 	for _, schemaDelta := range schemaDeltas {
-		if schemaDelta == nil || len(schemaDelta.AddedColumns) == 0 {
+		if model.IsEmptySchemaDelta(schemaDelta) {
 			continue
 		}
+		dstDatasetTable, _ := c.convertToDatasetTable(schemaDelta.DstTableName)
+		runDDL := func(ddl string) error {
+			query := c.client.Query(ddl)
+			query.DefaultProjectID = c.projectID
+			query.DefaultDatasetID = dstDatasetTable.dataset
+			_, err := readQuery(ctx, query)
+			return err
+		}
+
+		for _, droppedColumn := range schemaDelta.DroppedColumns {
+			err := runDDL(fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS `%s`", dstDatasetTable.table, droppedColumn))
+			if err != nil {
+				return fmt.Errorf("failed to drop column %s for table %s: %w", droppedColumn,
+					schemaDelta.DstTableName, err)
+			}
+			c.logger.Info(fmt.Sprintf("[schema delta replay] dropped column %s from table %s",
+				droppedColumn, schemaDelta.DstTableName))
+		}
+
+		for _, renamedColumn := range schemaDelta.RenamedColumns {
+			err := runDDL(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN IF EXISTS `%s` TO `%s`",
+				dstDatasetTable.table, renamedColumn.OldName, renamedColumn.NewName))
+			if err != nil {
+				return fmt.Errorf("failed to rename column %s to %s for table %s: %w", renamedColumn.OldName,
+					renamedColumn.NewName, schemaDelta.DstTableName, err)
+			}
+			c.logger.Info(fmt.Sprintf("[schema delta replay] renamed column %s to %s in table %s",
+				renamedColumn.OldName, renamedColumn.NewName, schemaDelta.DstTableName))
+		}
+
+		for _, alteredColumn := range schemaDelta.AlteredColumns {
+			oldType := qValueKindToBigQueryType(alteredColumn.OldType)
+			newType := qValueKindToBigQueryType(alteredColumn.NewType)
+			if oldType == newType {
+				continue
+			}
+			// BigQuery only changes the types of integer columns in place, the old column would reject other values
+			ddlType, ok := bigQueryIntegerWidenings[newType]
+			if oldType != bigquery.IntegerFieldType || !ok {
+				return fmt.Errorf("can't change type of column %s of table %s from %s to %s in place, "+
+					"change it on the destination or resync the table", alteredColumn.ColumnName,
+					schemaDelta.DstTableName, oldType, newType)
+			}
+			err := runDDL(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN `%s` SET DATA TYPE %s",
+				dstDatasetTable.table, alteredColumn.ColumnName, ddlType))
+			if err != nil {
+				return fmt.Errorf("failed to change type of column %s for table %s: %w", alteredColumn.ColumnName,
+					schemaDelta.DstTableName, err)
+			}
+			c.logger.Info(fmt.Sprintf("[schema delta replay] changed type of column %s of table %s from %s to %s",
+				alteredColumn.ColumnName, schemaDelta.DstTableName, oldType, newType))
+		}
 
 		for _, addedColumn := range schemaDelta.AddedColumns {
-			dstDatasetTable, _ := c.convertToDatasetTable(schemaDelta.DstTableName)
-			query := c.client.Query(fmt.Sprintf(
+			err := runDDL(fmt.Sprintf(
 				"ALTER TABLE %s ADD COLUMN IF NOT EXISTS `%s` %s",
 				dstDatasetTable.table, addedColumn.ColumnName,
 				qValueKindToBigQueryType(addedColumn.ColumnType)))
-			query.DefaultProjectID = c.projectID
-			query.DefaultDatasetID = dstDatasetTable.dataset
-			_, err := query.Read(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to add column %s for table %s: %w", addedColumn.ColumnName,
 					schemaDelta.DstTableName, err)
@@ -350,7 +443,8 @@ func (c *BigQueryConnector) getDistinctTableNamesInBatch(
 	q := c.client.Query(query)
 	q.DefaultProjectID = c.projectID
 	q.DefaultDatasetID = c.datasetID
-	it, err := q.Read(ctx)
+	q.DestinationEncryptionConfig = c.encryptionConfig()
+	it, err := readQuery(ctx, q)
 	if err != nil {
 		err = fmt.Errorf("failed to run query %s on BigQuery:\n %w", query, err)
 		return nil, err
@@ -397,7 +491,8 @@ func (c *BigQueryConnector) getTableNametoUnchangedCols(
 	q := c.client.Query(query)
 	q.DefaultDatasetID = c.datasetID
 	q.DefaultProjectID = c.projectID
-	it, err := q.Read(ctx)
+	q.DestinationEncryptionConfig = c.encryptionConfig()
+	it, err := readQuery(ctx, q)
 	if err != nil {
 		err = fmt.Errorf("failed to run query %s on BigQuery:\n %w", query, err)
 		return nil, err
@@ -431,7 +526,11 @@ func (c *BigQueryConnector) SyncRecords(ctx context.Context, req *model.SyncReco
 
 	c.logger.Info(fmt.Sprintf("pushing records to %s.%s...", c.datasetID, rawTableName))
 
-	res, err := c.syncRecordsViaAvro(ctx, req, rawTableName, req.SyncBatchID)
+	batch, err := c.pgMetadata.StartBatch(ctx, req.FlowJobName, req.SyncBatchID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.syncRecordsViaAvro(ctx, req, rawTableName, batch)
 	if err != nil {
 		return nil, err
 	}
@@ -444,10 +543,10 @@ func (c *BigQueryConnector) syncRecordsViaAvro(
 	ctx context.Context,
 	req *model.SyncRecordsRequest,
 	rawTableName string,
-	syncBatchID int64,
+	batch *metadataStore.SyncBatch,
 ) (*model.SyncResponse, error) {
 	tableNameRowsMapping := make(map[string]uint32)
-	streamReq := model.NewRecordsToStreamRequest(req.Records.GetRecords(), tableNameRowsMapping, syncBatchID)
+	streamReq := model.NewRecordsToStreamRequest(req.Records.GetRecords(), tableNameRowsMapping, batch.SyncBatchID)
 	streamRes, err := utils.RecordsToRawTableStream(streamReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert records to raw table stream: %w", err)
@@ -460,7 +559,7 @@ func (c *BigQueryConnector) syncRecordsViaAvro(
 	}
 
 	res, err := avroSync.SyncRecords(ctx, req, rawTableName,
-		rawTableMetadata, syncBatchID, streamRes.Stream, streamReq.TableMapping)
+		rawTableMetadata, batch, streamRes.Stream, streamReq.TableMapping)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sync records via avro: %w", err)
 	}
@@ -510,51 +609,28 @@ func (c *BigQueryConnector) NormalizeRecords(ctx context.Context, req *model.Nor
 	c.logger.Info(fmt.Sprintf("merge raw records to corresponding tables: %s %s %v",
 		c.datasetID, rawTableName, distinctTableNames))
 
-	for _, tableName := range distinctTableNames {
-		unchangedToastColumns := tableNametoUnchangedToastCols[tableName]
-		dstDatasetTable, _ := c.convertToDatasetTable(tableName)
-		mergeGen := &mergeStmtGenerator{
-			rawDatasetTable: datasetTable{
-				project: c.projectID,
-				dataset: c.datasetID,
-				table:   rawTableName,
-			},
-			dstTableName:          tableName,
-			dstDatasetTable:       dstDatasetTable,
-			normalizedTableSchema: req.TableNameSchemaMapping[tableName],
-			syncBatchID:           req.SyncBatchID,
-			normalizeBatchID:      normBatchID,
-			peerdbCols: &protos.PeerDBColumns{
-				SoftDeleteColName: req.SoftDeleteColName,
-				SyncedAtColName:   req.SyncedAtColName,
-				SoftDelete:        req.SoftDelete,
-			},
-			shortColumn: map[string]string{},
-		}
-
-		// normalize anything between last normalized batch id to last sync batchid
-		// TODO (kaushik): This is so that the statement size for individual merge statements
-		// doesn't exceed the limit. We should make this configurable.
-		const batchSize = 8
-		stmtNum := 0
-		err = utils.ArrayIterChunks(unchangedToastColumns, batchSize, func(chunk []string) error {
-			stmtNum += 1
-			mergeStmt := mergeGen.generateMergeStmt(chunk)
-			c.logger.Info(fmt.Sprintf("running merge statement %d for table %s..",
-				stmtNum, tableName))
-
-			q := c.client.Query(mergeStmt)
-			q.DefaultProjectID = c.projectID
-			q.DefaultDatasetID = dstDatasetTable.dataset
-			_, err := q.Read(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to execute merge statement %s: %v", mergeStmt, err)
-			}
-			return nil
-		})
+	if tablesPerScript := dynamicconf.PeerDBBigQueryMergeScriptTables(ctx); tablesPerScript > 0 {
+		err = c.runMergeScripts(ctx, req, normBatchID, distinctTableNames, tableNametoUnchangedToastCols, tablesPerScript)
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		for _, tableName := range distinctTableNames {
+			dstDatasetTable, _ := c.convertToDatasetTable(tableName)
+			mergeStmts := c.generateMergeStmts(req, normBatchID, tableName, tableNametoUnchangedToastCols[tableName])
+			for i, mergeStmt := range mergeStmts {
+				c.logger.Info(fmt.Sprintf("running merge statement %d for table %s..",
+					i+1, tableName))
+
+				q := c.client.Query(mergeStmt)
+				q.DefaultProjectID = c.projectID
+				q.DefaultDatasetID = dstDatasetTable.dataset
+				_, err := readQuery(ctx, q)
+				if err != nil {
+					return nil, fmt.Errorf("failed to execute merge statement %s: %v", mergeStmt, err)
+				}
+			}
+		}
 	}
 
 	err = c.pgMetadata.UpdateNormalizeBatchID(ctx, req.FlowJobName, req.SyncBatchID)
@@ -569,6 +645,140 @@ func (c *BigQueryConnector) NormalizeRecords(ctx context.Context, req *model.Nor
 	}, nil
 }
 
+// generateMergeStmts returns the statements merging a batch range of the raw table into tableName.
+func (c *BigQueryConnector) generateMergeStmts(
+	req *model.NormalizeRecordsRequest,
+	normBatchID int64,
+	tableName string,
+	unchangedToastColumns []string,
+) []string {
+	dstDatasetTable, _ := c.convertToDatasetTable(tableName)
+	mergeGen := &mergeStmtGenerator{
+		rawDatasetTable: datasetTable{
+			project: c.projectID,
+			dataset: c.datasetID,
+			table:   c.getRawTableName(req.FlowJobName),
+		},
+		dstTableName:          tableName,
+		dstDatasetTable:       dstDatasetTable,
+		normalizedTableSchema: req.TableNameSchemaMapping[tableName],
+		syncBatchID:           req.SyncBatchID,
+		normalizeBatchID:      normBatchID,
+		peerdbCols: &protos.PeerDBColumns{
+			SoftDeleteColName: req.SoftDeleteColName,
+			SyncedAtColName:   req.SyncedAtColName,
+			SoftDelete:        req.SoftDelete,
+		},
+		shortColumn:           map[string]string{},
+		numericOverflowPolicy: req.NumericOverflowPolicy,
+	}
+
+	// normalize anything between last normalized batch id to last sync batchid
+	// TODO (kaushik): This is so that the statement size for individual merge statements
+	// doesn't exceed the limit. We should make this configurable.
+	const batchSize = 8
+	var mergeStmts []string
+	_ = utils.ArrayIterChunks(unchangedToastColumns, batchSize, func(chunk []string) error {
+		mergeStmts = append(mergeStmts, mergeGen.generateMergeStmt(chunk))
+		return nil
+	})
+	return mergeStmts
+}
+
+// runMergeScripts merges tables in multi-statement scripts rather than one job per MERGE,
+// which cuts job scheduling overhead and concurrent query usage for mirrors with many tables.
+// MERGE statements name destination tables relative to the default dataset, so scripts are built per dataset.
+func (c *BigQueryConnector) runMergeScripts(
+	ctx context.Context,
+	req *model.NormalizeRecordsRequest,
+	normBatchID int64,
+	tableNames []string,
+	tableNametoUnchangedToastCols map[string][]string,
+	tablesPerScript int,
+) error {
+	datasets := make([]string, 0, 1)
+	datasetMergeStmts := make(map[string][][]string)
+	for _, tableName := range tableNames {
+		dstDatasetTable, _ := c.convertToDatasetTable(tableName)
+		if _, ok := datasetMergeStmts[dstDatasetTable.dataset]; !ok {
+			datasets = append(datasets, dstDatasetTable.dataset)
+		}
+		datasetMergeStmts[dstDatasetTable.dataset] = append(datasetMergeStmts[dstDatasetTable.dataset],
+			c.generateMergeStmts(req, normBatchID, tableName, tableNametoUnchangedToastCols[tableName]))
+	}
+
+	for _, dataset := range datasets {
+		scripts := generateMergeScripts(datasetMergeStmts[dataset], tablesPerScript)
+		for i, script := range scripts {
+			c.logger.Info(fmt.Sprintf("running merge script %d of %d for dataset %s..",
+				i+1, len(scripts), dataset))
+
+			q := c.client.Query(script)
+			q.DefaultProjectID = c.projectID
+			q.DefaultDatasetID = dataset
+			_, err := readQuery(ctx, q)
+			if err != nil {
+				return fmt.Errorf("failed to execute merge script for dataset %s: %w", dataset, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SimulateNormalize renders the MERGE statements NormalizeRecords would run for a table.
+// BigQuery has no EXPLAIN, so a dry run is used instead: it validates the statement and reports the bytes it would scan.
+func (c *BigQueryConnector) SimulateNormalize(
+	ctx context.Context,
+	req *model.NormalizeRecordsRequest,
+	tableName string,
+	explain bool,
+) (*model.NormalizeSimulation, error) {
+	normBatchID, err := c.GetLastNormalizeBatchID(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch for the current mirror: %v", err)
+	}
+	simulation := &model.NormalizeSimulation{
+		StartBatchID: normBatchID + 1,
+		EndBatchID:   req.SyncBatchID,
+	}
+	if normBatchID >= req.SyncBatchID {
+		return simulation, nil
+	}
+
+	tableNametoUnchangedToastCols, err := c.getTableNametoUnchangedCols(
+		ctx,
+		req.FlowJobName,
+		req.SyncBatchID,
+		normBatchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get tablename to unchanged cols mapping: %w", err)
+	}
+
+	dstDatasetTable, _ := c.convertToDatasetTable(tableName)
+	for _, mergeStmt := range c.generateMergeStmts(req, normBatchID, tableName, tableNametoUnchangedToastCols[tableName]) {
+		stmt := model.NormalizeStatement{SQL: mergeStmt}
+		if explain {
+			q := c.client.Query(mergeStmt)
+			q.DefaultProjectID = c.projectID
+			q.DefaultDatasetID = dstDatasetTable.dataset
+			q.DryRun = true
+			job, err := q.Run(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to dry run merge statement: %w", err)
+			}
+			status := job.LastStatus()
+			if err := status.Err(); err != nil {
+				return nil, fmt.Errorf("failed to dry run merge statement: %w", err)
+			}
+			stmt.Plan = fmt.Sprintf("dry run succeeded, %d bytes would be processed",
+				status.Statistics.TotalBytesProcessed)
+		}
+		simulation.Statements = append(simulation.Statements, stmt)
+	}
+	return simulation, nil
+}
+
 // CreateRawTable creates a raw table, implementing the Connector interface.
 // create a table with the following schema
 // _peerdb_uid STRING
@@ -627,6 +837,7 @@ func (c *BigQueryConnector) CreateRawTable(ctx context.Context, req *protos.Crea
 		Schema:            schema,
 		RangePartitioning: partitioning,
 		Clustering:        clustering,
+		EncryptionConfig:  c.encryptionConfig(),
 		Name:              rawTableName,
 	}
 
@@ -657,10 +868,9 @@ func (c *BigQueryConnector) CleanupSetupNormalizedTables(_ context.Context, _ in
 func (c *BigQueryConnector) SetupNormalizedTable(
 	ctx context.Context,
 	tx interface{},
+	config *protos.SetupNormalizedTableBatchInput,
 	tableIdentifier string,
 	tableSchema *protos.TableSchema,
-	softDeleteColName string,
-	syncedAtColName string,
 ) (bool, error) {
 	datasetTablesSet := tx.(map[datasetTable]struct{})
 
@@ -685,7 +895,10 @@ func (c *BigQueryConnector) SetupNormalizedTable(
 				datasetTable.dataset, err)
 		}
 		c.logger.Info(fmt.Sprintf("creating dataset %s...", dataset.DatasetID))
-		err = dataset.Create(ctx, nil)
+		err = dataset.Create(ctx, &bigquery.DatasetMetadata{
+			Location:                c.bqConfig.Location,
+			DefaultEncryptionConfig: c.encryptionConfig(),
+		})
 		if err != nil {
 			return false, fmt.Errorf("failed to create BigQuery dataset %s: %w", dataset.DatasetID, err)
 		}
@@ -704,11 +917,7 @@ func (c *BigQueryConnector) SetupNormalizedTable(
 	for _, column := range tableSchema.Columns {
 		genericColType := column.Type
 		if genericColType == "numeric" {
-			precision, scale := numeric.ParseNumericTypmod(column.TypeModifier)
-			if column.TypeModifier == -1 || precision > 38 || scale > 37 {
-				precision = numeric.PeerDBNumericPrecision
-				scale = numeric.PeerDBNumericScale
-			}
+			precision, scale := qvalue.DetermineNumericSettingForTypmod(column.TypeModifier, qvalue.QDWHTypeBigQuery)
 			columns = append(columns, &bigquery.FieldSchema{
 				Name:      column.Name,
 				Type:      bigquery.BigNumericFieldType,
@@ -725,17 +934,17 @@ func (c *BigQueryConnector) SetupNormalizedTable(
 		}
 	}
 
-	if softDeleteColName != "" {
+	if config.SoftDeleteColName != "" {
 		columns = append(columns, &bigquery.FieldSchema{
-			Name:     softDeleteColName,
+			Name:     config.SoftDeleteColName,
 			Type:     bigquery.BooleanFieldType,
 			Repeated: false,
 		})
 	}
 
-	if syncedAtColName != "" {
+	if config.SyncedAtColName != "" {
 		columns = append(columns, &bigquery.FieldSchema{
-			Name:     syncedAtColName,
+			Name:     config.SyncedAtColName,
 			Type:     bigquery.TimestampFieldType,
 			Repeated: false,
 		})
@@ -744,19 +953,32 @@ func (c *BigQueryConnector) SetupNormalizedTable(
 	// create the table using the columns
 	schema := bigquery.Schema(columns)
 
-	// cluster by the primary key if < 4 columns.
-	var clustering *bigquery.Clustering
+	var tableSettings *protos.BigqueryTableSettings
+	for _, tm := range config.TableMappings {
+		if tm.DestinationTableIdentifier == tableIdentifier {
+			tableSettings = tm.Bigquery
+			break
+		}
+	}
+	timePartitioning, clustering, err := getPartitioningAndClustering(tableSettings)
+	if err != nil {
+		return false, fmt.Errorf("invalid partitioning for table %s: %w", tableIdentifier, err)
+	}
+
+	// cluster by the primary key if < 4 columns, synthetic keys may include columns which can't be clustered on.
 	numPkeyCols := len(tableSchema.PrimaryKeyColumns)
-	if numPkeyCols > 0 && numPkeyCols < 4 {
+	if clustering == nil && numPkeyCols > 0 && numPkeyCols < 4 && !tableSchema.SyntheticPrimaryKey {
 		clustering = &bigquery.Clustering{
 			Fields: tableSchema.PrimaryKeyColumns,
 		}
 	}
 
 	metadata := &bigquery.TableMetadata{
-		Schema:     schema,
-		Name:       datasetTable.table,
-		Clustering: clustering,
+		Schema:           schema,
+		Name:             datasetTable.table,
+		TimePartitioning: timePartitioning,
+		Clustering:       clustering,
+		EncryptionConfig: c.encryptionConfig(),
 	}
 
 	err = table.Create(ctx, metadata)
@@ -865,7 +1087,7 @@ func (c *BigQueryConnector) RenameTables(ctx context.Context, req *protos.Rename
 
 			query.DefaultProjectID = c.projectID
 			query.DefaultDatasetID = c.datasetID
-			_, err := query.Read(ctx)
+			_, err := readQuery(ctx, query)
 			if err != nil {
 				return nil, fmt.Errorf("unable to handle soft-deletes for table %s: %w", dstDatasetTable.string(), err)
 			}
@@ -886,7 +1108,7 @@ func (c *BigQueryConnector) RenameTables(ctx context.Context, req *protos.Rename
 
 			query.DefaultProjectID = c.projectID
 			query.DefaultDatasetID = c.datasetID
-			_, err := query.Read(ctx)
+			_, err := readQuery(ctx, query)
 			if err != nil {
 				return nil, fmt.Errorf("unable to set synced at column for table %s: %w", srcDatasetTable.string(), err)
 			}
@@ -897,7 +1119,7 @@ func (c *BigQueryConnector) RenameTables(ctx context.Context, req *protos.Rename
 		dropQuery := c.client.Query("DROP TABLE IF EXISTS " + dstDatasetTable.string())
 		dropQuery.DefaultProjectID = c.projectID
 		dropQuery.DefaultDatasetID = c.datasetID
-		_, err = dropQuery.Read(ctx)
+		_, err = readQuery(ctx, dropQuery)
 		if err != nil {
 			return nil, fmt.Errorf("unable to drop table %s: %w", dstDatasetTable.string(), err)
 		}
@@ -909,7 +1131,7 @@ func (c *BigQueryConnector) RenameTables(ctx context.Context, req *protos.Rename
 			srcDatasetTable.string(), dstDatasetTable.table))
 		query.DefaultProjectID = c.projectID
 		query.DefaultDatasetID = c.datasetID
-		_, err = query.Read(ctx)
+		_, err = readQuery(ctx, query)
 		if err != nil {
 			return nil, fmt.Errorf("unable to rename table %s to %s: %w", srcDatasetTable.string(),
 				dstDatasetTable.string(), err)
@@ -928,6 +1150,13 @@ func (c *BigQueryConnector) CreateTablesFromExisting(
 	ctx context.Context,
 	req *protos.CreateTablesFromExistingInput,
 ) (*protos.CreateTablesFromExistingOutput, error) {
+	tableSettings := make(map[string]*protos.BigqueryTableSettings, len(req.TableMappings))
+	for _, tm := range req.TableMappings {
+		if tm.Bigquery != nil {
+			tableSettings[tm.DestinationTableIdentifier] = tm.Bigquery
+		}
+	}
+
 	for newTable, existingTable := range req.NewToExistingTableMapping {
 		newDatasetTable, _ := c.convertToDatasetTable(newTable)
 		existingDatasetTable, _ := c.convertToDatasetTable(existingTable)
@@ -935,14 +1164,22 @@ func (c *BigQueryConnector) CreateTablesFromExisting(
 
 		activity.RecordHeartbeat(ctx, fmt.Sprintf("creating table '%s' similar to '%s'", newTable, existingTable))
 
-		// rename the src table to dst
-		query := c.client.Query(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` LIKE `%s`",
-			newDatasetTable.string(), existingDatasetTable.string()))
-		query.DefaultProjectID = c.projectID
-		query.DefaultDatasetID = c.datasetID
-		_, err := query.Read(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("unable to create table %s: %w", newTable, err)
+		if settings, ok := tableSettings[existingTable]; ok {
+			// LIKE can't change the partitioning or clustering it copies, create from the existing metadata instead
+			err := c.createTableWithSettings(ctx, &newDatasetTable, &existingDatasetTable, settings)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create table %s: %w", newTable, err)
+			}
+		} else {
+			// rename the src table to dst
+			query := c.client.Query(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` LIKE `%s`",
+				newDatasetTable.string(), existingDatasetTable.string()))
+			query.DefaultProjectID = c.projectID
+			query.DefaultDatasetID = c.datasetID
+			_, err := readQuery(ctx, query)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create table %s: %w", newTable, err)
+			}
 		}
 
 		c.logger.Info(fmt.Sprintf("successfully created table '%s'", newTable))
@@ -953,6 +1190,97 @@ func (c *BigQueryConnector) CreateTablesFromExisting(
 	}, nil
 }
 
+func (c *BigQueryConnector) createTableWithSettings(
+	ctx context.Context,
+	newDatasetTable *datasetTable,
+	existingDatasetTable *datasetTable,
+	settings *protos.BigqueryTableSettings,
+) error {
+	existingMetadata, err := c.client.DatasetInProject(c.projectID, existingDatasetTable.dataset).
+		Table(existingDatasetTable.table).Metadata(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of table %s: %w", existingDatasetTable.string(), err)
+	}
+
+	metadata, err := tableMetadataWithSettings(existingMetadata, settings)
+	if err != nil {
+		return err
+	}
+	metadata.Name = newDatasetTable.table
+	metadata.EncryptionConfig = c.encryptionConfig()
+
+	newTable := c.client.DatasetInProject(c.projectID, newDatasetTable.dataset).Table(newDatasetTable.table)
+	if _, err := newTable.Metadata(ctx); err == nil {
+		return nil
+	}
+	return newTable.Create(ctx, metadata)
+}
+
+// tableMetadataWithSettings returns the metadata of a table like existing with the partitioning and clustering
+// of settings, keeping those of existing which settings don't specify.
+func tableMetadataWithSettings(
+	existing *bigquery.TableMetadata,
+	settings *protos.BigqueryTableSettings,
+) (*bigquery.TableMetadata, error) {
+	timePartitioning, clustering, err := getPartitioningAndClustering(settings)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &bigquery.TableMetadata{
+		Schema:                 existing.Schema,
+		TimePartitioning:       timePartitioning,
+		Clustering:             clustering,
+		RequirePartitionFilter: existing.RequirePartitionFilter,
+	}
+	if timePartitioning == nil {
+		metadata.TimePartitioning = existing.TimePartitioning
+		metadata.RangePartitioning = existing.RangePartitioning
+	}
+	if clustering == nil {
+		metadata.Clustering = existing.Clustering
+	}
+	return metadata, nil
+}
+
+// getPartitioningAndClustering converts table settings from the mirror config,
+// nil is returned for anything that was not specified.
+func getPartitioningAndClustering(
+	settings *protos.BigqueryTableSettings,
+) (*bigquery.TimePartitioning, *bigquery.Clustering, error) {
+	var timePartitioning *bigquery.TimePartitioning
+	if settings.GetPartitionColumn() != "" {
+		var partitioningType bigquery.TimePartitioningType
+		switch strings.ToUpper(settings.PartitionGranularity) {
+		case "", "DAY":
+			partitioningType = bigquery.DayPartitioningType
+		case "HOUR":
+			partitioningType = bigquery.HourPartitioningType
+		case "MONTH":
+			partitioningType = bigquery.MonthPartitioningType
+		case "YEAR":
+			partitioningType = bigquery.YearPartitioningType
+		default:
+			return nil, nil, fmt.Errorf("unsupported partition granularity %s", settings.PartitionGranularity)
+		}
+		timePartitioning = &bigquery.TimePartitioning{
+			Type:  partitioningType,
+			Field: settings.PartitionColumn,
+		}
+	}
+
+	var clustering *bigquery.Clustering
+	if clusterColumns := settings.GetClusterColumns(); len(clusterColumns) > 0 {
+		if len(clusterColumns) > 4 {
+			return nil, nil, fmt.Errorf("at most 4 clustering columns are supported, got %d", len(clusterColumns))
+		}
+		clustering = &bigquery.Clustering{
+			Fields: clusterColumns,
+		}
+	}
+
+	return timePartitioning, clustering, nil
+}
+
 type datasetTable struct {
 	project string
 	dataset string
@@ -0,0 +1,112 @@
+package connbigquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestGetPartitioningAndClustering(t *testing.T) {
+	timePartitioning, clustering, err := getPartitioningAndClustering(nil)
+	if err != nil || timePartitioning != nil || clustering != nil {
+		t.Errorf("Expected no partitioning or clustering without settings. Got: %v, %v, %v",
+			timePartitioning, clustering, err)
+	}
+
+	timePartitioning, clustering, err = getPartitioningAndClustering(&protos.BigqueryTableSettings{
+		PartitionColumn:      "created_at",
+		PartitionGranularity: "month",
+		ClusterColumns:       []string{"tenant_id", "id"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedPartitioning := &bigquery.TimePartitioning{
+		Type:  bigquery.MonthPartitioningType,
+		Field: "created_at",
+	}
+	if !reflect.DeepEqual(timePartitioning, expectedPartitioning) {
+		t.Errorf("Time partitioning is not correct. Got: %v", timePartitioning)
+	}
+	if !reflect.DeepEqual(clustering.Fields, []string{"tenant_id", "id"}) {
+		t.Errorf("Clustering is not correct. Got: %v", clustering.Fields)
+	}
+
+	_, _, err = getPartitioningAndClustering(&protos.BigqueryTableSettings{
+		PartitionColumn:      "created_at",
+		PartitionGranularity: "WEEK",
+	})
+	if err == nil {
+		t.Error("Expected error for unsupported partition granularity")
+	}
+
+	_, _, err = getPartitioningAndClustering(&protos.BigqueryTableSettings{
+		ClusterColumns: []string{"a", "b", "c", "d", "e"},
+	})
+	if err == nil {
+		t.Error("Expected error for more than 4 clustering columns")
+	}
+}
+
+func TestTableMetadataWithSettings(t *testing.T) {
+	existing := &bigquery.TableMetadata{
+		Schema: bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+		RangePartitioning: &bigquery.RangePartitioning{
+			Field: "id",
+			Range: &bigquery.RangePartitioningRange{Start: 0, End: 1000, Interval: 10},
+		},
+		Clustering:             &bigquery.Clustering{Fields: []string{"id"}},
+		RequirePartitionFilter: true,
+	}
+
+	metadata, err := tableMetadataWithSettings(existing, &protos.BigqueryTableSettings{
+		ClusterColumns: []string{"tenant_id"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.RangePartitioning != existing.RangePartitioning || !metadata.RequirePartitionFilter {
+		t.Errorf("Partitioning of the existing table is not kept. Got: %v", metadata.RangePartitioning)
+	}
+	if !reflect.DeepEqual(metadata.Clustering.Fields, []string{"tenant_id"}) {
+		t.Errorf("Clustering is not correct. Got: %v", metadata.Clustering.Fields)
+	}
+
+	metadata, err = tableMetadataWithSettings(existing, &protos.BigqueryTableSettings{
+		PartitionColumn: "created_at",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.RangePartitioning != nil || metadata.TimePartitioning.Field != "created_at" {
+		t.Errorf("Partitioning is not replaced. Got: %v, %v", metadata.TimePartitioning, metadata.RangePartitioning)
+	}
+	if metadata.Clustering != existing.Clustering {
+		t.Errorf("Clustering of the existing table is not kept. Got: %v", metadata.Clustering)
+	}
+}
+
+func TestJSONColumnsUseNativeType(t *testing.T) {
+	for _, kind := range []qvalue.QValueKind{qvalue.QValueKindJSON, qvalue.QValueKindHStore} {
+		if fieldType := qValueKindToBigQueryType(string(kind)); fieldType != bigquery.JSONFieldType {
+			t.Errorf("Expected %s to map to JSON, got %s", kind, fieldType)
+		}
+	}
+
+	m := &mergeStmtGenerator{
+		normalizedTableSchema: &protos.TableSchema{
+			Columns: []*protos.FieldDescription{{Name: "doc", Type: string(qvalue.QValueKindJSON)}},
+		},
+		shortColumn: map[string]string{"doc": "_c0"},
+		peerdbCols:  &protos.PeerDBColumns{},
+	}
+	if cte := m.generateFlattenedCTE(); !strings.Contains(cte,
+		"CAST(PARSE_JSON(JSON_VALUE(_peerdb_data, '$.doc'),wide_number_mode=>'round') AS JSON) AS `_c0`") {
+		t.Errorf("Expected JSON column to be parsed into native JSON. Got: %s", cte)
+	}
+}
@@ -0,0 +1,50 @@
+package connbigquery
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/logger"
+)
+
+// readQuery runs q like Query.Read, bounded by the activity's deadline. BigQuery runs jobs to completion
+// whether or not anyone waits for them, so the job is cancelled if ctx is done before it finishes.
+func readQuery(ctx context.Context, q *bigquery.Query) (*bigquery.RowIterator, error) {
+	q.JobTimeout = utils.StatementTimeout(ctx)
+	job, err := q.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	it, err := job.Read(ctx)
+	if err != nil {
+		cancelAbandonedJob(ctx, job)
+		return nil, err
+	}
+	return it, nil
+}
+
+// waitJob waits for job like Job.Wait, cancelling it if ctx is done before it finishes.
+func waitJob(ctx context.Context, job *bigquery.Job) (*bigquery.JobStatus, error) {
+	status, err := job.Wait(ctx)
+	if err != nil {
+		cancelAbandonedJob(ctx, job)
+		return nil, err
+	}
+	return status, nil
+}
+
+func cancelAbandonedJob(ctx context.Context, job *bigquery.Job) {
+	if ctx.Err() == nil {
+		return
+	}
+	cancelCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := job.Cancel(cancelCtx); err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to cancel BigQuery job",
+			slog.String("jobID", job.ID()), slog.Any("error", err))
+	}
+}
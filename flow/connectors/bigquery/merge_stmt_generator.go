@@ -11,6 +11,9 @@ import (
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 )
 
+// maximum length of a query or script BigQuery accepts, in characters
+const maxMergeScriptLength = 1024 * 1024
+
 type mergeStmtGenerator struct {
 	// dataset + raw table
 	rawDatasetTable datasetTable
@@ -28,6 +31,8 @@ type mergeStmtGenerator struct {
 	peerdbCols *protos.PeerDBColumns
 	// map for shorter columns
 	shortColumn map[string]string
+	// what happens to numerics which don't fit their columns
+	numericOverflowPolicy protos.NumericOverflowPolicy
 }
 
 // generateFlattenedCTE generates a flattened CTE.
@@ -61,9 +66,18 @@ func (m *mergeStmtGenerator) generateFlattenedCTE() string {
 			castStmt = fmt.Sprintf("ARRAY(SELECT CAST(element AS %s) FROM "+
 				"UNNEST(CAST(JSON_VALUE_ARRAY(_peerdb_data, '$.%s') AS ARRAY<STRING>)) AS element WHERE element IS NOT null) AS `%s`",
 				bqType, column.Name, shortCol)
+		case qvalue.QValueKindNumeric:
+			// SAFE_CAST nulls values with too many integer digits, CAST fails on them
+			precision, scale := qvalue.DetermineNumericSettingForTypmod(column.TypeModifier, qvalue.QDWHTypeBigQuery)
+			castFunc := "SAFE_CAST"
+			if m.numericOverflowPolicy == protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ERROR {
+				castFunc = "CAST"
+			}
+			castStmt = fmt.Sprintf("%s(JSON_VALUE(_peerdb_data, '$.%s') AS BIGNUMERIC(%d, %d)) AS `%s`",
+				castFunc, column.Name, precision, scale, shortCol)
 		case qvalue.QValueKindGeography, qvalue.QValueKindGeometry, qvalue.QValueKindPoint:
-			castStmt = fmt.Sprintf("CAST(ST_GEOGFROMTEXT(JSON_VALUE(_peerdb_data, '$.%s')) AS %s) AS `%s`",
-				column.Name, bqType, shortCol)
+			castStmt = fmt.Sprintf("CAST(%s AS %s) AS `%s`",
+				geographyFromText(fmt.Sprintf("JSON_VALUE(_peerdb_data, '$.%s')", column.Name)), bqType, shortCol)
 		// MAKE_INTERVAL(years INT64, months INT64, days INT64, hours INT64, minutes INT64, seconds INT64)
 		// Expecting interval to be in the format of {"Microseconds":2000000,"Days":0,"Months":0,"Valid":true}
 		// json.Marshal in SyncRecords for Postgres already does this - once new data-stores are added,
@@ -118,6 +132,9 @@ func (m *mergeStmtGenerator) generateDeDupedCTE() string {
 
 	pkeyColsStr := fmt.Sprintf("(CONCAT(%s))", strings.Join(shortPkeys,
 		", '_peerdb_concat_', "))
+	if m.normalizedTableSchema.SyntheticPrimaryKey {
+		pkeyColsStr = syntheticKeyHash(shortPkeys)
+	}
 	return fmt.Sprintf(cte, pkeyColsStr)
 }
 
@@ -158,6 +175,17 @@ func (m *mergeStmtGenerator) generateMergeStmt(unchangedToastColumns []string) s
 	}
 	// t.<pkey1> = d.<pkey1> AND t.<pkey2> = d.<pkey2> ...
 	pkeySelectSQL := strings.Join(pkeySelectSQLArray, " AND ")
+	if m.normalizedTableSchema.SyntheticPrimaryKey {
+		// synthetic keys span all columns, which may be NULL or of types without equality like JSON
+		targetCols := make([]string, 0, len(m.normalizedTableSchema.PrimaryKeyColumns))
+		sourceCols := make([]string, 0, len(m.normalizedTableSchema.PrimaryKeyColumns))
+		for _, pkeyColName := range m.normalizedTableSchema.PrimaryKeyColumns {
+			shortCol := m.shortColumn[pkeyColName]
+			targetCols = append(targetCols, fmt.Sprintf("_t.`%s` AS %s", pkeyColName, shortCol))
+			sourceCols = append(sourceCols, "_d."+shortCol)
+		}
+		pkeySelectSQL = syntheticKeyHash(targetCols) + "=" + syntheticKeyHash(sourceCols)
+	}
 
 	deletePart := "DELETE"
 	if m.peerdbCols.SoftDelete {
@@ -177,6 +205,50 @@ func (m *mergeStmtGenerator) generateMergeStmt(unchangedToastColumns []string) s
 		pkeySelectSQL, insertColumnsSQL, insertValuesSQL, updateStringToastCols, deletePart)
 }
 
+// syntheticKeyHash hashes all columns of a row, keying tables without a primary key
+func syntheticKeyHash(cols []string) string {
+	return fmt.Sprintf("FARM_FINGERPRINT(TO_JSON_STRING(STRUCT(%s)))", strings.Join(cols, ","))
+}
+
+// generateMergeScripts packs the merge statements of several tables into multi-statement scripts,
+// each of which runs as a single BigQuery job. A script holds at most tablesPerScript tables and stays under
+// the script length limit. Statements of a table are kept in one script unless they alone exceed the limit,
+// in which case each of them becomes its own script.
+func generateMergeScripts(tableMergeStmts [][]string, tablesPerScript int) []string {
+	scripts := make([]string, 0, len(tableMergeStmts))
+	script := strings.Builder{}
+	numTables := 0
+	flush := func() {
+		if numTables > 0 {
+			scripts = append(scripts, script.String())
+			script.Reset()
+			numTables = 0
+		}
+	}
+
+	for _, mergeStmts := range tableMergeStmts {
+		tableLength := 0
+		for _, mergeStmt := range mergeStmts {
+			tableLength += len(mergeStmt) + 1
+		}
+		if tableLength > maxMergeScriptLength {
+			flush()
+			scripts = append(scripts, mergeStmts...)
+			continue
+		}
+		if numTables >= tablesPerScript || script.Len()+tableLength > maxMergeScriptLength {
+			flush()
+		}
+		for _, mergeStmt := range mergeStmts {
+			script.WriteString(mergeStmt)
+			script.WriteString("\n")
+		}
+		numTables += 1
+	}
+	flush()
+	return scripts
+}
+
 /*
 This function takes an array of unique unchanged toast column groups and an array of all column names,
 and returns suitable UPDATE statements as part of a MERGE operation.
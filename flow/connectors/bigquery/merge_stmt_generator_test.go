@@ -2,6 +2,7 @@ package connbigquery
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
@@ -187,3 +188,31 @@ func TestGenerateUpdateStatement_WithUnchangedToastColsAndSoftDelete(t *testing.
 		t.Errorf("Unexpected result. Expected: %v,\nbut got: %v", expected, result)
 	}
 }
+
+func TestGenerateMergeScripts(t *testing.T) {
+	tableMergeStmts := [][]string{
+		{"MERGE `a` _t;"},
+		{"MERGE `b` _t;", "MERGE `b` _t;"},
+		{"MERGE `c` _t;"},
+	}
+
+	expected := []string{
+		"MERGE `a` _t;\nMERGE `b` _t;\nMERGE `b` _t;\n",
+		"MERGE `c` _t;\n",
+	}
+	result := generateMergeScripts(tableMergeStmts, 2)
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Unexpected scripts. Expected: %v, but got: %v", expected, result)
+	}
+
+	largeStmt := "MERGE `large` _t; -- " + strings.Repeat("x", maxMergeScriptLength)
+	expected = []string{
+		"MERGE `a` _t;\n",
+		largeStmt,
+		"MERGE `c` _t;\n",
+	}
+	result = generateMergeScripts([][]string{tableMergeStmts[0], {largeStmt}, tableMergeStmts[2]}, 10)
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected a statement over the length limit to be run on its own. Got: %d scripts", len(result))
+	}
+}
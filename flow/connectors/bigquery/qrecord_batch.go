@@ -0,0 +1,199 @@
+package connbigquery
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"google.golang.org/api/iterator"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func toQValue(bqValue bigquery.Value) (qvalue.QValue, error) {
+	// Based on the real type of the bigquery.Value, we create a qvalue.QValue
+	switch v := bqValue.(type) {
+	case int, int32:
+		return qvalue.New(qvalue.QValueKindInt32, v), nil
+	case int64:
+		return qvalue.New(qvalue.QValueKindInt64, v), nil
+	case float32:
+		return qvalue.New(qvalue.QValueKindFloat32, v), nil
+	case float64:
+		return qvalue.New(qvalue.QValueKindFloat64, v), nil
+	case string:
+		return qvalue.New(qvalue.QValueKindString, v), nil
+	case bool:
+		return qvalue.New(qvalue.QValueKindBoolean, v), nil
+	case civil.Date:
+		return qvalue.New(qvalue.QValueKindDate, v.In(time.UTC)), nil
+	case civil.Time:
+		return qvalue.New(qvalue.QValueKindTime, v), nil
+	case time.Time:
+		return qvalue.New(qvalue.QValueKindTimestamp, v), nil
+	case *big.Rat:
+		return qvalue.New(qvalue.QValueKindNumeric, v), nil
+	case []uint8:
+		return qvalue.New(qvalue.QValueKindBytes, v), nil
+	case []bigquery.Value:
+		// If the type is an array, we need to convert each element
+		// we can assume all elements are of the same type, let us use first element
+		if len(v) == 0 {
+			return qvalue.QValue{Kind: qvalue.QValueKindInvalid}, nil
+		}
+
+		firstElement := v[0]
+		switch et := firstElement.(type) {
+		case int, int32:
+			var arr []int32
+			for _, val := range v {
+				arr = append(arr, val.(int32))
+			}
+			return qvalue.New(qvalue.QValueKindArrayInt32, arr), nil
+		case int64:
+			var arr []int64
+			for _, val := range v {
+				arr = append(arr, val.(int64))
+			}
+			return qvalue.New(qvalue.QValueKindArrayInt64, arr), nil
+		case float32:
+			var arr []float32
+			for _, val := range v {
+				arr = append(arr, val.(float32))
+			}
+			return qvalue.New(qvalue.QValueKindArrayFloat32, arr), nil
+		case float64:
+			var arr []float64
+			for _, val := range v {
+				arr = append(arr, val.(float64))
+			}
+			return qvalue.New(qvalue.QValueKindArrayFloat64, arr), nil
+		case string:
+			var arr []string
+			for _, val := range v {
+				arr = append(arr, val.(string))
+			}
+			return qvalue.New(qvalue.QValueKindArrayString, arr), nil
+		case time.Time:
+			var arr []time.Time
+			for _, val := range v {
+				arr = append(arr, val.(time.Time))
+			}
+			return qvalue.New(qvalue.QValueKindArrayTimestamp, arr), nil
+		case civil.Date:
+			var arr []civil.Date
+			for _, val := range v {
+				arr = append(arr, val.(civil.Date))
+			}
+			return qvalue.New(qvalue.QValueKindArrayDate, arr), nil
+		case bool:
+			var arr []bool
+
+			for _, val := range v {
+				arr = append(arr, val.(bool))
+			}
+			return qvalue.New(qvalue.QValueKindArrayBoolean, arr), nil
+		default:
+			// If type is unsupported, return error
+			return qvalue.QValue{}, fmt.Errorf("unsupported BigQuery type %T", et)
+		}
+
+	case nil:
+		return qvalue.QValue{Kind: qvalue.QValueKindInvalid}, nil
+	default:
+		// If type is unsupported, return error
+		return qvalue.QValue{}, fmt.Errorf("unsupported BigQuery type %T", v)
+	}
+}
+
+func bqFieldSchemaToQField(fieldSchema *bigquery.FieldSchema) (model.QField, error) {
+	qValueKind, err := BigQueryTypeToQValueKind(fieldSchema.Type)
+	if err != nil {
+		return model.QField{}, err
+	}
+
+	return model.QField{
+		Name:     fieldSchema.Name,
+		Type:     qValueKind,
+		Nullable: !fieldSchema.Required,
+	}, nil
+}
+
+// bqSchemaToQRecordSchema converts a bigquery schema to a QRecordSchema.
+func bqSchemaToQRecordSchema(schema bigquery.Schema) (*model.QRecordSchema, error) {
+	fields := make([]model.QField, 0, len(schema))
+	for _, fieldSchema := range schema {
+		qField, err := bqFieldSchemaToQField(fieldSchema)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, qField)
+	}
+
+	return &model.QRecordSchema{
+		Fields: fields,
+	}, nil
+}
+
+// ReadQRecordBatch runs a query and collects its results into a QRecordBatch.
+func ReadQRecordBatch(ctx context.Context, q *bigquery.Query) (*model.QRecordBatch, error) {
+	var records [][]qvalue.QValue
+	it, err := scanQuery(ctx, q, func(qValues []qvalue.QValue) error {
+		records = append(records, qValues)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var schema *model.QRecordSchema
+	if it.Schema != nil {
+		schema, err = bqSchemaToQRecordSchema(it.Schema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.QRecordBatch{
+		Records: records,
+		Schema:  schema,
+	}, nil
+}
+
+// scanQuery runs a query and calls fn with each row, returning the exhausted iterator for the result's schema.
+func scanQuery(ctx context.Context, q *bigquery.Query, fn func([]qvalue.QValue) error) (*bigquery.RowIterator, error) {
+	it, err := readQuery(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command: %w", err)
+	}
+
+	for {
+		var row []bigquery.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over query results: %w", err)
+		}
+
+		// Convert []bigquery.Value to []qvalue.QValue
+		qValues := make([]qvalue.QValue, len(row))
+		for i, val := range row {
+			qv, err := toQValue(val)
+			if err != nil {
+				return nil, err
+			}
+			qValues[i] = qv
+		}
+
+		if err := fn(qValues); err != nil {
+			return nil, err
+		}
+	}
+	return it, nil
+}
@@ -2,11 +2,16 @@ package connbigquery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
@@ -29,23 +34,72 @@ func (c *BigQueryConnector) SyncQRepRecords(
 	if err != nil {
 		return 0, err
 	}
+	c.logger.Info(fmt.Sprintf("QRep sync function called for partition %s of destination table %s",
+		partition.PartitionId, destTable))
 
-	done, err := c.pgMetadata.IsQrepPartitionSynced(ctx, config.FlowJobName, partition.PartitionId)
-	if err != nil {
-		return 0, fmt.Errorf("failed to check if partition %s is synced: %w", partition.PartitionId, err)
+	avroSync := NewQRepAvroSyncMethod(c, config.StagingPath, config.FlowJobName)
+	if config.NativeImport {
+		return avroSync.ExportQRepRecords(ctx, config, partition, tblMetadata, stream)
 	}
+	return avroSync.SyncQRepRecords(ctx, config.FlowJobName, destTable, partition,
+		tblMetadata, stream, config.SyncedAtColName, config.SoftDeleteColName)
+}
 
-	if done {
-		c.logger.Info(fmt.Sprintf("Partition %s has already been synced", partition.PartitionId))
-		return 0, nil
+// ConsolidateQRepPartitions imports partitions exported to GCS when native import is enabled.
+func (c *BigQueryConnector) ConsolidateQRepPartitions(ctx context.Context, config *protos.QRepConfig) error {
+	if !config.NativeImport {
+		return nil
 	}
-	c.logger.Info(fmt.Sprintf("QRep sync function called and partition existence checked for"+
-		" partition %s of destination table %s",
-		partition.PartitionId, destTable))
 
 	avroSync := NewQRepAvroSyncMethod(c, config.StagingPath, config.FlowJobName)
-	return avroSync.SyncQRepRecords(ctx, config.FlowJobName, destTable, partition,
-		tblMetadata, stream, config.SyncedAtColName, config.SoftDeleteColName)
+	return avroSync.ImportQRepPartitions(ctx, config)
+}
+
+// CleanupQRepFlow removes the files exported to GCS when native import is enabled.
+func (c *BigQueryConnector) CleanupQRepFlow(ctx context.Context, config *protos.QRepConfig) error {
+	if !config.NativeImport {
+		return nil
+	}
+
+	bucket := c.storageClient.Bucket(config.StagingPath)
+	it := bucket.Objects(ctx, &storage.Query{Prefix: config.FlowJobName + "/"})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list exported files: %w", err)
+		}
+		if err := bucket.Object(attrs.Name).Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete exported file %s: %w", attrs.Name, err)
+		}
+	}
+
+	dstDatasetTable, err := c.convertToDatasetTable(config.DestinationTableIdentifier)
+	if err != nil {
+		return err
+	}
+	importsTable := &datasetTable{
+		project: c.projectID,
+		dataset: dstDatasetTable.dataset,
+		table:   qrepImportsTableName,
+	}
+	if _, err := c.client.DatasetInProject(c.projectID, importsTable.dataset).Table(importsTable.table).Metadata(ctx); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to get metadata of table %s: %w", importsTable.string(), err)
+	}
+	query := c.client.Query(fmt.Sprintf("DELETE FROM `%s` WHERE flow_job_name = @flowJobName", importsTable.string()))
+	query.DefaultDatasetID = c.datasetID
+	query.DefaultProjectID = c.projectID
+	query.Parameters = []bigquery.QueryParameter{{Name: "flowJobName", Value: config.FlowJobName}}
+	if _, err := readQuery(ctx, query); err != nil {
+		return fmt.Errorf("failed to delete imported partitions of flow: %w", err)
+	}
+	return nil
 }
 
 func (c *BigQueryConnector) replayTableSchemaDeltasQRep(
@@ -103,7 +157,7 @@ func (c *BigQueryConnector) SetupQRepMetadataTables(ctx context.Context, config
 		query := c.client.Query("TRUNCATE TABLE " + config.DestinationTableIdentifier)
 		query.DefaultDatasetID = c.datasetID
 		query.DefaultProjectID = c.projectID
-		_, err := query.Read(ctx)
+		_, err := readQuery(ctx, query)
 		if err != nil {
 			return fmt.Errorf("failed to TRUNCATE table before query replication: %w", err)
 		}
@@ -3,16 +3,19 @@ package connbigquery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
-	"time"
 
 	"cloud.google.com/go/bigquery"
 	"go.temporal.io/sdk/activity"
+	"google.golang.org/api/iterator"
 
+	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	avro "github.com/PeerDB-io/peer-flow/connectors/utils/avro"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
@@ -22,6 +25,9 @@ import (
 	"github.com/PeerDB-io/peer-flow/shared"
 )
 
+// BigQuery load jobs accept at most 10,000 source URIs
+const maxLoadJobURIs = 10000
+
 type QRepAvroSyncMethod struct {
 	connector   *BigQueryConnector
 	gcsBucket   string
@@ -43,10 +49,11 @@ func (s *QRepAvroSyncMethod) SyncRecords(
 	req *model.SyncRecordsRequest,
 	rawTableName string,
 	dstTableMetadata *bigquery.TableMetadata,
-	syncBatchID int64,
+	batch *metadataStore.SyncBatch,
 	stream *model.QRecordStream,
 	tableNameRowsMapping map[string]uint32,
 ) (*model.SyncResponse, error) {
+	syncBatchID := batch.SyncBatchID
 	activity.RecordHeartbeat(ctx,
 		fmt.Sprintf("Flow job %s: Obtaining Avro schema"+
 			" for destination table %s and sync batch ID %d",
@@ -71,8 +78,12 @@ func (s *QRepAvroSyncMethod) SyncRecords(
 
 	bqClient := s.connector.client
 	datasetID := s.connector.datasetID
-	insertStmt := fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s`;",
-		rawTableName, stagingTable)
+	insertStmt := fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s`;", rawTableName, stagingTable)
+	if batch.Retry() {
+		// rows an earlier attempt at the batch inserted are replaced, as a retry may pull a different set of records
+		insertStmt = fmt.Sprintf("BEGIN TRANSACTION; DELETE FROM `%s` WHERE _peerdb_batch_id = %d; %s COMMIT TRANSACTION;",
+			rawTableName, syncBatchID, insertStmt)
+	}
 
 	activity.RecordHeartbeat(ctx,
 		fmt.Sprintf("Flow job %s: performing insert and update transaction"+
@@ -88,12 +99,15 @@ func (s *QRepAvroSyncMethod) SyncRecords(
 	query := bqClient.Query(insertStmt)
 	query.DefaultDatasetID = s.connector.datasetID
 	query.DefaultProjectID = s.connector.projectID
-	_, err = query.Read(ctx)
+	_, err = readQuery(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute statements in a transaction: %w", err)
 	}
 
-	lastCP := req.Records.GetLastCheckpoint()
+	lastCP, err := req.Records.GetLastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
 	err = s.connector.pgMetadata.FinishBatch(ctx, req.FlowJobName, syncBatchID, lastCP)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update metadata: %w", err)
@@ -130,7 +144,7 @@ func getTransformedColumns(dstSchema *bigquery.Schema, syncedAtCol string, softD
 		switch col.Type {
 		case bigquery.GeographyFieldType:
 			transformedColumns = append(transformedColumns,
-				fmt.Sprintf("ST_GEOGFROMTEXT(`%s`) AS `%s`", col.Name, col.Name))
+				fmt.Sprintf("%s AS `%s`", geographyFromText("`"+col.Name+"`"), col.Name))
 		case bigquery.JSONFieldType:
 			transformedColumns = append(transformedColumns,
 				fmt.Sprintf("PARSE_JSON(`%s`,wide_number_mode=>'round') AS `%s`", col.Name, col.Name))
@@ -151,7 +165,6 @@ func (s *QRepAvroSyncMethod) SyncQRepRecords(
 	syncedAtCol string,
 	softDeleteCol string,
 ) (int, error) {
-	startTime := time.Now()
 	flowLog := slog.Group("sync_metadata",
 		slog.String(string(shared.FlowNameKey), flowJobName),
 		slog.String(string(shared.PartitionIDKey), partition.PartitionId),
@@ -182,8 +195,244 @@ func (s *QRepAvroSyncMethod) SyncQRepRecords(
 			" destination table %s and partition ID %s",
 		flowJobName, dstTableName, partition.PartitionId),
 	)
-	bqClient := s.connector.client
+	s.connector.logger.Info("Performing transaction inside QRep sync function", flowLog)
+	err = s.insertFromStagingTable(ctx, dstTableName, stagingDatasetTable, dstTableMetadata, syncedAtCol, softDeleteCol)
+	if err != nil {
+		return -1, err
+	}
+
+	s.dropStagingTable(ctx, stagingDatasetTable, flowLog)
+	s.connector.logger.Info("loaded stage into "+dstTableName, flowLog)
+	return numRecords, nil
+}
+
+// ExportQRepRecords only writes the partition to GCS, exported partitions are
+// loaded into the destination table together by ImportQRepPartitions.
+func (s *QRepAvroSyncMethod) ExportQRepRecords(
+	ctx context.Context,
+	config *protos.QRepConfig,
+	partition *protos.QRepPartition,
+	dstTableMetadata *bigquery.TableMetadata,
+	stream *model.QRecordStream,
+) (int, error) {
+	if s.gcsBucket == "" {
+		return 0, errors.New("native import requires a GCS staging bucket")
+	}
+
+	avroSchema, err := DefineAvroSchema(config.DestinationTableIdentifier, dstTableMetadata,
+		config.SyncedAtColName, config.SoftDeleteColName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to define Avro schema: %w", err)
+	}
+
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		return "exporting partition " + partition.PartitionId
+	})
+	defer shutdown()
+
+	objectPath := fmt.Sprintf("%s/%s.avro", s.flowJobName, partition.PartitionId)
+	// canceling the writer's context aborts the upload, so a failed export doesn't leave a partial object behind
+	writerCtx, cancelWriter := context.WithCancel(ctx)
+	defer cancelWriter()
+	w := s.connector.storageClient.Bucket(s.gcsBucket).Object(objectPath).NewWriter(writerCtx)
+	ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema,
+		avro.StagingCodec(qvalue.QDWHTypeBigQuery, avro.CompressNone), qvalue.QDWHTypeBigQuery)
+	numRecords, err := ocfWriter.WriteOCF(ctx, w)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write records to Avro file on GCS: %w", err)
+	}
+	// the object is only committed once the writer is closed
+	if err := w.Close(); err != nil {
+		return 0, fmt.Errorf("failed to upload Avro file to GCS: %w", err)
+	}
+
+	if numRecords > 0 {
+		err = s.connector.pgMetadata.FinishQrepPartitionExport(ctx, s.flowJobName, partition.PartitionId, objectPath, numRecords)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record exported partition: %w", err)
+		}
+	}
+
+	s.connector.logger.Info(fmt.Sprintf("exported %d records to gs://%s/%s", numRecords, s.gcsBucket, objectPath),
+		slog.String(string(shared.PartitionIDKey), partition.PartitionId))
+	return numRecords, nil
+}
 
+// qrepImportsTableName is the table in a destination table's dataset recording the partitions imported into it,
+// in the same transaction as their rows, so retries skip partitions imported before checkpointing them in the catalog.
+const qrepImportsTableName = "_peerdb_qrep_imports"
+
+// ImportQRepPartitions loads all exported but not yet imported partitions into the destination table.
+// Files are loaded in chunks that stay within the URI limit of a load job, each chunk is
+// checkpointed in the catalog so that a retry only imports what is left.
+func (s *QRepAvroSyncMethod) ImportQRepPartitions(ctx context.Context, config *protos.QRepConfig) error {
+	pending, err := s.connector.pgMetadata.GetPendingQrepImports(ctx, s.flowJobName)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		s.connector.logger.Info("no exported partitions pending import")
+		return nil
+	}
+
+	dstTableName := config.DestinationTableIdentifier
+	dstDatasetTable, err := s.connector.convertToDatasetTable(dstTableName)
+	if err != nil {
+		return err
+	}
+	dstTableMetadata, err := s.connector.client.DatasetInProject(s.connector.projectID, dstDatasetTable.dataset).
+		Table(dstDatasetTable.table).Metadata(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of table %s: %w", dstDatasetTable.string(), err)
+	}
+	stagingDatasetTable := &datasetTable{
+		project: s.connector.projectID,
+		dataset: dstDatasetTable.dataset,
+		table:   dstDatasetTable.table + "_import_staging",
+	}
+	flowLog := slog.Group("import_metadata",
+		slog.String(string(shared.FlowNameKey), s.flowJobName),
+		slog.String("destinationTable", dstTableName),
+	)
+
+	importsTable := &datasetTable{
+		project: s.connector.projectID,
+		dataset: dstDatasetTable.dataset,
+		table:   qrepImportsTableName,
+	}
+	imported, err := s.importedPartitions(ctx, importsTable, pending)
+	if err != nil {
+		return err
+	}
+	if len(imported) > 0 {
+		// an earlier attempt inserted them but failed to checkpoint them
+		if err := s.connector.pgMetadata.FinishQrepImport(ctx, s.flowJobName, imported); err != nil {
+			return fmt.Errorf("failed to checkpoint imported partitions: %w", err)
+		}
+		for _, partitionID := range imported {
+			delete(pending, partitionID)
+		}
+	}
+
+	partitionIDs := make([]string, 0, len(pending))
+	for partitionID := range pending {
+		partitionIDs = append(partitionIDs, partitionID)
+	}
+	slices.Sort(partitionIDs)
+
+	for start := 0; start < len(partitionIDs); start += maxLoadJobURIs {
+		chunk := partitionIDs[start:min(start+maxLoadJobURIs, len(partitionIDs))]
+		uris := make([]string, 0, len(chunk))
+		for _, partitionID := range chunk {
+			uris = append(uris, fmt.Sprintf("gs://%s/%s", s.gcsBucket, pending[partitionID]))
+		}
+
+		gcsRef := bigquery.NewGCSReference(uris...)
+		gcsRef.SourceFormat = bigquery.Avro
+		loader := s.connector.client.DatasetInProject(s.connector.projectID, stagingDatasetTable.dataset).
+			Table(stagingDatasetTable.table).LoaderFrom(gcsRef)
+		loader.UseAvroLogicalTypes = true
+		loader.DecimalTargetTypes = []bigquery.DecimalTargetType{bigquery.BigNumericTargetType}
+		loader.WriteDisposition = bigquery.WriteTruncate
+		loader.DestinationEncryptionConfig = s.connector.encryptionConfig()
+		loader.JobTimeout = utils.StatementTimeout(ctx)
+		job, err := loader.Run(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to run BigQuery load job: %w", err)
+		}
+		status, err := waitJob(ctx, job)
+		if err != nil {
+			return fmt.Errorf("failed to wait for BigQuery load job: %w", err)
+		}
+		if err := status.Err(); err != nil {
+			return fmt.Errorf("failed to load exported files into BigQuery table: %w", err)
+		}
+		if err := s.connector.waitForTableReady(ctx, stagingDatasetTable); err != nil {
+			return fmt.Errorf("failed to wait for table to be ready: %w", err)
+		}
+
+		insertStmt := insertFromStagingStmt(dstTableName, stagingDatasetTable, dstTableMetadata,
+			config.SyncedAtColName, config.SoftDeleteColName)
+		query := s.connector.client.Query(fmt.Sprintf(`BEGIN TRANSACTION; %s
+			INSERT INTO `+"`%s`"+` (flow_job_name, partition_id, imported_at)
+			SELECT @flowJobName, partition_id, CURRENT_TIMESTAMP() FROM UNNEST(@partitionIDs) AS partition_id;
+			COMMIT TRANSACTION;`, insertStmt, importsTable.string()))
+		query.DefaultDatasetID = s.connector.datasetID
+		query.DefaultProjectID = s.connector.projectID
+		query.Parameters = []bigquery.QueryParameter{
+			{Name: "flowJobName", Value: s.flowJobName},
+			{Name: "partitionIDs", Value: chunk},
+		}
+		if _, err := readQuery(ctx, query); err != nil {
+			return fmt.Errorf("failed to insert imported partitions into destination table: %w", err)
+		}
+		if err := s.connector.pgMetadata.FinishQrepImport(ctx, s.flowJobName, chunk); err != nil {
+			return fmt.Errorf("failed to checkpoint imported partitions: %w", err)
+		}
+		s.connector.logger.Info(fmt.Sprintf("imported %d exported partitions", len(chunk)), flowLog)
+	}
+
+	s.dropStagingTable(ctx, stagingDatasetTable, flowLog)
+	return nil
+}
+
+// importedPartitions returns the pending partitions recorded in importsTable by an earlier import, creating it if needed.
+func (s *QRepAvroSyncMethod) importedPartitions(
+	ctx context.Context,
+	importsTable *datasetTable,
+	pending map[string]string,
+) ([]string, error) {
+	createQuery := s.connector.client.Query(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS `%s` (flow_job_name STRING, partition_id STRING, imported_at TIMESTAMP)",
+		importsTable.string()))
+	createQuery.DefaultDatasetID = s.connector.datasetID
+	createQuery.DefaultProjectID = s.connector.projectID
+	if _, err := readQuery(ctx, createQuery); err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %w", importsTable.string(), err)
+	}
+
+	partitionIDs := make([]string, 0, len(pending))
+	for partitionID := range pending {
+		partitionIDs = append(partitionIDs, partitionID)
+	}
+	query := s.connector.client.Query(fmt.Sprintf(
+		"SELECT partition_id FROM `%s` WHERE flow_job_name = @flowJobName AND partition_id IN UNNEST(@partitionIDs)",
+		importsTable.string()))
+	query.DefaultDatasetID = s.connector.datasetID
+	query.DefaultProjectID = s.connector.projectID
+	query.Parameters = []bigquery.QueryParameter{
+		{Name: "flowJobName", Value: s.flowJobName},
+		{Name: "partitionIDs", Value: partitionIDs},
+	}
+	it, err := readQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query imported partitions: %w", err)
+	}
+	var imported []string
+	for {
+		var row []bigquery.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read imported partitions: %w", err)
+		}
+		if partitionID, ok := row[0].(string); ok {
+			imported = append(imported, partitionID)
+		}
+	}
+	return imported, nil
+}
+
+// insertFromStagingStmt returns the statement inserting the rows of a staging table into the destination table.
+func insertFromStagingStmt(
+	dstTableName string,
+	stagingTable *datasetTable,
+	dstTableMetadata *bigquery.TableMetadata,
+	syncedAtCol string,
+	softDeleteCol string,
+) string {
 	transformedColumns := getTransformedColumns(&dstTableMetadata.Schema, syncedAtCol, softDeleteCol)
 	selector := strings.Join(transformedColumns, ", ")
 
@@ -194,35 +443,37 @@ func (s *QRepAvroSyncMethod) SyncQRepRecords(
 		selector += ", CURRENT_TIMESTAMP"
 	}
 	// Insert the records from the staging table into the destination table
-	insertStmt := fmt.Sprintf("INSERT INTO `%s` SELECT %s FROM `%s`;",
-		dstTableName, selector, stagingDatasetTable.string())
-
-	s.connector.logger.Info("Performing transaction inside QRep sync function", flowLog)
+	return fmt.Sprintf("INSERT INTO `%s` SELECT %s FROM `%s`;",
+		dstTableName, selector, stagingTable.string())
+}
 
-	query := bqClient.Query(insertStmt)
+func (s *QRepAvroSyncMethod) insertFromStagingTable(
+	ctx context.Context,
+	dstTableName string,
+	stagingTable *datasetTable,
+	dstTableMetadata *bigquery.TableMetadata,
+	syncedAtCol string,
+	softDeleteCol string,
+) error {
+	insertStmt := insertFromStagingStmt(dstTableName, stagingTable, dstTableMetadata, syncedAtCol, softDeleteCol)
+	query := s.connector.client.Query(insertStmt)
 	query.DefaultDatasetID = s.connector.datasetID
 	query.DefaultProjectID = s.connector.projectID
-	_, err = query.Read(ctx)
+	_, err := readQuery(ctx, query)
 	if err != nil {
-		return -1, fmt.Errorf("failed to execute statements in a transaction: %w", err)
-	}
-
-	err = s.connector.pgMetadata.FinishQrepPartition(ctx, partition, flowJobName, startTime)
-	if err != nil {
-		return -1, err
+		return fmt.Errorf("failed to execute statements in a transaction: %w", err)
 	}
+	return nil
+}
 
-	// drop the staging table
-	if err := bqClient.DatasetInProject(s.connector.projectID, stagingDatasetTable.dataset).
-		Table(stagingDatasetTable.table).Delete(ctx); err != nil {
+func (s *QRepAvroSyncMethod) dropStagingTable(ctx context.Context, stagingTable *datasetTable, flowLog slog.Attr) {
+	if err := s.connector.client.DatasetInProject(s.connector.projectID, stagingTable.dataset).
+		Table(stagingTable.table).Delete(ctx); err != nil {
 		// just log the error this isn't fatal.
-		s.connector.logger.Warn("failed to delete staging table "+stagingDatasetTable.string(),
+		s.connector.logger.Warn("failed to delete staging table "+stagingTable.string(),
 			slog.Any("error", err),
 			flowLog)
 	}
-
-	s.connector.logger.Info("loaded stage into "+dstTableName, flowLog)
-	return numRecords, nil
 }
 
 type AvroField struct {
@@ -406,7 +657,8 @@ func (s *QRepAvroSyncMethod) writeToStage(
 	defer shutdown()
 
 	var avroFile *avro.AvroFile
-	ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, avro.CompressNone, qvalue.QDWHTypeBigQuery)
+	ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema,
+		avro.StagingCodec(qvalue.QDWHTypeBigQuery, avro.CompressNone), qvalue.QDWHTypeBigQuery)
 	idLog := slog.Group("write-metadata",
 		slog.String(string(shared.FlowNameKey), flowName),
 		slog.String("batchOrPartitionID", syncID),
@@ -468,12 +720,14 @@ func (s *QRepAvroSyncMethod) writeToStage(
 	loader.UseAvroLogicalTypes = true
 	loader.DecimalTargetTypes = []bigquery.DecimalTargetType{bigquery.BigNumericTargetType}
 	loader.WriteDisposition = bigquery.WriteTruncate
+	loader.DestinationEncryptionConfig = s.connector.encryptionConfig()
+	loader.JobTimeout = utils.StatementTimeout(ctx)
 	job, err := loader.Run(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to run BigQuery load job: %w", err)
 	}
 
-	status, err := job.Wait(ctx)
+	status, err := waitJob(ctx, job)
 	if err != nil {
 		return 0, fmt.Errorf("failed to wait for BigQuery load job: %w", err)
 	}
@@ -24,7 +24,7 @@ func qValueKindToBigQueryType(colType string) bigquery.FieldType {
 	// string related
 	case qvalue.QValueKindString:
 		return bigquery.StringFieldType
-	// json also is stored as string for now
+	// json is stored natively, values are staged as strings and converted with PARSE_JSON
 	case qvalue.QValueKindJSON, qvalue.QValueKindHStore:
 		return bigquery.JSONFieldType
 	// time related
@@ -92,3 +92,12 @@ func BigQueryTypeToQValueKind(fieldType bigquery.FieldType) (qvalue.QValueKind,
 		return "", fmt.Errorf("unsupported bigquery field type: %v", fieldType)
 	}
 }
+
+// geographyFromText returns SQL parsing expr, text geometries are replicated as, to a geography.
+// Geometries come as WKT prefixed with their SRID when they have one, like SRID=4326;POINT(1 2).
+// BigQuery geographies are always WGS84, so other SRIDs fail replication rather than being dropped.
+func geographyFromText(expr string) string {
+	return fmt.Sprintf("IF(%[1]s IS NULL OR NOT STARTS_WITH(%[1]s, 'SRID=') OR REGEXP_CONTAINS(%[1]s, r'^SRID=(0|4326);'), "+
+		"ST_GEOGFROMTEXT(REGEXP_REPLACE(%[1]s, r'^SRID=[0-9]+;', '')), "+
+		"ERROR(CONCAT('geography with an SRID other than 4326 is not supported: ', SUBSTR(%[1]s, 1, 64))))", expr)
+}
@@ -0,0 +1,18 @@
+package connbigquery
+
+import (
+	"context"
+	"fmt"
+)
+
+// PruneRawTable deletes the rows of normalized batches from a mirror's raw table, implementing RawTablePruneConnector.
+func (c *BigQueryConnector) PruneRawTable(ctx context.Context, flowJobName string, batchID int64) error {
+	rawTableName := c.getRawTableName(flowJobName)
+	query := c.client.Query(fmt.Sprintf("DELETE FROM %s WHERE _peerdb_batch_id <= %d", rawTableName, batchID))
+	query.DefaultProjectID = c.projectID
+	query.DefaultDatasetID = c.datasetID
+	if _, err := readQuery(ctx, query); err != nil {
+		return fmt.Errorf("failed to prune raw table %s.%s: %w", c.datasetID, rawTableName, err)
+	}
+	return nil
+}
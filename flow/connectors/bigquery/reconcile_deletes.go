@@ -0,0 +1,99 @@
+package connbigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func (c *BigQueryConnector) ScanPrimaryKeys(
+	ctx context.Context,
+	tableIdentifier string,
+	pkeyColumns []string,
+	softDeleteColName string,
+	fn func(key []qvalue.QValue) error,
+) error {
+	return c.ScanRows(ctx, tableIdentifier, pkeyColumns, softDeleteColName, fn)
+}
+
+func (c *BigQueryConnector) ScanRows(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	softDeleteColName string,
+	fn func(row []qvalue.QValue) error,
+) error {
+	dstDatasetTable, err := c.convertToDatasetTable(tableIdentifier)
+	if err != nil {
+		return err
+	}
+
+	quotedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quotedColumns = append(quotedColumns, fmt.Sprintf("`%s`", col))
+	}
+	query := fmt.Sprintf("SELECT %s FROM `%s`", strings.Join(quotedColumns, ","), dstDatasetTable.string())
+	if softDeleteColName != "" {
+		query += fmt.Sprintf(" WHERE NOT COALESCE(`%s`,FALSE)", softDeleteColName)
+	}
+
+	q := c.client.Query(query)
+	q.DefaultProjectID = c.projectID
+	q.DefaultDatasetID = dstDatasetTable.dataset
+	q.DestinationEncryptionConfig = c.encryptionConfig()
+	_, err = scanQuery(ctx, q, fn)
+	return err
+}
+
+func (c *BigQueryConnector) DeleteRowsByPrimaryKey(
+	ctx context.Context,
+	tableIdentifier string,
+	pkeyColumns []string,
+	keys [][]qvalue.QValue,
+	softDeleteColName string,
+) (int64, error) {
+	dstDatasetTable, err := c.convertToDatasetTable(tableIdentifier)
+	if err != nil {
+		return 0, err
+	}
+
+	quotedPkeyColumns := make([]string, 0, len(pkeyColumns))
+	for _, col := range pkeyColumns {
+		quotedPkeyColumns = append(quotedPkeyColumns, fmt.Sprintf("`%s`", col))
+	}
+	filter := utils.PrimaryKeyFilter(quotedPkeyColumns, len(keys), func(i int) string {
+		return fmt.Sprintf("@k%d", i)
+	})
+
+	var query string
+	if softDeleteColName != "" {
+		query = fmt.Sprintf("UPDATE `%s` SET `%s`=TRUE WHERE %s", dstDatasetTable.string(), softDeleteColName, filter)
+	} else {
+		query = fmt.Sprintf("DELETE FROM `%s` WHERE %s", dstDatasetTable.string(), filter)
+	}
+	q := c.client.Query(query)
+	q.DefaultProjectID = c.projectID
+	q.DefaultDatasetID = dstDatasetTable.dataset
+	q.Parameters = primaryKeyParams(keys)
+	q.JobTimeout = utils.StatementTimeout(ctx)
+	job, err := q.Run(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rows from table %s: %w", dstDatasetTable.string(), err)
+	}
+	status, err := waitJob(ctx, job)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rows from table %s: %w", dstDatasetTable.string(), err)
+	}
+	if err := status.Err(); err != nil {
+		return 0, fmt.Errorf("failed to delete rows from table %s: %w", dstDatasetTable.string(), err)
+	}
+	if stats, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+		return stats.NumDMLAffectedRows, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,76 @@
+package connbigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/google/uuid"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// bigQueryParameterValue adapts a source value to the type its column has in BigQuery.
+func bigQueryParameterValue(value qvalue.QValue) any {
+	switch v := value.Value().(type) {
+	case [16]byte:
+		return uuid.UUID(v).String()
+	case time.Time:
+		if value.Kind == qvalue.QValueKindDate {
+			return civil.DateOf(v)
+		}
+	}
+	return value.Value()
+}
+
+func (c *BigQueryConnector) GetRowsByPrimaryKey(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	pkeyColumns []string,
+	keys [][]qvalue.QValue,
+) (*model.QRecordBatch, error) {
+	dstDatasetTable, err := c.convertToDatasetTable(tableIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	quotedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quotedColumns = append(quotedColumns, fmt.Sprintf("`%s`", col))
+	}
+	quotedPkeyColumns := make([]string, 0, len(pkeyColumns))
+	for _, col := range pkeyColumns {
+		quotedPkeyColumns = append(quotedPkeyColumns, fmt.Sprintf("`%s`", col))
+	}
+	filter := utils.PrimaryKeyFilter(quotedPkeyColumns, len(keys), func(i int) string {
+		return fmt.Sprintf("@k%d", i)
+	})
+
+	q := c.client.Query(fmt.Sprintf("SELECT %s FROM `%s` WHERE %s",
+		strings.Join(quotedColumns, ","), dstDatasetTable.string(), filter))
+	q.DefaultProjectID = c.projectID
+	q.DefaultDatasetID = dstDatasetTable.dataset
+	q.Parameters = primaryKeyParams(keys)
+	q.DestinationEncryptionConfig = c.encryptionConfig()
+	return ReadQRecordBatch(ctx, q)
+}
+
+// primaryKeyParams names the values of keys @k0, @k1, ... in the order utils.PrimaryKeyFilter consumes them.
+func primaryKeyParams(keys [][]qvalue.QValue) []bigquery.QueryParameter {
+	var params []bigquery.QueryParameter
+	for _, key := range keys {
+		for _, value := range key {
+			params = append(params, bigquery.QueryParameter{
+				Name:  fmt.Sprintf("k%d", len(params)),
+				Value: bigQueryParameterValue(value),
+			})
+		}
+	}
+	return params
+}
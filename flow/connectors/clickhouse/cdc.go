@@ -9,12 +9,13 @@ import (
 	"regexp"
 	"strings"
 
-	_ "github.com/ClickHouse/clickhouse-go/v2"
 	_ "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 
+	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
 )
 
 const (
@@ -53,7 +54,7 @@ type MirrorJobRow struct {
 func (c *ClickhouseConnector) CreateRawTable(ctx context.Context, req *protos.CreateRawTableInput) (*protos.CreateRawTableOutput, error) {
 	rawTableName := c.getRawTableName(req.FlowJobName)
 
-	createRawTableSQL := `CREATE TABLE IF NOT EXISTS %s (
+	createRawTableSQL := `CREATE TABLE IF NOT EXISTS %s%s (
 		_peerdb_uid String NOT NULL,
 		_peerdb_timestamp Int64 NOT NULL,
 		_peerdb_destination_table_name String NOT NULL,
@@ -62,10 +63,10 @@ func (c *ClickhouseConnector) CreateRawTable(ctx context.Context, req *protos.Cr
 		_peerdb_match_data String,
 		_peerdb_batch_id Int,
 		_peerdb_unchanged_toast_columns String
-	) ENGINE = ReplacingMergeTree ORDER BY _peerdb_uid;`
+	) ENGINE = %s ORDER BY _peerdb_uid%s;`
 
-	_, err := c.database.ExecContext(ctx,
-		fmt.Sprintf(createRawTableSQL, rawTableName))
+	_, err := c.database.ExecContext(ctx, fmt.Sprintf(createRawTableSQL, rawTableName, onCluster(c.config),
+		mergeTreeEngine(c.config, "ReplacingMergeTree"), dedupTableSettings(c.config)))
 	if err != nil {
 		return nil, fmt.Errorf("unable to create raw table: %w", err)
 	}
@@ -74,12 +75,15 @@ func (c *ClickhouseConnector) CreateRawTable(ctx context.Context, req *protos.Cr
 	}, nil
 }
 
-func (c *ClickhouseConnector) syncRecordsViaAvro(
+// syncRecordsNative inserts records into the raw table over the native protocol, building each insert
+// column by column so that a block is sent as is instead of staging the batch on S3 first.
+func (c *ClickhouseConnector) syncRecordsNative(
 	ctx context.Context,
 	req *model.SyncRecordsRequest,
 	rawTableIdentifier string,
-	syncBatchID int64,
+	batch *metadataStore.SyncBatch,
 ) (*model.SyncResponse, error) {
+	syncBatchID := batch.SyncBatchID
 	tableNameRowsMapping := make(map[string]uint32)
 	streamReq := model.NewRecordsToStreamRequest(req.Records.GetRecords(), tableNameRowsMapping, syncBatchID)
 	streamRes, err := utils.RecordsToRawTableStream(streamReq)
@@ -87,29 +91,57 @@ func (c *ClickhouseConnector) syncRecordsViaAvro(
 		return nil, fmt.Errorf("failed to convert records to raw table stream: %w", err)
 	}
 
-	qrepConfig := &protos.QRepConfig{
-		StagingPath:                c.creds.BucketPath,
-		FlowJobName:                req.FlowJobName,
-		DestinationTableIdentifier: strings.ToLower(rawTableIdentifier),
-	}
-	avroSyncer := NewClickhouseAvroSyncMethod(qrepConfig, c)
-	destinationTableSchema, err := c.getTableSchema(qrepConfig.DestinationTableIdentifier)
-	if err != nil {
-		return nil, err
+	if batch.Retry() {
+		if err := c.deleteBatchFromRawTable(ctx, rawTableIdentifier, syncBatchID); err != nil {
+			return nil, err
+		}
 	}
 
-	numRecords, err := avroSyncer.SyncRecords(ctx, destinationTableSchema, streamRes.Stream, req.FlowJobName)
-	if err != nil {
-		return nil, err
+	numRecords := 0
+	numBlocks := 0
+	columns := newRawTableColumns(rawTableBlockRows)
+	flush := func() error {
+		if err := c.insertRawTableBlock(ctx, rawTableIdentifier, columns); err != nil {
+			return err
+		}
+		numRecords += columns.len()
+		numBlocks += 1
+		columns = newRawTableColumns(rawTableBlockRows)
+		return nil
 	}
+	for qRecordOrErr := range streamRes.Stream.Records {
+		if qRecordOrErr.Err != nil {
+			return nil, qRecordOrErr.Err
+		}
+		if err := columns.append(qRecordOrErr.Record); err != nil {
+			return nil, err
+		}
+		if columns.len() >= rawTableBlockRows {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if columns.len() > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	c.logger.Info(fmt.Sprintf("inserted %d records into raw table in %d blocks", numRecords, numBlocks))
 
 	err = c.ReplayTableSchemaDeltas(ctx, req.FlowJobName, req.Records.SchemaDeltas)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sync schema changes: %w", err)
 	}
 
+	// like whether records spilled to disk all arrived, the checkpoint is only known once the stream is drained
+	lastCheckpoint, err := req.Records.GetLastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+
 	return &model.SyncResponse{
-		LastSyncedCheckpointID: req.Records.GetLastCheckpoint(),
+		LastSyncedCheckpointID: lastCheckpoint,
 		NumRecordsSynced:       int64(numRecords),
 		CurrentSyncBatchID:     syncBatchID,
 		TableNameRowsMapping:   tableNameRowsMapping,
@@ -117,11 +149,60 @@ func (c *ClickhouseConnector) syncRecordsViaAvro(
 	}, nil
 }
 
+// deleteBatchFromRawTable deletes the rows an earlier attempt at a batch inserted into the raw table.
+// A retried sync pulls the batch again, possibly up to a later checkpoint, and every record gets a fresh _peerdb_uid,
+// so they'd otherwise be inserted twice. The batch only counts as synced once FinishBatch commits it in the catalog.
+// The mutation is only run when there are such rows and is waited on, as it only applies to parts which exist
+// when it starts.
+func (c *ClickhouseConnector) deleteBatchFromRawTable(ctx context.Context, rawTableIdentifier string, syncBatchID int64) error {
+	var earlierRows uint64
+	if err := c.database.QueryRowContext(ctx, fmt.Sprintf("SELECT count() FROM %s WHERE _peerdb_batch_id = %d",
+		rawTableIdentifier, syncBatchID)).Scan(&earlierRows); err != nil {
+		return fmt.Errorf("failed to count earlier rows of batch %d: %w", syncBatchID, err)
+	}
+	if earlierRows == 0 {
+		return nil
+	}
+
+	c.logger.Warn(fmt.Sprintf("replacing %d rows of batch %d from an earlier attempt", earlierRows, syncBatchID))
+	if _, err := c.database.ExecContext(withExecutionDeadline(ctx), fmt.Sprintf(
+		"ALTER TABLE %s%s DELETE WHERE _peerdb_batch_id = %d SETTINGS mutations_sync = 2",
+		rawTableIdentifier, onCluster(c.config), syncBatchID)); err != nil {
+		return fmt.Errorf("failed to delete earlier rows of batch %d: %w", syncBatchID, err)
+	}
+	return nil
+}
+
+func (c *ClickhouseConnector) insertRawTableBlock(
+	ctx context.Context,
+	rawTableIdentifier string,
+	columns *rawTableColumns,
+) error {
+	batch, err := c.nativeConn.PrepareBatch(ctx, "INSERT INTO "+rawTableIdentifier+" ("+strings.Join(rawTableColumnNames, ",")+")")
+	if err != nil {
+		return fmt.Errorf("failed to prepare raw table insert: %w", err)
+	}
+	for i, column := range columns.columns() {
+		if err := batch.Column(i).Append(column); err != nil {
+			_ = batch.Abort()
+			return fmt.Errorf("failed to append column %s to raw table insert: %w", rawTableColumnNames[i], err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to insert into raw table: %w", err)
+	}
+	return nil
+}
+
 func (c *ClickhouseConnector) SyncRecords(ctx context.Context, req *model.SyncRecordsRequest) (*model.SyncResponse, error) {
 	rawTableName := c.getRawTableName(req.FlowJobName)
 	c.logger.Info("pushing records to Clickhouse table " + rawTableName)
 
-	res, err := c.syncRecordsViaAvro(ctx, req, rawTableName, req.SyncBatchID)
+	batch, err := c.pgMetadata.StartBatch(ctx, req.FlowJobName, req.SyncBatchID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.syncRecordsNative(ctx, req, rawTableName, batch)
 	if err != nil {
 		return nil, err
 	}
@@ -143,9 +224,56 @@ func (c *ClickhouseConnector) SyncFlowCleanup(ctx context.Context, jobName strin
 	return nil
 }
 
-func (c *ClickhouseConnector) ReplayTableSchemaDeltas(_ context.Context, flowJobName string,
+// ReplayTableSchemaDeltas changes a destination table to match the schema at source, for distributed peers
+// the local tables holding the data and then the Distributed table over them.
+func (c *ClickhouseConnector) ReplayTableSchemaDeltas(ctx context.Context, flowJobName string,
 	schemaDeltas []*protos.TableSchemaDelta,
 ) error {
+	for _, schemaDelta := range schemaDeltas {
+		if model.IsEmptySchemaDelta(schemaDelta) {
+			continue
+		}
+
+		var alterations []string
+		for _, droppedColumn := range schemaDelta.DroppedColumns {
+			alterations = append(alterations, fmt.Sprintf("DROP COLUMN IF EXISTS `%s`", droppedColumn))
+		}
+		for _, renamedColumn := range schemaDelta.RenamedColumns {
+			alterations = append(alterations,
+				fmt.Sprintf("RENAME COLUMN IF EXISTS `%s` TO `%s`", renamedColumn.OldName, renamedColumn.NewName))
+		}
+		for _, alteredColumn := range schemaDelta.AlteredColumns {
+			chType, err := qValueKindToClickhouseType(qvalue.QValueKind(alteredColumn.NewType))
+			if err != nil {
+				return fmt.Errorf("failed to convert column type %s to clickhouse type: %w", alteredColumn.NewType, err)
+			}
+			alterations = append(alterations, fmt.Sprintf("MODIFY COLUMN `%s` %s", alteredColumn.ColumnName, chType))
+		}
+		for _, addedColumn := range schemaDelta.AddedColumns {
+			chType, err := qValueKindToClickhouseType(qvalue.QValueKind(addedColumn.ColumnType))
+			if err != nil {
+				return fmt.Errorf("failed to convert column type %s to clickhouse type: %w", addedColumn.ColumnType, err)
+			}
+			alterations = append(alterations, fmt.Sprintf("ADD COLUMN IF NOT EXISTS `%s` %s", addedColumn.ColumnName, chType))
+		}
+
+		tables := []string{schemaDelta.DstTableName}
+		if c.config.GetDistributed() {
+			tables = []string{schemaDelta.DstTableName + distributedLocalSuffix, schemaDelta.DstTableName}
+		}
+		for _, table := range tables {
+			for _, alteration := range alterations {
+				_, err := c.database.ExecContext(ctx,
+					fmt.Sprintf("ALTER TABLE `%s`%s %s", table, onCluster(c.config), alteration))
+				if err != nil {
+					return fmt.Errorf("failed to replay schema change %q on table %s: %w", alteration, table, err)
+				}
+			}
+		}
+		c.logger.Info("[schema delta replay] changed table "+schemaDelta.DstTableName,
+			slog.String("srcTableName", schemaDelta.SrcTableName),
+			slog.Any("changes", alterations))
+	}
 	return nil
 }
 
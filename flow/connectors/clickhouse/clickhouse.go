@@ -4,11 +4,13 @@ import (
 	"context"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
 	"net/url"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
-	_ "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"go.temporal.io/sdk/log"
 
@@ -21,11 +23,13 @@ import (
 
 type ClickhouseConnector struct {
 	database           *sql.DB
+	nativeConn         driver.Conn
 	pgMetadata         *metadataStore.PostgresMetadataStore
 	tableSchemaMapping map[string]*protos.TableSchema
 	logger             log.Logger
 	config             *protos.ClickhouseConfig
 	creds              *utils.ClickhouseS3Credentials
+	pooled             *utils.PooledDB
 }
 
 func ValidateS3(ctx context.Context, creds *utils.ClickhouseS3Credentials) error {
@@ -85,19 +89,43 @@ func NewClickhouseConnector(
 	config *protos.ClickhouseConfig,
 ) (*ClickhouseConnector, error) {
 	logger := logger.LoggerFromCtx(ctx)
-	database, err := connect(ctx, config)
+	if config.Distributed && config.Cluster == "" {
+		return nil, errors.New("distributed tables require a cluster to be set on the Clickhouse peer")
+	}
+	pooled, err := utils.AcquireDB(ctx, config, config.PoolConfig, func(ctx context.Context) (*sql.DB, *utils.Dialer, error) {
+		dialer, err := utils.NewDialer(ctx, config.SshConfig, config.ProxyConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create tunnel to Clickhouse peer: %w", err)
+		}
+		database, err := connect(ctx, config, dialer)
+		if err != nil {
+			dialer.Close()
+			return nil, nil, fmt.Errorf("failed to open connection to Clickhouse peer: %w", err)
+		}
+
+		err = ValidateClickhouse(ctx, database)
+		if err != nil {
+			database.Close()
+			dialer.Close()
+			return nil, nil, fmt.Errorf("invalidated Clickhouse peer: %w", err)
+		}
+		return database, dialer, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open connection to Clickhouse peer: %w", err)
+		return nil, err
 	}
 
-	err = ValidateClickhouse(ctx, database)
+	nativeConn, err := connectNative(ctx, config, pooled.Dialer)
 	if err != nil {
-		return nil, fmt.Errorf("invalidated Clickhouse peer: %w", err)
+		pooled.Release()
+		return nil, err
 	}
 
 	pgMetadata, err := metadataStore.NewPostgresMetadataStore(ctx)
 	if err != nil {
 		logger.Error("failed to create postgres metadata store", "error", err)
+		nativeConn.Close()
+		pooled.Release()
 		return nil, err
 	}
 
@@ -124,25 +152,49 @@ func NewClickhouseConnector(
 
 	validateErr := ValidateS3(ctx, clickhouseS3Creds)
 	if validateErr != nil {
+		nativeConn.Close()
+		pooled.Release()
 		return nil, fmt.Errorf("failed to validate S3 bucket: %w", validateErr)
 	}
 
 	return &ClickhouseConnector{
-		database:           database,
+		database:           pooled.DB,
+		nativeConn:         nativeConn,
 		pgMetadata:         pgMetadata,
 		tableSchemaMapping: nil,
 		config:             config,
 		creds:              clickhouseS3Creds,
+		pooled:             pooled,
 		logger:             logger,
 	}, nil
 }
 
-func connect(ctx context.Context, config *protos.ClickhouseConfig) (*sql.DB, error) {
+func clickhouseOptions(config *protos.ClickhouseConfig, dialer *utils.Dialer) (*clickhouse.Options, error) {
 	var tlsSetting *tls.Config
 	if !config.DisableTls {
-		tlsSetting = &tls.Config{MinVersion: tls.VersionTLS13}
+		var err error
+		tlsSetting, err = utils.CreateTLSConfig(config.TlsConfig, config.Host, tls.VersionTLS13)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS config: %w", err)
+		}
+	}
+	var dialContext func(ctx context.Context, addr string) (net.Conn, error)
+	if dialer.Tunneled() {
+		// the driver leaves TLS to custom dialers
+		dialContext = func(ctx context.Context, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil || tlsSetting == nil {
+				return conn, err
+			}
+			tlsConn := tls.Client(conn, tlsSetting)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		}
 	}
-	conn := clickhouse.OpenDB(&clickhouse.Options{
+	return &clickhouse.Options{
 		Addr: []string{fmt.Sprintf("%s:%d", config.Host, config.Port)},
 		Auth: clickhouse.Auth{
 			Database: config.Database,
@@ -150,6 +202,7 @@ func connect(ctx context.Context, config *protos.ClickhouseConfig) (*sql.DB, err
 			Password: config.Password,
 		},
 		TLS:         tlsSetting,
+		DialContext: dialContext,
 		Compression: &clickhouse.Compression{Method: clickhouse.CompressionLZ4},
 		ClientInfo: clickhouse.ClientInfo{
 			Products: []struct {
@@ -159,7 +212,15 @@ func connect(ctx context.Context, config *protos.ClickhouseConfig) (*sql.DB, err
 				{Name: "peerdb"},
 			},
 		},
-	})
+	}, nil
+}
+
+func connect(ctx context.Context, config *protos.ClickhouseConfig, dialer *utils.Dialer) (*sql.DB, error) {
+	options, err := clickhouseOptions(config, dialer)
+	if err != nil {
+		return nil, err
+	}
+	conn := clickhouse.OpenDB(options)
 
 	if err := conn.PingContext(ctx); err != nil {
 		conn.Close()
@@ -169,11 +230,32 @@ func connect(ctx context.Context, config *protos.ClickhouseConfig) (*sql.DB, err
 	return conn, nil
 }
 
+// connectNative opens a native protocol connection, used where columnar batch inserts beat going through database/sql.
+func connectNative(ctx context.Context, config *protos.ClickhouseConfig, dialer *utils.Dialer) (driver.Conn, error) {
+	options, err := clickhouseOptions(config, dialer)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := clickhouse.Open(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open native connection to Clickhouse peer: %w", err)
+	}
+
+	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping to Clickhouse peer: %w", err)
+	}
+
+	return conn, nil
+}
+
 func (c *ClickhouseConnector) Close() error {
 	if c != nil {
-		err := c.database.Close()
+		// the native connection is the connector's own, unlike the pool shared with other activities
+		defer c.pooled.Release()
+		err := c.nativeConn.Close()
 		if err != nil {
-			return fmt.Errorf("error while closing connection to Clickhouse peer: %w", err)
+			return fmt.Errorf("error while closing native connection to Clickhouse peer: %w", err)
 		}
 	}
 	return nil
@@ -183,3 +265,10 @@ func (c *ClickhouseConnector) ConnectionActive(ctx context.Context) error {
 	// This also checks if database exists
 	return c.database.PingContext(ctx)
 }
+
+// withExecutionDeadline bounds the queries run with ctx by its deadline on the server too. The driver only derives
+// max_execution_time from the deadline of contexts carrying query options, so that ClickHouse stops a query
+// of a timed out activity even if the cancel sent by the driver never reaches it.
+func withExecutionDeadline(ctx context.Context) context.Context {
+	return clickhouse.Context(ctx)
+}
@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
+	"golang.org/x/sync/errgroup"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
-	"github.com/PeerDB-io/peer-flow/model/numeric"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 )
 
@@ -19,6 +23,10 @@ const (
 	signColType    = "Int8"
 	versionColName = "_peerdb_version"
 	versionColType = "Int64"
+	// suffix of the per-shard tables behind a Distributed normalized table
+	distributedLocalSuffix = "_local"
+	// number of recent insert blocks whose hashes non replicated tables keep for deduplication
+	nonReplicatedDedupWindow = 1000
 )
 
 func (c *ClickhouseConnector) StartSetupNormalizedTables(_ context.Context) (interface{}, error) {
@@ -35,10 +43,9 @@ func (c *ClickhouseConnector) CleanupSetupNormalizedTables(_ context.Context, _
 func (c *ClickhouseConnector) SetupNormalizedTable(
 	ctx context.Context,
 	tx interface{},
+	config *protos.SetupNormalizedTableBatchInput,
 	tableIdentifier string,
 	tableSchema *protos.TableSchema,
-	softDeleteColName string,
-	syncedAtColName string,
 ) (bool, error) {
 	tableAlreadyExists, err := c.checkIfTableExists(ctx, c.config.Database, tableIdentifier)
 	if err != nil {
@@ -48,31 +55,116 @@ func (c *ClickhouseConnector) SetupNormalizedTable(
 		return true, nil
 	}
 
-	normalizedTableCreateSQL, err := generateCreateTableSQLForNormalizedTable(
+	var tableMapping *protos.TableMapping
+	for _, tm := range config.TableMappings {
+		if tm.DestinationTableIdentifier == tableIdentifier {
+			tableMapping = tm
+			break
+		}
+	}
+
+	stmts, err := generateNormalizedTableDDL(
 		tableIdentifier,
 		tableSchema,
-		softDeleteColName,
-		syncedAtColName,
+		tableMapping,
+		config.SoftDeleteColName,
+		config.SyncedAtColName,
+		c.config,
 	)
 	if err != nil {
 		return false, fmt.Errorf("error while generating create table sql for normalized table: %w", err)
 	}
 
-	_, err = c.database.ExecContext(ctx, normalizedTableCreateSQL)
-	if err != nil {
-		return false, fmt.Errorf("[ch] error while creating normalized table: %w", err)
+	for _, stmt := range stmts {
+		if _, err := c.database.ExecContext(ctx, stmt); err != nil {
+			return false, fmt.Errorf("[ch] error while creating normalized table: %w", err)
+		}
 	}
 	return false, nil
 }
 
+// generateNormalizedTableDDL returns the statements creating a normalized table. For distributed peers the data
+// lives in per-shard local tables, with a Distributed table under the mirrored name sharding rows by primary key,
+// so that all versions of a row end up on the same shard for ReplacingMergeTree to collapse.
+func generateNormalizedTableDDL(
+	normalizedTable string,
+	tableSchema *protos.TableSchema,
+	tableMapping *protos.TableMapping,
+	softDeleteColName string,
+	syncedAtColName string,
+	config *protos.ClickhouseConfig,
+) ([]string, error) {
+	if !config.GetDistributed() {
+		stmt, err := generateCreateTableSQLForNormalizedTable(normalizedTable, tableSchema, tableMapping,
+			softDeleteColName, syncedAtColName, config)
+		if err != nil {
+			return nil, err
+		}
+		return []string{stmt}, nil
+	}
+
+	localTable := normalizedTable + distributedLocalSuffix
+	localStmt, err := generateCreateTableSQLForNormalizedTable(localTable, tableSchema, tableMapping,
+		softDeleteColName, syncedAtColName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	shardingKey := "rand()"
+	if len(tableSchema.PrimaryKeyColumns) > 0 {
+		shardingKey = "cityHash64(" + strings.Join(tableSchema.PrimaryKeyColumns, ",") + ")"
+	}
+	distributedStmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`%s AS `%s` ENGINE = Distributed(`%s`, `%s`, `%s`, %s)",
+		normalizedTable, onCluster(config), localTable, config.Cluster, config.Database, localTable, shardingKey)
+	return []string{localStmt, distributedStmt}, nil
+}
+
+// onCluster returns the ON CLUSTER clause for DDL when the peer is a cluster.
+func onCluster(config *protos.ClickhouseConfig) string {
+	if config.GetCluster() == "" {
+		return ""
+	}
+	return fmt.Sprintf(" ON CLUSTER `%s`", config.Cluster)
+}
+
+// mergeTreeEngine returns the replicated variant of a MergeTree family engine when the peer asks for it.
+// Replica path and name are left to the server's default_replica_path and default_replica_name.
+func mergeTreeEngine(config *protos.ClickhouseConfig, engine string) string {
+	if config.GetReplicated() && !strings.HasPrefix(engine, "Replicated") {
+		return "Replicated" + engine
+	}
+	return engine
+}
+
+// dedupTableSettings has non replicated tables remember recent inserts, so that inserts retried with the same
+// insert_deduplication_token are dropped. Replicated tables deduplicate inserts by default.
+func dedupTableSettings(config *protos.ClickhouseConfig) string {
+	if config.GetReplicated() {
+		return ""
+	}
+	return fmt.Sprintf(" SETTINGS non_replicated_deduplication_window = %d", nonReplicatedDedupWindow)
+}
+
+// insertDedupSettings returns the SETTINGS clause making an INSERT idempotent across retries with the same token.
+func insertDedupSettings(token string) string {
+	return fmt.Sprintf("SETTINGS insert_deduplication_token = '%s'", strings.ReplaceAll(token, "'", "''"))
+}
+
 func generateCreateTableSQLForNormalizedTable(
 	normalizedTable string,
 	tableSchema *protos.TableSchema,
-	_ string, // softDeleteColName
+	tableMapping *protos.TableMapping,
+	softDeleteColName string,
 	syncedAtColName string,
+	config *protos.ClickhouseConfig,
 ) (string, error) {
+	columnSettings := make(map[string]*protos.ColumnSetting, len(tableMapping.GetColumns()))
+	for _, col := range tableMapping.GetColumns() {
+		columnSettings[col.SourceName] = col
+	}
+
 	var stmtBuilder strings.Builder
-	stmtBuilder.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (", normalizedTable))
+	stmtBuilder.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`%s (", normalizedTable, onCluster(config)))
 
 	for _, column := range tableSchema.Columns {
 		colName := column.Name
@@ -84,19 +176,27 @@ func generateCreateTableSQLForNormalizedTable(
 
 		switch colType {
 		case qvalue.QValueKindNumeric:
-			precision, scale := numeric.ParseNumericTypmod(column.TypeModifier)
-			if column.TypeModifier == -1 || precision > 76 || scale > precision {
-				precision = numeric.PeerDBClickhousePrecision
-				scale = numeric.PeerDBClickhouseScale
-			}
-			stmtBuilder.WriteString(fmt.Sprintf("`%s` DECIMAL(%d, %d), ",
+			precision, scale := qvalue.DetermineNumericSettingForTypmod(column.TypeModifier, qvalue.QDWHTypeClickhouse)
+			stmtBuilder.WriteString(fmt.Sprintf("`%s` DECIMAL(%d, %d)",
 				colName, precision, scale))
 		default:
-			stmtBuilder.WriteString(fmt.Sprintf("`%s` %s, ", colName, clickhouseType))
+			stmtBuilder.WriteString(fmt.Sprintf("`%s` %s", colName, clickhouseType))
 		}
+
+		if colSetting, ok := columnSettings[colName]; ok {
+			if colSetting.Codec != "" {
+				stmtBuilder.WriteString(fmt.Sprintf(" CODEC(%s)", colSetting.Codec))
+			}
+			if colSetting.Ttl != "" {
+				stmtBuilder.WriteString(" TTL " + colSetting.Ttl)
+			}
+		}
+		stmtBuilder.WriteString(", ")
+	}
+	// soft delete and synced at columns will be added to all normalized tables
+	if colName := softDeleteColumn(softDeleteColName); colName != "" {
+		stmtBuilder.WriteString(fmt.Sprintf("`%s` Bool DEFAULT false, ", colName))
 	}
-	// TODO support soft delete
-	// synced at column will be added to all normalized tables
 	if syncedAtColName != "" {
 		colName := strings.ToLower(syncedAtColName)
 		stmtBuilder.WriteString(fmt.Sprintf("`%s` %s, ", colName, "DateTime64(9) DEFAULT now()"))
@@ -106,22 +206,117 @@ func generateCreateTableSQLForNormalizedTable(
 	stmtBuilder.WriteString(fmt.Sprintf("`%s` %s, ", signColName, signColType))
 	stmtBuilder.WriteString(fmt.Sprintf("`%s` %s", versionColName, versionColType))
 
-	stmtBuilder.WriteString(fmt.Sprintf(") ENGINE = ReplacingMergeTree(`%s`) ", versionColName))
+	stmtBuilder.WriteString(")")
+	stmtBuilder.WriteString(tableEngineClauses(sortingKeyColumns(tableSchema), tableMapping, config))
+
+	return stmtBuilder.String(), nil
+}
+
+// sortingKeyColumns returns what rows of a normalized table are deduplicated on.
+// A synthetic key spans all columns, which may be nullable, so rows are keyed by a hash of them instead.
+func sortingKeyColumns(tableSchema *protos.TableSchema) []string {
+	if !tableSchema.SyntheticPrimaryKey || len(tableSchema.PrimaryKeyColumns) == 0 {
+		return tableSchema.PrimaryKeyColumns
+	}
+	quotedCols := make([]string, 0, len(tableSchema.PrimaryKeyColumns))
+	for _, col := range tableSchema.PrimaryKeyColumns {
+		quotedCols = append(quotedCols, fmt.Sprintf("`%s`", col))
+	}
+	return []string{"cityHash64(toString(tuple(" + strings.Join(quotedCols, ",") + ")))"}
+}
+
+// tableEngineClauses returns the engine, partitioning, ordering and TTL of a normalized table,
+// taking overrides from the table mapping's ClickHouse settings.
+func tableEngineClauses(pkeys []string, tableMapping *protos.TableMapping, config *protos.ClickhouseConfig) string {
+	settings := tableMapping.GetClickhouse()
+	engine := fmt.Sprintf("ReplacingMergeTree(`%s`)", versionColName)
+	if settings.GetEngine() != "" {
+		engine = settings.Engine
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(" ENGINE = %s ", mergeTreeEngine(config, engine)))
+
+	if partitionBy := settings.GetPartitionBy(); partitionBy != "" {
+		builder.WriteString("PARTITION BY ")
+		builder.WriteString(partitionBy)
+		builder.WriteString(" ")
+	}
 
-	pkeys := tableSchema.PrimaryKeyColumns
-	if len(pkeys) > 0 {
+	if orderBy := settings.GetOrderBy(); orderBy != "" {
+		// primary key defaults to the sorting key, which has to start with it
+		builder.WriteString("ORDER BY (")
+		builder.WriteString(orderBy)
+		builder.WriteString(")")
+	} else if len(pkeys) > 0 {
 		pkeyStr := strings.Join(pkeys, ",")
 
-		stmtBuilder.WriteString("PRIMARY KEY (")
-		stmtBuilder.WriteString(pkeyStr)
-		stmtBuilder.WriteString(") ")
+		builder.WriteString("PRIMARY KEY (")
+		builder.WriteString(pkeyStr)
+		builder.WriteString(") ")
 
-		stmtBuilder.WriteString("ORDER BY (")
-		stmtBuilder.WriteString(pkeyStr)
-		stmtBuilder.WriteString(")")
+		builder.WriteString("ORDER BY (")
+		builder.WriteString(pkeyStr)
+		builder.WriteString(")")
 	}
 
-	return stmtBuilder.String(), nil
+	if ttl := tableMapping.GetTtl(); ttl != "" {
+		builder.WriteString(" TTL ")
+		builder.WriteString(ttl)
+	}
+	builder.WriteString(dedupTableSettings(config))
+
+	return builder.String()
+}
+
+// ValidateTableSettings checks the ClickHouse settings of table mappings before a mirror is created,
+// having the server parse them so that mistakes don't surface only once normalized tables are set up.
+func ValidateTableSettings(ctx context.Context, config *protos.ClickhouseConfig, tableMappings []*protos.TableMapping) error {
+	var conn *sql.DB
+	var dialer *utils.Dialer
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+		if dialer != nil {
+			dialer.Close()
+		}
+	}()
+
+	for _, tableMapping := range tableMappings {
+		if tableMapping.Clickhouse == nil && tableMapping.Ttl == "" {
+			continue
+		}
+
+		if engine := tableMapping.Clickhouse.GetEngine(); engine != "" {
+			engineName, _, _ := strings.Cut(engine, "(")
+			if !strings.HasSuffix(strings.TrimSpace(engineName), "MergeTree") {
+				return fmt.Errorf("engine %s of table %s is not a MergeTree family engine",
+					engine, tableMapping.DestinationTableIdentifier)
+			}
+		}
+
+		if conn == nil {
+			var err error
+			dialer, err = utils.NewDialer(ctx, config.SshConfig, config.ProxyConfig)
+			if err != nil {
+				return err
+			}
+			conn, err = connect(ctx, config, dialer)
+			if err != nil {
+				return err
+			}
+		}
+		// EXPLAIN AST only parses the statement, so columns referenced by the expressions need not exist
+		rows, err := conn.QueryContext(ctx, "EXPLAIN AST CREATE TABLE `peerdb_validation` (`id` Int64)"+
+			tableEngineClauses(nil, tableMapping, config))
+		if err != nil {
+			return fmt.Errorf("invalid settings for table %s: %w", tableMapping.DestinationTableIdentifier, err)
+		}
+		rows.Close()
+	}
+
+	return nil
 }
 
 func (c *ClickhouseConnector) NormalizeRecords(ctx context.Context, req *model.NormalizeRecordsRequest) (*model.NormalizeResponse, error) {
@@ -152,98 +347,219 @@ func (c *ClickhouseConnector) NormalizeRecords(ctx context.Context, req *model.N
 	}
 
 	rawTbl := c.getRawTableName(req.FlowJobName)
-
-	// model the raw table data as inserts.
+	softDeleteColName := normalizeSoftDeleteColName(req)
+
+	// model the raw table data as inserts, tables are independent so they are normalized concurrently.
+	// Every table runs to completion even if another fails, so the error names all failing tables.
+	var g errgroup.Group
+	g.SetLimit(dynamicconf.PeerDBClickhouseNormalizeParallelism(ctx))
+	var tableErrsLock sync.Mutex
+	var tableErrs []error
 	for _, tbl := range destinationTableNames {
-		// SELECT projection FROM raw_table WHERE _peerdb_batch_id > normalize_batch_id AND _peerdb_batch_id <= sync_batch_id
-		selectQuery := strings.Builder{}
-		selectQuery.WriteString("SELECT ")
+		g.Go(func() error {
+			q, _, err := generateNormalizeQuery(tbl, rawTbl, req.TableNameSchemaMapping[tbl], softDeleteColName,
+				normBatchID, req.SyncBatchID, req.NumericOverflowPolicy)
+			if err == nil {
+				c.logger.Info("[clickhouse] insert into select query " + q)
+				_, err = c.database.ExecContext(withExecutionDeadline(ctx), q)
+			}
+			if err != nil {
+				tableErrsLock.Lock()
+				tableErrs = append(tableErrs, fmt.Errorf("error while inserting into normalized table %s: %w", tbl, err))
+				tableErrsLock.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if len(tableErrs) > 0 {
+		return nil, errors.Join(tableErrs...)
+	}
 
-		colSelector := strings.Builder{}
-		colSelector.WriteString("(")
+	endNormalizeBatchId := normBatchID + 1
+	err = c.pgMetadata.UpdateNormalizeBatchID(ctx, req.FlowJobName, endNormalizeBatchId)
+	if err != nil {
+		c.logger.Error("[clickhouse] error while updating normalize batch id", "error", err)
+		return nil, err
+	}
 
-		schema := req.TableNameSchemaMapping[tbl]
+	return &model.NormalizeResponse{
+		Done:         true,
+		StartBatchID: endNormalizeBatchId,
+		EndBatchID:   req.SyncBatchID,
+	}, nil
+}
 
-		projection := strings.Builder{}
+// softDeleteColumn returns the boolean column to flag deletes in. The default soft delete column name
+// lowercases to the sign column, which already holds 1 for deletes, so no extra column is needed then.
+func softDeleteColumn(softDeleteColName string) string {
+	colName := strings.ToLower(softDeleteColName)
+	if colName == signColName {
+		return ""
+	}
+	return colName
+}
 
-		for _, column := range schema.Columns {
-			cn := column.Name
-			ct := column.Type
+func normalizeSoftDeleteColName(req *model.NormalizeRecordsRequest) string {
+	if !req.SoftDelete {
+		return ""
+	}
+	return softDeleteColumn(req.SoftDeleteColName)
+}
 
-			colSelector.WriteString(fmt.Sprintf("`%s`,", cn))
-			colType := qvalue.QValueKind(ct)
-			clickhouseType, err := qValueKindToClickhouseType(colType)
-			if err != nil {
-				return nil, fmt.Errorf("error while converting column type to clickhouse type: %w", err)
-			}
+// generateNormalizeQuery returns the INSERT INTO ... SELECT that normalizes a batch range of the raw table into tbl,
+// along with its SELECT part on its own. With a soft delete column, delete records also set it to true,
+// next to the sign column ReplacingMergeTree relies on. The insert is deduplicated on the raw table, table and
+// batch range, which select the same rows every time, so a retried normalize doesn't insert them twice.
+func generateNormalizeQuery(
+	tbl string,
+	rawTbl string,
+	schema *protos.TableSchema,
+	softDeleteColName string,
+	normBatchID int64,
+	syncBatchID int64,
+	numericOverflowPolicy protos.NumericOverflowPolicy,
+) (string, string, error) {
+	// SELECT projection FROM raw_table WHERE _peerdb_batch_id > normalize_batch_id AND _peerdb_batch_id <= sync_batch_id
+	selectQuery := strings.Builder{}
+	selectQuery.WriteString("SELECT ")
 
-			switch clickhouseType {
-			case "Date":
-				projection.WriteString(fmt.Sprintf(
-					"toDate(parseDateTime64BestEffortOrNull(JSONExtractString(_peerdb_data, '%s'))) AS `%s`,",
-					cn,
-					cn,
-				))
-			case "DateTime64(6)":
+	colSelector := strings.Builder{}
+	colSelector.WriteString("(")
+
+	projection := strings.Builder{}
+
+	for _, column := range schema.Columns {
+		cn := column.Name
+		ct := column.Type
+
+		colSelector.WriteString(fmt.Sprintf("`%s`,", cn))
+		colType := qvalue.QValueKind(ct)
+		clickhouseType, err := qValueKindToClickhouseType(colType)
+		if err != nil {
+			return "", "", fmt.Errorf("error while converting column type to clickhouse type: %w", err)
+		}
+
+		if colType == qvalue.QValueKindNumeric {
+			// extracted with the precision and scale of the column rather than the generic Decimal128(9)
+			precision, scale := qvalue.DetermineNumericSettingForTypmod(column.TypeModifier, qvalue.QDWHTypeClickhouse)
+			clickhouseType = fmt.Sprintf("Decimal(%d, %d)", precision, scale)
+			if numericOverflowPolicy == protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ERROR {
+				// unlike JSONExtract, which extracts values it can't convert as the default, CAST fails on them
 				projection.WriteString(fmt.Sprintf(
-					"parseDateTime64BestEffortOrNull(JSONExtractString(_peerdb_data, '%s')) AS `%s`,",
+					"CAST(nullIf(JSONExtractString(_peerdb_data, '%s'), ''), 'Nullable(%s)') AS `%s`,",
 					cn,
+					clickhouseType,
 					cn,
 				))
-			default:
-				projection.WriteString(fmt.Sprintf("JSONExtract(_peerdb_data, '%s', '%s') AS `%s`,", cn, clickhouseType, cn))
+				continue
 			}
 		}
 
-		// add _peerdb_sign as _peerdb_record_type / 2
-		projection.WriteString(fmt.Sprintf("intDiv(_peerdb_record_type, 2) AS `%s`,", signColName))
-		colSelector.WriteString(fmt.Sprintf("`%s`,", signColName))
-
-		// add _peerdb_timestamp as _peerdb_version
-		projection.WriteString(fmt.Sprintf("_peerdb_timestamp AS `%s`", versionColName))
-		colSelector.WriteString(versionColName)
-		colSelector.WriteString(") ")
-
-		selectQuery.WriteString(projection.String())
-		selectQuery.WriteString(" FROM ")
-		selectQuery.WriteString(rawTbl)
-		selectQuery.WriteString(" WHERE _peerdb_batch_id > ")
-		selectQuery.WriteString(strconv.FormatInt(normBatchID, 10))
-		selectQuery.WriteString(" AND _peerdb_batch_id <= ")
-		selectQuery.WriteString(strconv.FormatInt(req.SyncBatchID, 10))
-		selectQuery.WriteString(" AND _peerdb_destination_table_name = '")
-		selectQuery.WriteString(tbl)
-		selectQuery.WriteString("'")
-
-		selectQuery.WriteString(" ORDER BY _peerdb_timestamp")
-
-		insertIntoSelectQuery := strings.Builder{}
-		insertIntoSelectQuery.WriteString("INSERT INTO ")
-		insertIntoSelectQuery.WriteString(tbl)
-		insertIntoSelectQuery.WriteString(colSelector.String())
-		insertIntoSelectQuery.WriteString(selectQuery.String())
-
-		q := insertIntoSelectQuery.String()
-		c.logger.Info("[clickhouse] insert into select query " + q)
-
-		_, err = c.database.ExecContext(ctx, q)
-		if err != nil {
-			return nil, fmt.Errorf("error while inserting into normalized table: %w", err)
+		switch clickhouseType {
+		case "Date":
+			projection.WriteString(fmt.Sprintf(
+				"toDate(parseDateTime64BestEffortOrNull(JSONExtractString(_peerdb_data, '%s'))) AS `%s`,",
+				cn,
+				cn,
+			))
+		case "DateTime64(6)":
+			projection.WriteString(fmt.Sprintf(
+				"parseDateTime64BestEffortOrNull(JSONExtractString(_peerdb_data, '%s')) AS `%s`,",
+				cn,
+				cn,
+			))
+		default:
+			projection.WriteString(fmt.Sprintf("JSONExtract(_peerdb_data, '%s', '%s') AS `%s`,", cn, clickhouseType, cn))
 		}
 	}
 
-	endNormalizeBatchId := normBatchID + 1
-	err = c.pgMetadata.UpdateNormalizeBatchID(ctx, req.FlowJobName, endNormalizeBatchId)
+	if softDeleteColName != "" {
+		projection.WriteString(fmt.Sprintf("_peerdb_record_type = 2 AS `%s`,", softDeleteColName))
+		colSelector.WriteString(fmt.Sprintf("`%s`,", softDeleteColName))
+	}
+
+	// add _peerdb_sign as _peerdb_record_type / 2
+	projection.WriteString(fmt.Sprintf("intDiv(_peerdb_record_type, 2) AS `%s`,", signColName))
+	colSelector.WriteString(fmt.Sprintf("`%s`,", signColName))
+
+	// add _peerdb_timestamp as _peerdb_version
+	projection.WriteString(fmt.Sprintf("_peerdb_timestamp AS `%s`", versionColName))
+	colSelector.WriteString(versionColName)
+	colSelector.WriteString(") ")
+
+	selectQuery.WriteString(projection.String())
+	selectQuery.WriteString(" FROM ")
+	selectQuery.WriteString(rawTbl)
+	selectQuery.WriteString(" WHERE _peerdb_batch_id > ")
+	selectQuery.WriteString(strconv.FormatInt(normBatchID, 10))
+	selectQuery.WriteString(" AND _peerdb_batch_id <= ")
+	selectQuery.WriteString(strconv.FormatInt(syncBatchID, 10))
+	selectQuery.WriteString(" AND _peerdb_destination_table_name = '")
+	selectQuery.WriteString(tbl)
+	selectQuery.WriteString("'")
+
+	selectQuery.WriteString(" ORDER BY _peerdb_timestamp")
+
+	insertIntoSelectQuery := strings.Builder{}
+	insertIntoSelectQuery.WriteString("INSERT INTO ")
+	insertIntoSelectQuery.WriteString(tbl)
+	insertIntoSelectQuery.WriteString(colSelector.String())
+	insertIntoSelectQuery.WriteString(insertDedupSettings(fmt.Sprintf("%s_%s_%d_%d", rawTbl, tbl, normBatchID, syncBatchID)))
+	insertIntoSelectQuery.WriteString(" ")
+	insertIntoSelectQuery.WriteString(selectQuery.String())
+
+	return insertIntoSelectQuery.String(), selectQuery.String(), nil
+}
+
+// SimulateNormalize renders the INSERT INTO ... SELECT NormalizeRecords would run for a table.
+// ClickHouse plans the SELECT part, which is where the raw table scan and JSON extraction happen.
+func (c *ClickhouseConnector) SimulateNormalize(
+	ctx context.Context,
+	req *model.NormalizeRecordsRequest,
+	tableName string,
+	explain bool,
+) (*model.NormalizeSimulation, error) {
+	normBatchID, err := c.GetLastNormalizeBatchID(ctx, req.FlowJobName)
 	if err != nil {
-		c.logger.Error("[clickhouse] error while updating normalize batch id", "error", err)
 		return nil, err
 	}
-
-	return &model.NormalizeResponse{
-		Done:         true,
-		StartBatchID: endNormalizeBatchId,
+	simulation := &model.NormalizeSimulation{
+		StartBatchID: normBatchID + 1,
 		EndBatchID:   req.SyncBatchID,
-	}, nil
+	}
+	if normBatchID >= req.SyncBatchID {
+		return simulation, nil
+	}
+
+	insertQuery, selectQuery, err := generateNormalizeQuery(tableName, c.getRawTableName(req.FlowJobName),
+		req.TableNameSchemaMapping[tableName], normalizeSoftDeleteColName(req), normBatchID, req.SyncBatchID,
+		req.NumericOverflowPolicy)
+	if err != nil {
+		return nil, err
+	}
+	stmt := model.NormalizeStatement{SQL: insertQuery}
+	if explain {
+		rows, err := c.database.QueryContext(ctx, "EXPLAIN "+selectQuery)
+		if err != nil {
+			return nil, fmt.Errorf("error while explaining normalize query: %w", err)
+		}
+		defer rows.Close()
+		var planLines []string
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return nil, fmt.Errorf("error while scanning query plan: %w", err)
+			}
+			planLines = append(planLines, line)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read rows: %w", err)
+		}
+		stmt.Plan = strings.Join(planLines, "\n")
+	}
+	simulation.Statements = append(simulation.Statements, stmt)
+	return simulation, nil
 }
 
 func (c *ClickhouseConnector) getDistinctTableNamesInBatch(
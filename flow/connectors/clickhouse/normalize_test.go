@@ -0,0 +1,186 @@
+package connclickhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestGenerateCreateTableSQLForNormalizedTable(t *testing.T) {
+	tableSchema := &protos.TableSchema{
+		TableIdentifier:   "public.events",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+			{Name: "created_at", Type: string(qvalue.QValueKindTimestamp), TypeModifier: -1},
+			{Name: "payload", Type: string(qvalue.QValueKindString), TypeModifier: -1},
+		},
+	}
+
+	t.Run("without table mapping", func(t *testing.T) {
+		sql, err := generateCreateTableSQLForNormalizedTable("events", tableSchema, nil, "", "", nil)
+		require.NoError(t, err)
+		require.Equal(t, "CREATE TABLE IF NOT EXISTS `events` (`id` Int64, `created_at` DateTime64(6), `payload` String, "+
+			"`_peerdb_is_deleted` Int8, `_peerdb_version` Int64) ENGINE = ReplacingMergeTree(`_peerdb_version`) "+
+			"PRIMARY KEY (id) ORDER BY (id) SETTINGS non_replicated_deduplication_window = 1000", sql)
+	})
+
+	t.Run("with codecs and ttl", func(t *testing.T) {
+		tableMapping := &protos.TableMapping{
+			SourceTableIdentifier:      "public.events",
+			DestinationTableIdentifier: "events",
+			Columns: []*protos.ColumnSetting{
+				{SourceName: "created_at", Codec: "DoubleDelta, ZSTD"},
+				{SourceName: "payload", Codec: "ZSTD(3)", Ttl: "created_at + INTERVAL 7 DAY"},
+			},
+			Ttl: "created_at + INTERVAL 30 DAY",
+		}
+		sql, err := generateCreateTableSQLForNormalizedTable("events", tableSchema, tableMapping, "", "", nil)
+		require.NoError(t, err)
+		require.Equal(t, "CREATE TABLE IF NOT EXISTS `events` (`id` Int64, "+
+			"`created_at` DateTime64(6) CODEC(DoubleDelta, ZSTD), "+
+			"`payload` String CODEC(ZSTD(3)) TTL created_at + INTERVAL 7 DAY, "+
+			"`_peerdb_is_deleted` Int8, `_peerdb_version` Int64) ENGINE = ReplacingMergeTree(`_peerdb_version`) "+
+			"PRIMARY KEY (id) ORDER BY (id) TTL created_at + INTERVAL 30 DAY SETTINGS non_replicated_deduplication_window = 1000", sql)
+	})
+}
+
+func TestTableEngineClausesOverrides(t *testing.T) {
+	tableMapping := &protos.TableMapping{
+		Clickhouse: &protos.ClickhouseTableSettings{
+			Engine:      "MergeTree",
+			OrderBy:     "tenant_id, id",
+			PartitionBy: "toYYYYMM(created_at)",
+		},
+		Ttl: "created_at + INTERVAL 30 DAY",
+	}
+	require.Equal(t, " ENGINE = MergeTree PARTITION BY toYYYYMM(created_at) ORDER BY (tenant_id, id) "+
+		"TTL created_at + INTERVAL 30 DAY SETTINGS non_replicated_deduplication_window = 1000", tableEngineClauses([]string{"id"}, tableMapping, nil))
+
+	config := &protos.ClickhouseConfig{Replicated: true}
+	require.Equal(t, " ENGINE = ReplicatedMergeTree ORDER BY (tenant_id, id)",
+		tableEngineClauses([]string{"id"}, &protos.TableMapping{
+			Clickhouse: &protos.ClickhouseTableSettings{Engine: "ReplicatedMergeTree", OrderBy: "tenant_id, id"},
+		}, config))
+
+	require.Equal(t, " ENGINE = ReplacingMergeTree(`_peerdb_version`) PARTITION BY tenant_id PRIMARY KEY (id) ORDER BY (id)"+
+		" SETTINGS non_replicated_deduplication_window = 1000",
+		tableEngineClauses([]string{"id"}, &protos.TableMapping{
+			Clickhouse: &protos.ClickhouseTableSettings{PartitionBy: "tenant_id"},
+		}, nil))
+}
+
+func TestValidateTableSettingsRejectsEngine(t *testing.T) {
+	err := ValidateTableSettings(context.Background(), &protos.ClickhouseConfig{}, []*protos.TableMapping{{
+		DestinationTableIdentifier: "events",
+		Clickhouse:                 &protos.ClickhouseTableSettings{Engine: "Log"},
+	}})
+	require.ErrorContains(t, err, "not a MergeTree family engine")
+}
+
+func TestGenerateNormalizedTableDDLOnCluster(t *testing.T) {
+	tableSchema := &protos.TableSchema{
+		TableIdentifier:   "public.events",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+		},
+	}
+	config := &protos.ClickhouseConfig{Database: "analytics", Cluster: "main", Replicated: true, Distributed: true}
+
+	stmts, err := generateNormalizedTableDDL("events", tableSchema, nil, "", "", config)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS `events_local` ON CLUSTER `main` (`id` Int64, " +
+			"`_peerdb_is_deleted` Int8, `_peerdb_version` Int64) ENGINE = ReplicatedReplacingMergeTree(`_peerdb_version`) " +
+			"PRIMARY KEY (id) ORDER BY (id)",
+		"CREATE TABLE IF NOT EXISTS `events` ON CLUSTER `main` AS `events_local` " +
+			"ENGINE = Distributed(`main`, `analytics`, `events_local`, cityHash64(id))",
+	}, stmts)
+
+	config.Distributed = false
+	stmts, err = generateNormalizedTableDDL("events", tableSchema, nil, "", "", config)
+	require.NoError(t, err)
+	require.Len(t, stmts, 1)
+	require.Contains(t, stmts[0], "CREATE TABLE IF NOT EXISTS `events` ON CLUSTER `main` (")
+}
+
+func TestGenerateNormalizeQuery(t *testing.T) {
+	tableSchema := &protos.TableSchema{
+		TableIdentifier:   "public.events",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+			{Name: "created_at", Type: string(qvalue.QValueKindTimestamp), TypeModifier: -1},
+		},
+	}
+
+	insertQuery, selectQuery, err := generateNormalizeQuery("events", "_peerdb_raw_mirror", tableSchema, "", 3, 5,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ROUND)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT JSONExtract(_peerdb_data, 'id', 'Int64') AS `id`,"+
+		"parseDateTime64BestEffortOrNull(JSONExtractString(_peerdb_data, 'created_at')) AS `created_at`,"+
+		"intDiv(_peerdb_record_type, 2) AS `_peerdb_is_deleted`,_peerdb_timestamp AS `_peerdb_version` "+
+		"FROM _peerdb_raw_mirror WHERE _peerdb_batch_id > 3 AND _peerdb_batch_id <= 5 "+
+		"AND _peerdb_destination_table_name = 'events' ORDER BY _peerdb_timestamp", selectQuery)
+	require.Equal(t, "INSERT INTO events(`id`,`created_at`,`_peerdb_is_deleted`,_peerdb_version) "+
+		"SETTINGS insert_deduplication_token = '_peerdb_raw_mirror_events_3_5' "+selectQuery, insertQuery)
+}
+
+func TestGenerateNormalizeQueryNumeric(t *testing.T) {
+	tableSchema := &protos.TableSchema{
+		TableIdentifier:   "public.prices",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			// numeric(10, 2) and unconstrained numeric
+			{Name: "id", Type: string(qvalue.QValueKindNumeric), TypeModifier: (10<<16 | 2) + 4},
+			{Name: "price", Type: string(qvalue.QValueKindNumeric), TypeModifier: -1},
+		},
+	}
+
+	_, selectQuery, err := generateNormalizeQuery("prices", "_peerdb_raw_mirror", tableSchema, "", 0, 1,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ROUND)
+	require.NoError(t, err)
+	require.Contains(t, selectQuery, "SELECT JSONExtract(_peerdb_data, 'id', 'Decimal(10, 2)') AS `id`,"+
+		"JSONExtract(_peerdb_data, 'price', 'Decimal(76, 38)') AS `price`,")
+
+	_, selectQuery, err = generateNormalizeQuery("prices", "_peerdb_raw_mirror", tableSchema, "", 0, 1,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ERROR)
+	require.NoError(t, err)
+	require.Contains(t, selectQuery,
+		"CAST(nullIf(JSONExtractString(_peerdb_data, 'price'), ''), 'Nullable(Decimal(76, 38))') AS `price`,")
+}
+
+func TestSoftDeleteColumn(t *testing.T) {
+	tableSchema := &protos.TableSchema{
+		TableIdentifier:   "public.events",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+		},
+	}
+
+	sql, err := generateCreateTableSQLForNormalizedTable("events", tableSchema, nil, "_PEERDB_IS_DELETED_FLAG", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE IF NOT EXISTS `events` (`id` Int64, `_peerdb_is_deleted_flag` Bool DEFAULT false, "+
+		"`_peerdb_is_deleted` Int8, `_peerdb_version` Int64) ENGINE = ReplacingMergeTree(`_peerdb_version`) "+
+		"PRIMARY KEY (id) ORDER BY (id) SETTINGS non_replicated_deduplication_window = 1000", sql)
+
+	sql, err = generateCreateTableSQLForNormalizedTable("events", tableSchema, nil, "_PEERDB_IS_DELETED", "", nil)
+	require.NoError(t, err)
+	require.NotContains(t, sql, "Bool")
+
+	insertQuery, _, err := generateNormalizeQuery("events", "_peerdb_raw_mirror", tableSchema, "_peerdb_is_deleted_flag", 0, 1,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ROUND)
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO events(`id`,`_peerdb_is_deleted_flag`,`_peerdb_is_deleted`,_peerdb_version) "+
+		"SETTINGS insert_deduplication_token = '_peerdb_raw_mirror_events_0_1' SELECT JSONExtract(_peerdb_data, 'id', 'Int64') AS `id`,"+
+		"_peerdb_record_type = 2 AS `_peerdb_is_deleted_flag`,"+
+		"intDiv(_peerdb_record_type, 2) AS `_peerdb_is_deleted`,_peerdb_timestamp AS `_peerdb_version` "+
+		"FROM _peerdb_raw_mirror WHERE _peerdb_batch_id > 0 AND _peerdb_batch_id <= 1 "+
+		"AND _peerdb_destination_table_name = 'events' ORDER BY _peerdb_timestamp", insertQuery)
+}
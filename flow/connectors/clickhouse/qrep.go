@@ -33,17 +33,7 @@ func (c *ClickhouseConnector) SyncQRepRecords(
 		slog.String("destinationTable", destTable),
 	)
 
-	done, err := c.isPartitionSynced(ctx, partition.PartitionId)
-	if err != nil {
-		return 0, fmt.Errorf("failed to check if partition %s is synced: %w", partition.PartitionId, err)
-	}
-
-	if done {
-		c.logger.Info("Partition has already been synced", flowLog)
-		return 0, nil
-	}
-
-	tblSchema, err := c.getTableSchema(destTable)
+	tblSchema, err := c.getTableSchema(ctx, destTable)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get schema of table %s: %w", destTable, err)
 	}
@@ -78,11 +68,11 @@ func (c *ClickhouseConnector) createMetadataInsertStatement(
 	return insertMetadataStmt, nil
 }
 
-func (c *ClickhouseConnector) getTableSchema(tableName string) ([]*sql.ColumnType, error) {
+func (c *ClickhouseConnector) getTableSchema(ctx context.Context, tableName string) ([]*sql.ColumnType, error) {
 	//nolint:gosec
 	queryString := fmt.Sprintf(`SELECT * FROM %s LIMIT 0`, tableName)
 	//nolint:rowserrcheck
-	rows, err := c.database.Query(queryString)
+	rows, err := c.database.QueryContext(ctx, queryString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -96,11 +86,13 @@ func (c *ClickhouseConnector) getTableSchema(tableName string) ([]*sql.ColumnTyp
 	return columnTypes, nil
 }
 
-func (c *ClickhouseConnector) isPartitionSynced(ctx context.Context, partitionID string) (bool, error) {
+// IsQRepPartitionSynced checks whether a partition was marked synced. ClickHouse marks it after inserting its rows,
+// which are deduplicated on the partition, so a partition marked by neither this nor the catalog is inserted again.
+func (c *ClickhouseConnector) IsQRepPartitionSynced(ctx context.Context, config *protos.QRepConfig, partitionID string) (bool, error) {
 	//nolint:gosec
-	queryString := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE partitionID = '%s'`, qRepMetadataTableName, partitionID)
+	queryString := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE flowJobName = ? AND partitionID = ?`, qRepMetadataTableName)
 
-	row := c.database.QueryRowContext(ctx, queryString)
+	row := c.database.QueryRowContext(ctx, queryString, config.FlowJobName, partitionID)
 
 	var count int
 	if err := row.Scan(&count); err != nil {
@@ -116,7 +108,7 @@ func (c *ClickhouseConnector) SetupQRepMetadataTables(ctx context.Context, confi
 	}
 
 	if config.WriteMode.WriteType == protos.QRepWriteType_QREP_WRITE_MODE_OVERWRITE {
-		_, err = c.database.ExecContext(ctx, "TRUNCATE TABLE "+config.DestinationTableIdentifier)
+		_, err = c.database.ExecContext(ctx, "TRUNCATE TABLE "+config.DestinationTableIdentifier+onCluster(c.config))
 		if err != nil {
 			return fmt.Errorf("failed to TRUNCATE table before query replication: %w", err)
 		}
@@ -128,16 +120,16 @@ func (c *ClickhouseConnector) SetupQRepMetadataTables(ctx context.Context, confi
 func (c *ClickhouseConnector) createQRepMetadataTable(ctx context.Context) error {
 	// Define the schema
 	schemaStatement := `
-	CREATE TABLE IF NOT EXISTS %s (
+	CREATE TABLE IF NOT EXISTS %s%s (
 		flowJobName String,
 		partitionID String,
 		syncPartition String,
 		syncStartTime DateTime64,
 		syncFinishTime DateTime64
-		) ENGINE = MergeTree()
+		) ENGINE = %s()
 		ORDER BY partitionID;
 	`
-	queryString := fmt.Sprintf(schemaStatement, qRepMetadataTableName)
+	queryString := fmt.Sprintf(schemaStatement, qRepMetadataTableName, onCluster(c.config), mergeTreeEngine(c.config, "MergeTree"))
 	_, err := c.database.ExecContext(ctx, queryString)
 	if err != nil {
 		c.logger.Error("failed to create table "+qRepMetadataTableName,
@@ -33,65 +33,6 @@ func NewClickhouseAvroSyncMethod(
 	}
 }
 
-func (s *ClickhouseAvroSyncMethod) CopyStageToDestination(ctx context.Context, avroFile *avro.AvroFile) error {
-	stagingPath := s.connector.creds.BucketPath
-	s3o, err := utils.NewS3BucketAndPrefix(stagingPath)
-	if err != nil {
-		return err
-	}
-
-	avroFileUrl := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s3o.Bucket,
-		s.connector.creds.Region, avroFile.FilePath)
-
-	if err != nil {
-		return err
-	}
-	//nolint:gosec
-	query := fmt.Sprintf("INSERT INTO %s SELECT * FROM s3('%s','%s','%s', 'Avro')",
-		s.config.DestinationTableIdentifier, avroFileUrl,
-		s.connector.creds.AccessKeyID, s.connector.creds.SecretAccessKey)
-
-	_, err = s.connector.database.ExecContext(ctx, query)
-
-	return err
-}
-
-func (s *ClickhouseAvroSyncMethod) SyncRecords(
-	ctx context.Context,
-	dstTableSchema []*sql.ColumnType,
-	stream *model.QRecordStream,
-	flowJobName string,
-) (int, error) {
-	tableLog := slog.String("destinationTable", s.config.DestinationTableIdentifier)
-	dstTableName := s.config.DestinationTableIdentifier
-
-	schema, err := stream.Schema()
-	if err != nil {
-		return -1, fmt.Errorf("failed to get schema from stream: %w", err)
-	}
-
-	s.connector.logger.Info("sync function called and schema acquired", tableLog)
-
-	avroSchema, err := s.getAvroSchema(dstTableName, schema)
-	if err != nil {
-		return 0, err
-	}
-
-	partitionID := shared.RandomString(16)
-	avroFile, err := s.writeToAvroFile(ctx, stream, avroSchema, partitionID, flowJobName)
-	if err != nil {
-		return 0, err
-	}
-	defer avroFile.Cleanup()
-	s.connector.logger.Info(fmt.Sprintf("written %d records to Avro file", avroFile.NumRecords), tableLog)
-	err = s.CopyStageToDestination(ctx, avroFile)
-	if err != nil {
-		return 0, err
-	}
-
-	return avroFile.NumRecords, nil
-}
-
 func (s *ClickhouseAvroSyncMethod) SyncQRepRecords(
 	ctx context.Context,
 	config *protos.QRepConfig,
@@ -137,11 +78,13 @@ func (s *ClickhouseAvroSyncMethod) SyncQRepRecords(
 	}
 	selectorStr := strings.Join(selector, ",")
 	//nolint:gosec
-	query := fmt.Sprintf("INSERT INTO %s(%s) SELECT * FROM s3('%s','%s','%s', 'Avro')",
-		config.DestinationTableIdentifier, selectorStr, avroFileUrl,
+	// partitions are retried as a whole until their metadata is inserted below, deduplicated on the partition
+	query := fmt.Sprintf("INSERT INTO %s(%s) %s SELECT * FROM s3('%s','%s','%s', 'Avro')",
+		config.DestinationTableIdentifier, selectorStr,
+		insertDedupSettings(config.FlowJobName+"_"+partition.PartitionId), avroFileUrl,
 		s.connector.creds.AccessKeyID, s.connector.creds.SecretAccessKey)
 
-	_, err = s.connector.database.ExecContext(ctx, query)
+	_, err = s.connector.database.ExecContext(withExecutionDeadline(ctx), query)
 	if err != nil {
 		return 0, err
 	}
@@ -175,13 +118,14 @@ func (s *ClickhouseAvroSyncMethod) writeToAvroFile(
 	flowJobName string,
 ) (*avro.AvroFile, error) {
 	stagingPath := s.connector.creds.BucketPath
-	ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, avro.CompressZstd, qvalue.QDWHTypeClickhouse)
+	codec := avro.StagingCodec(qvalue.QDWHTypeClickhouse, avro.CompressZstd)
+	ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, codec, qvalue.QDWHTypeClickhouse)
 	s3o, err := utils.NewS3BucketAndPrefix(stagingPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse staging path: %w", err)
 	}
 
-	s3AvroFileKey := fmt.Sprintf("%s/%s/%s.avro.zst", s3o.Prefix, flowJobName, partitionID)
+	s3AvroFileKey := fmt.Sprintf("%s/%s/%s%s", s3o.Prefix, flowJobName, partitionID, codec.FileExtension())
 	s3AvroFileKey = strings.Trim(s3AvroFileKey, "/")
 
 	avroFile, err := ocfWriter.WriteRecordsToS3(ctx, s3o.Bucket, s3AvroFileKey, utils.S3PeerCredentials{
@@ -0,0 +1,102 @@
+package connclickhouse
+
+import (
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// rows per insert into the raw table, bounding how much of a batch is held in memory at once
+const rawTableBlockRows = 1 << 17
+
+// columns of the raw table in the order RecordsToRawTableStream produces them
+var rawTableColumnNames = []string{
+	"_peerdb_uid",
+	"_peerdb_timestamp",
+	"_peerdb_destination_table_name",
+	"_peerdb_data",
+	"_peerdb_record_type",
+	"_peerdb_match_data",
+	"_peerdb_batch_id",
+	"_peerdb_unchanged_toast_columns",
+}
+
+// rawTableColumns accumulates raw table records column by column for a native protocol insert.
+type rawTableColumns struct {
+	uid                   []string
+	timestamp             []int64
+	destinationTableName  []string
+	data                  []string
+	recordType            []int32
+	matchData             []string
+	batchID               []int32
+	unchangedToastColumns []string
+}
+
+func newRawTableColumns(capacity int) *rawTableColumns {
+	return &rawTableColumns{
+		uid:                   make([]string, 0, capacity),
+		timestamp:             make([]int64, 0, capacity),
+		destinationTableName:  make([]string, 0, capacity),
+		data:                  make([]string, 0, capacity),
+		recordType:            make([]int32, 0, capacity),
+		matchData:             make([]string, 0, capacity),
+		batchID:               make([]int32, 0, capacity),
+		unchangedToastColumns: make([]string, 0, capacity),
+	}
+}
+
+func (r *rawTableColumns) len() int {
+	return len(r.uid)
+}
+
+func (r *rawTableColumns) columns() []any {
+	return []any{r.uid, r.timestamp, r.destinationTableName, r.data, r.recordType, r.matchData, r.batchID, r.unchangedToastColumns}
+}
+
+func (r *rawTableColumns) append(record []qvalue.QValue) error {
+	if len(record) != len(rawTableColumnNames) {
+		return fmt.Errorf("raw table record has %d values, expected %d", len(record), len(rawTableColumnNames))
+	}
+
+	timestamp, err := rawTableInt(record[1])
+	if err != nil {
+		return err
+	}
+	recordType, err := rawTableInt(record[4])
+	if err != nil {
+		return err
+	}
+	batchID, err := rawTableInt(record[6])
+	if err != nil {
+		return err
+	}
+
+	r.uid = append(r.uid, rawTableString(record[0]))
+	r.timestamp = append(r.timestamp, timestamp)
+	r.destinationTableName = append(r.destinationTableName, rawTableString(record[2]))
+	r.data = append(r.data, rawTableString(record[3]))
+	r.recordType = append(r.recordType, int32(recordType))
+	r.matchData = append(r.matchData, rawTableString(record[5]))
+	r.batchID = append(r.batchID, int32(batchID))
+	r.unchangedToastColumns = append(r.unchangedToastColumns, rawTableString(record[7]))
+	return nil
+}
+
+func rawTableString(value qvalue.QValue) string {
+	str, _ := value.Value().(string)
+	return str
+}
+
+func rawTableInt(value qvalue.QValue) (int64, error) {
+	switch v := value.Value().(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("unexpected raw table value %v of type %T", value.Value(), value.Value())
+	}
+}
@@ -0,0 +1,17 @@
+package connclickhouse
+
+import (
+	"context"
+	"fmt"
+)
+
+// PruneRawTable deletes the rows of normalized batches from a mirror's raw table, implementing RawTablePruneConnector.
+// The rows are deleted by a mutation, which ClickHouse applies in the background.
+func (c *ClickhouseConnector) PruneRawTable(ctx context.Context, flowJobName string, batchID int64) error {
+	rawTableName := c.getRawTableName(flowJobName)
+	if _, err := c.database.ExecContext(withExecutionDeadline(ctx), fmt.Sprintf("ALTER TABLE %s%s DELETE WHERE _peerdb_batch_id <= %d",
+		rawTableName, onCluster(c.config), batchID)); err != nil {
+		return fmt.Errorf("unable to prune raw table: %w", err)
+	}
+	return nil
+}
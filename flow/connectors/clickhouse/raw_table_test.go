@@ -0,0 +1,30 @@
+package connclickhouse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestRawTableColumnsAppend(t *testing.T) {
+	columns := newRawTableColumns(2)
+	require.NoError(t, columns.append([]qvalue.QValue{
+		qvalue.New(qvalue.QValueKindString, "uid"),
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(1700000000)),
+		qvalue.New(qvalue.QValueKindString, "events"),
+		qvalue.New(qvalue.QValueKindString, `{"id":1}`),
+		qvalue.New(qvalue.QValueKindInt64, 2),
+		qvalue.New(qvalue.QValueKindString, `{"id":1}`),
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(7)),
+		qvalue.New(qvalue.QValueKindString, ""),
+	}))
+	require.Equal(t, 1, columns.len())
+	require.Equal(t, []any{
+		[]string{"uid"}, []int64{1700000000}, []string{"events"}, []string{`{"id":1}`},
+		[]int32{2}, []string{`{"id":1}`}, []int32{7}, []string{""},
+	}, columns.columns())
+
+	require.Error(t, columns.append(make([]qvalue.QValue, 3)))
+}
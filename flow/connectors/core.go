@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	connbigquery "github.com/PeerDB-io/peer-flow/connectors/bigquery"
 	connclickhouse "github.com/PeerDB-io/peer-flow/connectors/clickhouse"
@@ -14,9 +15,11 @@ import (
 	conns3 "github.com/PeerDB-io/peer-flow/connectors/s3"
 	connsnowflake "github.com/PeerDB-io/peer-flow/connectors/snowflake"
 	connsqlserver "github.com/PeerDB-io/peer-flow/connectors/sqlserver"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
 	"github.com/PeerDB-io/peer-flow/shared/alerting"
 )
 
@@ -67,6 +70,14 @@ type CDCPullConnector interface {
 	AddTablesToPublication(ctx context.Context, req *protos.AddTablesToPublicationInput) error
 }
 
+type SlotReleaseConnector interface {
+	CDCPullConnector
+
+	// ReleaseReplicationSlot disconnects whatever still holds a replication slot active,
+	// so that a restarted mirror can replicate from it.
+	ReleaseReplicationSlot(ctx context.Context, slotName string) error
+}
+
 type NormalizedTablesConnector interface {
 	Connector
 
@@ -77,10 +88,9 @@ type NormalizedTablesConnector interface {
 	SetupNormalizedTable(
 		ctx context.Context,
 		tx any,
+		config *protos.SetupNormalizedTableBatchInput,
 		tableIdentifier string,
 		tableSchema *protos.TableSchema,
-		softDeleteColName string,
-		syncedAtColName string,
 	) (bool, error)
 
 	// CleanupSetupNormalizedTables may be used to rollback transaction started by StartSetupNormalizedTables.
@@ -91,6 +101,28 @@ type NormalizedTablesConnector interface {
 	FinishSetupNormalizedTables(ctx context.Context, tx any) error
 }
 
+type TableAdoptionConnector interface {
+	NormalizedTablesConnector
+
+	// CheckAdoptedTable checks that an existing table can be replicated into as if SetupNormalizedTable had created it,
+	// having every column of tableSchema and the soft delete and synced at columns with compatible types.
+	CheckAdoptedTable(
+		ctx context.Context,
+		config *protos.SetupNormalizedTableBatchInput,
+		tableIdentifier string,
+		tableSchema *protos.TableSchema,
+	) error
+
+	// DiffTable compares an existing table with the columns a mirror replicating tableSchema writes to,
+	// so that columns users dropped, added or retyped on the destination are caught before they fail a normalize.
+	DiffTable(
+		ctx context.Context,
+		config *protos.SetupNormalizedTableBatchInput,
+		tableIdentifier string,
+		tableSchema *protos.TableSchema,
+	) (*utils.TableDrift, error)
+}
+
 type CDCSyncConnector interface {
 	Connector
 
@@ -119,20 +151,96 @@ type CDCSyncConnector interface {
 
 	// SyncRecords pushes records to the destination peer and stores it in PeerDB specific tables.
 	// This method should be idempotent, and should be able to be called multiple times with the same request.
+	// A retry after the batch's metadata failed to be recorded pulls the batch again under the same batch id,
+	// possibly ending at a later checkpoint, so writing batch N must replace whatever an earlier attempt wrote for it,
+	// either in the same transaction as the metadata or by deleting the earlier attempt's rows in the one writing them.
+	// Destinations keeping their metadata in the catalog track attempts at a batch with the shared
+	// connmetadata.SyncBatch bookkeeping, started before writing and finished along with the last sync state.
+	// Event streams such as EventHub can't take records back, retries skip what was recorded as delivered
+	// and events carry a message id stable across attempts for consumers to drop the rest.
 	SyncRecords(ctx context.Context, req *model.SyncRecordsRequest) (*model.SyncResponse, error)
 
 	// SyncFlowCleanup drops metadata tables on the destination, as a part of DROP MIRROR.
 	SyncFlowCleanup(ctx context.Context, jobName string) error
 }
 
+// RawTableUpgradeConnector is implemented by destinations whose raw tables need changes when shared.RawTableVersion is bumped,
+// mirrors into destinations with a raw table which don't implement it fail until they do.
+type RawTableUpgradeConnector interface {
+	CDCSyncConnector
+
+	// UpgradeRawTable changes the layout of a mirror's raw table in place from fromVersion to shared.RawTableVersion.
+	// It is retried if recording the new version fails, so it must be safe to run on a table already upgraded.
+	UpgradeRawTable(ctx context.Context, flowJobName string, fromVersion int32) error
+}
+
+type RawTablePruneConnector interface {
+	CDCSyncConnector
+
+	// GetLastNormalizeBatchID gets the last batch normalized on the destination from the metadata table
+	GetLastNormalizeBatchID(ctx context.Context, jobName string) (int64, error)
+
+	// PruneRawTable deletes the rows of batches up to and including batchID from a mirror's raw table,
+	// which must all have been normalized.
+	PruneRawTable(ctx context.Context, flowJobName string, batchID int64) error
+}
+
 type CDCNormalizeConnector interface {
 	Connector
 
 	// NormalizeRecords merges records pushed earlier into the destination table.
 	// This method should be idempotent, and should be able to be called multiple times with the same request.
+	// The batch range it normalizes always selects the same raw rows, so merges keyed on the primary key
+	// or inserts deduplicated on the range apply them once even if recording the normalize batch id fails.
 	NormalizeRecords(ctx context.Context, req *model.NormalizeRecordsRequest) (*model.NormalizeResponse, error)
 }
 
+type NormalizeSimulationConnector interface {
+	CDCSyncConnector
+	CDCNormalizeConnector
+
+	// SimulateNormalize renders the statements NormalizeRecords would run on a table for the batches after the last
+	// normalized one, without running them. If explain is set, the destination's plan is attached to each statement.
+	SimulateNormalize(ctx context.Context, req *model.NormalizeRecordsRequest, tableName string,
+		explain bool) (*model.NormalizeSimulation, error)
+}
+
+type RowSampleConnector interface {
+	Connector
+
+	// GetRowsByPrimaryKey fetches columns of the rows in a table whose primary key is one of keys,
+	// each key holding a value per primary key column. Columns come back in the order they were requested.
+	GetRowsByPrimaryKey(ctx context.Context, tableIdentifier string, columns []string, pkeyColumns []string,
+		keys [][]qvalue.QValue) (*model.QRecordBatch, error)
+}
+
+type PrimaryKeyScanConnector interface {
+	Connector
+
+	// ScanPrimaryKeys calls fn with the primary key of every row in a table, each key holding a value per
+	// primary key column. Rows marked deleted in softDeleteColName are skipped if it's set.
+	ScanPrimaryKeys(ctx context.Context, tableIdentifier string, pkeyColumns []string, softDeleteColName string,
+		fn func(key []qvalue.QValue) error) error
+}
+
+type RowScanConnector interface {
+	PrimaryKeyScanConnector
+
+	// ScanRows calls fn with the values of columns of every row in a table, in the order columns are given.
+	// Rows marked deleted in softDeleteColName are skipped if it's set.
+	ScanRows(ctx context.Context, tableIdentifier string, columns []string, softDeleteColName string,
+		fn func(row []qvalue.QValue) error) error
+}
+
+type DeleteReconcileConnector interface {
+	PrimaryKeyScanConnector
+
+	// DeleteRowsByPrimaryKey deletes the rows in a table whose primary key is one of keys,
+	// or marks them deleted in softDeleteColName if it's set. Returns the number of rows affected.
+	DeleteRowsByPrimaryKey(ctx context.Context, tableIdentifier string, pkeyColumns []string,
+		keys [][]qvalue.QValue, softDeleteColName string) (int64, error)
+}
+
 type QRepPullConnector interface {
 	Connector
 
@@ -143,6 +251,31 @@ type QRepPullConnector interface {
 	PullQRepRecords(ctx context.Context, config *protos.QRepConfig, partition *protos.QRepPartition) (*model.QRecordBatch, error)
 }
 
+type QRepPullStreamConnector interface {
+	QRepPullConnector
+
+	// PullQRepRecordStream hands on the records for a given partition to stream as they're read,
+	// closing it once done, and returns the number of records pulled.
+	PullQRepRecordStream(ctx context.Context, config *protos.QRepConfig, partition *protos.QRepPartition,
+		stream *model.QRecordStream) (int, error)
+}
+
+type QRepThrottleConnector interface {
+	Connector
+
+	// PartitionParallelism returns how many of maxParallelism partitions may be replicated in parallel
+	// given the source's current load.
+	PartitionParallelism(ctx context.Context, maxParallelism int) (int, error)
+}
+
+type QRepRowEstimateConnector interface {
+	Connector
+
+	// EstimateQRepRows estimates the rows of the watermark table from statistics, without scanning it.
+	// Returns 0 if the table has no statistics.
+	EstimateQRepRows(ctx context.Context, config *protos.QRepConfig) (int64, error)
+}
+
 type QRepSyncConnector interface {
 	Connector
 
@@ -155,6 +288,16 @@ type QRepSyncConnector interface {
 		stream *model.QRecordStream) (int, error)
 }
 
+// QRepCheckpointConnector is implemented by destinations which mark partitions synced themselves. Postgres marks
+// them in the transaction writing their rows, so the mark holds even when the activity fails before the catalog
+// records the partition, and mirrors set up before the catalog recorded partitions have theirs there.
+type QRepCheckpointConnector interface {
+	QRepSyncConnector
+
+	// IsQRepPartitionSynced checks whether the partition was marked synced on the destination.
+	IsQRepPartitionSynced(ctx context.Context, config *protos.QRepConfig, partitionID string) (bool, error)
+}
+
 type QRepConsolidateConnector interface {
 	Connector
 
@@ -226,6 +369,33 @@ func GetQRepConsolidateConnector(ctx context.Context, config *protos.Peer) (QRep
 	return GetConnectorAs[QRepConsolidateConnector](ctx, config)
 }
 
+// PeerCredentialExpiries returns the expiry of credentials attached to a peer, see utils.PeerCredentialExpiries,
+// along with that of the Snowflake user a key pair authenticates as.
+func PeerCredentialExpiries(ctx context.Context, peer *protos.Peer) ([]*protos.PeerCredentialExpiry, error) {
+	expiries, err := utils.PeerCredentialExpiries(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+	if config, ok := peer.Config.(*protos.Peer_SnowflakeConfig); ok {
+		conn, err := connsnowflake.NewSnowflakeConnector(ctx, config.SnowflakeConfig)
+		if err != nil {
+			return nil, err
+		}
+		defer CloseConnector(ctx, conn)
+		expiresAt, err := conn.UserExpiry(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !expiresAt.IsZero() {
+			expiries = append(expiries, &protos.PeerCredentialExpiry{
+				Credential: "key pair user",
+				ExpiresAt:  timestamppb.New(expiresAt),
+			})
+		}
+	}
+	return expiries, nil
+}
+
 func CloseConnector(ctx context.Context, conn Connector) {
 	err := conn.Close()
 	if err != nil {
@@ -237,6 +407,8 @@ func CloseConnector(ctx context.Context, conn Connector) {
 var (
 	_ CDCPullConnector = &connpostgres.PostgresConnector{}
 
+	_ SlotReleaseConnector = &connpostgres.PostgresConnector{}
+
 	_ CDCSyncConnector = &connpostgres.PostgresConnector{}
 	_ CDCSyncConnector = &connbigquery.BigQueryConnector{}
 	_ CDCSyncConnector = &connsnowflake.SnowflakeConnector{}
@@ -244,24 +416,63 @@ var (
 	_ CDCSyncConnector = &conns3.S3Connector{}
 	_ CDCSyncConnector = &connclickhouse.ClickhouseConnector{}
 
+	_ RawTablePruneConnector = &connpostgres.PostgresConnector{}
+	_ RawTablePruneConnector = &connbigquery.BigQueryConnector{}
+	_ RawTablePruneConnector = &connsnowflake.SnowflakeConnector{}
+	_ RawTablePruneConnector = &connclickhouse.ClickhouseConnector{}
+
 	_ CDCNormalizeConnector = &connpostgres.PostgresConnector{}
 	_ CDCNormalizeConnector = &connbigquery.BigQueryConnector{}
 	_ CDCNormalizeConnector = &connsnowflake.SnowflakeConnector{}
 	_ CDCNormalizeConnector = &connclickhouse.ClickhouseConnector{}
 
+	_ NormalizeSimulationConnector = &connpostgres.PostgresConnector{}
+	_ NormalizeSimulationConnector = &connbigquery.BigQueryConnector{}
+	_ NormalizeSimulationConnector = &connsnowflake.SnowflakeConnector{}
+	_ NormalizeSimulationConnector = &connclickhouse.ClickhouseConnector{}
+
+	_ RowSampleConnector = &connpostgres.PostgresConnector{}
+	_ RowSampleConnector = &connbigquery.BigQueryConnector{}
+	_ RowSampleConnector = &connsnowflake.SnowflakeConnector{}
+
+	_ PrimaryKeyScanConnector = &connpostgres.PostgresConnector{}
+
+	_ DeleteReconcileConnector = &connpostgres.PostgresConnector{}
+	_ DeleteReconcileConnector = &connbigquery.BigQueryConnector{}
+	_ DeleteReconcileConnector = &connsnowflake.SnowflakeConnector{}
+
+	_ RowScanConnector = &connpostgres.PostgresConnector{}
+	_ RowScanConnector = &connbigquery.BigQueryConnector{}
+	_ RowScanConnector = &connsnowflake.SnowflakeConnector{}
+
 	_ NormalizedTablesConnector = &connpostgres.PostgresConnector{}
 	_ NormalizedTablesConnector = &connbigquery.BigQueryConnector{}
 	_ NormalizedTablesConnector = &connsnowflake.SnowflakeConnector{}
 	_ NormalizedTablesConnector = &connclickhouse.ClickhouseConnector{}
 
+	_ TableAdoptionConnector = &connpostgres.PostgresConnector{}
+	_ TableAdoptionConnector = &connbigquery.BigQueryConnector{}
+	_ TableAdoptionConnector = &connsnowflake.SnowflakeConnector{}
+
 	_ QRepPullConnector = &connpostgres.PostgresConnector{}
 	_ QRepPullConnector = &connsqlserver.SQLServerConnector{}
 
+	_ QRepPullStreamConnector = &connpostgres.PostgresConnector{}
+	_ QRepPullStreamConnector = &connsqlserver.SQLServerConnector{}
+
+	_ QRepThrottleConnector = &connpostgres.PostgresConnector{}
+
+	_ QRepRowEstimateConnector = &connpostgres.PostgresConnector{}
+
 	_ QRepSyncConnector = &connpostgres.PostgresConnector{}
 	_ QRepSyncConnector = &connbigquery.BigQueryConnector{}
 	_ QRepSyncConnector = &connsnowflake.SnowflakeConnector{}
 	_ QRepSyncConnector = &connclickhouse.ClickhouseConnector{}
 
+	_ QRepCheckpointConnector = &connpostgres.PostgresConnector{}
+	_ QRepCheckpointConnector = &connclickhouse.ClickhouseConnector{}
+
 	_ QRepConsolidateConnector = &connsnowflake.SnowflakeConnector{}
+	_ QRepConsolidateConnector = &connbigquery.BigQueryConnector{}
 	_ QRepConsolidateConnector = &connclickhouse.ClickhouseConnector{}
 )
@@ -12,10 +12,10 @@ import (
 
 	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/model"
-	"github.com/PeerDB-io/peer-flow/peerdbenv"
 )
 
 type EventHubConnector struct {
@@ -97,20 +97,23 @@ func (c *EventHubConnector) SetLastOffset(ctx context.Context, jobName string, o
 	return nil
 }
 
+// processBatch sends the records of a batch, skipping those an earlier attempt at the batch delivered,
 // returns the number of records synced
 func (c *EventHubConnector) processBatch(
 	ctx context.Context,
 	flowJobName string,
 	batch *model.CDCRecordStream,
+	syncBatch *metadataStore.SyncBatch,
 ) (uint32, error) {
 	batchPerTopic := NewHubBatches(c.hubManager)
 	toJSONOpts := model.NewToJSONOptions(c.config.UnnestColumns, false)
 
-	ticker := time.NewTicker(peerdbenv.PeerDBEventhubFlushTimeoutSeconds())
+	ticker := time.NewTicker(dynamicconf.PeerDBEventhubFlushTimeoutSeconds(ctx))
 	defer ticker.Stop()
 
-	lastSeenLSN := int64(0)
-	lastUpdatedOffset := int64(0)
+	lastSeenLSN := syncBatch.LastOffset
+	lastUpdatedOffset := syncBatch.LastOffset
+	skippedRecords := 0
 
 	numRecords := atomic.Uint32{}
 	shutdown := utils.HeartbeatRoutine(ctx, func() string {
@@ -130,13 +133,20 @@ func (c *EventHubConnector) processBatch(
 
 				currNumRecords := numRecords.Load()
 
+				if skippedRecords != 0 {
+					c.logger.Warn("processBatch", slog.Int("records delivered by an earlier attempt", skippedRecords))
+				}
 				c.logger.Info("processBatch", slog.Int("Total records sent to event hub", int(currNumRecords)))
 				return currNumRecords, nil
 			}
 
+			recordLSN := record.GetCheckpointID()
+			if skipDeliveredRecord(syncBatch, recordLSN) {
+				skippedRecords += 1
+				continue
+			}
 			numRecords.Add(1)
 
-			recordLSN := record.GetCheckpointID()
 			if recordLSN > lastSeenLSN {
 				lastSeenLSN = recordLSN
 			}
@@ -164,14 +174,15 @@ func (c *EventHubConnector) processBatch(
 			// partition_column is the column in the table that is used to determine
 			// the partition key for the eventhub.
 			partitionColumn := destination.PartitionKeyColumn
-			partitionValue := record.GetItems().GetColumnValue(partitionColumn).Value
+			partitionValue := record.GetItems().GetColumnValue(partitionColumn).Value()
 			var partitionKey string
 			if partitionValue != nil {
 				partitionKey = fmt.Sprint(partitionValue)
 			}
 			partitionKey = utils.HashedPartitionKey(partitionKey, numPartitions)
 			destination.SetPartitionValue(partitionKey)
-			err = batchPerTopic.AddEvent(ctx, destination, json, false)
+			err = batchPerTopic.AddEvent(ctx, destination,
+				newEventData(flowJobName, syncBatch.SyncBatchID, recordLSN, json), false)
 			if err != nil {
 				c.logger.Error("failed to add event to batch", slog.Any("error", err))
 				return 0, err
@@ -192,6 +203,10 @@ func (c *EventHubConnector) processBatch(
 			}
 
 			if lastSeenLSN > lastUpdatedOffset {
+				// records up to here are delivered, a retry of the batch skips them
+				if err := c.pgMetadata.RecordBatchOffset(ctx, flowJobName, syncBatch.SyncBatchID, lastSeenLSN); err != nil {
+					return 0, err
+				}
 				err = c.SetLastOffset(ctx, flowJobName, lastSeenLSN)
 				lastUpdatedOffset = lastSeenLSN
 				c.logger.Info("processBatch", slog.Int64("updated last offset", lastSeenLSN))
@@ -203,16 +218,31 @@ func (c *EventHubConnector) processBatch(
 	}
 }
 
+// skipDeliveredRecord returns whether an earlier attempt at the batch delivered the record with checkpointID.
+// Event Hubs can't take events back, so a retry resumes after the checkpoint earlier attempts recorded as delivered.
+func skipDeliveredRecord(syncBatch *metadataStore.SyncBatch, checkpointID int64) bool {
+	return syncBatch.Retry() && checkpointID <= syncBatch.LastOffset
+}
+
+// SyncRecords sends the records of a batch as events. Records are only skipped by retries up to the checkpoint
+// recorded once they were sent, events sent again after a failure in between have the same message id.
 func (c *EventHubConnector) SyncRecords(ctx context.Context, req *model.SyncRecordsRequest) (*model.SyncResponse, error) {
 	batch := req.Records
 
-	numRecords, err := c.processBatch(ctx, req.FlowJobName, batch)
+	syncBatch, err := c.pgMetadata.StartBatch(ctx, req.FlowJobName, req.SyncBatchID)
+	if err != nil {
+		return nil, err
+	}
+	numRecords, err := c.processBatch(ctx, req.FlowJobName, batch, syncBatch)
 	if err != nil {
 		c.logger.Error("failed to process batch", slog.Any("error", err))
 		return nil, err
 	}
 
-	lastCheckpoint := req.Records.GetLastCheckpoint()
+	lastCheckpoint, err := req.Records.GetLastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
 	err = c.pgMetadata.FinishBatch(ctx, req.FlowJobName, req.SyncBatchID, lastCheckpoint)
 	if err != nil {
 		c.logger.Error("failed to increment id", slog.Any("error", err))
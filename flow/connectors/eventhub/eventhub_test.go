@@ -0,0 +1,28 @@
+package conneventhub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
+)
+
+func TestSkipDeliveredRecord(t *testing.T) {
+	first := &metadataStore.SyncBatch{SyncBatchID: 3, Attempts: 1}
+	require.False(t, skipDeliveredRecord(first, 1))
+
+	retry := &metadataStore.SyncBatch{SyncBatchID: 3, Attempts: 2, LastOffset: 100}
+	require.True(t, skipDeliveredRecord(retry, 99))
+	require.True(t, skipDeliveredRecord(retry, 100))
+	require.False(t, skipDeliveredRecord(retry, 101))
+}
+
+func TestEventMessageID(t *testing.T) {
+	event := newEventData("mirror", 3, 100, `{"id":1}`)
+	retried := newEventData("mirror", 3, 100, `{"id":1}`)
+	require.Equal(t, *event.MessageID, *retried.MessageID, "attempts at a batch send events with the same message id")
+	require.Equal(t, int64(3), event.Properties[batchIDProperty])
+	require.NotEqual(t, *event.MessageID, *newEventData("mirror", 3, 101, `{"id":2}`).MessageID)
+	require.NotEqual(t, *event.MessageID, *newEventData("other", 3, 100, `{"id":1}`).MessageID)
+}
@@ -30,7 +30,7 @@ func NewHubBatches(manager *EventHubManager) *HubBatches {
 func (h *HubBatches) AddEvent(
 	ctx context.Context,
 	destination ScopedEventhub,
-	event string,
+	event *azeventhubs.EventData,
 	// this is true when we are retrying to send the event after the batch size exceeded
 	// this should initially be false, and then true when we are retrying.
 	retryForBatchSizeExceed bool,
@@ -159,14 +159,21 @@ func (h *HubBatches) Clear() {
 	h.batch = make(map[ScopedEventhub]*azeventhubs.EventDataBatch)
 }
 
-func tryAddEventToBatch(event string, batch *azeventhubs.EventDataBatch) error {
-	eventData := eventDataFromString(event)
+func tryAddEventToBatch(event *azeventhubs.EventData, batch *azeventhubs.EventDataBatch) error {
 	opts := &azeventhubs.AddEventDataOptions{}
-	return batch.AddEventData(eventData, opts)
+	return batch.AddEventData(event, opts)
 }
 
-func eventDataFromString(s string) *azeventhubs.EventData {
+// batchIDProperty is the property of events holding the id of the batch which synced them
+const batchIDProperty = "peerdb_batch_id"
+
+// newEventData returns the event of a record. Its message id is the same whichever attempt at a batch sends it,
+// so consumers can drop the events a retry sends again.
+func newEventData(flowJobName string, syncBatchID int64, checkpointID int64, body string) *azeventhubs.EventData {
+	messageID := fmt.Sprintf("%s_%d", flowJobName, checkpointID)
 	return &azeventhubs.EventData{
-		Body: []byte(s),
+		Body:       []byte(body),
+		MessageID:  &messageID,
+		Properties: map[string]any{batchIDProperty: syncBatchID},
 	}
 }
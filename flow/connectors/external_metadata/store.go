@@ -20,6 +20,7 @@ import (
 const (
 	lastSyncStateTableName = "metadata_last_sync_state"
 	qrepTableName          = "metadata_qrep_partitions"
+	qrepImportTableName    = "metadata_qrep_import_stages"
 )
 
 type PostgresMetadataStore struct {
@@ -136,25 +137,6 @@ func (p *PostgresMetadataStore) UpdateLastOffset(ctx context.Context, jobName st
 	return nil
 }
 
-func (p *PostgresMetadataStore) FinishBatch(ctx context.Context, jobName string, syncBatchID int64, offset int64) error {
-	p.logger.Info("finishing batch", "SyncBatchID", syncBatchID, "offset", offset)
-	_, err := p.pool.Exec(ctx, `
-		INSERT INTO `+lastSyncStateTableName+` (job_name, last_offset, sync_batch_id)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (job_name)
-		DO UPDATE SET
-			last_offset = GREATEST(`+lastSyncStateTableName+`.last_offset, excluded.last_offset),
-			sync_batch_id = GREATEST(`+lastSyncStateTableName+`.sync_batch_id, excluded.sync_batch_id),
-			updated_at = NOW()
-	`, jobName, offset, syncBatchID)
-	if err != nil {
-		p.logger.Error("failed to finish batch", slog.Any("error", err))
-		return err
-	}
-
-	return nil
-}
-
 func (p *PostgresMetadataStore) UpdateNormalizeBatchID(ctx context.Context, jobName string, batchID int64) error {
 	p.logger.Info("updating normalize batch id for job")
 	_, err := p.pool.Exec(ctx,
@@ -200,6 +182,69 @@ func (p *PostgresMetadataStore) IsQrepPartitionSynced(ctx context.Context, jobNa
 	return exists, nil
 }
 
+// GetQrepPartition returns the partition as it was checkpointed once synced, nil when it hasn't been synced.
+func (p *PostgresMetadataStore) GetQrepPartition(ctx context.Context, jobName string, partitionID string) (*protos.QRepPartition, error) {
+	var partitionJSON string
+	err := p.pool.QueryRow(ctx,
+		`SELECT sync_partition FROM `+qrepTableName+` WHERE job_name = $1 AND partition_id = $2`,
+		jobName, partitionID).Scan(&partitionJSON)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	var partition protos.QRepPartition
+	if err := protojson.Unmarshal([]byte(partitionJSON), &partition); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal partition: %w", err)
+	}
+	return &partition, nil
+}
+
+// FinishQrepPartitionExport records a partition exported to object storage, pending import by the destination.
+func (p *PostgresMetadataStore) FinishQrepPartitionExport(
+	ctx context.Context,
+	jobName string,
+	partitionID string,
+	objectPath string,
+	numRecords int,
+) error {
+	_, err := p.pool.Exec(ctx,
+		`INSERT INTO `+qrepImportTableName+`(job_name, partition_id, object_path, num_records) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (job_name, partition_id) DO UPDATE SET object_path = $3, num_records = $4, exported_at = NOW()`,
+		jobName, partitionID, objectPath, numRecords)
+	return err
+}
+
+// GetPendingQrepImports returns partition id to object path of exported partitions not yet imported.
+func (p *PostgresMetadataStore) GetPendingQrepImports(ctx context.Context, jobName string) (map[string]string, error) {
+	rows, err := p.pool.Query(ctx,
+		`SELECT partition_id, object_path FROM `+qrepImportTableName+
+			` WHERE job_name = $1 AND imported_at IS NULL`, jobName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending imports: %w", err)
+	}
+
+	pending := make(map[string]string)
+	var partitionID, objectPath string
+	_, err = pgx.ForEachRow(rows, []any{&partitionID, &objectPath}, func() error {
+		pending[partitionID] = objectPath
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending imports: %w", err)
+	}
+	return pending, nil
+}
+
+func (p *PostgresMetadataStore) FinishQrepImport(ctx context.Context, jobName string, partitionIDs []string) error {
+	_, err := p.pool.Exec(ctx,
+		`UPDATE `+qrepImportTableName+` SET imported_at = NOW() WHERE job_name = $1 AND partition_id = ANY($2)`,
+		jobName, partitionIDs)
+	return err
+}
+
 func (p *PostgresMetadataStore) DropMetadata(ctx context.Context, jobName string) error {
 	_, err := p.pool.Exec(ctx,
 		`DELETE FROM `+lastSyncStateTableName+` WHERE job_name = $1`, jobName)
@@ -212,5 +257,15 @@ func (p *PostgresMetadataStore) DropMetadata(ctx context.Context, jobName string
 		return err
 	}
 
+	_, err = p.pool.Exec(ctx, `DELETE FROM `+qrepImportTableName+` WHERE job_name = $1`, jobName)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.pool.Exec(ctx, `DELETE FROM `+syncBatchesTableName+` WHERE job_name = $1`, jobName)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
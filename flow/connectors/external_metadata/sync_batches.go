@@ -0,0 +1,113 @@
+package connmetadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const syncBatchesTableName = "metadata_sync_batches"
+
+// SyncBatch is the bookkeeping of a CDC batch for destinations keeping their metadata in the catalog,
+// kept under (job, batch id) from the batch's first attempt until FinishBatch commits it with the last sync state.
+// A retry of the batch uses it to replace what earlier attempts wrote, so the batch is applied once.
+type SyncBatch struct {
+	// when the batch's first attempt started, for objects named by time to be named the same by retries
+	StartedAt time.Time
+	// objects such as files earlier attempts wrote, the retry overwrites or removes them
+	WrittenObjects []string
+	SyncBatchID    int64
+	// checkpoint up to which earlier attempts delivered records to destinations which can't take them back
+	LastOffset int64
+	// 1 on the batch's first attempt, when nothing of the batch can have been written yet
+	Attempts int32
+}
+
+// Retry returns whether earlier attempts at the batch may have written part of it.
+func (b *SyncBatch) Retry() bool {
+	return b.Attempts > 1
+}
+
+// StartBatch records an attempt at syncing a batch, returning what earlier attempts recorded about it.
+// Destinations call it before writing anything of the batch.
+func (p *PostgresMetadataStore) StartBatch(ctx context.Context, jobName string, syncBatchID int64) (*SyncBatch, error) {
+	batch := &SyncBatch{SyncBatchID: syncBatchID}
+	if err := p.pool.QueryRow(ctx, `
+		INSERT INTO `+syncBatchesTableName+` (job_name, sync_batch_id) VALUES ($1, $2)
+		ON CONFLICT (job_name, sync_batch_id) DO UPDATE SET attempts = `+syncBatchesTableName+`.attempts + 1
+		RETURNING attempts, started_at, written_objects, COALESCE(last_offset, 0)
+	`, jobName, syncBatchID).Scan(&batch.Attempts, &batch.StartedAt, &batch.WrittenObjects, &batch.LastOffset); err != nil {
+		return nil, fmt.Errorf("failed to start batch %d: %w", syncBatchID, err)
+	}
+	if batch.Retry() {
+		p.logger.Warn("retrying batch", slog.Int64("syncBatchID", syncBatchID), slog.Int("attempt", int(batch.Attempts)))
+	}
+	return batch, nil
+}
+
+// RecordBatchObjects adds objects to those written for a batch, before they are written so a retry knows of them
+// even when the attempt fails halfway.
+func (p *PostgresMetadataStore) RecordBatchObjects(ctx context.Context, jobName string, syncBatchID int64, objects []string) error {
+	if _, err := p.pool.Exec(ctx, `UPDATE `+syncBatchesTableName+
+		` SET written_objects = ARRAY(SELECT DISTINCT unnest(written_objects || $3::text[]))
+		WHERE job_name = $1 AND sync_batch_id = $2`, jobName, syncBatchID, objects); err != nil {
+		return fmt.Errorf("failed to record objects of batch %d: %w", syncBatchID, err)
+	}
+	return nil
+}
+
+// RecordBatchOffset records the checkpoint up to which records of a batch were delivered.
+func (p *PostgresMetadataStore) RecordBatchOffset(ctx context.Context, jobName string, syncBatchID int64, offset int64) error {
+	if _, err := p.pool.Exec(ctx, `UPDATE `+syncBatchesTableName+
+		` SET last_offset = GREATEST(last_offset, $3) WHERE job_name = $1 AND sync_batch_id = $2`,
+		jobName, syncBatchID, offset); err != nil {
+		return fmt.Errorf("failed to record offset of batch %d: %w", syncBatchID, err)
+	}
+	return nil
+}
+
+// FinishBatch commits a batch, advancing the last sync state of the job to it in the same transaction as
+// marking the batch finished. Bookkeeping of earlier batches is no longer needed by then and is dropped.
+func (p *PostgresMetadataStore) FinishBatch(ctx context.Context, jobName string, syncBatchID int64, offset int64) error {
+	p.logger.Info("finishing batch", "SyncBatchID", syncBatchID, "offset", offset)
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			p.logger.Error("failed to rollback finishing batch", slog.Any("error", err))
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO `+lastSyncStateTableName+` (job_name, last_offset, sync_batch_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (job_name)
+		DO UPDATE SET
+			last_offset = GREATEST(`+lastSyncStateTableName+`.last_offset, excluded.last_offset),
+			sync_batch_id = GREATEST(`+lastSyncStateTableName+`.sync_batch_id, excluded.sync_batch_id),
+			updated_at = NOW()
+	`, jobName, offset, syncBatchID); err != nil {
+		p.logger.Error("failed to finish batch", slog.Any("error", err))
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE `+syncBatchesTableName+
+		` SET finished_at = NOW(), last_offset = GREATEST(last_offset, $3) WHERE job_name = $1 AND sync_batch_id = $2`,
+		jobName, syncBatchID, offset); err != nil {
+		return fmt.Errorf("failed to mark batch %d finished: %w", syncBatchID, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM `+syncBatchesTableName+` WHERE job_name = $1 AND sync_batch_id < $2`,
+		jobName, syncBatchID); err != nil {
+		return fmt.Errorf("failed to drop bookkeeping of batches before %d: %w", syncBatchID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit batch %d: %w", syncBatchID, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package connmetadata
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+func TestSyncBatchRetry(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewPostgresMetadataStore(ctx)
+	require.NoError(t, err)
+	jobName := "sync_batches_" + strings.ToLower(shared.RandomString(8))
+	t.Cleanup(func() {
+		require.NoError(t, store.DropMetadata(context.Background(), jobName))
+	})
+
+	batch, err := store.StartBatch(ctx, jobName, 1)
+	require.NoError(t, err)
+	require.False(t, batch.Retry())
+	require.Empty(t, batch.WrittenObjects)
+	require.NoError(t, store.RecordBatchObjects(ctx, jobName, 1, []string{"a"}))
+	require.NoError(t, store.RecordBatchObjects(ctx, jobName, 1, []string{"a", "b"}))
+	require.NoError(t, store.RecordBatchOffset(ctx, jobName, 1, 100))
+	require.NoError(t, store.RecordBatchOffset(ctx, jobName, 1, 50))
+
+	// the attempt failed before finishing the batch, the retry finds what it wrote
+	retry, err := store.StartBatch(ctx, jobName, 1)
+	require.NoError(t, err)
+	require.True(t, retry.Retry())
+	require.Equal(t, int32(2), retry.Attempts)
+	require.ElementsMatch(t, []string{"a", "b"}, retry.WrittenObjects)
+	require.Equal(t, int64(100), retry.LastOffset)
+	require.True(t, batch.StartedAt.Equal(retry.StartedAt), "retries name objects by the time of the first attempt")
+
+	lastBatchID, err := store.GetLastBatchID(ctx, jobName)
+	require.NoError(t, err)
+	require.Zero(t, lastBatchID)
+	require.NoError(t, store.FinishBatch(ctx, jobName, 1, 120))
+	lastBatchID, err = store.GetLastBatchID(ctx, jobName)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), lastBatchID)
+	lastOffset, err := store.FetchLastOffset(ctx, jobName)
+	require.NoError(t, err)
+	require.Equal(t, int64(120), lastOffset)
+
+	next, err := store.StartBatch(ctx, jobName, 2)
+	require.NoError(t, err)
+	require.False(t, next.Retry())
+	require.Zero(t, next.LastOffset)
+	require.NoError(t, store.FinishBatch(ctx, jobName, 2, 130))
+
+	var batches int
+	require.NoError(t, store.pool.QueryRow(ctx,
+		"SELECT count(*) FROM "+syncBatchesTableName+" WHERE job_name = $1", jobName).Scan(&batches))
+	require.Equal(t, 1, batches, "bookkeeping of batches before the last finished one is dropped")
+}
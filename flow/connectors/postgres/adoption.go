@@ -0,0 +1,90 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func (c *PostgresConnector) CheckAdoptedTable(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+	tableIdentifier string,
+	tableSchema *protos.TableSchema,
+) error {
+	drift, err := c.DiffTable(ctx, config, tableIdentifier, tableSchema)
+	if err != nil {
+		return err
+	}
+	return drift.Err(tableIdentifier)
+}
+
+func (c *PostgresConnector) DiffTable(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+	tableIdentifier string,
+	tableSchema *protos.TableSchema,
+) (*utils.TableDrift, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing table schema and name: %w", err)
+	}
+	exists, err := c.tableExists(ctx, parsedTable)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("table %s %w: it doesn't exist", tableIdentifier, utils.ErrNotAdoptable)
+	}
+
+	// type modifiers are left out, numeric columns of any precision are accepted
+	rows, err := c.conn.Query(ctx, `SELECT attname, format_type(atttypid, NULL) FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`, parsedTable.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s: %w", tableIdentifier, err)
+	}
+	existing := make(map[string]string)
+	var name, columnType string
+	if _, err := pgx.ForEachRow(rows, []any{&name, &columnType}, func() error {
+		existing[name] = columnType
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s: %w", tableIdentifier, err)
+	}
+
+	expected := make([]utils.AdoptedColumn, 0, len(tableSchema.Columns)+2)
+	for _, column := range tableSchema.Columns {
+		expected = append(expected, utils.AdoptedColumn{
+			Name: column.Name, Type: qValueKindToPostgresType(column.Type), Source: column,
+		})
+	}
+	if config.SoftDeleteColName != "" {
+		expected = append(expected, utils.AdoptedColumn{Name: config.SoftDeleteColName, Type: "BOOLEAN"})
+	}
+	if config.SyncedAtColName != "" {
+		expected = append(expected, utils.AdoptedColumn{Name: config.SyncedAtColName, Type: "TIMESTAMP"})
+	}
+
+	// types are compared by the names Postgres gives them, so that aliases like TIMESTAMPTZ match
+	formattedTypes := make(map[string]string)
+	for i, column := range expected {
+		formatted, ok := formattedTypes[column.Type]
+		if !ok {
+			if err := c.conn.QueryRow(ctx, "SELECT format_type($1::regtype, NULL)", column.Type).Scan(&formatted); err != nil {
+				c.logger.Warn("failed to resolve type, comparing it by name",
+					slog.String("type", column.Type), slog.Any("error", err))
+				formatted = strings.ToLower(column.Type)
+			}
+			formattedTypes[column.Type] = formatted
+		}
+		expected[i].Type = formatted
+	}
+
+	return utils.DiffAdoptedColumns(expected, existing), nil
+}
@@ -0,0 +1,133 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// Postgres doesn't enforce the dimensions array columns are declared with, nor describe them in results,
+// so arrays are replicated as flat arrays of their kind unless declared with more than one dimension.
+// Those, and arrays of JSON or composites, are replicated as JSON with their elements nested by dimension,
+// as not all destinations have nested arrays.
+
+// isArrayOID returns whether values of a type are decoded as arrays.
+func isArrayOID(typeMap *pgtype.Map, typeOID uint32) bool {
+	if dt, ok := typeMap.TypeForOID(typeOID); ok {
+		_, ok := dt.Codec.(*pgtype.ArrayCodec)
+		return ok
+	}
+	return false
+}
+
+// decodeArray decodes an array with its dimensions, where decoding to a slice would flatten them.
+func decodeArray(typeMap *pgtype.Map, typeOID uint32, formatCode int16, data []byte) (pgtype.Array[any], error) {
+	var arr pgtype.Array[any]
+	if err := typeMap.Scan(typeOID, formatCode, data, &arr); err != nil {
+		return arr, fmt.Errorf("failed to decode array: %w", err)
+	}
+	return arr, nil
+}
+
+// arrayToJSON returns an array as JSON, with the elements of arrays of more than one dimension nested by dimension.
+func arrayToJSON(arr pgtype.Array[any]) (qvalue.QValue, error) {
+	if !arr.Valid {
+		return qvalue.QValue{Kind: qvalue.QValueKindJSON}, nil
+	}
+	return parseJSON(nestArrayElements(arr.Elements, arr.Dims))
+}
+
+// nestArrayElements splits the elements of an array, which Postgres stores in row-major order,
+// into one slice per index of the first dimension, recursively.
+func nestArrayElements(elements []any, dims []pgtype.ArrayDimension) []any {
+	if len(dims) == 0 || dims[0].Length == 0 {
+		return []any{}
+	}
+	if len(dims) == 1 {
+		return elements
+	}
+	length := int(dims[0].Length)
+	size := len(elements) / length
+	nested := make([]any, 0, length)
+	for i := range length {
+		nested = append(nested, nestArrayElements(elements[i*size:(i+1)*size], dims[1:]))
+	}
+	return nested
+}
+
+// typeOIDToQValueKind returns the kind values of a type are replicated as, custom types included.
+func (c *PostgresConnector) typeOIDToQValueKind(typeOID uint32) qvalue.QValueKind {
+	if qValueKind := c.postgresOIDToQValueKind(typeOID); qValueKind != qvalue.QValueKindInvalid {
+		return qValueKind
+	}
+	if customType, ok := c.customTypesMapping[typeOID]; ok {
+		return c.customTypeToQKind(typeOID, customType)
+	}
+	return qvalue.QValueKindString
+}
+
+// decodeArrayValue decodes an array for CDC, which replicates arrays as JSON when they are of JSON or composites
+// or turn out to have more than one dimension, and otherwise as flat arrays of their kind.
+func (c *PostgresConnector) decodeArrayValue(
+	typeMap *pgtype.Map,
+	typeOID uint32,
+	formatCode int16,
+	data []byte,
+) (qvalue.QValue, error) {
+	arr, err := decodeArray(typeMap, typeOID, formatCode, data)
+	if err != nil {
+		return qvalue.QValue{}, err
+	}
+	qValueKind := c.typeOIDToQValueKind(typeOID)
+	if len(arr.Dims) > 1 || qValueKind == qvalue.QValueKindJSON {
+		return arrayToJSON(arr)
+	}
+	if !arr.Valid {
+		return qvalue.QValue{Kind: qValueKind}, nil
+	}
+	return parseFieldFromQValueKind(qValueKind, arr.Elements)
+}
+
+// getMultiDimArrayFields returns the indexes of the fields read from array columns
+// declared with more than one dimension, which are replicated as JSON.
+func (c *PostgresConnector) getMultiDimArrayFields(
+	ctx context.Context,
+	conn pgQuerier,
+	fds []pgconn.FieldDescription,
+) (map[int]struct{}, error) {
+	relIDs := make([]uint32, 0, len(fds))
+	attNums := make([]int16, 0, len(fds))
+	indexes := make([]int32, 0, len(fds))
+	for i, fd := range fds {
+		// fields computed by queries don't come from a table
+		if fd.TableOID != 0 && isArrayOID(c.typeMap, fd.DataTypeOID) {
+			relIDs = append(relIDs, fd.TableOID)
+			attNums = append(attNums, int16(fd.TableAttributeNumber))
+			indexes = append(indexes, int32(i))
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, nil
+	}
+
+	rows, err := conn.Query(ctx, `SELECT f.i FROM unnest($1::oid[], $2::int2[], $3::int4[]) AS f(relid, attnum, i)
+		JOIN pg_attribute a ON a.attrelid = f.relid AND a.attnum = f.attnum
+		WHERE a.attndims > 1`, relIDs, attNums, indexes)
+	if err != nil {
+		return nil, fmt.Errorf("error getting dimensions of array columns: %w", err)
+	}
+	multiDimIndexes, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, fmt.Errorf("error getting dimensions of array columns: %w", err)
+	}
+	multiDimFields := make(map[int]struct{}, len(multiDimIndexes))
+	for _, i := range multiDimIndexes {
+		multiDimFields[int(i)] = struct{}{}
+	}
+	return multiDimFields, nil
+}
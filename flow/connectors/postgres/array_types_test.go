@@ -0,0 +1,36 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestArrayToJSON(t *testing.T) {
+	typeMap := pgtype.NewMap()
+	for _, tc := range []struct {
+		typeOID  uint32
+		text     string
+		expected string
+	}{
+		{typeOID: pgtype.Int4ArrayOID, text: "{1,2,3}", expected: "[1,2,3]"},
+		{typeOID: pgtype.Int4ArrayOID, text: "{{1,2,3},{4,5,6}}", expected: "[[1,2,3],[4,5,6]]"},
+		{typeOID: pgtype.Int4ArrayOID, text: "{{{1},{2}},{{3},{NULL}}}", expected: "[[[1],[2]],[[3],[null]]]"},
+		{typeOID: pgtype.Int4ArrayOID, text: "{}", expected: "[]"},
+		{typeOID: pgtype.TextArrayOID, text: `{{a,"b c"},{NULL,d}}`, expected: `[["a","b c"],[null,"d"]]`},
+		{typeOID: pgtype.JSONBArrayOID, text: `{"{\"a\": 1}","[2, 3]"}`, expected: `[{"a":1},[2,3]]`},
+	} {
+		arr, err := decodeArray(typeMap, tc.typeOID, pgtype.TextFormatCode, []byte(tc.text))
+		require.NoError(t, err)
+		actual, err := arrayToJSON(arr)
+		require.NoError(t, err)
+		require.Equal(t, qvalue.New(qvalue.QValueKindJSON, tc.expected), actual, tc.text)
+	}
+
+	actual, err := arrayToJSON(pgtype.Array[any]{})
+	require.NoError(t, err)
+	require.Equal(t, qvalue.QValue{Kind: qvalue.QValueKindJSON}, actual)
+}
@@ -3,6 +3,7 @@ package connpostgres
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"log/slog"
 	"slices"
@@ -18,6 +19,7 @@ import (
 	"go.temporal.io/sdk/activity"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
 	"github.com/PeerDB-io/peer-flow/connectors/utils/cdc_records"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/geo"
@@ -36,6 +38,17 @@ type PostgresCDCSource struct {
 	typeMap                *pgtype.Map
 	commitLock             bool
 
+	// set when the slot decodes prepared transactions, preparing holds the changes
+	// between BEGIN PREPARE and PREPARE of the transaction being decoded
+	twoPhase  bool
+	preparing *preparingTransaction
+
+	// set when in-progress transactions are streamed, changes between STREAM START and STREAM STOP
+	// are buffered in streams until their STREAM COMMIT
+	streams   *cdc_records.StreamedTransactions
+	inStream  bool
+	streamXid uint32
+
 	// for partitioned tables, maps child relid to parent relid
 	childToParentRelIDMapping map[uint32]uint32
 
@@ -53,6 +66,8 @@ type PostgresCDCConfig struct {
 	ChildToParentRelIDMap  map[uint32]uint32
 	CatalogPool            *pgxpool.Pool
 	FlowJobName            string
+	TwoPhase               bool
+	Streams                *cdc_records.StreamedTransactions
 }
 
 type startReplicationOpts struct {
@@ -71,14 +86,16 @@ func (c *PostgresConnector) NewPostgresCDCSource(cdcConfig *PostgresCDCConfig) *
 		publication:               cdcConfig.Publication,
 		relationMessageMapping:    cdcConfig.RelationMessageMapping,
 		childToParentRelIDMapping: cdcConfig.ChildToParentRelIDMap,
-		typeMap:                   pgtype.NewMap(),
+		typeMap:                   c.customTypeMap(),
 		commitLock:                false,
 		catalogPool:               cdcConfig.CatalogPool,
 		flowJobName:               cdcConfig.FlowJobName,
+		twoPhase:                  cdcConfig.TwoPhase,
+		streams:                   cdcConfig.Streams,
 	}
 }
 
-func GetChildToParentRelIDMap(ctx context.Context, conn *pgx.Conn) (map[uint32]uint32, error) {
+func GetChildToParentRelIDMap(ctx context.Context, conn utils.Querier) (map[uint32]uint32, error) {
 	query := `
 		SELECT parent.oid AS parentrelid, child.oid AS childrelid
 		FROM pg_inherits
@@ -104,7 +121,7 @@ func GetChildToParentRelIDMap(ctx context.Context, conn *pgx.Conn) (map[uint32]u
 		childToParentRelIDMap[childRelID.Uint32] = parentRelID.Uint32
 	}
 
-	return childToParentRelIDMap, nil
+	return resolvePartitionRoots(childToParentRelIDMap), nil
 }
 
 // PullRecords pulls records from the cdc stream
@@ -126,6 +143,13 @@ func (p *PostgresCDCSource) PullRecords(ctx context.Context, req *model.PullReco
 		}
 	}
 
+	if p.twoPhase {
+		err := catalog.PrunePreparedTransactions(ctx, p.catalogPool, p.flowJobName, req.LastOffset)
+		if err != nil {
+			return err
+		}
+	}
+
 	var standByLastLogged time.Time
 	cdcRecordsStorage := cdc_records.NewCDCRecordsStore(p.flowJobName)
 	defer func() {
@@ -151,12 +175,27 @@ func (p *PostgresCDCSource) PullRecords(ctx context.Context, req *model.PullReco
 	nextStandbyMessageDeadline := time.Now().Add(standbyMessageTimeout)
 
 	logger := logger.LoggerFromCtx(ctx)
+	var batchBytes uint64
 	addRecordWithKey := func(key *model.TableWithPkey, rec model.Record) error {
 		err := cdcRecordsStorage.Set(logger, key, rec)
 		if err != nil {
 			return err
 		}
-		records.AddRecord(rec)
+		batchBytes += uint64(model.EstimatedRecordSize(rec))
+		// records are stored untransformed, later records of the row are backfilled from them
+		if req.Transform != nil {
+			transformed, err := req.Transform(rec)
+			if err != nil {
+				return err
+			}
+			if transformed != nil {
+				if err := records.AddRecord(transformed); err != nil {
+					return err
+				}
+			}
+		} else if err := records.AddRecord(rec); err != nil {
+			return err
+		}
 
 		if cdcRecordsStorage.Len() == 1 {
 			records.SignalAsNotEmpty()
@@ -166,6 +205,123 @@ func (p *PostgresCDCSource) PullRecords(ctx context.Context, req *model.PullReco
 		return nil
 	}
 
+	processRecord := func(rec model.Record) error {
+		tableName := rec.GetDestinationTableName()
+		switch r := rec.(type) {
+		case *model.UpdateRecord:
+			// tableName here is destination tableName.
+			// should be ideally sourceTableName as we are in PullRecords.
+			// will change in future
+			isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
+			if req.TableNameSchemaMapping[tableName].SyntheticPrimaryKey {
+				for _, splitRec := range splitSyntheticKeyUpdate(r) {
+					if err := addRecordWithKey(nil, splitRec); err != nil {
+						return err
+					}
+				}
+			} else if isFullReplica {
+				err := addRecordWithKey(nil, rec)
+				if err != nil {
+					return err
+				}
+			} else {
+				tablePkeyVal, err := p.recToTablePKey(req, rec)
+				if err != nil {
+					return err
+				}
+
+				latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					err = addRecordWithKey(tablePkeyVal, rec)
+				} else {
+					// iterate through unchanged toast cols and set them in new record
+					updatedCols := r.NewItems.UpdateIfNotExists(latestRecord.GetItems())
+					for _, col := range updatedCols {
+						delete(r.UnchangedToastColumns, col)
+					}
+					err = addRecordWithKey(tablePkeyVal, rec)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+		case *model.InsertRecord:
+			isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
+			if isFullReplica {
+				err := addRecordWithKey(nil, rec)
+				if err != nil {
+					return err
+				}
+			} else {
+				tablePkeyVal, err := p.recToTablePKey(req, rec)
+				if err != nil {
+					return err
+				}
+
+				err = addRecordWithKey(tablePkeyVal, rec)
+				if err != nil {
+					return err
+				}
+			}
+		case *model.DeleteRecord:
+			isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
+			if isFullReplica {
+				err := addRecordWithKey(nil, rec)
+				if err != nil {
+					return err
+				}
+			} else {
+				tablePkeyVal, err := p.recToTablePKey(req, rec)
+				if err != nil {
+					return err
+				}
+
+				latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
+				if err != nil {
+					return err
+				}
+				if ok {
+					deleteRecord := rec.(*model.DeleteRecord)
+					deleteRecord.Items = latestRecord.GetItems()
+					updateRecord, ok := latestRecord.(*model.UpdateRecord)
+					if ok {
+						deleteRecord.UnchangedToastColumns = updateRecord.UnchangedToastColumns
+					}
+				} else {
+					deleteRecord := rec.(*model.DeleteRecord)
+					// there is nothing to backfill the items in the delete record with,
+					// so don't update the row with this record
+					// add sentinel value to prevent update statements from selecting
+					deleteRecord.UnchangedToastColumns = map[string]struct{}{
+						"_peerdb_not_backfilled_delete": {},
+					}
+				}
+
+				// A delete can only be followed by an INSERT, which does not need backfilling
+				// No need to store DeleteRecords in memory or disk.
+				err = addRecordWithKey(nil, rec)
+				if err != nil {
+					return err
+				}
+			}
+
+		case *model.RelationRecord:
+			tableSchemaDelta := r.TableSchemaDelta
+			if !model.IsEmptySchemaDelta(tableSchemaDelta) {
+				p.logger.Info(fmt.Sprintf("Detected schema change for table %s, addedColumns: %v, droppedColumns: %v, "+
+					"alteredColumns: %v, renamedColumns: %v, narrowedColumns: %v", tableSchemaDelta.SrcTableName,
+					tableSchemaDelta.AddedColumns, tableSchemaDelta.DroppedColumns, tableSchemaDelta.AlteredColumns,
+					tableSchemaDelta.RenamedColumns, tableSchemaDelta.NarrowedColumns))
+				records.AddSchemaDelta(req.TableNameMapping, tableSchemaDelta)
+			}
+		}
+		return nil
+	}
+
 	pkmRequiresResponse := false
 	waitingForCommit := false
 
@@ -190,6 +346,12 @@ func (p *PostgresCDCSource) PullRecords(ctx context.Context, req *model.PullReco
 				return nil
 			}
 
+			if req.MaxBatchBytes > 0 && batchBytes >= req.MaxBatchBytes {
+				p.logger.Info(fmt.Sprintf("batch reached %d bytes with %d records, returning accumulated records",
+					batchBytes, cdcRecordsStorage.Len()))
+				return nil
+			}
+
 			if waitingForCommit {
 				p.logger.Info(fmt.Sprintf(
 					"[%s] commit received, returning currently accumulated records - %d",
@@ -285,115 +447,32 @@ func (p *PostgresCDCSource) PullRecords(ctx context.Context, req *model.PullReco
 
 			p.logger.Debug(fmt.Sprintf("XLogData => WALStart %s ServerWALEnd %s ServerTime %s\n",
 				xld.WALStart, xld.ServerWALEnd, xld.ServerTime))
-			rec, err := p.processMessage(ctx, records, xld, clientXLogPos)
-			if err != nil {
-				return fmt.Errorf("error processing message: %w", err)
-			}
-
-			if rec != nil {
-				tableName := rec.GetDestinationTableName()
-				switch r := rec.(type) {
-				case *model.UpdateRecord:
-					// tableName here is destination tableName.
-					// should be ideally sourceTableName as we are in PullRecords.
-					// will change in future
-					isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
-					if isFullReplica {
-						err := addRecordWithKey(nil, rec)
-						if err != nil {
-							return err
-						}
-					} else {
-						tablePkeyVal, err := p.recToTablePKey(req, rec)
-						if err != nil {
-							return err
-						}
-
-						latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
-						if err != nil {
-							return err
-						}
-						if !ok {
-							err = addRecordWithKey(tablePkeyVal, rec)
-						} else {
-							// iterate through unchanged toast cols and set them in new record
-							updatedCols := r.NewItems.UpdateIfNotExists(latestRecord.GetItems())
-							for _, col := range updatedCols {
-								delete(r.UnchangedToastColumns, col)
-							}
-							err = addRecordWithKey(tablePkeyVal, rec)
-						}
-						if err != nil {
-							return err
-						}
-					}
-
-				case *model.InsertRecord:
-					isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
-					if isFullReplica {
-						err := addRecordWithKey(nil, rec)
-						if err != nil {
-							return err
-						}
-					} else {
-						tablePkeyVal, err := p.recToTablePKey(req, rec)
-						if err != nil {
-							return err
-						}
-
-						err = addRecordWithKey(tablePkeyVal, rec)
-						if err != nil {
-							return err
-						}
-					}
-				case *model.DeleteRecord:
-					isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
-					if isFullReplica {
-						err := addRecordWithKey(nil, rec)
-						if err != nil {
-							return err
-						}
-					} else {
-						tablePkeyVal, err := p.recToTablePKey(req, rec)
-						if err != nil {
-							return err
-						}
-
-						latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
-						if err != nil {
-							return err
-						}
-						if ok {
-							deleteRecord := rec.(*model.DeleteRecord)
-							deleteRecord.Items = latestRecord.GetItems()
-							updateRecord, ok := latestRecord.(*model.UpdateRecord)
-							if ok {
-								deleteRecord.UnchangedToastColumns = updateRecord.UnchangedToastColumns
-							}
-						} else {
-							deleteRecord := rec.(*model.DeleteRecord)
-							// there is nothing to backfill the items in the delete record with,
-							// so don't update the row with this record
-							// add sentinel value to prevent update statements from selecting
-							deleteRecord.UnchangedToastColumns = map[string]struct{}{
-								"_peerdb_not_backfilled_delete": {},
-							}
-						}
-
-						// A delete can only be followed by an INSERT, which does not need backfilling
-						// No need to store DeleteRecords in memory or disk.
-						err = addRecordWithKey(nil, rec)
-						if err != nil {
-							return err
-						}
+			if p.streams != nil && len(xld.WALData) > 0 && xld.WALData[0] == byte(pglogrepl.MessageTypeStreamCommit) {
+				if err := p.commitStreamedTransaction(records, xld, processRecord); err != nil {
+					return fmt.Errorf("error committing streamed transaction: %w", err)
+				}
+			} else if p.twoPhase && isTwoPhaseMessage(xld.WALData) {
+				committed, err := p.processTwoPhaseMessage(ctx, records, xld)
+				if err != nil {
+					return fmt.Errorf("error processing two-phase message: %w", err)
+				}
+				for _, rec := range committed {
+					if err := processRecord(rec); err != nil {
+						return err
 					}
+				}
+			} else {
+				rec, err := p.processMessage(ctx, records, xld, clientXLogPos)
+				if err != nil {
+					return fmt.Errorf("error processing message: %w", err)
+				}
 
-				case *model.RelationRecord:
-					tableSchemaDelta := r.TableSchemaDelta
-					if len(tableSchemaDelta.AddedColumns) > 0 {
-						p.logger.Info(fmt.Sprintf("Detected schema change for table %s, addedColumns: %v",
-							tableSchemaDelta.SrcTableName, tableSchemaDelta.AddedColumns))
-						records.AddSchemaDelta(req.TableNameMapping, tableSchemaDelta)
+				if rec != nil {
+					if _, isRelation := rec.(*model.RelationRecord); p.preparing != nil && !isRelation {
+						// changes of a prepared transaction are held back until it is committed
+						p.preparing.records = append(p.preparing.records, rec)
+					} else if err := processRecord(rec); err != nil {
+						return err
 					}
 				}
 			}
@@ -411,7 +490,13 @@ func (p *PostgresCDCSource) processMessage(
 	xld pglogrepl.XLogData,
 	currentClientXlogPos pglogrepl.LSN,
 ) (model.Record, error) {
-	logicalMsg, err := pglogrepl.Parse(xld.WALData)
+	var logicalMsg pglogrepl.Message
+	var err error
+	if p.streams != nil {
+		logicalMsg, err = pglogrepl.ParseV2(xld.WALData, p.inStream)
+	} else {
+		logicalMsg, err = pglogrepl.Parse(xld.WALData)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error parsing logical message: %w", err)
 	}
@@ -434,29 +519,73 @@ func (p *PostgresCDCSource) processMessage(
 		batch.UpdateLatestCheckpoint(int64(msg.CommitLSN))
 		p.commitLock = false
 	case *pglogrepl.RelationMessage:
-		// treat all relation messages as corresponding to parent if partitioned.
-		msg.RelationID = p.getParentRelIDIfPartitioned(msg.RelationID)
+		return p.processRelation(ctx, msg, currentClientXlogPos)
+	case *pglogrepl.TruncateMessage:
+		p.logger.Warn("TruncateMessage not supported")
 
+	// protocol version 2 messages, only parsed when streaming in-progress transactions
+	case *pglogrepl.StreamStartMessageV2:
+		p.logger.Debug(fmt.Sprintf("StreamStartMessage => XID: %v, FirstSegment: %v", msg.Xid, msg.FirstSegment))
+		p.inStream = true
+		p.streamXid = msg.Xid
+	case *pglogrepl.StreamStopMessageV2:
+		p.inStream = false
+	case *pglogrepl.StreamAbortMessageV2:
+		p.logger.Debug(fmt.Sprintf("StreamAbortMessage => XID: %v, SubXID: %v", msg.Xid, msg.SubXid))
+		return nil, p.streams.Abort(msg.Xid, msg.SubXid)
+	case *pglogrepl.InsertMessageV2:
+		rec, err := p.processInsertMessage(xld.WALStart, &msg.InsertMessage)
+		return p.bufferStreamed(msg.Xid, rec, err)
+	case *pglogrepl.UpdateMessageV2:
+		rec, err := p.processUpdateMessage(xld.WALStart, &msg.UpdateMessage)
+		return p.bufferStreamed(msg.Xid, rec, err)
+	case *pglogrepl.DeleteMessageV2:
+		rec, err := p.processDeleteMessage(xld.WALStart, &msg.DeleteMessage)
+		return p.bufferStreamed(msg.Xid, rec, err)
+	case *pglogrepl.RelationMessageV2:
+		return p.processRelation(ctx, &msg.RelationMessage, currentClientXlogPos)
+	case *pglogrepl.TruncateMessageV2:
+		p.logger.Warn("TruncateMessage not supported")
+	}
+
+	return nil, nil
+}
+
+func (p *PostgresCDCSource) processRelation(
+	ctx context.Context,
+	msg *pglogrepl.RelationMessage,
+	currentClientXlogPos pglogrepl.LSN,
+) (model.Record, error) {
+	// treat all relation messages as corresponding to parent if partitioned.
+	msg.RelationID = p.getParentRelIDIfPartitioned(msg.RelationID)
+
+	if _, exists := p.SrcTableIDNameMapping[msg.RelationID]; !exists {
+		// could be a partition created after replication started, which is only published
+		// on its own when the publication doesn't publish via the partition root
+		if err := p.refreshChildToParentRelIDMap(ctx); err != nil {
+			return nil, err
+		}
+		msg.RelationID = p.getParentRelIDIfPartitioned(msg.RelationID)
 		if _, exists := p.SrcTableIDNameMapping[msg.RelationID]; !exists {
 			return nil, nil
 		}
+	}
 
-		p.logger.Debug(fmt.Sprintf("RelationMessage => RelationID: %d, Namespace: %s, RelationName: %s, Columns: %v",
-			msg.RelationID, msg.Namespace, msg.RelationName, msg.Columns))
-
-		if p.relationMessageMapping[msg.RelationID] == nil {
-			p.relationMessageMapping[msg.RelationID] = convertRelationMessageToProto(msg)
-		} else {
-			// RelationMessages don't contain an LSN, so we use current clientXlogPos instead.
-			// https://github.com/postgres/postgres/blob/8b965c549dc8753be8a38c4a1b9fabdb535a4338/src/backend/replication/logical/proto.c#L670
-			return p.processRelationMessage(ctx, currentClientXlogPos, convertRelationMessageToProto(msg))
-		}
+	p.logger.Debug(fmt.Sprintf("RelationMessage => RelationID: %d, Namespace: %s, RelationName: %s, Columns: %v",
+		msg.RelationID, msg.Namespace, msg.RelationName, msg.Columns))
 
-	case *pglogrepl.TruncateMessage:
-		p.logger.Warn("TruncateMessage not supported")
+	attnums, err := p.relationAttnums(ctx, msg.RelationID)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, nil
+	currRel := convertRelationMessageToProto(msg, attnums)
+	if p.relationMessageMapping[msg.RelationID] == nil {
+		p.relationMessageMapping[msg.RelationID] = currRel
+		return nil, nil
+	}
+	// RelationMessages don't contain an LSN, so we use current clientXlogPos instead.
+	// https://github.com/postgres/postgres/blob/8b965c549dc8753be8a38c4a1b9fabdb535a4338/src/backend/replication/logical/proto.c#L670
+	return p.processRelationMessage(ctx, currentClientXlogPos, currRel)
 }
 
 func (p *PostgresCDCSource) processInsertMessage(
@@ -480,17 +609,17 @@ func (p *PostgresCDCSource) processInsertMessage(
 	}
 
 	// create empty map of string to interface{}
-	items, _, err := p.convertTupleToMap(msg.Tuple, rel, p.TableNameMapping[tableName].Exclude)
+	items, _, err := p.convertTupleToMap(msg.Tuple, rel, p.TableNameMapping[tableName])
 	if err != nil {
 		return nil, fmt.Errorf("error converting tuple to map: %w", err)
 	}
 
-	return &model.InsertRecord{
+	return filterAndMaskRecord(p.TableNameMapping[tableName], &model.InsertRecord{
 		CheckpointID:         int64(lsn),
 		Items:                items,
 		DestinationTableName: p.TableNameMapping[tableName].Name,
 		SourceTableName:      tableName,
-	}, nil
+	})
 }
 
 // processUpdateMessage processes an update message and returns an UpdateRecord
@@ -515,25 +644,25 @@ func (p *PostgresCDCSource) processUpdateMessage(
 	}
 
 	// create empty map of string to interface{}
-	oldItems, _, err := p.convertTupleToMap(msg.OldTuple, rel, p.TableNameMapping[tableName].Exclude)
+	oldItems, _, err := p.convertTupleToMap(msg.OldTuple, rel, p.TableNameMapping[tableName])
 	if err != nil {
 		return nil, fmt.Errorf("error converting old tuple to map: %w", err)
 	}
 
 	newItems, unchangedToastColumns, err := p.convertTupleToMap(msg.NewTuple,
-		rel, p.TableNameMapping[tableName].Exclude)
+		rel, p.TableNameMapping[tableName])
 	if err != nil {
 		return nil, fmt.Errorf("error converting new tuple to map: %w", err)
 	}
 
-	return &model.UpdateRecord{
+	return filterAndMaskRecord(p.TableNameMapping[tableName], &model.UpdateRecord{
 		CheckpointID:          int64(lsn),
 		OldItems:              oldItems,
 		NewItems:              newItems,
 		DestinationTableName:  p.TableNameMapping[tableName].Name,
 		SourceTableName:       tableName,
 		UnchangedToastColumns: unchangedToastColumns,
-	}, nil
+	})
 }
 
 // processDeleteMessage processes a delete message and returns a DeleteRecord
@@ -558,17 +687,17 @@ func (p *PostgresCDCSource) processDeleteMessage(
 	}
 
 	// create empty map of string to interface{}
-	items, _, err := p.convertTupleToMap(msg.OldTuple, rel, p.TableNameMapping[tableName].Exclude)
+	items, _, err := p.convertTupleToMap(msg.OldTuple, rel, p.TableNameMapping[tableName])
 	if err != nil {
 		return nil, fmt.Errorf("error converting tuple to map: %w", err)
 	}
 
-	return &model.DeleteRecord{
+	return filterAndMaskRecord(p.TableNameMapping[tableName], &model.DeleteRecord{
 		CheckpointID:         int64(lsn),
 		Items:                items,
 		DestinationTableName: p.TableNameMapping[tableName].Name,
 		SourceTableName:      tableName,
-	}, nil
+	})
 }
 
 /*
@@ -581,7 +710,7 @@ It takes a tuple and a relation message as input and returns
 func (p *PostgresCDCSource) convertTupleToMap(
 	tuple *pglogrepl.TupleData,
 	rel *protos.RelationMessage,
-	exclude map[string]struct{},
+	filter model.NameAndExclude,
 ) (*model.RecordItems, map[string]struct{}, error) {
 	// if the tuple is nil, return an empty map
 	if tuple == nil {
@@ -594,23 +723,23 @@ func (p *PostgresCDCSource) convertTupleToMap(
 
 	for idx, col := range tuple.Columns {
 		colName := rel.Columns[idx].Name
-		if _, ok := exclude[colName]; ok {
+		if filter.Excluded(colName) {
 			continue
 		}
 		switch col.DataType {
 		case 'n': // null
-			val := qvalue.QValue{Kind: qvalue.QValueKindInvalid, Value: nil}
+			val := qvalue.QValue{Kind: qvalue.QValueKindInvalid}
 			items.AddColumn(colName, val)
 		case 't': // text
 			/* bytea also appears here as a hex */
 			data, err := p.decodeColumnData(col.Data, rel.Columns[idx].DataType, pgtype.TextFormatCode)
-			if err != nil {
+			if err != nil && !p.logInvalidGeometry(err, rel, colName) {
 				return nil, nil, fmt.Errorf("error decoding text column data: %w", err)
 			}
 			items.AddColumn(colName, data)
 		case 'b': // binary
 			data, err := p.decodeColumnData(col.Data, rel.Columns[idx].DataType, pgtype.BinaryFormatCode)
-			if err != nil {
+			if err != nil && !p.logInvalidGeometry(err, rel, colName) {
 				return nil, nil, fmt.Errorf("error decoding binary column data: %w", err)
 			}
 			items.AddColumn(colName, data)
@@ -623,10 +752,38 @@ func (p *PostgresCDCSource) convertTupleToMap(
 	return items, unchangedToastColumns, nil
 }
 
+// logInvalidGeometry logs an invalid geometry with the table and column it's from, as it's replicated as null,
+// returning false for other errors.
+func (p *PostgresCDCSource) logInvalidGeometry(err error, rel *protos.RelationMessage, colName string) bool {
+	var geoErr *geo.InvalidGeometryError
+	if !errors.As(err, &geoErr) {
+		return false
+	}
+	p.logger.Warn("replicating invalid geometry as null",
+		slog.String("table", p.SrcTableIDNameMapping[rel.RelationId]),
+		slog.String("column", colName),
+		slog.Any("error", err))
+	return true
+}
+
 func (p *PostgresCDCSource) decodeColumnData(data []byte, dataType uint32, formatCode int16) (qvalue.QValue, error) {
+	// relation messages carry the domain, unlike query results which carry its base type
+	dataType = p.domainBaseOID(dataType)
+	if customType, ok := p.customTypesMapping[dataType]; ok {
+		switch customType.Type {
+		case 'c':
+			return p.decodeComposite(data, dataType, formatCode)
+		case 'e':
+			return qvalue.New(qvalue.QValueKindString, string(data)), nil
+		}
+	}
+
 	var parsedData any
 	var err error
 	if dt, ok := p.typeMap.TypeForOID(dataType); ok {
+		if _, ok := dt.Codec.(*pgtype.ArrayCodec); ok {
+			return p.decodeArrayValue(p.typeMap, dataType, formatCode, data)
+		}
 		if dt.Name == "uuid" || dt.Name == "cidr" || dt.Name == "inet" || dt.Name == "macaddr" {
 			// below is required to decode above types to string
 			parsedData, err = dt.Codec.DecodeDatabaseSQLValue(p.typeMap, dataType, pgtype.TextFormatCode, data)
@@ -658,40 +815,46 @@ func (p *PostgresCDCSource) decodeColumnData(data []byte, dataType uint32, forma
 		return retVal, nil
 	}
 
-	typeName, ok := p.customTypesMapping[dataType]
+	customType, ok := p.customTypesMapping[dataType]
 	if ok {
-		customQKind := customTypeToQKind(typeName)
+		customQKind := p.customTypeToQKind(dataType, customType)
 		if customQKind == qvalue.QValueKindGeography || customQKind == qvalue.QValueKindGeometry {
 			wkt, err := geo.GeoValidate(string(data))
 			if err != nil {
-				return qvalue.QValue{
-					Kind:  customQKind,
-					Value: nil,
-				}, nil
+				return qvalue.QValue{Kind: customQKind}, err
 			} else {
-				return qvalue.QValue{
-					Kind:  customQKind,
-					Value: wkt,
-				}, nil
+				return qvalue.New(customQKind, wkt), nil
 			}
 		} else {
-			return qvalue.QValue{
-				Kind:  customQKind,
-				Value: string(data),
-			}, nil
+			return qvalue.New(customQKind, string(data)), nil
 		}
 	}
 
-	return qvalue.QValue{Kind: qvalue.QValueKindString, Value: string(data)}, nil
+	return qvalue.New(qvalue.QValueKindString, string(data)), nil
 }
 
-func convertRelationMessageToProto(msg *pglogrepl.RelationMessage) *protos.RelationMessage {
+// decodeComposite decodes a composite value to a JSON object of its fields,
+// composites pgx couldn't load are passed on as their text representation.
+func (p *PostgresCDCSource) decodeComposite(data []byte, dataType uint32, formatCode int16) (qvalue.QValue, error) {
+	dt, ok := p.typeMap.TypeForOID(dataType)
+	if !ok {
+		return qvalue.New(qvalue.QValueKindString, string(data)), nil
+	}
+	parsedData, err := dt.Codec.DecodeValue(p.typeMap, dataType, formatCode, data)
+	if err != nil {
+		return qvalue.QValue{}, fmt.Errorf("error decoding composite %s: %w", dt.Name, err)
+	}
+	return parseJSON(parsedData)
+}
+
+func convertRelationMessageToProto(msg *pglogrepl.RelationMessage, attnums map[string]int32) *protos.RelationMessage {
 	protoColArray := make([]*protos.RelationMessageColumn, 0)
 	for _, column := range msg.Columns {
 		protoColArray = append(protoColArray, &protos.RelationMessageColumn{
 			Name:     column.Name,
 			Flags:    uint32(column.Flags),
 			DataType: column.DataType,
+			Attnum:   attnums[column.Name],
 		})
 	}
 	return &protos.RelationMessage{
@@ -725,20 +888,6 @@ func (p *PostgresCDCSource) processRelationMessage(
 ) (model.Record, error) {
 	// retrieve initial RelationMessage for table changed.
 	prevRel := p.relationMessageMapping[currRel.RelationId]
-	// creating maps for lookup later
-	prevRelMap := make(map[string]*protos.PostgresTableIdentifier)
-	currRelMap := make(map[string]*protos.PostgresTableIdentifier)
-	for _, column := range prevRel.Columns {
-		prevRelMap[column.Name] = &protos.PostgresTableIdentifier{
-			RelId: column.DataType,
-		}
-	}
-	for _, column := range currRel.Columns {
-		currRelMap[column.Name] = &protos.PostgresTableIdentifier{
-			RelId: column.DataType,
-		}
-	}
-
 	schemaDelta := &protos.TableSchemaDelta{
 		// set it to the source table for now, so we can update the schema on the source side
 		// then at the Workflow level we set it t
@@ -746,41 +895,134 @@ func (p *PostgresCDCSource) processRelationMessage(
 		DstTableName: p.TableNameMapping[p.SrcTableIDNameMapping[currRel.RelationId]].Name,
 		AddedColumns: make([]*protos.DeltaAddedColumn, 0),
 	}
+	diffRelationMessages(schemaDelta, prevRel, currRel, func(dataType uint32) qvalue.QValueKind {
+		qKind := p.postgresOIDToQValueKind(dataType)
+		if qKind == qvalue.QValueKindInvalid {
+			customType, ok := p.customTypesMapping[dataType]
+			if ok {
+				qKind = p.customTypeToQKind(dataType, customType)
+			}
+		}
+		return qKind
+	})
+	for _, column := range schemaDelta.NarrowedColumns {
+		p.logger.Warn(fmt.Sprintf("Detected type change of column %s in table %s from %s to %s, "+
+			"which isn't a widening", column.ColumnName, schemaDelta.SrcTableName, column.OldType, column.NewType))
+	}
+
+	p.relationMessageMapping[currRel.RelationId] = currRel
+	rec := &model.RelationRecord{
+		TableSchemaDelta: schemaDelta,
+		CheckpointID:     int64(lsn),
+	}
+	return rec, p.auditSchemaDelta(ctx, p.flowJobName, rec)
+}
+
+// diffRelationMessages adds the changes between two relation messages of a table to schemaDelta.
+// Relation messages only carry names and types, so a column missing from currRel is dropped and a new one added,
+// unless both have the same attnum, which only a rename keeps. A column dropped and added back under the same name
+// has a new attnum, and is dropped and added too.
+func diffRelationMessages(
+	schemaDelta *protos.TableSchemaDelta,
+	prevRel *protos.RelationMessage,
+	currRel *protos.RelationMessage,
+	columnKind func(dataType uint32) qvalue.QValueKind,
+) {
+	prevByName := make(map[string]*protos.RelationMessageColumn, len(prevRel.Columns))
+	prevByAttnum := make(map[int32]*protos.RelationMessageColumn, len(prevRel.Columns))
+	for _, column := range prevRel.Columns {
+		prevByName[column.Name] = column
+		if column.Attnum > 0 {
+			prevByAttnum[column.Attnum] = column
+		}
+	}
+	currNames := make(map[string]struct{}, len(currRel.Columns))
 	for _, column := range currRel.Columns {
-		// not present in previous relation message, but in current one, so added.
-		if prevRelMap[column.Name] == nil {
-			qKind := p.postgresOIDToQValueKind(column.DataType)
-			if qKind == qvalue.QValueKindInvalid {
-				typeName, ok := p.customTypesMapping[column.DataType]
-				if ok {
-					qKind = customTypeToQKind(typeName)
-				}
+		currNames[column.Name] = struct{}{}
+	}
+
+	alterColumn := func(name string, oldDataType uint32, newDataType uint32) {
+		oldKind, newKind := columnKind(oldDataType), columnKind(newDataType)
+		if oldKind == newKind {
+			return
+		}
+		alteredColumn := &protos.DeltaAlteredColumn{
+			ColumnName: name,
+			OldType:    string(oldKind),
+			NewType:    string(newKind),
+		}
+		if !oldKind.CanWidenTo(newKind) {
+			schemaDelta.NarrowedColumns = append(schemaDelta.NarrowedColumns, alteredColumn)
+		} else {
+			schemaDelta.AlteredColumns = append(schemaDelta.AlteredColumns, alteredColumn)
+		}
+	}
+	addColumn := func(column *protos.RelationMessageColumn) {
+		schemaDelta.AddedColumns = append(schemaDelta.AddedColumns, &protos.DeltaAddedColumn{
+			ColumnName: column.Name,
+			ColumnType: string(columnKind(column.DataType)),
+		})
+	}
+
+	renamedFrom := make(map[string]struct{})
+	for _, column := range currRel.Columns {
+		prevColumn := prevByName[column.Name]
+		switch {
+		case prevColumn != nil && prevColumn.Attnum > 0 && column.Attnum > 0 && prevColumn.Attnum != column.Attnum:
+			// dropped and added back under the same name, its old values are gone
+			schemaDelta.DroppedColumns = append(schemaDelta.DroppedColumns, column.Name)
+			addColumn(column)
+		case prevColumn != nil:
+			alterColumn(column.Name, prevColumn.DataType, column.DataType)
+		default:
+			renamed := prevByAttnum[column.Attnum]
+			if column.Attnum == 0 || renamed == nil {
+				addColumn(column)
+				continue
+			}
+			if _, ok := currNames[renamed.Name]; ok {
+				addColumn(column)
+				continue
 			}
-			schemaDelta.AddedColumns = append(schemaDelta.AddedColumns, &protos.DeltaAddedColumn{
-				ColumnName: column.Name,
-				ColumnType: string(qKind),
+			renamedFrom[renamed.Name] = struct{}{}
+			schemaDelta.RenamedColumns = append(schemaDelta.RenamedColumns, &protos.DeltaRenamedColumn{
+				OldName:    renamed.Name,
+				NewName:    column.Name,
+				ColumnType: string(columnKind(column.DataType)),
 			})
-			// present in previous and current relation messages, but data types have changed.
-			// so we add it to AddedColumns and DroppedColumns, knowing that we process DroppedColumns first.
-		} else if prevRelMap[column.Name].RelId != currRelMap[column.Name].RelId {
-			p.logger.Warn(fmt.Sprintf("Detected dropped column %s in table %s, but not propagating", column,
-				schemaDelta.SrcTableName))
+			// destinations rename columns before changing their types
+			alterColumn(column.Name, renamed.DataType, column.DataType)
 		}
 	}
 	for _, column := range prevRel.Columns {
 		// present in previous relation message, but not in current one, so dropped.
-		if currRelMap[column.Name] == nil {
-			p.logger.Warn(fmt.Sprintf("Detected dropped column %s in table %s, but not propagating", column,
-				schemaDelta.SrcTableName))
+		if _, ok := renamedFrom[column.Name]; ok {
+			continue
+		}
+		if _, ok := currNames[column.Name]; !ok {
+			schemaDelta.DroppedColumns = append(schemaDelta.DroppedColumns, column.Name)
 		}
 	}
+}
 
-	p.relationMessageMapping[currRel.RelationId] = currRel
-	rec := &model.RelationRecord{
-		TableSchemaDelta: schemaDelta,
-		CheckpointID:     int64(lsn),
+// relationAttnums returns the attnums of the columns of a relation, by name, as the catalog has them now.
+// Relation messages are decoded after the fact, columns changed since are left out of what they're compared to.
+func (p *PostgresCDCSource) relationAttnums(ctx context.Context, relID uint32) (map[string]int32, error) {
+	rows, err := p.conn.Query(ctx,
+		"SELECT attname, attnum FROM pg_attribute WHERE attrelid=$1 AND attnum>0 AND NOT attisdropped", relID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attnums of relation %d: %w", relID, err)
 	}
-	return rec, p.auditSchemaDelta(ctx, p.flowJobName, rec)
+	attnums := make(map[string]int32)
+	var name string
+	var attnum int16
+	if _, err := pgx.ForEachRow(rows, []any{&name, &attnum}, func() error {
+		attnums[name] = int32(attnum)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error reading attnums of relation %d: %w", relID, err)
+	}
+	return attnums, nil
 }
 
 func (p *PostgresCDCSource) recToTablePKey(req *model.PullRecordsRequest,
@@ -794,7 +1036,7 @@ func (p *PostgresCDCSource) recToTablePKey(req *model.PullRecordsRequest,
 		if err != nil {
 			return nil, fmt.Errorf("error getting pkey column value: %w", err)
 		}
-		pkeyColsMerged = append(pkeyColsMerged, []byte(fmt.Sprint(pkeyColVal.Value)))
+		pkeyColsMerged = append(pkeyColsMerged, []byte(fmt.Sprint(pkeyColVal.Value())))
 	}
 
 	return &model.TableWithPkey{
@@ -803,6 +1045,15 @@ func (p *PostgresCDCSource) recToTablePKey(req *model.PullRecordsRequest,
 	}, nil
 }
 
+func (p *PostgresCDCSource) refreshChildToParentRelIDMap(ctx context.Context) error {
+	childToParentRelIDMap, err := GetChildToParentRelIDMap(ctx, p.conn)
+	if err != nil {
+		return fmt.Errorf("error refreshing child to parent relid map: %w", err)
+	}
+	p.childToParentRelIDMapping = childToParentRelIDMap
+	return nil
+}
+
 func (p *PostgresCDCSource) getParentRelIDIfPartitioned(relID uint32) uint32 {
 	parentRelID, ok := p.childToParentRelIDMapping[relID]
 	if ok {
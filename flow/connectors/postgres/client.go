@@ -23,7 +23,9 @@ type PGVersion int
 const (
 	POSTGRES_12 PGVersion = 120000
 	POSTGRES_13 PGVersion = 130000
+	POSTGRES_14 PGVersion = 140000
 	POSTGRES_15 PGVersion = 150000
+	POSTGRES_16 PGVersion = 160000
 )
 
 const (
@@ -83,6 +85,10 @@ const (
 	dropTableIfExistsSQL     = "DROP TABLE IF EXISTS %s.%s"
 	deleteJobMetadataSQL     = "DELETE FROM %s.%s WHERE mirror_job_name=$1"
 	getNumConnectionsForUser = "SELECT COUNT(*) FROM pg_stat_activity WHERE usename=$1 AND client_addr IS NOT NULL"
+	// only transactions which have written hold back the slot, other users' sessions need pg_read_all_stats to be visible
+	getOldestWritingTransactionSQL = `SELECT pid, datname, usename, state, left(query, 1024), xact_start,
+		extract(epoch FROM now() - xact_start)::bigint FROM pg_stat_activity
+		WHERE backend_xid IS NOT NULL AND xact_start IS NOT NULL AND pid <> pg_backend_pid() ORDER BY xact_start LIMIT 1`
 )
 
 type ReplicaIdentityType rune
@@ -223,11 +229,20 @@ func (c *PostgresConnector) checkSlotAndPublication(ctx context.Context, slot st
 	slotExists := false
 	publicationExists := false
 
-	// Check if the replication slot exists
+	// Check if the replication slot exists, two_phase is only reported from Postgres 14
+	hasTwoPhase, _, err := c.MajorVersionCheck(ctx, POSTGRES_14)
+	if err != nil {
+		return SlotCheckResult{}, fmt.Errorf("error checking Postgres version: %w", err)
+	}
+	twoPhaseColumn := "false"
+	if hasTwoPhase {
+		twoPhaseColumn = "two_phase"
+	}
 	var slotName pgtype.Text
-	err := c.conn.QueryRow(ctx,
-		"SELECT slot_name FROM pg_replication_slots WHERE slot_name = $1",
-		slot).Scan(&slotName)
+	var slotTwoPhase bool
+	err = c.conn.QueryRow(ctx,
+		"SELECT slot_name, "+twoPhaseColumn+" FROM pg_replication_slots WHERE slot_name = $1",
+		slot).Scan(&slotName, &slotTwoPhase)
 	if err != nil {
 		// check if the error is a "no rows" error
 		if err != pgx.ErrNoRows {
@@ -254,10 +269,11 @@ func (c *PostgresConnector) checkSlotAndPublication(ctx context.Context, slot st
 	return SlotCheckResult{
 		SlotExists:        slotExists,
 		PublicationExists: publicationExists,
+		SlotTwoPhase:      slotTwoPhase,
 	}, nil
 }
 
-func getSlotInfo(ctx context.Context, conn *pgx.Conn, slotName string, database string) ([]*protos.SlotInfo, error) {
+func getSlotInfo(ctx context.Context, conn utils.Querier, slotName string, database string) ([]*protos.SlotInfo, error) {
 	var whereClause string
 	if slotName != "" {
 		whereClause = "WHERE slot_name=" + QuoteLiteral(slotName)
@@ -275,9 +291,11 @@ func getSlotInfo(ctx context.Context, conn *pgx.Conn, slotName string, database
 	}
 	rows, err := conn.Query(ctx, fmt.Sprintf(`SELECT slot_name, redo_lsn::Text,restart_lsn::text,%s,
 		confirmed_flush_lsn::text,active,
-		round((CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END
-		- restart_lsn) / 1024 / 1024) AS MB_Behind
-		FROM pg_control_checkpoint(),pg_replication_slots %s`, walStatusSelector, whereClause))
+		round((current_lsn - restart_lsn) / 1024 / 1024) AS MB_Behind,
+		current_lsn::text,(current_lsn - restart_lsn)::bigint,(current_lsn - confirmed_flush_lsn)::bigint
+		FROM pg_control_checkpoint(),pg_replication_slots,
+		(SELECT CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END AS current_lsn) wal
+		%s`, walStatusSelector, whereClause))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read information for slots: %w", err)
 	}
@@ -291,19 +309,26 @@ func getSlotInfo(ctx context.Context, conn *pgx.Conn, slotName string, database
 		var active pgtype.Bool
 		var lagInMB pgtype.Float4
 		var walStatus pgtype.Text
-		err := rows.Scan(&slotName, &redoLSN, &restartLSN, &walStatus, &confirmedFlushLSN, &active, &lagInMB)
+		var currentLSN pgtype.Text
+		var restartLagBytes pgtype.Int8
+		var confirmedFlushLagBytes pgtype.Int8
+		err := rows.Scan(&slotName, &redoLSN, &restartLSN, &walStatus, &confirmedFlushLSN, &active, &lagInMB,
+			&currentLSN, &restartLagBytes, &confirmedFlushLagBytes)
 		if err != nil {
 			return nil, err
 		}
 
 		slotInfoRows = append(slotInfoRows, &protos.SlotInfo{
-			RedoLSN:           redoLSN.String,
-			RestartLSN:        restartLSN.String,
-			WalStatus:         walStatus.String,
-			ConfirmedFlushLSN: confirmedFlushLSN.String,
-			SlotName:          slotName.String,
-			Active:            active.Bool,
-			LagInMb:           lagInMB.Float32,
+			RedoLSN:                redoLSN.String,
+			RestartLSN:             restartLSN.String,
+			WalStatus:              walStatus.String,
+			ConfirmedFlushLSN:      confirmedFlushLSN.String,
+			SlotName:               slotName.String,
+			Active:                 active.Bool,
+			LagInMb:                lagInMB.Float32,
+			CurrentLSN:             currentLSN.String,
+			RestartLagBytes:        restartLagBytes.Int64,
+			ConfirmedFlushLagBytes: confirmedFlushLagBytes.Int64,
 		})
 	}
 	return slotInfoRows, nil
@@ -316,6 +341,36 @@ func (c *PostgresConnector) GetSlotInfo(ctx context.Context, slotName string) ([
 	return getSlotInfo(ctx, c.conn, slotName, c.config.Database)
 }
 
+// ReleaseReplicationSlot terminates the backend a replication slot is active for, implementing SlotReleaseConnector.
+func (c *PostgresConnector) ReleaseReplicationSlot(ctx context.Context, slotName string) error {
+	_, err := c.conn.Exec(ctx,
+		"SELECT pg_terminate_backend(active_pid) FROM pg_replication_slots WHERE slot_name = $1 AND active", slotName)
+	if err != nil {
+		return fmt.Errorf("error releasing replication slot %s: %w", slotName, err)
+	}
+	return nil
+}
+
+// createTwoPhaseReplicationSlot creates a slot which decodes prepared transactions at PREPARE TRANSACTION,
+// the TWO_PHASE option needs the new CREATE_REPLICATION_SLOT syntax from Postgres 15.
+func (c *PostgresConnector) createTwoPhaseReplicationSlot(
+	ctx context.Context,
+	conn *pgx.Conn,
+	slot string,
+) (pglogrepl.CreateReplicationSlotResult, error) {
+	supportsTwoPhase, version, err := c.MajorVersionCheck(ctx, POSTGRES_15)
+	if err != nil {
+		return pglogrepl.CreateReplicationSlotResult{}, fmt.Errorf("error checking Postgres version: %w", err)
+	}
+	if !supportsTwoPhase {
+		return pglogrepl.CreateReplicationSlotResult{},
+			fmt.Errorf("two-phase commit decoding requires Postgres 15 or later, source is version %d", version)
+	}
+
+	return pglogrepl.ParseCreateReplicationSlot(conn.PgConn().Exec(ctx,
+		fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL pgoutput (TWO_PHASE, SNAPSHOT 'export')", slot)))
+}
+
 // createSlotAndPublication creates the replication slot and publication.
 func (c *PostgresConnector) createSlotAndPublication(
 	ctx context.Context,
@@ -325,20 +380,22 @@ func (c *PostgresConnector) createSlotAndPublication(
 	publication string,
 	tableNameMapping map[string]model.NameAndExclude,
 	doInitialCopy bool,
+	twoPhase bool,
+	columnLists bool,
 ) error {
-	/*
-		iterating through source tables and creating a publication.
-		expecting tablenames to be schema qualified
-	*/
-	srcTableNames := make([]string, 0, len(tableNameMapping))
-	for srcTableName := range tableNameMapping {
-		parsedSrcTableName, err := utils.ParseSchemaTable(srcTableName)
+	standby, err := c.checkStandby(ctx)
+	if err != nil {
+		return err
+	}
+	// a standby can't run DDL, it reaches publications created on its primary through replication
+	var primary *PostgresConnector
+	if standby && (!s.PublicationExists || c.config.Primary != nil) {
+		primary, err = c.primaryConn(ctx)
 		if err != nil {
-			return fmt.Errorf("source table identifier %s is invalid", srcTableName)
+			return err
 		}
-		srcTableNames = append(srcTableNames, parsedSrcTableName.String())
+		defer primary.Close()
 	}
-	tableNameString := strings.Join(srcTableNames, ", ")
 
 	if !s.PublicationExists {
 		// check and enable publish_via_partition_root
@@ -350,9 +407,56 @@ func (c *PostgresConnector) createSlotAndPublication(
 		if supportsPubViaRoot {
 			pubViaRootString = "WITH(publish_via_partition_root=true)"
 		}
+		useColumnLists, err := c.supportsPublicationColumnLists(ctx, columnLists)
+		if err != nil {
+			return err
+		}
+
+		/*
+			iterating through source tables and creating a publication.
+			expecting tablenames to be schema qualified
+		*/
+		srcTableNames := make([]string, 0, len(tableNameMapping))
+		for srcTableName, nameAndExclude := range tableNameMapping {
+			parsedSrcTableName, err := utils.ParseSchemaTable(srcTableName)
+			if err != nil {
+				return fmt.Errorf("source table identifier %s is invalid", srcTableName)
+			}
+			if !supportsPubViaRoot {
+				// partitioned tables can't be published before Postgres 13, publish their leaves instead.
+				// Changes to them are mapped back to the partitioned table by relid.
+				leaves, err := getLeafPartitions(ctx, c.conn, parsedSrcTableName)
+				if err != nil {
+					return err
+				}
+				if len(leaves) > 0 {
+					c.logger.Warn(fmt.Sprintf("publishing the %d partitions of %s, partitions created later are not replicated",
+						len(leaves), parsedSrcTableName))
+					for _, leaf := range leaves {
+						srcTableNames = append(srcTableNames, leaf.String())
+					}
+					continue
+				}
+			}
+			if !useColumnLists {
+				srcTableNames = append(srcTableNames, parsedSrcTableName.String())
+				continue
+			}
+			publicationTable, err := c.publicationTable(ctx, parsedSrcTableName, nameAndExclude)
+			if err != nil {
+				return fmt.Errorf("error building column list for table %s: %w", srcTableName, err)
+			}
+			srcTableNames = append(srcTableNames, publicationTable)
+		}
+		tableNameString := strings.Join(srcTableNames, ", ")
+
 		// Create the publication to help filter changes only for the given tables
 		stmt := fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE %s %s", publication, tableNameString, pubViaRootString)
-		_, err = c.conn.Exec(ctx, stmt)
+		ddlConn := c.conn
+		if primary != nil {
+			ddlConn = primary.conn
+		}
+		_, err = ddlConn.Exec(ctx, stmt)
 		if err != nil {
 			c.logger.Warn(fmt.Sprintf("Error creating publication '%s': %v", publication, err))
 			return fmt.Errorf("error creating publication '%s' : %w", publication, err)
@@ -379,11 +483,24 @@ func (c *PostgresConnector) createSlotAndPublication(
 			return fmt.Errorf("[slot] error setting lock_timeout: %w", err)
 		}
 
-		opts := pglogrepl.CreateReplicationSlotOptions{
-			Temporary: false,
-			Mode:      pglogrepl.LogicalReplication,
+		snapshotCtx, stopSnapshots := context.WithCancel(ctx)
+		if primary != nil {
+			go c.logStandbySnapshots(snapshotCtx, primary)
+		} else if standby {
+			c.logger.Warn("creating slot on standby without a primary configured, " +
+				"this waits for the primary to log running transactions")
+		}
+		var res pglogrepl.CreateReplicationSlotResult
+		if twoPhase {
+			res, err = c.createTwoPhaseReplicationSlot(ctx, conn, slot)
+		} else {
+			opts := pglogrepl.CreateReplicationSlotOptions{
+				Temporary: false,
+				Mode:      pglogrepl.LogicalReplication,
+			}
+			res, err = pglogrepl.CreateReplicationSlot(ctx, conn.PgConn(), slot, "pgoutput", opts)
 		}
-		res, err := pglogrepl.CreateReplicationSlot(ctx, conn.PgConn(), slot, "pgoutput", opts)
+		stopSnapshots()
 		if err != nil {
 			return fmt.Errorf("[slot] error creating replication slot: %w", err)
 		}
@@ -457,8 +574,8 @@ func generateCreateTableSQLForNormalizedTable(
 			QuoteIdentifier(syncedAtColName)+` TIMESTAMP DEFAULT CURRENT_TIMESTAMP`)
 	}
 
-	// add composite primary key to the table
-	if len(sourceTableSchema.PrimaryKeyColumns) > 0 {
+	// add composite primary key to the table, a synthetic key spans nullable columns so it isn't a constraint
+	if len(sourceTableSchema.PrimaryKeyColumns) > 0 && !sourceTableSchema.SyntheticPrimaryKey {
 		primaryKeyColsQuoted := make([]string, 0, len(sourceTableSchema.PrimaryKeyColumns))
 		for _, primaryKeyCol := range sourceTableSchema.PrimaryKeyColumns {
 			primaryKeyColsQuoted = append(primaryKeyColsQuoted, QuoteIdentifier(primaryKeyCol))
@@ -514,7 +631,7 @@ func (c *PostgresConnector) jobMetadataExists(ctx context.Context, jobName strin
 	return result.Bool, nil
 }
 
-func majorVersionCheck(ctx context.Context, conn *pgx.Conn, majorVersion PGVersion) (bool, int64, error) {
+func majorVersionCheck(ctx context.Context, conn utils.Querier, majorVersion PGVersion) (bool, int64, error) {
 	var version pgtype.Int8
 	err := conn.QueryRow(ctx, "SELECT current_setting('server_version_num')::INTEGER").Scan(&version)
 	if err != nil {
@@ -0,0 +1,230 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// publicationColumnList returns the columns to publish for a table, leaving out excluded columns.
+// Replica identity columns are always published, Postgres refuses updates and deletes otherwise.
+// Returns nil when every column would be published, so no column list is needed.
+func publicationColumnList(columns []string, exclude map[string]struct{}, identityColumns []string) []string {
+	identity := make(map[string]struct{}, len(identityColumns))
+	for _, col := range identityColumns {
+		identity[col] = struct{}{}
+	}
+
+	published := make([]string, 0, len(columns))
+	for _, col := range columns {
+		_, excluded := exclude[col]
+		_, isIdentity := identity[col]
+		if !excluded || isIdentity {
+			published = append(published, col)
+		}
+	}
+	if len(published) == len(columns) {
+		return nil
+	}
+	return published
+}
+
+// columnListChanged returns whether the columns a table is published with differ from the columns it should be.
+func columnListChanged(current []string, desired []string) bool {
+	if len(current) != len(desired) {
+		return true
+	}
+	published := make(map[string]struct{}, len(current))
+	for _, col := range current {
+		published[col] = struct{}{}
+	}
+	for _, col := range desired {
+		if _, ok := published[col]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// publicationColumns returns the columns of the table to publish and all of its publishable columns,
+// the former being nil when the table doesn't need a column list.
+func (c *PostgresConnector) publicationColumns(
+	ctx context.Context,
+	schemaTable *utils.SchemaTable,
+	filter model.NameAndExclude,
+) ([]string, []string, error) {
+	relID, err := c.getRelIDForTable(ctx, schemaTable)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get relation id for table %s: %w", schemaTable, err)
+	}
+	// generated columns are never published and cannot be part of a column list
+	rows, err := c.conn.Query(ctx,
+		`SELECT attname FROM pg_attribute
+		 WHERE attrelid = $1 AND attnum > 0 AND NOT attisdropped AND attgenerated = ''
+		 ORDER BY attnum`, relID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting columns for table %s: %w", schemaTable, err)
+	}
+	columns, err := pgx.CollectRows[string](rows, pgx.RowTo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error scanning columns for table %s: %w", schemaTable, err)
+	}
+	if !filter.Filtered() {
+		return nil, columns, nil
+	}
+
+	replicaIdentity, err := c.getReplicaIdentityType(ctx, schemaTable)
+	if err != nil {
+		return nil, nil, err
+	}
+	// with REPLICA IDENTITY FULL every column is part of the identity
+	if replicaIdentity == ReplicaIdentityFull {
+		c.logger.Info("not using a column list for table with replica identity full",
+			slog.String("table", schemaTable.String()))
+		return nil, columns, nil
+	}
+	identityColumns, err := c.getUniqueColumns(ctx, replicaIdentity, schemaTable)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exclude := make(map[string]struct{})
+	for _, col := range columns {
+		if filter.Excluded(col) {
+			exclude[col] = struct{}{}
+		}
+	}
+	return publicationColumnList(columns, exclude, identityColumns), columns, nil
+}
+
+// publicationTableWithColumns returns the table as it should be listed in CREATE/ALTER PUBLICATION,
+// with its column list when it has one.
+func publicationTableWithColumns(schemaTable *utils.SchemaTable, published []string) string {
+	if published == nil {
+		return schemaTable.String()
+	}
+	quoted := make([]string, 0, len(published))
+	for _, col := range published {
+		quoted = append(quoted, utils.QuoteIdentifier(col))
+	}
+	return fmt.Sprintf("%s (%s)", schemaTable.String(), strings.Join(quoted, ", "))
+}
+
+// publicationTable returns the table as it should be listed in CREATE/ALTER PUBLICATION,
+// with a column list leaving out excluded columns when possible.
+func (c *PostgresConnector) publicationTable(
+	ctx context.Context,
+	schemaTable *utils.SchemaTable,
+	filter model.NameAndExclude,
+) (string, error) {
+	if !filter.Filtered() {
+		return schemaTable.String(), nil
+	}
+	published, _, err := c.publicationColumns(ctx, schemaTable, filter)
+	if err != nil {
+		return "", err
+	}
+	return publicationTableWithColumns(schemaTable, published), nil
+}
+
+// refreshPublicationColumnLists brings the column lists of the publication's tables up to date with their columns,
+// so that columns added to the source since the lists were written are published, and show up as schema changes.
+// Tables are dropped and added back in one transaction, which needs no lock on them.
+func (c *PostgresConnector) refreshPublicationColumnLists(
+	ctx context.Context,
+	publicationName string,
+	tableNameMapping map[string]model.NameAndExclude,
+) error {
+	rows, err := c.conn.Query(ctx,
+		"SELECT schemaname, tablename, attnames FROM pg_publication_tables WHERE pubname = $1", publicationName)
+	if err != nil {
+		return fmt.Errorf("error getting tables of publication %s: %w", publicationName, err)
+	}
+	publishedColumns := make(map[string][]string)
+	var schemaName, tableName string
+	var attnames []string
+	if _, err := pgx.ForEachRow(rows, []any{&schemaName, &tableName, &attnames}, func() error {
+		publishedColumns[(&utils.SchemaTable{Schema: schemaName, Table: tableName}).String()] = attnames
+		return nil
+	}); err != nil {
+		return fmt.Errorf("error scanning tables of publication %s: %w", publicationName, err)
+	}
+
+	var alterStmts []string
+	for srcTableName, filter := range tableNameMapping {
+		if !filter.Filtered() {
+			continue
+		}
+		schemaTable, err := utils.ParseSchemaTable(srcTableName)
+		if err != nil {
+			return fmt.Errorf("source table identifier %s is invalid", srcTableName)
+		}
+		current, ok := publishedColumns[schemaTable.String()]
+		if !ok {
+			continue
+		}
+		published, columns, err := c.publicationColumns(ctx, schemaTable, filter)
+		if err != nil {
+			return err
+		}
+		desired := published
+		if desired == nil {
+			// pg_publication_tables lists every column of tables without a column list
+			desired = columns
+		}
+		if !columnListChanged(current, desired) {
+			continue
+		}
+		c.logger.Info("updating column list of published table",
+			slog.String("table", schemaTable.String()), slog.Any("columns", desired))
+		alterStmts = append(alterStmts,
+			fmt.Sprintf("ALTER PUBLICATION %s DROP TABLE %s", utils.QuoteIdentifier(publicationName), schemaTable),
+			fmt.Sprintf("ALTER PUBLICATION %s ADD TABLE %s", utils.QuoteIdentifier(publicationName),
+				publicationTableWithColumns(schemaTable, published)))
+	}
+	if len(alterStmts) == 0 {
+		return nil
+	}
+
+	alterTx, err := c.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for updating publication: %w", err)
+	}
+	defer func() {
+		deferErr := alterTx.Rollback(ctx)
+		if deferErr != pgx.ErrTxClosed && deferErr != nil {
+			c.logger.Error("error rolling back transaction for updating publication", slog.Any("error", deferErr))
+		}
+	}()
+	for _, stmt := range alterStmts {
+		if _, err := alterTx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("error updating column lists of publication %s: %w", publicationName, err)
+		}
+	}
+	if err := alterTx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing column lists of publication %s: %w", publicationName, err)
+	}
+	return nil
+}
+
+// supportsPublicationColumnLists logs and returns false when column lists were requested on a source older than Postgres 15.
+func (c *PostgresConnector) supportsPublicationColumnLists(ctx context.Context, requested bool) (bool, error) {
+	if !requested {
+		return false, nil
+	}
+	supported, version, err := c.MajorVersionCheck(ctx, POSTGRES_15)
+	if err != nil {
+		return false, fmt.Errorf("error checking Postgres version: %w", err)
+	}
+	if !supported {
+		c.logger.Warn("publication column lists need Postgres 15+, excluded columns are filtered by the worker",
+			slog.Int64("version", version))
+	}
+	return supported, nil
+}
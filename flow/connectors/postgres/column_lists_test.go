@@ -0,0 +1,28 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicationColumnList(t *testing.T) {
+	columns := []string{"id", "tenant_id", "payload", "blob"}
+
+	require.Nil(t, publicationColumnList(columns, map[string]struct{}{"missing": {}}, []string{"id"}))
+	require.Equal(t, []string{"id", "tenant_id", "payload"},
+		publicationColumnList(columns, map[string]struct{}{"blob": {}}, []string{"id"}))
+	// replica identity columns are published even when excluded
+	require.Equal(t, []string{"id", "tenant_id"},
+		publicationColumnList(columns, map[string]struct{}{"tenant_id": {}, "payload": {}, "blob": {}},
+			[]string{"id", "tenant_id"}))
+}
+
+func TestColumnListChanged(t *testing.T) {
+	require.False(t, columnListChanged([]string{"id", "payload"}, []string{"id", "payload"}))
+	require.False(t, columnListChanged([]string{"payload", "id"}, []string{"id", "payload"}))
+	// a column added to the source table
+	require.True(t, columnListChanged([]string{"id", "payload"}, []string{"id", "payload", "added"}))
+	// a published column dropped and another one added
+	require.True(t, columnListChanged([]string{"id", "payload"}, []string{"id", "added"}))
+}
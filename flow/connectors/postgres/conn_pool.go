@@ -0,0 +1,176 @@
+package connpostgres
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.temporal.io/sdk/log"
+)
+
+// ConnPool runs each statement on a connection acquired from the peer's pool for just that statement,
+// and each transaction on one acquired until it ends, so idle connectors don't hold on to connections
+// other activities on the worker are waiting for.
+// Session settings don't outlive a statement, transactions set them with SET LOCAL.
+type ConnPool struct {
+	pool   *pgxpool.Pool
+	logger log.Logger
+}
+
+// acquire gets a connection from the pool and cancels the statement running on it once ctx is done,
+// pgx only drops the connection then, which would leave the statement running on the server.
+// release must be called once done with the connection, with discard when it ran more than a statement,
+// so what the session was left with, like settings, prepared statements, temporary tables or advisory locks,
+// isn't inherited by the next one to acquire it.
+func (p *ConnPool) acquire(ctx context.Context) (*pgxpool.Conn, func(discard bool), error) {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stopCancel := context.AfterFunc(ctx, func() {
+		cancelCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := conn.Conn().PgConn().CancelRequest(cancelCtx); err != nil {
+			p.logger.Warn("failed to cancel running statement", slog.Any("error", err))
+		}
+	})
+	var once sync.Once
+	release := func(discard bool) {
+		once.Do(func() {
+			// the cancel request may still reach the server once another statement runs on the connection
+			if !stopCancel() {
+				closeConn(conn)
+				return
+			}
+			if discard {
+				discardCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				// DISCARD ALL deallocates prepared statements, DeallocateAll also forgets pgx's cached ones
+				if _, err := conn.Exec(discardCtx, "DISCARD ALL"); err != nil {
+					closeConn(conn)
+					return
+				}
+				if err := conn.Conn().DeallocateAll(discardCtx); err != nil {
+					closeConn(conn)
+					return
+				}
+			}
+			conn.Release()
+		})
+	}
+	return conn, release, nil
+}
+
+// closeConn takes a connection out of the pool and closes it.
+func closeConn(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = conn.Hijack().Close(ctx)
+}
+
+func (p *ConnPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	conn, release, err := p.acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release(false)
+	return conn.Exec(ctx, sql, args...)
+}
+
+// Query runs a query on a connection held until the rows are closed or read to the end.
+func (p *ConnPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	conn, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		release(false)
+		return nil, err
+	}
+	return &releasingRows{Rows: rows, release: release}, nil
+}
+
+// QueryRow runs a query on a connection held until the row is scanned.
+func (p *ConnPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	conn, release, err := p.acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return releasingRow{Row: conn.QueryRow(ctx, sql, args...), release: release}
+}
+
+func (p *ConnPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return p.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction on a connection held until it's committed or rolled back.
+func (p *ConnPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	conn, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.BeginTx(ctx, txOptions)
+	if err != nil {
+		release(true)
+		return nil, err
+	}
+	return &releasingTx{Tx: tx, release: release}, nil
+}
+
+type releasingRows struct {
+	pgx.Rows
+	release func(discard bool)
+}
+
+func (r *releasingRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// pgx closes the rows once they're read to the end
+	r.release(false)
+	return false
+}
+
+func (r *releasingRows) Close() {
+	r.Rows.Close()
+	r.release(false)
+}
+
+type releasingRow struct {
+	pgx.Row
+	release func(discard bool)
+}
+
+func (r releasingRow) Scan(dest ...any) error {
+	defer r.release(false)
+	return r.Row.Scan(dest...)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+type releasingTx struct {
+	pgx.Tx
+	release func(discard bool)
+}
+
+func (tx *releasingTx) Commit(ctx context.Context) error {
+	defer tx.release(true)
+	return tx.Tx.Commit(ctx)
+}
+
+func (tx *releasingTx) Rollback(ctx context.Context) error {
+	defer tx.release(true)
+	return tx.Tx.Rollback(ctx)
+}
@@ -0,0 +1,94 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// replicas behind logical slots are consumers like this mirror, only physical replicas count towards lag
+const sourceLoadSQL = `SELECT
+	(SELECT count(*) FROM pg_stat_activity
+		WHERE state='active' AND backend_type='client backend' AND pid<>pg_backend_pid()),
+	(SELECT COALESCE(sum(blks_read),0)::BIGINT FROM pg_stat_database),
+	(SELECT COALESCE(max(pg_wal_lsn_diff(r.sent_lsn,r.replay_lsn)),0)::BIGINT FROM pg_stat_replication r
+		WHERE NOT EXISTS (SELECT 1 FROM pg_replication_slots s WHERE s.active_pid=r.pid AND s.slot_type='logical'))`
+
+type sourceLoadSample struct {
+	at             time.Time
+	activeQueries  int64
+	blocksRead     int64
+	replicaLagByte int64
+}
+
+func (c *PostgresConnector) sampleSourceLoad(ctx context.Context) (*sourceLoadSample, error) {
+	sample := &sourceLoadSample{at: time.Now()}
+	err := c.conn.QueryRow(ctx, sourceLoadSQL).Scan(&sample.activeQueries, &sample.blocksRead, &sample.replicaLagByte)
+	if err != nil {
+		return nil, fmt.Errorf("error sampling source load: %w", err)
+	}
+	return sample, nil
+}
+
+// PartitionParallelism returns how many of maxParallelism partitions may be replicated in parallel
+// under the source's current load, per the peer's load throttle.
+func (c *PostgresConnector) PartitionParallelism(ctx context.Context, maxParallelism int) (int, error) {
+	throttle := c.config.LoadThrottle
+	if throttle == nil {
+		return maxParallelism, nil
+	}
+
+	sample, err := c.sampleSourceLoad(ctx)
+	if err != nil {
+		return 0, err
+	}
+	prev := c.loadSample
+	if prev == nil {
+		// blocks read is a counter, its rate needs a second sample
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		prev = sample
+		if sample, err = c.sampleSourceLoad(ctx); err != nil {
+			return 0, err
+		}
+	}
+	c.loadSample = sample
+
+	var blocksReadPerSecond float64
+	if elapsed := sample.at.Sub(prev.at).Seconds(); elapsed > 0 {
+		blocksReadPerSecond = float64(sample.blocksRead-prev.blocksRead) / elapsed
+	}
+	parallelism := throttledParallelism(throttle, maxParallelism,
+		float64(sample.activeQueries), blocksReadPerSecond, float64(sample.replicaLagByte))
+	if parallelism < maxParallelism {
+		c.logger.Info("throttling partitions by source load",
+			slog.Int("parallelism", parallelism),
+			slog.Int64("activeQueries", sample.activeQueries),
+			slog.Float64("blocksReadPerSecond", blocksReadPerSecond),
+			slog.Int64("replicaLagBytes", sample.replicaLagByte))
+	}
+	return parallelism, nil
+}
+
+// throttledParallelism scales maxParallelism down by how far the most exceeded threshold is exceeded.
+func throttledParallelism(throttle *protos.PostgresLoadThrottle, maxParallelism int,
+	activeQueries float64, blocksReadPerSecond float64, replicaLagBytes float64,
+) int {
+	pressure := 1.0
+	if throttle.MaxActiveQueries > 0 {
+		pressure = max(pressure, activeQueries/float64(throttle.MaxActiveQueries))
+	}
+	if throttle.MaxBlocksReadPerSecond > 0 {
+		pressure = max(pressure, blocksReadPerSecond/float64(throttle.MaxBlocksReadPerSecond))
+	}
+	if throttle.MaxReplicaLagBytes > 0 {
+		pressure = max(pressure, replicaLagBytes/float64(throttle.MaxReplicaLagBytes))
+	}
+	return max(1, int(float64(maxParallelism)/pressure))
+}
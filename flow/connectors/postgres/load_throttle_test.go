@@ -0,0 +1,18 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestThrottledParallelism(t *testing.T) {
+	throttle := &protos.PostgresLoadThrottle{MaxActiveQueries: 10, MaxReplicaLagBytes: 1 << 20}
+
+	require.Equal(t, 8, throttledParallelism(throttle, 8, 5, 1e9, 0), "under thresholds, unchecked IO ignored")
+	require.Equal(t, 4, throttledParallelism(throttle, 8, 20, 0, 0))
+	require.Equal(t, 2, throttledParallelism(throttle, 8, 20, 0, 4<<20), "most exceeded threshold wins")
+	require.Equal(t, 1, throttledParallelism(throttle, 8, 1000, 0, 0))
+}
@@ -40,6 +40,9 @@ func (n *normalizeStmtGenerator) generateNormalizeStatements() []string {
 	if n.peerdbCols.SoftDelete {
 		n.logger.Warn("soft delete enabled with fallback statements! this combination is unsupported")
 	}
+	if n.normalizedTableSchema.SyntheticPrimaryKey {
+		n.logger.Warn("table without primary key with fallback statements! this combination is unsupported")
+	}
 	return n.generateFallbackStatements()
 }
 
@@ -130,10 +133,14 @@ func (n *normalizeStmtGenerator) generateMergeStatement() string {
 		}
 		if slices.Contains(n.normalizedTableSchema.PrimaryKeyColumns, column.Name) {
 			primaryKeyColumnCasts[column.Name] = fmt.Sprintf("(_peerdb_data->>%s)::%s", stringCol, pgType)
-			primaryKeySelectSQLArray = append(primaryKeySelectSQLArray, fmt.Sprintf("src.%s=dst.%s",
-				quotedCol, quotedCol))
+			primaryKeySelectSQLArray = append(primaryKeySelectSQLArray, n.primaryKeyMatch(quotedCol, genericColumnType))
 		}
 	}
+	primaryKeyPartition := strings.Join(maps.Values(primaryKeyColumnCasts), ",")
+	if n.normalizedTableSchema.SyntheticPrimaryKey {
+		// rows are keyed by all their columns, which is what _peerdb_data holds
+		primaryKeyPartition = "_peerdb_data"
+	}
 	flattenedCastsSQL := strings.Join(flattenedCastsSQLArray, ",")
 	insertValuesSQLArray := make([]string, 0, columnCount+2)
 	for _, quotedCol := range quotedColumnNames {
@@ -171,7 +178,7 @@ func (n *normalizeStmtGenerator) generateMergeStatement() string {
 
 	mergeStmt := fmt.Sprintf(
 		mergeStatementSQL,
-		strings.Join(maps.Values(primaryKeyColumnCasts), ","),
+		primaryKeyPartition,
 		n.metadataSchema,
 		n.rawTableName,
 		parsedDstTable.String(),
@@ -186,6 +193,18 @@ func (n *normalizeStmtGenerator) generateMergeStatement() string {
 	return mergeStmt
 }
 
+// primaryKeyMatch compares a primary key column of the source and destination rows.
+// Synthetic keys span all columns, which may be NULL and include json without an equality operator.
+func (n *normalizeStmtGenerator) primaryKeyMatch(quotedCol string, genericColumnType string) string {
+	if !n.normalizedTableSchema.SyntheticPrimaryKey {
+		return fmt.Sprintf("src.%s=dst.%s", quotedCol, quotedCol)
+	}
+	if qvalue.QValueKind(genericColumnType) == qvalue.QValueKindJSON {
+		return fmt.Sprintf("src.%s::text IS NOT DISTINCT FROM dst.%s::text", quotedCol, quotedCol)
+	}
+	return fmt.Sprintf("src.%s IS NOT DISTINCT FROM dst.%s", quotedCol, quotedCol)
+}
+
 func (n *normalizeStmtGenerator) generateUpdateStatements(quotedCols []string) []string {
 	handleSoftDelete := n.peerdbCols.SoftDelete && (n.peerdbCols.SoftDeleteColName != "")
 	// weird way of doing it but avoids prealloc lint
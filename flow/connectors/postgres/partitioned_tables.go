@@ -0,0 +1,74 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type pgQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// resolvePartitionRoots maps every partition to the root of its partition tree,
+// so leaves of sub-partitioned tables are not mapped to an intermediate partitioned table.
+func resolvePartitionRoots(childToParent map[uint32]uint32) map[uint32]uint32 {
+	childToRoot := make(map[uint32]uint32, len(childToParent))
+	for child, parent := range childToParent {
+		root := parent
+		// bounded by the number of partitions in case of a cycle, which Postgres doesn't allow anyway
+		for range len(childToParent) {
+			grandParent, ok := childToParent[root]
+			if !ok {
+				break
+			}
+			root = grandParent
+		}
+		childToRoot[child] = root
+	}
+	return childToRoot
+}
+
+// getLeafPartitions returns the leaf partitions of a partitioned table, at any depth.
+// Returns nothing for tables which are not partitioned.
+func getLeafPartitions(ctx context.Context, conn pgQuerier, schemaTable *utils.SchemaTable) ([]*utils.SchemaTable, error) {
+	rows, err := conn.Query(ctx, `WITH RECURSIVE partitions AS (
+			SELECT inhrelid FROM pg_inherits
+			JOIN pg_class parent ON parent.oid = inhparent
+			JOIN pg_namespace n ON n.oid = parent.relnamespace
+			WHERE n.nspname = $1 AND parent.relname = $2 AND parent.relkind = 'p'
+			UNION ALL
+			SELECT i.inhrelid FROM pg_inherits i JOIN partitions p ON i.inhparent = p.inhrelid
+		)
+		SELECT n.nspname, c.relname FROM partitions
+		JOIN pg_class c ON c.oid = partitions.inhrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'r'
+		ORDER BY n.nspname, c.relname`, schemaTable.Schema, schemaTable.Table)
+	if err != nil {
+		return nil, fmt.Errorf("error getting partitions of table %s: %w", schemaTable, err)
+	}
+	leaves, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*utils.SchemaTable, error) {
+		leaf := &utils.SchemaTable{}
+		err := row.Scan(&leaf.Schema, &leaf.Table)
+		return leaf, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning partitions of table %s: %w", schemaTable, err)
+	}
+	return leaves, nil
+}
+
+// leafPartitionQuery restricts a query on a partitioned table to the leaf partition the qrep partition
+// was computed on, ctid ranges are only meaningful within a single leaf.
+// The query is expected to end with its WHERE clause, like snapshot queries do.
+func leafPartitionQuery(query string, partition *protos.QRepPartition) string {
+	if partition.LeafPartition == "" {
+		return query
+	}
+	return fmt.Sprintf("%s AND tableoid = %s::regclass", query, QuoteLiteral(partition.LeafPartition))
+}
@@ -0,0 +1,22 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestResolvePartitionRoots(t *testing.T) {
+	// 10 is partitioned into 11 and 12, 12 is sub-partitioned into 13 and 14
+	childToRoot := resolvePartitionRoots(map[uint32]uint32{11: 10, 12: 10, 13: 12, 14: 12})
+	require.Equal(t, map[uint32]uint32{11: 10, 12: 10, 13: 10, 14: 10}, childToRoot)
+}
+
+func TestLeafPartitionQuery(t *testing.T) {
+	query := `SELECT * FROM "public"."events" WHERE ctid BETWEEN $1 AND $2`
+	require.Equal(t, query, leafPartitionQuery(query, &protos.QRepPartition{}))
+	require.Equal(t, query+` AND tableoid = '"public"."events_2024"'::regclass`,
+		leafPartitionQuery(query, &protos.QRepPartition{LeafPartition: `"public"."events_2024"`}))
+}
@@ -18,9 +18,12 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/cdc_records"
 	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/model"
@@ -29,59 +32,86 @@ import (
 )
 
 type PostgresConnector struct {
-	connStr            string
-	config             *protos.PostgresConfig
-	ssh                *SSHTunnel
-	conn               *pgx.Conn
-	replConfig         *pgx.ConnConfig
-	replConn           *pgx.Conn
-	replState          *ReplState
-	replLock           sync.Mutex
-	customTypesMapping map[uint32]string
+	connStr string
+	config  *protos.PostgresConfig
+	// tunnel of the pool, which closes it
+	ssh  *SSHTunnel
+	pool *utils.PooledPgPool
+	// runs statements on connections of the pool acquired for each of them
+	conn       *ConnPool
+	replConfig *pgx.ConnConfig
+	replConn   *pgx.Conn
+	replState  *ReplState
+	replLock   sync.Mutex
+	// types of the peer's values decoded by the connector, with its custom types registered
+	typeMap            *pgtype.Map
+	customTypesMapping map[uint32]utils.CustomDataType
 	metadataSchema     string
 	hushWarnOID        map[uint32]struct{}
 	logger             log.Logger
+	// last sample of the source's load, for rates between partition throttling checks
+	loadSample *sourceLoadSample
 }
 
 type ReplState struct {
 	Slot        string
 	Publication string
 	Offset      int64
+	// offset the last pull started from, which every destination synced up to, pings confirm it to the source
+	// rather than Offset, which may not be synced yet
+	ConsumedOffset int64
+	// buffers of transactions streamed before they commit, nil when not streaming.
+	// Kept across pulls since the source only streams them again if replication is restarted.
+	Streams *cdc_records.StreamedTransactions
 }
 
 func NewPostgresConnector(ctx context.Context, pgConfig *protos.PostgresConfig) (*PostgresConnector, error) {
 	connectionString := utils.GetPGConnectionString(pgConfig)
 
-	// create a separate connection pool for non-replication queries as replication connections cannot
-	// be used for extended query protocol, i.e. prepared statements
-	connConfig, err := pgx.ParseConfig(connectionString)
-	replConfig := connConfig.Copy()
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse connection string: %w", err)
-	}
-
-	runtimeParams := connConfig.Config.RuntimeParams
-	runtimeParams["idle_in_transaction_session_timeout"] = "0"
-	runtimeParams["statement_timeout"] = "0"
-
-	tunnel, err := NewSSHTunnel(ctx, pgConfig.SshConfig)
+	// connections for non-replication queries come from a pool shared by the connectors to the peer,
+	// replication connections are opened separately as they cannot use the extended query protocol, i.e. prepared statements
+	pool, err := utils.AcquirePgPool(ctx, pgConfig, func(ctx context.Context) (*pgxpool.Pool, *utils.Dialer, error) {
+		return newPostgresPool(ctx, pgConfig)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ssh tunnel: %w", err)
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
+	conn := &ConnPool{pool: pool.Pool, logger: logger.LoggerFromCtx(ctx)}
 
-	conn, err := tunnel.NewPostgresConnFromConfig(ctx, connConfig)
+	replConfig, err := utils.GetPGConnConfig(ctx, pgConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create connection: %w", err)
+		pool.Release()
+		return nil, err
 	}
-
 	// ensure that replication is set to database
 	replConfig.Config.RuntimeParams["replication"] = "database"
 	replConfig.Config.RuntimeParams["bytea_output"] = "hex"
 
+	// pooled connections register the custom types when they connect, the connector's own type map
+	// has those created since then too
 	customTypeMap, err := utils.GetCustomDataTypes(ctx, conn)
 	if err != nil {
+		pool.Release()
 		return nil, fmt.Errorf("failed to get custom type map: %w", err)
 	}
+	typeMap := pgtype.NewMap()
+	if err := pool.Pool.AcquireFunc(ctx, func(pooledConn *pgxpool.Conn) error {
+		if err := utils.RegisterCustomTypes(ctx, pooledConn.Conn(), typeMap, customTypeMap); err != nil {
+			return err
+		}
+		// connections of the pool predate a new type, replace them as they're released
+		for typeOID := range customTypeMap {
+			_, registered := typeMap.TypeForOID(typeOID)
+			if _, pooled := pooledConn.Conn().TypeMap().TypeForOID(typeOID); registered && !pooled {
+				pool.Pool.Reset()
+				break
+			}
+		}
+		return nil
+	}); err != nil {
+		pool.Release()
+		return nil, fmt.Errorf("failed to register custom types: %w", err)
+	}
 
 	metadataSchema := "_peerdb_internal"
 	if pgConfig.MetadataSchema != nil {
@@ -91,11 +121,13 @@ func NewPostgresConnector(ctx context.Context, pgConfig *protos.PostgresConfig)
 	return &PostgresConnector{
 		connStr:            connectionString,
 		config:             pgConfig,
-		ssh:                tunnel,
+		ssh:                &SSHTunnel{dialer: pool.Dialer},
+		pool:               pool,
 		conn:               conn,
 		replConfig:         replConfig,
 		replState:          nil,
 		replLock:           sync.Mutex{},
+		typeMap:            typeMap,
 		customTypesMapping: customTypeMap,
 		metadataSchema:     metadataSchema,
 		hushWarnOID:        make(map[uint32]struct{}),
@@ -104,6 +136,10 @@ func NewPostgresConnector(ctx context.Context, pgConfig *protos.PostgresConfig)
 }
 
 func (c *PostgresConnector) CreateReplConn(ctx context.Context) (*pgx.Conn, error) {
+	// the token made when connecting the connector may have expired by now
+	if err := utils.SetPostgresIAMAuthToken(ctx, c.config, c.replConfig); err != nil {
+		return nil, fmt.Errorf("failed to get IAM auth token: %w", err)
+	}
 	conn, err := c.ssh.NewPostgresConnFromConfig(ctx, c.replConfig)
 	if err != nil {
 		logger.LoggerFromCtx(ctx).Error("failed to create replication connection", "error", err)
@@ -130,7 +166,7 @@ func (c *PostgresConnector) ReplPing(ctx context.Context) error {
 			return pglogrepl.SendStandbyStatusUpdate(
 				ctx,
 				c.replConn.PgConn(),
-				pglogrepl.StandbyStatusUpdate{WALWritePosition: pglogrepl.LSN(c.replState.Offset)},
+				pglogrepl.StandbyStatusUpdate{WALWritePosition: pglogrepl.LSN(c.replState.ConsumedOffset)},
 			)
 		}
 	}
@@ -141,6 +177,7 @@ func (c *PostgresConnector) MaybeStartReplication(
 	ctx context.Context,
 	slotName string,
 	publicationName string,
+	twoPhase bool,
 	req *model.PullRecordsRequest,
 ) error {
 	if c.replState != nil && (c.replState.Offset != req.LastOffset ||
@@ -154,7 +191,19 @@ func (c *PostgresConnector) MaybeStartReplication(
 	}
 
 	if c.replState == nil {
-		replicationOpts, err := c.replicationOptions(publicationName)
+		streaming := false
+		if dynamicconf.PeerDBCDCStreamInProgressTransactions(ctx) {
+			var err error
+			streaming, _, err = c.MajorVersionCheck(ctx, POSTGRES_14)
+			if err != nil {
+				return fmt.Errorf("error checking Postgres version: %w", err)
+			}
+			if !streaming {
+				c.logger.Warn("streaming in-progress transactions requires Postgres 14 or later, not streaming")
+			}
+		}
+
+		replicationOpts, err := c.replicationOptions(publicationName, twoPhase, streaming)
 		if err != nil {
 			return fmt.Errorf("error getting replication options: %w", err)
 		}
@@ -182,6 +231,9 @@ func (c *PostgresConnector) MaybeStartReplication(
 			Publication: publicationName,
 			Offset:      req.LastOffset,
 		}
+		if streaming {
+			c.replState.Streams = cdc_records.NewStreamedTransactions(req.FlowJobName)
+		}
 	}
 	return nil
 }
@@ -197,9 +249,22 @@ func (c *PostgresConnector) startReplication(ctx context.Context, slotName strin
 	return nil
 }
 
-func (c *PostgresConnector) replicationOptions(publicationName string) (*pglogrepl.StartReplicationOptions, error) {
-	pluginArguments := []string{
-		"proto_version '1'",
+func (c *PostgresConnector) replicationOptions(
+	publicationName string,
+	twoPhase bool,
+	streaming bool,
+) (*pglogrepl.StartReplicationOptions, error) {
+	var pluginArguments []string
+	// prepared transactions are only sent from protocol version 3, streamed transactions from version 2
+	if twoPhase {
+		pluginArguments = append(pluginArguments, "proto_version '3'", "two_phase 'on'")
+	} else if streaming {
+		pluginArguments = append(pluginArguments, "proto_version '2'")
+	} else {
+		pluginArguments = append(pluginArguments, "proto_version '1'")
+	}
+	if streaming {
+		pluginArguments = append(pluginArguments, "streaming 'on'")
 	}
 
 	if publicationName != "" {
@@ -214,24 +279,26 @@ func (c *PostgresConnector) replicationOptions(publicationName string) (*pglogre
 
 // Close closes all connections.
 func (c *PostgresConnector) Close() error {
-	var connerr, replerr error
+	var replerr, streamserr error
 	if c != nil {
-		timeout, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		connerr = c.conn.Close(timeout)
+		if c.pool != nil {
+			c.pool.Release()
+		}
 
 		if c.replConn != nil {
 			timeout, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer cancel()
 			replerr = c.replConn.Close(timeout)
 		}
-
-		c.ssh.Close()
+		if c.replState != nil && c.replState.Streams != nil {
+			streamserr = c.replState.Streams.Close()
+		}
 	}
-	return errors.Join(connerr, replerr)
+	return errors.Join(replerr, streamserr)
 }
 
-func (c *PostgresConnector) Conn() *pgx.Conn {
+// Conn runs statements on the peer, each on a connection of the pool acquired for it.
+func (c *PostgresConnector) Conn() *ConnPool {
 	return c.conn
 }
 
@@ -334,8 +401,30 @@ func (c *PostgresConnector) PullRecords(ctx context.Context, catalogPool *pgxpoo
 		return fmt.Errorf("replication slot %s does not exist", slotName)
 	}
 
+	conflicting, err := c.slotConflicting(ctx, slotName)
+	if err != nil {
+		return err
+	}
+	if conflicting {
+		return slotInvalidatedError(slotName)
+	}
+
 	c.logger.Info("PullRecords: performed checks for slot and publication")
 
+	// columns added to tables with column lists aren't published until they're added to the lists,
+	// custom publications are left to their owners
+	if req.PublicationColumnLists && req.OverridePublicationName == "" && publicationName != "" {
+		supported, _, err := c.MajorVersionCheck(ctx, POSTGRES_15)
+		if err != nil {
+			return fmt.Errorf("error checking Postgres version: %w", err)
+		}
+		if supported {
+			if err := c.refreshPublicationColumnLists(ctx, publicationName, req.TableNameMapping); err != nil {
+				return err
+			}
+		}
+	}
+
 	childToParentRelIDMap, err := GetChildToParentRelIDMap(ctx, c.conn)
 	if err != nil {
 		return fmt.Errorf("error getting child to parent relid map: %w", err)
@@ -344,11 +433,12 @@ func (c *PostgresConnector) PullRecords(ctx context.Context, catalogPool *pgxpoo
 	c.replLock.Lock()
 	defer c.replLock.Unlock()
 
-	err = c.MaybeStartReplication(ctx, slotName, publicationName, req)
+	err = c.MaybeStartReplication(ctx, slotName, publicationName, exists.SlotTwoPhase, req)
 	if err != nil {
 		c.logger.Error("error starting replication", slog.Any("error", err))
 		return err
 	}
+	c.replState.ConsumedOffset = req.LastOffset
 
 	cdc := c.NewPostgresCDCSource(&PostgresCDCConfig{
 		SrcTableIDNameMapping:  req.SrcTableIDNameMapping,
@@ -359,6 +449,8 @@ func (c *PostgresConnector) PullRecords(ctx context.Context, catalogPool *pgxpoo
 		ChildToParentRelIDMap:  childToParentRelIDMap,
 		CatalogPool:            catalogPool,
 		FlowJobName:            req.FlowJobName,
+		TwoPhase:               exists.SlotTwoPhase,
+		Streams:                c.replState.Streams,
 	})
 
 	err = cdc.PullRecords(ctx, req)
@@ -368,7 +460,10 @@ func (c *PostgresConnector) PullRecords(ctx context.Context, catalogPool *pgxpoo
 	}
 
 	req.RecordStream.Close()
-	c.replState.Offset = req.RecordStream.GetLastCheckpoint()
+	c.replState.Offset, err = req.RecordStream.GetLastCheckpoint()
+	if err != nil {
+		return err
+	}
 
 	latestLSN, err := c.getCurrentLSN(ctx)
 	if err != nil {
@@ -509,7 +604,10 @@ func (c *PostgresConnector) SyncRecords(ctx context.Context, req *model.SyncReco
 		syncedRecordsCount, rawTableIdentifier))
 
 	// updating metadata with new offset and syncBatchID
-	lastCP := req.Records.GetLastCheckpoint()
+	lastCP, err := req.Records.GetLastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
 	err = c.updateSyncMetadata(ctx, req.FlowJobName, lastCP, req.SyncBatchID, syncRecordsTx)
 	if err != nil {
 		return nil, err
@@ -529,9 +627,29 @@ func (c *PostgresConnector) SyncRecords(ctx context.Context, req *model.SyncReco
 	}, nil
 }
 
-func (c *PostgresConnector) NormalizeRecords(ctx context.Context, req *model.NormalizeRecordsRequest) (*model.NormalizeResponse, error) {
-	rawTableIdentifier := getRawTableIdentifier(req.FlowJobName)
+func (c *PostgresConnector) newNormalizeStmtGenerator(
+	req *model.NormalizeRecordsRequest,
+	destinationTableName string,
+	unchangedToastColumns []string,
+	supportsMerge bool,
+) *normalizeStmtGenerator {
+	return &normalizeStmtGenerator{
+		rawTableName:          getRawTableIdentifier(req.FlowJobName),
+		dstTableName:          destinationTableName,
+		normalizedTableSchema: req.TableNameSchemaMapping[destinationTableName],
+		unchangedToastColumns: unchangedToastColumns,
+		peerdbCols: &protos.PeerDBColumns{
+			SoftDeleteColName: req.SoftDeleteColName,
+			SyncedAtColName:   req.SyncedAtColName,
+			SoftDelete:        req.SoftDelete,
+		},
+		supportsMerge:  supportsMerge,
+		metadataSchema: c.metadataSchema,
+		logger:         c.logger,
+	}
+}
 
+func (c *PostgresConnector) NormalizeRecords(ctx context.Context, req *model.NormalizeRecordsRequest) (*model.NormalizeResponse, error) {
 	jobMetadataExists, err := c.jobMetadataExists(ctx, req.FlowJobName)
 	if err != nil {
 		return nil, err
@@ -589,20 +707,8 @@ func (c *PostgresConnector) NormalizeRecords(ctx context.Context, req *model.Nor
 	mergeStatementsBatch := &pgx.Batch{}
 	totalRowsAffected := 0
 	for _, destinationTableName := range destinationTableNames {
-		normalizeStmtGen := &normalizeStmtGenerator{
-			rawTableName:          rawTableIdentifier,
-			dstTableName:          destinationTableName,
-			normalizedTableSchema: req.TableNameSchemaMapping[destinationTableName],
-			unchangedToastColumns: unchangedToastColsMap[destinationTableName],
-			peerdbCols: &protos.PeerDBColumns{
-				SoftDeleteColName: req.SoftDeleteColName,
-				SyncedAtColName:   req.SyncedAtColName,
-				SoftDelete:        req.SoftDelete,
-			},
-			supportsMerge:  supportsMerge,
-			metadataSchema: c.metadataSchema,
-			logger:         c.logger,
-		}
+		normalizeStmtGen := c.newNormalizeStmtGenerator(req, destinationTableName,
+			unchangedToastColsMap[destinationTableName], supportsMerge)
 		normalizeStatements := normalizeStmtGen.generateNormalizeStatements()
 		for _, normalizeStatement := range normalizeStatements {
 			mergeStatementsBatch.Queue(normalizeStatement, normBatchID, req.SyncBatchID, destinationTableName).Exec(
@@ -639,9 +745,70 @@ func (c *PostgresConnector) NormalizeRecords(ctx context.Context, req *model.Nor
 	}, nil
 }
 
+// SimulateNormalize renders the statements NormalizeRecords would queue for a table, bound to the same arguments.
+// EXPLAIN without ANALYZE only plans the statements, so nothing is written to the destination either way.
+func (c *PostgresConnector) SimulateNormalize(
+	ctx context.Context,
+	req *model.NormalizeRecordsRequest,
+	tableName string,
+	explain bool,
+) (*model.NormalizeSimulation, error) {
+	normBatchID, err := c.GetLastNormalizeBatchID(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch for the current mirror: %v", err)
+	}
+	simulation := &model.NormalizeSimulation{
+		StartBatchID: normBatchID + 1,
+		EndBatchID:   req.SyncBatchID,
+	}
+	if normBatchID >= req.SyncBatchID {
+		return simulation, nil
+	}
+
+	unchangedToastColsMap, err := c.getTableNametoUnchangedCols(ctx, req.FlowJobName,
+		req.SyncBatchID, normBatchID)
+	if err != nil {
+		return nil, err
+	}
+	supportsMerge, _, err := c.MajorVersionCheck(ctx, POSTGRES_15)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizeStmtGen := c.newNormalizeStmtGenerator(req, tableName, unchangedToastColsMap[tableName], supportsMerge)
+	for _, normalizeStatement := range normalizeStmtGen.generateNormalizeStatements() {
+		stmt := model.NormalizeStatement{
+			SQL:  normalizeStatement,
+			Args: []any{normBatchID, req.SyncBatchID, tableName},
+		}
+		if explain {
+			stmt.Plan, err = c.explainStatement(ctx, stmt.SQL, stmt.Args...)
+			if err != nil {
+				return nil, err
+			}
+		}
+		simulation.Statements = append(simulation.Statements, stmt)
+	}
+	return simulation, nil
+}
+
+func (c *PostgresConnector) explainStatement(ctx context.Context, stmt string, args ...any) (string, error) {
+	rows, err := c.conn.Query(ctx, "EXPLAIN "+stmt, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to explain statement: %w", err)
+	}
+	planLines, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", fmt.Errorf("failed to read query plan: %w", err)
+	}
+	return strings.Join(planLines, "\n"), nil
+}
+
 type SlotCheckResult struct {
 	SlotExists        bool
 	PublicationExists bool
+	// slot was created with two-phase decoding of prepared transactions
+	SlotTwoPhase bool
 }
 
 // CreateRawTable creates a raw table, implementing the Connector interface.
@@ -741,9 +908,9 @@ func (c *PostgresConnector) getTableSchemaForTable(
 	for _, fieldDescription := range fields {
 		genericColType := c.postgresOIDToQValueKind(fieldDescription.DataTypeOID)
 		if genericColType == qvalue.QValueKindInvalid {
-			typeName, ok := c.customTypesMapping[fieldDescription.DataTypeOID]
+			customType, ok := c.customTypesMapping[fieldDescription.DataTypeOID]
 			if ok {
-				genericColType = customTypeToQKind(typeName)
+				genericColType = c.customTypeToQKind(fieldDescription.DataTypeOID, customType)
 			} else {
 				genericColType = qvalue.QValueKindString
 			}
@@ -760,8 +927,19 @@ func (c *PostgresConnector) getTableSchemaForTable(
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating over table schema: %w", err)
 	}
+	rows.Close()
+
+	multiDimFields, err := c.getMultiDimArrayFields(ctx, c.conn, fields)
+	if err != nil {
+		return nil, fmt.Errorf("[getTableSchema] error getting array columns for table %s: %w", schemaTable, err)
+	}
+	for i := range multiDimFields {
+		columns[i].Type = string(qvalue.QValueKindJSON)
+	}
+
 	// if we have no pkey, we will use all columns as the pkey for the MERGE statement
-	if replicaIdentityType == ReplicaIdentityFull && len(pKeyCols) == 0 {
+	syntheticPkey := replicaIdentityType == ReplicaIdentityFull && len(pKeyCols) == 0
+	if syntheticPkey {
 		pKeyCols = columnNames
 	}
 
@@ -770,6 +948,7 @@ func (c *PostgresConnector) getTableSchemaForTable(
 		PrimaryKeyColumns:     pKeyCols,
 		IsReplicaIdentityFull: replicaIdentityType == ReplicaIdentityFull,
 		Columns:               columns,
+		SyntheticPrimaryKey:   syntheticPkey,
 	}, nil
 }
 
@@ -792,10 +971,9 @@ func (c *PostgresConnector) FinishSetupNormalizedTables(ctx context.Context, tx
 func (c *PostgresConnector) SetupNormalizedTable(
 	ctx context.Context,
 	tx any,
+	config *protos.SetupNormalizedTableBatchInput,
 	tableIdentifier string,
 	tableSchema *protos.TableSchema,
-	softDeleteColName string,
-	syncedAtColName string,
 ) (bool, error) {
 	createNormalizedTablesTx := tx.(pgx.Tx)
 
@@ -813,7 +991,7 @@ func (c *PostgresConnector) SetupNormalizedTable(
 
 	// convert the column names and types to Postgres types
 	normalizedTableCreateSQL := generateCreateTableSQLForNormalizedTable(
-		parsedNormalizedTable.String(), tableSchema, softDeleteColName, syncedAtColName)
+		parsedNormalizedTable.String(), tableSchema, config.SoftDeleteColName, config.SyncedAtColName)
 	_, err = createNormalizedTablesTx.Exec(ctx, normalizedTableCreateSQL)
 	if err != nil {
 		return false, fmt.Errorf("error while creating normalized table: %w", err)
@@ -823,7 +1001,7 @@ func (c *PostgresConnector) SetupNormalizedTable(
 }
 
 // ReplayTableSchemaDelta changes a destination table to match the schema at source
-// This could involve adding or dropping multiple columns.
+// This could involve adding, dropping, renaming or widening multiple columns.
 func (c *PostgresConnector) ReplayTableSchemaDeltas(
 	ctx context.Context,
 	flowJobName string,
@@ -847,10 +1025,68 @@ func (c *PostgresConnector) ReplayTableSchemaDeltas(
 	}()
 
 	for _, schemaDelta := range schemaDeltas {
-		if schemaDelta == nil || len(schemaDelta.AddedColumns) == 0 {
+		if model.IsEmptySchemaDelta(schemaDelta) {
 			continue
 		}
 
+		for _, droppedColumn := range schemaDelta.DroppedColumns {
+			_, err = tableSchemaModifyTx.Exec(ctx, fmt.Sprintf(
+				"ALTER TABLE %s DROP COLUMN IF EXISTS %s",
+				schemaDelta.DstTableName, QuoteIdentifier(droppedColumn)))
+			if err != nil {
+				return fmt.Errorf("failed to drop column %s for table %s: %w", droppedColumn,
+					schemaDelta.DstTableName, err)
+			}
+			c.logger.Info("[schema delta replay] dropped column "+droppedColumn,
+				slog.String("srcTableName", schemaDelta.SrcTableName),
+				slog.String("dstTableName", schemaDelta.DstTableName),
+			)
+		}
+
+		for _, renamedColumn := range schemaDelta.RenamedColumns {
+			// Postgres has no RENAME COLUMN IF EXISTS, columns renamed by an earlier replay are skipped
+			var exists bool
+			err = tableSchemaModifyTx.QueryRow(ctx,
+				"SELECT EXISTS(SELECT 1 FROM pg_attribute WHERE attrelid=$1::regclass AND attname=$2 AND NOT attisdropped)",
+				schemaDelta.DstTableName, renamedColumn.OldName).Scan(&exists)
+			if err != nil {
+				return fmt.Errorf("failed to check for column %s of table %s: %w", renamedColumn.OldName,
+					schemaDelta.DstTableName, err)
+			}
+			if !exists {
+				continue
+			}
+			_, err = tableSchemaModifyTx.Exec(ctx, fmt.Sprintf(
+				"ALTER TABLE %s RENAME COLUMN %s TO %s",
+				schemaDelta.DstTableName, QuoteIdentifier(renamedColumn.OldName), QuoteIdentifier(renamedColumn.NewName)))
+			if err != nil {
+				return fmt.Errorf("failed to rename column %s to %s for table %s: %w", renamedColumn.OldName,
+					renamedColumn.NewName, schemaDelta.DstTableName, err)
+			}
+			c.logger.Info(fmt.Sprintf("[schema delta replay] renamed column %s to %s",
+				renamedColumn.OldName, renamedColumn.NewName),
+				slog.String("srcTableName", schemaDelta.SrcTableName),
+				slog.String("dstTableName", schemaDelta.DstTableName),
+			)
+		}
+
+		for _, alteredColumn := range schemaDelta.AlteredColumns {
+			pgType := qValueKindToPostgresType(alteredColumn.NewType)
+			_, err = tableSchemaModifyTx.Exec(ctx, fmt.Sprintf(
+				"ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s",
+				schemaDelta.DstTableName, QuoteIdentifier(alteredColumn.ColumnName), pgType,
+				QuoteIdentifier(alteredColumn.ColumnName), pgType))
+			if err != nil {
+				return fmt.Errorf("failed to change type of column %s for table %s: %w", alteredColumn.ColumnName,
+					schemaDelta.DstTableName, err)
+			}
+			c.logger.Info(fmt.Sprintf("[schema delta replay] changed type of column %s from %s to %s",
+				alteredColumn.ColumnName, alteredColumn.OldType, alteredColumn.NewType),
+				slog.String("srcTableName", schemaDelta.SrcTableName),
+				slog.String("dstTableName", schemaDelta.DstTableName),
+			)
+		}
+
 		for _, addedColumn := range schemaDelta.AddedColumns {
 			_, err = tableSchemaModifyTx.Exec(ctx, fmt.Sprintf(
 				"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
@@ -934,12 +1170,12 @@ func (c *PostgresConnector) ExportSnapshot(ctx context.Context) (string, any, er
 		return "", nil, err
 	}
 
-	_, err = tx.Exec(ctx, "SET idle_in_transaction_session_timeout = 0")
+	_, err = tx.Exec(ctx, "SET LOCAL idle_in_transaction_session_timeout = 0")
 	if err != nil {
 		return "", nil, fmt.Errorf("[export-snapshot] error setting idle_in_transaction_session_timeout: %w", err)
 	}
 
-	_, err = tx.Exec(ctx, "SET lock_timeout = 0")
+	_, err = tx.Exec(ctx, "SET LOCAL lock_timeout = 0")
 	if err != nil {
 		return "", nil, fmt.Errorf("[export-snapshot] error setting lock_timeout: %w", err)
 	}
@@ -985,16 +1221,17 @@ func (c *PostgresConnector) SetupReplication(ctx context.Context, signal SlotSig
 		return err
 	}
 
+	mappings := make(map[string]*protos.TableMapping, len(req.TableMappings))
+	for _, tableMapping := range req.TableMappings {
+		mappings[tableMapping.SourceTableIdentifier] = tableMapping
+	}
 	tableNameMapping := make(map[string]model.NameAndExclude)
 	for k, v := range req.TableNameMapping {
-		tableNameMapping[k] = model.NameAndExclude{
-			Name:    v,
-			Exclude: make(map[string]struct{}, 0),
-		}
+		tableNameMapping[k] = model.NewNameAndExclude(v, mappings[k].GetExclude(), mappings[k].GetInclude())
 	}
 	// Create the replication slot and publication
-	err = c.createSlotAndPublication(ctx, signal, exists,
-		slotName, publicationName, tableNameMapping, req.DoInitialSnapshot)
+	err = c.createSlotAndPublication(ctx, signal, exists, slotName, publicationName, tableNameMapping,
+		req.DoInitialSnapshot, req.TwoPhaseCommit, req.PublicationColumnLists)
 	if err != nil {
 		return fmt.Errorf("error creating replication slot and publication: %w", err)
 	}
@@ -1071,8 +1308,22 @@ func (c *PostgresConnector) HandleSlotInfo(
 		return nil
 	}
 
-	logger.Info(fmt.Sprintf("Checking %s lag for %s", slotName, peerName), slog.Float64("LagInMB", float64(slotInfo[0].LagInMb)))
+	if _, err := c.checkStandbyPromotion(ctx); err != nil {
+		logger.Warn("warning: failed to check if standby was promoted", "error", err)
+		return err
+	}
+
+	flushLag, err := monitoring.GetSlotConfirmedFlushLag(ctx, catalogPool, peerName, slotInfo[0])
+	if err != nil {
+		logger.Warn("warning: failed to get slot confirmed flush lag", "error", err)
+		return err
+	}
+	slotInfo[0].ConfirmedFlushLagSeconds = flushLag.Seconds()
+
+	logger.Info(fmt.Sprintf("Checking %s lag for %s", slotName, peerName), slog.Float64("LagInMB", float64(slotInfo[0].LagInMb)),
+		slog.Int64("ConfirmedFlushLagBytes", slotInfo[0].ConfirmedFlushLagBytes), slog.Duration("ConfirmedFlushLag", flushLag))
 	alerter.AlertIfSlotLag(ctx, peerName, slotInfo[0])
+	alerter.AlertIfSlotFlushLag(ctx, peerName, slotInfo[0])
 
 	// Also handles alerts for PeerDB user connections exceeding a given limit here
 	res, err := getOpenConnectionsForUser(ctx, c.conn, c.config.User)
@@ -1082,10 +1333,52 @@ func (c *PostgresConnector) HandleSlotInfo(
 	}
 	alerter.AlertIfOpenConnections(ctx, peerName, res)
 
+	if alertMinutes := dynamicconf.PeerDBPGPeerLongTransactionAlertMinutes(ctx); alertMinutes > 0 {
+		openTx, err := getOldestWritingTransaction(ctx, c.conn)
+		if err != nil {
+			logger.Warn("warning: failed to get oldest open transaction", "error", err)
+			return err
+		}
+		if openTx != nil && openTx.AgeSeconds < int64(alertMinutes)*60 {
+			openTx = nil
+		}
+		if openTx != nil {
+			alerter.AlertIfLongTransaction(ctx, peerName, slotName, openTx)
+		}
+		if err := monitoring.UpdateOpenTransaction(ctx, catalogPool, peerName, openTx); err != nil {
+			return err
+		}
+	}
+
 	return monitoring.AppendSlotSizeInfo(ctx, catalogPool, peerName, slotInfo[0])
 }
 
-func getOpenConnectionsForUser(ctx context.Context, conn *pgx.Conn, user string) (*protos.GetOpenConnectionsForUserResult, error) {
+func getOldestWritingTransaction(ctx context.Context, conn utils.Querier) (*protos.OpenTransaction, error) {
+	var pid int32
+	var database, user, state, query pgtype.Text
+	var xactStart time.Time
+	var ageSeconds int64
+	err := conn.QueryRow(ctx, getOldestWritingTransactionSQL).Scan(&pid, &database, &user, &state, &query, &xactStart, &ageSeconds)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while reading result row: %w", err)
+	}
+
+	return &protos.OpenTransaction{
+		Pid:        pid,
+		Database:   database.String,
+		User:       user.String,
+		State:      state.String,
+		Query:      query.String,
+		XactStart:  timestamppb.New(xactStart),
+		CheckedAt:  timestamppb.Now(),
+		AgeSeconds: ageSeconds,
+	}, nil
+}
+
+func getOpenConnectionsForUser(ctx context.Context, conn utils.Querier, user string) (*protos.GetOpenConnectionsForUserResult, error) {
 	row := conn.QueryRow(ctx, getNumConnectionsForUser, user)
 
 	// COUNT() returns BIGINT
@@ -1130,14 +1423,43 @@ func (c *PostgresConnector) AddTablesToPublication(ctx context.Context, req *pro
 				strings.Join(notPresentTables, ", "))
 		}
 	} else {
-		for _, additionalSrcTable := range additionalSrcTables {
+		supportsPubViaRoot, _, err := c.MajorVersionCheck(ctx, POSTGRES_13)
+		if err != nil {
+			return fmt.Errorf("error checking Postgres version: %w", err)
+		}
+		useColumnLists, err := c.supportsPublicationColumnLists(ctx, req.PublicationColumnLists)
+		if err != nil {
+			return err
+		}
+		for _, additionalTableMapping := range req.AdditionalTables {
+			additionalSrcTable := additionalTableMapping.SourceTableIdentifier
 			schemaTable, err := utils.ParseSchemaTable(additionalSrcTable)
 			if err != nil {
 				return err
 			}
+			publicationTable := schemaTable.String()
+			if !supportsPubViaRoot {
+				leaves, err := getLeafPartitions(ctx, c.conn, schemaTable)
+				if err != nil {
+					return err
+				}
+				if len(leaves) > 0 {
+					leafNames := make([]string, 0, len(leaves))
+					for _, leaf := range leaves {
+						leafNames = append(leafNames, leaf.String())
+					}
+					publicationTable = strings.Join(leafNames, ", ")
+				}
+			} else if useColumnLists {
+				publicationTable, err = c.publicationTable(ctx, schemaTable,
+					model.NewNameAndExclude("", additionalTableMapping.Exclude, additionalTableMapping.Include))
+				if err != nil {
+					return fmt.Errorf("error building column list for table %s: %w", additionalSrcTable, err)
+				}
+			}
 			_, err = c.conn.Exec(ctx, fmt.Sprintf("ALTER PUBLICATION %s ADD TABLE %s",
 				utils.QuoteIdentifier(c.getDefaultPublicationName(req.FlowJobName)),
-				schemaTable.String()))
+				publicationTable))
 			// don't error out if table is already added to our publication
 			if err != nil && !strings.Contains(err.Error(), "SQLSTATE 42710") {
 				return fmt.Errorf("failed to alter publication: %w", err)
@@ -95,6 +95,48 @@ func (s PostgresSchemaDeltaTestSuite) TestSimpleAddColumn() {
 	}, output.TableNameSchemaMapping[tableName])
 }
 
+func (s PostgresSchemaDeltaTestSuite) TestDropRenameWidenColumns() {
+	tableName := s.schema + ".drop_rename_widen_columns"
+	_, err := s.connector.conn.Exec(context.Background(),
+		fmt.Sprintf("CREATE TABLE %s(id INT PRIMARY KEY, old_name TEXT, gone TEXT, amount INT)", tableName))
+	require.NoError(s.t, err)
+
+	deltas := []*protos.TableSchemaDelta{{
+		SrcTableName:   tableName,
+		DstTableName:   tableName,
+		DroppedColumns: []string{"gone"},
+		RenamedColumns: []*protos.DeltaRenamedColumn{{
+			OldName:    "old_name",
+			NewName:    "new_name",
+			ColumnType: string(qvalue.QValueKindString),
+		}},
+		AlteredColumns: []*protos.DeltaAlteredColumn{{
+			ColumnName: "amount",
+			OldType:    string(qvalue.QValueKindInt32),
+			NewType:    string(qvalue.QValueKindInt64),
+		}},
+	}}
+	err = s.connector.ReplayTableSchemaDeltas(context.Background(), "schema_delta_flow", deltas)
+	require.NoError(s.t, err)
+	// replaying again is harmless
+	err = s.connector.ReplayTableSchemaDeltas(context.Background(), "schema_delta_flow", deltas)
+	require.NoError(s.t, err)
+
+	output, err := s.connector.GetTableSchema(context.Background(), &protos.GetTableSchemaBatchInput{
+		TableIdentifiers: []string{tableName},
+	})
+	require.NoError(s.t, err)
+	require.Equal(s.t, &protos.TableSchema{
+		TableIdentifier:   tableName,
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt32), TypeModifier: -1},
+			{Name: "new_name", Type: string(qvalue.QValueKindString), TypeModifier: -1},
+			{Name: "amount", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+		},
+	}, output.TableNameSchemaMapping[tableName])
+}
+
 func (s PostgresSchemaDeltaTestSuite) TestAddAllColumnTypes() {
 	tableName := s.schema + ".add_drop_all_column_types"
 	_, err := s.connector.conn.Exec(context.Background(),
@@ -23,12 +23,16 @@ import (
 
 const qRepMetadataTableName = "_peerdb_query_replication_metadata"
 
+// xmin syncs compare 32-bit xids by their age, which is only meaningful for xids less than 2^31 apart,
+// syncs further behind than this rescan the whole table rather than risk missing wrapped around rows
+const xminWraparoundHorizon = 1 << 30
+
 func (c *PostgresConnector) GetQRepPartitions(
 	ctx context.Context,
 	config *protos.QRepConfig,
 	last *protos.QRepPartition,
 ) ([]*protos.QRepPartition, error) {
-	if config.WatermarkColumn == "" {
+	if config.WatermarkColumn == "" && len(config.WatermarkColumns) == 0 {
 		// if no watermark column is specified, return a single partition
 		partition := &protos.QRepPartition{
 			PartitionId:        uuid.New().String(),
@@ -66,7 +70,91 @@ func (c *PostgresConnector) GetQRepPartitions(
 	// 	log.Warnf("failed to lock table %s: %v", config.WatermarkTable, err)
 	// }
 
-	return c.getNumRowsPartitions(ctx, tx, config, last)
+	parsedWatermarkTable, err := utils.ParseSchemaTable(config.WatermarkTable)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse watermark table: %w", err)
+	}
+
+	var partitions []*protos.QRepPartition
+	var leaves []*utils.SchemaTable
+	if config.WatermarkColumn == "ctid" {
+		leaves, err = getLeafPartitions(ctx, tx, parsedWatermarkTable)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(config.WatermarkColumns) != 0 {
+		partitions, err = c.getCompositePartitions(ctx, tx, config, parsedWatermarkTable, last)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(leaves) == 0 {
+		partitions, err = c.getPartitions(ctx, tx, config, parsedWatermarkTable, last)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c.logger.Info(fmt.Sprintf("computing ctid partitions for each of the %d leaf partitions of %s",
+			len(leaves), parsedWatermarkTable))
+		for _, leaf := range leaves {
+			leafPartitions, err := c.getPartitions(ctx, tx, config, leaf, last)
+			if err != nil {
+				return nil, err
+			}
+			for _, partition := range leafPartitions {
+				partition.LeafPartition = leaf.String()
+			}
+			partitions = append(partitions, leafPartitions...)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return partitions, nil
+}
+
+// EstimateQRepRows estimates the rows of the watermark table from the statistics of it and its partitions,
+// tables never analyzed count as empty.
+func (c *PostgresConnector) EstimateQRepRows(ctx context.Context, config *protos.QRepConfig) (int64, error) {
+	watermarkTable, err := utils.ParseSchemaTable(config.WatermarkTable)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse watermark table: %w", err)
+	}
+	var rows int64
+	if err := c.conn.QueryRow(ctx, `SELECT COALESCE(SUM(GREATEST(reltuples, 0)), 0)::BIGINT FROM pg_class
+		WHERE oid = $1::regclass OR oid IN (SELECT inhrelid FROM pg_inherits WHERE inhparent = $1::regclass)`,
+		watermarkTable.String()).Scan(&rows); err != nil {
+		return 0, fmt.Errorf("failed to estimate rows of table %s: %w", watermarkTable, err)
+	}
+	return rows, nil
+}
+
+func (c *PostgresConnector) getPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	config *protos.QRepConfig,
+	watermarkTable *utils.SchemaTable,
+	last *protos.QRepPartition,
+) ([]*protos.QRepPartition, error) {
+	// ranges are only computed for full loads, incremental runs only cover the rows added since the last one
+	if config.NumRowsPerPartition > 0 && (last == nil || last.Range == nil) {
+		var partitions []*protos.QRepPartition
+		var ok bool
+		var err error
+		switch config.PartitionMode {
+		case protos.QRepPartitionMode_QREP_PARTITION_MODE_RANGES:
+			partitions, ok, err = c.getRangePartitions(ctx, tx, config, watermarkTable)
+		case protos.QRepPartitionMode_QREP_PARTITION_MODE_ADAPTIVE:
+			partitions, ok, err = c.getAdaptivePartitions(ctx, tx, config, watermarkTable)
+		}
+		if err != nil || ok {
+			return partitions, err
+		}
+	}
+	return c.getNumRowsPartitions(ctx, tx, config, watermarkTable, last)
 }
 
 func (c *PostgresConnector) setTransactionSnapshot(ctx context.Context, tx pgx.Tx) error {
@@ -84,6 +172,7 @@ func (c *PostgresConnector) getNumRowsPartitions(
 	ctx context.Context,
 	tx pgx.Tx,
 	config *protos.QRepConfig,
+	parsedWatermarkTable *utils.SchemaTable,
 	last *protos.QRepPartition,
 ) ([]*protos.QRepPartition, error) {
 	var err error
@@ -95,11 +184,6 @@ func (c *PostgresConnector) getNumRowsPartitions(
 		whereClause = fmt.Sprintf(`WHERE %s > $1`, quotedWatermarkColumn)
 	}
 
-	parsedWatermarkTable, err := utils.ParseSchemaTable(config.WatermarkTable)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse watermark table: %w", err)
-	}
-
 	// Query to get the total number of rows in the table
 	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %s %s`, parsedWatermarkTable.String(), whereClause)
 	var row pgx.Row
@@ -194,11 +278,6 @@ func (c *PostgresConnector) getNumRowsPartitions(
 		return nil, fmt.Errorf("failed to read rows: %w", err)
 	}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return partitionHelper.GetPartitions(), nil
 }
 
@@ -284,6 +363,10 @@ func (c *PostgresConnector) CheckForUpdatedMaxValue(
 	config *protos.QRepConfig,
 	last *protos.QRepPartition,
 ) (bool, error) {
+	if len(config.WatermarkColumns) != 0 {
+		return c.checkForRowsAfterCompositeWatermark(ctx, config, last)
+	}
+
 	tx, err := c.conn.Begin(ctx)
 	if err != nil {
 		return false, fmt.Errorf("unable to begin transaction for getting max value: %w", err)
@@ -326,48 +409,28 @@ func (c *PostgresConnector) PullQRepRecords(
 		c.logger.Info("pulling full table partition", partitionIdLog)
 		executor := c.NewQRepQueryExecutorSnapshot(c.config.TransactionSnapshot,
 			config.FlowJobName, partition.PartitionId)
-		query := config.Query
+		query := rowFilterQuery(config.Query, config)
 		return executor.ExecuteAndProcessQuery(ctx, query)
 	}
 
-	var rangeStart interface{}
-	var rangeEnd interface{}
-
-	// Depending on the type of the range, convert the range into the correct type
-	switch x := partition.Range.Range.(type) {
-	case *protos.PartitionRange_IntRange:
-		rangeStart = x.IntRange.Start
-		rangeEnd = x.IntRange.End
-	case *protos.PartitionRange_TimestampRange:
-		rangeStart = x.TimestampRange.Start.AsTime()
-		rangeEnd = x.TimestampRange.End.AsTime()
-	case *protos.PartitionRange_TidRange:
-		rangeStart = pgtype.TID{
-			BlockNumber:  x.TidRange.Start.BlockNumber,
-			OffsetNumber: uint16(x.TidRange.Start.OffsetNumber),
-			Valid:        true,
-		}
-		rangeEnd = pgtype.TID{
-			BlockNumber:  x.TidRange.End.BlockNumber,
-			OffsetNumber: uint16(x.TidRange.End.OffsetNumber),
-			Valid:        true,
-		}
-	default:
-		return nil, fmt.Errorf("unknown range type: %v", x)
+	rangeArgs, err := partitionRangeArgs(partition)
+	if err != nil {
+		return nil, err
 	}
 	c.logger.Info("Obtained ranges for partition for PullQRep", partitionIdLog)
 
 	// Build the query to pull records within the range from the source table
 	// Be sure to order the results by the watermark column to ensure consistency across pulls
-	query, err := BuildQuery(c.logger, config.Query, config.FlowJobName)
+	query, err := buildPartitionQuery(c.logger, config, partition)
 	if err != nil {
 		return nil, err
 	}
+	query = rowFilterQuery(leafPartitionQuery(query, partition), config)
 
 	executor := c.NewQRepQueryExecutorSnapshot(c.config.TransactionSnapshot,
 		config.FlowJobName, partition.PartitionId)
 
-	records, err := executor.ExecuteAndProcessQuery(ctx, query, rangeStart, rangeEnd)
+	records, err := executor.ExecuteAndProcessQuery(ctx, query, rangeArgs...)
 	if err != nil {
 		return nil, err
 	}
@@ -387,49 +450,29 @@ func (c *PostgresConnector) PullQRepRecordStream(
 		executor := c.NewQRepQueryExecutorSnapshot(c.config.TransactionSnapshot,
 			config.FlowJobName, partition.PartitionId)
 
-		query := config.Query
+		query := rowFilterQuery(config.Query, config)
 		_, err := executor.ExecuteAndProcessQueryStream(ctx, stream, query)
 		return 0, err
 	}
 	c.logger.Info("Obtained ranges for partition for PullQRepStream", partitionIdLog)
 
-	var rangeStart interface{}
-	var rangeEnd interface{}
-
-	// Depending on the type of the range, convert the range into the correct type
-	switch x := partition.Range.Range.(type) {
-	case *protos.PartitionRange_IntRange:
-		rangeStart = x.IntRange.Start
-		rangeEnd = x.IntRange.End
-	case *protos.PartitionRange_TimestampRange:
-		rangeStart = x.TimestampRange.Start.AsTime()
-		rangeEnd = x.TimestampRange.End.AsTime()
-	case *protos.PartitionRange_TidRange:
-		rangeStart = pgtype.TID{
-			BlockNumber:  x.TidRange.Start.BlockNumber,
-			OffsetNumber: uint16(x.TidRange.Start.OffsetNumber),
-			Valid:        true,
-		}
-		rangeEnd = pgtype.TID{
-			BlockNumber:  x.TidRange.End.BlockNumber,
-			OffsetNumber: uint16(x.TidRange.End.OffsetNumber),
-			Valid:        true,
-		}
-	default:
-		return 0, fmt.Errorf("unknown range type: %v", x)
+	rangeArgs, err := partitionRangeArgs(partition)
+	if err != nil {
+		return 0, err
 	}
 
 	// Build the query to pull records within the range from the source table
 	// Be sure to order the results by the watermark column to ensure consistency across pulls
-	query, err := BuildQuery(c.logger, config.Query, config.FlowJobName)
+	query, err := buildPartitionQuery(c.logger, config, partition)
 	if err != nil {
 		return 0, err
 	}
+	query = rowFilterQuery(leafPartitionQuery(query, partition), config)
 
 	executor := c.NewQRepQueryExecutorSnapshot(c.config.TransactionSnapshot,
 		config.FlowJobName, partition.PartitionId)
 
-	numRecords, err := executor.ExecuteAndProcessQueryStream(ctx, stream, query, rangeStart, rangeEnd)
+	numRecords, err := executor.ExecuteAndProcessQueryStream(ctx, stream, query, rangeArgs...)
 	if err != nil {
 		return 0, err
 	}
@@ -458,15 +501,6 @@ func (c *PostgresConnector) SyncQRepRecords(
 		return 0, fmt.Errorf("table %s does not exist, used schema: %s", dstTable.Table, dstTable.Schema)
 	}
 
-	done, err := c.isPartitionSynced(ctx, partition.PartitionId)
-	if err != nil {
-		return 0, fmt.Errorf("failed to check if partition is synced: %w", err)
-	}
-
-	if done {
-		c.logger.Info(fmt.Sprintf("partition %s already synced", partition.PartitionId))
-		return 0, nil
-	}
 	c.logger.Info("SyncRecords called and initial checks complete.")
 
 	stagingTableSync := &QRepStagingTableSync{connector: c}
@@ -482,6 +516,7 @@ func (c *PostgresConnector) SetupQRepMetadataTables(ctx context.Context, config
 		return fmt.Errorf("error creating metadata schema: %w", err)
 	}
 
+	// partitions are marked synced in the transaction writing their rows, see IsQRepPartitionSynced
 	metadataTableIdentifier := pgx.Identifier{c.metadataSchema, qRepMetadataTableName}
 	createQRepMetadataTableSQL := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
 		flowJobName TEXT,
@@ -490,7 +525,6 @@ func (c *PostgresConnector) SetupQRepMetadataTables(ctx context.Context, config
 		syncStartTime TIMESTAMP,
 		syncFinishTime TIMESTAMP DEFAULT NOW()
 	)`, metadataTableIdentifier.Sanitize())
-	// execute create table query
 	_, err = c.conn.Exec(ctx, createQRepMetadataTableSQL)
 	if err != nil && !utils.IsUniqueError(err) {
 		return fmt.Errorf("failed to create table %s: %w", qRepMetadataTableName, err)
@@ -518,17 +552,31 @@ func (c *PostgresConnector) PullXminRecordStream(
 	var currentSnapshotXmin int64
 	query := config.Query
 	oldxid := ""
+	// the first sync, and syncs too far behind, replicate the whole table
+	sinceLastXmin := false
 	if partition.Range != nil {
-		oldxid = strconv.FormatInt(partition.Range.Range.(*protos.PartitionRange_IntRange).IntRange.Start&0xffffffff, 10)
-		query += " WHERE age(xmin) > 0 AND age(xmin) <= age($1::xid)"
+		lastXmin := partition.Range.Range.(*protos.PartitionRange_IntRange).IntRange.Start
+		lag, err := c.XminLag(ctx, lastXmin)
+		if err != nil {
+			return 0, 0, err
+		}
+		if lag >= xminWraparoundHorizon {
+			c.logger.Warn("xids since the last sync could have wrapped around, rescanning the whole table",
+				slog.Int64("lastXmin", lastXmin), slog.Int64("lag", lag))
+		} else {
+			sinceLastXmin = true
+			oldxid = strconv.FormatInt(lastXmin&0xffffffff, 10)
+			query += " WHERE age(xmin) > 0 AND age(xmin) <= age($1::xid)"
+		}
 	}
+	query = rowFilterQuery(query, config)
 
 	executor := c.NewQRepQueryExecutorSnapshot(c.config.TransactionSnapshot,
 		config.FlowJobName, partition.PartitionId)
 
 	var err error
 	var numRecords int
-	if partition.Range != nil {
+	if sinceLastXmin {
 		numRecords, currentSnapshotXmin, err = executor.ExecuteAndProcessQueryStreamGettingCurrentSnapshotXmin(
 			ctx,
 			stream,
@@ -550,15 +598,78 @@ func (c *PostgresConnector) PullXminRecordStream(
 	return numRecords, currentSnapshotXmin, nil
 }
 
-func BuildQuery(logger log.Logger, query string, flowJobName string) (string, error) {
-	tmpl, err := template.New("query").Parse(query)
+// XminLag returns how many xids the oldest transaction still running on the source is ahead of lastXmin,
+// both epoch-qualified so that the difference is unaffected by the 32-bit xids wrapping around.
+func (c *PostgresConnector) XminLag(ctx context.Context, lastXmin int64) (int64, error) {
+	var currentXmin int64
+	err := c.conn.QueryRow(ctx, "SELECT txid_snapshot_xmin(txid_current_snapshot())").Scan(&currentXmin)
 	if err != nil {
-		return "", err
+		return 0, fmt.Errorf("failed to get current snapshot xmin: %w", err)
 	}
+	return currentXmin - lastXmin, nil
+}
+
+// partitionRangeArgs returns the start and end of a partition as the arguments of its query.
+func partitionRangeArgs(partition *protos.QRepPartition) ([]interface{}, error) {
+	// Depending on the type of the range, convert the range into the correct type
+	switch x := partition.Range.Range.(type) {
+	case *protos.PartitionRange_IntRange:
+		return []interface{}{x.IntRange.Start, x.IntRange.End}, nil
+	case *protos.PartitionRange_TimestampRange:
+		return []interface{}{x.TimestampRange.Start.AsTime(), x.TimestampRange.End.AsTime()}, nil
+	case *protos.PartitionRange_TidRange:
+		return []interface{}{
+			pgtype.TID{
+				BlockNumber:  x.TidRange.Start.BlockNumber,
+				OffsetNumber: uint16(x.TidRange.Start.OffsetNumber),
+				Valid:        true,
+			},
+			pgtype.TID{
+				BlockNumber:  x.TidRange.End.BlockNumber,
+				OffsetNumber: uint16(x.TidRange.End.OffsetNumber),
+				Valid:        true,
+			},
+		}, nil
+	case *protos.PartitionRange_CompositeRange:
+		return append(partition_utils.CompositeKeyArgs(x.CompositeRange.Start),
+			partition_utils.CompositeKeyArgs(x.CompositeRange.End)...), nil
+	default:
+		return nil, fmt.Errorf("unknown range type: %v", x)
+	}
+}
+
+// buildPartitionQuery templates the query of a mirror for a partition,
+// the start and end of composite ranges are rows of a parameter per watermark column.
+func buildPartitionQuery(logger log.Logger, config *protos.QRepConfig, partition *protos.QRepPartition) (string, error) {
+	if compositeRange := partition.Range.GetCompositeRange(); compositeRange != nil {
+		numColumns := len(compositeRange.Start)
+		return executeQueryTemplate(logger, config.Query, map[string]interface{}{
+			"start": compositePlaceholders(1, numColumns),
+			"end":   compositePlaceholders(numColumns+1, numColumns),
+		})
+	}
+	return BuildQuery(logger, config.Query, config.FlowJobName)
+}
+
+// rowFilterQuery limits the rows of a query to those matching the row filter of a mirror.
+func rowFilterQuery(query string, config *protos.QRepConfig) string {
+	if config.RowFilter == "" {
+		return query
+	}
+	return fmt.Sprintf("SELECT * FROM (%s) peerdb_filtered WHERE %s", query, config.RowFilter)
+}
 
-	data := map[string]interface{}{
+func BuildQuery(logger log.Logger, query string, flowJobName string) (string, error) {
+	return executeQueryTemplate(logger, query, map[string]interface{}{
 		"start": "$1",
 		"end":   "$2",
+	})
+}
+
+func executeQueryTemplate(logger log.Logger, query string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New("query").Parse(query)
+	if err != nil {
+		return "", err
 	}
 
 	buf := new(bytes.Buffer)
@@ -573,21 +684,22 @@ func BuildQuery(logger log.Logger, query string, flowJobName string) (string, er
 	return res, nil
 }
 
-// isPartitionSynced checks whether a specific partition is synced
-func (c *PostgresConnector) isPartitionSynced(ctx context.Context, partitionID string) (bool, error) {
-	// setup the query string
+// IsQRepPartitionSynced checks whether a partition was marked synced, which is committed with its rows.
+func (c *PostgresConnector) IsQRepPartitionSynced(ctx context.Context, config *protos.QRepConfig, partitionID string) (bool, error) {
 	metadataTableIdentifier := pgx.Identifier{c.metadataSchema, qRepMetadataTableName}
-	queryString := fmt.Sprintf(
-		"SELECT COUNT(*)>0 FROM %s WHERE partitionID = $1;",
-		metadataTableIdentifier.Sanitize(),
-	)
+	var exists bool
+	// mirrors set up before the table existed have nothing marked
+	if err := c.conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL",
+		metadataTableIdentifier.Sanitize()).Scan(&exists); err != nil || !exists {
+		return false, err
+	}
 
-	// prepare and execute the query
 	var result bool
-	err := c.conn.QueryRow(ctx, queryString, partitionID).Scan(&result)
-	if err != nil {
+	if err := c.conn.QueryRow(ctx, fmt.Sprintf(
+		"SELECT COUNT(*)>0 FROM %s WHERE flowJobName = $1 AND partitionID = $2",
+		metadataTableIdentifier.Sanitize(),
+	), config.FlowJobName, partitionID).Scan(&result); err != nil {
 		return false, fmt.Errorf("failed to execute query: %w", err)
 	}
-
 	return result, nil
 }
@@ -0,0 +1,315 @@
+package connpostgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// watermarkStats is what ANALYZE knows about the distribution of the watermark column, timestamps as unix microseconds.
+// The histogram splits the values which aren't among the most common ones into buckets of equal row counts.
+type watermarkStats struct {
+	nullFrac        float64
+	histogramBounds []int64
+	mostCommonVals  []int64
+	mostCommonFreqs []float64
+}
+
+// getAdaptivePartitions splits the watermark table into ranges of roughly equal row counts estimated from
+// the statistics of the watermark column, so skewed columns don't end up with a few huge partitions.
+// Returns false when the column has no statistics or isn't an integer or timestamp column.
+func (c *PostgresConnector) getAdaptivePartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	config *protos.QRepConfig,
+	watermarkTable *utils.SchemaTable,
+) ([]*protos.QRepPartition, bool, error) {
+	var relTuples float64
+	err := tx.QueryRow(ctx, "SELECT reltuples FROM pg_class WHERE oid = $1::regclass",
+		watermarkTable.String()).Scan(&relTuples)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get statistics of table %s: %w", watermarkTable, err)
+	}
+	if relTuples <= 0 {
+		c.logger.Info(fmt.Sprintf("table %s has no statistics, partitioning by row counts", watermarkTable))
+		return nil, false, nil
+	}
+
+	var columnType string
+	err = tx.QueryRow(ctx, `SELECT atttypid::regtype::text FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attname = $2 AND NOT attisdropped`,
+		watermarkTable.String(), config.WatermarkColumn).Scan(&columnType)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get type of watermark column %s: %w", config.WatermarkColumn, err)
+	}
+	var isTimestamp bool
+	switch columnType {
+	case "smallint", "integer", "bigint":
+	case "timestamp without time zone", "timestamp with time zone":
+		isTimestamp = true
+	default:
+		c.logger.Info(fmt.Sprintf("watermark column %s is of type %s, partitioning by row counts",
+			config.WatermarkColumn, columnType))
+		return nil, false, nil
+	}
+
+	stats, ok, err := getWatermarkStats(ctx, tx, watermarkTable, config.WatermarkColumn, columnType, isTimestamp)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		c.logger.Info(fmt.Sprintf("watermark column %s has no statistics, partitioning by row counts",
+			config.WatermarkColumn))
+		return nil, false, nil
+	}
+
+	quotedWatermarkColumn := QuoteIdentifier(config.WatermarkColumn)
+	minMaxQuery := fmt.Sprintf("SELECT MIN(%[1]s), MAX(%[1]s) FROM %[2]s", quotedWatermarkColumn, watermarkTable.String())
+	var minVal, maxVal int64
+	if isTimestamp {
+		var minTime, maxTime *time.Time
+		if err := tx.QueryRow(ctx, minMaxQuery).Scan(&minTime, &maxTime); err != nil {
+			return nil, false, fmt.Errorf("failed to get range of watermark column %s: %w", config.WatermarkColumn, err)
+		}
+		if minTime == nil || maxTime == nil {
+			return nil, true, nil
+		}
+		minVal, maxVal = minTime.UnixMicro(), maxTime.UnixMicro()
+	} else {
+		var minInt, maxInt *int64
+		if err := tx.QueryRow(ctx, minMaxQuery).Scan(&minInt, &maxInt); err != nil {
+			return nil, false, fmt.Errorf("failed to get range of watermark column %s: %w", config.WatermarkColumn, err)
+		}
+		if minInt == nil || maxInt == nil {
+			return nil, true, nil
+		}
+		minVal, maxVal = *minInt, *maxInt
+	}
+
+	splits := adaptiveSplits(minVal, maxVal, stats, relTuples, int64(config.NumRowsPerPartition))
+	c.logger.Info(fmt.Sprintf("split watermark column %s of table %s into %d partitions from its statistics",
+		config.WatermarkColumn, watermarkTable, len(splits)+1))
+	return splitPartitions(config, c.config.TransactionSnapshot, watermarkTable, minVal, maxVal, splits, isTimestamp), true, nil
+}
+
+func getWatermarkStats(
+	ctx context.Context,
+	tx pgx.Tx,
+	table *utils.SchemaTable,
+	column string,
+	columnType string,
+	isTimestamp bool,
+) (watermarkStats, bool, error) {
+	// anyarray columns of pg_stats can only be read after casting them through text
+	query := fmt.Sprintf(`SELECT null_frac, histogram_bounds::text::%[1]s[], most_common_vals::text::%[1]s[],
+		most_common_freqs FROM pg_stats WHERE schemaname = $1 AND tablename = $2 AND attname = $3
+		ORDER BY inherited DESC LIMIT 1`, columnType)
+
+	var stats watermarkStats
+	var mostCommonFreqs []float32
+	var err error
+	if isTimestamp {
+		var histogramBounds, mostCommonVals []time.Time
+		err = tx.QueryRow(ctx, query, table.Schema, table.Table, column).Scan(
+			&stats.nullFrac, &histogramBounds, &mostCommonVals, &mostCommonFreqs)
+		stats.histogramBounds = unixMicros(histogramBounds)
+		stats.mostCommonVals = unixMicros(mostCommonVals)
+	} else {
+		err = tx.QueryRow(ctx, query, table.Schema, table.Table, column).Scan(
+			&stats.nullFrac, &stats.histogramBounds, &stats.mostCommonVals, &mostCommonFreqs)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return stats, false, nil
+	} else if err != nil {
+		return stats, false, fmt.Errorf("failed to get statistics of watermark column %s: %w", column, err)
+	}
+
+	for _, freq := range mostCommonFreqs {
+		stats.mostCommonFreqs = append(stats.mostCommonFreqs, float64(freq))
+	}
+	return stats, len(stats.histogramBounds) >= 2 || len(stats.mostCommonVals) != 0, nil
+}
+
+func unixMicros(times []time.Time) []int64 {
+	micros := make([]int64, 0, len(times))
+	for _, t := range times {
+		micros = append(micros, t.UnixMicro())
+	}
+	return micros
+}
+
+// adaptiveSplits estimates the distribution of the watermark column between minVal and maxVal from its statistics
+// and returns the values starting each partition after the first, at even steps of that distribution.
+// Histogram buckets hold their rows evenly, most common values are points, and values beyond the histogram,
+// written since the last ANALYZE, are assumed as dense as the histogram on average.
+func adaptiveSplits(minVal int64, maxVal int64, stats watermarkStats, relTuples float64, rowsPerPartition int64) []int64 {
+	type segment struct {
+		lo, hi float64
+		mass   float64
+	}
+	var segments []segment
+	pointMass := make(map[float64]float64)
+
+	histogramMass := 1 - stats.nullFrac
+	for _, freq := range stats.mostCommonFreqs {
+		histogramMass -= freq
+	}
+	if bounds := stats.histogramBounds; len(bounds) >= 2 && histogramMass > 0 {
+		bucketMass := histogramMass / float64(len(bounds)-1)
+		for i := range len(bounds) - 1 {
+			lo, hi := float64(bounds[i]), float64(bounds[i+1])
+			if hi > lo {
+				segments = append(segments, segment{lo: lo, hi: hi, mass: bucketMass})
+			} else {
+				pointMass[lo] += bucketMass
+			}
+		}
+
+		first, last := float64(bounds[0]), float64(bounds[len(bounds)-1])
+		if last > first {
+			density := histogramMass / (last - first)
+			if lo := float64(minVal); lo < first {
+				segments = append(segments, segment{lo: lo, hi: first, mass: density * (first - lo)})
+			}
+			if hi := float64(maxVal); hi > last {
+				segments = append(segments, segment{lo: last, hi: hi, mass: density * (hi - last)})
+			}
+		}
+	}
+	for i, val := range stats.mostCommonVals {
+		if i < len(stats.mostCommonFreqs) {
+			pointMass[float64(val)] += stats.mostCommonFreqs[i]
+		}
+	}
+
+	// sweep over every bound and point in order, density is constant between consecutive ones
+	densityDelta := make(map[float64]float64)
+	var totalMass float64
+	for _, seg := range segments {
+		densityDelta[seg.lo] += seg.mass / (seg.hi - seg.lo)
+		densityDelta[seg.hi] -= seg.mass / (seg.hi - seg.lo)
+		totalMass += seg.mass
+	}
+	for _, mass := range pointMass {
+		totalMass += mass
+	}
+	numPartitions := int64(math.Ceil(relTuples * totalMass / float64(rowsPerPartition)))
+	if numPartitions <= 1 || totalMass <= 0 {
+		return nil
+	}
+
+	positions := make([]float64, 0, len(densityDelta)+len(pointMass))
+	for pos := range densityDelta {
+		positions = append(positions, pos)
+	}
+	for pos := range pointMass {
+		positions = append(positions, pos)
+	}
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+
+	maxSplits := int(numPartitions - 1)
+	splits := make([]int64, 0, maxSplits)
+	addSplit := func(split int64) {
+		if split > minVal && split <= maxVal && (len(splits) == 0 || split > splits[len(splits)-1]) {
+			splits = append(splits, split)
+		}
+	}
+
+	step := totalMass / float64(numPartitions)
+	target := step
+	var cumulative, density float64
+	for i, pos := range positions {
+		density += densityDelta[pos]
+		// a common value goes to the partition which it fills the most
+		if mass := pointMass[pos]; mass > 0 {
+			for target < cumulative+mass && len(splits) < maxSplits {
+				if target-cumulative < mass/2 {
+					addSplit(floatToInt64(pos))
+				} else if split := floatToInt64(pos); split < math.MaxInt64 {
+					addSplit(split + 1)
+				}
+				target += step
+			}
+			cumulative += mass
+		}
+		if i+1 == len(positions) || density <= 0 {
+			continue
+		}
+		next := positions[i+1]
+		mass := density * (next - pos)
+		for target < cumulative+mass && len(splits) < maxSplits {
+			split := pos + (target-cumulative)/density
+			addSplit(min(max(floatToInt64(math.Round(split)), floatToInt64(pos)), floatToInt64(next)))
+			target += step
+		}
+		cumulative += mass
+	}
+	return splits
+}
+
+// floatToInt64 converts f without overflowing, the bounds of bigint columns aren't exact as floats
+func floatToInt64(f float64) int64 {
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	} else if f <= math.MinInt64 {
+		return math.MinInt64
+	}
+	return int64(f)
+}
+
+// splitPartitions turns the values splitting minVal to maxVal into partitions covering the values in between.
+func splitPartitions(
+	config *protos.QRepConfig,
+	snapshot string,
+	table *utils.SchemaTable,
+	minVal int64,
+	maxVal int64,
+	splits []int64,
+	isTimestamp bool,
+) []*protos.QRepPartition {
+	partitions := make([]*protos.QRepPartition, 0, len(splits)+1)
+	start := minVal
+	for i := range len(splits) + 1 {
+		end := maxVal
+		if i < len(splits) {
+			end = splits[i] - 1
+		}
+
+		partition := &protos.QRepPartition{}
+		if isTimestamp {
+			startTime, endTime := time.UnixMicro(start).UTC(), time.UnixMicro(end).UTC()
+			partition.PartitionId = rangePartitionID(config, snapshot, table, startTime, endTime)
+			partition.Range = &protos.PartitionRange{
+				Range: &protos.PartitionRange_TimestampRange{
+					TimestampRange: &protos.TimestampPartitionRange{
+						Start: timestamppb.New(startTime),
+						End:   timestamppb.New(endTime),
+					},
+				},
+			}
+		} else {
+			partition.PartitionId = rangePartitionID(config, snapshot, table, start, end)
+			partition.Range = &protos.PartitionRange{
+				Range: &protos.PartitionRange_IntRange{
+					IntRange: &protos.IntPartitionRange{Start: start, End: end},
+				},
+			}
+		}
+		partitions = append(partitions, partition)
+
+		if i < len(splits) {
+			start = splits[i]
+		}
+	}
+	return partitions
+}
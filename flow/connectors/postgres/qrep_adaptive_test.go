@@ -0,0 +1,65 @@
+package connpostgres
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestAdaptiveSplitsUniform(t *testing.T) {
+	stats := watermarkStats{histogramBounds: []int64{0, 250, 500, 750, 1000}}
+	require.Equal(t, []int64{250, 500, 750}, adaptiveSplits(0, 1000, stats, 1000, 250))
+	// a single partition needs no splits
+	require.Empty(t, adaptiveSplits(0, 1000, stats, 1000, 5000))
+}
+
+func TestAdaptiveSplitsSkewed(t *testing.T) {
+	// half the rows are below 10, the other half spread up to 1000
+	stats := watermarkStats{histogramBounds: []int64{0, 10, 1000}}
+	require.Equal(t, []int64{5, 10, 505}, adaptiveSplits(0, 1000, stats, 1000, 250))
+
+	// a value holding half the rows gets a partition of its own
+	stats = watermarkStats{
+		histogramBounds: []int64{0, 100},
+		mostCommonVals:  []int64{50},
+		mostCommonFreqs: []float64{0.5},
+	}
+	require.Equal(t, []int64{50, 51}, adaptiveSplits(0, 100, stats, 1000, 250))
+}
+
+func TestAdaptiveSplitsBeyondHistogram(t *testing.T) {
+	// rows written since the last ANALYZE are assumed as dense as the ones before
+	stats := watermarkStats{histogramBounds: []int64{0, 500, 1000}}
+	require.Equal(t, []int64{500, 1000, 1500}, adaptiveSplits(0, 2000, stats, 1000, 500))
+}
+
+func TestAdaptiveSplitsFullRange(t *testing.T) {
+	stats := watermarkStats{histogramBounds: []int64{math.MinInt64, 0, math.MaxInt64}}
+	require.Equal(t, []int64{-1 << 62, 0, 1 << 62}, adaptiveSplits(math.MinInt64, math.MaxInt64, stats, 1000, 250))
+}
+
+func TestSplitPartitions(t *testing.T) {
+	config := &protos.QRepConfig{FlowJobName: "clone_events"}
+	table := &utils.SchemaTable{Schema: "public", Table: "events"}
+
+	partitions := splitPartitions(config, "", table, 1, 100, []int64{10, 50}, false)
+	var ranges [][2]int64
+	for _, partition := range partitions {
+		intRange := partition.Range.GetIntRange()
+		ranges = append(ranges, [2]int64{intRange.Start, intRange.End})
+	}
+	require.Equal(t, [][2]int64{{1, 9}, {10, 49}, {50, 100}}, ranges)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	split := start.Add(time.Hour)
+	partitions = splitPartitions(config, "", table, start.UnixMicro(), start.Add(2*time.Hour).UnixMicro(),
+		[]int64{split.UnixMicro()}, true)
+	require.Len(t, partitions, 2)
+	require.Equal(t, split.Add(-time.Microsecond), partitions[0].Range.GetTimestampRange().End.AsTime())
+	require.Equal(t, split, partitions[1].Range.GetTimestampRange().Start.AsTime())
+}
@@ -0,0 +1,150 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	partition_utils "github.com/PeerDB-io/peer-flow/connectors/utils/partition"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// compositeWatermark returns the quoted watermark columns of a composite watermark, joined for use as a row.
+func compositeWatermark(config *protos.QRepConfig) string {
+	quotedColumns := make([]string, 0, len(config.WatermarkColumns))
+	for _, column := range config.WatermarkColumns {
+		quotedColumns = append(quotedColumns, QuoteIdentifier(column))
+	}
+	return strings.Join(quotedColumns, ", ")
+}
+
+// compositePlaceholders returns a row of numColumns query parameters, starting at $first.
+func compositePlaceholders(first int, numColumns int) string {
+	placeholders := make([]string, 0, numColumns)
+	for i := range numColumns {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", first+i))
+	}
+	return "ROW(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// getCompositePartitions splits the rows of a watermark table into partitions of about NumRowsPerPartition rows
+// in the lexicographic order of its watermark columns, starting after the end of the last partition.
+func (c *PostgresConnector) getCompositePartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	config *protos.QRepConfig,
+	watermarkTable *utils.SchemaTable,
+	last *protos.QRepPartition,
+) ([]*protos.QRepPartition, error) {
+	watermark := compositeWatermark(config)
+	numColumns := len(config.WatermarkColumns)
+
+	whereClause := ""
+	var args []interface{}
+	if lastRange := last.GetRange().GetCompositeRange(); lastRange != nil {
+		whereClause = fmt.Sprintf("WHERE ROW(%s) > %s", watermark, compositePlaceholders(1, numColumns))
+		args = partition_utils.CompositeKeyArgs(lastRange.End)
+	}
+
+	var totalRows int64
+	if err := tx.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s %s", watermarkTable, whereClause),
+		args...).Scan(&totalRows); err != nil {
+		return nil, fmt.Errorf("failed to query for total rows: %w", err)
+	}
+	if totalRows == 0 {
+		c.logger.Warn("no records to replicate, returning")
+		return nil, nil
+	}
+
+	numPartitions := int64(1)
+	if config.NumRowsPerPartition > 0 {
+		numPartitions = (totalRows + int64(config.NumRowsPerPartition) - 1) / int64(config.NumRowsPerPartition)
+	}
+	c.logger.Info(fmt.Sprintf("total rows: %d, num partitions: %d, composite watermark: (%s)",
+		totalRows, numPartitions, watermark))
+
+	descending := make([]string, 0, numColumns)
+	for _, column := range config.WatermarkColumns {
+		descending = append(descending, QuoteIdentifier(column)+" DESC")
+	}
+	// the first and last row of each bucket, in order. NTILE returns an integer, cast so buckets are read as int64
+	partitionsQuery := fmt.Sprintf(`SELECT bucket, %[2]s FROM (
+			SELECT bucket, %[2]s,
+				ROW_NUMBER() OVER (PARTITION BY bucket ORDER BY %[2]s) AS first_row,
+				ROW_NUMBER() OVER (PARTITION BY bucket ORDER BY %[3]s) AS last_row
+			FROM (SELECT NTILE(%[1]d) OVER (ORDER BY %[2]s)::bigint AS bucket, %[2]s FROM %[4]s %[5]s) buckets
+		) bounds WHERE first_row = 1 OR last_row = 1 ORDER BY bucket, %[2]s`,
+		numPartitions, watermark, strings.Join(descending, ", "), watermarkTable, whereClause)
+	rows, err := tx.Query(ctx, partitionsQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query for partitions: %w", err)
+	}
+	defer rows.Close()
+
+	partitionHelper := partition_utils.NewPartitionHelper()
+	var bucketStart []interface{}
+	prevBucket := int64(-1)
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		bucket, ok := values[0].(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected bucket %v", values[0])
+		}
+		if bucket != prevBucket {
+			// a bucket of a single row only has one bound
+			if bucketStart != nil {
+				if err := partitionHelper.AddCompositePartition(bucketStart, bucketStart); err != nil {
+					return nil, fmt.Errorf("failed to add partition: %w", err)
+				}
+			}
+			bucketStart = values[1:]
+			prevBucket = bucket
+		} else {
+			if err := partitionHelper.AddCompositePartition(bucketStart, values[1:]); err != nil {
+				return nil, fmt.Errorf("failed to add partition: %w", err)
+			}
+			bucketStart = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	if bucketStart != nil {
+		if err := partitionHelper.AddCompositePartition(bucketStart, bucketStart); err != nil {
+			return nil, fmt.Errorf("failed to add partition: %w", err)
+		}
+	}
+
+	return partitionHelper.GetPartitions(), nil
+}
+
+// checkForRowsAfterCompositeWatermark checks whether a watermark table has rows after the end of the last partition.
+func (c *PostgresConnector) checkForRowsAfterCompositeWatermark(
+	ctx context.Context,
+	config *protos.QRepConfig,
+	last *protos.QRepPartition,
+) (bool, error) {
+	lastRange := last.GetRange().GetCompositeRange()
+	if lastRange == nil {
+		return false, fmt.Errorf("unknown range type: %v", last.GetRange().GetRange())
+	}
+	watermarkTable, err := utils.ParseSchemaTable(config.WatermarkTable)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse watermark table: %w", err)
+	}
+
+	var exists bool
+	if err := c.conn.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE ROW(%s) > %s)",
+		watermarkTable, compositeWatermark(config), compositePlaceholders(1, len(config.WatermarkColumns))),
+		partition_utils.CompositeKeyArgs(lastRange.End)...,
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check for new rows: %w", err)
+	}
+	return exists, nil
+}
@@ -26,6 +26,8 @@ type QRepQueryExecutor struct {
 	flowJobName string
 	partitionID string
 	logger      log.Logger
+	// fields of the query being processed read from multidimensional array columns, which are replicated as JSON
+	multiDimArrayFields map[int]struct{}
 }
 
 func (c *PostgresConnector) NewQRepQueryExecutor(flowJobName string, partitionID string) *QRepQueryExecutor {
@@ -89,13 +91,16 @@ func (qe *QRepQueryExecutor) fieldDescriptionsToSchema(fds []pgconn.FieldDescrip
 		cname := fd.Name
 		ctype := qe.postgresOIDToQValueKind(fd.DataTypeOID)
 		if ctype == qvalue.QValueKindInvalid {
-			typeName, ok := qe.customTypesMapping[fd.DataTypeOID]
+			customType, ok := qe.customTypesMapping[fd.DataTypeOID]
 			if ok {
-				ctype = customTypeToQKind(typeName)
+				ctype = qe.customTypeToQKind(fd.DataTypeOID, customType)
 			} else {
 				ctype = qvalue.QValueKindString
 			}
 		}
+		if _, ok := qe.multiDimArrayFields[i]; ok {
+			ctype = qvalue.QValueKindJSON
+		}
 		// there isn't a way to know if a column is nullable or not
 		// TODO fix this.
 		cnullable := true
@@ -382,6 +387,26 @@ func (qe *QRepQueryExecutor) ExecuteAndProcessQueryStreamWithTx(
 		return 0, fmt.Errorf("[pg_query_executor] failed to generate random uint: %w", err)
 	}
 
+	// the dimensions of array columns aren't described by results, so they're looked up before fetching any
+	sd, err := tx.Conn().PgConn().Prepare(ctx, "", query, nil)
+	if err != nil {
+		stream.Records <- model.QRecordOrError{
+			Err: fmt.Errorf("failed to describe query: %w", err),
+		}
+		qe.logger.Error("[pg_query_executor] failed to describe query",
+			slog.Any("error", err), slog.String("query", query))
+		return 0, fmt.Errorf("[pg_query_executor] failed to describe query: %w", err)
+	}
+	qe.multiDimArrayFields, err = qe.getMultiDimArrayFields(ctx, tx, sd.Fields)
+	if err != nil {
+		stream.Records <- model.QRecordOrError{
+			Err: err,
+		}
+		qe.logger.Error("[pg_query_executor] failed to get array columns",
+			slog.Any("error", err), slog.String("query", query))
+		return 0, fmt.Errorf("[pg_query_executor] failed to get array columns: %w", err)
+	}
+
 	cursorName := fmt.Sprintf("peerdb_cursor_%d", randomUint)
 	fetchSize := shared.FetchAndChannelSize
 	cursorQuery := fmt.Sprintf("DECLARE %s CURSOR FOR %s", cursorName, query)
@@ -447,8 +472,19 @@ func (qe *QRepQueryExecutor) mapRowToQRecord(
 	}
 
 	for i, fd := range fds {
+		if _, ok := qe.multiDimArrayFields[i]; ok {
+			arr, err := decodeArray(qe.typeMap, fd.DataTypeOID, fd.Format, row.RawValues()[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse array field %s: %w", fd.Name, err)
+			}
+			record[i], err = arrayToJSON(arr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse array field %s: %w", fd.Name, err)
+			}
+			continue
+		}
 		// Check if it's a custom type first
-		typeName, ok := qe.customTypesMapping[fd.DataTypeOID]
+		customType, ok := qe.customTypesMapping[fd.DataTypeOID]
 		if !ok {
 			tmp, err := qe.parseFieldFromPostgresOID(fd.DataTypeOID, values[i])
 			if err != nil {
@@ -457,20 +493,29 @@ func (qe *QRepQueryExecutor) mapRowToQRecord(
 			}
 			record[i] = tmp
 		} else {
-			customQKind := customTypeToQKind(typeName)
+			customQKind := qe.customTypeToQKind(fd.DataTypeOID, customType)
 			if customQKind == qvalue.QValueKindGeography || customQKind == qvalue.QValueKindGeometry {
-				wkbString, ok := values[i].(string)
-				wkt, err := geo.GeoValidate(wkbString)
-				if err != nil || !ok {
-					values[i] = nil
+				if wkbString, ok := values[i].(string); ok {
+					wkt, err := geo.GeoValidate(wkbString)
+					if err != nil {
+						qe.logger.Warn("replicating invalid geometry as null",
+							slog.String(string(shared.FlowNameKey), qe.flowJobName), slog.String("column", fd.Name), slog.Any("error", err))
+						values[i] = nil
+					} else {
+						values[i] = wkt
+					}
 				} else {
-					values[i] = wkt
+					values[i] = nil
 				}
+			} else if customQKind == qvalue.QValueKindJSON && values[i] != nil {
+				// composites decode to a map of their fields
+				composite, err := parseJSON(values[i])
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse composite field %s: %w", fd.Name, err)
+				}
+				values[i] = composite.Value()
 			}
-			record[i] = qvalue.QValue{
-				Kind:  customQKind,
-				Value: values[i],
-			}
+			record[i] = qvalue.New(customQKind, values[i])
 		}
 	}
 
@@ -9,9 +9,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
 )
 
 func setupDB(t *testing.T) (*PostgresConnector, string) {
@@ -41,7 +41,7 @@ func setupDB(t *testing.T) (*PostgresConnector, string) {
 	return connector, schemaName
 }
 
-func teardownDB(t *testing.T, conn *pgx.Conn, schemaName string) {
+func teardownDB(t *testing.T, conn *ConnPool, schemaName string) {
 	t.Helper()
 
 	_, err := conn.Exec(context.Background(), fmt.Sprintf("DROP SCHEMA %s CASCADE;", schemaName))
@@ -82,8 +82,8 @@ func TestExecuteAndProcessQuery(t *testing.T) {
 		t.Fatalf("expected 1 record, got %v", len(batch.Records))
 	}
 
-	if batch.Records[0][1].Value != "testdata" {
-		t.Fatalf("expected 'testdata', got %v", batch.Records[0][0].Value)
+	if batch.Records[0][1].Value() != "testdata" {
+		t.Fatalf("expected 'testdata', got %v", batch.Records[0][0].Value())
 	}
 }
 
@@ -91,7 +91,7 @@ func TestAllDataTypes(t *testing.T) {
 	ctx := context.Background()
 	connector, schemaName := setupDB(t)
 	conn := connector.conn
-	defer conn.Close(ctx)
+	defer connector.Close()
 	defer teardownDB(t, conn, schemaName)
 
 	// Create a table that contains every data type we want to test
@@ -196,53 +196,105 @@ func TestAllDataTypes(t *testing.T) {
 	record := batch.Records[0]
 
 	expectedBool := true
-	if record[0].Value.(bool) != expectedBool {
-		t.Fatalf("expected %v, got %v", expectedBool, record[0].Value)
+	if record[0].Value().(bool) != expectedBool {
+		t.Fatalf("expected %v, got %v", expectedBool, record[0].Value())
 	}
 
 	expectedInt4 := int32(2)
-	if record[1].Value.(int32) != expectedInt4 {
-		t.Fatalf("expected %v, got %v", expectedInt4, record[1].Value)
+	if record[1].Value().(int32) != expectedInt4 {
+		t.Fatalf("expected %v, got %v", expectedInt4, record[1].Value())
 	}
 
 	expectedInt8 := int64(3)
-	if record[2].Value.(int64) != expectedInt8 {
-		t.Fatalf("expected %v, got %v", expectedInt8, record[2].Value)
+	if record[2].Value().(int64) != expectedInt8 {
+		t.Fatalf("expected %v, got %v", expectedInt8, record[2].Value())
 	}
 
 	expectedFloat4 := float32(1.1)
-	if record[3].Value.(float32) != expectedFloat4 {
-		t.Fatalf("expected %v, got %v", expectedFloat4, record[3].Value)
+	if record[3].Value().(float32) != expectedFloat4 {
+		t.Fatalf("expected %v, got %v", expectedFloat4, record[3].Value())
 	}
 
 	expectedFloat8 := float64(2.2)
-	if record[4].Value.(float64) != expectedFloat8 {
-		t.Fatalf("expected %v, got %v", expectedFloat8, record[4].Value)
+	if record[4].Value().(float64) != expectedFloat8 {
+		t.Fatalf("expected %v, got %v", expectedFloat8, record[4].Value())
 	}
 
 	expectedText := "text"
-	if record[5].Value.(string) != expectedText {
-		t.Fatalf("expected %v, got %v", expectedText, record[5].Value)
+	if record[5].Value().(string) != expectedText {
+		t.Fatalf("expected %v, got %v", expectedText, record[5].Value())
 	}
 
 	expectedBytea := []byte("bytea")
-	if !bytes.Equal(record[6].Value.([]byte), expectedBytea) {
-		t.Fatalf("expected %v, got %v", expectedBytea, record[6].Value)
+	if !bytes.Equal(record[6].Value().([]byte), expectedBytea) {
+		t.Fatalf("expected %v, got %v", expectedBytea, record[6].Value())
 	}
 
 	expectedJSON := `{"key":"value"}`
-	if record[7].Value.(string) != expectedJSON {
-		t.Fatalf("expected %v, got %v", expectedJSON, record[7].Value)
+	if record[7].Value().(string) != expectedJSON {
+		t.Fatalf("expected %v, got %v", expectedJSON, record[7].Value())
 	}
 
-	actualUUID := record[8].Value.([16]uint8)
+	actualUUID := record[8].Value().([16]uint8)
 	if !bytes.Equal(actualUUID[:], savedUUID[:]) {
 		t.Fatalf("expected %v, got %v", savedUUID, actualUUID)
 	}
 
 	expectedNumeric := "123.456"
-	actualNumeric := record[10].Value.(*big.Rat).FloatString(3)
+	actualNumeric := record[10].Value().(*big.Rat).FloatString(3)
 	if actualNumeric != expectedNumeric {
 		t.Fatalf("expected %v, got %v", expectedNumeric, actualNumeric)
 	}
 }
+
+func TestCustomDataTypes(t *testing.T) {
+	ctx := context.Background()
+	connector, schemaName := setupDB(t)
+	conn := connector.conn
+	defer connector.Close()
+	defer teardownDB(t, conn, schemaName)
+
+	_, err := conn.Exec(ctx, fmt.Sprintf(`
+	CREATE TYPE %[1]s.mood AS ENUM ('sad', 'happy');
+	CREATE DOMAIN %[1]s.positive AS INT CHECK (VALUE > 0);
+	CREATE TYPE %[1]s.pair AS (label TEXT, mood %[1]s.mood);
+	CREATE TABLE %[1]s.test(m %[1]s.mood, p %[1]s.positive, c %[1]s.pair);
+	INSERT INTO %[1]s.test VALUES ('happy', 7, ROW('a b', 'sad'));`, schemaName))
+	if err != nil {
+		t.Fatalf("error while creating custom types: %v", err)
+	}
+
+	// custom types are loaded when connecting
+	typedConnector, err := NewPostgresConnector(ctx, connector.config)
+	if err != nil {
+		t.Fatalf("unable to create connector: %v", err)
+	}
+	defer typedConnector.Close()
+
+	qe := typedConnector.NewQRepQueryExecutor("test flow", "test part")
+	batch, err := qe.ExecuteAndProcessQuery(ctx, fmt.Sprintf("SELECT * FROM %s.test;", schemaName))
+	if err != nil {
+		t.Fatalf("error while executing and processing query: %v", err)
+	}
+	if len(batch.Records) != 1 {
+		t.Fatalf("expected 1 record, got %v", len(batch.Records))
+	}
+
+	kinds := []qvalue.QValueKind{qvalue.QValueKindString, qvalue.QValueKindInt32, qvalue.QValueKindJSON}
+	for i, field := range batch.Schema.Fields {
+		if field.Type != kinds[i] {
+			t.Fatalf("expected %s to be %v, got %v", field.Name, kinds[i], field.Type)
+		}
+	}
+
+	record := batch.Records[0]
+	if record[0].Value() != "happy" {
+		t.Fatalf("expected happy, got %v", record[0].Value())
+	}
+	if record[1].Value() != int32(7) {
+		t.Fatalf("expected 7, got %v", record[1].Value())
+	}
+	if expectedJSON := `{"label":"a b","mood":"sad"}`; record[2].Value() != expectedJSON {
+		t.Fatalf("expected %v, got %v", expectedJSON, record[2].Value())
+	}
+}
@@ -0,0 +1,144 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// getRangePartitions splits the watermark table into ranges sized from table statistics instead of scanning it,
+// heap block ranges when the watermark column is ctid and value ranges for integer watermark columns.
+// Returns false when the table can't be split this way, so partitions are computed by row counts instead.
+func (c *PostgresConnector) getRangePartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	config *protos.QRepConfig,
+	watermarkTable *utils.SchemaTable,
+) ([]*protos.QRepPartition, bool, error) {
+	var relPages int64
+	var relTuples float64
+	var numBlocks int64
+	err := tx.QueryRow(ctx, `SELECT relpages, reltuples,
+		pg_relation_size(oid) / current_setting('block_size')::int FROM pg_class WHERE oid = $1::regclass`,
+		watermarkTable.String()).Scan(&relPages, &relTuples, &numBlocks)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get statistics of table %s: %w", watermarkTable, err)
+	}
+	if relPages <= 0 || relTuples <= 0 {
+		c.logger.Info(fmt.Sprintf("table %s has no statistics, partitioning by row counts", watermarkTable))
+		return nil, false, nil
+	}
+
+	if config.WatermarkColumn == "ctid" {
+		// ctid ranges are read with a full scan per partition before TID range scans
+		supportsTidRangeScan, _, err := c.MajorVersionCheck(ctx, POSTGRES_14)
+		if err != nil {
+			return nil, false, fmt.Errorf("error checking Postgres version: %w", err)
+		}
+		if !supportsTidRangeScan {
+			c.logger.Info("ctid range partitions need Postgres 14+, partitioning by row counts")
+			return nil, false, nil
+		}
+		rowsPerBlock := relTuples / float64(relPages)
+		blocksPerPartition := max(int64(float64(config.NumRowsPerPartition)/rowsPerBlock), 1)
+		return blockRangePartitions(config, c.config.TransactionSnapshot, watermarkTable, numBlocks, blocksPerPartition), true, nil
+	}
+
+	var columnType string
+	err = tx.QueryRow(ctx, `SELECT atttypid::regtype::text FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attname = $2 AND NOT attisdropped`,
+		watermarkTable.String(), config.WatermarkColumn).Scan(&columnType)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get type of watermark column %s: %w", config.WatermarkColumn, err)
+	}
+	if columnType != "smallint" && columnType != "integer" && columnType != "bigint" {
+		c.logger.Info(fmt.Sprintf("watermark column %s is of type %s, partitioning by row counts",
+			config.WatermarkColumn, columnType))
+		return nil, false, nil
+	}
+
+	var minVal, maxVal *int64
+	quotedWatermarkColumn := QuoteIdentifier(config.WatermarkColumn)
+	err = tx.QueryRow(ctx, fmt.Sprintf("SELECT MIN(%[1]s), MAX(%[1]s) FROM %[2]s",
+		quotedWatermarkColumn, watermarkTable.String())).Scan(&minVal, &maxVal)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get range of watermark column %s: %w", config.WatermarkColumn, err)
+	}
+	if minVal == nil || maxVal == nil {
+		return nil, true, nil
+	}
+	numPartitions := int64(math.Ceil(relTuples / float64(config.NumRowsPerPartition)))
+	return intRangePartitions(config, c.config.TransactionSnapshot, watermarkTable, *minVal, *maxVal, numPartitions), true, nil
+}
+
+// rangePartitionID derives a partition's id from its range, so recomputing partitions of the same
+// snapshot gives the same ids and destinations skip the partitions they already synced.
+func rangePartitionID(config *protos.QRepConfig, snapshot string, table *utils.SchemaTable, start any, end any) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID,
+		[]byte(fmt.Sprintf("%s/%s/%s/%v/%v", config.FlowJobName, snapshot, table, start, end))).String()
+}
+
+func blockRangePartitions(
+	config *protos.QRepConfig,
+	snapshot string,
+	table *utils.SchemaTable,
+	numBlocks int64,
+	blocksPerPartition int64,
+) []*protos.QRepPartition {
+	partitions := make([]*protos.QRepPartition, 0, numBlocks/blocksPerPartition+1)
+	for start := int64(0); start < numBlocks; start += blocksPerPartition {
+		end := min(start+blocksPerPartition, numBlocks) - 1
+		partitions = append(partitions, &protos.QRepPartition{
+			PartitionId: rangePartitionID(config, snapshot, table, start, end),
+			Range: &protos.PartitionRange{
+				Range: &protos.PartitionRange_TidRange{
+					TidRange: &protos.TIDPartitionRange{
+						Start: &protos.TID{BlockNumber: uint32(start), OffsetNumber: 0},
+						End:   &protos.TID{BlockNumber: uint32(end), OffsetNumber: math.MaxUint16},
+					},
+				},
+			},
+		})
+	}
+	return partitions
+}
+
+func intRangePartitions(
+	config *protos.QRepConfig,
+	snapshot string,
+	table *utils.SchemaTable,
+	minVal int64,
+	maxVal int64,
+	numPartitions int64,
+) []*protos.QRepPartition {
+	numPartitions = max(numPartitions, 1)
+	// offsets from minVal are unsigned, the span of a bigint column can overflow int64
+	span := uint64(maxVal) - uint64(minVal)
+	width := span/uint64(numPartitions) + 1
+
+	partitions := make([]*protos.QRepPartition, 0, numPartitions)
+	for offset := uint64(0); ; offset += width {
+		endOffset := span
+		if span-offset >= width {
+			endOffset = offset + width - 1
+		}
+		start, end := int64(uint64(minVal)+offset), int64(uint64(minVal)+endOffset)
+		partitions = append(partitions, &protos.QRepPartition{
+			PartitionId: rangePartitionID(config, snapshot, table, start, end),
+			Range: &protos.PartitionRange{
+				Range: &protos.PartitionRange_IntRange{
+					IntRange: &protos.IntPartitionRange{Start: start, End: end},
+				},
+			},
+		})
+		if endOffset == span {
+			return partitions
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package connpostgres
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestBlockRangePartitions(t *testing.T) {
+	config := &protos.QRepConfig{FlowJobName: "clone_events"}
+	table := &utils.SchemaTable{Schema: "public", Table: "events"}
+
+	partitions := blockRangePartitions(config, "snap", table, 25, 10)
+	require.Len(t, partitions, 3)
+	var blocks [][2]uint32
+	for _, partition := range partitions {
+		tidRange := partition.Range.GetTidRange()
+		require.Equal(t, uint32(0), tidRange.Start.OffsetNumber)
+		require.Equal(t, uint32(math.MaxUint16), tidRange.End.OffsetNumber)
+		blocks = append(blocks, [2]uint32{tidRange.Start.BlockNumber, tidRange.End.BlockNumber})
+	}
+	require.Equal(t, [][2]uint32{{0, 9}, {10, 19}, {20, 24}}, blocks)
+
+	// ids are stable for the same snapshot, and differ for another one
+	require.Equal(t, partitions[1].PartitionId, blockRangePartitions(config, "snap", table, 25, 10)[1].PartitionId)
+	require.NotEqual(t, partitions[1].PartitionId, blockRangePartitions(config, "other", table, 25, 10)[1].PartitionId)
+}
+
+func TestIntRangePartitions(t *testing.T) {
+	config := &protos.QRepConfig{FlowJobName: "clone_events"}
+	table := &utils.SchemaTable{Schema: "public", Table: "events"}
+
+	ranges := func(partitions []*protos.QRepPartition) [][2]int64 {
+		var res [][2]int64
+		for _, partition := range partitions {
+			intRange := partition.Range.GetIntRange()
+			res = append(res, [2]int64{intRange.Start, intRange.End})
+		}
+		return res
+	}
+
+	require.Equal(t, [][2]int64{{1, 34}, {35, 68}, {69, 100}}, ranges(intRangePartitions(config, "", table, 1, 100, 3)))
+	require.Equal(t, [][2]int64{{5, 5}}, ranges(intRangePartitions(config, "", table, 5, 5, 4)))
+	require.Equal(t, [][2]int64{{math.MinInt64, -1}, {0, math.MaxInt64}},
+		ranges(intRangePartitions(config, "", table, math.MinInt64, math.MaxInt64, 2)))
+}
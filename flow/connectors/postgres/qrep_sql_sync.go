@@ -44,7 +44,6 @@ func (s *QRepStagingTableSync) SyncQRepRecords(
 		slog.String(string(shared.PartitionIDKey), partition.PartitionId),
 		slog.String("destinationTable", dstTableName.String()),
 	)
-	partitionID := partition.PartitionId
 	startTime := time.Now()
 	schema, err := stream.Schema()
 	if err != nil {
@@ -52,20 +51,8 @@ func (s *QRepStagingTableSync) SyncQRepRecords(
 		return 0, fmt.Errorf("failed to get schema from stream: %w", err)
 	}
 
-	txConfig := s.connector.conn.Config()
-	txConn, err := pgx.ConnectConfig(ctx, txConfig)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create tx pool: %w", err)
-	}
-	defer txConn.Close(ctx)
-
-	err = utils.RegisterHStore(ctx, txConn)
-	if err != nil {
-		return 0, fmt.Errorf("failed to register hstore: %w", err)
-	}
-
 	// Second transaction - to handle rest of the processing
-	tx, err := txConn.Begin(ctx)
+	tx, err := s.connector.conn.Begin(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -192,29 +179,17 @@ func (s *QRepStagingTableSync) SyncQRepRecords(
 
 	s.connector.logger.Info(fmt.Sprintf("pushed %d records to %s", numRowsSynced, dstTableName), syncLog)
 
-	// marshal the partition to json using protojson
+	// marking the partition synced commits with its rows, so a retry after a crash never appends them twice
 	pbytes, err := protojson.Marshal(partition)
 	if err != nil {
 		return -1, fmt.Errorf("failed to marshal partition to json: %v", err)
 	}
-
 	metadataTableIdentifier := pgx.Identifier{s.connector.metadataSchema, qRepMetadataTableName}
-	insertMetadataStmt := fmt.Sprintf(
-		"INSERT INTO %s VALUES ($1, $2, $3, $4, $5);",
-		metadataTableIdentifier.Sanitize(),
-	)
-	s.connector.logger.Info("Executing transaction inside Qrep sync", syncLog)
-	_, err = tx.Exec(
-		context.Background(),
-		insertMetadataStmt,
-		flowJobName,
-		partitionID,
-		string(pbytes),
-		startTime,
-		time.Now(),
-	)
+	_, err = tx.Exec(context.Background(),
+		fmt.Sprintf("INSERT INTO %s VALUES ($1, $2, $3, $4, $5)", metadataTableIdentifier.Sanitize()),
+		flowJobName, partition.PartitionId, string(pbytes), startTime, time.Now())
 	if err != nil {
-		return -1, fmt.Errorf("failed to execute statements in a transaction: %v", err)
+		return -1, fmt.Errorf("failed to mark partition synced: %v", err)
 	}
 
 	err = tx.Commit(context.Background())
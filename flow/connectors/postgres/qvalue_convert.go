@@ -37,7 +37,7 @@ func (c *PostgresConnector) postgresOIDToQValueKind(recvOID uint32) qvalue.QValu
 		return qvalue.QValueKindString
 	case pgtype.ByteaOID:
 		return qvalue.QValueKindBytes
-	case pgtype.JSONOID, pgtype.JSONBOID:
+	case pgtype.JSONOID, pgtype.JSONBOID, pgtype.JSONArrayOID, pgtype.JSONBArrayOID:
 		return qvalue.QValueKindJSON
 	case pgtype.UUIDOID:
 		return qvalue.QValueKindUUID
@@ -81,6 +81,9 @@ func (c *PostgresConnector) postgresOIDToQValueKind(recvOID uint32) qvalue.QValu
 		return qvalue.QValueKindArrayTimestampTZ
 	case pgtype.TextArrayOID, pgtype.VarcharArrayOID, pgtype.BPCharArrayOID:
 		return qvalue.QValueKindArrayString
+	case pgtype.Int4rangeOID, pgtype.Int8rangeOID, pgtype.NumrangeOID,
+		pgtype.TsrangeOID, pgtype.TstzrangeOID, pgtype.DaterangeOID:
+		return rangeOIDToQValueKind[recvOID]
 	default:
 		typeName, ok := pgtype.NewMap().TypeForOID(recvOID)
 		if !ok {
@@ -159,6 +162,18 @@ func qValueKindToPostgresType(colTypeStr string) string {
 		return "CIDR"
 	case qvalue.QValueKindMacaddr:
 		return "MACADDR"
+	case qvalue.QValueKindInt4Range:
+		return "INT4RANGE"
+	case qvalue.QValueKindInt8Range:
+		return "INT8RANGE"
+	case qvalue.QValueKindNumRange:
+		return "NUMRANGE"
+	case qvalue.QValueKindTsRange:
+		return "TSRANGE"
+	case qvalue.QValueKindTsTzRange:
+		return "TSTZRANGE"
+	case qvalue.QValueKindDateRange:
+		return "DATERANGE"
 	case qvalue.QValueKindArrayInt16:
 		return "SMALLINT[]"
 	case qvalue.QValueKindArrayInt32:
@@ -195,19 +210,19 @@ func parseJSON(value interface{}) (qvalue.QValue, error) {
 	if err != nil {
 		return qvalue.QValue{}, fmt.Errorf("failed to parse JSON: %w", err)
 	}
-	return qvalue.QValue{Kind: qvalue.QValueKindJSON, Value: string(jsonVal)}, nil
+	return qvalue.New(qvalue.QValueKindJSON, string(jsonVal)), nil
 }
 
 func convertToArray[T any](kind qvalue.QValueKind, value interface{}) (qvalue.QValue, error) {
 	switch v := value.(type) {
 	case pgtype.Array[T]:
 		if v.Valid {
-			return qvalue.QValue{Kind: kind, Value: v.Elements}, nil
+			return qvalue.New(kind, v.Elements), nil
 		}
 	case []T:
-		return qvalue.QValue{Kind: kind, Value: v}, nil
+		return qvalue.New(kind, v), nil
 	case []interface{}:
-		return qvalue.QValue{Kind: kind, Value: utils.ArrayCastElements[T](v)}, nil
+		return qvalue.New(kind, utils.ArrayCastElements[T](v)), nil
 	}
 	return qvalue.QValue{}, fmt.Errorf("failed to parse array %s from %T: %v", kind, value, value)
 }
@@ -216,20 +231,15 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 	val := qvalue.QValue{}
 
 	if value == nil {
-		val = qvalue.QValue{Kind: qvalueKind, Value: nil}
+		val = qvalue.QValue{Kind: qvalueKind}
 		return val, nil
 	}
 
 	switch qvalueKind {
-	case qvalue.QValueKindTimestamp:
-		timestamp := value.(time.Time)
-		val = qvalue.QValue{Kind: qvalue.QValueKindTimestamp, Value: timestamp}
-	case qvalue.QValueKindTimestampTZ:
-		timestamp := value.(time.Time)
-		val = qvalue.QValue{Kind: qvalue.QValueKindTimestampTZ, Value: timestamp}
-	case qvalue.QValueKindDate:
-		date := value.(time.Time)
-		val = qvalue.QValue{Kind: qvalue.QValueKindDate, Value: date}
+	case qvalue.QValueKindTimestamp, qvalue.QValueKindTimestampTZ, qvalue.QValueKindDate:
+		if t, ok := value.(time.Time); ok {
+			val = qvalue.NewTime(qvalueKind, t)
+		}
 	case qvalue.QValueKindTime:
 		timeVal := value.(pgtype.Time)
 		if timeVal.Valid {
@@ -245,7 +255,7 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 			if err != nil {
 				return qvalue.QValue{}, fmt.Errorf("failed to parse time: %w", err)
 			}
-			val = qvalue.QValue{Kind: qvalue.QValueKindTime, Value: t}
+			val = qvalue.NewTime(qvalue.QValueKindTime, t)
 		}
 	case qvalue.QValueKindTimeTZ:
 		timeVal := value.(string)
@@ -258,11 +268,12 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 			return qvalue.QValue{}, fmt.Errorf("failed to parse time: %w", err)
 		}
 		t = t.AddDate(1970, 0, 0)
-		val = qvalue.QValue{Kind: qvalue.QValueKindTimeTZ, Value: t}
+		val = qvalue.NewTime(qvalue.QValueKindTimeTZ, t)
 
 	case qvalue.QValueKindBoolean:
-		boolVal := value.(bool)
-		val = qvalue.QValue{Kind: qvalue.QValueKindBoolean, Value: boolVal}
+		if boolVal, ok := value.(bool); ok {
+			val = qvalue.NewBool(qvalueKind, boolVal)
+		}
 	case qvalue.QValueKindJSON:
 		tmp, err := parseJSON(value)
 		if err != nil {
@@ -271,67 +282,74 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 		val = tmp
 	case qvalue.QValueKindInt16:
 		intVal := value.(int16)
-		val = qvalue.QValue{Kind: qvalue.QValueKindInt16, Value: int32(intVal)}
+		val = qvalue.NewInt32(qvalue.QValueKindInt16, int32(intVal))
 	case qvalue.QValueKindInt32:
-		intVal := value.(int32)
-		val = qvalue.QValue{Kind: qvalue.QValueKindInt32, Value: intVal}
+		if intVal, ok := value.(int32); ok {
+			val = qvalue.NewInt32(qvalueKind, intVal)
+		}
 	case qvalue.QValueKindInt64:
-		intVal := value.(int64)
-		val = qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: intVal}
+		if intVal, ok := value.(int64); ok {
+			val = qvalue.NewInt64(qvalueKind, intVal)
+		}
 	case qvalue.QValueKindFloat32:
-		floatVal := value.(float32)
-		val = qvalue.QValue{Kind: qvalue.QValueKindFloat32, Value: floatVal}
+		if floatVal, ok := value.(float32); ok {
+			val = qvalue.NewFloat32(qvalueKind, floatVal)
+		}
 	case qvalue.QValueKindFloat64:
-		floatVal := value.(float64)
-		val = qvalue.QValue{Kind: qvalue.QValueKindFloat64, Value: floatVal}
+		if floatVal, ok := value.(float64); ok {
+			val = qvalue.NewFloat64(qvalueKind, floatVal)
+		}
 	case qvalue.QValueKindQChar:
-		val = qvalue.QValue{Kind: qvalue.QValueKindQChar, Value: uint8(value.(rune))}
+		val = qvalue.NewUint8(qvalue.QValueKindQChar, uint8(value.(rune)))
 	case qvalue.QValueKindString:
 		// handling all unsupported types with strings as well for now.
-		val = qvalue.QValue{Kind: qvalue.QValueKindString, Value: fmt.Sprint(value)}
+		if _, ok := value.(string); ok {
+			val = qvalue.New(qvalue.QValueKindString, value)
+		} else {
+			val = qvalue.New(qvalue.QValueKindString, fmt.Sprint(value))
+		}
 	case qvalue.QValueKindUUID:
 		switch value.(type) {
 		case string:
-			val = qvalue.QValue{Kind: qvalue.QValueKindUUID, Value: value}
+			val = qvalue.New(qvalue.QValueKindUUID, value)
 		case [16]byte:
-			val = qvalue.QValue{Kind: qvalue.QValueKindUUID, Value: value}
+			val = qvalue.New(qvalue.QValueKindUUID, value)
 		default:
 			return qvalue.QValue{}, fmt.Errorf("failed to parse UUID: %v", value)
 		}
 	case qvalue.QValueKindINET:
 		switch value.(type) {
 		case string:
-			val = qvalue.QValue{Kind: qvalue.QValueKindINET, Value: value}
+			val = qvalue.New(qvalue.QValueKindINET, value)
 		case [16]byte:
-			val = qvalue.QValue{Kind: qvalue.QValueKindINET, Value: value}
+			val = qvalue.New(qvalue.QValueKindINET, value)
 		default:
 			return qvalue.QValue{}, fmt.Errorf("failed to parse INET: %v", value)
 		}
 	case qvalue.QValueKindCIDR:
 		switch value.(type) {
 		case string:
-			val = qvalue.QValue{Kind: qvalue.QValueKindCIDR, Value: value}
+			val = qvalue.New(qvalue.QValueKindCIDR, value)
 		case [16]byte:
-			val = qvalue.QValue{Kind: qvalue.QValueKindCIDR, Value: value}
+			val = qvalue.New(qvalue.QValueKindCIDR, value)
 		default:
 			return qvalue.QValue{}, fmt.Errorf("failed to parse CIDR: %v", value)
 		}
 	case qvalue.QValueKindMacaddr:
 		switch value.(type) {
 		case string:
-			val = qvalue.QValue{Kind: qvalue.QValueKindMacaddr, Value: value}
+			val = qvalue.New(qvalue.QValueKindMacaddr, value)
 		case [16]byte:
-			val = qvalue.QValue{Kind: qvalue.QValueKindMacaddr, Value: value}
+			val = qvalue.New(qvalue.QValueKindMacaddr, value)
 		default:
 			return qvalue.QValue{}, fmt.Errorf("failed to parse MACADDR: %v", value)
 		}
 	case qvalue.QValueKindBytes:
-		rawBytes := value.([]byte)
-		val = qvalue.QValue{Kind: qvalue.QValueKindBytes, Value: rawBytes}
+		val = boxedQValue[[]byte](qvalueKind, value)
 	case qvalue.QValueKindBit:
 		bitsVal := value.(pgtype.Bits)
 		if bitsVal.Valid {
-			val = qvalue.QValue{Kind: qvalue.QValueKindBit, Value: bitsVal.Bytes}
+			val = qvalue.New(qvalue.QValueKindBit, bitsVal.Bytes)
 		}
 	case qvalue.QValueKindNumeric:
 		numVal := value.(pgtype.Numeric)
@@ -340,7 +358,7 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 			if err != nil {
 				return qvalue.QValue{}, fmt.Errorf("failed to convert numeric [%v] to rat: %w", value, err)
 			}
-			val = qvalue.QValue{Kind: qvalue.QValueKindNumeric, Value: rat}
+			val = qvalue.New(qvalue.QValueKindNumeric, rat)
 		}
 	case qvalue.QValueKindArrayFloat32:
 		return convertToArray[float32](qvalueKind, value)
@@ -358,18 +376,19 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 		return convertToArray[bool](qvalueKind, value)
 	case qvalue.QValueKindArrayString:
 		return convertToArray[string](qvalueKind, value)
+	case qvalue.QValueKindInt4Range, qvalue.QValueKindInt8Range, qvalue.QValueKindNumRange,
+		qvalue.QValueKindTsRange, qvalue.QValueKindTsTzRange, qvalue.QValueKindDateRange:
+		rangeVal, err := rangeToString(qvalueKind, value)
+		if err != nil {
+			return qvalue.QValue{}, err
+		}
+		val = qvalue.New(qvalueKind, rangeVal)
 	case qvalue.QValueKindPoint:
 		xCoord := value.(pgtype.Point).P.X
 		yCoord := value.(pgtype.Point).P.Y
-		val = qvalue.QValue{
-			Kind:  qvalue.QValueKindPoint,
-			Value: fmt.Sprintf("POINT(%f %f)", xCoord, yCoord),
-		}
+		val = qvalue.New(qvalue.QValueKindPoint, fmt.Sprintf("POINT(%f %f)", xCoord, yCoord))
 	default:
-		textVal, ok := value.(string)
-		if ok {
-			val = qvalue.QValue{Kind: qvalue.QValueKindString, Value: textVal}
-		}
+		val = boxedQValue[string](qvalue.QValueKindString, value)
 	}
 
 	// parsing into pgtype failed.
@@ -379,6 +398,15 @@ func parseFieldFromQValueKind(qvalueKind qvalue.QValueKind, value interface{}) (
 	return val, nil
 }
 
+// boxedQValue returns a QValue of kind holding value as is when value is a T, or the zero QValue otherwise.
+// Strings and bytes are scanned boxed in an interface already, handing that on saves boxing them again for every row.
+func boxedQValue[T string | []byte](kind qvalue.QValueKind, value interface{}) qvalue.QValue {
+	if _, ok := value.(T); !ok {
+		return qvalue.QValue{}
+	}
+	return qvalue.New(kind, value)
+}
+
 func (c *PostgresConnector) parseFieldFromPostgresOID(oid uint32, value interface{}) (qvalue.QValue, error) {
 	return parseFieldFromQValueKind(c.postgresOIDToQValueKind(oid), value)
 }
@@ -410,9 +438,39 @@ func numericToRat(numVal *pgtype.Numeric) (*big.Rat, error) {
 	return nil, errors.New("invalid numeric")
 }
 
-func customTypeToQKind(typeName string) qvalue.QValueKind {
+func (c *PostgresConnector) customTypeToQKind(typeOID uint32, customType utils.CustomDataType) qvalue.QValueKind {
+	switch customType.Type {
+	case 'e':
+		return qvalue.QValueKindString
+	case 'd':
+		baseOID := c.domainBaseOID(typeOID)
+		if baseType, ok := c.customTypesMapping[baseOID]; ok {
+			return c.customTypeToQKind(baseOID, baseType)
+		}
+		if qValueKind := c.postgresOIDToQValueKind(baseOID); qValueKind != qvalue.QValueKindInvalid {
+			return qValueKind
+		}
+		return qvalue.QValueKindString
+	case 'b':
+		// arrays of composites pgx loaded are replicated as JSON, like the composites
+		if customType.ElemOID != 0 {
+			if isArrayOID(c.typeMap, typeOID) {
+				return qvalue.QValueKindJSON
+			}
+			return qvalue.QValueKindString
+		}
+	case 'c':
+		// composites pgx couldn't load are passed on as text
+		if dt, ok := c.typeMap.TypeForOID(typeOID); ok {
+			if _, ok := dt.Codec.(*pgtype.CompositeCodec); ok {
+				return qvalue.QValueKindJSON
+			}
+		}
+		return qvalue.QValueKindString
+	}
+
 	var qValueKind qvalue.QValueKind
-	switch typeName {
+	switch customType.Name {
 	case "geometry":
 		qValueKind = qvalue.QValueKindGeometry
 	case "geography":
@@ -424,3 +482,29 @@ func customTypeToQKind(typeName string) qvalue.QValueKind {
 	}
 	return qValueKind
 }
+
+// domainBaseOID follows domains down to the type they're built on, other types are returned as is.
+func (c *PostgresConnector) domainBaseOID(typeOID uint32) uint32 {
+	for {
+		customType, ok := c.customTypesMapping[typeOID]
+		if !ok || customType.Type != 'd' {
+			return typeOID
+		}
+		typeOID = customType.BaseOID
+	}
+}
+
+// customTypeMap returns a type map with the enums, domains, composites and their arrays registered on the connection,
+// for decoding composites and their fields apart from the connection.
+func (c *PostgresConnector) customTypeMap() *pgtype.Map {
+	typeMap := pgtype.NewMap()
+	for typeOID, customType := range c.customTypesMapping {
+		if customType.Type == 'b' && customType.ElemOID == 0 {
+			continue
+		}
+		if dt, ok := c.typeMap.TypeForOID(typeOID); ok {
+			typeMap.RegisterType(dt)
+		}
+	}
+	return typeMap
+}
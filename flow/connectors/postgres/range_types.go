@@ -0,0 +1,114 @@
+package connpostgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+var rangeOIDToQValueKind = map[uint32]qvalue.QValueKind{
+	pgtype.Int4rangeOID: qvalue.QValueKindInt4Range,
+	pgtype.Int8rangeOID: qvalue.QValueKindInt8Range,
+	pgtype.NumrangeOID:  qvalue.QValueKindNumRange,
+	pgtype.TsrangeOID:   qvalue.QValueKindTsRange,
+	pgtype.TstzrangeOID: qvalue.QValueKindTsTzRange,
+	pgtype.DaterangeOID: qvalue.QValueKindDateRange,
+}
+
+// rangeToString renders a range the way Postgres outputs it, e.g. [1,10), ["2024-01-01 00:00:00+00",) or empty,
+// so values read in binary by QRep match the text CDC reads. Timestamps with time zone are rendered in UTC.
+func rangeToString(kind qvalue.QValueKind, value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case pgtype.Range[any]:
+		if v.LowerType == pgtype.Empty {
+			return "empty", nil
+		}
+		var sb strings.Builder
+		if v.LowerType == pgtype.Inclusive {
+			sb.WriteByte('[')
+		} else {
+			sb.WriteByte('(')
+		}
+		if v.LowerType != pgtype.Unbounded {
+			bound, err := rangeBoundToString(kind, v.Lower)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(bound)
+		}
+		sb.WriteByte(',')
+		if v.UpperType != pgtype.Unbounded {
+			bound, err := rangeBoundToString(kind, v.Upper)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(bound)
+		}
+		if v.UpperType == pgtype.Inclusive {
+			sb.WriteByte(']')
+		} else {
+			sb.WriteByte(')')
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("failed to parse %s from %T", kind, value)
+	}
+}
+
+func rangeBoundToString(kind qvalue.QValueKind, bound any) (string, error) {
+	var s string
+	switch v := bound.(type) {
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case pgtype.Numeric:
+		text, err := v.Value()
+		if err != nil {
+			return "", fmt.Errorf("failed to render bound of %s: %w", kind, err)
+		}
+		s = text.(string)
+	case pgtype.InfinityModifier:
+		if v == pgtype.Infinity {
+			s = "infinity"
+		} else {
+			s = "-infinity"
+		}
+	case time.Time:
+		switch kind {
+		case qvalue.QValueKindDateRange:
+			s = v.Format(time.DateOnly)
+		case qvalue.QValueKindTsTzRange:
+			s = v.UTC().Format("2006-01-02 15:04:05.999999-07")
+		default:
+			s = v.Format("2006-01-02 15:04:05.999999")
+		}
+	default:
+		return "", fmt.Errorf("unexpected bound %T of %s", bound, kind)
+	}
+	return quoteRangeBound(s), nil
+}
+
+// quoteRangeBound quotes bounds containing characters which are special in range literals, like Postgres does.
+func quoteRangeBound(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\r\v\f,()[]\"\\") {
+		return s
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range s {
+		if c == '"' || c == '\\' {
+			sb.WriteRune(c)
+		}
+		sb.WriteRune(c)
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
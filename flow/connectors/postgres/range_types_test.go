@@ -0,0 +1,77 @@
+package connpostgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestRangeToString(t *testing.T) {
+	for _, tc := range []struct {
+		kind     qvalue.QValueKind
+		value    any
+		expected string
+	}{
+		{
+			kind:     qvalue.QValueKindInt4Range,
+			value:    pgtype.Range[any]{Lower: int32(1), Upper: int32(10), LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive, Valid: true},
+			expected: "[1,10)",
+		},
+		{
+			kind:     qvalue.QValueKindInt8Range,
+			value:    pgtype.Range[any]{Lower: int64(5), LowerType: pgtype.Exclusive, UpperType: pgtype.Unbounded, Valid: true},
+			expected: "(5,)",
+		},
+		{
+			kind:     qvalue.QValueKindInt4Range,
+			value:    pgtype.Range[any]{LowerType: pgtype.Empty, UpperType: pgtype.Empty, Valid: true},
+			expected: "empty",
+		},
+		{
+			kind: qvalue.QValueKindDateRange,
+			value: pgtype.Range[any]{
+				Lower:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Upper:     pgtype.Infinity,
+				LowerType: pgtype.Inclusive,
+				UpperType: pgtype.Exclusive,
+				Valid:     true,
+			},
+			expected: "[2024-01-01,infinity)",
+		},
+		{
+			kind: qvalue.QValueKindTsTzRange,
+			value: pgtype.Range[any]{
+				Lower:     time.Date(2024, 1, 1, 2, 0, 0, 0, time.FixedZone("", 2*60*60)),
+				Upper:     time.Date(2024, 1, 2, 0, 0, 0, 500000000, time.UTC),
+				LowerType: pgtype.Inclusive,
+				UpperType: pgtype.Inclusive,
+				Valid:     true,
+			},
+			expected: `["2024-01-01 00:00:00+00","2024-01-02 00:00:00.5+00"]`,
+		},
+		{
+			// text decoded by CDC is canonical already
+			kind:     qvalue.QValueKindNumRange,
+			value:    "[1.5,2.25)",
+			expected: "[1.5,2.25)",
+		},
+	} {
+		actual, err := rangeToString(tc.kind, tc.value)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, actual)
+	}
+
+	_, err := rangeToString(qvalue.QValueKindInt4Range, int32(1))
+	require.Error(t, err)
+}
+
+func TestQuoteRangeBound(t *testing.T) {
+	require.Equal(t, "42", quoteRangeBound("42"))
+	require.Equal(t, `""`, quoteRangeBound(""))
+	require.Equal(t, `"a b"`, quoteRangeBound("a b"))
+	require.Equal(t, `"a""b\\c"`, quoteRangeBound(`a"b\c`))
+}
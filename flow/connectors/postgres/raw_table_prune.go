@@ -0,0 +1,16 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// PruneRawTable deletes the rows of normalized batches from a mirror's raw table, implementing RawTablePruneConnector.
+func (c *PostgresConnector) PruneRawTable(ctx context.Context, flowJobName string, batchID int64) error {
+	rawTableIdentifier := getRawTableIdentifier(flowJobName)
+	if _, err := c.conn.Exec(ctx, fmt.Sprintf("DELETE FROM %s.%s WHERE _peerdb_batch_id <= $1",
+		c.metadataSchema, rawTableIdentifier), batchID); err != nil {
+		return fmt.Errorf("error pruning raw table: %w", err)
+	}
+	return nil
+}
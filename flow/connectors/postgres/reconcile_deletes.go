@@ -0,0 +1,101 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func (c *PostgresConnector) ScanPrimaryKeys(
+	ctx context.Context,
+	tableIdentifier string,
+	pkeyColumns []string,
+	softDeleteColName string,
+	fn func(key []qvalue.QValue) error,
+) error {
+	return c.ScanRows(ctx, tableIdentifier, pkeyColumns, softDeleteColName, fn)
+}
+
+func (c *PostgresConnector) ScanRows(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	softDeleteColName string,
+	fn func(row []qvalue.QValue) error,
+) error {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s", quoteColumns(columns),
+		QuoteIdentifier(parsedTable.Schema), QuoteIdentifier(parsedTable.Table))
+	if softDeleteColName != "" {
+		query += fmt.Sprintf(" WHERE NOT COALESCE(%s,false)", QuoteIdentifier(softDeleteColName))
+	}
+
+	qe := c.NewQRepQueryExecutor("", "")
+	rows, err := qe.ExecuteQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	fieldDescriptions := rows.FieldDescriptions()
+	for rows.Next() {
+		row, err := qe.mapRowToQRecord(rows, fieldDescriptions)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error scanning rows of table %s: %w", parsedTable, err)
+	}
+	return nil
+}
+
+func (c *PostgresConnector) DeleteRowsByPrimaryKey(
+	ctx context.Context,
+	tableIdentifier string,
+	pkeyColumns []string,
+	keys [][]qvalue.QValue,
+	softDeleteColName string,
+) (int64, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return 0, err
+	}
+
+	quotedPkeyColumns := make([]string, 0, len(pkeyColumns))
+	for _, col := range pkeyColumns {
+		quotedPkeyColumns = append(quotedPkeyColumns, QuoteIdentifier(col))
+	}
+	args := make([]any, 0, len(keys)*len(pkeyColumns))
+	for _, key := range keys {
+		for _, value := range key {
+			args = append(args, value.Value())
+		}
+	}
+	filter := utils.PrimaryKeyFilter(quotedPkeyColumns, len(keys), func(i int) string {
+		return "$" + strconv.Itoa(i+1)
+	})
+
+	var query string
+	if softDeleteColName != "" {
+		query = fmt.Sprintf("UPDATE %s.%s SET %s=true WHERE %s", QuoteIdentifier(parsedTable.Schema),
+			QuoteIdentifier(parsedTable.Table), QuoteIdentifier(softDeleteColName), filter)
+	} else {
+		query = fmt.Sprintf("DELETE FROM %s.%s WHERE %s", QuoteIdentifier(parsedTable.Schema),
+			QuoteIdentifier(parsedTable.Table), filter)
+	}
+	ct, err := c.conn.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting rows from table %s: %w", parsedTable, err)
+	}
+	return ct.RowsAffected(), nil
+}
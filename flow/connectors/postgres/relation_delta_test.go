@@ -0,0 +1,89 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/lib/pq/oid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func testColumnKind(dataType uint32) qvalue.QValueKind {
+	switch oid.Oid(dataType) {
+	case oid.T_int4:
+		return qvalue.QValueKindInt32
+	case oid.T_int8:
+		return qvalue.QValueKindInt64
+	case oid.T_text:
+		return qvalue.QValueKindString
+	default:
+		return qvalue.QValueKindInvalid
+	}
+}
+
+func testRelation(columns ...*protos.RelationMessageColumn) *protos.RelationMessage {
+	return &protos.RelationMessage{RelationId: 1, RelationName: "t", Columns: columns}
+}
+
+func TestDiffRelationMessages(t *testing.T) {
+	id := &protos.RelationMessageColumn{Name: "id", DataType: uint32(oid.T_int8), Attnum: 1}
+	prev := testRelation(id,
+		&protos.RelationMessageColumn{Name: "a", DataType: uint32(oid.T_int4), Attnum: 2},
+		&protos.RelationMessageColumn{Name: "b", DataType: uint32(oid.T_text), Attnum: 3},
+		&protos.RelationMessageColumn{Name: "c", DataType: uint32(oid.T_int4), Attnum: 4},
+	)
+
+	// a renamed to a2 and widened, b dropped and d added in its place, c dropped and added back under its name
+	delta := &protos.TableSchemaDelta{}
+	diffRelationMessages(delta, prev, testRelation(id,
+		&protos.RelationMessageColumn{Name: "a2", DataType: uint32(oid.T_int8), Attnum: 2},
+		&protos.RelationMessageColumn{Name: "d", DataType: uint32(oid.T_text), Attnum: 5},
+		&protos.RelationMessageColumn{Name: "c", DataType: uint32(oid.T_int4), Attnum: 6},
+	), testColumnKind)
+	require.Equal(t, []*protos.DeltaRenamedColumn{
+		{OldName: "a", NewName: "a2", ColumnType: string(qvalue.QValueKindInt64)},
+	}, delta.RenamedColumns)
+	require.Equal(t, []*protos.DeltaAlteredColumn{
+		{ColumnName: "a2", OldType: string(qvalue.QValueKindInt32), NewType: string(qvalue.QValueKindInt64)},
+	}, delta.AlteredColumns)
+	require.Equal(t, []string{"c", "b"}, delta.DroppedColumns)
+	require.Equal(t, []*protos.DeltaAddedColumn{
+		{ColumnName: "d", ColumnType: string(qvalue.QValueKindString)},
+		{ColumnName: "c", ColumnType: string(qvalue.QValueKindInt32)},
+	}, delta.AddedColumns)
+	require.Empty(t, delta.NarrowedColumns)
+}
+
+func TestDiffRelationMessagesUnconfirmedRename(t *testing.T) {
+	// without attnums, as for relations cached before they were recorded or columns changed again since,
+	// a column in the place of another of the same type is dropped and added
+	prev := testRelation(
+		&protos.RelationMessageColumn{Name: "id", DataType: uint32(oid.T_int8)},
+		&protos.RelationMessageColumn{Name: "a", DataType: uint32(oid.T_text)},
+	)
+	delta := &protos.TableSchemaDelta{}
+	diffRelationMessages(delta, prev, testRelation(
+		&protos.RelationMessageColumn{Name: "id", DataType: uint32(oid.T_int4)},
+		&protos.RelationMessageColumn{Name: "b", DataType: uint32(oid.T_text), Attnum: 2},
+	), testColumnKind)
+	require.Empty(t, delta.RenamedColumns)
+	require.Equal(t, []string{"a"}, delta.DroppedColumns)
+	require.Equal(t, []*protos.DeltaAddedColumn{{ColumnName: "b", ColumnType: string(qvalue.QValueKindString)}},
+		delta.AddedColumns)
+	require.Equal(t, []*protos.DeltaAlteredColumn{
+		{ColumnName: "id", OldType: string(qvalue.QValueKindInt64), NewType: string(qvalue.QValueKindInt32)},
+	}, delta.NarrowedColumns)
+
+	// an attnum taken by a column still present isn't a rename
+	prev = testRelation(&protos.RelationMessageColumn{Name: "a", DataType: uint32(oid.T_text), Attnum: 2})
+	delta = &protos.TableSchemaDelta{}
+	diffRelationMessages(delta, prev, testRelation(
+		&protos.RelationMessageColumn{Name: "a", DataType: uint32(oid.T_text)},
+		&protos.RelationMessageColumn{Name: "b", DataType: uint32(oid.T_text), Attnum: 2},
+	), testColumnKind)
+	require.Empty(t, delta.RenamedColumns)
+	require.Empty(t, delta.DroppedColumns)
+	require.Len(t, delta.AddedColumns, 1)
+}
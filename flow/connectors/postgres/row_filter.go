@@ -0,0 +1,86 @@
+package connpostgres
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/rowfilter"
+)
+
+func recordItemsLookup(items *model.RecordItems) func(string) (any, bool) {
+	return func(column string) (any, bool) {
+		idx, ok := items.ColToValIdx[column]
+		if !ok {
+			return nil, false
+		}
+		return items.Values[idx].Value(), true
+	}
+}
+
+// matchRowFilter returns whether items match a row filter and whether that is known,
+// items lacking columns the filter depends on are taken to match.
+func matchRowFilter(filter *rowfilter.Filter, items *model.RecordItems) (bool, bool, error) {
+	match, err := filter.Match(recordItemsLookup(items))
+	if errors.Is(err, rowfilter.ErrMissingColumn) {
+		return true, false, nil
+	} else if err != nil {
+		return false, false, fmt.Errorf("error evaluating row filter %s: %w", filter, err)
+	}
+	return match, true, nil
+}
+
+// filterRecord applies the row filter of a table to a record, returning nil when it isn't replicated.
+// Updates of rows that stop matching are replicated as deletes, rows that no longer match may have been
+// replicated before. Records that lack columns the filter depends on, like deletes of tables with a
+// default replica identity, are always replicated.
+func filterRecord(filter *rowfilter.Filter, record model.Record) (model.Record, error) {
+	if filter == nil || record == nil {
+		return record, nil
+	}
+
+	switch r := record.(type) {
+	case *model.InsertRecord:
+		match, _, err := matchRowFilter(filter, r.Items)
+		if err != nil || !match {
+			return nil, err
+		}
+	case *model.UpdateRecord:
+		match, _, err := matchRowFilter(filter, r.NewItems)
+		if err != nil || match {
+			return record, err
+		}
+		items := r.NewItems
+		if r.OldItems.Len() != 0 {
+			oldMatch, known, err := matchRowFilter(filter, r.OldItems)
+			if err != nil {
+				return nil, err
+			} else if known && !oldMatch {
+				// neither the old nor the new row were replicated
+				return nil, nil
+			}
+			items = r.OldItems
+		}
+		return &model.DeleteRecord{
+			SourceTableName:      r.SourceTableName,
+			DestinationTableName: r.DestinationTableName,
+			CheckpointID:         r.CheckpointID,
+			Items:                items,
+		}, nil
+	case *model.DeleteRecord:
+		match, _, err := matchRowFilter(filter, r.Items)
+		if err != nil || !match {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// filterAndMaskRecord applies the row filter and then the column masks of a table to a record.
+func filterAndMaskRecord(mapping model.NameAndExclude, record model.Record) (model.Record, error) {
+	record, err := filterRecord(mapping.RowFilter, record)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	return mapping.Masks.MaskRecord(record), nil
+}
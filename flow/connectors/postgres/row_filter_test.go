@@ -0,0 +1,57 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/model/rowfilter"
+)
+
+func tenantItems(id int64, tenantID int64) *model.RecordItems {
+	return model.NewRecordItemWithData([]string{"id", "tenant_id"}, []qvalue.QValue{
+		qvalue.New(qvalue.QValueKindInt64, id),
+		qvalue.New(qvalue.QValueKindInt64, tenantID),
+	})
+}
+
+func TestFilterRecord(t *testing.T) {
+	filter, err := rowfilter.Parse("tenant_id = 42")
+	require.NoError(t, err)
+
+	rec, err := filterRecord(filter, &model.InsertRecord{Items: tenantItems(1, 42)})
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	rec, err = filterRecord(filter, &model.InsertRecord{Items: tenantItems(1, 7)})
+	require.NoError(t, err)
+	require.Nil(t, rec)
+
+	// moving a row out of the filter deletes it
+	rec, err = filterRecord(filter, &model.UpdateRecord{
+		OldItems: tenantItems(1, 42), NewItems: tenantItems(1, 7), DestinationTableName: "orders",
+	})
+	require.NoError(t, err)
+	deleteRecord, ok := rec.(*model.DeleteRecord)
+	require.True(t, ok)
+	require.Equal(t, "orders", deleteRecord.DestinationTableName)
+
+	// without the old row it isn't known whether it was replicated
+	rec, err = filterRecord(filter, &model.UpdateRecord{OldItems: model.NewRecordItems(0), NewItems: tenantItems(1, 7)})
+	require.NoError(t, err)
+	require.IsType(t, &model.DeleteRecord{}, rec)
+
+	rec, err = filterRecord(filter, &model.UpdateRecord{OldItems: tenantItems(1, 5), NewItems: tenantItems(1, 7)})
+	require.NoError(t, err)
+	require.Nil(t, rec)
+
+	// deletes of tables with a default replica identity only carry the key
+	keyOnly := model.NewRecordItemWithData([]string{"id"}, []qvalue.QValue{qvalue.NewInt64(qvalue.QValueKindInt64, int64(1))})
+	rec, err = filterRecord(filter, &model.DeleteRecord{Items: keyOnly})
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	rec, err = filterRecord(filter, &model.DeleteRecord{Items: tenantItems(1, 7)})
+	require.NoError(t, err)
+	require.Nil(t, rec)
+}
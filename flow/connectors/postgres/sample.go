@@ -0,0 +1,102 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func quoteColumns(columns []string) string {
+	quotedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quotedColumns = append(quotedColumns, QuoteIdentifier(col))
+	}
+	return strings.Join(quotedColumns, ",")
+}
+
+// SampleRows returns the given columns of up to limit rows picked at random from a table.
+func (c *PostgresConnector) SampleRows(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	limit int,
+) (*model.QRecordBatch, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s ORDER BY random() LIMIT %d", quoteColumns(columns),
+		QuoteIdentifier(parsedTable.Schema), QuoteIdentifier(parsedTable.Table), limit)
+	return c.queryQRecordBatch(ctx, query)
+}
+
+func (c *PostgresConnector) GetRowsByPrimaryKey(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	pkeyColumns []string,
+	keys [][]qvalue.QValue,
+) (*model.QRecordBatch, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	quotedPkeyColumns := make([]string, 0, len(pkeyColumns))
+	for _, col := range pkeyColumns {
+		quotedPkeyColumns = append(quotedPkeyColumns, QuoteIdentifier(col))
+	}
+	args := make([]any, 0, len(keys)*len(pkeyColumns))
+	for _, key := range keys {
+		for _, value := range key {
+			args = append(args, value.Value())
+		}
+	}
+	filter := utils.PrimaryKeyFilter(quotedPkeyColumns, len(keys), func(i int) string {
+		return "$" + strconv.Itoa(i+1)
+	})
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s", quoteColumns(columns),
+		QuoteIdentifier(parsedTable.Schema), QuoteIdentifier(parsedTable.Table), filter)
+	return c.queryQRecordBatch(ctx, query, args...)
+}
+
+// CitextColumns returns the columns of a table typed citext, which compare case-insensitively.
+func (c *PostgresConnector) CitextColumns(ctx context.Context, tableIdentifier string) ([]string, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	relID, err := c.getRelIDForTable(ctx, parsedTable)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.conn.Query(ctx, `SELECT a.attname FROM pg_attribute a JOIN pg_type t ON a.atttypid=t.oid
+		WHERE a.attrelid=$1 AND a.attnum>0 AND NOT a.attisdropped AND t.typname='citext'`, relID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting citext columns of table %s: %w", parsedTable, err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("error getting citext columns of table %s: %w", parsedTable, err)
+	}
+	return columns, nil
+}
+
+// queryQRecordBatch runs a small query outside of an activity, so unlike ExecuteAndProcessQuery it doesn't heartbeat.
+func (c *PostgresConnector) queryQRecordBatch(ctx context.Context, query string, args ...any) (*model.QRecordBatch, error) {
+	qe := c.NewQRepQueryExecutor("", "")
+	rows, err := qe.ExecuteQuery(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return qe.ProcessRows(rows, rows.FieldDescriptions())
+}
@@ -0,0 +1,34 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SendSourceHeartbeat writes a transactional logical decoding message to the source's WAL. Decoding it gives
+// the slot of a database without other writes something to confirm, so it can move on and release WAL.
+// pgoutput doesn't forward messages unless asked to, so the mirrors never see it as a change.
+// Every slot of the database decodes the message, so one is written for all of the mirrors given.
+func (c *PostgresConnector) SendSourceHeartbeat(ctx context.Context, flowJobNames []string) error {
+	inRecovery, err := c.isInRecovery(ctx)
+	if err != nil {
+		return err
+	}
+	conn := c
+	if inRecovery {
+		// a standby can't write WAL, the message reaches its slots from the primary through replication
+		primary, err := c.primaryConn(ctx)
+		if err != nil {
+			return err
+		}
+		defer primary.Close()
+		conn = primary
+	}
+
+	if _, err := conn.conn.Exec(ctx,
+		"SELECT pg_logical_emit_message(true,'peerdb_heartbeat',$1::text)", strings.Join(flowJobNames, ",")); err != nil {
+		return fmt.Errorf("error writing heartbeat message: %w", err)
+	}
+	return nil
+}
@@ -4,83 +4,44 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.temporal.io/sdk/log"
-	"golang.org/x/crypto/ssh"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
 )
 
+// SSHTunnel opens connections to a peer through its SSH tunnel or SOCKS5 proxy, or directly if it has neither.
 type SSHTunnel struct {
-	sshConfig *ssh.ClientConfig
-	sshServer string
-	sshClient *ssh.Client
+	dialer *utils.Dialer
 }
 
 func NewSSHTunnel(
 	ctx context.Context,
 	sshConfig *protos.SSHConfig,
+	proxyConfig *protos.ProxyConfig,
 ) (*SSHTunnel, error) {
-	var sshServer string
-	var clientConfig *ssh.ClientConfig
-
-	if sshConfig != nil {
-		sshServer = fmt.Sprintf("%s:%d", sshConfig.Host, sshConfig.Port)
-		var err error
-		clientConfig, err = utils.GetSSHClientConfig(sshConfig)
-		if err != nil {
-			logger.LoggerFromCtx(ctx).Error("Failed to get SSH client config", "error", err)
-			return nil, err
-		}
-	}
-
-	tunnel := &SSHTunnel{
-		sshConfig: clientConfig,
-		sshServer: sshServer,
-	}
-
-	err := tunnel.setupSSH(logger.LoggerFromCtx(ctx))
+	dialer, err := utils.NewDialer(ctx, sshConfig, proxyConfig)
 	if err != nil {
 		return nil, err
 	}
-
-	return tunnel, nil
-}
-
-func (tunnel *SSHTunnel) setupSSH(logger log.Logger) error {
-	if tunnel.sshConfig == nil {
-		return nil
-	}
-
-	logger.Info("Setting up SSH connection to " + tunnel.sshServer)
-
-	var err error
-	tunnel.sshClient, err = ssh.Dial("tcp", tunnel.sshServer, tunnel.sshConfig)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return &SSHTunnel{dialer: dialer}, nil
 }
 
 func (tunnel *SSHTunnel) Close() {
-	if tunnel.sshClient != nil {
-		tunnel.sshClient.Close()
-	}
+	tunnel.dialer.Close()
 }
 
 func (tunnel *SSHTunnel) NewPostgresConnFromPostgresConfig(
 	ctx context.Context,
 	pgConfig *protos.PostgresConfig,
 ) (*pgx.Conn, error) {
-	connectionString := utils.GetPGConnectionString(pgConfig)
-
-	connConfig, err := pgx.ParseConfig(connectionString)
+	connConfig, err := utils.GetPGConnConfig(ctx, pgConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -93,14 +54,8 @@ func (tunnel *SSHTunnel) NewPostgresConnFromConfig(
 	ctx context.Context,
 	connConfig *pgx.ConnConfig,
 ) (*pgx.Conn, error) {
-	if tunnel.sshClient != nil {
-		connConfig.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			conn, err := tunnel.sshClient.Dial(network, addr)
-			if err != nil {
-				return nil, err
-			}
-			return &noDeadlineConn{Conn: conn}, nil
-		}
+	if tunnel.dialer.Tunneled() {
+		connConfig.DialFunc = tunnel.dialer.DialContext
 	}
 
 	logger := logger.LoggerFromCtx(ctx)
@@ -128,6 +83,57 @@ func (tunnel *SSHTunnel) NewPostgresConnFromConfig(
 	return conn, nil
 }
 
+// newPostgresPool opens a pool of connections to a peer through its SSH tunnel or SOCKS5 proxy, for utils.AcquirePgPool.
+// The tunnel is returned for the cache to close along with the pool.
+func newPostgresPool(ctx context.Context, pgConfig *protos.PostgresConfig) (*pgxpool.Pool, *utils.Dialer, error) {
+	poolConfig, err := pgxpool.ParseConfig(utils.GetPGConnectionString(pgConfig))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	poolConfig.ConnConfig, err = utils.GetPGConnConfig(ctx, pgConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	poolConfig.ConnConfig.RuntimeParams["idle_in_transaction_session_timeout"] = "0"
+	// RDS IAM tokens expire, connections opened later on need a token of their own
+	poolConfig.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+		return utils.SetPostgresIAMAuthToken(ctx, pgConfig, connConfig)
+	}
+	// connections decode the peer's enums, domains and composites and copy hstore values,
+	// NewPostgresConnector has them replaced once it finds types created later on
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		if err := utils.RegisterHStore(ctx, conn); err != nil {
+			return err
+		}
+		customTypes, err := utils.GetCustomDataTypes(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return utils.RegisterCustomTypes(ctx, conn, conn.TypeMap(), customTypes)
+	}
+	utils.ConfigurePgPool(poolConfig, pgConfig.PoolConfig, peerdbenv.PeerDBConnectionCacheIdleTimeout())
+
+	dialer, err := utils.NewDialer(ctx, pgConfig.SshConfig, pgConfig.ProxyConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create ssh tunnel: %w", err)
+	}
+	if dialer.Tunneled() {
+		poolConfig.ConnConfig.DialFunc = dialer.DialContext
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err == nil {
+		err = pool.Ping(ctx)
+		if err != nil {
+			pool.Close()
+		}
+	}
+	if err != nil {
+		dialer.Close()
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return pool, dialer, nil
+}
+
 type retryFunc func() error
 
 func retryWithBackoff(logger log.Logger, fn retryFunc, maxRetries int, backoff time.Duration) error {
@@ -146,10 +152,3 @@ func retryWithBackoff(logger log.Logger, fn retryFunc, maxRetries int, backoff t
 		}
 	}
 }
-
-// see: https://github.com/jackc/pgx/issues/382#issuecomment-1496586216
-type noDeadlineConn struct{ net.Conn }
-
-func (c *noDeadlineConn) SetDeadline(t time.Time) error      { return nil }
-func (c *noDeadlineConn) SetReadDeadline(t time.Time) error  { return nil }
-func (c *noDeadlineConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,109 @@
+package connpostgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.temporal.io/sdk/temporal"
+)
+
+func (c *PostgresConnector) isInRecovery(ctx context.Context) (bool, error) {
+	var inRecovery bool
+	if err := c.conn.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return false, fmt.Errorf("error checking if Postgres is in recovery: %w", err)
+	}
+	return inRecovery, nil
+}
+
+// checkStandby returns whether the peer is a hot standby, erroring when it is one which can't decode changes.
+func (c *PostgresConnector) checkStandby(ctx context.Context) (bool, error) {
+	inRecovery, err := c.isInRecovery(ctx)
+	if err != nil || !inRecovery {
+		return false, err
+	}
+	supported, version, err := c.MajorVersionCheck(ctx, POSTGRES_16)
+	if err != nil {
+		return false, fmt.Errorf("error checking Postgres version: %w", err)
+	}
+	if !supported {
+		return false, fmt.Errorf("logical replication from a standby requires Postgres 16 or later, source is version %d", version)
+	}
+	return true, nil
+}
+
+// primaryConn connects to the primary of a standby peer, for the statements a standby can't run.
+// The caller closes the connector.
+func (c *PostgresConnector) primaryConn(ctx context.Context) (*PostgresConnector, error) {
+	if c.config.Primary == nil {
+		return nil, errors.New("source is a standby without a primary configured, " +
+			"configure the peer's primary or create the publication on the primary and use it for the mirror")
+	}
+	primary, err := NewPostgresConnector(ctx, c.config.Primary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to primary %s: %w", c.config.Primary.Host, err)
+	}
+	return primary, nil
+}
+
+// logStandbySnapshots has the primary log the running transactions every few seconds until ctx is done.
+// Creating a slot on a standby waits for such a record to reach a consistent point, which without
+// this only comes with the primary's next checkpoint or bgwriter cycle.
+func (c *PostgresConnector) logStandbySnapshots(ctx context.Context, primary *PostgresConnector) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		if _, err := primary.conn.Exec(ctx, "SELECT pg_log_standby_snapshot()"); err != nil && ctx.Err() == nil {
+			c.logger.Warn("failed to log standby snapshot on primary", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// slotConflicting reports whether a slot on a standby was invalidated by a recovery conflict,
+// after the primary removed rows or WAL the slot still needed. The slot can't be used anymore.
+func (c *PostgresConnector) slotConflicting(ctx context.Context, slot string) (bool, error) {
+	supported, _, err := c.MajorVersionCheck(ctx, POSTGRES_16)
+	if err != nil || !supported {
+		return false, err
+	}
+	var conflicting *bool
+	err = c.conn.QueryRow(ctx, "SELECT conflicting FROM pg_replication_slots WHERE slot_name = $1", slot).Scan(&conflicting)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error checking if slot %s was invalidated: %w", slot, err)
+	}
+	return conflicting != nil && *conflicting, nil
+}
+
+func slotInvalidatedError(slot string) error {
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("replication slot %s was invalidated by a recovery conflict on the standby, the mirror needs to be resynced. "+
+			"Enable hot_standby_feedback with a physical slot between primary and standby to avoid this", slot),
+		"slotInvalidated", nil)
+}
+
+// checkStandbyPromotion logs when a standby peer was promoted, its slots survive promotion so replication carries on.
+func (c *PostgresConnector) checkStandbyPromotion(ctx context.Context) (bool, error) {
+	if c.config.Primary == nil {
+		return false, nil
+	}
+	inRecovery, err := c.isInRecovery(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !inRecovery {
+		c.logger.Warn("standby peer was promoted, replicating from it as a primary",
+			slog.String("host", c.config.Host), slog.String("previousPrimary", c.config.Primary.Host))
+	}
+	return !inRecovery, nil
+}
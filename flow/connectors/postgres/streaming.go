@@ -0,0 +1,45 @@
+package connpostgres
+
+import (
+	"fmt"
+
+	"github.com/jackc/pglogrepl"
+
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// bufferStreamed holds back a change of a transaction being streamed before it commits,
+// changes outside of a stream are returned to be added to the batch right away.
+func (p *PostgresCDCSource) bufferStreamed(subXid uint32, rec model.Record, err error) (model.Record, error) {
+	if err != nil || rec == nil || !p.inStream {
+		return rec, err
+	}
+	if err := p.streams.Append(p.streamXid, subXid, rec); err != nil {
+		return nil, fmt.Errorf("error buffering change of streamed transaction %d: %w", p.streamXid, err)
+	}
+	return nil, nil
+}
+
+// commitStreamedTransaction adds the buffered changes of a streamed transaction to the batch once it commits.
+func (p *PostgresCDCSource) commitStreamedTransaction(
+	batch *model.CDCRecordStream,
+	xld pglogrepl.XLogData,
+	processRecord func(model.Record) error,
+) error {
+	logicalMsg, err := pglogrepl.ParseV2(xld.WALData, false)
+	if err != nil {
+		return fmt.Errorf("error parsing stream commit message: %w", err)
+	}
+	msg, ok := logicalMsg.(*pglogrepl.StreamCommitMessageV2)
+	if !ok {
+		return fmt.Errorf("unexpected message %T while committing streamed transaction", logicalMsg)
+	}
+
+	p.logger.Debug(fmt.Sprintf("StreamCommitMessage => XID: %v, CommitLSN: %v, TransactionEndLSN: %v",
+		msg.Xid, msg.CommitLSN, msg.TransactionEndLSN))
+	if err := p.streams.Commit(msg.Xid, processRecord); err != nil {
+		return err
+	}
+	batch.UpdateLatestCheckpoint(int64(msg.CommitLSN))
+	return nil
+}
@@ -0,0 +1,48 @@
+package connpostgres
+
+import (
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// splitSyntheticKeyUpdate turns an update of a table keyed by all its columns into a delete of the old row
+// and an insert of the new one, as the update moves the row to a different key at the destination.
+// Updates which leave every column unchanged keep matching their row and pass through as is.
+func splitSyntheticKeyUpdate(r *model.UpdateRecord) []model.Record {
+	// REPLICA IDENTITY FULL sends the whole old row, fill in unchanged TOAST values from it
+	r.NewItems.UpdateIfNotExists(r.OldItems)
+	r.UnchangedToastColumns = nil
+
+	if syntheticKeyUnchanged(r.OldItems, r.NewItems) {
+		return []model.Record{r}
+	}
+	return []model.Record{
+		&model.DeleteRecord{
+			SourceTableName:      r.SourceTableName,
+			DestinationTableName: r.DestinationTableName,
+			CheckpointID:         r.CheckpointID,
+			Items:                r.OldItems,
+		},
+		&model.InsertRecord{
+			SourceTableName:      r.SourceTableName,
+			DestinationTableName: r.DestinationTableName,
+			CheckpointID:         r.CheckpointID,
+			Items:                r.NewItems,
+		},
+	}
+}
+
+func syntheticKeyUnchanged(oldItems *model.RecordItems, newItems *model.RecordItems) bool {
+	if oldItems.Len() != newItems.Len() {
+		return false
+	}
+	for col, idx := range oldItems.ColToValIdx {
+		newIdx, ok := newItems.ColToValIdx[col]
+		// compared like recToTablePKey keys rows, QValue.Equals doesn't compare JSON
+		if !ok || fmt.Sprint(oldItems.Values[idx].Value()) != fmt.Sprint(newItems.Values[newIdx].Value()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestSplitSyntheticKeyUpdate(t *testing.T) {
+	items := func(id int64, name string) *model.RecordItems {
+		return model.NewRecordItemWithData([]string{"id", "name"}, []qvalue.QValue{
+			qvalue.New(qvalue.QValueKindInt64, id),
+			qvalue.New(qvalue.QValueKindString, name),
+		})
+	}
+
+	unchanged := &model.UpdateRecord{DestinationTableName: "t", OldItems: items(1, "a"), NewItems: items(1, "a")}
+	require.Equal(t, []model.Record{unchanged}, splitSyntheticKeyUpdate(unchanged))
+
+	changed := &model.UpdateRecord{DestinationTableName: "t", CheckpointID: 5, OldItems: items(1, "a"), NewItems: items(1, "b")}
+	split := splitSyntheticKeyUpdate(changed)
+	require.Len(t, split, 2)
+	require.Equal(t, &model.DeleteRecord{DestinationTableName: "t", CheckpointID: 5, Items: items(1, "a")}, split[0])
+	require.Equal(t, &model.InsertRecord{DestinationTableName: "t", CheckpointID: 5, Items: items(1, "b")}, split[1])
+
+	// unchanged TOAST columns come from the old row
+	toasted := &model.UpdateRecord{
+		DestinationTableName:  "t",
+		OldItems:              items(1, "a"),
+		NewItems:              model.NewRecordItemWithData([]string{"id"}, []qvalue.QValue{qvalue.NewInt64(qvalue.QValueKindInt64, int64(2))}),
+		UnchangedToastColumns: map[string]struct{}{"name": {}},
+	}
+	split = splitSyntheticKeyUpdate(toasted)
+	require.Len(t, split, 2)
+	require.Equal(t, "a", split[1].GetItems().GetColumnValue("name").Value())
+}
@@ -0,0 +1,300 @@
+package connpostgres
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/cdc_records"
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// pgoutput messages for prepared transactions, sent with proto_version 3 and two_phase enabled.
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+const (
+	beginPrepareMessageType     byte = 'b'
+	prepareMessageType          byte = 'P'
+	commitPreparedMessageType   byte = 'K'
+	rollbackPreparedMessageType byte = 'r'
+	// PREPARE of a transaction which was streamed before it was prepared
+	streamPrepareMessageType byte = 'p'
+)
+
+var errTwoPhaseMessageTooShort = errors.New("two-phase message is too short")
+
+type preparingTransaction struct {
+	gid     string
+	records []model.Record
+}
+
+type beginPrepareMessage struct {
+	PrepareLSN  pglogrepl.LSN
+	EndLSN      pglogrepl.LSN
+	PrepareTime time.Time
+	Xid         uint32
+	GID         string
+}
+
+type prepareMessage beginPrepareMessage
+
+type commitPreparedMessage struct {
+	CommitLSN  pglogrepl.LSN
+	EndLSN     pglogrepl.LSN
+	CommitTime time.Time
+	Xid        uint32
+	GID        string
+}
+
+type rollbackPreparedMessage struct {
+	PreparedEndLSN pglogrepl.LSN
+	RollbackEndLSN pglogrepl.LSN
+	PrepareTime    time.Time
+	RollbackTime   time.Time
+	Xid            uint32
+	GID            string
+}
+
+func isTwoPhaseMessage(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	switch data[0] {
+	case beginPrepareMessageType, prepareMessageType, commitPreparedMessageType, rollbackPreparedMessageType,
+		streamPrepareMessageType:
+		return true
+	default:
+		return false
+	}
+}
+
+// twoPhaseDecoder reads the fixed width fields of a two-phase message, followed by the gid.
+type twoPhaseDecoder struct {
+	src []byte
+	err error
+}
+
+func (d *twoPhaseDecoder) uint64() uint64 {
+	if d.err != nil || len(d.src) < 8 {
+		d.err = errTwoPhaseMessageTooShort
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.src)
+	d.src = d.src[8:]
+	return v
+}
+
+func (d *twoPhaseDecoder) uint32() uint32 {
+	if d.err != nil || len(d.src) < 4 {
+		d.err = errTwoPhaseMessageTooShort
+		return 0
+	}
+	v := binary.BigEndian.Uint32(d.src)
+	d.src = d.src[4:]
+	return v
+}
+
+func (d *twoPhaseDecoder) skipFlags() {
+	if d.err != nil || len(d.src) < 1 {
+		d.err = errTwoPhaseMessageTooShort
+		return
+	}
+	d.src = d.src[1:]
+}
+
+func (d *twoPhaseDecoder) lsn() pglogrepl.LSN {
+	return pglogrepl.LSN(d.uint64())
+}
+
+// timestamps are microseconds since the Postgres epoch
+func (d *twoPhaseDecoder) time() time.Time {
+	micros := int64(d.uint64())
+	return time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(micros) * time.Microsecond)
+}
+
+func (d *twoPhaseDecoder) gid() string {
+	if d.err != nil {
+		return ""
+	}
+	for i, b := range d.src {
+		if b == 0 {
+			gid := string(d.src[:i])
+			d.src = d.src[i+1:]
+			return gid
+		}
+	}
+	d.err = errTwoPhaseMessageTooShort
+	return ""
+}
+
+func parseBeginPrepareMessage(src []byte) (*beginPrepareMessage, error) {
+	d := &twoPhaseDecoder{src: src}
+	msg := &beginPrepareMessage{}
+	msg.PrepareLSN = d.lsn()
+	msg.EndLSN = d.lsn()
+	msg.PrepareTime = d.time()
+	msg.Xid = d.uint32()
+	msg.GID = d.gid()
+	return msg, d.err
+}
+
+func parsePrepareMessage(src []byte) (*prepareMessage, error) {
+	d := &twoPhaseDecoder{src: src}
+	msg := &prepareMessage{}
+	d.skipFlags()
+	msg.PrepareLSN = d.lsn()
+	msg.EndLSN = d.lsn()
+	msg.PrepareTime = d.time()
+	msg.Xid = d.uint32()
+	msg.GID = d.gid()
+	return msg, d.err
+}
+
+func parseCommitPreparedMessage(src []byte) (*commitPreparedMessage, error) {
+	d := &twoPhaseDecoder{src: src}
+	msg := &commitPreparedMessage{}
+	d.skipFlags()
+	msg.CommitLSN = d.lsn()
+	msg.EndLSN = d.lsn()
+	msg.CommitTime = d.time()
+	msg.Xid = d.uint32()
+	msg.GID = d.gid()
+	return msg, d.err
+}
+
+func parseRollbackPreparedMessage(src []byte) (*rollbackPreparedMessage, error) {
+	d := &twoPhaseDecoder{src: src}
+	msg := &rollbackPreparedMessage{}
+	d.skipFlags()
+	msg.PreparedEndLSN = d.lsn()
+	msg.RollbackEndLSN = d.lsn()
+	msg.PrepareTime = d.time()
+	msg.RollbackTime = d.time()
+	msg.Xid = d.uint32()
+	msg.GID = d.gid()
+	return msg, d.err
+}
+
+// processTwoPhaseMessage buffers the changes of a transaction from BEGIN PREPARE until PREPARE,
+// when they are saved to the catalog since the slot will not send them again once it moves past the PREPARE.
+// On COMMIT PREPARED the saved changes are returned to be added to the batch, on ROLLBACK PREPARED they are dropped.
+func (p *PostgresCDCSource) processTwoPhaseMessage(
+	ctx context.Context,
+	batch *model.CDCRecordStream,
+	xld pglogrepl.XLogData,
+) ([]model.Record, error) {
+	switch xld.WALData[0] {
+	case beginPrepareMessageType:
+		msg, err := parseBeginPrepareMessage(xld.WALData[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing begin prepare message: %w", err)
+		}
+		p.logger.Debug(fmt.Sprintf("BeginPrepareMessage => PrepareLSN: %v, XID: %v, GID: %s", msg.PrepareLSN, msg.Xid, msg.GID))
+		p.preparing = &preparingTransaction{gid: msg.GID}
+		p.commitLock = true
+
+	case prepareMessageType:
+		msg, err := parsePrepareMessage(xld.WALData[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing prepare message: %w", err)
+		}
+		if p.preparing == nil || p.preparing.gid != msg.GID {
+			return nil, fmt.Errorf("received PREPARE for transaction %s without its BEGIN PREPARE", msg.GID)
+		}
+		p.logger.Debug(fmt.Sprintf("PrepareMessage => PrepareLSN: %v, XID: %v, GID: %s, records: %d",
+			msg.PrepareLSN, msg.Xid, msg.GID, len(p.preparing.records)))
+		if err := p.savePreparedTransaction(ctx, batch, msg, p.preparing.records); err != nil {
+			return nil, err
+		}
+		p.preparing = nil
+		p.commitLock = false
+
+	case streamPrepareMessageType:
+		msg, err := parsePrepareMessage(xld.WALData[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing stream prepare message: %w", err)
+		}
+		if p.streams == nil {
+			return nil, fmt.Errorf("received STREAM PREPARE for transaction %s without streaming enabled", msg.GID)
+		}
+		var records []model.Record
+		if err := p.streams.Commit(msg.Xid, func(rec model.Record) error {
+			records = append(records, rec)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		p.logger.Debug(fmt.Sprintf("StreamPrepareMessage => PrepareLSN: %v, XID: %v, GID: %s, records: %d",
+			msg.PrepareLSN, msg.Xid, msg.GID, len(records)))
+		if err := p.savePreparedTransaction(ctx, batch, msg, records); err != nil {
+			return nil, err
+		}
+
+	case commitPreparedMessageType:
+		msg, err := parseCommitPreparedMessage(xld.WALData[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing commit prepared message: %w", err)
+		}
+		txn, err := catalog.GetPreparedTransaction(ctx, p.catalogPool, p.flowJobName, msg.GID)
+		if err != nil {
+			return nil, err
+		}
+		if txn == nil {
+			return nil, fmt.Errorf("received COMMIT PREPARED for transaction %s which was never prepared by this mirror", msg.GID)
+		}
+		records, err := cdc_records.DecodeRecords(txn.Records)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding records of prepared transaction %s: %w", msg.GID, err)
+		}
+		p.logger.Info(fmt.Sprintf("applying prepared transaction %s with %d records", msg.GID, len(records)))
+
+		if err := catalog.FinishPreparedTransaction(ctx, p.catalogPool, p.flowJobName, msg.GID, int64(msg.CommitLSN)); err != nil {
+			return nil, err
+		}
+		batch.UpdateLatestCheckpoint(int64(msg.CommitLSN))
+		return records, nil
+
+	case rollbackPreparedMessageType:
+		msg, err := parseRollbackPreparedMessage(xld.WALData[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing rollback prepared message: %w", err)
+		}
+		p.logger.Info("discarding rolled back prepared transaction " + msg.GID)
+
+		err = catalog.FinishPreparedTransaction(ctx, p.catalogPool, p.flowJobName, msg.GID, int64(msg.RollbackEndLSN))
+		if err != nil {
+			return nil, err
+		}
+		batch.UpdateLatestCheckpoint(int64(msg.RollbackEndLSN))
+	}
+
+	return nil, nil
+}
+
+func (p *PostgresCDCSource) savePreparedTransaction(
+	ctx context.Context,
+	batch *model.CDCRecordStream,
+	msg *prepareMessage,
+	records []model.Record,
+) error {
+	encoded, err := cdc_records.EncodeRecords(records)
+	if err != nil {
+		return fmt.Errorf("error encoding records of prepared transaction %s: %w", msg.GID, err)
+	}
+	err = catalog.SavePreparedTransaction(ctx, p.catalogPool, p.flowJobName, &catalog.PreparedTransaction{
+		GID:        msg.GID,
+		Xid:        msg.Xid,
+		PrepareLSN: int64(msg.PrepareLSN),
+		Records:    encoded,
+		PreparedAt: msg.PrepareTime,
+	})
+	if err != nil {
+		return err
+	}
+	batch.UpdateLatestCheckpoint(int64(msg.PrepareLSN))
+	return nil
+}
@@ -0,0 +1,48 @@
+package connpostgres
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+)
+
+func TestParseTwoPhaseMessages(t *testing.T) {
+	prepareTime := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	pgMicros := uint64(prepareTime.Sub(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)).Microseconds())
+
+	// flags, prepare lsn, end lsn, prepare timestamp, xid, gid
+	data := []byte{'P', 0}
+	data = binary.BigEndian.AppendUint64(data, 0x16B3748)
+	data = binary.BigEndian.AppendUint64(data, 0x16B3780)
+	data = binary.BigEndian.AppendUint64(data, pgMicros)
+	data = binary.BigEndian.AppendUint32(data, 742)
+	data = append(data, "txn_42\x00"...)
+
+	if !isTwoPhaseMessage(data) {
+		t.Fatal("Expected PREPARE to be a two-phase message")
+	}
+	msg, err := parsePrepareMessage(data[1:])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if msg.PrepareLSN != pglogrepl.LSN(0x16B3748) || msg.EndLSN != pglogrepl.LSN(0x16B3780) {
+		t.Errorf("Unexpected LSNs: %s, %s", msg.PrepareLSN, msg.EndLSN)
+	}
+	if !msg.PrepareTime.Equal(prepareTime) || msg.Xid != 742 || msg.GID != "txn_42" {
+		t.Errorf("Unexpected prepare message: %+v", msg)
+	}
+
+	// rollback carries two timestamps, so the same bytes are too short for it
+	if _, err := parseRollbackPreparedMessage(data[1:]); err == nil {
+		t.Error("Expected error parsing truncated rollback prepared message")
+	}
+	if _, err := parseCommitPreparedMessage(data[1 : len(data)-1]); err == nil {
+		t.Error("Expected error parsing message without gid terminator")
+	}
+
+	if isTwoPhaseMessage([]byte{'B'}) {
+		t.Error("Expected BEGIN not to be a two-phase message")
+	}
+}
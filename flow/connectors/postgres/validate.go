@@ -61,6 +61,32 @@ func (c *PostgresConnector) CheckSourceTables(ctx context.Context,
 	return nil
 }
 
+// CheckTableKeys checks that every table has a key to replicate changes by. Tables without a primary key
+// need REPLICA IDENTITY FULL, their rows are then matched on all columns, which is returned as a warning.
+func (c *PostgresConnector) CheckTableKeys(ctx context.Context, tableNames []*utils.SchemaTable) ([]string, error) {
+	var warnings []string
+	for _, parsedTable := range tableNames {
+		replicaIdentity, err := c.getReplicaIdentityType(ctx, parsedTable)
+		if err != nil {
+			return nil, err
+		}
+		pKeyCols, err := c.getUniqueColumns(ctx, replicaIdentity, parsedTable)
+		if err != nil {
+			return nil, err
+		}
+		if len(pKeyCols) > 0 {
+			continue
+		}
+		if replicaIdentity != ReplicaIdentityFull {
+			return nil, fmt.Errorf("table %s has no primary key, set REPLICA IDENTITY FULL on it or exclude it from the mirror",
+				parsedTable)
+		}
+		warnings = append(warnings, fmt.Sprintf("table %s has no primary key, rows are matched on all their columns: "+
+			"identical rows can't be told apart and updates are replicated as a delete and an insert", parsedTable))
+	}
+	return warnings, nil
+}
+
 func (c *PostgresConnector) CheckReplicationPermissions(ctx context.Context, username string) error {
 	if c.conn == nil {
 		return errors.New("check replication permissions: conn is nil")
@@ -0,0 +1,210 @@
+package conns3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// tableCatalog registers the files written by syncs as external tables in a metastore,
+// so that Athena, Trino or Spark can query them as soon as a sync finishes.
+type tableCatalog interface {
+	// ensureTable creates the table, or updates its columns when the schema changed
+	ensureTable(ctx context.Context, table *catalogTable) error
+	// addPartitions adds the partitions which the catalog doesn't know yet
+	addPartitions(ctx context.Context, table *catalogTable, partitions []catalogPartition) error
+	Close() error
+}
+
+type catalogColumn struct {
+	name     string
+	hiveType string
+}
+
+type catalogTable struct {
+	name          string
+	location      string
+	format        protos.S3Format
+	columns       []catalogColumn
+	partitionKeys []string
+}
+
+type catalogPartition struct {
+	values   []string
+	location string
+}
+
+func newTableCatalog(ctx context.Context, config *protos.TableCatalogConfig, creds utils.S3PeerCredentials) (tableCatalog, error) {
+	if config == nil || config.Type == protos.TableCatalogType_TABLE_CATALOG_NONE {
+		return nil, nil
+	}
+	if config.Database == "" {
+		return nil, errors.New("table catalog requires a database")
+	}
+
+	switch config.Type {
+	case protos.TableCatalogType_TABLE_CATALOG_GLUE:
+		return newGlueCatalog(config, creds)
+	case protos.TableCatalogType_TABLE_CATALOG_HIVE:
+		return newHiveCatalog(ctx, config)
+	default:
+		return nil, fmt.Errorf("unsupported table catalog %s", config.Type)
+	}
+}
+
+var nonIdentifierCharRe = regexp.MustCompile(`[^a-z0-9_]`)
+
+// catalogIdentifier lowercases name and replaces what Glue and Hive don't allow in names with underscores
+func catalogIdentifier(name string) string {
+	return nonIdentifierCharRe.ReplaceAllString(strings.ToLower(name), "_")
+}
+
+// storage formats of the files in a table, as Hive class names
+type hiveStorageFormat struct {
+	inputFormat  string
+	outputFormat string
+	serde        string
+}
+
+func storageFormat(format protos.S3Format) hiveStorageFormat {
+	if format == protos.S3Format_S3_FORMAT_PARQUET {
+		return hiveStorageFormat{
+			inputFormat:  "org.apache.hadoop.hive.ql.io.parquet.MapredParquetInputFormat",
+			outputFormat: "org.apache.hadoop.hive.ql.io.parquet.MapredParquetOutputFormat",
+			serde:        "org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe",
+		}
+	}
+	return hiveStorageFormat{
+		inputFormat:  "org.apache.hadoop.hive.ql.io.avro.AvroContainerInputFormat",
+		outputFormat: "org.apache.hadoop.hive.ql.io.avro.AvroContainerOutputFormat",
+		serde:        "org.apache.hadoop.hive.serde2.avro.AvroSerDe",
+	}
+}
+
+func hiveType(kind qvalue.QValueKind, precision int16, scale int16) string {
+	switch kind {
+	case qvalue.QValueKindInt16:
+		return "smallint"
+	case qvalue.QValueKindInt32:
+		return "int"
+	case qvalue.QValueKindInt64:
+		return "bigint"
+	case qvalue.QValueKindFloat32:
+		return "float"
+	case qvalue.QValueKindFloat64:
+		return "double"
+	case qvalue.QValueKindBoolean:
+		return "boolean"
+	case qvalue.QValueKindBytes, qvalue.QValueKindBit:
+		return "binary"
+	case qvalue.QValueKindNumeric:
+		precision, scale := qvalue.DetermineNumericSettingForDWH(precision, scale, qvalue.QDWHTypeS3)
+		return fmt.Sprintf("decimal(%d,%d)", precision, scale)
+	case qvalue.QValueKindDate:
+		return "date"
+	case qvalue.QValueKindTimestamp, qvalue.QValueKindTimestampTZ:
+		return "timestamp"
+	case qvalue.QValueKindArrayFloat32:
+		return "array<float>"
+	case qvalue.QValueKindArrayFloat64:
+		return "array<double>"
+	case qvalue.QValueKindArrayInt16:
+		return "array<smallint>"
+	case qvalue.QValueKindArrayInt32:
+		return "array<int>"
+	case qvalue.QValueKindArrayInt64:
+		return "array<bigint>"
+	case qvalue.QValueKindArrayBoolean:
+		return "array<boolean>"
+	case qvalue.QValueKindArrayDate:
+		return "array<date>"
+	case qvalue.QValueKindArrayTimestamp, qvalue.QValueKindArrayTimestampTZ:
+		return "array<timestamp>"
+	case qvalue.QValueKindArrayString:
+		return "array<string>"
+	default:
+		// Hive has no types for times, UUIDs, JSON or geospatial values
+		return "string"
+	}
+}
+
+// splitPartitionPath splits the directory of key into the path of its table and the key=value segments after it,
+// which are the partitions of the table.
+func splitPartitionPath(key string) (string, []string, []string) {
+	segments := strings.Split(path.Dir(key), "/")
+	for i, segment := range segments {
+		if strings.Contains(segment, "=") {
+			var partitionKeys, partitionValues []string
+			for _, partition := range segments[i:] {
+				partitionKey, partitionValue, _ := strings.Cut(partition, "=")
+				partitionKeys = append(partitionKeys, catalogIdentifier(partitionKey))
+				partitionValues = append(partitionValues, partitionValue)
+			}
+			return strings.Join(segments[:i], "/"), partitionKeys, partitionValues
+		}
+	}
+	return path.Dir(key), nil, nil
+}
+
+// registerFiles registers the table the files at keys belong to and the partitions they were written to.
+func (c *S3Connector) registerFiles(ctx context.Context, tableName string, schema *model.QRecordSchema, keys []string) error {
+	s3o, err := utils.NewS3BucketAndPrefix(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to parse bucket path: %w", err)
+	}
+	location := func(dir string) string {
+		return "s3://" + path.Join(s3o.Bucket, s3o.Prefix, dir) + "/"
+	}
+
+	tableDir, partitionKeys, _ := splitPartitionPath(keys[0])
+	columns := make([]catalogColumn, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		columns = append(columns, catalogColumn{
+			name:     catalogIdentifier(field.Name),
+			hiveType: hiveType(field.Type, field.Precision, field.Scale),
+		})
+	}
+	table := &catalogTable{
+		name:          catalogIdentifier(tableName),
+		location:      location(tableDir),
+		format:        c.format,
+		columns:       columns,
+		partitionKeys: partitionKeys,
+	}
+	if err := c.tableCatalog.ensureTable(ctx, table); err != nil {
+		return fmt.Errorf("failed to register table %s: %w", table.name, err)
+	}
+	if len(partitionKeys) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	partitions := make([]catalogPartition, 0, len(keys))
+	for _, key := range keys {
+		dir := path.Dir(key)
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+		keyTableDir, keyPartitionKeys, partitionValues := splitPartitionPath(key)
+		if keyTableDir != tableDir || len(keyPartitionKeys) != len(partitionKeys) {
+			return fmt.Errorf("file %s isn't in a partition of table %s", key, table.name)
+		}
+		partitions = append(partitions, catalogPartition{
+			values:   partitionValues,
+			location: location(dir),
+		})
+	}
+	if err := c.tableCatalog.addPartitions(ctx, table, partitions); err != nil {
+		return fmt.Errorf("failed to register partitions of table %s: %w", table.name, err)
+	}
+	return nil
+}
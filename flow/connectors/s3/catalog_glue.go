@@ -0,0 +1,146 @@
+package conns3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/glue"
+	"github.com/aws/aws-sdk-go-v2/service/glue/types"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// BatchCreatePartition takes at most 100 partitions per call
+const glueMaxPartitionsPerBatch = 100
+
+type glueCatalog struct {
+	client    *glue.Client
+	database  string
+	catalogID *string
+}
+
+func newGlueCatalog(config *protos.TableCatalogConfig, creds utils.S3PeerCredentials) (*glueCatalog, error) {
+	awsSecrets, err := utils.GetAWSSecrets(creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS secrets: %w", err)
+	}
+	region := config.GlueRegion
+	if region == "" {
+		region = awsSecrets.Region
+	}
+
+	var catalogID *string
+	if config.GlueCatalogId != "" {
+		catalogID = aws.String(config.GlueCatalogId)
+	}
+	return &glueCatalog{
+		client: glue.New(glue.Options{
+			Region:      region,
+			Credentials: credentials.NewStaticCredentialsProvider(awsSecrets.AccessKeyID, awsSecrets.SecretAccessKey, ""),
+		}),
+		database:  config.Database,
+		catalogID: catalogID,
+	}, nil
+}
+
+func (g *glueCatalog) storageDescriptor(table *catalogTable, location string) *types.StorageDescriptor {
+	columns := make([]types.Column, 0, len(table.columns))
+	for _, column := range table.columns {
+		columns = append(columns, types.Column{Name: aws.String(column.name), Type: aws.String(column.hiveType)})
+	}
+	format := storageFormat(table.format)
+	return &types.StorageDescriptor{
+		Columns:      columns,
+		Location:     aws.String(location),
+		InputFormat:  aws.String(format.inputFormat),
+		OutputFormat: aws.String(format.outputFormat),
+		SerdeInfo:    &types.SerDeInfo{SerializationLibrary: aws.String(format.serde)},
+	}
+}
+
+func (g *glueCatalog) tableInput(table *catalogTable) *types.TableInput {
+	partitionKeys := make([]types.Column, 0, len(table.partitionKeys))
+	for _, key := range table.partitionKeys {
+		partitionKeys = append(partitionKeys, types.Column{Name: aws.String(key), Type: aws.String("string")})
+	}
+	return &types.TableInput{
+		Name:              aws.String(table.name),
+		TableType:         aws.String("EXTERNAL_TABLE"),
+		Parameters:        map[string]string{"EXTERNAL": "TRUE"},
+		StorageDescriptor: g.storageDescriptor(table, table.location),
+		PartitionKeys:     partitionKeys,
+	}
+}
+
+func (g *glueCatalog) ensureTable(ctx context.Context, table *catalogTable) error {
+	existing, err := g.client.GetTable(ctx, &glue.GetTableInput{
+		CatalogId:    g.catalogID,
+		DatabaseName: aws.String(g.database),
+		Name:         aws.String(table.name),
+	})
+	var notFound *types.EntityNotFoundException
+	if errors.As(err, &notFound) {
+		_, err := g.client.CreateTable(ctx, &glue.CreateTableInput{
+			CatalogId:    g.catalogID,
+			DatabaseName: aws.String(g.database),
+			TableInput:   g.tableInput(table),
+		})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if existing.Table.StorageDescriptor != nil && slices.EqualFunc(existing.Table.StorageDescriptor.Columns, table.columns,
+		func(existing types.Column, column catalogColumn) bool {
+			return aws.ToString(existing.Name) == column.name && aws.ToString(existing.Type) == column.hiveType
+		}) {
+		return nil
+	}
+	// partition keys of a table can't change, so the existing ones are kept
+	input := g.tableInput(table)
+	input.PartitionKeys = existing.Table.PartitionKeys
+	_, err = g.client.UpdateTable(ctx, &glue.UpdateTableInput{
+		CatalogId:    g.catalogID,
+		DatabaseName: aws.String(g.database),
+		TableInput:   input,
+	})
+	return err
+}
+
+func (g *glueCatalog) addPartitions(ctx context.Context, table *catalogTable, partitions []catalogPartition) error {
+	for start := 0; start < len(partitions); start += glueMaxPartitionsPerBatch {
+		batch := partitions[start:min(start+glueMaxPartitionsPerBatch, len(partitions))]
+		inputs := make([]types.PartitionInput, 0, len(batch))
+		for _, partition := range batch {
+			inputs = append(inputs, types.PartitionInput{
+				Values:            partition.values,
+				StorageDescriptor: g.storageDescriptor(table, partition.location),
+			})
+		}
+		res, err := g.client.BatchCreatePartition(ctx, &glue.BatchCreatePartitionInput{
+			CatalogId:          g.catalogID,
+			DatabaseName:       aws.String(g.database),
+			TableName:          aws.String(table.name),
+			PartitionInputList: inputs,
+		})
+		if err != nil {
+			return err
+		}
+		for _, partitionErr := range res.Errors {
+			if partitionErr.ErrorDetail != nil && aws.ToString(partitionErr.ErrorDetail.ErrorCode) != "AlreadyExistsException" {
+				return fmt.Errorf("failed to create partition %v: %s",
+					partitionErr.PartitionValues, aws.ToString(partitionErr.ErrorDetail.ErrorMessage))
+			}
+		}
+	}
+	return nil
+}
+
+func (g *glueCatalog) Close() error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package conns3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/beltran/gohive"
+	"github.com/beltran/gohive/hive_metastore"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type hiveCatalog struct {
+	client   *gohive.HiveMetastoreClient
+	database string
+}
+
+func newHiveCatalog(_ context.Context, config *protos.TableCatalogConfig) (*hiveCatalog, error) {
+	host, portStr, err := net.SplitHostPort(config.HiveMetastoreAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hive metastore address %s: %w", config.HiveMetastoreAddress, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hive metastore port %s: %w", portStr, err)
+	}
+
+	client, err := gohive.ConnectToMetastore(host, port, "NOSASL", gohive.NewMetastoreConnectConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to hive metastore at %s: %w", config.HiveMetastoreAddress, err)
+	}
+	return &hiveCatalog{
+		client:   client,
+		database: config.Database,
+	}, nil
+}
+
+func (h *hiveCatalog) storageDescriptor(table *catalogTable, location string) *hive_metastore.StorageDescriptor {
+	columns := make([]*hive_metastore.FieldSchema, 0, len(table.columns))
+	for _, column := range table.columns {
+		columns = append(columns, &hive_metastore.FieldSchema{Name: column.name, Type: column.hiveType})
+	}
+	format := storageFormat(table.format)
+	return &hive_metastore.StorageDescriptor{
+		Cols:         columns,
+		Location:     location,
+		InputFormat:  format.inputFormat,
+		OutputFormat: format.outputFormat,
+		SerdeInfo:    &hive_metastore.SerDeInfo{SerializationLib: format.serde, Parameters: map[string]string{}},
+	}
+}
+
+func (h *hiveCatalog) ensureTable(ctx context.Context, table *catalogTable) error {
+	existing, err := h.client.Client.GetTable(ctx, h.database, table.name)
+	var notFound *hive_metastore.NoSuchObjectException
+	if errors.As(err, &notFound) {
+		partitionKeys := make([]*hive_metastore.FieldSchema, 0, len(table.partitionKeys))
+		for _, key := range table.partitionKeys {
+			partitionKeys = append(partitionKeys, &hive_metastore.FieldSchema{Name: key, Type: "string"})
+		}
+		return h.client.Client.CreateTable(ctx, &hive_metastore.Table{
+			TableName:     table.name,
+			DbName:        h.database,
+			TableType:     "EXTERNAL_TABLE",
+			Parameters:    map[string]string{"EXTERNAL": "TRUE"},
+			Sd:            h.storageDescriptor(table, table.location),
+			PartitionKeys: partitionKeys,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	if existing.Sd != nil && slices.EqualFunc(existing.Sd.Cols, table.columns,
+		func(existing *hive_metastore.FieldSchema, column catalogColumn) bool {
+			return existing.Name == column.name && existing.Type == column.hiveType
+		}) {
+		return nil
+	}
+	// only the columns change, partition keys of a table can't
+	existing.Sd = h.storageDescriptor(table, table.location)
+	return h.client.Client.AlterTable(ctx, h.database, table.name, existing)
+}
+
+func (h *hiveCatalog) addPartitions(ctx context.Context, table *catalogTable, partitions []catalogPartition) error {
+	parts := make([]*hive_metastore.Partition, 0, len(partitions))
+	for _, partition := range partitions {
+		parts = append(parts, &hive_metastore.Partition{
+			Values:    partition.values,
+			DbName:    h.database,
+			TableName: table.name,
+			Sd:        h.storageDescriptor(table, partition.location),
+		})
+	}
+	_, err := h.client.Client.AddPartitionsReq(ctx, &hive_metastore.AddPartitionsRequest{
+		DbName:      h.database,
+		TblName:     table.name,
+		Parts:       parts,
+		IfNotExists: true,
+	})
+	return err
+}
+
+func (h *hiveCatalog) Close() error {
+	h.client.Close()
+	return nil
+}
@@ -0,0 +1,32 @@
+package conns3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestSplitPartitionPath(t *testing.T) {
+	tableDir, keys, values := splitPartitionPath("orders/dt=2024-03-07/Hour=09/42-1.parquet")
+	assert.Equal(t, "orders", tableDir)
+	assert.Equal(t, []string{"dt", "hour"}, keys)
+	assert.Equal(t, []string{"2024-03-07", "09"}, values)
+
+	tableDir, keys, values = splitPartitionPath("orders_mirror/42.avro")
+	assert.Equal(t, "orders_mirror", tableDir)
+	assert.Empty(t, keys)
+	assert.Empty(t, values)
+}
+
+func TestCatalogIdentifier(t *testing.T) {
+	assert.Equal(t, "public_orders", catalogIdentifier("public.Orders"))
+	assert.Equal(t, "raw_table_orders_mirror", catalogIdentifier("raw_table_orders_mirror"))
+}
+
+func TestHiveType(t *testing.T) {
+	assert.Equal(t, "decimal(10,2)", hiveType(qvalue.QValueKindNumeric, 10, 2))
+	assert.Equal(t, "array<bigint>", hiveType(qvalue.QValueKindArrayInt64, 0, 0))
+	assert.Equal(t, "string", hiveType(qvalue.QValueKindJSON, 0, 0))
+}
@@ -0,0 +1,98 @@
+package conns3
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKeyTemplate      = "{job}/{batch}.{ext}"
+	defaultSplitKeyTemplate = "{job}/{batch}_{file}.{ext}"
+)
+
+var (
+	keyPlaceholderRe = regexp.MustCompile(`\{([^{}]*)\}`)
+	// date patterns are written like in Java and Spark, the longest tokens come first so yyyy isn't read as yy twice
+	keyDateTokens = strings.NewReplacer(
+		"yyyy", "2006",
+		"yy", "06",
+		"MM", "01",
+		"dd", "02",
+		"HH", "15",
+		"mm", "04",
+		"ss", "05",
+	)
+	keyDatePatternRe = regexp.MustCompile(`^(yyyy|yy|MM|dd|HH|mm|ss|[-_./= ])+$`)
+)
+
+type keyTemplateValues struct {
+	job   string
+	table string
+	batch string
+	ext   string
+	time  time.Time
+}
+
+// keyTemplate builds the keys of files written by a sync, relative to the bucket prefix.
+type keyTemplate struct {
+	template string
+}
+
+func newKeyTemplate(template string, splitsFiles bool) (*keyTemplate, error) {
+	if template == "" {
+		if splitsFiles {
+			template = defaultSplitKeyTemplate
+		} else {
+			template = defaultKeyTemplate
+		}
+	}
+
+	hasBatch, hasFile := false, false
+	for _, match := range keyPlaceholderRe.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case "batch":
+			hasBatch = true
+		case "file":
+			hasFile = true
+		case "job", "table", "ext":
+		default:
+			if !keyDatePatternRe.MatchString(match[1]) {
+				return nil, fmt.Errorf("unknown placeholder %s in key template", match[0])
+			}
+		}
+	}
+	if !hasBatch {
+		return nil, errors.New("key template must contain {batch} so syncs don't overwrite each other's files")
+	}
+	if splitsFiles && !hasFile {
+		return nil, errors.New("key template must contain {file} when files are limited by size or rows")
+	}
+	if strings.HasPrefix(template, "/") {
+		return nil, errors.New("key template must be relative to the bucket prefix")
+	}
+
+	return &keyTemplate{template: template}, nil
+}
+
+func (k *keyTemplate) key(values keyTemplateValues, file int) string {
+	return keyPlaceholderRe.ReplaceAllStringFunc(k.template, func(placeholder string) string {
+		switch name := placeholder[1 : len(placeholder)-1]; name {
+		case "job":
+			return values.job
+		case "table":
+			return values.table
+		case "batch":
+			return values.batch
+		case "file":
+			return strconv.Itoa(file)
+		case "ext":
+			return values.ext
+		default:
+			return values.time.Format(keyDateTokens.Replace(name))
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package conns3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyTemplate(t *testing.T) {
+	values := keyTemplateValues{
+		job:   "orders_mirror",
+		table: "orders",
+		batch: "42",
+		ext:   "parquet",
+		time:  time.Date(2024, 3, 7, 9, 5, 0, 0, time.UTC),
+	}
+
+	keys, err := newKeyTemplate("", false)
+	require.NoError(t, err)
+	assert.Equal(t, "orders_mirror/42.parquet", keys.key(values, 0))
+
+	keys, err = newKeyTemplate("", true)
+	require.NoError(t, err)
+	assert.Equal(t, "orders_mirror/42_3.parquet", keys.key(values, 3))
+
+	keys, err = newKeyTemplate("{table}/dt={yyyy-MM-dd}/hour={HH}/{batch}-{file}.{ext}", true)
+	require.NoError(t, err)
+	assert.Equal(t, "orders/dt=2024-03-07/hour=09/42-1.parquet", keys.key(values, 1))
+}
+
+func TestKeyTemplateValidation(t *testing.T) {
+	_, err := newKeyTemplate("{table}/{yyyy}.{ext}", false)
+	require.Error(t, err, "files of different batches would overwrite each other")
+
+	_, err = newKeyTemplate("{table}/{batch}.{ext}", true)
+	require.Error(t, err, "split files would overwrite each other")
+
+	_, err = newKeyTemplate("{table}/{batch}/{partition}.{ext}", false)
+	require.Error(t, err, "unknown placeholder")
+
+	_, err = newKeyTemplate("/{table}/{batch}.{ext}", false)
+	require.Error(t, err, "keys are relative to the prefix")
+}
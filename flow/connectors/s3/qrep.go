@@ -4,41 +4,92 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	avro "github.com/PeerDB-io/peer-flow/connectors/utils/avro"
+	parquet "github.com/PeerDB-io/peer-flow/connectors/utils/parquet"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 	"github.com/PeerDB-io/peer-flow/shared"
 )
 
+// records buffered between the stream of a sync and the file being written when batches are split
+const fileStreamBufferSize = 1024
+
 func (c *S3Connector) SyncQRepRecords(
 	ctx context.Context,
 	config *protos.QRepConfig,
 	partition *protos.QRepPartition,
 	stream *model.QRecordStream,
 ) (int, error) {
+	numRecords, _, err := c.syncFiles(ctx, config, partition, stream, time.Now(), nil)
+	return numRecords, err
+}
+
+// syncFiles writes the records of stream to files keyed on the partition and startTime, calling beforeWrite
+// with each key before its file is written. It returns the keys of the files written relative to the bucket prefix.
+func (c *S3Connector) syncFiles(
+	ctx context.Context,
+	config *protos.QRepConfig,
+	partition *protos.QRepPartition,
+	stream *model.QRecordStream,
+	startTime time.Time,
+	beforeWrite func(key string) error,
+) (int, []string, error) {
 	schema, err := stream.Schema()
 	if err != nil {
 		c.logger.Error("failed to get schema from stream",
 			slog.Any("error", err),
 			slog.String(string(shared.PartitionIDKey), partition.PartitionId))
-		return 0, fmt.Errorf("failed to get schema from stream: %w", err)
+		return 0, nil, fmt.Errorf("failed to get schema from stream: %w", err)
 	}
 
-	dstTableName := config.DestinationTableIdentifier
-	avroSchema, err := getAvroSchema(dstTableName, schema)
-	if err != nil {
-		return 0, err
+	values := keyTemplateValues{
+		job:   config.FlowJobName,
+		table: config.DestinationTableIdentifier,
+		batch: partition.PartitionId,
+		time:  startTime.UTC(),
+	}
+
+	var writeFile func(context.Context, *model.QRecordStream, string) (int, error)
+	if c.format == protos.S3Format_S3_FORMAT_PARQUET {
+		values.ext = "parquet"
+		writeFile = c.writeToParquetFile
+	} else {
+		avroSchema, err := getAvroSchema(config.DestinationTableIdentifier, schema)
+		if err != nil {
+			return 0, nil, err
+		}
+		values.ext = "avro"
+		writeFile = func(ctx context.Context, stream *model.QRecordStream, key string) (int, error) {
+			return c.writeToAvroFile(ctx, stream, avroSchema, key)
+		}
 	}
 
-	numRecords, err := c.writeToAvroFile(ctx, stream, avroSchema, partition.PartitionId, config.FlowJobName)
+	if beforeWrite != nil {
+		write := writeFile
+		writeFile = func(ctx context.Context, stream *model.QRecordStream, key string) (int, error) {
+			if err := beforeWrite(key); err != nil {
+				return 0, err
+			}
+			return write(ctx, stream, key)
+		}
+	}
+
+	numRecords, keys, err := c.writeFiles(ctx, stream, schema, values, writeFile)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	return numRecords, nil
+	if c.tableCatalog != nil && len(keys) > 0 {
+		if err := c.registerFiles(ctx, values.table, schema, keys); err != nil {
+			return 0, nil, fmt.Errorf("failed to register files in table catalog: %w", err)
+		}
+	}
+
+	return numRecords, keys, nil
 }
 
 func getAvroSchema(
@@ -53,19 +104,113 @@ func getAvroSchema(
 	return avroSchema, nil
 }
 
+func (c *S3Connector) splitsFiles() bool {
+	return c.maxRowsPerFile > 0 || c.targetFileSizeBytes > 0
+}
+
+// writeFiles writes the records of stream to one file, or to as many as needed to stay within the file limits.
+// It returns the keys of the files written relative to the bucket prefix.
+func (c *S3Connector) writeFiles(
+	ctx context.Context,
+	stream *model.QRecordStream,
+	schema *model.QRecordSchema,
+	values keyTemplateValues,
+	writeFile func(context.Context, *model.QRecordStream, string) (int, error),
+) (int, []string, error) {
+	if !c.splitsFiles() {
+		key := c.keyTemplate.key(values, 0)
+		numRecords, err := writeFile(ctx, stream, key)
+		if err != nil {
+			return 0, nil, err
+		}
+		return numRecords, []string{key}, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	numRecords := 0
+	var keys []string
+	for file := 0; ; file++ {
+		var first model.QRecordOrError
+		var ok bool
+		select {
+		case first, ok = <-stream.Records:
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+		if !ok {
+			return numRecords, keys, nil
+		}
+
+		fileStream := model.NewQRecordStream(fileStreamBufferSize)
+		if err := fileStream.SetSchema(schema); err != nil {
+			return 0, nil, err
+		}
+		go c.forwardFileRecords(ctx, first, stream, fileStream)
+
+		key := c.keyTemplate.key(values, file)
+		fileRecords, err := writeFile(ctx, fileStream, key)
+		if err != nil {
+			return 0, nil, err
+		}
+		numRecords += fileRecords
+		keys = append(keys, key)
+		c.logger.Info(fmt.Sprintf("wrote file %d of batch %s with %d records", file, values.batch, fileRecords))
+	}
+}
+
+// forwardFileRecords hands on first and the records after it from stream to fileStream, until the file is full.
+func (c *S3Connector) forwardFileRecords(
+	ctx context.Context,
+	first model.QRecordOrError,
+	stream *model.QRecordStream,
+	fileStream *model.QRecordStream,
+) {
+	defer close(fileStream.Records)
+
+	rows := uint32(0)
+	size := uint64(0)
+	record, ok := first, true
+	for ok {
+		select {
+		case fileStream.Records <- record:
+		case <-ctx.Done():
+			return
+		}
+		if record.Err != nil {
+			return
+		}
+
+		rows += 1
+		for _, qv := range record.Record {
+			size += uint64(qv.EstimatedSize())
+		}
+		if (c.maxRowsPerFile > 0 && rows >= c.maxRowsPerFile) ||
+			(c.targetFileSizeBytes > 0 && size >= c.targetFileSizeBytes) {
+			return
+		}
+
+		select {
+		case record, ok = <-stream.Records:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func (c *S3Connector) writeToAvroFile(
 	ctx context.Context,
 	stream *model.QRecordStream,
 	avroSchema *model.QRecordAvroSchemaDefinition,
-	partitionID string,
-	jobName string,
+	key string,
 ) (int, error) {
 	s3o, err := utils.NewS3BucketAndPrefix(c.url)
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse bucket path: %w", err)
 	}
 
-	s3AvroFileKey := fmt.Sprintf("%s/%s/%s.avro", s3o.Prefix, jobName, partitionID)
+	s3AvroFileKey := s3o.Prefix + "/" + key
 	writer := avro.NewPeerDBOCFWriter(stream, avroSchema, avro.CompressNone, qvalue.QDWHTypeSnowflake)
 	avroFile, err := writer.WriteRecordsToS3(ctx, s3o.Bucket, s3AvroFileKey, c.creds)
 	if err != nil {
@@ -76,6 +221,26 @@ func (c *S3Connector) writeToAvroFile(
 	return avroFile.NumRecords, nil
 }
 
+func (c *S3Connector) writeToParquetFile(
+	ctx context.Context,
+	stream *model.QRecordStream,
+	key string,
+) (int, error) {
+	s3o, err := utils.NewS3BucketAndPrefix(c.url)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse bucket path: %w", err)
+	}
+
+	s3ParquetFileKey := s3o.Prefix + "/" + key
+	writer := parquet.NewPeerDBParquetWriter(stream, c.parquetCompression)
+	parquetFile, err := writer.WriteRecordsToS3(ctx, s3o.Bucket, s3ParquetFileKey, c.creds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write records to S3: %w", err)
+	}
+
+	return parquetFile.NumRecords, nil
+}
+
 // S3 just sets up destination, not metadata tables
 func (c *S3Connector) SetupQRepMetadataTables(_ context.Context, config *protos.QRepConfig) error {
 	c.logger.Info("QRep metadata setup not needed for S3.")
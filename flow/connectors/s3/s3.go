@@ -3,12 +3,14 @@ package conns3
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"go.temporal.io/sdk/log"
 
 	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
@@ -23,11 +25,17 @@ const (
 )
 
 type S3Connector struct {
-	url        string
-	pgMetadata *metadataStore.PostgresMetadataStore
-	client     s3.Client
-	creds      utils.S3PeerCredentials
-	logger     log.Logger
+	url                 string
+	pgMetadata          *metadataStore.PostgresMetadataStore
+	client              s3.Client
+	creds               utils.S3PeerCredentials
+	logger              log.Logger
+	format              protos.S3Format
+	parquetCompression  protos.ParquetCompression
+	keyTemplate         *keyTemplate
+	targetFileSizeBytes uint64
+	maxRowsPerFile      uint32
+	tableCatalog        tableCatalog
 }
 
 func NewS3Connector(
@@ -66,17 +74,34 @@ func NewS3Connector(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create S3 client: %w", err)
 	}
+	fileKeys, err := newKeyTemplate(config.KeyTemplate, config.TargetFileSizeBytes > 0 || config.MaxRowsPerFile > 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 key template: %w", err)
+	}
+	catalog, err := newTableCatalog(ctx, config.TableCatalog, s3PeerCreds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create table catalog: %w", err)
+	}
 	pgMetadata, err := metadataStore.NewPostgresMetadataStore(ctx)
 	if err != nil {
 		logger.Error("failed to create postgres metadata store", "error", err)
+		if catalog != nil {
+			catalog.Close()
+		}
 		return nil, err
 	}
 	return &S3Connector{
-		url:        config.Url,
-		pgMetadata: pgMetadata,
-		client:     *s3Client,
-		creds:      s3PeerCreds,
-		logger:     logger,
+		url:                 config.Url,
+		pgMetadata:          pgMetadata,
+		client:              *s3Client,
+		creds:               s3PeerCreds,
+		logger:              logger,
+		format:              config.Format,
+		parquetCompression:  config.ParquetCompression,
+		keyTemplate:         fileKeys,
+		targetFileSizeBytes: config.TargetFileSizeBytes,
+		maxRowsPerFile:      config.MaxRowsPerFile,
+		tableCatalog:        catalog,
 	}, nil
 }
 
@@ -86,6 +111,9 @@ func (c *S3Connector) CreateRawTable(_ context.Context, req *protos.CreateRawTab
 }
 
 func (c *S3Connector) Close() error {
+	if c.tableCatalog != nil {
+		return c.tableCatalog.Close()
+	}
 	return nil
 }
 
@@ -163,7 +191,14 @@ func (c *S3Connector) SetLastOffset(ctx context.Context, jobName string, offset
 	return c.pgMetadata.UpdateLastOffset(ctx, jobName, offset)
 }
 
+// SyncRecords writes a batch to files keyed on the batch id and the time its first attempt started, so a retry
+// overwrites the files of earlier attempts, and removes those the retry didn't write again before finishing the batch.
 func (c *S3Connector) SyncRecords(ctx context.Context, req *model.SyncRecordsRequest) (*model.SyncResponse, error) {
+	batch, err := c.pgMetadata.StartBatch(ctx, req.FlowJobName, req.SyncBatchID)
+	if err != nil {
+		return nil, err
+	}
+
 	tableNameRowsMapping := make(map[string]uint32)
 	streamReq := model.NewRecordsToStreamRequest(req.Records.GetRecords(), tableNameRowsMapping, req.SyncBatchID)
 	streamRes, err := utils.RecordsToRawTableStream(streamReq)
@@ -178,13 +213,26 @@ func (c *S3Connector) SyncRecords(ctx context.Context, req *model.SyncRecordsReq
 	partition := &protos.QRepPartition{
 		PartitionId: strconv.FormatInt(req.SyncBatchID, 10),
 	}
-	numRecords, err := c.SyncQRepRecords(ctx, qrepConfig, partition, recordStream)
+	numRecords, keys, err := c.syncFiles(ctx, qrepConfig, partition, recordStream, batch.StartedAt,
+		func(key string) error {
+			return c.pgMetadata.RecordBatchObjects(ctx, req.FlowJobName, req.SyncBatchID, []string{key})
+		})
 	if err != nil {
 		return nil, err
 	}
 	c.logger.Info(fmt.Sprintf("Synced %d records", numRecords))
 
-	lastCheckpoint := req.Records.GetLastCheckpoint()
+	if stale := staleBatchFiles(batch.WrittenObjects, keys); len(stale) != 0 {
+		c.logger.Warn(fmt.Sprintf("removing %d files of batch %d from an earlier attempt", len(stale), req.SyncBatchID))
+		if err := c.deleteFiles(ctx, stale); err != nil {
+			return nil, err
+		}
+	}
+
+	lastCheckpoint, err := req.Records.GetLastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
 	err = c.pgMetadata.FinishBatch(ctx, req.FlowJobName, req.SyncBatchID, lastCheckpoint)
 	if err != nil {
 		c.logger.Error("failed to increment id", "error", err)
@@ -199,6 +247,46 @@ func (c *S3Connector) SyncRecords(ctx context.Context, req *model.SyncRecordsReq
 	}, nil
 }
 
+// staleBatchFiles returns the files earlier attempts at a batch wrote which the last attempt didn't overwrite,
+// such as the last files of a batch split into fewer files this time.
+func staleBatchFiles(earlier []string, written []string) []string {
+	var stale []string
+	for _, key := range earlier {
+		if !slices.Contains(written, key) {
+			stale = append(stale, key)
+		}
+	}
+	return stale
+}
+
+// deleteFiles deletes files by their keys relative to the bucket prefix, those already gone are ignored.
+func (c *S3Connector) deleteFiles(ctx context.Context, keys []string) error {
+	s3o, err := utils.NewS3BucketAndPrefix(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to parse bucket path: %w", err)
+	}
+
+	// DeleteObjects takes up to 1000 keys
+	for start := 0; start < len(keys); start += 1000 {
+		chunk := keys[start:min(start+1000, len(keys))]
+		objects := make([]types.ObjectIdentifier, 0, len(chunk))
+		for _, key := range chunk {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(s3o.Prefix + "/" + key)})
+		}
+		out, err := c.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s3o.Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete files: %w", err)
+		}
+		if len(out.Errors) != 0 {
+			return fmt.Errorf("failed to delete file %s: %s", aws.ToString(out.Errors[0].Key), aws.ToString(out.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
 func (c *S3Connector) ReplayTableSchemaDeltas(_ context.Context, flowJobName string, schemaDeltas []*protos.TableSchemaDelta) error {
 	c.logger.Info("ReplayTableSchemaDeltas for S3 is a no-op")
 	return nil
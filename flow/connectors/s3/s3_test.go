@@ -0,0 +1,29 @@
+package conns3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleBatchFiles(t *testing.T) {
+	require.Empty(t, staleBatchFiles(nil, []string{"job/1_0.avro"}))
+	require.Empty(t, staleBatchFiles([]string{"job/1_0.avro"}, []string{"job/1_0.avro"}))
+	// a retry which split the batch into fewer files leaves the last files of the earlier attempt behind
+	require.Equal(t, []string{"job/1_2.avro"},
+		staleBatchFiles([]string{"job/1_0.avro", "job/1_1.avro", "job/1_2.avro"}, []string{"job/1_0.avro", "job/1_1.avro"}))
+}
+
+func TestRetriedBatchKeys(t *testing.T) {
+	template, err := newKeyTemplate("{job}/{yyyy/MM/dd/HH}/{batch}.{ext}", false)
+	require.NoError(t, err)
+	started := time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC)
+	values := keyTemplateValues{job: "job", batch: "7", ext: "avro", time: started}
+	first := template.key(values, 0)
+	require.Equal(t, first, template.key(values, 0))
+
+	// retries key files on the time the batch's first attempt started, keying on when they run would miss the files
+	values.time = started.Add(time.Hour)
+	require.NotEqual(t, first, template.key(values, 0))
+}
@@ -0,0 +1,108 @@
+package connsnowflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+const getTableColumnsSQL = `SELECT COLUMN_NAME, DATA_TYPE FROM INFORMATION_SCHEMA.COLUMNS
+	WHERE TABLE_SCHEMA=? AND TABLE_NAME=?`
+
+// snowflakeDataType maps a type as written in DDL to the name INFORMATION_SCHEMA.COLUMNS reports for it
+func snowflakeDataType(columnType string) string {
+	baseType, _, _ := strings.Cut(strings.ToUpper(columnType), "(")
+	switch strings.TrimSpace(baseType) {
+	case "INT", "INTEGER", "BIGINT", "SMALLINT", "TINYINT", "BYTEINT", "NUMBER", "NUMERIC", "DECIMAL":
+		return "NUMBER"
+	case "FLOAT", "FLOAT4", "FLOAT8", "DOUBLE", "DOUBLE PRECISION", "REAL":
+		return "FLOAT"
+	case "VARCHAR", "CHAR", "CHARACTER", "STRING", "TEXT":
+		return "TEXT"
+	case "BINARY", "VARBINARY":
+		return "BINARY"
+	case "DATETIME", "TIMESTAMP", "TIMESTAMP_NTZ":
+		return "TIMESTAMP_NTZ"
+	default:
+		return strings.TrimSpace(baseType)
+	}
+}
+
+func unquotedIdentifier(identifier string) string {
+	return strings.Trim(SnowflakeIdentifierNormalize(identifier), `"`)
+}
+
+func (c *SnowflakeConnector) CheckAdoptedTable(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+	tableIdentifier string,
+	tableSchema *protos.TableSchema,
+) error {
+	drift, err := c.DiffTable(ctx, config, tableIdentifier, tableSchema)
+	if err != nil {
+		return err
+	}
+	return drift.Err(tableIdentifier)
+}
+
+func (c *SnowflakeConnector) DiffTable(
+	ctx context.Context,
+	config *protos.SetupNormalizedTableBatchInput,
+	tableIdentifier string,
+	tableSchema *protos.TableSchema,
+) (*utils.TableDrift, error) {
+	schemaTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing table schema and name: %w", err)
+	}
+
+	rows, err := c.database.QueryContext(ctx, getTableColumnsSQL,
+		unquotedIdentifier(schemaTable.Schema), unquotedIdentifier(schemaTable.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s: %w", tableIdentifier, err)
+	}
+	defer rows.Close()
+	existing := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("failed to get columns of table %s: %w", tableIdentifier, err)
+		}
+		existing[name] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get columns of table %s: %w", tableIdentifier, err)
+	}
+	if len(existing) == 0 {
+		return nil, fmt.Errorf("table %s %w: it doesn't exist", tableIdentifier, utils.ErrNotAdoptable)
+	}
+
+	expected := make([]utils.AdoptedColumn, 0, len(tableSchema.Columns)+2)
+	for _, column := range tableSchema.Columns {
+		sfColType, err := qValueKindToSnowflakeType(qvalue.QValueKind(column.Type))
+		if err != nil {
+			// not created by SetupNormalizedTable either
+			continue
+		}
+		expected = append(expected, utils.AdoptedColumn{
+			Name:   unquotedIdentifier(column.Name),
+			Type:   snowflakeDataType(sfColType),
+			Source: column,
+		})
+	}
+	if config.SoftDeleteColName != "" {
+		expected = append(expected, utils.AdoptedColumn{Name: strings.ToUpper(config.SoftDeleteColName), Type: "BOOLEAN"})
+	}
+	if config.SyncedAtColName != "" {
+		expected = append(expected, utils.AdoptedColumn{
+			Name: strings.ToUpper(config.SyncedAtColName),
+			Type: snowflakeDataType("TIMESTAMP"),
+		})
+	}
+
+	return utils.DiffAdoptedColumns(expected, existing), nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/linkedin/goavro/v2"
 	"github.com/stretchr/testify/require"
 
 	avro "github.com/PeerDB-io/peer-flow/connectors/utils/avro"
@@ -54,10 +55,7 @@ func createQValue(t *testing.T, kind qvalue.QValueKind, placeHolder int) qvalue.
 		require.Failf(t, "unsupported QValueKind", "unsupported QValueKind: %s", kind)
 	}
 
-	return qvalue.QValue{
-		Kind:  kind,
-		Value: value,
-	}
+	return qvalue.New(kind, value)
 }
 
 //nolint:unparam
@@ -119,7 +117,7 @@ func generateRecords(
 			placeHolder := int(row) * i
 			entries[i] = createQValue(t, kind, placeHolder)
 			if allnulls {
-				entries[i].Value = nil
+				entries[i] = qvalue.QValue{Kind: kind}
 			}
 		}
 
@@ -213,6 +211,41 @@ func TestWriteRecordsToDeflateAvroFileHappyPath(t *testing.T) {
 	require.NotZero(t, info.Size(), "expected file to not be empty")
 }
 
+func TestWriteRecordsToSnappyAvroFileInParallel(t *testing.T) {
+	t.Setenv("PEERDB_AVRO_ENCODE_PARALLELISM", "4")
+
+	tmpfile, err := os.CreateTemp("", "example_*.avro")
+	require.NoError(t, err)
+
+	defer os.Remove(tmpfile.Name()) // clean up
+	defer tmpfile.Close()           // close file after test ends
+
+	// enough rows for several batches, the last of them partial
+	records, schema := generateRecords(t, false, 5000, false)
+
+	avroSchema, err := model.GetAvroSchemaDefinition("not_applicable", schema, qvalue.QDWHTypeSnowflake)
+	require.NoError(t, err)
+
+	writer := avro.NewPeerDBOCFWriter(records, avroSchema, avro.CompressSnappy, qvalue.QDWHTypeSnowflake)
+	avroFile, err := writer.WriteRecordsToAvroFile(context.Background(), tmpfile.Name())
+	require.NoError(t, err, "expected WriteRecordsToAvroFile to complete without errors")
+	require.Equal(t, 5000, avroFile.NumRecords)
+
+	// blocks are snappy compressed and records are in the order they were read
+	reader, err := goavro.NewOCFReader(tmpfile)
+	require.NoError(t, err)
+	require.Equal(t, goavro.CompressionSnappyLabel, reader.CompressionName())
+	row := 0
+	for reader.Scan() {
+		record, err := reader.Read()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("string%d", row*6), record.(map[string]interface{})[string(qvalue.QValueKindString)])
+		row += 1
+	}
+	require.NoError(t, reader.Err())
+	require.Equal(t, 5000, row)
+}
+
 func TestWriteRecordsToAvroFileNonNull(t *testing.T) {
 	// Create temporary file
 	tmpfile, err := os.CreateTemp("", "example_*.avro")
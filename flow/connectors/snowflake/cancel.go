@@ -0,0 +1,41 @@
+package connsnowflake
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+// sqlExecer is what statements run on, a database or a transaction on it.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// execCancelable runs a long statement on db, cancelling it on Snowflake if ctx is done before it finishes.
+// The driver stops polling for the result when ctx is done but leaves the statement running in the warehouse.
+func (c *SnowflakeConnector) execCancelable(ctx context.Context, db sqlExecer, query string, args ...any) (sql.Result, error) {
+	queryID := make(chan string, 1)
+	result, err := db.ExecContext(gosnowflake.WithQueryIDChan(ctx, queryID), query, args...)
+	if err != nil && ctx.Err() != nil {
+		select {
+		case id, ok := <-queryID:
+			if ok {
+				c.cancelQuery(id)
+			}
+		default:
+			// not accepted by Snowflake yet, abort_detached_query aborts it once the session is gone
+		}
+	}
+	return result, err
+}
+
+func (c *SnowflakeConnector) cancelQuery(queryID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := c.database.ExecContext(ctx, "SELECT SYSTEM$CANCEL_QUERY(?)", queryID); err != nil {
+		c.logger.Warn("failed to cancel Snowflake query", slog.String("queryID", queryID), slog.Any("error", err))
+	}
+}
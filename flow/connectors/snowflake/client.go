@@ -58,7 +58,7 @@ func NewSnowflakeClient(ctx context.Context, config *protos.SnowflakeConfig) (*S
 
 	logger := logger.LoggerFromCtx(ctx)
 	genericExecutor := *peersql.NewGenericSQLQueryExecutor(
-		logger, database, snowflakeTypeToQValueKindMap, qvalue.QValueKindToSnowflakeTypeMap)
+		logger, database, snowflakeDialect{}, snowflakeTypeToQValueKindMap, qvalue.QValueKindToSnowflakeTypeMap)
 
 	return &SnowflakeClient{
 		GenericSQLQueryExecutor: genericExecutor,
@@ -90,9 +90,16 @@ func SnowflakeIdentifierNormalize(identifier string) string {
 	// Snowflake follows the SQL standard, but Postgres does the opposite.
 	// Ergo, we suffer.
 	if utils.IsLower(identifier) {
-		return fmt.Sprintf(`"%s"`, strings.ToUpper(identifier))
+		return peersql.DoubleQuoteIdentifier(strings.ToUpper(identifier))
 	}
-	return fmt.Sprintf(`"%s"`, identifier)
+	return peersql.DoubleQuoteIdentifier(identifier)
+}
+
+// snowflakeDialect quotes identifiers for the generic executor as tables and columns are created by mirrors.
+type snowflakeDialect struct{}
+
+func (snowflakeDialect) QuoteIdentifier(identifier string) string {
+	return SnowflakeIdentifierNormalize(identifier)
 }
 
 func snowflakeSchemaTableNormalize(schemaTable *utils.SchemaTable) string {
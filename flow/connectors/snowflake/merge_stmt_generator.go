@@ -6,7 +6,6 @@ import (
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
-	"github.com/PeerDB-io/peer-flow/model/numeric"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 )
 
@@ -24,6 +23,8 @@ type mergeStmtGenerator struct {
 	unchangedToastColumns []string
 	// _PEERDB_IS_DELETED and _SYNCED_AT columns
 	peerdbCols *protos.PeerDBColumns
+	// what happens to numerics which don't fit their columns
+	numericOverflowPolicy protos.NumericOverflowPolicy
 }
 
 func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
@@ -62,15 +63,16 @@ func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
 		// 		"Microseconds*1000) "+
 		// 		"AS %s", toVariantColumnName, columnName, columnName))
 		case qvalue.QValueKindNumeric:
-			precision, scale := numeric.ParseNumericTypmod(column.TypeModifier)
-			if column.TypeModifier == -1 || precision > 38 || scale > 37 {
-				precision = numeric.PeerDBNumericPrecision
-				scale = numeric.PeerDBNumericScale
-			}
+			precision, scale := qvalue.DetermineNumericSettingForTypmod(column.TypeModifier, qvalue.QDWHTypeSnowflake)
 			numericType := fmt.Sprintf("NUMERIC(%d,%d)", precision, scale)
+			// TRY_CAST nulls values with too many integer digits, CAST fails on them
+			castFunc := "TRY_CAST"
+			if m.numericOverflowPolicy == protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ERROR {
+				castFunc = "CAST"
+			}
 			flattenedCastsSQLArray = append(flattenedCastsSQLArray,
-				fmt.Sprintf("TRY_CAST((%s:\"%s\")::text AS %s) AS %s",
-					toVariantColumnName, column.Name, numericType, targetColumnName))
+				fmt.Sprintf("%s((%s:\"%s\")::text AS %s) AS %s",
+					castFunc, toVariantColumnName, column.Name, numericType, targetColumnName))
 		default:
 			flattenedCastsSQLArray = append(flattenedCastsSQLArray, fmt.Sprintf("CAST(%s:\"%s\" AS %s) AS %s",
 				toVariantColumnName, column.Name, sfType, targetColumnName))
@@ -119,7 +121,12 @@ func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
 	for _, pkeyColName := range m.normalizedTableSchema.PrimaryKeyColumns {
 		normalizedPkeyColName := SnowflakeIdentifierNormalize(pkeyColName)
 		normalizedpkeyColsArray = append(normalizedpkeyColsArray, normalizedPkeyColName)
-		pkeySelectSQLArray = append(pkeySelectSQLArray, fmt.Sprintf("TARGET.%s = SOURCE.%s",
+		pkeyMatch := "TARGET.%s = SOURCE.%s"
+		if m.normalizedTableSchema.SyntheticPrimaryKey {
+			// synthetic keys span all columns, which may be NULL
+			pkeyMatch = "EQUAL_NULL(TARGET.%s, SOURCE.%s)"
+		}
+		pkeySelectSQLArray = append(pkeySelectSQLArray, fmt.Sprintf(pkeyMatch,
 			normalizedPkeyColName, normalizedPkeyColName))
 	}
 	// TARGET.<pkey1> = SOURCE.<pkey1> AND TARGET.<pkey2> = SOURCE.<pkey2> ...
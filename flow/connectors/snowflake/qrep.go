@@ -35,16 +35,6 @@ func (c *SnowflakeConnector) SyncQRepRecords(
 	}
 	c.logger.Info("Called QRep sync function and obtained table schema", flowLog)
 
-	done, err := c.pgMetadata.IsQrepPartitionSynced(ctx, config.FlowJobName, partition.PartitionId)
-	if err != nil {
-		return 0, fmt.Errorf("failed to check if partition %s is synced: %w", partition.PartitionId, err)
-	}
-
-	if done {
-		c.logger.Info("Partition has already been synced", flowLog)
-		return 0, nil
-	}
-
 	avroSync := NewSnowflakeAvroSyncHandler(config, c)
 	return avroSync.SyncQRepRecords(ctx, config, partition, tblSchema, stream)
 }
@@ -171,6 +161,21 @@ func (c *SnowflakeConnector) ConsolidateQRepPartitions(ctx context.Context, conf
 		return fmt.Errorf("failed to copy stage to destination: %w", err)
 	}
 
+	if config.NativeImport {
+		// COPY skips files it already loaded, so checkpointing after the fact is safe on retries
+		pending, err := c.pgMetadata.GetPendingQrepImports(ctx, config.FlowJobName)
+		if err != nil {
+			return err
+		}
+		partitionIDs := make([]string, 0, len(pending))
+		for partitionID := range pending {
+			partitionIDs = append(partitionIDs, partitionID)
+		}
+		if err := c.pgMetadata.FinishQrepImport(ctx, config.FlowJobName, partitionIDs); err != nil {
+			return fmt.Errorf("failed to checkpoint imported partitions: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/shared"
@@ -19,6 +21,8 @@ type SnowflakeAvroConsolidateHandler struct {
 	stage        string
 	allColNames  []string
 	allColTypes  []string
+	// set when retrying a CDC batch into a mirror's raw table, rows earlier attempts at the batch loaded are replaced
+	replaceSyncBatchID int64
 }
 
 // NewSnowflakeAvroConsolidateHandler creates a new SnowflakeAvroWriteHandler
@@ -130,9 +134,13 @@ func (s *SnowflakeAvroConsolidateHandler) getCopyTransformation(copyDstTable str
 
 func (s *SnowflakeAvroConsolidateHandler) handleAppendMode(ctx context.Context) error {
 	parsedDstTable, _ := utils.ParseSchemaTable(s.dstTableName)
-	copyCmd := s.getCopyTransformation(snowflakeSchemaTableNormalize(parsedDstTable))
+	dstTable := snowflakeSchemaTableNormalize(parsedDstTable)
+	copyCmd := s.getCopyTransformation(dstTable)
 	s.connector.logger.Info("running copy command: " + copyCmd)
-	_, err := s.connector.database.ExecContext(ctx, copyCmd)
+	if s.replaceSyncBatchID != 0 {
+		return s.replaceBatch(ctx, dstTable, copyCmd)
+	}
+	_, err := s.connector.execCancelable(ctx, s.connector.database, copyCmd)
 	if err != nil {
 		return fmt.Errorf("failed to run COPY INTO command: %w", err)
 	}
@@ -141,6 +149,33 @@ func (s *SnowflakeAvroConsolidateHandler) handleAppendMode(ctx context.Context)
 	return nil
 }
 
+// replaceBatch deletes the rows of the batch left by an earlier attempt and copies the stage in the same transaction.
+// The stage's file is named afresh by every attempt, so COPY's load history doesn't skip it like a file it loaded before.
+func (s *SnowflakeAvroConsolidateHandler) replaceBatch(ctx context.Context, dstTable string, copyCmd string) error {
+	err := s.connector.queryExecutor().WithTx(ctx, nil, func(tx *sqlx.Tx) error {
+		//nolint:gosec
+		deleteCmd := fmt.Sprintf("DELETE FROM %s WHERE _PEERDB_BATCH_ID = %d", dstTable, s.replaceSyncBatchID)
+		res, err := s.connector.execCancelable(ctx, tx, deleteCmd)
+		if err != nil {
+			return fmt.Errorf("failed to delete earlier rows of batch %d: %w", s.replaceSyncBatchID, err)
+		}
+		if deleted, err := res.RowsAffected(); err == nil && deleted > 0 {
+			s.connector.logger.Warn(fmt.Sprintf("replacing %d rows of batch %d from an earlier attempt", deleted, s.replaceSyncBatchID))
+		}
+
+		if _, err := s.connector.execCancelable(ctx, tx, copyCmd); err != nil {
+			return fmt.Errorf("failed to run COPY INTO command: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.connector.logger.Info("copied file from stage " + s.stage + " to table " + s.dstTableName)
+	return nil
+}
+
 func (s *SnowflakeAvroConsolidateHandler) generateUpsertMergeCommand(
 	tempTableName string,
 ) string {
@@ -213,7 +248,7 @@ func (s *SnowflakeAvroConsolidateHandler) handleUpsertMode(ctx context.Context)
 	s.connector.logger.Info("created temp table " + tempTableName)
 
 	copyCmd := s.getCopyTransformation(tempTableName)
-	_, err = s.connector.database.ExecContext(ctx, copyCmd)
+	_, err = s.connector.execCancelable(ctx, s.connector.database, copyCmd)
 	if err != nil {
 		return fmt.Errorf("failed to run COPY INTO command: %w", err)
 	}
@@ -222,7 +257,7 @@ func (s *SnowflakeAvroConsolidateHandler) handleUpsertMode(ctx context.Context)
 	mergeCmd := s.generateUpsertMergeCommand(tempTableName)
 
 	startTime := time.Now()
-	rows, err := s.connector.database.ExecContext(ctx, mergeCmd)
+	rows, err := s.connector.execCancelable(ctx, s.connector.database, mergeCmd)
 	if err != nil {
 		return fmt.Errorf("failed to merge data into destination table '%s': %w", mergeCmd, err)
 	}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
+	"slices"
 	"strings"
-	"time"
 
+	"github.com/jmoiron/sqlx"
 	_ "github.com/snowflakedb/gosnowflake"
 	"go.temporal.io/sdk/activity"
 
@@ -40,6 +42,7 @@ func (s *SnowflakeAvroSyncHandler) SyncRecords(
 	dstTableSchema []*sql.ColumnType,
 	stream *model.QRecordStream,
 	flowJobName string,
+	replaceSyncBatchID int64,
 ) (int, error) {
 	tableLog := slog.String("destinationTable", s.config.DestinationTableIdentifier)
 	dstTableName := s.config.DestinationTableIdentifier
@@ -71,13 +74,13 @@ func (s *SnowflakeAvroSyncHandler) SyncRecords(
 	}
 	s.connector.logger.Info("Created stage " + stage)
 
-	err = s.putFileToStage(ctx, avroFile, stage)
-	if err != nil {
+	if _, err := s.putFileToStage(ctx, avroFile, stage); err != nil {
 		return 0, err
 	}
 	s.connector.logger.Info("pushed avro file to stage", tableLog)
 
 	writeHandler := NewSnowflakeAvroConsolidateHandler(s.connector, s.config, s.config.DestinationTableIdentifier, stage)
+	writeHandler.replaceSyncBatchID = replaceSyncBatchID
 	err = writeHandler.CopyStageToDestination(ctx)
 	if err != nil {
 		return 0, err
@@ -96,7 +99,6 @@ func (s *SnowflakeAvroSyncHandler) SyncQRepRecords(
 	stream *model.QRecordStream,
 ) (int, error) {
 	partitionLog := slog.String(string(shared.PartitionIDKey), partition.PartitionId)
-	startTime := time.Now()
 	dstTableName := config.DestinationTableIdentifier
 
 	schema, err := stream.Schema()
@@ -123,15 +125,18 @@ func (s *SnowflakeAvroSyncHandler) SyncQRepRecords(
 
 	stage := s.connector.getStageNameForJob(config.FlowJobName)
 
-	err = s.putFileToStage(ctx, avroFile, stage)
+	stagedKey, err := s.putFileToStage(ctx, avroFile, stage)
 	if err != nil {
 		return 0, err
 	}
 	s.connector.logger.Info("Put file to stage in Avro sync for snowflake", partitionLog)
 
-	err = s.connector.pgMetadata.FinishQrepPartition(ctx, partition, config.FlowJobName, startTime)
-	if err != nil {
-		return -1, err
+	if config.NativeImport && avroFile.NumRecords > 0 {
+		err = s.connector.pgMetadata.FinishQrepPartitionExport(ctx, config.FlowJobName, partition.PartitionId,
+			stagedKey, avroFile.NumRecords)
+		if err != nil {
+			return -1, fmt.Errorf("failed to record exported partition: %w", err)
+		}
 	}
 
 	activity.RecordHeartbeat(ctx, "finished syncing records")
@@ -168,30 +173,27 @@ func (s *SnowflakeAvroSyncHandler) addMissingColumns(
 	}
 
 	if len(colsToTypes) > 0 {
-		tx, err := s.connector.database.Begin()
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
-		}
-
-		for colName, colType := range colsToTypes {
-			sfColType, err := colType.ToDWHColumnType(qvalue.QDWHTypeSnowflake)
-			if err != nil {
-				return fmt.Errorf("failed to convert QValueKind to Snowflake column type: %w", err)
-			}
-			upperCasedColName := strings.ToUpper(colName)
-			alterTableCmd := fmt.Sprintf("ALTER TABLE %s ", dstTableName)
-			alterTableCmd += fmt.Sprintf("ADD COLUMN IF NOT EXISTS \"%s\" %s;", upperCasedColName, sfColType)
-
-			s.connector.logger.Info(fmt.Sprintf("altering destination table %s with command `%s`",
-				dstTableName, alterTableCmd), partitionLog)
-
-			if _, err := tx.ExecContext(ctx, alterTableCmd); err != nil {
-				return fmt.Errorf("failed to alter destination table: %w", err)
+		err := s.connector.queryExecutor().WithDDLTx(ctx, nil, func(tx *sqlx.Tx) error {
+			for colName, colType := range colsToTypes {
+				sfColType, err := colType.ToDWHColumnType(qvalue.QDWHTypeSnowflake)
+				if err != nil {
+					return fmt.Errorf("failed to convert QValueKind to Snowflake column type: %w", err)
+				}
+				upperCasedColName := strings.ToUpper(colName)
+				alterTableCmd := fmt.Sprintf("ALTER TABLE %s ", dstTableName)
+				alterTableCmd += fmt.Sprintf("ADD COLUMN IF NOT EXISTS \"%s\" %s;", upperCasedColName, sfColType)
+
+				s.connector.logger.Info(fmt.Sprintf("altering destination table %s with command `%s`",
+					dstTableName, alterTableCmd), partitionLog)
+
+				if _, err := tx.ExecContext(ctx, alterTableCmd); err != nil {
+					return fmt.Errorf("failed to alter destination table: %w", err)
+				}
 			}
-		}
-
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
 		s.connector.logger.Info("successfully added missing columns to destination table "+
@@ -223,15 +225,16 @@ func (s *SnowflakeAvroSyncHandler) writeToAvroFile(
 	partitionID string,
 	flowJobName string,
 ) (*avro.AvroFile, error) {
+	codec := avro.StagingCodec(qvalue.QDWHTypeSnowflake, avro.CompressZstd)
 	if s.config.StagingPath == "" {
-		ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, avro.CompressZstd, qvalue.QDWHTypeSnowflake)
+		ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, codec, qvalue.QDWHTypeSnowflake)
 		tmpDir := fmt.Sprintf("%s/peerdb-avro-%s", os.TempDir(), flowJobName)
 		err := os.MkdirAll(tmpDir, os.ModePerm)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create temp dir: %w", err)
 		}
 
-		localFilePath := fmt.Sprintf("%s/%s.avro.zst", tmpDir, partitionID)
+		localFilePath := fmt.Sprintf("%s/%s%s", tmpDir, partitionID, codec.FileExtension())
 		s.connector.logger.Info("writing records to local file " + localFilePath)
 		avroFile, err := ocfWriter.WriteRecordsToAvroFile(ctx, localFilePath)
 		if err != nil {
@@ -240,13 +243,13 @@ func (s *SnowflakeAvroSyncHandler) writeToAvroFile(
 
 		return avroFile, nil
 	} else if strings.HasPrefix(s.config.StagingPath, "s3://") {
-		ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, avro.CompressZstd, qvalue.QDWHTypeSnowflake)
+		ocfWriter := avro.NewPeerDBOCFWriter(stream, avroSchema, codec, qvalue.QDWHTypeSnowflake)
 		s3o, err := utils.NewS3BucketAndPrefix(s.config.StagingPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse staging path: %w", err)
 		}
 
-		s3AvroFileKey := fmt.Sprintf("%s/%s/%s.avro.zst", s3o.Prefix, s.config.FlowJobName, partitionID)
+		s3AvroFileKey := fmt.Sprintf("%s/%s/%s%s", s3o.Prefix, s.config.FlowJobName, partitionID, codec.FileExtension())
 		s.connector.logger.Info("OCF: Writing records to S3",
 			slog.String(string(shared.PartitionIDKey), partitionID))
 		avroFile, err := ocfWriter.WriteRecordsToS3(ctx, s3o.Bucket, s3AvroFileKey, utils.S3PeerCredentials{})
@@ -260,10 +263,12 @@ func (s *SnowflakeAvroSyncHandler) writeToAvroFile(
 	return nil, fmt.Errorf("unsupported staging path: %s", s.config.StagingPath)
 }
 
-func (s *SnowflakeAvroSyncHandler) putFileToStage(ctx context.Context, avroFile *avro.AvroFile, stage string) error {
+// putFileToStage puts a local Avro file to the stage and returns the key of the staged object,
+// files written to S3 are already under the external stage's URL.
+func (s *SnowflakeAvroSyncHandler) putFileToStage(ctx context.Context, avroFile *avro.AvroFile, stage string) (string, error) {
 	if avroFile.StorageLocation != avro.AvroLocalStorage {
 		s.connector.logger.Info("no file to put to stage")
-		return nil
+		return fmt.Sprintf("@%s/%s", stage, path.Base(avroFile.FilePath)), nil
 	}
 
 	activity.RecordHeartbeat(ctx, "putting file to stage")
@@ -274,10 +279,41 @@ func (s *SnowflakeAvroSyncHandler) putFileToStage(ctx context.Context, avroFile
 	})
 	defer shutdown()
 
-	if _, err := s.connector.database.ExecContext(ctx, putCmd); err != nil {
-		return fmt.Errorf("failed to put file to stage: %w", err)
+	// PUT may compress the file, so the staged name is read from its target column
+	rows, err := s.connector.database.QueryContext(ctx, putCmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to put file to stage: %w", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("failed to read result of putting file to stage: %w", err)
+	}
+	targetIdx := slices.IndexFunc(cols, func(col string) bool {
+		return strings.EqualFold(col, "target")
+	})
+	if targetIdx == -1 {
+		return "", fmt.Errorf("result of putting file to stage has no target column: %v", cols)
+	}
+	var target string
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]any, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", fmt.Errorf("failed to read result of putting file to stage: %w", err)
+		}
+		target = values[targetIdx].String
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to put file to stage: %w", err)
+	}
+	if target == "" {
+		return "", fmt.Errorf("putting file %s to stage %s returned no staged file", avroFile.FilePath, stage)
 	}
 
-	s.connector.logger.Info(fmt.Sprintf("put file %s to stage %s", avroFile.FilePath, stage))
-	return nil
+	s.connector.logger.Info(fmt.Sprintf("put file %s to stage %s as %s", avroFile.FilePath, stage, target))
+	return fmt.Sprintf("@%s/%s", stage, target), nil
 }
@@ -0,0 +1,16 @@
+package connsnowflake
+
+import (
+	"context"
+	"fmt"
+)
+
+// PruneRawTable deletes the rows of normalized batches from a mirror's raw table, implementing RawTablePruneConnector.
+func (c *SnowflakeConnector) PruneRawTable(ctx context.Context, flowJobName string, batchID int64) error {
+	rawTableIdentifier := getRawTableIdentifier(flowJobName)
+	if _, err := c.execCancelable(ctx, c.database, fmt.Sprintf("DELETE FROM %s.%s WHERE _PEERDB_BATCH_ID <= %d",
+		c.rawSchema, rawTableIdentifier, batchID)); err != nil {
+		return fmt.Errorf("unable to prune raw table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package connsnowflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func (c *SnowflakeConnector) ScanPrimaryKeys(
+	ctx context.Context,
+	tableIdentifier string,
+	pkeyColumns []string,
+	softDeleteColName string,
+	fn func(key []qvalue.QValue) error,
+) error {
+	return c.ScanRows(ctx, tableIdentifier, pkeyColumns, softDeleteColName, fn)
+}
+
+func (c *SnowflakeConnector) ScanRows(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	softDeleteColName string,
+	fn func(row []qvalue.QValue) error,
+) error {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return err
+	}
+
+	normalizedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		normalizedColumns = append(normalizedColumns, SnowflakeIdentifierNormalize(col))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(normalizedColumns, ","),
+		snowflakeSchemaTableNormalize(parsedTable))
+	if softDeleteColName != "" {
+		query += fmt.Sprintf(" WHERE NOT COALESCE(%s,FALSE)", SnowflakeIdentifierNormalize(softDeleteColName))
+	}
+	executor := c.queryExecutor()
+	defer executor.CloseStatements()
+	return executor.ScanQuery(ctx, query, fn)
+}
+
+func (c *SnowflakeConnector) DeleteRowsByPrimaryKey(
+	ctx context.Context,
+	tableIdentifier string,
+	pkeyColumns []string,
+	keys [][]qvalue.QValue,
+	softDeleteColName string,
+) (int64, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return 0, err
+	}
+
+	normalizedPkeyColumns := make([]string, 0, len(pkeyColumns))
+	for _, col := range pkeyColumns {
+		normalizedPkeyColumns = append(normalizedPkeyColumns, SnowflakeIdentifierNormalize(col))
+	}
+	filter := utils.PrimaryKeyFilter(normalizedPkeyColumns, len(keys), func(int) string { return "?" })
+
+	var query string
+	if softDeleteColName != "" {
+		query = fmt.Sprintf("UPDATE %s SET %s=TRUE WHERE %s", snowflakeSchemaTableNormalize(parsedTable),
+			SnowflakeIdentifierNormalize(softDeleteColName), filter)
+	} else {
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s", snowflakeSchemaTableNormalize(parsedTable), filter)
+	}
+	result, err := c.execCancelable(ctx, c.database, query, primaryKeyArgs(keys)...)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting rows from table %s: %w", parsedTable, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,67 @@
+package connsnowflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	peersql "github.com/PeerDB-io/peer-flow/connectors/sql"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func (c *SnowflakeConnector) GetRowsByPrimaryKey(
+	ctx context.Context,
+	tableIdentifier string,
+	columns []string,
+	pkeyColumns []string,
+	keys [][]qvalue.QValue,
+) (*model.QRecordBatch, error) {
+	parsedTable, err := utils.ParseSchemaTable(tableIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		normalizedColumns = append(normalizedColumns, SnowflakeIdentifierNormalize(col))
+	}
+	normalizedPkeyColumns := make([]string, 0, len(pkeyColumns))
+	for _, col := range pkeyColumns {
+		normalizedPkeyColumns = append(normalizedPkeyColumns, SnowflakeIdentifierNormalize(col))
+	}
+	filter := utils.PrimaryKeyFilter(normalizedPkeyColumns, len(keys), func(int) string { return "?" })
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(normalizedColumns, ","),
+		snowflakeSchemaTableNormalize(parsedTable), filter)
+	executor := c.queryExecutor()
+	defer executor.CloseStatements()
+	return executor.ExecuteAndProcessQuery(ctx, query, primaryKeyArgs(keys)...)
+}
+
+// primaryKeyArgs flattens keys into bind arguments, in the order utils.PrimaryKeyFilter consumes them.
+func primaryKeyArgs(keys [][]qvalue.QValue) []any {
+	var args []any
+	for _, key := range keys {
+		for _, value := range key {
+			// UUIDs are stored as strings in Snowflake
+			if u, ok := value.Value().([16]byte); ok {
+				args = append(args, uuid.UUID(u).String())
+			} else {
+				args = append(args, value.Value())
+			}
+		}
+	}
+	return args
+}
+
+// queryExecutor runs queries on the connector's pool, which outlives the executor,
+// so statements it prepares are to be closed by the caller with CloseStatements.
+func (c *SnowflakeConnector) queryExecutor() *peersql.GenericSQLQueryExecutor {
+	return peersql.NewGenericSQLQueryExecutor(c.logger, sqlx.NewDb(c.database, "snowflake"), snowflakeDialect{},
+		snowflakeTypeToQValueKindMap, qvalue.QValueKindToSnowflakeTypeMap)
+}
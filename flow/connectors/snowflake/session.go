@@ -0,0 +1,101 @@
+package connsnowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+
+	"github.com/snowflakedb/gosnowflake"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// sessions are connection pools opened with a mirror's session overrides, shared by copies of a connector
+type sessions struct {
+	sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+func (s *sessions) close() error {
+	s.Lock()
+	defer s.Unlock()
+	for key, db := range s.dbs {
+		if err := db.Close(); err != nil {
+			return err
+		}
+		delete(s.dbs, key)
+	}
+	return nil
+}
+
+func sessionConfig(config gosnowflake.Config, settings *protos.SnowflakeSessionSettings) gosnowflake.Config {
+	if settings.Warehouse != "" {
+		config.Warehouse = settings.Warehouse
+	}
+	config.Params = maps.Clone(config.Params)
+	if config.Params == nil {
+		config.Params = make(map[string]*string)
+	}
+	if settings.QueryTag != "" {
+		queryTag := settings.QueryTag
+		config.Params["query_tag"] = &queryTag
+	}
+	if settings.StatementTimeoutSeconds != 0 {
+		timeout := strconv.FormatUint(uint64(settings.StatementTimeoutSeconds), 10)
+		config.Params["statement_timeout_in_seconds"] = &timeout
+	}
+	return config
+}
+
+// withSession returns a copy of the connector running its queries with the given session overrides,
+// or the connector itself when there are none. The copy is closed along with the connector.
+func (c *SnowflakeConnector) withSession(
+	ctx context.Context,
+	settings *protos.SnowflakeSessionSettings,
+) (*SnowflakeConnector, error) {
+	if settings.GetWarehouse() == "" && settings.GetQueryTag() == "" && settings.GetStatementTimeoutSeconds() == 0 {
+		return c, nil
+	}
+
+	key := fmt.Sprintf("%s\x00%s\x00%d", settings.Warehouse, settings.QueryTag, settings.StatementTimeoutSeconds)
+	c.sessions.Lock()
+	defer c.sessions.Unlock()
+	db, ok := c.sessions.dbs[key]
+	if !ok {
+		config := sessionConfig(c.config, settings)
+		var err error
+		db, err = openDB(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.PingContext(ctx); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open Snowflake session with warehouse %s: %w", config.Warehouse, err)
+		}
+		c.sessions.dbs[key] = db
+	}
+
+	session := *c
+	session.database = db
+	return &session, nil
+}
+
+// openDB opens a connection pool for config. A DSN can't carry the transport dialing through
+// the peer's tunnel or proxy, so a config with one is handed to the driver as is.
+func openDB(config gosnowflake.Config) (*sql.DB, error) {
+	if config.Transporter != nil {
+		return sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, config)), nil
+	}
+	dsn, err := gosnowflake.DSN(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DSN from Snowflake config: %w", err)
+	}
+	db, err := sql.Open("snowflake", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection to Snowflake peer: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,35 @@
+package connsnowflake
+
+import (
+	"testing"
+
+	"github.com/snowflakedb/gosnowflake"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestSessionConfig(t *testing.T) {
+	peerConfig := gosnowflake.Config{Warehouse: "INGEST_WH"}
+	config := sessionConfig(peerConfig, &protos.SnowflakeSessionSettings{
+		Warehouse:               "MERGE_WH",
+		QueryTag:                "peerdb_normalize",
+		StatementTimeoutSeconds: 3600,
+	})
+	if config.Warehouse != "MERGE_WH" {
+		t.Errorf("Expected warehouse to be overridden, got %s", config.Warehouse)
+	}
+	if tag := config.Params["query_tag"]; tag == nil || *tag != "peerdb_normalize" {
+		t.Errorf("Expected query tag to be set, got %v", tag)
+	}
+	if timeout := config.Params["statement_timeout_in_seconds"]; timeout == nil || *timeout != "3600" {
+		t.Errorf("Expected statement timeout to be set, got %v", timeout)
+	}
+	if peerConfig.Params != nil {
+		t.Errorf("Expected peer config to be left alone, got %v", peerConfig.Params)
+	}
+
+	config = sessionConfig(peerConfig, &protos.SnowflakeSessionSettings{QueryTag: "peerdb_sync"})
+	if config.Warehouse != "INGEST_WH" {
+		t.Errorf("Expected peer warehouse to be kept, got %s", config.Warehouse)
+	}
+}
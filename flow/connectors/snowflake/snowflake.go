@@ -2,28 +2,33 @@ package connsnowflake
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jmoiron/sqlx"
 	"github.com/snowflakedb/gosnowflake"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/log"
 	"golang.org/x/sync/errgroup"
 
 	metadataStore "github.com/PeerDB-io/peer-flow/connectors/external_metadata"
+	peersql "github.com/PeerDB-io/peer-flow/connectors/sql"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/model"
-	"github.com/PeerDB-io/peer-flow/model/numeric"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 	"github.com/PeerDB-io/peer-flow/shared"
 )
@@ -76,6 +81,9 @@ const (
 
 type SnowflakeConnector struct {
 	database   *sql.DB
+	config     gosnowflake.Config
+	sessions   *sessions
+	pooled     *utils.PooledDB
 	pgMetadata *metadataStore.PostgresMetadataStore
 	rawSchema  string
 	logger     log.Logger
@@ -106,56 +114,38 @@ func TableCheck(ctx context.Context, database *sql.DB) error {
 
 	// In a transaction, create a table, insert a row into the table and then drop the table
 	// If any of these steps fail, the transaction will be rolled back
-	tx, err := database.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	// in case we return after error, ensure transaction is rolled back
-	defer func() {
-		deferErr := tx.Rollback()
-		if deferErr != sql.ErrTxDone && deferErr != nil {
-			logger.LoggerFromCtx(ctx).Error("error while rolling back transaction for table check",
-				"error", deferErr)
+	return peersql.WithDDLTxDB(ctx, logger.LoggerFromCtx(ctx), database, nil, func(tx *sql.Tx) error {
+		// create schema
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(createSchemaSQL, dummySchema))
+		if err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
 		}
-	}()
-
-	// create schema
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(createSchemaSQL, dummySchema))
-	if err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
-	}
-
-	// create table
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(createDummyTableSQL, dummySchema, dummyTable))
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
-	}
-
-	// insert row
-	_, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s.%s VALUES ('dummy')", dummySchema, dummyTable))
-	if err != nil {
-		return fmt.Errorf("failed to insert row: %w", err)
-	}
 
-	// drop table
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(dropTableIfExistsSQL, dummySchema, dummyTable))
-	if err != nil {
-		return fmt.Errorf("failed to drop table: %w", err)
-	}
+		// create table
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(createDummyTableSQL, dummySchema, dummyTable))
+		if err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
 
-	// drop schema
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(dropSchemaIfExistsSQL, dummySchema))
-	if err != nil {
-		return fmt.Errorf("failed to drop schema: %w", err)
-	}
+		// insert row
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s.%s VALUES ('dummy')", dummySchema, dummyTable))
+		if err != nil {
+			return fmt.Errorf("failed to insert row: %w", err)
+		}
 
-	// commit transaction
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
+		// drop table
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(dropTableIfExistsSQL, dummySchema, dummyTable))
+		if err != nil {
+			return fmt.Errorf("failed to drop table: %w", err)
+		}
 
-	return nil
+		// drop schema
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(dropSchemaIfExistsSQL, dummySchema))
+		if err != nil {
+			return fmt.Errorf("failed to drop schema: %w", err)
+		}
+		return nil
+	})
 }
 
 func NewSnowflakeConnector(
@@ -169,6 +159,8 @@ func NewSnowflakeConnector(
 		return nil, err
 	}
 
+	// have Snowflake abort statements once the worker running them is gone rather than run them to completion
+	abortDetachedQuery := "true"
 	snowflakeConfig := gosnowflake.Config{
 		Account:          snowflakeProtoConfig.AccountId,
 		User:             snowflakeProtoConfig.Username,
@@ -179,26 +171,52 @@ func NewSnowflakeConnector(
 		Role:             snowflakeProtoConfig.Role,
 		RequestTimeout:   time.Duration(snowflakeProtoConfig.QueryTimeout),
 		DisableTelemetry: true,
-	}
-	snowflakeConfigDSN, err := gosnowflake.DSN(&snowflakeConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get DSN from Snowflake config: %w", err)
+		Params:           map[string]*string{"abort_detached_query": &abortDetachedQuery},
 	}
 
-	database, err := sql.Open("snowflake", snowflakeConfigDSN)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open connection to Snowflake peer: %w", err)
-	}
+	pooled, err := utils.AcquireDB(ctx, snowflakeProtoConfig, snowflakeProtoConfig.PoolConfig,
+		func(ctx context.Context) (*sql.DB, *utils.Dialer, error) {
+			dialer, err := utils.NewDialer(ctx, snowflakeProtoConfig.SshConfig, snowflakeProtoConfig.ProxyConfig)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create tunnel to Snowflake peer: %w", err)
+			}
+			config := snowflakeConfig
+			config.Transporter, err = snowflakeTransport(snowflakeProtoConfig, dialer)
+			if err != nil {
+				dialer.Close()
+				return nil, nil, err
+			}
+
+			database, err := openDB(config)
+			if err != nil {
+				dialer.Close()
+				return nil, nil, err
+			}
 
-	// checking if connection was actually established, since sql.Open doesn't guarantee that
-	err = database.PingContext(ctx)
+			// checking if connection was actually established, since sql.Open doesn't guarantee that
+			err = database.PingContext(ctx)
+			if err != nil {
+				database.Close()
+				dialer.Close()
+				return nil, nil, fmt.Errorf("failed to open connection to Snowflake peer: %w", err)
+			}
+
+			err = TableCheck(ctx, database)
+			if err != nil {
+				database.Close()
+				dialer.Close()
+				return nil, nil, fmt.Errorf("could not validate snowflake peer: %w", err)
+			}
+			return database, dialer, nil
+		})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open connection to Snowflake peer: %w", err)
+		return nil, err
 	}
-
-	err = TableCheck(ctx, database)
+	// sessions open their own pools, through the same tunnel as the shared one
+	snowflakeConfig.Transporter, err = snowflakeTransport(snowflakeProtoConfig, pooled.Dialer)
 	if err != nil {
-		return nil, fmt.Errorf("could not validate snowflake peer: %w", err)
+		pooled.Release()
+		return nil, err
 	}
 
 	rawSchema := "_PEERDB_INTERNAL"
@@ -208,11 +226,15 @@ func NewSnowflakeConnector(
 
 	pgMetadata, err := metadataStore.NewPostgresMetadataStore(ctx)
 	if err != nil {
+		pooled.Release()
 		return nil, fmt.Errorf("could not connect to metadata store: %w", err)
 	}
 
 	return &SnowflakeConnector{
-		database:   database,
+		database:   pooled.DB,
+		config:     snowflakeConfig,
+		sessions:   &sessions{dbs: make(map[string]*sql.DB)},
+		pooled:     pooled,
 		pgMetadata: pgMetadata,
 		rawSchema:  rawSchema,
 		logger:     logger,
@@ -221,19 +243,80 @@ func NewSnowflakeConnector(
 
 func (c *SnowflakeConnector) Close() error {
 	if c != nil {
-		err := c.database.Close()
-		if err != nil {
-			return fmt.Errorf("error while closing connection to Snowflake peer: %w", err)
+		// sessions are the connector's own, unlike the pool shared with other activities
+		defer c.pooled.Release()
+		if err := c.sessions.close(); err != nil {
+			return fmt.Errorf("error while closing Snowflake sessions: %w", err)
 		}
 	}
 	return nil
 }
 
+// snowflakeTransport dials Snowflake through the peer's tunnel and with its TLS settings, nil if it has neither.
+func snowflakeTransport(config *protos.SnowflakeConfig, dialer *utils.Dialer) (http.RoundTripper, error) {
+	if !dialer.Tunneled() && config.TlsConfig == nil {
+		return nil, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if dialer.Tunneled() {
+		transport.Proxy = nil
+		transport.DialContext = dialer.DialContext
+	}
+	if config.TlsConfig != nil {
+		// server name is left to the transport, which talks to more than the account's host
+		tlsConfig, err := utils.CreateTLSConfig(config.TlsConfig, "", tls.VersionTLS12)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS config: %w", err)
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
 func (c *SnowflakeConnector) ConnectionActive(ctx context.Context) error {
 	// This also checks if database exists
 	return c.database.PingContext(ctx)
 }
 
+// UserExpiry returns when the user the connector authenticates as expires, a zero time when it doesn't.
+// Key pairs don't expire themselves, but can't be used once their user did.
+func (c *SnowflakeConnector) UserExpiry(ctx context.Context) (time.Time, error) {
+	var user string
+	if err := c.database.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&user); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get current user: %w", err)
+	}
+	rows, err := c.database.QueryContext(ctx, fmt.Sprintf(`DESC USER "%s"`, strings.ReplaceAll(user, `"`, `""`)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to describe user %s: %w", user, err)
+	}
+	defer rows.Close()
+	var property, value, defaultValue, description sql.NullString
+	for rows.Next() {
+		if err := rows.Scan(&property, &value, &defaultValue, &description); err != nil {
+			return time.Time{}, fmt.Errorf("failed to describe user %s: %w", user, err)
+		}
+		if property.String == "DAYS_TO_EXPIRY" {
+			return userExpiry(value.String, time.Now())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return time.Time{}, fmt.Errorf("failed to describe user %s: %w", user, err)
+	}
+	return time.Time{}, nil
+}
+
+// userExpiry converts the DAYS_TO_EXPIRY property of a user, which is fractional and null for users that don't expire.
+func userExpiry(daysToExpiry string, now time.Time) (time.Time, error) {
+	if daysToExpiry == "" || strings.EqualFold(daysToExpiry, "null") {
+		return time.Time{}, nil
+	}
+	days, err := strconv.ParseFloat(daysToExpiry, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid days to expiry %q: %w", daysToExpiry, err)
+	}
+	return now.Add(time.Duration(days * float64(24*time.Hour))), nil
+}
+
 func (c *SnowflakeConnector) NeedsSetupMetadataTables(_ context.Context) bool {
 	return false
 }
@@ -336,10 +419,9 @@ func (c *SnowflakeConnector) CleanupSetupNormalizedTables(_ context.Context, _ i
 func (c *SnowflakeConnector) SetupNormalizedTable(
 	ctx context.Context,
 	tx interface{},
+	config *protos.SetupNormalizedTableBatchInput,
 	tableIdentifier string,
 	tableSchema *protos.TableSchema,
-	softDeleteColName string,
-	syncedAtColName string,
 ) (bool, error) {
 	normalizedSchemaTable, err := utils.ParseSchemaTable(tableIdentifier)
 	if err != nil {
@@ -354,7 +436,7 @@ func (c *SnowflakeConnector) SetupNormalizedTable(
 	}
 
 	normalizedTableCreateSQL := generateCreateTableSQLForNormalizedTable(
-		normalizedSchemaTable, tableSchema, softDeleteColName, syncedAtColName)
+		normalizedSchemaTable, tableSchema, config.SoftDeleteColName, config.SyncedAtColName)
 	_, err = c.database.ExecContext(ctx, normalizedTableCreateSQL)
 	if err != nil {
 		return false, fmt.Errorf("[sf] error while creating normalized table: %w", err)
@@ -363,7 +445,7 @@ func (c *SnowflakeConnector) SetupNormalizedTable(
 }
 
 // ReplayTableSchemaDeltas changes a destination table to match the schema at source
-// This could involve adding or dropping multiple columns.
+// This could involve adding, dropping, renaming or widening multiple columns.
 func (c *SnowflakeConnector) ReplayTableSchemaDeltas(
 	ctx context.Context,
 	flowJobName string,
@@ -373,47 +455,104 @@ func (c *SnowflakeConnector) ReplayTableSchemaDeltas(
 		return nil
 	}
 
-	tableSchemaModifyTx, err := c.database.Begin()
-	if err != nil {
-		return fmt.Errorf("error starting transaction for schema modification: %w",
-			err)
-	}
-	defer func() {
-		deferErr := tableSchemaModifyTx.Rollback()
-		if deferErr != sql.ErrTxDone && deferErr != nil {
-			c.logger.Error("error rolling back transaction for table schema modification", "error", deferErr)
-		}
-	}()
+	err := c.queryExecutor().WithDDLTx(ctx, nil, func(tableSchemaModifyTx *sqlx.Tx) error {
+		for _, schemaDelta := range schemaDeltas {
+			if model.IsEmptySchemaDelta(schemaDelta) {
+				continue
+			}
 
-	for _, schemaDelta := range schemaDeltas {
-		if schemaDelta == nil || len(schemaDelta.AddedColumns) == 0 {
-			continue
-		}
+			for _, droppedColumn := range schemaDelta.DroppedColumns {
+				_, err := tableSchemaModifyTx.ExecContext(ctx,
+					fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS \"%s\"",
+						schemaDelta.DstTableName, strings.ToUpper(droppedColumn)))
+				if err != nil {
+					return fmt.Errorf("failed to drop column %s for table %s: %w", droppedColumn,
+						schemaDelta.DstTableName, err)
+				}
+				c.logger.Info("[schema delta replay] dropped column "+droppedColumn,
+					"destination table name", schemaDelta.DstTableName,
+					"source table name", schemaDelta.SrcTableName)
+			}
 
-		for _, addedColumn := range schemaDelta.AddedColumns {
-			sfColtype, err := qValueKindToSnowflakeType(qvalue.QValueKind(addedColumn.ColumnType))
-			if err != nil {
-				return fmt.Errorf("failed to convert column type %s to snowflake type: %w",
-					addedColumn.ColumnType, err)
+			if len(schemaDelta.RenamedColumns) != 0 {
+				// Snowflake has no RENAME COLUMN IF EXISTS, columns renamed by an earlier replay are skipped
+				columns, _, err := c.getColsFromTable(ctx, schemaDelta.DstTableName)
+				if err != nil {
+					return err
+				}
+				for _, renamedColumn := range schemaDelta.RenamedColumns {
+					if !slices.Contains(columns, strings.ToUpper(renamedColumn.OldName)) {
+						continue
+					}
+					_, err := tableSchemaModifyTx.ExecContext(ctx,
+						fmt.Sprintf("ALTER TABLE %s RENAME COLUMN \"%s\" TO \"%s\"", schemaDelta.DstTableName,
+							strings.ToUpper(renamedColumn.OldName), strings.ToUpper(renamedColumn.NewName)))
+					if err != nil {
+						return fmt.Errorf("failed to rename column %s to %s for table %s: %w", renamedColumn.OldName,
+							renamedColumn.NewName, schemaDelta.DstTableName, err)
+					}
+					c.logger.Info(fmt.Sprintf("[schema delta replay] renamed column %s to %s",
+						renamedColumn.OldName, renamedColumn.NewName),
+						"destination table name", schemaDelta.DstTableName,
+						"source table name", schemaDelta.SrcTableName)
+				}
 			}
-			_, err = tableSchemaModifyTx.ExecContext(ctx,
-				fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS \"%s\" %s",
-					schemaDelta.DstTableName, strings.ToUpper(addedColumn.ColumnName), sfColtype))
-			if err != nil {
-				return fmt.Errorf("failed to add column %s for table %s: %w", addedColumn.ColumnName,
-					schemaDelta.DstTableName, err)
+
+			for _, alteredColumn := range schemaDelta.AlteredColumns {
+				oldType, err := qValueKindToSnowflakeType(qvalue.QValueKind(alteredColumn.OldType))
+				if err != nil {
+					return fmt.Errorf("failed to convert column type %s to snowflake type: %w", alteredColumn.OldType, err)
+				}
+				newType, err := qValueKindToSnowflakeType(qvalue.QValueKind(alteredColumn.NewType))
+				if err != nil {
+					return fmt.Errorf("failed to convert column type %s to snowflake type: %w", alteredColumn.NewType, err)
+				}
+				if oldType == newType {
+					continue
+				}
+				// Snowflake only changes types in place by widening text, loading values of other types
+				// into the old column would truncate or reject them
+				if newType != "STRING" {
+					return fmt.Errorf("can't change type of column %s of table %s from %s to %s in place, "+
+						"change it on the destination or resync the table", alteredColumn.ColumnName,
+						schemaDelta.DstTableName, oldType, newType)
+				}
+				_, err = tableSchemaModifyTx.ExecContext(ctx,
+					fmt.Sprintf("ALTER TABLE %s ALTER COLUMN \"%s\" SET DATA TYPE %s",
+						schemaDelta.DstTableName, strings.ToUpper(alteredColumn.ColumnName), newType))
+				if err != nil {
+					return fmt.Errorf("failed to change type of column %s for table %s: %w", alteredColumn.ColumnName,
+						schemaDelta.DstTableName, err)
+				}
+				c.logger.Info(fmt.Sprintf("[schema delta replay] changed type of column %s from %s to %s",
+					alteredColumn.ColumnName, oldType, newType),
+					"destination table name", schemaDelta.DstTableName,
+					"source table name", schemaDelta.SrcTableName)
 			}
-			c.logger.Info(fmt.Sprintf("[schema delta replay] added column %s with data type %s", addedColumn.ColumnName,
-				addedColumn.ColumnType),
-				"destination table name", schemaDelta.DstTableName,
-				"source table name", schemaDelta.SrcTableName)
-		}
-	}
 
-	err = tableSchemaModifyTx.Commit()
+			for _, addedColumn := range schemaDelta.AddedColumns {
+				sfColtype, err := qValueKindToSnowflakeType(qvalue.QValueKind(addedColumn.ColumnType))
+				if err != nil {
+					return fmt.Errorf("failed to convert column type %s to snowflake type: %w",
+						addedColumn.ColumnType, err)
+				}
+				_, err = tableSchemaModifyTx.ExecContext(ctx,
+					fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS \"%s\" %s",
+						schemaDelta.DstTableName, strings.ToUpper(addedColumn.ColumnName), sfColtype))
+				if err != nil {
+					return fmt.Errorf("failed to add column %s for table %s: %w", addedColumn.ColumnName,
+						schemaDelta.DstTableName, err)
+				}
+				c.logger.Info(fmt.Sprintf("[schema delta replay] added column %s with data type %s", addedColumn.ColumnName,
+					addedColumn.ColumnType),
+					"destination table name", schemaDelta.DstTableName,
+					"source table name", schemaDelta.SrcTableName)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction for table schema modification: %w",
-			err)
+		return fmt.Errorf("failed to modify table schemas: %w", err)
 	}
 
 	return nil
@@ -423,7 +562,15 @@ func (c *SnowflakeConnector) SyncRecords(ctx context.Context, req *model.SyncRec
 	rawTableIdentifier := getRawTableIdentifier(req.FlowJobName)
 	c.logger.Info("pushing records to Snowflake table " + rawTableIdentifier)
 
-	res, err := c.syncRecordsViaAvro(ctx, req, rawTableIdentifier, req.SyncBatchID)
+	session, err := c.withSession(ctx, req.SnowflakeSession)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := c.pgMetadata.StartBatch(ctx, req.FlowJobName, req.SyncBatchID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := session.syncRecordsViaAvro(ctx, req, rawTableIdentifier, batch)
 	if err != nil {
 		return nil, err
 	}
@@ -440,8 +587,9 @@ func (c *SnowflakeConnector) syncRecordsViaAvro(
 	ctx context.Context,
 	req *model.SyncRecordsRequest,
 	rawTableIdentifier string,
-	syncBatchID int64,
+	batch *metadataStore.SyncBatch,
 ) (*model.SyncResponse, error) {
+	syncBatchID := batch.SyncBatchID
 	tableNameRowsMapping := make(map[string]uint32)
 	streamReq := model.NewRecordsToStreamRequest(req.Records.GetRecords(), tableNameRowsMapping, syncBatchID)
 	streamRes, err := utils.RecordsToRawTableStream(streamReq)
@@ -461,7 +609,12 @@ func (c *SnowflakeConnector) syncRecordsViaAvro(
 		return nil, err
 	}
 
-	numRecords, err := avroSyncer.SyncRecords(ctx, destinationTableSchema, streamRes.Stream, req.FlowJobName)
+	// only a retry can find rows of the batch in the raw table
+	var replaceSyncBatchID int64
+	if batch.Retry() {
+		replaceSyncBatchID = syncBatchID
+	}
+	numRecords, err := avroSyncer.SyncRecords(ctx, destinationTableSchema, streamRes.Stream, req.FlowJobName, replaceSyncBatchID)
 	if err != nil {
 		return nil, err
 	}
@@ -471,8 +624,13 @@ func (c *SnowflakeConnector) syncRecordsViaAvro(
 		return nil, fmt.Errorf("failed to sync schema changes: %w", err)
 	}
 
+	lastCheckpoint, err := req.Records.GetLastCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+
 	return &model.SyncResponse{
-		LastSyncedCheckpointID: req.Records.GetLastCheckpoint(),
+		LastSyncedCheckpointID: lastCheckpoint,
 		NumRecordsSynced:       int64(numRecords),
 		CurrentSyncBatchID:     syncBatchID,
 		TableNameRowsMapping:   tableNameRowsMapping,
@@ -511,25 +669,19 @@ func (c *SnowflakeConnector) NormalizeRecords(ctx context.Context, req *model.No
 		return nil, fmt.Errorf("couldn't tablename to unchanged cols mapping: %w", err)
 	}
 
+	// MERGEs run with the mirror's normalize session, which may use its own warehouse
+	session, err := c.withSession(ctx, req.SnowflakeSession)
+	if err != nil {
+		return nil, err
+	}
+
 	var totalRowsAffected int64 = 0
 	g, gCtx := errgroup.WithContext(ctx)
 	g.SetLimit(8) // limit parallel merges to 8
 
 	for _, tableName := range destinationTableNames {
 		g.Go(func() error {
-			mergeGen := &mergeStmtGenerator{
-				rawTableName:          getRawTableIdentifier(req.FlowJobName),
-				dstTableName:          tableName,
-				syncBatchID:           req.SyncBatchID,
-				normalizeBatchID:      normBatchID,
-				normalizedTableSchema: req.TableNameSchemaMapping[tableName],
-				unchangedToastColumns: tableNameToUnchangedToastCols[tableName],
-				peerdbCols: &protos.PeerDBColumns{
-					SoftDelete:        req.SoftDelete,
-					SoftDeleteColName: req.SoftDeleteColName,
-					SyncedAtColName:   req.SyncedAtColName,
-				},
-			}
+			mergeGen := newMergeStmtGenerator(req, normBatchID, tableName, tableNameToUnchangedToastCols[tableName])
 			mergeStatement, err := mergeGen.generateMergeStmt()
 			if err != nil {
 				return err
@@ -538,7 +690,7 @@ func (c *SnowflakeConnector) NormalizeRecords(ctx context.Context, req *model.No
 			startTime := time.Now()
 			c.logger.Info("[merge] merging records...", "destTable", tableName)
 
-			result, err := c.database.ExecContext(gCtx, mergeStatement, tableName)
+			result, err := session.execCancelable(gCtx, session.database, mergeStatement, tableName)
 			if err != nil {
 				return fmt.Errorf("failed to merge records into %s (statement: %s): %w",
 					tableName, mergeStatement, err)
@@ -578,28 +730,89 @@ func (c *SnowflakeConnector) NormalizeRecords(ctx context.Context, req *model.No
 	}, nil
 }
 
-func (c *SnowflakeConnector) CreateRawTable(ctx context.Context, req *protos.CreateRawTableInput) (*protos.CreateRawTableOutput, error) {
-	_, err := c.database.ExecContext(ctx, fmt.Sprintf(createSchemaSQL, c.rawSchema))
+func newMergeStmtGenerator(
+	req *model.NormalizeRecordsRequest,
+	normBatchID int64,
+	tableName string,
+	unchangedToastColumns []string,
+) *mergeStmtGenerator {
+	return &mergeStmtGenerator{
+		rawTableName:          getRawTableIdentifier(req.FlowJobName),
+		dstTableName:          tableName,
+		syncBatchID:           req.SyncBatchID,
+		normalizeBatchID:      normBatchID,
+		normalizedTableSchema: req.TableNameSchemaMapping[tableName],
+		unchangedToastColumns: unchangedToastColumns,
+		peerdbCols: &protos.PeerDBColumns{
+			SoftDelete:        req.SoftDelete,
+			SoftDeleteColName: req.SoftDeleteColName,
+			SyncedAtColName:   req.SyncedAtColName,
+		},
+		numericOverflowPolicy: req.NumericOverflowPolicy,
+	}
+}
+
+// SimulateNormalize renders the MERGE statement NormalizeRecords would run for a table,
+// optionally with the plan from EXPLAIN USING TEXT.
+func (c *SnowflakeConnector) SimulateNormalize(
+	ctx context.Context,
+	req *model.NormalizeRecordsRequest,
+	tableName string,
+	explain bool,
+) (*model.NormalizeSimulation, error) {
+	normBatchID, err := c.GetLastNormalizeBatchID(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+	simulation := &model.NormalizeSimulation{
+		StartBatchID: normBatchID + 1,
+		EndBatchID:   req.SyncBatchID,
+	}
+	if normBatchID >= req.SyncBatchID {
+		return simulation, nil
+	}
+
+	tableNameToUnchangedToastCols, err := c.getTableNameToUnchangedCols(ctx, req.FlowJobName, req.SyncBatchID, normBatchID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't tablename to unchanged cols mapping: %w", err)
+	}
+	mergeStatement, err := newMergeStmtGenerator(req, normBatchID, tableName,
+		tableNameToUnchangedToastCols[tableName]).generateMergeStmt()
 	if err != nil {
 		return nil, err
 	}
 
-	createRawTableTx, err := c.database.BeginTx(ctx, nil)
+	stmt := model.NormalizeStatement{
+		SQL:  mergeStatement,
+		Args: []any{tableName},
+	}
+	if explain {
+		err := c.database.QueryRowContext(ctx, "EXPLAIN USING TEXT "+mergeStatement, tableName).Scan(&stmt.Plan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain merge statement for %s: %w", tableName, err)
+		}
+	}
+	simulation.Statements = append(simulation.Statements, stmt)
+	return simulation, nil
+}
+
+func (c *SnowflakeConnector) CreateRawTable(ctx context.Context, req *protos.CreateRawTableInput) (*protos.CreateRawTableOutput, error) {
+	_, err := c.database.ExecContext(ctx, fmt.Sprintf(createSchemaSQL, c.rawSchema))
 	if err != nil {
-		return nil, fmt.Errorf("unable to begin transaction for creation of raw table: %w", err)
+		return nil, err
 	}
+
 	// there is no easy way to check if a table has the same schema in Snowflake,
 	// so just executing the CREATE TABLE IF NOT EXISTS blindly.
 	rawTableIdentifier := getRawTableIdentifier(req.FlowJobName)
-	_, err = createRawTableTx.ExecContext(ctx,
-		fmt.Sprintf(createRawTableSQL, c.rawSchema, rawTableIdentifier))
+	err = c.queryExecutor().WithDDLTx(ctx, nil, func(createRawTableTx *sqlx.Tx) error {
+		_, err := createRawTableTx.ExecContext(ctx,
+			fmt.Sprintf(createRawTableSQL, c.rawSchema, rawTableIdentifier))
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("unable to create raw table: %w", err)
 	}
-	err = createRawTableTx.Commit()
-	if err != nil {
-		return nil, fmt.Errorf("unable to commit transaction for creation of raw table: %w", err)
-	}
 
 	stage := c.getStageNameForJob(req.FlowJobName)
 	err = c.createStage(ctx, stage, &protos.QRepConfig{})
@@ -618,17 +831,6 @@ func (c *SnowflakeConnector) SyncFlowCleanup(ctx context.Context, jobName string
 		return fmt.Errorf("unable to clear metadata for sync flow cleanup: %w", err)
 	}
 
-	syncFlowCleanupTx, err := c.database.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("unable to begin transaction for sync flow cleanup: %w", err)
-	}
-	defer func() {
-		deferErr := syncFlowCleanupTx.Rollback()
-		if deferErr != sql.ErrTxDone && deferErr != nil {
-			c.logger.Error("error while rolling back transaction for flow cleanup", "error", deferErr)
-		}
-	}()
-
 	err = c.dropStage(ctx, "", jobName)
 	if err != nil {
 		return err
@@ -668,11 +870,7 @@ func generateCreateTableSQLForNormalizedTable(
 		}
 
 		if genericColumnType == "numeric" {
-			precision, scale := numeric.ParseNumericTypmod(column.TypeModifier)
-			if column.TypeModifier == -1 || precision > 38 || scale > 37 {
-				precision = numeric.PeerDBNumericPrecision
-				scale = numeric.PeerDBNumericScale
-			}
+			precision, scale := qvalue.DetermineNumericSettingForTypmod(column.TypeModifier, qvalue.QDWHTypeSnowflake)
 			sfColType = fmt.Sprintf("NUMERIC(%d,%d)", precision, scale)
 		}
 
@@ -692,8 +890,8 @@ func generateCreateTableSQLForNormalizedTable(
 		createTableSQLArray = append(createTableSQLArray, syncedAtColName+" TIMESTAMP DEFAULT CURRENT_TIMESTAMP")
 	}
 
-	// add composite primary key to the table
-	if len(sourceTableSchema.PrimaryKeyColumns) > 0 {
+	// add composite primary key to the table, a synthetic key spans nullable columns so it isn't a constraint
+	if len(sourceTableSchema.PrimaryKeyColumns) > 0 && !sourceTableSchema.SyntheticPrimaryKey {
 		normalizedPrimaryKeyCols := make([]string, 0, len(sourceTableSchema.PrimaryKeyColumns))
 		for _, primaryKeyCol := range sourceTableSchema.PrimaryKeyColumns {
 			normalizedPrimaryKeyCols = append(normalizedPrimaryKeyCols,
@@ -713,88 +911,78 @@ func getRawTableIdentifier(jobName string) string {
 }
 
 func (c *SnowflakeConnector) RenameTables(ctx context.Context, req *protos.RenameTablesInput) (*protos.RenameTablesOutput, error) {
-	renameTablesTx, err := c.database.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("unable to begin transaction for rename tables: %w", err)
-	}
-	defer func() {
-		deferErr := renameTablesTx.Rollback()
-		if deferErr != sql.ErrTxDone && deferErr != nil {
-			c.logger.Error("error rolling back transaction for renaming tables", "error", err)
-		}
-	}()
-
-	if req.SyncedAtColName != nil {
-		for _, renameRequest := range req.RenameTableOptions {
-			resyncTblName := renameRequest.CurrentName
+	err := c.queryExecutor().WithDDLTx(ctx, nil, func(renameTablesTx *sqlx.Tx) error {
+		if req.SyncedAtColName != nil {
+			for _, renameRequest := range req.RenameTableOptions {
+				resyncTblName := renameRequest.CurrentName
 
-			c.logger.Info(fmt.Sprintf("setting synced at column for table '%s'...", resyncTblName))
+				c.logger.Info(fmt.Sprintf("setting synced at column for table '%s'...", resyncTblName))
 
-			activity.RecordHeartbeat(ctx, fmt.Sprintf("setting synced at column for table '%s'...",
-				resyncTblName))
+				activity.RecordHeartbeat(ctx, fmt.Sprintf("setting synced at column for table '%s'...",
+					resyncTblName))
 
-			_, err = renameTablesTx.ExecContext(ctx,
-				fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP", resyncTblName, *req.SyncedAtColName))
-			if err != nil {
-				return nil, fmt.Errorf("unable to set synced at column for table %s: %w", resyncTblName, err)
+				_, err := renameTablesTx.ExecContext(ctx,
+					fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP", resyncTblName, *req.SyncedAtColName))
+				if err != nil {
+					return fmt.Errorf("unable to set synced at column for table %s: %w", resyncTblName, err)
+				}
 			}
 		}
-	}
 
-	if req.SoftDeleteColName != nil {
-		for _, renameRequest := range req.RenameTableOptions {
-			src := renameRequest.CurrentName
-			dst := renameRequest.NewName
+		if req.SoftDeleteColName != nil {
+			for _, renameRequest := range req.RenameTableOptions {
+				src := renameRequest.CurrentName
+				dst := renameRequest.NewName
 
-			columnNames := make([]string, 0, len(renameRequest.TableSchema.Columns))
-			for _, col := range renameRequest.TableSchema.Columns {
-				columnNames = append(columnNames, col.Name)
-			}
+				columnNames := make([]string, 0, len(renameRequest.TableSchema.Columns))
+				for _, col := range renameRequest.TableSchema.Columns {
+					columnNames = append(columnNames, col.Name)
+				}
 
-			allCols := strings.Join(columnNames, ",")
-			pkeyCols := strings.Join(renameRequest.TableSchema.PrimaryKeyColumns, ",")
+				allCols := strings.Join(columnNames, ",")
+				pkeyCols := strings.Join(renameRequest.TableSchema.PrimaryKeyColumns, ",")
 
-			c.logger.Info(fmt.Sprintf("handling soft-deletes for table '%s'...", dst))
+				c.logger.Info(fmt.Sprintf("handling soft-deletes for table '%s'...", dst))
 
-			activity.RecordHeartbeat(ctx, fmt.Sprintf("handling soft-deletes for table '%s'...", dst))
+				activity.RecordHeartbeat(ctx, fmt.Sprintf("handling soft-deletes for table '%s'...", dst))
 
-			_, err = renameTablesTx.ExecContext(ctx,
-				fmt.Sprintf("INSERT INTO %s(%s) SELECT %s,true AS %s FROM %s WHERE (%s) NOT IN (SELECT %s FROM %s)",
-					src, fmt.Sprintf("%s,%s", allCols, *req.SoftDeleteColName), allCols, *req.SoftDeleteColName,
-					dst, pkeyCols, pkeyCols, src))
-			if err != nil {
-				return nil, fmt.Errorf("unable to handle soft-deletes for table %s: %w", dst, err)
+				_, err := renameTablesTx.ExecContext(ctx,
+					fmt.Sprintf("INSERT INTO %s(%s) SELECT %s,true AS %s FROM %s WHERE (%s) NOT IN (SELECT %s FROM %s)",
+						src, fmt.Sprintf("%s,%s", allCols, *req.SoftDeleteColName), allCols, *req.SoftDeleteColName,
+						dst, pkeyCols, pkeyCols, src))
+				if err != nil {
+					return fmt.Errorf("unable to handle soft-deletes for table %s: %w", dst, err)
+				}
 			}
 		}
-	}
-
-	// renaming and dropping such that the _resync table is the new destination
-	for _, renameRequest := range req.RenameTableOptions {
-		src := renameRequest.CurrentName
-		dst := renameRequest.NewName
 
-		c.logger.Info(fmt.Sprintf("renaming table '%s' to '%s'...", src, dst))
+		// renaming and dropping such that the _resync table is the new destination
+		for _, renameRequest := range req.RenameTableOptions {
+			src := renameRequest.CurrentName
+			dst := renameRequest.NewName
 
-		activity.RecordHeartbeat(ctx, fmt.Sprintf("renaming table '%s' to '%s'...", src, dst))
+			c.logger.Info(fmt.Sprintf("renaming table '%s' to '%s'...", src, dst))
 
-		// drop the dst table if exists
-		_, err = renameTablesTx.ExecContext(ctx, "DROP TABLE IF EXISTS "+dst)
-		if err != nil {
-			return nil, fmt.Errorf("unable to drop table %s: %w", dst, err)
-		}
+			activity.RecordHeartbeat(ctx, fmt.Sprintf("renaming table '%s' to '%s'...", src, dst))
 
-		// rename the src table to dst
-		_, err = renameTablesTx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", src, dst))
-		if err != nil {
-			return nil, fmt.Errorf("unable to rename table %s to %s: %w", src, dst, err)
-		}
+			// drop the dst table if exists
+			_, err := renameTablesTx.ExecContext(ctx, "DROP TABLE IF EXISTS "+dst)
+			if err != nil {
+				return fmt.Errorf("unable to drop table %s: %w", dst, err)
+			}
 
-		c.logger.Info(fmt.Sprintf("successfully renamed table '%s' to '%s'", src, dst))
-	}
+			// rename the src table to dst
+			_, err = renameTablesTx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", src, dst))
+			if err != nil {
+				return fmt.Errorf("unable to rename table %s to %s: %w", src, dst, err)
+			}
 
-	err = renameTablesTx.Commit()
+			c.logger.Info(fmt.Sprintf("successfully renamed table '%s' to '%s'", src, dst))
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to commit transaction for rename tables: %w", err)
+		return nil, fmt.Errorf("unable to rename tables: %w", err)
 	}
 
 	return &protos.RenameTablesOutput{
@@ -805,35 +993,25 @@ func (c *SnowflakeConnector) RenameTables(ctx context.Context, req *protos.Renam
 func (c *SnowflakeConnector) CreateTablesFromExisting(ctx context.Context, req *protos.CreateTablesFromExistingInput) (
 	*protos.CreateTablesFromExistingOutput, error,
 ) {
-	createTablesFromExistingTx, err := c.database.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("unable to begin transaction for rename tables: %w", err)
-	}
-	defer func() {
-		deferErr := createTablesFromExistingTx.Rollback()
-		if deferErr != sql.ErrTxDone && deferErr != nil {
-			c.logger.Info("error rolling back transaction for creating tables", "error", err)
-		}
-	}()
+	err := c.queryExecutor().WithDDLTx(ctx, nil, func(createTablesFromExistingTx *sqlx.Tx) error {
+		for newTable, existingTable := range req.NewToExistingTableMapping {
+			c.logger.Info(fmt.Sprintf("creating table '%s' similar to '%s'", newTable, existingTable))
 
-	for newTable, existingTable := range req.NewToExistingTableMapping {
-		c.logger.Info(fmt.Sprintf("creating table '%s' similar to '%s'", newTable, existingTable))
+			activity.RecordHeartbeat(ctx, fmt.Sprintf("creating table '%s' similar to '%s'", newTable, existingTable))
 
-		activity.RecordHeartbeat(ctx, fmt.Sprintf("creating table '%s' similar to '%s'", newTable, existingTable))
+			// rename the src table to dst
+			_, err := createTablesFromExistingTx.ExecContext(ctx,
+				fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", newTable, existingTable))
+			if err != nil {
+				return fmt.Errorf("unable to create table %s: %w", newTable, err)
+			}
 
-		// rename the src table to dst
-		_, err = createTablesFromExistingTx.ExecContext(ctx,
-			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", newTable, existingTable))
-		if err != nil {
-			return nil, fmt.Errorf("unable to create table %s: %w", newTable, err)
+			c.logger.Info(fmt.Sprintf("successfully created table '%s'", newTable))
 		}
-
-		c.logger.Info(fmt.Sprintf("successfully created table '%s'", newTable))
-	}
-
-	err = createTablesFromExistingTx.Commit()
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("unable to commit transaction for creating tables: %w", err)
+		return nil, fmt.Errorf("unable to create tables from existing: %w", err)
 	}
 
 	return &protos.CreateTablesFromExistingOutput{
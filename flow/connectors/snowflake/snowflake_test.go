@@ -0,0 +1,28 @@
+package connsnowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, daysToExpiry := range []string{"", "null"} {
+		expiresAt, err := userExpiry(daysToExpiry, now)
+		if err != nil || !expiresAt.IsZero() {
+			t.Errorf("Expected no expiry for %q, got %v, %v", daysToExpiry, expiresAt, err)
+		}
+	}
+
+	expiresAt, err := userExpiry("1.5", now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := now.Add(36 * time.Hour); !expiresAt.Equal(expected) {
+		t.Errorf("Expected expiry %v, got %v", expected, expiresAt)
+	}
+
+	if _, err := userExpiry("soon", now); err == nil {
+		t.Error("Expected error for invalid days to expiry")
+	}
+}
@@ -0,0 +1,18 @@
+package peersql
+
+import "strings"
+
+// Dialect quotes identifiers for the database behind a GenericSQLQueryExecutor,
+// names of schemas, tables and columns are never interpolated into SQL unquoted.
+type Dialect interface {
+	QuoteIdentifier(identifier string) string
+}
+
+func (g *GenericSQLQueryExecutor) quoteTable(schemaName string, tableName string) string {
+	return g.dialect.QuoteIdentifier(schemaName) + "." + g.dialect.QuoteIdentifier(tableName)
+}
+
+// DoubleQuoteIdentifier quotes an identifier the SQL standard way, doubling embedded quotes.
+func DoubleQuoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"reflect"
 	"strings"
 
 	"github.com/google/uuid"
@@ -33,28 +35,42 @@ type SQLQueryExecutor interface {
 
 	ExecuteAndProcessQuery(ctx context.Context, query string, args ...interface{}) (*model.QRecordBatch, error)
 	NamedExecuteAndProcessQuery(ctx context.Context, query string, arg interface{}) (*model.QRecordBatch, error)
+	ExecuteAndStreamQuery(ctx context.Context, stream *model.QRecordStream, query string, args ...interface{}) (int, error)
+	NamedExecuteAndStreamQuery(ctx context.Context, stream *model.QRecordStream, query string, arg interface{}) (int, error)
 	ExecuteQuery(ctx context.Context, query string, args ...interface{}) error
 	NamedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+	WithTx(ctx context.Context, opts *sql.TxOptions, fn func(*sqlx.Tx) error) error
+	WithDDLTx(ctx context.Context, opts *sql.TxOptions, fn func(*sqlx.Tx) error) error
 }
 
 type GenericSQLQueryExecutor struct {
 	db                 *sqlx.DB
+	dialect            Dialect
 	dbtypeToQValueKind map[string]qvalue.QValueKind
 	qvalueKindToDBType map[qvalue.QValueKind]string
 	logger             log.Logger
+	// queries are prepared once per executor, connectors copy the executor so the caches are shared
+	stmts      *stmtCache[*sqlx.Stmt]
+	namedStmts *stmtCache[*sqlx.NamedStmt]
 }
 
 func NewGenericSQLQueryExecutor(
 	logger log.Logger,
 	db *sqlx.DB,
+	dialect Dialect,
 	dbtypeToQValueKind map[string]qvalue.QValueKind,
 	qvalueKindToDBType map[qvalue.QValueKind]string,
 ) *GenericSQLQueryExecutor {
 	return &GenericSQLQueryExecutor{
 		db:                 db,
+		dialect:            dialect,
 		dbtypeToQValueKind: dbtypeToQValueKind,
 		qvalueKindToDBType: qvalueKindToDBType,
 		logger:             logger,
+		stmts:              newStmtCache(db.PreparexContext),
+		namedStmts:         newStmtCache(db.PrepareNamedContext),
 	}
 }
 
@@ -64,16 +80,21 @@ func (g *GenericSQLQueryExecutor) ConnectionActive(ctx context.Context) bool {
 }
 
 func (g *GenericSQLQueryExecutor) Close() error {
-	return g.db.Close()
+	return errors.Join(g.CloseStatements(), g.db.Close())
+}
+
+// CloseStatements closes the statements prepared by the executor, leaving open the pool it may share with others.
+func (g *GenericSQLQueryExecutor) CloseStatements() error {
+	return errors.Join(g.stmts.close(), g.namedStmts.close())
 }
 
 func (g *GenericSQLQueryExecutor) CreateSchema(ctx context.Context, schemaName string) error {
-	_, err := g.db.ExecContext(ctx, "CREATE SCHEMA "+schemaName)
+	_, err := g.db.ExecContext(ctx, "CREATE SCHEMA "+g.dialect.QuoteIdentifier(schemaName))
 	return err
 }
 
 func (g *GenericSQLQueryExecutor) DropSchema(ctx context.Context, schemaName string) error {
-	_, err := g.db.ExecContext(ctx, "DROP SCHEMA IF EXISTS "+schemaName+" CASCADE")
+	_, err := g.db.ExecContext(ctx, "DROP SCHEMA IF EXISTS "+g.dialect.QuoteIdentifier(schemaName)+" CASCADE")
 	return err
 }
 
@@ -107,10 +128,10 @@ func (g *GenericSQLQueryExecutor) CreateTable(ctx context.Context, schema *model
 		if !ok {
 			return fmt.Errorf("unsupported qvalue type %s", field.Type)
 		}
-		fields = append(fields, fmt.Sprintf(`"%s" %s`, field.Name, dbType))
+		fields = append(fields, g.dialect.QuoteIdentifier(field.Name)+" "+dbType)
 	}
 
-	command := fmt.Sprintf("CREATE TABLE %s.%s (%s)", schemaName, tableName, strings.Join(fields, ", "))
+	command := fmt.Sprintf("CREATE TABLE %s (%s)", g.quoteTable(schemaName, tableName), strings.Join(fields, ", "))
 
 	_, err := g.db.ExecContext(ctx, command)
 	if err != nil {
@@ -122,7 +143,7 @@ func (g *GenericSQLQueryExecutor) CreateTable(ctx context.Context, schema *model
 
 func (g *GenericSQLQueryExecutor) CountRows(ctx context.Context, schemaName string, tableName string) (int64, error) {
 	var count pgtype.Int8
-	err := g.db.QueryRowxContext(ctx, "SELECT COUNT(*) FROM "+schemaName+"."+tableName).Scan(&count)
+	err := g.db.QueryRowxContext(ctx, "SELECT COUNT(*) FROM "+g.quoteTable(schemaName, tableName)).Scan(&count)
 	return count.Int64, err
 }
 
@@ -133,8 +154,8 @@ func (g *GenericSQLQueryExecutor) CountNonNullRows(
 	columnName string,
 ) (int64, error) {
 	var count pgtype.Int8
-	err := g.db.QueryRowxContext(ctx, "SELECT COUNT(CASE WHEN "+columnName+
-		" IS NOT NULL THEN 1 END) AS non_null_count FROM "+schemaName+"."+tableName).Scan(&count)
+	err := g.db.QueryRowxContext(ctx, "SELECT COUNT(CASE WHEN "+g.dialect.QuoteIdentifier(columnName)+
+		" IS NOT NULL THEN 1 END) AS non_null_count FROM "+g.quoteTable(schemaName, tableName)).Scan(&count)
 	return count.Int64, err
 }
 
@@ -154,6 +175,58 @@ func (g *GenericSQLQueryExecutor) columnTypeToQField(ct *sql.ColumnType) (model.
 }
 
 func (g *GenericSQLQueryExecutor) processRows(ctx context.Context, rows *sqlx.Rows) (*model.QRecordBatch, error) {
+	qfields, err := g.rowsToQFields(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]qvalue.QValue
+	if err := g.scanRows(ctx, rows, qfields, func(qValues []qvalue.QValue) error {
+		records = append(records, qValues)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Return a QRecordBatch
+	return &model.QRecordBatch{
+		Records: records,
+		Schema:  model.NewQRecordSchema(qfields),
+	}, nil
+}
+
+// processRowsStream hands on the schema and rows of rows to stream as they're scanned,
+// blocking while the stream's buffer is full so rows aren't held in memory. The stream is closed once done.
+func (g *GenericSQLQueryExecutor) processRowsStream(ctx context.Context, stream *model.QRecordStream, rows *sqlx.Rows) (int, error) {
+	defer close(stream.Records)
+
+	qfields, err := g.rowsToQFields(rows)
+	if err != nil {
+		_ = stream.SetSchemaError(err)
+		return 0, err
+	}
+	_ = stream.SetSchema(model.NewQRecordSchema(qfields))
+
+	numRows := 0
+	if err := g.scanRows(ctx, rows, qfields, func(qValues []qvalue.QValue) error {
+		select {
+		case stream.Records <- model.QRecordOrError{Record: qValues}:
+			numRows += 1
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}); err != nil {
+		select {
+		case stream.Records <- model.QRecordOrError{Err: err}:
+		case <-ctx.Done():
+		}
+		return numRows, err
+	}
+	return numRows, nil
+}
+
+func (g *GenericSQLQueryExecutor) rowsToQFields(rows *sqlx.Rows) ([]model.QField, error) {
 	dbColTypes, err := rows.ColumnTypes()
 	if err != nil {
 		return nil, err
@@ -170,15 +243,23 @@ func (g *GenericSQLQueryExecutor) processRows(ctx context.Context, rows *sqlx.Ro
 		}
 		qfields[i] = qfield
 	}
+	return qfields, nil
+}
 
-	var records [][]qvalue.QValue
+// scanRows calls fn with each row converted to QValues of qfields.
+func (g *GenericSQLQueryExecutor) scanRows(
+	ctx context.Context,
+	rows *sqlx.Rows,
+	qfields []model.QField,
+	fn func([]qvalue.QValue) error,
+) error {
 	totalRowsProcessed := 0
 	const heartBeatNumRows = 25000
 
 	for rows.Next() {
 		columns, err := rows.Columns()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		values := make([]interface{}, len(columns))
@@ -222,7 +303,7 @@ func (g *GenericSQLQueryExecutor) processRows(ctx context.Context, rows *sqlx.Ro
 		}
 
 		if err := rows.Scan(values...); err != nil {
-			return nil, err
+			return err
 		}
 
 		qValues := make([]qvalue.QValue, len(values))
@@ -230,12 +311,14 @@ func (g *GenericSQLQueryExecutor) processRows(ctx context.Context, rows *sqlx.Ro
 			qv, err := toQValue(qfields[i].Type, val)
 			if err != nil {
 				g.logger.Error("failed to convert value", slog.Any("error", err))
-				return nil, err
+				return err
 			}
 			qValues[i] = qv
 		}
 
-		records = append(records, qValues)
+		if err := fn(qValues); err != nil {
+			return err
+		}
 		totalRowsProcessed += 1
 
 		if totalRowsProcessed%heartBeatNumRows == 0 {
@@ -245,14 +328,9 @@ func (g *GenericSQLQueryExecutor) processRows(ctx context.Context, rows *sqlx.Ro
 
 	if err := rows.Err(); err != nil {
 		g.logger.Error("failed to iterate over rows", slog.Any("Error", err))
-		return nil, err
+		return err
 	}
-
-	// Return a QRecordBatch
-	return &model.QRecordBatch{
-		Records: records,
-		Schema:  model.NewQRecordSchema(qfields),
-	}, nil
+	return nil
 }
 
 func (g *GenericSQLQueryExecutor) ExecuteAndProcessQuery(
@@ -260,7 +338,7 @@ func (g *GenericSQLQueryExecutor) ExecuteAndProcessQuery(
 	query string,
 	args ...interface{},
 ) (*model.QRecordBatch, error) {
-	rows, err := g.db.QueryxContext(ctx, query, args...)
+	rows, err := g.queryx(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -269,12 +347,51 @@ func (g *GenericSQLQueryExecutor) ExecuteAndProcessQuery(
 	return g.processRows(ctx, rows)
 }
 
+// ScanQuery runs a query and calls fn with each row, without holding the result in memory.
+func (g *GenericSQLQueryExecutor) ScanQuery(
+	ctx context.Context,
+	query string,
+	fn func([]qvalue.QValue) error,
+	args ...interface{},
+) error {
+	rows, err := g.queryx(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	qfields, err := g.rowsToQFields(rows)
+	if err != nil {
+		return err
+	}
+	return g.scanRows(ctx, rows, qfields, fn)
+}
+
+// ExecuteAndStreamQuery runs a query and hands on its rows to stream, returning the number of rows handed on.
+// The stream is closed once done, rows are handed on as the stream is read rather than held in memory.
+func (g *GenericSQLQueryExecutor) ExecuteAndStreamQuery(
+	ctx context.Context,
+	stream *model.QRecordStream,
+	query string,
+	args ...interface{},
+) (int, error) {
+	rows, err := g.queryx(ctx, query, args...)
+	if err != nil {
+		_ = stream.SetSchemaError(err)
+		close(stream.Records)
+		return 0, err
+	}
+	defer rows.Close()
+
+	return g.processRowsStream(ctx, stream, rows)
+}
+
 func (g *GenericSQLQueryExecutor) NamedExecuteAndProcessQuery(
 	ctx context.Context,
 	query string,
 	arg interface{},
 ) (*model.QRecordBatch, error) {
-	rows, err := g.db.NamedQueryContext(ctx, query, arg)
+	rows, err := g.namedQueryx(ctx, query, arg)
 	if err != nil {
 		return nil, err
 	}
@@ -283,6 +400,41 @@ func (g *GenericSQLQueryExecutor) NamedExecuteAndProcessQuery(
 	return g.processRows(ctx, rows)
 }
 
+// NamedExecuteAndStreamQuery is ExecuteAndStreamQuery for a query with named parameters.
+func (g *GenericSQLQueryExecutor) NamedExecuteAndStreamQuery(
+	ctx context.Context,
+	stream *model.QRecordStream,
+	query string,
+	arg interface{},
+) (int, error) {
+	rows, err := g.namedQueryx(ctx, query, arg)
+	if err != nil {
+		_ = stream.SetSchemaError(err)
+		close(stream.Records)
+		return 0, err
+	}
+	defer rows.Close()
+
+	return g.processRowsStream(ctx, stream, rows)
+}
+
+// queryx runs a query through the statement prepared for it.
+func (g *GenericSQLQueryExecutor) queryx(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	stmt, err := g.stmts.get(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare query: %w", err)
+	}
+	return stmt.QueryxContext(ctx, args...)
+}
+
+func (g *GenericSQLQueryExecutor) namedQueryx(ctx context.Context, query string, arg interface{}) (*sqlx.Rows, error) {
+	stmt, err := g.namedStmts.get(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare query: %w", err)
+	}
+	return stmt.QueryxContext(ctx, arg)
+}
+
 func (g *GenericSQLQueryExecutor) ExecuteQuery(ctx context.Context, query string, args ...interface{}) error {
 	_, err := g.db.ExecContext(ctx, query, args...)
 	return err
@@ -295,9 +447,13 @@ func (g *GenericSQLQueryExecutor) NamedExec(ctx context.Context, query string, a
 // returns true if any of the columns are null in value
 func (g *GenericSQLQueryExecutor) CheckNull(ctx context.Context, schema string, tableName string, colNames []string) (bool, error) {
 	var count pgtype.Int8
-	joinedString := strings.Join(colNames, " is null or ") + " is null"
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s WHERE %s",
-		schema, tableName, joinedString)
+	quotedColNames := make([]string, 0, len(colNames))
+	for _, colName := range colNames {
+		quotedColNames = append(quotedColNames, g.dialect.QuoteIdentifier(colName))
+	}
+	joinedString := strings.Join(quotedColNames, " is null or ") + " is null"
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s",
+		g.quoteTable(schema, tableName), joinedString)
 
 	err := g.db.QueryRowxContext(ctx, query).Scan(&count)
 	if err != nil {
@@ -309,74 +465,68 @@ func (g *GenericSQLQueryExecutor) CheckNull(ctx context.Context, schema string,
 
 func toQValue(kind qvalue.QValueKind, val interface{}) (qvalue.QValue, error) {
 	if val == nil {
-		return qvalue.QValue{Kind: kind, Value: nil}, nil
+		return qvalue.QValue{Kind: kind}, nil
 	}
 	switch kind {
 	case qvalue.QValueKindInt32:
 		if v, ok := val.(*sql.NullInt32); ok {
 			if v.Valid {
-				return qvalue.QValue{Kind: qvalue.QValueKindInt32, Value: v.Int32}, nil
+				return qvalue.NewInt32(qvalue.QValueKindInt32, v.Int32), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindInt32, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindInt32}, nil
 			}
 		}
 	case qvalue.QValueKindInt64:
 		if v, ok := val.(*sql.NullInt64); ok {
 			if v.Valid {
-				return qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: v.Int64}, nil
+				return qvalue.NewInt64(qvalue.QValueKindInt64, v.Int64), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindInt64}, nil
 			}
 		}
 	case qvalue.QValueKindFloat32:
 		if v, ok := val.(*sql.NullFloat64); ok {
 			if v.Valid {
-				return qvalue.QValue{Kind: qvalue.QValueKindFloat32, Value: float32(v.Float64)}, nil
+				return qvalue.NewFloat32(qvalue.QValueKindFloat32, float32(v.Float64)), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindFloat32, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindFloat32}, nil
 			}
 		}
 	case qvalue.QValueKindFloat64:
 		if v, ok := val.(*sql.NullFloat64); ok {
 			if v.Valid {
-				return qvalue.QValue{Kind: qvalue.QValueKindFloat64, Value: v.Float64}, nil
+				return qvalue.NewFloat64(qvalue.QValueKindFloat64, v.Float64), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindFloat64, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindFloat64}, nil
 			}
 		}
 	case qvalue.QValueKindQChar:
 		if v, ok := val.(uint8); ok {
-			return qvalue.QValue{Kind: qvalue.QValueKindQChar, Value: v}, nil
+			return qvalue.New(qvalue.QValueKindQChar, v), nil
 		}
 	case qvalue.QValueKindString:
 		if v, ok := val.(*sql.NullString); ok {
 			if v.Valid {
-				return qvalue.QValue{Kind: qvalue.QValueKindString, Value: v.String}, nil
+				return qvalue.New(qvalue.QValueKindString, v.String), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindString, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindString}, nil
 			}
 		}
 	case qvalue.QValueKindBoolean:
 		if v, ok := val.(*sql.NullBool); ok {
 			if v.Valid {
-				return qvalue.QValue{Kind: qvalue.QValueKindBoolean, Value: v.Bool}, nil
+				return qvalue.NewBool(qvalue.QValueKindBoolean, v.Bool), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindBoolean, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindBoolean}, nil
 			}
 		}
 	case qvalue.QValueKindTimestamp, qvalue.QValueKindTimestampTZ, qvalue.QValueKindDate,
 		qvalue.QValueKindTime, qvalue.QValueKindTimeTZ:
 		if t, ok := val.(*sql.NullTime); ok {
 			if t.Valid {
-				return qvalue.QValue{
-					Kind:  kind,
-					Value: t.Time,
-				}, nil
+				return qvalue.NewTime(kind, t.Time), nil
 			} else {
-				return qvalue.QValue{
-					Kind:  kind,
-					Value: nil,
-				}, nil
+				return qvalue.QValue{Kind: kind}, nil
 			}
 		}
 	case qvalue.QValueKindNumeric:
@@ -386,14 +536,14 @@ func toQValue(kind qvalue.QValueKind, val interface{}) (qvalue.QValue, error) {
 				if _, ok := numeric.SetString(v.String); !ok {
 					return qvalue.QValue{}, fmt.Errorf("failed to parse numeric: %v", v.String)
 				}
-				return qvalue.QValue{Kind: qvalue.QValueKindNumeric, Value: numeric}, nil
+				return qvalue.New(qvalue.QValueKindNumeric, numeric), nil
 			} else {
-				return qvalue.QValue{Kind: qvalue.QValueKindNumeric, Value: nil}, nil
+				return qvalue.QValue{Kind: qvalue.QValueKindNumeric}, nil
 			}
 		}
 	case qvalue.QValueKindBytes, qvalue.QValueKindBit:
 		if v, ok := val.(*[]byte); ok && v != nil {
-			return qvalue.QValue{Kind: kind, Value: *v}, nil
+			return qvalue.New(kind, *v), nil
 		}
 
 	case qvalue.QValueKindUUID:
@@ -403,11 +553,11 @@ func toQValue(kind qvalue.QValueKind, val interface{}) (qvalue.QValue, error) {
 			if err != nil {
 				return qvalue.QValue{}, fmt.Errorf("failed to parse uuid: %v", *v)
 			}
-			return qvalue.QValue{Kind: qvalue.QValueKindString, Value: uuidVal.String()}, nil
+			return qvalue.New(qvalue.QValueKindString, uuidVal.String()), nil
 		}
 
 		if v, ok := val.(*[16]byte); ok && v != nil {
-			return qvalue.QValue{Kind: qvalue.QValueKindString, Value: *v}, nil
+			return qvalue.New(qvalue.QValueKindString, *v), nil
 		}
 
 	case qvalue.QValueKindJSON:
@@ -425,33 +575,17 @@ func toQValue(kind qvalue.QValueKind, val interface{}) (qvalue.QValue, error) {
 				return qvalue.QValue{}, fmt.Errorf("failed to parse json array: %v", vstring)
 			}
 
-			// assume all elements in the array are of the same type
-			// based on the first element, determine the type
-			if len(v) > 0 {
-				kind := qvalue.QValueKindString
-				switch v[0].(type) {
-				case float32:
-					kind = qvalue.QValueKindArrayFloat32
-				case float64:
-					kind = qvalue.QValueKindArrayFloat64
-				case int16:
-					kind = qvalue.QValueKindArrayInt16
-				case int32:
-					kind = qvalue.QValueKindArrayInt32
-				case int64:
-					kind = qvalue.QValueKindArrayInt64
-				case string:
-					kind = qvalue.QValueKindArrayString
-				}
-
+			// arrays of arrays, objects or elements of different types stay JSON,
+			// as they are what nested arrays are replicated as
+			if kind := jsonArrayKind(v); kind != qvalue.QValueKindJSON {
 				return toQValueArray(kind, v)
 			}
 		}
 
-		return qvalue.QValue{Kind: qvalue.QValueKindJSON, Value: vstring}, nil
+		return qvalue.New(qvalue.QValueKindJSON, vstring), nil
 
 	case qvalue.QValueKindHStore:
-		return qvalue.QValue{Kind: qvalue.QValueKindHStore, Value: val}, nil
+		return qvalue.New(qvalue.QValueKindHStore, val), nil
 
 	case qvalue.QValueKindArrayFloat32, qvalue.QValueKindArrayFloat64,
 		qvalue.QValueKindArrayInt16,
@@ -464,6 +598,29 @@ func toQValue(kind qvalue.QValueKind, val interface{}) (qvalue.QValue, error) {
 	return qvalue.QValue{}, fmt.Errorf("unsupported type %T for kind %s", val, kind)
 }
 
+// jsonArrayKind returns the kind of array a JSON array is a flat array of, based on the type of its elements,
+// or JSON if it's empty or its elements aren't all scalars of the same type.
+func jsonArrayKind(v []interface{}) qvalue.QValueKind {
+	if len(v) == 0 {
+		return qvalue.QValueKindJSON
+	}
+	var kind qvalue.QValueKind
+	switch v[0].(type) {
+	case float64:
+		kind = qvalue.QValueKindArrayFloat64
+	case string:
+		kind = qvalue.QValueKindArrayString
+	default:
+		return qvalue.QValueKindJSON
+	}
+	for _, elem := range v[1:] {
+		if reflect.TypeOf(elem) != reflect.TypeOf(v[0]) {
+			return qvalue.QValueKindJSON
+		}
+	}
+	return kind
+}
+
 func toQValueArray(kind qvalue.QValueKind, value interface{}) (qvalue.QValue, error) {
 	var result interface{}
 	switch kind {
@@ -552,5 +709,5 @@ func toQValueArray(kind qvalue.QValueKind, value interface{}) (qvalue.QValue, er
 		}
 	}
 
-	return qvalue.QValue{Kind: kind, Value: result}, nil
+	return qvalue.New(kind, result), nil
 }
@@ -0,0 +1,74 @@
+package peersql
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// maxCachedStatements bounds how many prepared statements an executor keeps per kind,
+// QRep mirrors run the same few queries for every partition.
+const maxCachedStatements = 64
+
+type cachedStmt[S interface{ Close() error }] struct {
+	query string
+	stmt  S
+}
+
+// stmtCache holds prepared statements by query text, closing the least recently used ones beyond maxCachedStatements.
+// Closing a statement still in use is deferred by database/sql until its rows are closed.
+type stmtCache[S interface{ Close() error }] struct {
+	prepare func(ctx context.Context, query string) (S, error)
+	stmts   map[string]*list.Element
+	order   *list.List
+	lock    sync.Mutex
+}
+
+func newStmtCache[S interface{ Close() error }](prepare func(ctx context.Context, query string) (S, error)) *stmtCache[S] {
+	return &stmtCache[S]{
+		prepare: prepare,
+		stmts:   make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the statement prepared for query, preparing it if it isn't cached.
+func (c *stmtCache[S]) get(ctx context.Context, query string) (S, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.stmts[query]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*cachedStmt[S]).stmt, nil
+	}
+
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		var zero S
+		return zero, err
+	}
+	c.stmts[query] = c.order.PushFront(&cachedStmt[S]{query: query, stmt: stmt})
+	if c.order.Len() > maxCachedStatements {
+		oldest := c.order.Remove(c.order.Back()).(*cachedStmt[S])
+		delete(c.stmts, oldest.query)
+		// a statement failing to close is of no consequence to the query being run
+		_ = oldest.stmt.Close()
+	}
+	return stmt, nil
+}
+
+func (c *stmtCache[S]) close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var errs []error
+	for _, elem := range c.stmts {
+		if err := elem.Value.(*cachedStmt[S]).stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	clear(c.stmts)
+	c.order.Init()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package peersql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStmt struct {
+	query  string
+	closed bool
+}
+
+func (s *fakeStmt) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestStmtCache(t *testing.T) {
+	ctx := context.Background()
+	prepared := 0
+	cache := newStmtCache(func(ctx context.Context, query string) (*fakeStmt, error) {
+		if query == "invalid" {
+			return nil, errors.New("syntax error")
+		}
+		prepared += 1
+		return &fakeStmt{query: query}, nil
+	})
+
+	first, err := cache.get(ctx, "SELECT 0")
+	require.NoError(t, err)
+	again, err := cache.get(ctx, "SELECT 0")
+	require.NoError(t, err)
+	require.Same(t, first, again)
+	require.Equal(t, 1, prepared)
+
+	_, err = cache.get(ctx, "invalid")
+	require.Error(t, err)
+
+	for i := 1; i < maxCachedStatements; i++ {
+		_, err := cache.get(ctx, fmt.Sprintf("SELECT %d", i))
+		require.NoError(t, err)
+	}
+	// SELECT 1 is the least recently used once SELECT 0 is used again
+	_, err = cache.get(ctx, "SELECT 0")
+	require.NoError(t, err)
+	second, err := cache.get(ctx, "SELECT 1")
+	require.NoError(t, err)
+	require.False(t, second.closed)
+	_, err = cache.get(ctx, "SELECT overflow")
+	require.NoError(t, err)
+	require.False(t, first.closed)
+	require.Equal(t, maxCachedStatements+1, prepared)
+	require.Len(t, cache.stmts, maxCachedStatements)
+	_, ok := cache.stmts["SELECT 2"]
+	require.False(t, ok)
+
+	require.NoError(t, cache.close())
+	require.True(t, first.closed)
+	require.True(t, second.closed)
+	require.Empty(t, cache.stmts)
+}
+
+func TestDoubleQuoteIdentifier(t *testing.T) {
+	require.Equal(t, `"users"`, DoubleQuoteIdentifier("users"))
+	require.Equal(t, `"a""; DROP TABLE users; --"`, DoubleQuoteIdentifier(`a"; DROP TABLE users; --`))
+}
@@ -0,0 +1,125 @@
+package peersql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/snowflakedb/gosnowflake"
+	"go.temporal.io/sdk/log"
+)
+
+const (
+	maxTxAttempts       = 5
+	txInitialBackoff    = 200 * time.Millisecond
+	txMaxBackoff        = 5 * time.Second
+	sqlStateSerialize   = "40001"
+	sqlStateDeadlock    = "40P01"
+	mssqlDeadlockVictim = 1205
+	mssqlUpdateConflict = 3960
+	// statement aborted waiting on a lock held by another transaction
+	snowflakeLockWaitAborted = 625
+)
+
+// IsRetryableTxError tells whether a transaction failed because it conflicted with another one,
+// such as a serialization failure or being picked as a deadlock victim, so running it again may succeed.
+func IsRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == sqlStateSerialize || pgErr.Code == sqlStateDeadlock
+	}
+
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		return mssqlErr.Number == mssqlDeadlockVictim || mssqlErr.Number == mssqlUpdateConflict
+	}
+
+	var sfErr *gosnowflake.SnowflakeError
+	if errors.As(err, &sfErr) {
+		return sfErr.Number == snowflakeLockWaitAborted || sfErr.SQLState == sqlStateSerialize
+	}
+
+	return false
+}
+
+// txBackoff is how long to wait before the next attempt at a transaction, doubling with every attempt up to a cap.
+func txBackoff(attempt int) time.Duration {
+	backoff := txInitialBackoff
+	for range attempt {
+		backoff *= 2
+		if backoff >= txMaxBackoff {
+			return txMaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (g *GenericSQLQueryExecutor) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	return g.db.BeginTxx(ctx, opts)
+}
+
+// sqlTx is a transaction of database/sql or sqlx.
+type sqlTx interface {
+	Commit() error
+	Rollback() error
+}
+
+// WithTx runs fn in a transaction, committing it if fn succeeds and rolling it back otherwise.
+// When the transaction fails with a retryable error it's run again from the start, so fn must not have other side effects
+// and must only run DML: DDL commits implicitly on Snowflake, so part of it would already be applied. Use WithDDLTx for DDL.
+func (g *GenericSQLQueryExecutor) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(*sqlx.Tx) error) error {
+	return withTx(ctx, g.logger, func() (*sqlx.Tx, error) { return g.BeginTx(ctx, opts) }, true, fn)
+}
+
+// WithDDLTx runs fn in a transaction like WithTx, but never runs it again, as fn's DDL may be applied even if it fails.
+func (g *GenericSQLQueryExecutor) WithDDLTx(ctx context.Context, opts *sql.TxOptions, fn func(*sqlx.Tx) error) error {
+	return withTx(ctx, g.logger, func() (*sqlx.Tx, error) { return g.BeginTx(ctx, opts) }, false, fn)
+}
+
+// WithDDLTxDB runs fn in a transaction on db like WithDDLTx, for callers without a query executor.
+func WithDDLTxDB(ctx context.Context, logger log.Logger, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	return withTx(ctx, logger, func() (*sql.Tx, error) { return db.BeginTx(ctx, opts) }, false, fn)
+}
+
+func withTx[T sqlTx](ctx context.Context, logger log.Logger, begin func() (T, error), retry bool, fn func(T) error) error {
+	for attempt := 1; ; attempt++ {
+		err := runTx(logger, begin, fn)
+		if err == nil || !retry || !IsRetryableTxError(err) || attempt >= maxTxAttempts {
+			return err
+		}
+
+		backoff := txBackoff(attempt - 1)
+		logger.Warn("transaction conflicted with another, retrying",
+			"attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func runTx[T sqlTx](logger log.Logger, begin func() (T, error), fn func(T) error) error {
+	tx, err := begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			logger.Error("error rolling back transaction", "error", err)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package peersql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/snowflakedb/gosnowflake"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/log"
+)
+
+func TestIsRetryableTxError(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		retryable bool
+	}{
+		{err: &pgconn.PgError{Code: "40001"}, retryable: true},
+		{err: fmt.Errorf("failed to rename table: %w", &pgconn.PgError{Code: "40P01"}), retryable: true},
+		{err: &pgconn.PgError{Code: "42P01"}, retryable: false},
+		{err: mssql.Error{Number: 1205}, retryable: true},
+		{err: fmt.Errorf("failed to commit transaction: %w", mssql.Error{Number: 3960}), retryable: true},
+		{err: mssql.Error{Number: 208}, retryable: false},
+		{err: &gosnowflake.SnowflakeError{Number: 625, SQLState: "57014"}, retryable: true},
+		{err: &gosnowflake.SnowflakeError{Number: 2003, SQLState: "42S02"}, retryable: false},
+		{err: errors.New("deadlock"), retryable: false},
+		{err: nil, retryable: false},
+	} {
+		require.Equal(t, tc.retryable, IsRetryableTxError(tc.err), tc.err)
+	}
+}
+
+func TestTxBackoff(t *testing.T) {
+	require.Equal(t, txInitialBackoff, txBackoff(0))
+	require.Equal(t, 2*txInitialBackoff, txBackoff(1))
+	require.Equal(t, txMaxBackoff, txBackoff(10))
+}
+
+// txCountingDB is a database/sql driver which only runs transactions, counting how they end.
+type txCountingDB struct {
+	// errors returned by the next commits
+	commitErrs []error
+	begins     int
+	commits    int
+	rollbacks  int
+}
+
+func (d *txCountingDB) Connect(context.Context) (driver.Conn, error) {
+	return txCountingConn{db: d}, nil
+}
+func (d *txCountingDB) Driver() driver.Driver { return nil }
+
+type txCountingConn struct {
+	db *txCountingDB
+}
+
+func (c txCountingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("statements not supported")
+}
+func (c txCountingConn) Close() error { return nil }
+func (c txCountingConn) Begin() (driver.Tx, error) {
+	c.db.begins += 1
+	return c, nil
+}
+
+func (c txCountingConn) Commit() error {
+	c.db.commits += 1
+	if len(c.db.commitErrs) != 0 {
+		err := c.db.commitErrs[0]
+		c.db.commitErrs = c.db.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (c txCountingConn) Rollback() error {
+	c.db.rollbacks += 1
+	return nil
+}
+
+func newTxCountingExecutor(t *testing.T) (*GenericSQLQueryExecutor, *txCountingDB) {
+	t.Helper()
+	counting := &txCountingDB{}
+	db := sql.OpenDB(counting)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewGenericSQLQueryExecutor(log.NewStructuredLogger(slog.Default()), sqlx.NewDb(db, "counting"), nil, nil, nil), counting
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	conflict := &pgconn.PgError{Code: "40001"}
+
+	t.Run("commit", func(t *testing.T) {
+		executor, db := newTxCountingExecutor(t)
+		require.NoError(t, executor.WithTx(ctx, nil, func(*sqlx.Tx) error { return nil }))
+		require.Equal(t, 1, db.commits)
+		require.Zero(t, db.rollbacks)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		executor, db := newTxCountingExecutor(t)
+		failed := errors.New("failed")
+		require.ErrorIs(t, executor.WithTx(ctx, nil, func(*sqlx.Tx) error { return failed }), failed)
+		require.Zero(t, db.commits)
+		require.Equal(t, 1, db.rollbacks)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		executor, db := newTxCountingExecutor(t)
+		attempts := 0
+		require.NoError(t, executor.WithTx(ctx, nil, func(*sqlx.Tx) error {
+			attempts += 1
+			if attempts == 1 {
+				return conflict
+			}
+			return nil
+		}))
+		require.Equal(t, 2, attempts)
+		require.Equal(t, 1, db.rollbacks)
+		require.Equal(t, 1, db.commits)
+
+		db.commitErrs = []error{fmt.Errorf("serialization: %w", conflict)}
+		attempts = 0
+		require.NoError(t, executor.WithTx(ctx, nil, func(*sqlx.Tx) error {
+			attempts += 1
+			return nil
+		}))
+		require.Equal(t, 2, attempts, "transactions failing to commit with a conflict are retried")
+	})
+
+	t.Run("not retried", func(t *testing.T) {
+		executor, db := newTxCountingExecutor(t)
+		require.ErrorIs(t, executor.WithDDLTx(ctx, nil, func(*sqlx.Tx) error { return conflict }), conflict)
+		require.Equal(t, 1, db.begins, "transactions with DDL aren't retried")
+
+		// retries stop once the context is done
+		canceledCtx, cancel := context.WithCancel(ctx)
+		require.ErrorIs(t, executor.WithTx(canceledCtx, nil, func(*sqlx.Tx) error {
+			cancel()
+			return conflict
+		}), context.Canceled)
+		require.Equal(t, 2, db.begins)
+	})
+}
@@ -61,7 +61,7 @@ func (c *SQLServerConnector) GetQRepPartitions(
 
 		err := func() error {
 			//nolint:sqlclosecheck
-			rows, err := c.db.NamedQuery(countQuery, params)
+			rows, err := c.db.NamedQueryContext(ctx, countQuery, params)
 			if err != nil {
 				return err
 			}
@@ -117,7 +117,7 @@ func (c *SQLServerConnector) GetQRepPartitions(
 		params := map[string]interface{}{
 			"minVal": minVal,
 		}
-		rows, err = c.db.NamedQuery(partitionsQuery, params)
+		rows, err = c.db.NamedQueryContext(ctx, partitionsQuery, params)
 	} else {
 		partitionsQuery := fmt.Sprintf(
 			`SELECT bucket_v, MIN(v_from) AS start_v, MAX(v_from) AS end_v
@@ -133,7 +133,7 @@ func (c *SQLServerConnector) GetQRepPartitions(
 			config.WatermarkTable,
 		)
 		c.logger.Info("partitions query: " + partitionsQuery)
-		rows, err = c.db.Queryx(partitionsQuery)
+		rows, err = c.db.QueryxContext(ctx, partitionsQuery)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query for partitions: %w", err)
@@ -163,16 +163,58 @@ func (c *SQLServerConnector) PullQRepRecords(
 	config *protos.QRepConfig,
 	partition *protos.QRepPartition,
 ) (*model.QRecordBatch, error) {
+	query, rangeParams, err := c.partitionQuery(config, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	if rangeParams == nil {
+		// this is a full table partition, so just run the query
+		return c.ExecuteAndProcessQuery(ctx, query)
+	}
+	return c.NamedExecuteAndProcessQuery(ctx, query, rangeParams)
+}
+
+// PullQRepRecordStream hands on the records for a given partition to stream as they're read,
+// so partitions aren't held in memory, and closes it once done.
+func (c *SQLServerConnector) PullQRepRecordStream(
+	ctx context.Context,
+	config *protos.QRepConfig,
+	partition *protos.QRepPartition,
+	stream *model.QRecordStream,
+) (int, error) {
+	query, rangeParams, err := c.partitionQuery(config, partition)
+	if err != nil {
+		_ = stream.SetSchemaError(err)
+		close(stream.Records)
+		return 0, err
+	}
+
+	if rangeParams == nil {
+		// this is a full table partition, so just run the query
+		return c.ExecuteAndStreamQuery(ctx, stream, query)
+	}
+	return c.NamedExecuteAndStreamQuery(ctx, stream, query, rangeParams)
+}
+
+// partitionQuery returns the query pulling the records of partition and its named range parameters,
+// which are nil for full table partitions.
+func (c *SQLServerConnector) partitionQuery(
+	config *protos.QRepConfig,
+	partition *protos.QRepPartition,
+) (string, map[string]interface{}, error) {
 	// Build the query to pull records within the range from the source table
 	// Be sure to order the results by the watermark column to ensure consistency across pulls
 	query, err := BuildQuery(c.logger, config.Query)
 	if err != nil {
-		return nil, err
+		return "", nil, err
+	}
+	if config.RowFilter != "" {
+		query = fmt.Sprintf("SELECT * FROM (%s) AS peerdb_filtered WHERE %s", query, config.RowFilter)
 	}
 
 	if partition.FullTablePartition {
-		// this is a full table partition, so just run the query
-		return c.ExecuteAndProcessQuery(ctx, query)
+		return query, nil, nil
 	}
 
 	var rangeStart interface{}
@@ -187,15 +229,13 @@ func (c *SQLServerConnector) PullQRepRecords(
 		rangeStart = x.TimestampRange.Start.AsTime()
 		rangeEnd = x.TimestampRange.End.AsTime()
 	default:
-		return nil, fmt.Errorf("unknown range type: %v", x)
+		return "", nil, fmt.Errorf("unknown range type: %v", x)
 	}
 
-	rangeParams := map[string]interface{}{
+	return query, map[string]interface{}{
 		"startRange": rangeStart,
 		"endRange":   rangeEnd,
-	}
-
-	return c.NamedExecuteAndProcessQuery(ctx, query, rangeParams)
+	}, nil
 }
 
 func BuildQuery(logger log.Logger, query string) (string, error) {
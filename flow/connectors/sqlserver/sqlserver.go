@@ -2,13 +2,16 @@ package connsqlserver
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/microsoft/go-mssqldb"
+	mssql "github.com/microsoft/go-mssqldb"
 	"go.temporal.io/sdk/log"
 
 	peersql "github.com/PeerDB-io/peer-flow/connectors/sql"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 )
@@ -18,41 +21,69 @@ type SQLServerConnector struct {
 
 	config *protos.SqlServerConfig
 	db     *sqlx.DB
+	pooled *utils.PooledDB
 	logger log.Logger
 }
 
 // NewSQLServerConnector creates a new SQL Server connection
 func NewSQLServerConnector(ctx context.Context, config *protos.SqlServerConfig) (*SQLServerConnector, error) {
-	connString := fmt.Sprintf("server=%s;user id=%s;password=%s;port=%d;database=%s;",
-		config.Server, config.User, config.Password, config.Port, config.Database)
+	pooled, err := utils.AcquireDB(ctx, config, config.PoolConfig, func(ctx context.Context) (*sql.DB, *utils.Dialer, error) {
+		connString := fmt.Sprintf("server=%s;user id=%s;password=%s;port=%d;database=%s;",
+			config.Server, config.User, config.Password, config.Port, config.Database)
 
-	db, err := sqlx.Open("sqlserver", connString)
-	if err != nil {
-		return nil, err
-	}
+		connector, err := mssql.NewConnector(connString)
+		if err != nil {
+			return nil, nil, err
+		}
+		dialer, err := utils.NewDialer(ctx, config.SshConfig, config.ProxyConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create tunnel to SQL Server peer: %w", err)
+		}
+		if dialer.Tunneled() {
+			connector.Dialer = dialer
+		}
+		db := sql.OpenDB(connector)
 
-	err = db.PingContext(ctx)
+		err = db.PingContext(ctx)
+		if err != nil {
+			db.Close()
+			dialer.Close()
+			return nil, nil, err
+		}
+		return db, dialer, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(pooled.DB, "sqlserver")
 
 	logger := logger.LoggerFromCtx(ctx)
 
 	genericExecutor := *peersql.NewGenericSQLQueryExecutor(
-		logger, db, sqlServerTypeToQValueKindMap, qValueKindToSQLServerTypeMap)
+		logger, db, sqlServerDialect{}, sqlServerTypeToQValueKindMap, qValueKindToSQLServerTypeMap)
 
 	return &SQLServerConnector{
 		GenericSQLQueryExecutor: genericExecutor,
 		config:                  config,
 		db:                      db,
+		pooled:                  pooled,
 		logger:                  logger,
 	}, nil
 }
 
-// Close closes the database connection
+// sqlServerDialect quotes identifiers with brackets, which don't depend on QUOTED_IDENTIFIER being on.
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) QuoteIdentifier(identifier string) string {
+	return "[" + strings.ReplaceAll(identifier, "]", "]]") + "]"
+}
+
+// Close releases the connector's pool to other activities connecting to the peer
 func (c *SQLServerConnector) Close() error {
 	if c != nil {
-		return c.db.Close()
+		err := c.CloseStatements()
+		c.pooled.Release()
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// ErrNotAdoptable is wrapped by errors about existing tables which a mirror can't replicate into.
+var ErrNotAdoptable = errors.New("can't be adopted")
+
+// AdoptedColumn is a column a mirror writes to, with the type it would have been created with.
+type AdoptedColumn struct {
+	Name string
+	Type string
+	// the column of the table's schema it is created for, nil for the soft delete and synced at columns
+	Source *protos.FieldDescription
+}
+
+// MismatchedColumn is a column an existing table has with another type than the mirror writes to it.
+type MismatchedColumn struct {
+	Column       AdoptedColumn
+	ExistingType string
+}
+
+// TableDrift is how an existing table differs from the columns a mirror writes to it.
+type TableDrift struct {
+	Missing    []AdoptedColumn
+	Mismatched []MismatchedColumn
+	// columns the mirror doesn't write to, they don't keep it from replicating into the table
+	Extra []string
+}
+
+func (d *TableDrift) Empty() bool {
+	return d.Adoptable() && len(d.Extra) == 0
+}
+
+// Adoptable returns whether the table has every column the mirror writes to with the type it writes.
+func (d *TableDrift) Adoptable() bool {
+	return len(d.Missing) == 0 && len(d.Mismatched) == 0
+}
+
+// String describes the drift as "missing columns a, b; column c is x instead of y; extra columns d".
+func (d *TableDrift) String() string {
+	var problems []string
+	if len(d.Missing) != 0 {
+		missing := make([]string, 0, len(d.Missing))
+		for _, column := range d.Missing {
+			missing = append(missing, column.Name)
+		}
+		problems = append(problems, "missing columns "+strings.Join(missing, ", "))
+	}
+	if len(d.Mismatched) != 0 {
+		mismatched := make([]string, 0, len(d.Mismatched))
+		for _, column := range d.Mismatched {
+			mismatched = append(mismatched, fmt.Sprintf("%s is %s instead of %s", column.Column.Name, column.ExistingType, column.Column.Type))
+		}
+		problems = append(problems, "column "+strings.Join(mismatched, ", "))
+	}
+	if len(d.Extra) != 0 {
+		problems = append(problems, "extra columns "+strings.Join(d.Extra, ", "))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Err describes the drift of table as an error wrapping ErrNotAdoptable, nil if it is adoptable.
+func (d *TableDrift) Err(table string) error {
+	if d.Adoptable() {
+		return nil
+	}
+	adoptable := &TableDrift{Missing: d.Missing, Mismatched: d.Mismatched}
+	return fmt.Errorf("table %s %w: %s", table, ErrNotAdoptable, adoptable)
+}
+
+// DiffAdoptedColumns compares an existing table, as a map of its column names to types, with the columns
+// a mirror writes to. Names and types are normalized by the caller, so that a destination's aliases of a type compare equal.
+func DiffAdoptedColumns(expected []AdoptedColumn, existing map[string]string) *TableDrift {
+	drift := &TableDrift{}
+	written := make(map[string]struct{}, len(expected))
+	for _, column := range expected {
+		written[column.Name] = struct{}{}
+		existingType, ok := existing[column.Name]
+		if !ok {
+			drift.Missing = append(drift.Missing, column)
+		} else if existingType != column.Type {
+			drift.Mismatched = append(drift.Mismatched, MismatchedColumn{Column: column, ExistingType: existingType})
+		}
+	}
+	for name := range existing {
+		if _, ok := written[name]; !ok {
+			drift.Extra = append(drift.Extra, name)
+		}
+	}
+	slices.Sort(drift.Extra)
+	return drift
+}
+
+// CheckAdoptedColumns checks that an existing table has every column a mirror writes to with a compatible type.
+func CheckAdoptedColumns(table string, expected []AdoptedColumn, existing map[string]string) error {
+	return DiffAdoptedColumns(expected, existing).Err(table)
+}
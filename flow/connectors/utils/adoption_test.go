@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAdoptedColumns(t *testing.T) {
+	expected := []AdoptedColumn{{Name: "id", Type: "bigint"}, {Name: "name", Type: "text"}}
+
+	require.NoError(t, CheckAdoptedColumns("public.users", expected,
+		map[string]string{"id": "bigint", "name": "text", "notes": "text"}))
+
+	err := CheckAdoptedColumns("public.users", expected, map[string]string{"id": "integer"})
+	require.ErrorIs(t, err, ErrNotAdoptable)
+	require.EqualError(t, err,
+		"table public.users can't be adopted: missing columns name; column id is integer instead of bigint")
+}
+
+func TestDiffAdoptedColumns(t *testing.T) {
+	expected := []AdoptedColumn{{Name: "id", Type: "bigint"}, {Name: "name", Type: "text"}, {Name: "_peerdb_synced_at", Type: "timestamp"}}
+
+	drift := DiffAdoptedColumns(expected, map[string]string{"id": "bigint", "name": "text", "_peerdb_synced_at": "timestamp"})
+	require.True(t, drift.Empty())
+	require.NoError(t, drift.Err("public.users"))
+
+	drift = DiffAdoptedColumns(expected, map[string]string{"id": "text", "notes": "text", "added_by": "text"})
+	require.Equal(t, []AdoptedColumn{expected[1], expected[2]}, drift.Missing)
+	require.Equal(t, []MismatchedColumn{{Column: expected[0], ExistingType: "text"}}, drift.Mismatched)
+	require.Equal(t, []string{"added_by", "notes"}, drift.Extra)
+	require.Equal(t,
+		"missing columns name, _peerdb_synced_at; column id is text instead of bigint; extra columns added_by, notes", drift.String())
+
+	// columns the mirror doesn't write to are drift, but don't keep the table from being adopted
+	drift = DiffAdoptedColumns(expected, map[string]string{"id": "bigint", "name": "text", "_peerdb_synced_at": "timestamp", "notes": "text"})
+	require.False(t, drift.Empty())
+	require.True(t, drift.Adoptable())
+	require.NoError(t, drift.Err("public.users"))
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// avroEncodeBatchSize is how many records are converted together when encoding in parallel,
+// each batch ending up in its own block of the file
+const avroEncodeBatchSize = 1024
+
+// ParseAvroCompressionCodec parses the codec names accepted by PEERDB_AVRO_CODEC.
+func ParseAvroCompressionCodec(name string) (AvroCompressionCodec, error) {
+	switch name {
+	case "none", "null":
+		return CompressNone, nil
+	case "zstd":
+		return CompressZstd, nil
+	case "deflate":
+		return CompressDeflate, nil
+	case "snappy":
+		return CompressSnappy, nil
+	default:
+		return CompressNone, fmt.Errorf("unknown Avro codec %q, expected one of none, zstd, deflate or snappy", name)
+	}
+}
+
+// blockCompression is the OCF codec compressing each block of the file, zstd compresses the file as a whole instead
+// as it's not a block codec goavro implements.
+func (c AvroCompressionCodec) blockCompression() string {
+	switch c {
+	case CompressDeflate:
+		return goavro.CompressionDeflateLabel
+	case CompressSnappy:
+		return goavro.CompressionSnappyLabel
+	default:
+		return goavro.CompressionNullLabel
+	}
+}
+
+// FileExtension is the extension of files written with the codec, which destinations detect compression by.
+func (c AvroCompressionCodec) FileExtension() string {
+	if c == CompressZstd {
+		return ".avro.zst"
+	}
+	return ".avro"
+}
+
+// StagingCodec is the codec of Avro files staged for loading into targetDWH, the one set by PEERDB_AVRO_CODEC
+// if targetDWH can load it and defaultCodec otherwise. BigQuery only loads Avro files compressed by block.
+func StagingCodec(targetDWH qvalue.QDWHType, defaultCodec AvroCompressionCodec) AvroCompressionCodec {
+	name := peerdbenv.PeerDBAvroCodec()
+	if name == "" {
+		return defaultCodec
+	}
+	codec, err := ParseAvroCompressionCodec(name)
+	if err != nil {
+		slog.Warn("ignoring PEERDB_AVRO_CODEC", slog.Any("error", err))
+		return defaultCodec
+	}
+	if codec == CompressZstd && targetDWH == qvalue.QDWHTypeBigQuery {
+		slog.Warn("BigQuery can't load zstd compressed Avro files, ignoring PEERDB_AVRO_CODEC")
+		return defaultCodec
+	}
+	return codec
+}
@@ -14,15 +14,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/djherbis/buffer"
 	"github.com/djherbis/nio/v3"
-	"github.com/klauspost/compress/flate"
-	"github.com/klauspost/compress/snappy"
 	"github.com/klauspost/compress/zstd"
 	"github.com/linkedin/goavro/v2"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
 )
 
 type (
@@ -83,20 +83,14 @@ func NewPeerDBOCFWriter(
 func (p *peerDBOCFWriter) initWriteCloser(w io.Writer) error {
 	var err error
 	switch p.avroCompressionCodec {
-	case CompressNone:
-		p.writer = &nopWriteCloser{w}
 	case CompressZstd:
 		p.writer, err = zstd.NewWriter(w)
 		if err != nil {
 			return fmt.Errorf("error while initializing zstd encoding writer: %w", err)
 		}
-	case CompressDeflate:
-		p.writer, err = flate.NewWriter(w, -1)
-		if err != nil {
-			return fmt.Errorf("error while initializing deflate encoding writer: %w", err)
-		}
-	case CompressSnappy:
-		p.writer = snappy.NewBufferedWriter(w)
+	default:
+		// deflate and snappy compress the blocks of the file instead of all of it
+		p.writer = &nopWriteCloser{w}
 	}
 
 	return nil
@@ -109,8 +103,9 @@ func (p *peerDBOCFWriter) createOCFWriter(w io.Writer) (*goavro.OCFWriter, error
 	}
 
 	ocfWriter, err := goavro.NewOCFWriter(goavro.OCFConfig{
-		W:      p.writer,
-		Schema: p.avroSchema.Schema,
+		W:               p.writer,
+		Schema:          p.avroSchema.Schema,
+		CompressionName: p.avroCompressionCodec.blockCompression(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCF writer: %w", err)
@@ -139,36 +134,148 @@ func (p *peerDBOCFWriter) writeRecordsToOCFWriter(ctx context.Context, ocfWriter
 		defer shutdown()
 	}
 
-	for qRecordOrErr := range p.stream.Records {
-		if qRecordOrErr.Err != nil {
-			logger.Error("[avro] failed to get record from stream", slog.Any("error", qRecordOrErr.Err))
-			return 0, fmt.Errorf("[avro] failed to get record from stream: %w", qRecordOrErr.Err)
-		}
-
-		avroConverter := model.NewQRecordAvroConverter(
-			qRecordOrErr.Record,
+	newConverter := func() *model.QRecordAvroConverter {
+		return model.NewQRecordAvroConverter(
 			p.targetDWH,
 			p.avroSchema.NullableFields,
 			colNames,
 			logger,
 		)
+	}
+	if parallelism := peerdbenv.PeerDBAvroEncodeParallelism(); parallelism > 1 {
+		err = p.encodeParallel(ctx, ocfWriter, newConverter, parallelism, &numRows)
+	} else {
+		err = p.encode(ocfWriter, newConverter(), &numRows)
+	}
+	if err != nil {
+		logger.Error("[avro] failed to write records to OCF", slog.Any("error", err))
+		return 0, err
+	}
 
-		avroMap, err := avroConverter.Convert()
-		if err != nil {
-			logger.Error("failed to convert QRecord to Avro compatible map: ", slog.Any("error", err))
-			return 0, fmt.Errorf("failed to convert QRecord to Avro compatible map: %w", err)
+	return int(numRows.Load()), nil
+}
+
+func (p *peerDBOCFWriter) encode(
+	ocfWriter *goavro.OCFWriter,
+	avroConverter *model.QRecordAvroConverter,
+	numRows *atomic.Uint32,
+) error {
+	// Append encodes the records it's given before returning, so one slice is reused for all of them
+	avroRecord := make([]interface{}, 1)
+
+	for qRecordOrErr := range p.stream.Records {
+		if qRecordOrErr.Err != nil {
+			return fmt.Errorf("[avro] failed to get record from stream: %w", qRecordOrErr.Err)
 		}
 
-		err = ocfWriter.Append([]interface{}{avroMap})
+		avroMap, err := avroConverter.Convert(qRecordOrErr.Record)
 		if err != nil {
-			logger.Error("failed to write record to OCF: ", slog.Any("error", err))
-			return 0, fmt.Errorf("failed to write record to OCF: %w", err)
+			return fmt.Errorf("failed to convert QRecord to Avro compatible map: %w", err)
+		}
+
+		avroRecord[0] = avroMap
+		if err := ocfWriter.Append(avroRecord); err != nil {
+			return fmt.Errorf("failed to write record to OCF: %w", err)
 		}
 
 		numRows.Add(1)
 	}
 
-	return int(numRows.Load()), nil
+	return nil
+}
+
+// avroBatch is a run of records converted by one of the encoding goroutines, done is closed once it's converted
+type avroBatch struct {
+	records [][]qvalue.QValue
+	avro    []interface{}
+	err     error
+	done    chan struct{}
+}
+
+// encodeParallel converts records to Avro on several goroutines, which is most of the cost of encoding wide tables.
+// Batches are appended in the order they were read, each one to a block of the file.
+func (p *peerDBOCFWriter) encodeParallel(
+	ctx context.Context,
+	ocfWriter *goavro.OCFWriter,
+	newConverter func() *model.QRecordAvroConverter,
+	parallelism int,
+	numRows *atomic.Uint32,
+) error {
+	g, gCtx := errgroup.WithContext(ctx)
+	toConvert := make(chan *avroBatch, parallelism)
+	toAppend := make(chan *avroBatch, 2*parallelism)
+
+	for range parallelism {
+		avroConverter := newConverter()
+		g.Go(func() error {
+			for batch := range toConvert {
+				batch.avro = make([]interface{}, 0, len(batch.records))
+				for _, record := range batch.records {
+					avroMap, err := avroConverter.Convert(record)
+					if err != nil {
+						batch.err = fmt.Errorf("failed to convert QRecord to Avro compatible map: %w", err)
+						break
+					}
+					batch.avro = append(batch.avro, avroMap)
+				}
+				close(batch.done)
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		for batch := range toAppend {
+			select {
+			case <-batch.done:
+			case <-gCtx.Done():
+				return gCtx.Err()
+			}
+			if batch.err != nil {
+				return batch.err
+			}
+			if err := ocfWriter.Append(batch.avro); err != nil {
+				return fmt.Errorf("failed to write record to OCF: %w", err)
+			}
+			numRows.Add(uint32(len(batch.avro)))
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		defer close(toAppend)
+		defer close(toConvert)
+
+		send := func(batch *avroBatch) bool {
+			for _, ch := range []chan *avroBatch{toAppend, toConvert} {
+				select {
+				case ch <- batch:
+				case <-gCtx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		batch := &avroBatch{done: make(chan struct{})}
+		for qRecordOrErr := range p.stream.Records {
+			if qRecordOrErr.Err != nil {
+				return fmt.Errorf("[avro] failed to get record from stream: %w", qRecordOrErr.Err)
+			}
+			batch.records = append(batch.records, qRecordOrErr.Record)
+			if len(batch.records) == avroEncodeBatchSize {
+				if !send(batch) {
+					return gCtx.Err()
+				}
+				batch = &avroBatch{done: make(chan struct{})}
+			}
+		}
+		if len(batch.records) > 0 && !send(batch) {
+			return gCtx.Err()
+		}
+		return nil
+	})
+
+	return g.Wait()
 }
 
 func (p *peerDBOCFWriter) WriteOCF(ctx context.Context, w io.Writer) (int, error) {
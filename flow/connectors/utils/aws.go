@@ -1,15 +1,20 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
@@ -95,6 +100,46 @@ func GetClickhouseAWSSecrets(bucketPathSuffix string) *ClickhouseS3Credentials {
 	}
 }
 
+// hex encoded SHA-256 of an empty payload, which is what a presigned GET signs
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// RDSIAMAuthToken builds a token which authenticates user to the RDS instance at host:port in place of a password.
+// It's signed with the credentials of the default AWS credential chain, e.g. the environment,
+// shared config, web identity or the instance role, and is valid for connecting for 15 minutes.
+func RDSIAMAuthToken(ctx context.Context, host string, port uint32, user string, region string) (string, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	awsConfig, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if awsConfig.Region == "" {
+		return "", errors.New("region or AWS_REGION must be set for RDS IAM authentication")
+	}
+	creds, err := awsConfig.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("Action", "connect")
+	query.Set("DBUser", user)
+	query.Set("X-Amz-Expires", "900")
+	endpoint := net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+endpoint+"/?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	signedURL, _, err := v4.NewSigner().PresignHTTP(ctx, creds, req, emptyPayloadHash, "rds-db", awsConfig.Region, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to sign RDS IAM token: %w", err)
+	}
+	// the token is the presigned URL without its scheme
+	return strings.TrimPrefix(signedURL, "https://"), nil
+}
+
 type S3BucketAndPrefix struct {
 	Bucket string
 	Prefix string
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRDSIAMAuthToken(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", t.TempDir()+"/credentials")
+
+	token, err := RDSIAMAuthToken(context.Background(), "db.example.us-west-2.rds.amazonaws.com", 5432, "peerdb", "us-west-2")
+	require.NoError(t, err)
+
+	endpoint, rawQuery, found := strings.Cut(token, "/?")
+	require.True(t, found)
+	require.Equal(t, "db.example.us-west-2.rds.amazonaws.com:5432", endpoint)
+	query, err := url.ParseQuery(rawQuery)
+	require.NoError(t, err)
+	require.Equal(t, "connect", query.Get("Action"))
+	require.Equal(t, "peerdb", query.Get("DBUser"))
+	require.Equal(t, "900", query.Get("X-Amz-Expires"))
+	require.Contains(t, query.Get("X-Amz-Credential"), "/us-west-2/rds-db/aws4_request")
+	require.NotEmpty(t, query.Get("X-Amz-Signature"))
+
+	// signed with the credentials of the default chain, which has the environment's ahead of the shared files
+	require.Contains(t, query.Get("X-Amz-Credential"), "AKIDEXAMPLE/")
+
+	t.Setenv("AWS_REGION", "")
+	_, err = RDSIAMAuthToken(context.Background(), "localhost", 5432, "peerdb", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MirrorRestart tracks the supervisor restarting a mirror whose workflow failed.
+type MirrorRestart struct {
+	// run of the mirror's workflow which failed last
+	FailedRunID   string
+	Restarts      int32
+	NextRestartAt time.Time
+	// when the supervisor last restarted the mirror, nil if it never did
+	RestartedAt *time.Time
+	GaveUp      bool
+}
+
+// GetMirrorRestart returns the restarts of a mirror, nil if it was never restarted.
+func GetMirrorRestart(ctx context.Context, pool *pgxpool.Pool, flowJobName string) (*MirrorRestart, error) {
+	var restart MirrorRestart
+	err := pool.QueryRow(ctx, `SELECT failed_run_id, restarts, next_restart_at, restarted_at, gave_up
+		FROM mirror_restarts WHERE flow_job_name = $1`, flowJobName).Scan(
+		&restart.FailedRunID, &restart.Restarts, &restart.NextRestartAt, &restart.RestartedAt, &restart.GaveUp)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get mirror restarts: %w", err)
+	}
+	return &restart, nil
+}
+
+func SaveMirrorRestart(ctx context.Context, pool *pgxpool.Pool, flowJobName string, restart *MirrorRestart) error {
+	_, err := pool.Exec(ctx, `INSERT INTO mirror_restarts
+		(flow_job_name, failed_run_id, restarts, next_restart_at, restarted_at, gave_up) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (flow_job_name) DO UPDATE SET failed_run_id = excluded.failed_run_id, restarts = excluded.restarts,
+		next_restart_at = excluded.next_restart_at, restarted_at = excluded.restarted_at, gave_up = excluded.gave_up`,
+		flowJobName, restart.FailedRunID, restart.Restarts, restart.NextRestartAt, restart.RestartedAt, restart.GaveUp)
+	if err != nil {
+		return fmt.Errorf("failed to save mirror restarts: %w", err)
+	}
+	return nil
+}
+
+func DeleteMirrorRestart(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM mirror_restarts WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete mirror restarts: %w", err)
+	}
+	return nil
+}
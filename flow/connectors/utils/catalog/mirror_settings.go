@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func DeleteMirrorSettings(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM mirror_settings WHERE flow_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete mirror settings: %w", err)
+	}
+	return nil
+}
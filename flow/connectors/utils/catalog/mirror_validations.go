@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// SaveTableValidation records the drift a mirror validation found for a table, replacing an earlier attempt's.
+func SaveTableValidation(ctx context.Context, pool *pgxpool.Pool, validationID string, flowJobName string,
+	result *protos.TableValidationResult,
+) error {
+	var validationError *string
+	if result.Error != "" {
+		validationError = &result.Error
+	}
+	_, err := pool.Exec(ctx, `INSERT INTO mirror_validations (validation_id, flow_job_name, source_table, destination_table,
+		source_rows, destination_rows, num_chunks, mismatched_chunks, error, validated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (validation_id, source_table) DO UPDATE SET destination_table = excluded.destination_table,
+		source_rows = excluded.source_rows, destination_rows = excluded.destination_rows, num_chunks = excluded.num_chunks,
+		mismatched_chunks = excluded.mismatched_chunks, error = excluded.error, validated_at = excluded.validated_at`,
+		validationID, flowJobName, result.SourceTableIdentifier, result.DestinationTableIdentifier,
+		result.SourceRows, result.DestinationRows, result.NumChunks, result.MismatchedChunks, validationError,
+		result.ValidatedAt.AsTime())
+	if err != nil {
+		return fmt.Errorf("failed to save table validation: %w", err)
+	}
+	return nil
+}
+
+// GetMirrorValidation returns the tables a validation of a mirror validated so far, in the order validated.
+// An empty validationID picks the mirror's latest validation, which is returned with its id.
+// Returns an empty id if the mirror has no such validation.
+func GetMirrorValidation(ctx context.Context, pool *pgxpool.Pool, flowJobName string, validationID string,
+) (string, []*protos.TableValidationResult, error) {
+	if validationID == "" {
+		err := pool.QueryRow(ctx, `SELECT validation_id FROM mirror_validations WHERE flow_job_name = $1
+			ORDER BY validated_at DESC LIMIT 1`, flowJobName).Scan(&validationID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, nil
+		} else if err != nil {
+			return "", nil, fmt.Errorf("failed to get latest mirror validation: %w", err)
+		}
+	}
+
+	rows, err := pool.Query(ctx, `SELECT source_table, destination_table, source_rows, destination_rows,
+		num_chunks, mismatched_chunks, COALESCE(error, ''), validated_at FROM mirror_validations
+		WHERE flow_job_name = $1 AND validation_id = $2 ORDER BY validated_at`, flowJobName, validationID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to query mirror validation: %w", err)
+	}
+	results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.TableValidationResult, error) {
+		var result protos.TableValidationResult
+		var validatedAt time.Time
+		err := row.Scan(&result.SourceTableIdentifier, &result.DestinationTableIdentifier, &result.SourceRows,
+			&result.DestinationRows, &result.NumChunks, &result.MismatchedChunks, &result.Error, &validatedAt)
+		result.ValidatedAt = timestamppb.New(validatedAt)
+		return &result, err
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to scan mirror validation: %w", err)
+	}
+	if len(results) == 0 {
+		return "", nil, nil
+	}
+	return validationID, results, nil
+}
+
+func DeleteMirrorValidations(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM mirror_validations WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete mirror validations: %w", err)
+	}
+	return nil
+}
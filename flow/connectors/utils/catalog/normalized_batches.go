@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RecordNormalizedBatch records that a mirror normalized every batch up to batchID.
+func RecordNormalizedBatch(ctx context.Context, pool *pgxpool.Pool, flowJobName string, batchID int64) error {
+	_, err := pool.Exec(ctx, `INSERT INTO normalized_batches (flow_job_name, batch_id) VALUES ($1, $2)
+		ON CONFLICT (flow_job_name, batch_id) DO NOTHING`, flowJobName, batchID)
+	if err != nil {
+		return fmt.Errorf("failed to record normalized batch: %w", err)
+	}
+	return nil
+}
+
+// LastBatchNormalizedBefore returns the last batch a mirror had normalized before cutoff, 0 if there is none.
+func LastBatchNormalizedBefore(ctx context.Context, pool *pgxpool.Pool, flowJobName string, cutoff time.Time) (int64, error) {
+	var batchID int64
+	err := pool.QueryRow(ctx, `SELECT coalesce(max(batch_id), 0) FROM normalized_batches
+		WHERE flow_job_name = $1 AND normalized_at < $2`, flowJobName, cutoff).Scan(&batchID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last normalized batch: %w", err)
+	}
+	return batchID, nil
+}
+
+// PruneNormalizedBatches forgets the normalized batches of a mirror before batchID, whose raw table rows were pruned.
+func PruneNormalizedBatches(ctx context.Context, pool *pgxpool.Pool, flowJobName string, batchID int64) error {
+	_, err := pool.Exec(ctx, "DELETE FROM normalized_batches WHERE flow_job_name = $1 AND batch_id < $2",
+		flowJobName, batchID)
+	if err != nil {
+		return fmt.Errorf("failed to prune normalized batches: %w", err)
+	}
+	return nil
+}
+
+func DeleteNormalizedBatches(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM normalized_batches WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete normalized batches: %w", err)
+	}
+	return nil
+}
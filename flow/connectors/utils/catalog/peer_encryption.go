@@ -0,0 +1,349 @@
+package utils
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// Peer configs are stored with envelope encryption when PEERDB_CATALOG_ENCRYPTION_KEYS is set:
+// options holds the config encrypted with AES-256-GCM under a data key of its own, enc_data_key holds
+// that data key wrapped by the key named by enc_key_id. Keys are static keys from the environment,
+// which wrap data keys with AES-256-GCM prefixed by their nonce, like nexus and the UI do.
+// Rotating keys only rewraps data keys, configs with a NULL enc_key_id are unencrypted.
+
+const (
+	dataKeySize = 32
+	// kmsKeyPrefix marks AWS KMS keys, which are rejected until nexus and the UI can unwrap data keys with them too
+	kmsKeyPrefix = "kms:"
+)
+
+// keyWrapper wraps data keys for storage next to what they encrypt, and unwraps them again.
+type keyWrapper interface {
+	wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+	unwrap(ctx context.Context, wrappedKey []byte) ([]byte, error)
+}
+
+type encryptionKey struct {
+	id      string
+	wrapper keyWrapper
+}
+
+// aeadKeyWrapper wraps data keys with a static key.
+type aeadKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+func (w aeadKeyWrapper) wrap(_ context.Context, dataKey []byte) ([]byte, error) {
+	return seal(w.aead, dataKey)
+}
+
+func (w aeadKeyWrapper) unwrap(_ context.Context, wrappedKey []byte) ([]byte, error) {
+	return open(w.aead, wrappedKey)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// parseEncryptionKeys parses PEERDB_CATALOG_ENCRYPTION_KEYS, the first key returned encrypts new configs.
+// Keys are id:base64 pairs of static keys, nexus and the UI parse them the same way.
+func parseEncryptionKeys(keyPairs []string) ([]encryptionKey, error) {
+	keys := make([]encryptionKey, 0, len(keyPairs))
+	for _, keyPair := range keyPairs {
+		id, encodedKey, ok := strings.Cut(keyPair, ":")
+		if !ok || id == "" {
+			return nil, errors.New("catalog encryption keys must be id:base64 pairs")
+		}
+		if strings.HasPrefix(encodedKey, kmsKeyPrefix) {
+			return nil, fmt.Errorf("catalog encryption key %s is an AWS KMS key, which nexus and the UI can't unwrap data keys with", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode catalog encryption key %s: %w", id, err)
+		}
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("catalog encryption key %s must be %d bytes", id, dataKeySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog encryption key %s: %w", id, err)
+		}
+		keys = append(keys, encryptionKey{id: id, wrapper: aeadKeyWrapper{aead: aead}})
+	}
+	return keys, nil
+}
+
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// encryptEnvelope encrypts plaintext, like a serialized peer config, under a new data key wrapped with the first key.
+// Without keys plaintext is returned as is, with a nil key id.
+func encryptEnvelope(ctx context.Context, keys []encryptionKey, plaintext []byte) ([]byte, *string, []byte, error) {
+	if len(keys) == 0 {
+		return plaintext, nil, nil, nil
+	}
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ciphertext, err := seal(dataAEAD, plaintext)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encrypt with data key: %w", err)
+	}
+	wrappedKey, err := keys[0].wrapper.wrap(ctx, dataKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to wrap data key with key %s: %w", keys[0].id, err)
+	}
+	return ciphertext, &keys[0].id, wrappedKey, nil
+}
+
+func unwrapDataKey(ctx context.Context, keys []encryptionKey, keyID string, wrappedKey []byte) ([]byte, error) {
+	for _, key := range keys {
+		if key.id == keyID {
+			dataKey, err := key.wrapper.unwrap(ctx, wrappedKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unwrap data key with key %s: %w", keyID, err)
+			}
+			return dataKey, nil
+		}
+	}
+	return nil, fmt.Errorf("catalog encryption key %s is not configured", keyID)
+}
+
+// decryptEnvelope returns the plaintext of what encryptEnvelope returned, decrypting it when keyID is set.
+func decryptEnvelope(
+	ctx context.Context,
+	keys []encryptionKey,
+	ciphertext []byte,
+	keyID *string,
+	wrappedKey []byte,
+) ([]byte, error) {
+	if keyID == nil {
+		return ciphertext, nil
+	}
+	dataKey, err := unwrapDataKey(ctx, keys, *keyID, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	decrypted, err := open(dataAEAD, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with data key: %w", err)
+	}
+	return decrypted, nil
+}
+
+// EncryptPeerOptions encrypts a serialized peer config for the catalog, returning the options, enc_key_id
+// and enc_data_key to store.
+func EncryptPeerOptions(ctx context.Context, options []byte) ([]byte, *string, []byte, error) {
+	keys, err := parseEncryptionKeys(peerdbenv.PeerDBCatalogEncryptionKeys())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return encryptEnvelope(ctx, keys, options)
+}
+
+// DecryptPeerOptions returns the serialized peer config of a catalog row from its options, enc_key_id and enc_data_key.
+func DecryptPeerOptions(ctx context.Context, options []byte, keyID *string, wrappedKey []byte) ([]byte, error) {
+	keys, err := parseEncryptionKeys(peerdbenv.PeerDBCatalogEncryptionKeys())
+	if err != nil {
+		return nil, err
+	}
+	return decryptEnvelope(ctx, keys, options, keyID, wrappedKey)
+}
+
+// RotatePeerEncryption brings every peer config under the first encryption key: data keys wrapped with an older key
+// are rewrapped, unencrypted configs are encrypted. Returns the number of peers updated.
+func RotatePeerEncryption(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	keys, err := parseEncryptionKeys(peerdbenv.PeerDBCatalogEncryptionKeys())
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	currentKey := keys[0]
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Error("failed to rollback peer encryption rotation", slog.Any("error", err))
+		}
+	}()
+
+	rows, err := tx.Query(ctx, `SELECT id,options,enc_key_id,enc_data_key FROM peers
+	 WHERE enc_key_id IS DISTINCT FROM $1 FOR UPDATE`, currentKey.id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query peers to rotate: %w", err)
+	}
+	type peerRow struct {
+		options    []byte
+		keyID      *string
+		wrappedKey []byte
+		id         int32
+	}
+	peers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (peerRow, error) {
+		var peer peerRow
+		err := row.Scan(&peer.id, &peer.options, &peer.keyID, &peer.wrappedKey)
+		return peer, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query peers to rotate: %w", err)
+	}
+
+	for _, peer := range peers {
+		options := peer.options
+		var wrappedKey []byte
+		if peer.keyID == nil {
+			options, _, wrappedKey, err = encryptEnvelope(ctx, keys, peer.options)
+		} else {
+			var dataKey []byte
+			if dataKey, err = unwrapDataKey(ctx, keys, *peer.keyID, peer.wrappedKey); err == nil {
+				wrappedKey, err = currentKey.wrapper.wrap(ctx, dataKey)
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to rotate encryption of peer %d: %w", peer.id, err)
+		}
+		if _, err := tx.Exec(ctx, "UPDATE peers SET options=$2,enc_key_id=$3,enc_data_key=$4 WHERE id=$1",
+			peer.id, options, currentKey.id, wrappedKey); err != nil {
+			return 0, fmt.Errorf("failed to update encryption of peer %d: %w", peer.id, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit peer encryption rotation: %w", err)
+	}
+	return len(peers), nil
+}
+
+// Secrets kept in other catalog rows, like the credentials in alert configs, are encrypted one by one and stored
+// as encryptedSecretPrefix followed by the key id, wrapped data key and ciphertext, separated by colons.
+const encryptedSecretPrefix = "peerdb-encrypted:"
+
+func encryptSecret(ctx context.Context, keys []encryptionKey, secret string) (string, error) {
+	if len(keys) == 0 || secret == "" || strings.HasPrefix(secret, encryptedSecretPrefix) {
+		return secret, nil
+	}
+	ciphertext, keyID, wrappedKey, err := encryptEnvelope(ctx, keys, []byte(secret))
+	if err != nil {
+		return "", err
+	}
+	return encryptedSecretPrefix + *keyID + ":" + base64.StdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func parseEncryptedSecret(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, encryptedSecretPrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("malformed encrypted secret")
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted secret: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted secret: %w", err)
+	}
+	return parts[0], wrappedKey, ciphertext, nil
+}
+
+// decryptSecret returns the secret encryptSecret encrypted, values which aren't encrypted are returned as is.
+func decryptSecret(ctx context.Context, keys []encryptionKey, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedSecretPrefix) {
+		return value, nil
+	}
+	keyID, wrappedKey, ciphertext, err := parseEncryptedSecret(value)
+	if err != nil {
+		return "", err
+	}
+	secret, err := decryptEnvelope(ctx, keys, ciphertext, &keyID, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// rotateSecret brings a secret under the first key, rewrapping its data key or encrypting it if it isn't yet.
+func rotateSecret(ctx context.Context, keys []encryptionKey, value string) (string, error) {
+	if len(keys) == 0 || !strings.HasPrefix(value, encryptedSecretPrefix) {
+		return encryptSecret(ctx, keys, value)
+	}
+	keyID, wrappedKey, ciphertext, err := parseEncryptedSecret(value)
+	if err != nil || keyID == keys[0].id {
+		return value, err
+	}
+	dataKey, err := unwrapDataKey(ctx, keys, keyID, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	if wrappedKey, err = keys[0].wrapper.wrap(ctx, dataKey); err != nil {
+		return "", fmt.Errorf("failed to wrap data key with key %s: %w", keys[0].id, err)
+	}
+	return encryptedSecretPrefix + keys[0].id + ":" + base64.StdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// EncryptSecret encrypts a secret to store in the catalog, it's returned as is without encryption keys.
+func EncryptSecret(ctx context.Context, secret string) (string, error) {
+	keys, err := parseEncryptionKeys(peerdbenv.PeerDBCatalogEncryptionKeys())
+	if err != nil {
+		return "", err
+	}
+	return encryptSecret(ctx, keys, secret)
+}
+
+// DecryptSecret returns a secret stored in the catalog, decrypting it if EncryptSecret encrypted it.
+func DecryptSecret(ctx context.Context, value string) (string, error) {
+	keys, err := parseEncryptionKeys(peerdbenv.PeerDBCatalogEncryptionKeys())
+	if err != nil {
+		return "", err
+	}
+	return decryptSecret(ctx, keys, value)
+}
+
+// RotateSecret returns a secret stored in the catalog encrypted with the current encryption key.
+func RotateSecret(ctx context.Context, value string) (string, error) {
+	keys, err := parseEncryptionKeys(peerdbenv.PeerDBCatalogEncryptionKeys())
+	if err != nil {
+		return "", err
+	}
+	return rotateSecret(ctx, keys, value)
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testEncryptionKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, dataKeySize))
+}
+
+func TestParseEncryptionKeys(t *testing.T) {
+	keys, err := parseEncryptionKeys(nil)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	keys, err = parseEncryptionKeys([]string{"new:" + testEncryptionKey(2), "old:" + testEncryptionKey(1)})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, "new", keys[0].id)
+	require.Equal(t, "old", keys[1].id)
+
+	_, err = parseEncryptionKeys([]string{testEncryptionKey(1)})
+	require.Error(t, err)
+	_, err = parseEncryptionKeys([]string{"short:" + base64.StdEncoding.EncodeToString([]byte("too short"))})
+	require.Error(t, err)
+}
+
+func TestPeerOptionsEncryption(t *testing.T) {
+	ctx := context.Background()
+	options := []byte("serialized peer config")
+
+	plain, keyID, wrappedKey, err := encryptEnvelope(ctx, nil, options)
+	require.NoError(t, err)
+	require.Nil(t, keyID)
+	require.Equal(t, options, plain)
+	decrypted, err := decryptEnvelope(ctx, nil, plain, keyID, wrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, options, decrypted)
+
+	oldKeys, err := parseEncryptionKeys([]string{"old:" + testEncryptionKey(1)})
+	require.NoError(t, err)
+	encrypted, keyID, wrappedKey, err := encryptEnvelope(ctx, oldKeys, options)
+	require.NoError(t, err)
+	require.Equal(t, "old", *keyID)
+	require.NotContains(t, string(encrypted), string(options))
+	decrypted, err = decryptEnvelope(ctx, oldKeys, encrypted, keyID, wrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, options, decrypted)
+
+	// after rotation the old key still unwraps data keys until they're rewrapped with the new one
+	rotatedKeys, err := parseEncryptionKeys([]string{"new:" + testEncryptionKey(2), "old:" + testEncryptionKey(1)})
+	require.NoError(t, err)
+	decrypted, err = decryptEnvelope(ctx, rotatedKeys, encrypted, keyID, wrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, options, decrypted)
+	dataKey, err := unwrapDataKey(ctx, rotatedKeys, *keyID, wrappedKey)
+	require.NoError(t, err)
+	rewrappedKey, err := rotatedKeys[0].wrapper.wrap(ctx, dataKey)
+	require.NoError(t, err)
+	newKeyID := rotatedKeys[0].id
+	decrypted, err = decryptEnvelope(ctx, rotatedKeys[:1], encrypted, &newKeyID, rewrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, options, decrypted)
+
+	_, err = decryptEnvelope(ctx, rotatedKeys[:1], encrypted, keyID, wrappedKey)
+	require.ErrorContains(t, err, "not configured")
+	_, err = decryptEnvelope(ctx, rotatedKeys[:1], encrypted, &newKeyID, wrappedKey)
+	require.Error(t, err)
+}
+
+func TestSecretEncryption(t *testing.T) {
+	ctx := context.Background()
+	secret, err := encryptSecret(ctx, nil, "hunter2")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", secret)
+
+	oldKeys, err := parseEncryptionKeys([]string{"old:" + testEncryptionKey(1)})
+	require.NoError(t, err)
+	encrypted, err := encryptSecret(ctx, oldKeys, "hunter2")
+	require.NoError(t, err)
+	require.NotContains(t, encrypted, "hunter2")
+	reencrypted, err := encryptSecret(ctx, oldKeys, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, reencrypted, "encrypted secrets aren't encrypted twice")
+	decrypted, err := decryptSecret(ctx, oldKeys, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", decrypted)
+	decrypted, err = decryptSecret(ctx, oldKeys, "stored before encryption")
+	require.NoError(t, err)
+	require.Equal(t, "stored before encryption", decrypted)
+
+	rotatedKeys, err := parseEncryptionKeys([]string{"new:" + testEncryptionKey(2), "old:" + testEncryptionKey(1)})
+	require.NoError(t, err)
+	rotated, err := rotateSecret(ctx, rotatedKeys, encrypted)
+	require.NoError(t, err)
+	require.NotEqual(t, encrypted, rotated)
+	decrypted, err = decryptSecret(ctx, rotatedKeys[:1], rotated)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", decrypted)
+	_, err = decryptSecret(ctx, rotatedKeys[:1], encrypted)
+	require.ErrorContains(t, err, "not configured")
+
+	rotated, err = rotateSecret(ctx, rotatedKeys, "stored before encryption")
+	require.NoError(t, err)
+	decrypted, err = decryptSecret(ctx, rotatedKeys[:1], rotated)
+	require.NoError(t, err)
+	require.Equal(t, "stored before encryption", decrypted)
+
+	_, err = decryptSecret(ctx, rotatedKeys, encryptedSecretPrefix+"new:AAAA")
+	require.Error(t, err)
+}
+
+func TestRejectKMSKeys(t *testing.T) {
+	// nexus and the UI only unwrap data keys with static keys
+	_, err := parseEncryptionKeys([]string{"kms1:kms:alias/peerdb", "old:" + testEncryptionKey(1)})
+	require.ErrorContains(t, err, "AWS KMS key")
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// LoadPeers returns all peers in the catalog along with their configs.
+func LoadPeers(ctx context.Context, pool *pgxpool.Pool) ([]*protos.Peer, error) {
+	rows, err := pool.Query(ctx, "SELECT name, type, options, enc_key_id, enc_data_key FROM peers ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query peers: %w", err)
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.Peer, error) {
+		var name string
+		var dbType int32
+		var options []byte
+		var encKeyID *string
+		var encDataKey []byte
+		if err := row.Scan(&name, &dbType, &options, &encKeyID, &encDataKey); err != nil {
+			return nil, err
+		}
+		return peerFromRow(ctx, name, protos.DBType(dbType), options, encKeyID, encDataKey)
+	})
+}
+
+// LoadPeer returns the peer with the given name along with its config.
+func LoadPeer(ctx context.Context, pool *pgxpool.Pool, peerName string) (*protos.Peer, error) {
+	var dbType int32
+	var options []byte
+	var encKeyID *string
+	var encDataKey []byte
+	err := pool.QueryRow(ctx, "SELECT type, options, enc_key_id, enc_data_key FROM peers WHERE name = $1",
+		peerName).Scan(&dbType, &options, &encKeyID, &encDataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer %s: %w", peerName, err)
+	}
+	return peerFromRow(ctx, peerName, protos.DBType(dbType), options, encKeyID, encDataKey)
+}
+
+// peerFromRow decrypts the config of a catalog row, if it's encrypted, before decoding it.
+func peerFromRow(
+	ctx context.Context,
+	name string,
+	dbType protos.DBType,
+	options []byte,
+	encKeyID *string,
+	encDataKey []byte,
+) (*protos.Peer, error) {
+	options, err := DecryptPeerOptions(ctx, options, encKeyID, encDataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config of peer %s: %w", name, err)
+	}
+	return peerFromOptions(name, dbType, options)
+}
+
+func peerFromOptions(name string, dbType protos.DBType, options []byte) (*protos.Peer, error) {
+	peer := &protos.Peer{
+		Name: name,
+		Type: dbType,
+	}
+
+	var config proto.Message
+	switch dbType {
+	case protos.DBType_BIGQUERY:
+		bqConfig := &protos.BigqueryConfig{}
+		peer.Config = &protos.Peer_BigqueryConfig{BigqueryConfig: bqConfig}
+		config = bqConfig
+	case protos.DBType_SNOWFLAKE:
+		sfConfig := &protos.SnowflakeConfig{}
+		peer.Config = &protos.Peer_SnowflakeConfig{SnowflakeConfig: sfConfig}
+		config = sfConfig
+	case protos.DBType_MONGO:
+		mongoConfig := &protos.MongoConfig{}
+		peer.Config = &protos.Peer_MongoConfig{MongoConfig: mongoConfig}
+		config = mongoConfig
+	case protos.DBType_POSTGRES:
+		pgConfig := &protos.PostgresConfig{}
+		peer.Config = &protos.Peer_PostgresConfig{PostgresConfig: pgConfig}
+		config = pgConfig
+	case protos.DBType_EVENTHUB:
+		ehConfig := &protos.EventHubConfig{}
+		peer.Config = &protos.Peer_EventhubConfig{EventhubConfig: ehConfig}
+		config = ehConfig
+	case protos.DBType_S3:
+		s3Config := &protos.S3Config{}
+		peer.Config = &protos.Peer_S3Config{S3Config: s3Config}
+		config = s3Config
+	case protos.DBType_SQLSERVER:
+		sqlServerConfig := &protos.SqlServerConfig{}
+		peer.Config = &protos.Peer_SqlserverConfig{SqlserverConfig: sqlServerConfig}
+		config = sqlServerConfig
+	case protos.DBType_EVENTHUB_GROUP:
+		ehGroupConfig := &protos.EventHubGroupConfig{}
+		peer.Config = &protos.Peer_EventhubGroupConfig{EventhubGroupConfig: ehGroupConfig}
+		config = ehGroupConfig
+	case protos.DBType_CLICKHOUSE:
+		chConfig := &protos.ClickhouseConfig{}
+		peer.Config = &protos.Peer_ClickhouseConfig{ClickhouseConfig: chConfig}
+		config = chConfig
+	default:
+		return nil, fmt.Errorf("unsupported peer type %s for peer %s", dbType, name)
+	}
+
+	if err := proto.Unmarshal(options, config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config of peer %s: %w", name, err)
+	}
+	return peer, nil
+}
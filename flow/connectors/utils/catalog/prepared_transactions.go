@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PreparedTransaction holds the encoded changes of a transaction decoded at PREPARE TRANSACTION,
+// kept until its COMMIT PREPARED or ROLLBACK PREPARED is decoded and synced.
+type PreparedTransaction struct {
+	GID        string
+	Xid        uint32
+	PrepareLSN int64
+	Records    []byte
+	PreparedAt time.Time
+}
+
+func SavePreparedTransaction(ctx context.Context, pool *pgxpool.Pool, flowJobName string, txn *PreparedTransaction) error {
+	_, err := pool.Exec(ctx, `INSERT INTO prepared_transactions
+		(flow_job_name, gid, xid, prepare_lsn, records, prepared_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (flow_job_name, gid) DO UPDATE SET xid = excluded.xid, prepare_lsn = excluded.prepare_lsn,
+		records = excluded.records, prepared_at = excluded.prepared_at, finished_lsn = NULL`,
+		flowJobName, txn.GID, int64(txn.Xid), txn.PrepareLSN, txn.Records, txn.PreparedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save prepared transaction %s: %w", txn.GID, err)
+	}
+	return nil
+}
+
+// GetPreparedTransaction returns the prepared transaction with the given gid, nil if there is none.
+func GetPreparedTransaction(ctx context.Context, pool *pgxpool.Pool, flowJobName string, gid string) (*PreparedTransaction, error) {
+	txn := &PreparedTransaction{GID: gid}
+	var xid int64
+	err := pool.QueryRow(ctx,
+		"SELECT xid, prepare_lsn, records, prepared_at FROM prepared_transactions WHERE flow_job_name = $1 AND gid = $2",
+		flowJobName, gid).Scan(&xid, &txn.PrepareLSN, &txn.Records, &txn.PreparedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get prepared transaction %s: %w", gid, err)
+	}
+	txn.Xid = uint32(xid)
+	return txn, nil
+}
+
+// FinishPreparedTransaction marks a prepared transaction as committed or rolled back at finishedLSN.
+// It is only deleted once the mirror has synced past that LSN, in case the batch is pulled again.
+func FinishPreparedTransaction(ctx context.Context, pool *pgxpool.Pool, flowJobName string, gid string, finishedLSN int64) error {
+	_, err := pool.Exec(ctx, "UPDATE prepared_transactions SET finished_lsn = $3 WHERE flow_job_name = $1 AND gid = $2",
+		flowJobName, gid, finishedLSN)
+	if err != nil {
+		return fmt.Errorf("failed to finish prepared transaction %s: %w", gid, err)
+	}
+	return nil
+}
+
+func PrunePreparedTransactions(ctx context.Context, pool *pgxpool.Pool, flowJobName string, syncedLSN int64) error {
+	_, err := pool.Exec(ctx, "DELETE FROM prepared_transactions WHERE flow_job_name = $1 AND finished_lsn <= $2",
+		flowJobName, syncedLSN)
+	if err != nil {
+		return fmt.Errorf("failed to prune prepared transactions: %w", err)
+	}
+	return nil
+}
+
+func DeletePreparedTransactions(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM prepared_transactions WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete prepared transactions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// GetQRepScheduleState returns the state the last run of a scheduled QRep mirror left, nil before its first run completes.
+func GetQRepScheduleState(ctx context.Context, pool *pgxpool.Pool, flowJobName string) (*protos.QRepFlowState, error) {
+	var stateBytes []byte
+	err := pool.QueryRow(ctx, "SELECT state_proto FROM qrep_schedule_states WHERE flow_job_name = $1",
+		flowJobName).Scan(&stateBytes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get qrep schedule state: %w", err)
+	}
+	var state protos.QRepFlowState
+	if err := proto.Unmarshal(stateBytes, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal qrep schedule state: %w", err)
+	}
+	return &state, nil
+}
+
+func SaveQRepScheduleState(ctx context.Context, pool *pgxpool.Pool, flowJobName string, state *protos.QRepFlowState) error {
+	stateBytes, err := proto.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal qrep schedule state: %w", err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO qrep_schedule_states (flow_job_name, state_proto) VALUES ($1, $2)
+		ON CONFLICT (flow_job_name) DO UPDATE SET state_proto = excluded.state_proto, updated_at = now()`,
+		flowJobName, stateBytes); err != nil {
+		return fmt.Errorf("failed to save qrep schedule state: %w", err)
+	}
+	return nil
+}
+
+func DeleteQRepScheduleState(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM qrep_schedule_states WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete qrep schedule state: %w", err)
+	}
+	return nil
+}
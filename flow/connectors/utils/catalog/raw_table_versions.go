@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GetRawTableVersion returns the layout version recorded for a mirror's raw table, 0 if there is none.
+func GetRawTableVersion(ctx context.Context, pool *pgxpool.Pool, flowJobName string) (int32, error) {
+	var version int32
+	err := pool.QueryRow(ctx, "SELECT version FROM raw_table_versions WHERE flow_job_name = $1",
+		flowJobName).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get raw table version: %w", err)
+	}
+	return version, nil
+}
+
+// InitRawTableVersion records the layout version of a newly created raw table, keeping any version already recorded.
+func InitRawTableVersion(ctx context.Context, pool *pgxpool.Pool, flowJobName string, version int32) error {
+	_, err := pool.Exec(ctx, `INSERT INTO raw_table_versions (flow_job_name, version) VALUES ($1, $2)
+		ON CONFLICT (flow_job_name) DO NOTHING`, flowJobName, version)
+	if err != nil {
+		return fmt.Errorf("failed to record raw table version: %w", err)
+	}
+	return nil
+}
+
+func SetRawTableVersion(ctx context.Context, pool *pgxpool.Pool, flowJobName string, version int32) error {
+	_, err := pool.Exec(ctx, `INSERT INTO raw_table_versions (flow_job_name, version) VALUES ($1, $2)
+		ON CONFLICT (flow_job_name) DO UPDATE SET version = excluded.version, updated_at = now()`,
+		flowJobName, version)
+	if err != nil {
+		return fmt.Errorf("failed to update raw table version: %w", err)
+	}
+	return nil
+}
+
+func DeleteRawTableVersion(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM raw_table_versions WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete raw table version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type QueuedSchemaDelta struct {
+	ID         int64
+	Delta      *protos.TableSchemaDelta
+	QueuedAt   time.Time
+	ApprovedAt *time.Time
+	// a change destinations can't follow which the mirror pauses on until it's approved
+	Incompatible bool
+}
+
+// QueueSchemaDeltas holds schema deltas of a mirror in the catalog until they are approved.
+func QueueSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string, deltas []*protos.TableSchemaDelta) error {
+	return queueSchemaDeltas(ctx, pool, flowJobName, deltas, false)
+}
+
+// QueueApprovedSchemaDeltas holds schema deltas of a mirror in the catalog, already approved, until they are applied.
+func QueueApprovedSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string, deltas []*protos.TableSchemaDelta) error {
+	return queueSchemaDeltas(ctx, pool, flowJobName, deltas, true)
+}
+
+func queueSchemaDeltas(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	flowJobName string,
+	deltas []*protos.TableSchemaDelta,
+	approved bool,
+) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, delta := range deltas {
+		batch.Queue("INSERT INTO schema_deltas_queue (flow_job_name, delta_info, approved_at) "+
+			"VALUES ($1, $2, CASE WHEN $3 THEN now() END)", flowJobName, delta, approved)
+	}
+	if err := pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to queue schema deltas: %w", err)
+	}
+	return nil
+}
+
+// QueueIncompatibleSchemaDeltas holds schema deltas destinations can't follow in the catalog, the mirror pauses
+// until they are approved. Deltas already pending are skipped, so a retried sync doesn't queue them twice.
+func QueueIncompatibleSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string, deltas []*protos.TableSchemaDelta) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, delta := range deltas {
+		batch.Queue("INSERT INTO schema_deltas_queue (flow_job_name, delta_info, incompatible) SELECT $1, $2, true "+
+			"WHERE NOT EXISTS (SELECT 1 FROM schema_deltas_queue "+
+			"WHERE flow_job_name = $1 AND delta_info = $2::jsonb AND incompatible AND approved_at IS NULL)", flowJobName, delta)
+	}
+	if err := pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to queue incompatible schema deltas: %w", err)
+	}
+	return nil
+}
+
+// ListQueuedSchemaDeltas returns the deltas of a mirror which have not been applied yet, oldest first.
+func ListQueuedSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string) ([]QueuedSchemaDelta, error) {
+	return queryQueuedSchemaDeltas(ctx, pool,
+		"SELECT id, delta_info, queued_at, approved_at, incompatible FROM schema_deltas_queue "+
+			"WHERE flow_job_name = $1 AND applied_at IS NULL ORDER BY id", flowJobName)
+}
+
+// ApprovedSchemaDeltas returns the deltas of a mirror which were approved but not applied yet, oldest first.
+func ApprovedSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string) ([]QueuedSchemaDelta, error) {
+	return queryQueuedSchemaDeltas(ctx, pool,
+		"SELECT id, delta_info, queued_at, approved_at, incompatible FROM schema_deltas_queue "+
+			"WHERE flow_job_name = $1 AND approved_at IS NOT NULL AND applied_at IS NULL ORDER BY id", flowJobName)
+}
+
+func queryQueuedSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, query string, args ...any) ([]QueuedSchemaDelta, error) {
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema deltas: %w", err)
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (QueuedSchemaDelta, error) {
+		queued := QueuedSchemaDelta{Delta: &protos.TableSchemaDelta{}}
+		err := row.Scan(&queued.ID, queued.Delta, &queued.QueuedAt, &queued.ApprovedAt, &queued.Incompatible)
+		return queued, err
+	})
+}
+
+type SchemaDeltaApprovals struct {
+	Approved int64
+	// incompatible deltas among those approved
+	Incompatible int64
+	// incompatible deltas of the mirror still waiting for approval, it stays paused while there are any
+	PendingIncompatible int64
+}
+
+// ApproveSchemaDeltas approves the given pending deltas of a mirror, or all of them if ids is empty,
+// only incompatible ones with incompatibleOnly.
+// Incompatible deltas are approved with resolution: with SCHEMA_CHANGE_RESOLUTION_APPLY their dropped columns
+// are left queued for the next sync to replay on the destination, otherwise they're done with once approved.
+func ApproveSchemaDeltas(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	flowJobName string,
+	ids []int64,
+	incompatibleOnly bool,
+	resolution protos.SchemaChangeResolution,
+) (SchemaDeltaApprovals, error) {
+	var approvals SchemaDeltaApprovals
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return approvals, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Error("failed to rollback schema delta approvals", slog.Any("error", err))
+		}
+	}()
+
+	query := "UPDATE schema_deltas_queue SET approved_at = now(), resolution = CASE WHEN incompatible THEN $2 END " +
+		"WHERE flow_job_name = $1 AND approved_at IS NULL AND applied_at IS NULL"
+	args := []any{flowJobName, resolution.String()}
+	if incompatibleOnly {
+		query += " AND incompatible"
+	}
+	if len(ids) != 0 {
+		query += " AND id = ANY($3)"
+		args = append(args, ids)
+	}
+	rows, err := tx.Query(ctx, query+" RETURNING id, delta_info, incompatible", args...)
+	if err != nil {
+		return approvals, fmt.Errorf("failed to approve schema deltas: %w", err)
+	}
+	approved, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (QueuedSchemaDelta, error) {
+		queued := QueuedSchemaDelta{Delta: &protos.TableSchemaDelta{}}
+		err := row.Scan(&queued.ID, queued.Delta, &queued.Incompatible)
+		return queued, err
+	})
+	if err != nil {
+		return approvals, fmt.Errorf("failed to approve schema deltas: %w", err)
+	}
+	approvals.Approved = int64(len(approved))
+
+	for _, queued := range approved {
+		if !queued.Incompatible {
+			continue
+		}
+		approvals.Incompatible += 1
+		// narrowed columns are never replayed, dropped primary key columns only when applying them
+		if resolution == protos.SchemaChangeResolution_SCHEMA_CHANGE_RESOLUTION_APPLY && len(queued.Delta.DroppedColumns) != 0 {
+			_, err = tx.Exec(ctx, "UPDATE schema_deltas_queue SET delta_info = $2 WHERE id = $1", queued.ID,
+				&protos.TableSchemaDelta{
+					SrcTableName:   queued.Delta.SrcTableName,
+					DstTableName:   queued.Delta.DstTableName,
+					DroppedColumns: queued.Delta.DroppedColumns,
+				})
+		} else {
+			_, err = tx.Exec(ctx, "UPDATE schema_deltas_queue SET applied_at = now() WHERE id = $1", queued.ID)
+		}
+		if err != nil {
+			return approvals, fmt.Errorf("failed to resolve incompatible schema delta %d: %w", queued.ID, err)
+		}
+	}
+
+	if err := tx.QueryRow(ctx, "SELECT count(*) FROM schema_deltas_queue "+
+		"WHERE flow_job_name = $1 AND incompatible AND approved_at IS NULL", flowJobName).Scan(&approvals.PendingIncompatible); err != nil {
+		return approvals, fmt.Errorf("failed to count pending incompatible schema deltas: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return approvals, fmt.Errorf("failed to commit schema delta approvals: %w", err)
+	}
+	return approvals, nil
+}
+
+// HasPendingIncompatibleSchemaDeltas returns whether a mirror is paused on incompatible schema deltas
+// which aren't approved yet.
+func HasPendingIncompatibleSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string) (bool, error) {
+	var pending bool
+	if err := pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM schema_deltas_queue "+
+		"WHERE flow_job_name = $1 AND incompatible AND approved_at IS NULL)", flowJobName).Scan(&pending); err != nil {
+		return false, fmt.Errorf("failed to check for pending incompatible schema deltas: %w", err)
+	}
+	return pending, nil
+}
+
+func MarkSchemaDeltasApplied(ctx context.Context, pool *pgxpool.Pool, ids []int64) error {
+	_, err := pool.Exec(ctx, "UPDATE schema_deltas_queue SET applied_at = now() WHERE id = ANY($1)", ids)
+	if err != nil {
+		return fmt.Errorf("failed to mark schema deltas as applied: %w", err)
+	}
+	return nil
+}
+
+func DeleteQueuedSchemaDeltas(ctx context.Context, pool *pgxpool.Pool, flowJobName string) error {
+	_, err := pool.Exec(ctx, "DELETE FROM schema_deltas_queue WHERE flow_job_name = $1", flowJobName)
+	if err != nil {
+		return fmt.Errorf("failed to delete queued schema deltas: %w", err)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"os"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -30,6 +31,30 @@ func encVal(val any) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+var registerGobTypes = sync.OnceFunc(func() {
+	// register future record classes here as well, if they are passed/stored as interfaces
+	gob.Register(&model.InsertRecord{})
+	gob.Register(&model.UpdateRecord{})
+	gob.Register(&model.DeleteRecord{})
+	gob.Register(time.Time{})
+	gob.Register(&big.Rat{})
+})
+
+// EncodeRecords serializes records with the same encoding used when spilling them to disk.
+func EncodeRecords(records []model.Record) ([]byte, error) {
+	registerGobTypes()
+	return encVal(records)
+}
+
+func DecodeRecords(encoded []byte) ([]model.Record, error) {
+	registerGobTypes()
+	var records []model.Record
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode records: %w", err)
+	}
+	return records, nil
+}
+
 type cdcRecordsStore struct {
 	inMemoryRecords           map[model.TableWithPkey]model.Record
 	pebbleDB                  *pebble.DB
@@ -67,12 +92,7 @@ func (c *cdcRecordsStore) initPebbleDB() error {
 		return nil
 	}
 
-	// register future record classes here as well, if they are passed/stored as interfaces
-	gob.Register(&model.InsertRecord{})
-	gob.Register(&model.UpdateRecord{})
-	gob.Register(&model.DeleteRecord{})
-	gob.Register(time.Time{})
-	gob.Register(&big.Rat{})
+	registerGobTypes()
 
 	var err error
 	// we don't want a WAL since cache, we don't want to overwrite another DB either
@@ -58,18 +58,9 @@ func genKeyAndRec(t *testing.T) (model.TableWithPkey, model.Record) {
 				"rv": 2,
 			},
 			Values: []qvalue.QValue{
-				{
-					Kind:  qvalue.QValueKindInt64,
-					Value: 1,
-				},
-				{
-					Kind:  qvalue.QValueKindTime,
-					Value: tv,
-				},
-				{
-					Kind:  qvalue.QValueKindNumeric,
-					Value: rv,
-				},
+				qvalue.New(qvalue.QValueKindInt64, 1),
+				qvalue.New(qvalue.QValueKindTime, tv),
+				qvalue.New(qvalue.QValueKindNumeric, rv),
 			},
 		},
 	}
@@ -166,3 +157,23 @@ func TestNullKeyDoesntStore(t *testing.T) {
 
 	require.NoError(t, cdcRecordsStore.Close())
 }
+
+func TestEncodeDecodeRecords(t *testing.T) {
+	t.Parallel()
+
+	_, insertRec := genKeyAndRec(t)
+	_, deleteRec := genKeyAndRec(t)
+	records := []model.Record{insertRec, &model.DeleteRecord{
+		SourceTableName:       "test_src_tbl",
+		DestinationTableName:  "test_dst_tbl",
+		CheckpointID:          3,
+		Items:                 deleteRec.GetItems(),
+		UnchangedToastColumns: map[string]struct{}{},
+	}}
+
+	encoded, err := EncodeRecords(records)
+	require.NoError(t, err)
+	decoded, err := DecodeRecords(encoded)
+	require.NoError(t, err)
+	require.Equal(t, records, decoded)
+}
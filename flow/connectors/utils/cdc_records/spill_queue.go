@@ -0,0 +1,117 @@
+package cdc_records
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// spillQueue is a first in, first out queue of records in a temporary file, which a CDC record stream
+// overflows into once its buffer is full. Records are encrypted with a key only held in memory, as they
+// contain source data, and the file is unlinked as soon as it's created so it's cleaned up even if the worker dies.
+type spillQueue struct {
+	file     *os.File
+	aead     cipher.AEAD
+	writeOff int64
+	readOff  int64
+}
+
+var _ model.RecordSpillQueue = (*spillQueue)(nil)
+
+func NewSpillQueue() (*spillQueue, error) {
+	registerGobTypes()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate spill encryption key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill cipher: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "peerdb-cdc-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to unlink spill file: %w", err)
+	}
+	return &spillQueue{file: file, aead: aead}, nil
+}
+
+// Push appends a record as its length, nonce and encrypted gob encoding.
+func (q *spillQueue) Push(record model.Record) error {
+	var encoded bytes.Buffer
+	// encoded through a pointer so the concrete type of the record is kept
+	if err := gob.NewEncoder(&encoded).Encode(&record); err != nil {
+		return fmt.Errorf("failed to encode spilled record: %w", err)
+	}
+
+	entry := make([]byte, 4+q.aead.NonceSize(), 4+q.aead.NonceSize()+encoded.Len()+q.aead.Overhead())
+	nonce := entry[4:]
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate spill nonce: %w", err)
+	}
+	entry = q.aead.Seal(entry, nonce, encoded.Bytes(), nil)
+	binary.LittleEndian.PutUint32(entry, uint32(len(entry)-4))
+
+	if _, err := q.file.WriteAt(entry, q.writeOff); err != nil {
+		return fmt.Errorf("failed to write spilled record: %w", err)
+	}
+	q.writeOff += int64(len(entry))
+	return nil
+}
+
+// Pop reads the oldest record back, the caller must not pop more records than it pushed.
+func (q *spillQueue) Pop() (model.Record, error) {
+	var header [4]byte
+	if _, err := q.file.ReadAt(header[:], q.readOff); err != nil {
+		return nil, fmt.Errorf("failed to read spilled record: %w", err)
+	}
+	entry := make([]byte, binary.LittleEndian.Uint32(header[:]))
+	if _, err := q.file.ReadAt(entry, q.readOff+4); err != nil {
+		return nil, fmt.Errorf("failed to read spilled record: %w", err)
+	}
+	nonceSize := q.aead.NonceSize()
+	if len(entry) < nonceSize {
+		return nil, errors.New("spilled record is truncated")
+	}
+	decrypted, err := q.aead.Open(entry[nonceSize:nonceSize], entry[:nonceSize], entry[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt spilled record: %w", err)
+	}
+
+	var record model.Record
+	if err := gob.NewDecoder(bytes.NewReader(decrypted)).Decode(&record); err != nil {
+		return nil, fmt.Errorf("failed to decode spilled record: %w", err)
+	}
+
+	q.readOff += 4 + int64(len(entry))
+	if q.readOff == q.writeOff {
+		// drained, start over so the file doesn't keep growing for the rest of the batch
+		if err := q.file.Truncate(0); err != nil {
+			return nil, fmt.Errorf("failed to truncate spill file: %w", err)
+		}
+		q.readOff = 0
+		q.writeOff = 0
+	}
+	return record, nil
+}
+
+func (q *spillQueue) Close() error {
+	return q.file.Close()
+}
@@ -0,0 +1,36 @@
+package cdc_records
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+func TestSpillQueue(t *testing.T) {
+	t.Parallel()
+
+	queue, err := NewSpillQueue()
+	require.NoError(t, err)
+	defer queue.Close()
+
+	records := make([]model.Record, 0, 3)
+	for range 3 {
+		_, rec := genKeyAndRec(t)
+		records = append(records, rec)
+		require.NoError(t, queue.Push(rec))
+	}
+	for _, rec := range records {
+		popped, err := queue.Pop()
+		require.NoError(t, err)
+		require.Equal(t, rec, popped)
+	}
+	require.Zero(t, queue.writeOff, "drained queue starts over")
+
+	_, rec := genKeyAndRec(t)
+	require.NoError(t, queue.Push(rec))
+	popped, err := queue.Pop()
+	require.NoError(t, err)
+	require.Equal(t, rec, popped)
+}
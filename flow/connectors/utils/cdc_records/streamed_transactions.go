@@ -0,0 +1,165 @@
+package cdc_records
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"os"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+type streamedRecord struct {
+	SubXid uint32
+	Record model.Record
+}
+
+// StreamedTransactions buffers changes of in-progress transactions streamed by the source until they commit.
+// Changes are kept in memory until the disk spill records threshold is reached, later ones are spilled to Pebble.
+// Spilled changes are keyed by the transaction's xid followed by a sequence number, so they are read back in order.
+type StreamedTransactions struct {
+	inMemoryRecords           map[uint32][]streamedRecord
+	numInMemoryRecords        int
+	abortedSubXids            map[uint32]map[uint32]struct{}
+	pebbleDB                  *pebble.DB
+	seq                       uint64
+	dbFolderName              string
+	numRecordsSwitchThreshold int
+}
+
+func NewStreamedTransactions(flowJobName string) *StreamedTransactions {
+	return &StreamedTransactions{
+		inMemoryRecords:           make(map[uint32][]streamedRecord),
+		abortedSubXids:            make(map[uint32]map[uint32]struct{}),
+		dbFolderName:              fmt.Sprintf("%s/%s_streams_%s", os.TempDir(), flowJobName, shared.RandomString(8)),
+		numRecordsSwitchThreshold: peerdbenv.PeerDBCDCDiskSpillRecordsThreshold(),
+	}
+}
+
+func streamedKey(xid uint32, seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, uint64(xid)), seq)
+}
+
+// streamedKeyRange returns the bounds of the keys of a transaction, 8 byte xids avoid overflowing the upper bound.
+func streamedKeyRange(xid uint32) ([]byte, []byte) {
+	return binary.BigEndian.AppendUint64(nil, uint64(xid)), binary.BigEndian.AppendUint64(nil, uint64(xid)+1)
+}
+
+// Append adds a change of subtransaction subXid to the streamed transaction xid.
+func (s *StreamedTransactions) Append(xid uint32, subXid uint32, rec model.Record) error {
+	if s.pebbleDB == nil && s.numInMemoryRecords < s.numRecordsSwitchThreshold {
+		s.inMemoryRecords[xid] = append(s.inMemoryRecords[xid], streamedRecord{SubXid: subXid, Record: rec})
+		s.numInMemoryRecords += 1
+		return nil
+	}
+
+	if s.pebbleDB == nil {
+		registerGobTypes()
+		var err error
+		s.pebbleDB, err = pebble.Open(s.dbFolderName, &pebble.Options{
+			DisableWAL:         true,
+			ErrorIfExists:      true,
+			FormatMajorVersion: pebble.FormatNewest,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize Pebble database: %w", err)
+		}
+	}
+
+	encodedRec, err := encVal(&streamedRecord{SubXid: subXid, Record: rec})
+	if err != nil {
+		return err
+	}
+	s.seq += 1
+	if err := s.pebbleDB.Set(streamedKey(xid, s.seq), encodedRec, pebble.NoSync); err != nil {
+		return fmt.Errorf("unable to store streamed record in Pebble: %w", err)
+	}
+	return nil
+}
+
+// Abort discards the changes of subtransaction subXid, or of the whole transaction when subXid is xid.
+func (s *StreamedTransactions) Abort(xid uint32, subXid uint32) error {
+	if xid == subXid {
+		return s.discard(xid)
+	}
+
+	aborted, ok := s.abortedSubXids[xid]
+	if !ok {
+		aborted = make(map[uint32]struct{})
+		s.abortedSubXids[xid] = aborted
+	}
+	aborted[subXid] = struct{}{}
+	return nil
+}
+
+// Commit calls fn with the changes of transaction xid in the order they were streamed, skipping aborted
+// subtransactions, and then discards them.
+func (s *StreamedTransactions) Commit(xid uint32, fn func(model.Record) error) error {
+	aborted := s.abortedSubXids[xid]
+	for _, rec := range s.inMemoryRecords[xid] {
+		if _, ok := aborted[rec.SubXid]; ok {
+			continue
+		}
+		if err := fn(rec.Record); err != nil {
+			return err
+		}
+	}
+
+	if s.pebbleDB != nil {
+		lower, upper := streamedKeyRange(xid)
+		iter, err := s.pebbleDB.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+		if err != nil {
+			return fmt.Errorf("failed to iterate streamed records: %w", err)
+		}
+		for iter.First(); iter.Valid(); iter.Next() {
+			var rec streamedRecord
+			if err := gob.NewDecoder(bytes.NewReader(iter.Value())).Decode(&rec); err != nil {
+				iter.Close()
+				return fmt.Errorf("failed to decode streamed record: %w", err)
+			}
+			if _, ok := aborted[rec.SubXid]; ok {
+				continue
+			}
+			if err := fn(rec.Record); err != nil {
+				iter.Close()
+				return err
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return fmt.Errorf("failed to iterate streamed records: %w", err)
+		}
+	}
+
+	return s.discard(xid)
+}
+
+func (s *StreamedTransactions) discard(xid uint32) error {
+	s.numInMemoryRecords -= len(s.inMemoryRecords[xid])
+	delete(s.inMemoryRecords, xid)
+	delete(s.abortedSubXids, xid)
+	if s.pebbleDB != nil {
+		lower, upper := streamedKeyRange(xid)
+		if err := s.pebbleDB.DeleteRange(lower, upper, pebble.NoSync); err != nil {
+			return fmt.Errorf("failed to delete streamed records: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *StreamedTransactions) Close() error {
+	s.inMemoryRecords = nil
+	if s.pebbleDB != nil {
+		if err := s.pebbleDB.Close(); err != nil {
+			return fmt.Errorf("failed to close database: %w", err)
+		}
+	}
+	if err := os.RemoveAll(s.dbFolderName); err != nil {
+		return fmt.Errorf("failed to delete database file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package cdc_records
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+func TestStreamedTransactionsSpillAndCommit(t *testing.T) {
+	t.Parallel()
+
+	streams := NewStreamedTransactions("test_streamed_transactions")
+	streams.numRecordsSwitchThreshold = 2
+
+	records := make([]model.Record, 0, 5)
+	for i := range 5 {
+		_, rec := genKeyAndRec(t)
+		rec.(*model.InsertRecord).CheckpointID = int64(i)
+		records = append(records, rec)
+	}
+
+	// 100 is the transaction, 101 a subtransaction which gets rolled back, 200 a concurrent transaction
+	require.NoError(t, streams.Append(100, 100, records[0]))
+	require.NoError(t, streams.Append(100, 101, records[1]))
+	require.NoError(t, streams.Append(200, 200, records[2]))
+	require.NoError(t, streams.Append(100, 100, records[3]))
+	require.NoError(t, streams.Append(100, 101, records[4]))
+	require.NotNil(t, streams.pebbleDB)
+	require.NoError(t, streams.Abort(100, 101))
+
+	var committed []int64
+	require.NoError(t, streams.Commit(100, func(rec model.Record) error {
+		committed = append(committed, rec.GetCheckpointID())
+		return nil
+	}))
+	require.Equal(t, []int64{0, 3}, committed)
+
+	require.NoError(t, streams.Abort(200, 200))
+	require.NoError(t, streams.Commit(200, func(rec model.Record) error {
+		t.Fatalf("aborted transaction should have no records, got %v", rec)
+		return nil
+	}))
+
+	require.NoError(t, streams.Close())
+}
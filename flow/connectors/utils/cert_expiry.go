@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// postgres SSLRequest code, see https://www.postgresql.org/docs/current/protocol-message-formats.html
+const pgSSLRequestCode = 80877103
+
+// CertificateExpiry returns the earliest expiry among PEM encoded certificates.
+func CertificateExpiry(pemCerts []byte) (time.Time, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, pemCerts = pem.Decode(pemCerts)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return time.Time{}, errors.New("no certificates found")
+	}
+	return earliestExpiry(certs), nil
+}
+
+func earliestExpiry(certs []*x509.Certificate) time.Time {
+	expiry := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	return expiry
+}
+
+func tlsInspectConfig(host string) *tls.Config {
+	return &tls.Config{
+		ServerName: host,
+		// only used to read the presented certificates, nothing is sent over the connection
+		//nolint:gosec
+		InsecureSkipVerify: true,
+	}
+}
+
+// TLSServerCertificateExpiry returns when the certificate chain presented by a TLS server expires.
+func TLSServerCertificateExpiry(ctx context.Context, host string, port uint32) (time.Time, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config:    tlsInspectConfig(host),
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no certificates presented by %s", host)
+	}
+	return earliestExpiry(certs), nil
+}
+
+// PostgresServerCertificateExpiry negotiates SSL with a Postgres server and returns when its certificate chain expires.
+// A zero time is returned when the server does not support SSL.
+func PostgresServerCertificateExpiry(ctx context.Context, host string, port uint32) (time.Time, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var sslRequest [8]byte
+	binary.BigEndian.PutUint32(sslRequest[0:4], 8)
+	binary.BigEndian.PutUint32(sslRequest[4:8], pgSSLRequestCode)
+	if _, err := conn.Write(sslRequest[:]); err != nil {
+		return time.Time{}, fmt.Errorf("failed to send SSL request: %w", err)
+	}
+	var response [1]byte
+	if _, err := io.ReadFull(conn, response[:]); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read SSL response: %w", err)
+	}
+	if response[0] != 'S' {
+		return time.Time{}, nil
+	}
+
+	tlsConn := tls.Client(conn, tlsInspectConfig(host))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return time.Time{}, fmt.Errorf("failed TLS handshake with %s: %w", host, err)
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no certificates presented by %s", host)
+	}
+	return earliestExpiry(certs), nil
+}
+
+// GCPServiceAccountKeyExpiry looks up the public certificate of a service account key, which expires along with the key.
+func GCPServiceAccountKeyExpiry(ctx context.Context, certURL string, keyID string) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certURL, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch service account certificates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("failed to fetch service account certificates: %s", resp.Status)
+	}
+
+	var certs map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode service account certificates: %w", err)
+	}
+	cert, ok := certs[keyID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("no certificate found for key %s, it may have been deleted", keyID)
+	}
+	return CertificateExpiry([]byte(cert))
+}
+
+// PeerCredentialExpiries returns the expiry of credentials attached to a peer that can be determined.
+// Credentials without an expiry, like passwords or Snowflake key pairs, are not reported,
+// connectors.PeerCredentialExpiries adds when the user of a Snowflake key pair expires.
+func PeerCredentialExpiries(ctx context.Context, peer *protos.Peer) ([]*protos.PeerCredentialExpiry, error) {
+	var expiries []*protos.PeerCredentialExpiry
+	addExpiry := func(credential string, expiresAt time.Time) {
+		if !expiresAt.IsZero() {
+			expiries = append(expiries, &protos.PeerCredentialExpiry{
+				Credential: credential,
+				ExpiresAt:  timestamppb.New(expiresAt),
+			})
+		}
+	}
+
+	var tlsConfig *protos.TLSConfig
+	switch config := peer.Config.(type) {
+	case *protos.Peer_PostgresConfig:
+		tlsConfig = config.PostgresConfig.TlsConfig
+		// with an SSH tunnel or proxy the server is usually not reachable directly
+		if config.PostgresConfig.SshConfig == nil && config.PostgresConfig.ProxyConfig == nil {
+			expiresAt, err := PostgresServerCertificateExpiry(ctx, config.PostgresConfig.Host, config.PostgresConfig.Port)
+			if err != nil {
+				return nil, err
+			}
+			addExpiry("server certificate", expiresAt)
+		}
+	case *protos.Peer_ClickhouseConfig:
+		tlsConfig = config.ClickhouseConfig.TlsConfig
+		if !config.ClickhouseConfig.DisableTls &&
+			config.ClickhouseConfig.SshConfig == nil && config.ClickhouseConfig.ProxyConfig == nil {
+			expiresAt, err := TLSServerCertificateExpiry(ctx, config.ClickhouseConfig.Host, config.ClickhouseConfig.Port)
+			if err != nil {
+				return nil, err
+			}
+			addExpiry("server certificate", expiresAt)
+		}
+	case *protos.Peer_SnowflakeConfig:
+		tlsConfig = config.SnowflakeConfig.TlsConfig
+	case *protos.Peer_BigqueryConfig:
+		if config.BigqueryConfig.ClientX509CertUrl != "" && config.BigqueryConfig.PrivateKeyId != "" {
+			expiresAt, err := GCPServiceAccountKeyExpiry(ctx,
+				config.BigqueryConfig.ClientX509CertUrl, config.BigqueryConfig.PrivateKeyId)
+			if err != nil {
+				return nil, err
+			}
+			addExpiry("service account key", expiresAt)
+		}
+	}
+
+	if tlsConfig.GetRootCa() != "" {
+		expiresAt, err := CertificateExpiry([]byte(tlsConfig.RootCa))
+		if err != nil {
+			return nil, err
+		}
+		addExpiry("root CA", expiresAt)
+	}
+	if tlsConfig.GetClientCert() != "" {
+		expiresAt, err := CertificateExpiry([]byte(tlsConfig.ClientCert))
+		if err != nil {
+			return nil, err
+		}
+		addExpiry("client certificate", expiresAt)
+	}
+
+	return expiries, nil
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func generateCertPEM(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "peerdb-test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	earlier := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Second).UTC()
+	later := earlier.Add(365 * 24 * time.Hour)
+
+	chain := append(generateCertPEM(t, later), generateCertPEM(t, earlier)...)
+	expiry, err := CertificateExpiry(chain)
+	require.NoError(t, err)
+	require.Equal(t, earlier, expiry.UTC())
+
+	_, err = CertificateExpiry([]byte("not a certificate"))
+	require.Error(t, err)
+}
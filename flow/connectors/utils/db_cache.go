@@ -0,0 +1,279 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// PooledDB is a database/sql pool to a peer, shared by the connectors to the peer on this worker.
+// Every AcquireDB must be paired with a Release, the pool is closed once it's been unused for
+// PEERDB_CONNECTION_CACHE_IDLE_TIMEOUT_SECONDS.
+type PooledDB struct {
+	DB *sql.DB
+	// tunnel the pool's connections go through, for connectors opening other connections to the peer
+	Dialer *Dialer
+	poolEntry
+}
+
+// PooledPgPool is a pgx pool to a Postgres peer, shared by the connectors to the peer on this worker like PooledDB.
+// Every AcquirePgPool must be paired with a Release.
+type PooledPgPool struct {
+	Pool *pgxpool.Pool
+	// tunnel the pool's connections go through
+	Dialer *Dialer
+	poolEntry
+}
+
+// poolEntry is what the cache keeps track of for each pool.
+type poolEntry struct {
+	key       string
+	refs      int
+	stale     bool
+	idleSince time.Time
+}
+
+func (e *poolEntry) entry() *poolEntry {
+	return e
+}
+
+// cachedPool is a pool kept in a poolCache.
+type cachedPool interface {
+	comparable
+	entry() *poolEntry
+	close() error
+}
+
+// OpenDBFunc opens a pool to a peer, checking it can connect.
+type OpenDBFunc func(ctx context.Context) (*sql.DB, *Dialer, error)
+
+// OpenPgPoolFunc opens a pgx pool to a Postgres peer, checking it can connect.
+type OpenPgPoolFunc func(ctx context.Context) (*pgxpool.Pool, *Dialer, error)
+
+type poolCache[P cachedPool] struct {
+	pools map[string]P
+	lock  sync.Mutex
+}
+
+var (
+	pooledDBs     = &poolCache[*PooledDB]{pools: make(map[string]*PooledDB)}
+	pooledPgPools = &poolCache[*PooledPgPool]{pools: make(map[string]*PooledPgPool)}
+)
+
+// AcquireDB returns the pool for a peer's config, opening it if this worker has none.
+// Configs are compared whole, so editing a peer gets connectors a new pool with the new settings.
+func AcquireDB(
+	ctx context.Context,
+	peerConfig proto.Message,
+	poolConfig *protos.ConnectionPoolConfig,
+	open OpenDBFunc,
+) (*PooledDB, error) {
+	key, err := dbCacheKey(peerConfig)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout := peerdbenv.PeerDBConnectionCacheIdleTimeout()
+
+	if pooled := pooledDBs.acquire(key); pooled != nil {
+		if !poolConfig.GetPingOnAcquire() {
+			return pooled, nil
+		}
+		err := pooled.DB.PingContext(ctx)
+		if err == nil {
+			return pooled, nil
+		}
+		slog.Warn("pooled connection to peer failed health check, reconnecting", slog.Any("error", err))
+		pooledDBs.evict(pooled, idleTimeout)
+	}
+
+	db, dialer, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configurePool(db, poolConfig, idleTimeout)
+	return pooledDBs.add(key, &PooledDB{DB: db, Dialer: dialer}), nil
+}
+
+// AcquirePgPool returns the pgx pool for a Postgres peer's config, opening it if this worker has none.
+// Configs are compared whole like in AcquireDB. The pool checks its connections' health itself when they're acquired,
+// open should apply the peer's pool settings with ConfigurePgPool.
+func AcquirePgPool(ctx context.Context, peerConfig *protos.PostgresConfig, open OpenPgPoolFunc) (*PooledPgPool, error) {
+	key, err := dbCacheKey(peerConfig)
+	if err != nil {
+		return nil, err
+	}
+	if pooled := pooledPgPools.acquire(key); pooled != nil {
+		return pooled, nil
+	}
+
+	pool, dialer, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pooledPgPools.add(key, &PooledPgPool{Pool: pool, Dialer: dialer}), nil
+}
+
+// Release hands the pool back to the cache, it must not be used by the caller afterwards.
+func (p *PooledDB) Release() {
+	pooledDBs.release(p, peerdbenv.PeerDBConnectionCacheIdleTimeout())
+}
+
+func (p *PooledDB) close() error {
+	err := p.DB.Close()
+	if p.Dialer != nil {
+		err = errors.Join(err, p.Dialer.Close())
+	}
+	return err
+}
+
+// Release hands the pool back to the cache, it must not be used by the caller afterwards.
+func (p *PooledPgPool) Release() {
+	pooledPgPools.release(p, peerdbenv.PeerDBConnectionCacheIdleTimeout())
+}
+
+func (p *PooledPgPool) close() error {
+	p.Pool.Close()
+	if p.Dialer != nil {
+		return p.Dialer.Close()
+	}
+	return nil
+}
+
+func dbCacheKey(peerConfig proto.Message) (string, error) {
+	serialized, err := proto.MarshalOptions{Deterministic: true}.Marshal(peerConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize peer config: %w", err)
+	}
+	// hashed so that credentials in the config aren't kept around as map keys
+	hash := sha256.Sum256(serialized)
+	return fmt.Sprintf("%T:%s", peerConfig, hex.EncodeToString(hash[:])), nil
+}
+
+func configurePool(db *sql.DB, poolConfig *protos.ConnectionPoolConfig, idleTimeout time.Duration) {
+	if maxOpen := poolConfig.GetMaxOpenConnections(); maxOpen > 0 {
+		db.SetMaxOpenConns(int(maxOpen))
+	}
+	if maxIdle := poolConfig.GetMaxIdleConnections(); maxIdle > 0 {
+		db.SetMaxIdleConns(int(maxIdle))
+	}
+	if lifetime := poolConfig.GetMaxConnectionLifetimeSeconds(); lifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(lifetime) * time.Second)
+	}
+	// connections left idle by activities are closed even if the pool is never swept
+	if idleTimeout > 0 {
+		db.SetConnMaxIdleTime(idleTimeout)
+	}
+}
+
+// ConfigurePgPool applies a Postgres peer's pool settings to its pgx pool before it's opened.
+// pgx keeps no idle connections beyond those in use, so max_idle_connections doesn't apply.
+func ConfigurePgPool(config *pgxpool.Config, poolConfig *protos.ConnectionPoolConfig, idleTimeout time.Duration) {
+	if maxOpen := poolConfig.GetMaxOpenConnections(); maxOpen > 0 {
+		config.MaxConns = int32(min(maxOpen, math.MaxInt32))
+	}
+	if lifetime := poolConfig.GetMaxConnectionLifetimeSeconds(); lifetime > 0 {
+		config.MaxConnLifetime = time.Duration(lifetime) * time.Second
+	}
+	if idleTimeout > 0 {
+		config.MaxConnIdleTime = idleTimeout
+	}
+	// pgx only pings connections idle for over a second itself
+	if poolConfig.GetPingOnAcquire() {
+		config.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+			return conn.Ping(ctx) == nil
+		}
+	}
+}
+
+func (c *poolCache[P]) acquire(key string) P {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	pooled, ok := c.pools[key]
+	if !ok {
+		var none P
+		return none
+	}
+	pooled.entry().refs += 1
+	return pooled
+}
+
+// add caches a newly opened pool, unless another one for the same key was opened meanwhile
+func (c *poolCache[P]) add(key string, pooled P) P {
+	c.lock.Lock()
+	existing, ok := c.pools[key]
+	if ok {
+		existing.entry().refs += 1
+	} else {
+		pooled.entry().key = key
+		pooled.entry().refs = 1
+		c.pools[key] = pooled
+	}
+	c.lock.Unlock()
+
+	if ok {
+		if err := pooled.close(); err != nil {
+			slog.Warn("failed to close duplicate connection pool to peer", slog.Any("error", err))
+		}
+		return existing
+	}
+	return pooled
+}
+
+// evict stops handing out a pool which failed its health check, it's closed once its last user releases it
+func (c *poolCache[P]) evict(pooled P, idleTimeout time.Duration) {
+	c.lock.Lock()
+	pooled.entry().stale = true
+	if c.pools[pooled.entry().key] == pooled {
+		delete(c.pools, pooled.entry().key)
+	}
+	c.lock.Unlock()
+
+	c.release(pooled, idleTimeout)
+}
+
+// release drops a reference to a pool, closing it if it's stale, and closes pools left unused for idleTimeout
+func (c *poolCache[P]) release(pooled P, idleTimeout time.Duration) {
+	now := time.Now()
+	var toClose []P
+
+	c.lock.Lock()
+	entry := pooled.entry()
+	entry.refs -= 1
+	if entry.refs == 0 {
+		entry.idleSince = now
+		if entry.stale || idleTimeout <= 0 {
+			toClose = append(toClose, pooled)
+			if c.pools[entry.key] == pooled {
+				delete(c.pools, entry.key)
+			}
+		}
+	}
+	for key, idle := range c.pools {
+		if idle.entry().refs == 0 && now.Sub(idle.entry().idleSince) >= idleTimeout {
+			toClose = append(toClose, idle)
+			delete(c.pools, key)
+		}
+	}
+	c.lock.Unlock()
+
+	for _, idle := range toClose {
+		if err := idle.close(); err != nil {
+			slog.Warn("failed to close connection pool to peer", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type testConnector struct{}
+
+func (testConnector) Connect(context.Context) (driver.Conn, error) { return testConn{}, nil }
+func (testConnector) Driver() driver.Driver                        { return nil }
+
+type testConn struct{}
+
+func (testConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (testConn) Close() error                        { return nil }
+func (testConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func testPooledDB() *PooledDB {
+	return &PooledDB{DB: sql.OpenDB(testConnector{})}
+}
+
+func TestDBCache(t *testing.T) {
+	cache := &poolCache[*PooledDB]{pools: make(map[string]*PooledDB)}
+
+	pooled := cache.add("peer", testPooledDB())
+	require.Same(t, pooled, cache.acquire("peer"))
+	cache.release(pooled, time.Hour)
+	cache.release(pooled, time.Hour)
+	// kept open for the next activity
+	require.NoError(t, pooled.DB.Ping())
+	require.Same(t, pooled, cache.acquire("peer"))
+
+	// a pool opened concurrently for the same peer is closed in favor of the cached one
+	duplicate := testPooledDB()
+	require.Same(t, pooled, cache.add("peer", duplicate))
+	require.Error(t, duplicate.DB.Ping())
+
+	// an evicted pool isn't handed out again and is closed once its last user is done
+	cache.evict(pooled, time.Hour)
+	require.Nil(t, cache.acquire("peer"))
+	require.NoError(t, pooled.DB.Ping())
+	cache.release(pooled, time.Hour)
+	require.Error(t, pooled.DB.Ping())
+
+	idle := cache.add("idle", testPooledDB())
+	cache.release(idle, time.Hour)
+	idle.idleSince = time.Now().Add(-2 * time.Hour)
+	other := cache.add("other", testPooledDB())
+	cache.release(other, time.Hour)
+	require.Error(t, idle.DB.Ping())
+	require.NoError(t, other.DB.Ping())
+	require.Nil(t, cache.acquire("idle"))
+
+	// without an idle timeout pools are closed as soon as they're unused
+	require.Same(t, other, cache.acquire("other"))
+	cache.release(other, 0)
+	require.Error(t, other.DB.Ping())
+	require.Empty(t, cache.pools)
+}
+
+func TestAcquirePgPool(t *testing.T) {
+	t.Setenv("PEERDB_CONNECTION_CACHE_IDLE_TIMEOUT_SECONDS", "0")
+	ctx := context.Background()
+	opened := 0
+	open := func(ctx context.Context) (*pgxpool.Pool, *Dialer, error) {
+		opened += 1
+		// pools only connect once a connection is acquired
+		pool, err := pgxpool.New(ctx, "postgres://peerdb@127.0.0.1:1/peerdb")
+		return pool, nil, err
+	}
+	peerConfig := &protos.PostgresConfig{Host: "127.0.0.1", Port: 1, User: "peerdb", Database: "peerdb"}
+
+	pooled, err := AcquirePgPool(ctx, peerConfig, open)
+	require.NoError(t, err)
+	again, err := AcquirePgPool(ctx, peerConfig, open)
+	require.NoError(t, err)
+	require.Same(t, pooled, again)
+	require.Equal(t, 1, opened, "connectors to the same peer share its pool")
+
+	edited, err := AcquirePgPool(ctx, &protos.PostgresConfig{Host: "127.0.0.1", Port: 1, User: "peerdb", Database: "other"}, open)
+	require.NoError(t, err)
+	require.NotSame(t, pooled, edited)
+	edited.Release()
+
+	pooled.Release()
+	require.NotNil(t, pooledPgPools.pools[pooled.key])
+	again.Release()
+	require.Nil(t, pooledPgPools.pools[pooled.key], "pools are closed once unused without an idle timeout")
+	_, err = pooled.Pool.Acquire(ctx)
+	require.Error(t, err)
+}
+
+func TestConfigurePgPool(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://peerdb@127.0.0.1:1/peerdb")
+	require.NoError(t, err)
+	defaults := *config
+
+	ConfigurePgPool(config, nil, 0)
+	require.Equal(t, defaults.MaxConns, config.MaxConns, "peers without pool settings keep pgx's defaults")
+	require.Equal(t, defaults.MaxConnLifetime, config.MaxConnLifetime)
+	require.Nil(t, config.BeforeAcquire)
+
+	ConfigurePgPool(config, &protos.ConnectionPoolConfig{
+		MaxOpenConnections:           3,
+		MaxConnectionLifetimeSeconds: 60,
+		PingOnAcquire:                true,
+	}, time.Minute)
+	require.Equal(t, int32(3), config.MaxConns)
+	require.Equal(t, time.Minute, config.MaxConnLifetime)
+	require.Equal(t, time.Minute, config.MaxConnIdleTime)
+	require.NotNil(t, config.BeforeAcquire)
+}
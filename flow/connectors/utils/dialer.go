@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// Dialer connects to a peer's host directly, through a SOCKS5 proxy, through an SSH tunnel,
+// or through an SSH tunnel to a bastion which is itself reached over the proxy.
+// It is shared by the SQL connectors so peers behind a bastion can be reached the same way everywhere.
+type Dialer struct {
+	proxy     proxy.ContextDialer
+	sshClient *ssh.Client
+}
+
+func NewDialer(ctx context.Context, sshConfig *protos.SSHConfig, proxyConfig *protos.ProxyConfig) (*Dialer, error) {
+	dialer := &Dialer{}
+
+	if proxyConfig != nil {
+		var auth *proxy.Auth
+		if proxyConfig.User != "" {
+			auth = &proxy.Auth{User: proxyConfig.User, Password: proxyConfig.Password}
+		}
+		proxyAddr := net.JoinHostPort(proxyConfig.Host, strconv.FormatUint(uint64(proxyConfig.Port), 10))
+		socksDialer, err := proxy.SOCKS5("tcp", proxyAddr, auth, &net.Dialer{Timeout: 30 * time.Second})
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up SOCKS5 proxy %s: %w", proxyAddr, err)
+		}
+		dialer.proxy = socksDialer.(proxy.ContextDialer)
+	}
+
+	if sshConfig != nil {
+		clientConfig, err := GetSSHClientConfig(sshConfig)
+		if err != nil {
+			slog.Error("Failed to get SSH client config", slog.Any("error", err))
+			return nil, err
+		}
+
+		sshServer := fmt.Sprintf("%s:%d", sshConfig.Host, sshConfig.Port)
+		slog.Info("Setting up SSH connection to " + sshServer)
+		conn, err := dialer.dialHost(ctx, "tcp", sshServer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to SSH server %s: %w", sshServer, err)
+		}
+		sshConn, chans, reqs, err := ssh.NewClientConn(conn, sshServer, clientConfig)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set up SSH connection to %s: %w", sshServer, err)
+		}
+		dialer.sshClient = ssh.NewClient(sshConn, chans, reqs)
+	}
+
+	return dialer, nil
+}
+
+// Tunneled is true when connections don't go straight to the peer's host.
+func (d *Dialer) Tunneled() bool {
+	return d.proxy != nil || d.sshClient != nil
+}
+
+// DialContext connects to addr through the tunnel or proxy, if any. Connections through SSH ignore deadlines,
+// which SSH channels don't support and drivers otherwise fail on.
+func (d *Dialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if d.sshClient != nil {
+		conn, err := d.sshClient.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &noDeadlineConn{Conn: conn}, nil
+	}
+	return d.dialHost(ctx, network, addr)
+}
+
+func (d *Dialer) dialHost(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if d.proxy != nil {
+		return d.proxy.DialContext(ctx, network, addr)
+	}
+	var netDialer net.Dialer
+	return netDialer.DialContext(ctx, network, addr)
+}
+
+func (d *Dialer) Close() error {
+	if d.sshClient != nil {
+		return d.sshClient.Close()
+	}
+	return nil
+}
+
+// see: https://github.com/jackc/pgx/issues/382#issuecomment-1496586216
+type noDeadlineConn struct{ net.Conn }
+
+func (c *noDeadlineConn) SetDeadline(t time.Time) error      { return nil }
+func (c *noDeadlineConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *noDeadlineConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// KeyBuckets summarizes a set of primary keys as a count and a sum of key hashes per bucket,
+// so the keys of a table at the source and destination can be compared bucket by bucket
+// without holding either set in memory. Keys are hashed by PrimaryKeyString.
+type KeyBuckets struct {
+	counts []int64
+	sums   []uint64
+}
+
+func NewKeyBuckets(numBuckets int) *KeyBuckets {
+	return &KeyBuckets{
+		counts: make([]int64, numBuckets),
+		sums:   make([]uint64, numBuckets),
+	}
+}
+
+// Add counts a key into its bucket and returns the bucket.
+func (b *KeyBuckets) Add(key []qvalue.QValue) int {
+	hash := hashPrimaryKey(PrimaryKeyString(key))
+	bucket := b.bucketOf(hash)
+	b.counts[bucket] += 1
+	b.sums[bucket] += hash
+	return bucket
+}
+
+// AddRow counts a row into the bucket of its key, summing a hash of the row's values instead of the key's,
+// so buckets also differ when rows with the same keys hold different values. Returns the bucket.
+func (b *KeyBuckets) AddRow(key []qvalue.QValue, row []qvalue.QValue) int {
+	bucket := b.Bucket(key)
+	b.counts[bucket] += 1
+	b.sums[bucket] += hashPrimaryKey(PrimaryKeyString(row))
+	return bucket
+}
+
+// Count returns the keys counted into every bucket.
+func (b *KeyBuckets) Count() int64 {
+	var count int64
+	for _, c := range b.counts {
+		count += c
+	}
+	return count
+}
+
+// Bucket returns the bucket of a key without counting it.
+func (b *KeyBuckets) Bucket(key []qvalue.QValue) int {
+	return b.bucketOf(hashPrimaryKey(PrimaryKeyString(key)))
+}
+
+func (b *KeyBuckets) bucketOf(hash uint64) int {
+	return int(hash % uint64(len(b.counts)))
+}
+
+// Mismatched returns the buckets holding different keys in b and other, which must have as many buckets.
+func (b *KeyBuckets) Mismatched(other *KeyBuckets) map[int]struct{} {
+	mismatched := make(map[int]struct{})
+	for i := range b.counts {
+		if b.counts[i] != other.counts[i] || b.sums[i] != other.sums[i] {
+			mismatched[i] = struct{}{}
+		}
+	}
+	return mismatched
+}
+
+func hashPrimaryKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// PrimaryKeyString renders a primary key the same way whichever peer it was read from,
+// numbers by value, UUIDs as text and times in UTC. Values are length prefixed to keep them apart.
+func PrimaryKeyString(key []qvalue.QValue) string {
+	var sb strings.Builder
+	for _, qv := range key {
+		var value string
+		switch v := qv.Value().(type) {
+		case nil:
+			sb.WriteString("-")
+			continue
+		case string:
+			value = v
+		case [16]byte:
+			value = uuid.UUID(v).String()
+		case []byte:
+			value = hex.EncodeToString(v)
+		case time.Time:
+			value = v.UTC().Format(time.RFC3339Nano)
+		case *big.Rat:
+			value = v.RatString()
+		default:
+			if rat, ok := canonicalNumeric(qv).Value().(*big.Rat); ok {
+				value = rat.RatString()
+			} else {
+				value = FormatSampleValue(qv)
+			}
+		}
+		sb.WriteString(strconv.Itoa(len(value)))
+		sb.WriteByte(':')
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
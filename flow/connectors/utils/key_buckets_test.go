@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestPrimaryKeyString(t *testing.T) {
+	id := uuid.New()
+	// the source's types
+	src := []qvalue.QValue{
+		qvalue.NewInt32(qvalue.QValueKindInt32, int32(7)),
+		qvalue.New(qvalue.QValueKindUUID, [16]byte(id)),
+	}
+	// as a destination which keeps integers as numbers and UUIDs as strings reads them back
+	dst := []qvalue.QValue{
+		qvalue.New(qvalue.QValueKindNumeric, big.NewRat(7, 1)),
+		qvalue.New(qvalue.QValueKindString, id.String()),
+	}
+	require.Equal(t, PrimaryKeyString(src), PrimaryKeyString(dst))
+
+	require.NotEqual(t,
+		PrimaryKeyString([]qvalue.QValue{qvalue.New(qvalue.QValueKindString, "a"), qvalue.New(qvalue.QValueKindString, "b")}),
+		PrimaryKeyString([]qvalue.QValue{qvalue.New(qvalue.QValueKindString, "a\x1fb")}))
+}
+
+func TestKeyBucketsMismatched(t *testing.T) {
+	key := func(id int64) []qvalue.QValue {
+		return []qvalue.QValue{qvalue.New(qvalue.QValueKindInt64, id)}
+	}
+
+	source := NewKeyBuckets(16)
+	destination := NewKeyBuckets(16)
+	for id := range int64(100) {
+		source.Add(key(id))
+		destination.Add(key(id))
+	}
+	require.Empty(t, source.Mismatched(destination))
+
+	deleted := destination.Add(key(100))
+	mismatched := source.Mismatched(destination)
+	require.Len(t, mismatched, 1)
+	require.Contains(t, mismatched, deleted)
+	require.Equal(t, deleted, source.Bucket(key(100)))
+}
+
+func TestKeyBucketsAddRow(t *testing.T) {
+	row := func(id int64, name string) []qvalue.QValue {
+		return []qvalue.QValue{qvalue.New(qvalue.QValueKindInt64, id), qvalue.New(qvalue.QValueKindString, name)}
+	}
+
+	source := NewKeyBuckets(16)
+	destination := NewKeyBuckets(16)
+	for id := range int64(100) {
+		source.AddRow(row(id, "a")[:1], row(id, "a"))
+		if id == 42 {
+			destination.AddRow(row(id, "b")[:1], row(id, "b"))
+		} else {
+			destination.AddRow(row(id, "a")[:1], row(id, "a"))
+		}
+	}
+	require.Equal(t, int64(100), source.Count())
+	require.Equal(t, source.Count(), destination.Count())
+	mismatched := source.Mismatched(destination)
+	require.Len(t, mismatched, 1, "rows with the same key but different values")
+	require.Contains(t, mismatched, source.Bucket(row(42, "")[:1]))
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.temporal.io/sdk/temporal"
+)
+
+// DestinationMaintenanceErrorType is the type of the non-retryable errors sync flows fail with while their destination
+// is read-only or down for maintenance, so the CDC flow backs off on its own instead of retrying the activity.
+const DestinationMaintenanceErrorType = "destinationMaintenance"
+
+const (
+	maintenanceInitialBackoff = 30 * time.Second
+	maintenanceMaxBackoff     = 10 * time.Minute
+)
+
+// ClickHouse error codes of writes to readonly replicas and tables
+const (
+	clickhouseReadonly        = 164
+	clickhouseTableIsReadOnly = 242
+)
+
+// lowercase fragments of errors destinations return while read-only or in maintenance, for drivers without codes for them
+var maintenanceMessages = []string{
+	"read-only",
+	"readonly mode",
+	"read only mode",
+	"in maintenance",
+	"under maintenance",
+	"undergoing maintenance",
+	"service unavailable",
+}
+
+// IsDestinationMaintenanceError tells whether err means the destination can't be written to for now,
+// as opposed to the write itself being wrong.
+func IsDestinationMaintenanceError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgerrcode.ReadOnlySQLTransaction || pgErr.Code == pgerrcode.CannotConnectNow
+	}
+	var chErr *clickhouse.Exception
+	if errors.As(err, &chErr) {
+		return chErr.Code == clickhouseReadonly || chErr.Code == clickhouseTableIsReadOnly
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range maintenanceMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// DestinationMaintenanceError wraps err to fail an activity without retrying it, see DestinationMaintenanceErrorType.
+func DestinationMaintenanceError(err error) error {
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("destination is read-only or in maintenance: %v", err), DestinationMaintenanceErrorType, err)
+}
+
+// IsDestinationMaintenanceFailure tells whether an activity failed with DestinationMaintenanceError.
+func IsDestinationMaintenanceFailure(err error) bool {
+	var appErr *temporal.ApplicationError
+	return errors.As(err, &appErr) && appErr.Type() == DestinationMaintenanceErrorType
+}
+
+// MaintenanceBackoff is how long to wait before the next attempt at a destination in maintenance,
+// doubling with every attempt up to a cap.
+func MaintenanceBackoff(attempt int) time.Duration {
+	backoff := maintenanceInitialBackoff
+	for range attempt {
+		backoff *= 2
+		if backoff >= maintenanceMaxBackoff {
+			return maintenanceMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// RetryDuringMaintenance calls f until it returns something other than a maintenance error,
+// backing off in between and calling onWait before each wait.
+func RetryDuringMaintenance(ctx context.Context, onWait func(attempt int, backoff time.Duration, err error), f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if !IsDestinationMaintenanceError(err) {
+			return err
+		}
+
+		backoff := MaintenanceBackoff(attempt)
+		onWait(attempt, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
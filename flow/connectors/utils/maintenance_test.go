@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDestinationMaintenanceError(t *testing.T) {
+	require.False(t, IsDestinationMaintenanceError(nil))
+	require.True(t, IsDestinationMaintenanceError(
+		fmt.Errorf("failed to insert: %w", &pgconn.PgError{Code: pgerrcode.ReadOnlySQLTransaction})))
+	require.True(t, IsDestinationMaintenanceError(&pgconn.PgError{Code: pgerrcode.CannotConnectNow}))
+	require.False(t, IsDestinationMaintenanceError(&pgconn.PgError{Code: pgerrcode.UniqueViolation}))
+	require.True(t, IsDestinationMaintenanceError(&clickhouse.Exception{Code: clickhouseReadonly}))
+	require.False(t, IsDestinationMaintenanceError(&clickhouse.Exception{Code: 60}))
+	require.True(t, IsDestinationMaintenanceError(errors.New("Warehouse is under maintenance")))
+	require.False(t, IsDestinationMaintenanceError(errors.New("column does not exist")))
+
+	wrapped := DestinationMaintenanceError(errors.New("cannot execute INSERT in a read-only transaction"))
+	require.True(t, IsDestinationMaintenanceFailure(fmt.Errorf("sync failed: %w", wrapped)))
+	require.False(t, IsDestinationMaintenanceFailure(errors.New("cannot execute INSERT in a read-only transaction")))
+}
+
+func TestMaintenanceBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, MaintenanceBackoff(0))
+	require.Equal(t, time.Minute, MaintenanceBackoff(1))
+	require.Equal(t, 8*time.Minute, MaintenanceBackoff(4))
+	require.Equal(t, 10*time.Minute, MaintenanceBackoff(5))
+	require.Equal(t, 10*time.Minute, MaintenanceBackoff(1000))
+}
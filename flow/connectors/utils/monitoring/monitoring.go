@@ -2,6 +2,7 @@ package monitoring
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
@@ -11,7 +12,9 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	partition_utils "github.com/PeerDB-io/peer-flow/connectors/utils/partition"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/logger"
 	"github.com/PeerDB-io/peer-flow/shared"
@@ -174,6 +177,43 @@ func InitializeQRepRun(
 	return nil
 }
 
+// UpdateEstimatedRowsForQRepRun marks a run as a mirror's initial copy, recording the rows it's estimated to copy,
+// 0 if they couldn't be estimated.
+func UpdateEstimatedRowsForQRepRun(ctx context.Context, pool *pgxpool.Pool, flowJobName string, runUUID string,
+	estimatedRows int64,
+) error {
+	_, err := pool.Exec(ctx,
+		"UPDATE peerdb_stats.qrep_runs SET estimated_rows=$1 WHERE flow_name=$2 AND run_uuid=$3",
+		estimatedRows, flowJobName, runUUID)
+	if err != nil {
+		return fmt.Errorf("error while updating estimated rows for run_uuid %s in qrep_runs: %w", runUUID, err)
+	}
+	return nil
+}
+
+// GetQRepInitialLoadProgress returns the progress of a mirror's initial copy from its partitions,
+// nil if the mirror had none. Rows are counted as partitions complete.
+func GetQRepInitialLoadProgress(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+) (*protos.InitialLoadProgress, error) {
+	var progress protos.InitialLoadProgress
+	var startTime pgtype.Timestamp
+	err := pool.QueryRow(ctx, `SELECT r.estimated_rows, MIN(p.start_time), COUNT(p.partition_uuid), COUNT(p.end_time),
+	 COALESCE(SUM(p.rows_synced) FILTER (WHERE p.end_time IS NOT NULL),0)::BIGINT
+	 FROM peerdb_stats.qrep_runs r LEFT JOIN peerdb_stats.qrep_partitions p ON p.run_uuid=r.run_uuid
+	 WHERE r.flow_name=$1 AND r.estimated_rows IS NOT NULL
+	 GROUP BY r.id ORDER BY r.id DESC LIMIT 1`, flowJobName).Scan(
+		&progress.EstimatedRows, &startTime, &progress.PartitionsTotal, &progress.PartitionsCompleted, &progress.RowsCopied)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error while querying initial load progress: %w", err)
+	}
+	if startTime.Valid {
+		progress.StartTime = timestamppb.New(startTime.Time)
+	}
+	return &progress, nil
+}
+
 func UpdateStartTimeForQRepRun(ctx context.Context, pool *pgxpool.Pool, runUUID string) error {
 	_, err := pool.Exec(ctx,
 		"UPDATE peerdb_stats.qrep_runs SET start_time=$1 WHERE run_uuid=$2",
@@ -204,8 +244,9 @@ func AppendSlotSizeInfo(
 ) error {
 	_, err := pool.Exec(ctx,
 		"INSERT INTO peerdb_stats.peer_slot_size"+
-			"(peer_name, slot_name, restart_lsn, redo_lsn, confirmed_flush_lsn, slot_size, wal_status) "+
-			"VALUES($1,$2,$3,$4,$5,$6,$7) ON CONFLICT DO NOTHING;",
+			"(peer_name, slot_name, restart_lsn, redo_lsn, confirmed_flush_lsn, slot_size, wal_status, "+
+			"current_lsn, restart_lag_bytes, confirmed_flush_lag_bytes, confirmed_flush_lag_seconds) "+
+			"VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) ON CONFLICT DO NOTHING;",
 		peerName,
 		slotInfo.SlotName,
 		slotInfo.RestartLSN,
@@ -213,6 +254,10 @@ func AppendSlotSizeInfo(
 		slotInfo.ConfirmedFlushLSN,
 		slotInfo.LagInMb,
 		slotInfo.WalStatus,
+		slotInfo.CurrentLSN,
+		slotInfo.RestartLagBytes,
+		slotInfo.ConfirmedFlushLagBytes,
+		slotInfo.ConfirmedFlushLagSeconds,
 	)
 	if err != nil {
 		return fmt.Errorf("error while upserting row for slot_size: %w", err)
@@ -221,6 +266,134 @@ func AppendSlotSizeInfo(
 	return nil
 }
 
+// GetSlotConfirmedFlushLag returns how long ago the source's WAL was at the slot's confirmed_flush_lsn,
+// from the WAL positions recorded with the slot's earlier sizes. It's measured from the last size recorded
+// before the WAL passed confirmed_flush_lsn, or from the first size recorded when it's older than all of them.
+func GetSlotConfirmedFlushLag(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	peerName string,
+	slotInfo *protos.SlotInfo,
+) (time.Duration, error) {
+	if slotInfo.ConfirmedFlushLagBytes <= 0 || slotInfo.ConfirmedFlushLSN == "" {
+		return 0, nil
+	}
+
+	var lagSeconds float64
+	err := pool.QueryRow(ctx, `SELECT coalesce(
+		 (SELECT extract(epoch FROM now()-updated_at)::float8 FROM peerdb_stats.peer_slot_size
+		  WHERE peer_name=$1 AND slot_name=$2 AND current_lsn::pg_lsn<=$3::pg_lsn ORDER BY updated_at DESC LIMIT 1),
+		 (SELECT extract(epoch FROM now()-min(updated_at))::float8 FROM peerdb_stats.peer_slot_size
+		  WHERE peer_name=$1 AND slot_name=$2 AND current_lsn IS NOT NULL),
+		 0)`,
+		peerName, slotInfo.SlotName, slotInfo.ConfirmedFlushLSN,
+	).Scan(&lagSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("error while reading confirmed flush lag of slot %s: %w", slotInfo.SlotName, err)
+	}
+	return time.Duration(lagSeconds * float64(time.Second)), nil
+}
+
+// GetSlotLagHistory returns the lag of a slot recorded in [start, end), oldest first.
+func GetSlotLagHistory(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	peerName string,
+	slotName string,
+	start time.Time,
+	end time.Time,
+) ([]*protos.SlotLagPoint, error) {
+	rows, err := pool.Query(ctx, `SELECT updated_at,restart_lsn,confirmed_flush_lsn,current_lsn,
+		 restart_lag_bytes,confirmed_flush_lag_bytes,confirmed_flush_lag_seconds,wal_status
+		 FROM peerdb_stats.peer_slot_size WHERE peer_name=$1 AND slot_name=$2 AND updated_at>=$3 AND updated_at<$4
+		 ORDER BY updated_at`,
+		peerName, slotName, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying lag of slot %s: %w", slotName, err)
+	}
+	points, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.SlotLagPoint, error) {
+		var updatedAt time.Time
+		var restartLSN, confirmedFlushLSN, currentLSN, walStatus pgtype.Text
+		// lags are null for sizes recorded before they were tracked
+		var restartLagBytes, confirmedFlushLagBytes pgtype.Int8
+		var confirmedFlushLagSeconds pgtype.Float8
+		if err := row.Scan(&updatedAt, &restartLSN, &confirmedFlushLSN, &currentLSN,
+			&restartLagBytes, &confirmedFlushLagBytes, &confirmedFlushLagSeconds, &walStatus); err != nil {
+			return nil, err
+		}
+		return &protos.SlotLagPoint{
+			UpdatedAt:                timestamppb.New(updatedAt),
+			RestartLSN:               restartLSN.String,
+			ConfirmedFlushLSN:        confirmedFlushLSN.String,
+			CurrentLSN:               currentLSN.String,
+			RestartLagBytes:          restartLagBytes.Int64,
+			ConfirmedFlushLagBytes:   confirmedFlushLagBytes.Int64,
+			ConfirmedFlushLagSeconds: confirmedFlushLagSeconds.Float64,
+			WalStatus:                walStatus.String,
+		}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while reading lag of slot %s: %w", slotName, err)
+	}
+	return points, nil
+}
+
+// UpdateOpenTransaction records the long running transaction currently open on a peer, or clears it when openTx is nil.
+func UpdateOpenTransaction(ctx context.Context, pool *pgxpool.Pool, peerName string, openTx *protos.OpenTransaction) error {
+	if openTx == nil {
+		_, err := pool.Exec(ctx, "DELETE FROM peerdb_stats.peer_open_transactions WHERE peer_name = $1", peerName)
+		if err != nil {
+			return fmt.Errorf("error while clearing open transaction: %w", err)
+		}
+		return nil
+	}
+
+	_, err := pool.Exec(ctx,
+		"INSERT INTO peerdb_stats.peer_open_transactions"+
+			"(peer_name, pid, database_name, user_name, state, query, xact_start, checked_at) "+
+			"VALUES($1,$2,$3,$4,$5,$6,$7,$8) ON CONFLICT (peer_name) DO UPDATE SET "+
+			"pid = EXCLUDED.pid, database_name = EXCLUDED.database_name, user_name = EXCLUDED.user_name, "+
+			"state = EXCLUDED.state, query = EXCLUDED.query, xact_start = EXCLUDED.xact_start, checked_at = EXCLUDED.checked_at",
+		peerName,
+		openTx.Pid,
+		openTx.Database,
+		openTx.User,
+		openTx.State,
+		openTx.Query,
+		openTx.XactStart.AsTime(),
+		openTx.CheckedAt.AsTime(),
+	)
+	if err != nil {
+		return fmt.Errorf("error while upserting open transaction: %w", err)
+	}
+	return nil
+}
+
+// GetOpenTransaction returns the last long running transaction recorded for a peer, nil if there is none.
+func GetOpenTransaction(ctx context.Context, pool *pgxpool.Pool, peerName string) (*protos.OpenTransaction, error) {
+	var openTx protos.OpenTransaction
+	var database, user, state, query pgtype.Text
+	var xactStart, checkedAt time.Time
+	err := pool.QueryRow(ctx, "SELECT pid, database_name, user_name, state, query, xact_start, checked_at "+
+		"FROM peerdb_stats.peer_open_transactions WHERE peer_name = $1", peerName,
+	).Scan(&openTx.Pid, &database, &user, &state, &query, &xactStart, &checkedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while reading open transaction: %w", err)
+	}
+
+	openTx.Database = database.String
+	openTx.User = user.String
+	openTx.State = state.String
+	openTx.Query = query.String
+	openTx.XactStart = timestamppb.New(xactStart)
+	openTx.CheckedAt = timestamppb.New(checkedAt)
+	openTx.AgeSeconds = int64(checkedAt.Sub(xactStart) / time.Second)
+	return &openTx, nil
+}
+
 func addPartitionToQRepRun(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
 	runUUID string, partition *protos.QRepPartition,
 ) error {
@@ -259,6 +432,9 @@ func addPartitionToQRepRun(ctx context.Context, pool *pgxpool.Pool, flowJobName
 			return fmt.Errorf("unable to encode TID as string: %w", err)
 		}
 		rangeEnd = rangeEndValue.(string)
+	case *protos.PartitionRange_CompositeRange:
+		rangeStart = partition_utils.CompositeKeyString(x.CompositeRange.Start)
+		rangeEnd = partition_utils.CompositeKeyString(x.CompositeRange.End)
 	default:
 		return fmt.Errorf("unknown range type: %v", x)
 	}
@@ -313,13 +489,98 @@ func UpdateEndTimeForPartition(ctx context.Context, pool *pgxpool.Pool, runUUID
 	return nil
 }
 
-func UpdateRowsSyncedForPartition(ctx context.Context, pool *pgxpool.Pool, rowsSynced int, runUUID string,
-	partition *protos.QRepPartition,
+// UpdateThrottledTimeForPartition records how long rate limits have held back a partition so far.
+func UpdateThrottledTimeForPartition(ctx context.Context, pool *pgxpool.Pool, runUUID string,
+	partition *protos.QRepPartition, throttled time.Duration,
+) error {
+	_, err := pool.Exec(ctx, `UPDATE peerdb_stats.qrep_partitions SET throttled_ms=$1
+	 WHERE run_uuid=$2 AND partition_uuid=$3`, throttled.Milliseconds(), runUUID, partition.PartitionId)
+	if err != nil {
+		return fmt.Errorf("error while updating throttled_ms in qrep_partitions: %w", err)
+	}
+	return nil
+}
+
+// GetQRepThrottledPartitions returns how many rate limited partitions of the latest run of a flow are
+// replicating, and how long they have been held back so far.
+func GetQRepThrottledPartitions(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+) (int64, time.Duration, error) {
+	var running, throttledMs int64
+	err := pool.QueryRow(ctx, `SELECT COUNT(*), COALESCE(SUM(throttled_ms),0)::BIGINT FROM peerdb_stats.qrep_partitions
+	 WHERE run_uuid=(SELECT run_uuid FROM peerdb_stats.qrep_runs WHERE flow_name=$1 ORDER BY start_time DESC LIMIT 1)
+	 AND throttled_ms IS NOT NULL AND end_time IS NULL`, flowJobName).Scan(&running, &throttledMs)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error while querying throttled partitions: %w", err)
+	}
+	return running, time.Duration(throttledMs) * time.Millisecond, nil
+}
+
+func UpdateRowsSyncedForPartition(ctx context.Context, pool *pgxpool.Pool, rowsSynced int, bytesSynced int64,
+	runUUID string, partition *protos.QRepPartition,
 ) error {
-	_, err := pool.Exec(ctx, `UPDATE peerdb_stats.qrep_partitions SET rows_synced=$1
-	 WHERE run_uuid=$2 AND partition_uuid=$3`, rowsSynced, runUUID, partition.PartitionId)
+	_, err := pool.Exec(ctx, `UPDATE peerdb_stats.qrep_partitions SET rows_synced=$1,bytes_synced=$2
+	 WHERE run_uuid=$3 AND partition_uuid=$4`, rowsSynced, bytesSynced, runUUID, partition.PartitionId)
 	if err != nil {
 		return fmt.Errorf("error while updating rows_synced in qrep_partitions: %w", err)
 	}
 	return nil
 }
+
+// RecordQRepRunSummary sums up a finished run from its partitions, warning about partitions which had to be
+// set up again after a retry or synced a different number of rows than were pulled for them.
+func RecordQRepRunSummary(ctx context.Context, pool *pgxpool.Pool, flowJobName string, runUUID string,
+	startTime time.Time,
+) error {
+	var partitions, restarts, mismatchedPartitions int32
+	var rowsSynced, bytesSynced int64
+	err := pool.QueryRow(ctx, `SELECT count(*),coalesce(sum(restart_count),0)::int,
+	 (count(*) FILTER (WHERE coalesce(rows_in_partition,0)<>coalesce(rows_synced,0)))::int,
+	 coalesce(sum(rows_synced),0)::bigint,coalesce(sum(bytes_synced),0)::bigint
+	 FROM peerdb_stats.qrep_partitions WHERE run_uuid=$1`, runUUID,
+	).Scan(&partitions, &restarts, &mismatchedPartitions, &rowsSynced, &bytesSynced)
+	if err != nil {
+		return fmt.Errorf("error while summing up partitions of run_uuid %s: %w", runUUID, err)
+	}
+
+	warnings := make([]string, 0, 2)
+	if restarts > 0 {
+		warnings = append(warnings, fmt.Sprintf("partitions were set up again %d times after retries", restarts))
+	}
+	if mismatchedPartitions > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d partitions synced a different number of rows than were pulled",
+			mismatchedPartitions))
+	}
+
+	_, err = pool.Exec(ctx, `INSERT INTO peerdb_stats.qrep_run_summaries
+		(run_uuid,flow_name,partitions_processed,rows_synced,bytes_synced,start_time,end_time,warnings)
+		 VALUES($1,$2,$3,$4,$5,$6,$7,$8) ON CONFLICT(run_uuid) DO UPDATE SET
+		 partitions_processed=$3,rows_synced=$4,bytes_synced=$5,end_time=$7,warnings=$8`,
+		runUUID, flowJobName, partitions, rowsSynced, bytesSynced, startTime.UTC(), time.Now().UTC(), warnings)
+	if err != nil {
+		return fmt.Errorf("error while inserting summary of run_uuid %s in qrep_run_summaries: %w", runUUID, err)
+	}
+	return nil
+}
+
+// ListQRepRunSummaries returns the summaries of a mirror's most recent runs, newest first.
+func ListQRepRunSummaries(ctx context.Context, pool *pgxpool.Pool, flowJobName string, limit int32,
+) ([]*protos.QRepRunSummary, error) {
+	rows, err := pool.Query(ctx, `SELECT run_uuid,partitions_processed,rows_synced,bytes_synced,
+	 start_time,end_time,warnings FROM peerdb_stats.qrep_run_summaries
+	 WHERE flow_name=$1 ORDER BY end_time DESC LIMIT $2`, flowJobName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying qrep_run_summaries: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.QRepRunSummary, error) {
+		var summary protos.QRepRunSummary
+		var startTime, endTime time.Time
+		if err := row.Scan(&summary.RunUuid, &summary.PartitionsProcessed, &summary.RowsSynced, &summary.BytesSynced,
+			&startTime, &endTime, &summary.Warnings); err != nil {
+			return nil, err
+		}
+		summary.StartTime = timestamppb.New(startTime)
+		summary.EndTime = timestamppb.New(endTime)
+		summary.DurationSeconds = endTime.Sub(startTime).Seconds()
+		return &summary, nil
+	})
+}
@@ -0,0 +1,236 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// StatsRetention is how long per-batch stats and hourly rollups are kept, 0 keeps them forever.
+type StatsRetention struct {
+	Raw    time.Duration
+	Hourly time.Duration
+}
+
+func StatsRetentionFromEnv() StatsRetention {
+	return StatsRetention{
+		Raw:    peerdbenv.PeerDBStatsRawRetention(),
+		Hourly: peerdbenv.PeerDBStatsHourlyRetention(),
+	}
+}
+
+const (
+	// batches are only listed one by one for ranges up to this long, longer ranges are read from rollups
+	maxBatchStatsRange  = 2 * 24 * time.Hour
+	maxHourlyStatsRange = 31 * 24 * time.Hour
+)
+
+// each rollup recomputes the newest bucket of a flow it already has and adds the buckets after it,
+// so it can run as often as needed and picks up where it left off after an outage
+const (
+	rollupHourlyBatchesSQL = `INSERT INTO peerdb_stats.cdc_batch_rollups
+	(flow_name,granularity,bucket_start,num_batches,rows_synced,sync_seconds)
+	SELECT b.flow_name,'hour',date_trunc('hour',b.end_time),count(*),sum(b.rows_in_batch),
+	 coalesce(sum(extract(epoch FROM b.end_time-b.start_time)),0)
+	FROM peerdb_stats.cdc_batches b
+	LEFT JOIN (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_batch_rollups
+	 WHERE granularity='hour' GROUP BY flow_name) r ON r.flow_name=b.flow_name
+	WHERE b.end_time IS NOT NULL AND (r.last_bucket IS NULL OR b.end_time>=r.last_bucket)
+	GROUP BY b.flow_name,date_trunc('hour',b.end_time)
+	ON CONFLICT(flow_name,granularity,bucket_start) DO UPDATE SET num_batches=EXCLUDED.num_batches,
+	 rows_synced=EXCLUDED.rows_synced,sync_seconds=EXCLUDED.sync_seconds`
+	rollupHourlyTablesSQL = `INSERT INTO peerdb_stats.cdc_table_rollups
+	(flow_name,destination_table_name,granularity,bucket_start,rows_synced)
+	SELECT t.flow_name,t.destination_table_name,'hour',date_trunc('hour',b.end_time),sum(t.num_rows)
+	FROM peerdb_stats.cdc_batch_table t
+	JOIN peerdb_stats.cdc_batches b ON b.flow_name=t.flow_name AND b.batch_id=t.batch_id
+	LEFT JOIN (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_table_rollups
+	 WHERE granularity='hour' GROUP BY flow_name) r ON r.flow_name=t.flow_name
+	WHERE b.end_time IS NOT NULL AND (r.last_bucket IS NULL OR b.end_time>=r.last_bucket)
+	GROUP BY t.flow_name,t.destination_table_name,date_trunc('hour',b.end_time)
+	ON CONFLICT(flow_name,granularity,bucket_start,destination_table_name) DO UPDATE SET rows_synced=EXCLUDED.rows_synced`
+	rollupDailyBatchesSQL = `INSERT INTO peerdb_stats.cdc_batch_rollups
+	(flow_name,granularity,bucket_start,num_batches,rows_synced,sync_seconds)
+	SELECT h.flow_name,'day',date_trunc('day',h.bucket_start),sum(h.num_batches),sum(h.rows_synced),sum(h.sync_seconds)
+	FROM peerdb_stats.cdc_batch_rollups h
+	LEFT JOIN (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_batch_rollups
+	 WHERE granularity='day' GROUP BY flow_name) r ON r.flow_name=h.flow_name
+	WHERE h.granularity='hour' AND (r.last_bucket IS NULL OR h.bucket_start>=r.last_bucket)
+	GROUP BY h.flow_name,date_trunc('day',h.bucket_start)
+	ON CONFLICT(flow_name,granularity,bucket_start) DO UPDATE SET num_batches=EXCLUDED.num_batches,
+	 rows_synced=EXCLUDED.rows_synced,sync_seconds=EXCLUDED.sync_seconds`
+	rollupDailyTablesSQL = `INSERT INTO peerdb_stats.cdc_table_rollups
+	(flow_name,destination_table_name,granularity,bucket_start,rows_synced)
+	SELECT h.flow_name,h.destination_table_name,'day',date_trunc('day',h.bucket_start),sum(h.rows_synced)
+	FROM peerdb_stats.cdc_table_rollups h
+	LEFT JOIN (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_table_rollups
+	 WHERE granularity='day' GROUP BY flow_name) r ON r.flow_name=h.flow_name
+	WHERE h.granularity='hour' AND (r.last_bucket IS NULL OR h.bucket_start>=r.last_bucket)
+	GROUP BY h.flow_name,h.destination_table_name,date_trunc('day',h.bucket_start)
+	ON CONFLICT(flow_name,granularity,bucket_start,destination_table_name) DO UPDATE SET rows_synced=EXCLUDED.rows_synced`
+)
+
+// rows are only deleted once the rollups they went into can't change anymore,
+// which is the case for every bucket before the newest one of a flow
+const (
+	deleteRawBatchTablesSQL = `DELETE FROM peerdb_stats.cdc_batch_table t USING peerdb_stats.cdc_batches b,
+	 (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_table_rollups
+	  WHERE granularity='hour' GROUP BY flow_name) r
+	WHERE b.flow_name=t.flow_name AND b.batch_id=t.batch_id AND r.flow_name=t.flow_name
+	 AND b.end_time<$1 AND b.end_time<r.last_bucket`
+	deleteRawBatchesSQL = `DELETE FROM peerdb_stats.cdc_batches b USING
+	 (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_batch_rollups
+	  WHERE granularity='hour' GROUP BY flow_name) r
+	WHERE r.flow_name=b.flow_name AND b.end_time<$1 AND b.end_time<r.last_bucket
+	 AND NOT EXISTS(SELECT 1 FROM peerdb_stats.cdc_batch_table t WHERE t.flow_name=b.flow_name AND t.batch_id=b.batch_id)`
+	deleteHourlyBatchRollupsSQL = `DELETE FROM peerdb_stats.cdc_batch_rollups h USING
+	 (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_batch_rollups
+	  WHERE granularity='day' GROUP BY flow_name) r
+	WHERE r.flow_name=h.flow_name AND h.granularity='hour' AND h.bucket_start<$1 AND h.bucket_start<r.last_bucket`
+	deleteHourlyTableRollupsSQL = `DELETE FROM peerdb_stats.cdc_table_rollups h USING
+	 (SELECT flow_name,max(bucket_start) AS last_bucket FROM peerdb_stats.cdc_table_rollups
+	  WHERE granularity='day' GROUP BY flow_name) r
+	WHERE r.flow_name=h.flow_name AND h.granularity='hour' AND h.bucket_start<$1 AND h.bucket_start<r.last_bucket`
+)
+
+// RollupCDCStats aggregates CDC batch stats into hourly and daily rollups,
+// then deletes per-batch stats and hourly rollups past their retention.
+func RollupCDCStats(ctx context.Context, pool *pgxpool.Pool, now time.Time, retention StatsRetention) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error while starting transaction for stats rollups: %w", err)
+	}
+	defer func() {
+		err := tx.Rollback(ctx)
+		if err != pgx.ErrTxClosed && err != nil {
+			logger.LoggerFromCtx(ctx).Error("error during transaction rollback", slog.Any("error", err))
+		}
+	}()
+
+	for _, query := range []string{rollupHourlyBatchesSQL, rollupHourlyTablesSQL, rollupDailyBatchesSQL, rollupDailyTablesSQL} {
+		if _, err := tx.Exec(ctx, query); err != nil {
+			return fmt.Errorf("error while rolling up cdc stats: %w", err)
+		}
+	}
+
+	if retention.Raw > 0 {
+		cutoff := now.Add(-retention.Raw)
+		for _, query := range []string{deleteRawBatchTablesSQL, deleteRawBatchesSQL} {
+			if _, err := tx.Exec(ctx, query, cutoff); err != nil {
+				return fmt.Errorf("error while deleting cdc batch stats past retention: %w", err)
+			}
+		}
+	}
+	if retention.Hourly > 0 {
+		cutoff := now.Add(-retention.Hourly)
+		for _, query := range []string{deleteHourlyBatchRollupsSQL, deleteHourlyTableRollupsSQL} {
+			if _, err := tx.Exec(ctx, query, cutoff); err != nil {
+				return fmt.Errorf("error while deleting hourly cdc stats past retention: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error while committing stats rollups: %w", err)
+	}
+	return nil
+}
+
+// ChooseStatsGranularity picks the finest granularity kept for all of [start, end) which doesn't return too many buckets.
+func ChooseStatsGranularity(start time.Time, end time.Time, now time.Time, retention StatsRetention) protos.StatsGranularity {
+	keptSince := func(retention time.Duration) bool {
+		return retention == 0 || !start.Before(now.Add(-retention))
+	}
+	statsRange := end.Sub(start)
+	if statsRange <= maxBatchStatsRange && keptSince(retention.Raw) {
+		return protos.StatsGranularity_STATS_GRANULARITY_BATCH
+	}
+	if statsRange <= maxHourlyStatsRange && keptSince(retention.Hourly) {
+		return protos.StatsGranularity_STATS_GRANULARITY_HOUR
+	}
+	return protos.StatsGranularity_STATS_GRANULARITY_DAY
+}
+
+// GetCDCBatchStats returns the stats of batches which ended in [start, end), per batch or from the rollups of granularity.
+func GetCDCBatchStats(
+	ctx context.Context,
+	pool *pgxpool.Pool,
+	flowJobName string,
+	start time.Time,
+	end time.Time,
+	granularity protos.StatsGranularity,
+) ([]*protos.CDCStatsBucket, []*protos.CDCTableStats, error) {
+	var bucketRows, tableRows pgx.Rows
+	var err error
+	if granularity == protos.StatsGranularity_STATS_GRANULARITY_BATCH {
+		bucketRows, err = pool.Query(ctx, `SELECT end_time,1,rows_in_batch,extract(epoch FROM end_time-start_time)::float8
+		 FROM peerdb_stats.cdc_batches WHERE flow_name=$1 AND end_time>=$2 AND end_time<$3 ORDER BY end_time`,
+			flowJobName, start, end)
+	} else {
+		bucketRows, err = pool.Query(ctx, `SELECT bucket_start,num_batches,rows_synced,sync_seconds
+		 FROM peerdb_stats.cdc_batch_rollups WHERE flow_name=$1 AND granularity=$2
+		 AND bucket_start>=date_trunc($2,$3::timestamp) AND bucket_start<$4 ORDER BY bucket_start`,
+			flowJobName, rollupGranularity(granularity), start, end)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while querying cdc batch stats: %w", err)
+	}
+	buckets, err := pgx.CollectRows(bucketRows, func(row pgx.CollectableRow) (*protos.CDCStatsBucket, error) {
+		var bucket protos.CDCStatsBucket
+		var bucketStart time.Time
+		if err := row.Scan(&bucketStart, &bucket.NumBatches, &bucket.RowsSynced, &bucket.SyncSeconds); err != nil {
+			return nil, err
+		}
+		bucket.BucketStart = timestamppb.New(bucketStart)
+		return &bucket, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while reading cdc batch stats: %w", err)
+	}
+
+	if granularity == protos.StatsGranularity_STATS_GRANULARITY_BATCH {
+		tableRows, err = pool.Query(ctx, `SELECT t.destination_table_name,sum(t.num_rows)::bigint
+		 FROM peerdb_stats.cdc_batch_table t
+		 JOIN peerdb_stats.cdc_batches b ON b.flow_name=t.flow_name AND b.batch_id=t.batch_id
+		 WHERE t.flow_name=$1 AND b.end_time>=$2 AND b.end_time<$3
+		 GROUP BY t.destination_table_name ORDER BY t.destination_table_name`,
+			flowJobName, start, end)
+	} else {
+		tableRows, err = pool.Query(ctx, `SELECT destination_table_name,sum(rows_synced)::bigint
+		 FROM peerdb_stats.cdc_table_rollups WHERE flow_name=$1 AND granularity=$2
+		 AND bucket_start>=date_trunc($2,$3::timestamp) AND bucket_start<$4
+		 GROUP BY destination_table_name ORDER BY destination_table_name`,
+			flowJobName, rollupGranularity(granularity), start, end)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while querying cdc table stats: %w", err)
+	}
+	tables, err := pgx.CollectRows(tableRows, func(row pgx.CollectableRow) (*protos.CDCTableStats, error) {
+		var table protos.CDCTableStats
+		if err := row.Scan(&table.DestinationTableName, &table.RowsSynced); err != nil {
+			return nil, err
+		}
+		return &table, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while reading cdc table stats: %w", err)
+	}
+
+	return buckets, tables, nil
+}
+
+func rollupGranularity(granularity protos.StatsGranularity) string {
+	if granularity == protos.StatsGranularity_STATS_GRANULARITY_HOUR {
+		return "hour"
+	}
+	return "day"
+}
@@ -0,0 +1,29 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestChooseStatsGranularity(t *testing.T) {
+	now := time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	retention := StatsRetention{Raw: 7 * day, Hourly: 90 * day}
+
+	assert.Equal(t, protos.StatsGranularity_STATS_GRANULARITY_BATCH,
+		ChooseStatsGranularity(now.Add(-time.Hour), now, now, retention))
+	assert.Equal(t, protos.StatsGranularity_STATS_GRANULARITY_HOUR,
+		ChooseStatsGranularity(now.Add(-7*day), now, now, retention), "a week is too many batches")
+	assert.Equal(t, protos.StatsGranularity_STATS_GRANULARITY_HOUR,
+		ChooseStatsGranularity(now.Add(-10*day), now.Add(-9*day), now, retention), "batches past retention were deleted")
+	assert.Equal(t, protos.StatsGranularity_STATS_GRANULARITY_DAY,
+		ChooseStatsGranularity(now.Add(-60*day), now, now, retention))
+	assert.Equal(t, protos.StatsGranularity_STATS_GRANULARITY_DAY,
+		ChooseStatsGranularity(now.Add(-100*day), now.Add(-99*day), now, retention), "hourly rollups past retention were deleted")
+	assert.Equal(t, protos.StatsGranularity_STATS_GRANULARITY_BATCH,
+		ChooseStatsGranularity(now.Add(-100*day), now.Add(-99*day), now, StatsRetention{}), "stats are kept forever")
+}
@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/decimal128"
+	"github.com/google/uuid"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+var (
+	timestampType   = &arrow.TimestampType{Unit: arrow.Microsecond}
+	timestampTZType = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+)
+
+// arrowTypeForKind maps a qvalue kind to the Arrow type its column is written as, which the Parquet writer
+// turns into the matching logical type: STRING, INT(bits), DECIMAL, DATE, TIME, TIMESTAMP or LIST.
+// UUIDs, JSON and geospatial values are written as strings, like in Avro files.
+func arrowTypeForKind(kind qvalue.QValueKind, precision int16, scale int16) (arrow.DataType, error) {
+	switch kind {
+	case qvalue.QValueKindString, qvalue.QValueKindQChar, qvalue.QValueKindUUID, qvalue.QValueKindJSON,
+		qvalue.QValueKindHStore, qvalue.QValueKindStruct, qvalue.QValueKindInvalid,
+		qvalue.QValueKindGeometry, qvalue.QValueKindGeography, qvalue.QValueKindPoint,
+		qvalue.QValueKindCIDR, qvalue.QValueKindINET, qvalue.QValueKindMacaddr,
+		qvalue.QValueKindInt4Range, qvalue.QValueKindInt8Range, qvalue.QValueKindNumRange,
+		qvalue.QValueKindTsRange, qvalue.QValueKindTsTzRange, qvalue.QValueKindDateRange:
+		return arrow.BinaryTypes.String, nil
+	case qvalue.QValueKindInt16:
+		return arrow.PrimitiveTypes.Int16, nil
+	case qvalue.QValueKindInt32:
+		return arrow.PrimitiveTypes.Int32, nil
+	case qvalue.QValueKindInt64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case qvalue.QValueKindFloat32:
+		return arrow.PrimitiveTypes.Float32, nil
+	case qvalue.QValueKindFloat64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case qvalue.QValueKindBoolean:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case qvalue.QValueKindBytes, qvalue.QValueKindBit:
+		return arrow.BinaryTypes.Binary, nil
+	case qvalue.QValueKindNumeric:
+		precision, scale := qvalue.DetermineNumericSettingForDWH(precision, scale, qvalue.QDWHTypeS3)
+		return &arrow.Decimal128Type{Precision: int32(precision), Scale: int32(scale)}, nil
+	case qvalue.QValueKindDate:
+		return arrow.FixedWidthTypes.Date32, nil
+	case qvalue.QValueKindTime, qvalue.QValueKindTimeTZ:
+		return arrow.FixedWidthTypes.Time64us, nil
+	case qvalue.QValueKindTimestamp:
+		return timestampType, nil
+	case qvalue.QValueKindTimestampTZ:
+		return timestampTZType, nil
+	case qvalue.QValueKindArrayFloat32:
+		return arrow.ListOf(arrow.PrimitiveTypes.Float32), nil
+	case qvalue.QValueKindArrayFloat64:
+		return arrow.ListOf(arrow.PrimitiveTypes.Float64), nil
+	case qvalue.QValueKindArrayInt16:
+		return arrow.ListOf(arrow.PrimitiveTypes.Int16), nil
+	case qvalue.QValueKindArrayInt32:
+		return arrow.ListOf(arrow.PrimitiveTypes.Int32), nil
+	case qvalue.QValueKindArrayInt64:
+		return arrow.ListOf(arrow.PrimitiveTypes.Int64), nil
+	case qvalue.QValueKindArrayString:
+		return arrow.ListOf(arrow.BinaryTypes.String), nil
+	case qvalue.QValueKindArrayBoolean:
+		return arrow.ListOf(arrow.FixedWidthTypes.Boolean), nil
+	case qvalue.QValueKindArrayDate:
+		return arrow.ListOf(arrow.FixedWidthTypes.Date32), nil
+	case qvalue.QValueKindArrayTimestamp:
+		return arrow.ListOf(timestampType), nil
+	case qvalue.QValueKindArrayTimestampTZ:
+		return arrow.ListOf(timestampTZType), nil
+	default:
+		return nil, fmt.Errorf("unsupported QValueKind for parquet: %s", kind)
+	}
+}
+
+func GetArrowSchema(schema *model.QRecordSchema) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		dataType, err := arrowTypeForKind(field.Type, field.Precision, field.Scale)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map column %s: %w", field.Name, err)
+		}
+		fields = append(fields, arrow.Field{Name: field.Name, Type: dataType, Nullable: field.Nullable})
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// appendValue appends a value to the builder of its column, numerics which don't fit the column's decimal
+// type are written as null.
+func appendValue(builder array.Builder, value any) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	switch b := builder.(type) {
+	case *array.StringBuilder:
+		switch v := value.(type) {
+		case string:
+			b.Append(v)
+		case uint8:
+			b.Append(string(rune(v)))
+		case [16]byte:
+			b.Append(uuid.UUID(v).String())
+		case uuid.UUID:
+			b.Append(v.String())
+		case []byte:
+			b.Append(string(v))
+		default:
+			b.Append(fmt.Sprint(v))
+		}
+	case *array.BinaryBuilder:
+		switch v := value.(type) {
+		case []byte:
+			b.Append(v)
+		case string:
+			b.AppendString(v)
+		default:
+			return fmt.Errorf("invalid bytes value %T", value)
+		}
+	case *array.Int16Builder:
+		v, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(int16(v))
+	case *array.Int32Builder:
+		v, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(int32(v))
+	case *array.Int64Builder:
+		v, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float32Builder:
+		switch v := value.(type) {
+		case float32:
+			b.Append(v)
+		case float64:
+			b.Append(float32(v))
+		default:
+			return fmt.Errorf("invalid float32 value %T", value)
+		}
+	case *array.Float64Builder:
+		switch v := value.(type) {
+		case float64:
+			b.Append(v)
+		case float32:
+			b.Append(float64(v))
+		default:
+			return fmt.Errorf("invalid float64 value %T", value)
+		}
+	case *array.BooleanBuilder:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("invalid bool value %T", value)
+		}
+		b.Append(v)
+	case *array.Decimal128Builder:
+		v, ok := value.(*big.Rat)
+		if !ok {
+			return fmt.Errorf("invalid numeric value %T", value)
+		}
+		decimalType := b.Type().(*arrow.Decimal128Type)
+		num, ok := toDecimal128(v, decimalType)
+		if !ok {
+			b.AppendNull()
+		} else {
+			b.Append(num)
+		}
+	case *array.Date32Builder:
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("invalid date value %T", value)
+		}
+		b.Append(arrow.Date32FromTime(t))
+	case *array.Time64Builder:
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("invalid time value %T", value)
+		}
+		t = t.UTC()
+		sinceMidnight := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+		b.Append(arrow.Time64(sinceMidnight.Microseconds()))
+	case *array.TimestampBuilder:
+		t, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("invalid timestamp value %T", value)
+		}
+		b.Append(arrow.Timestamp(t.UnixMicro()))
+	case *array.ListBuilder:
+		return appendList(b, value)
+	default:
+		return fmt.Errorf("unsupported parquet column builder %T", builder)
+	}
+	return nil
+}
+
+func appendList(b *array.ListBuilder, value any) error {
+	var values []any
+	switch v := value.(type) {
+	case []float32:
+		values = toAnySlice(v)
+	case []float64:
+		values = toAnySlice(v)
+	case []int16:
+		values = toAnySlice(v)
+	case []int32:
+		values = toAnySlice(v)
+	case []int64:
+		values = toAnySlice(v)
+	case []string:
+		values = toAnySlice(v)
+	case []bool:
+		values = toAnySlice(v)
+	case []time.Time:
+		values = toAnySlice(v)
+	case []any:
+		values = v
+	default:
+		return fmt.Errorf("invalid array value %T", value)
+	}
+
+	b.Append(true)
+	valueBuilder := b.ValueBuilder()
+	for _, v := range values {
+		if err := appendValue(valueBuilder, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toAnySlice[T any](values []T) []any {
+	anyValues := make([]any, 0, len(values))
+	for _, v := range values {
+		anyValues = append(anyValues, v)
+	}
+	return anyValues
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("invalid integer value %T", value)
+	}
+}
+
+// toDecimal128 rounds a numeric to the scale of the column, false if it then has more digits than fit.
+func toDecimal128(rat *big.Rat, decimalType *arrow.Decimal128Type) (decimal128.Num, bool) {
+	scaled := new(big.Rat).Mul(rat, new(big.Rat).SetInt(pow10(decimalType.Scale)))
+	unscaled, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	// round half away from zero
+	if new(big.Int).Abs(new(big.Int).Lsh(remainder, 1)).Cmp(scaled.Denom()) >= 0 {
+		if scaled.Sign() < 0 {
+			unscaled.Sub(unscaled, big.NewInt(1))
+		} else {
+			unscaled.Add(unscaled, big.NewInt(1))
+		}
+	}
+	if new(big.Int).Abs(unscaled).Cmp(pow10(decimalType.Precision)) >= 0 {
+		return decimal128.Num{}, false
+	}
+	return decimal128.FromBigInt(unscaled), true
+}
+
+func pow10(exp int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestGetArrowSchema(t *testing.T) {
+	schema, err := GetArrowSchema(&model.QRecordSchema{Fields: []model.QField{
+		{Name: "id", Type: qvalue.QValueKindInt64},
+		{Name: "amount", Type: qvalue.QValueKindNumeric, Precision: 10, Scale: 2, Nullable: true},
+		{Name: "created_at", Type: qvalue.QValueKindTimestampTZ},
+		{Name: "tags", Type: qvalue.QValueKindArrayString, Nullable: true},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, arrow.PrimitiveTypes.Int64, schema.Field(0).Type)
+	assert.Equal(t, &arrow.Decimal128Type{Precision: 10, Scale: 2}, schema.Field(1).Type)
+	assert.True(t, schema.Field(1).Nullable)
+	assert.Equal(t, "UTC", schema.Field(2).Type.(*arrow.TimestampType).TimeZone)
+	assert.Equal(t, arrow.LIST, schema.Field(3).Type.ID())
+}
+
+func TestToDecimal128(t *testing.T) {
+	decimalType := &arrow.Decimal128Type{Precision: 5, Scale: 2}
+
+	num, ok := toDecimal128(big.NewRat(-12345, 1000), decimalType)
+	require.True(t, ok)
+	assert.Equal(t, "-1235", num.BigInt().String())
+
+	_, ok = toDecimal128(big.NewRat(1000, 1), decimalType)
+	assert.False(t, ok, "1000.00 doesn't fit in numeric(5,2)")
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/compress"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/djherbis/buffer"
+	"github.com/djherbis/nio/v3"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+// rows buffered in memory before they are flushed to the file as a row group
+const parquetRowGroupSize = 64 * 1024
+
+type ParquetFile struct {
+	NumRecords int
+	FilePath   string
+}
+
+type peerDBParquetWriter struct {
+	stream      *model.QRecordStream
+	compression protos.ParquetCompression
+}
+
+func NewPeerDBParquetWriter(stream *model.QRecordStream, compression protos.ParquetCompression) *peerDBParquetWriter {
+	return &peerDBParquetWriter{
+		stream:      stream,
+		compression: compression,
+	}
+}
+
+func (p *peerDBParquetWriter) compressionCodec() compress.Compression {
+	switch p.compression {
+	case protos.ParquetCompression_PARQUET_COMPRESSION_ZSTD:
+		return compress.Codecs.Zstd
+	case protos.ParquetCompression_PARQUET_COMPRESSION_NONE:
+		return compress.Codecs.Uncompressed
+	default:
+		return compress.Codecs.Snappy
+	}
+}
+
+func (p *peerDBParquetWriter) WriteParquet(ctx context.Context, w io.Writer) (int, error) {
+	logger := logger.LoggerFromCtx(ctx)
+	schema, err := p.stream.Schema()
+	if err != nil {
+		logger.Error("failed to get schema from stream", slog.Any("error", err))
+		return 0, fmt.Errorf("failed to get schema from stream: %w", err)
+	}
+
+	arrowSchema, err := GetArrowSchema(schema)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get parquet schema: %w", err)
+	}
+
+	fileWriter, err := pqarrow.NewFileWriter(arrowSchema, w,
+		parquet.NewWriterProperties(parquet.WithCompression(p.compressionCodec())),
+		pqarrow.DefaultWriterProps())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+
+	recordBuilder := array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+	defer recordBuilder.Release()
+
+	numRows := atomic.Uint32{}
+	shutdown := utils.HeartbeatRoutine(ctx, func() string {
+		written := numRows.Load()
+		return fmt.Sprintf("[parquet] written %d rows", written)
+	})
+	defer shutdown()
+
+	flushRowGroup := func() error {
+		record := recordBuilder.NewRecord()
+		defer record.Release()
+		if err := fileWriter.Write(record); err != nil {
+			return fmt.Errorf("failed to write row group to parquet: %w", err)
+		}
+		return nil
+	}
+
+	pendingRows := 0
+	for qRecordOrErr := range p.stream.Records {
+		if qRecordOrErr.Err != nil {
+			logger.Error("[parquet] failed to get record from stream", slog.Any("error", qRecordOrErr.Err))
+			return 0, fmt.Errorf("[parquet] failed to get record from stream: %w", qRecordOrErr.Err)
+		}
+
+		for i, qv := range qRecordOrErr.Record {
+			if err := appendValue(recordBuilder.Field(i), qv.Value()); err != nil {
+				return 0, fmt.Errorf("failed to convert column %s to parquet: %w", schema.Fields[i].Name, err)
+			}
+		}
+
+		pendingRows += 1
+		numRows.Add(1)
+		if pendingRows >= parquetRowGroupSize {
+			if err := flushRowGroup(); err != nil {
+				return 0, err
+			}
+			pendingRows = 0
+		}
+	}
+
+	if pendingRows > 0 {
+		if err := flushRowGroup(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := fileWriter.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+
+	return int(numRows.Load()), nil
+}
+
+func (p *peerDBParquetWriter) WriteRecordsToS3(
+	ctx context.Context, bucketName, key string, s3Creds utils.S3PeerCredentials,
+) (*ParquetFile, error) {
+	logger := logger.LoggerFromCtx(ctx)
+	s3svc, err := utils.CreateS3Client(s3Creds)
+	if err != nil {
+		logger.Error("failed to create S3 client: ", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	buf := buffer.New(32 * 1024 * 1024) // 32MB in memory Buffer
+	r, w := nio.Pipe(buf)
+
+	defer r.Close()
+	var writeParquetError error
+	var numRows int
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				writeParquetError = fmt.Errorf("panic occurred during WriteParquet: %v", r)
+				stack := string(debug.Stack())
+				logger.Error("panic during WriteParquet", slog.Any("error", writeParquetError), slog.String("stack", stack))
+			}
+			w.Close()
+		}()
+		numRows, writeParquetError = p.WriteParquet(ctx, w)
+	}()
+
+	_, err = manager.NewUploader(s3svc).Upload(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	if err != nil {
+		s3Path := "s3://" + bucketName + "/" + key
+		logger.Error("failed to upload file: ", slog.Any("error", err), slog.Any("s3_path", s3Path))
+		return nil, fmt.Errorf("failed to upload file to path %s: %w", s3Path, err)
+	}
+
+	if writeParquetError != nil {
+		logger.Error("failed to write records to parquet: ", slog.Any("error", writeParquetError))
+		return nil, writeParquetError
+	}
+
+	return &ParquetFile{
+		NumRecords: numRows,
+		FilePath:   key,
+	}, nil
+}
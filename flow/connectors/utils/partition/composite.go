@@ -0,0 +1,132 @@
+package partition_utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// CompositeKeyValue converts a value of a composite watermark column as read from Postgres.
+func CompositeKeyValue(value interface{}) (*protos.PartitionKeyValue, error) {
+	switch v := value.(type) {
+	case int16:
+		return &protos.PartitionKeyValue{Value: &protos.PartitionKeyValue_IntValue{IntValue: int64(v)}}, nil
+	case int32:
+		return &protos.PartitionKeyValue{Value: &protos.PartitionKeyValue_IntValue{IntValue: int64(v)}}, nil
+	case int64:
+		return &protos.PartitionKeyValue{Value: &protos.PartitionKeyValue_IntValue{IntValue: v}}, nil
+	case time.Time:
+		return &protos.PartitionKeyValue{
+			Value: &protos.PartitionKeyValue_TimestampValue{TimestampValue: timestamppb.New(v)},
+		}, nil
+	case string:
+		return &protos.PartitionKeyValue{Value: &protos.PartitionKeyValue_StringValue{StringValue: v}}, nil
+	case [16]byte:
+		return &protos.PartitionKeyValue{
+			Value: &protos.PartitionKeyValue_StringValue{StringValue: uuid.UUID(v).String()},
+		}, nil
+	case nil:
+		return nil, errors.New("composite watermark columns can't be null")
+	default:
+		return nil, fmt.Errorf("unsupported type for composite watermark column: %T", v)
+	}
+}
+
+// CompositeKeyArgs returns the values of a composite watermark as query arguments.
+func CompositeKeyArgs(key []*protos.PartitionKeyValue) []interface{} {
+	args := make([]interface{}, 0, len(key))
+	for _, value := range key {
+		switch v := value.Value.(type) {
+		case *protos.PartitionKeyValue_IntValue:
+			args = append(args, v.IntValue)
+		case *protos.PartitionKeyValue_TimestampValue:
+			args = append(args, v.TimestampValue.AsTime())
+		case *protos.PartitionKeyValue_StringValue:
+			args = append(args, v.StringValue)
+		default:
+			args = append(args, nil)
+		}
+	}
+	return args
+}
+
+// CompositeKeyString formats a composite watermark as a row, e.g. (2024-01-01 00:00:00 +0000 UTC,42).
+func CompositeKeyString(key []*protos.PartitionKeyValue) string {
+	values := make([]string, 0, len(key))
+	for _, value := range key {
+		switch v := value.Value.(type) {
+		case *protos.PartitionKeyValue_IntValue:
+			values = append(values, strconv.FormatInt(v.IntValue, 10))
+		case *protos.PartitionKeyValue_TimestampValue:
+			values = append(values, v.TimestampValue.AsTime().String())
+		case *protos.PartitionKeyValue_StringValue:
+			values = append(values, v.StringValue)
+		default:
+			values = append(values, "")
+		}
+	}
+	return "(" + strings.Join(values, ",") + ")"
+}
+
+func compositeKeysEqual(a []*protos.PartitionKeyValue, b []*protos.PartitionKeyValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddCompositePartition adds a partition of the rows whose composite watermark is between start and end,
+// which are in the order of the watermark columns. Partitions must be added in order, a partition starting
+// where the previous one ends is merged into it, so that rows sharing that watermark are in one partition.
+func (p *PartitionHelper) AddCompositePartition(start []interface{}, end []interface{}) error {
+	startKey := make([]*protos.PartitionKeyValue, 0, len(start))
+	for _, value := range start {
+		keyValue, err := CompositeKeyValue(value)
+		if err != nil {
+			return err
+		}
+		startKey = append(startKey, keyValue)
+	}
+	endKey := make([]*protos.PartitionKeyValue, 0, len(end))
+	for _, value := range end {
+		keyValue, err := CompositeKeyValue(value)
+		if err != nil {
+			return err
+		}
+		endKey = append(endKey, keyValue)
+	}
+
+	if len(p.partitions) > 0 {
+		prevRange := p.partitions[len(p.partitions)-1].Range.GetCompositeRange()
+		if prevRange != nil && compositeKeysEqual(prevRange.End, startKey) {
+			prevRange.End = endKey
+			return nil
+		}
+	}
+
+	p.partitions = append(p.partitions, &protos.QRepPartition{
+		PartitionId: uuid.New().String(),
+		Range: &protos.PartitionRange{
+			Range: &protos.PartitionRange_CompositeRange{
+				CompositeRange: &protos.CompositePartitionRange{
+					Start: startKey,
+					End:   endKey,
+				},
+			},
+		},
+	})
+	return nil
+}
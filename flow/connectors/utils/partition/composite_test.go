@@ -0,0 +1,45 @@
+package partition_utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestAddCompositePartition(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	helper := NewPartitionHelper()
+
+	require.NoError(t, helper.AddCompositePartition([]interface{}{day, int32(1)}, []interface{}{day, int64(10)}))
+	// starts where the previous partition ends, so rows sharing that watermark stay in one partition
+	require.NoError(t, helper.AddCompositePartition([]interface{}{day, int64(10)}, []interface{}{day, int64(20)}))
+	require.NoError(t, helper.AddCompositePartition([]interface{}{day, int64(21)}, []interface{}{day.Add(time.Hour), "a"}))
+	// a bucket of a single row
+	require.NoError(t, helper.AddCompositePartition([]interface{}{day.Add(2 * time.Hour), "b"},
+		[]interface{}{day.Add(2 * time.Hour), "b"}))
+
+	partitions := helper.GetPartitions()
+	require.Len(t, partitions, 3)
+
+	first := partitions[0].Range.GetCompositeRange()
+	require.Equal(t, "(2024-01-01 00:00:00 +0000 UTC,1)", CompositeKeyString(first.Start))
+	require.Equal(t, "(2024-01-01 00:00:00 +0000 UTC,20)", CompositeKeyString(first.End))
+	second := partitions[1].Range.GetCompositeRange()
+	require.Equal(t, "(2024-01-01 00:00:00 +0000 UTC,21)", CompositeKeyString(second.Start))
+	require.Equal(t, "(2024-01-01 01:00:00 +0000 UTC,a)", CompositeKeyString(second.End))
+	third := partitions[2].Range.GetCompositeRange()
+	require.Equal(t, CompositeKeyString(third.Start), CompositeKeyString(third.End))
+
+	require.Equal(t, []interface{}{day, int64(1)}, CompositeKeyArgs(first.Start))
+	require.IsType(t, &protos.PartitionKeyValue_StringValue{}, second.End[1].Value)
+}
+
+func TestAddCompositePartitionUnsupported(t *testing.T) {
+	helper := NewPartitionHelper()
+	require.Error(t, helper.AddCompositePartition([]interface{}{nil}, []interface{}{int64(1)}))
+	require.Error(t, helper.AddCompositePartition([]interface{}{3.5}, []interface{}{int64(1)}))
+	require.Empty(t, helper.GetPartitions())
+}
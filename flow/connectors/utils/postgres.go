@@ -2,9 +2,11 @@ package utils
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/url"
+	"strconv"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -33,31 +35,145 @@ func GetPGConnectionString(pgConfig *protos.PostgresConfig) string {
 	return connString
 }
 
-func GetCustomDataTypes(ctx context.Context, conn *pgx.Conn) (map[uint32]string, error) {
+// CustomDataType is a type defined outside of the system catalogs. Type is its typtype,
+// which tells base types ('b') from composites ('c'), domains ('d') and enums ('e').
+// Arrays of composites are base types with the composite as ElemOID.
+type CustomDataType struct {
+	Name    string
+	Type    byte
+	BaseOID uint32
+	ElemOID uint32
+}
+
+// SetPostgresIAMAuthToken makes a fresh RDS IAM token the password of connConfig when the peer authenticates
+// with IAM. Tokens are only checked when connecting, so this is needed before each new connection.
+func SetPostgresIAMAuthToken(ctx context.Context, pgConfig *protos.PostgresConfig, connConfig *pgx.ConnConfig) error {
+	if pgConfig.AwsRdsIamAuth == nil {
+		return nil
+	}
+	token, err := RDSIAMAuthToken(ctx, pgConfig.Host, pgConfig.Port, pgConfig.User, pgConfig.AwsRdsIamAuth.Region)
+	if err != nil {
+		return err
+	}
+	connConfig.Password = token
+	return nil
+}
+
+// GetPGConnConfig parses the peer's connection string, authenticating with an RDS IAM token if the peer uses IAM.
+func GetPGConnConfig(ctx context.Context, pgConfig *protos.PostgresConfig) (*pgx.ConnConfig, error) {
+	connConfig, err := pgx.ParseConfig(GetPGConnectionString(pgConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	if err := SetPostgresIAMAuthToken(ctx, pgConfig, connConfig); err != nil {
+		return nil, fmt.Errorf("failed to get IAM auth token: %w", err)
+	}
+	if pgConfig.TlsConfig != nil {
+		tlsConfig, err := CreateTLSConfig(pgConfig.TlsConfig, pgConfig.Host, tls.VersionTLS12)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS config: %w", err)
+		}
+		// unlike the default of trying TLS first, a peer with TLS settings doesn't fall back to plaintext
+		connConfig.TLSConfig = tlsConfig
+		connConfig.Fallbacks = nil
+	}
+	return connConfig, nil
+}
+
+// Querier is what runs queries on a Postgres peer, a connection, a pool or a transaction.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func GetCustomDataTypes(ctx context.Context, conn Querier) (map[uint32]CustomDataType, error) {
 	rows, err := conn.Query(ctx, `
-		SELECT t.oid, t.typname as type
+		SELECT t.oid, t.typname as type, t.typtype::text, t.typbasetype, coalesce(el.oid, 0)
 		FROM pg_type t
 		LEFT JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_catalog.pg_type el ON el.oid = t.typelem AND el.typarray = t.oid
 		WHERE (t.typrelid = 0 OR (SELECT c.relkind = 'c' FROM pg_catalog.pg_class c WHERE c.oid = t.typrelid))
-		AND NOT EXISTS(SELECT 1 FROM pg_catalog.pg_type el WHERE el.oid = t.typelem AND el.typarray = t.oid)
+		AND (el.oid IS NULL OR el.typtype = 'c')
 		AND n.nspname NOT IN ('pg_catalog', 'information_schema');
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get custom types: %w", err)
 	}
 
-	customTypeMap := map[uint32]string{}
+	customTypeMap := map[uint32]CustomDataType{}
 	for rows.Next() {
 		var typeID pgtype.Uint32
 		var typeName pgtype.Text
-		if err := rows.Scan(&typeID, &typeName); err != nil {
+		var typeType pgtype.Text
+		var baseTypeID pgtype.Uint32
+		var elemTypeID pgtype.Uint32
+		if err := rows.Scan(&typeID, &typeName, &typeType, &baseTypeID, &elemTypeID); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-		customTypeMap[typeID.Uint32] = typeName.String
+		customType := CustomDataType{Name: typeName.String, Type: 'b', BaseOID: baseTypeID.Uint32, ElemOID: elemTypeID.Uint32}
+		if typeType.String != "" {
+			customType.Type = typeType.String[0]
+		}
+		customTypeMap[typeID.Uint32] = customType
 	}
 	return customTypeMap, nil
 }
 
+// RegisterCustomTypes registers enums, domains and composites with typeMap, the type map of conn or one built alongside it,
+// so their values decode to strings, to their base type and to maps of field values respectively.
+// Composites with fields of types pgx can't decode are left unregistered and arrive as text, as do arrays of them.
+func RegisterCustomTypes(
+	ctx context.Context,
+	conn *pgx.Conn,
+	typeMap *pgtype.Map,
+	customTypes map[uint32]CustomDataType,
+) error {
+	for typeOID, customType := range customTypes {
+		if customType.Type == 'e' {
+			typeMap.RegisterType(&pgtype.Type{Name: customType.Name, OID: typeOID, Codec: &pgtype.EnumCodec{}})
+		}
+	}
+
+	// domains and composites may be built on each other, register until no more can be
+	for registered := true; registered; {
+		registered = false
+		for typeOID, customType := range customTypes {
+			if _, ok := typeMap.TypeForOID(typeOID); ok {
+				continue
+			}
+			switch customType.Type {
+			case 'd':
+				if baseType, ok := typeMap.TypeForOID(customType.BaseOID); ok {
+					typeMap.RegisterType(&pgtype.Type{Name: customType.Name, OID: typeOID, Codec: baseType.Codec})
+					registered = true
+				}
+			case 'c':
+				// regtype takes an oid, which saves looking up the schema to qualify the name with
+				compositeType, err := conn.LoadType(ctx, strconv.FormatUint(uint64(typeOID), 10))
+				if err != nil {
+					if ctx.Err() != nil {
+						return fmt.Errorf("failed to load composite type %s: %w", customType.Name, err)
+					}
+					continue
+				}
+				compositeType.Name = customType.Name
+				typeMap.RegisterType(compositeType)
+				registered = true
+			case 'b':
+				if customType.ElemOID == 0 {
+					continue
+				}
+				if elemType, ok := typeMap.TypeForOID(customType.ElemOID); ok {
+					typeMap.RegisterType(&pgtype.Type{Name: customType.Name, OID: typeOID, Codec: &pgtype.ArrayCodec{ElementType: elemType}})
+					registered = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func RegisterHStore(ctx context.Context, conn *pgx.Conn) error {
 	var hstoreOID uint32
 	err := conn.QueryRow(context.Background(), `select oid from pg_type where typname = 'hstore'`).Scan(&hstoreOID)
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// PrimaryKeyFilter builds a WHERE condition matching any of numKeys primary keys, where placeholder(i)
+// returns the bind parameter for the i-th argument. Arguments are consumed key by key, in pkeyColumns order.
+func PrimaryKeyFilter(quotedPkeyColumns []string, numKeys int, placeholder func(int) string) string {
+	keyConditions := make([]string, 0, numKeys)
+	argIdx := 0
+	for range numKeys {
+		colConditions := make([]string, 0, len(quotedPkeyColumns))
+		for _, col := range quotedPkeyColumns {
+			colConditions = append(colConditions, fmt.Sprintf("%s=%s", col, placeholder(argIdx)))
+			argIdx += 1
+		}
+		keyConditions = append(keyConditions, "("+strings.Join(colConditions, " AND ")+")")
+	}
+	return strings.Join(keyConditions, " OR ")
+}
+
+// FormatSampleValue renders a value for a sample diff report.
+func FormatSampleValue(qv qvalue.QValue) string {
+	switch v := qv.Value().(type) {
+	case nil:
+		return "NULL"
+	case [16]byte:
+		return uuid.UUID(v).String()
+	case []byte:
+		return "\\x" + hex.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// SampleCanonicalizer rewrites compared values into a canonical form, see protos.CompareCanonicalization.
+type SampleCanonicalizer struct {
+	rules           *protos.CompareCanonicalization
+	caseFoldColumns []string
+}
+
+// NewSampleCanonicalizer returns nil when there are no rules, which leaves values as they are.
+// caseFoldColumns adds to the rules' case_fold_columns, like the source's citext columns.
+func NewSampleCanonicalizer(rules *protos.CompareCanonicalization, caseFoldColumns []string) *SampleCanonicalizer {
+	if rules == nil && len(caseFoldColumns) == 0 {
+		return nil
+	}
+	return &SampleCanonicalizer{
+		rules:           rules,
+		caseFoldColumns: slices.Concat(rules.GetCaseFoldColumns(), caseFoldColumns),
+	}
+}
+
+// canonicalize rewrites the values of a column at the source and destination, typed by the source's kind.
+func (c *SampleCanonicalizer) canonicalize(column string, src qvalue.QValue, dst qvalue.QValue) (qvalue.QValue, qvalue.QValue) {
+	if c == nil {
+		return src, dst
+	}
+	if c.rules.GetNumericByValue() && src.Kind == qvalue.QValueKindNumeric {
+		return canonicalNumeric(src), canonicalNumeric(dst)
+	}
+	caseFold := slices.Contains(c.caseFoldColumns, column)
+	return c.canonicalValue(src, caseFold), c.canonicalValue(dst, caseFold)
+}
+
+func (c *SampleCanonicalizer) canonicalValue(qv qvalue.QValue, caseFold bool) qvalue.QValue {
+	switch v := qv.Value().(type) {
+	case string:
+		if c.rules.GetTrimWhitespace() {
+			v = strings.TrimSpace(v)
+		}
+		if caseFold {
+			v = strings.ToLower(v)
+		}
+		return qvalue.New(qv.Kind, v)
+	case time.Time:
+		if c.rules != nil && c.rules.TimePrecision != nil {
+			digits := min(int(*c.rules.TimePrecision), 9)
+			precision := time.Duration(1)
+			for range 9 - digits {
+				precision *= 10
+			}
+			v = v.Truncate(precision)
+		}
+		return qvalue.New(qv.Kind, v)
+	default:
+		return qv
+	}
+}
+
+// canonicalNumeric turns a numeric, float or string value into a rational, which drops trailing zeros.
+// Values which don't parse as a number are left for the comparison to report.
+func canonicalNumeric(qv qvalue.QValue) qvalue.QValue {
+	if qv.IsNull() {
+		return qvalue.QValue{Kind: qvalue.QValueKindNumeric}
+	}
+	rat, ok := new(big.Rat).SetString(fmt.Sprint(qv.Value()))
+	if !ok {
+		return qv
+	}
+	return qvalue.New(qvalue.QValueKindNumeric, rat)
+}
+
+// DiffSampleRows pairs every source row with the destination row holding the same primary key and reports
+// the columns whose values differ once types are normalized, along with source rows missing at the destination.
+// Both sides are expected to hold columns in the same order, pkeyIdx giving the positions of the primary key.
+// Fields are compared after canonicalizer rewrites them, which may be nil.
+func DiffSampleRows(
+	columns []string,
+	pkeyIdx []int,
+	source [][]qvalue.QValue,
+	destination [][]qvalue.QValue,
+	canonicalizer *SampleCanonicalizer,
+) []*protos.SampleRowDiff {
+	var diffs []*protos.SampleRowDiff
+	for _, srcRow := range source {
+		pkeyValues := make([]string, 0, len(pkeyIdx))
+		for _, idx := range pkeyIdx {
+			pkeyValues = append(pkeyValues, FormatSampleValue(srcRow[idx]))
+		}
+		rowDiff := &protos.SampleRowDiff{PrimaryKey: strings.Join(pkeyValues, ",")}
+
+		var dstRow []qvalue.QValue
+		for _, row := range destination {
+			matches := true
+			for _, idx := range pkeyIdx {
+				if !srcRow[idx].Equals(row[idx]) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				dstRow = row
+				break
+			}
+		}
+		if dstRow == nil {
+			rowDiff.MissingInDestination = true
+			diffs = append(diffs, rowDiff)
+			continue
+		}
+
+		for i, column := range columns {
+			srcValue, dstValue := canonicalizer.canonicalize(column, srcRow[i], dstRow[i])
+			if !srcValue.Equals(dstValue) {
+				rowDiff.Fields = append(rowDiff.Fields, &protos.SampleFieldDiff{
+					Column:           column,
+					SourceValue:      FormatSampleValue(srcRow[i]),
+					DestinationValue: FormatSampleValue(dstRow[i]),
+				})
+			}
+		}
+		if len(rowDiff.Fields) > 0 {
+			diffs = append(diffs, rowDiff)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestPrimaryKeyFilter(t *testing.T) {
+	filter := PrimaryKeyFilter([]string{`"a"`, `"b"`}, 2, func(i int) string { return "?" })
+	require.Equal(t, `("a"=? AND "b"=?) OR ("a"=? AND "b"=?)`, filter)
+}
+
+func TestDiffSampleRows(t *testing.T) {
+	columns := []string{"id", "name", "score"}
+	srcRow := func(id int32, name string, score int64) []qvalue.QValue {
+		return []qvalue.QValue{
+			qvalue.New(qvalue.QValueKindInt32, id),
+			qvalue.New(qvalue.QValueKindString, name),
+			qvalue.New(qvalue.QValueKindInt64, score),
+		}
+	}
+	// destinations may widen types, which should not count as a difference
+	dstRow := func(id int64, name string, score int64) []qvalue.QValue {
+		return []qvalue.QValue{
+			qvalue.New(qvalue.QValueKindInt64, id),
+			qvalue.New(qvalue.QValueKindString, name),
+			qvalue.New(qvalue.QValueKindInt64, score),
+		}
+	}
+	source := [][]qvalue.QValue{srcRow(1, "a", 10), srcRow(2, "b", 20), srcRow(3, "c", 30)}
+	destination := [][]qvalue.QValue{dstRow(2, "b", 21), dstRow(1, "a", 10)}
+
+	diffs := DiffSampleRows(columns, []int{0}, source, destination, nil)
+	require.Len(t, diffs, 2)
+
+	require.Equal(t, "2", diffs[0].PrimaryKey)
+	require.False(t, diffs[0].MissingInDestination)
+	require.Len(t, diffs[0].Fields, 1)
+	require.Equal(t, "score", diffs[0].Fields[0].Column)
+	require.Equal(t, "20", diffs[0].Fields[0].SourceValue)
+	require.Equal(t, "21", diffs[0].Fields[0].DestinationValue)
+
+	require.Equal(t, "3", diffs[1].PrimaryKey)
+	require.True(t, diffs[1].MissingInDestination)
+}
+
+func TestDiffSampleRowsCanonicalized(t *testing.T) {
+	columns := []string{"id", "amount", "code", "email", "at"}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	source := [][]qvalue.QValue{{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(1)),
+		qvalue.New(qvalue.QValueKindNumeric, big.NewRat(11, 10)),
+		qvalue.New(qvalue.QValueKindString, "ab"),
+		qvalue.New(qvalue.QValueKindString, "Someone@Example.com"),
+		qvalue.New(qvalue.QValueKindTimestamp, at),
+	}}
+	destination := [][]qvalue.QValue{{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(1)),
+		// a float holding 1.1 isn't exactly 11/10
+		qvalue.New(qvalue.QValueKindFloat64, 1.1),
+		qvalue.New(qvalue.QValueKindString, "ab  "),
+		qvalue.New(qvalue.QValueKindString, "someone@example.com"),
+		qvalue.New(qvalue.QValueKindTimestamp, at.Truncate(time.Millisecond)),
+	}}
+
+	require.Len(t, DiffSampleRows(columns, []int{0}, source, destination, nil)[0].Fields, 4)
+
+	precision := uint32(3)
+	canonicalizer := NewSampleCanonicalizer(&protos.CompareCanonicalization{
+		NumericByValue: true,
+		TimePrecision:  &precision,
+		TrimWhitespace: true,
+	}, []string{"email"})
+	require.Empty(t, DiffSampleRows(columns, []int{0}, source, destination, canonicalizer))
+}
+
+func TestDiffSampleRowsNulls(t *testing.T) {
+	columns := []string{"id", "tags", "doc"}
+	source := [][]qvalue.QValue{{
+		qvalue.New(qvalue.QValueKindInt64, int64(1)),
+		qvalue.New(qvalue.QValueKindHStore, `"a"=>"1"`),
+		qvalue.New(qvalue.QValueKindJSON, `{"a": 1}`),
+	}}
+	destination := [][]qvalue.QValue{{
+		qvalue.New(qvalue.QValueKindInt64, int64(1)),
+		{Kind: qvalue.QValueKindHStore},
+		qvalue.New(qvalue.QValueKindJSON, `{"a": 2}`),
+	}}
+
+	diffs := DiffSampleRows(columns, []int{0}, source, destination, nil)
+	require.Len(t, diffs, 1)
+	require.Len(t, diffs[0].Fields, 2, "a NULL destination value and a changed document are both reported")
+	require.Equal(t, "tags", diffs[0].Fields[0].Column)
+	require.Equal(t, "doc", diffs[0].Fields[1].Column)
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// StatementTimeout is how long a statement run for ctx may take on a peer, the time left until the activity's deadline.
+// It's zero when ctx has no deadline, peers then leave statements unbounded.
+func StatementTimeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	// peers treat a zero timeout as none, a statement starting this late fails right away instead
+	return max(time.Until(deadline), time.Millisecond)
+}
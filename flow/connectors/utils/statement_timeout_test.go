@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementTimeout(t *testing.T) {
+	require.Zero(t, StatementTimeout(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	timeout := StatementTimeout(ctx)
+	require.Greater(t, timeout, 59*time.Minute)
+	require.LessOrEqual(t, timeout, time.Hour)
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Minute))
+	defer cancelExpired()
+	require.Equal(t, time.Millisecond, StatementTimeout(expired))
+}
@@ -86,22 +86,10 @@ func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, recor
 			}
 		}
 
-		entries[3] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: itemsJSON,
-		}
-		entries[4] = qvalue.QValue{
-			Kind:  qvalue.QValueKindInt64,
-			Value: 0,
-		}
-		entries[5] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: "",
-		}
-		entries[7] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: "",
-		}
+		entries[3] = qvalue.New(qvalue.QValueKindString, itemsJSON)
+		entries[4] = qvalue.New(qvalue.QValueKindInt64, 0)
+		entries[5] = qvalue.New(qvalue.QValueKindString, "")
+		entries[7] = qvalue.New(qvalue.QValueKindString, "")
 		tableMapping[typedRecord.DestinationTableName] += 1
 	case *model.UpdateRecord:
 		newItemsJSON, err := typedRecord.NewItems.ToJSON()
@@ -117,22 +105,10 @@ func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, recor
 			}
 		}
 
-		entries[3] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: newItemsJSON,
-		}
-		entries[4] = qvalue.QValue{
-			Kind:  qvalue.QValueKindInt64,
-			Value: 1,
-		}
-		entries[5] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: oldItemsJSON,
-		}
-		entries[7] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: KeysToString(typedRecord.UnchangedToastColumns),
-		}
+		entries[3] = qvalue.New(qvalue.QValueKindString, newItemsJSON)
+		entries[4] = qvalue.New(qvalue.QValueKindInt64, 1)
+		entries[5] = qvalue.New(qvalue.QValueKindString, oldItemsJSON)
+		entries[7] = qvalue.New(qvalue.QValueKindString, KeysToString(typedRecord.UnchangedToastColumns))
 		tableMapping[typedRecord.DestinationTableName] += 1
 	case *model.DeleteRecord:
 		itemsJSON, err := typedRecord.Items.ToJSON()
@@ -142,22 +118,10 @@ func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, recor
 			}
 		}
 
-		entries[3] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: itemsJSON,
-		}
-		entries[4] = qvalue.QValue{
-			Kind:  qvalue.QValueKindInt64,
-			Value: 2,
-		}
-		entries[5] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: itemsJSON,
-		}
-		entries[7] = qvalue.QValue{
-			Kind:  qvalue.QValueKindString,
-			Value: KeysToString(typedRecord.UnchangedToastColumns),
-		}
+		entries[3] = qvalue.New(qvalue.QValueKindString, itemsJSON)
+		entries[4] = qvalue.New(qvalue.QValueKindInt64, 2)
+		entries[5] = qvalue.New(qvalue.QValueKindString, itemsJSON)
+		entries[7] = qvalue.New(qvalue.QValueKindString, KeysToString(typedRecord.UnchangedToastColumns))
 		tableMapping[typedRecord.DestinationTableName] += 1
 	default:
 		return model.QRecordOrError{
@@ -165,22 +129,10 @@ func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, recor
 		}
 	}
 
-	entries[0] = qvalue.QValue{
-		Kind:  qvalue.QValueKindString,
-		Value: uuid.New().String(),
-	}
-	entries[1] = qvalue.QValue{
-		Kind:  qvalue.QValueKindInt64,
-		Value: time.Now().UnixNano(),
-	}
-	entries[2] = qvalue.QValue{
-		Kind:  qvalue.QValueKindString,
-		Value: record.GetDestinationTableName(),
-	}
-	entries[6] = qvalue.QValue{
-		Kind:  qvalue.QValueKindInt64,
-		Value: batchID,
-	}
+	entries[0] = qvalue.New(qvalue.QValueKindString, uuid.New().String())
+	entries[1] = qvalue.New(qvalue.QValueKindInt64, time.Now().UnixNano())
+	entries[2] = qvalue.New(qvalue.QValueKindString, record.GetDestinationTableName())
+	entries[6] = qvalue.New(qvalue.QValueKindInt64, batchID)
 
 	return model.QRecordOrError{
 		Record: entries[:],
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// QRepThrottle holds back the records of a QRep activity to its share of the mirror's rows and bytes per second.
+type QRepThrottle struct {
+	rows      *rate.Limiter
+	bytes     *rate.Limiter
+	throttled atomic.Int64
+}
+
+// NewQRepThrottle returns the throttle of one of the workers replicating a mirror's partitions in parallel,
+// nil if it isn't rate limited.
+func NewQRepThrottle(config *protos.QRepConfig, workers int) *QRepThrottle {
+	if config.MaxRowsPerSecond == 0 && config.MaxBytesPerSecond == 0 {
+		return nil
+	}
+	burstSeconds := uint64(config.ThrottleBurstSeconds)
+	if burstSeconds == 0 {
+		burstSeconds = 1
+	}
+
+	return &QRepThrottle{
+		rows:  throttleLimiter(uint64(config.MaxRowsPerSecond), uint64(max(workers, 1)), burstSeconds),
+		bytes: throttleLimiter(config.MaxBytesPerSecond, uint64(max(workers, 1)), burstSeconds),
+	}
+}
+
+func throttleLimiter(perSecond uint64, workers uint64, burstSeconds uint64) *rate.Limiter {
+	if perSecond == 0 {
+		return nil
+	}
+	share := max(perSecond/workers, 1)
+	return rate.NewLimiter(rate.Limit(share), int(min(share*burstSeconds, 1<<30)))
+}
+
+// Wait blocks until a record of size bytes may be replicated.
+func (t *QRepThrottle) Wait(ctx context.Context, size int64) error {
+	start := time.Now()
+	if t.rows != nil {
+		if err := t.rows.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if t.bytes != nil && size > 0 {
+		// records larger than the burst take it all, waiting for more could never succeed
+		if err := t.bytes.WaitN(ctx, int(min(size, int64(t.bytes.Burst())))); err != nil {
+			return err
+		}
+	}
+	if waited := time.Since(start); waited > time.Millisecond {
+		t.throttled.Add(int64(waited))
+	}
+	return nil
+}
+
+// Throttled returns how long Wait has held records back so far.
+func (t *QRepThrottle) Throttled() time.Duration {
+	return time.Duration(t.throttled.Load())
+}
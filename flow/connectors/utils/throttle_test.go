@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestQRepThrottle(t *testing.T) {
+	require.Nil(t, NewQRepThrottle(&protos.QRepConfig{}, 4))
+
+	throttle := NewQRepThrottle(&protos.QRepConfig{MaxRowsPerSecond: 40, ThrottleBurstSeconds: 2}, 4)
+	require.NotNil(t, throttle)
+	require.Nil(t, throttle.bytes)
+	require.Equal(t, 10.0, float64(throttle.rows.Limit()))
+	require.Equal(t, 20, throttle.rows.Burst())
+
+	throttle = NewQRepThrottle(&protos.QRepConfig{MaxBytesPerSecond: 1000}, 1)
+	ctx := context.Background()
+	// the burst passes without waiting, records larger than it take all of it
+	require.NoError(t, throttle.Wait(ctx, 1000))
+	require.Zero(t, throttle.Throttled())
+	require.NoError(t, throttle.Wait(ctx, 5000))
+	require.Greater(t, throttle.Throttled(), 500*time.Millisecond)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Error(t, throttle.Wait(canceled, 1000))
+}
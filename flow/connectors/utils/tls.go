@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// CreateTLSConfig builds the TLS settings for connecting to a peer from its TLS options, which may be nil.
+// serverName is verified and sent for SNI and minVersion is required unless the options override them.
+func CreateTLSConfig(options *protos.TLSConfig, serverName string, minVersion uint16) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: serverName,
+		MinVersion: minVersion,
+	}
+	if options == nil {
+		return config, nil
+	}
+
+	if options.ServerName != "" {
+		config.ServerName = options.ServerName
+	}
+
+	switch options.MinVersion {
+	case "":
+	case "1.2":
+		config.MinVersion = tls.VersionTLS12
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported minimum TLS version %s, expected 1.2 or 1.3", options.MinVersion)
+	}
+
+	if options.RootCa != "" {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM([]byte(options.RootCa)) {
+			return nil, errors.New("no PEM certificates found in root CA")
+		}
+		config.RootCAs = rootCAs
+	}
+
+	if options.ClientCert != "" || options.ClientKey != "" {
+		clientCert, err := tls.X509KeyPair([]byte(options.ClientCert), []byte(options.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestCreateTLSConfig(t *testing.T) {
+	config, err := CreateTLSConfig(nil, "db.internal", tls.VersionTLS12)
+	require.NoError(t, err)
+	require.Equal(t, "db.internal", config.ServerName)
+	require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	require.Nil(t, config.RootCAs)
+
+	config, err = CreateTLSConfig(&protos.TLSConfig{
+		RootCa:     string(generateCertPEM(t, time.Now().Add(24*time.Hour))),
+		MinVersion: "1.3",
+		ServerName: "db.example.com",
+	}, "10.0.0.1", tls.VersionTLS12)
+	require.NoError(t, err)
+	require.Equal(t, "db.example.com", config.ServerName)
+	require.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	require.NotNil(t, config.RootCAs)
+
+	_, err = CreateTLSConfig(&protos.TLSConfig{RootCa: "not a certificate"}, "db.internal", tls.VersionTLS12)
+	require.Error(t, err)
+	_, err = CreateTLSConfig(&protos.TLSConfig{MinVersion: "1.0"}, "db.internal", tls.VersionTLS12)
+	require.Error(t, err)
+	_, err = CreateTLSConfig(&protos.TLSConfig{ClientCert: "not a certificate"}, "db.internal", tls.VersionTLS12)
+	require.Error(t, err)
+}
@@ -2,68 +2,499 @@ package dynamicconf
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 
 	utils "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
 	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+	"github.com/PeerDB-io/peer-flow/shared"
 )
 
-func dynamicConfKeyExists(ctx context.Context, conn *pgxpool.Pool, key string) bool {
-	var exists pgtype.Bool
-	query := "SELECT EXISTS(SELECT 1 FROM alerting_settings WHERE config_name = $1)"
-	err := conn.QueryRow(ctx, query, key).Scan(&exists)
+// Settings are layered: their default is overridden by the environment variable of the same name,
+// which is overridden by the catalog-wide value in alerting_settings, which is overridden by the value
+// set for the mirror in mirror_settings. The mirror is the one named by shared.FlowNameKey in the context.
+// Catalog values are reloaded every settingsRefreshInterval, so changing them needs no worker restarts.
+
+const (
+	settingsRefreshInterval = 30 * time.Second
+	// after a failed reload the settings loaded before are used this long, instead of every read retrying it
+	settingsRetryInterval = 5 * time.Second
+)
+
+type SettingType string
+
+const (
+	SettingTypeUint SettingType = "uint"
+	SettingTypeBool SettingType = "bool"
+)
+
+type SettingSource string
+
+const (
+	SettingSourceDefault SettingSource = "default"
+	SettingSourceEnv     SettingSource = "env"
+	SettingSourceCatalog SettingSource = "catalog"
+	SettingSourceMirror  SettingSource = "mirror"
+)
+
+type DynamicSetting struct {
+	Name         string
+	DefaultValue string
+	Type         SettingType
+	Description  string
+}
+
+// Validate returns an error when value isn't a valid value of the setting.
+func (s *DynamicSetting) Validate(value string) error {
+	var err error
+	switch s.Type {
+	case SettingTypeUint:
+		_, err = strconv.ParseUint(value, 10, 32)
+	case SettingTypeBool:
+		_, err = strconv.ParseBool(value)
+	}
 	if err != nil {
-		logger.LoggerFromCtx(ctx).Error("Failed to check if key exists: %v", err)
-		return false
+		return fmt.Errorf("invalid %s value %q for %s: %w", s.Type, value, s.Name, err)
+	}
+	return nil
+}
+
+var (
+	slotLagMBAlertThreshold = &DynamicSetting{
+		Name:         "PEERDB_SLOT_LAG_MB_ALERT_THRESHOLD",
+		DefaultValue: "5000",
+		Type:         SettingTypeUint,
+		Description:  "alert when a slot's restart_lsn is this far behind the source's WAL, 0 disables slot lag alerting entirely",
+	}
+	slotFlushLagMBAlertThreshold = &DynamicSetting{
+		Name:         "PEERDB_SLOT_FLUSH_LAG_MB_ALERT_THRESHOLD",
+		DefaultValue: "0",
+		Type:         SettingTypeUint,
+		Description:  "alert when a slot's confirmed_flush_lsn is this far behind the source's WAL, 0 disables the alert",
+	}
+	slotFlushLagMinutesAlertThreshold = &DynamicSetting{
+		Name:         "PEERDB_SLOT_FLUSH_LAG_MINUTES_ALERT_THRESHOLD",
+		DefaultValue: "60",
+		Type:         SettingTypeUint,
+		Description:  "alert when the source's WAL was at a slot's confirmed_flush_lsn this long ago, 0 disables the alert",
+	}
+	alertingGapMinutes = &DynamicSetting{
+		Name:         "PEERDB_ALERTING_GAP_MINUTES",
+		DefaultValue: "15",
+		Type:         SettingTypeUint,
+		Description:  "minimum gap between two alerts with the same key, 0 disables all alerting entirely",
+	}
+	openConnectionsAlertThreshold = &DynamicSetting{
+		Name:         "PEERDB_PGPEER_OPEN_CONNECTIONS_ALERT_THRESHOLD",
+		DefaultValue: "5",
+		Type:         SettingTypeUint,
+		Description:  "alert when PeerDB has this many connections open to a Postgres peer, 0 disables the alert",
+	}
+	pgPeerLongTransactionAlertMinutes = &DynamicSetting{
+		Name:         "PEERDB_PGPEER_LONG_TRANSACTION_ALERT_MINUTES",
+		DefaultValue: "30",
+		Type:         SettingTypeUint,
+		Description:  "alert when a writing transaction on a source peer stays open this long, 0 disables the check",
+	}
+	credentialExpiryAlertDays = &DynamicSetting{
+		Name:         "PEERDB_CREDENTIAL_EXPIRY_ALERT_DAYS",
+		DefaultValue: "14",
+		Type:         SettingTypeUint,
+		Description:  "alert when peer credentials expire within this many days, 0 disables the alert",
+	}
+	eventhubFlushTimeoutSeconds = &DynamicSetting{
+		Name:         "PEERDB_EVENTHUB_FLUSH_TIMEOUT_SECONDS",
+		DefaultValue: "10",
+		Type:         SettingTypeUint,
+		Description:  "how often batches are flushed to Event Hubs while syncing",
+	}
+	sourceHeartbeatIntervalSeconds = &DynamicSetting{
+		Name:         "PEERDB_SOURCE_HEARTBEAT_INTERVAL_SECONDS",
+		DefaultValue: "0",
+		Type:         SettingTypeUint,
+		Description: "how often mirrors write a heartbeat message to their Postgres source unless they set their own interval, " +
+			"0 disables source heartbeats",
+	}
+	cdcStreamInProgressTransactions = &DynamicSetting{
+		Name:         "PEERDB_CDC_STREAM_IN_PROGRESS_TRANSACTIONS",
+		DefaultValue: "false",
+		Type:         SettingTypeBool,
+		Description: "have Postgres 14+ sources stream transactions larger than logical_decoding_work_mem before they commit, " +
+			"instead of spilling them to disk on the source, applies when replication restarts",
+	}
+	bigQueryMergeScriptTables = &DynamicSetting{
+		Name:         "PEERDB_BIGQUERY_MERGE_SCRIPT_TABLES",
+		DefaultValue: "0",
+		Type:         SettingTypeUint,
+		Description:  "number of tables merged per BigQuery script job, 0 runs one job per MERGE",
+	}
+	clickhouseNormalizeParallelism = &DynamicSetting{
+		Name:         "PEERDB_CLICKHOUSE_NORMALIZE_PARALLELISM",
+		DefaultValue: "4",
+		Type:         SettingTypeUint,
+		Description:  "number of tables normalized concurrently in a ClickHouse batch",
+	}
+	mirrorRestartMaxAttempts = &DynamicSetting{
+		Name:         "PEERDB_MIRROR_RESTART_MAX_ATTEMPTS",
+		DefaultValue: "5",
+		Type:         SettingTypeUint,
+		Description:  "how often a failed mirror is restarted before alerting and giving up, 0 leaves failed mirrors as they are",
 	}
+	mirrorRestartBackoffSeconds = &DynamicSetting{
+		Name:         "PEERDB_MIRROR_RESTART_BACKOFF_SECONDS",
+		DefaultValue: "60",
+		Type:         SettingTypeUint,
+		Description:  "how long after failing a mirror is first restarted, doubling with each restart",
+	}
+	mirrorRestartMaxBackoffSeconds = &DynamicSetting{
+		Name:         "PEERDB_MIRROR_RESTART_MAX_BACKOFF_SECONDS",
+		DefaultValue: "3600",
+		Type:         SettingTypeUint,
+		Description:  "the longest a failed mirror waits to be restarted",
+	}
+	enableParallelSyncNormalize = &DynamicSetting{
+		Name:         "PEERDB_ENABLE_PARALLEL_SYNC_NORMALIZE",
+		DefaultValue: "false",
+		Type:         SettingTypeBool,
+		Description:  "run normalize flows alongside sync flows instead of after them",
+	}
+	cdcCatchUpLagThresholdMB = &DynamicSetting{
+		Name:         "PEERDB_CDC_CATCH_UP_LAG_THRESHOLD_MB",
+		DefaultValue: "0",
+		Type:         SettingTypeUint,
+		Description:  "replication lag above which mirrors switch to paced catch-up, 0 disables catch-up mode",
+	}
+	maxConcurrentInitialLoads = &DynamicSetting{
+		Name:         "PEERDB_MAX_CONCURRENT_INITIAL_LOADS",
+		DefaultValue: "0",
+		Type:         SettingTypeUint,
+		Description:  "how many CDC mirrors snapshot their tables at once across all peers, 0 doesn't limit them",
+	}
+	maxConcurrentNormalizesPerPeer = &DynamicSetting{
+		Name:         "PEERDB_MAX_CONCURRENT_NORMALIZES_PER_PEER",
+		DefaultValue: "0",
+		Type:         SettingTypeUint,
+		Description:  "how many CDC mirrors normalize into the same destination peer at once, 0 doesn't limit them",
+	}
+)
 
-	return exists.Bool
+// DynamicSettings are all settings which can be set in the catalog.
+var DynamicSettings = []*DynamicSetting{
+	slotLagMBAlertThreshold,
+	slotFlushLagMBAlertThreshold,
+	slotFlushLagMinutesAlertThreshold,
+	alertingGapMinutes,
+	openConnectionsAlertThreshold,
+	pgPeerLongTransactionAlertMinutes,
+	credentialExpiryAlertDays,
+	eventhubFlushTimeoutSeconds,
+	sourceHeartbeatIntervalSeconds,
+	cdcStreamInProgressTransactions,
+	bigQueryMergeScriptTables,
+	clickhouseNormalizeParallelism,
+	mirrorRestartMaxAttempts,
+	mirrorRestartBackoffSeconds,
+	mirrorRestartMaxBackoffSeconds,
+	enableParallelSyncNormalize,
+	cdcCatchUpLagThresholdMB,
+	maxConcurrentInitialLoads,
+	maxConcurrentNormalizesPerPeer,
 }
 
-func dynamicConfUint32(ctx context.Context, key string, defaultValue uint32) uint32 {
+// EnvVars returns the environment variables of the settings, documented alongside those of peerdbenv.
+func EnvVars() []peerdbenv.EnvVar {
+	envVars := make([]peerdbenv.EnvVar, 0, len(DynamicSettings))
+	for _, setting := range DynamicSettings {
+		envVarType := peerdbenv.EnvVarTypeUint
+		if setting.Type == SettingTypeBool {
+			envVarType = peerdbenv.EnvVarTypeBool
+		}
+		envVars = append(envVars, peerdbenv.EnvVar{
+			Name:        setting.Name,
+			Type:        envVarType,
+			Default:     setting.DefaultValue,
+			Description: setting.Description,
+		})
+	}
+	return envVars
+}
+
+// FindSetting returns the setting named name, or nil if there is none.
+func FindSetting(name string) *DynamicSetting {
+	for _, setting := range DynamicSettings {
+		if setting.Name == name {
+			return setting
+		}
+	}
+	return nil
+}
+
+// catalogSettings are the values of settings set in the catalog, catalog-wide and per mirror.
+type catalogSettings struct {
+	global   map[string]string
+	byMirror map[string]map[string]string
+	loadedAt time.Time
+}
+
+// settings are the catalog settings last loaded, replaced as a whole on reload so reads don't need a lock.
+// loadLock serializes reloads, which callers finding settings fresh enough don't wait for.
+// loadFailedAt is when the last reload failed, in Unix nanoseconds.
+var (
+	settings     atomic.Pointer[catalogSettings]
+	loadLock     sync.Mutex
+	loadFailedAt atomic.Int64
+)
+
+// loadCatalogSettings returns the catalog settings, reloading them once they're older than maxAge.
+// When they can't be reloaded the settings loaded before are returned alongside the error, nil if there are none.
+// Within settingsRetryInterval of a failed reload they're returned without retrying it, unless maxAge is 0.
+func loadCatalogSettings(ctx context.Context, maxAge time.Duration) (*catalogSettings, error) {
+	fresh := func(cached *catalogSettings) bool {
+		if cached != nil && time.Since(cached.loadedAt) < maxAge {
+			return true
+		}
+		return maxAge > 0 && time.Since(time.Unix(0, loadFailedAt.Load())) < settingsRetryInterval
+	}
+	if cached := settings.Load(); fresh(cached) {
+		return cached, nil
+	}
+
+	loadLock.Lock()
+	defer loadLock.Unlock()
+	// settings may have been reloaded, or failed to, while waiting for another caller's reload
+	cached := settings.Load()
+	if fresh(cached) {
+		return cached, nil
+	}
+	loaded, err := readCatalogSettings(ctx)
+	if err != nil {
+		loadFailedAt.Store(time.Now().UnixNano())
+		return cached, err
+	}
+	settings.Store(loaded)
+	return loaded, nil
+}
+
+func readCatalogSettings(ctx context.Context) (*catalogSettings, error) {
 	conn, err := utils.GetCatalogConnectionPoolFromEnv(ctx)
 	if err != nil {
-		logger.LoggerFromCtx(ctx).Error("Failed to get catalog connection pool: %v", err)
-		return defaultValue
+		return nil, fmt.Errorf("failed to get catalog connection pool: %w", err)
+	}
+	loaded := &catalogSettings{
+		global:   make(map[string]string),
+		byMirror: make(map[string]map[string]string),
+		loadedAt: time.Now(),
 	}
 
-	if !dynamicConfKeyExists(ctx, conn, key) {
-		return defaultValue
+	rows, err := conn.Query(ctx, "SELECT config_name,config_value FROM alerting_settings")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	var name, value string
+	if _, err := pgx.ForEachRow(rows, []any{&name, &value}, func() error {
+		loaded.global[name] = value
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
 	}
 
-	var value pgtype.Text
-	query := "SELECT config_value FROM alerting_settings WHERE config_name = $1"
-	err = conn.QueryRow(ctx, query, key).Scan(&value)
+	rows, err = conn.Query(ctx, "SELECT flow_name,config_name,config_value FROM mirror_settings")
 	if err != nil {
-		logger.LoggerFromCtx(ctx).Error("Failed to get key: %v", err)
-		return defaultValue
+		return nil, fmt.Errorf("failed to read mirror settings: %w", err)
+	}
+	var flowName string
+	if _, err := pgx.ForEachRow(rows, []any{&flowName, &name, &value}, func() error {
+		if loaded.byMirror[flowName] == nil {
+			loaded.byMirror[flowName] = make(map[string]string)
+		}
+		loaded.byMirror[flowName][name] = value
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read mirror settings: %w", err)
 	}
+	return loaded, nil
+}
+
+// resolve returns the value of a setting for a mirror, all mirrors when flowName is empty, and where it comes from.
+// loaded may be nil when the catalog couldn't be read.
+func resolve(setting *DynamicSetting, flowName string, loaded *catalogSettings) (string, SettingSource) {
+	if loaded != nil {
+		if value, ok := loaded.byMirror[flowName][setting.Name]; ok && flowName != "" {
+			return value, SettingSourceMirror
+		}
+		if value, ok := loaded.global[setting.Name]; ok {
+			return value, SettingSourceCatalog
+		}
+	}
+	if value, ok := os.LookupEnv(setting.Name); ok {
+		return value, SettingSourceEnv
+	}
+	return setting.DefaultValue, SettingSourceDefault
+}
 
-	result, err := strconv.ParseUint(value.String, 10, 32)
+func lookup(ctx context.Context, setting *DynamicSetting) string {
+	loaded, err := loadCatalogSettings(ctx, settingsRefreshInterval)
 	if err != nil {
-		logger.LoggerFromCtx(ctx).Error("Failed to parse uint32: %v", err)
-		return defaultValue
+		logger.LoggerFromCtx(ctx).Warn("failed to load settings from catalog", slog.Any("error", err))
 	}
+	flowName, _ := ctx.Value(shared.FlowNameKey).(string)
+	value, _ := resolve(setting, flowName, loaded)
+	return value
+}
 
+func dynamicConfUint32(ctx context.Context, setting *DynamicSetting) uint32 {
+	value := lookup(ctx, setting)
+	result, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Error("Failed to parse uint32",
+			slog.String("setting", setting.Name), slog.String("value", value), slog.Any("error", err))
+		result, _ = strconv.ParseUint(setting.DefaultValue, 10, 32)
+	}
 	return uint32(result)
 }
 
+func dynamicConfBool(ctx context.Context, setting *DynamicSetting) bool {
+	value := lookup(ctx, setting)
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Error("Failed to parse bool",
+			slog.String("setting", setting.Name), slog.String("value", value), slog.Any("error", err))
+		result, _ = strconv.ParseBool(setting.DefaultValue)
+	}
+	return result
+}
+
+type ResolvedSetting struct {
+	*DynamicSetting
+	Value  string
+	Source SettingSource
+}
+
+// ResolveSettings returns the values of all settings for a mirror, all mirrors when flowName is empty,
+// as currently set in the catalog.
+func ResolveSettings(ctx context.Context, flowName string) ([]ResolvedSetting, error) {
+	loaded, err := loadCatalogSettings(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]ResolvedSetting, 0, len(DynamicSettings))
+	for _, setting := range DynamicSettings {
+		value, source := resolve(setting, flowName, loaded)
+		resolved = append(resolved, ResolvedSetting{DynamicSetting: setting, Value: value, Source: source})
+	}
+	return resolved, nil
+}
+
 // PEERDB_SLOT_LAG_MB_ALERT_THRESHOLD, 0 disables slot lag alerting entirely
 func PeerDBSlotLagMBAlertThreshold(ctx context.Context) uint32 {
-	return dynamicConfUint32(ctx, "PEERDB_SLOT_LAG_MB_ALERT_THRESHOLD", 5000)
+	return dynamicConfUint32(ctx, slotLagMBAlertThreshold)
+}
+
+// PEERDB_SLOT_FLUSH_LAG_MB_ALERT_THRESHOLD, alert when a slot's confirmed_flush_lsn is this far behind the source's WAL, 0 disables the alert
+func PeerDBSlotFlushLagMBAlertThreshold(ctx context.Context) uint32 {
+	return dynamicConfUint32(ctx, slotFlushLagMBAlertThreshold)
+}
+
+// PEERDB_SLOT_FLUSH_LAG_MINUTES_ALERT_THRESHOLD, alert when the source's WAL was at a slot's confirmed_flush_lsn this long ago,
+// 0 disables the alert
+func PeerDBSlotFlushLagMinutesAlertThreshold(ctx context.Context) uint32 {
+	return dynamicConfUint32(ctx, slotFlushLagMinutesAlertThreshold)
 }
 
 // PEERDB_ALERTING_GAP_MINUTES, 0 disables all alerting entirely
 func PeerDBAlertingGapMinutesAsDuration(ctx context.Context) time.Duration {
-	why := int64(dynamicConfUint32(ctx, "PEERDB_ALERTING_GAP_MINUTES", 15))
+	why := int64(dynamicConfUint32(ctx, alertingGapMinutes))
 	return time.Duration(why) * time.Minute
 }
 
 // PEERDB_PGPEER_OPEN_CONNECTIONS_ALERT_THRESHOLD, 0 disables open connections alerting entirely
 func PeerDBOpenConnectionsAlertThreshold(ctx context.Context) uint32 {
-	return dynamicConfUint32(ctx, "PEERDB_PGPEER_OPEN_CONNECTIONS_ALERT_THRESHOLD", 5)
+	return dynamicConfUint32(ctx, openConnectionsAlertThreshold)
+}
+
+// PEERDB_PGPEER_LONG_TRANSACTION_ALERT_MINUTES, alert when a writing transaction on a source peer stays open this long, 0 disables the check
+func PeerDBPGPeerLongTransactionAlertMinutes(ctx context.Context) uint32 {
+	return dynamicConfUint32(ctx, pgPeerLongTransactionAlertMinutes)
+}
+
+// PEERDB_CREDENTIAL_EXPIRY_ALERT_DAYS, alert when peer credentials expire within this many days, 0 disables the alert
+func PeerDBCredentialExpiryAlertDays(ctx context.Context) uint32 {
+	return dynamicConfUint32(ctx, credentialExpiryAlertDays)
+}
+
+// PEERDB_EVENTHUB_FLUSH_TIMEOUT_SECONDS, at least a second
+func PeerDBEventhubFlushTimeoutSeconds(ctx context.Context) time.Duration {
+	x := max(dynamicConfUint32(ctx, eventhubFlushTimeoutSeconds), 1)
+	return time.Duration(x) * time.Second
+}
+
+// PEERDB_SOURCE_HEARTBEAT_INTERVAL_SECONDS, how often mirrors write a heartbeat message to their Postgres source
+// unless they set their own interval, 0 disables source heartbeats
+func PeerDBSourceHeartbeatInterval(ctx context.Context) time.Duration {
+	x := dynamicConfUint32(ctx, sourceHeartbeatIntervalSeconds)
+	return time.Duration(x) * time.Second
+}
+
+// PEERDB_CDC_STREAM_IN_PROGRESS_TRANSACTIONS, have Postgres 14+ sources stream transactions larger than
+// logical_decoding_work_mem before they commit, instead of spilling them to disk on the source
+func PeerDBCDCStreamInProgressTransactions(ctx context.Context) bool {
+	return dynamicConfBool(ctx, cdcStreamInProgressTransactions)
+}
+
+// PEERDB_BIGQUERY_MERGE_SCRIPT_TABLES, number of tables merged per BigQuery script job, 0 runs one job per MERGE
+func PeerDBBigQueryMergeScriptTables(ctx context.Context) int {
+	return int(dynamicConfUint32(ctx, bigQueryMergeScriptTables))
+}
+
+// PEERDB_CLICKHOUSE_NORMALIZE_PARALLELISM, number of tables normalized concurrently in a ClickHouse batch
+func PeerDBClickhouseNormalizeParallelism(ctx context.Context) int {
+	return max(int(dynamicConfUint32(ctx, clickhouseNormalizeParallelism)), 1)
+}
+
+// PEERDB_MIRROR_RESTART_MAX_ATTEMPTS, how often a failed mirror is restarted before alerting and giving up,
+// 0 leaves failed mirrors as they are
+func PeerDBMirrorRestartMaxAttempts(ctx context.Context) int {
+	return int(dynamicConfUint32(ctx, mirrorRestartMaxAttempts))
+}
+
+// PEERDB_MIRROR_RESTART_BACKOFF_SECONDS, how long after failing a mirror is first restarted, doubling with each restart
+func PeerDBMirrorRestartBackoff(ctx context.Context) time.Duration {
+	x := dynamicConfUint32(ctx, mirrorRestartBackoffSeconds)
+	return time.Duration(x) * time.Second
+}
+
+// PEERDB_MIRROR_RESTART_MAX_BACKOFF_SECONDS, the longest a failed mirror waits to be restarted
+func PeerDBMirrorRestartMaxBackoff(ctx context.Context) time.Duration {
+	x := dynamicConfUint32(ctx, mirrorRestartMaxBackoffSeconds)
+	return time.Duration(x) * time.Second
+}
+
+// PEERDB_ENABLE_PARALLEL_SYNC_NORMALIZE, run normalize flows alongside sync flows instead of after them
+func PeerDBEnableParallelSyncNormalize(ctx context.Context) bool {
+	return dynamicConfBool(ctx, enableParallelSyncNormalize)
+}
+
+// PEERDB_CDC_CATCH_UP_LAG_THRESHOLD_MB, replication lag above which mirrors switch to paced catch-up, 0 disables catch-up mode
+func PeerDBCDCCatchUpLagThresholdMB(ctx context.Context) uint32 {
+	return dynamicConfUint32(ctx, cdcCatchUpLagThresholdMB)
+}
+
+// PEERDB_MAX_CONCURRENT_INITIAL_LOADS, how many CDC mirrors snapshot their tables at once across all peers,
+// 0 doesn't limit them
+func PeerDBMaxConcurrentInitialLoads(ctx context.Context) int {
+	return int(dynamicConfUint32(ctx, maxConcurrentInitialLoads))
+}
+
+// PEERDB_MAX_CONCURRENT_NORMALIZES_PER_PEER, how many CDC mirrors normalize into the same destination peer at once,
+// 0 doesn't limit them
+func PeerDBMaxConcurrentNormalizesPerPeer(ctx context.Context) int {
+	return int(dynamicConfUint32(ctx, maxConcurrentNormalizesPerPeer))
 }
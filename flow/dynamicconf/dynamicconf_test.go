@@ -0,0 +1,83 @@
+package dynamicconf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	setting := clickhouseNormalizeParallelism
+
+	value, source := resolve(setting, "", nil)
+	assert.Equal(t, setting.DefaultValue, value)
+	assert.Equal(t, SettingSourceDefault, source)
+
+	t.Setenv(setting.Name, "8")
+	value, source = resolve(setting, "mirror", nil)
+	assert.Equal(t, "8", value)
+	assert.Equal(t, SettingSourceEnv, source, "settings fall back to the environment without a catalog")
+
+	loaded := &catalogSettings{
+		global:   map[string]string{setting.Name: "2"},
+		byMirror: map[string]map[string]string{"mirror": {setting.Name: "16"}},
+	}
+	value, source = resolve(setting, "", loaded)
+	assert.Equal(t, "2", value)
+	assert.Equal(t, SettingSourceCatalog, source)
+
+	value, source = resolve(setting, "other_mirror", loaded)
+	assert.Equal(t, "2", value)
+	assert.Equal(t, SettingSourceCatalog, source)
+
+	value, source = resolve(setting, "mirror", loaded)
+	assert.Equal(t, "16", value)
+	assert.Equal(t, SettingSourceMirror, source)
+}
+
+func TestSettingValidate(t *testing.T) {
+	require.NoError(t, clickhouseNormalizeParallelism.Validate("8"))
+	require.Error(t, clickhouseNormalizeParallelism.Validate("-1"))
+	require.NoError(t, cdcStreamInProgressTransactions.Validate("true"))
+	require.Error(t, cdcStreamInProgressTransactions.Validate("sometimes"))
+
+	for _, setting := range DynamicSettings {
+		require.NoError(t, setting.Validate(setting.DefaultValue), setting.Name)
+		assert.Same(t, setting, FindSetting(setting.Name))
+	}
+	assert.Nil(t, FindSetting("PEERDB_UNKNOWN"))
+}
+
+func TestLoadCatalogSettingsCached(t *testing.T) {
+	cached := &catalogSettings{
+		global:   map[string]string{maxConcurrentInitialLoads.Name: "3"},
+		loadedAt: time.Now(),
+	}
+	settings.Store(cached)
+	t.Cleanup(func() { settings.Store(nil) })
+
+	loaded, err := loadCatalogSettings(context.Background(), time.Minute)
+	require.NoError(t, err)
+	assert.Same(t, cached, loaded, "fresh settings are returned without reading the catalog")
+	assert.Equal(t, 3, PeerDBMaxConcurrentInitialLoads(context.Background()))
+}
+
+func TestLoadCatalogSettingsBackoff(t *testing.T) {
+	stale := &catalogSettings{
+		global:   map[string]string{maxConcurrentInitialLoads.Name: "3"},
+		loadedAt: time.Now().Add(-time.Hour),
+	}
+	settings.Store(stale)
+	loadFailedAt.Store(time.Now().UnixNano())
+	t.Cleanup(func() {
+		settings.Store(nil)
+		loadFailedAt.Store(0)
+	})
+
+	loaded, err := loadCatalogSettings(context.Background(), time.Minute)
+	require.NoError(t, err)
+	assert.Same(t, stale, loaded, "settings aren't reloaded right after a failed reload")
+}
@@ -5,13 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
 	"os"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/bigquery"
-	"cloud.google.com/go/civil"
 	"google.golang.org/api/iterator"
 
 	peer_bq "github.com/PeerDB-io/peer-flow/connectors/bigquery"
@@ -205,178 +203,10 @@ func (b *BigQueryTestHelper) countRowsWithDataset(dataset, tableName string, non
 	return int(cntI64), nil
 }
 
-func toQValue(bqValue bigquery.Value) (qvalue.QValue, error) {
-	// Based on the real type of the bigquery.Value, we create a qvalue.QValue
-	switch v := bqValue.(type) {
-	case int, int32:
-		return qvalue.QValue{Kind: qvalue.QValueKindInt32, Value: v}, nil
-	case int64:
-		return qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: v}, nil
-	case float32:
-		return qvalue.QValue{Kind: qvalue.QValueKindFloat32, Value: v}, nil
-	case float64:
-		return qvalue.QValue{Kind: qvalue.QValueKindFloat64, Value: v}, nil
-	case string:
-		return qvalue.QValue{Kind: qvalue.QValueKindString, Value: v}, nil
-	case bool:
-		return qvalue.QValue{Kind: qvalue.QValueKindBoolean, Value: v}, nil
-	case civil.Date:
-		return qvalue.QValue{Kind: qvalue.QValueKindDate, Value: v.In(time.UTC)}, nil
-	case civil.Time:
-		return qvalue.QValue{Kind: qvalue.QValueKindTime, Value: v}, nil
-	case time.Time:
-		return qvalue.QValue{Kind: qvalue.QValueKindTimestamp, Value: v}, nil
-	case *big.Rat:
-		return qvalue.QValue{Kind: qvalue.QValueKindNumeric, Value: v}, nil
-	case []uint8:
-		return qvalue.QValue{Kind: qvalue.QValueKindBytes, Value: v}, nil
-	case []bigquery.Value:
-		// If the type is an array, we need to convert each element
-		// we can assume all elements are of the same type, let us use first element
-		if len(v) == 0 {
-			return qvalue.QValue{Kind: qvalue.QValueKindInvalid, Value: nil}, nil
-		}
-
-		firstElement := v[0]
-		switch et := firstElement.(type) {
-		case int, int32:
-			var arr []int32
-			for _, val := range v {
-				arr = append(arr, val.(int32))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayInt32, Value: arr}, nil
-		case int64:
-			var arr []int64
-			for _, val := range v {
-				arr = append(arr, val.(int64))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayInt64, Value: arr}, nil
-		case float32:
-			var arr []float32
-			for _, val := range v {
-				arr = append(arr, val.(float32))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayFloat32, Value: arr}, nil
-		case float64:
-			var arr []float64
-			for _, val := range v {
-				arr = append(arr, val.(float64))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayFloat64, Value: arr}, nil
-		case string:
-			var arr []string
-			for _, val := range v {
-				arr = append(arr, val.(string))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayString, Value: arr}, nil
-		case time.Time:
-			var arr []time.Time
-			for _, val := range v {
-				arr = append(arr, val.(time.Time))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayTimestamp, Value: arr}, nil
-		case civil.Date:
-			var arr []civil.Date
-			for _, val := range v {
-				arr = append(arr, val.(civil.Date))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayDate, Value: arr}, nil
-		case bool:
-			var arr []bool
-
-			for _, val := range v {
-				arr = append(arr, val.(bool))
-			}
-			return qvalue.QValue{Kind: qvalue.QValueKindArrayBoolean, Value: arr}, nil
-		default:
-			// If type is unsupported, return error
-			return qvalue.QValue{}, fmt.Errorf("bqHelper unsupported type %T", et)
-		}
-
-	case nil:
-		return qvalue.QValue{Kind: qvalue.QValueKindInvalid, Value: nil}, nil
-	default:
-		// If type is unsupported, return error
-		return qvalue.QValue{}, fmt.Errorf("bqHelper unsupported type %T", v)
-	}
-}
-
-func bqFieldSchemaToQField(fieldSchema *bigquery.FieldSchema) (model.QField, error) {
-	qValueKind, err := peer_bq.BigQueryTypeToQValueKind(fieldSchema.Type)
-	if err != nil {
-		return model.QField{}, err
-	}
-
-	return model.QField{
-		Name:     fieldSchema.Name,
-		Type:     qValueKind,
-		Nullable: !fieldSchema.Required,
-	}, nil
-}
-
-// bqSchemaToQRecordSchema converts a bigquery schema to a QRecordSchema.
-func bqSchemaToQRecordSchema(schema bigquery.Schema) (*model.QRecordSchema, error) {
-	fields := make([]model.QField, 0, len(schema))
-	for _, fieldSchema := range schema {
-		qField, err := bqFieldSchemaToQField(fieldSchema)
-		if err != nil {
-			return nil, err
-		}
-		fields = append(fields, qField)
-	}
-
-	return &model.QRecordSchema{
-		Fields: fields,
-	}, nil
-}
-
 func (b *BigQueryTestHelper) ExecuteAndProcessQuery(query string) (*model.QRecordBatch, error) {
 	q := b.client.Query(query)
 	q.DisableQueryCache = true
-	it, err := q.Read(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to run command: %w", err)
-	}
-
-	var records [][]qvalue.QValue
-	for {
-		var row []bigquery.Value
-		err := it.Next(&row)
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate over query results: %w", err)
-		}
-
-		// Convert []bigquery.Value to []qvalue.QValue
-		qValues := make([]qvalue.QValue, len(row))
-		for i, val := range row {
-			qv, err := toQValue(val)
-			if err != nil {
-				return nil, err
-			}
-			qValues[i] = qv
-		}
-
-		records = append(records, qValues)
-	}
-
-	// Now you should fill the column names as well. Here we assume the schema is
-	// retrieved from the query itself
-	var schema *model.QRecordSchema
-	if it.Schema != nil {
-		schema, err = bqSchemaToQRecordSchema(it.Schema)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Return a QRecordBatch
-	return &model.QRecordBatch{
-		Records: records,
-		Schema:  schema,
-	}, nil
+	return peer_bq.ReadQRecordBatch(context.Background(), q)
 }
 
 // returns whether the function errors or there are no nulls
@@ -518,5 +348,5 @@ func (b *BigQueryTestHelper) RunInt64Query(query string) (int64, error) {
 		return 0, fmt.Errorf("expected only 1 record, got %d", len(recordBatch.Records))
 	}
 
-	return recordBatch.Records[0][0].Value.(int64), nil
+	return recordBatch.Records[0][0].Value().(int64), nil
 }
@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
@@ -37,7 +36,7 @@ func (s PeerFlowE2ETestSuiteBQ) T() *testing.T {
 	return s.t
 }
 
-func (s PeerFlowE2ETestSuiteBQ) Conn() *pgx.Conn {
+func (s PeerFlowE2ETestSuiteBQ) Conn() *connpostgres.ConnPool {
 	return s.conn.Conn()
 }
 
@@ -88,7 +87,7 @@ func (s PeerFlowE2ETestSuiteBQ) checkJSONValue(tableName, colName, fieldName, va
 		return fmt.Errorf("bad json: empty result set from %s", tableName)
 	}
 
-	jsonVal := res.Records[0][0].Value
+	jsonVal := res.Records[0][0].Value()
 	if jsonVal != value {
 		return fmt.Errorf("bad json value in field %s of column %s: %v. expected: %v", fieldName, colName, jsonVal, value)
 	}
@@ -122,7 +121,7 @@ func (s *PeerFlowE2ETestSuiteBQ) checkPeerdbColumns(dstQualified string, softDel
 	for _, record := range recordBatch.Records {
 		for _, entry := range record {
 			if entry.Kind == qvalue.QValueKindBoolean {
-				isDeleteVal, ok := entry.Value.(bool)
+				isDeleteVal, ok := entry.Value().(bool)
 				if !(ok && isDeleteVal) {
 					return errors.New("peerdb column failed: _PEERDB_IS_DELETED is not true")
 				}
@@ -130,7 +129,7 @@ func (s *PeerFlowE2ETestSuiteBQ) checkPeerdbColumns(dstQualified string, softDel
 			}
 
 			if entry.Kind == qvalue.QValueKindTimestamp {
-				_, ok := entry.Value.(time.Time)
+				_, ok := entry.Value().(time.Time)
 				if !ok {
 					return errors.New("peerdb column failed: _PEERDB_SYNCED_AT is not valid")
 				}
@@ -32,7 +32,7 @@ func GetTestPostgresConf() *protos.PostgresConfig {
 	}
 }
 
-func cleanPostgres(conn *pgx.Conn, suffix string) error {
+func cleanPostgres(conn *connpostgres.ConnPool, suffix string) error {
 	// drop the e2e_test schema with the given suffix if it exists
 	_, err := conn.Exec(context.Background(), fmt.Sprintf("DROP SCHEMA IF EXISTS e2e_test_%s CASCADE", suffix))
 	if err != nil {
@@ -72,7 +72,7 @@ func cleanPostgres(conn *pgx.Conn, suffix string) error {
 	return nil
 }
 
-func setupPostgresSchema(t *testing.T, conn *pgx.Conn, suffix string) error {
+func setupPostgresSchema(t *testing.T, conn *connpostgres.ConnPool, suffix string) error {
 	t.Helper()
 
 	setupTx, err := conn.Begin(context.Background())
@@ -156,7 +156,7 @@ func TearDownPostgres[T Suite](s T) {
 		for {
 			err := cleanPostgres(conn, suffix)
 			if err == nil {
-				conn.Close(context.Background())
+				s.Connector().Close()
 				return
 			} else if time.Now().After(deadline) {
 				require.Fail(t, "failed to teardown postgres schema", "%s: %v", suffix, err)
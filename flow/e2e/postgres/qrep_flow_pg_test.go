@@ -32,7 +32,7 @@ func (s PeerFlowE2ETestSuitePG) T() *testing.T {
 	return s.t
 }
 
-func (s PeerFlowE2ETestSuitePG) Conn() *pgx.Conn {
+func (s PeerFlowE2ETestSuitePG) Conn() *connpostgres.ConnPool {
 	return s.conn.Conn()
 }
 
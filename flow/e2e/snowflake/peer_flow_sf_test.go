@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
@@ -43,7 +42,7 @@ func (s PeerFlowE2ETestSuiteSF) Connector() *connpostgres.PostgresConnector {
 	return s.conn
 }
 
-func (s PeerFlowE2ETestSuiteSF) Conn() *pgx.Conn {
+func (s PeerFlowE2ETestSuiteSF) Conn() *connpostgres.ConnPool {
 	return s.Connector().Conn()
 }
 
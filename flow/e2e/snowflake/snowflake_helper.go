@@ -169,12 +169,12 @@ func (s *SnowflakeTestHelper) RunIntQuery(query string) (int, error) {
 
 	switch rec[0].Kind {
 	case qvalue.QValueKindInt32:
-		return int(rec[0].Value.(int32)), nil
+		return int(rec[0].Value().(int32)), nil
 	case qvalue.QValueKindInt64:
-		return int(rec[0].Value.(int64)), nil
+		return int(rec[0].Value().(int64)), nil
 	case qvalue.QValueKindNumeric:
 		// get big.Rat and convert to int
-		rat := rec[0].Value.(*big.Rat)
+		rat := rec[0].Value().(*big.Rat)
 		return int(rat.Num().Int64() / rat.Denom().Int64()), nil
 	default:
 		return 0, fmt.Errorf("failed to execute query: %s, returned value of type %s", query, rec[0].Kind)
@@ -193,7 +193,7 @@ func (s *SnowflakeTestHelper) checkSyncedAt(query string) error {
 			if entry.Kind != qvalue.QValueKindTimestamp {
 				return errors.New("synced_at column check failed: _PEERDB_SYNCED_AT is not timestamp")
 			}
-			_, ok := entry.Value.(time.Time)
+			_, ok := entry.Value().(time.Time)
 			if !ok {
 				return errors.New("synced_at column failed: _PEERDB_SYNCED_AT is not valid")
 			}
@@ -9,7 +9,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
@@ -35,7 +34,7 @@ func (s PeerFlowE2ETestSuiteSQLServer) T() *testing.T {
 	return s.t
 }
 
-func (s PeerFlowE2ETestSuiteSQLServer) Conn() *pgx.Conn {
+func (s PeerFlowE2ETestSuiteSQLServer) Conn() *connpostgres.ConnPool {
 	return s.conn.Conn()
 }
 
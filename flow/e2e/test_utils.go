@@ -14,7 +14,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
@@ -227,7 +226,7 @@ func SetupCDCFlowStatusQuery(t *testing.T, env *testsuite.TestWorkflowEnvironmen
 	}
 }
 
-func CreateTableForQRep(conn *pgx.Conn, suffix string, tableName string) error {
+func CreateTableForQRep(conn *connpostgres.ConnPool, suffix string, tableName string) error {
 	createMoodEnum := "CREATE TYPE mood AS ENUM ('happy', 'sad', 'angry');"
 
 	tblFields := []string{
@@ -319,7 +318,7 @@ func generate20MBJson() ([]byte, error) {
 	return v, nil
 }
 
-func PopulateSourceTable(conn *pgx.Conn, suffix string, tableName string, rowCount int) error {
+func PopulateSourceTable(conn *connpostgres.ConnPool, suffix string, tableName string, rowCount int) error {
 	var id0 string
 	rows := make([]string, 0, rowCount)
 	for i := range rowCount - 1 {
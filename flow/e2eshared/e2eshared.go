@@ -66,7 +66,7 @@ func CheckQRecordEquality(t *testing.T, q []qvalue.QValue, other []qvalue.QValue
 	for i, entry := range q {
 		otherEntry := other[i]
 		if !entry.Equals(otherEntry) {
-			t.Logf("entry %d: %T %v != %T %v", i, entry.Value, entry, otherEntry.Value, otherEntry)
+			t.Logf("entry %d: %T %v != %T %v", i, entry.Value(), entry, otherEntry.Value(), otherEntry)
 			return false
 		}
 	}
@@ -0,0 +1,320 @@
+// Package federation serves the FlowService API in front of several regional PeerDB deployments,
+// each with its own catalog and Temporal, by proxying calls to their API servers.
+package federation
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// RegionMetadataKey is the gRPC metadata key, or HTTP header through the gateway, naming the region a call is for.
+const RegionMetadataKey = "x-peerdb-region"
+
+type Federation struct {
+	protos.UnimplementedFlowServiceServer
+	conns         map[string]*grpc.ClientConn
+	clients       map[string]protos.FlowServiceClient
+	regionNames   []string
+	defaultRegion string
+}
+
+// ParseRegions parses a comma separated list of region=host:port pairs.
+func ParseRegions(regions string) (map[string]string, error) {
+	addrs := make(map[string]string)
+	for _, region := range strings.Split(regions, ",") {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+		name, addr, ok := strings.Cut(region, "=")
+		if !ok || name == "" || addr == "" {
+			return nil, fmt.Errorf("invalid region %q, expected region=host:port", region)
+		}
+		if _, exists := addrs[name]; exists {
+			return nil, fmt.Errorf("region %s is listed more than once", name)
+		}
+		addrs[name] = addr
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no regions configured")
+	}
+	return addrs, nil
+}
+
+func New(addrs map[string]string, defaultRegion string, useTLS bool) (*Federation, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	conns := make(map[string]*grpc.ClientConn, len(addrs))
+	for region, addr := range addrs {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			return nil, fmt.Errorf("unable to dial region %s at %s: %w", region, addr, err)
+		}
+		conns[region] = conn
+	}
+	return newFederation(conns, defaultRegion)
+}
+
+func newFederation(conns map[string]*grpc.ClientConn, defaultRegion string) (*Federation, error) {
+	if _, ok := conns[defaultRegion]; defaultRegion != "" && !ok {
+		return nil, fmt.Errorf("default region %s is not one of the configured regions", defaultRegion)
+	}
+
+	clients := make(map[string]protos.FlowServiceClient, len(conns))
+	regionNames := make([]string, 0, len(conns))
+	for region, conn := range conns {
+		clients[region] = protos.NewFlowServiceClient(conn)
+		regionNames = append(regionNames, region)
+	}
+	slices.Sort(regionNames)
+
+	return &Federation{
+		conns:         conns,
+		clients:       clients,
+		regionNames:   regionNames,
+		defaultRegion: defaultRegion,
+	}, nil
+}
+
+func (f *Federation) Close() error {
+	var firstErr error
+	for _, conn := range f.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// fanOut calls fn for every region concurrently, failing if any region fails.
+func (f *Federation) fanOut(ctx context.Context, fn func(ctx context.Context, region string) error) error {
+	g, groupCtx := errgroup.WithContext(ctx)
+	for _, region := range f.regionNames {
+		g.Go(func() error {
+			if err := fn(groupCtx, region); err != nil {
+				return fmt.Errorf("region %s: %w", region, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// ListMirrors lists the mirrors of every region, tagged with their region.
+func (f *Federation) ListMirrors(ctx context.Context, req *protos.ListMirrorsRequest) (*protos.ListMirrorsResponse, error) {
+	var mu sync.Mutex
+	res := &protos.ListMirrorsResponse{}
+	err := f.fanOut(ctx, func(ctx context.Context, region string) error {
+		regionRes, err := f.clients[region].ListMirrors(ctx, req)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range regionRes.Items {
+			item.Region = region
+			res.Items = append(res.Items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *protos.MirrorListItem) int {
+		return strings.Compare(a.Region+"/"+a.Name, b.Region+"/"+b.Name)
+	})
+	return res, nil
+}
+
+// ListPeers lists the peers of every region, tagged with their region.
+func (f *Federation) ListPeers(ctx context.Context, req *protos.ListPeersRequest) (*protos.ListPeersResponse, error) {
+	var mu sync.Mutex
+	res := &protos.ListPeersResponse{}
+	err := f.fanOut(ctx, func(ctx context.Context, region string) error {
+		regionRes, err := f.clients[region].ListPeers(ctx, req)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range regionRes.Items {
+			item.Region = region
+			res.Items = append(res.Items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(res.Items, func(a, b *protos.PeerListItem) int {
+		return strings.Compare(a.Region+"/"+a.Name, b.Region+"/"+b.Name)
+	})
+	return res, nil
+}
+
+// UnaryInterceptor proxies every FlowService call which the federation doesn't serve itself to a single region.
+func (f *Federation) UnaryInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	servicePrefix := "/" + protos.FlowService_ServiceDesc.ServiceName + "/"
+	method, isFlowService := strings.CutPrefix(info.FullMethod, servicePrefix)
+	if !isFlowService || method == "ListMirrors" || method == "ListPeers" {
+		return handler(ctx, req)
+	}
+
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "unexpected request type %T", req)
+	}
+	region, err := f.route(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := newReply(method)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("proxying call", slog.String("method", method), slog.String("region", region))
+	if err := f.conns[region].Invoke(ctx, info.FullMethod, req, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// newReply creates an empty response message of a FlowService method.
+func newReply(method string) (proto.Message, error) {
+	serviceDesc, err := protoregistry.GlobalFiles.FindDescriptorByName(
+		protoreflect.FullName(protos.FlowService_ServiceDesc.ServiceName))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to find FlowService descriptor: %v", err)
+	}
+	methodDesc := serviceDesc.(protoreflect.ServiceDescriptor).Methods().ByName(protoreflect.Name(method))
+	if methodDesc == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	replyType, err := protoregistry.GlobalTypes.FindMessageByName(methodDesc.Output().FullName())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to find response type of %s: %v", method, err)
+	}
+	return replyType.New().Interface(), nil
+}
+
+// route picks the region of a call: the region named in the call's metadata, else the region
+// of the mirror or peer the request names, else the default region.
+func (f *Federation) route(ctx context.Context, req proto.Message) (string, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if regions := md.Get(RegionMetadataKey); len(regions) > 0 && regions[0] != "" {
+			if _, ok := f.conns[regions[0]]; !ok {
+				return "", status.Errorf(codes.InvalidArgument, "unknown region %s", regions[0])
+			}
+			return regions[0], nil
+		}
+	}
+
+	if mirror := stringField(req, "flow_job_name"); mirror != "" {
+		return f.findRegion(ctx, "mirror", mirror, func(ctx context.Context, region string) ([]string, error) {
+			res, err := f.clients[region].ListMirrors(ctx, &protos.ListMirrorsRequest{})
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(res.Items))
+			for _, item := range res.Items {
+				names = append(names, item.Name)
+			}
+			return names, nil
+		})
+	}
+	if peer := stringField(req, "peer_name"); peer != "" {
+		return f.findRegion(ctx, "peer", peer, func(ctx context.Context, region string) ([]string, error) {
+			res, err := f.clients[region].ListPeers(ctx, &protos.ListPeersRequest{})
+			if err != nil {
+				return nil, err
+			}
+			names := make([]string, 0, len(res.Items))
+			for _, item := range res.Items {
+				names = append(names, item.Name)
+			}
+			return names, nil
+		})
+	}
+
+	if f.defaultRegion == "" {
+		return "", status.Errorf(codes.InvalidArgument, "no region given, set the %s header", RegionMetadataKey)
+	}
+	return f.defaultRegion, nil
+}
+
+// findRegion looks for the single region having a mirror or peer of the given name.
+func (f *Federation) findRegion(
+	ctx context.Context,
+	kind string,
+	name string,
+	list func(ctx context.Context, region string) ([]string, error),
+) (string, error) {
+	var mu sync.Mutex
+	var found []string
+	err := f.fanOut(ctx, func(ctx context.Context, region string) error {
+		names, err := list(ctx, region)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(names, name) {
+			mu.Lock()
+			found = append(found, region)
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", status.Errorf(codes.Unavailable, "unable to find region of %s %s: %v", kind, name, err)
+	}
+
+	switch len(found) {
+	case 0:
+		return "", status.Errorf(codes.NotFound, "%s %s not found in any region", kind, name)
+	case 1:
+		return found[0], nil
+	default:
+		slices.Sort(found)
+		return "", status.Errorf(codes.FailedPrecondition, "%s %s exists in regions %s, set the %s header",
+			kind, name, strings.Join(found, ", "), RegionMetadataKey)
+	}
+}
+
+// stringField returns the value of a top level string field of a request, if it has one.
+func stringField(msg proto.Message, name protoreflect.Name) string {
+	m := msg.ProtoReflect()
+	field := m.Descriptor().Fields().ByName(name)
+	if field == nil || field.Kind() != protoreflect.StringKind || field.IsList() {
+		return ""
+	}
+	return m.Get(field).String()
+}
@@ -0,0 +1,119 @@
+package federation
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type regionServer struct {
+	protos.UnimplementedFlowServiceServer
+	region  string
+	mirrors []string
+}
+
+func (s *regionServer) ListMirrors(context.Context, *protos.ListMirrorsRequest) (*protos.ListMirrorsResponse, error) {
+	res := &protos.ListMirrorsResponse{}
+	for _, mirror := range s.mirrors {
+		res.Items = append(res.Items, &protos.MirrorListItem{Name: mirror})
+	}
+	return res, nil
+}
+
+func (s *regionServer) ListPeers(context.Context, *protos.ListPeersRequest) (*protos.ListPeersResponse, error) {
+	return &protos.ListPeersResponse{}, nil
+}
+
+func (s *regionServer) MirrorStatus(_ context.Context, req *protos.MirrorStatusRequest) (*protos.MirrorStatusResponse, error) {
+	return &protos.MirrorStatusResponse{FlowJobName: req.FlowJobName, ErrorMessage: s.region}, nil
+}
+
+func listen(t *testing.T, register func(*grpc.Server), opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(opts...)
+	register(server)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestFederation(t *testing.T) {
+	regions := map[string][]string{
+		"us": {"orders", "shared"},
+		"eu": {"users", "shared"},
+	}
+	conns := make(map[string]*grpc.ClientConn, len(regions))
+	for region, mirrors := range regions {
+		conns[region] = listen(t, func(s *grpc.Server) {
+			protos.RegisterFlowServiceServer(s, &regionServer{region: region, mirrors: mirrors})
+		})
+	}
+	fed, err := newFederation(conns, "")
+	require.NoError(t, err)
+
+	client := protos.NewFlowServiceClient(listen(t, func(s *grpc.Server) {
+		protos.RegisterFlowServiceServer(s, fed)
+	}, grpc.UnaryInterceptor(fed.UnaryInterceptor)))
+	ctx := context.Background()
+
+	t.Run("lists mirrors of every region", func(t *testing.T) {
+		res, err := client.ListMirrors(ctx, &protos.ListMirrorsRequest{})
+		require.NoError(t, err)
+		var names []string
+		for _, item := range res.Items {
+			names = append(names, item.Region+"/"+item.Name)
+		}
+		require.Equal(t, []string{"eu/shared", "eu/users", "us/orders", "us/shared"}, names)
+	})
+
+	t.Run("routes by mirror name", func(t *testing.T) {
+		res, err := client.MirrorStatus(ctx, &protos.MirrorStatusRequest{FlowJobName: "users"})
+		require.NoError(t, err)
+		require.Equal(t, "eu", res.ErrorMessage)
+
+		_, err = client.MirrorStatus(ctx, &protos.MirrorStatusRequest{FlowJobName: "missing"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.MirrorStatus(ctx, &protos.MirrorStatusRequest{FlowJobName: "shared"})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("routes by region header", func(t *testing.T) {
+		res, err := client.MirrorStatus(metadata.AppendToOutgoingContext(ctx, RegionMetadataKey, "us"),
+			&protos.MirrorStatusRequest{FlowJobName: "shared"})
+		require.NoError(t, err)
+		require.Equal(t, "us", res.ErrorMessage)
+
+		_, err = client.MirrorStatus(metadata.AppendToOutgoingContext(ctx, RegionMetadataKey, "ap"),
+			&protos.MirrorStatusRequest{FlowJobName: "shared"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestParseRegions(t *testing.T) {
+	addrs, err := ParseRegions("us=peerdb-us:8110, eu=peerdb-eu:8110")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"us": "peerdb-us:8110", "eu": "peerdb-eu:8110"}, addrs)
+
+	_, err = ParseRegions("us=peerdb-us:8110,us=other:8110")
+	require.Error(t, err)
+	_, err = ParseRegions("peerdb-us:8110")
+	require.Error(t, err)
+}
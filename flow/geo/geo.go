@@ -2,44 +2,104 @@ package geo
 
 import (
 	"encoding/hex"
-	"errors"
 	"fmt"
-	"log/slog"
+	"strconv"
+	"strings"
 
 	geom "github.com/twpayne/go-geos"
 )
 
-// returns the WKT representation of the geometry object if it is valid
+// InvalidGeometryError is returned for values which aren't valid geometries, which are replicated as null.
+type InvalidGeometryError struct {
+	Value  string
+	Reason string
+}
+
+func (e *InvalidGeometryError) Error() string {
+	value := e.Value
+	if len(value) > 64 {
+		value = value[:64] + "..."
+	}
+	return fmt.Sprintf("invalid geometry %s: %s", value, e.Reason)
+}
+
+// returns the EWKT representation of the geometry object if it is valid,
+// which is its WKT prefixed by its SRID when it has one
 func GeoValidate(hexWkb string) (string, error) {
 	// Decode the WKB hex string into binary
 	wkb, hexErr := hex.DecodeString(hexWkb)
 	if hexErr != nil {
-		slog.Warn("Ignoring invalid WKB: " + hexWkb)
-		return "", hexErr
+		return "", &InvalidGeometryError{Value: hexWkb, Reason: hexErr.Error()}
 	}
 
-	// UnmarshalWKB performs geometry validation along with WKB parsing
+	// UnmarshalWKB performs geometry validation along with WKB parsing,
+	// WKB extended with an SRID like PostGIS outputs is read with it
 	geometryObject, geoErr := geom.NewGeomFromWKB(wkb)
 	if geoErr != nil {
-		return "", geoErr
+		return "", &InvalidGeometryError{Value: hexWkb, Reason: geoErr.Error()}
 	}
 
 	invalidReason := geometryObject.IsValidReason()
 	if invalidReason != "Valid Geometry" {
-		slog.Warn(fmt.Sprintf("Ignoring invalid geometry shape %s: %s", hexWkb, invalidReason))
-		return "", errors.New(invalidReason)
+		return "", &InvalidGeometryError{Value: hexWkb, Reason: invalidReason}
+	}
+
+	if srid := geometryObject.SRID(); srid != 0 {
+		return fmt.Sprintf("SRID=%d;%s", srid, geometryObject.ToWKT()), nil
+	}
+	return geometryObject.ToWKT(), nil
+}
+
+// SplitEWKT splits EWKT into its SRID, 0 for WKT without one, and its WKT.
+func SplitEWKT(ewkt string) (int, string, error) {
+	rest, ok := strings.CutPrefix(ewkt, "SRID=")
+	if !ok {
+		return 0, ewkt, nil
 	}
+	sridStr, wkt, ok := strings.Cut(rest, ";")
+	if !ok {
+		return 0, "", fmt.Errorf("invalid EWKT, SRID isn't followed by a geometry: %s", ewkt)
+	}
+	srid, err := strconv.Atoi(sridStr)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid EWKT SRID %s: %w", sridStr, err)
+	}
+	return srid, wkt, nil
+}
 
-	wkt := geometryObject.ToWKT()
-	return wkt, nil
+func geomFromEWKT(ewkt string) (*geom.Geom, error) {
+	srid, wkt, err := SplitEWKT(ewkt)
+	if err != nil {
+		return nil, err
+	}
+	geometryObject, err := geom.NewGeomFromWKT(wkt)
+	if err != nil {
+		return nil, err
+	}
+	if srid != 0 {
+		geometryObject.SetSRID(srid)
+	}
+	return geometryObject, nil
 }
 
+// GeoToWKB converts EWKT to WKB, extended with the SRID when it has one.
 func GeoToWKB(wkt string) ([]byte, error) {
-	// UnmarshalWKB performs geometry validation along with WKB parsing
-	geometryObject, geoErr := geom.NewGeomFromWKT(wkt)
+	geometryObject, geoErr := geomFromEWKT(wkt)
 	if geoErr != nil {
 		return []byte{}, geoErr
 	}
 
+	if geometryObject.SRID() != 0 {
+		return geometryObject.ToEWKBWithSRID(), nil
+	}
 	return geometryObject.ToWKB(), nil
 }
+
+// GeoToGeoJSON converts EWKT to a GeoJSON geometry, GeoJSON having no place for the SRID.
+func GeoToGeoJSON(wkt string) (string, error) {
+	geometryObject, err := geomFromEWKT(wkt)
+	if err != nil {
+		return "", err
+	}
+	return geometryObject.ToGeoJSON(-1), nil
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"sync/atomic"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
@@ -12,12 +15,44 @@ type CDCRecordStream struct {
 	records chan Record
 	// Schema changes from the slot
 	SchemaDeltas []*protos.TableSchemaDelta
+	// Schema changes kept away from the destination, see HoldSchemaDeltas
+	HeldSchemaDeltas []*protos.TableSchemaDelta
+	holdSchemaDeltas bool
+	// Schema changes the mirror pauses on, see SetSchemaChangePolicy
+	PausingSchemaDeltas []*protos.TableSchemaDelta
+	schemaChangePolicy  protos.SchemaChangePolicy
+	// Schema changes destinations can't follow, see PauseOnIncompatibleSchemaChanges
+	IncompatibleSchemaDeltas []*protos.TableSchemaDelta
+	incompatibleTableSchemas map[string]*protos.TableSchema
 	// Indicates if the last checkpoint has been set.
 	lastCheckpointSet bool
 	// lastCheckpointID is the last ID of the commit that corresponds to this batch.
 	lastCheckpointID atomic.Int64
 	// empty signal to indicate if the records are going to be empty or not.
 	emptySignal chan bool
+	// overflow of records once the channel is full, nil blocks the puller until the consumer catches up
+	spill *recordSpill
+	// why records were lost on the way to the consumer, passed on by Fanout
+	err error
+}
+
+// RecordSpillQueue is a first in, first out queue of records on disk.
+type RecordSpillQueue interface {
+	Push(Record) error
+	Pop() (Record, error)
+	Close() error
+}
+
+type recordSpill struct {
+	queue RecordSpillQueue
+	ctx   context.Context
+	lock  sync.Mutex
+	// records pushed to the queue and not yet handed to the consumer, later records queue up behind them
+	queued   int
+	draining bool
+	closing  bool
+	err      error
+	notify   chan struct{}
 }
 
 func NewCDCRecordStream() *CDCRecordStream {
@@ -31,6 +66,19 @@ func NewCDCRecordStream() *CDCRecordStream {
 	}
 }
 
+// NewSpillingCDCRecordStream returns a stream which spills records to queue rather than blocking the puller
+// once its channel buffer is full. Spilled records are handed on in order until ctx is done,
+// and queue is closed once they all are.
+func NewSpillingCDCRecordStream(ctx context.Context, queue RecordSpillQueue) *CDCRecordStream {
+	stream := NewCDCRecordStream()
+	stream.spill = &recordSpill{
+		queue:  queue,
+		ctx:    ctx,
+		notify: make(chan struct{}, 1),
+	}
+	return stream
+}
+
 func (r *CDCRecordStream) UpdateLatestCheckpoint(val int64) {
 	// TODO update with https://github.com/golang/go/issues/63999 once implemented
 	// r.lastCheckpointID.Max(val)
@@ -40,15 +88,136 @@ func (r *CDCRecordStream) UpdateLatestCheckpoint(val int64) {
 	}
 }
 
-func (r *CDCRecordStream) GetLastCheckpoint() int64 {
+// GetLastCheckpoint returns the checkpoint the stream's records were pulled up to,
+// or an error if some of them were lost while spilled to disk and the batch mustn't be committed.
+func (r *CDCRecordStream) GetLastCheckpoint() (int64, error) {
 	if !r.lastCheckpointSet {
 		panic("last checkpoint not set, stream is still active")
 	}
-	return r.lastCheckpointID.Load()
+	if err := r.Err(); err != nil {
+		return 0, err
+	}
+	return r.lastCheckpointID.Load(), nil
+}
+
+// Err is why records of the stream didn't reach the consumer, once it has read all of them.
+func (r *CDCRecordStream) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.spill != nil {
+		r.spill.lock.Lock()
+		defer r.spill.lock.Unlock()
+		return r.spill.err
+	}
+	return nil
+}
+
+func (r *CDCRecordStream) AddRecord(record Record) error {
+	if r.spill == nil {
+		r.records <- record
+		return nil
+	}
+	return r.spill.add(r.records, record)
+}
+
+func (s *recordSpill) add(records chan Record, record Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+
+	if s.queued == 0 {
+		select {
+		case records <- record:
+			return nil
+		default:
+		}
+	}
+	if err := s.queue.Push(record); err != nil {
+		return fmt.Errorf("failed to spill record to disk: %w", err)
+	}
+	s.queued += 1
+	if !s.draining {
+		s.draining = true
+		go s.drain(records)
+	} else {
+		s.wake()
+	}
+	return nil
+}
+
+func (s *recordSpill) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// drain hands spilled records on to the consumer, closing records once the stream is closed and they're all handed on.
+// If a record can't be read back records is closed early, with err telling the consumer its batch is incomplete.
+func (s *recordSpill) drain(records chan Record) {
+	fail := func(err error) {
+		s.err = err
+		_ = s.queue.Close()
+		close(records)
+	}
+
+	for {
+		s.lock.Lock()
+		if s.queued == 0 {
+			if s.closing {
+				_ = s.queue.Close()
+				close(records)
+				s.lock.Unlock()
+				return
+			}
+			s.lock.Unlock()
+			select {
+			case <-s.notify:
+			case <-s.ctx.Done():
+				s.lock.Lock()
+				fail(s.ctx.Err())
+				s.lock.Unlock()
+				return
+			}
+			continue
+		}
+		record, err := s.queue.Pop()
+		if err != nil {
+			fail(fmt.Errorf("failed to read spilled record back from disk: %w", err))
+			s.lock.Unlock()
+			return
+		}
+		s.lock.Unlock()
+
+		select {
+		case records <- record:
+		case <-s.ctx.Done():
+			s.lock.Lock()
+			fail(s.ctx.Err())
+			s.lock.Unlock()
+			return
+		}
+
+		s.lock.Lock()
+		s.queued -= 1
+		s.lock.Unlock()
+	}
 }
 
-func (r *CDCRecordStream) AddRecord(record Record) {
-	r.records <- record
+// close has drain close records once it has handed on the spilled records, returning false if it isn't running.
+func (s *recordSpill) close() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.draining {
+		_ = s.queue.Close()
+		return false
+	}
+	s.closing = true
+	s.wake()
+	return true
 }
 
 func (r *CDCRecordStream) SignalAsEmpty() {
@@ -66,9 +235,12 @@ func (r *CDCRecordStream) WaitAndCheckEmpty() bool {
 
 func (r *CDCRecordStream) Close() {
 	if !r.lastCheckpointSet {
-		close(r.emptySignal)
-		close(r.records)
+		// set before closing the channels, consumers read it once they've drained them
 		r.lastCheckpointSet = true
+		close(r.emptySignal)
+		if r.spill == nil || !r.spill.close() {
+			close(r.records)
+		}
 	}
 }
 
@@ -76,22 +248,82 @@ func (r *CDCRecordStream) GetRecords() <-chan Record {
 	return r.records
 }
 
+// HoldSchemaDeltas makes AddSchemaDelta collect deltas into HeldSchemaDeltas,
+// so that destinations don't replay them as part of syncing this stream.
+func (r *CDCRecordStream) HoldSchemaDeltas() {
+	r.holdSchemaDeltas = true
+}
+
+// SetSchemaChangePolicy sets what AddSchemaDelta does with dropped, widened and renamed columns.
+// Changes the policy pauses on are collected into PausingSchemaDeltas instead of being replayed.
+func (r *CDCRecordStream) SetSchemaChangePolicy(policy protos.SchemaChangePolicy) {
+	r.schemaChangePolicy = policy
+}
+
+// PauseOnIncompatibleSchemaChanges makes AddSchemaDelta collect narrowed columns, and dropped columns among
+// the primary key columns of the destination tables' schemas, into IncompatibleSchemaDeltas instead of
+// leaving them to the schema change policy. Without it narrowed columns are left out of deltas.
+func (r *CDCRecordStream) PauseOnIncompatibleSchemaChanges(tableNameSchemaMapping map[string]*protos.TableSchema) {
+	r.incompatibleTableSchemas = tableNameSchemaMapping
+	if r.incompatibleTableSchemas == nil {
+		r.incompatibleTableSchemas = make(map[string]*protos.TableSchema)
+	}
+}
+
 func (r *CDCRecordStream) AddSchemaDelta(tableNameMapping map[string]NameAndExclude, delta *protos.TableSchemaDelta) {
-	if tm, ok := tableNameMapping[delta.SrcTableName]; ok && len(tm.Exclude) != 0 {
-		added := make([]*protos.DeltaAddedColumn, 0, len(delta.AddedColumns))
-		for _, column := range delta.AddedColumns {
-			if _, has := tm.Exclude[column.ColumnName]; !has {
-				added = append(added, column)
-			}
-		}
-		if len(added) != 0 {
-			r.SchemaDeltas = append(r.SchemaDeltas, &protos.TableSchemaDelta{
-				SrcTableName: delta.SrcTableName,
-				DstTableName: delta.DstTableName,
-				AddedColumns: added,
-			})
-		}
+	if tm, ok := tableNameMapping[delta.SrcTableName]; ok {
+		delta = filterSchemaDelta(tm, delta)
+	}
+	delta, incompatible := splitIncompatibleSchemaDelta(delta, r.incompatibleTableSchemas[delta.DstTableName])
+	if r.incompatibleTableSchemas != nil && !IsEmptySchemaDelta(incompatible) {
+		r.IncompatibleSchemaDeltas = append(r.IncompatibleSchemaDeltas, incompatible)
+	}
+	delta, pausing := splitSchemaDelta(delta, r.schemaChangePolicy)
+	if !IsEmptySchemaDelta(pausing) {
+		r.PausingSchemaDeltas = append(r.PausingSchemaDeltas, pausing)
+	}
+	if IsEmptySchemaDelta(delta) {
+		return
+	}
+
+	if r.holdSchemaDeltas {
+		r.HeldSchemaDeltas = append(r.HeldSchemaDeltas, delta)
 	} else {
 		r.SchemaDeltas = append(r.SchemaDeltas, delta)
 	}
 }
+
+// Fanout hands each record of the stream on to a stream per offset, leaving records at or before the offset
+// out of its stream, so destinations which already synced part of the stream skip it. Once the stream is closed,
+// its schema changes and last checkpoint are passed on and the streams are closed.
+// Records are shared between the streams, and the slowest consumer paces the others.
+// Once ctxs[i] is done, records are no longer handed on to the i-th stream, so a consumer which failed
+// doesn't hold up the others. Once all are done, the rest of the stream is drained without handing it on.
+func (r *CDCRecordStream) Fanout(ctxs []context.Context, offsets []int64) []*CDCRecordStream {
+	streams := make([]*CDCRecordStream, 0, len(offsets))
+	for range offsets {
+		streams = append(streams, NewCDCRecordStream())
+	}
+	go func() {
+		for record := range r.records {
+			for i, stream := range streams {
+				if ctxs[i].Err() == nil && record.GetCheckpointID() > offsets[i] {
+					select {
+					case stream.records <- record:
+					case <-ctxs[i].Done():
+					}
+				}
+			}
+		}
+		for _, stream := range streams {
+			stream.SchemaDeltas = r.SchemaDeltas
+			stream.HeldSchemaDeltas = r.HeldSchemaDeltas
+			stream.PausingSchemaDeltas = r.PausingSchemaDeltas
+			stream.IncompatibleSchemaDeltas = r.IncompatibleSchemaDeltas
+			stream.UpdateLatestCheckpoint(r.lastCheckpointID.Load())
+			stream.err = r.Err()
+			stream.Close()
+		}
+	}()
+	return streams
+}
@@ -0,0 +1,275 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+func TestAddSchemaDeltaHeld(t *testing.T) {
+	tableNameMapping := map[string]model.NameAndExclude{
+		"public.t": model.NewNameAndExclude("t", []string{"secret"}, nil),
+	}
+	delta := &protos.TableSchemaDelta{
+		SrcTableName: "public.t",
+		DstTableName: "t",
+		AddedColumns: []*protos.DeltaAddedColumn{
+			{ColumnName: "secret", ColumnType: "string"},
+			{ColumnName: "name", ColumnType: "string"},
+		},
+	}
+
+	stream := model.NewCDCRecordStream()
+	stream.AddSchemaDelta(tableNameMapping, delta)
+	assert.Len(t, stream.SchemaDeltas, 1)
+	assert.Empty(t, stream.HeldSchemaDeltas)
+
+	held := model.NewCDCRecordStream()
+	held.HoldSchemaDeltas()
+	held.AddSchemaDelta(tableNameMapping, delta)
+	assert.Empty(t, held.SchemaDeltas)
+	assert.Len(t, held.HeldSchemaDeltas, 1)
+	assert.Equal(t, []*protos.DeltaAddedColumn{delta.AddedColumns[1]}, held.HeldSchemaDeltas[0].AddedColumns)
+}
+
+func TestAddSchemaDeltaIncluded(t *testing.T) {
+	tableNameMapping := map[string]model.NameAndExclude{
+		"public.t": model.NewNameAndExclude("t", nil, []string{"id", "name"}),
+	}
+
+	stream := model.NewCDCRecordStream()
+	stream.AddSchemaDelta(tableNameMapping, &protos.TableSchemaDelta{
+		SrcTableName: "public.t",
+		DstTableName: "t",
+		AddedColumns: []*protos.DeltaAddedColumn{{ColumnName: "blob", ColumnType: "bytes"}},
+	})
+	assert.Empty(t, stream.SchemaDeltas, "columns missing from the include list are left out")
+
+	stream.AddSchemaDelta(tableNameMapping, &protos.TableSchemaDelta{
+		SrcTableName: "public.t",
+		DstTableName: "t",
+		AddedColumns: []*protos.DeltaAddedColumn{{ColumnName: "name", ColumnType: "string"}},
+	})
+	assert.Len(t, stream.SchemaDeltas, 1)
+}
+
+func TestAddSchemaDeltaDeclaredColumns(t *testing.T) {
+	declared := model.NewNameAndExclude("t", nil, nil)
+	declared.DeclaredColumns = true
+	tableNameMapping := map[string]model.NameAndExclude{"public.t": declared}
+
+	stream := model.NewCDCRecordStream()
+	stream.AddSchemaDelta(tableNameMapping, &protos.TableSchemaDelta{
+		SrcTableName:   "public.t",
+		DstTableName:   "t",
+		AddedColumns:   []*protos.DeltaAddedColumn{{ColumnName: "name", ColumnType: "string"}},
+		DroppedColumns: []string{"email"},
+	})
+	assert.Empty(t, stream.SchemaDeltas, "tables with columns declared by a transformation script aren't changed")
+	assert.Empty(t, stream.PausingSchemaDeltas)
+}
+
+func TestAddSchemaDeltaPolicy(t *testing.T) {
+	tableNameMapping := map[string]model.NameAndExclude{
+		"public.t": model.NewNameAndExclude("t", []string{"secret"}, nil),
+	}
+	delta := &protos.TableSchemaDelta{
+		SrcTableName:   "public.t",
+		DstTableName:   "t",
+		AddedColumns:   []*protos.DeltaAddedColumn{{ColumnName: "name", ColumnType: "string"}},
+		DroppedColumns: []string{"gone", "secret"},
+		RenamedColumns: []*protos.DeltaRenamedColumn{{OldName: "a", NewName: "b", ColumnType: "int64"}},
+		AlteredColumns: []*protos.DeltaAlteredColumn{{ColumnName: "n", OldType: "int32", NewType: "int64"}},
+	}
+
+	ignored := model.NewCDCRecordStream()
+	ignored.AddSchemaDelta(tableNameMapping, delta)
+	require.Len(t, ignored.SchemaDeltas, 1)
+	assert.Empty(t, ignored.PausingSchemaDeltas)
+	assert.Equal(t, []*protos.DeltaAddedColumn{
+		{ColumnName: "name", ColumnType: "string"},
+		{ColumnName: "b", ColumnType: "int64"},
+	}, ignored.SchemaDeltas[0].AddedColumns, "renamed columns are added by default")
+	assert.Empty(t, ignored.SchemaDeltas[0].DroppedColumns)
+	assert.Empty(t, ignored.SchemaDeltas[0].AlteredColumns)
+
+	applied := model.NewCDCRecordStream()
+	applied.SetSchemaChangePolicy(protos.SchemaChangePolicy_SCHEMA_CHANGE_APPLY)
+	applied.AddSchemaDelta(tableNameMapping, delta)
+	require.Len(t, applied.SchemaDeltas, 1)
+	assert.Equal(t, []string{"gone"}, applied.SchemaDeltas[0].DroppedColumns, "excluded columns aren't dropped")
+	assert.Len(t, applied.SchemaDeltas[0].RenamedColumns, 1)
+	assert.Len(t, applied.SchemaDeltas[0].AlteredColumns, 1)
+
+	paused := model.NewCDCRecordStream()
+	paused.SetSchemaChangePolicy(protos.SchemaChangePolicy_SCHEMA_CHANGE_PAUSE)
+	paused.AddSchemaDelta(tableNameMapping, delta)
+	require.Len(t, paused.SchemaDeltas, 1)
+	assert.Len(t, paused.SchemaDeltas[0].AddedColumns, 1)
+	assert.Empty(t, paused.SchemaDeltas[0].RenamedColumns)
+	require.Len(t, paused.PausingSchemaDeltas, 1)
+	assert.Equal(t, []string{"gone"}, paused.PausingSchemaDeltas[0].DroppedColumns)
+	assert.Len(t, paused.PausingSchemaDeltas[0].RenamedColumns, 1)
+}
+
+func TestAddSchemaDeltaIncompatible(t *testing.T) {
+	tableNameMapping := map[string]model.NameAndExclude{
+		"public.t": model.NewNameAndExclude("t", nil, nil),
+	}
+	delta := &protos.TableSchemaDelta{
+		SrcTableName:    "public.t",
+		DstTableName:    "t",
+		DroppedColumns:  []string{"id", "gone"},
+		NarrowedColumns: []*protos.DeltaAlteredColumn{{ColumnName: "n", OldType: "int64", NewType: "int32"}},
+	}
+
+	stream := model.NewCDCRecordStream()
+	stream.SetSchemaChangePolicy(protos.SchemaChangePolicy_SCHEMA_CHANGE_APPLY)
+	stream.AddSchemaDelta(tableNameMapping, delta)
+	require.Len(t, stream.SchemaDeltas, 1)
+	assert.Equal(t, []string{"id", "gone"}, stream.SchemaDeltas[0].DroppedColumns)
+	assert.Empty(t, stream.SchemaDeltas[0].NarrowedColumns, "narrowed columns are never replayed")
+	assert.Empty(t, stream.IncompatibleSchemaDeltas)
+
+	paused := model.NewCDCRecordStream()
+	paused.SetSchemaChangePolicy(protos.SchemaChangePolicy_SCHEMA_CHANGE_APPLY)
+	paused.PauseOnIncompatibleSchemaChanges(map[string]*protos.TableSchema{
+		"t": {TableIdentifier: "t", PrimaryKeyColumns: []string{"id"}},
+	})
+	paused.AddSchemaDelta(tableNameMapping, delta)
+	require.Len(t, paused.SchemaDeltas, 1)
+	assert.Equal(t, []string{"gone"}, paused.SchemaDeltas[0].DroppedColumns)
+	require.Len(t, paused.IncompatibleSchemaDeltas, 1)
+	assert.Equal(t, []string{"id"}, paused.IncompatibleSchemaDeltas[0].DroppedColumns)
+	assert.Equal(t, delta.NarrowedColumns, paused.IncompatibleSchemaDeltas[0].NarrowedColumns)
+}
+
+type memorySpillQueue struct {
+	records []model.Record
+	closed  bool
+}
+
+func (q *memorySpillQueue) Push(record model.Record) error {
+	q.records = append(q.records, record)
+	return nil
+}
+
+func (q *memorySpillQueue) Pop() (model.Record, error) {
+	if len(q.records) == 0 {
+		return nil, errors.New("spill queue is empty")
+	}
+	record := q.records[0]
+	q.records = q.records[1:]
+	return record, nil
+}
+
+func (q *memorySpillQueue) Close() error {
+	q.closed = true
+	return nil
+}
+
+func TestSpillingCDCRecordStream(t *testing.T) {
+	t.Setenv("PEERDB_CDC_CHANNEL_BUFFER_SIZE", "2")
+
+	queue := &memorySpillQueue{}
+	stream := model.NewSpillingCDCRecordStream(context.Background(), queue)
+	for i := range 10 {
+		require.NoError(t, stream.AddRecord(&model.InsertRecord{CheckpointID: int64(i)}), "puller isn't blocked")
+	}
+	stream.UpdateLatestCheckpoint(9)
+	stream.Close()
+
+	var checkpoints []int64
+	for record := range stream.GetRecords() {
+		checkpoints = append(checkpoints, record.GetCheckpointID())
+	}
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, checkpoints)
+	lastCheckpoint, err := stream.GetLastCheckpoint()
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), lastCheckpoint)
+	assert.True(t, queue.closed)
+}
+
+func TestSpillingCDCRecordStreamCanceled(t *testing.T) {
+	t.Setenv("PEERDB_CDC_CHANNEL_BUFFER_SIZE", "1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := model.NewSpillingCDCRecordStream(ctx, &memorySpillQueue{})
+	for i := range 3 {
+		require.NoError(t, stream.AddRecord(&model.InsertRecord{CheckpointID: int64(i)}))
+	}
+	cancel()
+
+	for range stream.GetRecords() {
+		// read until the stream gives up on the spilled records
+	}
+	stream.Close()
+	_, err := stream.GetLastCheckpoint()
+	require.ErrorIs(t, err, context.Canceled, "a batch missing spilled records isn't committed")
+}
+
+func fanoutSource(t *testing.T, checkpoints ...int64) *model.CDCRecordStream {
+	t.Helper()
+	source := model.NewCDCRecordStream()
+	go func() {
+		for _, checkpoint := range checkpoints {
+			require.NoError(t, source.AddRecord(&model.InsertRecord{CheckpointID: checkpoint}))
+		}
+		source.SchemaDeltas = append(source.SchemaDeltas, &protos.TableSchemaDelta{SrcTableName: "public.t", DstTableName: "t"})
+		source.UpdateLatestCheckpoint(checkpoints[len(checkpoints)-1])
+		source.Close()
+	}()
+	return source
+}
+
+func fanoutCheckpoints(stream *model.CDCRecordStream) []int64 {
+	var checkpoints []int64
+	for record := range stream.GetRecords() {
+		checkpoints = append(checkpoints, record.GetCheckpointID())
+	}
+	return checkpoints
+}
+
+func TestCDCRecordStreamFanout(t *testing.T) {
+	source := fanoutSource(t, 1, 2, 3, 4, 5)
+	streams := source.Fanout([]context.Context{context.Background(), context.Background()}, []int64{0, 3})
+	require.Len(t, streams, 2)
+
+	done := make(chan []int64)
+	go func() { done <- fanoutCheckpoints(streams[1]) }()
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, fanoutCheckpoints(streams[0]))
+	assert.Equal(t, []int64{4, 5}, <-done, "records a destination already synced are left out of its stream")
+
+	for _, stream := range streams {
+		checkpoint, err := stream.GetLastCheckpoint()
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), checkpoint)
+		assert.Equal(t, source.SchemaDeltas, stream.SchemaDeltas)
+	}
+}
+
+func TestCDCRecordStreamFanoutCanceled(t *testing.T) {
+	// a consumer which stopped reading doesn't hold up the others once its context is canceled
+	t.Setenv("PEERDB_CDC_CHANNEL_BUFFER_SIZE", "1")
+	source := fanoutSource(t, 1, 2, 3, 4, 5)
+	failedCtx, cancelFailed := context.WithCancel(context.Background())
+	streams := source.Fanout([]context.Context{context.Background(), failedCtx}, []int64{0, 0})
+
+	first := <-streams[1].GetRecords()
+	assert.Equal(t, int64(1), first.GetCheckpointID())
+	cancelFailed()
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, fanoutCheckpoints(streams[0]))
+
+	// the failed stream is still closed, with what it was handed before the cancellation
+	assert.LessOrEqual(t, len(fanoutCheckpoints(streams[1])), 1)
+	checkpoint, err := streams[1].GetLastCheckpoint()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), checkpoint)
+	assert.Len(t, streams[1].SchemaDeltas, 1)
+}
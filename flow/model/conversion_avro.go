@@ -9,51 +9,46 @@ import (
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
 )
 
+// QRecordAvroConverter converts the records of a stream to Avro,
+// it's created once per stream so converting a record allocates no more than its map.
 type QRecordAvroConverter struct {
-	QRecord        []qvalue.QValue
-	TargetDWH      qvalue.QDWHType
-	NullableFields map[string]struct{}
-	ColNames       []string
-	logger         log.Logger
+	converter *qvalue.QValueAvroConverter
+	colNames  []string
+	nullable  []bool
 }
 
 func NewQRecordAvroConverter(
-	q []qvalue.QValue,
 	targetDWH qvalue.QDWHType,
 	nullableFields map[string]struct{},
 	colNames []string,
 	logger log.Logger,
 ) *QRecordAvroConverter {
+	nullable := make([]bool, len(colNames))
+	for idx, colName := range colNames {
+		_, nullable[idx] = nullableFields[colName]
+	}
 	return &QRecordAvroConverter{
-		QRecord:        q,
-		TargetDWH:      targetDWH,
-		NullableFields: nullableFields,
-		ColNames:       colNames,
-		logger:         logger,
+		converter: qvalue.NewQValueAvroConverter(qvalue.QValue{}, targetDWH, false, logger),
+		colNames:  colNames,
+		nullable:  nullable,
 	}
 }
 
-func (qac *QRecordAvroConverter) Convert() (map[string]interface{}, error) {
-	m := make(map[string]interface{}, len(qac.QRecord))
-
-	for idx, val := range qac.QRecord {
-		key := qac.ColNames[idx]
-		_, nullable := qac.NullableFields[key]
+func (qac *QRecordAvroConverter) Convert(qrecord []qvalue.QValue) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(qrecord))
 
-		avroConverter := qvalue.NewQValueAvroConverter(
-			val,
-			qac.TargetDWH,
-			nullable,
-			qac.logger,
-		)
+	for idx, val := range qrecord {
+		qac.converter.Value = val
+		qac.converter.Nullable = qac.nullable[idx]
 
-		avroVal, err := avroConverter.ToAvroValue()
+		avroVal, err := qac.converter.ToAvroValue()
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert QValue to Avro-compatible value: %w", err)
 		}
 
-		m[key] = avroVal
+		m[qac.colNames[idx]] = avroVal
 	}
+	qac.converter.Value = qvalue.QValue{}
 
 	return m, nil
 }
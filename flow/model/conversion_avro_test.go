@@ -0,0 +1,66 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestQRecordAvroConverter(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	converter := model.NewQRecordAvroConverter(qvalue.QDWHTypeS3, map[string]struct{}{"name": {}, "updated_at": {}},
+		[]string{"id", "name", "updated_at"}, nil)
+
+	avroMap, err := converter.Convert([]qvalue.QValue{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(1)),
+		qvalue.New(qvalue.QValueKindString, "a"),
+		qvalue.New(qvalue.QValueKindTimestamp, ts),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":         int64(1),
+		"name":       goavro.Union("string", "a"),
+		"updated_at": goavro.Union("long.timestamp-micros", ts.UnixMicro()),
+	}, avroMap)
+
+	// the converter is reused for the records of a stream
+	avroMap, err = converter.Convert([]qvalue.QValue{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(2)),
+		qvalue.QValue{Kind: qvalue.QValueKindString},
+		qvalue.QValue{Kind: qvalue.QValueKindTimestamp},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": int64(2), "name": nil, "updated_at": nil}, avroMap)
+}
+
+func BenchmarkQRecordAvroConverter(b *testing.B) {
+	colNames := []string{"id", "amount", "active", "name", "created_at", "updated_at"}
+	nullableFields := make(map[string]struct{}, len(colNames))
+	for _, colName := range colNames[1:] {
+		nullableFields[colName] = struct{}{}
+	}
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	record := []qvalue.QValue{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(123456789)),
+		qvalue.New(qvalue.QValueKindFloat64, 1234.5),
+		qvalue.New(qvalue.QValueKindBoolean, true),
+		qvalue.New(qvalue.QValueKindString, "benchmark"),
+		qvalue.New(qvalue.QValueKindTimestampTZ, ts),
+		qvalue.New(qvalue.QValueKindDate, ts),
+	}
+
+	converter := model.NewQRecordAvroConverter(qvalue.QDWHTypeS3, nullableFields, colNames, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := converter.Convert(record); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
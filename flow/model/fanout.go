@@ -0,0 +1,37 @@
+package model
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// FanoutFlowJobName is the flow job name a fan-out destination of a CDC mirror keeps its raw table
+// and sync and normalize progress under.
+func FanoutFlowJobName(flowJobName string, peerName string) string {
+	return flowJobName + "_fanout_" + peerName
+}
+
+// FanoutConfigs returns a config per fan-out destination of a CDC mirror, which sets up, syncs and normalizes
+// that destination like the mirror's own config does its destination. The change stream is still read
+// through the mirror's replication slot and publication.
+func FanoutConfigs(cfg *protos.FlowConnectionConfigs) []*protos.FlowConnectionConfigs {
+	if len(cfg.FanoutDestinations) == 0 {
+		return nil
+	}
+	configs := make([]*protos.FlowConnectionConfigs, 0, len(cfg.FanoutDestinations))
+	for _, destination := range cfg.FanoutDestinations {
+		fanoutCfg := proto.Clone(cfg).(*protos.FlowConnectionConfigs)
+		fanoutCfg.FlowJobName = FanoutFlowJobName(cfg.FlowJobName, destination.Name)
+		fanoutCfg.Destination = destination
+		fanoutCfg.FanoutDestinations = nil
+		if fanoutCfg.ReplicationSlotName == "" {
+			fanoutCfg.ReplicationSlotName = "peerflow_slot_" + cfg.FlowJobName
+		}
+		if fanoutCfg.PublicationName == "" {
+			fanoutCfg.PublicationName = "peerflow_pub_" + cfg.FlowJobName
+		}
+		configs = append(configs, fanoutCfg)
+	}
+	return configs
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/geo"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// GeoFormatApplies returns whether peers of dbType have no geospatial types,
+// so geometries are replicated to them as text in the mirror's geo format.
+func GeoFormatApplies(dbType protos.DBType) bool {
+	switch dbType {
+	case protos.DBType_CLICKHOUSE, protos.DBType_S3, protos.DBType_EVENTHUB, protos.DBType_EVENTHUB_GROUP:
+		return true
+	default:
+		return false
+	}
+}
+
+func isGeoKind(kind qvalue.QValueKind) bool {
+	return kind == qvalue.QValueKindGeography || kind == qvalue.QValueKindGeometry || kind == qvalue.QValueKindPoint
+}
+
+// formatGeo converts a geospatial value, which is EWKT as read from the source, to GeoJSON.
+// Other values, and null geometries, are returned as is.
+func formatGeo(value qvalue.QValue) (qvalue.QValue, error) {
+	wkt, ok := value.Value().(string)
+	if !isGeoKind(value.Kind) || !ok {
+		return value, nil
+	}
+	geoJSON, err := geo.GeoToGeoJSON(wkt)
+	if err != nil {
+		return qvalue.QValue{}, fmt.Errorf("failed to convert geometry to GeoJSON: %w", err)
+	}
+	return qvalue.New(value.Kind, geoJSON), nil
+}
+
+// formatGeoItems returns a copy of items with geometries converted to GeoJSON, or items itself when it has none.
+func formatGeoItems(items *RecordItems) (*RecordItems, error) {
+	formatted := items
+	for idx, value := range items.Values {
+		if _, ok := value.Value().(string); !ok || !isGeoKind(value.Kind) {
+			continue
+		}
+		geoValue, err := formatGeo(value)
+		if err != nil {
+			return nil, err
+		}
+		if formatted == items {
+			formatted = &RecordItems{
+				ColToValIdx: items.ColToValIdx,
+				Values:      append([]qvalue.QValue(nil), items.Values...),
+			}
+		}
+		formatted.Values[idx] = geoValue
+	}
+	return formatted, nil
+}
+
+// GeoFormatTransform returns a record transform converting the geometries of CDC records to GeoJSON after next,
+// when geometries are replicated to peers of dbType in that format. Otherwise next is returned as is.
+func GeoFormatTransform(dbType protos.DBType, format protos.GeoFormat, next RecordTransform) RecordTransform {
+	if !GeoFormatApplies(dbType) || format != protos.GeoFormat_GEO_FORMAT_GEOJSON {
+		return next
+	}
+	return func(record Record) (Record, error) {
+		if next != nil {
+			var err error
+			record, err = next(record)
+			if err != nil || record == nil {
+				return record, err
+			}
+		}
+		var err error
+		switch r := record.(type) {
+		case *InsertRecord:
+			formatted := *r
+			formatted.Items, err = formatGeoItems(r.Items)
+			return &formatted, err
+		case *UpdateRecord:
+			formatted := *r
+			if formatted.OldItems, err = formatGeoItems(r.OldItems); err != nil {
+				return nil, err
+			}
+			formatted.NewItems, err = formatGeoItems(r.NewItems)
+			return &formatted, err
+		case *DeleteRecord:
+			formatted := *r
+			formatted.Items, err = formatGeoItems(r.Items)
+			return &formatted, err
+		default:
+			return record, nil
+		}
+	}
+}
+
+// GeoFormatStream returns a stream handing on the records of a QRep stream with geometries converted to GeoJSON,
+// when geometries are replicated to peers of dbType in that format. Otherwise the stream is returned as is.
+func GeoFormatStream(
+	ctx context.Context,
+	stream *QRecordStream,
+	buffer int,
+	dbType protos.DBType,
+	format protos.GeoFormat,
+) *QRecordStream {
+	if !GeoFormatApplies(dbType) || format != protos.GeoFormat_GEO_FORMAT_GEOJSON {
+		return stream
+	}
+	return stream.Mapped(ctx, buffer, func(schema *QRecordSchema) *QRecordSchema {
+		return schema
+	}, func(record []qvalue.QValue) ([]qvalue.QValue, error) {
+		for i, value := range record {
+			geoValue, err := formatGeo(value)
+			if err != nil {
+				return nil, err
+			}
+			record[i] = geoValue
+		}
+		return record, nil
+	})
+}
@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestGeoFormatTransform(t *testing.T) {
+	assert.Nil(t, model.GeoFormatTransform(protos.DBType_SNOWFLAKE, protos.GeoFormat_GEO_FORMAT_GEOJSON, nil),
+		"Snowflake has geospatial types")
+	assert.Nil(t, model.GeoFormatTransform(protos.DBType_CLICKHOUSE, protos.GeoFormat_GEO_FORMAT_WKT, nil),
+		"geometries are read as WKT")
+
+	items := model.NewRecordItemWithData([]string{"id", "location", "area"}, []qvalue.QValue{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(1)),
+		qvalue.New(qvalue.QValueKindGeography, "SRID=4326;POINT (1 2)"),
+		qvalue.QValue{Kind: qvalue.QValueKindGeometry},
+	})
+	transform := model.GeoFormatTransform(protos.DBType_CLICKHOUSE, protos.GeoFormat_GEO_FORMAT_GEOJSON, nil)
+	rec, err := transform(&model.InsertRecord{DestinationTableName: "places", Items: items})
+	require.NoError(t, err)
+	formatted := rec.(*model.InsertRecord).Items
+
+	assert.Equal(t, int64(1), formatted.GetColumnValue("id").Value())
+	location := formatted.GetColumnValue("location")
+	assert.Equal(t, qvalue.QValueKindGeography, location.Kind)
+	assert.JSONEq(t, `{"type": "Point", "coordinates": [1, 2]}`, location.Value().(string))
+	assert.Nil(t, formatted.GetColumnValue("area").Value(), "null geometries stay null")
+	// the record passed in isn't modified
+	assert.Equal(t, "SRID=4326;POINT (1 2)", items.GetColumnValue("location").Value())
+
+	dropAll := func(model.Record) (model.Record, error) { return nil, nil }
+	transform = model.GeoFormatTransform(protos.DBType_S3, protos.GeoFormat_GEO_FORMAT_GEOJSON, dropAll)
+	rec, err = transform(&model.InsertRecord{DestinationTableName: "places", Items: items})
+	require.NoError(t, err)
+	assert.Nil(t, rec, "records dropped by the next transform stay dropped")
+}
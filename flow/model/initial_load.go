@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// InitialLoadETA extrapolates when an initial load finishes from how long copying the rows copied so far took,
+// or the partitions completed if the rows couldn't be estimated or outgrew the estimate.
+// Returns nil until there is progress to go by, and once every partition completed.
+func InitialLoadETA(progress *protos.InitialLoadProgress, now time.Time) *timestamppb.Timestamp {
+	if progress.StartTime == nil || progress.PartitionsTotal == 0 || progress.PartitionsCompleted >= progress.PartitionsTotal {
+		return nil
+	}
+	done, total := float64(progress.PartitionsCompleted), float64(progress.PartitionsTotal)
+	if progress.RowsCopied > 0 && progress.RowsCopied < progress.EstimatedRows {
+		done, total = float64(progress.RowsCopied), float64(progress.EstimatedRows)
+	}
+	elapsed := now.Sub(progress.StartTime.AsTime())
+	if done == 0 || elapsed <= 0 {
+		return nil
+	}
+	return timestamppb.New(now.Add(time.Duration(float64(elapsed) * (total - done) / done)))
+}
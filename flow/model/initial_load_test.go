@@ -0,0 +1,44 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+)
+
+func TestInitialLoadETA(t *testing.T) {
+	start := time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+	progress := &protos.InitialLoadProgress{
+		StartTime:       timestamppb.New(start),
+		EstimatedRows:   4_000_000,
+		PartitionsTotal: 40,
+	}
+	assert.Nil(t, model.InitialLoadETA(progress, now), "nothing copied yet")
+
+	progress.RowsCopied = 1_000_000
+	progress.PartitionsCompleted = 20
+	eta := model.InitialLoadETA(progress, now)
+	require.NotNil(t, eta)
+	assert.Equal(t, now.Add(3*time.Hour), eta.AsTime(), "rows are extrapolated over partitions")
+
+	progress.RowsCopied = 5_000_000
+	eta = model.InitialLoadETA(progress, now)
+	require.NotNil(t, eta)
+	assert.Equal(t, now.Add(time.Hour), eta.AsTime(), "partitions are extrapolated once rows outgrow the estimate")
+
+	progress.EstimatedRows = 0
+	progress.RowsCopied = 0
+	eta = model.InitialLoadETA(progress, now)
+	require.NotNil(t, eta)
+	assert.Equal(t, now.Add(time.Hour), eta.AsTime(), "partitions are extrapolated without an estimate")
+
+	progress.PartitionsCompleted = 40
+	assert.Nil(t, model.InitialLoadETA(progress, now), "done")
+}
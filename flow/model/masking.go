@@ -0,0 +1,231 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// RedactedValue replaces the values of redacted columns.
+const RedactedValue = "[REDACTED]"
+
+// ColumnMasks holds the masks of a table's columns by column name, nil when none are masked.
+type ColumnMasks map[string]*protos.ColumnMask
+
+func NewColumnMasks(masks []*protos.ColumnMask) ColumnMasks {
+	if len(masks) == 0 {
+		return nil
+	}
+	columnMasks := make(ColumnMasks, len(masks))
+	for _, mask := range masks {
+		if mask.Policy != protos.ColumnMaskPolicy_COLUMN_MASK_NONE {
+			columnMasks[mask.Column] = mask
+		}
+	}
+	return columnMasks
+}
+
+// MaskedKind returns the kind of a column on the destination once masked.
+func MaskedKind(policy protos.ColumnMaskPolicy, kind qvalue.QValueKind) qvalue.QValueKind {
+	switch policy {
+	case protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256, protos.ColumnMaskPolicy_COLUMN_MASK_REDACT,
+		protos.ColumnMaskPolicy_COLUMN_MASK_TRUNCATE:
+		return qvalue.QValueKindString
+	default:
+		return kind
+	}
+}
+
+// Mask applies the mask of a column to one of its values, values of columns without a mask are returned as is.
+// Null values stay null.
+func (m ColumnMasks) Mask(column string, value qvalue.QValue) qvalue.QValue {
+	mask, ok := m[column]
+	if !ok {
+		return value
+	}
+	if mask.Policy == protos.ColumnMaskPolicy_COLUMN_MASK_NULLIFY {
+		return qvalue.QValue{Kind: value.Kind}
+	}
+
+	kind := MaskedKind(mask.Policy, value.Kind)
+	str, ok := maskableString(value.Value())
+	if !ok {
+		return qvalue.QValue{Kind: kind}
+	}
+	switch mask.Policy {
+	case protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256:
+		hash := sha256.Sum256([]byte(str))
+		return qvalue.New(kind, hex.EncodeToString(hash[:]))
+	case protos.ColumnMaskPolicy_COLUMN_MASK_REDACT:
+		return qvalue.New(kind, RedactedValue)
+	case protos.ColumnMaskPolicy_COLUMN_MASK_TRUNCATE:
+		if runes := []rune(str); len(runes) > int(mask.Length) {
+			str = string(runes[:mask.Length])
+		}
+		return qvalue.New(kind, str)
+	default:
+		return value
+	}
+}
+
+// MaskItems returns a copy of items with the masks applied, or items itself when no column of it is masked.
+func (m ColumnMasks) MaskItems(items *RecordItems) *RecordItems {
+	masked := items
+	for column, mask := range m {
+		idx, ok := items.ColToValIdx[column]
+		if !ok {
+			continue
+		}
+		if masked == items {
+			masked = &RecordItems{
+				ColToValIdx: items.ColToValIdx,
+				Values:      append([]qvalue.QValue(nil), items.Values...),
+			}
+		}
+		masked.Values[idx] = m.Mask(mask.Column, items.Values[idx])
+	}
+	return masked
+}
+
+// MaskRecord returns a copy of a CDC record with the masks applied.
+func (m ColumnMasks) MaskRecord(record Record) Record {
+	if len(m) == 0 {
+		return record
+	}
+	switch r := record.(type) {
+	case *InsertRecord:
+		masked := *r
+		masked.Items = m.MaskItems(r.Items)
+		return &masked
+	case *UpdateRecord:
+		masked := *r
+		masked.OldItems = m.MaskItems(r.OldItems)
+		masked.NewItems = m.MaskItems(r.NewItems)
+		return &masked
+	case *DeleteRecord:
+		masked := *r
+		masked.Items = m.MaskItems(r.Items)
+		return &masked
+	default:
+		return record
+	}
+}
+
+// MaskSchema returns a copy of a schema with the kinds of masked fields changed to what they are masked to.
+func (m ColumnMasks) MaskSchema(schema *QRecordSchema) *QRecordSchema {
+	fields := make([]QField, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		if mask, ok := m[field.Name]; ok {
+			if kind := MaskedKind(mask.Policy, field.Type); kind != field.Type {
+				field = QField{Name: field.Name, Type: kind, Nullable: field.Nullable}
+			}
+			if mask.Policy == protos.ColumnMaskPolicy_COLUMN_MASK_NULLIFY {
+				field.Nullable = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return NewQRecordSchema(fields)
+}
+
+// MaskTableSchema returns a copy of a CDC table schema with the types of masked columns changed to what they are
+// masked to. Rows are matched on their primary key on the destination, so it can't be masked.
+func (m ColumnMasks) MaskTableSchema(schema *protos.TableSchema) (*protos.TableSchema, error) {
+	if len(m) == 0 {
+		return schema, nil
+	}
+	if !schema.SyntheticPrimaryKey {
+		for _, column := range schema.PrimaryKeyColumns {
+			if _, ok := m[column]; ok {
+				return nil, fmt.Errorf("primary key column %s of table %s can't be masked", column, schema.TableIdentifier)
+			}
+		}
+	}
+	columns := make([]*protos.FieldDescription, 0, len(schema.Columns))
+	for _, column := range schema.Columns {
+		if mask, ok := m[column.Name]; ok {
+			if kind := MaskedKind(mask.Policy, qvalue.QValueKind(column.Type)); string(kind) != column.Type {
+				column = &protos.FieldDescription{Name: column.Name, Type: string(kind), TypeModifier: -1}
+			}
+		}
+		columns = append(columns, column)
+	}
+	return &protos.TableSchema{
+		TableIdentifier:       schema.TableIdentifier,
+		PrimaryKeyColumns:     schema.PrimaryKeyColumns,
+		IsReplicaIdentityFull: schema.IsReplicaIdentityFull,
+		Columns:               columns,
+		SyntheticPrimaryKey:   schema.SyntheticPrimaryKey,
+	}, nil
+}
+
+// MaskStream returns a stream handing on the records of a QRep stream with the masks applied.
+func (m ColumnMasks) MaskStream(ctx context.Context, stream *QRecordStream, buffer int) *QRecordStream {
+	if len(m) == 0 {
+		return stream
+	}
+	var maskedFields []int
+	var maskedColumns []string
+	return stream.Mapped(ctx, buffer, func(schema *QRecordSchema) *QRecordSchema {
+		for i, field := range schema.Fields {
+			if _, ok := m[field.Name]; ok {
+				maskedFields = append(maskedFields, i)
+				maskedColumns = append(maskedColumns, field.Name)
+			}
+		}
+		return m.MaskSchema(schema)
+	}, func(record []qvalue.QValue) ([]qvalue.QValue, error) {
+		for i, field := range maskedFields {
+			record[field] = m.Mask(maskedColumns[i], record[field])
+		}
+		return record, nil
+	})
+}
+
+// maskableString formats a value for hashing and truncating, false for nulls
+func maskableString(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case [16]byte:
+		return uuid.UUID(v).String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int16:
+		return strconv.FormatInt(int64(v), 10), true
+	case int32:
+		return strconv.FormatInt(int64(v), 10), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case *big.Rat:
+		if v == nil {
+			return "", false
+		}
+		if v.IsInt() {
+			return v.Num().String(), true
+		}
+		return strings.TrimRight(v.FloatString(100), "0"), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
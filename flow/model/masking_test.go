@@ -0,0 +1,104 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func testMasks() model.ColumnMasks {
+	return model.NewColumnMasks([]*protos.ColumnMask{
+		{Column: "email", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256},
+		{Column: "ssn", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_REDACT},
+		{Column: "zip", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_TRUNCATE, Length: 3},
+		{Column: "age", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_NULLIFY},
+	})
+}
+
+func TestMaskRecord(t *testing.T) {
+	items := model.NewRecordItemWithData([]string{"id", "email", "ssn", "zip", "age"}, []qvalue.QValue{
+		qvalue.NewInt64(qvalue.QValueKindInt64, int64(1)),
+		qvalue.New(qvalue.QValueKindString, "a@example.com"),
+		qvalue.QValue{Kind: qvalue.QValueKindString},
+		qvalue.NewInt32(qvalue.QValueKindInt32, int32(94107)),
+		qvalue.NewInt16(qvalue.QValueKindInt16, int16(42)),
+	})
+	rec := testMasks().MaskRecord(&model.InsertRecord{DestinationTableName: "customers", Items: items})
+	masked := rec.(*model.InsertRecord).Items
+
+	assert.Equal(t, int64(1), masked.GetColumnValue("id").Value())
+	assert.Equal(t, qvalue.New(
+		qvalue.QValueKindString,
+		"08168cd80dfd534ab0f10af10f1303fe00af2d43ab5c1432360d137f8197e17a",
+	), masked.GetColumnValue("email"))
+	assert.Nil(t, masked.GetColumnValue("ssn").Value(), "null values stay null")
+	assert.Equal(t, qvalue.New(qvalue.QValueKindString, "941"), masked.GetColumnValue("zip"))
+	assert.Equal(t, qvalue.QValue{Kind: qvalue.QValueKindInt16}, masked.GetColumnValue("age"))
+	// the record passed in isn't modified
+	assert.Equal(t, "a@example.com", items.GetColumnValue("email").Value())
+
+	redacted := testMasks().Mask("ssn", qvalue.New(qvalue.QValueKindString, "123-45-6789"))
+	assert.Equal(t, model.RedactedValue, redacted.Value())
+}
+
+func TestMaskTableSchema(t *testing.T) {
+	schema := &protos.TableSchema{
+		TableIdentifier:   "public.customers",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+			{Name: "zip", Type: string(qvalue.QValueKindInt32), TypeModifier: -1},
+			{Name: "age", Type: string(qvalue.QValueKindInt16), TypeModifier: -1},
+		},
+	}
+	masked, err := testMasks().MaskTableSchema(schema)
+	require.NoError(t, err)
+	assert.Equal(t, string(qvalue.QValueKindString), masked.Columns[1].Type)
+	assert.Equal(t, string(qvalue.QValueKindInt16), masked.Columns[2].Type)
+	assert.Equal(t, string(qvalue.QValueKindInt32), schema.Columns[1].Type)
+
+	_, err = model.NewColumnMasks([]*protos.ColumnMask{
+		{Column: "id", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256},
+	}).MaskTableSchema(schema)
+	require.Error(t, err, "primary keys can't be masked")
+}
+
+func TestMaskStream(t *testing.T) {
+	stream := model.NewQRecordStream(2)
+	require.NoError(t, stream.SetSchema(model.NewQRecordSchema([]model.QField{
+		{Name: "id", Type: qvalue.QValueKindInt64},
+		{Name: "ssn", Type: qvalue.QValueKindString, Nullable: true},
+		{Name: "age", Type: qvalue.QValueKindInt16},
+	})))
+	go func() {
+		for i := range 3 {
+			stream.Records <- model.QRecordOrError{Record: []qvalue.QValue{
+				qvalue.NewInt64(qvalue.QValueKindInt64, int64(i)),
+				qvalue.New(qvalue.QValueKindString, "123-45-6789"),
+				qvalue.NewInt16(qvalue.QValueKindInt16, int16(30)),
+			}}
+		}
+		close(stream.Records)
+	}()
+
+	maskedStream := testMasks().MaskStream(context.Background(), stream, 2)
+	schema, err := maskedStream.Schema()
+	require.NoError(t, err)
+	assert.True(t, schema.Fields[2].Nullable, "nullified columns are nullable")
+
+	var records int
+	for record := range maskedStream.Records {
+		require.NoError(t, record.Err)
+		assert.Equal(t, int64(records), record.Record[0].Value())
+		assert.Equal(t, model.RedactedValue, record.Record[1].Value())
+		assert.Nil(t, record.Record[2].Value())
+		records += 1
+	}
+	assert.Equal(t, 3, records)
+}
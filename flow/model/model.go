@@ -1,22 +1,69 @@
 package model
 
 import (
+	"slices"
 	"time"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/rowfilter"
 )
 
 type NameAndExclude struct {
 	Name    string
 	Exclude map[string]struct{}
+	// only these columns are replicated when not nil
+	Include map[string]struct{}
+	// only matching rows are replicated when not nil
+	RowFilter *rowfilter.Filter
+	Masks     ColumnMasks
+	// the mirror's transformation script declares the columns of the destination table,
+	// so schema changes of the source table aren't replayed on it
+	DeclaredColumns bool
 }
 
-func NewNameAndExclude(name string, exclude []string) NameAndExclude {
+func NewNameAndExclude(name string, exclude []string, include []string) NameAndExclude {
 	exset := make(map[string]struct{}, len(exclude))
 	for _, col := range exclude {
 		exset[col] = struct{}{}
 	}
-	return NameAndExclude{Name: name, Exclude: exset}
+	var inset map[string]struct{}
+	if len(include) != 0 {
+		inset = make(map[string]struct{}, len(include))
+		for _, col := range include {
+			inset[col] = struct{}{}
+		}
+	}
+	return NameAndExclude{Name: name, Exclude: exset, Include: inset}
+}
+
+// Filtered returns whether any columns are left out of the table.
+func (n NameAndExclude) Filtered() bool {
+	return len(n.Exclude) != 0 || n.Include != nil
+}
+
+// Excluded returns whether a column is left out of the table.
+func (n NameAndExclude) Excluded(column string) bool {
+	if _, excluded := n.Exclude[column]; excluded {
+		return true
+	}
+	if n.Include != nil {
+		_, included := n.Include[column]
+		return !included
+	}
+	return false
+}
+
+// ColumnExcluded returns whether a table mapping leaves a column out of its destination table.
+func ColumnExcluded(mapping *protos.TableMapping, column string) bool {
+	if slices.Contains(mapping.Exclude, column) {
+		return true
+	}
+	return len(mapping.Include) != 0 && !slices.Contains(mapping.Include, column)
+}
+
+// ColumnsFiltered returns whether a table mapping leaves any columns out of its destination table.
+func ColumnsFiltered(mapping *protos.TableMapping) bool {
+	return len(mapping.Exclude) != 0 || len(mapping.Include) != 0
 }
 
 type PullRecordsRequest struct {
@@ -26,6 +73,8 @@ type PullRecordsRequest struct {
 	LastOffset int64
 	// MaxBatchSize is the max number of records to fetch.
 	MaxBatchSize uint32
+	// MaxBatchBytes bounds the estimated size of the fetched records, 0 doesn't bound it.
+	MaxBatchBytes uint64
 	// IdleTimeout is the timeout to wait for new records.
 	IdleTimeout time.Duration
 	// relId to name Mapping
@@ -38,12 +87,19 @@ type PullRecordsRequest struct {
 	OverridePublicationName string
 	// override replication slot name
 	OverrideReplicationSlotName string
+	// whether the publication leaves excluded columns out with column lists, which are updated for added columns
+	PublicationColumnLists bool
 	// for supporting schema changes
 	RelationMessageMapping RelationMessageMapping
 	// record batch for pushing changes into
 	RecordStream *CDCRecordStream
+	// rewrites records before they are pushed, dropping those it returns nil for
+	Transform RecordTransform
 }
 
+// RecordTransform rewrites a record without modifying it, returning nil to drop it.
+type RecordTransform func(Record) (Record, error)
+
 type Record interface {
 	// GetCheckpointID returns the ID of the record.
 	GetCheckpointID() int64
@@ -53,6 +109,14 @@ type Record interface {
 	GetItems() *RecordItems
 }
 
+// EstimatedRecordSize approximates the bytes a record adds to a batch, updates carrying both their old and new values.
+func EstimatedRecordSize(rec Record) int {
+	if r, ok := rec.(*UpdateRecord); ok {
+		return r.OldItems.EstimatedSize() + r.NewItems.EstimatedSize()
+	}
+	return rec.GetItems().EstimatedSize()
+}
+
 type ToJSONOptions struct {
 	UnnestColumns map[string]struct{}
 	HStoreAsJSON  bool
@@ -165,6 +229,8 @@ type SyncRecordsRequest struct {
 	TableMappings []*protos.TableMapping
 	// Staging path for AVRO files in CDC
 	StagingPath string
+	// session overrides for Snowflake destinations
+	SnowflakeSession *protos.SnowflakeSessionSettings
 }
 
 type NormalizeRecordsRequest struct {
@@ -174,6 +240,10 @@ type NormalizeRecordsRequest struct {
 	SoftDeleteColName      string
 	SyncedAtColName        string
 	TableNameSchemaMapping map[string]*protos.TableSchema
+	// session overrides for Snowflake destinations
+	SnowflakeSession *protos.SnowflakeSessionSettings
+	// what happens to numerics which don't fit the decimal columns they are normalized into
+	NumericOverflowPolicy protos.NumericOverflowPolicy
 }
 
 type SyncResponse struct {
@@ -187,14 +257,46 @@ type SyncResponse struct {
 	TableNameRowsMapping map[string]uint32
 	// to be carried to parent workflow
 	TableSchemaDeltas []*protos.TableSchemaDelta
+	// schema changes the mirror pauses on, which weren't replayed on the destination
+	PausingSchemaDeltas []*protos.TableSchemaDelta
+	// schema changes destinations can't follow, the mirror pauses on them until they're approved
+	IncompatibleSchemaDeltas []*protos.TableSchemaDelta
 	// to be stored in state for future PullFlows
 	RelationMessageMapping RelationMessageMapping
+	// replication lag of the source after this sync, only measured when catch-up mode is enabled
+	SourceLagMB float32
+	// batch synced to each fan-out destination of the mirror, by the flow job name of the destination
+	FanoutSyncBatchIDs map[string]int64
+	// fan-out destination peers which failed to sync the batch, the next batch reads the slot from where they are
+	FailedFanoutDestinations []string
 }
 
 type NormalizePayload struct {
 	Done                   bool
 	SyncBatchID            int64
 	TableNameSchemaMapping map[string]*protos.TableSchema
+	// normalize the pending batches regardless of the mirror's normalize schedule
+	Trigger bool
+}
+
+type LeaseKind string
+
+const (
+	// snapshotting the tables of a CDC mirror, limited across all peers
+	LeaseInitialLoad LeaseKind = "initial-load"
+	// normalizing a batch, limited per destination peer
+	LeaseNormalize LeaseKind = "normalize"
+)
+
+// LeaseRequest asks the global scheduler for a lease, the same request releases it once granted.
+type LeaseRequest struct {
+	// unique per request, repeating a request doesn't queue it twice
+	ID   string
+	Kind LeaseKind
+	Peer string
+	// the workflow run the lease is granted to
+	WorkflowID string
+	RunID      string
 }
 
 type NormalizeResponse struct {
@@ -204,6 +306,21 @@ type NormalizeResponse struct {
 	EndBatchID   int64
 }
 
+// NormalizeSimulation holds the statements NormalizeRecords would run for a table, without running them.
+type NormalizeSimulation struct {
+	StartBatchID int64
+	EndBatchID   int64
+	Statements   []NormalizeStatement
+}
+
+type NormalizeStatement struct {
+	SQL string
+	// bind arguments, for connectors that run normalize statements with placeholders
+	Args []any
+	// destination's EXPLAIN output, only filled in when requested
+	Plan string
+}
+
 // being clever and passing the delta back as a regular record instead of heavy CDC refactoring.
 type RelationRecord struct {
 	CheckpointID     int64                    `json:"checkpointId"`
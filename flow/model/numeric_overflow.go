@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/numeric"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// NumericOverflowDWH returns the destination whose decimal limits numerics replicated to peers of dbType are held to,
+// false for peers numerics are replicated to as they are, like Postgres.
+func NumericOverflowDWH(dbType protos.DBType) (qvalue.QDWHType, bool) {
+	switch dbType {
+	case protos.DBType_SNOWFLAKE:
+		return qvalue.QDWHTypeSnowflake, true
+	case protos.DBType_BIGQUERY:
+		return qvalue.QDWHTypeBigQuery, true
+	case protos.DBType_CLICKHOUSE:
+		return qvalue.QDWHTypeClickhouse, true
+	case protos.DBType_S3:
+		return qvalue.QDWHTypeS3, true
+	default:
+		return 0, false
+	}
+}
+
+// NumericOverflowTableSchema returns a copy of a CDC table schema with the numeric columns which exceed the limits
+// of peers of dbType changed to strings, when policy is NUMERIC_OVERFLOW_STRING. Otherwise the schema is returned as is.
+func NumericOverflowTableSchema(
+	schema *protos.TableSchema,
+	dbType protos.DBType,
+	policy protos.NumericOverflowPolicy,
+) *protos.TableSchema {
+	dwh, ok := NumericOverflowDWH(dbType)
+	if !ok || policy != protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING {
+		return schema
+	}
+	columns := make([]*protos.FieldDescription, 0, len(schema.Columns))
+	changed := false
+	for _, column := range schema.Columns {
+		if column.Type == string(qvalue.QValueKindNumeric) {
+			precision, scale := numeric.ParseNumericTypmod(column.TypeModifier)
+			if column.TypeModifier == -1 || qvalue.NumericExceedsDWH(precision, scale, dwh) {
+				column = &protos.FieldDescription{Name: column.Name, Type: string(qvalue.QValueKindString), TypeModifier: -1}
+				changed = true
+			}
+		}
+		columns = append(columns, column)
+	}
+	if !changed {
+		return schema
+	}
+	return &protos.TableSchema{
+		TableIdentifier:       schema.TableIdentifier,
+		PrimaryKeyColumns:     schema.PrimaryKeyColumns,
+		IsReplicaIdentityFull: schema.IsReplicaIdentityFull,
+		Columns:               columns,
+		SyntheticPrimaryKey:   schema.SyntheticPrimaryKey,
+	}
+}
+
+// NumericOverflowStream returns a stream handing on the records of a QRep stream to peers of dbType with numerics
+// of fields exceeding their limits handled by policy: replicated as exact strings, nulled if they don't fit
+// the default precision and scale the fields are replicated with, or failing the stream if they don't.
+func NumericOverflowStream(
+	ctx context.Context,
+	stream *QRecordStream,
+	buffer int,
+	dbType protos.DBType,
+	policy protos.NumericOverflowPolicy,
+) *QRecordStream {
+	dwh, ok := NumericOverflowDWH(dbType)
+	if !ok {
+		return stream
+	}
+	// fields exceeding the limits are replicated with the default precision and scale
+	precision, scale := qvalue.DetermineNumericSettingForDWH(0, 0, dwh)
+	var overflowFields []int
+	return stream.Mapped(ctx, buffer, func(schema *QRecordSchema) *QRecordSchema {
+		fields := make([]QField, 0, len(schema.Fields))
+		for i, field := range schema.Fields {
+			if field.Type == qvalue.QValueKindNumeric && qvalue.NumericExceedsDWH(field.Precision, field.Scale, dwh) {
+				overflowFields = append(overflowFields, i)
+				switch policy {
+				case protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING:
+					field = QField{Name: field.Name, Type: qvalue.QValueKindString, Nullable: field.Nullable}
+				case protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ROUND:
+					field.Nullable = true
+				}
+			}
+			fields = append(fields, field)
+		}
+		return NewQRecordSchema(fields)
+	}, func(record []qvalue.QValue) ([]qvalue.QValue, error) {
+		for _, i := range overflowFields {
+			num, ok := record[i].Value().(*big.Rat)
+			if !ok || num == nil {
+				if policy == protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING {
+					record[i] = qvalue.QValue{Kind: qvalue.QValueKindString}
+				}
+				continue
+			}
+			switch policy {
+			case protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING:
+				record[i] = qvalue.New(qvalue.QValueKindString, qvalue.NumericString(num))
+			case protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ERROR:
+				if !qvalue.NumericFits(num, precision, scale) {
+					return nil, fmt.Errorf("numeric %s doesn't fit NUMERIC(%d, %d)", qvalue.NumericString(num), precision, scale)
+				}
+			default:
+				if !qvalue.NumericFits(num, precision, scale) {
+					record[i] = qvalue.QValue{Kind: qvalue.QValueKindNumeric}
+				}
+			}
+		}
+		return record, nil
+	})
+}
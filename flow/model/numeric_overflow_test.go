@@ -0,0 +1,128 @@
+package model_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func mustRat(t *testing.T, s string) *big.Rat {
+	t.Helper()
+	num, ok := new(big.Rat).SetString(s)
+	require.True(t, ok)
+	return num
+}
+
+func TestNumericString(t *testing.T) {
+	long := "1." + strings.Repeat("0", 120) + "1"
+	for _, s := range []string{"0", "42", "-42", "1.5", "-0.001", "123456789012345678901234567890.123456789", long} {
+		assert.Equal(t, s, qvalue.NumericString(mustRat(t, s)))
+	}
+	assert.Equal(t, "0."+strings.Repeat("3", 100), qvalue.NumericString(big.NewRat(1, 3)))
+}
+
+func TestNumericFits(t *testing.T) {
+	assert.True(t, qvalue.NumericFits(mustRat(t, "999.99"), 5, 2))
+	assert.True(t, qvalue.NumericFits(mustRat(t, "-999.994"), 5, 2), "rounded to 999.99")
+	assert.False(t, qvalue.NumericFits(mustRat(t, "999.995"), 5, 2), "rounded to 1000.00")
+	assert.False(t, qvalue.NumericFits(mustRat(t, "1000"), 5, 2))
+}
+
+func TestNumericOverflowTableSchema(t *testing.T) {
+	schema := &protos.TableSchema{
+		TableIdentifier:   "public.prices",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+			// numeric(10, 2), numeric(50, 2) and unconstrained numeric
+			{Name: "small", Type: string(qvalue.QValueKindNumeric), TypeModifier: (10<<16 | 2) + 4},
+			{Name: "wide", Type: string(qvalue.QValueKindNumeric), TypeModifier: (50<<16 | 2) + 4},
+			{Name: "any", Type: string(qvalue.QValueKindNumeric), TypeModifier: -1},
+		},
+	}
+
+	assert.Same(t, schema, model.NumericOverflowTableSchema(schema, protos.DBType_SNOWFLAKE,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ROUND))
+	assert.Same(t, schema, model.NumericOverflowTableSchema(schema, protos.DBType_POSTGRES,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING))
+
+	snowflakeSchema := model.NumericOverflowTableSchema(schema, protos.DBType_SNOWFLAKE,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING)
+	kinds := make([]string, 0, len(snowflakeSchema.Columns))
+	for _, column := range snowflakeSchema.Columns {
+		kinds = append(kinds, column.Type)
+	}
+	assert.Equal(t, []string{"int64", "numeric", "string", "string"}, kinds)
+
+	// ClickHouse decimals go up to 76 digits
+	clickhouseSchema := model.NumericOverflowTableSchema(schema, protos.DBType_CLICKHOUSE,
+		protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING)
+	assert.Equal(t, "numeric", clickhouseSchema.Columns[2].Type)
+	assert.Equal(t, "string", clickhouseSchema.Columns[3].Type)
+}
+
+func numericOverflowStream(t *testing.T, policy protos.NumericOverflowPolicy) *model.QRecordStream {
+	t.Helper()
+	stream := model.NewQRecordStream(2)
+	require.NoError(t, stream.SetSchema(model.NewQRecordSchema([]model.QField{
+		{Name: "small", Type: qvalue.QValueKindNumeric, Precision: 10, Scale: 2},
+		{Name: "any", Type: qvalue.QValueKindNumeric},
+	})))
+	small := mustRat(t, "1.25")
+	values := []*big.Rat{mustRat(t, "1.5"), mustRat(t, "1"+strings.Repeat("0", 30))}
+	go func() {
+		for _, value := range values {
+			stream.Records <- model.QRecordOrError{Record: []qvalue.QValue{
+				qvalue.New(qvalue.QValueKindNumeric, small),
+				qvalue.New(qvalue.QValueKindNumeric, value),
+			}}
+		}
+		close(stream.Records)
+	}()
+	return model.NumericOverflowStream(context.Background(), stream, 2, protos.DBType_SNOWFLAKE, policy)
+}
+
+func TestNumericOverflowStream(t *testing.T) {
+	stringStream := numericOverflowStream(t, protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_STRING)
+	schema, err := stringStream.Schema()
+	require.NoError(t, err)
+	assert.Equal(t, qvalue.QValueKindNumeric, schema.Fields[0].Type)
+	assert.Equal(t, qvalue.QValueKindString, schema.Fields[1].Type)
+	var values []any
+	for record := range stringStream.Records {
+		require.NoError(t, record.Err)
+		values = append(values, record.Record[1].Value())
+	}
+	assert.Equal(t, []any{"1.5", "1" + strings.Repeat("0", 30)}, values)
+
+	roundStream := numericOverflowStream(t, protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ROUND)
+	schema, err = roundStream.Schema()
+	require.NoError(t, err)
+	assert.True(t, schema.Fields[1].Nullable, "overflowing values are nulled")
+	values = nil
+	for record := range roundStream.Records {
+		require.NoError(t, record.Err)
+		values = append(values, record.Record[1].Value())
+	}
+	require.Len(t, values, 2)
+	assert.Nil(t, values[1], "31 integer digits don't fit NUMERIC(38, 20)")
+
+	errorStream := numericOverflowStream(t, protos.NumericOverflowPolicy_NUMERIC_OVERFLOW_ERROR)
+	_, err = errorStream.Schema()
+	require.NoError(t, err)
+	var errs int
+	for record := range errorStream.Records {
+		if record.Err != nil {
+			errs += 1
+		}
+	}
+	assert.Equal(t, 1, errs)
+}
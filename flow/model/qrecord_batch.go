@@ -41,7 +41,7 @@ func (q *QRecordBatch) ToQRecordStream(buffer int) (*QRecordStream, error) {
 }
 
 func constructArray[T any](qValue qvalue.QValue, typeName string) (*pgtype.Array[T], error) {
-	v, ok := qValue.Value.([]T)
+	v, ok := qValue.Value().([]T)
 	if !ok {
 		return nil, fmt.Errorf("invalid %s value", typeName)
 	}
@@ -52,11 +52,49 @@ func constructArray[T any](qValue qvalue.QValue, typeName string) (*pgtype.Array
 	}, nil
 }
 
+// decodeRange decodes the canonical text of a range into a range of its element type, which can be copied in binary
+func decodeRange[T any](typeMap *pgtype.Map, oid uint32, text string) (pgtype.Range[T], error) {
+	var r pgtype.Range[T]
+	err := typeMap.Scan(oid, pgtype.TextFormatCode, []byte(text), &r)
+	return r, err
+}
+
+func rangeCopyValue(typeMap *pgtype.Map, qValue qvalue.QValue) (any, error) {
+	text, ok := qValue.Value().(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s value", qValue.Kind)
+	}
+	var r any
+	var err error
+	switch qValue.Kind {
+	case qvalue.QValueKindInt4Range:
+		r, err = decodeRange[pgtype.Int4](typeMap, pgtype.Int4rangeOID, text)
+	case qvalue.QValueKindInt8Range:
+		r, err = decodeRange[pgtype.Int8](typeMap, pgtype.Int8rangeOID, text)
+	case qvalue.QValueKindNumRange:
+		r, err = decodeRange[pgtype.Numeric](typeMap, pgtype.NumrangeOID, text)
+	case qvalue.QValueKindTsRange:
+		r, err = decodeRange[pgtype.Timestamp](typeMap, pgtype.TsrangeOID, text)
+	case qvalue.QValueKindTsTzRange:
+		r, err = decodeRange[pgtype.Timestamptz](typeMap, pgtype.TstzrangeOID, text)
+	case qvalue.QValueKindDateRange:
+		r, err = decodeRange[pgtype.Date](typeMap, pgtype.DaterangeOID, text)
+	default:
+		return nil, fmt.Errorf("%s is not a range", qValue.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %s: %w", qValue.Kind, text, err)
+	}
+	return r, nil
+}
+
 type QRecordBatchCopyFromSource struct {
 	numRecords    int
 	stream        *QRecordStream
 	currentRecord QRecordOrError
 	err           error
+	// decodes range values, created for the first one
+	typeMap *pgtype.Map
 }
 
 func NewQRecordBatchCopyFromSource(
@@ -92,14 +130,14 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 
 	values := make([]interface{}, numEntries)
 	for i, qValue := range record {
-		if qValue.Value == nil {
+		if qValue.IsNull() {
 			values[i] = nil
 			continue
 		}
 
 		switch qValue.Kind {
 		case qvalue.QValueKindFloat32:
-			v, ok := qValue.Value.(float32)
+			v, ok := qValue.Value().(float32)
 			if !ok {
 				src.err = errors.New("invalid float32 value")
 				return nil, src.err
@@ -107,7 +145,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindFloat64:
-			v, ok := qValue.Value.(float64)
+			v, ok := qValue.Value().(float64)
 			if !ok {
 				src.err = errors.New("invalid float64 value")
 				return nil, src.err
@@ -115,7 +153,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindInt16, qvalue.QValueKindInt32:
-			v, ok := qValue.Value.(int32)
+			v, ok := qValue.Value().(int32)
 			if !ok {
 				src.err = errors.New("invalid int32 value")
 				return nil, src.err
@@ -123,7 +161,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindInt64:
-			v, ok := qValue.Value.(int64)
+			v, ok := qValue.Value().(int64)
 			if !ok {
 				src.err = errors.New("invalid int64 value")
 				return nil, src.err
@@ -131,7 +169,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindBoolean:
-			v, ok := qValue.Value.(bool)
+			v, ok := qValue.Value().(bool)
 			if !ok {
 				src.err = errors.New("invalid boolean value")
 				return nil, src.err
@@ -139,7 +177,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindQChar:
-			v, ok := qValue.Value.(uint8)
+			v, ok := qValue.Value().(uint8)
 			if !ok {
 				src.err = errors.New("invalid \"char\" value")
 				return nil, src.err
@@ -147,7 +185,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = rune(v)
 
 		case qvalue.QValueKindString:
-			v, ok := qValue.Value.(string)
+			v, ok := qValue.Value().(string)
 			if !ok {
 				src.err = errors.New("invalid string value")
 				return nil, src.err
@@ -155,7 +193,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindTime:
-			t, ok := qValue.Value.(time.Time)
+			t, ok := qValue.Time()
 			if !ok {
 				src.err = errors.New("invalid Time value")
 				return nil, src.err
@@ -164,7 +202,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = time
 
 		case qvalue.QValueKindTimestamp:
-			t, ok := qValue.Value.(time.Time)
+			t, ok := qValue.Time()
 			if !ok {
 				src.err = errors.New("invalid ExtendedTime value")
 				return nil, src.err
@@ -173,7 +211,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = timestamp
 
 		case qvalue.QValueKindTimestampTZ:
-			t, ok := qValue.Value.(time.Time)
+			t, ok := qValue.Time()
 			if !ok {
 				src.err = errors.New("invalid ExtendedTime value")
 				return nil, src.err
@@ -182,17 +220,17 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = timestampTZ
 
 		case qvalue.QValueKindUUID:
-			v, ok := qValue.Value.([16]byte) // treat it as byte slice
+			v, ok := qValue.Value().([16]byte) // treat it as byte slice
 			if !ok {
-				src.err = fmt.Errorf("invalid UUID value %v", qValue.Value)
+				src.err = fmt.Errorf("invalid UUID value %v", qValue.Value())
 				return nil, src.err
 			}
 			values[i] = uuid.UUID(v)
 
 		case qvalue.QValueKindNumeric:
-			v, ok := qValue.Value.(*big.Rat)
+			v, ok := qValue.Value().(*big.Rat)
 			if !ok {
-				src.err = fmt.Errorf("invalid Numeric value %v", qValue.Value)
+				src.err = fmt.Errorf("invalid Numeric value %v", qValue.Value())
 				return nil, src.err
 			}
 			if v == nil {
@@ -210,7 +248,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v.FloatString(38)
 
 		case qvalue.QValueKindBytes, qvalue.QValueKindBit:
-			v, ok := qValue.Value.([]byte)
+			v, ok := qValue.Value().([]byte)
 			if !ok {
 				src.err = errors.New("invalid Bytes value")
 				return nil, src.err
@@ -218,7 +256,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = v
 
 		case qvalue.QValueKindDate:
-			t, ok := qValue.Value.(time.Time)
+			t, ok := qValue.Time()
 			if !ok {
 				src.err = errors.New("invalid Date value")
 				return nil, src.err
@@ -227,7 +265,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			values[i] = date
 
 		case qvalue.QValueKindHStore:
-			v, ok := qValue.Value.(string)
+			v, ok := qValue.Value().(string)
 			if !ok {
 				src.err = errors.New("invalid HStore value")
 				return nil, src.err
@@ -235,7 +273,7 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 
 			values[i] = v
 		case qvalue.QValueKindGeography, qvalue.QValueKindGeometry, qvalue.QValueKindPoint:
-			v, ok := qValue.Value.(string)
+			v, ok := qValue.Value().(string)
 			if !ok {
 				src.err = errors.New("invalid Geospatial value")
 				return nil, src.err
@@ -311,12 +349,23 @@ func (src *QRecordBatchCopyFromSource) Values() ([]interface{}, error) {
 			}
 			values[i] = v
 		case qvalue.QValueKindJSON:
-			v, ok := qValue.Value.(string)
+			v, ok := qValue.Value().(string)
 			if !ok {
 				src.err = errors.New("invalid JSON value")
 				return nil, src.err
 			}
 			values[i] = v
+		case qvalue.QValueKindInt4Range, qvalue.QValueKindInt8Range, qvalue.QValueKindNumRange,
+			qvalue.QValueKindTsRange, qvalue.QValueKindTsTzRange, qvalue.QValueKindDateRange:
+			if src.typeMap == nil {
+				src.typeMap = pgtype.NewMap()
+			}
+			v, err := rangeCopyValue(src.typeMap, qValue)
+			if err != nil {
+				src.err = err
+				return nil, src.err
+			}
+			values[i] = v
 
 		// And so on for the other types...
 		default:
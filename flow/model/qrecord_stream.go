@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"errors"
 
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
@@ -80,6 +81,19 @@ func (s *QRecordStream) SetSchema(schema *QRecordSchema) error {
 	return nil
 }
 
+// SetSchemaError hands err on to readers of the schema in its place, for streams failing before their schema is known.
+func (s *QRecordStream) SetSchemaError(err error) error {
+	if s.schemaSet {
+		return errors.New("Schema already set")
+	}
+
+	s.schema <- QRecordSchemaOrError{
+		Err: err,
+	}
+	s.schemaSet = true
+	return nil
+}
+
 func (s *QRecordStream) IsSchemaSet() bool {
 	return s.schemaSet
 }
@@ -87,3 +101,75 @@ func (s *QRecordStream) IsSchemaSet() bool {
 func (s *QRecordStream) SchemaChan() <-chan QRecordSchemaOrError {
 	return s.schema
 }
+
+// Measured returns a stream handing on the schema and records of s, calling measure on each record first.
+// The returned stream is closed once s is, records stop being handed on when ctx is done.
+func (s *QRecordStream) Measured(ctx context.Context, buffer int, measure func(record []qvalue.QValue)) *QRecordStream {
+	return s.Mapped(ctx, buffer, nil, func(record []qvalue.QValue) ([]qvalue.QValue, error) {
+		measure(record)
+		return record, nil
+	})
+}
+
+// Mapped returns a stream handing on the schema and records of s through mapSchema, unless it is nil,
+// and mapRecord, which is only called once the schema has been mapped. Errors of mapRecord are handed on in place of the record,
+// records it maps to nil without an error are left out.
+// The returned stream is closed once s is, records stop being handed on when ctx is done.
+func (s *QRecordStream) Mapped(
+	ctx context.Context,
+	buffer int,
+	mapSchema func(*QRecordSchema) *QRecordSchema,
+	mapRecord func([]qvalue.QValue) ([]qvalue.QValue, error),
+) *QRecordStream {
+	mapped := NewQRecordStream(buffer)
+	go func() {
+		defer close(mapped.Records)
+		schema := s.schema
+		handOnSchema := func(schemaOrError QRecordSchemaOrError) {
+			if schemaOrError.Err == nil && schemaOrError.Schema != nil && mapSchema != nil {
+				schemaOrError.Schema = mapSchema(schemaOrError.Schema)
+			}
+			mapped.schema <- schemaOrError
+			// a nil channel is never selected, the schema is only set once
+			schema = nil
+		}
+		for {
+			select {
+			case schemaOrError := <-schema:
+				handOnSchema(schemaOrError)
+			case record, ok := <-s.Records:
+				if !ok {
+					if schema != nil {
+						select {
+						case schemaOrError := <-schema:
+							handOnSchema(schemaOrError)
+						default:
+						}
+					}
+					return
+				}
+				if record.Err == nil {
+					if schema != nil && mapSchema != nil {
+						// the schema is set before records, but both may be ready at once
+						select {
+						case schemaOrError := <-schema:
+							handOnSchema(schemaOrError)
+						case <-ctx.Done():
+							return
+						}
+					}
+					record.Record, record.Err = mapRecord(record.Record)
+					if record.Err == nil && record.Record == nil {
+						continue
+					}
+				}
+				select {
+				case mapped.Records <- record:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return mapped
+}
@@ -0,0 +1,78 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestMeasuredQRecordStream(t *testing.T) {
+	stream := model.NewQRecordStream(2)
+	schema := model.NewQRecordSchema([]model.QField{{Name: "id", Type: qvalue.QValueKindInt64}})
+	require.NoError(t, stream.SetSchema(schema))
+	go func() {
+		for i := range 3 {
+			stream.Records <- model.QRecordOrError{
+				Record: []qvalue.QValue{qvalue.NewInt64(qvalue.QValueKindInt64, int64(i))},
+			}
+		}
+		close(stream.Records)
+	}()
+
+	var measured int
+	measuredStream := stream.Measured(context.Background(), 2, func(record []qvalue.QValue) {
+		measured += record[0].EstimatedSize()
+	})
+
+	measuredSchema, err := measuredStream.Schema()
+	require.NoError(t, err)
+	assert.Equal(t, schema, measuredSchema)
+
+	var records int
+	for range measuredStream.Records {
+		records += 1
+	}
+	assert.Equal(t, 3, records)
+	assert.Equal(t, 24, measured)
+}
+
+func TestMappedQRecordStreamDropsRecords(t *testing.T) {
+	stream := model.NewQRecordStream(2)
+	require.NoError(t, stream.SetSchema(model.NewQRecordSchema([]model.QField{{Name: "id", Type: qvalue.QValueKindInt64}})))
+	go func() {
+		for i := range 4 {
+			stream.Records <- model.QRecordOrError{
+				Record: []qvalue.QValue{qvalue.NewInt64(qvalue.QValueKindInt64, int64(i))},
+			}
+		}
+		close(stream.Records)
+	}()
+
+	mapped := stream.Mapped(context.Background(), 2, nil, func(record []qvalue.QValue) ([]qvalue.QValue, error) {
+		if record[0].Value().(int64)%2 == 0 {
+			return nil, nil
+		}
+		return record, nil
+	})
+	var ids []int64
+	for record := range mapped.Records {
+		require.NoError(t, record.Err)
+		ids = append(ids, record.Record[0].Value().(int64))
+	}
+	assert.Equal(t, []int64{1, 3}, ids)
+}
+
+func TestQRecordStreamSchemaError(t *testing.T) {
+	stream := model.NewQRecordStream(1)
+	require.NoError(t, stream.SetSchemaError(errors.New("query failed")))
+	require.Error(t, stream.SetSchema(model.NewQRecordSchema(nil)), "the schema is only set once")
+
+	_, err := stream.Schema()
+	require.EqualError(t, err, "query failed")
+}
@@ -60,6 +60,9 @@ func GetAvroSchemaFromQValueKind(kind QValueKind, targetDWH QDWHType, precision
 	switch kind {
 	case QValueKindString, QValueKindQChar:
 		return "string", nil
+	case QValueKindInt4Range, QValueKindInt8Range, QValueKindNumRange,
+		QValueKindTsRange, QValueKindTsTzRange, QValueKindDateRange:
+		return "string", nil
 	case QValueKindUUID:
 		return AvroSchemaLogical{
 			Type:        "string",
@@ -174,14 +177,14 @@ func NewQValueAvroConverter(value QValue, targetDWH QDWHType, nullable bool, log
 }
 
 func (c *QValueAvroConverter) ToAvroValue() (interface{}, error) {
-	if c.Nullable && c.Value.Value == nil {
+	if c.Nullable && c.Value.IsNull() {
 		return nil, nil
 	}
 
 	switch c.Value.Kind {
 	case QValueKindInvalid:
 		// we will attempt to convert invalid to a string
-		return c.processNullableUnion("string", c.Value.Value)
+		return c.processNullableUnion("string", c.Value.Value())
 	case QValueKindTime:
 		t, err := c.processGoTime()
 		if err != nil || t == nil {
@@ -189,82 +192,58 @@ func (c *QValueAvroConverter) ToAvroValue() (interface{}, error) {
 		}
 
 		if c.TargetDWH == QDWHTypeSnowflake {
-			if c.Nullable {
-				return c.processNullableUnion("string", t.(string))
-			} else {
-				return t.(string), nil
-			}
+			return c.processNullableUnion("string", t)
 		}
 
 		if c.TargetDWH == QDWHTypeClickhouse {
-			if c.Nullable {
-				return c.processNullableUnion("string", t.(string))
-			} else {
-				return t.(string), nil
-			}
+			return c.processNullableUnion("string", t)
 		}
 		if c.Nullable {
-			return goavro.Union("long.time-micros", t.(int64)), nil
+			return goavro.Union("long.time-micros", t), nil
 		}
-		return t.(int64), nil
+		return t, nil
 	case QValueKindTimeTZ:
 		t, err := c.processGoTimeTZ()
 		if err != nil || t == nil {
 			return t, err
 		}
 		if c.TargetDWH == QDWHTypeSnowflake {
-			if c.Nullable {
-				return c.processNullableUnion("string", t.(string))
-			} else {
-				return t.(string), nil
-			}
+			return c.processNullableUnion("string", t)
 		}
 
 		if c.TargetDWH == QDWHTypeClickhouse {
-			if c.Nullable {
-				return c.processNullableUnion("long", t.(int64))
-			} else {
-				return t.(int64), nil
-			}
+			return c.processNullableUnion("long", t)
 		}
 		if c.Nullable {
-			return goavro.Union("long.time-micros", t.(int64)), nil
+			return goavro.Union("long.time-micros", t), nil
 		}
-		return t.(int64), nil
+		return t, nil
 	case QValueKindTimestamp:
 		t, err := c.processGoTimestamp()
 		if err != nil || t == nil {
 			return t, err
 		}
 		if c.TargetDWH == QDWHTypeSnowflake {
-			if c.Nullable {
-				return c.processNullableUnion("string", t.(string))
-			} else {
-				return t.(string), nil
-			}
+			return c.processNullableUnion("string", t)
 		}
 
 		if c.Nullable {
-			return goavro.Union("long.timestamp-micros", t.(int64)), nil
+			return goavro.Union("long.timestamp-micros", t), nil
 		}
-		return t.(int64), nil
+		return t, nil
 	case QValueKindTimestampTZ:
 		t, err := c.processGoTimestampTZ()
 		if err != nil || t == nil {
 			return t, err
 		}
 		if c.TargetDWH == QDWHTypeSnowflake {
-			if c.Nullable {
-				return c.processNullableUnion("string", t.(string))
-			} else {
-				return t.(string), nil
-			}
+			return c.processNullableUnion("string", t)
 		}
 
 		if c.Nullable {
-			return goavro.Union("long.timestamp-micros", t.(int64)), nil
+			return goavro.Union("long.timestamp-micros", t), nil
 		}
-		return t.(int64), nil
+		return t, nil
 	case QValueKindDate:
 		t, err := c.processGoDate()
 		if err != nil || t == nil {
@@ -272,11 +251,7 @@ func (c *QValueAvroConverter) ToAvroValue() (interface{}, error) {
 		}
 
 		if c.TargetDWH == QDWHTypeSnowflake {
-			if c.Nullable {
-				return c.processNullableUnion("string", t.(string))
-			} else {
-				return t.(string), nil
-			}
+			return c.processNullableUnion("string", t)
 		}
 
 		if c.Nullable {
@@ -284,31 +259,33 @@ func (c *QValueAvroConverter) ToAvroValue() (interface{}, error) {
 		}
 		return t, nil
 	case QValueKindQChar:
-		return c.processNullableUnion("string", string(c.Value.Value.(uint8)))
-	case QValueKindString, QValueKindCIDR, QValueKindINET, QValueKindMacaddr:
-		if c.TargetDWH == QDWHTypeSnowflake && c.Value.Value != nil &&
-			(len(c.Value.Value.(string)) > 15*1024*1024) {
+		return c.processNullableUnion("string", string(c.Value.Value().(uint8)))
+	case QValueKindString, QValueKindCIDR, QValueKindINET, QValueKindMacaddr,
+		QValueKindInt4Range, QValueKindInt8Range, QValueKindNumRange,
+		QValueKindTsRange, QValueKindTsTzRange, QValueKindDateRange:
+		if c.TargetDWH == QDWHTypeSnowflake && !c.Value.IsNull() &&
+			(len(c.Value.Value().(string)) > 15*1024*1024) {
 			slog.Warn("Truncating TEXT value > 15MB for Snowflake!")
 			slog.Warn("Check this issue for details: https://github.com/PeerDB-io/peerdb/issues/309")
 			return c.processNullableUnion("string", "")
 		}
-		return c.processNullableUnion("string", c.Value.Value)
+		return c.processNullableUnion("string", c.Value.Value())
 	case QValueKindFloat32:
 		if c.TargetDWH == QDWHTypeBigQuery {
-			return c.processNullableUnion("double", c.Value.Value)
+			return c.processNullableUnion("double", c.Value.Value())
 		}
-		return c.processNullableUnion("float", c.Value.Value)
+		return c.processNullableUnion("float", c.Value.Value())
 	case QValueKindFloat64:
 		if c.TargetDWH == QDWHTypeSnowflake || c.TargetDWH == QDWHTypeBigQuery {
-			if f32Val, ok := c.Value.Value.(float32); ok {
+			if f32Val, ok := c.Value.Value().(float32); ok {
 				return c.processNullableUnion("double", float64(f32Val))
 			}
 		}
-		return c.processNullableUnion("double", c.Value.Value)
+		return c.processNullableUnion("double", c.Value.Value())
 	case QValueKindInt16, QValueKindInt32, QValueKindInt64:
-		return c.processNullableUnion("long", c.Value.Value)
+		return c.processNullableUnion("long", c.Value.Value())
 	case QValueKindBoolean:
-		return c.processNullableUnion("boolean", c.Value.Value)
+		return c.processNullableUnion("boolean", c.Value.Value())
 	case QValueKindStruct:
 		return nil, errors.New("QValueKindStruct not supported")
 	case QValueKindNumeric:
@@ -357,11 +334,11 @@ func (c *QValueAvroConverter) ToAvroValue() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processGoTimeTZ() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	t, ok := c.Value.Value.(time.Time)
+	t, ok := c.Value.Time()
 	if !ok {
 		return nil, errors.New("invalid TimeTZ value")
 	}
@@ -375,11 +352,11 @@ func (c *QValueAvroConverter) processGoTimeTZ() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processGoTime() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	t, ok := c.Value.Value.(time.Time)
+	t, ok := c.Value.Time()
 	if !ok {
 		return nil, errors.New("invalid Time value")
 	}
@@ -397,11 +374,11 @@ func (c *QValueAvroConverter) processGoTime() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processGoTimestampTZ() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	t, ok := c.Value.Value.(time.Time)
+	t, ok := c.Value.Time()
 	if !ok {
 		return nil, errors.New("invalid TimestampTZ value")
 	}
@@ -422,11 +399,11 @@ func (c *QValueAvroConverter) processGoTimestampTZ() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processGoTimestamp() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	t, ok := c.Value.Value.(time.Time)
+	t, ok := c.Value.Time()
 	if !ok {
 		return nil, errors.New("invalid Timestamp value")
 	}
@@ -447,11 +424,11 @@ func (c *QValueAvroConverter) processGoTimestamp() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processGoDate() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	t, ok := c.Value.Value.(time.Time)
+	t, ok := c.Value.Time()
 	if !ok {
 		return nil, errors.New("invalid Time value for Date")
 	}
@@ -478,13 +455,13 @@ func (c *QValueAvroConverter) processNullableUnion(
 }
 
 func (c *QValueAvroConverter) processNumeric() (interface{}, error) {
-	if c.Value.Value == nil {
+	if c.Value.IsNull() {
 		return nil, nil
 	}
 
-	num, ok := c.Value.Value.(*big.Rat)
+	num, ok := c.Value.Value().(*big.Rat)
 	if !ok {
-		return nil, fmt.Errorf("invalid Numeric value: expected *big.Rat, got %T", c.Value.Value)
+		return nil, fmt.Errorf("invalid Numeric value: expected *big.Rat, got %T", c.Value.Value())
 	}
 
 	if num == nil {
@@ -501,14 +478,14 @@ func (c *QValueAvroConverter) processNumeric() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processBytes() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
 	if c.TargetDWH == QDWHTypeClickhouse {
-		bigNum, ok := c.Value.Value.(*big.Rat)
+		bigNum, ok := c.Value.Value().(*big.Rat)
 		if !ok {
-			return nil, fmt.Errorf("invalid Numeric value: expected float64, got %T", c.Value.Value)
+			return nil, fmt.Errorf("invalid Numeric value: expected float64, got %T", c.Value.Value())
 		}
 		num, ok := bigNum.Float64()
 		if !ok {
@@ -517,7 +494,7 @@ func (c *QValueAvroConverter) processBytes() (interface{}, error) {
 		return goavro.Union("double", num), nil
 	}
 
-	byteData, ok := c.Value.Value.([]byte)
+	byteData, ok := c.Value.Value().([]byte)
 	if !ok {
 		return nil, errors.New("invalid Bytes value")
 	}
@@ -530,13 +507,13 @@ func (c *QValueAvroConverter) processBytes() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processJSON() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	jsonString, ok := c.Value.Value.(string)
+	jsonString, ok := c.Value.Value().(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid JSON value %v", c.Value.Value)
+		return nil, fmt.Errorf("invalid JSON value %v", c.Value.Value())
 	}
 
 	if c.Nullable {
@@ -557,11 +534,11 @@ func (c *QValueAvroConverter) processJSON() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayBoolean() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]bool)
+	arrayData, ok := c.Value.Value().([]bool)
 	if !ok {
 		return nil, errors.New("invalid Boolean array value")
 	}
@@ -574,11 +551,11 @@ func (c *QValueAvroConverter) processArrayBoolean() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayTime() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayTime, ok := c.Value.Value.([]time.Time)
+	arrayTime, ok := c.Value.Value().([]time.Time)
 	if !ok {
 		return nil, errors.New("invalid Timestamp array value")
 	}
@@ -602,11 +579,11 @@ func (c *QValueAvroConverter) processArrayTime() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayDate() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayDate, ok := c.Value.Value.([]time.Time)
+	arrayDate, ok := c.Value.Value().([]time.Time)
 	if !ok {
 		return nil, errors.New("invalid Date array value")
 	}
@@ -628,13 +605,13 @@ func (c *QValueAvroConverter) processArrayDate() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processHStore() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	hstoreString, ok := c.Value.Value.(string)
+	hstoreString, ok := c.Value.Value().(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid HSTORE value %v", c.Value.Value)
+		return nil, fmt.Errorf("invalid HSTORE value %v", c.Value.Value())
 	}
 
 	jsonString, err := hstore_util.ParseHstore(hstoreString)
@@ -660,16 +637,16 @@ func (c *QValueAvroConverter) processHStore() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processUUID() (interface{}, error) {
-	if c.Value.Value == nil {
+	if c.Value.IsNull() {
 		return nil, nil
 	}
 
-	byteData, ok := c.Value.Value.([16]byte)
+	byteData, ok := c.Value.Value().([16]byte)
 	if !ok {
 		// attempt to convert google.uuid to [16]byte
-		byteData, ok = c.Value.Value.(uuid.UUID)
+		byteData, ok = c.Value.Value().(uuid.UUID)
 		if !ok {
-			return nil, fmt.Errorf("[conversion] invalid UUID value %v", c.Value.Value)
+			return nil, fmt.Errorf("[conversion] invalid UUID value %v", c.Value.Value())
 		}
 	}
 
@@ -688,13 +665,13 @@ func (c *QValueAvroConverter) processUUID() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processGeospatial() (interface{}, error) {
-	if c.Value.Value == nil {
+	if c.Value.IsNull() {
 		return nil, nil
 	}
 
-	geoString, ok := c.Value.Value.(string)
+	geoString, ok := c.Value.Value().(string)
 	if !ok {
-		return nil, fmt.Errorf("[conversion] invalid geospatial value %v", c.Value.Value)
+		return nil, fmt.Errorf("[conversion] invalid geospatial value %v", c.Value.Value())
 	}
 
 	if c.Nullable {
@@ -704,11 +681,11 @@ func (c *QValueAvroConverter) processGeospatial() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayInt16() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]int16)
+	arrayData, ok := c.Value.Value().([]int16)
 	if !ok {
 		return nil, errors.New("invalid Int16 array value")
 	}
@@ -727,11 +704,11 @@ func (c *QValueAvroConverter) processArrayInt16() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayInt32() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]int32)
+	arrayData, ok := c.Value.Value().([]int32)
 	if !ok {
 		return nil, errors.New("invalid Int32 array value")
 	}
@@ -744,11 +721,11 @@ func (c *QValueAvroConverter) processArrayInt32() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayInt64() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]int64)
+	arrayData, ok := c.Value.Value().([]int64)
 	if !ok {
 		return nil, errors.New("invalid Int64 array value")
 	}
@@ -761,11 +738,11 @@ func (c *QValueAvroConverter) processArrayInt64() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayFloat32() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]float32)
+	arrayData, ok := c.Value.Value().([]float32)
 	if !ok {
 		return nil, errors.New("invalid Float32 array value")
 	}
@@ -778,11 +755,11 @@ func (c *QValueAvroConverter) processArrayFloat32() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayFloat64() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]float64)
+	arrayData, ok := c.Value.Value().([]float64)
 	if !ok {
 		return nil, errors.New("invalid Float64 array value")
 	}
@@ -795,11 +772,11 @@ func (c *QValueAvroConverter) processArrayFloat64() (interface{}, error) {
 }
 
 func (c *QValueAvroConverter) processArrayString() (interface{}, error) {
-	if c.Value.Value == nil && c.Nullable {
+	if c.Value.IsNull() && c.Nullable {
 		return nil, nil
 	}
 
-	arrayData, ok := c.Value.Value.([]string)
+	arrayData, ok := c.Value.Value().([]string)
 	if !ok {
 		return nil, errors.New("invalid String array value")
 	}
@@ -11,18 +11,39 @@ const (
 	QDWHTypeClickhouse QDWHType = 4
 )
 
+// NumericExceedsDWH returns whether numerics of a precision and scale can't be created as such on dwh,
+// which is the case for unconstrained numerics, whose type modifier parses to an out of range precision.
+func NumericExceedsDWH(precision int16, scale int16, dwh QDWHType) bool {
+	if precision <= 0 || scale < 0 || scale > precision {
+		return true
+	}
+	switch dwh {
+	case QDWHTypeClickhouse:
+		return precision > numeric.PeerDBClickhousePrecision
+	case QDWHTypeSnowflake, QDWHTypeBigQuery:
+		return precision > numeric.PeerDBNumericPrecision || scale > numeric.PeerDBNumericPrecision-1
+	default:
+		return precision > numeric.PeerDBNumericPrecision || scale > numeric.PeerDBNumericScale
+	}
+}
+
 func DetermineNumericSettingForDWH(precision int16, scale int16, dwh QDWHType) (int16, int16) {
-	if dwh == QDWHTypeClickhouse {
-		if precision > numeric.PeerDBClickhousePrecision || precision <= 0 ||
-			scale > precision || scale < 0 {
+	if NumericExceedsDWH(precision, scale, dwh) {
+		if dwh == QDWHTypeClickhouse {
 			return numeric.PeerDBClickhousePrecision, numeric.PeerDBClickhouseScale
 		}
-	} else {
-		if precision > numeric.PeerDBNumericPrecision || precision <= 0 ||
-			scale > numeric.PeerDBNumericScale || scale < 0 {
-			return numeric.PeerDBNumericPrecision, numeric.PeerDBNumericScale
-		}
+		return numeric.PeerDBNumericPrecision, numeric.PeerDBNumericScale
 	}
 
 	return precision, scale
 }
+
+// DetermineNumericSettingForTypmod returns the precision and scale numeric columns with a type modifier
+// are created with on dwh, unconstrained numerics having a type modifier of -1.
+func DetermineNumericSettingForTypmod(typmod int32, dwh QDWHType) (int16, int16) {
+	if typmod == -1 {
+		return DetermineNumericSettingForDWH(0, 0, dwh)
+	}
+	precision, scale := numeric.ParseNumericTypmod(typmod)
+	return DetermineNumericSettingForDWH(precision, scale, dwh)
+}
@@ -2,6 +2,7 @@ package qvalue
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -39,6 +40,14 @@ const (
 	QValueKindINET    QValueKind = "inet"
 	QValueKindMacaddr QValueKind = "macaddr"
 
+	// range types, values are their canonical Postgres text, e.g. [1,10), [2024-01-01,2024-02-01) or empty
+	QValueKindInt4Range QValueKind = "int4range"
+	QValueKindInt8Range QValueKind = "int8range"
+	QValueKindNumRange  QValueKind = "numrange"
+	QValueKindTsRange   QValueKind = "tsrange"
+	QValueKindTsTzRange QValueKind = "tstzrange"
+	QValueKindDateRange QValueKind = "daterange"
+
 	// array types
 	QValueKindArrayFloat32     QValueKind = "array_float32"
 	QValueKindArrayFloat64     QValueKind = "array_float64"
@@ -56,6 +65,31 @@ func (kind QValueKind) IsArray() bool {
 	return strings.HasPrefix(string(kind), "array_")
 }
 
+func (kind QValueKind) IsRange() bool {
+	switch kind {
+	case QValueKindInt4Range, QValueKindInt8Range, QValueKindNumRange,
+		QValueKindTsRange, QValueKindTsTzRange, QValueKindDateRange:
+		return true
+	default:
+		return false
+	}
+}
+
+// qValueKindWidenings holds the kinds each kind can change to while keeping all of its values.
+var qValueKindWidenings = map[QValueKind][]QValueKind{
+	QValueKindInt16:   {QValueKindInt32, QValueKindInt64, QValueKindFloat64, QValueKindNumeric},
+	QValueKindInt32:   {QValueKindInt64, QValueKindFloat64, QValueKindNumeric},
+	QValueKindInt64:   {QValueKindNumeric},
+	QValueKindFloat32: {QValueKindFloat64},
+	QValueKindQChar:   {QValueKindString},
+	QValueKindDate:    {QValueKindTimestamp},
+}
+
+// CanWidenTo returns whether a column of this kind can change to kind to without losing any of its values.
+func (kind QValueKind) CanWidenTo(to QValueKind) bool {
+	return slices.Contains(qValueKindWidenings[kind], to)
+}
+
 var QValueKindToSnowflakeTypeMap = map[QValueKind]string{
 	QValueKindBoolean:     "BOOLEAN",
 	QValueKindInt16:       "INTEGER",
@@ -82,6 +116,14 @@ var QValueKindToSnowflakeTypeMap = map[QValueKind]string{
 	QValueKindGeometry:    "GEOMETRY",
 	QValueKindPoint:       "GEOMETRY",
 
+	// range types keep their canonical text
+	QValueKindInt4Range: "STRING",
+	QValueKindInt8Range: "STRING",
+	QValueKindNumRange:  "STRING",
+	QValueKindTsRange:   "STRING",
+	QValueKindTsTzRange: "STRING",
+	QValueKindDateRange: "STRING",
+
 	// array types will be mapped to VARIANT
 	QValueKindArrayFloat32:     "VARIANT",
 	QValueKindArrayFloat64:     "VARIANT",
@@ -117,6 +159,12 @@ var QValueKindToClickhouseTypeMap = map[QValueKind]string{
 	QValueKindTimeTZ:      "String",
 	QValueKindInvalid:     "String",
 	QValueKindHStore:      "String",
+	QValueKindInt4Range:   "String",
+	QValueKindInt8Range:   "String",
+	QValueKindNumRange:    "String",
+	QValueKindTsRange:     "String",
+	QValueKindTsTzRange:   "String",
+	QValueKindDateRange:   "String",
 	// array types will be mapped to VARIANT
 	QValueKindArrayFloat32: "Array(Float32)",
 	QValueKindArrayFloat64: "Array(Float64)",
@@ -0,0 +1,52 @@
+package qvalue
+
+import (
+	"math/big"
+	"strings"
+)
+
+var bigTen = big.NewInt(10)
+
+// NumericString formats a numeric exactly, with no more fractional digits than it has.
+// Numerics read from Postgres are decimals, other fractions are cut off after 100 digits like before.
+func NumericString(num *big.Rat) string {
+	if num.IsInt() {
+		return num.Num().String()
+	}
+	// a fraction in lowest terms is a decimal when its denominator has no prime factors but 2 and 5,
+	// with as many fractional digits as the larger power of them
+	denom := new(big.Int).Set(num.Denom())
+	twos := divideOut(denom, 2)
+	fives := divideOut(denom, 5)
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return strings.TrimRight(strings.TrimRight(num.FloatString(100), "0"), ".")
+	}
+	return num.FloatString(max(twos, fives))
+}
+
+// divideOut divides n by factor as long as it is divisible, returning how many times it was.
+func divideOut(n *big.Int, factor int64) int {
+	f := big.NewInt(factor)
+	quo, rem := new(big.Int), new(big.Int)
+	count := 0
+	for {
+		quo.QuoRem(n, f, rem)
+		if rem.Sign() != 0 {
+			return count
+		}
+		n.Set(quo)
+		count++
+	}
+}
+
+// NumericFits returns whether a numeric rounded to scale fractional digits has at most precision digits,
+// so that a decimal of that precision and scale holds it.
+func NumericFits(num *big.Rat, precision int16, scale int16) bool {
+	scaled := new(big.Rat).Mul(num, new(big.Rat).SetInt(new(big.Int).Exp(bigTen, big.NewInt(int64(scale)), nil)))
+	// FloatString rounds half away from zero
+	rounded, ok := new(big.Int).SetString(scaled.FloatString(0), 10)
+	if !ok {
+		return false
+	}
+	return rounded.CmpAbs(new(big.Int).Exp(bigTen, big.NewInt(int64(precision)), nil)) < 0
+}
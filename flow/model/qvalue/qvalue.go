@@ -18,16 +18,175 @@ import (
 	hstore_util "github.com/PeerDB-io/peer-flow/hstore"
 )
 
+// QValue is a value of kind. Bools, integers, floats and times are held as they are rather than boxed in an interface,
+// so that records don't take an allocation per value, see New. Other values are held boxed.
 // if new types are added, register them in gob - cdc_records_storage.go
 type QValue struct {
-	Kind  QValueKind
-	Value interface{}
+	Kind QValueKind
+	// val is the boxed value, or a tag of the type of the value held in bits and nsec, nil for null values
+	val  interface{}
+	bits uint64
+	nsec int32
+}
+
+// tags of the types of values held unboxed, they take no allocation to box
+type (
+	boolTag    struct{}
+	int16Tag   struct{}
+	int32Tag   struct{}
+	int64Tag   struct{}
+	uint8Tag   struct{}
+	float32Tag struct{}
+	float64Tag struct{}
+	// times are held as their seconds and nanoseconds since the Unix epoch
+	timeTag struct{ loc *time.Location }
+)
+
+// New returns a QValue of kind holding value, nil for null values.
+// Prefer the typed constructors for values which aren't boxed yet, they don't box them.
+func New(kind QValueKind, value interface{}) QValue {
+	switch v := value.(type) {
+	case bool:
+		return NewBool(kind, v)
+	case int16:
+		return NewInt16(kind, v)
+	case int32:
+		return NewInt32(kind, v)
+	case int64:
+		return NewInt64(kind, v)
+	case uint8:
+		return NewUint8(kind, v)
+	case float32:
+		return NewFloat32(kind, v)
+	case float64:
+		return NewFloat64(kind, v)
+	case time.Time:
+		return NewTime(kind, v)
+	default:
+		return QValue{Kind: kind, val: value}
+	}
+}
+
+func NewBool(kind QValueKind, value bool) QValue {
+	var bits uint64
+	if value {
+		bits = 1
+	}
+	return QValue{Kind: kind, val: boolTag{}, bits: bits}
+}
+
+func NewInt16(kind QValueKind, value int16) QValue {
+	return QValue{Kind: kind, val: int16Tag{}, bits: uint64(value)}
+}
+
+func NewInt32(kind QValueKind, value int32) QValue {
+	return QValue{Kind: kind, val: int32Tag{}, bits: uint64(value)}
+}
+
+func NewInt64(kind QValueKind, value int64) QValue {
+	return QValue{Kind: kind, val: int64Tag{}, bits: uint64(value)}
+}
+
+func NewUint8(kind QValueKind, value uint8) QValue {
+	return QValue{Kind: kind, val: uint8Tag{}, bits: uint64(value)}
+}
+
+func NewFloat32(kind QValueKind, value float32) QValue {
+	return QValue{Kind: kind, val: float32Tag{}, bits: uint64(math.Float32bits(value))}
+}
+
+func NewFloat64(kind QValueKind, value float64) QValue {
+	return QValue{Kind: kind, val: float64Tag{}, bits: math.Float64bits(value)}
+}
+
+// NewTime returns a QValue of kind holding value, without its monotonic clock reading.
+func NewTime(kind QValueKind, value time.Time) QValue {
+	return QValue{Kind: kind, val: timeTag{loc: value.Location()}, bits: uint64(value.Unix()), nsec: int32(value.Nanosecond())}
+}
+
+// IsNull reports whether the value is null, without boxing it like comparing Value to nil would.
+func (q QValue) IsNull() bool {
+	return q.val == nil
+}
+
+// Value returns the value boxed in an interface, nil for null values.
+func (q QValue) Value() interface{} {
+	switch tag := q.val.(type) {
+	case boolTag:
+		return q.bits != 0
+	case int16Tag:
+		return int16(q.bits)
+	case int32Tag:
+		return int32(q.bits)
+	case int64Tag:
+		return int64(q.bits)
+	case uint8Tag:
+		return uint8(q.bits)
+	case float32Tag:
+		return math.Float32frombits(uint32(q.bits))
+	case float64Tag:
+		return math.Float64frombits(q.bits)
+	case timeTag:
+		return q.time(tag)
+	default:
+		return q.val
+	}
+}
+
+func (q QValue) time(tag timeTag) time.Time {
+	return time.Unix(int64(q.bits), int64(q.nsec)).In(tag.loc)
+}
+
+// Scalar reports whether the value is a bool, integer, float or time, which are held unboxed.
+func (q QValue) Scalar() bool {
+	switch q.val.(type) {
+	case boolTag, int16Tag, int32Tag, int64Tag, uint8Tag, float32Tag, float64Tag, timeTag:
+		return true
+	default:
+		return false
+	}
+}
+
+// Int64 returns the value when it's an integer, converted to an int64.
+func (q QValue) Int64() (int64, bool) {
+	switch q.val.(type) {
+	case int16Tag:
+		return int64(int16(q.bits)), true
+	case int32Tag:
+		return int64(int32(q.bits)), true
+	case int64Tag:
+		return int64(q.bits), true
+	case uint8Tag:
+		return int64(uint8(q.bits)), true
+	default:
+		return 0, false
+	}
 }
 
+// Float64 returns the value when it's a float, converted to a float64.
+func (q QValue) Float64() (float64, bool) {
+	switch q.val.(type) {
+	case float32Tag:
+		return float64(math.Float32frombits(uint32(q.bits))), true
+	case float64Tag:
+		return math.Float64frombits(q.bits), true
+	default:
+		return 0, false
+	}
+}
+
+// Time returns the value when it's a time.
+func (q QValue) Time() (time.Time, bool) {
+	if tag, ok := q.val.(timeTag); ok {
+		return q.time(tag), true
+	}
+	return time.Time{}, false
+}
+
+// Equals compares q with other, which may be of another kind when read from a destination. Values of types
+// the comparison of q's kind doesn't expect, including NULL on one side, compare different.
 func (q QValue) Equals(other QValue) bool {
-	if q.Kind == QValueKindJSON {
-		return true // TODO fix
-	} else if q.Value == nil && other.Value == nil {
+	if q.IsNull() && other.IsNull() {
 		return true
 	}
 
@@ -37,63 +196,63 @@ func (q QValue) Equals(other QValue) bool {
 	case QValueKindInvalid:
 		return true
 	case QValueKindFloat32:
-		return compareFloat32(q.Value, other.Value)
+		return compareFloat32(q.Value(), other.Value())
 	case QValueKindFloat64:
-		return compareFloat64(q.Value, other.Value)
+		return compareFloat64(q.Value(), other.Value())
 	case QValueKindInt16:
-		return compareInt16(q.Value, other.Value)
+		return compareInt16(q.Value(), other.Value())
 	case QValueKindInt32:
-		return compareInt32(q.Value, other.Value)
+		return compareInt32(q.Value(), other.Value())
 	case QValueKindInt64:
-		return compareInt64(q.Value, other.Value)
+		return compareInt64(q.Value(), other.Value())
 	case QValueKindBoolean:
-		return compareBoolean(q.Value, other.Value)
+		return compareBoolean(q.Value(), other.Value())
 	case QValueKindStruct:
-		return compareStruct(q.Value, other.Value)
+		return compareStruct(q.Value(), other.Value())
 	case QValueKindQChar:
-		if (q.Value == nil) == (other.Value == nil) {
-			return q.Value == nil || q.Value.(uint8) == other.Value.(uint8)
-		} else {
-			return false
-		}
-	case QValueKindString:
-		return compareString(q.Value, other.Value)
+		char1, ok1 := q.Value().(uint8)
+		char2, ok2 := other.Value().(uint8)
+		return ok1 && ok2 && char1 == char2
+	case QValueKindString,
+		QValueKindInt4Range, QValueKindInt8Range, QValueKindNumRange,
+		QValueKindTsRange, QValueKindTsTzRange, QValueKindDateRange:
+		return compareString(q.Value(), other.Value())
 	// all internally represented as a Golang time.Time
 	case QValueKindDate,
 		QValueKindTimestamp, QValueKindTimestampTZ:
-		return compareGoTime(q.Value, other.Value)
+		return compareGoTime(q.Value(), other.Value())
 	case QValueKindTime, QValueKindTimeTZ:
-		return compareGoCivilTime(q.Value, other.Value)
+		return compareGoCivilTime(q.Value(), other.Value())
 	case QValueKindNumeric:
-		return compareNumeric(q.Value, other.Value)
+		return compareNumeric(q.Value(), other.Value())
 	case QValueKindBytes:
-		return compareBytes(q.Value, other.Value)
+		return compareBytes(q.Value(), other.Value())
 	case QValueKindUUID:
-		return compareUUID(q.Value, other.Value)
+		return compareUUID(q.Value(), other.Value())
 	case QValueKindJSON:
-		return compareJSON(q.Value, other.Value)
+		return compareJSON(q.Value(), other.Value())
 	case QValueKindBit:
-		return compareBit(q.Value, other.Value)
+		return compareBit(q.Value(), other.Value())
 	case QValueKindGeometry, QValueKindGeography:
-		return compareGeometry(q.Value, other.Value)
+		return compareGeometry(q.Value(), other.Value())
 	case QValueKindHStore:
-		return compareHstore(q.Value, other.Value)
+		return compareHstore(q.Value(), other.Value())
 	case QValueKindArrayFloat32:
-		return compareNumericArrays(q.Value, other.Value)
+		return compareNumericArrays(q.Value(), other.Value())
 	case QValueKindArrayFloat64:
-		return compareNumericArrays(q.Value, other.Value)
+		return compareNumericArrays(q.Value(), other.Value())
 	case QValueKindArrayInt32, QValueKindArrayInt16:
-		return compareNumericArrays(q.Value, other.Value)
+		return compareNumericArrays(q.Value(), other.Value())
 	case QValueKindArrayInt64:
-		return compareNumericArrays(q.Value, other.Value)
+		return compareNumericArrays(q.Value(), other.Value())
 	case QValueKindArrayDate:
-		return compareDateArrays(q.Value, other.Value)
+		return compareDateArrays(q.Value(), other.Value())
 	case QValueKindArrayTimestamp, QValueKindArrayTimestampTZ:
-		return compareTimeArrays(q.Value, other.Value)
+		return compareTimeArrays(q.Value(), other.Value())
 	case QValueKindArrayBoolean:
-		return compareBoolArrays(q.Value, other.Value)
+		return compareBoolArrays(q.Value(), other.Value())
 	case QValueKindArrayString:
-		return compareArrayString(q.Value, other.Value)
+		return compareArrayString(q.Value(), other.Value())
 	default:
 		return false
 	}
@@ -101,16 +260,16 @@ func (q QValue) Equals(other QValue) bool {
 
 func (q QValue) GoTimeConvert() (string, error) {
 	if q.Kind == QValueKindTime || q.Kind == QValueKindTimeTZ {
-		return q.Value.(time.Time).Format("15:04:05.999999"), nil
+		return q.Value().(time.Time).Format("15:04:05.999999"), nil
 		// no connector supports time with timezone yet
 		// } else if q.Kind == QValueKindTimeTZ {
 		// 	return q.Value.(time.Time).Format("15:04:05.999999-0700"), nil
 	} else if q.Kind == QValueKindDate {
-		return q.Value.(time.Time).Format("2006-01-02"), nil
+		return q.Value().(time.Time).Format("2006-01-02"), nil
 	} else if q.Kind == QValueKindTimestamp {
-		return q.Value.(time.Time).Format("2006-01-02 15:04:05.999999"), nil
+		return q.Value().(time.Time).Format("2006-01-02 15:04:05.999999"), nil
 	} else if q.Kind == QValueKindTimestampTZ {
-		return q.Value.(time.Time).Format("2006-01-02 15:04:05.999999-0700"), nil
+		return q.Value().(time.Time).Format("2006-01-02 15:04:05.999999-0700"), nil
 	} else {
 		return "", fmt.Errorf("unsupported QValueKind: %s", q.Kind)
 	}
@@ -262,43 +421,41 @@ func compareString(value1, value2 interface{}) bool {
 }
 
 func compareHstore(value1, value2 interface{}) bool {
-	str2 := value2.(string)
+	str2, ok := value2.(string)
+	if !ok {
+		return false
+	}
 	switch v1 := value1.(type) {
 	case pgtype.Hstore:
 		bytes, err := json.Marshal(v1)
-		if err != nil {
-			panic(err)
-		}
-		return string(bytes) == str2
+		return err == nil && string(bytes) == str2
 	case string:
 		parsedHStore1, err := hstore_util.ParseHstore(v1)
-		if err != nil {
-			panic(err)
-		}
-		return parsedHStore1 == str2
+		return err == nil && parsedHStore1 == str2
 	default:
-		panic(fmt.Sprintf("invalid hstore value type %T: %v", value1, value1))
+		return false
 	}
 }
 
 func compareGeometry(value1, value2 interface{}) bool {
-	geo2, err := geom.NewGeomFromWKT(value2.(string))
-	if err != nil {
-		panic(err)
+	wkt2, ok := value2.(string)
+	if !ok {
+		return false
 	}
-
+	var geo1 *geom.Geom
 	switch v1 := value1.(type) {
 	case *geom.Geom:
-		return v1.Equals(geo2)
+		geo1 = v1
 	case string:
-		geo1, err := geom.NewGeomFromWKT(v1)
-		if err != nil {
-			panic(err)
+		var err error
+		if geo1, err = geom.NewGeomFromWKT(v1); err != nil {
+			return false
 		}
-		return geo1.Equals(geo2)
 	default:
-		panic(fmt.Sprintf("invalid geometry value type %T: %v", value1, value1))
+		return false
 	}
+	geo2, err := geom.NewGeomFromWKT(wkt2)
+	return err == nil && geo1 != nil && geo1.Equals(geo2)
 }
 
 func compareStruct(value1, value2 interface{}) bool {
@@ -321,9 +478,37 @@ func compareStruct(value1, value2 interface{}) bool {
 	return true
 }
 
+// compareJSON compares JSON documents by value, so that formatting and the order of object keys don't matter.
 func compareJSON(value1, value2 interface{}) bool {
-	// TODO (kaushik): fix for tests
-	return true
+	json1, ok1 := normalizeJSON(value1)
+	json2, ok2 := normalizeJSON(value2)
+	return ok1 && ok2 && reflect.DeepEqual(json1, json2)
+}
+
+func normalizeJSON(v interface{}) (interface{}, bool) {
+	var raw []byte
+	switch value := v.(type) {
+	case nil:
+		return nil, false
+	case string:
+		raw = []byte(value)
+	case []byte:
+		raw = value
+	case json.RawMessage:
+		raw = value
+	default:
+		// already decoded, e.g. into a map, normalized by encoding it again
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, false
+		}
+		raw = encoded
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, false
+	}
+	return normalized, true
 }
 
 func compareBit(value1, value2 interface{}) bool {
@@ -658,3 +843,37 @@ func getRat(v interface{}) (*big.Rat, bool) {
 	}
 	return nil, false
 }
+
+// EstimatedSize approximates the bytes taken up by a value, for reporting how much data was replicated.
+func (q QValue) EstimatedSize() int {
+	switch v := q.val.(type) {
+	case nil:
+		return 0
+	case boolTag, uint8Tag:
+		return 1
+	case int16Tag:
+		return 2
+	case int32Tag, float32Tag:
+		return 4
+	case int64Tag, float64Tag, timeTag:
+		return 8
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case int8:
+		return 1
+	case uint16:
+		return 2
+	case uint32, civil.Date:
+		return 4
+	case int, uint, uint64, civil.Time:
+		return 8
+	case [16]byte, uuid.UUID:
+		return 16
+	case *big.Rat:
+		return (v.Num().BitLen() + v.Denom().BitLen() + 7) / 8
+	default:
+		return len(fmt.Sprint(v))
+	}
+}
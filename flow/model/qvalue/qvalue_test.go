@@ -0,0 +1,116 @@
+package qvalue
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQValueValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.FixedZone("UTC+5", 5*60*60))
+	for _, value := range []interface{}{
+		true,
+		false,
+		int16(-2),
+		int32(math.MinInt32),
+		int64(math.MaxInt64),
+		uint8(255),
+		float32(-1.5),
+		math.Inf(1),
+		ts,
+		time.Time{},
+		"text",
+		[]byte("bytes"),
+		big.NewRat(1, 3),
+		[]int64{1, 2},
+	} {
+		q := New(QValueKindInvalid, value)
+		assert.False(t, q.IsNull())
+		assert.Equal(t, value, q.Value(), "%T", value)
+	}
+
+	q := New(QValueKindTimestampTZ, ts)
+	require.True(t, q.Scalar())
+	got, ok := q.Time()
+	require.True(t, ok)
+	assert.Equal(t, ts.Location(), got.Location())
+
+	q = New(QValueKindInt64, nil)
+	assert.True(t, q.IsNull())
+	assert.Nil(t, q.Value())
+	assert.Equal(t, QValue{Kind: QValueKindInt64}, q)
+}
+
+func TestQValueAccessors(t *testing.T) {
+	i, ok := NewInt32(QValueKindInt32, -7).Int64()
+	require.True(t, ok)
+	assert.Equal(t, int64(-7), i)
+	_, ok = NewFloat64(QValueKindFloat64, 1).Int64()
+	assert.False(t, ok)
+
+	f, ok := NewFloat32(QValueKindFloat32, 0.5).Float64()
+	require.True(t, ok)
+	assert.InDelta(t, 0.5, f, 0)
+	_, ok = New(QValueKindString, "1").Float64()
+	assert.False(t, ok)
+
+	_, ok = NewInt64(QValueKindInt64, 1).Time()
+	assert.False(t, ok)
+	assert.False(t, New(QValueKindString, "a").Scalar())
+}
+
+func TestQValueAllocs(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	values := make([]QValue, 5)
+	allocs := testing.AllocsPerRun(100, func() {
+		values[0] = NewBool(QValueKindBoolean, true)
+		values[1] = NewInt32(QValueKindInt32, 1<<20)
+		values[2] = NewInt64(QValueKindInt64, 1<<40)
+		values[3] = NewFloat64(QValueKindFloat64, 1234.5)
+		values[4] = NewTime(QValueKindTimestamp, ts)
+		for _, value := range values {
+			_ = value.IsNull()
+			_, _ = value.Int64()
+			_, _ = value.Float64()
+			_, _ = value.Time()
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestQValueEqualsNull(t *testing.T) {
+	for _, q := range []QValue{
+		New(QValueKindString, "text"),
+		NewUint8(QValueKindQChar, 'a'),
+		New(QValueKindHStore, `"a"=>"1"`),
+		New(QValueKindGeometry, "POINT(1 2)"),
+		New(QValueKindJSON, `{"a":1}`),
+		NewInt64(QValueKindInt64, 1),
+	} {
+		null := QValue{Kind: q.Kind}
+		assert.False(t, q.Equals(null), "%s against NULL", q.Kind)
+		assert.False(t, null.Equals(q), "NULL against %s", q.Kind)
+		assert.True(t, null.Equals(null), "NULL against NULL %s", q.Kind)
+	}
+}
+
+func TestQValueEqualsMismatchedKinds(t *testing.T) {
+	assert.False(t, NewUint8(QValueKindQChar, 'a').Equals(New(QValueKindString, "a")))
+	assert.False(t, New(QValueKindHStore, `"a"=>"1"`).Equals(NewInt64(QValueKindInt64, 1)))
+	assert.False(t, New(QValueKindHStore, NewInt64(QValueKindInt64, 1)).Equals(New(QValueKindString, "")))
+	assert.False(t, New(QValueKindGeometry, "POINT(1 2)").Equals(NewFloat64(QValueKindFloat64, 1)))
+	assert.False(t, New(QValueKindGeometry, int64(1)).Equals(New(QValueKindString, "POINT(1 2)")))
+	assert.False(t, New(QValueKindJSON, `{"a":1}`).Equals(NewBool(QValueKindBoolean, true)))
+}
+
+func TestQValueEqualsJSON(t *testing.T) {
+	doc := New(QValueKindJSON, `{"a": 1, "b": [true, null]}`)
+	assert.True(t, doc.Equals(New(QValueKindJSON, `{"b":[true,null],"a":1}`)), "formatting and key order don't matter")
+	assert.True(t, doc.Equals(New(QValueKindJSON, map[string]interface{}{"a": 1, "b": []interface{}{true, nil}})))
+	assert.False(t, doc.Equals(New(QValueKindJSON, `{"a": 2, "b": [true, null]}`)))
+	assert.False(t, doc.Equals(New(QValueKindJSON, `{"a": 1`)), "invalid documents compare different")
+}
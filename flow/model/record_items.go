@@ -1,11 +1,14 @@
 package model
 
 import (
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"reflect"
 	"time"
 
 	hstore_util "github.com/PeerDB-io/peer-flow/hstore"
@@ -18,6 +21,46 @@ type RecordItems struct {
 	Values      []qvalue.QValue
 }
 
+// gobRecordItems is how RecordItems are encoded with encoding/gob, values are encoded boxed
+type gobRecordItems struct {
+	ColToValIdx map[string]int
+	Kinds       []qvalue.QValueKind
+	Values      []interface{}
+}
+
+func (r *RecordItems) GobEncode() ([]byte, error) {
+	items := gobRecordItems{
+		ColToValIdx: r.ColToValIdx,
+		Kinds:       make([]qvalue.QValueKind, 0, len(r.Values)),
+		Values:      make([]interface{}, 0, len(r.Values)),
+	}
+	for _, val := range r.Values {
+		items.Kinds = append(items.Kinds, val.Kind)
+		items.Values = append(items.Values, val.Value())
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&items); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *RecordItems) GobDecode(data []byte) error {
+	var items gobRecordItems
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&items); err != nil {
+		return err
+	}
+	if len(items.Kinds) != len(items.Values) {
+		return fmt.Errorf("record items have %d kinds for %d values", len(items.Kinds), len(items.Values))
+	}
+	r.ColToValIdx = items.ColToValIdx
+	r.Values = make([]qvalue.QValue, 0, len(items.Values))
+	for i, val := range items.Values {
+		r.Values = append(r.Values, qvalue.New(items.Kinds[i], val))
+	}
+	return nil
+}
+
 func NewRecordItems(capacity int) *RecordItems {
 	return &RecordItems{
 		ColToValIdx: make(map[string]int, capacity),
@@ -78,6 +121,46 @@ func (r *RecordItems) Len() int {
 	return len(r.Values)
 }
 
+// EstimatedSize approximates how many bytes the values take, counting strings and bytes by length
+// and other values by their fixed size, which is what makes batches with wide JSON or TOAST columns large.
+func (r *RecordItems) EstimatedSize() int {
+	if r == nil {
+		return 0
+	}
+	size := 0
+	for _, val := range r.Values {
+		if val.Scalar() {
+			size += 8
+		} else {
+			size += estimatedValueSize(val.Value())
+		}
+	}
+	return size
+}
+
+func estimatedValueSize(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case []string:
+		size := 0
+		for _, s := range v {
+			size += len(s)
+		}
+		return size
+	case *big.Rat:
+		return 16
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice {
+		return rv.Len() * 8
+	}
+	return 8
+}
+
 func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 	if r.ColToValIdx == nil {
 		return nil, errors.New("colToValIdx is nil")
@@ -86,7 +169,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 	jsonStruct := make(map[string]interface{}, len(r.ColToValIdx))
 	for col, idx := range r.ColToValIdx {
 		v := r.Values[idx]
-		if v.Value == nil {
+		if v.IsNull() {
 			jsonStruct[col] = nil
 			continue
 		}
@@ -94,7 +177,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 		var err error
 		switch v.Kind {
 		case qvalue.QValueKindBit, qvalue.QValueKindBytes:
-			bitVal, ok := v.Value.([]byte)
+			bitVal, ok := v.Value().([]byte)
 			if !ok {
 				return nil, errors.New("expected []byte value")
 			}
@@ -109,16 +192,18 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 
 			jsonStruct[col] = binStr
 		case qvalue.QValueKindQChar:
-			ch, ok := v.Value.(uint8)
+			ch, ok := v.Value().(uint8)
 			if !ok {
-				return nil, fmt.Errorf("expected \"char\" value for column %s for %T", col, v.Value)
+				return nil, fmt.Errorf("expected \"char\" value for column %s for %T", col, v.Value())
 			}
 
 			jsonStruct[col] = string(ch)
-		case qvalue.QValueKindString, qvalue.QValueKindJSON:
-			strVal, ok := v.Value.(string)
+		case qvalue.QValueKindString, qvalue.QValueKindJSON,
+			qvalue.QValueKindInt4Range, qvalue.QValueKindInt8Range, qvalue.QValueKindNumRange,
+			qvalue.QValueKindTsRange, qvalue.QValueKindTsTzRange, qvalue.QValueKindDateRange:
+			strVal, ok := v.Value().(string)
 			if !ok {
-				return nil, fmt.Errorf("expected string value for column %s for %T", col, v.Value)
+				return nil, fmt.Errorf("expected string value for column %s for %T", col, v.Value())
 			}
 
 			if len(strVal) > 15*1024*1024 {
@@ -127,9 +212,9 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 				jsonStruct[col] = strVal
 			}
 		case qvalue.QValueKindHStore:
-			hstoreVal, ok := v.Value.(string)
+			hstoreVal, ok := v.Value().(string)
 			if !ok {
-				return nil, fmt.Errorf("expected string value for hstore column %s for value %T", col, v.Value)
+				return nil, fmt.Errorf("expected string value for hstore column %s for value %T", col, v.Value())
 			}
 
 			if !hstoreAsJSON {
@@ -137,7 +222,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 			} else {
 				jsonVal, err := hstore_util.ParseHstore(hstoreVal)
 				if err != nil {
-					return nil, fmt.Errorf("unable to convert hstore column %s to json for value %T", col, v.Value)
+					return nil, fmt.Errorf("unable to convert hstore column %s to json for value %T", col, v.Value())
 				}
 
 				if len(jsonVal) > 15*1024*1024 {
@@ -154,7 +239,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 				return nil, err
 			}
 		case qvalue.QValueKindArrayDate:
-			dateArr, ok := v.Value.([]time.Time)
+			dateArr, ok := v.Value().([]time.Time)
 			if !ok {
 				return nil, errors.New("expected []time.Time value")
 			}
@@ -164,7 +249,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 			}
 			jsonStruct[col] = formattedDateArr
 		case qvalue.QValueKindNumeric:
-			bigRat, ok := v.Value.(*big.Rat)
+			bigRat, ok := v.Value().(*big.Rat)
 			if !ok {
 				return nil, errors.New("expected *big.Rat value")
 			}
@@ -173,9 +258,9 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 				jsonStruct[col] = nil
 				continue
 			}
-			jsonStruct[col] = bigRat.FloatString(100)
+			jsonStruct[col] = qvalue.NumericString(bigRat)
 		case qvalue.QValueKindFloat64:
-			floatVal, ok := v.Value.(float64)
+			floatVal, ok := v.Value().(float64)
 			if !ok {
 				return nil, errors.New("expected float64 value")
 			}
@@ -185,7 +270,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 				jsonStruct[col] = floatVal
 			}
 		case qvalue.QValueKindFloat32:
-			floatVal, ok := v.Value.(float32)
+			floatVal, ok := v.Value().(float32)
 			if !ok {
 				return nil, errors.New("expected float32 value")
 			}
@@ -195,7 +280,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 				jsonStruct[col] = floatVal
 			}
 		case qvalue.QValueKindArrayFloat64:
-			floatArr, ok := v.Value.([]float64)
+			floatArr, ok := v.Value().([]float64)
 			if !ok {
 				return nil, errors.New("expected []float64 value")
 			}
@@ -210,7 +295,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 			}
 			jsonStruct[col] = nullableFloatArr
 		case qvalue.QValueKindArrayFloat32:
-			floatArr, ok := v.Value.([]float32)
+			floatArr, ok := v.Value().([]float32)
 			if !ok {
 				return nil, errors.New("expected []float32 value")
 			}
@@ -225,7 +310,7 @@ func (r *RecordItems) toMap(hstoreAsJSON bool) (map[string]interface{}, error) {
 			jsonStruct[col] = nullableFloatArr
 
 		default:
-			jsonStruct[col] = v.Value
+			jsonStruct[col] = v.Value()
 		}
 	}
 
@@ -254,7 +339,7 @@ func (r *RecordItems) ToJSONWithOpts(opts *ToJSONOptions) (string, error) {
 		if v.Kind == qvalue.QValueKindJSON {
 			if _, ok := opts.UnnestColumns[col]; ok {
 				var unnestStruct map[string]interface{}
-				err := json.Unmarshal([]byte(v.Value.(string)), &unnestStruct)
+				err := json.Unmarshal([]byte(v.Value().(string)), &unnestStruct)
 				if err != nil {
 					return "", err
 				}
@@ -0,0 +1,24 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestEstimatedRecordSize(t *testing.T) {
+	items := model.NewRecordItems(4)
+	items.AddColumn("id", qvalue.NewInt64(qvalue.QValueKindInt64, int64(1)))
+	items.AddColumn("doc", qvalue.New(qvalue.QValueKindJSON, strings.Repeat("x", 1000)))
+	items.AddColumn("tags", qvalue.New(qvalue.QValueKindArrayString, []string{"a", "bc"}))
+	items.AddColumn("deleted_at", qvalue.QValue{Kind: qvalue.QValueKindTimestamp})
+	require.Equal(t, 8+1000+3, items.EstimatedSize())
+
+	require.Equal(t, 1011, model.EstimatedRecordSize(&model.InsertRecord{Items: items}))
+	require.Equal(t, 2022, model.EstimatedRecordSize(&model.UpdateRecord{OldItems: items, NewItems: items}))
+	require.Zero(t, model.EstimatedRecordSize(&model.RelationRecord{}))
+}
@@ -0,0 +1,289 @@
+package rowfilter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenKeyword
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	text string
+	kind tokenKind
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("`%s`", t.text)
+}
+
+var keywords = []string{"and", "or", "not", "is", "in", "null", "true", "false"}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ","})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{kind: tokenOperator, text: "="})
+			i++
+		case r == '<' || r == '>' || r == '!':
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+			if op == "!" {
+				return nil, errors.New("unexpected `!` in row filter")
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op})
+			i += len(op)
+		case r == '\'' || r == '"':
+			// quotes are escaped by doubling them
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == r {
+					if j+1 < len(runes) && runes[j+1] == r {
+						j++
+					} else {
+						break
+					}
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated %c in row filter", r)
+			}
+			kind := tokenString
+			if r == '"' {
+				kind = tokenIdent
+			}
+			tokens = append(tokens, token{kind: kind, text: sb.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || r == '-' || r == '.':
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' ||
+				runes[j] == 'e' || runes[j] == 'E' || ((runes[j] == '-' || runes[j] == '+') && unicode.ToLower(runes[j-1]) == 'e')) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '$') {
+				j++
+			}
+			word := strings.ToLower(string(runes[i:j]))
+			kind := tokenIdent
+			if slices.Contains(keywords, word) {
+				kind = tokenKeyword
+			}
+			tokens = append(tokens, token{kind: kind, text: word})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected `%c` in row filter", r)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+	tokens  []token
+	columns []string
+	pos     int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if t := p.peek(); t.kind == tokenKeyword && t.text == keyword {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, what string) error {
+	if t := p.next(); t.kind != kind {
+		return fmt.Errorf("expected %s in row filter, found %s", what, t)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.acceptKeyword("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (node, error) {
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, "`)`"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch t := p.peek(); {
+	case t.kind == tokenOperator:
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{left: left, right: right, op: t.text}, nil
+	case p.acceptKeyword("is"):
+		not := p.acceptKeyword("not")
+		if !p.acceptKeyword("null") {
+			return nil, fmt.Errorf("expected `null` in row filter, found %s", p.peek())
+		}
+		return isNullNode{operand: left, not: not}, nil
+	case p.acceptKeyword("not"):
+		if !p.acceptKeyword("in") {
+			return nil, fmt.Errorf("expected `in` in row filter, found %s", p.peek())
+		}
+		return p.parseIn(left, true)
+	case p.acceptKeyword("in"):
+		return p.parseIn(left, false)
+	default:
+		return valueNode{operand: left}, nil
+	}
+}
+
+func (p *parser) parseIn(left operand, not bool) (node, error) {
+	if err := p.expect(tokenLParen, "`(`"); err != nil {
+		return nil, err
+	}
+	var list []operand
+	for {
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if item.column != "" {
+			return nil, fmt.Errorf("only literals are supported in the list of row filter IN, found column %s", item.column)
+		}
+		list = append(list, item)
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(tokenRParen, "`)`"); err != nil {
+		return nil, err
+	}
+	return inNode{operand: left, list: list, not: not}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenIdent:
+		if !slices.Contains(p.columns, t.text) {
+			p.columns = append(p.columns, t.text)
+		}
+		return operand{column: t.text}, nil
+	case tokenString:
+		return operand{literal: t.text}, nil
+	case tokenNumber:
+		rat, ok := new(big.Rat).SetString(t.text)
+		if !ok {
+			return operand{}, fmt.Errorf("invalid number %s in row filter", t.text)
+		}
+		return operand{literal: rat}, nil
+	case tokenKeyword:
+		switch t.text {
+		case "true":
+			return operand{literal: true}, nil
+		case "false":
+			return operand{literal: false}, nil
+		case "null":
+			return operand{literal: nil}, nil
+		}
+	}
+	return operand{}, fmt.Errorf("expected a column or literal in row filter, found %s", t)
+}
@@ -0,0 +1,359 @@
+// Package rowfilter evaluates the row filters of mirrors, a subset of SQL WHERE clauses
+// such as `tenant_id = 42 AND NOT is_test`, against replicated records.
+package rowfilter
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrMissingColumn is returned when a record doesn't carry a column the filter depends on,
+// e.g. an unchanged TOAST column of an update, so whether it matches can't be told.
+var ErrMissingColumn = errors.New("row filter column missing from record")
+
+// Filter is a parsed row filter.
+type Filter struct {
+	expr    node
+	source  string
+	columns []string
+}
+
+// Parse parses a row filter, which supports comparisons of columns and literals with =, <>, !=, <, <=, >, >=,
+// IS [NOT] NULL, [NOT] IN (...), combined with AND, OR, NOT and parentheses.
+// Unquoted column names are folded to lowercase like Postgres does.
+func Parse(source string) (*Filter, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s in row filter", p.peek())
+	}
+	return &Filter{expr: expr, source: source, columns: p.columns}, nil
+}
+
+// String returns the filter as it was written, to be used in SQL queries.
+func (f *Filter) String() string {
+	return f.source
+}
+
+// Columns returns the columns the filter depends on.
+func (f *Filter) Columns() []string {
+	return f.columns
+}
+
+// Match returns whether a record satisfies the filter, lookup returns the values of its columns.
+// Like in SQL a comparison with null is never satisfied.
+func (f *Filter) Match(lookup func(column string) (any, bool)) (bool, error) {
+	result, err := f.expr.eval(lookup)
+	if err != nil {
+		return false, err
+	}
+	return result == truthTrue, nil
+}
+
+type truth int8
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	truthUnknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+type node interface {
+	eval(lookup func(string) (any, bool)) (truth, error)
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	left, err := n.left.eval(lookup)
+	if err != nil || left == truthFalse {
+		return left, err
+	}
+	right, err := n.right.eval(lookup)
+	if err != nil || right == truthFalse {
+		return right, err
+	}
+	if left == truthUnknown || right == truthUnknown {
+		return truthUnknown, nil
+	}
+	return truthTrue, nil
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	left, err := n.left.eval(lookup)
+	if err != nil || left == truthTrue {
+		return left, err
+	}
+	right, err := n.right.eval(lookup)
+	if err != nil || right == truthTrue {
+		return right, err
+	}
+	if left == truthUnknown || right == truthUnknown {
+		return truthUnknown, nil
+	}
+	return truthFalse, nil
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	operand, err := n.operand.eval(lookup)
+	if err != nil {
+		return operand, err
+	}
+	return negate(operand), nil
+}
+
+func negate(t truth) truth {
+	switch t {
+	case truthTrue:
+		return truthFalse
+	case truthFalse:
+		return truthTrue
+	default:
+		return truthUnknown
+	}
+}
+
+// operand is either a column or a literal, literals are nil, bool, string or *big.Rat
+type operand struct {
+	column  string
+	literal any
+}
+
+func (o operand) value(lookup func(string) (any, bool)) (any, error) {
+	if o.column == "" {
+		return o.literal, nil
+	}
+	value, ok := lookup(o.column)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissingColumn, o.column)
+	}
+	// numerics that are NaN or infinite are read as nil
+	if rat, ok := value.(*big.Rat); ok && rat == nil {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// valueNode is a bare boolean column or literal, as in `NOT is_test`
+type valueNode struct{ operand operand }
+
+func (n valueNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	value, err := n.operand.value(lookup)
+	if err != nil {
+		return truthUnknown, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return truthUnknown, nil
+	case bool:
+		return truthOf(v), nil
+	default:
+		return truthUnknown, fmt.Errorf("row filter operand %v is not a boolean", value)
+	}
+}
+
+type isNullNode struct {
+	operand operand
+	not     bool
+}
+
+func (n isNullNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	value, err := n.operand.value(lookup)
+	if err != nil {
+		return truthUnknown, err
+	}
+	return truthOf((value == nil) != n.not), nil
+}
+
+type compareNode struct {
+	left, right operand
+	op          string
+}
+
+func (n compareNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	left, err := n.left.value(lookup)
+	if err != nil {
+		return truthUnknown, err
+	}
+	right, err := n.right.value(lookup)
+	if err != nil {
+		return truthUnknown, err
+	}
+	cmp, ok, err := compare(left, right)
+	if err != nil || !ok {
+		return truthUnknown, err
+	}
+	switch n.op {
+	case "=":
+		return truthOf(cmp == 0), nil
+	case "<>", "!=":
+		return truthOf(cmp != 0), nil
+	case "<":
+		return truthOf(cmp < 0), nil
+	case "<=":
+		return truthOf(cmp <= 0), nil
+	case ">":
+		return truthOf(cmp > 0), nil
+	case ">=":
+		return truthOf(cmp >= 0), nil
+	default:
+		return truthUnknown, fmt.Errorf("unknown row filter operator %s", n.op)
+	}
+}
+
+type inNode struct {
+	operand operand
+	list    []operand
+	not     bool
+}
+
+func (n inNode) eval(lookup func(string) (any, bool)) (truth, error) {
+	value, err := n.operand.value(lookup)
+	if err != nil {
+		return truthUnknown, err
+	}
+	result := truthFalse
+	for _, item := range n.list {
+		cmp, ok, err := compare(value, item.literal)
+		if err != nil {
+			return truthUnknown, err
+		}
+		if !ok {
+			result = truthUnknown
+		} else if cmp == 0 {
+			result = truthTrue
+			break
+		}
+	}
+	if n.not {
+		return negate(result), nil
+	}
+	return result, nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// compare orders two values, ok is false when either is null
+func compare(left any, right any) (int, bool, error) {
+	if left == nil || right == nil {
+		return 0, false, nil
+	}
+	if l, isNum := toRat(left); isNum {
+		if r, isNum := toRat(right); isNum {
+			return l.Cmp(r), true, nil
+		}
+	}
+	switch l := left.(type) {
+	case bool:
+		if r, ok := right.(bool); ok {
+			return compareBool(l, r), true, nil
+		}
+	case time.Time:
+		if r, ok := toTime(right); ok {
+			return l.Compare(r), true, nil
+		}
+	default:
+		if r, ok := right.(time.Time); ok {
+			if l, ok := toTime(left); ok {
+				return l.Compare(r), true, nil
+			}
+		}
+		if l, ok := toString(left); ok {
+			if r, ok := toString(right); ok {
+				return strings.Compare(l, r), true, nil
+			}
+		}
+	}
+	return 0, false, fmt.Errorf("can't compare %v (%T) with %v (%T) in row filter", left, left, right, right)
+}
+
+func compareBool(left bool, right bool) int {
+	switch {
+	case left == right:
+		return 0
+	case right:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func toRat(value any) (*big.Rat, bool) {
+	switch v := value.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int16:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case float32:
+		rat := new(big.Rat).SetFloat64(float64(v))
+		return rat, rat != nil
+	case float64:
+		rat := new(big.Rat).SetFloat64(v)
+		return rat, rat != nil
+	case *big.Rat:
+		return v, v != nil
+	default:
+		return nil, false
+	}
+}
+
+func toTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func toString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case uint8:
+		return string(rune(v)), true
+	case [16]byte:
+		return uuid.UUID(v).String(), true
+	default:
+		return "", false
+	}
+}
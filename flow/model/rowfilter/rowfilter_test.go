@@ -0,0 +1,78 @@
+package rowfilter
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func lookupMap(values map[string]any) func(string) (any, bool) {
+	return func(column string) (any, bool) {
+		value, ok := values[column]
+		return value, ok
+	}
+}
+
+func TestParse(t *testing.T) {
+	filter, err := Parse(`Tenant_ID = 42 AND NOT "isTest" AND (region IN ('eu', 'us') OR deleted_at IS NOT NULL)`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenant_id", "isTest", "region", "deleted_at"}, filter.Columns())
+
+	for _, invalid := range []string{"", "a =", "a = 'b", "a IN (b)", "(a = 1", "a = 1 b", "a ! 1", "a IS 1"} {
+		_, err := Parse(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	filter, err := Parse(`tenant_id = 42 AND NOT is_test`)
+	require.NoError(t, err)
+
+	match, err := filter.Match(lookupMap(map[string]any{"tenant_id": int64(42), "is_test": false}))
+	require.NoError(t, err)
+	require.True(t, match)
+
+	match, err = filter.Match(lookupMap(map[string]any{"tenant_id": int32(7), "is_test": false}))
+	require.NoError(t, err)
+	require.False(t, match)
+
+	// null compares as unknown, which doesn't match
+	match, err = filter.Match(lookupMap(map[string]any{"tenant_id": nil, "is_test": false}))
+	require.NoError(t, err)
+	require.False(t, match)
+
+	_, err = filter.Match(lookupMap(map[string]any{"tenant_id": int64(42)}))
+	require.ErrorIs(t, err, ErrMissingColumn)
+
+	// a false conjunct decides without the missing column
+	match, err = filter.Match(lookupMap(map[string]any{"tenant_id": int64(7)}))
+	require.NoError(t, err)
+	require.False(t, match)
+
+	filter, err = Parse(`amount >= 10.5 AND code NOT IN ('a', 'b') AND created_at < '2024-01-01' AND note IS NULL`)
+	require.NoError(t, err)
+	match, err = filter.Match(lookupMap(map[string]any{
+		"amount":     big.NewRat(21, 2),
+		"code":       "c",
+		"created_at": time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		"note":       nil,
+	}))
+	require.NoError(t, err)
+	require.True(t, match)
+
+	match, err = filter.Match(lookupMap(map[string]any{
+		"amount":     float64(10),
+		"code":       "c",
+		"created_at": time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		"note":       nil,
+	}))
+	require.NoError(t, err)
+	require.False(t, match)
+
+	filter, err = Parse(`kind = 42`)
+	require.NoError(t, err)
+	_, err = filter.Match(lookupMap(map[string]any{"kind": "answer"}))
+	require.Error(t, err, "strings don't compare with numbers")
+}
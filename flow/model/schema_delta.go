@@ -0,0 +1,145 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+// IsEmptySchemaDelta returns whether a schema delta doesn't change its table.
+func IsEmptySchemaDelta(delta *protos.TableSchemaDelta) bool {
+	return delta == nil || (len(delta.AddedColumns) == 0 && len(delta.DroppedColumns) == 0 &&
+		len(delta.AlteredColumns) == 0 && len(delta.RenamedColumns) == 0 && len(delta.NarrowedColumns) == 0)
+}
+
+// filterSchemaDelta leaves the changes to columns a table mapping leaves out of the destination table out of a delta,
+// and changes the types of masked columns to what they are masked to.
+// Renaming a column out of the mapped columns drops it, renaming one into them adds it.
+// Tables whose columns a transformation script declares aren't changed by deltas at all.
+func filterSchemaDelta(tm NameAndExclude, delta *protos.TableSchemaDelta) *protos.TableSchemaDelta {
+	if tm.DeclaredColumns {
+		return &protos.TableSchemaDelta{SrcTableName: delta.SrcTableName, DstTableName: delta.DstTableName}
+	}
+	if !tm.Filtered() && len(tm.Masks) == 0 {
+		return delta
+	}
+	maskedType := func(column string, columnType string) string {
+		if mask, ok := tm.Masks[column]; ok {
+			return string(MaskedKind(mask.Policy, qvalue.QValueKind(columnType)))
+		}
+		return columnType
+	}
+
+	filtered := &protos.TableSchemaDelta{
+		SrcTableName: delta.SrcTableName,
+		DstTableName: delta.DstTableName,
+	}
+	for _, column := range delta.AddedColumns {
+		if !tm.Excluded(column.ColumnName) {
+			filtered.AddedColumns = append(filtered.AddedColumns, &protos.DeltaAddedColumn{
+				ColumnName: column.ColumnName,
+				ColumnType: maskedType(column.ColumnName, column.ColumnType),
+			})
+		}
+	}
+	for _, column := range delta.DroppedColumns {
+		if !tm.Excluded(column) {
+			filtered.DroppedColumns = append(filtered.DroppedColumns, column)
+		}
+	}
+	for _, column := range delta.AlteredColumns {
+		// masked columns keep the type they are masked to
+		if !tm.Excluded(column.ColumnName) && maskedType(column.ColumnName, column.NewType) == column.NewType {
+			filtered.AlteredColumns = append(filtered.AlteredColumns, column)
+		}
+	}
+	for _, column := range delta.NarrowedColumns {
+		if !tm.Excluded(column.ColumnName) && maskedType(column.ColumnName, column.NewType) == column.NewType {
+			filtered.NarrowedColumns = append(filtered.NarrowedColumns, column)
+		}
+	}
+	for _, column := range delta.RenamedColumns {
+		oldExcluded, newExcluded := tm.Excluded(column.OldName), tm.Excluded(column.NewName)
+		switch {
+		case oldExcluded && !newExcluded:
+			filtered.AddedColumns = append(filtered.AddedColumns, &protos.DeltaAddedColumn{
+				ColumnName: column.NewName,
+				ColumnType: maskedType(column.NewName, column.ColumnType),
+			})
+		case !oldExcluded && newExcluded:
+			filtered.DroppedColumns = append(filtered.DroppedColumns, column.OldName)
+		case !oldExcluded:
+			filtered.RenamedColumns = append(filtered.RenamedColumns, &protos.DeltaRenamedColumn{
+				OldName:    column.OldName,
+				NewName:    column.NewName,
+				ColumnType: maskedType(column.NewName, column.ColumnType),
+			})
+		}
+	}
+	return filtered
+}
+
+// splitSchemaDelta splits the dropped, widened and renamed columns the policy doesn't apply off a delta.
+// It returns the delta to replay on the destination and, for SCHEMA_CHANGE_PAUSE, the changes to pause on.
+func splitSchemaDelta(
+	delta *protos.TableSchemaDelta,
+	policy protos.SchemaChangePolicy,
+) (*protos.TableSchemaDelta, *protos.TableSchemaDelta) {
+	if policy == protos.SchemaChangePolicy_SCHEMA_CHANGE_APPLY ||
+		(len(delta.DroppedColumns) == 0 && len(delta.AlteredColumns) == 0 && len(delta.RenamedColumns) == 0) {
+		return delta, nil
+	}
+
+	applied := &protos.TableSchemaDelta{
+		SrcTableName: delta.SrcTableName,
+		DstTableName: delta.DstTableName,
+		AddedColumns: slices.Clone(delta.AddedColumns),
+	}
+	if policy == protos.SchemaChangePolicy_SCHEMA_CHANGE_PAUSE {
+		return applied, &protos.TableSchemaDelta{
+			SrcTableName:   delta.SrcTableName,
+			DstTableName:   delta.DstTableName,
+			DroppedColumns: delta.DroppedColumns,
+			AlteredColumns: delta.AlteredColumns,
+			RenamedColumns: delta.RenamedColumns,
+		}
+	}
+	// renamed columns are added under their new name, leaving the old one in place
+	for _, column := range delta.RenamedColumns {
+		applied.AddedColumns = append(applied.AddedColumns, &protos.DeltaAddedColumn{
+			ColumnName: column.NewName,
+			ColumnType: column.ColumnType,
+		})
+	}
+	return applied, nil
+}
+
+// splitIncompatibleSchemaDelta splits the changes destinations can't follow off a delta, narrowed columns and dropped
+// primary key columns of tableSchema, which may be nil when the table's schema isn't known.
+// It returns the rest of the delta and those changes.
+func splitIncompatibleSchemaDelta(
+	delta *protos.TableSchemaDelta,
+	tableSchema *protos.TableSchema,
+) (*protos.TableSchemaDelta, *protos.TableSchemaDelta) {
+	rest := &protos.TableSchemaDelta{
+		SrcTableName:   delta.SrcTableName,
+		DstTableName:   delta.DstTableName,
+		AddedColumns:   delta.AddedColumns,
+		AlteredColumns: delta.AlteredColumns,
+		RenamedColumns: delta.RenamedColumns,
+	}
+	incompatible := &protos.TableSchemaDelta{
+		SrcTableName:    delta.SrcTableName,
+		DstTableName:    delta.DstTableName,
+		NarrowedColumns: delta.NarrowedColumns,
+	}
+	for _, column := range delta.DroppedColumns {
+		if slices.Contains(tableSchema.GetPrimaryKeyColumns(), column) {
+			incompatible.DroppedColumns = append(incompatible.DroppedColumns, column)
+		} else {
+			rest.DroppedColumns = append(rest.DroppedColumns, column)
+		}
+	}
+	return rest, incompatible
+}
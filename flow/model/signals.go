@@ -130,10 +130,33 @@ var CDCDynamicPropertiesSignal = TypedSignal[*protos.CDCFlowConfigUpdate]{
 	Name: "cdc-dynamic-properties",
 }
 
+var QRepDynamicPropertiesSignal = TypedSignal[*protos.QRepFlowConfigUpdate]{
+	Name: "qrep-dynamic-properties",
+}
+
 var NormalizeSignal = TypedSignal[NormalizePayload]{
 	Name: "normalize",
 }
 
+// NormalizeTriggerSignal asks a CDC flow to normalize the batches pending under its normalize schedule.
+var NormalizeTriggerSignal = TypedSignal[struct{}]{
+	Name: "normalize-trigger",
+}
+
+// LeaseRequestSignal asks the global scheduler for a lease, which answers with a LeaseGrantedSignal once granted.
+var LeaseRequestSignal = TypedSignal[*LeaseRequest]{
+	Name: "lease-request",
+}
+
+var LeaseReleaseSignal = TypedSignal[*LeaseRequest]{
+	Name: "lease-release",
+}
+
+// LeaseGrantedSignal carries the id of the lease request granted.
+var LeaseGrantedSignal = TypedSignal[string]{
+	Name: "lease-granted",
+}
+
 var NormalizeErrorSignal = TypedSignal[string]{
 	Name: "normalize-error",
 }
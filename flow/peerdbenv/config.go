@@ -6,7 +6,9 @@ import (
 
 // This file contains functions to get the values of various peerdb environment
 // variables. This will help catalog the environment variables that are used
-// throughout the codebase.
+// throughout the codebase. Settings tunable at runtime, from the catalog and per mirror, are in dynamicconf.
+// Variables are also listed in registry.go, which documents them and checks them on startup.
+// Durations are either counted in the unit their name ends with, or Go durations like 90s.
 
 // PEERDB_VERSION_SHA_SHORT
 func PeerDBVersionShaShort() string {
@@ -23,12 +25,6 @@ func PeerDBCDCChannelBufferSize() int {
 	return getEnvInt("PEERDB_CDC_CHANNEL_BUFFER_SIZE", 1<<18)
 }
 
-// PEERDB_EVENTHUB_FLUSH_TIMEOUT_SECONDS
-func PeerDBEventhubFlushTimeoutSeconds() time.Duration {
-	x := getEnvInt("PEERDB_EVENTHUB_FLUSH_TIMEOUT_SECONDS", 10)
-	return time.Duration(x) * time.Second
-}
-
 // env variable doesn't exist anymore, but tests appear to depend on this
 // in lieu of an actual value of IdleTimeoutSeconds
 func PeerDBCDCIdleTimeoutSeconds(providedValue int) time.Duration {
@@ -51,6 +47,11 @@ func PeerDBCDCDiskSpillMemPercentThreshold() int {
 	return getEnvInt("PEERDB_CDC_DISK_SPILL_MEM_PERCENT_THRESHOLD", -1)
 }
 
+// PEERDB_CDC_STREAM_SPILL_TO_DISK, once PEERDB_CDC_CHANNEL_BUFFER_SIZE records are buffered the rest spill to disk
+func PeerDBCDCStreamSpillToDisk() bool {
+	return getEnvBool("PEERDB_CDC_STREAM_SPILL_TO_DISK", false)
+}
+
 // GOMEMLIMIT is a variable internal to Golang itself, we use this for internal targets, 0 means no maximum
 func PeerDBFlowWorkerMaxMemBytes() uint64 {
 	return getEnvUint[uint64]("GOMEMLIMIT", 0)
@@ -81,12 +82,183 @@ func PeerDBCatalogDatabase() string {
 	return getEnvString("PEERDB_CATALOG_DATABASE", "")
 }
 
+// PEERDB_CATALOG_ENCRYPTION_KEYS, comma separated id:base64 pairs of 256 bit keys encrypting peer configs in the catalog,
+// the first encrypts new configs and the others still decrypt configs encrypted before a rotation. Empty stores them unencrypted
+func PeerDBCatalogEncryptionKeys() []string {
+	return getEnvStringSlice("PEERDB_CATALOG_ENCRYPTION_KEYS", nil)
+}
+
 // PEERDB_ENABLE_WAL_HEARTBEAT
 func PeerDBEnableWALHeartbeat() bool {
 	return getEnvBool("PEERDB_ENABLE_WAL_HEARTBEAT", false)
 }
 
-// PEERDB_ENABLE_PARALLEL_SYNC_NORMALIZE
-func PeerDBEnableParallelSyncNormalize() bool {
-	return getEnvBool("PEERDB_ENABLE_PARALLEL_SYNC_NORMALIZE", false)
+// PEERDB_CDC_MAX_BATCH_BYTES, estimated size of the records at which sync batches end for mirrors not setting their own,
+// 0 only bounds batches by records and time
+func PeerDBCDCMaxBatchBytes() uint64 {
+	return getEnvUint[uint64]("PEERDB_CDC_MAX_BATCH_BYTES", 0)
+}
+
+// PEERDB_CDC_CATCH_UP_BATCH_SIZE, upper bound on the batch size of sync flows while catching up
+func PeerDBCDCCatchUpBatchSize() uint32 {
+	return getEnvUint[uint32]("PEERDB_CDC_CATCH_UP_BATCH_SIZE", 100_000)
+}
+
+// PEERDB_CDC_CATCH_UP_PACING_SECONDS, pause between sync flows while catching up
+func PeerDBCDCCatchUpPacing() time.Duration {
+	return getEnvDuration("PEERDB_CDC_CATCH_UP_PACING_SECONDS", 10*time.Second, time.Second)
+}
+
+// PEERDB_CDC_ADAPTIVE_SYNC_INTERVAL, sync flows shorten their idle timeout under load and back off while the source is idle
+func PeerDBCDCAdaptiveSyncInterval() bool {
+	return getEnvBool("PEERDB_CDC_ADAPTIVE_SYNC_INTERVAL", false)
+}
+
+// PEERDB_CDC_ADAPTIVE_SYNC_MIN_INTERVAL_SECONDS, shortest idle timeout of sync flows under the adaptive sync interval
+func PeerDBCDCAdaptiveSyncMinInterval() time.Duration {
+	return getEnvDuration("PEERDB_CDC_ADAPTIVE_SYNC_MIN_INTERVAL_SECONDS", time.Second, time.Second)
+}
+
+// PEERDB_CDC_ADAPTIVE_SYNC_MAX_INTERVAL_SECONDS, longest idle timeout of sync flows under the adaptive sync interval
+func PeerDBCDCAdaptiveSyncMaxInterval() time.Duration {
+	return getEnvDuration("PEERDB_CDC_ADAPTIVE_SYNC_MAX_INTERVAL_SECONDS", 5*time.Minute, time.Second)
+}
+
+// PEERDB_DESTINATION_DRIFT_CHECK_INTERVAL_SECONDS, how often mirrors with a destination drift policy
+// compare their destination tables with the schemas they replicate
+func PeerDBDestinationDriftCheckInterval() time.Duration {
+	return getEnvDuration("PEERDB_DESTINATION_DRIFT_CHECK_INTERVAL_SECONDS", 15*time.Minute, time.Second)
+}
+
+// PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_URL, s3:// or gs:// location large Temporal payloads are offloaded to, empty keeps them in history
+func PeerDBTemporalPayloadOffloadURL() string {
+	return getEnvString("PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_URL", "")
+}
+
+// PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_THRESHOLD_BYTES, size above which Temporal payloads are offloaded
+func PeerDBTemporalPayloadOffloadThresholdBytes() uint32 {
+	return getEnvUint[uint32]("PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_THRESHOLD_BYTES", 256*1024)
+}
+
+// PEERDB_TEMPORAL_PAYLOAD_ENCRYPTION_KEYS, comma separated id:base64 pairs of 256 bit keys encrypting Temporal payloads,
+// the first encrypts new payloads and the others still decrypt older ones. Empty leaves payloads unencrypted
+func PeerDBTemporalPayloadEncryptionKeys() []string {
+	return getEnvStringSlice("PEERDB_TEMPORAL_PAYLOAD_ENCRYPTION_KEYS", nil)
+}
+
+// PEERDB_TEMPORAL_CODEC_SERVER_ORIGIN, origin of the Temporal UI allowed to decode payloads through the codec server,
+// empty doesn't serve the codec server
+func PeerDBTemporalCodecServerOrigin() string {
+	return getEnvString("PEERDB_TEMPORAL_CODEC_SERVER_ORIGIN", "")
+}
+
+// PEERDB_TEMPORAL_CODEC_SERVER_ADDRESS, address of the operator listener the API server serves the codec server on,
+// apart from the public API gateway
+func PeerDBTemporalCodecServerAddress() string {
+	return getEnvString("PEERDB_TEMPORAL_CODEC_SERVER_ADDRESS", "127.0.0.1:8114")
+}
+
+// PEERDB_TEMPORAL_CODEC_SERVER_TOKEN, bearer token callers of the codec server must send, required to serve it
+func PeerDBTemporalCodecServerToken() string {
+	return getEnvString("PEERDB_TEMPORAL_CODEC_SERVER_TOKEN", "")
+}
+
+// PEERDB_PEER_VALIDATION_CACHE_TTL_SECONDS, how long peer validation results are reused, 0 disables caching
+func PeerDBPeerValidationCacheTTL() time.Duration {
+	return getEnvDuration("PEERDB_PEER_VALIDATION_CACHE_TTL_SECONDS", 5*time.Minute, time.Second)
+}
+
+// PEERDB_CONNECTION_CACHE_IDLE_TIMEOUT_SECONDS, how long a worker keeps a peer's connection pool open once no activity uses it,
+// 0 closes pools as soon as the last activity using them is done
+func PeerDBConnectionCacheIdleTimeout() time.Duration {
+	return getEnvDuration("PEERDB_CONNECTION_CACHE_IDLE_TIMEOUT_SECONDS", 10*time.Minute, time.Second)
+}
+
+// PEERDB_AVRO_CODEC, none, zstd, deflate or snappy compression of Avro files staged by QRep and CDC syncs,
+// empty leaves each destination's default
+func PeerDBAvroCodec() string {
+	return getEnvString("PEERDB_AVRO_CODEC", "")
+}
+
+// PEERDB_AVRO_ENCODE_PARALLELISM, how many goroutines convert records while writing an Avro file, 1 converts them inline
+func PeerDBAvroEncodeParallelism() int {
+	return getEnvInt("PEERDB_AVRO_ENCODE_PARALLELISM", 4)
+}
+
+// PEERDB_OPENLINEAGE_URL, OpenLineage endpoint receiving run events of mirrors, e.g. http://marquez:5000/api/v1/lineage
+func PeerDBOpenLineageURL() string {
+	return getEnvURL("PEERDB_OPENLINEAGE_URL", "")
+}
+
+// PEERDB_OPENLINEAGE_API_KEY, sent as a bearer token to the OpenLineage endpoint
+func PeerDBOpenLineageAPIKey() string {
+	return getEnvString("PEERDB_OPENLINEAGE_API_KEY", "")
+}
+
+// PEERDB_OPENLINEAGE_NAMESPACE, namespace of the jobs PeerDB reports
+func PeerDBOpenLineageNamespace() string {
+	return getEnvString("PEERDB_OPENLINEAGE_NAMESPACE", "peerdb")
+}
+
+// PEERDB_DATA_CATALOG, datahub or amundsen to publish tables created by mirrors to, empty disables publishing
+func PeerDBDataCatalog() string {
+	return getEnvString("PEERDB_DATA_CATALOG", "")
+}
+
+// PEERDB_DATA_CATALOG_URL, DataHub GMS or Amundsen metadata service URL
+func PeerDBDataCatalogURL() string {
+	return getEnvURL("PEERDB_DATA_CATALOG_URL", "")
+}
+
+// PEERDB_DATA_CATALOG_TOKEN, sent as a bearer token to the data catalog
+func PeerDBDataCatalogToken() string {
+	return getEnvString("PEERDB_DATA_CATALOG_TOKEN", "")
+}
+
+// PEERDB_FEDERATION_REGIONS, comma separated region=host:port list of the regional API servers the federation API routes to
+func PeerDBFederationRegions() string {
+	return getEnvString("PEERDB_FEDERATION_REGIONS", "")
+}
+
+// PEERDB_FEDERATION_DEFAULT_REGION, region for requests which don't name one and can't be routed by mirror or peer name
+func PeerDBFederationDefaultRegion() string {
+	return getEnvString("PEERDB_FEDERATION_DEFAULT_REGION", "")
+}
+
+// PEERDB_FEDERATION_TLS, connect to regional API servers over TLS
+func PeerDBFederationTLS() bool {
+	return getEnvBool("PEERDB_FEDERATION_TLS", false)
+}
+
+// PEERDB_WORKER_BUILD_ID, build id of this worker release for Temporal worker versioning,
+// empty runs the worker unversioned
+func PeerDBWorkerBuildID() string {
+	return getEnvString("PEERDB_WORKER_BUILD_ID", "")
+}
+
+// PEERDB_WORKER_MAX_CONCURRENT_ACTIVITIES, how many activities a worker runs at once
+func PeerDBWorkerMaxConcurrentActivities() int {
+	return getEnvInt("PEERDB_WORKER_MAX_CONCURRENT_ACTIVITIES", 1000)
+}
+
+// PEERDB_RAW_TABLE_RETENTION_HOURS, how long raw table rows of CDC mirrors are kept once normalized
+// unless mirrors set their own retention, 0 keeps them forever
+func PeerDBRawTableRetention() time.Duration {
+	return getEnvDuration("PEERDB_RAW_TABLE_RETENTION_HOURS", 0, time.Hour)
+}
+
+// PEERDB_STATS_RAW_RETENTION_DAYS, how long per-batch mirror stats are kept once rolled up, 0 keeps them forever
+func PeerDBStatsRawRetention() time.Duration {
+	return getEnvDuration("PEERDB_STATS_RAW_RETENTION_DAYS", 7*24*time.Hour, 24*time.Hour)
+}
+
+// PEERDB_STATS_HOURLY_RETENTION_DAYS, how long hourly rollups of mirror stats are kept, 0 keeps them forever,
+// daily rollups are always kept
+func PeerDBStatsHourlyRetention() time.Duration {
+	return getEnvDuration("PEERDB_STATS_HOURLY_RETENTION_DAYS", 90*24*time.Hour, 24*time.Hour)
+}
+
+// PEERDB_STRICT_CONFIG, fail startup on malformed, unknown or missing PEERDB_* variables instead of logging them
+func PeerDBStrictConfig() bool {
+	return getEnvBool("PEERDB_STRICT_CONFIG", false)
 }
@@ -1,8 +1,12 @@
 package peerdbenv
 
 import (
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/exp/constraints"
 )
@@ -76,3 +80,71 @@ func getEnvString(name string, defaultValue string) string {
 
 	return val
 }
+
+// getEnvDuration returns the value of the environment variable with the given name
+// or defaultValue if the environment variable is not set or is not a valid duration.
+// Values are durations like 90s or 2h, or integers counted in unit.
+func getEnvDuration(name string, defaultValue time.Duration, unit time.Duration) time.Duration {
+	val, ok := getEnv(name)
+	if !ok {
+		return defaultValue
+	}
+
+	d, err := parseDuration(val, unit)
+	if err != nil {
+		return defaultValue
+	}
+
+	return d
+}
+
+func parseDuration(val string, unit time.Duration) (time.Duration, error) {
+	if i, err := strconv.Atoi(val); err == nil {
+		return time.Duration(i) * unit, nil
+	}
+	return time.ParseDuration(val)
+}
+
+// getEnvStringSlice returns the comma separated values of the environment variable with the given name,
+// with whitespace and empty values dropped, or defaultValue if the environment variable is not set.
+func getEnvStringSlice(name string, defaultValue []string) []string {
+	val, ok := getEnv(name)
+	if !ok {
+		return defaultValue
+	}
+
+	var values []string
+	for _, v := range strings.Split(val, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// getEnvURL returns the value of the environment variable with the given name
+// or defaultValue if the environment variable is not set or is not an absolute http(s) URL.
+func getEnvURL(name string, defaultValue string) string {
+	val, ok := getEnv(name)
+	if !ok {
+		return defaultValue
+	}
+
+	if err := parseURL(val); err != nil {
+		return defaultValue
+	}
+
+	return val
+}
+
+func parseURL(val string) error {
+	u, err := url.ParseRequestURI(val)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s is not an absolute http(s) URL", val)
+	}
+	return nil
+}
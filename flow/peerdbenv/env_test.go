@@ -0,0 +1,46 @@
+package peerdbenv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEnvDuration(t *testing.T) {
+	require.Equal(t, time.Minute, getEnvDuration("PEERDB_TEST_DURATION", time.Minute, time.Second))
+	t.Setenv("PEERDB_TEST_DURATION", "90")
+	require.Equal(t, 90*time.Second, getEnvDuration("PEERDB_TEST_DURATION", time.Minute, time.Second))
+	t.Setenv("PEERDB_TEST_DURATION", "2h")
+	require.Equal(t, 2*time.Hour, getEnvDuration("PEERDB_TEST_DURATION", time.Minute, time.Second))
+	t.Setenv("PEERDB_TEST_DURATION", "soon")
+	require.Equal(t, time.Minute, getEnvDuration("PEERDB_TEST_DURATION", time.Minute, time.Second))
+}
+
+func TestGetEnvStringSlice(t *testing.T) {
+	require.Equal(t, []string{"a"}, getEnvStringSlice("PEERDB_TEST_SLICE", []string{"a"}))
+	t.Setenv("PEERDB_TEST_SLICE", " b, ,c ")
+	require.Equal(t, []string{"b", "c"}, getEnvStringSlice("PEERDB_TEST_SLICE", []string{"a"}))
+	t.Setenv("PEERDB_TEST_SLICE", "")
+	require.Empty(t, getEnvStringSlice("PEERDB_TEST_SLICE", []string{"a"}))
+}
+
+func TestGetEnvURL(t *testing.T) {
+	require.Equal(t, "http://default", getEnvURL("PEERDB_TEST_URL", "http://default"))
+	t.Setenv("PEERDB_TEST_URL", "https://marquez:5000/api/v1/lineage")
+	require.Equal(t, "https://marquez:5000/api/v1/lineage", getEnvURL("PEERDB_TEST_URL", "http://default"))
+	t.Setenv("PEERDB_TEST_URL", "marquez:5000")
+	require.Equal(t, "http://default", getEnvURL("PEERDB_TEST_URL", "http://default"))
+}
+
+func TestRegistry(t *testing.T) {
+	names := make(map[string]struct{})
+	for _, envVar := range Registry() {
+		require.NotContains(t, names, envVar.Name)
+		names[envVar.Name] = struct{}{}
+		require.NotEmpty(t, envVar.Description, envVar.Name)
+		if envVar.Default != "" {
+			require.NoError(t, parseEnvVar(envVar, envVar.Default), envVar.Name)
+		}
+	}
+}
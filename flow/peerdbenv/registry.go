@@ -0,0 +1,277 @@
+package peerdbenv
+
+import "slices"
+
+type EnvVarType string
+
+const (
+	EnvVarTypeString      EnvVarType = "string"
+	EnvVarTypeInt         EnvVarType = "int"
+	EnvVarTypeUint        EnvVarType = "uint"
+	EnvVarTypeBool        EnvVarType = "bool"
+	EnvVarTypeDuration    EnvVarType = "duration"
+	EnvVarTypeStringSlice EnvVarType = "string_slice"
+	EnvVarTypeURL         EnvVarType = "url"
+)
+
+// EnvVar documents a PEERDB_* environment variable, its default is as it would be set in the environment.
+type EnvVar struct {
+	Name        string
+	Type        EnvVarType
+	Default     string
+	Description string
+}
+
+// envVars are the PEERDB_* variables read through this package, and elsewhere by flow, nexus and the UI.
+// New variables are added here so they're documented and validation doesn't report them as unknown.
+var envVars = []EnvVar{
+	{
+		Name: "PEERDB_VERSION_SHA_SHORT", Type: EnvVarTypeString, Default: "unknown",
+		Description: "version of the PeerDB release, set by release images",
+	},
+	{
+		Name: "PEERDB_DEPLOYMENT_UID", Type: EnvVarTypeString,
+		Description: "prefixes the task queues and alerts of the deployment",
+	},
+	{
+		Name: "PEERDB_CDC_CHANNEL_BUFFER_SIZE", Type: EnvVarTypeInt, Default: "262144",
+		Description: "number of records buffered between reading from the source and syncing to the destination",
+	},
+	{
+		Name: "PEERDB_CDC_DISK_SPILL_RECORDS_THRESHOLD", Type: EnvVarTypeInt, Default: "1000000",
+		Description: "number of records held in memory per batch before spilling to disk",
+	},
+	{
+		Name: "PEERDB_CDC_DISK_SPILL_MEM_PERCENT_THRESHOLD", Type: EnvVarTypeInt, Default: "-1",
+		Description: "percentage of GOMEMLIMIT in use above which batches spill to disk, negative disables the threshold",
+	},
+	{
+		Name: "PEERDB_CDC_STREAM_SPILL_TO_DISK", Type: EnvVarTypeBool, Default: "false",
+		Description: "spill records to an encrypted temporary file rather than pausing reads from the source once the channel buffer is full",
+	},
+	{
+		Name: "PEERDB_CATALOG_HOST", Type: EnvVarTypeString,
+		Description: "host of the catalog Postgres database",
+	},
+	{
+		Name: "PEERDB_CATALOG_PORT", Type: EnvVarTypeUint, Default: "5432",
+		Description: "port of the catalog Postgres database",
+	},
+	{
+		Name: "PEERDB_CATALOG_USER", Type: EnvVarTypeString,
+		Description: "user connecting to the catalog",
+	},
+	{
+		Name: "PEERDB_CATALOG_PASSWORD", Type: EnvVarTypeString,
+		Description: "password of the catalog user",
+	},
+	{
+		Name: "PEERDB_CATALOG_DATABASE", Type: EnvVarTypeString,
+		Description: "name of the catalog database",
+	},
+	{
+		Name: "PEERDB_CATALOG_ENCRYPTION_KEYS", Type: EnvVarTypeStringSlice,
+		Description: "id:base64 256 bit keys encrypting peer configs and " +
+			"alert config secrets in the catalog, the first encrypts new configs, " +
+			"empty stores them unencrypted",
+	},
+	{
+		Name: "PEERDB_ENABLE_WAL_HEARTBEAT", Type: EnvVarTypeBool, Default: "false",
+		Description: "have mirrors write WAL heartbeats to their Postgres sources",
+	},
+	{
+		Name: "PEERDB_CDC_MAX_BATCH_BYTES", Type: EnvVarTypeUint, Default: "0",
+		Description: "estimated size of the records at which sync batches end, 0 only bounds batches by records and time",
+	},
+	{
+		Name: "PEERDB_CDC_CATCH_UP_BATCH_SIZE", Type: EnvVarTypeUint, Default: "100000",
+		Description: "upper bound on the batch size of sync flows while catching up",
+	},
+	{
+		Name: "PEERDB_CDC_CATCH_UP_PACING_SECONDS", Type: EnvVarTypeDuration, Default: "10",
+		Description: "pause between sync flows while catching up",
+	},
+	{
+		Name: "PEERDB_CDC_ADAPTIVE_SYNC_INTERVAL", Type: EnvVarTypeBool, Default: "false",
+		Description: "replace the idle timeout of mirrors with one shortening under load and backing off while the source is idle",
+	},
+	{
+		Name: "PEERDB_CDC_ADAPTIVE_SYNC_MIN_INTERVAL_SECONDS", Type: EnvVarTypeDuration, Default: "1",
+		Description: "shortest idle timeout of sync flows under the adaptive sync interval, rounded up to whole seconds",
+	},
+	{
+		Name: "PEERDB_CDC_ADAPTIVE_SYNC_MAX_INTERVAL_SECONDS", Type: EnvVarTypeDuration, Default: "300",
+		Description: "longest idle timeout of sync flows under the adaptive sync interval",
+	},
+	{
+		Name: "PEERDB_DESTINATION_DRIFT_CHECK_INTERVAL_SECONDS", Type: EnvVarTypeDuration, Default: "900",
+		Description: "how often mirrors with a destination drift policy compare their destination tables with the schemas they replicate",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_URL", Type: EnvVarTypeString,
+		Description: "s3:// or gs:// location large Temporal payloads are offloaded to, must be the same for workers and the API server",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_THRESHOLD_BYTES", Type: EnvVarTypeUint, Default: "262144",
+		Description: "size above which Temporal payloads are offloaded",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_PAYLOAD_ENCRYPTION_KEYS", Type: EnvVarTypeStringSlice,
+		Description: "id:base64 256 bit keys encrypting Temporal payloads, the first encrypts new payloads, " +
+			"empty leaves them unencrypted",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_CODEC_SERVER_ORIGIN", Type: EnvVarTypeString,
+		Description: "origin of the Temporal UI allowed to decode payloads through the codec server, empty doesn't serve the codec server",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_CODEC_SERVER_ADDRESS", Type: EnvVarTypeString, Default: "127.0.0.1:8114",
+		Description: "address of the operator listener the API server serves the codec server on, apart from the public API gateway",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_CODEC_SERVER_TOKEN", Type: EnvVarTypeString,
+		Description: "bearer token callers of the codec server must send, required to serve it",
+	},
+	{
+		Name: "PEERDB_PEER_VALIDATION_CACHE_TTL_SECONDS", Type: EnvVarTypeDuration, Default: "300",
+		Description: "how long peer validation results are reused, 0 disables caching",
+	},
+	{
+		Name: "PEERDB_CONNECTION_CACHE_IDLE_TIMEOUT_SECONDS", Type: EnvVarTypeDuration, Default: "600",
+		Description: "how long a worker keeps a peer's connection pool open once no activity uses it, 0 closes it right away",
+	},
+	{
+		Name: "PEERDB_AVRO_CODEC", Type: EnvVarTypeString,
+		Description: "none, zstd, deflate or snappy compression of staged Avro files, empty leaves each destination's default",
+	},
+	{
+		Name: "PEERDB_AVRO_ENCODE_PARALLELISM", Type: EnvVarTypeInt, Default: "4",
+		Description: "how many goroutines convert records while writing an Avro file, 1 converts them inline",
+	},
+	{
+		Name: "PEERDB_OPENLINEAGE_URL", Type: EnvVarTypeURL,
+		Description: "OpenLineage endpoint receiving run events of mirrors, empty disables lineage",
+	},
+	{
+		Name: "PEERDB_OPENLINEAGE_API_KEY", Type: EnvVarTypeString,
+		Description: "sent as a bearer token to the OpenLineage endpoint",
+	},
+	{
+		Name: "PEERDB_OPENLINEAGE_NAMESPACE", Type: EnvVarTypeString, Default: "peerdb",
+		Description: "namespace of the jobs PeerDB reports",
+	},
+	{
+		Name: "PEERDB_DATA_CATALOG", Type: EnvVarTypeString,
+		Description: "datahub or amundsen to publish tables created by mirrors to, empty disables publishing",
+	},
+	{
+		Name: "PEERDB_DATA_CATALOG_URL", Type: EnvVarTypeURL,
+		Description: "DataHub GMS or Amundsen metadata service URL",
+	},
+	{
+		Name: "PEERDB_DATA_CATALOG_TOKEN", Type: EnvVarTypeString,
+		Description: "sent as a bearer token to the data catalog",
+	},
+	{
+		Name: "PEERDB_FEDERATION_REGIONS", Type: EnvVarTypeString,
+		Description: "comma separated region=host:port list of the regional API servers the federation API routes to",
+	},
+	{
+		Name: "PEERDB_FEDERATION_DEFAULT_REGION", Type: EnvVarTypeString,
+		Description: "region for federation requests which don't name one and can't be routed by mirror or peer name",
+	},
+	{
+		Name: "PEERDB_FEDERATION_TLS", Type: EnvVarTypeBool, Default: "false",
+		Description: "connect to regional API servers over TLS",
+	},
+	{
+		Name: "PEERDB_WORKER_BUILD_ID", Type: EnvVarTypeString,
+		Description: "build id of the worker release for Temporal worker versioning, empty runs workers unversioned",
+	},
+	{
+		Name: "PEERDB_WORKER_MAX_CONCURRENT_ACTIVITIES", Type: EnvVarTypeInt, Default: "1000",
+		Description: "how many activities a worker runs at once",
+	},
+	{
+		Name: "PEERDB_RAW_TABLE_RETENTION_HOURS", Type: EnvVarTypeDuration, Default: "0",
+		Description: "how long raw table rows of CDC mirrors are kept once normalized, 0 keeps them forever",
+	},
+	{
+		Name: "PEERDB_STATS_RAW_RETENTION_DAYS", Type: EnvVarTypeDuration, Default: "7",
+		Description: "how long per-batch mirror stats are kept once rolled up, 0 keeps them forever",
+	},
+	{
+		Name: "PEERDB_STATS_HOURLY_RETENTION_DAYS", Type: EnvVarTypeDuration, Default: "90",
+		Description: "how long hourly rollups of mirror stats are kept, 0 keeps them forever",
+	},
+	{
+		Name: "PEERDB_STRICT_CONFIG", Type: EnvVarTypeBool, Default: "false",
+		Description: "fail startup on malformed, unknown or missing PEERDB_* variables instead of logging them",
+	},
+	{
+		Name: "PEERDB_TEMPORAL_NAMESPACE", Type: EnvVarTypeString, Default: "default",
+		Description: "Temporal namespace of the workflows",
+	},
+	{
+		Name: "PEERDB_WORKER_TASK_QUEUE", Type: EnvVarTypeString,
+		Description: "dedicated task queue a worker polls instead of the default one, for mirrors pinned to it",
+	},
+	{
+		Name: "PEERDB_CLICKHOUSE_AWS_CREDENTIALS_AWS_REGION", Type: EnvVarTypeString,
+		Description: "region of the S3 bucket staging data for ClickHouse peers without their own",
+	},
+	{
+		Name: "PEERDB_CLICKHOUSE_AWS_CREDENTIALS_AWS_ACCESS_KEY_ID", Type: EnvVarTypeString,
+		Description: "access key of the S3 bucket staging data for ClickHouse peers",
+	},
+	{
+		Name: "PEERDB_CLICKHOUSE_AWS_CREDENTIALS_AWS_SECRET_ACCESS_KEY", Type: EnvVarTypeString,
+		Description: "secret key of the S3 bucket staging data for ClickHouse peers",
+	},
+	{
+		Name: "PEERDB_CLICKHOUSE_AWS_S3_BUCKET_NAME", Type: EnvVarTypeString,
+		Description: "S3 bucket staging data for ClickHouse peers",
+	},
+	// read by nexus and the UI, deployments usually share one environment between them and flow
+	{
+		Name: "PEERDB_PASSWORD", Type: EnvVarTypeString, Default: "peerdb",
+		Description: "password of the nexus Postgres interface and the UI",
+	},
+	{
+		Name: "PEERDB_HOST", Type: EnvVarTypeString, Default: "0.0.0.0",
+		Description: "host nexus binds to",
+	},
+	{
+		Name: "PEERDB_PORT", Type: EnvVarTypeUint, Default: "9900",
+		Description: "port of the nexus Postgres interface",
+	},
+	{
+		Name: "PEERDB_LOG_DIR", Type: EnvVarTypeString, Default: "/var/log/peerdb",
+		Description: "directory nexus writes its logs to",
+	},
+	{
+		Name: "PEERDB_TLS_CERT", Type: EnvVarTypeString,
+		Description: "path to the TLS certificate of the nexus Postgres interface",
+	},
+	{
+		Name: "PEERDB_TLS_KEY", Type: EnvVarTypeString,
+		Description: "path to the TLS private key of the nexus Postgres interface",
+	},
+	{
+		Name: "PEERDB_FDW_MODE", Type: EnvVarTypeBool, Default: "false",
+		Description: "have nexus describe queries without their schema, for clients like postgres_fdw",
+	},
+	{
+		Name: "PEERDB_FLOW_SERVER_ADDRESS", Type: EnvVarTypeString,
+		Description: "gRPC address of the flow API used by nexus, empty disables MIRROR commands",
+	},
+	{
+		Name: "PEERDB_FLOW_SERVER_HTTP", Type: EnvVarTypeURL,
+		Description: "HTTP address of the flow API used by the UI",
+	},
+}
+
+// Registry returns every PEERDB_* environment variable documented by this package.
+func Registry() []EnvVar {
+	return slices.Clone(envVars)
+}
@@ -0,0 +1,97 @@
+package peerdbenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CatalogEnvVars must be set by every process connecting to the catalog.
+var CatalogEnvVars = []string{"PEERDB_CATALOG_HOST", "PEERDB_CATALOG_USER", "PEERDB_CATALOG_DATABASE"}
+
+type MalformedEnvVarError struct {
+	Err   error
+	Name  string
+	Value string
+	Type  EnvVarType
+}
+
+func (e *MalformedEnvVarError) Error() string {
+	return fmt.Sprintf("%s=%q is not a valid %s: %v", e.Name, e.Value, e.Type, e.Err)
+}
+
+func (e *MalformedEnvVarError) Unwrap() error {
+	return e.Err
+}
+
+type UnknownEnvVarError struct {
+	Name string
+}
+
+func (e *UnknownEnvVarError) Error() string {
+	return e.Name + " is not a known PeerDB environment variable"
+}
+
+type MissingEnvVarError struct {
+	Name string
+}
+
+func (e *MissingEnvVarError) Error() string {
+	return e.Name + " is required but not set"
+}
+
+func parseEnvVar(envVar EnvVar, value string) error {
+	var err error
+	switch envVar.Type {
+	case EnvVarTypeInt:
+		_, err = strconv.Atoi(value)
+	case EnvVarTypeUint:
+		_, err = strconv.ParseUint(value, 10, 64)
+	case EnvVarTypeBool:
+		_, err = strconv.ParseBool(value)
+	case EnvVarTypeDuration:
+		_, err = parseDuration(value, time.Second)
+	case EnvVarTypeURL:
+		if value != "" {
+			err = parseURL(value)
+		}
+	}
+	return err
+}
+
+// ValidateEnv checks the PEERDB_* variables of the process, where the getters of this package would silently
+// fall back to their defaults. known lists variables read outside this package, like dynamic settings.
+// Every malformed, unknown or missing required variable is reported,
+// as a *MalformedEnvVarError, *UnknownEnvVarError or *MissingEnvVarError joined in the returned error.
+func ValidateEnv(known []EnvVar, required []string) error {
+	byName := make(map[string]EnvVar, len(envVars)+len(known))
+	for _, envVar := range slices.Concat(envVars, known) {
+		byName[envVar.Name] = envVar
+	}
+
+	var errs []error
+	environ := os.Environ()
+	slices.Sort(environ)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "PEERDB_") {
+			continue
+		}
+		envVar, ok := byName[name]
+		if !ok {
+			errs = append(errs, &UnknownEnvVarError{Name: name})
+		} else if err := parseEnvVar(envVar, value); err != nil {
+			errs = append(errs, &MalformedEnvVarError{Name: name, Value: value, Type: envVar.Type, Err: err})
+		}
+	}
+	for _, name := range required {
+		if _, ok := getEnv(name); !ok {
+			errs = append(errs, &MissingEnvVarError{Name: name})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+package peerdbenv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEnv(t *testing.T) {
+	t.Setenv("PEERDB_CATALOG_PORT", "5432")
+	t.Setenv("PEERDB_ENABLE_WAL_HEARTBEAT", "true")
+	require.NoError(t, ValidateEnv(nil, []string{"PEERDB_CATALOG_PORT"}))
+
+	t.Setenv("PEERDB_CDC_CHANNEL_BUFFER_SIZE", "lots")
+	t.Setenv("PEERDB_ENABLE_WAL_HEARTBEAT", "maybe")
+	t.Setenv("PEERDB_NOT_A_SETTING", "1")
+	t.Setenv("PEERDB_DYNAMIC_SETTING", "-1")
+	err := ValidateEnv([]EnvVar{{Name: "PEERDB_DYNAMIC_SETTING", Type: EnvVarTypeUint}}, []string{"PEERDB_REQUIRED_SETTING"})
+	require.Error(t, err)
+
+	var malformed, unknown, missing []string
+	for _, envErr := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var malformedErr *MalformedEnvVarError
+		var unknownErr *UnknownEnvVarError
+		var missingErr *MissingEnvVarError
+		switch {
+		case errors.As(envErr, &malformedErr):
+			malformed = append(malformed, malformedErr.Name)
+		case errors.As(envErr, &unknownErr):
+			unknown = append(unknown, unknownErr.Name)
+		case errors.As(envErr, &missingErr):
+			missing = append(missing, missingErr.Name)
+		}
+	}
+	require.Subset(t, malformed, []string{"PEERDB_CDC_CHANNEL_BUFFER_SIZE", "PEERDB_DYNAMIC_SETTING", "PEERDB_ENABLE_WAL_HEARTBEAT"})
+	require.Contains(t, unknown, "PEERDB_NOT_A_SETTING")
+	require.Equal(t, []string{"PEERDB_REQUIRED_SETTING"}, missing)
+}
@@ -0,0 +1,156 @@
+// Package sdk lets other services create and manage PeerDB mirrors through the flow API.
+// Peers and mirrors are described with the builders of this package, which check configs
+// the same way the flow API does before they are sent.
+package sdk
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+type Client struct {
+	conn *grpc.ClientConn
+	flow protos.FlowServiceClient
+}
+
+// NewClient connects to the flow API's gRPC endpoint, opts should at least set transport credentials.
+func NewClient(ctx context.Context, target string, opts ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.DialContext(ctx, target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to flow API at %s: %w", target, err)
+	}
+	return &Client{
+		conn: conn,
+		flow: protos.NewFlowServiceClient(conn),
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// FlowService gives access to the calls which this client doesn't wrap.
+func (c *Client) FlowService() protos.FlowServiceClient {
+	return c.flow
+}
+
+// CreatePeer validates the peer by connecting to it and stores it in the catalog.
+func (c *Client) CreatePeer(ctx context.Context, peer *protos.Peer) error {
+	res, err := c.flow.CreatePeer(ctx, &protos.CreatePeerRequest{Peer: peer})
+	if err != nil {
+		return fmt.Errorf("unable to create peer %s: %w", peer.Name, err)
+	}
+	if res.Status != protos.CreatePeerStatus_CREATED {
+		return fmt.Errorf("unable to create peer %s: %s", peer.Name, res.Message)
+	}
+	return nil
+}
+
+func (c *Client) DropPeer(ctx context.Context, peerName string) error {
+	res, err := c.flow.DropPeer(ctx, &protos.DropPeerRequest{PeerName: peerName})
+	if err != nil {
+		return fmt.Errorf("unable to drop peer %s: %w", peerName, err)
+	}
+	if !res.Ok {
+		return fmt.Errorf("unable to drop peer %s: %s", peerName, res.ErrorMessage)
+	}
+	return nil
+}
+
+// CreateCDCMirror starts a CDC mirror and returns the id of its workflow.
+func (c *Client) CreateCDCMirror(ctx context.Context, mirror *CDCMirror, metadata *protos.MirrorMetadata) (string, error) {
+	cfg, err := mirror.Build()
+	if err != nil {
+		return "", err
+	}
+	res, err := c.flow.CreateCDCFlow(ctx, &protos.CreateCDCFlowRequest{
+		ConnectionConfigs:  cfg,
+		CreateCatalogEntry: true,
+		Metadata:           metadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create mirror %s: %w", mirror.Name, err)
+	}
+	return res.WorkflowId, nil
+}
+
+// CreateQRepMirror starts a query replication mirror and returns the id of its workflow.
+func (c *Client) CreateQRepMirror(ctx context.Context, mirror *QRepMirror) (string, error) {
+	cfg, err := mirror.Build()
+	if err != nil {
+		return "", err
+	}
+	res, err := c.flow.CreateQRepFlow(ctx, &protos.CreateQRepFlowRequest{
+		QrepConfig:         cfg,
+		CreateCatalogEntry: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create mirror %s: %w", mirror.Name, err)
+	}
+	return res.WorkflowId, nil
+}
+
+func (c *Client) MirrorStatus(ctx context.Context, mirrorName string) (*protos.MirrorStatusResponse, error) {
+	res, err := c.flow.MirrorStatus(ctx, &protos.MirrorStatusRequest{FlowJobName: mirrorName})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get status of mirror %s: %w", mirrorName, err)
+	}
+	if res.ErrorMessage != "" {
+		return res, fmt.Errorf("unable to get status of mirror %s: %s", mirrorName, res.ErrorMessage)
+	}
+	return res, nil
+}
+
+func (c *Client) PauseMirror(ctx context.Context, mirrorName string) error {
+	return c.changeMirrorState(ctx, &protos.FlowStateChangeRequest{
+		FlowJobName:        mirrorName,
+		RequestedFlowState: protos.FlowStatus_STATUS_PAUSED,
+	})
+}
+
+func (c *Client) ResumeMirror(ctx context.Context, mirrorName string) error {
+	return c.changeMirrorState(ctx, &protos.FlowStateChangeRequest{
+		FlowJobName:        mirrorName,
+		RequestedFlowState: protos.FlowStatus_STATUS_RUNNING,
+	})
+}
+
+// DropMirror stops a mirror and cleans up after it on its peers, which are looked up from its status.
+func (c *Client) DropMirror(ctx context.Context, mirrorName string) error {
+	status, err := c.MirrorStatus(ctx, mirrorName)
+	if err != nil {
+		return err
+	}
+	req := &protos.FlowStateChangeRequest{
+		FlowJobName:        mirrorName,
+		RequestedFlowState: protos.FlowStatus_STATUS_TERMINATED,
+	}
+	switch s := status.Status.(type) {
+	case *protos.MirrorStatusResponse_CdcStatus:
+		req.SourcePeer = s.CdcStatus.GetConfig().GetSource()
+		req.DestinationPeer = s.CdcStatus.GetConfig().GetDestination()
+	case *protos.MirrorStatusResponse_QrepStatus:
+		req.SourcePeer = s.QrepStatus.GetConfig().GetSourcePeer()
+		req.DestinationPeer = s.QrepStatus.GetConfig().GetDestinationPeer()
+	}
+	if req.SourcePeer == nil || req.DestinationPeer == nil {
+		return fmt.Errorf("unable to find peers of mirror %s", mirrorName)
+	}
+	return c.changeMirrorState(ctx, req)
+}
+
+func (c *Client) changeMirrorState(ctx context.Context, req *protos.FlowStateChangeRequest) error {
+	res, err := c.flow.FlowStateChange(ctx, req)
+	if err != nil {
+		return fmt.Errorf("unable to change state of mirror %s to %v: %w", req.FlowJobName, req.RequestedFlowState, err)
+	}
+	if !res.Ok {
+		return fmt.Errorf("unable to change state of mirror %s to %v: %s", req.FlowJobName, req.RequestedFlowState,
+			res.ErrorMessage)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/rowfilter"
+	"github.com/PeerDB-io/peer-flow/transform"
+)
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// ValidateName checks the name of a peer or mirror, which is used in slot, publication and table names.
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("invalid name `%s`, it should only contain lowercase letters, numbers and underscores", name)
+	}
+	return nil
+}
+
+// ValidateCDCConfig checks the settings of a CDC mirror which can be checked without connecting to its peers.
+func ValidateCDCConfig(cfg *protos.FlowConnectionConfigs) error {
+	if cfg == nil {
+		return errors.New("connection configs is nil")
+	}
+	if cfg.Source == nil || cfg.Destination == nil {
+		return errors.New("mirror requires a source and a destination peer")
+	}
+	if err := validateFanoutDestinations(cfg); err != nil {
+		return err
+	}
+	if cfg.SnapshotNativeImport && cfg.SnapshotStagingPath == "" {
+		return errors.New("snapshot native import requires a snapshot staging path")
+	}
+	if cfg.AdoptExistingTables && (cfg.DoInitialSnapshot || cfg.InitialSnapshotOnly || cfg.Resync) {
+		return errors.New("adopting existing tables can't be combined with an initial snapshot or resync")
+	}
+	if cfg.VerifyAdoptedTables && !cfg.AdoptExistingTables {
+		return errors.New("verifying adopted tables requires adopting existing tables")
+	}
+	if schedule := cfg.NormalizeSchedule; schedule != nil && schedule.Manual &&
+		(schedule.EveryBatches != 0 || schedule.IntervalSeconds != 0) {
+		return errors.New("manual normalize schedules can't also normalize every few batches or on an interval")
+	}
+	if len(cfg.TableMappings) == 0 {
+		return errors.New("mirror requires at least one table mapping")
+	}
+	destinationTables := make(map[string]string, len(cfg.TableMappings))
+	for _, tableMapping := range cfg.TableMappings {
+		if tableMapping.SourceTableIdentifier == "" || tableMapping.DestinationTableIdentifier == "" {
+			return errors.New("table mappings require a source and a destination table")
+		}
+		if len(tableMapping.Exclude) != 0 && len(tableMapping.Include) != 0 {
+			return fmt.Errorf("table %s can't have both excluded and included columns", tableMapping.SourceTableIdentifier)
+		}
+		if tableMapping.RowFilter != "" {
+			if err := validateRowFilter(tableMapping); err != nil {
+				return err
+			}
+		}
+		if err := validateMasks(tableMapping.SourceTableIdentifier, tableMapping.Masks); err != nil {
+			return err
+		}
+		for _, mask := range tableMapping.Masks {
+			if slices.Contains(tableMapping.Exclude, mask.Column) ||
+				(len(tableMapping.Include) != 0 && !slices.Contains(tableMapping.Include, mask.Column)) {
+				return fmt.Errorf("masked column %s of table %s isn't replicated", mask.Column, tableMapping.SourceTableIdentifier)
+			}
+		}
+		if source, ok := destinationTables[tableMapping.DestinationTableIdentifier]; ok {
+			return fmt.Errorf("source tables %s and %s are both mapped to destination table %s",
+				source, tableMapping.SourceTableIdentifier, tableMapping.DestinationTableIdentifier)
+		}
+		destinationTables[tableMapping.DestinationTableIdentifier] = tableMapping.SourceTableIdentifier
+	}
+	return nil
+}
+
+// validateFanoutDestinations checks the fan-out destinations of a mirror are distinct peers,
+// their raw tables and progress are kept under names derived from the peer names.
+func validateFanoutDestinations(cfg *protos.FlowConnectionConfigs) error {
+	seen := map[string]struct{}{cfg.Destination.Name: {}}
+	for _, destination := range cfg.FanoutDestinations {
+		if destination == nil || destination.Name == "" {
+			return errors.New("fan-out destinations require a peer")
+		}
+		if _, ok := seen[destination.Name]; ok {
+			return fmt.Errorf("peer %s is a destination of the mirror more than once", destination.Name)
+		}
+		seen[destination.Name] = struct{}{}
+	}
+	return nil
+}
+
+// validateRowFilter checks that the row filter of a table parses and only depends on replicated columns,
+// which CDC evaluates it on.
+func validateRowFilter(tableMapping *protos.TableMapping) error {
+	filter, err := rowfilter.Parse(tableMapping.RowFilter)
+	if err != nil {
+		return fmt.Errorf("invalid row filter of table %s: %w", tableMapping.SourceTableIdentifier, err)
+	}
+	for _, column := range filter.Columns() {
+		if slices.Contains(tableMapping.Exclude, column) ||
+			(len(tableMapping.Include) != 0 && !slices.Contains(tableMapping.Include, column)) {
+			return fmt.Errorf("row filter of table %s depends on column %s, which isn't replicated",
+				tableMapping.SourceTableIdentifier, column)
+		}
+	}
+	return nil
+}
+
+// validateMasks checks the column masks of a table, primary keys are only known once connected to the source.
+func validateMasks(table string, masks []*protos.ColumnMask) error {
+	masked := make(map[string]struct{}, len(masks))
+	for _, mask := range masks {
+		if mask.Column == "" {
+			return fmt.Errorf("column masks of table %s require a column", table)
+		}
+		if _, ok := masked[mask.Column]; ok {
+			return fmt.Errorf("column %s of table %s is masked more than once", mask.Column, table)
+		}
+		masked[mask.Column] = struct{}{}
+		switch mask.Policy {
+		case protos.ColumnMaskPolicy_COLUMN_MASK_NONE:
+			return fmt.Errorf("mask of column %s of table %s requires a policy", mask.Column, table)
+		case protos.ColumnMaskPolicy_COLUMN_MASK_TRUNCATE:
+			if mask.Length == 0 {
+				return fmt.Errorf("truncating column %s of table %s requires a length", mask.Column, table)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyCDCDefaults fills in the soft delete and synced at column names, which are uppercased when given.
+func ApplyCDCDefaults(cfg *protos.FlowConnectionConfigs) {
+	if cfg.SoftDeleteColName == "" {
+		cfg.SoftDeleteColName = "_PEERDB_IS_DELETED"
+	} else {
+		cfg.SoftDeleteColName = strings.ToUpper(cfg.SoftDeleteColName)
+	}
+
+	if cfg.SyncedAtColName == "" {
+		cfg.SyncedAtColName = "_PEERDB_SYNCED_AT"
+	} else {
+		cfg.SyncedAtColName = strings.ToUpper(cfg.SyncedAtColName)
+	}
+}
+
+// ValidateQRepConfig checks the settings of a query replication mirror which can be checked without
+// connecting to its peers.
+func ValidateQRepConfig(cfg *protos.QRepConfig) error {
+	if cfg == nil {
+		return errors.New("qrep config is nil")
+	}
+	if cfg.SourcePeer == nil || cfg.DestinationPeer == nil {
+		return errors.New("mirror requires a source and a destination peer")
+	}
+	if cfg.NativeImport && cfg.StagingPath == "" {
+		return errors.New("native import requires a staging path")
+	}
+	if cfg.DeleteReconciliationIntervalSeconds > 0 && len(cfg.WriteMode.GetUpsertKeyColumns()) == 0 {
+		return errors.New("delete reconciliation requires upsert key columns")
+	}
+	if cfg.ThrottleBurstSeconds != 0 && cfg.MaxRowsPerSecond == 0 && cfg.MaxBytesPerSecond == 0 {
+		return errors.New("a throttle burst requires a rows or bytes per second limit")
+	}
+	if err := validateMasks(cfg.WatermarkTable, cfg.Masks); err != nil {
+		return err
+	}
+	for _, mask := range cfg.Masks {
+		if slices.Contains(cfg.WriteMode.GetUpsertKeyColumns(), mask.Column) {
+			return fmt.Errorf("upsert key column %s can't be masked", mask.Column)
+		}
+	}
+	if cfg.TransformScript != "" {
+		script, err := transform.LoadTable(context.Background(), cfg.TransformScript, cfg.DestinationTableIdentifier)
+		if err != nil {
+			return err
+		}
+		script.Close()
+	}
+	if len(cfg.WatermarkColumns) != 0 {
+		if cfg.SourcePeer.Type != protos.DBType_POSTGRES {
+			return errors.New("composite watermark columns are only supported for Postgres sources")
+		}
+		for _, column := range cfg.WatermarkColumns {
+			if column == "" || column == "ctid" || column == "xmin" {
+				return fmt.Errorf("%q can't be a composite watermark column", column)
+			}
+		}
+	}
+	if cfg.Schedule != nil {
+		if cfg.Schedule.Cron == "" {
+			return errors.New("a schedule requires a cron expression")
+		}
+		if cfg.InitialCopyOnly {
+			return errors.New("initial copy only mirrors can't be scheduled")
+		}
+		if cfg.SourcePeer.Type == protos.DBType_POSTGRES && strings.HasPrefix(cfg.WatermarkColumn, "xmin") {
+			return errors.New("xmin mirrors can't be scheduled")
+		}
+	}
+	return nil
+}
+
+// ApplyQRepDefaults fills in the synced at column name, which is uppercased when given.
+func ApplyQRepDefaults(cfg *protos.QRepConfig) {
+	if cfg.SyncedAtColName == "" {
+		cfg.SyncedAtColName = "_PEERDB_SYNCED_AT"
+	} else {
+		cfg.SyncedAtColName = strings.ToUpper(cfg.SyncedAtColName)
+	}
+}
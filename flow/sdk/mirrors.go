@@ -0,0 +1,240 @@
+package sdk
+
+import (
+	"errors"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// Table maps a source table to its destination table, leaving out the Exclude columns,
+// or every column but the Include columns when those are given.
+// Only rows matching RowFilter, e.g. tenant_id = 42, are replicated when it is set.
+// Masks hash, redact, truncate or nullify columns before they reach the destination.
+type Table struct {
+	Source      string
+	Destination string
+	Exclude     []string
+	Include     []string
+	RowFilter   string
+	Masks       []*protos.ColumnMask
+}
+
+// CDCMirror describes a change data capture mirror from a Postgres peer.
+// Settings without a field here can be set on the config Build returns.
+type CDCMirror struct {
+	Name        string
+	Source      *protos.Peer
+	Destination *protos.Peer
+	Tables      []Table
+	// further destinations the same tables are replicated to, read from the same replication slot
+	FanoutDestinations []*protos.Peer
+
+	// created by the mirror when empty
+	PublicationName     string
+	ReplicationSlotName string
+
+	MaxBatchSize uint32
+	IdleTimeout  time.Duration
+
+	InitialSnapshot             bool
+	InitialSnapshotOnly         bool
+	SnapshotNumRowsPerPartition uint32
+	SnapshotMaxParallelWorkers  uint32
+	SnapshotStagingPath         string
+	SnapshotNativeImport        bool
+	CdcStagingPath              string
+
+	// tables already holding a copy of the data are replicated into instead of snapshotted
+	AdoptExistingTables bool
+	VerifyAdoptedTables bool
+
+	SoftDelete        bool
+	SoftDeleteColName string
+	SyncedAtColName   string
+
+	SchemaChangesRequireApproval bool
+	// what happens to dropped, widened and renamed source columns, ignored by default
+	SchemaChangePolicy protos.SchemaChangePolicy
+	// pause on narrowed and dropped primary key columns until they're approved with ApproveSchemaChange
+	PauseOnIncompatibleSchemaChanges bool
+	// what happens when destination tables are changed outside of the mirror, not checked by default
+	DestinationDriftPolicy protos.DestinationDriftPolicy
+	ApplyDelay             time.Duration
+	// when synced batches are normalized, each batch once synced by default
+	NormalizeSchedule *protos.NormalizeSchedule
+	// how long raw table rows are kept once normalized, 0 uses PEERDB_RAW_TABLE_RETENTION_HOURS
+	RawTableRetention time.Duration
+
+	// Lua script defining transform(record), which records pass through before they are synced
+	TransformScript string
+	// dedicated task queue the mirror runs on, polled by workers started with --task-queue
+	TaskQueue string
+	// what happens to numerics which don't fit the destination's decimal types, rounded by default
+	NumericOverflowPolicy protos.NumericOverflowPolicy
+	// what geometries are replicated as to destinations without geospatial types, WKT by default
+	GeoFormat protos.GeoFormat
+}
+
+// Build checks the mirror and returns the config to create it with.
+func (m *CDCMirror) Build() (*protos.FlowConnectionConfigs, error) {
+	if err := ValidateName(m.Name); err != nil {
+		return nil, err
+	}
+	if m.Source != nil && m.Source.Type != protos.DBType_POSTGRES {
+		return nil, errors.New("source peer of a CDC mirror must be Postgres")
+	}
+
+	tableMappings := make([]*protos.TableMapping, 0, len(m.Tables))
+	for _, table := range m.Tables {
+		tableMappings = append(tableMappings, &protos.TableMapping{
+			SourceTableIdentifier:      table.Source,
+			DestinationTableIdentifier: table.Destination,
+			Exclude:                    table.Exclude,
+			Include:                    table.Include,
+			RowFilter:                  table.RowFilter,
+			Masks:                      table.Masks,
+		})
+	}
+
+	cfg := &protos.FlowConnectionConfigs{
+		FlowJobName:                      m.Name,
+		Source:                           m.Source,
+		Destination:                      m.Destination,
+		FanoutDestinations:               m.FanoutDestinations,
+		TableMappings:                    tableMappings,
+		MaxBatchSize:                     m.MaxBatchSize,
+		IdleTimeoutSeconds:               uint64(m.IdleTimeout / time.Second),
+		CdcStagingPath:                   m.CdcStagingPath,
+		PublicationName:                  m.PublicationName,
+		ReplicationSlotName:              m.ReplicationSlotName,
+		DoInitialSnapshot:                m.InitialSnapshot || m.InitialSnapshotOnly,
+		SnapshotNumRowsPerPartition:      m.SnapshotNumRowsPerPartition,
+		SnapshotStagingPath:              m.SnapshotStagingPath,
+		SnapshotMaxParallelWorkers:       m.SnapshotMaxParallelWorkers,
+		InitialSnapshotOnly:              m.InitialSnapshotOnly,
+		SoftDelete:                       m.SoftDelete,
+		SoftDeleteColName:                m.SoftDeleteColName,
+		SyncedAtColName:                  m.SyncedAtColName,
+		SnapshotNativeImport:             m.SnapshotNativeImport,
+		SchemaChangesRequireApproval:     m.SchemaChangesRequireApproval,
+		ApplyDelaySeconds:                uint32(m.ApplyDelay / time.Second),
+		AdoptExistingTables:              m.AdoptExistingTables,
+		VerifyAdoptedTables:              m.VerifyAdoptedTables,
+		TransformScript:                  m.TransformScript,
+		SchemaChangePolicy:               m.SchemaChangePolicy,
+		PauseOnIncompatibleSchemaChanges: m.PauseOnIncompatibleSchemaChanges,
+		DestinationDriftPolicy:           m.DestinationDriftPolicy,
+		NormalizeSchedule:                m.NormalizeSchedule,
+		RawTableRetentionHours:           uint32(m.RawTableRetention / time.Hour),
+		TaskQueue:                        m.TaskQueue,
+		NumericOverflowPolicy:            m.NumericOverflowPolicy,
+		GeoFormat:                        m.GeoFormat,
+	}
+	if err := ValidateCDCConfig(cfg); err != nil {
+		return nil, err
+	}
+	ApplyCDCDefaults(cfg)
+	return cfg, nil
+}
+
+// QRepMirror describes a query replication mirror, which runs Query for each partition of WatermarkTable.
+// Settings without a field here can be set on the config Build returns.
+type QRepMirror struct {
+	Name                       string
+	Source                     *protos.Peer
+	Destination                *protos.Peer
+	Query                      string
+	WatermarkTable             string
+	WatermarkColumn            string
+	WatermarkColumns           []string // partitioned on in lexicographic order instead of WatermarkColumn when set
+	DestinationTableIdentifier string
+
+	// Append when unset
+	WriteMode *protos.QRepWriteMode
+
+	InitialCopyOnly      bool
+	MaxParallelWorkers   uint32
+	NumRowsPerPartition  uint32
+	WaitBetweenBatches   time.Duration
+	StagingPath          string
+	NativeImport         bool
+	SyncedAtColName      string
+	SoftDeleteColName    string
+	DeleteReconciliation time.Duration
+	// rate limits across all parallel workers, unlimited when 0
+	MaxRowsPerSecond  uint32
+	MaxBytesPerSecond uint64
+	// how much replication may burst after idling, a second's worth when unset
+	ThrottleBurst time.Duration
+	// only rows of Query matching this SQL condition are replicated, e.g. tenant_id = 42
+	RowFilter string
+	// masks of columns of Query, which can't include upsert key columns
+	Masks []*protos.ColumnMask
+	// runs are started by a Temporal Schedule when set, instead of waiting WaitBetweenBatches for new rows
+	Schedule *protos.QRepSchedule
+	// dedicated task queue the mirror runs on, polled by workers started with --task-queue
+	TaskQueue string
+	// what happens to numerics which don't fit the destination's decimal types, rounded by default
+	NumericOverflowPolicy protos.NumericOverflowPolicy
+	// what geometries are replicated as to destinations without geospatial types, WKT by default
+	GeoFormat protos.GeoFormat
+	// time partitioning and clustering of the destination table on BigQuery
+	Bigquery *protos.BigqueryTableSettings
+	// Lua script defining transform(record), which rows pass through as inserts before they are synced
+	TransformScript string
+}
+
+// Build checks the mirror and returns the config to create it with.
+func (m *QRepMirror) Build() (*protos.QRepConfig, error) {
+	if err := ValidateName(m.Name); err != nil {
+		return nil, err
+	}
+	if m.Query == "" || m.WatermarkTable == "" || (m.WatermarkColumn == "" && len(m.WatermarkColumns) == 0) {
+		return nil, errors.New("query replication requires a query, a watermark table and a watermark column")
+	}
+	if m.DestinationTableIdentifier == "" {
+		return nil, errors.New("query replication requires a destination table")
+	}
+
+	writeMode := m.WriteMode
+	if writeMode == nil {
+		writeMode = &protos.QRepWriteMode{WriteType: protos.QRepWriteType_QREP_WRITE_MODE_APPEND}
+	}
+	cfg := &protos.QRepConfig{
+		FlowJobName:                         m.Name,
+		SourcePeer:                          m.Source,
+		DestinationPeer:                     m.Destination,
+		DestinationTableIdentifier:          m.DestinationTableIdentifier,
+		Query:                               m.Query,
+		WatermarkTable:                      m.WatermarkTable,
+		WatermarkColumn:                     m.WatermarkColumn,
+		WatermarkColumns:                    m.WatermarkColumns,
+		InitialCopyOnly:                     m.InitialCopyOnly,
+		MaxParallelWorkers:                  m.MaxParallelWorkers,
+		WaitBetweenBatchesSeconds:           uint32(m.WaitBetweenBatches / time.Second),
+		WriteMode:                           writeMode,
+		StagingPath:                         m.StagingPath,
+		NumRowsPerPartition:                 m.NumRowsPerPartition,
+		SyncedAtColName:                     m.SyncedAtColName,
+		SoftDeleteColName:                   m.SoftDeleteColName,
+		NativeImport:                        m.NativeImport,
+		DeleteReconciliationIntervalSeconds: uint32(m.DeleteReconciliation / time.Second),
+		MaxRowsPerSecond:                    m.MaxRowsPerSecond,
+		MaxBytesPerSecond:                   m.MaxBytesPerSecond,
+		ThrottleBurstSeconds:                uint32(m.ThrottleBurst / time.Second),
+		RowFilter:                           m.RowFilter,
+		Masks:                               m.Masks,
+		Schedule:                            m.Schedule,
+		TaskQueue:                           m.TaskQueue,
+		NumericOverflowPolicy:               m.NumericOverflowPolicy,
+		GeoFormat:                           m.GeoFormat,
+		Bigquery:                            m.Bigquery,
+		TransformScript:                     m.TransformScript,
+	}
+	if err := ValidateQRepConfig(cfg); err != nil {
+		return nil, err
+	}
+	ApplyQRepDefaults(cfg)
+	return cfg, nil
+}
@@ -0,0 +1,203 @@
+package sdk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/sdk"
+)
+
+func testPeers(t *testing.T) (*protos.Peer, *protos.Peer) {
+	t.Helper()
+	source, err := sdk.PostgresPeer("source", &protos.PostgresConfig{
+		Host: "localhost", Port: 5432, User: "postgres", Database: "postgres",
+	})
+	require.NoError(t, err)
+	destination, err := sdk.SnowflakePeer("destination", &protos.SnowflakeConfig{
+		AccountId: "account", Username: "user", PrivateKey: "key", Database: "db", Warehouse: "wh",
+	})
+	require.NoError(t, err)
+	return source, destination
+}
+
+func TestCDCMirrorBuild(t *testing.T) {
+	source, destination := testPeers(t)
+	mirror := sdk.CDCMirror{
+		Name:            "orders_mirror",
+		Source:          source,
+		Destination:     destination,
+		Tables:          []sdk.Table{{Source: "public.orders", Destination: "orders"}},
+		IdleTimeout:     time.Minute,
+		InitialSnapshot: true,
+		SyncedAtColName: "synced_at",
+	}
+	cfg, err := mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "orders_mirror", cfg.FlowJobName)
+	assert.Equal(t, uint64(60), cfg.IdleTimeoutSeconds)
+	assert.True(t, cfg.DoInitialSnapshot)
+	assert.Equal(t, "_PEERDB_IS_DELETED", cfg.SoftDeleteColName)
+	assert.Equal(t, "SYNCED_AT", cfg.SyncedAtColName)
+
+	mirror.Name = "Orders"
+	_, err = mirror.Build()
+	require.Error(t, err, "names must be lowercase")
+
+	mirror.Name = "orders_mirror"
+	mirror.Tables = append(mirror.Tables, sdk.Table{Source: "public.orders_v2", Destination: "orders"})
+	_, err = mirror.Build()
+	require.Error(t, err, "two tables can't be mapped to one destination")
+
+	mirror.Tables = mirror.Tables[:1]
+	mirror.Tables[0].Exclude = []string{"notes"}
+	mirror.Tables[0].Include = []string{"id", "total"}
+	_, err = mirror.Build()
+	require.Error(t, err, "columns are either excluded or included")
+
+	mirror.Tables[0].Exclude = nil
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "total"}, cfg.TableMappings[0].Include)
+
+	mirror.Tables[0].RowFilter = "tenant_id = 42"
+	_, err = mirror.Build()
+	require.Error(t, err, "row filters can only depend on replicated columns")
+
+	mirror.Tables[0].RowFilter = "total >"
+	_, err = mirror.Build()
+	require.Error(t, err, "row filters must parse")
+
+	mirror.Tables[0].RowFilter = "total > 100 AND id IS NOT NULL"
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "total > 100 AND id IS NOT NULL", cfg.TableMappings[0].RowFilter)
+
+	mirror.Tables[0].Masks = []*protos.ColumnMask{{Column: "total", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_TRUNCATE}}
+	_, err = mirror.Build()
+	require.Error(t, err, "truncating requires a length")
+
+	mirror.Tables[0].Masks = []*protos.ColumnMask{{Column: "email", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256}}
+	_, err = mirror.Build()
+	require.Error(t, err, "masked columns must be replicated")
+
+	mirror.Tables[0].Masks = []*protos.ColumnMask{{Column: "total", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_REDACT}}
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "total", cfg.TableMappings[0].Masks[0].Column)
+
+	mirror.AdoptExistingTables = true
+	_, err = mirror.Build()
+	require.Error(t, err, "adopted tables aren't snapshotted")
+
+	mirror.InitialSnapshot = false
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.True(t, cfg.AdoptExistingTables)
+
+	mirror.NormalizeSchedule = &protos.NormalizeSchedule{Manual: true, EveryBatches: 10}
+	_, err = mirror.Build()
+	require.Error(t, err, "manual schedules only normalize when triggered")
+
+	mirror.NormalizeSchedule = &protos.NormalizeSchedule{EveryBatches: 10, IntervalSeconds: 3600}
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), cfg.NormalizeSchedule.EveryBatches)
+
+	mirror.FanoutDestinations = []*protos.Peer{destination}
+	_, err = mirror.Build()
+	require.Error(t, err, "fan-out destinations are distinct from the destination")
+
+	mirror.FanoutDestinations = nil
+	mirror.Source = destination
+	_, err = mirror.Build()
+	require.Error(t, err, "CDC sources must be Postgres")
+}
+
+func TestQRepMirrorBuild(t *testing.T) {
+	source, destination := testPeers(t)
+	mirror := sdk.QRepMirror{
+		Name:                       "orders_qrep",
+		Source:                     source,
+		Destination:                destination,
+		Query:                      "SELECT * FROM public.orders WHERE updated_at BETWEEN {{.start}} AND {{.end}}",
+		WatermarkTable:             "public.orders",
+		WatermarkColumn:            "updated_at",
+		DestinationTableIdentifier: "orders",
+	}
+	cfg, err := mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, protos.QRepWriteType_QREP_WRITE_MODE_APPEND, cfg.WriteMode.WriteType)
+	assert.Equal(t, "_PEERDB_SYNCED_AT", cfg.SyncedAtColName)
+
+	mirror.DeleteReconciliation = time.Hour
+	_, err = mirror.Build()
+	require.Error(t, err, "delete reconciliation needs upsert keys")
+
+	mirror.WriteMode = &protos.QRepWriteMode{
+		WriteType:        protos.QRepWriteType_QREP_WRITE_MODE_UPSERT,
+		UpsertKeyColumns: []string{"id"},
+	}
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3600), cfg.DeleteReconciliationIntervalSeconds)
+
+	mirror.Masks = []*protos.ColumnMask{{Column: "id", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256}}
+	_, err = mirror.Build()
+	require.Error(t, err, "upsert keys can't be masked")
+
+	mirror.Masks = []*protos.ColumnMask{{Column: "email", Policy: protos.ColumnMaskPolicy_COLUMN_MASK_HASH_SHA256}}
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Len(t, cfg.Masks, 1)
+
+	mirror.WatermarkColumn = ""
+	mirror.WatermarkColumns = []string{"updated_at", "xmin"}
+	_, err = mirror.Build()
+	require.Error(t, err, "xmin can't be part of a composite watermark")
+
+	mirror.WatermarkColumns = []string{"updated_at", "id"}
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"updated_at", "id"}, cfg.WatermarkColumns)
+
+	mirror.ThrottleBurst = 10 * time.Second
+	_, err = mirror.Build()
+	require.Error(t, err, "a burst needs a rate limit")
+
+	mirror.MaxRowsPerSecond = 5000
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), cfg.ThrottleBurstSeconds)
+
+	mirror.Schedule = &protos.QRepSchedule{}
+	_, err = mirror.Build()
+	require.Error(t, err, "a schedule needs a cron expression")
+
+	mirror.Schedule.Cron = "*/15 * * * *"
+	cfg, err = mirror.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "*/15 * * * *", cfg.Schedule.Cron)
+
+	mirror.InitialCopyOnly = true
+	_, err = mirror.Build()
+	require.Error(t, err, "initial copies can't be scheduled")
+}
+
+func TestPeerValidation(t *testing.T) {
+	_, err := sdk.PostgresPeer("pg", &protos.PostgresConfig{Host: "localhost", Port: 5432, User: "postgres"})
+	require.Error(t, err, "database is required")
+
+	_, err = sdk.BigQueryPeer("bq", &protos.BigqueryConfig{ProjectId: "project", DatasetId: "dataset"})
+	require.Error(t, err, "service accounts need a key")
+
+	peer, err := sdk.BigQueryPeer("bq", &protos.BigqueryConfig{
+		AuthType: "application_default", ProjectId: "project", DatasetId: "dataset",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, protos.DBType_BIGQUERY, peer.Type)
+	assert.Equal(t, "bq", peer.Name)
+}
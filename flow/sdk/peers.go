@@ -0,0 +1,119 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+// same as connbigquery.ApplicationDefaultAuthType, the SDK doesn't pull in the connectors
+const bigqueryApplicationDefaultAuthType = "application_default"
+
+type requiredField struct {
+	name  string
+	value string
+}
+
+// checkRequired returns an error naming the first of fields which is empty.
+func checkRequired(peerType string, fields ...requiredField) error {
+	for _, field := range fields {
+		if field.value == "" {
+			return fmt.Errorf("%s peer requires %s", peerType, field.name)
+		}
+	}
+	return nil
+}
+
+func newPeer(name string, config *protos.Peer) (*protos.Peer, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	config.Name = name
+	return config, nil
+}
+
+// PostgresPeer makes a Postgres peer, checking the settings needed to connect to it.
+func PostgresPeer(name string, config *protos.PostgresConfig) (*protos.Peer, error) {
+	if config == nil {
+		return nil, errors.New("postgres peer requires a config")
+	}
+	if err := checkRequired("postgres",
+		requiredField{"host", config.Host}, requiredField{"user", config.User}, requiredField{"database", config.Database},
+	); err != nil {
+		return nil, err
+	}
+	if config.Port == 0 {
+		return nil, errors.New("postgres peer requires port")
+	}
+	return newPeer(name, &protos.Peer{
+		Type:   protos.DBType_POSTGRES,
+		Config: &protos.Peer_PostgresConfig{PostgresConfig: config},
+	})
+}
+
+// SnowflakePeer makes a Snowflake peer, checking the settings needed to connect to it.
+func SnowflakePeer(name string, config *protos.SnowflakeConfig) (*protos.Peer, error) {
+	if config == nil {
+		return nil, errors.New("snowflake peer requires a config")
+	}
+	if err := checkRequired("snowflake",
+		requiredField{"account_id", config.AccountId}, requiredField{"username", config.Username},
+		requiredField{"database", config.Database}, requiredField{"warehouse", config.Warehouse},
+	); err != nil {
+		return nil, err
+	}
+	if config.PrivateKey == "" && config.GetPassword() == "" {
+		return nil, errors.New("snowflake peer requires private_key or password")
+	}
+	return newPeer(name, &protos.Peer{
+		Type:   protos.DBType_SNOWFLAKE,
+		Config: &protos.Peer_SnowflakeConfig{SnowflakeConfig: config},
+	})
+}
+
+// BigQueryPeer makes a BigQuery peer, checking the settings needed to connect to it.
+func BigQueryPeer(name string, config *protos.BigqueryConfig) (*protos.Peer, error) {
+	if config == nil {
+		return nil, errors.New("bigquery peer requires a config")
+	}
+	if err := checkRequired("bigquery",
+		requiredField{"project_id", config.ProjectId}, requiredField{"dataset_id", config.DatasetId},
+	); err != nil {
+		return nil, err
+	}
+	if config.AuthType != bigqueryApplicationDefaultAuthType {
+		if err := checkRequired("bigquery",
+			requiredField{"private_key", config.PrivateKey}, requiredField{"client_email", config.ClientEmail},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return newPeer(name, &protos.Peer{
+		Type:   protos.DBType_BIGQUERY,
+		Config: &protos.Peer_BigqueryConfig{BigqueryConfig: config},
+	})
+}
+
+// ClickhousePeer makes a ClickHouse peer, checking the settings needed to connect to it.
+func ClickhousePeer(name string, config *protos.ClickhouseConfig) (*protos.Peer, error) {
+	if config == nil {
+		return nil, errors.New("clickhouse peer requires a config")
+	}
+	if err := checkRequired("clickhouse",
+		requiredField{"host", config.Host}, requiredField{"user", config.User}, requiredField{"database", config.Database},
+		requiredField{"s3_path", config.S3Path},
+	); err != nil {
+		return nil, err
+	}
+	if config.Port == 0 {
+		return nil, errors.New("clickhouse peer requires port")
+	}
+	if config.Distributed && config.Cluster == "" {
+		return nil, errors.New("distributed clickhouse tables require cluster")
+	}
+	return newPeer(name, &protos.Peer{
+		Type:   protos.DBType_CLICKHOUSE,
+		Config: &protos.Peer_ClickhouseConfig{ClickhouseConfig: config},
+	})
+}
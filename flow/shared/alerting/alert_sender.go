@@ -0,0 +1,69 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	SlackServiceType     = "slack"
+	PagerDutyServiceType = "pagerduty"
+	EmailServiceType     = "email"
+	WebhookServiceType   = "webhook"
+)
+
+// AlertSender delivers alerts to a service configured in peerdb_stats.alerting_config
+type AlertSender interface {
+	SendAlert(ctx context.Context, alertKey string, alertMessage string) error
+	// thresholds overriding the dynamic settings for alerts sent to this sender, 0 when not overridden
+	SlotLagMBAlertThreshold() uint32
+	OpenConnectionsAlertThreshold() uint32
+}
+
+// AlertSenderThresholds are the thresholds any sender's config may override
+type AlertSenderThresholds struct {
+	SlotLagMB       uint32 `json:"slot_lag_mb_alert_threshold"`
+	OpenConnections uint32 `json:"open_connections_alert_threshold"`
+}
+
+func (t AlertSenderThresholds) SlotLagMBAlertThreshold() uint32 {
+	return t.SlotLagMB
+}
+
+func (t AlertSenderThresholds) OpenConnectionsAlertThreshold() uint32 {
+	return t.OpenConnections
+}
+
+// NewAlertSender creates the sender for a service_type and service_config of peerdb_stats.alerting_config,
+// returning an error for unknown service types and configs missing what the service needs.
+func NewAlertSender(serviceType string, serviceConfig []byte) (AlertSender, error) {
+	switch serviceType {
+	case SlackServiceType:
+		var config slackAlertConfig
+		if err := json.Unmarshal(serviceConfig, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Slack service config: %w", err)
+		}
+		return newSlackAlertSender(&config)
+	case PagerDutyServiceType:
+		var config pagerDutyAlertConfig
+		if err := json.Unmarshal(serviceConfig, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal PagerDuty service config: %w", err)
+		}
+		return newPagerDutyAlertSender(&config)
+	case EmailServiceType:
+		var config emailAlertConfig
+		if err := json.Unmarshal(serviceConfig, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal email service config: %w", err)
+		}
+		return newEmailAlertSender(&config)
+	case WebhookServiceType:
+		var config webhookAlertConfig
+		if err := json.Unmarshal(serviceConfig, &config); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal webhook service config: %w", err)
+		}
+		return newWebhookAlertSender(&config)
+	default:
+		return nil, fmt.Errorf("unknown service type: %s", serviceType)
+	}
+}
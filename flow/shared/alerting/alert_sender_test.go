@@ -0,0 +1,72 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAlertSender(t *testing.T) {
+	for _, tc := range []struct {
+		serviceType   string
+		serviceConfig string
+		valid         bool
+	}{
+		{serviceType: "slack", serviceConfig: `{"auth_token": "xoxb", "channel_ids": ["C1"]}`, valid: true},
+		{serviceType: "slack", serviceConfig: `{"auth_token": "xoxb"}`},
+		{serviceType: "pagerduty", serviceConfig: `{"routing_key": "key"}`, valid: true},
+		{serviceType: "pagerduty", serviceConfig: `{"routing_key": "key", "severity": "fatal"}`},
+		{serviceType: "email", serviceConfig: `{"smtp_host": "smtp.example.com", "from": "peerdb@example.com",
+			"recipients": ["Ops <ops@example.com>"]}`, valid: true},
+		{serviceType: "email", serviceConfig: `{"smtp_host": "smtp.example.com", "from": "peerdb", "recipients": ["ops@example.com"]}`},
+		{serviceType: "webhook", serviceConfig: `{"url": "https://example.com/hook"}`, valid: true},
+		{serviceType: "webhook", serviceConfig: `{"url": "ftp://example.com/hook"}`},
+		{serviceType: "webhook", serviceConfig: `not json`},
+		{serviceType: "carrier-pigeon", serviceConfig: `{}`},
+	} {
+		_, err := NewAlertSender(tc.serviceType, []byte(tc.serviceConfig))
+		if tc.valid {
+			require.NoError(t, err, tc.serviceConfig)
+		} else {
+			require.Error(t, err, tc.serviceConfig)
+		}
+	}
+
+	sender, err := NewAlertSender("slack",
+		[]byte(`{"auth_token": "xoxb", "channel_ids": ["C1"], "slot_lag_mb_alert_threshold": 100}`))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(100), sender.SlotLagMBAlertThreshold())
+	assert.Equal(t, uint32(0), sender.OpenConnectionsAlertThreshold())
+}
+
+func TestWebhookAlertSender(t *testing.T) {
+	var received webhookAlert
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	sender, err := NewAlertSender("webhook", []byte(`{"url": "`+server.URL+`", "headers": {"Authorization": "Bearer token"}}`))
+	require.NoError(t, err)
+	require.NoError(t, sender.SendAlert(context.Background(), "mirror-restarts-exhausted", "mirror failed"))
+	assert.Equal(t, "Bearer token", authorization)
+	assert.Equal(t, "mirror-restarts-exhausted", received.AlertKey)
+	assert.Equal(t, "mirror failed", received.Message)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	sender, err = NewAlertSender("webhook", []byte(`{"url": "`+failing.URL+`"}`))
+	require.NoError(t, err)
+	require.ErrorContains(t, sender.SendAlert(context.Background(), "key", "message"), "500")
+}
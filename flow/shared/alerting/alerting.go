@@ -2,10 +2,10 @@ package alerting
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -22,32 +22,35 @@ type Alerter struct {
 	catalogPool *pgxpool.Pool
 }
 
-func (a *Alerter) registerSendersFromPool(ctx context.Context) ([]*slackAlertSender, error) {
+func (a *Alerter) registerSendersFromPool(ctx context.Context) ([]AlertSender, error) {
 	rows, err := a.catalogPool.Query(ctx,
 		"SELECT service_type,service_config FROM peerdb_stats.alerting_config")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read alerter config from catalog: %w", err)
 	}
 
-	var slackAlertSenders []*slackAlertSender
+	var alertSenders []AlertSender
 	var serviceType, serviceConfig string
 	_, err = pgx.ForEachRow(rows, []any{&serviceType, &serviceConfig}, func() error {
-		switch serviceType {
-		case "slack":
-			var slackServiceConfig slackAlertConfig
-			err = json.Unmarshal([]byte(serviceConfig), &slackServiceConfig)
-			if err != nil {
-				return fmt.Errorf("failed to unmarshal Slack service config: %w", err)
-			}
-
-			slackAlertSenders = append(slackAlertSenders, newSlackAlertSender(&slackServiceConfig))
-		default:
-			return fmt.Errorf("unknown service type: %s", serviceType)
+		config, err := DecryptServiceConfig(ctx, serviceType, []byte(serviceConfig))
+		if err != nil {
+			logger.LoggerFromCtx(ctx).Warn("skipping alert sender config which can't be decrypted", slog.Any("error", err))
+			return nil
 		}
+		alertSender, err := NewAlertSender(serviceType, config)
+		if err != nil {
+			// a broken config shouldn't keep alerts from the other senders
+			logger.LoggerFromCtx(ctx).Warn("skipping invalid alert sender config", slog.Any("error", err))
+			return nil
+		}
+		alertSenders = append(alertSenders, alertSender)
 		return nil
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerter config from catalog: %w", err)
+	}
 
-	return slackAlertSenders, nil
+	return alertSenders, nil
 }
 
 // doesn't take care of closing pool, needs to be done externally.
@@ -62,9 +65,9 @@ func NewAlerter(catalogPool *pgxpool.Pool) (*Alerter, error) {
 }
 
 func (a *Alerter) AlertIfSlotLag(ctx context.Context, peerName string, slotInfo *protos.SlotInfo) {
-	slackAlertSenders, err := a.registerSendersFromPool(ctx)
+	alertSenders, err := a.registerSendersFromPool(ctx)
 	if err != nil {
-		logger.LoggerFromCtx(ctx).Warn("failed to set Slack senders", slog.Any("error", err))
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
 		return
 	}
 
@@ -76,28 +79,27 @@ func (a *Alerter) AlertIfSlotLag(ctx context.Context, peerName string, slotInfo
 	defaultSlotLagMBAlertThreshold := dynamicconf.PeerDBSlotLagMBAlertThreshold(ctx)
 	// catalog cannot use default threshold to space alerts properly, use the lowest set threshold instead
 	lowestSlotLagMBAlertThreshold := defaultSlotLagMBAlertThreshold
-	for _, slackAlertSender := range slackAlertSenders {
-		if slackAlertSender.slotLagMBAlertThreshold > 0 {
-			lowestSlotLagMBAlertThreshold = min(lowestSlotLagMBAlertThreshold, slackAlertSender.slotLagMBAlertThreshold)
+	for _, alertSender := range alertSenders {
+		if alertSender.SlotLagMBAlertThreshold() > 0 {
+			lowestSlotLagMBAlertThreshold = min(lowestSlotLagMBAlertThreshold, alertSender.SlotLagMBAlertThreshold())
 		}
 	}
 
 	alertKey := peerName + "-slot-lag-threshold-exceeded"
 	alertMessageTemplate := fmt.Sprintf("%sSlot `%s` on peer `%s` has exceeded threshold size of %%dMB, "+
-		`currently at %.2fMB!
-		cc: <!channel>`, deploymentUIDPrefix, slotInfo.SlotName, peerName, slotInfo.LagInMb)
+		"currently at %.2fMB!", deploymentUIDPrefix, slotInfo.SlotName, peerName, slotInfo.LagInMb)
 
 	if slotInfo.LagInMb > float32(lowestSlotLagMBAlertThreshold) &&
 		a.checkAndAddAlertToCatalog(ctx, alertKey, fmt.Sprintf(alertMessageTemplate, lowestSlotLagMBAlertThreshold)) {
-		for _, slackAlertSender := range slackAlertSenders {
-			if slackAlertSender.slotLagMBAlertThreshold > 0 {
-				if slotInfo.LagInMb > float32(slackAlertSender.slotLagMBAlertThreshold) {
-					a.alertToSlack(ctx, slackAlertSender, alertKey,
-						fmt.Sprintf(alertMessageTemplate, slackAlertSender.slotLagMBAlertThreshold))
+		for _, alertSender := range alertSenders {
+			if alertSender.SlotLagMBAlertThreshold() > 0 {
+				if slotInfo.LagInMb > float32(alertSender.SlotLagMBAlertThreshold()) {
+					a.alertToSender(ctx, alertSender, alertKey,
+						fmt.Sprintf(alertMessageTemplate, alertSender.SlotLagMBAlertThreshold()))
 				}
 			} else {
 				if slotInfo.LagInMb > float32(defaultSlotLagMBAlertThreshold) {
-					a.alertToSlack(ctx, slackAlertSender, alertKey,
+					a.alertToSender(ctx, alertSender, alertKey,
 						fmt.Sprintf(alertMessageTemplate, defaultSlotLagMBAlertThreshold))
 				}
 			}
@@ -105,12 +107,52 @@ func (a *Alerter) AlertIfSlotLag(ctx context.Context, peerName string, slotInfo
 	}
 }
 
+// AlertIfSlotFlushLag alerts when a slot's confirmed_flush_lsn falls behind the source's WAL by more than the configured
+// size or time, which is how far behind the source the mirror reading from the slot is.
+func (a *Alerter) AlertIfSlotFlushLag(ctx context.Context, peerName string, slotInfo *protos.SlotInfo) {
+	lagMB := float64(slotInfo.ConfirmedFlushLagBytes) / 1024 / 1024
+	lag := time.Duration(slotInfo.ConfirmedFlushLagSeconds * float64(time.Second))
+
+	var exceeded []string
+	if thresholdMB := dynamicconf.PeerDBSlotFlushLagMBAlertThreshold(ctx); thresholdMB > 0 && lagMB > float64(thresholdMB) {
+		exceeded = append(exceeded, fmt.Sprintf("%dMB", thresholdMB))
+	}
+	if thresholdMinutes := dynamicconf.PeerDBSlotFlushLagMinutesAlertThreshold(ctx); thresholdMinutes > 0 &&
+		lag > time.Duration(thresholdMinutes)*time.Minute {
+		exceeded = append(exceeded, fmt.Sprintf("%d minutes", thresholdMinutes))
+	}
+	if len(exceeded) == 0 {
+		return
+	}
+
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := peerName + "-slot-flush-lag-threshold-exceeded"
+	alertMessage := fmt.Sprintf("%sSlot `%s` on peer `%s` has exceeded flush lag threshold of %s, "+
+		"its confirmed flush LSN is %.2fMB and %s behind the source!",
+		deploymentUIDPrefix, slotInfo.SlotName, peerName, strings.Join(exceeded, " and "), lagMB, lag.Round(time.Second))
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
 func (a *Alerter) AlertIfOpenConnections(ctx context.Context, peerName string,
 	openConnections *protos.GetOpenConnectionsForUserResult,
 ) {
-	slackAlertSenders, err := a.registerSendersFromPool(ctx)
+	alertSenders, err := a.registerSendersFromPool(ctx)
 	if err != nil {
-		logger.LoggerFromCtx(ctx).Warn("failed to set Slack senders", slog.Any("error", err))
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
 		return
 	}
 
@@ -122,28 +164,28 @@ func (a *Alerter) AlertIfOpenConnections(ctx context.Context, peerName string,
 	// same as with slot lag, use lowest threshold for catalog
 	defaultOpenConnectionsThreshold := dynamicconf.PeerDBOpenConnectionsAlertThreshold(ctx)
 	lowestOpenConnectionsThreshold := defaultOpenConnectionsThreshold
-	for _, slackAlertSender := range slackAlertSenders {
-		if slackAlertSender.openConnectionsAlertThreshold > 0 {
-			lowestOpenConnectionsThreshold = min(lowestOpenConnectionsThreshold, slackAlertSender.openConnectionsAlertThreshold)
+	for _, alertSender := range alertSenders {
+		if alertSender.OpenConnectionsAlertThreshold() > 0 {
+			lowestOpenConnectionsThreshold = min(lowestOpenConnectionsThreshold, alertSender.OpenConnectionsAlertThreshold())
 		}
 	}
 
 	alertKey := peerName + "-max-open-connections-threshold-exceeded"
 	alertMessageTemplate := fmt.Sprintf("%sOpen connections from PeerDB user `%s` on peer `%s`"+
-		` has exceeded threshold size of %%d connections, currently at %d connections!
-		cc: <!channel>`, deploymentUIDPrefix, openConnections.UserName, peerName, openConnections.CurrentOpenConnections)
+		" has exceeded threshold size of %%d connections, currently at %d connections!",
+		deploymentUIDPrefix, openConnections.UserName, peerName, openConnections.CurrentOpenConnections)
 
 	if openConnections.CurrentOpenConnections > int64(lowestOpenConnectionsThreshold) &&
 		a.checkAndAddAlertToCatalog(ctx, alertKey, fmt.Sprintf(alertMessageTemplate, lowestOpenConnectionsThreshold)) {
-		for _, slackAlertSender := range slackAlertSenders {
-			if slackAlertSender.openConnectionsAlertThreshold > 0 {
-				if openConnections.CurrentOpenConnections > int64(slackAlertSender.openConnectionsAlertThreshold) {
-					a.alertToSlack(ctx, slackAlertSender, alertKey,
-						fmt.Sprintf(alertMessageTemplate, slackAlertSender.openConnectionsAlertThreshold))
+		for _, alertSender := range alertSenders {
+			if alertSender.OpenConnectionsAlertThreshold() > 0 {
+				if openConnections.CurrentOpenConnections > int64(alertSender.OpenConnectionsAlertThreshold()) {
+					a.alertToSender(ctx, alertSender, alertKey,
+						fmt.Sprintf(alertMessageTemplate, alertSender.OpenConnectionsAlertThreshold()))
 				}
 			} else {
 				if openConnections.CurrentOpenConnections > int64(defaultOpenConnectionsThreshold) {
-					a.alertToSlack(ctx, slackAlertSender, alertKey,
+					a.alertToSender(ctx, alertSender, alertKey,
 						fmt.Sprintf(alertMessageTemplate, defaultOpenConnectionsThreshold))
 				}
 			}
@@ -151,9 +193,153 @@ func (a *Alerter) AlertIfOpenConnections(ctx context.Context, peerName string,
 	}
 }
 
-func (a *Alerter) alertToSlack(ctx context.Context, slackAlertSender *slackAlertSender, alertKey string, alertMessage string) {
-	err := slackAlertSender.sendAlert(ctx,
-		":rotating_light:Alert:rotating_light:: "+alertKey, alertMessage)
+func (a *Alerter) AlertIfLongTransaction(ctx context.Context, peerName string, slotName string, openTx *protos.OpenTransaction) {
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := peerName + "-long-transaction"
+	alertMessage := fmt.Sprintf("%sA transaction on peer `%s` (pid %d, user `%s`, database `%s`) has been open for %s, "+
+		"slot `%s` cannot advance past it until it commits or is terminated!",
+		deploymentUIDPrefix, peerName, openTx.Pid, openTx.User, openTx.Database, time.Duration(openTx.AgeSeconds)*time.Second, slotName)
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
+func (a *Alerter) AlertIfCredentialExpiring(ctx context.Context, peerName string, expiry *protos.PeerCredentialExpiry) {
+	alertDays := dynamicconf.PeerDBCredentialExpiryAlertDays(ctx)
+	if alertDays == 0 {
+		return
+	}
+	expiresAt := expiry.ExpiresAt.AsTime()
+	if time.Until(expiresAt) > time.Duration(alertDays)*24*time.Hour {
+		return
+	}
+
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := fmt.Sprintf("%s-%s-expiring", peerName, expiry.Credential)
+	alertMessage := fmt.Sprintf("%sThe %s of peer `%s` expires at %s, rotate it to avoid interrupting mirrors!",
+		deploymentUIDPrefix, expiry.Credential, peerName, expiresAt.Format(time.RFC3339))
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
+func (a *Alerter) AlertSchemaChangePaused(ctx context.Context, flowName string, changes string) {
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := flowName + "-schema-change-paused"
+	alertMessage := fmt.Sprintf("%sMirror `%s` paused on schema changes of its source tables: %s. "+
+		"Make them on the destination tables and resume the mirror.", deploymentUIDPrefix, flowName, changes)
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
+func (a *Alerter) AlertSchemaChangeApprovalPending(ctx context.Context, flowName string, changes string) {
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := flowName + "-schema-change-approval"
+	alertMessage := fmt.Sprintf("%sMirror `%s` paused on schema changes of its source tables its destination can't follow: %s. "+
+		"Approve them with ApproveSchemaChange to resume the mirror.", deploymentUIDPrefix, flowName, changes)
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
+func (a *Alerter) AlertDestinationDrift(ctx context.Context, flowName string, drift string, paused bool) {
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := flowName + "-destination-drift"
+	alertMessage := fmt.Sprintf("%sDestination tables of mirror `%s` were changed outside of the mirror: %s",
+		deploymentUIDPrefix, flowName, drift)
+	if paused {
+		alertMessage += ". The mirror is paused until they are fixed and it is resumed."
+	}
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
+func (a *Alerter) AlertMirrorRestartsExhausted(ctx context.Context, flowName string, restarts int32, failure string) {
+	alertSenders, err := a.registerSendersFromPool(ctx)
+	if err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to set alert senders", slog.Any("error", err))
+		return
+	}
+
+	deploymentUIDPrefix := ""
+	if peerdbenv.PeerDBDeploymentUID() != "" {
+		deploymentUIDPrefix = fmt.Sprintf("[%s] ", peerdbenv.PeerDBDeploymentUID())
+	}
+
+	alertKey := flowName + "-restarts-exhausted"
+	alertMessage := fmt.Sprintf("%sMirror `%s` failed again after being restarted %d times and is no longer restarted: %s",
+		deploymentUIDPrefix, flowName, restarts, failure)
+	if a.checkAndAddAlertToCatalog(ctx, alertKey, alertMessage) {
+		for _, alertSender := range alertSenders {
+			a.alertToSender(ctx, alertSender, alertKey, alertMessage)
+		}
+	}
+}
+
+func (a *Alerter) alertToSender(ctx context.Context, alertSender AlertSender, alertKey string, alertMessage string) {
+	err := alertSender.SendAlert(ctx, alertKey, alertMessage)
 	if err != nil {
 		logger.LoggerFromCtx(ctx).Warn("failed to send alert", slog.Any("error", err))
 		return
@@ -162,7 +348,7 @@ func (a *Alerter) alertToSlack(ctx context.Context, slackAlertSender *slackAlert
 
 // Only raises an alert if another alert with the same key hasn't been raised
 // in the past X minutes, where X is configurable and defaults to 15 minutes
-// returns true if alert added to catalog, so proceed with processing alerts to slack
+// returns true if alert added to catalog, so proceed with sending alerts
 func (a *Alerter) checkAndAddAlertToCatalog(ctx context.Context, alertKey string, alertMessage string) bool {
 	dur := dynamicconf.PeerDBAlertingGapMinutesAsDuration(ctx)
 	if dur == 0 {
@@ -194,11 +380,14 @@ func (a *Alerter) checkAndAddAlertToCatalog(ctx context.Context, alertKey string
 	return false
 }
 
+// LogFlowError stores err for the mirror, along with its class for the mirror's status.
 func (a *Alerter) LogFlowError(ctx context.Context, flowName string, err error) {
 	errorWithStack := fmt.Sprintf("%+v", err)
+	errorInfo := ClassifyError(err)
 	_, err = a.catalogPool.Exec(ctx,
-		"INSERT INTO peerdb_stats.flow_errors(flow_name,error_message,error_type) VALUES($1,$2,$3)",
-		flowName, errorWithStack, "error")
+		`INSERT INTO peerdb_stats.flow_errors(flow_name,error_message,error_type,error_class,error_code)
+		 VALUES($1,$2,$3,$4,$5)`,
+		flowName, errorWithStack, "error", string(errorInfo.Class), errorInfo.Code)
 	if err != nil {
 		logger.LoggerFromCtx(ctx).Warn("failed to insert flow error", slog.Any("error", err))
 		return
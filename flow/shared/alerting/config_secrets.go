@@ -0,0 +1,170 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	catalog "github.com/PeerDB-io/peer-flow/connectors/utils/catalog"
+)
+
+// secretConfigKeys are the keys of the service configs of each service type holding credentials, either a string
+// or an object of strings like webhook headers. They're encrypted in the catalog and redacted from listed configs.
+var secretConfigKeys = map[string][]string{
+	SlackServiceType:     {"auth_token"},
+	PagerDutyServiceType: {"routing_key"},
+	EmailServiceType:     {"smtp_password"},
+	WebhookServiceType:   {"headers"},
+}
+
+// mapConfigSecrets replaces each secret of a service config with what f returns for it,
+// f is called with the secret's key, or key and header name for webhook headers, and its value.
+func mapConfigSecrets(
+	serviceType string,
+	serviceConfig []byte,
+	f func(key string, value string) (string, error),
+) ([]byte, error) {
+	keys := secretConfigKeys[serviceType]
+	if len(keys) == 0 {
+		return serviceConfig, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(serviceConfig))
+	decoder.UseNumber()
+	var config map[string]interface{}
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s service config: %w", serviceType, err)
+	}
+
+	for _, key := range keys {
+		switch value := config[key].(type) {
+		case string:
+			secret, err := f(key, value)
+			if err != nil {
+				return nil, err
+			}
+			config[key] = secret
+		case map[string]interface{}:
+			for name, nested := range value {
+				if nestedValue, ok := nested.(string); ok {
+					secret, err := f(key+"."+name, nestedValue)
+					if err != nil {
+						return nil, err
+					}
+					value[name] = secret
+				}
+			}
+		}
+	}
+	return json.Marshal(config)
+}
+
+// EncryptServiceConfig encrypts the secrets of a service config to store it in the catalog.
+func EncryptServiceConfig(ctx context.Context, serviceType string, serviceConfig []byte) ([]byte, error) {
+	return mapConfigSecrets(serviceType, serviceConfig, func(_ string, value string) (string, error) {
+		return catalog.EncryptSecret(ctx, value)
+	})
+}
+
+// DecryptServiceConfig decrypts the secrets of a service config stored in the catalog.
+func DecryptServiceConfig(ctx context.Context, serviceType string, serviceConfig []byte) ([]byte, error) {
+	return mapConfigSecrets(serviceType, serviceConfig, func(key string, value string) (string, error) {
+		secret, err := catalog.DecryptSecret(ctx, value)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt %s of %s service config: %w", key, serviceType, err)
+		}
+		return secret, nil
+	})
+}
+
+// RedactServiceConfig blanks the secrets of a service config, so it can be shown without them.
+func RedactServiceConfig(serviceType string, serviceConfig []byte) ([]byte, error) {
+	return mapConfigSecrets(serviceType, serviceConfig, func(string, string) (string, error) {
+		return "", nil
+	})
+}
+
+// KeepServiceConfigSecrets fills the secrets an update of a service config leaves blank, as they are when
+// configs are listed, with those of the stored config.
+func KeepServiceConfigSecrets(serviceType string, serviceConfig []byte, storedConfig []byte) ([]byte, error) {
+	storedSecrets := make(map[string]string)
+	if _, err := mapConfigSecrets(serviceType, storedConfig, func(key string, value string) (string, error) {
+		storedSecrets[key] = value
+		return value, nil
+	}); err != nil {
+		return nil, err
+	}
+	return mapConfigSecrets(serviceType, serviceConfig, func(key string, value string) (string, error) {
+		if value == "" {
+			return storedSecrets[key], nil
+		}
+		return value, nil
+	})
+}
+
+// RotateAlertConfigEncryption brings the secrets of every alert config under the current catalog encryption key,
+// encrypting those stored unencrypted. Returns the number of configs updated.
+func RotateAlertConfigEncryption(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Error("failed to rollback alert config encryption rotation", slog.Any("error", err))
+		}
+	}()
+
+	rows, err := tx.Query(ctx,
+		"SELECT id,service_type,service_config::text FROM peerdb_stats.alerting_config FOR UPDATE")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query alert configs to rotate: %w", err)
+	}
+	type configRow struct {
+		serviceType   string
+		serviceConfig string
+		id            int64
+	}
+	configs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (configRow, error) {
+		var config configRow
+		err := row.Scan(&config.id, &config.serviceType, &config.serviceConfig)
+		return config, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query alert configs to rotate: %w", err)
+	}
+
+	rotated := 0
+	for _, config := range configs {
+		changed := false
+		serviceConfig, err := mapConfigSecrets(config.serviceType, []byte(config.serviceConfig),
+			func(_ string, value string) (string, error) {
+				secret, err := catalog.RotateSecret(ctx, value)
+				changed = changed || secret != value
+				return secret, err
+			})
+		if err != nil {
+			// configs which don't parse aren't used to send alerts either
+			slog.Warn("skipping invalid alert config", slog.Int64("id", config.id), slog.Any("error", err))
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if _, err := tx.Exec(ctx, "UPDATE peerdb_stats.alerting_config SET service_config=$2 WHERE id=$1",
+			config.id, string(serviceConfig)); err != nil {
+			return 0, fmt.Errorf("failed to update encryption of alert config %d: %w", config.id, err)
+		}
+		rotated += 1
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit alert config encryption rotation: %w", err)
+	}
+	return rotated, nil
+}
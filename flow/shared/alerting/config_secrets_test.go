@@ -0,0 +1,52 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceConfigSecrets(t *testing.T) {
+	t.Setenv("PEERDB_CATALOG_ENCRYPTION_KEYS", "k1:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+
+	serviceConfig := []byte(`{"url": "https://example.com/hook", "headers": {"Authorization": "Bearer hunter2"}, "retries": 3}`)
+	encrypted, err := EncryptServiceConfig(context.Background(), WebhookServiceType, serviceConfig)
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), "hunter2")
+	require.Contains(t, string(encrypted), "https://example.com/hook")
+	decrypted, err := DecryptServiceConfig(context.Background(), WebhookServiceType, encrypted)
+	require.NoError(t, err)
+	require.JSONEq(t, string(serviceConfig), string(decrypted))
+
+	redacted, err := RedactServiceConfig(WebhookServiceType, encrypted)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"url": "https://example.com/hook", "headers": {"Authorization": ""}, "retries": 3}`, string(redacted))
+
+	kept, err := KeepServiceConfigSecrets(WebhookServiceType,
+		[]byte(`{"url": "https://example.com/new", "headers": {"Authorization": "", "X-Team": "data"}}`), decrypted)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"url": "https://example.com/new", "headers": {"Authorization": "Bearer hunter2", "X-Team": "data"}}`,
+		string(kept))
+
+	for serviceType, secretKey := range map[string]string{
+		SlackServiceType:     "auth_token",
+		PagerDutyServiceType: "routing_key",
+		EmailServiceType:     "smtp_password",
+	} {
+		serviceConfig, err := json.Marshal(map[string]string{secretKey: "hunter2", "other": "kept"})
+		require.NoError(t, err)
+		redacted, err := RedactServiceConfig(serviceType, serviceConfig)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"`+secretKey+`": "", "other": "kept"}`, string(redacted), serviceType)
+		kept, err := KeepServiceConfigSecrets(serviceType, redacted, serviceConfig)
+		require.NoError(t, err)
+		require.JSONEq(t, string(serviceConfig), string(kept), serviceType)
+		changed, err := KeepServiceConfigSecrets(serviceType, []byte(`{"`+secretKey+`": "changed"}`), serviceConfig)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"`+secretKey+`": "changed"}`, string(changed), serviceType)
+	}
+}
@@ -0,0 +1,104 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type emailAlertSender struct {
+	AlertSenderThresholds
+	addr       string
+	auth       smtp.Auth
+	from       string
+	recipients []string
+}
+
+type emailAlertConfig struct {
+	AlertSenderThresholds
+	SMTPHost string `json:"smtp_host"`
+	// defaults to 587, the submission port, where STARTTLS is used when the server supports it
+	SMTPPort     uint16   `json:"smtp_port"`
+	SMTPUsername string   `json:"smtp_username"`
+	SMTPPassword string   `json:"smtp_password"`
+	From         string   `json:"from"`
+	Recipients   []string `json:"recipients"`
+}
+
+func newEmailAlertSender(config *emailAlertConfig) (*emailAlertSender, error) {
+	if config.SMTPHost == "" || config.From == "" || len(config.Recipients) == 0 {
+		return nil, errors.New("email service config needs smtp_host, from and recipients")
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid email sender %s: %w", config.From, err)
+	}
+	for _, recipient := range config.Recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return nil, fmt.Errorf("invalid email recipient %s: %w", recipient, err)
+		}
+	}
+
+	port := config.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		// PlainAuth refuses to send credentials over connections without TLS, except to localhost
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
+	}
+	return &emailAlertSender{
+		AlertSenderThresholds: config.AlertSenderThresholds,
+		addr:                  net.JoinHostPort(config.SMTPHost, strconv.Itoa(int(port))),
+		auth:                  auth,
+		from:                  config.From,
+		recipients:            config.Recipients,
+	}, nil
+}
+
+func (s *emailAlertSender) SendAlert(ctx context.Context, alertKey string, alertMessage string) error {
+	from, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("invalid email sender %s: %w", s.from, err)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + s.from + "\r\n")
+	msg.WriteString("To: " + strings.Join(s.recipients, ", ") + "\r\n")
+	// keys are made of peer and mirror names, which mustn't end the header
+	msg.WriteString("Subject: [PeerDB] Alert: " + strings.NewReplacer("\r", " ", "\n", " ").Replace(alertKey) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(alertMessage, "\n", "\r\n") + "\r\n")
+
+	recipients := make([]string, 0, len(s.recipients))
+	for _, recipient := range s.recipients {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid email recipient %s: %w", recipient, err)
+		}
+		recipients = append(recipients, address.Address)
+	}
+
+	// net/smtp doesn't take a context, so sending happens in the background and is abandoned once ctx is done
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(s.addr, s.auth, from.Address, recipients, []byte(msg.String()))
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("failed to send alert email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,167 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/snowflakedb/gosnowflake"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrorClass is the category of a mirror error. Its values are stored with the error and returned by the API,
+// so they must not change.
+type ErrorClass string
+
+const (
+	ErrorClassSourceUnreachable ErrorClass = "SOURCE_UNREACHABLE"
+	ErrorClassDestinationQuota  ErrorClass = "DESTINATION_QUOTA"
+	ErrorClassSchemaMismatch    ErrorClass = "SCHEMA_MISMATCH"
+	ErrorClassData              ErrorClass = "DATA_ERROR"
+	ErrorClassInternal          ErrorClass = "INTERNAL"
+)
+
+// Action describes what can be done about errors of the class.
+func (c ErrorClass) Action() string {
+	switch c {
+	case ErrorClassSourceUnreachable:
+		return "Check that the peer is reachable from PeerDB and that its credentials are valid."
+	case ErrorClassDestinationQuota:
+		return "The destination is out of capacity or rate limited, raise its limits or lower the mirror's batch size."
+	case ErrorClassSchemaMismatch:
+		return "A table or column differs between source and destination, check recent schema changes."
+	case ErrorClassData:
+		return "A value couldn't be written to the destination, check the row named in the error."
+	default:
+		return "Unexpected error, retry the mirror or contact support if it persists."
+	}
+}
+
+// ErrorInfo classifies an error. Code is a stable identifier of the cause within its class,
+// the SQLSTATE or error number of the database reporting it when there is one.
+type ErrorInfo struct {
+	Class ErrorClass
+	Code  string
+}
+
+// ClassifyError returns the class of a connector or workflow error, going by the database or network error it wraps.
+func ClassifyError(err error) ErrorInfo {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return ErrorInfo{Class: classifyPostgresError(pgErr.Code), Code: "PG_" + pgErr.Code}
+	}
+	var pgConnErr *pgconn.ConnectError
+	if errors.As(err, &pgConnErr) {
+		return ErrorInfo{Class: ErrorClassSourceUnreachable, Code: "PG_CONNECT"}
+	}
+
+	var chErr *clickhouse.Exception
+	if errors.As(err, &chErr) {
+		return ErrorInfo{Class: classifyClickhouseError(chErr.Code), Code: "CH_" + strconv.Itoa(int(chErr.Code))}
+	}
+
+	var sfErr *gosnowflake.SnowflakeError
+	if errors.As(err, &sfErr) {
+		return ErrorInfo{Class: classifySnowflakeError(sfErr.Number), Code: "SF_" + strconv.Itoa(sfErr.Number)}
+	}
+
+	var bqErr *googleapi.Error
+	if errors.As(err, &bqErr) {
+		reason := strconv.Itoa(bqErr.Code)
+		if len(bqErr.Errors) > 0 && bqErr.Errors[0].Reason != "" {
+			reason = bqErr.Errors[0].Reason
+		}
+		return ErrorInfo{Class: classifyBigQueryError(bqErr.Code, reason), Code: "BQ_" + reason}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorInfo{Class: ErrorClassSourceUnreachable, Code: "NET_DNS"}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorInfo{Class: ErrorClassSourceUnreachable, Code: "NET_TIMEOUT"}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrorInfo{Class: ErrorClassSourceUnreachable, Code: "NET_CONNECTION"}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorInfo{Class: ErrorClassInternal, Code: "TIMEOUT"}
+	}
+	return ErrorInfo{Class: ErrorClassInternal, Code: "INTERNAL"}
+}
+
+func classifyPostgresError(code string) ErrorClass {
+	switch {
+	// connection exceptions and invalid authorization
+	case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "28"):
+		return ErrorClassSourceUnreachable
+	// insufficient resources and program limits, like disk_full and too_many_connections
+	case strings.HasPrefix(code, "53"), strings.HasPrefix(code, "54"):
+		return ErrorClassDestinationQuota
+	// data exceptions and integrity constraint violations
+	case strings.HasPrefix(code, "22"), strings.HasPrefix(code, "23"):
+		return ErrorClassData
+	// undefined_column, undefined_table, datatype_mismatch, cannot_coerce
+	case code == "42703", code == "42P01", code == "42804", code == "42846":
+		return ErrorClassSchemaMismatch
+	default:
+		return ErrorClassInternal
+	}
+}
+
+func classifyClickhouseError(code int32) ErrorClass {
+	switch code {
+	// MEMORY_LIMIT_EXCEEDED, TOO_MANY_SIMULTANEOUS_QUERIES, TOO_MANY_PARTS, NOT_ENOUGH_SPACE
+	case 241, 202, 252, 243:
+		return ErrorClassDestinationQuota
+	// NO_SUCH_COLUMN_IN_TABLE, UNKNOWN_IDENTIFIER, TYPE_MISMATCH, UNKNOWN_TABLE
+	case 16, 47, 53, 60:
+		return ErrorClassSchemaMismatch
+	// CANNOT_PARSE_TEXT, CANNOT_PARSE_INPUT_ASSERTION_FAILED, CANNOT_PARSE_DATETIME, CANNOT_CONVERT_TYPE
+	case 6, 27, 41, 70:
+		return ErrorClassData
+	// AUTHENTICATION_FAILED
+	case 516:
+		return ErrorClassSourceUnreachable
+	default:
+		return ErrorClassInternal
+	}
+}
+
+func classifySnowflakeError(number int) ErrorClass {
+	switch number {
+	// object does not exist or not authorized, invalid identifier
+	case 2003, 904:
+		return ErrorClassSchemaMismatch
+	// numeric, timestamp and date values not recognized
+	case 100038, 100035, 100040:
+		return ErrorClassData
+	// incorrect username or password
+	case 390100:
+		return ErrorClassSourceUnreachable
+	default:
+		return ErrorClassInternal
+	}
+}
+
+func classifyBigQueryError(httpCode int, reason string) ErrorClass {
+	switch {
+	case reason == "quotaExceeded" || reason == "rateLimitExceeded" || httpCode == 429:
+		return ErrorClassDestinationQuota
+	case reason == "notFound":
+		return ErrorClassSchemaMismatch
+	case reason == "invalid":
+		return ErrorClassData
+	case httpCode == 401 || httpCode == 403:
+		return ErrorClassSourceUnreachable
+	default:
+		return ErrorClassInternal
+	}
+}
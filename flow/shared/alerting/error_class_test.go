@@ -0,0 +1,60 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/snowflakedb/gosnowflake"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected ErrorInfo
+	}{
+		{
+			err:      fmt.Errorf("failed to sync records: %w", &pgconn.PgError{Code: "42703"}),
+			expected: ErrorInfo{Class: ErrorClassSchemaMismatch, Code: "PG_42703"},
+		},
+		{
+			err:      &pgconn.PgError{Code: "22P02"},
+			expected: ErrorInfo{Class: ErrorClassData, Code: "PG_22P02"},
+		},
+		{
+			err:      &pgconn.PgError{Code: "53100"},
+			expected: ErrorInfo{Class: ErrorClassDestinationQuota, Code: "PG_53100"},
+		},
+		{
+			err:      fmt.Errorf("failed to insert: %w", &clickhouse.Exception{Code: 252, Name: "TOO_MANY_PARTS"}),
+			expected: ErrorInfo{Class: ErrorClassDestinationQuota, Code: "CH_252"},
+		},
+		{
+			err:      &gosnowflake.SnowflakeError{Number: 904},
+			expected: ErrorInfo{Class: ErrorClassSchemaMismatch, Code: "SF_904"},
+		},
+		{
+			err:      &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}},
+			expected: ErrorInfo{Class: ErrorClassDestinationQuota, Code: "BQ_quotaExceeded"},
+		},
+		{
+			err:      fmt.Errorf("failed to connect: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}),
+			expected: ErrorInfo{Class: ErrorClassSourceUnreachable, Code: "NET_CONNECTION"},
+		},
+		{
+			err:      &net.DNSError{Name: "db.example.com", IsNotFound: true},
+			expected: ErrorInfo{Class: ErrorClassSourceUnreachable, Code: "NET_DNS"},
+		},
+		{
+			err:      errors.New("unexpected"),
+			expected: ErrorInfo{Class: ErrorClassInternal, Code: "INTERNAL"},
+		},
+	} {
+		assert.Equal(t, tc.expected, ClassifyError(tc.err), tc.err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+type pagerDutyAlertSender struct {
+	AlertSenderThresholds
+	client     *http.Client
+	eventsURL  string
+	routingKey string
+	severity   string
+}
+
+type pagerDutyAlertConfig struct {
+	AlertSenderThresholds
+	// integration key of a PagerDuty service's Events API v2 integration
+	RoutingKey string `json:"routing_key"`
+	// critical, error, warning or info, defaults to critical
+	Severity string `json:"severity"`
+}
+
+func newPagerDutyAlertSender(config *pagerDutyAlertConfig) (*pagerDutyAlertSender, error) {
+	if config.RoutingKey == "" {
+		return nil, errors.New("PagerDuty service config needs routing_key")
+	}
+	severity := config.Severity
+	switch severity {
+	case "":
+		severity = "critical"
+	case "critical", "error", "warning", "info":
+	default:
+		return nil, fmt.Errorf("unknown PagerDuty severity: %s", severity)
+	}
+	return &pagerDutyAlertSender{
+		AlertSenderThresholds: config.AlertSenderThresholds,
+		client:                &http.Client{Timeout: time.Minute},
+		eventsURL:             pagerDutyEventsURL,
+		routingKey:            config.RoutingKey,
+		severity:              severity,
+	}, nil
+}
+
+func (s *pagerDutyAlertSender) SendAlert(ctx context.Context, alertKey string, alertMessage string) error {
+	summary := alertMessage
+	// PagerDuty truncates summaries to 1024 characters
+	if len(summary) > 1024 {
+		summary = summary[:1021] + "..."
+	}
+	event, err := json.Marshal(map[string]interface{}{
+		"routing_key":  s.routingKey,
+		"event_action": "trigger",
+		// alerts with the same key are grouped into one incident while it's open
+		"dedup_key": alertKey,
+		"payload": map[string]interface{}{
+			"summary":  summary,
+			"source":   "peerdb",
+			"severity": s.severity,
+			"custom_details": map[string]string{
+				"alert_key": alertKey,
+				"message":   alertMessage,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal PagerDuty event: %w", err)
+	}
+	return postAlert(ctx, s.client, s.eventsURL, nil, event, "PagerDuty")
+}
+
+// postAlert posts a JSON alert body to url, erroring when the response status isn't 2xx
+func postAlert(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte, service string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", service, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert to %s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to send alert to %s, status %s: %s", service, resp.Status, respBody)
+	}
+	return nil
+}
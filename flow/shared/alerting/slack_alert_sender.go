@@ -2,39 +2,41 @@ package alerting
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/slack-go/slack"
 )
 
 type slackAlertSender struct {
-	client                        *slack.Client
-	channelIDs                    []string
-	slotLagMBAlertThreshold       uint32
-	openConnectionsAlertThreshold uint32
+	AlertSenderThresholds
+	client     *slack.Client
+	channelIDs []string
 }
 
 type slackAlertConfig struct {
-	AuthToken                     string   `json:"auth_token"`
-	ChannelIDs                    []string `json:"channel_ids"`
-	SlotLagMBAlertThreshold       uint32   `json:"slot_lag_mb_alert_threshold"`
-	OpenConnectionsAlertThreshold uint32   `json:"open_connections_alert_threshold"`
+	AlertSenderThresholds
+	AuthToken  string   `json:"auth_token"`
+	ChannelIDs []string `json:"channel_ids"`
 }
 
-func newSlackAlertSender(config *slackAlertConfig) *slackAlertSender {
-	return &slackAlertSender{
-		client:                        slack.New(config.AuthToken),
-		channelIDs:                    config.ChannelIDs,
-		slotLagMBAlertThreshold:       config.SlotLagMBAlertThreshold,
-		openConnectionsAlertThreshold: config.OpenConnectionsAlertThreshold,
+func newSlackAlertSender(config *slackAlertConfig) (*slackAlertSender, error) {
+	if config.AuthToken == "" || len(config.ChannelIDs) == 0 {
+		return nil, errors.New("Slack service config needs auth_token and channel_ids")
 	}
+	return &slackAlertSender{
+		AlertSenderThresholds: config.AlertSenderThresholds,
+		client:                slack.New(config.AuthToken),
+		channelIDs:            config.ChannelIDs,
+	}, nil
 }
 
-func (s *slackAlertSender) sendAlert(ctx context.Context, alertTitle string, alertMessage string) error {
+func (s *slackAlertSender) SendAlert(ctx context.Context, alertKey string, alertMessage string) error {
 	for _, channelID := range s.channelIDs {
 		_, _, _, err := s.client.SendMessageContext(ctx, channelID, slack.MsgOptionBlocks(
-			slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text", alertTitle, true, false)),
-			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", alertMessage, false, false), nil, nil),
+			slack.NewHeaderBlock(slack.NewTextBlockObject("plain_text",
+				":rotating_light:Alert:rotating_light:: "+alertKey, true, false)),
+			slack.NewSectionBlock(slack.NewTextBlockObject("mrkdwn", alertMessage+"\ncc: <!channel>", false, false), nil, nil),
 		))
 		if err != nil {
 			return fmt.Errorf("failed to send message to Slack channel %s: %w", channelID, err)
@@ -0,0 +1,67 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+type webhookAlertSender struct {
+	AlertSenderThresholds
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+type webhookAlertConfig struct {
+	AlertSenderThresholds
+	URL string `json:"url"`
+	// sent with every request, like an Authorization header
+	Headers map[string]string `json:"headers"`
+}
+
+// webhookAlert is the JSON body posted to webhooks
+type webhookAlert struct {
+	AlertKey      string    `json:"alert_key"`
+	Message       string    `json:"message"`
+	DeploymentUID string    `json:"deployment_uid,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+func newWebhookAlertSender(config *webhookAlertConfig) (*webhookAlertSender, error) {
+	if config.URL == "" {
+		return nil, errors.New("webhook service config needs url")
+	}
+	parsedURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("webhook url must be http or https: %s", config.URL)
+	}
+	return &webhookAlertSender{
+		AlertSenderThresholds: config.AlertSenderThresholds,
+		client:                &http.Client{Timeout: time.Minute},
+		url:                   config.URL,
+		headers:               config.Headers,
+	}, nil
+}
+
+func (s *webhookAlertSender) SendAlert(ctx context.Context, alertKey string, alertMessage string) error {
+	body, err := json.Marshal(webhookAlert{
+		AlertKey:      alertKey,
+		Message:       alertMessage,
+		DeploymentUID: peerdbenv.PeerDBDeploymentUID(),
+		Timestamp:     time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook alert: %w", err)
+	}
+	return postAlert(ctx, s.client, s.url, s.headers, body, "webhook")
+}
@@ -15,12 +15,28 @@ const (
 	CDCFlowStateQuery  = "q-cdc-flow-state"
 	QRepFlowStateQuery = "q-qrep-flow-state"
 	FlowStatusQuery    = "q-flow-status"
+	// progress of a query replication mirror's initial copy
+	InitialLoadProgressQuery = "q-initial-load-progress"
 
 	// Updates
 	FlowStatusUpdate = "u-flow-status"
 )
 
-const MirrorNameSearchAttribute = "MirrorName"
+// partition batches replicated in parallel when a QRep config doesn't set max_parallel_workers
+const DefaultQRepMaxParallelWorkers = 16
+
+// layout of the raw tables CDC mirrors sync into, bumped with every change so existing raw tables get upgraded
+//  1. _peerdb_data JSON, record type, match data, batch id and unchanged toast columns per record
+const RawTableVersion int32 = 1
+
+// workflow id of the global scheduler, which mirrors ask for leases before expensive phases
+const SchedulerWorkflowID = "peerdb-scheduler"
+
+const (
+	MirrorNameSearchAttribute = "MirrorName"
+	// memo holding the owner, runbook and description given at mirror creation
+	MirrorMetadataMemoKey = "MirrorMetadata"
+)
 
 type (
 	ContextKey string
@@ -52,6 +68,15 @@ func GetPeerFlowTaskQueueName(taskQueueID TaskQueueID) (string, error) {
 	}
 }
 
+// GetMirrorTaskQueueName returns the task queue of a mirror pinned to the given task queue,
+// the default peer flow task queue when it isn't pinned.
+func GetMirrorTaskQueueName(taskQueue string) (string, error) {
+	if taskQueue == "" {
+		return GetPeerFlowTaskQueueName(PeerFlowTaskQueueID)
+	}
+	return prependUIDToTaskQueueName(taskQueue), nil
+}
+
 func prependUIDToTaskQueueName(taskQueueName string) string {
 	deploymentUID := peerdbenv.PeerDBDeploymentUID()
 	if deploymentUID == "" {
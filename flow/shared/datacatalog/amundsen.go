@@ -0,0 +1,45 @@
+package datacatalog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// amundsenPublisher annotates tables through Amundsen's metadata service.
+// Amundsen has no API to create tables, so tables must already be ingested by its databuilder.
+type amundsenPublisher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// tableKey builds Amundsen's table key, {database}://{cluster}.{schema}/{table}, using the peer name as cluster.
+func (p *amundsenPublisher) tableKey(table *Table) string {
+	schema, name := "public", table.DestinationTable
+	if lastDot := strings.LastIndexByte(table.DestinationTable, '.'); lastDot != -1 {
+		schema, name = table.DestinationTable[:lastDot], table.DestinationTable[lastDot+1:]
+	}
+	return fmt.Sprintf("%s://%s.%s/%s", platform(table.Destination), table.Destination.GetName(), schema, name)
+}
+
+func (p *amundsenPublisher) PublishTable(ctx context.Context, table *Table) error {
+	tableURL := p.url + "/table/" + url.PathEscape(p.tableKey(table))
+	if err := sendJSON(ctx, p.client, http.MethodPut, tableURL+"/description", p.token, nil,
+		map[string]string{"description": description(table)},
+	); err != nil {
+		return fmt.Errorf("failed to publish description of %s to Amundsen: %w", table.DestinationTable, err)
+	}
+	if err := sendJSON(ctx, p.client, http.MethodPut, tableURL+"/tag/peerdb?tag_type=default", p.token, nil, nil); err != nil {
+		return fmt.Errorf("failed to tag %s in Amundsen: %w", table.DestinationTable, err)
+	}
+	return nil
+}
+
+// PublishFreshness is a no-op, Amundsen picks up watermarks from its own databuilder jobs.
+func (p *amundsenPublisher) PublishFreshness(context.Context, *Table, time.Time) error {
+	return nil
+}
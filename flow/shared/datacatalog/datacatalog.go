@@ -0,0 +1,110 @@
+package datacatalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// Table is a destination table of a mirror along with the source table it is replicated from.
+type Table struct {
+	FlowJobName      string
+	Source           *protos.Peer
+	SourceTable      string
+	Destination      *protos.Peer
+	DestinationTable string
+	Schema           *protos.TableSchema
+}
+
+// Publisher keeps an external data catalog up to date with the tables mirrors write to.
+type Publisher interface {
+	// PublishTable registers the table with its schema and provenance, called when mirrors set up tables.
+	PublishTable(ctx context.Context, table *Table) error
+	// PublishFreshness records that the table received data at the given time.
+	PublishFreshness(ctx context.Context, table *Table, updatedAt time.Time) error
+}
+
+// NewPublisherFromEnv returns the publisher configured by PEERDB_DATA_CATALOG, nil if none is.
+func NewPublisherFromEnv() Publisher {
+	url := strings.TrimSuffix(peerdbenv.PeerDBDataCatalogURL(), "/")
+	token := peerdbenv.PeerDBDataCatalogToken()
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	switch catalog := peerdbenv.PeerDBDataCatalog(); catalog {
+	case "":
+		return nil
+	case "datahub":
+		return &dataHubPublisher{url: url, token: token, client: client}
+	case "amundsen":
+		return &amundsenPublisher{url: url, token: token, client: client}
+	default:
+		slog.Warn("unknown data catalog, not publishing tables", slog.String("catalog", catalog))
+		return nil
+	}
+}
+
+func sendJSON(ctx context.Context, client *http.Client, method string, url string, token string,
+	headers map[string]string, body any,
+) error {
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s responded with %s: %s", method, url, resp.Status, respBody)
+	}
+	return nil
+}
+
+// platform returns the name catalogs know the peer's database type by.
+func platform(peer *protos.Peer) string {
+	switch peer.Type {
+	case protos.DBType_POSTGRES:
+		return "postgres"
+	case protos.DBType_SNOWFLAKE:
+		return "snowflake"
+	case protos.DBType_BIGQUERY:
+		return "bigquery"
+	case protos.DBType_CLICKHOUSE:
+		return "clickhouse"
+	default:
+		return strings.ToLower(peer.Type.String())
+	}
+}
+
+func description(table *Table) string {
+	return fmt.Sprintf("Replicated by PeerDB mirror %s from %s table %s.",
+		table.FlowJobName, table.Source.GetName(), table.SourceTable)
+}
@@ -0,0 +1,97 @@
+package datacatalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func testTable() *Table {
+	return &Table{
+		FlowJobName: "events_mirror",
+		Source: &protos.Peer{Name: "pg", Type: protos.DBType_POSTGRES, Config: &protos.Peer_PostgresConfig{
+			PostgresConfig: &protos.PostgresConfig{Database: "app"},
+		}},
+		SourceTable: "public.events",
+		Destination: &protos.Peer{Name: "ch", Type: protos.DBType_CLICKHOUSE, Config: &protos.Peer_ClickhouseConfig{
+			ClickhouseConfig: &protos.ClickhouseConfig{Database: "analytics"},
+		}},
+		DestinationTable: "events",
+		Schema: &protos.TableSchema{
+			PrimaryKeyColumns: []string{"id"},
+			Columns:           []*protos.FieldDescription{{Name: "id", Type: "int64"}},
+		},
+	}
+}
+
+func TestDataHubPublishTable(t *testing.T) {
+	var mu sync.Mutex
+	aspects := make(map[string]map[string]any)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/aspects", r.URL.Path)
+		require.Equal(t, "ingestProposal", r.URL.Query().Get("action"))
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var body struct {
+			Proposal struct {
+				EntityURN  string `json:"entityUrn"`
+				AspectName string `json:"aspectName"`
+				Aspect     struct {
+					Value string `json:"value"`
+				} `json:"aspect"`
+			} `json:"proposal"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "urn:li:dataset:(urn:li:dataPlatform:clickhouse,analytics.events,PROD)", body.Proposal.EntityURN)
+
+		var aspect map[string]any
+		require.NoError(t, json.Unmarshal([]byte(body.Proposal.Aspect.Value), &aspect))
+		mu.Lock()
+		aspects[body.Proposal.AspectName] = aspect
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	publisher := &dataHubPublisher{url: server.URL, token: "token", client: server.Client()}
+	require.NoError(t, publisher.PublishTable(context.Background(), testTable()))
+
+	require.Len(t, aspects, 3)
+	require.Equal(t, []any{map[string]any{
+		"dataset": "urn:li:dataset:(urn:li:dataPlatform:postgres,app.public.events,PROD)",
+		"type":    "COPY",
+	}}, aspects["upstreamLineage"]["upstreams"])
+	require.Len(t, aspects["schemaMetadata"]["fields"], 1)
+}
+
+func TestAmundsenPublishTable(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	publisher := &amundsenPublisher{url: server.URL, client: server.Client()}
+	require.NoError(t, publisher.PublishTable(context.Background(), testTable()))
+	require.Equal(t, []string{
+		"/table/clickhouse://ch.public/events/description",
+		"/table/clickhouse://ch.public/events/tag/peerdb",
+	}, paths)
+}
+
+func TestPublishErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	publisher := &dataHubPublisher{url: server.URL, client: server.Client()}
+	require.ErrorContains(t, publisher.PublishTable(context.Background(), testTable()), "401 Unauthorized")
+}
@@ -0,0 +1,104 @@
+package datacatalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/shared/lineage"
+)
+
+// dataHubPublisher writes aspects of datasets through the ingestProposal endpoint of DataHub's GMS.
+type dataHubPublisher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func dataHubDatasetURN(table string, peerPlatform string) string {
+	return fmt.Sprintf("urn:li:dataset:(urn:li:dataPlatform:%s,%s,PROD)", peerPlatform, table)
+}
+
+func (p *dataHubPublisher) PublishTable(ctx context.Context, table *Table) error {
+	urn := dataHubDatasetURN(lineage.DatasetName(table.Destination, table.DestinationTable), platform(table.Destination))
+	upstreamURN := dataHubDatasetURN(lineage.DatasetName(table.Source, table.SourceTable), platform(table.Source))
+
+	aspects := map[string]any{
+		"datasetProperties": map[string]any{
+			"description": description(table),
+			"customProperties": map[string]string{
+				"peerdb_mirror":       table.FlowJobName,
+				"peerdb_source_peer":  table.Source.GetName(),
+				"peerdb_source_table": table.SourceTable,
+			},
+		},
+		"upstreamLineage": map[string]any{
+			"upstreams": []map[string]any{{"dataset": upstreamURN, "type": "COPY"}},
+		},
+	}
+	if table.Schema != nil {
+		fields := make([]map[string]any, 0, len(table.Schema.Columns))
+		for _, column := range table.Schema.Columns {
+			fields = append(fields, map[string]any{
+				"fieldPath":      column.Name,
+				"nativeDataType": column.Type,
+				"type":           map[string]any{"type": map[string]any{"com.linkedin.schema.NullType": map[string]any{}}},
+			})
+		}
+		aspects["schemaMetadata"] = map[string]any{
+			"schemaName":     table.DestinationTable,
+			"platform":       "urn:li:dataPlatform:" + platform(table.Destination),
+			"version":        0,
+			"hash":           "",
+			"platformSchema": map[string]any{"com.linkedin.schema.OtherSchema": map[string]any{"rawSchema": ""}},
+			"fields":         fields,
+			"primaryKeys":    table.Schema.PrimaryKeyColumns,
+		}
+	}
+
+	for aspectName, aspect := range aspects {
+		if err := p.ingestProposal(ctx, urn, aspectName, aspect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *dataHubPublisher) PublishFreshness(ctx context.Context, table *Table, updatedAt time.Time) error {
+	urn := dataHubDatasetURN(lineage.DatasetName(table.Destination, table.DestinationTable), platform(table.Destination))
+	return p.ingestProposal(ctx, urn, "operation", map[string]any{
+		"timestampMillis":      time.Now().UnixMilli(),
+		"lastUpdatedTimestamp": updatedAt.UnixMilli(),
+		"operationType":        "UPSERT",
+		"actor":                "urn:li:corpuser:peerdb",
+		"customProperties":     map[string]string{"peerdb_mirror": table.FlowJobName},
+	})
+}
+
+func (p *dataHubPublisher) ingestProposal(ctx context.Context, urn string, aspectName string, aspect any) error {
+	aspectJSON, err := json.Marshal(aspect)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s aspect: %w", aspectName, err)
+	}
+	err = sendJSON(ctx, p.client, http.MethodPost, p.url+"/aspects?action=ingestProposal", p.token,
+		map[string]string{"X-RestLi-Protocol-Version": "2.0.0"},
+		map[string]any{
+			"proposal": map[string]any{
+				"entityType": "dataset",
+				"entityUrn":  urn,
+				"changeType": "UPSERT",
+				"aspectName": aspectName,
+				"aspect": map[string]any{
+					"value":       string(aspectJSON),
+					"contentType": "application/json",
+				},
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s of %s to DataHub: %w", aspectName, strings.TrimPrefix(urn, "urn:li:dataset:"), err)
+	}
+	return nil
+}
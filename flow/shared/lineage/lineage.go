@@ -0,0 +1,231 @@
+package lineage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/logger"
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+const (
+	producer          = "https://github.com/PeerDB-io/peerdb"
+	runEventSchemaURL = "https://openlineage.io/spec/2-0-2/OpenLineage.json#/$defs/RunEvent"
+	schemaFacetURL    = "https://openlineage.io/spec/facets/1-1-1/SchemaDatasetFacet.json#/$defs/SchemaDatasetFacet"
+	columnFacetURL    = "https://openlineage.io/spec/facets/1-1-0/ColumnLineageDatasetFacet.json#/$defs/ColumnLineageDatasetFacet"
+)
+
+type EventType string
+
+const (
+	EventTypeStart    EventType = "START"
+	EventTypeComplete EventType = "COMPLETE"
+	EventTypeFail     EventType = "FAIL"
+)
+
+type RunEvent struct {
+	EventType EventType `json:"eventType"`
+	EventTime time.Time `json:"eventTime"`
+	Producer  string    `json:"producer"`
+	SchemaURL string    `json:"schemaURL"`
+	Run       Run       `json:"run"`
+	Job       Job       `json:"job"`
+	Inputs    []Dataset `json:"inputs"`
+	Outputs   []Dataset `json:"outputs"`
+}
+
+type Run struct {
+	RunID string `json:"runId"`
+}
+
+type Job struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type Dataset struct {
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	Facets    map[string]any `json:"facets,omitempty"`
+}
+
+type SchemaFacet struct {
+	Producer  string        `json:"_producer"`
+	SchemaURL string        `json:"_schemaURL"`
+	Fields    []SchemaField `json:"fields"`
+}
+
+type SchemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ColumnLineageFacet struct {
+	Producer  string                        `json:"_producer"`
+	SchemaURL string                        `json:"_schemaURL"`
+	Fields    map[string]ColumnLineageField `json:"fields"`
+}
+
+type ColumnLineageField struct {
+	InputFields []InputField `json:"inputFields"`
+}
+
+type InputField struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Field     string `json:"field"`
+}
+
+// Emitter posts OpenLineage run events, it is a no-op unless PEERDB_OPENLINEAGE_URL is set.
+// Lineage is best effort, failing to emit an event is logged and never fails the flow reporting it.
+type Emitter struct {
+	url       string
+	apiKey    string
+	namespace string
+	client    *http.Client
+}
+
+func NewEmitterFromEnv() *Emitter {
+	return &Emitter{
+		url:       peerdbenv.PeerDBOpenLineageURL(),
+		apiKey:    peerdbenv.PeerDBOpenLineageAPIKey(),
+		namespace: peerdbenv.PeerDBOpenLineageNamespace(),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *Emitter) Enabled() bool {
+	return e != nil && e.url != ""
+}
+
+// NewRunEvent builds an event for a run of the given flow, datasets are to be filled in by the caller.
+func (e *Emitter) NewRunEvent(eventType EventType, flowJobName string, runID string) *RunEvent {
+	return &RunEvent{
+		EventType: eventType,
+		EventTime: time.Now().UTC(),
+		Producer:  producer,
+		SchemaURL: runEventSchemaURL,
+		Run:       Run{RunID: runID},
+		Job:       Job{Namespace: e.namespace, Name: flowJobName},
+	}
+}
+
+func (e *Emitter) Emit(ctx context.Context, event *RunEvent) {
+	if !e.Enabled() {
+		return
+	}
+	if err := e.post(ctx, event); err != nil {
+		logger.LoggerFromCtx(ctx).Warn("failed to emit lineage event",
+			slog.String("job", event.Job.Name), slog.String("eventType", string(event.EventType)), slog.Any("error", err))
+	}
+}
+
+func (e *Emitter) post(ctx context.Context, event *RunEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lineage event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("lineage endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// BatchRunID derives a stable run id for a batch of a flow, so events about the same batch share a run.
+func BatchRunID(flowJobName string, batchID int64) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%d", flowJobName, batchID))).String()
+}
+
+// PeerNamespace returns the dataset namespace of a peer following OpenLineage naming conventions.
+func PeerNamespace(peer *protos.Peer) string {
+	switch config := peer.Config.(type) {
+	case *protos.Peer_PostgresConfig:
+		return fmt.Sprintf("postgres://%s:%d", config.PostgresConfig.Host, config.PostgresConfig.Port)
+	case *protos.Peer_SnowflakeConfig:
+		return "snowflake://" + config.SnowflakeConfig.AccountId
+	case *protos.Peer_BigqueryConfig:
+		return "bigquery"
+	case *protos.Peer_ClickhouseConfig:
+		return fmt.Sprintf("clickhouse://%s:%d", config.ClickhouseConfig.Host, config.ClickhouseConfig.Port)
+	default:
+		return "peerdb://" + peer.Name
+	}
+}
+
+// peerDatabase qualifies dataset names with the database of peers whose namespace doesn't include it.
+func peerDatabase(peer *protos.Peer) string {
+	switch config := peer.Config.(type) {
+	case *protos.Peer_PostgresConfig:
+		return config.PostgresConfig.Database
+	case *protos.Peer_SnowflakeConfig:
+		return config.SnowflakeConfig.Database
+	case *protos.Peer_BigqueryConfig:
+		return config.BigqueryConfig.ProjectId + "." + config.BigqueryConfig.DatasetId
+	case *protos.Peer_ClickhouseConfig:
+		return config.ClickhouseConfig.Database
+	default:
+		return ""
+	}
+}
+
+// DatasetName returns the name of a table qualified by the database of its peer.
+func DatasetName(peer *protos.Peer, table string) string {
+	if database := peerDatabase(peer); database != "" {
+		return database + "." + table
+	}
+	return table
+}
+
+func NewDataset(peer *protos.Peer, table string) Dataset {
+	return Dataset{Namespace: PeerNamespace(peer), Name: DatasetName(peer, table)}
+}
+
+// TableDatasets returns the source and destination datasets of a replicated table. With a schema,
+// both carry it and every destination column is traced back to the source column it is replicated from.
+func TableDatasets(source *protos.Peer, destination *protos.Peer, srcTable string, dstTable string,
+	schema *protos.TableSchema,
+) (Dataset, Dataset) {
+	input := NewDataset(source, srcTable)
+	output := NewDataset(destination, dstTable)
+	if schema == nil {
+		return input, output
+	}
+
+	fields := make([]SchemaField, 0, len(schema.Columns))
+	columnLineage := make(map[string]ColumnLineageField, len(schema.Columns))
+	for _, column := range schema.Columns {
+		fields = append(fields, SchemaField{Name: column.Name, Type: column.Type})
+		columnLineage[column.Name] = ColumnLineageField{
+			InputFields: []InputField{{Namespace: input.Namespace, Name: input.Name, Field: column.Name}},
+		}
+	}
+	input.Facets = map[string]any{
+		"schema": SchemaFacet{Producer: producer, SchemaURL: schemaFacetURL, Fields: fields},
+	}
+	output.Facets = map[string]any{
+		"schema":        SchemaFacet{Producer: producer, SchemaURL: schemaFacetURL, Fields: fields},
+		"columnLineage": ColumnLineageFacet{Producer: producer, SchemaURL: columnFacetURL, Fields: columnLineage},
+	}
+	return input, output
+}
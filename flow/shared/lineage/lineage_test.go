@@ -0,0 +1,57 @@
+package lineage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestTableDatasetsColumnLineage(t *testing.T) {
+	source := &protos.Peer{Name: "pg", Config: &protos.Peer_PostgresConfig{
+		PostgresConfig: &protos.PostgresConfig{Host: "db", Port: 5432, Database: "app"},
+	}}
+	destination := &protos.Peer{Name: "ch", Config: &protos.Peer_ClickhouseConfig{
+		ClickhouseConfig: &protos.ClickhouseConfig{Host: "ch", Port: 9000, Database: "analytics"},
+	}}
+	schema := &protos.TableSchema{Columns: []*protos.FieldDescription{{Name: "id", Type: "int64"}}}
+
+	input, output := TableDatasets(source, destination, "public.events", "events", schema)
+	require.Equal(t, "postgres://db:5432", input.Namespace)
+	require.Equal(t, "app.public.events", input.Name)
+	require.Equal(t, "clickhouse://ch:9000", output.Namespace)
+	require.Equal(t, "analytics.events", output.Name)
+	require.Equal(t, ColumnLineageFacet{
+		Producer:  producer,
+		SchemaURL: columnFacetURL,
+		Fields: map[string]ColumnLineageField{
+			"id": {InputFields: []InputField{{Namespace: "postgres://db:5432", Name: "app.public.events", Field: "id"}}},
+		},
+	}, output.Facets["columnLineage"])
+}
+
+func TestEmit(t *testing.T) {
+	var received RunEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	emitter := &Emitter{url: server.URL, apiKey: "key", namespace: "peerdb", client: server.Client()}
+	runID := BatchRunID("mirror", 4)
+	require.Equal(t, runID, BatchRunID("mirror", 4))
+	emitter.Emit(context.Background(), emitter.NewRunEvent(EventTypeComplete, "mirror", runID))
+
+	require.Equal(t, EventTypeComplete, received.EventType)
+	require.Equal(t, Job{Namespace: "peerdb", Name: "mirror"}, received.Job)
+	require.Equal(t, runID, received.Run.RunID)
+
+	var disabled *Emitter
+	require.False(t, disabled.Enabled())
+}
@@ -0,0 +1,91 @@
+package payloadcodec
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"go.temporal.io/sdk/converter"
+
+	"github.com/PeerDB-io/peer-flow/peerdbenv"
+)
+
+// CodecServerPath is where the API server serves the codec server on PEERDB_TEMPORAL_CODEC_SERVER_ADDRESS,
+// the Temporal UI and CLI take the codec server URL with this path as their codec endpoint.
+const CodecServerPath = "/temporal-codec"
+
+// codecsFromEnv returns the payload codecs configured by peerdbenv, in the order NewCodecDataConverter takes them:
+// payloads are encrypted before they're offloaded, so offloaded payloads are encrypted too.
+func codecsFromEnv(ctx context.Context) ([]converter.PayloadCodec, error) {
+	var codecs []converter.PayloadCodec
+	if url := peerdbenv.PeerDBTemporalPayloadOffloadURL(); url != "" {
+		codec, err := NewOffloadCodec(ctx, url, int(peerdbenv.PeerDBTemporalPayloadOffloadThresholdBytes()))
+		if err != nil {
+			return nil, err
+		}
+		codecs = append(codecs, codec)
+	}
+	if keys := peerdbenv.PeerDBTemporalPayloadEncryptionKeys(); len(keys) != 0 {
+		codec, err := NewEncryptionCodec(keys)
+		if err != nil {
+			return nil, err
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs, nil
+}
+
+// NewDataConverterFromEnv returns the default data converter, encrypting payloads with
+// PEERDB_TEMPORAL_PAYLOAD_ENCRYPTION_KEYS and offloading large ones to PEERDB_TEMPORAL_PAYLOAD_OFFLOAD_URL if set.
+// Workers and the API server must agree on both, as neither can read payloads encoded with settings it lacks.
+func NewDataConverterFromEnv(ctx context.Context) (converter.DataConverter, error) {
+	codecs, err := codecsFromEnv(ctx)
+	if err != nil || len(codecs) == 0 {
+		return converter.GetDefaultDataConverter(), err
+	}
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codecs...), nil
+}
+
+// NewCodecServerFromEnv returns a codec server decoding payloads for the Temporal UI at PEERDB_TEMPORAL_CODEC_SERVER_ORIGIN,
+// nil if that isn't set. Anyone able to call it can decrypt payloads, so it requires PEERDB_TEMPORAL_CODEC_SERVER_TOKEN
+// as bearer token and is only served on the operator listener at PEERDB_TEMPORAL_CODEC_SERVER_ADDRESS.
+func NewCodecServerFromEnv(ctx context.Context) (http.Handler, error) {
+	origin := peerdbenv.PeerDBTemporalCodecServerOrigin()
+	if origin == "" {
+		return nil, nil
+	}
+	token := peerdbenv.PeerDBTemporalCodecServerToken()
+	if token == "" {
+		return nil, errors.New("PEERDB_TEMPORAL_CODEC_SERVER_TOKEN must be set to serve the codec server")
+	}
+	codecs, err := codecsFromEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newCodecServer(origin, token, codecs...), nil
+}
+
+func newCodecServer(origin string, token string, codecs ...converter.PayloadCodec) http.Handler {
+	handler := converter.NewPayloadCodecHTTPHandler(codecs...)
+	authorization := []byte("Bearer " + token)
+
+	mux := http.NewServeMux()
+	mux.Handle(CodecServerPath+"/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Allow-Methods", "POST,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,X-Namespace,Authorization")
+		// preflight requests of the Temporal UI, browsers send them without credentials
+		if r.Method == http.MethodOptions {
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), authorization) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	return mux
+}
@@ -0,0 +1,56 @@
+package payloadcodec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+)
+
+func TestCodecServerAuth(t *testing.T) {
+	codec, err := NewEncryptionCodec([]string{testKey(t, "k")})
+	require.NoError(t, err)
+	payloads, err := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codec).ToPayloads("secret")
+	require.NoError(t, err)
+	body, err := converter.NewJSONPayloadConverter().ToPayload(payloads)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(newCodecServer("https://temporal.example.com", "token", codec))
+	defer server.Close()
+
+	decode := func(authorization string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+CodecServerPath+"/decode", strings.NewReader(string(body.Data)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	require.Equal(t, http.StatusUnauthorized, decode("").StatusCode)
+	require.Equal(t, http.StatusUnauthorized, decode("Bearer wrong").StatusCode)
+	require.Equal(t, http.StatusOK, decode("Bearer token").StatusCode)
+
+	// preflight requests carry no credentials
+	req, err := http.NewRequest(http.MethodOptions, server.URL+CodecServerPath+"/decode", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "https://temporal.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// only the codec server is served
+	resp, err = http.Post(server.URL+"/v1/peers", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,154 @@
+package payloadcodec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// encoding of payloads encrypted by EncryptionCodec, payloads with other encodings are left as they are
+	encryptedEncoding = "binary/peerdb-encrypted"
+	// metadata naming the key a payload is encrypted with
+	metadataEncryptionKeyID = "encryption-key-id"
+	encryptionKeySize       = 32
+	// bound on decompressed payloads, well above Temporal's own limits
+	maxDecodedPayloadSize = 256 << 20
+)
+
+type encryptionKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// EncryptionCodec compresses payloads with zstd and encrypts them with AES-256-GCM, so flow configs holding
+// connection details never reach Temporal history in plaintext. It keeps no state beyond its keys,
+// so it can also back a codec server letting the Temporal UI and CLI show decrypted payloads.
+type EncryptionCodec struct {
+	keys    []encryptionKey
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+var _ converter.PayloadCodec = (*EncryptionCodec)(nil)
+
+// NewEncryptionCodec takes id:base64 pairs of 256 bit keys, the first encrypts payloads and the others
+// still decrypt payloads encrypted before a rotation.
+func NewEncryptionCodec(keyPairs []string) (*EncryptionCodec, error) {
+	if len(keyPairs) == 0 {
+		return nil, errors.New("no payload encryption keys")
+	}
+	keys := make([]encryptionKey, 0, len(keyPairs))
+	for _, keyPair := range keyPairs {
+		id, encodedKey, ok := strings.Cut(keyPair, ":")
+		if !ok || id == "" {
+			return nil, errors.New("payload encryption keys must be id:base64 pairs")
+		}
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode payload encryption key %s: %w", id, err)
+		}
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("payload encryption key %s must be %d bytes", id, encryptionKeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload encryption key %s: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload encryption key %s: %w", id, err)
+		}
+		keys = append(keys, encryptionKey{id: id, aead: aead})
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedPayloadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	return &EncryptionCodec{keys: keys, encoder: encoder, decoder: decoder}, nil
+}
+
+// Encode compresses and encrypts payloads with the first key, the key id is bound to the ciphertext.
+func (c *EncryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	key := c.keys[0]
+	encoded := make([]*commonpb.Payload, len(payloads))
+	for i, payload := range payloads {
+		data, err := payload.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize payload to encrypt: %w", err)
+		}
+		compressed := c.encoder.EncodeAll(data, nil)
+
+		sealed := make([]byte, key.aead.NonceSize(), key.aead.NonceSize()+len(compressed)+key.aead.Overhead())
+		if _, err := rand.Read(sealed); err != nil {
+			return nil, fmt.Errorf("failed to generate payload nonce: %w", err)
+		}
+		sealed = key.aead.Seal(sealed, sealed, compressed, []byte(key.id))
+
+		encoded[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(encryptedEncoding),
+				metadataEncryptionKeyID:    []byte(key.id),
+			},
+			Data: sealed,
+		}
+	}
+	return encoded, nil
+}
+
+// Decode decrypts payloads with the key they name, payloads which aren't encrypted are passed through
+// so histories from before encryption was enabled can still be read.
+func (c *EncryptionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	decoded := make([]*commonpb.Payload, len(payloads))
+	for i, payload := range payloads {
+		if string(payload.GetMetadata()[converter.MetadataEncoding]) != encryptedEncoding {
+			decoded[i] = payload
+			continue
+		}
+
+		keyID := string(payload.GetMetadata()[metadataEncryptionKeyID])
+		var aead cipher.AEAD
+		for _, key := range c.keys {
+			if key.id == keyID {
+				aead = key.aead
+				break
+			}
+		}
+		if aead == nil {
+			return nil, fmt.Errorf("payload encryption key %s is not configured", keyID)
+		}
+
+		sealed := payload.GetData()
+		if len(sealed) < aead.NonceSize() {
+			return nil, errors.New("encrypted payload is too short")
+		}
+		compressed, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt payload with key %s: %w", keyID, err)
+		}
+		data, err := c.decoder.DecodeAll(compressed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+
+		original := &commonpb.Payload{}
+		if err := original.Unmarshal(data); err != nil {
+			return nil, fmt.Errorf("failed to deserialize decrypted payload: %w", err)
+		}
+		decoded[i] = original
+	}
+	return decoded, nil
+}
@@ -0,0 +1,156 @@
+package payloadcodec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	// encoding of payloads standing in for ones offloaded to the blob store
+	offloadedEncoding = "binary/peerdb-offloaded"
+	blobTimeout       = time.Minute
+)
+
+// blobStore holds offloaded payloads by key, keys are the payload's hash so the same payload is stored once.
+type blobStore interface {
+	// location is where a key is stored, such as s3://bucket/prefix/key
+	location(key string) string
+	put(ctx context.Context, key string, data []byte) error
+	get(ctx context.Context, key string) ([]byte, error)
+}
+
+// reference is the data of a payload offloaded to the blob store.
+type reference struct {
+	Location string `json:"location"`
+	SHA256   string `json:"sha256"`
+	Size     int    `json:"size"`
+}
+
+// OffloadCodec offloads payloads larger than a threshold to a blob store, leaving only a reference to them in
+// workflow history, which keeps large table schemas and partition lists clear of Temporal's blob size limit.
+type OffloadCodec struct {
+	store     blobStore
+	threshold int
+}
+
+var _ converter.PayloadCodec = (*OffloadCodec)(nil)
+
+// NewOffloadCodec offloads payloads above threshold bytes to an s3:// or gs:// location.
+func NewOffloadCodec(ctx context.Context, url string, threshold int) (*OffloadCodec, error) {
+	store, err := newBlobStore(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up Temporal payload offloading to %s: %w", url, err)
+	}
+	return &OffloadCodec{store: store, threshold: threshold}, nil
+}
+
+// Encode replaces payloads above the threshold with references to them in the blob store.
+func (c *OffloadCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	encoded := make([]*commonpb.Payload, len(payloads))
+	for i, payload := range payloads {
+		if payload.Size() <= c.threshold {
+			encoded[i] = payload
+			continue
+		}
+
+		data, err := payload.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize payload to offload: %w", err)
+		}
+		hash := sha256.Sum256(data)
+		key := hex.EncodeToString(hash[:])
+
+		ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+		err = c.store.put(ctx, key, data)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to offload payload: %w", err)
+		}
+
+		ref, err := json.Marshal(reference{Location: c.store.location(key), SHA256: key, Size: len(data)})
+		if err != nil {
+			return nil, err
+		}
+		slog.Debug("offloaded Temporal payload", slog.String("location", c.store.location(key)), slog.Int("size", len(data)))
+		encoded[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{converter.MetadataEncoding: []byte(offloadedEncoding)},
+			Data:     ref,
+		}
+	}
+	return encoded, nil
+}
+
+// Decode fetches payloads offloaded by Encode back from the blob store, other payloads are passed through.
+func (c *OffloadCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	decoded := make([]*commonpb.Payload, len(payloads))
+	for i, payload := range payloads {
+		if string(payload.GetMetadata()[converter.MetadataEncoding]) != offloadedEncoding {
+			decoded[i] = payload
+			continue
+		}
+
+		var ref reference
+		if err := json.Unmarshal(payload.GetData(), &ref); err != nil {
+			return nil, fmt.Errorf("failed to parse offloaded payload reference: %w", err)
+		}
+		if ref.Location != c.store.location(ref.SHA256) {
+			return nil, fmt.Errorf("payload was offloaded to %s, which isn't under the configured offload location", ref.Location)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), blobTimeout)
+		data, err := c.store.get(ctx, ref.SHA256)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch offloaded payload from %s: %w", ref.Location, err)
+		}
+		hash := sha256.Sum256(data)
+		if hex.EncodeToString(hash[:]) != ref.SHA256 {
+			return nil, fmt.Errorf("offloaded payload at %s doesn't match its hash", ref.Location)
+		}
+
+		original := &commonpb.Payload{}
+		if err := original.Unmarshal(data); err != nil {
+			return nil, fmt.Errorf("failed to deserialize offloaded payload: %w", err)
+		}
+		decoded[i] = original
+	}
+	return decoded, nil
+}
+
+func newBlobStore(ctx context.Context, url string) (blobStore, error) {
+	scheme, path, ok := strings.Cut(url, "://")
+	if !ok {
+		return nil, errors.New("offload location must be an s3:// or gs:// URL")
+	}
+	bucket, prefix, _ := strings.Cut(path, "/")
+	if bucket == "" {
+		return nil, errors.New("offload location is missing a bucket")
+	}
+	prefix = strings.Trim(prefix, "/")
+
+	switch scheme {
+	case "s3":
+		return newS3Store(bucket, prefix)
+	case "gs":
+		return newGCSStore(ctx, bucket, prefix)
+	default:
+		return nil, fmt.Errorf("unsupported offload location scheme %s", scheme)
+	}
+}
+
+func objectKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
@@ -0,0 +1,91 @@
+package payloadcodec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+)
+
+type s3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// newS3Store uses the AWS credentials of the environment, which can point at any S3 compatible store with AWS_ENDPOINT
+func newS3Store(bucket string, prefix string) (*s3Store, error) {
+	client, err := utils.CreateS3Client(utils.S3PeerCredentials{})
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *s3Store) location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, objectKey(s.prefix, key))
+}
+
+func (s *s3Store) put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey(s.prefix, key)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *s3Store) get(ctx context.Context, key string) ([]byte, error) {
+	obj, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey(s.prefix, key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(obj.Body)
+}
+
+type gcsStore struct {
+	bucket *storage.BucketHandle
+	name   string
+	prefix string
+}
+
+// newGCSStore uses the application default credentials of the environment
+func newGCSStore(ctx context.Context, bucket string, prefix string) (*gcsStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return &gcsStore{bucket: client.Bucket(bucket), name: bucket, prefix: prefix}, nil
+}
+
+func (s *gcsStore) location(key string) string {
+	return fmt.Sprintf("gs://%s/%s", s.name, objectKey(s.prefix, key))
+}
+
+func (s *gcsStore) put(ctx context.Context, key string, data []byte) error {
+	writer := s.bucket.Object(objectKey(s.prefix, key)).NewWriter(ctx)
+	if _, err := writer.Write(data); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+func (s *gcsStore) get(ctx context.Context, key string) ([]byte, error) {
+	reader, err := s.bucket.Object(objectKey(s.prefix, key)).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
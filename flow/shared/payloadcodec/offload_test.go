@@ -0,0 +1,59 @@
+package payloadcodec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+)
+
+type memoryStore struct {
+	blobs map[string][]byte
+}
+
+func (s *memoryStore) location(key string) string {
+	return "mem://bucket/" + key
+}
+
+func (s *memoryStore) put(_ context.Context, key string, data []byte) error {
+	s.blobs[key] = data
+	return nil
+}
+
+func (s *memoryStore) get(_ context.Context, key string) ([]byte, error) {
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, errors.New("no such blob")
+	}
+	return data, nil
+}
+
+func TestOffloadCodec(t *testing.T) {
+	store := &memoryStore{blobs: make(map[string][]byte)}
+	dataConverter := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), &OffloadCodec{store: store, threshold: 1024})
+
+	small := "schema"
+	large := strings.Repeat("partition", 1000)
+	payloads, err := dataConverter.ToPayloads(small, large)
+	require.NoError(t, err)
+	require.Len(t, store.blobs, 1, "only the large payload is offloaded")
+	require.Less(t, payloads.Size(), 1024)
+
+	var decodedSmall, decodedLarge string
+	require.NoError(t, dataConverter.FromPayloads(payloads, &decodedSmall, &decodedLarge))
+	require.Equal(t, small, decodedSmall)
+	require.Equal(t, large, decodedLarge)
+
+	// the same payload is stored once
+	_, err = dataConverter.ToPayloads(large)
+	require.NoError(t, err)
+	require.Len(t, store.blobs, 1)
+
+	for key := range store.blobs {
+		store.blobs[key] = []byte("tampered")
+	}
+	require.Error(t, dataConverter.FromPayloads(payloads, &decodedSmall, &decodedLarge))
+}
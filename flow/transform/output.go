@@ -0,0 +1,208 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+const outputGlobal = "output"
+
+// OutputColumn is a column of a destination table as the script declares it.
+type OutputColumn struct {
+	Name string
+	Kind qvalue.QValueKind
+}
+
+// outputKinds are the kinds declared columns can have, those scripts can assign values of
+var outputKinds = map[qvalue.QValueKind]struct{}{
+	qvalue.QValueKindBoolean:     {},
+	qvalue.QValueKindInt16:       {},
+	qvalue.QValueKindInt32:       {},
+	qvalue.QValueKindInt64:       {},
+	qvalue.QValueKindFloat32:     {},
+	qvalue.QValueKindFloat64:     {},
+	qvalue.QValueKindNumeric:     {},
+	qvalue.QValueKindString:      {},
+	qvalue.QValueKindJSON:        {},
+	qvalue.QValueKindBytes:       {},
+	qvalue.QValueKindUUID:        {},
+	qvalue.QValueKindTimestamp:   {},
+	qvalue.QValueKindTimestampTZ: {},
+	qvalue.QValueKindDate:        {},
+}
+
+// loadOutputs reads the columns a script declares destination tables to have, in order, e.g.
+//
+//	output = {
+//		["public.orders"] = {
+//			{name = "id", type = "int64"},
+//			{name = "email_domain", type = "string"},
+//		},
+//	}
+//
+// Types are those of columns of records, see qvalue.QValueKind. Outputs of tables other than the destinations
+// are an error when checkOutputs is set, and ignored otherwise.
+func loadOutputs(value lua.LValue, destinations map[string]struct{}, checkOutputs bool,
+) (map[string][]OutputColumn, error) {
+	if value == lua.LNil {
+		return nil, nil
+	}
+	table, ok := value.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("transformation script's %s is a %s instead of a table", outputGlobal, value.Type())
+	}
+
+	outputs := make(map[string][]OutputColumn)
+	var err error
+	table.ForEach(func(key lua.LValue, luaColumns lua.LValue) {
+		if err != nil {
+			return
+		}
+		destination, ok := key.(lua.LString)
+		if !ok {
+			err = fmt.Errorf("transformation script declares the output of a %s instead of a table name", key.Type())
+			return
+		}
+		if _, ok := destinations[string(destination)]; !ok {
+			if checkOutputs {
+				err = fmt.Errorf("transformation script declares the output of %s, which isn't a destination table of the mirror",
+					destination)
+			}
+			return
+		}
+		var columns []OutputColumn
+		columns, err = loadOutputColumns(string(destination), luaColumns)
+		outputs[string(destination)] = columns
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+func loadOutputColumns(destination string, value lua.LValue) ([]OutputColumn, error) {
+	table, ok := value.(*lua.LTable)
+	if !ok || table.Len() == 0 {
+		return nil, fmt.Errorf("transformation script's output of %s isn't a list of columns", destination)
+	}
+
+	columns := make([]OutputColumn, 0, table.Len())
+	for i := 1; i <= table.Len(); i++ {
+		column, ok := table.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			return nil, fmt.Errorf("column %d of the output of %s isn't a table", i, destination)
+		}
+		name, ok := column.RawGetString("name").(lua.LString)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("column %d of the output of %s has no name", i, destination)
+		}
+		kind, ok := column.RawGetString("type").(lua.LString)
+		if _, supported := outputKinds[qvalue.QValueKind(kind)]; !ok || !supported {
+			return nil, fmt.Errorf("column %s of the output of %s has an unsupported type %s",
+				name, destination, column.RawGetString("type"))
+		}
+		if slices.ContainsFunc(columns, func(c OutputColumn) bool { return c.Name == string(name) }) {
+			return nil, fmt.Errorf("column %s of the output of %s is declared twice", name, destination)
+		}
+		columns = append(columns, OutputColumn{Name: string(name), Kind: qvalue.QValueKind(kind)})
+	}
+	return columns, nil
+}
+
+// DestinationSchema returns the schema of a destination table, given the schema it has without the script.
+// Tables the script declares the output of have the declared columns instead, keeping the primary key columns,
+// which have to be part of the output, or all of the columns when the table has no primary key.
+func (s *Script) DestinationSchema(destination string, schema *protos.TableSchema) (*protos.TableSchema, error) {
+	columns, ok := s.outputs[destination]
+	if !ok {
+		return schema, nil
+	}
+
+	fields := make([]*protos.FieldDescription, 0, len(columns))
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, &protos.FieldDescription{Name: column.Name, Type: string(column.Kind), TypeModifier: -1})
+		names = append(names, column.Name)
+	}
+	primaryKeyColumns := schema.PrimaryKeyColumns
+	if schema.SyntheticPrimaryKey {
+		primaryKeyColumns = names
+	} else {
+		for _, column := range primaryKeyColumns {
+			if !slices.Contains(names, column) {
+				return nil, fmt.Errorf("primary key column %s of %s is missing from the transformation script's output",
+					column, destination)
+			}
+		}
+	}
+	return &protos.TableSchema{
+		TableIdentifier:       schema.TableIdentifier,
+		PrimaryKeyColumns:     primaryKeyColumns,
+		IsReplicaIdentityFull: schema.IsReplicaIdentityFull,
+		Columns:               fields,
+		SyntheticPrimaryKey:   schema.SyntheticPrimaryKey,
+	}, nil
+}
+
+// DeclaresOutput reports whether the script declares the columns of a destination table.
+func (s *Script) DeclaresOutput(destination string) bool {
+	_, ok := s.outputs[destination]
+	return ok
+}
+
+// TransformStream passes the rows a snapshot or query replication of sourceTable copies into destinationTable
+// through the script as inserts, leaving out those it drops. The stream has the declared output of the table as
+// its schema, or else keeps its schema, and fails on rows routed to other tables as it only copies into one.
+func (s *Script) TransformStream(
+	ctx context.Context,
+	stream *model.QRecordStream,
+	buffer int,
+	sourceTable string,
+	destinationTable string,
+) *model.QRecordStream {
+	var columns []string
+	var schema *model.QRecordSchema
+	return stream.Mapped(ctx, buffer, func(inputSchema *model.QRecordSchema) *model.QRecordSchema {
+		columns = inputSchema.GetColumnNames()
+		schema = inputSchema
+		if outputColumns, ok := s.outputs[destinationTable]; ok {
+			fields := make([]model.QField, 0, len(outputColumns))
+			for _, column := range outputColumns {
+				fields = append(fields, model.QField{Name: column.Name, Type: column.Kind, Nullable: true})
+			}
+			schema = model.NewQRecordSchema(fields)
+		}
+		return schema
+	}, func(record []qvalue.QValue) ([]qvalue.QValue, error) {
+		transformed, err := s.Transform(&model.InsertRecord{
+			SourceTableName:      sourceTable,
+			DestinationTableName: destinationTable,
+			Items:                model.NewRecordItemWithData(columns, record),
+		})
+		if err != nil || transformed == nil {
+			return nil, err
+		}
+		if target := transformed.GetDestinationTableName(); target != destinationTable {
+			return nil, fmt.Errorf("transformation script routed a row of %s to %s, copied rows can only go to %s",
+				sourceTable, target, destinationTable)
+		}
+
+		items := transformed.(*model.InsertRecord).Items
+		row := make([]qvalue.QValue, 0, len(schema.Fields))
+		for _, field := range schema.Fields {
+			if idx, ok := items.ColToValIdx[field.Name]; ok && !items.Values[idx].IsNull() {
+				row = append(row, items.Values[idx])
+			} else {
+				row = append(row, qvalue.QValue{Kind: field.Type})
+			}
+		}
+		return row, nil
+	})
+}
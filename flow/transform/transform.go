@@ -0,0 +1,415 @@
+// Package transform runs the Lua scripts mirrors transform their CDC records with between pulling and syncing them.
+//
+// A script defines a function transform(record), which is called with a table of each record:
+//
+//	record.kind    "insert", "update" or "delete"
+//	record.source  the source table
+//	record.target  the destination table, which may be changed to route the record to another table of the mirror
+//	record.row     the columns of the row, the new row of updates
+//	record.old     the old row of updates, when the source sends it
+//
+// Columns of the row can be renamed, dropped by setting them to nil, derived or redacted by assigning them.
+// Null values are the global null, which can be assigned to null columns.
+// The function returns the record to sync it, or nil to drop it.
+//
+// Scripts changing the columns of a destination table declare its columns in the global output, see loadOutputs,
+// which the table is then created with. Rows of snapshots pass through transform too, as inserts.
+package transform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+const transformFunction = "transform"
+
+// callTimeout is how long a script may take to load or to transform a record before it's stopped
+const callTimeout = 10 * time.Second
+
+// Script is a loaded transformation script. Records are transformed one at a time,
+// and transforming a record fails once the script is closed.
+type Script struct {
+	ctx          context.Context
+	mu           sync.Mutex
+	state        *lua.LState
+	fn           *lua.LFunction
+	null         *lua.LUserData
+	destinations map[string]struct{}
+	outputs      map[string][]OutputColumn
+	outputKinds  map[string]map[string]qvalue.QValueKind
+}
+
+// Load runs a transformation script in a sandbox without access to the filesystem or processes.
+// Records can only be routed to the destinations, the destination tables of the mirror.
+// The script is stopped once ctx is done, or when loading it or transforming a record takes longer than callTimeout.
+func Load(ctx context.Context, source string, destinations []string) (*Script, error) {
+	return load(ctx, source, destinations, true)
+}
+
+// LoadTable loads a transformation script to transform the rows of destination, which is one
+// of the destination tables of the mirror, so outputs declared for other tables are ignored.
+func LoadTable(ctx context.Context, source string, destination string) (*Script, error) {
+	return load(ctx, source, []string{destination}, false)
+}
+
+func load(ctx context.Context, source string, destinations []string, checkOutputs bool) (*Script, error) {
+	state := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+	for _, unsafe := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module"} {
+		state.SetGlobal(unsafe, lua.LNil)
+	}
+	// nil can't be stored in tables, so null values of columns are represented by a sentinel
+	null := state.NewUserData()
+	state.SetGlobal("null", null)
+
+	if err := withDeadline(ctx, state, func() error {
+		return state.DoString(source)
+	}); err != nil {
+		state.Close()
+		return nil, fmt.Errorf("failed to load transformation script: %w", err)
+	}
+	fn, ok := state.GetGlobal(transformFunction).(*lua.LFunction)
+	if !ok {
+		state.Close()
+		return nil, fmt.Errorf("transformation script doesn't define a %s function", transformFunction)
+	}
+
+	destinationSet := make(map[string]struct{}, len(destinations))
+	for _, destination := range destinations {
+		destinationSet[destination] = struct{}{}
+	}
+	outputs, err := loadOutputs(state.GetGlobal(outputGlobal), destinationSet, checkOutputs)
+	if err != nil {
+		state.Close()
+		return nil, err
+	}
+	outputKinds := make(map[string]map[string]qvalue.QValueKind, len(outputs))
+	for table, columns := range outputs {
+		kinds := make(map[string]qvalue.QValueKind, len(columns))
+		for _, column := range columns {
+			kinds[column.Name] = column.Kind
+		}
+		outputKinds[table] = kinds
+	}
+	return &Script{
+		ctx:          ctx,
+		state:        state,
+		fn:           fn,
+		null:         null,
+		destinations: destinationSet,
+		outputs:      outputs,
+		outputKinds:  outputKinds,
+	}, nil
+}
+
+// Close releases the Lua state of the script, waiting for a record being transformed.
+func (s *Script) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Close()
+	s.state = nil
+}
+
+// Transform runs the script on a record, returning the record to sync or nil when the script drops it.
+// Columns the script didn't change keep their values and types, the record passed in isn't modified.
+func (s *Script) Transform(record model.Record) (model.Record, error) {
+	var kind string
+	var sourceTable string
+	var row *model.RecordItems
+	var old *model.RecordItems
+	switch r := record.(type) {
+	case *model.InsertRecord:
+		kind, sourceTable, row = "insert", r.SourceTableName, r.Items
+	case *model.UpdateRecord:
+		kind, sourceTable, row, old = "update", r.SourceTableName, r.NewItems, r.OldItems
+	case *model.DeleteRecord:
+		kind, sourceTable, row = "delete", r.SourceTableName, r.Items
+	default:
+		return record, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return nil, errors.New("transformation script is closed")
+	}
+
+	luaRecord := s.state.NewTable()
+	luaRecord.RawSetString("kind", lua.LString(kind))
+	luaRecord.RawSetString("source", lua.LString(sourceTable))
+	luaRecord.RawSetString("target", lua.LString(record.GetDestinationTableName()))
+	luaRow := s.itemsToTable(row)
+	luaRecord.RawSetString("row", luaRow)
+	var luaOld *lua.LTable
+	if old != nil {
+		luaOld = s.itemsToTable(old)
+		luaRecord.RawSetString("old", luaOld)
+	}
+
+	if err := withDeadline(s.ctx, s.state, func() error {
+		return s.state.CallByParam(lua.P{Fn: s.fn, NRet: 1, Protect: true}, luaRecord)
+	}); err != nil {
+		return nil, fmt.Errorf("transformation script failed on record of table %s: %w", sourceTable, err)
+	}
+	ret := s.state.Get(-1)
+	s.state.Pop(1)
+	if ret == lua.LNil {
+		return nil, nil
+	}
+	result, ok := ret.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("transformation script returned a %s instead of the record", ret.Type())
+	}
+
+	target, ok := result.RawGetString("target").(lua.LString)
+	if !ok {
+		return nil, errors.New("transformation script returned a record without a target table")
+	}
+	if _, ok := s.destinations[string(target)]; !ok {
+		return nil, fmt.Errorf("transformation script routed a record to %s, which isn't a destination table of the mirror",
+			target)
+	}
+	newRow, err := s.tableToItems(result.RawGetString("row"), luaRow, row, string(target))
+	if err != nil {
+		return nil, err
+	}
+
+	switch r := record.(type) {
+	case *model.InsertRecord:
+		return &model.InsertRecord{
+			SourceTableName:      r.SourceTableName,
+			DestinationTableName: string(target),
+			CheckpointID:         r.CheckpointID,
+			Items:                newRow,
+		}, nil
+	case *model.UpdateRecord:
+		newOld := old
+		if luaOld != nil {
+			newOld, err = s.tableToItems(result.RawGetString("old"), luaOld, old, string(target))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &model.UpdateRecord{
+			SourceTableName:       r.SourceTableName,
+			DestinationTableName:  string(target),
+			CheckpointID:          r.CheckpointID,
+			OldItems:              newOld,
+			NewItems:              newRow,
+			UnchangedToastColumns: r.UnchangedToastColumns,
+		}, nil
+	case *model.DeleteRecord:
+		return &model.DeleteRecord{
+			SourceTableName:       r.SourceTableName,
+			DestinationTableName:  string(target),
+			CheckpointID:          r.CheckpointID,
+			Items:                 newRow,
+			UnchangedToastColumns: r.UnchangedToastColumns,
+		}, nil
+	default:
+		return record, nil
+	}
+}
+
+// withDeadline runs call on state, which stops running the script once ctx is done or callTimeout passed.
+func withDeadline(ctx context.Context, state *lua.LState, call func() error) error {
+	callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+	state.SetContext(callCtx)
+	defer state.RemoveContext()
+	return call()
+}
+
+func (s *Script) itemsToTable(items *model.RecordItems) *lua.LTable {
+	table := s.state.CreateTable(0, items.Len())
+	for column, idx := range items.ColToValIdx {
+		table.RawSetString(column, s.valueToLua(items.Values[idx]))
+	}
+	return table
+}
+
+// valueToLua converts a value for the script, values without a Lua counterpart are passed as strings
+func (s *Script) valueToLua(value qvalue.QValue) lua.LValue {
+	switch v := value.Value().(type) {
+	case nil:
+		return s.null
+	case bool:
+		return lua.LBool(v)
+	case int16:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []byte:
+		return lua.LString(v)
+	case [16]byte:
+		return lua.LString(uuid.UUID(v).String())
+	case time.Time:
+		return lua.LString(v.Format(time.RFC3339Nano))
+	case *big.Rat:
+		if v == nil {
+			return s.null
+		}
+		return lua.LString(v.FloatString(100))
+	default:
+		return lua.LString(fmt.Sprint(v))
+	}
+}
+
+// tableToItems reads a row of a record routed to target back from the script. Columns the script didn't change
+// keep their values. Changed columns take their kind from the declared output of target, or else from the column
+// of the same name passed to the script, and columns left out of a declared output are dropped.
+func (s *Script) tableToItems(value lua.LValue, original *lua.LTable, items *model.RecordItems, target string,
+) (*model.RecordItems, error) {
+	table, ok := value.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("transformation script returned a row that is a %s instead of a table", value.Type())
+	}
+	declaredKinds, declared := s.outputKinds[target]
+
+	result := model.NewRecordItems(items.Len())
+	var err error
+	table.ForEach(func(key lua.LValue, luaValue lua.LValue) {
+		if err != nil {
+			return
+		}
+		column, ok := key.(lua.LString)
+		if !ok {
+			err = fmt.Errorf("transformation script returned a row with a %s column name", key.Type())
+			return
+		}
+		kind, inOutput := declaredKinds[string(column)]
+		if declared && !inOutput {
+			return
+		}
+		previous := qvalue.QValue{}
+		if idx, ok := items.ColToValIdx[string(column)]; ok {
+			previous = items.Values[idx]
+			if original.RawGetString(string(column)) == luaValue &&
+				(!inOutput || previous.Kind == kind || previous.IsNull()) {
+				result.AddColumn(string(column), previous)
+				return
+			}
+		}
+		if !inOutput {
+			kind = previous.Kind
+		}
+		qv, convErr := s.luaToValue(luaValue, kind)
+		if convErr == nil && inOutput && !qv.IsNull() && qv.Kind != kind {
+			convErr = fmt.Errorf("a %s can't be assigned to a column declared as %s", qv.Kind, kind)
+		}
+		if convErr != nil {
+			err = fmt.Errorf("transformation script returned an invalid value for column %s: %w", column, convErr)
+			return
+		}
+		result.AddColumn(string(column), qv)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// luaToValue converts a value the script assigned, keeping the kind of the column when it is compatible
+func (s *Script) luaToValue(value lua.LValue, kind qvalue.QValueKind) (qvalue.QValue, error) {
+	if value == s.null {
+		return qvalue.QValue{Kind: qvalue.QValueKindInvalid}, nil
+	}
+	switch v := value.(type) {
+	case lua.LBool:
+		return qvalue.New(qvalue.QValueKindBoolean, bool(v)), nil
+	case lua.LString:
+		return stringToValue(string(v), kind)
+	case lua.LNumber:
+		f := float64(v)
+		integral := f == math.Trunc(f) && math.Abs(f) < 1<<63
+		switch {
+		case kind == qvalue.QValueKindInt16 && integral:
+			return qvalue.NewInt16(kind, int16(f)), nil
+		case kind == qvalue.QValueKindInt32 && integral:
+			return qvalue.NewInt32(kind, int32(f)), nil
+		case kind == qvalue.QValueKindInt64 && integral:
+			return qvalue.NewInt64(kind, int64(f)), nil
+		case kind == qvalue.QValueKindFloat32:
+			return qvalue.NewFloat32(kind, float32(f)), nil
+		case kind == qvalue.QValueKindFloat64:
+			return qvalue.New(kind, f), nil
+		case kind == qvalue.QValueKindNumeric:
+			rat := new(big.Rat).SetFloat64(f)
+			if rat == nil {
+				return qvalue.QValue{}, fmt.Errorf("%v is not a valid numeric", f)
+			}
+			return qvalue.New(kind, rat), nil
+		case integral:
+			return qvalue.NewInt64(qvalue.QValueKindInt64, int64(f)), nil
+		default:
+			return qvalue.New(qvalue.QValueKindFloat64, f), nil
+		}
+	default:
+		return qvalue.QValue{}, fmt.Errorf("unsupported value of type %s", value.Type())
+	}
+}
+
+// stringToValue converts a string the script assigned to the kind of the column. Values of kinds
+// without a Lua counterpart are passed to scripts as strings, timestamps and dates in RFC 3339.
+func stringToValue(value string, kind qvalue.QValueKind) (qvalue.QValue, error) {
+	switch kind {
+	case qvalue.QValueKindTimestamp, qvalue.QValueKindTimestampTZ, qvalue.QValueKindDate:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil && kind == qvalue.QValueKindDate {
+			t, err = time.Parse(time.DateOnly, value)
+		}
+		if err != nil {
+			return qvalue.QValue{}, err
+		}
+		return qvalue.New(kind, t), nil
+	case qvalue.QValueKindUUID:
+		u, err := uuid.Parse(value)
+		if err != nil {
+			return qvalue.QValue{}, err
+		}
+		return qvalue.New(kind, [16]byte(u)), nil
+	case qvalue.QValueKindNumeric:
+		rat, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return qvalue.QValue{}, fmt.Errorf("%s is not a valid numeric", value)
+		}
+		return qvalue.New(kind, rat), nil
+	case qvalue.QValueKindBytes:
+		return qvalue.New(kind, []byte(value)), nil
+	case qvalue.QValueKindJSON:
+		return qvalue.New(kind, value), nil
+	default:
+		return qvalue.New(qvalue.QValueKindString, value), nil
+	}
+}
@@ -0,0 +1,295 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+const testScript = `
+function transform(record)
+	local row = record.row
+	if row.is_test == true then
+		return nil
+	end
+	-- rename
+	row.customer_email, row.email = row.email, nil
+	-- redact
+	if row.ssn ~= null then
+		row.ssn = "***"
+	end
+	-- derive
+	row.total_cents = row.total * 100
+	-- route
+	if row.region == "eu" then
+		record.target = "orders_eu"
+	end
+	return record
+end
+`
+
+func orderItems(region string, isTest bool) *model.RecordItems {
+	return model.NewRecordItemWithData(
+		[]string{"id", "email", "ssn", "total", "region", "is_test", "created_at"},
+		[]qvalue.QValue{
+			qvalue.NewInt64(qvalue.QValueKindInt64, int64(9007199254740993)),
+			qvalue.New(qvalue.QValueKindString, "a@example.com"),
+			qvalue.QValue{Kind: qvalue.QValueKindInvalid},
+			qvalue.New(qvalue.QValueKindFloat64, 12.5),
+			qvalue.New(qvalue.QValueKindString, region),
+			qvalue.New(qvalue.QValueKindBoolean, isTest),
+			qvalue.New(qvalue.QValueKindTimestamp, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		},
+	)
+}
+
+func TestTransform(t *testing.T) {
+	script, err := Load(context.Background(), testScript, []string{"orders", "orders_eu"})
+	require.NoError(t, err)
+	defer script.Close()
+
+	items := orderItems("us", false)
+	rec, err := script.Transform(&model.InsertRecord{
+		SourceTableName: "public.orders", DestinationTableName: "orders", CheckpointID: 7, Items: items,
+	})
+	require.NoError(t, err)
+	insert, ok := rec.(*model.InsertRecord)
+	require.True(t, ok)
+	require.Equal(t, "orders", insert.DestinationTableName)
+	require.Equal(t, int64(7), insert.CheckpointID)
+	// unchanged columns keep their exact values and kinds
+	require.Equal(t, int64(9007199254740993), insert.Items.GetColumnValue("id").Value())
+	require.Equal(t, qvalue.QValueKindTimestamp, insert.Items.GetColumnValue("created_at").Kind)
+	require.Equal(t, "a@example.com", insert.Items.GetColumnValue("customer_email").Value())
+	_, err = insert.Items.GetValueByColName("email")
+	require.Error(t, err)
+	require.Nil(t, insert.Items.GetColumnValue("ssn").Value())
+	require.Equal(t, qvalue.NewInt64(qvalue.QValueKindInt64, int64(1250)), insert.Items.GetColumnValue("total_cents"))
+	// the record passed in isn't modified
+	require.Equal(t, "a@example.com", items.GetColumnValue("email").Value())
+
+	rec, err = script.Transform(&model.UpdateRecord{
+		DestinationTableName: "orders", OldItems: model.NewRecordItems(0), NewItems: orderItems("eu", false),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "orders_eu", rec.GetDestinationTableName())
+
+	rec, err = script.Transform(&model.DeleteRecord{DestinationTableName: "orders", Items: orderItems("us", true)})
+	require.NoError(t, err)
+	require.Nil(t, rec)
+}
+
+func TestTransformErrors(t *testing.T) {
+	_, err := Load(context.Background(), "function other(record) return record end", nil)
+	require.Error(t, err, "scripts must define transform")
+
+	_, err = Load(context.Background(), `dofile("/etc/passwd")`, nil)
+	require.Error(t, err, "scripts can't access files")
+
+	script, err := Load(context.Background(), `function transform(record) record.target = "elsewhere" return record end`,
+		[]string{"orders"})
+	require.NoError(t, err)
+	defer script.Close()
+	_, err = script.Transform(&model.InsertRecord{DestinationTableName: "orders", Items: orderItems("us", false)})
+	require.Error(t, err, "records can only be routed to destination tables of the mirror")
+}
+
+func TestTransformLoopingScript(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := Load(ctx, "while true do end", nil)
+	require.Error(t, err, "loading a looping script stops once ctx is done")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	script, err := Load(ctx, "function transform(record) while true do end end", []string{"orders"})
+	require.NoError(t, err)
+	defer script.Close()
+	start := time.Now()
+	_, err = script.Transform(&model.InsertRecord{DestinationTableName: "orders", Items: orderItems("us", false)})
+	require.Error(t, err, "transforming with a looping script stops once ctx is done")
+	require.Less(t, time.Since(start), callTimeout)
+}
+
+const outputScript = `
+output = {
+	orders = {
+		{name = "id", type = "int64"},
+		{name = "email_domain", type = "string"},
+		{name = "placed_at", type = "timestamp"},
+		{name = "zip", type = "int32"},
+	},
+}
+
+function transform(record)
+	local row = record.row
+	row.email_domain = string.match(row.email, "@(.+)$")
+	row.placed_at = row.created_at
+	-- same value as id, kinds come from the declared output and not from columns with equal values
+	row.zip = row.shard
+	return record
+end
+`
+
+func TestTransformOutput(t *testing.T) {
+	script, err := Load(context.Background(), outputScript, []string{"orders", "orders_eu"})
+	require.NoError(t, err)
+	defer script.Close()
+	require.True(t, script.DeclaresOutput("orders"))
+	require.False(t, script.DeclaresOutput("orders_eu"))
+
+	items := model.NewRecordItemWithData(
+		[]string{"id", "email", "created_at", "shard"},
+		[]qvalue.QValue{
+			qvalue.NewInt64(qvalue.QValueKindInt64, int64(5)),
+			qvalue.New(qvalue.QValueKindString, "a@example.com"),
+			qvalue.New(qvalue.QValueKindTimestamp, time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)),
+			qvalue.NewInt16(qvalue.QValueKindInt16, int16(5)),
+		},
+	)
+	rec, err := script.Transform(&model.InsertRecord{DestinationTableName: "orders", Items: items})
+	require.NoError(t, err)
+	row := rec.(*model.InsertRecord).Items
+	// columns left out of the declared output are dropped
+	require.Equal(t, 4, row.Len())
+	require.Equal(t, qvalue.NewInt64(qvalue.QValueKindInt64, int64(5)), row.GetColumnValue("id"))
+	require.Equal(t, qvalue.New(qvalue.QValueKindString, "example.com"), row.GetColumnValue("email_domain"))
+	require.Equal(t, qvalue.New(qvalue.QValueKindTimestamp, time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)),
+		row.GetColumnValue("placed_at"))
+	require.Equal(t, qvalue.NewInt32(qvalue.QValueKindInt32, int32(5)), row.GetColumnValue("zip"))
+
+	// tables without a declared output keep the kinds of columns of the same name
+	rec, err = script.Transform(&model.InsertRecord{DestinationTableName: "orders_eu", Items: items})
+	require.NoError(t, err)
+	row = rec.(*model.InsertRecord).Items
+	require.Equal(t, qvalue.NewInt16(qvalue.QValueKindInt16, int16(5)), row.GetColumnValue("shard"))
+	require.Equal(t, qvalue.QValueKindString, row.GetColumnValue("placed_at").Kind)
+
+	mismatched, err := Load(context.Background(), `
+output = {orders = {{name = "id", type = "int64"}, {name = "flag", type = "bool"}}}
+function transform(record) record.row.flag = "yes" return record end`, []string{"orders"})
+	require.NoError(t, err)
+	defer mismatched.Close()
+	_, err = mismatched.Transform(&model.InsertRecord{DestinationTableName: "orders", Items: items})
+	require.ErrorContains(t, err, "flag")
+}
+
+func TestLoadOutputErrors(t *testing.T) {
+	for _, source := range []string{
+		`output = {elsewhere = {{name = "id", type = "int64"}}}`,
+		`output = {orders = {{name = "id", type = "interval"}}}`,
+		`output = {orders = {{type = "int64"}}}`,
+		`output = {orders = {{name = "id", type = "int64"}, {name = "id", type = "string"}}}`,
+		`output = {orders = {}}`,
+		`output = "orders"`,
+	} {
+		_, err := Load(context.Background(), source+"\nfunction transform(record) return record end", []string{"orders"})
+		require.Error(t, err, source)
+	}
+
+	// scripts loaded for one table ignore the outputs of the mirror's other tables
+	script, err := LoadTable(context.Background(), `output = {elsewhere = {{name = "id", type = "int64"}}}
+function transform(record) return record end`, "orders")
+	require.NoError(t, err)
+	require.False(t, script.DeclaresOutput("elsewhere"))
+	script.Close()
+	_, err = script.Transform(&model.InsertRecord{DestinationTableName: "orders", Items: model.NewRecordItems(0)})
+	require.Error(t, err, "closed scripts don't transform records")
+}
+
+func TestDestinationSchema(t *testing.T) {
+	script, err := Load(context.Background(), outputScript, []string{"orders", "orders_eu"})
+	require.NoError(t, err)
+	defer script.Close()
+
+	source := &protos.TableSchema{
+		TableIdentifier:   "public.orders",
+		PrimaryKeyColumns: []string{"id"},
+		Columns: []*protos.FieldDescription{
+			{Name: "id", Type: string(qvalue.QValueKindInt64), TypeModifier: -1},
+			{Name: "email", Type: string(qvalue.QValueKindString), TypeModifier: -1},
+		},
+	}
+	unchanged, err := script.DestinationSchema("orders_eu", source)
+	require.NoError(t, err)
+	require.Same(t, source, unchanged)
+
+	schema, err := script.DestinationSchema("orders", source)
+	require.NoError(t, err)
+	require.Equal(t, "public.orders", schema.TableIdentifier)
+	require.Equal(t, []string{"id"}, schema.PrimaryKeyColumns)
+	require.Equal(t, []*protos.FieldDescription{
+		{Name: "id", Type: "int64", TypeModifier: -1},
+		{Name: "email_domain", Type: "string", TypeModifier: -1},
+		{Name: "placed_at", Type: "timestamp", TypeModifier: -1},
+		{Name: "zip", Type: "int32", TypeModifier: -1},
+	}, schema.Columns)
+
+	_, err = script.DestinationSchema("orders", &protos.TableSchema{PrimaryKeyColumns: []string{"order_id"}})
+	require.ErrorContains(t, err, "order_id")
+
+	synthetic, err := script.DestinationSchema("orders", &protos.TableSchema{
+		PrimaryKeyColumns: []string{"id", "email"}, SyntheticPrimaryKey: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "email_domain", "placed_at", "zip"}, synthetic.PrimaryKeyColumns)
+}
+
+func TestTransformStream(t *testing.T) {
+	script, err := LoadTable(context.Background(), `
+output = {orders = {{name = "id", type = "int64"}, {name = "email_domain", type = "string"}}}
+function transform(record)
+	if record.kind ~= "insert" or record.source ~= "public.orders" then
+		error("unexpected record")
+	end
+	if record.row.id == 2 then
+		return nil
+	end
+	if record.row.id == 4 then
+		record.target = "orders_eu"
+	end
+	record.row.email_domain = string.match(record.row.email, "@(.+)$")
+	return record
+end`, "orders")
+	require.NoError(t, err)
+	defer script.Close()
+
+	stream := model.NewQRecordStream(4)
+	require.NoError(t, stream.SetSchema(model.NewQRecordSchema([]model.QField{
+		{Name: "id", Type: qvalue.QValueKindInt64},
+		{Name: "email", Type: qvalue.QValueKindString},
+	})))
+	go func() {
+		for i := range 5 {
+			stream.Records <- model.QRecordOrError{Record: []qvalue.QValue{
+				qvalue.NewInt64(qvalue.QValueKindInt64, int64(i)),
+				qvalue.New(qvalue.QValueKindString, "user@example.com"),
+			}}
+		}
+		close(stream.Records)
+	}()
+
+	transformed := script.TransformStream(context.Background(), stream, 4, "public.orders", "orders")
+	schema, err := transformed.Schema()
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "email_domain"}, schema.GetColumnNames())
+
+	var ids []int64
+	var routeErr error
+	for record := range transformed.Records {
+		if record.Err != nil {
+			routeErr = record.Err
+			continue
+		}
+		ids = append(ids, record.Record[0].Value().(int64))
+		require.Equal(t, qvalue.New(qvalue.QValueKindString, "example.com"), record.Record[1])
+	}
+	require.Equal(t, []int64{0, 1, 3}, ids)
+	require.ErrorContains(t, routeErr, "orders_eu", "copied rows can't be routed to other tables")
+}
@@ -1,10 +1,12 @@
 package peerflow
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,6 +17,9 @@ import (
 	"go.temporal.io/sdk/workflow"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/PeerDB-io/peer-flow/activities"
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/peerdbenv"
@@ -46,6 +51,36 @@ type CDCFlowWorkflowState struct {
 	FlowConfigUpdates []*protos.CDCFlowConfigUpdate
 	// options passed to all SyncFlows
 	SyncFlowOptions *protos.SyncFlowOptions
+	// set while a backlog is worked through in smaller, paced sync flows
+	CatchingUp bool
+	// replication lag reported by the last sync flow, negative if unknown
+	SourceLagMB float32
+	// idle timeout of the next sync flow under the adaptive sync interval, 0 until it's seeded from the mirror's idle timeout
+	SyncInterval time.Duration
+	// batches the previous normalize flow still held back due to the apply delay
+	DelayedNormalizeBatches []DelayedSyncBatch
+	// state of the previous normalize flow when it left batches pending under the mirror's normalize schedule
+	PendingNormalizeState *NormalizeState
+	// state of the previous normalize flows of fan-out destinations which left batches pending or delayed,
+	// by the flow job name of the destination
+	FanoutNormalizeStates map[string]*NormalizeState
+	// set while the destination is read-only or in maintenance, nil once a sync succeeds again
+	DestinationMaintenance *DestinationMaintenanceState
+	// schema changes the mirror paused on, the schemas of their tables are refreshed once it is resumed
+	PausingSchemaDeltas []*protos.TableSchemaDelta
+	// snapshot settings for additional tables changed while the mirror was running, 0 if unchanged
+	SnapshotMaxParallelWorkers  uint32
+	SnapshotNumRowsPerPartition uint32
+	// when destination tables were last checked for drift under the mirror's destination drift policy
+	LastDestinationDriftCheck time.Time
+}
+
+// DestinationMaintenanceState tracks syncs waiting for the destination to become writable again.
+type DestinationMaintenanceState struct {
+	Since     time.Time
+	NextRetry time.Time
+	LastError string
+	Retries   int
 }
 
 // returns a new empty PeerFlowState
@@ -64,8 +99,10 @@ func NewCDCFlowWorkflowState(cfg *protos.FlowConnectionConfigs) *CDCFlowWorkflow
 		NormalizeFlowErrors:   nil,
 		CurrentFlowStatus:     protos.FlowStatus_STATUS_SETUP,
 		FlowConfigUpdates:     nil,
+		SourceLagMB:           -1,
 		SyncFlowOptions: &protos.SyncFlowOptions{
 			BatchSize:          cfg.MaxBatchSize,
+			BatchBytes:         cfg.MaxBatchBytes,
 			IdleTimeoutSeconds: cfg.IdleTimeoutSeconds,
 			TableMappings:      tableMappings,
 		},
@@ -112,6 +149,27 @@ func NewCDCFlowWorkflowExecution(ctx workflow.Context, flowName string) *CDCFlow
 	}
 }
 
+// readSettings reads dynamic settings of a mirror from the catalog in a local activity, bounding how long it may take.
+// flowName may be empty for settings read outside of mirrors.
+// Settings fall back to their zero value, which is their default, when they can't be read.
+func readSettings[T any](ctx workflow.Context, flowName string, read func(context.Context) T) T {
+	settingsCtx := workflow.WithLocalActivityOptions(ctx, workflow.LocalActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+	var result T
+	if err := workflow.ExecuteLocalActivity(settingsCtx, func(ctx context.Context) (T, error) {
+		return read(context.WithValue(ctx, shared.FlowNameKey, flowName)), nil
+	}).Get(settingsCtx, &result); err != nil {
+		workflow.GetLogger(ctx).Warn("failed to read settings, using their defaults", slog.Any("error", err))
+		var zero T
+		return zero
+	}
+	return result
+}
+
 func GetSideEffect[T any](ctx workflow.Context, f func(workflow.Context) T) T {
 	sideEffect := workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
 		return f(ctx)
@@ -172,6 +230,14 @@ func (w *CDCFlowWorkflowExecution) processCDCFlowConfigUpdates(ctx workflow.Cont
 		additionalTablesCfg := proto.Clone(cfg).(*protos.FlowConnectionConfigs)
 		additionalTablesCfg.DoInitialSnapshot = true
 		additionalTablesCfg.InitialSnapshotOnly = true
+		additionalTablesCfg.AdoptExistingTables = false
+		additionalTablesCfg.VerifyAdoptedTables = false
+		if state.SnapshotMaxParallelWorkers > 0 {
+			additionalTablesCfg.SnapshotMaxParallelWorkers = state.SnapshotMaxParallelWorkers
+		}
+		if state.SnapshotNumRowsPerPartition > 0 {
+			additionalTablesCfg.SnapshotNumRowsPerPartition = state.SnapshotNumRowsPerPartition
+		}
 		additionalTablesCfg.TableMappings = flowConfigUpdate.AdditionalTables
 
 		// execute the sync flow as a child workflow
@@ -307,12 +373,36 @@ func CDCFlowWorkflow(
 			WaitForCancellation: true,
 		}
 		snapshotFlowCtx := workflow.WithChildOptions(ctx, childSnapshotFlowOpts)
+		// the initial load waits its turn under the global scheduler's limit on concurrent initial loads
+		releaseInitialLoad := acquireLease(ctx, w.logger, model.LeaseInitialLoad, cfg.Source.Name)
 		snapshotFlowFuture := workflow.ExecuteChildWorkflow(snapshotFlowCtx, SnapshotFlowWorkflow, cfg)
-		if err := snapshotFlowFuture.Get(snapshotFlowCtx, nil); err != nil {
+		err = snapshotFlowFuture.Get(snapshotFlowCtx, nil)
+		releaseInitialLoad()
+		if err != nil {
 			w.logger.Error("snapshot flow failed", slog.Any("error", err))
 			return state, fmt.Errorf("failed to execute snapshot workflow: %w", err)
 		}
 
+		// changes since the replication slot was created are replayed over adopted tables
+		if cfg.AdoptExistingTables {
+			adoptTablesCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+				StartToCloseTimeout: 24 * time.Hour,
+				HeartbeatTimeout:    time.Minute,
+				RetryPolicy: &temporal.RetryPolicy{
+					MaximumAttempts: 3,
+				},
+			})
+			adoptTablesFuture := workflow.ExecuteActivity(adoptTablesCtx, flowable.AdoptTables,
+				cfg, state.SyncFlowOptions.TableNameSchemaMapping)
+			var adoptTablesOutput *protos.AdoptTablesOutput
+			if err := adoptTablesFuture.Get(adoptTablesCtx, &adoptTablesOutput); err != nil {
+				w.logger.Error("failed to adopt existing tables", slog.Any("error", err))
+				return state, fmt.Errorf("failed to adopt existing tables: %w", err)
+			}
+			state.Progress = append(state.Progress,
+				"adopted existing tables, replicating from LSN "+adoptTablesOutput.StartLsn)
+		}
+
 		if cfg.Resync {
 			renameOpts := &protos.RenameTablesInput{}
 			renameOpts.FlowJobName = cfg.FlowJobName
@@ -356,6 +446,18 @@ func CDCFlowWorkflow(
 		}
 	}
 
+	// raw tables of mirrors created by older workers may need upgrading before syncing resumes
+	upgradeRawTableCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Minute,
+	})
+	fanoutConfigs := model.FanoutConfigs(cfg)
+	for _, rawTableCfg := range append([]*protos.FlowConnectionConfigs{cfg}, fanoutConfigs...) {
+		upgradeRawTableFuture := workflow.ExecuteActivity(upgradeRawTableCtx, flowable.UpgradeRawTable, rawTableCfg)
+		if err := upgradeRawTableFuture.Get(upgradeRawTableCtx, nil); err != nil {
+			return state, fmt.Errorf("failed to upgrade raw table: %w", err)
+		}
+	}
+
 	sessionOptions := &workflow.SessionOptions{
 		CreationTimeout:  5 * time.Minute,
 		ExecutionTimeout: 144 * time.Hour,
@@ -400,11 +502,57 @@ func CDCFlowWorkflow(
 		WaitForCancellation: true,
 	}
 	normCtx := workflow.WithChildOptions(ctx, normalizeFlowOpts)
-	normalizeFlowFuture := workflow.ExecuteChildWorkflow(normCtx, NormalizeFlowWorkflow, cfg, nil)
+	var normalizeState *NormalizeState
+	if state.PendingNormalizeState != nil {
+		normalizeState = state.PendingNormalizeState
+		normalizeState.Wait = true
+		normalizeState.Stop = false
+		normalizeState.TableNameSchemaMapping = state.SyncFlowOptions.TableNameSchemaMapping
+		state.PendingNormalizeState = nil
+	} else if len(state.DelayedNormalizeBatches) != 0 {
+		normalizeState = NewNormalizeState()
+		normalizeState.SyncBatchID = state.DelayedNormalizeBatches[len(state.DelayedNormalizeBatches)-1].SyncBatchID
+		normalizeState.DelayedBatches = state.DelayedNormalizeBatches
+		normalizeState.TableNameSchemaMapping = state.SyncFlowOptions.TableNameSchemaMapping
+		state.DelayedNormalizeBatches = nil
+	}
+	normalizeFlowFuture := workflow.ExecuteChildWorkflow(normCtx, NormalizeFlowWorkflow, cfg, normalizeState)
+
+	// fan-out destinations are normalized by normalize flows of their own, each with its own state
+	fanoutNormalizeFutures := make([]workflow.ChildWorkflowFuture, 0, len(fanoutConfigs))
+	for _, fanoutCfg := range fanoutConfigs {
+		fanoutNormalizeOpts := normalizeFlowOpts
+		fanoutNormalizeOpts.WorkflowID = GetChildWorkflowID("normalize-flow", fanoutCfg.FlowJobName, originalRunID)
+		fanoutNormalizeState := state.FanoutNormalizeStates[fanoutCfg.FlowJobName]
+		if fanoutNormalizeState != nil {
+			fanoutNormalizeState.Wait = true
+			fanoutNormalizeState.Stop = false
+			fanoutNormalizeState.TableNameSchemaMapping = state.SyncFlowOptions.TableNameSchemaMapping
+		}
+		fanoutNormalizeFutures = append(fanoutNormalizeFutures, workflow.ExecuteChildWorkflow(
+			workflow.WithChildOptions(ctx, fanoutNormalizeOpts), NormalizeFlowWorkflow, fanoutCfg, fanoutNormalizeState))
+	}
+	state.FanoutNormalizeStates = nil
 
 	var waitSelector workflow.Selector
-	parallel := GetSideEffect(ctx, func(_ workflow.Context) bool {
-		return peerdbenv.PeerDBEnableParallelSyncNormalize()
+	parallel := readSettings(ctx, cfg.FlowJobName, dynamicconf.PeerDBEnableParallelSyncNormalize)
+	catchUpLagThresholdMB := readSettings(ctx, cfg.FlowJobName, dynamicconf.PeerDBCDCCatchUpLagThresholdMB)
+	catchUp := GetSideEffect(ctx, func(_ workflow.Context) catchUpSettings {
+		return catchUpSettings{
+			LagThresholdMB: catchUpLagThresholdMB,
+			BatchSize:      peerdbenv.PeerDBCDCCatchUpBatchSize(),
+			Pacing:         peerdbenv.PeerDBCDCCatchUpPacing(),
+		}
+	})
+	adaptiveSync := GetSideEffect(ctx, func(_ workflow.Context) adaptiveSyncSettings {
+		return adaptiveSyncSettings{
+			Enabled:     peerdbenv.PeerDBCDCAdaptiveSyncInterval(),
+			MinInterval: peerdbenv.PeerDBCDCAdaptiveSyncMinInterval(),
+			MaxInterval: peerdbenv.PeerDBCDCAdaptiveSyncMaxInterval(),
+		}
+	})
+	driftCheckInterval := GetSideEffect(ctx, func(_ workflow.Context) time.Duration {
+		return peerdbenv.PeerDBDestinationDriftCheckInterval()
 	})
 	if !parallel {
 		waitSelector = workflow.NewNamedSelector(ctx, "NormalizeWait")
@@ -420,13 +568,42 @@ func CDCFlowWorkflow(
 			Done:        true,
 			SyncBatchID: -1,
 		})
-		if err := normalizeFlowFuture.Get(ctx, nil); err != nil {
+		for _, fanoutNormalizeFuture := range fanoutNormalizeFutures {
+			model.NormalizeSignal.SignalChildWorkflow(ctx, fanoutNormalizeFuture, model.NormalizePayload{
+				Done:        true,
+				SyncBatchID: -1,
+			})
+		}
+		var finalNormalizeState *NormalizeState
+		if err := normalizeFlowFuture.Get(ctx, &finalNormalizeState); err != nil {
 			w.logger.Error("failed to execute normalize flow", slog.Any("error", err))
 			var panicErr *temporal.PanicError
 			if errors.As(err, &panicErr) {
 				w.logger.Error("PANIC", panicErr.Error(), panicErr.StackTrace())
 			}
 			state.NormalizeFlowErrors = append(state.NormalizeFlowErrors, err.Error())
+		} else if finalNormalizeState != nil && finalNormalizeState.PendingBatches != 0 {
+			// the schema mapping is passed on from the parent's state instead
+			finalNormalizeState.TableNameSchemaMapping = nil
+			state.PendingNormalizeState = finalNormalizeState
+		} else if finalNormalizeState != nil {
+			state.DelayedNormalizeBatches = finalNormalizeState.DelayedBatches
+		}
+
+		for i, fanoutNormalizeFuture := range fanoutNormalizeFutures {
+			var finalFanoutState *NormalizeState
+			if err := fanoutNormalizeFuture.Get(ctx, &finalFanoutState); err != nil {
+				w.logger.Error("failed to execute fan-out normalize flow",
+					slog.String("destination", fanoutConfigs[i].Destination.Name), slog.Any("error", err))
+				state.NormalizeFlowErrors = append(state.NormalizeFlowErrors, err.Error())
+			} else if finalFanoutState != nil &&
+				(finalFanoutState.PendingBatches != 0 || len(finalFanoutState.DelayedBatches) != 0) {
+				finalFanoutState.TableNameSchemaMapping = nil
+				if state.FanoutNormalizeStates == nil {
+					state.FanoutNormalizeStates = make(map[string]*NormalizeState, len(fanoutConfigs))
+				}
+				state.FanoutNormalizeStates[fanoutConfigs[i].FlowJobName] = finalFanoutState
+			}
 		}
 	}
 
@@ -448,6 +625,20 @@ func CDCFlowWorkflow(
 		state.NormalizeFlowErrors = append(state.NormalizeFlowErrors, err)
 	})
 
+	normTriggerChan := model.NormalizeTriggerSignal.GetSignalChannel(ctx)
+	normTriggerChan.AddToSelector(mainLoopSelector, func(_ struct{}, _ bool) {
+		w.logger.Info("normalize triggered")
+		for _, future := range append([]workflow.ChildWorkflowFuture{normalizeFlowFuture}, fanoutNormalizeFutures...) {
+			err := model.NormalizeSignal.SignalChildWorkflow(ctx, future, model.NormalizePayload{
+				SyncBatchID: -1,
+				Trigger:     true,
+			}).Get(ctx, nil)
+			if err != nil {
+				w.logger.Error("failed to trigger normalize", slog.Any("error", err))
+			}
+		}
+	})
+
 	normResultChan := model.NormalizeResultSignal.GetSignalChannel(ctx)
 	normResultChan.AddToSelector(mainLoopSelector, func(result model.NormalizeResponse, _ bool) {
 		state.NormalizeFlowStatuses = append(state.NormalizeFlowStatuses, result)
@@ -460,8 +651,19 @@ func CDCFlowWorkflow(
 		if cdcConfigUpdate.BatchSize > 0 {
 			state.SyncFlowOptions.BatchSize = cdcConfigUpdate.BatchSize
 		}
+		if cdcConfigUpdate.BatchBytes > 0 {
+			state.SyncFlowOptions.BatchBytes = cdcConfigUpdate.BatchBytes
+		}
 		if cdcConfigUpdate.IdleTimeout > 0 {
 			state.SyncFlowOptions.IdleTimeoutSeconds = cdcConfigUpdate.IdleTimeout
+			// the adaptive sync interval starts over from the new idle timeout
+			state.SyncInterval = 0
+		}
+		if cdcConfigUpdate.SnapshotMaxParallelWorkers > 0 {
+			state.SnapshotMaxParallelWorkers = cdcConfigUpdate.SnapshotMaxParallelWorkers
+		}
+		if cdcConfigUpdate.SnapshotNumRowsPerPartition > 0 {
+			state.SnapshotNumRowsPerPartition = cdcConfigUpdate.SnapshotNumRowsPerPartition
 		}
 		if len(cdcConfigUpdate.AdditionalTables) > 0 {
 			state.FlowConfigUpdates = append(state.FlowConfigUpdates, cdcConfigUpdate)
@@ -469,7 +671,10 @@ func CDCFlowWorkflow(
 
 		w.logger.Info("CDC Signal received. Parameters on signal reception:",
 			slog.Int("BatchSize", int(state.SyncFlowOptions.BatchSize)),
+			slog.Uint64("BatchBytes", state.SyncFlowOptions.BatchBytes),
 			slog.Int("IdleTimeout", int(state.SyncFlowOptions.IdleTimeoutSeconds)),
+			slog.Int("SnapshotMaxParallelWorkers", int(state.SnapshotMaxParallelWorkers)),
+			slog.Int("SnapshotNumRowsPerPartition", int(state.SnapshotNumRowsPerPartition)),
 			slog.Any("AdditionalTables", cdcConfigUpdate.AdditionalTables))
 	})
 
@@ -498,10 +703,22 @@ func CDCFlowWorkflow(
 			}
 
 			w.logger.Info("mirror has been resumed after ", time.Since(startTime))
+			// the schema changes paused on have been made on the destination by now
+			if len(state.PausingSchemaDeltas) != 0 {
+				w.refreshTableSchemas(ctx, cfg, state, state.PausingSchemaDeltas)
+				state.PausingSchemaDeltas = nil
+			}
 		}
 
 		state.CurrentFlowStatus = protos.FlowStatus_STATUS_RUNNING
 
+		if cfg.DestinationDriftPolicy != protos.DestinationDriftPolicy_DESTINATION_DRIFT_IGNORE &&
+			workflow.Now(ctx).Sub(state.LastDestinationDriftCheck) >= driftCheckInterval {
+			if w.checkDestinationDrift(ctx, cfg, state) {
+				continue
+			}
+		}
+
 		// check if total sync flows have been completed
 		// since this happens immediately after we check for signals, the case of a signal being missed
 		// due to a new workflow starting is vanishingly low, but possible
@@ -520,58 +737,74 @@ func CDCFlowWorkflow(
 			WaitForCancellation: true,
 		})
 
+		syncFlowOptions := state.SyncFlowOptions
+		if state.CatchingUp || adaptiveSync.Enabled {
+			syncFlowOptions = proto.Clone(state.SyncFlowOptions).(*protos.SyncFlowOptions)
+		}
+		if state.CatchingUp {
+			if syncFlowOptions.BatchSize == 0 || syncFlowOptions.BatchSize > catchUp.BatchSize {
+				syncFlowOptions.BatchSize = catchUp.BatchSize
+			}
+		}
+		if adaptiveSync.Enabled {
+			if state.SyncInterval == 0 {
+				state.SyncInterval = adaptiveSync.clamp(time.Duration(state.SyncFlowOptions.IdleTimeoutSeconds) * time.Second)
+			}
+			syncFlowOptions.IdleTimeoutSeconds = uint64((state.SyncInterval + time.Second - 1) / time.Second)
+		}
+
 		w.logger.Info("executing sync flow")
-		syncFlowFuture := workflow.ExecuteActivity(syncFlowCtx, flowable.SyncFlow, cfg, state.SyncFlowOptions, sessionInfo.SessionID)
+		syncFlowFuture := workflow.ExecuteActivity(syncFlowCtx, flowable.SyncFlow, cfg, syncFlowOptions, sessionInfo.SessionID)
 
-		var syncDone, syncErr bool
+		var syncDone, syncErr, inMaintenance, fanoutFailed bool
 		mustWait := waitSelector != nil
+		// normalize flows owing a NormalizeDoneSignal for this sync
+		normalizeWaits := 0
 		mainLoopSelector.AddFuture(syncFlowFuture, func(f workflow.Future) {
 			syncDone = true
 
 			var childSyncFlowRes *model.SyncResponse
-			if err := f.Get(ctx, &childSyncFlowRes); err != nil {
+			if err := f.Get(ctx, &childSyncFlowRes); utils.IsDestinationMaintenanceFailure(err) {
+				w.enterDestinationMaintenance(ctx, state, err)
+				inMaintenance = true
+				mustWait = false
+			} else if err != nil {
 				w.logger.Error("failed to execute sync flow", slog.Any("error", err))
 				state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
 				syncErr = true
 				mustWait = false
 			} else if childSyncFlowRes != nil {
+				w.exitDestinationMaintenance(state)
 				state.SyncFlowStatuses = append(state.SyncFlowStatuses, childSyncFlowRes)
 				state.SyncFlowOptions.RelationMessageMapping = childSyncFlowRes.RelationMessageMapping
 				totalRecordsSynced += childSyncFlowRes.NumRecordsSynced
 				w.logger.Info("Total records synced: ",
 					slog.Int64("totalRecordsSynced", totalRecordsSynced))
-
-				tableSchemaDeltasCount := len(childSyncFlowRes.TableSchemaDeltas)
+				w.updateCatchUp(state, catchUp, childSyncFlowRes.SourceLagMB)
+				if len(childSyncFlowRes.FailedFanoutDestinations) != 0 {
+					w.logger.Warn("fan-out destinations failed to sync",
+						slog.Any("destinations", childSyncFlowRes.FailedFanoutDestinations))
+					fanoutFailed = true
+				}
+				if adaptiveSync.Enabled {
+					w.updateSyncInterval(state, adaptiveSync, childSyncFlowRes.NumRecordsSynced, syncFlowOptions.BatchSize)
+				}
 
 				// slightly hacky: table schema mapping is cached, so we need to manually update it if schema changes.
-				if tableSchemaDeltasCount != 0 {
-					modifiedSrcTables := make([]string, 0, tableSchemaDeltasCount)
-					modifiedDstTables := make([]string, 0, tableSchemaDeltasCount)
-					for _, tableSchemaDelta := range childSyncFlowRes.TableSchemaDeltas {
-						modifiedSrcTables = append(modifiedSrcTables, tableSchemaDelta.SrcTableName)
-						modifiedDstTables = append(modifiedDstTables, tableSchemaDelta.DstTableName)
-					}
-
-					getModifiedSchemaCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
-						StartToCloseTimeout: 5 * time.Minute,
-					})
-					getModifiedSchemaFuture := workflow.ExecuteActivity(getModifiedSchemaCtx, flowable.GetTableSchema,
-						&protos.GetTableSchemaBatchInput{
-							PeerConnectionConfig: cfg.Source,
-							TableIdentifiers:     modifiedSrcTables,
-							FlowName:             cfg.FlowJobName,
-						})
-
-					var getModifiedSchemaRes *protos.GetTableSchemaBatchOutput
-					if err := getModifiedSchemaFuture.Get(ctx, &getModifiedSchemaRes); err != nil {
-						w.logger.Error("failed to execute schema update at source: ", err)
-						state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
-					} else {
-						for i, srcTable := range modifiedSrcTables {
-							dstTable := modifiedDstTables[i]
-							state.SyncFlowOptions.TableNameSchemaMapping[dstTable] = getModifiedSchemaRes.TableNameSchemaMapping[srcTable]
-						}
-					}
+				if len(childSyncFlowRes.TableSchemaDeltas) != 0 {
+					w.refreshTableSchemas(ctx, cfg, state, childSyncFlowRes.TableSchemaDeltas)
+				}
+				if len(childSyncFlowRes.PausingSchemaDeltas) != 0 {
+					w.logger.Warn("pausing mirror on schema changes", slog.Int("tables", len(childSyncFlowRes.PausingSchemaDeltas)))
+					state.PausingSchemaDeltas = append(state.PausingSchemaDeltas, childSyncFlowRes.PausingSchemaDeltas...)
+					state.ActiveSignal = model.PauseSignal
+				}
+				// ApproveSchemaChange resumes the mirror once they're approved
+				if len(childSyncFlowRes.IncompatibleSchemaDeltas) != 0 {
+					w.logger.Warn("pausing mirror on incompatible schema changes",
+						slog.Int("tables", len(childSyncFlowRes.IncompatibleSchemaDeltas)))
+					state.PausingSchemaDeltas = append(state.PausingSchemaDeltas, childSyncFlowRes.IncompatibleSchemaDeltas...)
+					state.ActiveSignal = model.PauseSignal
 				}
 
 				err := model.NormalizeSignal.SignalChildWorkflow(ctx, normalizeFlowFuture, model.NormalizePayload{
@@ -581,9 +814,31 @@ func CDCFlowWorkflow(
 				}).Get(ctx, nil)
 				if err != nil {
 					w.logger.Error("failed to trigger normalize, so skip wait", slog.Any("error", err))
+				} else {
+					normalizeWaits += 1
+				}
+				for i, fanoutNormalizeFuture := range fanoutNormalizeFutures {
+					syncBatchID, ok := childSyncFlowRes.FanoutSyncBatchIDs[fanoutConfigs[i].FlowJobName]
+					if !ok {
+						syncBatchID = -1
+					}
+					err := model.NormalizeSignal.SignalChildWorkflow(ctx, fanoutNormalizeFuture, model.NormalizePayload{
+						Done:                   false,
+						SyncBatchID:            syncBatchID,
+						TableNameSchemaMapping: state.SyncFlowOptions.TableNameSchemaMapping,
+					}).Get(ctx, nil)
+					if err != nil {
+						w.logger.Error("failed to trigger fan-out normalize, so skip its wait",
+							slog.String("destination", fanoutConfigs[i].Destination.Name), slog.Any("error", err))
+					} else {
+						normalizeWaits += 1
+					}
+				}
+				if normalizeWaits == 0 {
 					mustWait = false
 				}
 			} else {
+				w.exitDestinationMaintenance(state)
 				mustWait = false
 			}
 		})
@@ -594,13 +849,49 @@ func CDCFlowWorkflow(
 		if canceled {
 			break
 		}
+		if inMaintenance {
+			// waiting here rather than in activity retries keeps the mirror running instead of failing,
+			// continuing as new afterwards restarts the pull from the last offset the destination has
+			retried := false
+			mainLoopSelector.AddFuture(workflow.NewTimer(ctx, state.DestinationMaintenance.NextRetry.Sub(workflow.Now(ctx))),
+				func(_ workflow.Future) {
+					retried = true
+				})
+			for !retried && !canceled {
+				mainLoopSelector.Select(ctx)
+			}
+			if canceled {
+				break
+			}
+			state.TruncateProgress(w.logger)
+			return state, workflow.NewContinueAsNewError(onDefaultBuild(ctx), CDCFlowWorkflow, cfg, state)
+		}
 		if syncErr {
 			state.TruncateProgress(w.logger)
-			return state, workflow.NewContinueAsNewError(ctx, CDCFlowWorkflow, cfg, state)
+			return state, workflow.NewContinueAsNewError(onDefaultBuild(ctx), CDCFlowWorkflow, cfg, state)
 		}
-		if mustWait {
+		for i := 0; mustWait && i < normalizeWaits && !canceled; i++ {
 			waitSelector.Select(ctx)
 		}
+		if fanoutFailed && !canceled {
+			// the pull carries on from the end of the batch, restarting it reads the slot again from
+			// the fan-out destinations which fell behind, the others skip what they already synced
+			state.TruncateProgress(w.logger)
+			return state, workflow.NewContinueAsNewError(onDefaultBuild(ctx), CDCFlowWorkflow, cfg, state)
+		}
+
+		if state.CatchingUp && catchUp.Pacing > 0 {
+			paced := false
+			mainLoopSelector.AddFuture(workflow.NewTimer(ctx, catchUp.Pacing), func(_ workflow.Future) {
+				paced = true
+			})
+			for !paced && !canceled {
+				mainLoopSelector.Select(ctx)
+			}
+			if canceled {
+				break
+			}
+		}
 	}
 
 	finishNormalize()
@@ -609,5 +900,240 @@ func CDCFlowWorkflow(
 		return nil, err
 	}
 
-	return state, workflow.NewContinueAsNewError(ctx, CDCFlowWorkflow, cfg, state)
+	return state, workflow.NewContinueAsNewError(onDefaultBuild(ctx), CDCFlowWorkflow, cfg, state)
+}
+
+// checkDestinationDrift checks the destination tables for changes made outside of the mirror,
+// returning whether the mirror pauses on them. Failing to check them doesn't stop the mirror, it's retried next interval.
+func (w *CDCFlowWorkflowExecution) checkDestinationDrift(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	state *CDCFlowWorkflowState,
+) bool {
+	state.LastDestinationDriftCheck = workflow.Now(ctx)
+	driftCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Minute,
+		HeartbeatTimeout:    time.Minute,
+	})
+	var res *protos.CheckDestinationDriftOutput
+	if err := workflow.ExecuteActivity(driftCtx, flowable.CheckDestinationDrift, &protos.CheckDestinationDriftInput{
+		FlowConnectionConfigs:  cfg,
+		TableNameSchemaMapping: state.SyncFlowOptions.TableNameSchemaMapping,
+	}).Get(ctx, &res); err != nil {
+		w.logger.Error("failed to check destination tables for drift", slog.Any("error", err))
+		state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
+		return false
+	}
+
+	if !pausesOnDestinationDrift(cfg.DestinationDriftPolicy, res.DriftedTables) {
+		return false
+	}
+	w.logger.Warn("pausing mirror on destination drift", slog.Any("tables", res.DriftedTables))
+	state.Progress = append(state.Progress, "paused on destination drift")
+	state.ActiveSignal = model.PauseSignal
+	return true
+}
+
+// pausesOnDestinationDrift returns whether the mirror pauses on the tables a drift check left drifted,
+// which are only alerted on under policies other than DESTINATION_DRIFT_PAUSE.
+func pausesOnDestinationDrift(policy protos.DestinationDriftPolicy, drifted []string) bool {
+	return len(drifted) != 0 && policy == protos.DestinationDriftPolicy_DESTINATION_DRIFT_PAUSE
+}
+
+// enterDestinationMaintenance records a sync failing on a read-only destination and schedules its retry.
+func (w *CDCFlowWorkflowExecution) enterDestinationMaintenance(ctx workflow.Context, state *CDCFlowWorkflowState, err error) {
+	now := workflow.Now(ctx)
+	if state.DestinationMaintenance == nil {
+		state.DestinationMaintenance = &DestinationMaintenanceState{Since: now}
+		state.Progress = append(state.Progress, "destination in maintenance")
+	} else {
+		state.DestinationMaintenance.Retries += 1
+	}
+	backoff := utils.MaintenanceBackoff(state.DestinationMaintenance.Retries)
+	state.DestinationMaintenance.NextRetry = now.Add(backoff)
+	state.DestinationMaintenance.LastError = err.Error()
+	w.logger.Warn("destination is read-only or in maintenance, backing off",
+		slog.Int("retries", state.DestinationMaintenance.Retries), slog.Duration("backoff", backoff), slog.Any("error", err))
+}
+
+func (w *CDCFlowWorkflowExecution) exitDestinationMaintenance(state *CDCFlowWorkflowState) {
+	if state.DestinationMaintenance == nil {
+		return
+	}
+	w.logger.Info("destination writable again",
+		slog.Int("retries", state.DestinationMaintenance.Retries))
+	state.Progress = append(state.Progress, "destination out of maintenance")
+	state.DestinationMaintenance = nil
+}
+
+type catchUpSettings struct {
+	LagThresholdMB uint32
+	BatchSize      uint32
+	Pacing         time.Duration
+}
+
+// updateCatchUp switches catch-up mode on when the source lag crosses the threshold, and back off once it drops below it.
+func (w *CDCFlowWorkflowExecution) updateCatchUp(state *CDCFlowWorkflowState, settings catchUpSettings, lagMB float32) {
+	if settings.LagThresholdMB == 0 {
+		state.CatchingUp = false
+		return
+	}
+	if lagMB < 0 {
+		return
+	}
+	state.SourceLagMB = lagMB
+
+	threshold := float32(settings.LagThresholdMB)
+	switch {
+	case !state.CatchingUp && lagMB >= threshold:
+		state.CatchingUp = true
+		w.logger.Info("replication lag above threshold, catching up", slog.Float64("lagMB", float64(lagMB)))
+		state.Progress = append(state.Progress, fmt.Sprintf("catching up, %.1f MB behind", lagMB))
+	case state.CatchingUp && lagMB < threshold:
+		state.CatchingUp = false
+		w.logger.Info("caught up, back to normal cadence", slog.Float64("lagMB", float64(lagMB)))
+		state.Progress = append(state.Progress, "caught up")
+	case state.CatchingUp:
+		w.logger.Info("still catching up", slog.Float64("lagMB", float64(lagMB)))
+		state.Progress = append(state.Progress, fmt.Sprintf("catching up, %.1f MB behind", lagMB))
+	}
+}
+
+type adaptiveSyncSettings struct {
+	Enabled     bool
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+func (s adaptiveSyncSettings) clamp(interval time.Duration) time.Duration {
+	// idle timeouts are in whole seconds
+	return max(min(interval, s.MaxInterval), s.MinInterval, time.Second)
+}
+
+// adaptiveSyncLowFill is the share of its batch size below which a batch counts as mostly spent waiting for the source.
+const adaptiveSyncLowFill = 0.1
+
+// updateSyncInterval halves the idle timeout of sync flows when a batch fills up to its batch size, and doubles it
+// when a batch is empty or holds less than adaptiveSyncLowFill of its batch size, so busy mirrors sync sooner and
+// idle ones sync less often. Time spent syncing to the destination doesn't count, only how much the source had.
+func (w *CDCFlowWorkflowExecution) updateSyncInterval(
+	state *CDCFlowWorkflowState,
+	settings adaptiveSyncSettings,
+	numRecords int64,
+	batchSize uint32,
+) {
+	if batchSize == 0 {
+		batchSize = activities.DefaultSyncBatchSize
+	}
+	fill := float64(numRecords) / float64(batchSize)
+
+	interval := state.SyncInterval
+	switch {
+	case fill >= 1:
+		interval = settings.clamp(interval / 2)
+	case fill < adaptiveSyncLowFill:
+		interval = settings.clamp(2 * interval)
+	default:
+		interval = settings.clamp(interval)
+	}
+	if interval != state.SyncInterval {
+		w.logger.Info("adapting sync interval to source activity",
+			slog.Int64("records", numRecords), slog.Uint64("batchSize", uint64(batchSize)),
+			slog.Duration("from", state.SyncInterval), slog.Duration("to", interval))
+		state.SyncInterval = interval
+	}
+}
+
+// refreshTableSchemas fetches the source schemas of the tables changed by deltas into the cached schemas of their
+// destination tables.
+func (w *CDCFlowWorkflowExecution) refreshTableSchemas(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	state *CDCFlowWorkflowState,
+	deltas []*protos.TableSchemaDelta,
+) {
+	modifiedSrcTables := make([]string, 0, len(deltas))
+	modifiedDstTables := make([]string, 0, len(deltas))
+	for _, tableSchemaDelta := range deltas {
+		modifiedSrcTables = append(modifiedSrcTables, tableSchemaDelta.SrcTableName)
+		modifiedDstTables = append(modifiedDstTables, tableSchemaDelta.DstTableName)
+	}
+
+	getModifiedSchemaCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	getModifiedSchemaFuture := workflow.ExecuteActivity(getModifiedSchemaCtx, flowable.GetTableSchema,
+		&protos.GetTableSchemaBatchInput{
+			PeerConnectionConfig: cfg.Source,
+			TableIdentifiers:     modifiedSrcTables,
+			FlowName:             cfg.FlowJobName,
+		})
+
+	var getModifiedSchemaRes *protos.GetTableSchemaBatchOutput
+	if err := getModifiedSchemaFuture.Get(ctx, &getModifiedSchemaRes); err != nil {
+		w.logger.Error("failed to execute schema update at source: ", err)
+		state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
+		return
+	}
+	for i, srcTable := range modifiedSrcTables {
+		dstTable := modifiedDstTables[i]
+		tableSchema := getModifiedSchemaRes.TableNameSchemaMapping[srcTable]
+		if cfg.SchemaChangesRequireApproval {
+			tableSchema = withoutPendingColumns(tableSchema, dstTable,
+				state.SyncFlowOptions.TableNameSchemaMapping[dstTable], deltas)
+		}
+		// masked columns keep the types they are masked to
+		for _, mapping := range cfg.TableMappings {
+			if mapping.SourceTableIdentifier == srcTable && tableSchema != nil {
+				maskedSchema, err := model.NewColumnMasks(mapping.Masks).MaskTableSchema(tableSchema)
+				if err != nil {
+					state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
+				} else {
+					tableSchema = maskedSchema
+				}
+			}
+		}
+		if tableSchema != nil {
+			tableSchema = model.NumericOverflowTableSchema(tableSchema, cfg.Destination.Type, cfg.NumericOverflowPolicy)
+		}
+		state.SyncFlowOptions.TableNameSchemaMapping[dstTable] = tableSchema
+	}
+}
+
+// withoutPendingColumns drops columns from a freshly fetched source schema which are neither in the
+// cached schema nor added by an applied delta, as those are still waiting for approval.
+func withoutPendingColumns(
+	fetched *protos.TableSchema,
+	dstTable string,
+	cached *protos.TableSchema,
+	appliedDeltas []*protos.TableSchemaDelta,
+) *protos.TableSchema {
+	if fetched == nil || cached == nil {
+		return fetched
+	}
+
+	known := make(map[string]struct{}, len(cached.Columns))
+	for _, column := range cached.Columns {
+		known[column.Name] = struct{}{}
+	}
+	for _, delta := range appliedDeltas {
+		if delta.DstTableName == dstTable {
+			for _, column := range delta.AddedColumns {
+				known[column.ColumnName] = struct{}{}
+			}
+		}
+	}
+
+	filtered := proto.Clone(fetched).(*protos.TableSchema)
+	filtered.Columns = slices.DeleteFunc(filtered.Columns, func(column *protos.FieldDescription) bool {
+		_, ok := known[column.Name]
+		return !ok
+	})
+	if filtered.SyntheticPrimaryKey {
+		filtered.PrimaryKeyColumns = slices.DeleteFunc(filtered.PrimaryKeyColumns, func(column string) bool {
+			_, ok := known[column]
+			return !ok
+		})
+	}
+	return filtered
 }
@@ -0,0 +1,57 @@
+package peerflow
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/log"
+
+	"github.com/PeerDB-io/peer-flow/activities"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestUpdateSyncInterval(t *testing.T) {
+	w := &CDCFlowWorkflowExecution{logger: log.NewStructuredLogger(slog.Default())}
+	settings := adaptiveSyncSettings{Enabled: true, MinInterval: 10 * time.Second, MaxInterval: 5 * time.Minute}
+
+	for _, tc := range []struct {
+		name       string
+		interval   time.Duration
+		numRecords int64
+		batchSize  uint32
+		expected   time.Duration
+	}{
+		{"halves on a full batch", time.Minute, 1000, 1000, 30 * time.Second},
+		{"halves when the default batch size fills", time.Minute, activities.DefaultSyncBatchSize, 0, 30 * time.Second},
+		{"doubles on an empty batch", time.Minute, 0, 1000, 2 * time.Minute},
+		{"doubles on a mostly empty batch", time.Minute, 99, 1000, 2 * time.Minute},
+		{"keeps a partly filled batch", time.Minute, 500, 1000, time.Minute},
+		{"keeps the default batch size partly filled", time.Minute, 500_000, 0, time.Minute},
+		{"clamps halving to the min interval", 15 * time.Second, 1000, 1000, 10 * time.Second},
+		{"clamps doubling to the max interval", 4 * time.Minute, 0, 1000, 5 * time.Minute},
+		{"clamps an unchanged interval", 10 * time.Minute, 500, 1000, 5 * time.Minute},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			state := &CDCFlowWorkflowState{SyncInterval: tc.interval}
+			w.updateSyncInterval(state, settings, tc.numRecords, tc.batchSize)
+			require.Equal(t, tc.expected, state.SyncInterval)
+		})
+	}
+
+	t.Run("never below a second", func(t *testing.T) {
+		state := &CDCFlowWorkflowState{SyncInterval: time.Second}
+		w.updateSyncInterval(state, adaptiveSyncSettings{Enabled: true, MaxInterval: time.Minute}, 10, 10)
+		require.Equal(t, time.Second, state.SyncInterval)
+	})
+}
+
+func TestPausesOnDestinationDrift(t *testing.T) {
+	drifted := []string{"public.users"}
+	require.True(t, pausesOnDestinationDrift(protos.DestinationDriftPolicy_DESTINATION_DRIFT_PAUSE, drifted))
+	require.False(t, pausesOnDestinationDrift(protos.DestinationDriftPolicy_DESTINATION_DRIFT_PAUSE, nil),
+		"a check which found nothing, or repaired everything, leaves the mirror running")
+	require.False(t, pausesOnDestinationDrift(protos.DestinationDriftPolicy_DESTINATION_DRIFT_ALERT, drifted))
+	require.False(t, pausesOnDestinationDrift(protos.DestinationDriftPolicy_DESTINATION_DRIFT_REPAIR, drifted))
+}
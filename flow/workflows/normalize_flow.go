@@ -7,9 +7,9 @@ import (
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/workflow"
 
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
-	"github.com/PeerDB-io/peer-flow/peerdbenv"
 	"github.com/PeerDB-io/peer-flow/shared"
 )
 
@@ -19,6 +19,47 @@ type NormalizeState struct {
 	LastSyncBatchID        int64
 	SyncBatchID            int64
 	TableNameSchemaMapping map[string]*protos.TableSchema
+	// sync batches held back by the mirror's apply delay, oldest first
+	DelayedBatches []DelayedSyncBatch
+	// sync batches waiting for the mirror's normalize schedule
+	PendingBatches   int
+	LastNormalizedAt time.Time
+}
+
+type DelayedSyncBatch struct {
+	SyncBatchID int64
+	SyncedAt    time.Time
+}
+
+// popDueBatches drops the delayed batches synced before cutoff, and returns the batch to normalize up to.
+func (s *NormalizeState) popDueBatches(cutoff time.Time) int64 {
+	batchID := s.LastSyncBatchID
+	due := 0
+	for due < len(s.DelayedBatches) && !s.DelayedBatches[due].SyncedAt.After(cutoff) {
+		batchID = s.DelayedBatches[due].SyncBatchID
+		due += 1
+	}
+	s.DelayedBatches = s.DelayedBatches[due:]
+	return batchID
+}
+
+// normalizeDue returns whether the pending batches are due to be normalized under a normalize schedule.
+func (s *NormalizeState) normalizeDue(schedule *protos.NormalizeSchedule, now time.Time) bool {
+	if s.PendingBatches == 0 || schedule.Manual {
+		return false
+	}
+	interval := time.Duration(schedule.IntervalSeconds) * time.Second
+	return (schedule.EveryBatches > 0 && s.PendingBatches >= int(schedule.EveryBatches)) ||
+		(interval > 0 && !now.Before(s.LastNormalizedAt.Add(interval)))
+}
+
+// normalizeSchedule returns the normalize schedule of a mirror, nil when each batch is normalized once synced.
+func normalizeSchedule(config *protos.FlowConnectionConfigs) *protos.NormalizeSchedule {
+	schedule := config.NormalizeSchedule
+	if schedule == nil || (schedule.EveryBatches == 0 && schedule.IntervalSeconds == 0 && !schedule.Manual) {
+		return nil
+	}
+	return schedule
 }
 
 func NewNormalizeState() *NormalizeState {
@@ -44,6 +85,14 @@ func ProcessLoop(ctx workflow.Context, logger log.Logger, selector workflow.Sele
 	} else if state.Stop && state.LastSyncBatchID == state.SyncBatchID {
 		logger.Info("normalize finished")
 		return true
+	} else if state.Stop && len(state.DelayedBatches) != 0 {
+		// delayed batches are handed back to the parent, which passes them on to the next normalize flow
+		logger.Info("normalize finished with delayed batches", slog.Int("delayedBatches", len(state.DelayedBatches)))
+		return true
+	} else if state.Stop && state.PendingBatches != 0 {
+		// as are batches pending under the normalize schedule, stopping doesn't normalize them early
+		logger.Info("normalize finished with pending batches", slog.Int("pendingBatches", state.PendingBatches))
+		return true
 	}
 	return false
 }
@@ -52,7 +101,7 @@ func NormalizeFlowWorkflow(
 	ctx workflow.Context,
 	config *protos.FlowConnectionConfigs,
 	state *NormalizeState,
-) error {
+) (*NormalizeState, error) {
 	parent := workflow.GetInfo(ctx).ParentWorkflowExecution
 	logger := log.With(workflow.GetLogger(ctx), slog.String(string(shared.FlowNameKey), config.FlowJobName))
 
@@ -65,6 +114,20 @@ func NormalizeFlowWorkflow(
 		HeartbeatTimeout:    time.Minute,
 	})
 
+	// with an apply delay, batches are only normalized once they have been synced for that long,
+	// leaving a window to react before a destructive change at the source reaches the normalized tables
+	applyDelay := time.Duration(config.ApplyDelaySeconds) * time.Second
+
+	// with a normalize schedule, synced batches are left pending until enough of them are,
+	// the interval since the last normalize has passed, or normalize is triggered through the API
+	schedule := normalizeSchedule(config)
+	if schedule != nil && state.LastNormalizedAt.IsZero() {
+		state.LastNormalizedAt = workflow.Now(ctx)
+	}
+
+	// whether the parent is owed a NormalizeDoneSignal, wakeups by timers and triggers don't answer a sync
+	signalled := false
+	triggered := false
 	selector := workflow.NewNamedSelector(ctx, "NormalizeLoop")
 	selector.AddReceive(ctx.Done(), func(_ workflow.ReceiveChannel, _ bool) {})
 	model.NormalizeSignal.GetSignalChannel(ctx).AddToSelector(selector, func(s model.NormalizePayload, _ bool) {
@@ -73,32 +136,94 @@ func NormalizeFlowWorkflow(
 		}
 		if s.SyncBatchID > state.SyncBatchID {
 			state.SyncBatchID = s.SyncBatchID
+			if schedule != nil {
+				state.PendingBatches += 1
+			}
+			if applyDelay > 0 {
+				state.DelayedBatches = append(state.DelayedBatches, DelayedSyncBatch{
+					SyncBatchID: s.SyncBatchID,
+					SyncedAt:    workflow.Now(ctx),
+				})
+			}
+		}
+		if s.TableNameSchemaMapping != nil {
+			state.TableNameSchemaMapping = s.TableNameSchemaMapping
 		}
-		state.TableNameSchemaMapping = s.TableNameSchemaMapping
 		state.Wait = false
+		if s.Trigger {
+			triggered = true
+		} else {
+			signalled = true
+		}
 	})
 
+	delayTimerArmed := false
+	armDelayTimer := func() {
+		if delayTimerArmed || len(state.DelayedBatches) == 0 {
+			return
+		}
+		delayTimerArmed = true
+		wait := state.DelayedBatches[0].SyncedAt.Add(applyDelay).Sub(workflow.Now(ctx))
+		selector.AddFuture(workflow.NewTimer(ctx, max(wait, time.Second)), func(_ workflow.Future) {
+			delayTimerArmed = false
+			state.Wait = false
+		})
+	}
+
+	intervalTimerArmed := false
+	armIntervalTimer := func() {
+		if intervalTimerArmed || schedule == nil || schedule.Manual || schedule.IntervalSeconds == 0 ||
+			state.PendingBatches == 0 {
+			return
+		}
+		intervalTimerArmed = true
+		interval := time.Duration(schedule.IntervalSeconds) * time.Second
+		wait := state.LastNormalizedAt.Add(interval).Sub(workflow.Now(ctx))
+		selector.AddFuture(workflow.NewTimer(ctx, max(wait, time.Second)), func(_ workflow.Future) {
+			intervalTimerArmed = false
+			state.Wait = false
+		})
+	}
+
 	for {
+		armDelayTimer()
+		armIntervalTimer()
 		for state.Wait && ctx.Err() == nil {
 			selector.Select(ctx)
 		}
 		if ProcessLoop(ctx, logger, selector, state) {
-			return ctx.Err()
+			return state, ctx.Err()
 		}
 
-		if state.LastSyncBatchID != state.SyncBatchID {
-			state.LastSyncBatchID = state.SyncBatchID
+		normalizeBatchID := state.SyncBatchID
+		if schedule != nil && !triggered && !state.normalizeDue(schedule, workflow.Now(ctx)) {
+			normalizeBatchID = state.LastSyncBatchID
+		} else if applyDelay > 0 {
+			normalizeBatchID = state.popDueBatches(workflow.Now(ctx).Add(-applyDelay))
+		}
+		triggered = false
+		if normalizeBatchID > state.LastSyncBatchID {
+			state.LastSyncBatchID = normalizeBatchID
+			if schedule != nil {
+				// batches still held back by the apply delay stay pending
+				state.PendingBatches = len(state.DelayedBatches)
+				state.LastNormalizedAt = workflow.Now(ctx)
+			}
 
 			logger.Info("executing normalize")
 			startNormalizeInput := &protos.StartNormalizeInput{
 				FlowConnectionConfigs:  config,
 				TableNameSchemaMapping: state.TableNameSchemaMapping,
-				SyncBatchID:            state.SyncBatchID,
+				SyncBatchID:            normalizeBatchID,
 			}
+			// merges into the destination wait their turn under the global scheduler's limit per destination peer
+			releaseNormalize := acquireLease(ctx, logger, model.LeaseNormalize, config.Destination.Name)
 			fStartNormalize := workflow.ExecuteActivity(normalizeFlowCtx, flowable.StartNormalize, startNormalizeInput)
 
 			var normalizeResponse *model.NormalizeResponse
-			if err := fStartNormalize.Get(normalizeFlowCtx, &normalizeResponse); err != nil {
+			err := fStartNormalize.Get(normalizeFlowCtx, &normalizeResponse)
+			releaseNormalize()
+			if err != nil {
 				_ = model.NormalizeErrorSignal.SignalExternalWorkflow(
 					ctx,
 					parent.ID,
@@ -115,10 +240,9 @@ func NormalizeFlowWorkflow(
 			}
 		}
 
-		if !state.Stop {
-			parallel := GetSideEffect(ctx, func(_ workflow.Context) bool {
-				return peerdbenv.PeerDBEnableParallelSyncNormalize()
-			})
+		if !state.Stop && signalled {
+			signalled = false
+			parallel := readSettings(ctx, config.FlowJobName, dynamicconf.PeerDBEnableParallelSyncNormalize)
 
 			if !parallel {
 				_ = model.NormalizeDoneSignal.SignalExternalWorkflow(
@@ -132,7 +256,7 @@ func NormalizeFlowWorkflow(
 
 		state.Wait = true
 		if ProcessLoop(ctx, logger, selector, state) {
-			return ctx.Err()
+			return state, ctx.Err()
 		}
 	}
 }
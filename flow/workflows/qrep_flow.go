@@ -11,6 +11,8 @@ import (
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
@@ -22,6 +24,7 @@ type QRepFlowExecution struct {
 	flowExecutionID string
 	logger          log.Logger
 	runUUID         string
+	startTime       time.Time
 	// being tracked for future workflow signalling
 	childPartitionWorkflows []workflow.ChildWorkflowFuture
 	// Current signalled state of the peer flow.
@@ -68,6 +71,7 @@ func NewQRepFlowExecution(ctx workflow.Context, config *protos.QRepConfig, runUU
 		flowExecutionID:         workflow.GetInfo(ctx).WorkflowExecution.ID,
 		logger:                  log.With(workflow.GetLogger(ctx), slog.String(string(shared.FlowNameKey), config.FlowJobName)),
 		runUUID:                 runUUID,
+		startTime:               workflow.Now(ctx),
 		childPartitionWorkflows: nil,
 		activeSignal:            model.NoopSignal,
 	}
@@ -143,10 +147,13 @@ func (q *QRepFlowExecution) SetupWatermarkTableOnDestination(ctx workflow.Contex
 		setupConfig := &protos.SetupNormalizedTableBatchInput{
 			PeerConnectionConfig: q.config.DestinationPeer,
 			TableNameSchemaMapping: map[string]*protos.TableSchema{
-				q.config.DestinationTableIdentifier: watermarkTableSchema,
+				q.config.DestinationTableIdentifier: model.NumericOverflowTableSchema(watermarkTableSchema,
+					q.config.DestinationPeer.Type, q.config.NumericOverflowPolicy),
 			},
 			SyncedAtColName: q.config.SyncedAtColName,
 			FlowName:        q.config.FlowJobName,
+			TableMappings:   qrepTableMappings(q.config),
+			SourcePeer:      q.config.SourcePeer,
 		}
 
 		future := workflow.ExecuteActivity(ctx, flowable.CreateNormalizedTable, setupConfig)
@@ -181,23 +188,25 @@ func (q *QRepFlowExecution) GetPartitions(
 	return partitions, nil
 }
 
-// ReplicatePartitions replicates the partition batch.
+// ReplicatePartitions replicates the partition batch, returning the rows synced.
 func (q *QRepPartitionFlowExecution) ReplicatePartitions(ctx workflow.Context,
 	partitions *protos.QRepPartitionBatch,
-) error {
+) (int64, error) {
 	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: 24 * 5 * time.Hour,
 		HeartbeatTimeout:    time.Minute,
+		WaitForCancellation: true,
 	})
 
 	msg := fmt.Sprintf("replicating partition batch - %d", partitions.BatchId)
 	q.logger.Info(msg)
+	var rowsSynced int64
 	if err := workflow.ExecuteActivity(ctx,
-		flowable.ReplicateQRepPartitions, q.config, partitions, q.runUUID).Get(ctx, nil); err != nil {
-		return fmt.Errorf("failed to replicate partition: %w", err)
+		flowable.ReplicateQRepPartitions, q.config, partitions, q.runUUID).Get(ctx, &rowsSynced); err != nil {
+		return 0, fmt.Errorf("failed to replicate partition: %w", err)
 	}
 
-	return nil
+	return rowsSynced, nil
 }
 
 // getPartitionWorkflowID returns the child workflow ID for a new sync flow.
@@ -221,17 +230,27 @@ func (q *QRepFlowExecution) startChildWorkflow(
 		SearchAttributes: map[string]interface{}{
 			shared.MirrorNameSearchAttribute: q.config.FlowJobName,
 		},
+		// a paused flow only continues once its partitions stopped replicating
+		WaitForCancellation: true,
 	})
 
 	return workflow.ExecuteChildWorkflow(partFlowCtx, QRepPartitionWorkflow, q.config, partitions, q.runUUID)
 }
 
 // processPartitions handles the logic for processing the partitions.
+// signalSelector receives signals while waiting for partitions, a pause signal cancels the batches still
+// replicating, whose partitions are returned to be replicated once the flow is resumed.
+// Batches completing are counted towards progress unless it's nil.
 func (q *QRepFlowExecution) processPartitions(
 	ctx workflow.Context,
 	maxParallelWorkers int,
 	partitions []*protos.QRepPartition,
-) error {
+	signalSelector workflow.Selector,
+	progress *protos.InitialLoadProgress,
+) ([]*protos.QRepPartition, error) {
+	if len(partitions) == 0 {
+		return nil, nil
+	}
 	chunkSize := shared.DivCeil(len(partitions), maxParallelWorkers)
 	batches := make([][]*protos.QRepPartition, 0, len(partitions)/chunkSize+1)
 	for i := 0; i < len(partitions); i += chunkSize {
@@ -241,33 +260,76 @@ func (q *QRepFlowExecution) processPartitions(
 
 	q.logger.Info("processing partitions in batches", "num batches", len(batches))
 
+	childCtx, cancelChildren := workflow.WithCancel(ctx)
+	selector := signalSelector
+	batchesDone := make([]bool, len(batches))
+	remaining := len(batches)
+	var childErr error
 	for i, parts := range batches {
 		batch := &protos.QRepPartitionBatch{
 			Partitions: parts,
 			BatchId:    int32(i + 1),
 		}
-		future := q.startChildWorkflow(ctx, batch)
+		future := q.startChildWorkflow(childCtx, batch)
 		q.childPartitionWorkflows = append(q.childPartitionWorkflows, future)
+		selector.AddFuture(future, func(f workflow.Future) {
+			remaining -= 1
+			var rowsSynced int64
+			if err := f.Get(ctx, &rowsSynced); err != nil {
+				// batches cancelled by a pause are replicated again on resume
+				if !temporal.IsCanceledError(err) {
+					childErr = err
+				}
+				return
+			}
+			batchesDone[i] = true
+			if progress != nil {
+				progress.RowsCopied += rowsSynced
+				progress.PartitionsCompleted += uint32(len(parts))
+			}
+		})
 	}
-
-	// wait for all the child workflows to complete
-	for _, future := range q.childPartitionWorkflows {
-		if err := future.Get(ctx, nil); err != nil {
-			return fmt.Errorf("failed to wait for child workflow: %w", err)
+	// wait for all the child workflows to complete, or to stop replicating once paused
+	paused := false
+	for remaining > 0 {
+		selector.Select(ctx)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if childErr != nil {
+			cancelChildren()
+			return nil, fmt.Errorf("failed to wait for child workflow: %w", childErr)
+		}
+		if !paused && q.activeSignal == model.PauseSignal {
+			q.logger.Info("pausing, cancelling partition batches still replicating", slog.Int("batches", remaining))
+			paused = true
+			cancelChildren()
 		}
 	}
-
+	cancelChildren()
 	q.childPartitionWorkflows = nil
-	q.logger.Info("all partitions in batch processed")
-	return nil
+
+	var pending []*protos.QRepPartition
+	for i, done := range batchesDone {
+		if !done {
+			pending = append(pending, batches[i]...)
+		}
+	}
+	if len(pending) != 0 {
+		q.logger.Info("paused before all partitions were processed", slog.Int("pending", len(pending)))
+	} else {
+		q.logger.Info("all partitions in batch processed")
+	}
+	return pending, nil
 }
 
 // For some targets we need to consolidate all the partitions from stages before
 // we proceed to next batch.
+// The summary of the run is recorded once it is done.
 func (q *QRepFlowExecution) consolidatePartitions(ctx workflow.Context) error {
 	q.logger.Info("consolidating partitions")
 
-	// only an operation for Snowflake currently.
+	// only an operation for Snowflake and BigQuery native import currently.
 	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: 24 * time.Hour,
 		HeartbeatTimeout:    time.Minute,
@@ -287,6 +349,14 @@ func (q *QRepFlowExecution) consolidatePartitions(ctx workflow.Context) error {
 
 	q.logger.Info("qrep flow cleaned up")
 
+	summaryCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	if err := workflow.ExecuteActivity(summaryCtx, flowable.RecordQRepRunSummary, q.config,
+		q.runUUID, q.startTime).Get(ctx, nil); err != nil {
+		return fmt.Errorf("failed to record qrep run summary: %w", err)
+	}
+
 	return nil
 }
 
@@ -306,6 +376,67 @@ func (q *QRepFlowExecution) waitForNewRows(ctx workflow.Context, lastPartition *
 	return nil
 }
 
+// reconcileDeletes deletes rows missing at the source from the destination, at most once per interval.
+func (q *QRepFlowExecution) reconcileDeletes(ctx workflow.Context, state *protos.QRepFlowState) error {
+	if q.config.DeleteReconciliationIntervalSeconds == 0 {
+		return nil
+	}
+	interval := time.Duration(q.config.DeleteReconciliationIntervalSeconds) * time.Second
+	now := workflow.Now(ctx)
+	if state.LastDeleteReconciliation != nil && now.Sub(state.LastDeleteReconciliation.AsTime()) < interval {
+		return nil
+	}
+	q.logger.Info("reconciling deletes")
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 24 * time.Hour,
+		HeartbeatTimeout:    time.Minute,
+	})
+
+	if err := workflow.ExecuteActivity(ctx, flowable.ReconcileQRepDeletes, q.config).Get(ctx, nil); err != nil {
+		return fmt.Errorf("failed to reconcile deletes: %w", err)
+	}
+	state.LastDeleteReconciliation = timestamppb.New(now)
+
+	return nil
+}
+
+// getQRepScheduleState returns the state the previous run of a scheduled mirror left, nil for its first run,
+// which starts from the state the schedule was created with.
+func getQRepScheduleState(ctx workflow.Context, flowJobName string) (*protos.QRepFlowState, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	var state *protos.QRepFlowState
+	if err := workflow.ExecuteActivity(ctx, flowable.GetQRepScheduleState, flowJobName).Get(ctx, &state); err != nil {
+		return nil, fmt.Errorf("failed to get state of scheduled run: %w", err)
+	}
+	return state, nil
+}
+
+func saveQRepScheduleState(ctx workflow.Context, flowJobName string, state *protos.QRepFlowState) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	if err := workflow.ExecuteActivity(ctx, flowable.SaveQRepScheduleState, flowJobName, state).Get(ctx, nil); err != nil {
+		return fmt.Errorf("failed to save state of scheduled run: %w", err)
+	}
+	return nil
+}
+
+// qrepTableMappings carries the destination table settings of a QRep mirror to the activities creating its table,
+// which look them up by destination table like those of CDC mirrors
+func qrepTableMappings(config *protos.QRepConfig) []*protos.TableMapping {
+	if config.Bigquery == nil {
+		return nil
+	}
+	return []*protos.TableMapping{{
+		SourceTableIdentifier:      config.WatermarkTable,
+		DestinationTableIdentifier: config.DestinationTableIdentifier,
+		Bigquery:                   config.Bigquery,
+	}}
+}
+
 func (q *QRepFlowExecution) handleTableCreationForResync(ctx workflow.Context, state *protos.QRepFlowState) error {
 	if state.NeedsResync && q.config.DstTableFullResync {
 		renamedTableIdentifier := q.config.DestinationTableIdentifier + "_peerdb_resync"
@@ -320,6 +451,7 @@ func (q *QRepFlowExecution) handleTableCreationForResync(ctx workflow.Context, s
 				NewToExistingTableMapping: map[string]string{
 					renamedTableIdentifier: q.config.DestinationTableIdentifier,
 				},
+				TableMappings: qrepTableMappings(q.config),
 			})
 		if err := createTablesFromExistingFuture.Get(createTablesFromExistingCtx, nil); err != nil {
 			return fmt.Errorf("failed to create table for mirror resync: %w", err)
@@ -363,6 +495,57 @@ func (q *QRepFlowExecution) handleTableRenameForResync(ctx workflow.Context, sta
 	return nil
 }
 
+// applyConfigUpdate records the settings changed by a config update in the flow state,
+// and applies them to the rest of the run.
+func (q *QRepFlowExecution) applyConfigUpdate(state *protos.QRepFlowState, update *protos.QRepFlowConfigUpdate) {
+	if state.ConfigOverrides == nil {
+		state.ConfigOverrides = &protos.QRepFlowConfigUpdate{}
+	}
+	if update.MaxParallelWorkers > 0 {
+		state.ConfigOverrides.MaxParallelWorkers = update.MaxParallelWorkers
+	}
+	if update.NumRowsPerPartition > 0 {
+		state.ConfigOverrides.NumRowsPerPartition = update.NumRowsPerPartition
+	}
+	if update.WaitBetweenBatchesSeconds > 0 {
+		state.ConfigOverrides.WaitBetweenBatchesSeconds = update.WaitBetweenBatchesSeconds
+	}
+	applyQRepConfigOverrides(q.config, state.ConfigOverrides)
+
+	q.logger.Info("QRep config update received. Parameters on signal reception:",
+		slog.Int("MaxParallelWorkers", int(q.config.MaxParallelWorkers)),
+		slog.Int("NumRowsPerPartition", int(q.config.NumRowsPerPartition)),
+		slog.Int("WaitBetweenBatchesSeconds", int(q.config.WaitBetweenBatchesSeconds)))
+}
+
+func (q *QRepFlowExecution) receiveConfigUpdatesAsync(state *protos.QRepFlowState,
+	configUpdateChan model.TypedReceiveChannel[*protos.QRepFlowConfigUpdate],
+) {
+	for {
+		update, ok := configUpdateChan.ReceiveAsync()
+		if !ok {
+			return
+		}
+		q.applyConfigUpdate(state, update)
+	}
+}
+
+// applyQRepConfigOverrides sets the settings changed while the mirror was running on its config.
+func applyQRepConfigOverrides(config *protos.QRepConfig, overrides *protos.QRepFlowConfigUpdate) {
+	if overrides == nil {
+		return
+	}
+	if overrides.MaxParallelWorkers > 0 {
+		config.MaxParallelWorkers = overrides.MaxParallelWorkers
+	}
+	if overrides.NumRowsPerPartition > 0 {
+		config.NumRowsPerPartition = overrides.NumRowsPerPartition
+	}
+	if overrides.WaitBetweenBatchesSeconds > 0 {
+		config.WaitBetweenBatchesSeconds = overrides.WaitBetweenBatchesSeconds
+	}
+}
+
 func (q *QRepFlowExecution) receiveAndHandleSignalAsync(signalChan model.TypedReceiveChannel[model.CDCFlowSignal]) {
 	val, ok := signalChan.ReceiveAsync()
 	if ok {
@@ -387,6 +570,20 @@ func setWorkflowQueries(ctx workflow.Context, state *protos.QRepFlowState) error
 		return fmt.Errorf("failed to set `%s` query handler: %w", shared.FlowStatusQuery, err)
 	}
 
+	// Support a Query for the progress of the initial copy, nil once it's done.
+	err = workflow.SetQueryHandler(ctx, shared.InitialLoadProgressQuery, func() (*protos.InitialLoadProgress, error) {
+		if state.InitialLoad == nil {
+			return nil, nil
+		}
+		progress := proto.Clone(state.InitialLoad).(*protos.InitialLoadProgress)
+		// queries aren't replayed, the ETA is extrapolated to when it's asked for
+		progress.Eta = model.InitialLoadETA(progress, time.Now())
+		return progress, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set `%s` query handler: %w", shared.InitialLoadProgressQuery, err)
+	}
+
 	// Support an Update for the current status of the qrep flow.
 	err = workflow.SetUpdateHandler(ctx, shared.FlowStatusUpdate, func(status *protos.FlowStatus) error {
 		state.CurrentFlowStatus = *status
@@ -412,8 +609,17 @@ func QRepFlowWorkflow(
 
 	originalRunID := workflow.GetInfo(ctx).OriginalRunID
 	ctx = workflow.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	if config.Schedule != nil {
+		scheduledState, err := getQRepScheduleState(ctx, config.FlowJobName)
+		if err != nil {
+			return err
+		} else if scheduledState != nil {
+			state = scheduledState
+		}
+	}
+	applyQRepConfigOverrides(config, state.ConfigOverrides)
 
-	maxParallelWorkers := 16
+	maxParallelWorkers := shared.DefaultQRepMaxParallelWorkers
 	if config.MaxParallelWorkers > 0 {
 		maxParallelWorkers = int(config.MaxParallelWorkers)
 	}
@@ -446,59 +652,112 @@ func QRepFlowWorkflow(
 	}
 	logger.Info("metadata tables setup for peer flow - ", config.FlowJobName)
 
-	err = q.handleTableCreationForResync(ctx, state)
-	if err != nil {
-		return err
-	}
+	// signals are received while partitions replicate, so that pausing doesn't wait for the whole run
+	signalSelector := workflow.NewSelector(ctx)
+	signalChan := model.FlowSignal.GetSignalChannel(ctx)
+	signalChan.AddToSelector(signalSelector, func(val model.CDCFlowSignal, _ bool) {
+		q.activeSignal = model.FlowSignalHandler(q.activeSignal, val, q.logger)
+	})
+	configUpdateChan := model.QRepDynamicPropertiesSignal.GetSignalChannel(ctx)
+	configUpdateChan.AddToSelector(signalSelector, func(update *protos.QRepFlowConfigUpdate, _ bool) {
+		q.applyConfigUpdate(state, update)
+	})
 
-	logger.Info("fetching partitions to replicate for peer flow - ", config.FlowJobName)
-	partitions, err := q.GetPartitions(ctx, state.LastPartition)
-	if err != nil {
-		return fmt.Errorf("failed to get partitions: %w", err)
+	var partitions []*protos.QRepPartition
+	if len(state.PendingPartitions) != 0 {
+		// resuming a paused run, its destination table was already set up
+		partitions = state.PendingPartitions
+		logger.Info("resuming partitions of paused run - ", len(partitions))
+	} else {
+		err = q.handleTableCreationForResync(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("fetching partitions to replicate for peer flow - ", config.FlowJobName)
+		partitionResult, err := q.GetPartitions(ctx, state.LastPartition)
+		if err != nil {
+			return fmt.Errorf("failed to get partitions: %w", err)
+		}
+		partitions = partitionResult.Partitions
+		// the first run copies the table, later ones only the rows added since
+		if len(partitions) > 0 && state.NumPartitionsProcessed == 0 && state.LastPartition.GetRange() == nil {
+			state.InitialLoad = &protos.InitialLoadProgress{
+				TableName:       config.WatermarkTable,
+				EstimatedRows:   partitionResult.EstimatedRows,
+				PartitionsTotal: uint32(len(partitions)),
+				StartTime:       timestamppb.New(workflow.Now(ctx)),
+			}
+		}
+		// replication continues after the last partition of the run even if it's paused
+		if len(partitions) > 0 {
+			state.LastPartition = partitions[len(partitions)-1]
+		}
 	}
 
-	logger.Info("partitions to replicate - ", len(partitions.Partitions))
-	if err := q.processPartitions(ctx, maxParallelWorkers, partitions.Partitions); err != nil {
+	logger.Info("partitions to replicate - ", len(partitions))
+	pending, err := q.processPartitions(ctx, maxParallelWorkers, partitions, signalSelector, state.InitialLoad)
+	if err != nil {
 		return err
 	}
+	state.PendingPartitions = pending
 
 	logger.Info("consolidating partitions for peer flow")
 	if err := q.consolidatePartitions(ctx); err != nil {
 		return err
 	}
-
-	if config.InitialCopyOnly {
-		logger.Info("initial copy completed for peer flow - ", config.FlowJobName)
-		return nil
+	if len(pending) == 0 && state.InitialLoad != nil {
+		logger.Info("initial load completed", slog.Int64("rows", state.InitialLoad.RowsCopied),
+			slog.Duration("duration", workflow.Now(ctx).Sub(state.InitialLoad.StartTime.AsTime())))
+		state.InitialLoad = nil
 	}
 
-	err = q.handleTableRenameForResync(ctx, state)
-	if err != nil {
-		return err
-	}
+	numPartitionsProcessed := len(partitions) - len(pending)
+	logger.Info("partitions processed - ", numPartitionsProcessed)
+	state.NumPartitionsProcessed += uint64(numPartitionsProcessed)
 
-	logger.Info("partitions processed - ", len(partitions.Partitions))
-	state.NumPartitionsProcessed += uint64(len(partitions.Partitions))
+	if len(pending) == 0 {
+		if config.InitialCopyOnly {
+			logger.Info("initial copy completed for peer flow - ", config.FlowJobName)
+			return nil
+		}
 
-	if len(partitions.Partitions) > 0 {
-		state.LastPartition = partitions.Partitions[len(partitions.Partitions)-1]
+		err = q.handleTableRenameForResync(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		if err := q.reconcileDeletes(ctx, state); err != nil {
+			return err
+		}
+
+		if !state.DisableWaitForNewRows && config.Schedule == nil {
+			// sleep for a while and continue the workflow
+			err = q.waitForNewRows(ctx, state.LastPartition)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	if !state.DisableWaitForNewRows {
-		// sleep for a while and continue the workflow
-		err = q.waitForNewRows(ctx, state.LastPartition)
-		if err != nil {
+	if config.Schedule != nil {
+		// the schedule starts the next run, which resumes from the saved state
+		q.receiveConfigUpdatesAsync(state, configUpdateChan)
+		if err := saveQRepScheduleState(ctx, config.FlowJobName, state); err != nil {
 			return err
 		}
+		logger.Info("scheduled run completed",
+			"Last Partition", state.LastPartition,
+			"Number of Partitions Processed", state.NumPartitionsProcessed)
+		return nil
 	}
 
 	logger.Info("Continuing as new workflow",
 		"Last Partition", state.LastPartition,
 		"Number of Partitions Processed", state.NumPartitionsProcessed)
 
-	// here, we handle signals after the end of the flow because a new workflow does not inherit the signals
-	// and the chance of missing a signal is much higher if the check is before the time consuming parts run
-	signalChan := model.FlowSignal.GetSignalChannel(ctx)
+	// signals which arrived after partitions were replicated are handled here too, because a new workflow
+	// does not inherit the signals
 	q.receiveAndHandleSignalAsync(signalChan)
 	if q.activeSignal == model.PauseSignal {
 		startTime := workflow.Now(ctx)
@@ -506,7 +765,7 @@ func QRepFlowWorkflow(
 
 		for q.activeSignal == model.PauseSignal {
 			logger.Info("mirror has been paused", slog.Any("duration", time.Since(startTime)))
-			// only place we block on receive, so signal processing is immediate
+			// blocking on receive, so signal processing is immediate
 			val, ok, _ := signalChan.ReceiveWithTimeout(ctx, 1*time.Minute)
 			if ok {
 				q.activeSignal = model.FlowSignalHandler(q.activeSignal, val, q.logger)
@@ -518,8 +777,10 @@ func QRepFlowWorkflow(
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	// config updates are carried over in the state, a new workflow does not inherit the signals
+	q.receiveConfigUpdatesAsync(state, configUpdateChan)
 	// Continue the workflow with new state
-	return workflow.NewContinueAsNewError(ctx, QRepFlowWorkflow, config, state)
+	return workflow.NewContinueAsNewError(onDefaultBuild(ctx), QRepFlowWorkflow, config, state)
 }
 
 // QRepPartitionWorkflow replicate a partition batch
@@ -528,7 +789,7 @@ func QRepPartitionWorkflow(
 	config *protos.QRepConfig,
 	partitions *protos.QRepPartitionBatch,
 	runUUID string,
-) error {
+) (int64, error) {
 	ctx = workflow.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
 	q := NewQRepPartitionFlowExecution(ctx, config, runUUID)
 	return q.ReplicatePartitions(ctx, partitions)
@@ -0,0 +1,24 @@
+package peerflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestQRepTableMappings(t *testing.T) {
+	config := &protos.QRepConfig{
+		WatermarkTable:             "public.events",
+		DestinationTableIdentifier: "dataset.events",
+	}
+	require.Empty(t, qrepTableMappings(config))
+
+	config.Bigquery = &protos.BigqueryTableSettings{PartitionColumn: "created_at", ClusterColumns: []string{"id"}}
+	mappings := qrepTableMappings(config)
+	require.Len(t, mappings, 1)
+	require.Equal(t, "dataset.events", mappings[0].DestinationTableIdentifier)
+	require.Same(t, config.Bigquery, mappings[0].Bigquery,
+		"the settings are looked up by the existing table when it's recreated for a resync")
+}
@@ -1,7 +1,9 @@
 package peerflow
 
 import (
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 )
 
 func RegisterFlowWorkerWorkflows(w worker.WorkflowRegistry) {
@@ -12,8 +14,19 @@ func RegisterFlowWorkerWorkflows(w worker.WorkflowRegistry) {
 	w.RegisterWorkflow(QRepFlowWorkflow)
 	w.RegisterWorkflow(QRepPartitionWorkflow)
 	w.RegisterWorkflow(XminFlowWorkflow)
+	w.RegisterWorkflow(ValidateMirrorWorkflow)
 
 	w.RegisterWorkflow(GlobalScheduleManagerWorkflow)
 	w.RegisterWorkflow(HeartbeatFlowWorkflow)
 	w.RegisterWorkflow(RecordSlotSizeWorkflow)
+	w.RegisterWorkflow(CredentialExpiryWorkflow)
+	w.RegisterWorkflow(StatsRollupWorkflow)
+	w.RegisterWorkflow(RawTablePruneWorkflow)
+	w.RegisterWorkflow(MirrorSupervisorWorkflow)
+}
+
+// onDefaultBuild continues mirrors as new on the default worker build of versioned task queues,
+// otherwise mirrors never finish and would keep a previous worker release running forever.
+func onDefaultBuild(ctx workflow.Context) workflow.Context {
+	return workflow.WithWorkflowVersioningIntent(ctx, temporal.VersioningIntentDefault)
 }
@@ -5,11 +5,9 @@ import (
 
 	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/workflow"
-
-	"github.com/PeerDB-io/peer-flow/peerdbenv"
 )
 
-// RecordSlotSizeWorkflow monitors replication slot size
+// RecordSlotSizeWorkflow monitors replication slot size and lag
 func RecordSlotSizeWorkflow(ctx workflow.Context) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -21,7 +19,7 @@ func RecordSlotSizeWorkflow(ctx workflow.Context) error {
 	return slotSizeFuture.Get(ctx, nil)
 }
 
-// HeartbeatFlowWorkflow sends WAL heartbeats
+// HeartbeatFlowWorkflow sends source heartbeats every minute, and WAL heartbeats every 12 minutes
 func HeartbeatFlowWorkflow(ctx workflow.Context) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -29,10 +27,62 @@ func HeartbeatFlowWorkflow(ctx workflow.Context) error {
 	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: time.Hour,
 	})
-	heartbeatFuture := workflow.ExecuteActivity(ctx, flowable.SendWALHeartbeat)
+	walHeartbeat := workflow.Now(ctx).Minute()%12 == 0
+	heartbeatFuture := workflow.ExecuteActivity(ctx, flowable.SendWALHeartbeat, walHeartbeat)
 	return heartbeatFuture.Get(ctx, nil)
 }
 
+// CredentialExpiryWorkflow checks for expiring peer credentials
+func CredentialExpiryWorkflow(ctx workflow.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Hour,
+		HeartbeatTimeout:    5 * time.Minute,
+	})
+	expiryFuture := workflow.ExecuteActivity(ctx, flowable.CheckPeerCredentialExpiry)
+	return expiryFuture.Get(ctx, nil)
+}
+
+// StatsRollupWorkflow rolls up mirror stats and deletes those past retention
+func StatsRollupWorkflow(ctx workflow.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Hour,
+	})
+	rollupFuture := workflow.ExecuteActivity(ctx, flowable.RollupStats)
+	return rollupFuture.Get(ctx, nil)
+}
+
+// RawTablePruneWorkflow deletes raw table rows of CDC mirrors normalized longer ago than their retention
+func RawTablePruneWorkflow(ctx workflow.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Hour,
+		HeartbeatTimeout:    5 * time.Minute,
+	})
+	pruneFuture := workflow.ExecuteActivity(ctx, flowable.PruneRawTables)
+	return pruneFuture.Get(ctx, nil)
+}
+
+// MirrorSupervisorWorkflow restarts failed mirrors under the restart policy
+func MirrorSupervisorWorkflow(ctx workflow.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Minute,
+		HeartbeatTimeout:    5 * time.Minute,
+	})
+	restartFuture := workflow.ExecuteActivity(ctx, flowable.RestartFailedMirrors)
+	return restartFuture.Get(ctx, nil)
+}
+
 func withCronOptions(ctx workflow.Context, workflowID string, cron string) workflow.Context {
 	return workflow.WithChildOptions(ctx,
 		workflow.ChildWorkflowOptions{
@@ -44,27 +94,48 @@ func withCronOptions(ctx workflow.Context, workflowID string, cron string) workf
 	)
 }
 
-func GlobalScheduleManagerWorkflow(ctx workflow.Context) error {
+// GlobalScheduleManagerWorkflow starts the scheduled flows, and schedules the expensive phases of mirrors
+// by granting leases under limits like how many initial loads run at once. Once canceled it returns the leases
+// it granted and the requests waiting for one.
+func GlobalScheduleManagerWorkflow(ctx workflow.Context, state *SchedulerState) (*SchedulerState, error) {
 	info := workflow.GetInfo(ctx)
 
-	walHeartbeatEnabled := GetSideEffect(ctx, func(_ workflow.Context) bool {
-		return peerdbenv.PeerDBEnableWALHeartbeat()
-	})
-	if walHeartbeatEnabled {
-		heartbeatCtx := withCronOptions(ctx,
-			"wal-heartbeat-"+info.OriginalRunID,
-			"*/12 * * * *")
-		workflow.ExecuteChildWorkflow(
-			heartbeatCtx,
-			HeartbeatFlowWorkflow,
-		)
-	}
+	// mirrors can have source heartbeats without WAL heartbeats being enabled, the activity checks for both
+	heartbeatCtx := withCronOptions(ctx,
+		"wal-heartbeat-"+info.OriginalRunID,
+		"* * * * *")
+	workflow.ExecuteChildWorkflow(
+		heartbeatCtx,
+		HeartbeatFlowWorkflow,
+	)
 
 	slotSizeCtx := withCronOptions(ctx,
 		"record-slot-size-"+info.OriginalRunID,
 		"*/5 * * * *")
 	workflow.ExecuteChildWorkflow(slotSizeCtx, RecordSlotSizeWorkflow)
 
-	ctx.Done().Receive(ctx, nil)
-	return ctx.Err()
+	credentialExpiryCtx := withCronOptions(ctx,
+		"credential-expiry-"+info.OriginalRunID,
+		"0 */6 * * *")
+	workflow.ExecuteChildWorkflow(credentialExpiryCtx, CredentialExpiryWorkflow)
+
+	statsRollupCtx := withCronOptions(ctx,
+		"stats-rollup-"+info.OriginalRunID,
+		"*/15 * * * *")
+	workflow.ExecuteChildWorkflow(statsRollupCtx, StatsRollupWorkflow)
+
+	rawTablePruneCtx := withCronOptions(ctx,
+		"raw-table-prune-"+info.OriginalRunID,
+		"30 * * * *")
+	workflow.ExecuteChildWorkflow(rawTablePruneCtx, RawTablePruneWorkflow)
+
+	mirrorSupervisorCtx := withCronOptions(ctx,
+		"mirror-supervisor-"+info.OriginalRunID,
+		"* * * * *")
+	workflow.ExecuteChildWorkflow(mirrorSupervisorCtx, MirrorSupervisorWorkflow)
+
+	if state == nil {
+		state = &SchedulerState{}
+	}
+	return runScheduler(ctx, state)
 }
@@ -0,0 +1,259 @@
+package peerflow
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/PeerDB-io/peer-flow/dynamicconf"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+const (
+	// how often mirrors waiting for a lease ask again, in case the scheduler restarted and lost their request
+	leaseRequestInterval = time.Minute
+	// how often the scheduler checks that the workflows holding or waiting for leases still run
+	leaseHolderCheckInterval = time.Minute
+)
+
+// SchedulerState holds the leases the global scheduler granted, and the requests waiting for one in the order made.
+type SchedulerState struct {
+	Leases  []*model.LeaseRequest
+	Waiting []*model.LeaseRequest
+}
+
+// leaseLimits caps the leases of each kind, 0 doesn't limit them
+type leaseLimits struct {
+	InitialLoads      int
+	NormalizesPerPeer int
+}
+
+// currentLeaseLimits reads the limits from the catalog's settings
+func currentLeaseLimits(ctx workflow.Context) leaseLimits {
+	return readSettings(ctx, "", func(ctx context.Context) leaseLimits {
+		return leaseLimits{
+			InitialLoads:      dynamicconf.PeerDBMaxConcurrentInitialLoads(ctx),
+			NormalizesPerPeer: dynamicconf.PeerDBMaxConcurrentNormalizesPerPeer(ctx),
+		}
+	})
+}
+
+func (l leaseLimits) limit(kind model.LeaseKind) int {
+	switch kind {
+	case model.LeaseInitialLoad:
+		return l.InitialLoads
+	case model.LeaseNormalize:
+		return l.NormalizesPerPeer
+	default:
+		return 0
+	}
+}
+
+// fits returns whether a request can be granted alongside the leases already granted
+func (l leaseLimits) fits(leases []*model.LeaseRequest, request *model.LeaseRequest) bool {
+	limit := l.limit(request.Kind)
+	if limit <= 0 {
+		return true
+	}
+	held := 0
+	for _, lease := range leases {
+		if lease.Kind == request.Kind && (request.Kind != model.LeaseNormalize || lease.Peer == request.Peer) {
+			held += 1
+		}
+	}
+	return held < limit
+}
+
+// request queues a lease request, returning whether it was already granted
+func (s *SchedulerState) request(request *model.LeaseRequest) bool {
+	isRequest := func(r *model.LeaseRequest) bool { return r.ID == request.ID }
+	if slices.ContainsFunc(s.Leases, isRequest) {
+		return true
+	}
+	if !slices.ContainsFunc(s.Waiting, isRequest) {
+		s.Waiting = append(s.Waiting, request)
+	}
+	return false
+}
+
+// release drops a lease, or a request still waiting for one
+func (s *SchedulerState) release(requestID string) {
+	isRequest := func(r *model.LeaseRequest) bool { return r.ID == requestID }
+	s.Leases = slices.DeleteFunc(s.Leases, isRequest)
+	s.Waiting = slices.DeleteFunc(s.Waiting, isRequest)
+}
+
+// grant moves the waiting requests which fit in the limits to the leases and returns them,
+// requests which don't fit don't hold up later ones for other kinds or peers
+func (s *SchedulerState) grant(limits leaseLimits) []*model.LeaseRequest {
+	var granted []*model.LeaseRequest
+	waiting := make([]*model.LeaseRequest, 0, len(s.Waiting))
+	for _, request := range s.Waiting {
+		if limits.fits(s.Leases, request) {
+			s.Leases = append(s.Leases, request)
+			granted = append(granted, request)
+		} else {
+			waiting = append(waiting, request)
+		}
+	}
+	s.Waiting = waiting
+	return granted
+}
+
+// runScheduler grants leases to mirrors asking for them, continuing as new with the leases and waiting requests
+// once its history grows long. Once canceled it returns them, for the scheduler replacing it to carry on with.
+func runScheduler(ctx workflow.Context, state *SchedulerState) (*SchedulerState, error) {
+	logger := workflow.GetLogger(ctx)
+	limits := currentLeaseLimits(ctx)
+	checkCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		HeartbeatTimeout:    time.Minute,
+	})
+
+	// requests repeated for leases already granted are answered again, the first answer may have been lost
+	var regranted []*model.LeaseRequest
+	onRequest := func(request *model.LeaseRequest, _ bool) {
+		if state.request(request) {
+			regranted = append(regranted, request)
+		}
+	}
+	onRelease := func(request *model.LeaseRequest, _ bool) {
+		state.release(request.ID)
+	}
+	requests := model.LeaseRequestSignal.GetSignalChannel(ctx)
+	releases := model.LeaseReleaseSignal.GetSignalChannel(ctx)
+	drainSignals := func() {
+		for {
+			request, ok := requests.ReceiveAsync()
+			if !ok {
+				break
+			}
+			onRequest(request, true)
+		}
+		for {
+			request, ok := releases.ReceiveAsync()
+			if !ok {
+				break
+			}
+			onRelease(request, true)
+		}
+	}
+
+	checkDue := false
+	checkArmed := false
+	selector := workflow.NewNamedSelector(ctx, "Scheduler")
+	selector.AddReceive(ctx.Done(), func(_ workflow.ReceiveChannel, _ bool) {})
+	requests.AddToSelector(selector, onRequest)
+	releases.AddToSelector(selector, onRelease)
+
+	for ctx.Err() == nil {
+		granted := append(regranted, state.grant(limits)...)
+		regranted = nil
+		dropped := false
+		for _, lease := range granted {
+			if err := model.LeaseGrantedSignal.SignalExternalWorkflow(
+				ctx, lease.WorkflowID, lease.RunID, lease.ID,
+			).Get(ctx, nil); err != nil {
+				// the run asking is gone, its lease goes to the next in line
+				logger.Warn("failed to grant lease", slog.String("workflowID", lease.WorkflowID),
+					slog.String("kind", string(lease.Kind)), slog.Any("error", err))
+				state.release(lease.ID)
+				dropped = true
+			}
+		}
+		if dropped {
+			continue
+		}
+
+		if checkDue {
+			checkDue = false
+			var stale []string
+			if err := workflow.ExecuteActivity(checkCtx, flowable.StaleLeaseRequests,
+				slices.Concat(state.Leases, state.Waiting)).Get(checkCtx, &stale); err != nil {
+				logger.Warn("failed to check lease holders", slog.Any("error", err))
+			}
+			for _, requestID := range stale {
+				state.release(requestID)
+			}
+			continue
+		}
+		if !checkArmed && (len(state.Leases) != 0 || len(state.Waiting) != 0) {
+			checkArmed = true
+			selector.AddFuture(workflow.NewTimer(ctx, leaseHolderCheckInterval), func(_ workflow.Future) {
+				checkArmed = false
+				checkDue = true
+			})
+		}
+
+		if workflow.GetInfo(ctx).GetContinueAsNewSuggested() {
+			drainSignals()
+			return nil, workflow.NewContinueAsNewError(onDefaultBuild(ctx), GlobalScheduleManagerWorkflow, state)
+		}
+		selector.Select(ctx)
+	}
+	// requests and releases which came in with the cancellation are part of what's handed over
+	drainSignals()
+	return state, nil
+}
+
+// acquireLease waits for the global scheduler to grant a lease of a kind, and returns a function releasing it.
+// Mirrors go ahead without a lease when no scheduler runs.
+func acquireLease(ctx workflow.Context, logger log.Logger, kind model.LeaseKind, peer string) func() {
+	// leases of kinds without a limit are always granted, there's no need to ask for them
+	limit := currentLeaseLimits(ctx).limit(kind)
+	if limit <= 0 {
+		return func() {}
+	}
+
+	info := workflow.GetInfo(ctx)
+	request := &model.LeaseRequest{
+		ID:         GetUUID(ctx),
+		Kind:       kind,
+		Peer:       peer,
+		WorkflowID: info.WorkflowExecution.ID,
+		RunID:      info.WorkflowExecution.RunID,
+	}
+	release := func() {
+		releaseCtx, cancel := workflow.NewDisconnectedContext(ctx)
+		defer cancel()
+		if err := model.LeaseReleaseSignal.SignalExternalWorkflow(
+			releaseCtx, shared.SchedulerWorkflowID, "", request,
+		).Get(releaseCtx, nil); err != nil {
+			logger.Warn("failed to release lease", slog.String("kind", string(kind)), slog.Any("error", err))
+		}
+	}
+
+	grants := model.LeaseGrantedSignal.GetSignalChannel(ctx)
+	waitingSince := workflow.Now(ctx)
+	for ctx.Err() == nil {
+		if err := model.LeaseRequestSignal.SignalExternalWorkflow(
+			ctx, shared.SchedulerWorkflowID, "", request,
+		).Get(ctx, nil); err != nil {
+			logger.Warn("scheduler unavailable, continuing without a lease",
+				slog.String("kind", string(kind)), slog.Any("error", err))
+			return func() {}
+		}
+		for {
+			requestID, ok, _ := grants.ReceiveWithTimeout(ctx, leaseRequestInterval)
+			if !ok {
+				break
+			} else if requestID == request.ID {
+				logger.Info("acquired lease", slog.String("kind", string(kind)),
+					slog.Duration("waited", workflow.Now(ctx).Sub(waitingSince)))
+				return release
+			}
+			// grants of earlier requests answered again are ignored
+		}
+		if ctx.Err() == nil {
+			logger.Info("waiting for lease", slog.String("kind", string(kind)), slog.String("peer", peer))
+		}
+	}
+	// canceled while waiting, the request is withdrawn
+	release()
+	return func() {}
+}
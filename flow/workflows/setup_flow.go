@@ -13,6 +13,7 @@ import (
 
 	"github.com/PeerDB-io/peer-flow/activities"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/shared"
 )
 
@@ -155,7 +156,7 @@ func (s *SetupFlowExecution) createRawTable(
 	// attempt to create the tables.
 	createRawTblInput := &protos.CreateRawTableInput{
 		PeerConnectionConfig: config.Destination,
-		FlowJobName:          s.cdcFlowName,
+		FlowJobName:          config.FlowJobName,
 		TableNameMapping:     s.tableNameMapping,
 	}
 
@@ -207,29 +208,61 @@ func (s *SetupFlowExecution) fetchTableSchemaAndSetupNormalizedTables(
 		normalizedTableName := s.tableNameMapping[srcTableName]
 		for _, mapping := range flowConnectionConfigs.TableMappings {
 			if mapping.SourceTableIdentifier == srcTableName {
-				if len(mapping.Exclude) != 0 {
+				if model.ColumnsFiltered(mapping) {
 					columnCount := len(tableSchema.Columns)
 					columns := make([]*protos.FieldDescription, 0, columnCount)
 					for _, column := range tableSchema.Columns {
-						if !slices.Contains(mapping.Exclude, column.Name) {
+						if !model.ColumnExcluded(mapping, column.Name) {
 							columns = append(columns, column)
 						}
 					}
+					pkeyColumns := tableSchema.PrimaryKeyColumns
+					if len(mapping.Include) != 0 && !tableSchema.SyntheticPrimaryKey {
+						for _, pkeyColumn := range pkeyColumns {
+							if !slices.Contains(mapping.Include, pkeyColumn) {
+								return nil, fmt.Errorf("primary key column %s of table %s is missing from its include list",
+									pkeyColumn, srcTableName)
+							}
+						}
+					}
+					if tableSchema.SyntheticPrimaryKey {
+						// rows are matched on all columns, which only includes the mirrored ones
+						pkeyColumns = make([]string, 0, len(columns))
+						for _, column := range columns {
+							pkeyColumns = append(pkeyColumns, column.Name)
+						}
+					}
 					tableSchema = &protos.TableSchema{
 						TableIdentifier:       tableSchema.TableIdentifier,
-						PrimaryKeyColumns:     tableSchema.PrimaryKeyColumns,
+						PrimaryKeyColumns:     pkeyColumns,
 						IsReplicaIdentityFull: tableSchema.IsReplicaIdentityFull,
 						Columns:               columns,
+						SyntheticPrimaryKey:   tableSchema.SyntheticPrimaryKey,
 					}
 				}
+				maskedSchema, err := model.NewColumnMasks(mapping.Masks).MaskTableSchema(tableSchema)
+				if err != nil {
+					return nil, err
+				}
+				tableSchema = maskedSchema
 				break
 			}
 		}
+		tableSchema = model.NumericOverflowTableSchema(tableSchema,
+			flowConnectionConfigs.Destination.Type, flowConnectionConfigs.NumericOverflowPolicy)
 		normalizedTableMapping[normalizedTableName] = tableSchema
 
 		s.logger.Info("normalized table schema: ", normalizedTableName, " -> ", tableSchema)
 	}
 
+	if flowConnectionConfigs.TransformScript != "" {
+		// tables the transformation script changes the columns of are created with the columns it declares
+		future = workflow.ExecuteActivity(ctx, flowable.TransformTableSchemas, flowConnectionConfigs, normalizedTableMapping)
+		if err := future.Get(ctx, &normalizedTableMapping); err != nil {
+			return nil, fmt.Errorf("failed to transform table schemas: %w", err)
+		}
+	}
+
 	// now setup the normalized tables on the destination peer
 	setupConfig := &protos.SetupNormalizedTableBatchInput{
 		PeerConnectionConfig:   flowConnectionConfigs.Destination,
@@ -237,12 +270,23 @@ func (s *SetupFlowExecution) fetchTableSchemaAndSetupNormalizedTables(
 		SoftDeleteColName:      flowConnectionConfigs.SoftDeleteColName,
 		SyncedAtColName:        flowConnectionConfigs.SyncedAtColName,
 		FlowName:               flowConnectionConfigs.FlowJobName,
+		TableMappings:          flowConnectionConfigs.TableMappings,
+		SourcePeer:             flowConnectionConfigs.Source,
 	}
 
-	future = workflow.ExecuteActivity(ctx, flowable.CreateNormalizedTable, setupConfig)
-	if err := future.Get(ctx, nil); err != nil {
-		s.logger.Error("failed to create normalized tables: ", err)
-		return nil, fmt.Errorf("failed to create normalized tables: %w", err)
+	// adopted tables already hold the data, they're only checked to be compatible
+	if flowConnectionConfigs.AdoptExistingTables {
+		future = workflow.ExecuteActivity(ctx, flowable.AdoptNormalizedTables, setupConfig)
+		if err := future.Get(ctx, nil); err != nil {
+			s.logger.Error("failed to adopt normalized tables: ", err)
+			return nil, fmt.Errorf("failed to adopt normalized tables: %w", err)
+		}
+	} else {
+		future = workflow.ExecuteActivity(ctx, flowable.CreateNormalizedTable, setupConfig)
+		if err := future.Get(ctx, nil); err != nil {
+			s.logger.Error("failed to create normalized tables: ", err)
+			return nil, fmt.Errorf("failed to create normalized tables: %w", err)
+		}
 	}
 
 	s.logger.Info("finished setting up normalized tables for peer flow")
@@ -283,6 +327,22 @@ func (s *SetupFlowExecution) executeSetupFlow(
 	}
 	setupFlowOutput.TableNameSchemaMapping = tableNameSchemaMapping
 
+	// fan-out destinations get metadata, raw and normalized tables of their own
+	for _, fanoutConfig := range model.FanoutConfigs(config) {
+		if err := s.checkConnectionsAndSetupMetadataTables(ctx, fanoutConfig); err != nil {
+			return nil, fmt.Errorf("failed to set up fan-out destination %s: %w", fanoutConfig.Destination.Name, err)
+		}
+		if !config.InitialSnapshotOnly {
+			if err := s.createRawTable(ctx, fanoutConfig); err != nil {
+				return nil, fmt.Errorf("failed to create raw table on fan-out destination %s: %w", fanoutConfig.Destination.Name, err)
+			}
+		}
+		if _, err := s.fetchTableSchemaAndSetupNormalizedTables(ctx, fanoutConfig); err != nil {
+			return nil, fmt.Errorf("failed to set up normalized tables on fan-out destination %s: %w",
+				fanoutConfig.Destination.Name, err)
+		}
+	}
+
 	return &setupFlowOutput, nil
 }
 
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log/slog"
 	"regexp"
-	"slices"
 	"strings"
 	"time"
 
@@ -16,6 +15,7 @@ import (
 	connpostgres "github.com/PeerDB-io/peer-flow/connectors/postgres"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/shared"
 )
 
@@ -51,6 +51,9 @@ func (s *SnapshotFlowExecution) setupReplication(
 		DoInitialSnapshot:           s.config.DoInitialSnapshot,
 		ExistingPublicationName:     s.config.PublicationName,
 		ExistingReplicationSlotName: s.config.ReplicationSlotName,
+		TwoPhaseCommit:              s.config.TwoPhaseCommit,
+		TableMappings:               s.config.TableMappings,
+		PublicationColumnLists:      s.config.PublicationColumnLists,
 	}
 
 	res := &protos.SetupReplicationOutput{}
@@ -87,9 +90,10 @@ func (s *SnapshotFlowExecution) cloneTable(
 	ctx workflow.Context,
 	boundSelector *concurrency.BoundSelector,
 	snapshotName string,
+	dstConfig *protos.FlowConnectionConfigs,
 	mapping *protos.TableMapping,
 ) error {
-	flowName := s.config.FlowJobName
+	flowName := dstConfig.FlowJobName
 	cloneLog := slog.Group("clone-log",
 		slog.String(string(shared.FlowNameKey), flowName),
 		slog.String("snapshotName", snapshotName))
@@ -105,7 +109,8 @@ func (s *SnapshotFlowExecution) cloneTable(
 	s.logger.Info(fmt.Sprintf("Obtained child id %s for source table %s and destination table %s",
 		childWorkflowID, srcName, dstName), cloneLog)
 
-	taskQueue, queueErr := shared.GetPeerFlowTaskQueueName(shared.PeerFlowTaskQueueID)
+	// the snapshot flow runs on the snapshot worker, table copies go back to the mirror's own task queue
+	taskQueue, queueErr := shared.GetMirrorTaskQueueName(s.config.TaskQueue)
 	if queueErr != nil {
 		return queueErr
 	}
@@ -132,12 +137,12 @@ func (s *SnapshotFlowExecution) cloneTable(
 		return fmt.Errorf("unable to parse source table: %w", err)
 	}
 	from := "*"
-	if len(mapping.Exclude) != 0 {
+	if model.ColumnsFiltered(mapping) {
 		for _, v := range s.tableNameSchemaMapping {
 			if v.TableIdentifier == srcName {
 				quotedColumns := make([]string, 0, len(v.Columns))
 				for _, col := range v.Columns {
-					if !slices.Contains(mapping.Exclude, col.Name) {
+					if !model.ColumnExcluded(mapping, col.Name) {
 						quotedColumns = append(quotedColumns, connpostgres.QuoteIdentifier(col.Name))
 					}
 				}
@@ -163,7 +168,7 @@ func (s *SnapshotFlowExecution) cloneTable(
 	config := &protos.QRepConfig{
 		FlowJobName:                childWorkflowID,
 		SourcePeer:                 sourcePostgres,
-		DestinationPeer:            s.config.Destination,
+		DestinationPeer:            dstConfig.Destination,
 		Query:                      query,
 		WatermarkColumn:            partitionCol,
 		WatermarkTable:             srcName,
@@ -172,8 +177,16 @@ func (s *SnapshotFlowExecution) cloneTable(
 		NumRowsPerPartition:        numRowsPerPartition,
 		MaxParallelWorkers:         numWorkers,
 		StagingPath:                s.config.SnapshotStagingPath,
+		NativeImport:               s.config.SnapshotNativeImport,
+		PartitionMode:              s.config.SnapshotPartitionMode,
 		SyncedAtColName:            s.config.SyncedAtColName,
 		SoftDeleteColName:          s.config.SoftDeleteColName,
+		RowFilter:                  mapping.RowFilter,
+		Masks:                      mapping.Masks,
+		TaskQueue:                  s.config.TaskQueue,
+		NumericOverflowPolicy:      s.config.NumericOverflowPolicy,
+		GeoFormat:                  s.config.GeoFormat,
+		TransformScript:            s.config.TransformScript,
 		WriteMode: &protos.QRepWriteMode{
 			WriteType: protos.QRepWriteType_QREP_WRITE_MODE_APPEND,
 		},
@@ -194,19 +207,24 @@ func (s *SnapshotFlowExecution) cloneTables(
 
 	boundSelector := concurrency.NewBoundSelector(maxParallelClones)
 
-	for _, v := range s.config.TableMappings {
-		source := v.SourceTableIdentifier
-		destination := v.DestinationTableIdentifier
-		snapshotName := slotInfo.SnapshotName
-		s.logger.Info(fmt.Sprintf(
-			"Cloning table with source table %s and destination table name %s",
-			source, destination),
-			slog.String("snapshotName", snapshotName),
-		)
-		err := s.cloneTable(ctx, boundSelector, snapshotName, v)
-		if err != nil {
-			s.logger.Error("failed to start clone child workflow: ", err)
-			continue
+	// fan-out destinations are loaded from the same snapshot, consistent with the slot they are synced from
+	dstConfigs := append([]*protos.FlowConnectionConfigs{s.config}, model.FanoutConfigs(s.config)...)
+	for _, dstConfig := range dstConfigs {
+		for _, v := range s.config.TableMappings {
+			source := v.SourceTableIdentifier
+			destination := v.DestinationTableIdentifier
+			snapshotName := slotInfo.SnapshotName
+			s.logger.Info(fmt.Sprintf(
+				"Cloning table with source table %s and destination table name %s",
+				source, destination),
+				slog.String("snapshotName", snapshotName),
+				slog.String("destinationPeer", dstConfig.Destination.Name),
+			)
+			err := s.cloneTable(ctx, boundSelector, snapshotName, dstConfig, v)
+			if err != nil {
+				s.logger.Error("failed to start clone child workflow: ", err)
+				continue
+			}
 		}
 	}
 
@@ -0,0 +1,76 @@
+package peerflow
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// ValidateMirrorWorkflow compares the tables of a CDC mirror with their source tables one after another,
+// recording the drift found for each table in the catalog as it goes. Tables failing to validate are recorded
+// with the error, and don't stop the rest from being validated.
+func ValidateMirrorWorkflow(ctx workflow.Context, input *protos.ValidateMirrorInput) error {
+	ctx = workflow.WithValue(ctx, shared.FlowNameKey, input.Config.FlowJobName)
+	logger := log.With(workflow.GetLogger(ctx),
+		slog.String(string(shared.FlowNameKey), input.Config.FlowJobName), slog.String("validationID", input.ValidationId))
+
+	validateCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 24 * time.Hour,
+		HeartbeatTimeout:    time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+	recordCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+
+	for _, tableMapping := range input.Config.TableMappings {
+		if len(input.SourceTableIdentifiers) != 0 &&
+			!slices.Contains(input.SourceTableIdentifiers, tableMapping.SourceTableIdentifier) {
+			continue
+		}
+
+		var result *protos.TableValidationResult
+		if err := workflow.ExecuteActivity(validateCtx, flowable.ValidateMirrorTable,
+			input, tableMapping).Get(validateCtx, &result); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Warn("failed to validate table",
+				slog.String("table", tableMapping.SourceTableIdentifier), slog.Any("error", err))
+			var appErr *temporal.ApplicationError
+			if errors.As(err, &appErr) {
+				err = appErr
+			}
+			result = &protos.TableValidationResult{
+				SourceTableIdentifier:      tableMapping.SourceTableIdentifier,
+				DestinationTableIdentifier: tableMapping.DestinationTableIdentifier,
+				NumChunks:                  input.NumChunks,
+				Error:                      err.Error(),
+				ValidatedAt:                timestamppb.New(workflow.Now(ctx)),
+			}
+		} else if result.MismatchedChunks != 0 || result.SourceRows != result.DestinationRows {
+			logger.Warn("table drifted from its source", slog.String("table", tableMapping.SourceTableIdentifier),
+				slog.Int64("sourceRows", result.SourceRows), slog.Int64("destinationRows", result.DestinationRows),
+				slog.Int("mismatchedChunks", int(result.MismatchedChunks)))
+		}
+
+		if err := workflow.ExecuteActivity(recordCtx, flowable.RecordTableValidation,
+			input.ValidationId, input.Config.FlowJobName, result).Get(recordCtx, nil); err != nil {
+			return fmt.Errorf("failed to record validation of table %s: %w", tableMapping.SourceTableIdentifier, err)
+		}
+	}
+	logger.Info("mirror validated")
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"time"
 
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
@@ -45,6 +46,20 @@ func XminFlowWorkflow(
 		return err
 	}
 
+	if lastXmin := state.LastPartition.GetRange().GetIntRange(); lastXmin != nil {
+		xminLagCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: 5 * time.Minute,
+			RetryPolicy: &temporal.RetryPolicy{
+				MaximumAttempts: 3,
+			},
+		})
+		// lag only informs the status, syncs check for wraparound themselves
+		if err := workflow.ExecuteActivity(xminLagCtx, flowable.XminLag, q.config, lastXmin.Start).
+			Get(ctx, &state.XminLag); err != nil {
+			logger.Warn("failed to get xmin lag", slog.Any("error", err))
+		}
+	}
+
 	var lastPartition int64
 	replicateXminPartitionCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: 24 * 5 * time.Hour,
@@ -108,5 +123,5 @@ func XminFlowWorkflow(
 		return err
 	}
 	// Continue the workflow with new state
-	return workflow.NewContinueAsNewError(ctx, XminFlowWorkflow, config, state)
+	return workflow.NewContinueAsNewError(onDefaultBuild(ctx), XminFlowWorkflow, config, state)
 }
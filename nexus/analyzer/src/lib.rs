@@ -10,8 +10,10 @@ use anyhow::Context;
 use pt::{
     flow_model::{FlowJob, FlowJobTableMapping, QRepFlowJob},
     peerdb_peers::{
-        peer::Config, BigqueryConfig, ClickhouseConfig, DbType, EventHubConfig, MongoConfig, Peer,
-        PostgresConfig, S3Config, SnowflakeConfig, SqlServerConfig,
+        peer::Config, AwsRdsIamAuth, BigqueryConfig, ClickhouseConfig, ConnectionPoolConfig,
+        DbType, EventHubConfig, MongoConfig, ParquetCompression, Peer, PostgresConfig, ProxyConfig,
+        S3Config, S3Format, SnowflakeConfig, SqlServerConfig, TableCatalogConfig, TableCatalogType,
+        TlsConfig,
     },
 };
 use qrep::process_options;
@@ -292,6 +294,13 @@ impl<'a> StatementAnalyzer for PeerDDLAnalyzer<'a> {
                             _ => None,
                         };
 
+                        let max_batch_bytes: Option<u64> = match raw_options
+                            .remove("max_batch_bytes")
+                        {
+                            Some(sqlparser::ast::Value::Number(n, _)) => Some(n.parse::<u64>()?),
+                            _ => None,
+                        };
+
                         let soft_delete_col_name: Option<String> = match raw_options
                             .remove("soft_delete_col_name")
                         {
@@ -329,6 +338,7 @@ impl<'a> StatementAnalyzer for PeerDDLAnalyzer<'a> {
                             push_batch_size,
                             push_parallelism,
                             max_batch_size,
+                            max_batch_bytes,
                             resync,
                             soft_delete_col_name,
                             synced_at_col_name,
@@ -512,6 +522,31 @@ fn parse_db_options(
     }
 
     let config = match db_type {
+        DbType::Bigquery if opts.get("type") == Some(&"application_default") => {
+            // credentials come from the environment, no service account key needed
+            let bq_config = BigqueryConfig {
+                auth_type: "application_default".to_string(),
+                project_id: opts
+                    .get("project_id")
+                    .ok_or_else(|| anyhow::anyhow!("missing project_id in peer options"))?
+                    .to_string(),
+                dataset_id: opts
+                    .get("dataset_id")
+                    .ok_or_else(|| anyhow::anyhow!("missing dataset_id in peer options"))?
+                    .to_string(),
+                location: opts
+                    .get("location")
+                    .map(|s| s.to_string())
+                    .unwrap_or_default(),
+                kms_key_name: opts
+                    .get("kms_key_name")
+                    .map(|s| s.to_string())
+                    .unwrap_or_default(),
+                ..Default::default()
+            };
+            let config = Config::BigqueryConfig(bq_config);
+            Some(config)
+        }
         DbType::Bigquery => {
             let pem_str = opts
                 .get("private_key")
@@ -564,6 +599,14 @@ fn parse_db_options(
                     .get("dataset_id")
                     .ok_or_else(|| anyhow::anyhow!("missing dataset_id in peer options"))?
                     .to_string(),
+                location: opts
+                    .get("location")
+                    .map(|s| s.to_string())
+                    .unwrap_or_default(),
+                kms_key_name: opts
+                    .get("kms_key_name")
+                    .map(|s| s.to_string())
+                    .unwrap_or_default(),
             };
             let config = Config::BigqueryConfig(bq_config);
             Some(config)
@@ -604,6 +647,10 @@ fn parse_db_options(
                 password: opts.get("password").map(|s| s.to_string()),
                 metadata_schema: opts.get("metadata_schema").map(|s| s.to_string()),
                 s3_integration: s3_int,
+                ssh_config: None,
+                proxy_config: parse_proxy_options(&opts)?,
+                tls_config: parse_tls_options(&opts),
+                pool_config: parse_pool_options(&opts)?,
             };
             let config = Config::SnowflakeConfig(snowflake_config);
             Some(config)
@@ -636,6 +683,15 @@ fn parse_db_options(
             Some(config)
         }
         DbType::Postgres => {
+            // an empty region defaults to AWS_REGION
+            let aws_rds_iam_auth = opts.get("aws_rds_iam_region").map(|region| AwsRdsIamAuth {
+                region: region.to_string(),
+            });
+            let password = match opts.get("password") {
+                Some(password) => password.to_string(),
+                None if aws_rds_iam_auth.is_some() => String::new(),
+                None => anyhow::bail!("no password specified"),
+            };
             let postgres_config = PostgresConfig {
                 host: opts.get("host").context("no host specified")?.to_string(),
                 port: opts
@@ -647,10 +703,7 @@ fn parse_db_options(
                     .get("user")
                     .context("no username specified")?
                     .to_string(),
-                password: opts
-                    .get("password")
-                    .context("no password specified")?
-                    .to_string(),
+                password,
                 database: opts
                     .get("database")
                     .context("no default database specified")?
@@ -658,6 +711,12 @@ fn parse_db_options(
                 metadata_schema: opts.get("metadata_schema").map(|s| s.to_string()),
                 transaction_snapshot: "".to_string(),
                 ssh_config: None,
+                primary: None,
+                load_throttle: None,
+                aws_rds_iam_auth,
+                proxy_config: parse_proxy_options(&opts)?,
+                tls_config: parse_tls_options(&opts),
+                pool_config: parse_pool_options(&opts)?,
             };
             let config = Config::PostgresConfig(postgres_config);
             Some(config)
@@ -713,6 +772,40 @@ fn parse_db_options(
                 region: opts.get("region").map(|s| s.to_string()),
                 role_arn: opts.get("role_arn").map(|s| s.to_string()),
                 endpoint: opts.get("endpoint").map(|s| s.to_string()),
+                format: match opts.get("format").map(|s| s.to_lowercase()).as_deref() {
+                    None | Some("avro") => S3Format::Avro,
+                    Some("parquet") => S3Format::Parquet,
+                    Some(format) => anyhow::bail!("unsupported S3 format: {}", format),
+                } as i32,
+                parquet_compression: match opts
+                    .get("parquet_compression")
+                    .map(|s| s.to_lowercase())
+                    .as_deref()
+                {
+                    None | Some("snappy") => ParquetCompression::Snappy,
+                    Some("zstd") => ParquetCompression::Zstd,
+                    Some("none") => ParquetCompression::None,
+                    Some(compression) => {
+                        anyhow::bail!("unsupported parquet compression: {}", compression)
+                    }
+                } as i32,
+                key_template: opts
+                    .get("key_template")
+                    .map(|s| s.to_string())
+                    .unwrap_or_default(),
+                target_file_size_bytes: opts
+                    .get("target_file_size_bytes")
+                    .map(|s| s.parse::<u64>())
+                    .transpose()
+                    .context("unable to parse target_file_size_bytes as valid int")?
+                    .unwrap_or_default(),
+                max_rows_per_file: opts
+                    .get("max_rows_per_file")
+                    .map(|s| s.parse::<u32>())
+                    .transpose()
+                    .context("unable to parse max_rows_per_file as valid int")?
+                    .unwrap_or_default(),
+                table_catalog: parse_table_catalog_options(&opts)?,
             };
             let config = Config::S3Config(s3_config);
             Some(config)
@@ -735,6 +828,9 @@ fn parse_db_options(
                     .get("database")
                     .context("database is not specified")?
                     .to_string(),
+                ssh_config: None,
+                proxy_config: parse_proxy_options(&opts)?,
+                pool_config: parse_pool_options(&opts)?,
             };
             let config = Config::SqlserverConfig(sqlserver_config);
             Some(config)
@@ -817,7 +913,23 @@ fn parse_db_options(
                 disable_tls: opts
                     .get("disable_tls")
                     .and_then(|s| s.parse::<bool>().ok())
-                    .unwrap_or_default()
+                    .unwrap_or_default(),
+                cluster: opts
+                    .get("cluster")
+                    .map(|s| s.to_string())
+                    .unwrap_or_default(),
+                replicated: opts
+                    .get("replicated")
+                    .and_then(|s| s.parse::<bool>().ok())
+                    .unwrap_or_default(),
+                distributed: opts
+                    .get("distributed")
+                    .and_then(|s| s.parse::<bool>().ok())
+                    .unwrap_or_default(),
+                ssh_config: None,
+                proxy_config: parse_proxy_options(&opts)?,
+                tls_config: parse_tls_options(&opts),
+                pool_config: parse_pool_options(&opts)?,
             };
             let config = Config::ClickhouseConfig(clickhouse_config);
             Some(config)
@@ -826,3 +938,94 @@ fn parse_db_options(
 
     Ok(config)
 }
+
+// a SOCKS5 proxy to reach the peer through, set with proxy_host and proxy_port
+fn parse_proxy_options(opts: &HashMap<&str, &str>) -> anyhow::Result<Option<ProxyConfig>> {
+    let Some(host) = opts.get("proxy_host") else {
+        return Ok(None);
+    };
+    Ok(Some(ProxyConfig {
+        host: host.to_string(),
+        port: opts
+            .get("proxy_port")
+            .context("no proxy_port specified")?
+            .parse::<u32>()
+            .context("unable to parse proxy_port as valid int")?,
+        user: opts
+            .get("proxy_user")
+            .map(|s| s.to_string())
+            .unwrap_or_default(),
+        password: opts
+            .get("proxy_password")
+            .map(|s| s.to_string())
+            .unwrap_or_default(),
+    }))
+}
+
+// TLS settings from tls_root_ca, tls_client_cert, tls_client_key, tls_min_version and tls_server_name
+fn parse_tls_options(opts: &HashMap<&str, &str>) -> Option<TlsConfig> {
+    let option = |name: &str| opts.get(name).map(|s| s.to_string()).unwrap_or_default();
+    let tls_config = TlsConfig {
+        root_ca: option("tls_root_ca"),
+        client_cert: option("tls_client_cert"),
+        client_key: option("tls_client_key"),
+        min_version: option("tls_min_version"),
+        server_name: option("tls_server_name"),
+    };
+    if tls_config == TlsConfig::default() {
+        None
+    } else {
+        Some(tls_config)
+    }
+}
+
+// connection pool settings from pool_max_open, pool_max_idle, pool_max_lifetime_seconds and pool_ping_on_acquire
+fn parse_pool_options(opts: &HashMap<&str, &str>) -> anyhow::Result<Option<ConnectionPoolConfig>> {
+    let count = |name: &str| -> anyhow::Result<u32> {
+        opts.get(name)
+            .map(|s| s.parse::<u32>())
+            .transpose()
+            .with_context(|| format!("unable to parse {} as valid int", name))
+            .map(Option::unwrap_or_default)
+    };
+    let pool_config = ConnectionPoolConfig {
+        max_open_connections: count("pool_max_open")?,
+        max_idle_connections: count("pool_max_idle")?,
+        max_connection_lifetime_seconds: count("pool_max_lifetime_seconds")?,
+        ping_on_acquire: opts
+            .get("pool_ping_on_acquire")
+            .and_then(|s| s.parse::<bool>().ok())
+            .unwrap_or_default(),
+    };
+    if pool_config == ConnectionPoolConfig::default() {
+        Ok(None)
+    } else {
+        Ok(Some(pool_config))
+    }
+}
+
+fn parse_table_catalog_options(
+    opts: &HashMap<&str, &str>,
+) -> anyhow::Result<Option<TableCatalogConfig>> {
+    let catalog_type = match opts
+        .get("table_catalog")
+        .map(|s| s.to_lowercase())
+        .as_deref()
+    {
+        None | Some("none") => return Ok(None),
+        Some("glue") => TableCatalogType::TableCatalogGlue,
+        Some("hive") => TableCatalogType::TableCatalogHive,
+        Some(catalog) => anyhow::bail!("unsupported table catalog: {}", catalog),
+    };
+    let option = |name: &str| opts.get(name).map(|s| s.to_string()).unwrap_or_default();
+    Ok(Some(TableCatalogConfig {
+        r#type: catalog_type as i32,
+        database: opts
+            .get("catalog_database")
+            .context("catalog_database not specified")?
+            .to_string(),
+        glue_region: option("glue_region"),
+        glue_catalog_id: option("glue_catalog_id"),
+        hive_metastore_address: option("hive_metastore_address"),
+    }))
+}
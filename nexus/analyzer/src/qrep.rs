@@ -54,12 +54,21 @@ const QREP_OPTIONS: &[QRepOptionType] = &[
     QRepOptionType::StringArray {
         name: "unique_key_columns",
     },
+    QRepOptionType::StringArray {
+        name: "watermark_columns",
+    },
     QRepOptionType::String {
         name: "staging_path",
         default_val: Some(""),
         required: false,
         accepted_values: None,
     },
+    QRepOptionType::String {
+        name: "row_filter",
+        default_val: None,
+        required: false,
+        accepted_values: None,
+    },
     QRepOptionType::Int {
         name: "parallelism",
         min_value: Some(1),
@@ -78,6 +87,24 @@ const QREP_OPTIONS: &[QRepOptionType] = &[
         default_value: 50000,
         required: true,
     },
+    QRepOptionType::Int {
+        name: "max_rows_per_second",
+        min_value: None,
+        default_value: 0,
+        required: false,
+    },
+    QRepOptionType::Int {
+        name: "max_bytes_per_second",
+        min_value: None,
+        default_value: 0,
+        required: false,
+    },
+    QRepOptionType::Int {
+        name: "throttle_burst_seconds",
+        min_value: None,
+        default_value: 0,
+        required: false,
+    },
     QRepOptionType::Boolean {
         name: "initial_copy_only",
         default_value: false,
@@ -93,6 +120,11 @@ const QREP_OPTIONS: &[QRepOptionType] = &[
         default_value: false,
         required: false,
     },
+    QRepOptionType::Boolean {
+        name: "native_import",
+        default_value: false,
+        required: false,
+    },
 ];
 
 pub fn process_options(
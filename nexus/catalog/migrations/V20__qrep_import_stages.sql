@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS metadata_qrep_import_stages (
+    job_name TEXT NOT NULL,
+    partition_id TEXT NOT NULL,
+    object_path TEXT NOT NULL,
+    num_records BIGINT NOT NULL,
+    exported_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    imported_at TIMESTAMPTZ,
+    PRIMARY KEY (job_name, partition_id)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS peerdb_stats.peer_credential_expiry (
+    peer_name TEXT NOT NULL,
+    credential TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (peer_name, credential)
+);
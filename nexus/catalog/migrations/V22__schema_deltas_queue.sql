@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS schema_deltas_queue (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    flow_job_name TEXT NOT NULL,
+    delta_info JSONB NOT NULL,
+    queued_at TIMESTAMP NOT NULL DEFAULT now(),
+    approved_at TIMESTAMP,
+    applied_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_schema_deltas_queue_flow_job_name
+ON schema_deltas_queue (flow_job_name) WHERE applied_at IS NULL;
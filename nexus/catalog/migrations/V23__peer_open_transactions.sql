@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS peerdb_stats.peer_open_transactions (
+    peer_name TEXT PRIMARY KEY,
+    pid INTEGER NOT NULL,
+    database_name TEXT,
+    user_name TEXT,
+    state TEXT,
+    query TEXT,
+    xact_start TIMESTAMPTZ NOT NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
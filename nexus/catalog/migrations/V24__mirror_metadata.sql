@@ -0,0 +1,2 @@
+ALTER TABLE flows
+ADD COLUMN IF NOT EXISTS mirror_metadata JSONB;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS prepared_transactions (
+    flow_job_name TEXT NOT NULL,
+    gid TEXT NOT NULL,
+    xid BIGINT NOT NULL,
+    prepare_lsn BIGINT NOT NULL,
+    records BYTEA NOT NULL,
+    prepared_at TIMESTAMPTZ NOT NULL,
+    finished_lsn BIGINT,
+    PRIMARY KEY (flow_job_name, gid)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS mirror_groups (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- a mirror belongs to at most one group
+CREATE TABLE IF NOT EXISTS mirror_group_members (
+    group_name TEXT NOT NULL REFERENCES mirror_groups(name) ON DELETE CASCADE,
+    flow_job_name TEXT NOT NULL UNIQUE,
+    PRIMARY KEY (group_name, flow_job_name)
+);
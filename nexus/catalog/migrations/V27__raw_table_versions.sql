@@ -0,0 +1,6 @@
+-- layout version of each CDC mirror's raw table, mirrors without a row predate versioning
+CREATE TABLE IF NOT EXISTS raw_table_versions (
+    flow_job_name TEXT PRIMARY KEY,
+    version INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,16 @@
+ALTER TABLE peerdb_stats.qrep_partitions
+ADD COLUMN bytes_synced BIGINT;
+
+CREATE TABLE IF NOT EXISTS peerdb_stats.qrep_run_summaries (
+    run_uuid TEXT PRIMARY KEY,
+    flow_name TEXT NOT NULL,
+    partitions_processed INTEGER NOT NULL,
+    rows_synced BIGINT NOT NULL,
+    bytes_synced BIGINT NOT NULL,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    warnings TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_qrep_run_summaries_flow_name_end_time
+ON peerdb_stats.qrep_run_summaries(flow_name, end_time DESC);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS peerdb_stats.cdc_batch_rollups (
+    flow_name TEXT NOT NULL,
+    granularity TEXT NOT NULL CHECK (granularity IN ('hour', 'day')),
+    bucket_start TIMESTAMP NOT NULL,
+    num_batches BIGINT NOT NULL,
+    rows_synced BIGINT NOT NULL,
+    sync_seconds DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (flow_name, granularity, bucket_start)
+);
+
+CREATE TABLE IF NOT EXISTS peerdb_stats.cdc_table_rollups (
+    flow_name TEXT NOT NULL,
+    destination_table_name TEXT NOT NULL,
+    granularity TEXT NOT NULL CHECK (granularity IN ('hour', 'day')),
+    bucket_start TIMESTAMP NOT NULL,
+    rows_synced BIGINT NOT NULL,
+    PRIMARY KEY (flow_name, granularity, bucket_start, destination_table_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cdc_batches_flow_name_end_time
+ON peerdb_stats.cdc_batches(flow_name, end_time);
+
+CREATE INDEX IF NOT EXISTS idx_cdc_batch_table_flow_name_batch_id
+ON peerdb_stats.cdc_batch_table(flow_name, batch_id);
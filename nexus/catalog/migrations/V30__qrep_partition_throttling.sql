@@ -0,0 +1,2 @@
+ALTER TABLE peerdb_stats.qrep_partitions
+ADD COLUMN throttled_ms BIGINT;
@@ -0,0 +1,7 @@
+-- batches each CDC mirror normalized and when, raw table rows are pruned once normalized for the retention period
+CREATE TABLE IF NOT EXISTS normalized_batches (
+    flow_job_name TEXT NOT NULL,
+    batch_id BIGINT NOT NULL,
+    normalized_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (flow_job_name, batch_id)
+);
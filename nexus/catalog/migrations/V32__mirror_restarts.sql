@@ -0,0 +1,9 @@
+-- restarts of failed mirrors by the supervisor, counted per failure of the mirror's workflow
+CREATE TABLE IF NOT EXISTS mirror_restarts (
+    flow_job_name TEXT PRIMARY KEY,
+    failed_run_id TEXT NOT NULL,
+    restarts INTEGER NOT NULL,
+    next_restart_at TIMESTAMPTZ NOT NULL,
+    restarted_at TIMESTAMPTZ,
+    gave_up BOOLEAN NOT NULL DEFAULT false
+);
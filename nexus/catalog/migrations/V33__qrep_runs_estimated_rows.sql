@@ -0,0 +1,3 @@
+-- rows of the watermark table estimated when a run copies it for the first time, for initial load progress
+ALTER TABLE peerdb_stats.qrep_runs
+ADD COLUMN estimated_rows BIGINT;
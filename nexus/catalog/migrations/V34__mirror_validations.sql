@@ -0,0 +1,16 @@
+-- drift reports of mirror validations, comparing row counts and chunked row hashes of each source and destination table
+CREATE TABLE IF NOT EXISTS mirror_validations (
+    validation_id TEXT NOT NULL,
+    flow_job_name TEXT NOT NULL,
+    source_table TEXT NOT NULL,
+    destination_table TEXT NOT NULL,
+    source_rows BIGINT NOT NULL,
+    destination_rows BIGINT NOT NULL,
+    num_chunks INTEGER NOT NULL,
+    mismatched_chunks INTEGER NOT NULL,
+    error TEXT,
+    validated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (validation_id, source_table)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mirror_validations_flow_job_name ON mirror_validations(flow_job_name, validated_at);
@@ -0,0 +1,6 @@
+-- state of QRep mirrors started by a Temporal Schedule, saved at the end of each run for the next run to resume from
+CREATE TABLE IF NOT EXISTS qrep_schedule_states (
+    flow_job_name TEXT PRIMARY KEY,
+    state_proto BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,8 @@
+ALTER TABLE peerdb_stats.peer_slot_size
+ADD COLUMN current_lsn TEXT,
+ADD COLUMN restart_lag_bytes BIGINT,
+ADD COLUMN confirmed_flush_lag_bytes BIGINT,
+ADD COLUMN confirmed_flush_lag_seconds DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_peer_slot_size_peer_slot_updated_at
+ON peerdb_stats.peer_slot_size (peer_name, slot_name, updated_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS peerdb_stats.audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    method TEXT NOT NULL,
+    caller TEXT NOT NULL,
+    caller_address TEXT,
+    request JSONB,
+    success BOOLEAN NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at
+ON peerdb_stats.audit_events (created_at);
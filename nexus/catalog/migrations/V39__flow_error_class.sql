@@ -0,0 +1,6 @@
+ALTER TABLE peerdb_stats.flow_errors
+ADD COLUMN error_class TEXT,
+ADD COLUMN error_code TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_flow_errors_flow_name_timestamp
+ON peerdb_stats.flow_errors (flow_name, error_timestamp);
@@ -0,0 +1,12 @@
+-- flow and snapshot workers, registered and kept up to date by their heartbeats
+CREATE TABLE IF NOT EXISTS flow_workers (
+    worker_id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    version TEXT NOT NULL,
+    build_id TEXT,
+    task_queues TEXT[] NOT NULL,
+    max_concurrent_activities INTEGER NOT NULL,
+    running_activities JSONB NOT NULL DEFAULT '[]',
+    started_at TIMESTAMPTZ NOT NULL,
+    last_heartbeat_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,7 @@
+-- per-mirror overrides of the catalog-wide settings in alerting_settings
+CREATE TABLE IF NOT EXISTS mirror_settings (
+    flow_name TEXT NOT NULL,
+    config_name TEXT NOT NULL,
+    config_value TEXT NOT NULL,
+    PRIMARY KEY (flow_name, config_name)
+);
@@ -0,0 +1,2 @@
+-- peers with an enc_key_id have their options encrypted under a data key, wrapped with that key in enc_data_key
+ALTER TABLE peers ADD COLUMN enc_key_id TEXT, ADD COLUMN enc_data_key BYTEA;
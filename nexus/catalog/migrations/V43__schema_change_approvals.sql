@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS schema_change_approvals (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    flow_job_name TEXT NOT NULL,
+    delta_info JSONB NOT NULL,
+    detected_at TIMESTAMP NOT NULL DEFAULT now(),
+    approved_at TIMESTAMP,
+    resolution TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_schema_change_approvals_flow_job_name
+ON schema_change_approvals (flow_job_name) WHERE approved_at IS NULL;
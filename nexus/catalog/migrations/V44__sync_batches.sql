@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS metadata_sync_batches (
+    job_name TEXT NOT NULL,
+    sync_batch_id BIGINT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    written_objects TEXT[] NOT NULL DEFAULT '{}',
+    last_offset BIGINT,
+    finished_at TIMESTAMPTZ,
+    PRIMARY KEY (job_name, sync_batch_id)
+);
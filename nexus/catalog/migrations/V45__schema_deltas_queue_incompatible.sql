@@ -0,0 +1,5 @@
+ALTER TABLE schema_deltas_queue
+ADD COLUMN IF NOT EXISTS incompatible BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS resolution TEXT;
+
+DROP TABLE IF EXISTS schema_change_approvals;
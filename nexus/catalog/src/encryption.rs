@@ -0,0 +1,134 @@
+//! Envelope encryption of peer configs in the catalog, matching the flow API's peer_encryption.go.
+//!
+//! When PEERDB_CATALOG_ENCRYPTION_KEYS is set, `options` holds the config encrypted with AES-256-GCM
+//! under a data key of its own, and `enc_data_key` holds that data key encrypted with the key named
+//! by `enc_key_id`. Both are prefixed by their nonce. Configs with a NULL `enc_key_id` are unencrypted.
+
+use anyhow::{anyhow, Context};
+use base64::{engine::general_purpose::STANDARD, Engine as _};
+use ring::{
+    aead::{Aad, LessSafeKey, Nonce, UnboundKey, AES_256_GCM, NONCE_LEN},
+    rand::{SecureRandom, SystemRandom},
+};
+
+const DATA_KEY_SIZE: usize = 32;
+
+pub struct EncryptionKey {
+    id: String,
+    key: LessSafeKey,
+}
+
+fn new_key(key: &[u8]) -> anyhow::Result<LessSafeKey> {
+    let unbound = UnboundKey::new(&AES_256_GCM, key).map_err(|_| anyhow!("invalid AES-256 key"))?;
+    Ok(LessSafeKey::new(unbound))
+}
+
+/// Parses `id:base64` pairs, separated by commas. The first key encrypts new configs.
+pub fn parse_encryption_keys(keys_env: &str) -> anyhow::Result<Vec<EncryptionKey>> {
+    if keys_env.is_empty() {
+        return Ok(Vec::new());
+    }
+    keys_env
+        .split(',')
+        .map(|key_pair| {
+            let (id, encoded_key) = key_pair
+                .trim()
+                .split_once(':')
+                .filter(|(id, _)| !id.is_empty())
+                .context("catalog encryption keys must be id:base64 pairs")?;
+            // like the flow API, AWS KMS keys are rejected until nexus and the UI can unwrap with them
+            if encoded_key.starts_with("kms:") {
+                return Err(anyhow!(
+                    "catalog encryption key {} is an AWS KMS key, which nexus can't unwrap data keys with",
+                    id
+                ));
+            }
+            let key = STANDARD
+                .decode(encoded_key)
+                .with_context(|| format!("failed to decode catalog encryption key {}", id))?;
+            if key.len() != DATA_KEY_SIZE {
+                return Err(anyhow!(
+                    "catalog encryption key {} must be {} bytes",
+                    id,
+                    DATA_KEY_SIZE
+                ));
+            }
+            Ok(EncryptionKey {
+                id: id.to_string(),
+                key: new_key(&key)?,
+            })
+        })
+        .collect()
+}
+
+pub fn encryption_keys_from_env() -> anyhow::Result<Vec<EncryptionKey>> {
+    parse_encryption_keys(&std::env::var("PEERDB_CATALOG_ENCRYPTION_KEYS").unwrap_or_default())
+}
+
+fn seal(key: &LessSafeKey, plaintext: &[u8]) -> anyhow::Result<Vec<u8>> {
+    let mut nonce = [0u8; NONCE_LEN];
+    SystemRandom::new()
+        .fill(&mut nonce)
+        .map_err(|_| anyhow!("failed to generate nonce"))?;
+    let mut in_out = plaintext.to_vec();
+    key.seal_in_place_append_tag(
+        Nonce::assume_unique_for_key(nonce),
+        Aad::empty(),
+        &mut in_out,
+    )
+    .map_err(|_| anyhow!("failed to encrypt"))?;
+    let mut sealed = nonce.to_vec();
+    sealed.extend_from_slice(&in_out);
+    Ok(sealed)
+}
+
+fn open(key: &LessSafeKey, sealed: &[u8]) -> anyhow::Result<Vec<u8>> {
+    if sealed.len() < NONCE_LEN {
+        return Err(anyhow!("ciphertext too short"));
+    }
+    let (nonce, ciphertext) = sealed.split_at(NONCE_LEN);
+    let nonce = Nonce::try_assume_unique_for_key(nonce).map_err(|_| anyhow!("invalid nonce"))?;
+    let mut in_out = ciphertext.to_vec();
+    let plaintext = key
+        .open_in_place(nonce, Aad::empty(), &mut in_out)
+        .map_err(|_| anyhow!("failed to decrypt"))?;
+    Ok(plaintext.to_vec())
+}
+
+/// Encrypts a serialized peer config under a new data key, wrapped with the first key.
+/// Returns the options, enc_key_id and enc_data_key to store, without keys the config is stored as is.
+pub fn encrypt_peer_options(
+    keys: &[EncryptionKey],
+    options: Vec<u8>,
+) -> anyhow::Result<(Vec<u8>, Option<String>, Option<Vec<u8>>)> {
+    let Some(current_key) = keys.first() else {
+        return Ok((options, None, None));
+    };
+    let mut data_key = [0u8; DATA_KEY_SIZE];
+    SystemRandom::new()
+        .fill(&mut data_key)
+        .map_err(|_| anyhow!("failed to generate data key"))?;
+    let enc_options =
+        seal(&new_key(&data_key)?, &options).context("failed to encrypt peer config")?;
+    let wrapped_key = seal(&current_key.key, &data_key).context("failed to wrap data key")?;
+    Ok((enc_options, Some(current_key.id.clone()), Some(wrapped_key)))
+}
+
+/// Returns the serialized peer config of a catalog row, decrypting it when it has a key id.
+pub fn decrypt_peer_options(
+    keys: &[EncryptionKey],
+    options: &[u8],
+    key_id: Option<&str>,
+    wrapped_key: Option<&[u8]>,
+) -> anyhow::Result<Vec<u8>> {
+    let Some(key_id) = key_id else {
+        return Ok(options.to_vec());
+    };
+    let key = keys
+        .iter()
+        .find(|key| key.id == key_id)
+        .with_context(|| format!("catalog encryption key {} is not configured", key_id))?;
+    let data_key = open(&key.key, wrapped_key.unwrap_or_default())
+        .with_context(|| format!("failed to unwrap data key with key {}", key_id))?;
+    open(&new_key(&data_key)?, options).context("failed to decrypt peer config")
+}
@@ -15,6 +15,8 @@ use serde_json::Value;
 use sqlparser::ast::Statement;
 use tokio_postgres::{types, Client};
 
+mod encryption;
+
 mod embedded {
     use refinery::embed_migrations;
     embed_migrations!("migrations");
@@ -22,6 +24,7 @@ mod embedded {
 
 pub struct Catalog {
     pg: Client,
+    encryption_keys: Vec<encryption::EncryptionKey>,
 }
 
 async fn run_migrations(client: &mut Client) -> anyhow::Result<()> {
@@ -67,6 +70,12 @@ impl<'a> CatalogConfig<'a> {
             transaction_snapshot: "".to_string(),
             metadata_schema: Some("".to_string()),
             ssh_config: None,
+            primary: None,
+            load_throttle: None,
+            aws_rds_iam_auth: None,
+            proxy_config: None,
+            tls_config: None,
+            pool_config: None,
         }
     }
 
@@ -78,7 +87,11 @@ impl<'a> CatalogConfig<'a> {
 impl Catalog {
     pub async fn new(pt_config: pt::peerdb_peers::PostgresConfig) -> anyhow::Result<Self> {
         let client = connect_postgres(&pt_config).await?;
-        Ok(Self { pg: client })
+        let encryption_keys = encryption::encryption_keys_from_env()?;
+        Ok(Self {
+            pg: client,
+            encryption_keys,
+        })
     }
 
     pub async fn run_migrations(&mut self) -> anyhow::Result<()> {
@@ -140,17 +153,34 @@ impl Catalog {
 
             buf
         };
+        let (config_blob, enc_key_id, enc_data_key) =
+            encryption::encrypt_peer_options(&self.encryption_keys, config_blob)?;
 
         let stmt = self
             .pg
             .prepare_typed(
-                "INSERT INTO peers (name, type, options) VALUES ($1, $2, $3)",
-                &[types::Type::TEXT, types::Type::INT4, types::Type::BYTEA],
+                "INSERT INTO peers (name, type, options, enc_key_id, enc_data_key) VALUES ($1, $2, $3, $4, $5)",
+                &[
+                    types::Type::TEXT,
+                    types::Type::INT4,
+                    types::Type::BYTEA,
+                    types::Type::TEXT,
+                    types::Type::BYTEA,
+                ],
             )
             .await?;
 
         self.pg
-            .execute(&stmt, &[&peer.name, &peer.r#type, &config_blob])
+            .execute(
+                &stmt,
+                &[
+                    &peer.name,
+                    &peer.r#type,
+                    &config_blob,
+                    &enc_key_id,
+                    &enc_data_key,
+                ],
+            )
             .await?;
 
         self.get_peer_id(&peer.name).await
@@ -199,7 +229,10 @@ impl Catalog {
     pub async fn get_peers(&self) -> anyhow::Result<HashMap<String, Peer>> {
         let stmt = self
             .pg
-            .prepare_typed("SELECT id, name, type, options FROM public.peers", &[])
+            .prepare_typed(
+                "SELECT id, name, type, options, enc_key_id, enc_data_key FROM public.peers",
+                &[],
+            )
             .await?;
 
         let rows = self.pg.query(&stmt, &[]).await?;
@@ -209,9 +242,9 @@ impl Catalog {
         for row in rows {
             let name: &str = row.get(1);
             let peer_type: i32 = row.get(2);
-            let options: &[u8] = row.get(3);
+            let options = self.decrypt_options(name, row.get(3), row.get(4), row.get(5))?;
             let db_type = DbType::try_from(peer_type).ok();
-            let config = self.get_config(db_type, name, options).await?;
+            let config = self.get_config(db_type, name, &options).await?;
 
             let peer = Peer {
                 name: name.to_lowercase(),
@@ -228,7 +261,7 @@ impl Catalog {
         let stmt = self
             .pg
             .prepare_typed(
-                "SELECT id, name, type, options FROM public.peers WHERE name = $1",
+                "SELECT id, name, type, options, enc_key_id, enc_data_key FROM public.peers WHERE name = $1",
                 &[],
             )
             .await?;
@@ -238,9 +271,9 @@ impl Catalog {
         if let Some(row) = rows.first() {
             let name: &str = row.get(1);
             let peer_type: i32 = row.get(2);
-            let options: &[u8] = row.get(3);
+            let options = self.decrypt_options(name, row.get(3), row.get(4), row.get(5))?;
             let db_type = DbType::try_from(peer_type).ok();
-            let config = self.get_config(db_type, name, options).await?;
+            let config = self.get_config(db_type, name, &options).await?;
 
             let peer = Peer {
                 name: name.to_lowercase(),
@@ -258,7 +291,7 @@ impl Catalog {
         let stmt = self
             .pg
             .prepare_typed(
-                "SELECT name, type, options FROM public.peers WHERE id = $1",
+                "SELECT name, type, options, enc_key_id, enc_data_key FROM public.peers WHERE id = $1",
                 &[],
             )
             .await?;
@@ -268,9 +301,9 @@ impl Catalog {
         if let Some(row) = rows.first() {
             let name: &str = row.get(0);
             let peer_type: i32 = row.get(1);
-            let options: &[u8] = row.get(2);
+            let options = self.decrypt_options(name, row.get(2), row.get(3), row.get(4))?;
             let db_type = DbType::try_from(peer_type).ok();
-            let config = self.get_config(db_type, name, options).await?;
+            let config = self.get_config(db_type, name, &options).await?;
 
             let peer = Peer {
                 name: name.to_lowercase(),
@@ -284,6 +317,18 @@ impl Catalog {
         }
     }
 
+    // decrypts the options of a peer row, if they're encrypted
+    fn decrypt_options(
+        &self,
+        name: &str,
+        options: &[u8],
+        enc_key_id: Option<&str>,
+        enc_data_key: Option<&[u8]>,
+    ) -> anyhow::Result<Vec<u8>> {
+        encryption::decrypt_peer_options(&self.encryption_keys, options, enc_key_id, enc_data_key)
+            .with_context(|| format!("unable to decrypt options for peer {}", name))
+    }
+
     pub async fn get_config(
         &self,
         db_type: Option<DbType>,
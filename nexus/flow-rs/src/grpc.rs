@@ -61,6 +61,7 @@ impl FlowGrpcClient {
     ) -> anyhow::Result<PeerValidationResult> {
         let validate_peer_req = pt::peerdb_route::ValidatePeerRequest {
             peer: validate_request.peer.clone(),
+            force_refresh: validate_request.force_refresh,
         };
         let response = self.client.validate_peer(validate_peer_req).await?;
         let response_body = &response.into_inner();
@@ -80,6 +81,7 @@ impl FlowGrpcClient {
         let create_peer_flow_req = pt::peerdb_route::CreateCdcFlowRequest {
             connection_configs: Some(peer_flow_config),
             create_catalog_entry: false,
+            metadata: None,
         };
         let response = self.client.create_cdc_flow(create_peer_flow_req).await?;
         let workflow_id = response.into_inner().workflow_id;
@@ -141,6 +143,7 @@ impl FlowGrpcClient {
                 destination_table_identifier: mapping.destination_table_identifier.clone(),
                 partition_key: mapping.partition_key.clone().unwrap_or_default(),
                 exclude: mapping.exclude.clone(),
+                ..Default::default()
             });
         });
 
@@ -166,6 +169,7 @@ impl FlowGrpcClient {
             soft_delete: job.soft_delete,
             replication_slot_name: replication_slot_name.unwrap_or_default(),
             max_batch_size: job.max_batch_size.unwrap_or_default(),
+            max_batch_bytes: job.max_batch_bytes.unwrap_or_default(),
             resync: job.resync,
             soft_delete_col_name: job.soft_delete_col_name.clone().unwrap_or_default(),
             synced_at_col_name: job.synced_at_col_name.clone().unwrap_or_default(),
@@ -224,6 +228,7 @@ impl FlowGrpcClient {
                         }
                     }
                     "staging_path" => cfg.staging_path = s.clone(),
+                    "row_filter" => cfg.row_filter = s.clone(),
                     _ => return anyhow::Result::Err(anyhow::anyhow!("invalid str option {}", key)),
                 },
                 Value::Number(n) => match key.as_str() {
@@ -242,6 +247,21 @@ impl FlowGrpcClient {
                             cfg.num_rows_per_partition = n as u32;
                         }
                     }
+                    "max_rows_per_second" => {
+                        if let Some(n) = n.as_i64() {
+                            cfg.max_rows_per_second = n as u32;
+                        }
+                    }
+                    "max_bytes_per_second" => {
+                        if let Some(n) = n.as_i64() {
+                            cfg.max_bytes_per_second = n as u64;
+                        }
+                    }
+                    "throttle_burst_seconds" => {
+                        if let Some(n) = n.as_i64() {
+                            cfg.throttle_burst_seconds = n as u32;
+                        }
+                    }
                     _ => return anyhow::Result::Err(anyhow::anyhow!("invalid num option {}", key)),
                 },
                 Value::Bool(v) => {
@@ -251,10 +271,19 @@ impl FlowGrpcClient {
                         cfg.setup_watermark_table_on_destination = *v;
                     } else if key == "dst_table_full_resync" {
                         cfg.dst_table_full_resync = *v;
+                    } else if key == "native_import" {
+                        cfg.native_import = *v;
                     } else {
                         return anyhow::Result::Err(anyhow::anyhow!("invalid bool option {}", key));
                     }
                 }
+                Value::Array(arr) if key == "watermark_columns" => {
+                    for v in arr {
+                        if let Value::String(s) = v {
+                            cfg.watermark_columns.push(s.clone());
+                        }
+                    }
+                }
                 _ => {
                     tracing::info!("ignoring option {} with value {:?}", key, value);
                 }
@@ -203,6 +203,7 @@ impl NexusBackend {
                 r#type: peer.r#type,
                 config: peer.config.clone(),
             }),
+            force_refresh: true,
         };
         let validity = flow_handler
             .validate_peer(&validate_request)
@@ -7,12 +7,13 @@ import { GetFlowHttpAddressFromEnv } from '@/rpc/http';
 
 export async function POST(request: Request) {
   const body = await request.json();
-  const { config } = body;
+  const { config, metadata } = body;
   console.log('/mirrors/cdc config: ', config);
   const flowServiceAddr = GetFlowHttpAddressFromEnv();
   const req: CreateCDCFlowRequest = {
     connectionConfigs: config,
     createCatalogEntry: true,
+    metadata,
   };
   try {
     const createStatus: CreateCDCFlowResponse = await fetch(
@@ -12,6 +12,7 @@ export async function POST(request: Request) {
   const req: CreateCDCFlowRequest = {
     connectionConfigs: config,
     createCatalogEntry: false,
+    metadata: undefined,
   };
   try {
     const validateResponse: ValidateCDCMirrorResponse = await fetch(
@@ -10,13 +10,18 @@ import {
   SnowflakeConfig,
   SqlServerConfig,
 } from '@/grpc_generated/peers';
+import { decryptPeerOptions } from './peerEncryption';
 
 export const getTruePeer = (peer: CatalogPeer) => {
   const newPeer: Peer = {
     name: peer.name,
     type: peer.type,
   };
-  const options = peer.options;
+  const options = decryptPeerOptions(
+    peer.options,
+    peer.enc_key_id,
+    peer.enc_data_key
+  );
   let config:
     | BigqueryConfig
     | SnowflakeConfig
@@ -0,0 +1,57 @@
+import { createDecipheriv } from 'crypto';
+
+// Peer configs are stored with envelope encryption when PEERDB_CATALOG_ENCRYPTION_KEYS is set:
+// options holds the config encrypted with AES-256-GCM under a data key of its own, enc_data_key holds
+// that data key encrypted with the key named by enc_key_id. Both are prefixed by their nonce.
+const nonceSize = 12;
+const tagSize = 16;
+
+const getEncryptionKey = (keyId: string): Buffer => {
+  const keys = (process.env.PEERDB_CATALOG_ENCRYPTION_KEYS ?? '').split(',');
+  for (const keyPair of keys) {
+    const [id, encodedKey] = keyPair.trim().split(':');
+    // like the flow API and nexus, AWS KMS keys are rejected until all of them
+    // can unwrap data keys with them
+    if (encodedKey === 'kms') {
+      throw new Error(
+        `catalog encryption key ${id} is an AWS KMS key, which the UI can't unwrap data keys with`
+      );
+    }
+    if (id === keyId && encodedKey) {
+      return Buffer.from(encodedKey, 'base64');
+    }
+  }
+  throw new Error(`catalog encryption key ${keyId} is not configured`);
+};
+
+const open = (key: Buffer, sealed: Buffer): Buffer => {
+  if (sealed.length < nonceSize + tagSize) {
+    throw new Error('ciphertext too short');
+  }
+  const decipher = createDecipheriv(
+    'aes-256-gcm',
+    key,
+    sealed.subarray(0, nonceSize)
+  );
+  decipher.setAuthTag(sealed.subarray(sealed.length - tagSize));
+  return Buffer.concat([
+    decipher.update(sealed.subarray(nonceSize, sealed.length - tagSize)),
+    decipher.final(),
+  ]);
+};
+
+// decryptPeerOptions returns the serialized config of a catalog peer, decrypting it if it's encrypted
+export const decryptPeerOptions = (
+  options: Buffer,
+  encKeyId?: string | null,
+  encDataKey?: Buffer | null
+): Buffer => {
+  if (!encKeyId) {
+    return options;
+  }
+  const dataKey = open(
+    getEncryptionKey(encKeyId),
+    encDataKey ?? Buffer.alloc(0)
+  );
+  return open(dataKey, options);
+};
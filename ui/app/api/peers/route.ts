@@ -77,7 +77,7 @@ export async function POST(request: Request) {
   const flowServiceAddr = GetFlowHttpAddressFromEnv();
   const peer = constructPeer(name, type, config);
   if (mode === 'validate') {
-    const validateReq: ValidatePeerRequest = { peer };
+    const validateReq: ValidatePeerRequest = { peer, forceRefresh: false };
     try {
       const validateStatus: ValidatePeerResponse = await fetch(
         `${flowServiceAddr}/v1/peers/validate`,
@@ -49,6 +49,8 @@ export type CatalogPeer = {
   name: string;
   type: number;
   options: Buffer;
+  enc_key_id?: string | null;
+  enc_data_key?: Buffer | null;
 };
 export type PeerSetter = React.Dispatch<React.SetStateAction<PeerConfig>>;
 
@@ -31,6 +31,10 @@ const notifyErr = (errMsg: string) => {
 const EditMirror = ({ params: { mirrorId } }: EditMirrorProps) => {
   const defaultBatchSize = blankCDCSetting.maxBatchSize;
   const defaultIdleTimeout = blankCDCSetting.idleTimeoutSeconds;
+  const defaultSnapshotMaxParallelWorkers =
+    blankCDCSetting.snapshotMaxParallelWorkers;
+  const defaultSnapshotNumRowsPerPartition =
+    blankCDCSetting.snapshotNumRowsPerPartition;
 
   const [rows, setRows] = useState<TableMapRow[]>([]);
   const [loading, setLoading] = useState(false);
@@ -39,6 +43,8 @@ const EditMirror = ({ params: { mirrorId } }: EditMirrorProps) => {
     batchSize: defaultBatchSize,
     idleTimeout: defaultIdleTimeout,
     additionalTables: [],
+    snapshotMaxParallelWorkers: defaultSnapshotMaxParallelWorkers,
+    snapshotNumRowsPerPartition: defaultSnapshotNumRowsPerPartition,
   });
   const { push } = useRouter();
 
@@ -61,9 +67,22 @@ const EditMirror = ({ params: { mirrorId } }: EditMirrorProps) => {
             (res as MirrorStatusResponse).cdcStatus?.config
               ?.idleTimeoutSeconds || defaultIdleTimeout,
           additionalTables: [],
+          snapshotMaxParallelWorkers:
+            (res as MirrorStatusResponse).cdcStatus?.config
+              ?.snapshotMaxParallelWorkers || defaultSnapshotMaxParallelWorkers,
+          snapshotNumRowsPerPartition:
+            (res as MirrorStatusResponse).cdcStatus?.config
+              ?.snapshotNumRowsPerPartition ||
+            defaultSnapshotNumRowsPerPartition,
         });
       });
-  }, [mirrorId, defaultBatchSize, defaultIdleTimeout]);
+  }, [
+    mirrorId,
+    defaultBatchSize,
+    defaultIdleTimeout,
+    defaultSnapshotMaxParallelWorkers,
+    defaultSnapshotNumRowsPerPartition,
+  ]);
 
   useEffect(() => {
     fetchStateAndUpdateDeps();
@@ -170,6 +189,58 @@ const EditMirror = ({ params: { mirrorId } }: EditMirrorProps) => {
         }
       />
 
+      <RowWithTextField
+        key={3}
+        label={<Label>{'Snapshot Maximum Parallel Workers'} </Label>}
+        action={
+          <div
+            style={{
+              display: 'flex',
+              flexDirection: 'row',
+              alignItems: 'center',
+            }}
+          >
+            <TextField
+              variant='simple'
+              type={'number'}
+              onChange={(e: React.ChangeEvent<HTMLInputElement>) =>
+                setConfig({
+                  ...config,
+                  snapshotMaxParallelWorkers: e.target.valueAsNumber,
+                })
+              }
+              defaultValue={config.snapshotMaxParallelWorkers}
+            />
+          </div>
+        }
+      />
+
+      <RowWithTextField
+        key={4}
+        label={<Label>{'Snapshot Number of Rows Per Partition'} </Label>}
+        action={
+          <div
+            style={{
+              display: 'flex',
+              flexDirection: 'row',
+              alignItems: 'center',
+            }}
+          >
+            <TextField
+              variant='simple'
+              type={'number'}
+              onChange={(e: React.ChangeEvent<HTMLInputElement>) =>
+                setConfig({
+                  ...config,
+                  snapshotNumRowsPerPartition: e.target.valueAsNumber,
+                })
+              }
+              defaultValue={config.snapshotNumRowsPerPartition}
+            />
+          </div>
+        }
+      />
+
       <TableMapping
         sourcePeerName={mirrorState.cdcStatus?.config?.source?.name || ''}
         peerType={mirrorState.cdcStatus?.config?.destination?.type}
@@ -65,6 +65,14 @@ const CDCCheck = (
     return 'Initial Snapshot Only cannot be true if Initial Snapshot is false.';
   }
 
+  if (config.adoptExistingTables == true && config.doInitialSnapshot == true) {
+    return 'Initial Snapshot must be turned off to adopt existing tables.';
+  }
+
+  if (config.verifyAdoptedTables == true && config.adoptExistingTables != true) {
+    return 'Verify Adopted Tables requires Adopt Existing Tables.';
+  }
+
   if (config.doInitialSnapshot == true && config.replicationSlotName !== '') {
     config.replicationSlotName = '';
   }
@@ -364,6 +372,13 @@ export const handleValidateCDC = async (
     setLoading(false);
     return;
   }
-  notify('CDC Mirror is valid', true);
+  if (status.warnings?.length) {
+    notify(
+      `CDC Mirror is valid with warnings: ${status.warnings.join('; ')}`,
+      true
+    );
+  } else {
+    notify('CDC Mirror is valid', true);
+  }
   setLoading(false);
 };
@@ -1,3 +1,4 @@
+import { QRepPartitionMode } from '@/grpc_generated/flow';
 import { CDCConfig } from '../../../dto/MirrorsDTO';
 import { MirrorSetting } from './common';
 export const cdcSettings: MirrorSetting[] = [
@@ -24,6 +25,18 @@ export const cdcSettings: MirrorSetting[] = [
     default: '1000000',
     advanced: true,
   },
+  {
+    label: 'Pull Batch Size (MB)',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        maxBatchBytes: ((value as number) || 0) * 1024 * 1024,
+      })),
+    tips: 'Ends a batch once its rows are estimated to take this many megabytes, so tables with large JSON or TOASTed values do not build batches too big for the worker or destination. Defaults to 0, which uses the deployment-wide limit.',
+    type: 'number',
+    default: '0',
+    advanced: true,
+  },
   {
     label: 'Sync Interval (Seconds)',
     stateHandler: (value, setter) =>
@@ -36,6 +49,30 @@ export const cdcSettings: MirrorSetting[] = [
     type: 'number',
     default: '60',
   },
+  {
+    label: 'Apply Delay (Seconds)',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        applyDelaySeconds: (value as number) || 0,
+      })),
+    tips: 'Keeps the destination tables this far behind by holding back normalization of synced batches, giving time to react to accidental destructive changes at the source. Defaults to 0, no delay.',
+    type: 'number',
+    default: '0',
+    advanced: true,
+  },
+  {
+    label: 'Source Heartbeat Interval (Seconds)',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        sourceHeartbeatIntervalSeconds: (value as number) || 0,
+      })),
+    tips: 'Writes a small logical decoding message to the source this often, so the replication slot of a database with little traffic keeps advancing instead of retaining WAL. Defaults to 0, which uses the deployment-wide interval.',
+    type: 'number',
+    default: '0',
+    advanced: true,
+  },
   {
     label: 'Publication Name',
     stateHandler: (value, setter) =>
@@ -76,6 +113,34 @@ export const cdcSettings: MirrorSetting[] = [
     default: '1',
     type: 'number',
   },
+  {
+    label: 'Snapshot Partitions From Table Statistics',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        snapshotPartitionMode: (value as boolean)
+          ? QRepPartitionMode.QREP_PARTITION_MODE_RANGES
+          : QRepPartitionMode.QREP_PARTITION_MODE_NUM_ROWS,
+      })),
+    tips: 'Splits tables into ctid block ranges (Postgres 14+) or integer primary key ranges sized from table statistics, instead of counting rows with a scan of the table. Partitions synced before a retry are not synced again. Recommended for very large tables.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Adaptive Snapshot Partition Sizing',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        snapshotPartitionMode: (value as boolean)
+          ? QRepPartitionMode.QREP_PARTITION_MODE_ADAPTIVE
+          : QRepPartitionMode.QREP_PARTITION_MODE_NUM_ROWS,
+      })),
+    tips: 'Sizes snapshot partitions of integer or timestamp primary keys from the histogram of the column, so that partitions hold roughly the number of rows per partition even when values are skewed. Requires tables to have been analyzed.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
   {
     label: 'Snapshot Number of Tables In Parallel',
     stateHandler: (value, setter) =>
@@ -96,6 +161,66 @@ export const cdcSettings: MirrorSetting[] = [
       })),
     tips: 'You can specify staging path for Snapshot sync mode AVRO. For Snowflake as destination peer, this must be either empty or an S3 bucket URL. For BigQuery, this must be either empty or an existing GCS bucket name. In both cases, if empty, the local filesystem will be used.',
   },
+  {
+    label: 'Snapshot Native Import',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        snapshotNativeImport: (value as boolean) || false,
+      })),
+    tips: 'If set, initial load partitions are only exported to the snapshot staging path and the destination imports them in one bulk load. Requires a staging path.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Require Approval For Schema Changes',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        schemaChangesRequireApproval: (value as boolean) || false,
+      })),
+    tips: 'If set, new columns detected at the source are queued until approved through the API, and only then added to the destination. Rows synced in the meantime will not have values for the pending columns.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Pause On Incompatible Schema Changes',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        pauseOnIncompatibleSchemaChanges: (value as boolean) || false,
+      })),
+    tips: 'If set, the mirror pauses when a source column is changed to a narrower type or a primary key column is dropped, until the change is approved through the API with how to proceed.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Decode Prepared Transactions',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        twoPhaseCommit: (value as boolean) || false,
+      })),
+    tips: 'Creates the replication slot with two-phase decoding, so transactions using PREPARE TRANSACTION are decoded when prepared and applied once COMMIT PREPARED is seen. Requires Postgres 15 or later.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Filter Excluded Columns At Source',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        publicationColumnLists: (value as boolean) || false,
+      })),
+    tips: 'Leaves excluded columns out of the publication using column lists, so Postgres does not decode or send them. Requires Postgres 15 or later. Columns added to the source tables later are not replicated until they are added to the publication.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
   {
     label: 'CDC Staging Path',
     stateHandler: (value, setter) =>
@@ -127,4 +252,38 @@ export const cdcSettings: MirrorSetting[] = [
     type: 'switch',
     advanced: true,
   },
+  {
+    label: 'Adopt Existing Tables',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        adoptExistingTables: (value as boolean) || false,
+      })),
+    tips: 'Replicates into destination tables which already hold a copy of the data, for example loaded by another tool, instead of creating them. Their columns must match the source tables. Initial copy must be turned off, changes made from when the mirror is created are replicated.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Verify Adopted Tables',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        verifyAdoptedTables: (value as boolean) || false,
+      })),
+    tips: 'Compares the primary keys of adopted tables with their source tables before replicating, failing the mirror if they differ. This scans every table on both peers.',
+    default: false,
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Transform Script',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        transformScript: (value as string) || '',
+      })),
+    tips: 'Lua script defining transform(record), which every change passes through before it is synced. It can rename, drop, derive or redact columns of record.row, route the record by setting record.target to another destination table of the mirror, or drop it by returning nil. Rows of the initial snapshot pass through it as inserts. Tables whose columns it changes are declared in output, e.g. output = { ["public.orders"] = { {name = "id", type = "int64"} } }, and created with those columns.',
+    advanced: true,
+  },
 ];
@@ -1,4 +1,8 @@
-import { FlowConnectionConfigs, QRepWriteType } from '@/grpc_generated/flow';
+import {
+  FlowConnectionConfigs,
+  QRepPartitionMode,
+  QRepWriteType,
+} from '@/grpc_generated/flow';
 import { Peer } from '@/grpc_generated/peers';
 
 export interface MirrorSetting {
@@ -21,12 +25,22 @@ export const blankCDCSetting: FlowConnectionConfigs = {
   flowJobName: '',
   tableMappings: [],
   maxBatchSize: 1000000,
+  maxBatchBytes: 0,
   doInitialSnapshot: true,
   publicationName: '',
   snapshotNumRowsPerPartition: 500000,
   snapshotMaxParallelWorkers: 1,
   snapshotNumTablesInParallel: 4,
   snapshotStagingPath: '',
+  snapshotNativeImport: false,
+  schemaChangesRequireApproval: false,
+  pauseOnIncompatibleSchemaChanges: false,
+  applyDelaySeconds: 0,
+  sourceHeartbeatIntervalSeconds: 0,
+  twoPhaseCommit: false,
+  snowflake: undefined,
+  publicationColumnLists: false,
+  snapshotPartitionMode: QRepPartitionMode.QREP_PARTITION_MODE_NUM_ROWS,
   cdcStagingPath: '',
   softDelete: false,
   replicationSlotName: '',
@@ -35,6 +49,8 @@ export const blankCDCSetting: FlowConnectionConfigs = {
   syncedAtColName: '',
   initialSnapshotOnly: false,
   idleTimeoutSeconds: 60,
+  adoptExistingTables: false,
+  verifyAdoptedTables: false,
 };
 
 export const blankQRepSetting = {
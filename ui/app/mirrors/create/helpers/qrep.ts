@@ -1,5 +1,6 @@
 import {
   QRepConfig,
+  QRepPartitionMode,
   QRepWriteMode,
   QRepWriteType,
 } from '@/grpc_generated/flow';
@@ -27,6 +28,15 @@ export const qrepSettings: MirrorSetting[] = [
     tips: 'Watermark column is used to track the progress of the replication. This column should be a unique column in the query. Example: id',
     required: true,
   },
+  {
+    label: 'Composite Watermark Columns',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        watermarkColumns: (value as string[]) || [],
+      })),
+    tips: 'Comma separated columns to partition on in lexicographic order instead of the watermark column, for tables where no single column is unique and increasing. Example: updated_at, id',
+  },
   {
     label: 'Create Destination Table',
     stateHandler: (value, setter) =>
@@ -70,6 +80,32 @@ export const qrepSettings: MirrorSetting[] = [
     default: '4',
     type: 'number',
   },
+  {
+    label: 'Partitions From Table Statistics',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        partitionMode: (value as boolean)
+          ? QRepPartitionMode.QREP_PARTITION_MODE_RANGES
+          : QRepPartitionMode.QREP_PARTITION_MODE_NUM_ROWS,
+      })),
+    tips: 'For full loads, splits the table into ctid block ranges (Postgres 14+) or integer watermark column ranges sized from table statistics, instead of counting rows with a scan of the table. Partitions synced before a retry are not synced again.',
+    default: false,
+    type: 'switch',
+  },
+  {
+    label: 'Adaptive Partition Sizing',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        partitionMode: (value as boolean)
+          ? QRepPartitionMode.QREP_PARTITION_MODE_ADAPTIVE
+          : QRepPartitionMode.QREP_PARTITION_MODE_NUM_ROWS,
+      })),
+    tips: 'For full loads, sizes partitions of integer or timestamp watermark columns from the histogram of the column, so that partitions hold roughly the number of rows per partition even when values are skewed. Requires the table to have been analyzed.',
+    default: false,
+    type: 'switch',
+  },
   {
     label: 'Staging Path',
     stateHandler: (value, setter) =>
@@ -137,6 +173,60 @@ export const qrepSettings: MirrorSetting[] = [
     default: 30,
     type: 'number',
   },
+  {
+    label: 'Delete Reconciliation Interval',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        deleteReconciliationIntervalSeconds: parseInt(value as string, 10) || 0,
+      })),
+    tips: `Time (in seconds) between comparing the destination table's upsert key columns with the watermark table's,
+    deleting rows missing at the source, or marking them deleted if a soft delete column is set. 0 disables this.`,
+    default: 0,
+    type: 'number',
+  },
+  {
+    label: 'Max Rows Per Second',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        maxRowsPerSecond: parseInt(value as string, 10) || 0,
+      })),
+    tips: 'Throttles replication to this many rows a second across all parallel workers, so backfills cannot saturate the source or destination. 0 is unlimited.',
+    default: 0,
+    type: 'number',
+  },
+  {
+    label: 'Max Bytes Per Second',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        maxBytesPerSecond: parseInt(value as string, 10) || 0,
+      })),
+    tips: 'Throttles replication to about this many bytes a second across all parallel workers. 0 is unlimited.',
+    default: 0,
+    type: 'number',
+  },
+  {
+    label: 'Throttle Burst',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        throttleBurstSeconds: parseInt(value as string, 10) || 0,
+      })),
+    tips: 'How many seconds worth of rows and bytes may be replicated at once after idling. The default is 1 second.',
+    default: 0,
+    type: 'number',
+  },
+  {
+    label: 'Row Filter',
+    stateHandler: (value, setter) =>
+      setter((curr: QRepConfig) => ({
+        ...curr,
+        rowFilter: (value as string) || '',
+      })),
+    tips: 'Only rows of the query matching this SQL condition are replicated. Example: tenant_id = 42 AND NOT is_test',
+  },
   // {
   //   label: 'Resync Destination Table',
   //   stateHandler: (value, setter) =>
@@ -67,6 +67,12 @@ export default function QRepConfigForm({
     } else if (setting.label === 'Upsert Key Columns') {
       const columns = val as string;
       stateVal = columns.split(',').map((item) => item.trim());
+    } else if (setting.label === 'Composite Watermark Columns') {
+      const columns = val as string;
+      stateVal = columns
+        .split(',')
+        .map((item) => item.trim())
+        .filter((item) => item !== '');
     }
     setting.stateHandler(stateVal, setter);
   };
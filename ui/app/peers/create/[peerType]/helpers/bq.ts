@@ -12,4 +12,6 @@ export const blankBigquerySetting: BigqueryConfig = {
   authProviderX509CertUrl: '',
   clientX509CertUrl: '',
   datasetId: '',
+  location: '',
+  kmsKeyName: '',
 };
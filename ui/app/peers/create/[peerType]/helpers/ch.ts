@@ -74,6 +74,31 @@ export const clickhouseSetting: PeerSetting[] = [
       setter((curr) => ({ ...curr, region: value as string })),
     tips: 'The region where your bucket is located. For example, us-east-1.',
   },
+  {
+    label: 'Cluster',
+    stateHandler: (value, setter) =>
+      setter((curr) => ({ ...curr, cluster: value as string })),
+    optional: true,
+    tips: 'Name of the ClickHouse cluster, tables are then created on all of its nodes with ON CLUSTER. Leave empty for a single server.',
+    helpfulLink:
+      'https://clickhouse.com/docs/en/sql-reference/distributed-ddl',
+  },
+  {
+    label: 'Replicated Tables?',
+    stateHandler: (value, setter) =>
+      setter((curr) => ({ ...curr, replicated: value as boolean })),
+    type: 'switch',
+    optional: true,
+    tips: 'Create tables with Replicated*MergeTree engines, using the replica path and name configured on the server.',
+  },
+  {
+    label: 'Distributed Tables?',
+    stateHandler: (value, setter) =>
+      setter((curr) => ({ ...curr, distributed: value as boolean })),
+    type: 'switch',
+    optional: true,
+    tips: 'Store mirrored tables as per-shard local tables with a Distributed table in front, sharded by primary key. Requires a cluster.',
+  },
 ];
 
 export const blankClickhouseSetting: ClickhouseConfig = {
@@ -87,4 +112,7 @@ export const blankClickhouseSetting: ClickhouseConfig = {
   secretAccessKey: '',
   region: '',
   disableTls: false,
+  cluster: '',
+  replicated: false,
+  distributed: false,
 };
@@ -1,4 +1,8 @@
-import { S3Config } from '@/grpc_generated/peers';
+import {
+  ParquetCompression,
+  S3Config,
+  S3Format,
+} from '@/grpc_generated/peers';
 import { PeerSetting } from './common';
 
 export const s3Setting: PeerSetting[] = [
@@ -52,4 +56,9 @@ export const blankS3Setting: S3Config = {
   roleArn: undefined,
   region: undefined,
   endpoint: '',
+  format: S3Format.S3_FORMAT_AVRO,
+  parquetCompression: ParquetCompression.PARQUET_COMPRESSION_SNAPPY,
+  keyTemplate: '',
+  targetFileSizeBytes: 0,
+  maxRowsPerFile: 0,
 };
@@ -227,6 +227,16 @@ export const bqSchema = z.object({
     })
     .min(1, { message: 'Dataset ID must be non-empty' })
     .max(1024, 'DatasetID must be less than 1025 characters'),
+  location: z
+    .string({
+      invalid_type_error: 'Location must be a string',
+    })
+    .optional(),
+  kmsKeyName: z
+    .string({
+      invalid_type_error: 'KMS key name must be a string',
+    })
+    .optional(),
 });
 
 export const chSchema = z.object({
@@ -278,6 +288,11 @@ export const chSchema = z.object({
   region: z
     .string({ invalid_type_error: 'Region must be a string' })
     .optional(),
+  cluster: z
+    .string({ invalid_type_error: 'Cluster must be a string' })
+    .optional(),
+  replicated: z.boolean().optional(),
+  distributed: z.boolean().optional(),
 });
 
 const urlSchema = z
@@ -15,6 +15,8 @@ interface BQProps {
 }
 export default function BigqueryForm(props: BQProps) {
   const [datasetID, setDatasetID] = useState<string>('');
+  const [location, setLocation] = useState<string>('');
+  const [kmsKeyName, setKmsKeyName] = useState<string>('');
   const handleJSONFile = (file: File) => {
     if (file) {
       const reader = new FileReader();
@@ -40,6 +42,8 @@ export default function BigqueryForm(props: BQProps) {
           authProviderX509CertUrl: bqJson.auth_provider_x509_cert_url,
           clientX509CertUrl: bqJson.client_x509_cert_url,
           datasetId: datasetID,
+          location: location,
+          kmsKeyName: kmsKeyName,
         };
         props.setter(bqConfig);
       };
@@ -127,6 +131,66 @@ export default function BigqueryForm(props: BQProps) {
           </div>
         }
       />
+
+      <RowWithTextField
+        label={<Label>Location</Label>}
+        action={
+          <div
+            style={{
+              display: 'flex',
+              flexDirection: 'row',
+              alignItems: 'center',
+            }}
+          >
+            <TextField
+              variant='simple'
+              onChange={(e: React.ChangeEvent<HTMLInputElement>) => {
+                setLocation(e.target.value);
+                props.setter((curr) => ({
+                  ...curr,
+                  location: e.target.value,
+                }));
+              }}
+            />
+            <InfoPopover
+              tips={
+                'Location to create datasets and run jobs in, for example EU or us-east1. Must match the location of the dataset above.'
+              }
+              link='https://cloud.google.com/bigquery/docs/locations'
+            />
+          </div>
+        }
+      />
+
+      <RowWithTextField
+        label={<Label>KMS Key Name</Label>}
+        action={
+          <div
+            style={{
+              display: 'flex',
+              flexDirection: 'row',
+              alignItems: 'center',
+            }}
+          >
+            <TextField
+              variant='simple'
+              onChange={(e: React.ChangeEvent<HTMLInputElement>) => {
+                setKmsKeyName(e.target.value);
+                props.setter((curr) => ({
+                  ...curr,
+                  kmsKeyName: e.target.value,
+                }));
+              }}
+            />
+            <InfoPopover
+              tips={
+                'Cloud KMS key used to encrypt the datasets, tables and job results PeerDB creates, in the form projects/P/locations/L/keyRings/R/cryptoKeys/K.'
+              }
+              link='https://cloud.google.com/bigquery/docs/customer-managed-encryption'
+            />
+          </div>
+        }
+      />
     </>
   );
 }